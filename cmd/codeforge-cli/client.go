@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/ws"
+)
+
+// client calls the CodeForge JSON API, authenticating with an API key if
+// one is set.
+type client struct {
+	baseURL string // Server URL without the /api/v1 prefix
+	apiKey  string
+	http    *http.Client
+}
+
+func newClient(baseURL, apiKey string) *client {
+	return &client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		http:    &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// apiError is an error response of the API.
+type apiError struct {
+	Status  int
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.Status)
+}
+
+// do sends a request to path below /api/v1 with in as JSON body, if set,
+// and decodes the response into out, if set.
+func (c *client) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader = http.NoBody
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/api/v1"+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.authorize(req.Header)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if json.Unmarshal(data, &e) != nil || e.Error == "" {
+			e.Error = strings.TrimSpace(string(data))
+		}
+		return &apiError{Status: resp.StatusCode, Message: e.Error}
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *client) authorize(h http.Header) {
+	if c.apiKey != "" {
+		h.Set("Authorization", "Bearer "+c.apiKey)
+	}
+}
+
+// subscribe connects to the WebSocket hub and subscribes to topics. Each
+// event is passed to fn until fn returns false, the context ends or the
+// connection closes.
+func (c *client) subscribe(ctx context.Context, topics []string, fn func(ws.Message) bool) error {
+	u, err := url.Parse(c.baseURL + "/ws")
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+	h := http.Header{}
+	c.authorize(h)
+	conn, _, err := websocket.Dial(ctx, u.String(), &websocket.DialOptions{HTTPHeader: h})
+	if err != nil {
+		return fmt.Errorf("connect %s: %w", u, err)
+	}
+	defer func() { _ = conn.CloseNow() }()
+	conn.SetReadLimit(1 << 20)
+
+	// Without topics the hub sends every event.
+	if len(topics) > 0 {
+		sub, _ := json.Marshal(map[string]any{"action": ws.ActionSubscribe, "topics": topics})
+		if err := conn.Write(ctx, websocket.MessageText, sub); err != nil {
+			return fmt.Errorf("subscribe: %w", err)
+		}
+	}
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			return err
+		}
+		var m ws.Message
+		if json.Unmarshal(data, &m) != nil {
+			continue
+		}
+		if m.Type == ws.EventError {
+			var e ws.ErrorPayload
+			_ = json.Unmarshal(m.Payload, &e)
+			return fmt.Errorf("subscribe: %s", e.Message)
+		}
+		if !fn(m) {
+			_ = conn.Close(websocket.StatusNormalClosure, "")
+			return nil
+		}
+	}
+}
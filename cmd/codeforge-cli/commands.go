@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/ws"
+	"github.com/Strob0t/CodeForge/internal/domain/cost"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+)
+
+// newFlags returns a flag set of a subcommand.
+func (c *cli) newFlags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.out)
+	return fs
+}
+
+// oneArg returns the single positional argument of a subcommand.
+func oneArg(fs *flag.FlagSet, args []string, what string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if fs.NArg() != 1 {
+		return "", fmt.Errorf("%s: expected <%s>", fs.Name(), what)
+	}
+	return fs.Arg(0), nil
+}
+
+// print writes v as JSON with -json, otherwise calls text.
+func (c *cli) print(v any, text func()) error {
+	if c.json {
+		enc := json.NewEncoder(c.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	text()
+	return nil
+}
+
+func (c *cli) projectList(ctx context.Context) error {
+	var projects []project.Project
+	if err := c.api.do(ctx, "GET", "/projects", nil, &projects); err != nil {
+		return err
+	}
+	return c.print(projects, func() {
+		for i := range projects {
+			p := &projects[i]
+			fmt.Fprintf(c.out, "%s  %-24s %s\n", p.ID, p.Name, p.RepoURL)
+		}
+	})
+}
+
+func (c *cli) projectCreate(ctx context.Context, args []string) error {
+	fs := c.newFlags("project create")
+	var req project.CreateRequest
+	fs.StringVar(&req.Name, "name", "", "project name (required)")
+	fs.StringVar(&req.Description, "description", "", "description")
+	fs.StringVar(&req.RepoURL, "repo-url", "", "git repository URL")
+	fs.StringVar(&req.Provider, "provider", "", "git provider")
+	fs.StringVar(&req.TenantID, "tenant", "", "tenant ID")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if req.Name == "" {
+		return errors.New("project create: -name is required")
+	}
+
+	var p project.Project
+	if err := c.api.do(ctx, "POST", "/projects", &req, &p); err != nil {
+		return err
+	}
+	return c.print(p, func() { fmt.Fprintf(c.out, "created project %s (%s)\n", p.ID, p.Name) })
+}
+
+func (c *cli) projectClone(ctx context.Context, args []string) error {
+	id, err := oneArg(c.newFlags("project clone"), args, "project-id")
+	if err != nil {
+		return err
+	}
+	var p project.Project
+	if err := c.api.do(ctx, "POST", "/projects/"+url.PathEscape(id)+"/clone", nil, &p); err != nil {
+		return err
+	}
+	return c.print(p, func() { fmt.Fprintf(c.out, "cloned %s into %s\n", p.RepoURL, p.WorkspacePath) })
+}
+
+func (c *cli) taskCreate(ctx context.Context, args []string) error {
+	fs := c.newFlags("task create")
+	var req task.CreateRequest
+	fs.StringVar(&req.ProjectID, "project", "", "project ID (required)")
+	fs.StringVar(&req.Title, "title", "", "task title (required)")
+	fs.StringVar(&req.Prompt, "prompt", "", `task prompt; "-" reads it from stdin (required)`)
+	fs.StringVar(&req.SubProjectID, "sub-project", "", "sub-project ID")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if req.Prompt == "-" {
+		data, err := io.ReadAll(c.stdin)
+		if err != nil {
+			return fmt.Errorf("read prompt: %w", err)
+		}
+		req.Prompt = strings.TrimSpace(string(data))
+	}
+	if req.ProjectID == "" || req.Title == "" || req.Prompt == "" {
+		return errors.New("task create: -project, -title and -prompt are required")
+	}
+
+	var t task.Task
+	if err := c.api.do(ctx, "POST", "/projects/"+url.PathEscape(req.ProjectID)+"/tasks", &req, &t); err != nil {
+		return err
+	}
+	return c.print(t, func() { fmt.Fprintf(c.out, "created task %s (%s)\n", t.ID, t.Title) })
+}
+
+func (c *cli) runStart(ctx context.Context, args []string) error {
+	fs := c.newFlags("run start")
+	var req run.StartRequest
+	var execMode, deliverMode string
+	fs.StringVar(&req.ProjectID, "project", "", "project ID (required)")
+	fs.StringVar(&req.TaskID, "task", "", "task ID (required)")
+	fs.StringVar(&req.AgentID, "agent", "", "agent ID (required)")
+	fs.StringVar(&req.PolicyProfile, "policy", "", "policy profile")
+	fs.StringVar(&execMode, "exec-mode", "", "execution mode (mount or sandbox)")
+	fs.StringVar(&deliverMode, "deliver-mode", "", "delivery mode (patch, commit-local, ...)")
+	fs.StringVar(&req.Model, "model", "", "model override")
+	fs.BoolVar(&req.Isolate, "isolate", false, "run in a dedicated git worktree")
+	watch := fs.Bool("watch", false, "stream the run's output until it ends")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if req.ProjectID == "" || req.TaskID == "" || req.AgentID == "" {
+		return errors.New("run start: -project, -task and -agent are required")
+	}
+	req.ExecMode, req.DeliverMode = run.ExecMode(execMode), run.DeliverMode(deliverMode)
+
+	var r run.Run
+	if err := c.api.do(ctx, "POST", "/runs", &req, &r); err != nil {
+		return err
+	}
+	if !*watch {
+		return c.print(r, func() { fmt.Fprintf(c.out, "started run %s (%s)\n", r.ID, r.Status) })
+	}
+	if !c.json {
+		fmt.Fprintf(c.out, "started run %s\n", r.ID)
+	}
+	return c.watch(ctx, r.ID)
+}
+
+func (c *cli) runGet(ctx context.Context, args []string) error {
+	id, err := oneArg(c.newFlags("run get"), args, "run-id")
+	if err != nil {
+		return err
+	}
+	var r run.Run
+	if err := c.api.do(ctx, "GET", "/runs/"+url.PathEscape(id), nil, &r); err != nil {
+		return err
+	}
+	return c.print(r, func() { renderRun(c.out, &r) })
+}
+
+func (c *cli) runWatch(ctx context.Context, args []string) error {
+	id, err := oneArg(c.newFlags("run watch"), args, "run-id")
+	if err != nil {
+		return err
+	}
+	return c.watch(ctx, id)
+}
+
+// watch streams the output and status of a run until it ends. With -json
+// every event is printed as one line of JSON.
+func (c *cli) watch(ctx context.Context, runID string) error {
+	var final string
+	err := c.api.subscribe(ctx, []string{ws.TopicRun + ":" + runID}, func(m ws.Message) bool {
+		if c.json && m.Type != ws.EventHeartbeat && m.Type != ws.EventAck {
+			data, _ := json.Marshal(m)
+			fmt.Fprintln(c.out, string(data))
+		}
+		status := ""
+		switch m.Type {
+		case ws.EventSnapshot:
+			var r run.Run
+			if json.Unmarshal(m.Payload, &r) == nil {
+				status = string(r.Status)
+			}
+		case ws.EventRunStatus:
+			var ev ws.RunStatusEvent
+			if json.Unmarshal(m.Payload, &ev) == nil {
+				status = ev.Status
+				if !c.json {
+					fmt.Fprintf(c.out, "-- %s (step %d, $%.4f)\n", ev.Status, ev.StepCount, ev.CostUSD)
+				}
+			}
+		case ws.EventTaskOutput:
+			var ev ws.TaskOutputEvent
+			if json.Unmarshal(m.Payload, &ev) == nil && !c.json {
+				fmt.Fprintln(c.out, ev.Line)
+			}
+		case ws.EventToolCallStatus:
+			var ev ws.ToolCallStatusEvent
+			if json.Unmarshal(m.Payload, &ev) == nil && !c.json {
+				fmt.Fprintf(c.out, "-- tool %s %s %s\n", ev.Tool, ev.Phase, ev.Decision)
+			}
+		}
+		if terminal(status) {
+			final = status
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if final == "" {
+		return errors.New("connection closed before the run ended")
+	}
+	if final != string(run.StatusCompleted) {
+		return &runFailedError{status: final}
+	}
+	return nil
+}
+
+// terminal reports whether a run status is final.
+func terminal(status string) bool {
+	switch run.Status(status) {
+	case run.StatusCompleted, run.StatusFailed, run.StatusCancelled, run.StatusTimeout:
+		return true
+	}
+	return false
+}
+
+func (c *cli) planGraph(ctx context.Context, args []string) error {
+	id, err := oneArg(c.newFlags("plan graph"), args, "plan-id")
+	if err != nil {
+		return err
+	}
+	var g plan.Graph
+	if err := c.api.do(ctx, "GET", "/plans/"+url.PathEscape(id)+"/graph", nil, &g); err != nil {
+		return err
+	}
+	return c.print(g, func() { renderGraph(c.out, &g) })
+}
+
+func (c *cli) cost(ctx context.Context, args []string) error {
+	fs := c.newFlags("cost")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var sums []cost.Summary
+	switch fs.NArg() {
+	case 0:
+		if err := c.api.do(ctx, "GET", "/costs", nil, &sums); err != nil {
+			return err
+		}
+	case 1:
+		var s cost.Summary
+		if err := c.api.do(ctx, "GET", "/projects/"+url.PathEscape(fs.Arg(0))+"/costs", nil, &s); err != nil {
+			return err
+		}
+		sums = []cost.Summary{s}
+	default:
+		return errors.New("cost: expected at most one <project-id>")
+	}
+	return c.print(sums, func() { renderCosts(c.out, sums) })
+}
@@ -0,0 +1,131 @@
+// Command codeforge-cli is a command-line client of the CodeForge API. It
+// creates and clones projects, creates tasks, starts runs and streams
+// their output over the WebSocket hub, renders plan graphs and summarizes
+// costs. "codeforge-cli tui" is a terminal UI for monitoring active runs
+// and answering plan approvals. Requests authenticate with an API key (server.api_keys).
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+const usage = `Usage: codeforge-cli [flags] <command> [args]
+
+Commands:
+  project list                            List projects
+  project create -name N [-repo-url U]    Create a project
+  project clone <project-id>              Clone a project's repository
+  task create -project P -title T -prompt X
+                                          Create a task ("-prompt -" reads stdin)
+  run start -project P -task T -agent A [-watch]
+                                          Start a run
+  run get <run-id>                        Show a run
+  run watch <run-id>                      Stream a run's output until it ends
+  plan graph <plan-id>                    Render an execution plan's step graph
+  cost [<project-id>]                     Summarize run costs per project
+  tui [-project P] [-user U]              Monitor active runs and approve plan steps
+
+Flags:
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	err := execute(ctx, os.Args[1:], os.Stdin, os.Stdout)
+	stop()
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "codeforge-cli:", err)
+		}
+		os.Exit(exitCode(err))
+	}
+}
+
+// cli holds the client and output settings shared by all commands.
+type cli struct {
+	api   *client
+	json  bool
+	stdin io.Reader
+	out   io.Writer
+}
+
+func execute(ctx context.Context, args []string, stdin io.Reader, out io.Writer) error {
+	fs := flag.NewFlagSet("codeforge-cli", flag.ContinueOnError)
+	server := fs.String("server", envOr("CODEFORGE_URL", "http://localhost:8080"), "server URL (env CODEFORGE_URL)")
+	apiKey := fs.String("api-key", os.Getenv("CODEFORGE_API_KEY"), "API key (env CODEFORGE_API_KEY)")
+	asJSON := fs.Bool("json", false, "print API responses as JSON")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	c := &cli{api: newClient(*server, *apiKey), json: *asJSON, stdin: stdin, out: out}
+
+	args = fs.Args()
+	if len(args) == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	cmd, sub := args[0], ""
+	if len(args) > 1 {
+		sub = args[1]
+	}
+	switch {
+	case cmd == "project" && sub == "list":
+		return c.projectList(ctx)
+	case cmd == "project" && sub == "create":
+		return c.projectCreate(ctx, args[2:])
+	case cmd == "project" && sub == "clone":
+		return c.projectClone(ctx, args[2:])
+	case cmd == "task" && sub == "create":
+		return c.taskCreate(ctx, args[2:])
+	case cmd == "run" && sub == "start":
+		return c.runStart(ctx, args[2:])
+	case cmd == "run" && sub == "get":
+		return c.runGet(ctx, args[2:])
+	case cmd == "run" && sub == "watch":
+		return c.runWatch(ctx, args[2:])
+	case cmd == "plan" && sub == "graph":
+		return c.planGraph(ctx, args[2:])
+	case cmd == "cost":
+		return c.cost(ctx, args[1:])
+	case cmd == "tui":
+		return c.tui(ctx, args[1:])
+	}
+	fs.Usage()
+	return fmt.Errorf("unknown command %q", strings.Join(args[:min(len(args), 2)], " "))
+}
+
+// runFailedError is returned by watched runs that did not complete.
+type runFailedError struct{ status string }
+
+func (e *runFailedError) Error() string { return "run " + e.status }
+
+// exitCode is 2 for usage errors, 3 for runs that did not complete and 1
+// for everything else.
+func exitCode(err error) int {
+	var failed *runFailedError
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return 2
+	case errors.As(err, &failed):
+		return 3
+	}
+	return 1
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
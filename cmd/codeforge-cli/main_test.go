@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/ws"
+	"github.com/Strob0t/CodeForge/internal/domain/cost"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/middleware"
+)
+
+func newTestServer(t *testing.T, hub *ws.Hub) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", hub.HandleWS)
+	mux.HandleFunc("/api/v1/costs", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode([]cost.Summary{
+			{ProjectID: "p1", ProjectName: "alpha", Runs: 2, CostUSD: 1.5, TokensIn: 100, TokensOut: 20},
+			{ProjectID: "p2", ProjectName: "beta", Runs: 1, CostUSD: 0.25, TokensIn: 10, TokensOut: 2},
+		})
+	})
+	srv := httptest.NewServer(middleware.Auth([]string{"secret"}, nil)(mux))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCost(t *testing.T) {
+	srv := newTestServer(t, ws.NewHub())
+	var out bytes.Buffer
+	if err := execute(context.Background(), []string{"-server", srv.URL, "-api-key", "secret", "cost"}, nil, &out); err != nil {
+		t.Fatalf("cost failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "alpha") || !strings.Contains(out.String(), "TOTAL") ||
+		!strings.Contains(out.String(), "1.7500") {
+		t.Fatalf("unexpected cost table:\n%s", out.String())
+	}
+
+	err := execute(context.Background(), []string{"-server", srv.URL, "cost"}, nil, &out)
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without an API key, got %v", err)
+	}
+}
+
+func TestRunWatch(t *testing.T) {
+	hub := ws.NewHub()
+	srv := newTestServer(t, hub)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Broadcast until the watcher ends: it may subscribe after the first
+	// events were sent.
+	go func() {
+		for ctx.Err() == nil {
+			hub.BroadcastEvent(ctx, ws.EventTaskOutput, ws.TaskOutputEvent{TaskID: "t1", RunID: "r1", Line: "hello from the agent"})
+			hub.BroadcastEvent(ctx, ws.EventRunStatus, ws.RunStatusEvent{RunID: "r1", Status: "failed", StepCount: 3})
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+
+	var out bytes.Buffer
+	err := execute(ctx, []string{"-server", srv.URL, "-api-key", "secret", "run", "watch", "r1"}, nil, &out)
+	cancel()
+	if exitCode(err) != 3 {
+		t.Fatalf("expected a failed run, got %v", err)
+	}
+	if !strings.Contains(out.String(), "-- failed (step 3") {
+		t.Fatalf("unexpected watch output:\n%s", out.String())
+	}
+}
+
+func TestRenderGraph(t *testing.T) {
+	g := &plan.Graph{
+		PlanID: "plan-1", Protocol: "parallel", Status: "running",
+		Nodes: []plan.GraphNode{
+			{StepID: "c", TaskID: "t3", Status: plan.StepStatusPending},
+			{StepID: "a", TaskID: "t1", Status: plan.StepStatusCompleted},
+			{StepID: "b", TaskID: "t2", Status: plan.StepStatusRunning, RunID: "r2"},
+		},
+		Edges: []plan.GraphEdge{{From: "a", To: "c"}, {From: "b", To: "c"}, {From: "a", To: "b", Kind: plan.EdgeCondition}},
+	}
+	var out bytes.Buffer
+	renderGraph(&out, g)
+	want := `Plan plan-1 (parallel, running)
+Layer 1
+  [x] a task=t1
+      condition -> b
+  [>] b task=t2 run=r2
+Layer 2
+  [ ] c task=t3 after=a,b
+`
+	if out.String() != want {
+		t.Fatalf("unexpected graph:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestTUIStateApply(t *testing.T) {
+	msg := func(typ string, payload any) ws.Message {
+		data, _ := json.Marshal(payload)
+		return ws.Message{Type: typ, Payload: data}
+	}
+	s := newTUIState("p1")
+	s.load([]run.Run{{ID: "r1", ProjectID: "p1", Status: run.StatusRunning}},
+		[]plan.Step{{ID: "s1", PlanID: "plan-1"}})
+	if s.selected != "r1" || len(s.approvals) != 1 {
+		t.Fatalf("unexpected state after load: %+v", s)
+	}
+
+	if got := s.apply(msg(ws.EventRunStatus, ws.RunStatusEvent{RunID: "r2", ProjectID: "p2", Status: "running"})); got != 0 {
+		t.Fatalf("event of another project changed panes %b", got)
+	}
+	if got := s.apply(msg(ws.EventRunStatus, ws.RunStatusEvent{RunID: "r1", ProjectID: "p1", Status: "completed", StepCount: 4})); got != paneRuns {
+		t.Fatalf("expected the runs pane, got %b", got)
+	}
+	if r := s.run("r1"); r.Status != "completed" || r.Steps != 4 {
+		t.Fatalf("run not updated: %+v", r)
+	}
+	if got := s.apply(msg(ws.EventTaskOutput, ws.TaskOutputEvent{RunID: "r1", Line: "hello"})); got != paneOutput {
+		t.Fatalf("expected the output pane, got %b", got)
+	}
+	if s.output["r1"][0] != "hello" {
+		t.Fatalf("output not recorded: %v", s.output)
+	}
+
+	s.apply(msg(ws.EventPlanApproval, ws.PlanApprovalEvent{PlanID: "plan-1", StepID: "s2", ProjectID: "p1", Phase: "requested"}))
+	s.apply(msg(ws.EventPlanApproval, ws.PlanApprovalEvent{PlanID: "plan-1", StepID: "s1", ProjectID: "p1", Phase: "approved"}))
+	if len(s.approvals) != 1 || s.approvals[0].StepID != "s2" || s.plan != "plan-1" {
+		t.Fatalf("unexpected approvals: %+v (plan %q)", s.approvals, s.plan)
+	}
+}
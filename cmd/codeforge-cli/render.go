@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Strob0t/CodeForge/internal/domain/cost"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+)
+
+func renderRun(out io.Writer, r *run.Run) {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Run\t%s\n", r.ID)
+	fmt.Fprintf(tw, "Task\t%s\n", r.TaskID)
+	fmt.Fprintf(tw, "Status\t%s\n", r.Status)
+	fmt.Fprintf(tw, "Steps\t%d\n", r.StepCount)
+	fmt.Fprintf(tw, "Cost\t$%.4f (%d in / %d out tokens)\n", r.CostUSD, r.TokensIn, r.TokensOut)
+	if r.Error != "" {
+		fmt.Fprintf(tw, "Error\t%s\n", r.Error)
+	}
+	_ = tw.Flush()
+	if r.Output != "" {
+		fmt.Fprintf(out, "\n%s\n", strings.TrimRight(r.Output, "\n"))
+	}
+}
+
+// stepMarks are the markers of step statuses in rendered graphs.
+var stepMarks = map[plan.StepStatus]string{
+	plan.StepStatusPending:         "[ ]",
+	plan.StepStatusRunning:         "[>]",
+	plan.StepStatusCompleted:       "[x]",
+	plan.StepStatusFailed:          "[!]",
+	plan.StepStatusSkipped:         "[-]",
+	plan.StepStatusCancelled:       "[-]",
+	plan.StepStatusWaitingApproval: "[?]",
+}
+
+// renderGraph prints a plan's steps in layers: each step appears one
+// layer below its deepest dependency, so steps of the same layer can run
+// in parallel. Condition and loop edges are listed after the step.
+func renderGraph(out io.Writer, g *plan.Graph) {
+	fmt.Fprintf(out, "Plan %s (%s, %s)\n", g.PlanID, g.Protocol, g.Status)
+
+	deps := make(map[string][]string)
+	extra := make(map[string][]string)
+	for _, e := range g.Edges {
+		if e.Kind == "" {
+			deps[e.To] = append(deps[e.To], e.From)
+		} else {
+			extra[e.From] = append(extra[e.From], e.Kind+" -> "+e.To)
+		}
+	}
+
+	depth := make(map[string]int)
+	var layerOf func(id string, seen map[string]bool) int
+	layerOf = func(id string, seen map[string]bool) int {
+		if d, ok := depth[id]; ok {
+			return d
+		}
+		if seen[id] {
+			return 0 // Cycles are rejected by the server; guard anyway.
+		}
+		seen[id] = true
+		d := 0
+		for _, dep := range deps[id] {
+			d = max(d, layerOf(dep, seen)+1)
+		}
+		depth[id] = d
+		return d
+	}
+
+	layers := make(map[int][]plan.GraphNode)
+	maxLayer := 0
+	for _, n := range g.Nodes {
+		d := layerOf(n.StepID, map[string]bool{})
+		layers[d] = append(layers[d], n)
+		maxLayer = max(maxLayer, d)
+	}
+	for d := 0; d <= maxLayer; d++ {
+		nodes := layers[d]
+		if len(nodes) == 0 {
+			continue
+		}
+		fmt.Fprintf(out, "Layer %d\n", d+1)
+		for _, n := range nodes {
+			mark, ok := stepMarks[n.Status]
+			if !ok {
+				mark = "[" + string(n.Status) + "]"
+			}
+			line := fmt.Sprintf("  %s %s task=%s", mark, n.StepID, n.TaskID)
+			if n.Type != "" {
+				line += " type=" + string(n.Type)
+			}
+			if n.RunID != "" {
+				line += " run=" + n.RunID
+			}
+			if n.MaxAttempts > 1 {
+				line += fmt.Sprintf(" attempt=%d/%d", n.Attempt, n.MaxAttempts)
+			}
+			if after := deps[n.StepID]; len(after) > 0 {
+				sorted := append([]string(nil), after...)
+				sort.Strings(sorted)
+				line += " after=" + strings.Join(sorted, ",")
+			}
+			fmt.Fprintln(out, line)
+			for _, e := range extra[n.StepID] {
+				fmt.Fprintf(out, "      %s\n", e)
+			}
+			if n.Error != "" {
+				fmt.Fprintf(out, "      error: %s\n", n.Error)
+			}
+		}
+	}
+}
+
+func renderCosts(out io.Writer, sums []cost.Summary) {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "PROJECT\tRUNS\tCOST (USD)\tTOKENS IN\tTOKENS OUT\t")
+	var total cost.Summary
+	for i := range sums {
+		s := &sums[i]
+		fmt.Fprintf(tw, "%s\t%d\t%.4f\t%d\t%d\t\n", s.ProjectName, s.Runs, s.CostUSD, s.TokensIn, s.TokensOut)
+		total.Runs += s.Runs
+		total.CostUSD += s.CostUSD
+		total.TokensIn += s.TokensIn
+		total.TokensOut += s.TokensOut
+	}
+	if len(sums) > 1 {
+		fmt.Fprintf(tw, "TOTAL\t%d\t%.4f\t%d\t%d\t\n", total.Runs, total.CostUSD, total.TokensIn, total.TokensOut)
+	}
+	_ = tw.Flush()
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/ws"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+)
+
+// reconnectDelay is the wait before the TUI reconnects to the hub.
+const reconnectDelay = 2 * time.Second
+
+const tuiHelp = "[yellow]Tab[-] switch pane  [yellow]Enter[-] show run output  [yellow]a[-] approve  [yellow]d[-] deny  [yellow]q[-] quit"
+
+// tui is the terminal UI: active runs, the selected run's output, pending
+// approvals and the graph of the plan with the latest event, kept up to
+// date over the WebSocket hub.
+type tui struct {
+	cli   *cli
+	user  string // Recorded as approved_by; empty votes as the API key
+	state *tuiState
+
+	app       *tview.Application
+	runs      *tview.Table
+	output    *tview.TextView
+	approvals *tview.List
+	planView  *tview.TextView
+	status    *tview.TextView
+	panes     []tview.Primitive
+}
+
+func (c *cli) tui(ctx context.Context, args []string) error {
+	fs := c.newFlags("tui")
+	project := fs.String("project", "", "only show this project")
+	user := fs.String("user", "", "name recorded on approvals without an API key (default $USER); with one, votes are recorded under the key")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *user == "" && c.api.apiKey == "" {
+		*user = cmp.Or(os.Getenv("USER"), "codeforge-cli")
+	}
+	t := &tui{cli: c, user: *user, state: newTUIState(*project)}
+	return t.run(ctx)
+}
+
+func (t *tui) run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	t.app = tview.NewApplication()
+	t.runs = tview.NewTable().SetSelectable(true, false).SetFixed(1, 0)
+	t.runs.SetBorder(true).SetTitle(" Active runs ")
+	t.runs.SetSelectedFunc(func(row, _ int) { t.selectRun(row) })
+	t.output = tview.NewTextView().SetScrollable(true)
+	t.output.SetBorder(true).SetTitle(" Output ")
+	t.approvals = tview.NewList().ShowSecondaryText(false)
+	t.approvals.SetBorder(true).SetTitle(" Pending approvals ")
+	t.planView = tview.NewTextView()
+	t.planView.SetBorder(true).SetTitle(" Plan ")
+	t.status = tview.NewTextView().SetDynamicColors(true).SetText(tuiHelp)
+	t.panes = []tview.Primitive{t.runs, t.approvals, t.output, t.planView}
+
+	top := tview.NewFlex().
+		AddItem(t.runs, 0, 3, true).
+		AddItem(t.approvals, 0, 2, false)
+	bottom := tview.NewFlex().
+		AddItem(t.output, 0, 3, false).
+		AddItem(t.planView, 0, 2, false)
+	root := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(top, 0, 1, true).
+		AddItem(bottom, 0, 2, false).
+		AddItem(t.status, 1, 0, false)
+	t.app.SetRoot(root, true).SetInputCapture(t.handleKey)
+
+	go t.stream(ctx)
+	return t.app.Run()
+}
+
+// handleKey implements the global key bindings.
+func (t *tui) handleKey(ev *tcell.EventKey) *tcell.EventKey {
+	switch {
+	case ev.Key() == tcell.KeyTab:
+		t.focusNext()
+		return nil
+	case ev.Rune() == 'q':
+		t.app.Stop()
+		return nil
+	case ev.Rune() == 'a', ev.Rune() == 'd':
+		t.resolve(ev.Rune() == 'a')
+		return nil
+	}
+	return ev
+}
+
+func (t *tui) focusNext() {
+	current := t.app.GetFocus()
+	for i, p := range t.panes {
+		if p == current {
+			t.app.SetFocus(t.panes[(i+1)%len(t.panes)])
+			return
+		}
+	}
+	t.app.SetFocus(t.panes[0])
+}
+
+// stream loads the current state and applies hub events, reconnecting
+// until ctx ends.
+func (t *tui) stream(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := t.reload(ctx); err != nil {
+			t.setStatus("[red]" + tview.Escape(err.Error()))
+		} else {
+			err = t.cli.api.subscribe(ctx, nil, func(m ws.Message) bool {
+				t.app.QueueUpdateDraw(func() { t.refresh(t.state.apply(m)) })
+				return true
+			})
+			if ctx.Err() != nil {
+				return
+			}
+			t.setStatus("[red]disconnected: " + tview.Escape(fmt.Sprint(err)) + "[-], reconnecting")
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// reload fetches the active runs and pending approvals.
+func (t *tui) reload(ctx context.Context) error {
+	q := ""
+	if t.state.project != "" {
+		q = "?project_id=" + url.QueryEscape(t.state.project)
+	}
+	var runs []run.Run
+	if err := t.cli.api.do(ctx, "GET", "/runs/active"+q, nil, &runs); err != nil {
+		return err
+	}
+	var steps []plan.Step
+	if err := t.cli.api.do(ctx, "GET", "/approvals"+q, nil, &steps); err != nil {
+		return err
+	}
+	t.app.QueueUpdateDraw(func() {
+		t.state.load(runs, steps)
+		t.refresh(paneRuns | paneOutput | paneApprovals)
+		t.status.SetText(tuiHelp)
+	})
+	return nil
+}
+
+func (t *tui) setStatus(text string) {
+	t.app.QueueUpdateDraw(func() { t.status.SetText(text) })
+}
+
+// refresh redraws the given panes. It runs on the UI goroutine.
+func (t *tui) refresh(panes int) {
+	if panes&paneRuns != 0 {
+		t.drawRuns()
+	}
+	if panes&paneOutput != 0 {
+		t.output.SetTitle(" Output " + t.state.selected + " ")
+		t.output.SetText(strings.Join(t.state.output[t.state.selected], "\n"))
+		t.output.ScrollToEnd()
+	}
+	if panes&paneApprovals != 0 {
+		t.drawApprovals()
+	}
+	if panes&panePlan != 0 && t.state.plan != "" {
+		go t.loadPlan(t.state.plan)
+	}
+}
+
+func (t *tui) drawRuns() {
+	row, _ := t.runs.GetSelection()
+	t.runs.Clear()
+	for col, h := range []string{"RUN", "STATUS", "STEPS", "COST", "TASK"} {
+		t.runs.SetCell(0, col, tview.NewTableCell(h).SetTextColor(tcell.ColorYellow).SetSelectable(false))
+	}
+	for i, r := range t.state.runs {
+		color := tcell.ColorWhite
+		switch {
+		case r.Status == string(run.StatusCompleted):
+			color = tcell.ColorGreen
+		case terminal(r.Status):
+			color = tcell.ColorRed
+		}
+		t.runs.SetCell(i+1, 0, tview.NewTableCell(r.ID))
+		t.runs.SetCell(i+1, 1, tview.NewTableCell(r.Status).SetTextColor(color))
+		t.runs.SetCell(i+1, 2, tview.NewTableCell(fmt.Sprint(r.Steps)).SetAlign(tview.AlignRight))
+		t.runs.SetCell(i+1, 3, tview.NewTableCell(fmt.Sprintf("$%.4f", r.CostUSD)).SetAlign(tview.AlignRight))
+		t.runs.SetCell(i+1, 4, tview.NewTableCell(r.TaskID))
+	}
+	if row >= 1 && row <= len(t.state.runs) {
+		t.runs.Select(row, 0)
+	}
+}
+
+func (t *tui) drawApprovals() {
+	current := t.approvals.GetCurrentItem()
+	t.approvals.Clear()
+	for _, a := range t.state.approvals {
+		t.approvals.AddItem(fmt.Sprintf("plan %s step %s", a.PlanID, a.StepID), "", 0, nil)
+	}
+	if current < t.approvals.GetItemCount() {
+		t.approvals.SetCurrentItem(current)
+	}
+}
+
+func (t *tui) selectRun(row int) {
+	if row < 1 || row > len(t.state.runs) {
+		return
+	}
+	t.state.selected = t.state.runs[row-1].ID
+	t.refresh(paneOutput)
+}
+
+// loadPlan fetches and shows the graph of a plan.
+func (t *tui) loadPlan(id string) {
+	var g plan.Graph
+	if err := t.cli.api.do(context.Background(), "GET", "/plans/"+url.PathEscape(id)+"/graph", nil, &g); err != nil {
+		t.setStatus("[red]plan " + tview.Escape(id) + ": " + tview.Escape(err.Error()))
+		return
+	}
+	var buf bytes.Buffer
+	renderGraph(&buf, &g)
+	t.app.QueueUpdateDraw(func() {
+		if t.state.plan == id {
+			t.planView.SetText(buf.String())
+		}
+	})
+}
+
+// resolve approves or rejects the selected approval.
+func (t *tui) resolve(approve bool) {
+	i := t.approvals.GetCurrentItem()
+	if i < 0 || i >= len(t.state.approvals) {
+		return
+	}
+	a := t.state.approvals[i]
+	action := "reject"
+	if approve {
+		action = "approve"
+	}
+	go func() {
+		path := "/plans/" + url.PathEscape(a.PlanID) + "/steps/" + url.PathEscape(a.StepID) + "/" + action
+		req := plan.ResolveApprovalRequest{By: t.user}
+		if err := t.cli.api.do(context.Background(), "POST", path, &req, nil); err != nil {
+			t.setStatus("[red]" + action + " failed: " + tview.Escape(err.Error()))
+			return
+		}
+		t.app.QueueUpdateDraw(func() {
+			t.state.removeApproval(a.PlanID, a.StepID)
+			t.drawApprovals()
+			t.status.SetText(fmt.Sprintf("%sd step %s. %s", action, a.StepID, tuiHelp))
+		})
+	}()
+}
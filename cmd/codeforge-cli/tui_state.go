@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/ws"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+)
+
+// maxOutputLines is the output kept per run in the TUI.
+const maxOutputLines = 500
+
+// Panes of the TUI that need redrawing after an event.
+const (
+	paneRuns = 1 << iota
+	paneOutput
+	paneApprovals
+	panePlan
+)
+
+// runRow is a run shown in the TUI.
+type runRow struct {
+	ID        string
+	TaskID    string
+	ProjectID string
+	Status    string
+	Steps     int
+	CostUSD   float64
+}
+
+// approvalRow is a plan step waiting for a human decision.
+type approvalRow struct {
+	PlanID string
+	StepID string
+	URL    string
+}
+
+// tuiState is what the TUI shows, updated from the API on start and from
+// WebSocket events afterwards. It is only used from the UI goroutine.
+type tuiState struct {
+	project   string // Only show this project; empty shows all
+	runs      []*runRow
+	output    map[string][]string
+	approvals []approvalRow
+	selected  string // Run whose output is shown
+	plan      string // Plan whose graph is shown: the one with the latest event
+}
+
+func newTUIState(project string) *tuiState {
+	return &tuiState{project: project, output: make(map[string][]string)}
+}
+
+// load replaces runs and approvals with the current state of the server.
+func (s *tuiState) load(runs []run.Run, steps []plan.Step) {
+	s.runs = s.runs[:0]
+	for i := range runs {
+		r := &runs[i]
+		s.runs = append(s.runs, &runRow{
+			ID: r.ID, TaskID: r.TaskID, ProjectID: r.ProjectID,
+			Status: string(r.Status), Steps: r.StepCount, CostUSD: r.CostUSD,
+		})
+	}
+	s.approvals = s.approvals[:0]
+	for i := range steps {
+		s.approvals = append(s.approvals, approvalRow{PlanID: steps[i].PlanID, StepID: steps[i].ID})
+	}
+	if s.selected == "" && len(s.runs) > 0 {
+		s.selected = s.runs[0].ID
+	}
+}
+
+func (s *tuiState) run(id string) *runRow {
+	for _, r := range s.runs {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// visible reports whether an event of a project belongs on screen.
+func (s *tuiState) visible(projectID string) bool {
+	return s.project == "" || projectID == s.project
+}
+
+// apply updates the state from an event and returns the panes to redraw.
+func (s *tuiState) apply(m ws.Message) int {
+	switch m.Type {
+	case ws.EventRunStatus:
+		var ev ws.RunStatusEvent
+		if json.Unmarshal(m.Payload, &ev) != nil || !s.visible(ev.ProjectID) {
+			return 0
+		}
+		r := s.run(ev.RunID)
+		if r == nil {
+			r = &runRow{ID: ev.RunID, TaskID: ev.TaskID, ProjectID: ev.ProjectID}
+			s.runs = append(s.runs, r)
+			if s.selected == "" {
+				s.selected = r.ID
+			}
+		}
+		r.Status, r.Steps, r.CostUSD = ev.Status, ev.StepCount, ev.CostUSD
+		return paneRuns
+
+	case ws.EventTaskOutput:
+		var ev ws.TaskOutputEvent
+		if json.Unmarshal(m.Payload, &ev) != nil || s.run(ev.RunID) == nil {
+			return 0
+		}
+		lines := append(s.output[ev.RunID], ev.Line)
+		if len(lines) > maxOutputLines {
+			lines = lines[len(lines)-maxOutputLines:]
+		}
+		s.output[ev.RunID] = lines
+		if ev.RunID == s.selected {
+			return paneOutput
+		}
+		return 0
+
+	case ws.EventPlanApproval:
+		var ev ws.PlanApprovalEvent
+		if json.Unmarshal(m.Payload, &ev) != nil || !s.visible(ev.ProjectID) {
+			return 0
+		}
+		s.removeApproval(ev.PlanID, ev.StepID)
+		if ev.Phase == "requested" {
+			s.approvals = append(s.approvals, approvalRow{PlanID: ev.PlanID, StepID: ev.StepID, URL: ev.URL})
+		}
+		s.plan = ev.PlanID
+		return paneApprovals | panePlan
+
+	case ws.EventPlanStatus, ws.EventPlanStepStatus:
+		var ev struct {
+			PlanID    string `json:"plan_id"`
+			ProjectID string `json:"project_id"`
+		}
+		if json.Unmarshal(m.Payload, &ev) != nil || !s.visible(ev.ProjectID) {
+			return 0
+		}
+		s.plan = ev.PlanID
+		return panePlan
+	}
+	return 0
+}
+
+func (s *tuiState) removeApproval(planID, stepID string) {
+	kept := s.approvals[:0]
+	for _, a := range s.approvals {
+		if a.PlanID != planID || a.StepID != stepID {
+			kept = append(kept, a)
+		}
+	}
+	s.approvals = kept
+}
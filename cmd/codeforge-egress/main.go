@@ -0,0 +1,95 @@
+// Command codeforge-egress is the egress proxy sidecar of sandboxed runs. It
+// enforces the run's egress policy for HTTP and HTTPS and publishes every
+// blocked connection attempt to NATS, where the core records it as a policy
+// event of the run.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/egress"
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/port/sandbox"
+)
+
+func main() {
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	if err := run(); err != nil {
+		slog.Error("fatal", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var p policy.EgressPolicy
+	if err := json.Unmarshal([]byte(os.Getenv(sandbox.EgressPolicyEnv)), &p); err != nil {
+		return fmt.Errorf("%s: %w", sandbox.EgressPolicyEnv, err)
+	}
+	if err := p.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", sandbox.EgressPolicyEnv, err)
+	}
+	runID := os.Getenv(sandbox.EgressRunEnv)
+	projectID := os.Getenv(sandbox.EgressProjectEnv)
+
+	// Blocked attempts are reported best effort: without NATS they are
+	// only logged.
+	var nc *nats.Conn
+	if url := os.Getenv("NATS_URL"); url != "" {
+		var err error
+		nc, err = nats.Connect(url, nats.MaxReconnects(-1))
+		if err != nil {
+			return fmt.Errorf("nats connect: %w", err)
+		}
+		defer nc.Close()
+	}
+	onBlocked := func(b egress.Blocked) {
+		slog.Warn("egress blocked", "run_id", runID, "host", b.Host, "port", b.Port, "method", b.Method, "reason", b.Reason)
+		if nc == nil {
+			return
+		}
+		data, _ := json.Marshal(messagequeue.EgressBlockedPayload{
+			RunID: runID, ProjectID: projectID, Host: b.Host, Port: b.Port, Method: b.Method, Reason: b.Reason,
+		})
+		if err := nc.Publish(messagequeue.SubjectRunEgressBlocked, data); err != nil {
+			slog.Error("publish egress blocked", "error", err)
+		}
+	}
+
+	srv := &http.Server{
+		Addr:              net.JoinHostPort("", strconv.Itoa(sandbox.EgressProxyPort)),
+		Handler:           egress.New(&p, onBlocked),
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("egress proxy listening", "addr", srv.Addr, "run_id", runID)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("listen: %w", err)
+	}
+	if nc != nil {
+		_ = nc.Flush()
+	}
+	return nil
+}
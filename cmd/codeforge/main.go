@@ -2,12 +2,16 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -16,40 +20,73 @@ import (
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/Strob0t/CodeForge/internal/adapter/a2aclient"
 	"github.com/Strob0t/CodeForge/internal/adapter/aider"
+	"github.com/Strob0t/CodeForge/internal/adapter/ctags"
+	"github.com/Strob0t/CodeForge/internal/adapter/graphql"
 	cfhttp "github.com/Strob0t/CodeForge/internal/adapter/http"
 	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
+	"github.com/Strob0t/CodeForge/internal/adapter/lsp"
+	cfmcp "github.com/Strob0t/CodeForge/internal/adapter/mcp"
 	cfnats "github.com/Strob0t/CodeForge/internal/adapter/nats"
+	"github.com/Strob0t/CodeForge/internal/adapter/ollama"
+	"github.com/Strob0t/CodeForge/internal/adapter/onnx"
 	"github.com/Strob0t/CodeForge/internal/adapter/postgres"
+	"github.com/Strob0t/CodeForge/internal/adapter/skillindex"
 	"github.com/Strob0t/CodeForge/internal/adapter/ws"
 	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/benchmark"
+	"github.com/Strob0t/CodeForge/internal/domain/codegraph"
 	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/domain/routing"
+	cfrun "github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/scaffold"
 	"github.com/Strob0t/CodeForge/internal/logger"
 	"github.com/Strob0t/CodeForge/internal/middleware"
+	"github.com/Strob0t/CodeForge/internal/port/ciprovider"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/port/pmprovider"
+	"github.com/Strob0t/CodeForge/internal/port/sandbox"
+	"github.com/Strob0t/CodeForge/internal/port/skillregistry"
+	"github.com/Strob0t/CodeForge/internal/port/websearch"
+	"github.com/Strob0t/CodeForge/internal/redact"
 	"github.com/Strob0t/CodeForge/internal/resilience"
 	"github.com/Strob0t/CodeForge/internal/service"
+	"github.com/Strob0t/CodeForge/internal/vault"
 )
 
 func main() {
 	// Temporary bootstrap logger until config is loaded.
 	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo})))
 
-	if err := run(); err != nil {
+	var opts config.Options
+	flag.StringVar(&opts.File, "config", config.DefaultConfigFile, "YAML config file")
+	flag.Func("set", "override a config value, e.g. -set server.port=9090 (repeatable)", func(kv string) error {
+		opts.Set = append(opts.Set, kv)
+		return nil
+	})
+	flag.Parse()
+
+	if err := run(opts); err != nil {
 		slog.Error("fatal", "error", err)
 		os.Exit(1)
 	}
 }
 
-func run() error {
-	cfg, err := config.Load()
+func run(opts config.Options) error {
+	cfg, prov, err := config.LoadFrom(opts)
 	if err != nil {
 		return fmt.Errorf("config: %w", err)
 	}
+	configSvc := service.NewConfigService(opts, cfg, prov)
+	configSvc.OnChange("logging.level", func(c *config.Config) { logger.SetLevel(c.Logging.Level) })
 
 	// Replace bootstrap logger with configured one.
 	slog.SetDefault(logger.New(cfg.Logging))
 
 	slog.Info("config loaded",
+		"file", opts.File,
 		"port", cfg.Server.Port,
 		"log_level", cfg.Logging.Level,
 		"pg_max_conns", cfg.Postgres.MaxConns,
@@ -67,8 +104,25 @@ func run() error {
 	slog.Info("postgres connected",
 		"max_conns", cfg.Postgres.MaxConns,
 		"min_conns", cfg.Postgres.MinConns,
+		"statement_cache", cfg.Postgres.StatementCache,
+		"query_timeout", cfg.Postgres.QueryTimeout,
 	)
 
+	// Read replicas for list and cost endpoints (optional)
+	var replicas *postgres.Replicas
+	cancelReplicas := func() {}
+	if len(cfg.Postgres.ReplicaDSNs) > 0 {
+		replicas, err = postgres.NewReplicas(ctx, cfg.Postgres)
+		if err != nil {
+			return fmt.Errorf("postgres replicas: %w", err)
+		}
+		cancelReplicas = replicas.Start(ctx)
+		slog.Info("postgres replicas configured",
+			"replicas", len(cfg.Postgres.ReplicaDSNs),
+			"max_lag", cfg.Postgres.ReplicaMaxLag,
+		)
+	}
+
 	// Run migrations
 	if err := postgres.RunMigrations(ctx, cfg.Postgres.DSN); err != nil {
 		return fmt.Errorf("migrations: %w", err)
@@ -76,7 +130,7 @@ func run() error {
 	slog.Info("migrations applied")
 
 	// NATS
-	queue, err := cfnats.Connect(ctx, cfg.NATS.URL)
+	queue, err := cfnats.Connect(ctx, cfg.NATS)
 	if err != nil {
 		return fmt.Errorf("nats: %w", err)
 	}
@@ -86,18 +140,65 @@ func run() error {
 	llmBreaker := resilience.NewBreaker(cfg.Breaker.MaxFailures, cfg.Breaker.Timeout)
 	queue.SetBreaker(natsBreaker)
 
+	// --- Leader Election (background jobs run on one server only) ---
+	var leaderBucket messagequeue.KeyValue
+	if cfg.Server.LeaderLease > 0 {
+		leaderBucket, err = queue.ExpiringKeyValue(ctx, service.LeaderBucket, cfg.Server.LeaderLease)
+		if err != nil {
+			return fmt.Errorf("leader bucket: %w", err)
+		}
+	}
+	serverID := instanceID()
+	leader := service.NewLeaderElector(leaderBucket, serverID, cfg.Server.LeaderLease)
+
 	// --- Agent Backends ---
 	aider.Register(queue)
+	a2aclient.Register()
 
 	// --- Services ---
 	hub := ws.NewHub()
 	store := postgres.NewStore(pool)
-	eventStore := postgres.NewEventStore(pool)
+	if replicas != nil {
+		store.SetReplicas(replicas)
+	}
+	snapshots := ws.NewStoreSnapshots(store)
+	hub.SetSnapshotSource(snapshots)
+	// Events of NATS subscribers and background jobs carry no tenant; they
+	// are scoped to the tenant of the project they refer to
+	hub.SetTenantSource(snapshots)
+	// Relay WebSocket events between servers so every client sees them all
+	cancelFanOut := func() {}
+	if cfg.Server.WSFanOut {
+		cancelFanOut, err = hub.StartFanOut(queue, serverID)
+		if err != nil {
+			return fmt.Errorf("websocket fan-out: %w", err)
+		}
+		slog.Info("websocket fan-out enabled", "subject", messagequeue.SubjectWSEvents+".>", "server", serverID)
+	}
+	eventStore := service.NewEventBatcher(postgres.NewEventStore(pool), cfg.Events)
+	cancelEventStore := eventStore.Start(ctx)
+	tenantSvc := service.NewTenantService(store)
 	projectSvc := service.NewProjectService(store)
+	projectSvc.SetTenantService(tenantSvc)
+	apiKeySvc := service.NewAPIKeyService(store)
 	taskSvc := service.NewTaskService(store, queue)
 	agentSvc := service.NewAgentService(store, queue, hub)
 	agentSvc.SetEventStore(eventStore)
 
+	// --- Output Redaction ---
+	var redaction *redact.Pipeline
+	if cfg.Redaction.Enabled {
+		redaction, err = redact.New(cfg.Redaction.Patterns, cfg.Redaction.EntropyThreshold, cfg.Redaction.MinTokenLength)
+		if err != nil {
+			return fmt.Errorf("redaction: %w", err)
+		}
+	}
+	agentSvc.SetRedaction(redaction)
+	slog.Info("output redaction initialized",
+		"enabled", cfg.Redaction.Enabled,
+		"patterns", len(cfg.Redaction.Patterns),
+	)
+
 	// --- Policy Service ---
 	var customPolicies []policy.PolicyProfile
 	if cfg.Policy.CustomDir != "" {
@@ -113,24 +214,240 @@ func run() error {
 		"profiles", len(policySvc.ListProfiles()),
 	)
 
+	// --- Secret Service ---
+	var secretVault *vault.Vault
+	if cfg.Secrets.MasterKey != "" {
+		secretVault, err = vault.New(cfg.Secrets.MasterKey, "secrets")
+		if err != nil {
+			return fmt.Errorf("secrets vault: %w", err)
+		}
+	}
+	secretSvc := service.NewSecretService(store, secretVault)
+	slog.Info("secret service initialized", "enabled", secretSvc.Available())
+	tenantSvc.SetSecretService(secretSvc)
+
+	// --- GitHub App Service (installation tokens instead of PATs) ---
+	githubAppSvc := service.NewGitHubAppService(store, secretSvc, projectSvc)
+	secretSvc.SetGitHubApps(githubAppSvc)
+	projectSvc.SetGitHubApps(githubAppSvc)
+
+	// --- MCP Server Registry (OAuth secrets share the secrets master key) ---
+	var mcpVault *vault.Vault
+	if cfg.Secrets.MasterKey != "" {
+		mcpVault, err = vault.New(cfg.Secrets.MasterKey, "mcp-servers")
+		if err != nil {
+			return fmt.Errorf("mcp vault: %w", err)
+		}
+	}
+	mcpSvc := service.NewMCPService(store, mcpVault)
+
+	// --- PM Sync Service ---
+	syncSvc := service.NewSyncService(store, secretSvc)
+	slog.Info("pm sync service initialized", "providers", pmprovider.Available())
+
+	// --- Review Service ---
+	reviewSvc := service.NewReviewService(store, secretSvc)
+	reviewSvc.SetPublicURL(cfg.Server.PublicURL)
+	slog.Info("review service initialized", "git_providers", gitprovider.Available())
+
+	// --- CI Service (workflow runs and pipelines of delivered commits) ---
+	ciSvc := service.NewCIService(store, secretSvc, cfg.CI)
+	reviewSvc.SetCIService(ciSvc)
+	leader.Register("ci poller", ciSvc.StartPoller)
+	slog.Info("ci service initialized", "providers", ciprovider.Available())
+
 	// --- Runtime Service (Phase 4B + 4C) ---
 	runtimeSvc := service.NewRuntimeService(store, queue, hub, eventStore, policySvc, &cfg.Runtime)
 	deliverSvc := service.NewDeliverService(store, &cfg.Runtime)
+	deliverSvc.SetSecretService(secretSvc)
+	deliverSvc.SetGitHubApps(githubAppSvc)
+	deliverSvc.SetPublicURL(cfg.Server.PublicURL)
+	deliverSvc.SetCIService(ciSvc)
 	runtimeSvc.SetDeliverService(deliverSvc)
+	snapshotSvc := service.NewSnapshotService(store, &cfg.Runtime)
+	runtimeSvc.SetSnapshotService(snapshotSvc)
+	replaySvc := service.NewReplayService(store, runtimeSvc, snapshotSvc)
+	projectSvc.SetWorktreeRoot(cfg.Runtime.WorktreeRoot)
+	runtimeSvc.SetProjectService(projectSvc)
+	testRunnerSvc := service.NewTestRunnerService(store, queue, &cfg.Runtime)
+	runtimeSvc.SetTestRunner(testRunnerSvc)
+	lintSvc := service.NewLintService(store, queue, runtimeSvc)
+	runtimeSvc.SetLintService(lintSvc)
+	runtimeSvc.SetSecretService(secretSvc)
+	runtimeSvc.SetTenantService(tenantSvc)
+	runtimeSvc.SetRedaction(redaction)
+	var sandboxDriver sandbox.Driver
+	if sb := cfg.Runtime.Sandbox; sb.Driver != "" {
+		driverCfg := map[string]string{
+			"api_server":      sb.Kubernetes.APIServer,
+			"namespace":       sb.Kubernetes.Namespace,
+			"token_file":      sb.Kubernetes.TokenFile,
+			"ca_file":         sb.Kubernetes.CAFile,
+			"workspace_claim": sb.Kubernetes.WorkspaceClaim,
+			"workspace_root":  sb.Kubernetes.WorkspaceRoot,
+			"clone_image":     sb.Kubernetes.CloneImage,
+			"storage_opt":     strconv.FormatBool(sb.StorageOpt),
+			"egress_network":  sb.EgressNetwork,
+		}
+		for level, rt := range sb.Runtimes {
+			driverCfg["runtime_"+level] = rt
+		}
+		driver, err := sandbox.New(sb.Driver, driverCfg)
+		if err != nil {
+			return fmt.Errorf("sandbox driver: %w", err)
+		}
+		sandboxDriver = driver
+		runtimeSvc.SetSandboxService(service.NewSandboxService(store, queue, driver, &cfg.Runtime.Sandbox))
+		slog.Info("sandbox driver initialized", "driver", sb.Driver, "image", sb.Image)
+	}
+	// --- Worker Message Dedupe (replayed results applied once, keys in NATS KV) ---
+	var dedupe *service.WorkerDedupe
+	if cfg.NATS.DedupeWindow > 0 {
+		dedupeBucket, err := queue.ExpiringKeyValue(ctx, service.WorkerDedupeBucket, cfg.NATS.DedupeWindow)
+		if err != nil {
+			return fmt.Errorf("worker dedupe bucket: %w", err)
+		}
+		dedupe = service.NewWorkerDedupe(dedupeBucket)
+		agentSvc.SetWorkerDedupe(dedupe)
+		runtimeSvc.SetWorkerDedupe(dedupe)
+	}
 	runtimeCancels, err := runtimeSvc.StartSubscribers(ctx)
 	if err != nil {
 		return fmt.Errorf("runtime subscribers: %w", err)
 	}
 	slog.Info("runtime service initialized", "subscribers", len(runtimeCancels))
 
+	// --- Event Retention ---
+	retentionSvc := service.NewRetentionService(store, eventStore, cfg.Retention)
+	runtimeSvc.SetRetentionService(retentionSvc)
+	leader.Register("event compactor", retentionSvc.StartCompactor)
+	slog.Info("event retention initialized",
+		"window", cfg.Retention.EventWindow,
+		"interval", cfg.Retention.Interval,
+	)
+
+	// --- Cost Rollups (daily totals the cost endpoints read) ---
+	costSvc := service.NewCostService(store, cfg.Costs)
+	leader.Register("cost rollups", costSvc.StartRollups)
+	slog.Info("cost rollups initialized",
+		"interval", cfg.Costs.RollupInterval,
+		"window", cfg.Costs.RollupWindow,
+	)
+
+	// --- Audit Log (exported to each tenant's SIEM sinks) ---
+	auditSvc := service.NewAuditService(store, eventStore, cfg.Audit)
+	auditSvc.SetSecretService(secretSvc)
+	leader.Register("audit publisher", auditSvc.StartPublisher)
+	slog.Info("audit log initialized",
+		"enabled", cfg.Audit.Enabled,
+		"export_interval", cfg.Audit.ExportInterval,
+	)
+
+	// --- Notifications (routed by project rules to chat webhooks) ---
+	notifySvc := service.NewNotificationService(store, cfg.Notify)
+	notifySvc.SetSecretService(secretSvc)
+	notifySvc.SetPublicURL(cfg.Server.PublicURL)
+	leader.Register("notification sender", notifySvc.StartSender)
+	slog.Info("notifications initialized",
+		"notifiers", notifySvc.Kinds(),
+		"send_interval", cfg.Notify.SendInterval,
+	)
+
+	// --- VCS Accounts (background checks of project git host tokens) ---
+	vcsAccountSvc := service.NewVCSAccountService(store, secretSvc, cfg.VCSAccounts)
+	vcsAccountSvc.SetNotificationService(notifySvc)
+	leader.Register("vcs account checker", vcsAccountSvc.StartChecker)
+	slog.Info("vcs account checker initialized",
+		"check_interval", cfg.VCSAccounts.CheckInterval,
+		"expiry_warning", cfg.VCSAccounts.ExpiryWarning,
+	)
+
+	// --- Feature Flags (propagated to all servers over NATS KV) ---
+	featureFlagSvc := service.NewFeatureFlagService(store)
+	flagBucket, err := queue.KeyValue(ctx, service.FeatureFlagBucket)
+	if err != nil {
+		return fmt.Errorf("feature flags: %w", err)
+	}
+	featureFlagSvc.SetKeyValue(flagBucket)
+	if err := featureFlagSvc.Load(ctx); err != nil {
+		return fmt.Errorf("feature flags: %w", err)
+	}
+	cancelFlagWatcher, err := featureFlagSvc.StartWatcher(ctx)
+	if err != nil {
+		return fmt.Errorf("feature flags: %w", err)
+	}
+	graphSvc := service.NewGraphService(store, runtimeSvc)
+	graphParser, err := codegraph.NewParser(cfg.CodeGraph.Grammars)
+	if err != nil {
+		return fmt.Errorf("codegraph: %w", err)
+	}
+	graphSvc.SetParser(graphParser)
+	if cfg.CodeGraph.Ctags != "" {
+		if t, err := ctags.New(cfg.CodeGraph.Ctags); err != nil {
+			slog.Warn("ctags fallback disabled", "error", err)
+		} else {
+			graphSvc.SetTagger(t)
+		}
+	}
+	slog.Info("code graph initialized", "grammars", graphParser.Grammars())
+	runtimeSvc.SetGraphService(graphSvc)
+	runtimeSvc.SetFeatureFlagService(featureFlagSvc)
+	lspSvc := service.NewLSPService(store, cfg.LSP, lsp.Start)
+	lspSvc.SetFeatureFlagService(featureFlagSvc)
+	lspSvc.SetPolicyService(policySvc)
+	deliverSvc.SetLSPService(lspSvc)
+	stopLSP := lspSvc.Start(ctx)
+	slog.Info("feature flags initialized", "bucket", service.FeatureFlagBucket)
+
 	// --- Orchestrator Service (Phase 5A) ---
 	orchSvc := service.NewOrchestratorService(store, hub, eventStore, runtimeSvc, &cfg.Orchestrator)
-	runtimeSvc.SetOnRunComplete(orchSvc.HandleRunCompleted)
+	orchSvc.SetAuditService(auditSvc)
+	orchSvc.SetNotificationService(notifySvc)
+	notifySvc.SetApprovalService(orchSvc)
+	orchSvc.SetPublicURL(cfg.Server.PublicURL)
+	if sandboxDriver != nil {
+		orchSvc.SetImageBuilder(service.NewImageBuildService(store, secretSvc, sandboxDriver, &cfg.Runtime.Sandbox))
+	}
+	leader.Register("approval timer", orchSvc.StartApprovalTimer)
 	slog.Info("orchestrator service initialized",
 		"max_parallel", cfg.Orchestrator.MaxParallel,
 		"ping_pong_max_rounds", cfg.Orchestrator.PingPongMaxRounds,
 	)
 
+	// --- Benchmark Service ---
+	suites, err := benchmark.LoadFromDirectory(cfg.Benchmark.SuitesDir)
+	if err != nil {
+		return fmt.Errorf("benchmark suites dir: %w", err)
+	}
+	benchmarkSvc := service.NewBenchmarkService(store, orchSvc, projectSvc, cfg.Benchmark, suites)
+	slog.Info("benchmark service initialized",
+		"suites_dir", cfg.Benchmark.SuitesDir,
+		"suites", len(suites),
+	)
+
+	// --- Issue Run Service (issues labeled or assigned on the git host) ---
+	issueRunSvc := service.NewIssueRunService(store, runtimeSvc, secretSvc)
+	issueRunSvc.SetPublicURL(cfg.Server.PublicURL)
+
+	// --- ChatOps Service (slash commands in pull request comments) ---
+	chatOpsSvc := service.NewChatOpsService(store, runtimeSvc, reviewSvc, secretSvc)
+	chatOpsSvc.SetPublicURL(cfg.Server.PublicURL)
+
+	// --- Poller (for projects that cannot receive webhooks) ---
+	syncSvc.SetIssueRunService(issueRunSvc)
+	syncSvc.SetChatOpsService(chatOpsSvc)
+	leader.Register("sync poller", syncSvc.StartPoller)
+
+	runtimeSvc.SetOnRunComplete(func(ctx context.Context, runID string, status cfrun.Status) {
+		orchSvc.HandleRunCompleted(ctx, runID, status)
+		// Validation commands can run for minutes; score off the result path.
+		go benchmarkSvc.HandleRunCompleted(context.WithoutCancel(ctx), runID, status)
+		// Issue comments and pull request replies call the git host's API.
+		go issueRunSvc.HandleRunCompleted(context.WithoutCancel(ctx), runID, status)
+		go chatOpsSvc.HandleRunCompleted(context.WithoutCancel(ctx), runID, status)
+		go notifySvc.HandleRunCompleted(context.WithoutCancel(ctx), runID, status)
+	})
+
 	// Start NATS subscribers (process results and streaming output from workers)
 	cancelResults, err := agentSvc.StartResultSubscriber(ctx)
 	if err != nil {
@@ -145,12 +462,37 @@ func run() error {
 	// --- HTTP ---
 	llmClient := litellm.NewClient(cfg.LiteLLM.URL, cfg.LiteLLM.MasterKey)
 	llmClient.SetBreaker(llmBreaker)
+	if cfg.LiteLLM.CacheEnabled {
+		llmClient.SetCache(litellm.NewResponseCache(cfg.LiteLLM.CacheTTL, cfg.LiteLLM.CacheMaxEntries))
+		slog.Info("llm response cache enabled",
+			"ttl", cfg.LiteLLM.CacheTTL,
+			"max_entries", cfg.LiteLLM.CacheMaxEntries,
+		)
+	}
+	orchSvc.SetArbiter(llmClient)
+	runtimeSvc.SetSummarizer(llmClient)
+
+	// --- Model Routing ---
+	routingSvc, err := service.NewRoutingService(store, &cfg.Routing, cfg.Breaker, map[routing.TaskType]string{
+		routing.TaskDecompose: cfg.Orchestrator.DecomposeModel,
+		routing.TaskSummarize: cfg.Research.SummaryModel,
+	})
+	if err != nil {
+		return fmt.Errorf("routing: %w", err)
+	}
+	runtimeSvc.SetRoutingService(routingSvc)
+	slog.Info("model routing initialized", "rules", len(cfg.Routing.Rules), "tenants", len(cfg.Routing.Tenants))
 
 	// --- Meta-Agent Service (Phase 5B) ---
 	metaAgentSvc := service.NewMetaAgentService(store, llmClient, orchSvc, &cfg.Orchestrator)
+	metaAgentSvc.SetRoutingService(routingSvc)
+	experienceSvc := service.NewExperienceService(store, &cfg.Orchestrator)
+	orchSvc.SetExperiencePool(experienceSvc)
+	metaAgentSvc.SetExperiencePool(experienceSvc)
 	slog.Info("meta-agent service initialized",
 		"mode", cfg.Orchestrator.Mode,
 		"decompose_model", cfg.Orchestrator.DecomposeModel,
+		"experience_limit", cfg.Orchestrator.ExperienceLimit,
 	)
 
 	// --- Pool Manager + Task Planner (Phase 5C) ---
@@ -160,9 +502,19 @@ func run() error {
 		"max_team_size", cfg.Orchestrator.MaxTeamSize,
 	)
 
+	// --- Tokenizers ---
+	tokenizerSvc := service.NewTokenizerService(&cfg.Tokenizers, llmClient)
+	slog.Info("tokenizers initialized",
+		"dir", cfg.Tokenizers.Dir,
+		"families", len(cfg.Tokenizers.Families),
+		"default_model", cfg.Tokenizers.DefaultModel,
+	)
+
 	// --- Context Optimizer + Shared Context (Phase 5D) ---
 	contextOptSvc := service.NewContextOptimizerService(store, &cfg.Orchestrator)
+	contextOptSvc.SetTokenizer(tokenizerSvc)
 	sharedCtxSvc := service.NewSharedContextService(store, hub, queue)
+	sharedCtxSvc.SetTokenizer(tokenizerSvc)
 	runtimeSvc.SetContextOptimizer(contextOptSvc)
 	slog.Info("context optimizer and shared context initialized",
 		"default_budget", cfg.Orchestrator.DefaultContextBudget,
@@ -173,9 +525,136 @@ func run() error {
 	poolManagerSvc.SetSharedContext(sharedCtxSvc)
 	orchSvc.SetSharedContext(sharedCtxSvc)
 
+	// --- Automatic agent scaling ---
+	poolManagerSvc.SetTenantService(tenantSvc)
+	orchSvc.SetPoolManager(poolManagerSvc)
+	leader.Register("agent reaper", poolManagerSvc.StartAgentReaper)
+
 	// --- Mode Service (Phase 5E) ---
 	modeSvc := service.NewModeService()
 	slog.Info("mode service initialized", "modes", len(modeSvc.List()))
+	runtimeSvc.SetModeService(modeSvc)
+
+	// --- Project Template Service ---
+	templates, err := scaffold.LoadFromDirectory(cfg.Templates.Dir)
+	if err != nil {
+		return fmt.Errorf("templates dir: %w", err)
+	}
+	templateSvc, err := service.NewTemplateService(store, projectSvc, agentSvc, orchSvc, modeSvc, policySvc, templates)
+	if err != nil {
+		return fmt.Errorf("project templates: %w", err)
+	}
+	slog.Info("template service initialized",
+		"templates_dir", cfg.Templates.Dir,
+		"templates", len(templates),
+	)
+
+	// --- Research Service ---
+	var searchProvider websearch.Provider
+	if cfg.Research.Provider != "" {
+		searchProvider, err = websearch.New(cfg.Research.Provider, map[string]string{"url": cfg.Research.URL})
+		if err != nil {
+			return fmt.Errorf("research provider: %w", err)
+		}
+	}
+	researchSvc := service.NewResearchService(store, hub, eventStore, policySvc, searchProvider, llmClient, &cfg.Research)
+	researchSvc.SetRoutingService(routingSvc)
+	artifactSvc := service.NewArtifactService(store)
+	slog.Info("research service initialized",
+		"provider", cfg.Research.Provider,
+		"policy_profile", cfg.Research.PolicyProfile,
+	)
+
+	// --- Retrieval Service ---
+	retrievalSvc := service.NewRetrievalService(store, &cfg.Retrieval,
+		litellm.Embedder{Client: llmClient},
+		ollama.NewEmbedder(cfg.Retrieval.OllamaURL),
+	)
+	retrievalSvc.SetRerankers(litellm.Reranker{Client: llmClient}, onnx.NewReranker(cfg.Retrieval.RerankURL))
+	slog.Info("retrieval service initialized",
+		"embedding_provider", cfg.Retrieval.EmbeddingProvider,
+		"embedding_model", cfg.Retrieval.EmbeddingModel,
+		"rerank_provider", cfg.Retrieval.RerankProvider,
+	)
+
+	// --- Knowledge Bases (external docs searched with the retrieval index) ---
+	knowledgeSvc := service.NewKnowledgeService(store, retrievalSvc, cfg.Knowledge)
+	knowledgeSvc.SetSecretService(secretSvc)
+	retrievalSvc.SetKnowledgeService(knowledgeSvc)
+	contextOptSvc.SetRetrievalService(retrievalSvc)
+	orchSvc.SetRetrieval(retrievalSvc)
+	orchSvc.SetGraph(graphSvc)
+	leader.Register("knowledge refresher", knowledgeSvc.StartRefresher)
+	slog.Info("knowledge bases initialized",
+		"check_interval", cfg.Knowledge.CheckInterval,
+		"max_documents", cfg.Knowledge.MaxDocuments,
+	)
+
+	// --- Retrieval Scopes (projects and knowledge bases searched together) ---
+	scopeSvc := service.NewScopeService(store, retrievalSvc)
+
+	// --- Memories ---
+	memorySvc := service.NewMemoryService(store, retrievalSvc, &cfg.Memory)
+	contextOptSvc.SetMemoryService(memorySvc, modeSvc)
+	slog.Info("memory service initialized",
+		"recall", cfg.Memory.Enabled,
+		"limit", cfg.Memory.Limit,
+		"half_life", cfg.Memory.HalfLife,
+	)
+
+	// --- Skills ---
+	var skillRegistry skillregistry.Registry
+	if cfg.Skills.RegistryURL != "" {
+		skillRegistry = skillindex.NewRegistry(cfg.Skills.RegistryURL)
+	}
+	skillSvc, err := service.NewSkillService(store, skillRegistry, &cfg.Skills)
+	if err != nil {
+		return fmt.Errorf("skills: %w", err)
+	}
+	slog.Info("skill service initialized",
+		"signing", cfg.Skills.SigningKey != "",
+		"key_id", cfg.Skills.KeyID,
+		"trusted_keys", len(cfg.Skills.TrustedKeys),
+		"registry", cfg.Skills.RegistryURL,
+	)
+
+	// --- Project Conventions ---
+	conventionSvc := service.NewConventionService(store, cfg.Conventions)
+	contextOptSvc.SetConventionService(conventionSvc)
+	leader.Register("conventions refresher", conventionSvc.StartRefresher)
+	slog.Info("convention service initialized",
+		"check_interval", cfg.Conventions.CheckInterval,
+		"refresh_interval", cfg.Conventions.RefreshInterval,
+		"merge_files", cfg.Conventions.MergeFiles,
+	)
+
+	// --- Microagents ---
+	microagentSvc := service.NewMicroagentService(store)
+	microagentSvc.SetConventionService(conventionSvc)
+	runtimeSvc.SetMicroagentService(microagentSvc)
+
+	// --- Conversations ---
+	conversationSvc := service.NewConversationService(store, llmClient, &cfg.Conversation)
+	conversationSvc.SetRoutingService(routingSvc)
+	conversationSvc.SetTokenizer(tokenizerSvc)
+	conversationSvc.SetContextOptimizer(contextOptSvc)
+	conversationSvc.SetMicroagentService(microagentSvc)
+	conversationSvc.SetFeatureFlagService(featureFlagSvc)
+	conversationSvc.SetBroadcaster(hub)
+	retrievalSvc.SetBroadcaster(hub)
+	runtimeSvc.SetConversationService(conversationSvc)
+	slog.Info("conversation service initialized",
+		"model", cfg.Conversation.Model,
+		"summarize_at", cfg.Conversation.SummarizeAt,
+	)
+
+	// --- Attachments ---
+	attachmentSvc := service.NewAttachmentService(store, cfg.Attachments)
+	conversationSvc.SetAttachmentService(attachmentSvc)
+	runtimeSvc.SetAttachmentService(attachmentSvc)
+
+	// --- Seatbelt Service (CI failures and reverts of delivered changes) ---
+	seatbeltSvc := service.NewSeatbeltService(store, runtimeSvc, secretSvc, attachmentSvc)
 
 	handlers := &cfhttp.Handlers{
 		Projects:         projectSvc,
@@ -191,21 +670,107 @@ func run() error {
 		ContextOptimizer: contextOptSvc,
 		SharedContext:    sharedCtxSvc,
 		Modes:            modeSvc,
+		Research:         researchSvc,
+		Artifacts:        artifactSvc,
+		Snapshots:        snapshotSvc,
+		Replays:          replaySvc,
+		VCSAccounts:      vcsAccountSvc,
+		Secrets:          secretSvc,
+		Retention:        retentionSvc,
+		Benchmarks:       benchmarkSvc,
+		Templates:        templateSvc,
+		Sync:             syncSvc,
+		IssueRuns:        issueRunSvc,
+		ChatOps:          chatOpsSvc,
+		Seatbelt:         seatbeltSvc,
+		CI:               ciSvc,
+		Reviews:          reviewSvc,
+		Graph:            graphSvc,
+		LSP:              lspSvc,
+		Conventions:      conventionSvc,
+		Tests:            testRunnerSvc,
+		Lint:             lintSvc,
+		Tenants:          tenantSvc,
+		GitHubApps:       githubAppSvc,
+		APIKeys:          apiKeySvc,
+		Audit:            auditSvc,
+		Notifications:    notifySvc,
+		Config:           configSvc,
+		FeatureFlags:     featureFlagSvc,
+		Routing:          routingSvc,
+		Retrieval:        retrievalSvc,
+		Knowledge:        knowledgeSvc,
+		Scopes:           scopeSvc,
+		Tokenizers:       tokenizerSvc,
+		Conversations:    conversationSvc,
+		Memories:         memorySvc,
+		Experience:       experienceSvc,
+		Skills:           skillSvc,
+		Microagents:      microagentSvc,
+		RunPresets:       service.NewRunPresetService(store, policySvc, modeSvc),
+		Attachments:      attachmentSvc,
+		Costs:            costSvc,
+		MCPServers:       mcpSvc,
+		Workspaces:       service.NewWorkspaceService(store, policySvc),
+		DeadLetters:      queue,
+		WorkerDedupe:     dedupe,
+		Hub:              hub,
+	}
+
+	// --- Idempotency (responses replayed to retries from NATS KV) ---
+	if cfg.Idempotency.Enabled {
+		idem := middleware.NewIdempotency(cfg.Idempotency.TTL, cfg.Idempotency.MaxBodyBytes, cfg.Idempotency.Routes)
+		idemBucket, err := queue.ExpiringKeyValue(ctx, middleware.IdempotencyBucket, idem.MaxTTL())
+		if err != nil {
+			return fmt.Errorf("idempotency: %w", err)
+		}
+		idem.SetKeyValue(idemBucket)
+		handlers.Idempotency = idem
+		slog.Info("idempotency initialized", "bucket", middleware.IdempotencyBucket, "ttl", cfg.Idempotency.TTL, "routes", len(cfg.Idempotency.Routes))
+	}
+	if cfg.Server.GraphQL {
+		handlers.GraphQL = graphql.NewHandler(store, eventStore)
+		slog.Info("graphql API enabled", "path", "/api/v1/graphql")
 	}
 
 	r := chi.NewRouter()
 
-	// Rate limiter
+	// Rate limiter: per client IP on this server, or shared by all servers
+	// per client IP and per API key and the tenant it is bound to
 	rateLimiter := middleware.NewRateLimiter(cfg.Rate.RequestsPerSecond, cfg.Rate.Burst)
+	var sharedLimiter *middleware.DistributedRateLimiter
+	if cfg.Rate.Distributed {
+		tiers := make(map[string]middleware.RateTier, len(cfg.Rate.Tiers))
+		for name, t := range cfg.Rate.Tiers {
+			tiers[name] = middleware.RateTier{RequestsPerSecond: t.RequestsPerSecond, Burst: t.Burst}
+		}
+		sharedLimiter = middleware.NewDistributedRateLimiter(
+			middleware.RateTier{RequestsPerSecond: cfg.Rate.RequestsPerSecond, Burst: cfg.Rate.Burst},
+			tiers, cfg.Rate.TenantTiers,
+		)
+		rateBucket, err := queue.ExpiringKeyValue(ctx, middleware.RateLimitBucket, sharedLimiter.MaxRefill())
+		if err != nil {
+			return fmt.Errorf("rate limiter: %w", err)
+		}
+		sharedLimiter.SetKeyValue(rateBucket)
+		slog.Info("distributed rate limiting enabled", "bucket", middleware.RateLimitBucket, "tiers", len(tiers))
+	}
 
 	// Middleware
 	r.Use(cfhttp.CORS(cfg.Server.CORSOrigin))
 	r.Use(middleware.RequestID)
 	r.Use(cfhttp.Logger)
+	r.Use(cfhttp.CacheBypass)
 	r.Use(chimw.RealIP)
 	r.Use(chimw.Recoverer)
 	r.Use(chimw.Timeout(30 * time.Second))
-	r.Use(rateLimiter.Handler)
+	// Every request is limited per client IP before Auth, so failed and
+	// unauthenticated requests are limited too
+	if sharedLimiter == nil {
+		r.Use(rateLimiter.Handler)
+	} else {
+		r.Use(sharedLimiter.IPHandler)
+	}
 
 	// Liveness (always 200)
 	r.Get("/health", livenessHandler)
@@ -213,11 +778,52 @@ func run() error {
 	// Readiness (pings DB, checks NATS, checks LiteLLM)
 	r.Get("/health/ready", readinessHandler(pool, queue, llmClient))
 
-	// WebSocket endpoint
-	r.Get("/ws", hub.HandleWS)
-
-	// API routes
-	cfhttp.MountRoutes(r, handlers)
+	// Slack button clicks (signed by Slack, not sent with an API key)
+	cfhttp.MountSlackRoutes(r, handlers)
+
+	r.Group(func(r chi.Router) {
+		// API keys (server.api_keys and managed keys), enforced once either
+		// exists; health checks stay open
+		r.Use(middleware.Auth(cfg.Server.APIKeys, apiKeySvc))
+		// X-Tenant-ID narrows operator credentials to one tenant; managed keys
+		// are already scoped to theirs and may not name another
+		r.Use(middleware.Tenant)
+		// Rate limits per API key: on this server, or shared by all servers
+		// per API key and the tenant it is bound to
+		if sharedLimiter == nil {
+			r.Use(middleware.KeyRateLimit)
+		} else {
+			r.Use(sharedLimiter.Handler)
+		}
+		// Retries with the same Idempotency-Key get the stored response
+		if handlers.Idempotency != nil {
+			r.Use(handlers.Idempotency.Handler)
+		}
+		// Changes are recorded in the audit log with their actor and tenant
+		r.Use(middleware.Audit(auditSvc))
+
+		// WebSocket endpoint
+		r.Get("/ws", hub.HandleWS)
+
+		// API routes
+		cfhttp.MountRoutes(r, handlers)
+
+		if cfg.Server.MCP {
+			r.Handle("/mcp", cfmcp.NewServer(cfmcp.Services{
+				Projects:     projectSvc,
+				Tasks:        taskSvc,
+				Runtime:      runtimeSvc,
+				Orchestrator: orchSvc,
+				Retrieval:    retrievalSvc,
+				Scopes:       scopeSvc,
+				Graph:        handlers.Graph,
+				Costs:        handlers.Costs,
+				LSP:          lspSvc,
+			}))
+			slog.Info("mcp server enabled", "path", "/mcp")
+		}
+	})
+	slog.Info("api key auth", "enabled", len(cfg.Server.APIKeys) > 0)
 
 	addr := ":" + cfg.Server.Port
 
@@ -230,6 +836,27 @@ func run() error {
 		IdleTimeout:       120 * time.Second,
 	}
 
+	// Reload the config on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if _, err := configSvc.Reload(); err != nil {
+				slog.Error("config reload failed, keeping the current config", "error", err)
+			}
+		}
+	}()
+
+	// Take over the runs a previous server interrupted when it shut down.
+	resumed, err := runtimeSvc.ResumeInterruptedRuns(ctx)
+	if err != nil {
+		slog.Error("resume interrupted runs", "error", err)
+	} else if resumed > 0 {
+		slog.Info("resumed interrupted runs", "runs", resumed)
+	}
+	cancelLeader := leader.Start(ctx)
+	slog.Info("leader election started", "lease", cfg.Server.LeaderLease)
+
 	// Wait for interrupt signal
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGTERM)
@@ -242,38 +869,66 @@ func run() error {
 	}()
 
 	<-done
+	signal.Stop(hup)
 
 	// --- Ordered Graceful Shutdown ---
-	// Phase 1: Stop accepting new HTTP requests
-	slog.Info("shutdown phase 1: stopping HTTP server")
+	// Phase 1: Refuse new runs, let this server's runs finish and mark the
+	// rest interrupted for another server to resume. Releasing the leader
+	// lease first lets another server take over the background jobs.
+	slog.Info("shutdown phase 1: draining runs", "timeout", cfg.Runtime.DrainTimeout)
+	cancelLeader()
+	if n := runtimeSvc.Drain(ctx, cfg.Runtime.DrainTimeout); n > 0 {
+		slog.Warn("interrupted runs still active at shutdown", "runs", n)
+	}
+
+	// Phase 2: Stop accepting new HTTP requests, then the language servers
+	slog.Info("shutdown phase 2: stopping HTTP server")
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer shutdownCancel()
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		slog.Error("http shutdown error", "error", err)
 	}
+	stopLSP()
 
-	// Phase 2: Cancel NATS subscribers (stop processing new messages)
-	slog.Info("shutdown phase 2: cancelling NATS subscribers")
+	// Phase 3: Cancel NATS subscribers (stop processing new messages)
+	slog.Info("shutdown phase 3: cancelling NATS subscribers")
 	for _, cancel := range runtimeCancels {
 		cancel()
 	}
 	cancelResults()
 	cancelOutput()
+	cancelFlagWatcher()
+	cancelFanOut()
+	cancelReplicas()
 
-	// Phase 3: Drain NATS (flush pending publishes, wait for acks)
-	slog.Info("shutdown phase 3: draining NATS connection")
+	// Phase 4: Drain NATS (flush pending publishes, wait for acks)
+	slog.Info("shutdown phase 4: draining NATS connection")
 	if err := queue.Drain(); err != nil {
 		slog.Error("nats drain error", "error", err)
 	}
 
-	// Phase 4: Close database (last, so in-flight queries can complete)
-	slog.Info("shutdown phase 4: closing database pool")
+	// Phase 5: Write buffered agent events, then close database (last, so
+	// in-flight queries can complete)
+	slog.Info("shutdown phase 5: closing database pool")
+	cancelEventStore()
+	if replicas != nil {
+		replicas.Close()
+	}
 	pool.Close()
 
 	slog.Info("shutdown complete")
 	return nil
 }
 
+// instanceID identifies this server in the leader lease: the hostname,
+// which is the pod name on Kubernetes, and a random suffix.
+func instanceID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return host + "-" + hex.EncodeToString(b)
+}
+
 // livenessHandler always returns 200 (Kubernetes liveness probe).
 func livenessHandler(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
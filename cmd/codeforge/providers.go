@@ -4,5 +4,18 @@ package main
 // Add new providers here as they are implemented.
 
 import (
+	_ "github.com/Strob0t/CodeForge/internal/adapter/confluence"
+	_ "github.com/Strob0t/CodeForge/internal/adapter/docker"
+	_ "github.com/Strob0t/CodeForge/internal/adapter/github"
+	_ "github.com/Strob0t/CodeForge/internal/adapter/gitlab"
 	_ "github.com/Strob0t/CodeForge/internal/adapter/gitlocal"
+	_ "github.com/Strob0t/CodeForge/internal/adapter/jira"
+	_ "github.com/Strob0t/CodeForge/internal/adapter/kubernetes"
+	_ "github.com/Strob0t/CodeForge/internal/adapter/linear"
+	_ "github.com/Strob0t/CodeForge/internal/adapter/notify"
+	_ "github.com/Strob0t/CodeForge/internal/adapter/notion"
+	_ "github.com/Strob0t/CodeForge/internal/adapter/searxng"
+	_ "github.com/Strob0t/CodeForge/internal/adapter/siem"
+	_ "github.com/Strob0t/CodeForge/internal/adapter/treesitter"
+	_ "github.com/Strob0t/CodeForge/internal/adapter/webcrawl"
 )
@@ -5,29 +5,129 @@ import type {
   AgentEvent,
   AgentTeam,
   ApiError,
+  ApiKey,
+  Attachment,
+  AuditEntry,
+  AuditSink,
+  BackendCapabilities,
   BackendList,
+  BenchmarkRun,
+  BenchmarkSuite,
   Branch,
+  CIStatus,
+  Citation,
+  CodeGraphState,
+  ConfigExplanation,
+  ContextCuration,
   ContextPack,
+  ContextPackPreview,
+  Conversation,
+  ConversationMessage,
+  CostDay,
+  CostSummary,
   CreateAgentRequest,
+  CreateApiKeyRequest,
+  CreateAuditSinkRequest,
+  CreateFromTemplateRequest,
+  CreateKnowledgeBaseRequest,
+  CreateMcpServerRequest,
+  CreateMemoryRequest,
+  CreateMicroagentRequest,
   CreateModeRequest,
   CreatePlanRequest,
   CreateProjectRequest,
+  CreateSkillRequest,
+  CreateSecretRequest,
+  CreateSubProjectRequest,
   CreateTaskRequest,
   CreateTeamRequest,
+  CreateTenantRequest,
+  CreatedApiKey,
+  CurationRule,
   DecomposeRequest,
+  DependencyPath,
   ExecutionPlan,
+  Experience,
+  FeatureFlag,
+  FeatureFlagKey,
+  FeatureFlagState,
+  FeatureFlagStatus,
   GitStatus,
+  GoldenQuery,
+  GraphNeighbors,
   HealthStatus,
+  Impact,
+  ImpactRequest,
+  IndexMigrateRequest,
+  IndexMigration,
+  IssueAutomation,
+  IssueRun,
+  IsolationReport,
+  LeaderboardEntry,
+  KnowledgeBase,
+  LintReport,
+  LintTool,
   LLMModel,
+  McpCallResult,
+  McpServer,
+  McpTool,
+  Memory,
+  Microagent,
   Mode,
+  PlanDebate,
   PlanFeatureRequest,
+  PlanGraph,
+  PlanSimulation,
+  PlanStep,
+  PollCursor,
+  PollResult,
+  PRCommand,
   Project,
+  ProjectConventions,
+  ProjectTemplate,
+  RecalledMemory,
+  RepoMap,
+  RepoMapStatus,
+  RerunPlanRequest,
+  ResolveApprovalRequest,
+  RetrievalEval,
+  RetrievalIndex,
+  RetrievalResult,
+  RetrievalSearchRequest,
+  Review,
+  ReviewPublishResult,
   ProviderList,
+  PublishReviewRequest,
+  RoadmapFeature,
+  RoadmapFeatureStatus,
+  RoadmapImportResult,
+  RoutingDecision,
+  RoutingRequest,
   Run,
+  RunComparison,
+  RunDelivery,
+  RunPreset,
+  RunPresetRequest,
+  Secret,
+  SetFeatureFlagRequest,
   SharedContext,
   SharedContextItem,
+  Skill,
+  SkillIndexEntry,
+  StartBenchmarkRequest,
   StartRunRequest,
+  SubProject,
   Task,
+  Tenant,
+  TenantGitHubApp,
+  TenantQuota,
+  TenantUsage,
+  TemplateResult,
+  TestReport,
+  TokenAccuracy,
+  WorkspaceEdit,
+  WorkspaceFile,
+  WorkspaceTree,
 } from "./types";
 
 const BASE = "/api/v1";
@@ -64,6 +164,13 @@ async function request<T>(path: string, init?: RequestInit): Promise<T> {
   return res.json() as Promise<T>;
 }
 
+/** Path of a workspace file endpoint; the file path keeps its slashes. */
+function workspaceFileUrl(projectId: string, path: string, runId?: string): string {
+  const file = path.split("/").map(encodeURIComponent).join("/");
+  const query = runId ? `?run_id=${encodeURIComponent(runId)}` : "";
+  return `/projects/${encodeURIComponent(projectId)}/files/${file}${query}`;
+}
+
 export const api = {
   health: {
     check: () => fetch("/health").then((r) => r.json() as Promise<HealthStatus>),
@@ -80,6 +187,12 @@ export const api = {
         body: JSON.stringify(data),
       }),
 
+    createFromTemplate: (template: string, data: CreateFromTemplateRequest) =>
+      request<TemplateResult>(`/projects/from-template/${encodeURIComponent(template)}`, {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
+
     delete: (id: string) =>
       request<void>(`/projects/${encodeURIComponent(id)}`, {
         method: "DELETE",
@@ -107,6 +220,68 @@ export const api = {
           body: JSON.stringify({ branch }),
         },
       ),
+
+    subProjects: (id: string) =>
+      request<SubProject[]>(`/projects/${encodeURIComponent(id)}/sub-projects`),
+
+    createSubProject: (id: string, data: CreateSubProjectRequest) =>
+      request<SubProject>(`/projects/${encodeURIComponent(id)}/sub-projects`, {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
+
+    deleteSubProject: (subProjectId: string) =>
+      request<void>(`/sub-projects/${encodeURIComponent(subProjectId)}`, {
+        method: "DELETE",
+      }),
+
+    graphImpact: (id: string, data: ImpactRequest) =>
+      request<Impact>(`/projects/${encodeURIComponent(id)}/graph/impact`, {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
+
+    repoMap: (id: string, maxFiles?: number) =>
+      request<RepoMap>(
+        `/projects/${encodeURIComponent(id)}/graph/repo-map${maxFiles ? `?max_files=${maxFiles}` : ""}`,
+      ),
+
+    repoMapStatus: (id: string) =>
+      request<RepoMapStatus>(`/projects/${encodeURIComponent(id)}/graph/repo-map/status`),
+
+    graph: (id: string) => request<CodeGraphState>(`/projects/${encodeURIComponent(id)}/graph`),
+
+    buildGraph: (id: string) =>
+      request<CodeGraphState>(`/projects/${encodeURIComponent(id)}/graph/build`, {
+        method: "POST",
+      }),
+
+    updateGraph: (id: string, paths: string[]) =>
+      request<CodeGraphState>(`/projects/${encodeURIComponent(id)}/graph/update`, {
+        method: "POST",
+        body: JSON.stringify({ paths }),
+      }),
+
+    graphNeighbors: (id: string, path: string) =>
+      request<GraphNeighbors>(
+        `/projects/${encodeURIComponent(id)}/graph/neighbors?path=${encodeURIComponent(path)}`,
+      ),
+
+    graphPath: (id: string, from: string, to: string) =>
+      request<DependencyPath>(
+        `/projects/${encodeURIComponent(id)}/graph/path?from=${encodeURIComponent(from)}&to=${encodeURIComponent(to)}`,
+      ),
+
+    conventions: (id: string) =>
+      request<ProjectConventions>(`/projects/${encodeURIComponent(id)}/conventions`),
+
+    extractConventions: (id: string) =>
+      request<ProjectConventions>(`/projects/${encodeURIComponent(id)}/conventions`, {
+        method: "POST",
+      }),
+
+    featureFlags: (id: string) =>
+      request<FeatureFlagState[]>(`/projects/${encodeURIComponent(id)}/feature-flags`),
   },
 
   agents: {
@@ -115,6 +290,12 @@ export const api = {
 
     get: (id: string) => request<Agent>(`/agents/${encodeURIComponent(id)}`),
 
+    setSubProject: (id: string, subProjectId: string) =>
+      request<Agent>(`/agents/${encodeURIComponent(id)}/sub-project`, {
+        method: "PUT",
+        body: JSON.stringify({ sub_project_id: subProjectId }),
+      }),
+
     create: (projectId: string, data: CreateAgentRequest) =>
       request<Agent>(`/projects/${encodeURIComponent(projectId)}/agents`, {
         method: "POST",
@@ -160,6 +341,26 @@ export const api = {
         method: "POST",
         body: JSON.stringify({ project_id: projectId, team_id: teamId ?? "" }),
       }),
+
+    previewContext: (taskId: string, projectId: string, teamId?: string) =>
+      request<ContextPackPreview>(`/tasks/${encodeURIComponent(taskId)}/context/preview`, {
+        method: "POST",
+        body: JSON.stringify({ project_id: projectId, team_id: teamId ?? "" }),
+      }),
+
+    curation: (taskId: string) =>
+      request<ContextCuration>(`/tasks/${encodeURIComponent(taskId)}/context/curation`),
+
+    setCuration: (taskId: string, rules: CurationRule[]) =>
+      request<ContextCuration>(`/tasks/${encodeURIComponent(taskId)}/context/curation`, {
+        method: "PUT",
+        body: JSON.stringify({ rules }),
+      }),
+
+    deleteCuration: (taskId: string) =>
+      request<void>(`/tasks/${encodeURIComponent(taskId)}/context/curation`, {
+        method: "DELETE",
+      }),
   },
 
   llm: {
@@ -194,6 +395,52 @@ export const api = {
       }),
 
     listByTask: (taskId: string) => request<Run[]>(`/tasks/${encodeURIComponent(taskId)}/runs`),
+
+    compare: (ids: string[]) =>
+      request<RunComparison>(`/runs/compare?ids=${ids.map(encodeURIComponent).join(",")}`),
+
+    /** Pending, running and quality-gate runs, oldest first. */
+    active: (projectId?: string) =>
+      request<Run[]>(
+        `/runs/active${projectId ? `?project_id=${encodeURIComponent(projectId)}` : ""}`,
+      ),
+
+    /** Download URL of the run's event trajectory (JSON Lines). */
+    trajectoryUrl: (id: string) => `${BASE}/runs/${encodeURIComponent(id)}/trajectory`,
+
+    /** Citations of the run's final output, resolved to file lines or documents. */
+    citations: (id: string) => request<Citation[]>(`/runs/${encodeURIComponent(id)}/citations`),
+
+    /** Where the run delivered its changes and whether they failed downstream. */
+    delivery: (id: string) => request<RunDelivery>(`/runs/${encodeURIComponent(id)}/delivery`),
+
+    /** CI workflow runs or pipelines of the commit the run delivered. */
+    ci: (id: string) => request<CIStatus>(`/runs/${encodeURIComponent(id)}/ci`),
+
+    review: (id: string) => request<Review>(`/runs/${encodeURIComponent(id)}/review`),
+
+    publishReview: (id: string, data: PublishReviewRequest) =>
+      request<ReviewPublishResult>(`/runs/${encodeURIComponent(id)}/review/publish`, {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
+
+    /** Download URL of the run's review and lint findings as a SARIF log. */
+    sarifUrl: (id: string) => `${BASE}/reviews/${encodeURIComponent(id)}/export?format=sarif`,
+
+    tests: (id: string) => request<TestReport>(`/runs/${encodeURIComponent(id)}/tests`),
+
+    runTests: (id: string) =>
+      request<{ status: string; command: string }>(`/runs/${encodeURIComponent(id)}/tests`, {
+        method: "POST",
+      }),
+
+    lint: (id: string) => request<LintReport>(`/runs/${encodeURIComponent(id)}/lint`),
+
+    runLint: (id: string) =>
+      request<{ status: string; linters: LintTool[] }>(`/runs/${encodeURIComponent(id)}/lint`, {
+        method: "POST",
+      }),
   },
 
   teams: {
@@ -238,12 +485,25 @@ export const api = {
 
     get: (id: string) => request<ExecutionPlan>(`/plans/${encodeURIComponent(id)}`),
 
+    graph: (id: string) => request<PlanGraph>(`/plans/${encodeURIComponent(id)}/graph`),
+
+    debate: (id: string, stepId: string) =>
+      request<PlanDebate>(
+        `/plans/${encodeURIComponent(id)}/steps/${encodeURIComponent(stepId)}/debate`,
+      ),
+
     create: (projectId: string, data: CreatePlanRequest) =>
       request<ExecutionPlan>(`/projects/${encodeURIComponent(projectId)}/plans`, {
         method: "POST",
         body: JSON.stringify(data),
       }),
 
+    /** Checks the plan and predicts its cost and critical path without starting it. */
+    validate: (id: string) =>
+      request<PlanSimulation>(`/plans/${encodeURIComponent(id)}/validate`, {
+        method: "POST",
+      }),
+
     start: (id: string) =>
       request<ExecutionPlan>(`/plans/${encodeURIComponent(id)}/start`, {
         method: "POST",
@@ -253,6 +513,31 @@ export const api = {
       request<{ status: string }>(`/plans/${encodeURIComponent(id)}/cancel`, {
         method: "POST",
       }),
+
+    /** Creates a pending plan that re-executes part of a finished one. */
+    rerun: (id: string, data: RerunPlanRequest) =>
+      request<ExecutionPlan>(`/plans/${encodeURIComponent(id)}/rerun`, {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
+
+    approveStep: (id: string, stepId: string, data: ResolveApprovalRequest) =>
+      request<PlanStep>(
+        `/plans/${encodeURIComponent(id)}/steps/${encodeURIComponent(stepId)}/approve`,
+        { method: "POST", body: JSON.stringify(data) },
+      ),
+
+    rejectStep: (id: string, stepId: string, data: ResolveApprovalRequest) =>
+      request<PlanStep>(
+        `/plans/${encodeURIComponent(id)}/steps/${encodeURIComponent(stepId)}/reject`,
+        { method: "POST", body: JSON.stringify(data) },
+      ),
+
+    /** Plan steps waiting for approval, across plans. */
+    pendingApprovals: (projectId?: string) =>
+      request<PlanStep[]>(
+        `/approvals${projectId ? `?project_id=${encodeURIComponent(projectId)}` : ""}`,
+      ),
   },
 
   modes: {
@@ -267,6 +552,544 @@ export const api = {
       }),
   },
 
+  secrets: {
+    list: (projectId?: string) =>
+      request<Secret[]>(projectId ? `/projects/${encodeURIComponent(projectId)}/secrets` : "/secrets"),
+
+    create: (data: CreateSecretRequest, projectId?: string) =>
+      request<Secret>(projectId ? `/projects/${encodeURIComponent(projectId)}/secrets` : "/secrets", {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
+
+    rotate: (id: string, value: string) =>
+      request<Secret>(`/secrets/${encodeURIComponent(id)}`, {
+        method: "PUT",
+        body: JSON.stringify({ value }),
+      }),
+
+    delete: (id: string) =>
+      request<void>(`/secrets/${encodeURIComponent(id)}`, { method: "DELETE" }),
+  },
+
+  tenants: {
+    list: () => request<Tenant[]>("/tenants"),
+
+    get: (id: string) => request<Tenant>(`/tenants/${encodeURIComponent(id)}`),
+
+    create: (data: CreateTenantRequest) =>
+      request<Tenant>("/tenants", {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
+
+    setQuota: (id: string, quota: TenantQuota) =>
+      request<Tenant>(`/tenants/${encodeURIComponent(id)}/quota`, {
+        method: "PUT",
+        body: JSON.stringify(quota),
+      }),
+
+    usage: (id: string) => request<TenantUsage>(`/tenants/${encodeURIComponent(id)}/usage`),
+
+    setGitHubApp: (id: string, app: TenantGitHubApp) =>
+      request<Tenant>(`/tenants/${encodeURIComponent(id)}/github-app`, {
+        method: "PUT",
+        body: JSON.stringify(app),
+      }),
+
+    deleteGitHubApp: (id: string) =>
+      request<void>(`/tenants/${encodeURIComponent(id)}/github-app`, { method: "DELETE" }),
+
+    isolation: () => request<IsolationReport>("/tenancy/isolation"),
+
+    rekey: () => request<{ rekeyed: number }>("/tenancy/rekey", { method: "POST" }),
+  },
+
+  apiKeys: {
+    list: () => request<ApiKey[]>("/auth/api-keys"),
+
+    get: (id: string) => request<ApiKey>(`/auth/api-keys/${encodeURIComponent(id)}`),
+
+    create: (data: CreateApiKeyRequest) =>
+      request<CreatedApiKey>("/auth/api-keys", {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
+
+    update: (id: string, data: CreateApiKeyRequest) =>
+      request<ApiKey>(`/auth/api-keys/${encodeURIComponent(id)}`, {
+        method: "PUT",
+        body: JSON.stringify(data),
+      }),
+
+    delete: (id: string) =>
+      request<void>(`/auth/api-keys/${encodeURIComponent(id)}`, { method: "DELETE" }),
+  },
+
+  audit: {
+    entries: (after = 0, limit = 100) =>
+      request<AuditEntry[]>(`/audit/entries?after=${after}&limit=${limit}`),
+
+    sinks: {
+      list: () => request<AuditSink[]>("/audit/sinks"),
+
+      get: (id: string) => request<AuditSink>(`/audit/sinks/${encodeURIComponent(id)}`),
+
+      create: (data: CreateAuditSinkRequest) =>
+        request<AuditSink>("/audit/sinks", {
+          method: "POST",
+          body: JSON.stringify(data),
+        }),
+
+      update: (id: string, data: CreateAuditSinkRequest) =>
+        request<AuditSink>(`/audit/sinks/${encodeURIComponent(id)}`, {
+          method: "PUT",
+          body: JSON.stringify(data),
+        }),
+
+      delete: (id: string) =>
+        request<void>(`/audit/sinks/${encodeURIComponent(id)}`, { method: "DELETE" }),
+    },
+  },
+
+  admin: {
+    config: () => request<ConfigExplanation>("/admin/config"),
+
+    featureFlags: {
+      list: () => request<FeatureFlagStatus[]>("/admin/feature-flags"),
+
+      set: (key: FeatureFlagKey, data: SetFeatureFlagRequest) =>
+        request<FeatureFlag>(`/admin/feature-flags/${encodeURIComponent(key)}`, {
+          method: "PUT",
+          body: JSON.stringify(data),
+        }),
+
+      delete: (key: FeatureFlagKey, scope: { tenant_id?: string; project_id?: string } = {}) =>
+        request<void>(
+          `/admin/feature-flags/${encodeURIComponent(key)}?${new URLSearchParams(scope).toString()}`,
+          { method: "DELETE" },
+        ),
+    },
+  },
+
+  retrieval: {
+    index: (projectId: string) =>
+      request<RetrievalIndex>(`/projects/${encodeURIComponent(projectId)}/retrieval/index`, {
+        method: "POST",
+      }),
+
+    status: (projectId: string) =>
+      request<RetrievalIndex>(`/projects/${encodeURIComponent(projectId)}/retrieval/index`),
+
+    search: (projectId: string, data: RetrievalSearchRequest) =>
+      request<RetrievalResult[]>(`/projects/${encodeURIComponent(projectId)}/retrieval/search`, {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
+
+    goldenQueries: (projectId: string) =>
+      request<GoldenQuery[]>(
+        `/projects/${encodeURIComponent(projectId)}/retrieval/golden-queries`,
+      ),
+
+    addGoldenQuery: (
+      projectId: string,
+      data: { query: string; paths?: string[]; chunk_ids?: string[] },
+    ) =>
+      request<GoldenQuery>(`/projects/${encodeURIComponent(projectId)}/retrieval/golden-queries`, {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
+
+    deleteGoldenQuery: (id: string) =>
+      request<void>(`/retrieval/golden-queries/${encodeURIComponent(id)}`, { method: "DELETE" }),
+
+    evals: (projectId: string, limit?: number) =>
+      request<RetrievalEval[]>(
+        `/projects/${encodeURIComponent(projectId)}/retrieval/evals${limit ? `?limit=${limit}` : ""}`,
+      ),
+
+    evaluate: (projectId: string, k?: number) =>
+      request<RetrievalEval>(`/projects/${encodeURIComponent(projectId)}/retrieval/evals`, {
+        method: "POST",
+        body: JSON.stringify({ k: k ?? 0 }),
+      }),
+
+    migrate: (projectId: string, data: IndexMigrateRequest = {}) =>
+      request<IndexMigration>(`/projects/${encodeURIComponent(projectId)}/index/migrate`, {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
+
+    migration: (projectId: string) =>
+      request<IndexMigration>(`/projects/${encodeURIComponent(projectId)}/index/migrate`),
+  },
+
+  knowledgeBases: {
+    list: () => request<KnowledgeBase[]>("/knowledge-bases"),
+
+    get: (id: string) => request<KnowledgeBase>(`/knowledge-bases/${encodeURIComponent(id)}`),
+
+    create: (data: CreateKnowledgeBaseRequest) =>
+      request<KnowledgeBase>("/knowledge-bases", {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
+
+    update: (id: string, data: CreateKnowledgeBaseRequest) =>
+      request<KnowledgeBase>(`/knowledge-bases/${encodeURIComponent(id)}`, {
+        method: "PUT",
+        body: JSON.stringify(data),
+      }),
+
+    delete: (id: string) =>
+      request<void>(`/knowledge-bases/${encodeURIComponent(id)}`, { method: "DELETE" }),
+
+    refresh: (id: string) =>
+      request<KnowledgeBase>(`/knowledge-bases/${encodeURIComponent(id)}/refresh`, {
+        method: "POST",
+      }),
+  },
+
+  routing: {
+    explain: (data: RoutingRequest) =>
+      request<RoutingDecision>("/routing/explain", {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
+  },
+
+  conversations: {
+    list: (projectId: string) =>
+      request<Conversation[]>(`/projects/${encodeURIComponent(projectId)}/conversations`),
+
+    create: (projectId: string, data: { title: string; model?: string; mode?: string }) =>
+      request<Conversation>(`/projects/${encodeURIComponent(projectId)}/conversations`, {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
+
+    get: (id: string) => request<Conversation>(`/conversations/${encodeURIComponent(id)}`),
+
+    messages: (id: string, compressed = false) =>
+      request<ConversationMessage[]>(
+        `/conversations/${encodeURIComponent(id)}/messages${compressed ? "?view=compressed" : ""}`,
+      ),
+
+    send: (id: string, content: string, attachments?: string[]) =>
+      request<ConversationMessage>(`/conversations/${encodeURIComponent(id)}/messages`, {
+        method: "POST",
+        body: JSON.stringify({ content, attachments }),
+      }),
+
+    /** Sends a message and calls onDelta with each piece of the reply as it streams. */
+    stream: async (
+      id: string,
+      content: string,
+      onDelta: (delta: string) => void,
+      attachments?: string[],
+    ): Promise<ConversationMessage> => {
+      const res = await fetch(`${BASE}/conversations/${encodeURIComponent(id)}/messages/stream`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ content, attachments }),
+      });
+      if (!res.ok || !res.body) {
+        throw new FetchError(res.status, (await res.json()) as ApiError);
+      }
+
+      const reader = res.body.pipeThrough(new TextDecoderStream()).getReader();
+      let buf = "";
+      for (;;) {
+        const { done, value } = await reader.read();
+        if (done) break;
+        buf += value;
+        let end: number;
+        while ((end = buf.indexOf("\n\n")) >= 0) {
+          const frame = buf.slice(0, end);
+          buf = buf.slice(end + 2);
+          const event = /^event: (.*)$/m.exec(frame)?.[1];
+          const data = JSON.parse(/^data: (.*)$/m.exec(frame)?.[1] ?? "null") as unknown;
+          if (event === "delta") onDelta((data as { content: string }).content);
+          if (event === "message") return data as ConversationMessage;
+          if (event === "error") throw new FetchError(res.status, data as ApiError);
+        }
+      }
+      throw new Error("conversation stream ended without a reply");
+    },
+  },
+
+  attachments: {
+    /** Uploads a file to a project, optionally attaching it to one of its tasks. */
+    upload: async (projectId: string, file: File, taskId?: string): Promise<Attachment> => {
+      const form = new FormData();
+      form.append("file", file);
+      if (taskId) form.append("task_id", taskId);
+      const res = await fetch(`${BASE}/projects/${encodeURIComponent(projectId)}/attachments`, {
+        method: "POST",
+        body: form,
+      });
+      if (!res.ok) {
+        throw new FetchError(res.status, (await res.json()) as ApiError);
+      }
+      return res.json() as Promise<Attachment>;
+    },
+
+    listByTask: (taskId: string) =>
+      request<Attachment[]>(`/tasks/${encodeURIComponent(taskId)}/attachments`),
+
+    contentUrl: (id: string) => `${BASE}/artifacts/${encodeURIComponent(id)}/content`,
+  },
+
+  workspace: {
+    /** Lists a directory of the project's workspace, or of a run's with runId. */
+    tree: (projectId: string, opts: { path?: string; depth?: number; runId?: string } = {}) => {
+      const q = new URLSearchParams();
+      if (opts.path) q.set("path", opts.path);
+      if (opts.depth !== undefined) q.set("depth", String(opts.depth));
+      if (opts.runId) q.set("run_id", opts.runId);
+      return request<WorkspaceTree>(
+        `/projects/${encodeURIComponent(projectId)}/files?${q.toString()}`,
+      );
+    },
+
+    /** Reads a file, or the lines start to end of it. */
+    read: (
+      projectId: string,
+      path: string,
+      opts: { start?: number; end?: number; runId?: string } = {},
+    ) => {
+      const q = new URLSearchParams();
+      if (opts.start) q.set("start", String(opts.start));
+      if (opts.end) q.set("end", String(opts.end));
+      if (opts.runId) q.set("run_id", opts.runId);
+      return request<WorkspaceFile>(`${workspaceFileUrl(projectId, path)}?${q.toString()}`);
+    },
+
+    /** Replaces a file read with hash baseHash; without baseHash it creates the file. */
+    write: (projectId: string, path: string, content: string, baseHash?: string, runId?: string) =>
+      request<WorkspaceFile>(workspaceFileUrl(projectId, path, runId), {
+        method: "PUT",
+        body: JSON.stringify({ content, base_hash: baseHash }),
+      }),
+
+    patch: (
+      projectId: string,
+      path: string,
+      baseHash: string,
+      edits: WorkspaceEdit[],
+      runId?: string,
+    ) =>
+      request<WorkspaceFile>(workspaceFileUrl(projectId, path, runId), {
+        method: "PATCH",
+        body: JSON.stringify({ base_hash: baseHash, edits }),
+      }),
+  },
+
+  experiences: {
+    list: (projectId: string) =>
+      request<Experience[]>(`/projects/${encodeURIComponent(projectId)}/experiences`),
+
+    rate: (id: string, useful: boolean) =>
+      request<Experience>(`/experiences/${encodeURIComponent(id)}/rate`, {
+        method: "POST",
+        body: JSON.stringify({ useful }),
+      }),
+  },
+
+  skills: {
+    list: (projectId: string) =>
+      request<Skill[]>(`/projects/${encodeURIComponent(projectId)}/skills`),
+
+    create: (projectId: string, data: CreateSkillRequest) =>
+      request<Skill>(`/projects/${encodeURIComponent(projectId)}/skills`, {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
+
+    get: (id: string) => request<Skill>(`/skills/${encodeURIComponent(id)}`),
+
+    delete: (id: string) =>
+      request<void>(`/skills/${encodeURIComponent(id)}`, { method: "DELETE" }),
+
+    exportUrl: (id: string) => `${BASE}/skills/${encodeURIComponent(id)}/export`,
+
+    import: (projectId: string, bundle: Blob) =>
+      request<Skill>(`/projects/${encodeURIComponent(projectId)}/skills/import`, {
+        method: "POST",
+        headers: { "Content-Type": "application/gzip" },
+        body: bundle,
+      }),
+
+    registry: () => request<SkillIndexEntry[]>("/skills/registry"),
+
+    install: (projectId: string, name: string, version?: string) =>
+      request<Skill>(`/projects/${encodeURIComponent(projectId)}/skills/install`, {
+        method: "POST",
+        body: JSON.stringify({ name, version }),
+      }),
+  },
+
+  costs: {
+    list: () => request<CostSummary[]>("/costs"),
+
+    project: (projectId: string) =>
+      request<CostSummary>(`/projects/${encodeURIComponent(projectId)}/costs`),
+
+    daily: (days?: number) => request<CostDay[]>(`/costs/daily${days ? `?days=${days}` : ""}`),
+
+    projectDaily: (projectId: string, days?: number) =>
+      request<CostDay[]>(
+        `/projects/${encodeURIComponent(projectId)}/costs/daily${days ? `?days=${days}` : ""}`,
+      ),
+  },
+
+  microagents: {
+    list: (projectId: string) =>
+      request<Microagent[]>(`/projects/${encodeURIComponent(projectId)}/microagents`),
+
+    create: (projectId: string, data: CreateMicroagentRequest) =>
+      request<Microagent>(`/projects/${encodeURIComponent(projectId)}/microagents`, {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
+
+    get: (id: string) => request<Microagent>(`/microagents/${encodeURIComponent(id)}`),
+
+    update: (id: string, data: CreateMicroagentRequest) =>
+      request<Microagent>(`/microagents/${encodeURIComponent(id)}`, {
+        method: "PUT",
+        body: JSON.stringify(data),
+      }),
+
+    delete: (id: string) =>
+      request<void>(`/microagents/${encodeURIComponent(id)}`, { method: "DELETE" }),
+  },
+
+  presets: {
+    list: (projectId: string) =>
+      request<RunPreset[]>(`/projects/${encodeURIComponent(projectId)}/presets`),
+
+    create: (projectId: string, data: RunPresetRequest) =>
+      request<RunPreset>(`/projects/${encodeURIComponent(projectId)}/presets`, {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
+
+    get: (projectId: string, name: string) =>
+      request<RunPreset>(
+        `/projects/${encodeURIComponent(projectId)}/presets/${encodeURIComponent(name)}`,
+      ),
+
+    update: (projectId: string, name: string, data: RunPresetRequest) =>
+      request<RunPreset>(
+        `/projects/${encodeURIComponent(projectId)}/presets/${encodeURIComponent(name)}`,
+        { method: "PUT", body: JSON.stringify(data) },
+      ),
+
+    delete: (projectId: string, name: string) =>
+      request<void>(
+        `/projects/${encodeURIComponent(projectId)}/presets/${encodeURIComponent(name)}`,
+        { method: "DELETE" },
+      ),
+  },
+
+  mcpServers: {
+    list: () => request<McpServer[]>("/mcp-servers"),
+
+    create: (data: CreateMcpServerRequest) =>
+      request<McpServer>("/mcp-servers", { method: "POST", body: JSON.stringify(data) }),
+
+    get: (id: string) => request<McpServer>(`/mcp-servers/${encodeURIComponent(id)}`),
+
+    update: (id: string, data: CreateMcpServerRequest) =>
+      request<McpServer>(`/mcp-servers/${encodeURIComponent(id)}`, {
+        method: "PUT",
+        body: JSON.stringify(data),
+      }),
+
+    delete: (id: string) =>
+      request<void>(`/mcp-servers/${encodeURIComponent(id)}`, { method: "DELETE" }),
+
+    tools: (id: string) => request<McpTool[]>(`/mcp-servers/${encodeURIComponent(id)}/tools`),
+
+    callTool: (id: string, name: string, args?: Record<string, unknown>) =>
+      request<McpCallResult>(`/mcp-servers/${encodeURIComponent(id)}/tools/call`, {
+        method: "POST",
+        body: JSON.stringify({ name, arguments: args }),
+      }),
+  },
+
+  memories: {
+    list: (projectId: string) =>
+      request<Memory[]>(`/projects/${encodeURIComponent(projectId)}/memories`),
+
+    create: (projectId: string, data: CreateMemoryRequest) =>
+      request<Memory>(`/projects/${encodeURIComponent(projectId)}/memories`, {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
+
+    recall: (projectId: string, query: string, limit?: number) =>
+      request<RecalledMemory[]>(`/projects/${encodeURIComponent(projectId)}/memories/recall`, {
+        method: "POST",
+        body: JSON.stringify({ query, limit }),
+      }),
+
+    delete: (id: string) =>
+      request<void>(`/memories/${encodeURIComponent(id)}`, { method: "DELETE" }),
+  },
+
+  tokenizers: {
+    diagnostics: () => request<TokenAccuracy[]>("/tokenizers/diagnostics"),
+
+    probe: (data: { model?: string; text: string }) =>
+      request<TokenAccuracy>("/tokenizers/probe", {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
+  },
+
+  templates: {
+    list: () => request<ProjectTemplate[]>("/templates"),
+
+    get: (name: string) => request<ProjectTemplate>(`/templates/${encodeURIComponent(name)}`),
+  },
+
+  benchmarks: {
+    suites: () => request<BenchmarkSuite[]>("/benchmarks/suites"),
+
+    leaderboard: (suite: string) =>
+      request<LeaderboardEntry[]>(`/benchmarks/suites/${encodeURIComponent(suite)}/leaderboard`),
+
+    runs: (suite?: string) =>
+      request<BenchmarkRun[]>(suite ? `/benchmarks/runs?suite=${encodeURIComponent(suite)}` : "/benchmarks/runs"),
+
+    get: (id: string) => request<BenchmarkRun>(`/benchmarks/runs/${encodeURIComponent(id)}`),
+
+    start: (data: StartBenchmarkRequest) =>
+      request<BenchmarkRun>("/benchmarks/runs", {
+        method: "POST",
+        body: JSON.stringify(data),
+      }),
+  },
+
+  roadmap: {
+    features: (projectId: string) =>
+      request<RoadmapFeature[]>(`/projects/${encodeURIComponent(projectId)}/roadmap/features`),
+
+    import: (projectId: string) =>
+      request<RoadmapImportResult>(`/projects/${encodeURIComponent(projectId)}/roadmap/import`, {
+        method: "POST",
+      }),
+
+    setStatus: (id: string, status: RoadmapFeatureStatus) =>
+      request<RoadmapFeature>(`/roadmap/features/${encodeURIComponent(id)}/status`, {
+        method: "PUT",
+        body: JSON.stringify({ status }),
+      }),
+  },
+
   policies: {
     list: () => request<{ profiles: string[] }>("/policies"),
   },
@@ -274,6 +1097,32 @@ export const api = {
   providers: {
     git: () => request<ProviderList>("/providers/git"),
     agent: () => request<BackendList>("/providers/agent"),
+    agentCapabilities: () =>
+      request<Record<string, BackendCapabilities>>("/providers/agent/capabilities"),
+    pm: () => request<ProviderList>("/providers/pm"),
+  },
+
+  automation: {
+    get: (projectId: string) =>
+      request<IssueAutomation>(`/projects/${encodeURIComponent(projectId)}/automation`),
+
+    update: (projectId: string, data: IssueAutomation) =>
+      request<IssueAutomation>(`/projects/${encodeURIComponent(projectId)}/automation`, {
+        method: "PUT",
+        body: JSON.stringify(data),
+      }),
+
+    issueRuns: (projectId: string) =>
+      request<IssueRun[]>(`/projects/${encodeURIComponent(projectId)}/issue-runs`),
+
+    prCommands: (projectId: string) =>
+      request<PRCommand[]>(`/projects/${encodeURIComponent(projectId)}/pr-commands`),
+
+    pollCursors: (projectId: string) =>
+      request<PollCursor[]>(`/projects/${encodeURIComponent(projectId)}/poll`),
+
+    poll: (projectId: string) =>
+      request<PollResult>(`/projects/${encodeURIComponent(projectId)}/poll`, { method: "POST" }),
   },
 } as const;
 
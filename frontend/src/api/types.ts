@@ -1,6 +1,7 @@
 /** Matches Go domain/project.Project */
 export interface Project {
   id: string;
+  tenant_id: string;
   name: string;
   description: string;
   repo_url: string;
@@ -13,6 +14,7 @@ export interface Project {
 
 /** Matches Go domain/project.CreateRequest */
 export interface CreateProjectRequest {
+  tenant_id?: string;
   name: string;
   description: string;
   repo_url: string;
@@ -37,6 +39,7 @@ export interface Task {
   id: string;
   project_id: string;
   agent_id?: string;
+  sub_project_id?: string;
   title: string;
   prompt: string;
   status: TaskStatus;
@@ -50,6 +53,29 @@ export interface Task {
 export interface CreateTaskRequest {
   title: string;
   prompt: string;
+  sub_project_id?: string;
+}
+
+/** Matches Go domain/project.SubProject */
+export interface SubProject {
+  id: string;
+  project_id: string;
+  name: string;
+  path: string;
+  language?: string;
+  build_command?: string;
+  test_command?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+/** Matches Go domain/project.CreateSubProjectRequest */
+export interface CreateSubProjectRequest {
+  name: string;
+  path: string;
+  language?: string;
+  build_command?: string;
+  test_command?: string;
 }
 
 /** Matches Go domain/project.GitStatus */
@@ -77,10 +103,12 @@ export type AgentStatus = "idle" | "running" | "error" | "stopped";
 export interface Agent {
   id: string;
   project_id: string;
+  sub_project_id?: string;
   name: string;
   backend: string;
   status: AgentStatus;
   config: Record<string, string>;
+  template_id?: string;
   created_at: string;
   updated_at: string;
 }
@@ -139,10 +167,11 @@ export type RunStatus =
   | "failed"
   | "cancelled"
   | "timeout"
-  | "quality_gate";
+  | "quality_gate"
+  | "failed_downstream";
 
 /** Deliver mode enum matching Go domain/run.DeliverMode */
-export type DeliverMode = "" | "patch" | "commit-local" | "branch" | "pr";
+export type DeliverMode = "" | "patch" | "commit-local" | "branch" | "pr" | "push" | "mirror";
 
 /** Matches Go domain/run.Run */
 export interface Run {
@@ -151,14 +180,22 @@ export interface Run {
   agent_id: string;
   project_id: string;
   team_id?: string;
+  conversation_id?: string;
   policy_profile: string;
   exec_mode: string;
   deliver_mode: DeliverMode;
+  branch?: string;
   status: RunStatus;
   step_count: number;
   cost_usd: number;
+  tokens_in: number;
+  tokens_out: number;
   output?: string;
   error?: string;
+  redactions: number;
+  structured_output?: unknown;
+  summary?: string;
+  events_archived_at?: string;
   version: number;
   started_at: string;
   completed_at?: string;
@@ -166,14 +203,109 @@ export interface Run {
   updated_at: string;
 }
 
+/** Matches Go domain/seatbelt.Status */
+export type RunDeliveryStatus = "delivered" | "failed" | "reverted";
+
+/** Matches Go domain/seatbelt.Delivery */
+export interface RunDelivery {
+  run_id: string;
+  project_id: string;
+  task_id: string;
+  mode: DeliverMode;
+  branch: string;
+  commit_sha: string;
+  pr_url?: string;
+  status: RunDeliveryStatus;
+  check?: string;
+  check_url?: string;
+  detail?: string;
+  follow_up_task_id?: string;
+  delivered_at: string;
+  failed_at?: string;
+}
+
+/** Matches Go domain/ci.State */
+export type CIState =
+  | "none"
+  | "pending"
+  | "running"
+  | "success"
+  | "failure"
+  | "cancelled"
+  | "skipped";
+
+/** Matches Go domain/ci.Pipeline */
+export interface CIPipeline {
+  id: string;
+  name: string;
+  branch: string;
+  sha: string;
+  state: CIState;
+  url?: string;
+  started_at?: string;
+  finished_at?: string;
+}
+
+/** Matches Go domain/ci.Status */
+export interface CIStatus {
+  run_id: string;
+  project_id: string;
+  provider: string;
+  branch: string;
+  sha: string;
+  state: CIState;
+  pipelines: CIPipeline[];
+  checked_at: string;
+  error?: string;
+}
+
+/** Matches Go domain/run.DiffStat */
+export interface DiffStat {
+  files: number;
+  insertions: number;
+  deletions: number;
+}
+
+/** Matches Go domain/run.ComparisonEntry */
+export interface RunComparisonEntry {
+  run_id: string;
+  agent_id: string;
+  backend?: string;
+  status: RunStatus;
+  cost_usd: number;
+  tokens_in: number;
+  tokens_out: number;
+  duration_ms: number;
+  step_count: number;
+  files_touched: string[];
+  diff?: DiffStat;
+  quality_gate?: "passed" | "failed";
+  tests_passed?: boolean;
+  lint_passed?: boolean;
+}
+
+/** Matches Go domain/run.Comparison */
+export interface RunComparison {
+  task_id: string;
+  runs: RunComparisonEntry[];
+}
+
 /** Matches Go domain/run.StartRequest */
 export interface StartRunRequest {
   task_id: string;
-  agent_id: string;
+  agent_id?: string; // Required unless a preset picks the agent
   project_id: string;
+  preset?: string;
+  mode?: string;
+  context_budget?: number;
   policy_profile?: string;
   exec_mode?: string;
   deliver_mode?: DeliverMode;
+  branch?: string;
+  model?: string;
+  task_type?: RoutingTaskType;
+  trigger_event?: string;
+  conversation_id?: string;
 }
 
 /** WS event: tool call status */
@@ -193,6 +325,7 @@ export interface RunStatusEvent {
   status: RunStatus;
   step_count: number;
   cost_usd?: number;
+  summary?: string;
 }
 
 /** WS event: quality gate status */
@@ -211,12 +344,14 @@ export interface DeliveryEvent {
   run_id: string;
   task_id: string;
   project_id: string;
-  status: "started" | "completed" | "failed";
+  status: "started" | "completed" | "failed" | "failed_downstream";
   mode: string;
   patch_path?: string;
   commit_hash?: string;
   branch_name?: string;
   pr_url?: string;
+  artifacts?: string[];
+  warnings?: string[];
   error?: string;
 }
 
@@ -235,14 +370,77 @@ export type PlanStepStatus =
   | "completed"
   | "failed"
   | "skipped"
-  | "cancelled";
+  | "cancelled"
+  | "waiting_approval";
+
+/** Matches Go domain/plan.StepType */
+export type PlanStepType = "run" | "approval" | "image";
+
+/** Matches Go domain/plan.ImageSpec */
+export interface ImageSpec {
+  builder?: "dockerfile" | "buildpacks";
+  context?: string;
+  dockerfile?: string;
+  repository: string;
+  tags?: string[];
+  build_args?: Record<string, string>;
+  registry_username?: string;
+  registry_secret?: string;
+}
+
+/** Matches Go domain/plan.ImageBuild */
+export interface ImageBuild extends ImageSpec {
+  source_run_id?: string;
+  digest?: string;
+  built_at?: string;
+}
+
+/** Matches Go domain/policy.ApprovalPolicy */
+export interface ApprovalPolicy {
+  approvers?: string[];
+  quorum?: number;
+  timeout_seconds?: number;
+  timeout_action?: "reject" | "approve";
+  escalate_after_seconds?: number;
+  escalate_to?: string[];
+}
+
+/** Matches Go domain/plan.ApprovalVote */
+export interface ApprovalVote {
+  approved: boolean;
+  by: string;
+  comment?: string;
+  at: string;
+}
+
+/** Matches Go domain/plan.Approval */
+export interface PlanApproval {
+  approved: boolean;
+  by: string;
+  comment?: string;
+  resolved_at?: string;
+  requested_at?: string;
+  policy?: ApprovalPolicy;
+  votes?: ApprovalVote[];
+  escalated_at?: string;
+  expired?: boolean;
+}
+
+/** Matches Go domain/plan.ResolveApprovalRequest */
+export interface ResolveApprovalRequest {
+  approved_by: string;
+  comment?: string;
+}
 
 /** Matches Go domain/plan.Step */
 export interface PlanStep {
   id: string;
   plan_id: string;
+  type?: PlanStepType;
+  name?: string;
   task_id: string;
   agent_id: string;
+  model?: string;
   policy_profile: string;
   deliver_mode: string;
   depends_on: string[];
@@ -250,10 +448,159 @@ export interface PlanStep {
   run_id: string;
   round: number;
   error: string;
+  retry?: RetryPolicy;
+  attempt: number;
+  attempts?: StepAttempt[];
+  retry_at?: string;
+  approval?: PlanApproval;
+  image?: ImageBuild;
+  when?: PlanCondition;
+  loop?: PlanLoop;
   created_at: string;
   updated_at: string;
 }
 
+/** Matches Go domain/plan.Condition */
+export interface PlanCondition {
+  step?: string;
+  verdict?: string;
+  status?: "completed" | "failed";
+  output_contains?: string;
+  field?: string;
+  equals?: string;
+}
+
+/** Matches Go domain/plan.Loop */
+export interface PlanLoop {
+  from: string;
+  max_iterations: number;
+  until?: PlanCondition;
+}
+
+/** Matches Go domain/plan.RetryPolicy */
+export interface RetryPolicy {
+  max_attempts: number;
+  backoff_seconds?: number;
+  backoff_factor?: number;
+  fallback_agents?: string[];
+  fallback_model?: string;
+}
+
+/** Matches Go domain/plan.StepAttempt */
+export interface StepAttempt {
+  attempt: number;
+  run_id: string;
+  agent_id: string;
+  model?: string;
+  error?: string;
+  failed_at: string;
+}
+
+/** Matches Go domain/plan.GraphNode */
+export interface PlanGraphNode {
+  step_id: string;
+  type?: PlanStepType;
+  task_id: string;
+  agent_id: string;
+  status: PlanStepStatus;
+  run_id?: string;
+  round: number;
+  attempt: number;
+  max_attempts: number;
+  attempts: StepAttempt[];
+  retry_at?: string;
+  error?: string;
+  approval?: PlanApproval;
+  image?: ImageBuild;
+  when?: PlanCondition;
+  loop?: PlanLoop;
+}
+
+/** Matches Go domain/plan.GraphEdge */
+export interface PlanGraphEdge {
+  from: string;
+  to: string;
+  kind?: "condition" | "loop";
+}
+
+/** Matches Go domain/plan.Graph */
+export interface PlanGraph {
+  plan_id: string;
+  protocol: PlanProtocol;
+  status: PlanStatus;
+  nodes: PlanGraphNode[];
+  edges: PlanGraphEdge[];
+}
+
+/** Matches Go domain/plan.Finding */
+export interface PlanFinding {
+  severity: "error" | "warning";
+  code:
+    | "cycle"
+    | "unreachable"
+    | "missing_agent"
+    | "missing_task"
+    | "policy"
+    | "deliver_mode"
+    | "budget"
+    | "no_estimate";
+  step_id?: string;
+  message: string;
+}
+
+/** Matches Go domain/plan.StepEstimate */
+export interface PlanStepEstimate {
+  step_id: string;
+  name?: string;
+  basis: "task" | "project" | "none";
+  cost_usd: number;
+  tokens: number;
+  duration_ns: number;
+  start_ns: number;
+}
+
+/** Matches Go domain/plan.Simulation */
+export interface PlanSimulation {
+  plan_id: string;
+  valid: boolean;
+  findings: PlanFinding[];
+  steps: PlanStepEstimate[];
+  cost_usd: number;
+  tokens: number;
+  critical_path: string[];
+  duration_ns: number;
+}
+
+/** Matches Go domain/plan.DebateRole */
+export type DebateRole = "proposal" | "critique" | "revision";
+
+/** Matches Go domain/plan.DebateTurn */
+export interface DebateTurn {
+  step_id: string;
+  round: number;
+  role: DebateRole;
+  run_id?: string;
+  agent_id?: string;
+  status: PlanStepStatus;
+  output: string;
+  created_at: string;
+}
+
+/** Matches Go domain/plan.DebateSummary */
+export interface DebateSummary {
+  text: string;
+  model: string;
+  created_at: string;
+}
+
+/** Matches Go domain/plan.Debate */
+export interface PlanDebate {
+  plan_id: string;
+  step_ids: string[];
+  turns: DebateTurn[];
+  summary?: DebateSummary;
+}
+
 /** Matches Go domain/plan.ExecutionPlan */
 export interface ExecutionPlan {
   id: string;
@@ -265,6 +612,8 @@ export interface ExecutionPlan {
   status: PlanStatus;
   max_parallel: number;
   steps: PlanStep[];
+  /** Plan this one re-executes part of */
+  rerun_of?: string;
   version: number;
   created_at: string;
   updated_at: string;
@@ -272,11 +621,18 @@ export interface ExecutionPlan {
 
 /** Matches Go domain/plan.CreateStepRequest */
 export interface CreateStepRequest {
+  type?: PlanStepType;
+  name?: string;
   task_id: string;
   agent_id: string;
+  model?: string;
   policy_profile?: string;
   deliver_mode?: string;
   depends_on?: string[];
+  retry?: RetryPolicy;
+  when?: PlanCondition;
+  loop?: PlanLoop;
+  image?: ImageSpec;
 }
 
 /** Matches Go domain/plan.CreatePlanRequest */
@@ -288,6 +644,28 @@ export interface CreatePlanRequest {
   steps: CreateStepRequest[];
 }
 
+/** Matches Go domain/plan.RerunRequest */
+export interface RerunPlanRequest {
+  mode: "failed" | "step" | "from";
+  /** Required by the step and from modes */
+  step_id?: string;
+}
+
+/** WS event: approval step requested, voted on, escalated or resolved */
+export interface PlanApprovalEvent {
+  plan_id: string;
+  step_id: string;
+  project_id: string;
+  phase: "requested" | "voted" | "escalated" | "approved" | "rejected";
+  url?: string;
+  by?: string;
+  comment?: string;
+  approvals?: number;
+  quorum?: number;
+  expires_at?: string;
+  escalate_to?: string[];
+}
+
 /** WS event: plan status change */
 export interface PlanStatusEvent {
   plan_id: string;
@@ -303,6 +681,7 @@ export interface PlanStepStatusEvent {
   status: PlanStepStatus;
   run_id: string;
   error: string;
+  attempt?: number;
 }
 
 // --- Feature Decomposition types (Phase 5B) ---
@@ -319,6 +698,7 @@ export interface DecomposeRequest {
   context?: string;
   model?: string;
   auto_start?: boolean;
+  backend?: string;
 }
 
 // --- Agent Team types (Phase 5C) ---
@@ -366,12 +746,20 @@ export interface PlanFeatureRequest {
   model?: string;
   auto_start?: boolean;
   auto_team?: boolean;
+  backend?: string;
 }
 
 // --- Context types (Phase 5D) ---
 
 /** Context entry kind enum matching Go domain/context.EntryKind */
-export type ContextEntryKind = "file" | "snippet" | "summary" | "shared";
+export type ContextEntryKind =
+  | "file"
+  | "snippet"
+  | "summary"
+  | "shared"
+  | "research"
+  | "memory"
+  | "conventions";
 
 /** Matches Go domain/context.ContextEntry */
 export interface ContextEntry {
@@ -382,6 +770,7 @@ export interface ContextEntry {
   content: string;
   tokens: number;
   priority: number;
+  citation?: Citation;
 }
 
 /** Matches Go domain/context.ContextPack */
@@ -395,6 +784,43 @@ export interface ContextPack {
   created_at: string;
 }
 
+/** Context curation action enum matching Go domain/context.CurationAction */
+export type CurationAction = "pin" | "exclude" | "priority";
+
+/** Matches Go domain/context.CurationRule */
+export interface CurationRule {
+  path?: string;
+  chunk_id?: string;
+  action: CurationAction;
+  priority?: number;
+}
+
+/** Matches Go domain/context.Curation */
+export interface ContextCuration {
+  task_id: string;
+  project_id: string;
+  rules: CurationRule[];
+  updated_at: string;
+}
+
+/** Matches Go domain/context.DroppedEntry */
+export interface DroppedContextEntry {
+  kind: ContextEntryKind;
+  path: string;
+  chunk_id?: string;
+  tokens: number;
+  priority: number;
+  reason: "excluded" | "budget";
+}
+
+/** Matches Go domain/context.PackPreview */
+export interface ContextPackPreview {
+  pack: ContextPack | null;
+  dropped: DroppedContextEntry[];
+  pinned?: string[];
+  curation?: ContextCuration;
+}
+
 /** Matches Go domain/context.SharedContextItem */
 export interface SharedContextItem {
   id: string;
@@ -436,6 +862,19 @@ export interface Mode {
   llm_scenario: string;
   autonomy: number;
   prompt_prefix: string;
+  skip_memories: boolean;
+  output_schema?: OutputSchema;
+}
+
+/** Matches Go domain/mode.OutputSchema */
+export interface OutputSchema {
+  type?: "object" | "array" | "string" | "number" | "integer" | "boolean" | "null";
+  description?: string;
+  properties?: Record<string, OutputSchema>;
+  required?: string[];
+  additionalProperties?: boolean;
+  items?: OutputSchema;
+  enum?: unknown[];
 }
 
 /** Create mode request */
@@ -447,6 +886,8 @@ export interface CreateModeRequest {
   llm_scenario?: string;
   autonomy: number;
   prompt_prefix?: string;
+  skip_memories?: boolean;
+  output_schema?: OutputSchema;
 }
 
 // --- WS events (Phase 5E) ---
@@ -472,20 +913,1217 @@ export interface ApiError {
   error: string;
 }
 
-/** Health endpoint response */
-export interface HealthStatus {
-  status: string;
-  postgres: string;
-  nats: string;
-  litellm: string;
+/** Matches Go domain/secret.Secret (the value is never returned) */
+export interface Secret {
+  id: string;
+  tenant_id: string;
+  project_id?: string;
+  name: string;
+  created_at: string;
+  updated_at: string;
 }
 
-/** Provider list response */
-export interface ProviderList {
-  providers: string[];
+/** Matches Go domain/secret.CreateRequest */
+export interface CreateSecretRequest {
+  name: string;
+  value: string;
 }
 
-/** Backend list response */
-export interface BackendList {
-  backends: string[];
+/** Matches Go domain/tenant.Quota (0 = unlimited) */
+export interface TenantQuota {
+  max_concurrent_runs: number;
+  max_projects: number;
+  monthly_token_budget: number;
+  max_storage_mb: number;
+}
+
+/** Matches Go domain/tenant.GitHubApp (key and webhook secret are secret names) */
+export interface TenantGitHubApp {
+  app_id: number;
+  private_key_secret: string;
+  webhook_secret?: string;
+  api_url?: string;
+}
+
+/** Matches Go domain/tenant.Tenant */
+export interface Tenant {
+  id: string;
+  name: string;
+  quota: TenantQuota;
+  github_app?: TenantGitHubApp;
+  created_at: string;
+  updated_at: string;
+}
+
+/** Matches Go domain/tenant.CreateRequest */
+export interface CreateTenantRequest {
+  id: string;
+  name: string;
+  quota?: TenantQuota;
+}
+
+/** Matches Go domain/tenant.Resource */
+export type TenantResource = "concurrent_runs" | "projects" | "monthly_tokens" | "storage_mb";
+
+/** Matches Go domain/tenant.Usage */
+export interface TenantUsage {
+  tenant_id: string;
+  quota: TenantQuota;
+  period_start: string;
+  concurrent_runs: number;
+  projects: number;
+  monthly_tokens: number;
+  storage_mb: number;
+  exceeded?: TenantResource[];
+}
+
+/** Matches Go domain/tenant.TableIsolation */
+export interface TableIsolation {
+  table: string;
+  enabled: boolean;
+  forced: boolean;
+  policies: number;
+}
+
+/** Matches Go domain/tenant.IsolationReport */
+export interface IsolationReport {
+  role: string;
+  bypasses_rls: boolean;
+  tables: TableIsolation[];
+  secrets: number;
+  legacy_secrets: number;
+  unreadable_secrets: number;
+  ok: boolean;
+  problems?: string[];
+}
+
+/** Matches Go domain/apikey.Scope */
+export type ApiKeyScope = "read" | "runs:write" | "admin";
+
+/** Matches Go domain/apikey.Key */
+export interface ApiKey {
+  id: string;
+  name: string;
+  prefix: string;
+  scopes: ApiKeyScope[];
+  tenant_id?: string;
+  rate_limit: number;
+  expires_at?: string;
+  last_used_at?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+/** Matches Go domain/apikey.Created */
+export interface CreatedApiKey extends ApiKey {
+  token: string;
+}
+
+/** Matches Go domain/apikey.CreateRequest */
+export interface CreateApiKeyRequest {
+  name: string;
+  scopes: ApiKeyScope[];
+  tenant_id?: string;
+  rate_limit?: number;
+  expires_at?: string;
+}
+
+/** Matches Go domain/event.AuditEntry */
+export interface AuditEntry {
+  seq: number;
+  tenant_id: string;
+  actor?: string;
+  method: string;
+  path: string;
+  status: number;
+  request_id?: string;
+  remote_addr?: string;
+  created_at: string;
+}
+
+/** Matches Go domain/audit.Kind */
+export type AuditSinkKind = "syslog" | "http" | "kafka";
+
+/** Matches Go domain/audit.Sink */
+export interface AuditSink {
+  id: string;
+  tenant_id: string;
+  name: string;
+  kind: AuditSinkKind;
+  config: Record<string, string>;
+  enabled: boolean;
+  checkpoint: number;
+  last_error?: string;
+  last_delivered_at?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+/** Matches Go domain/audit.CreateRequest */
+export interface CreateAuditSinkRequest {
+  name: string;
+  tenant_id?: string;
+  kind: AuditSinkKind;
+  config: Record<string, string>;
+  enabled?: boolean;
+}
+
+/** Matches Go config.Source */
+export type ConfigSource = "default" | "yaml" | "env" | "flag";
+
+/** Matches Go config.Value */
+export interface ConfigValue {
+  key: string;
+  value: unknown;
+  source: ConfigSource;
+  origin?: string;
+}
+
+/** Matches Go config.Change */
+export interface ConfigChange {
+  key: string;
+  old: unknown;
+  new: unknown;
+  restart_required: boolean;
+}
+
+/** Matches Go service.ConfigExplanation */
+export interface ConfigExplanation {
+  file: string;
+  loaded_at: string;
+  values: ConfigValue[];
+  pending_restart: ConfigChange[];
+}
+
+/** Matches Go domain/featureflag.Key */
+export type FeatureFlagKey = "graph_rag" | "lsp" | "agentic_conversations";
+
+/** Matches Go domain/featureflag.Scope */
+export type FeatureFlagScope = "default" | "global" | "tenant" | "project";
+
+/** Matches Go domain/featureflag.Flag */
+export interface FeatureFlag {
+  key: FeatureFlagKey;
+  tenant_id?: string;
+  project_id?: string;
+  enabled: boolean;
+  updated_at: string;
+}
+
+/** Matches Go domain/featureflag.Status */
+export interface FeatureFlagStatus {
+  key: FeatureFlagKey;
+  description: string;
+  default: boolean;
+  overrides: FeatureFlag[];
+}
+
+/** Matches Go domain/featureflag.State */
+export interface FeatureFlagState {
+  key: FeatureFlagKey;
+  enabled: boolean;
+  scope: FeatureFlagScope;
+}
+
+/** Matches Go domain/featureflag.SetRequest */
+export interface SetFeatureFlagRequest {
+  tenant_id?: string;
+  project_id?: string;
+  enabled: boolean;
+}
+
+/** Matches Go domain/scaffold.Template */
+export interface ProjectTemplate {
+  name: string;
+  description: string;
+  stack: { language: string; test_command?: string; linters?: string[] };
+  variables?: { name: string; description?: string; default?: string }[];
+  files: { path: string; content: string; executable?: boolean }[];
+  config?: Record<string, string>;
+  modes?: Mode[];
+  agents?: {
+    name: string;
+    backend: string;
+    mode?: string;
+    model?: string;
+    policy_profile?: string;
+    config?: Record<string, string>;
+  }[];
+  pipelines?: {
+    name: string;
+    description?: string;
+    protocol: PlanProtocol;
+    steps: { title: string; prompt: string; agent: string; depends_on?: number[] }[];
+  }[];
+}
+
+/** Matches Go domain/scaffold.CreateRequest */
+export interface CreateFromTemplateRequest {
+  tenant_id?: string;
+  name: string;
+  description: string;
+  provider?: string;
+  repo_url?: string;
+  config?: Record<string, string>;
+  variables?: Record<string, string>;
+  auto_start?: boolean;
+}
+
+/** Matches Go domain/scaffold.Result */
+export interface TemplateResult {
+  template: string;
+  project: Project;
+  files: string[];
+  agents: Agent[];
+  plans: ExecutionPlan[];
+}
+
+/** Matches Go domain/issuerun.Settings */
+export interface IssueAutomation {
+  label: string;
+  assignee: string;
+  agent_id?: string;
+  deliver_mode: DeliverMode;
+}
+
+/** Issue run status enum matching Go domain/issuerun.Status */
+export type IssueRunStatus = "running" | "completed" | "failed";
+
+/** Matches Go domain/issuerun.IssueRun */
+export interface IssueRun {
+  id: string;
+  project_id: string;
+  provider: string;
+  issue_number: number;
+  issue_url?: string;
+  task_id: string;
+  run_id?: string;
+  comment_id?: string;
+  status: IssueRunStatus;
+  pr_url?: string;
+  error?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+/** Pull request command enum matching Go domain/chatops.Command */
+export type PRCommandName = "rerun" | "fix" | "explain" | "help";
+
+/** Command status enum matching Go domain/chatops.Status */
+export type PRCommandStatus = "running" | "completed" | "failed";
+
+/** Matches Go domain/chatops.CommandRun */
+export interface PRCommand {
+  id: string;
+  project_id: string;
+  provider: string;
+  pr_number: number;
+  comment_id: string;
+  branch: string;
+  command: string; // A PRCommandName, or an unknown command as posted
+  args?: string;
+  author: string;
+  run_id?: string;
+  reply_id?: string;
+  status: PRCommandStatus;
+  result?: string;
+  error?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+/** Poll source enum matching Go domain/poll.Source */
+export type PollSource = "issues" | "pr_comments" | "pm_items";
+
+/** Matches Go domain/poll.Cursor */
+export interface PollCursor {
+  project_id: string;
+  source: PollSource;
+  position?: string;
+  etag?: string;
+  error?: string;
+  polled_at: string;
+}
+
+/** Matches Go domain/poll.Result */
+export interface PollResult {
+  issue_runs: number;
+  commands: number;
+  features: number;
+}
+
+/** Matches Go domain/retrieval.Index */
+export interface RetrievalIndex {
+  project_id: string;
+  provider: string;
+  model: string;
+  dimensions: number;
+  files: number;
+  chunks: number;
+  indexed_at: string;
+  /** Built by a running index migration, not yet searched. */
+  staged?: boolean;
+}
+
+/** Matches Go domain/retrieval.SearchRequest */
+export interface RetrievalSearchRequest {
+  query: string;
+  limit?: number;
+  /** Rerank with the cross-encoder; defaults to whenever one is configured. */
+  rerank?: boolean;
+}
+
+/** Matches Go domain/retrieval.Result */
+export interface RetrievalResult {
+  /** Stable chunk ID, cited as [cite:<id>]. */
+  id: string;
+  path: string;
+  title?: string;
+  source?: string;
+  start_line: number;
+  end_line: number;
+  content: string;
+  /** Embedding similarity. */
+  score: number;
+  /** Cross-encoder score; set when the results were reranked and ordered by it. */
+  rerank_score?: number;
+}
+
+/** Matches Go domain/retrieval.GoldenQuery */
+export interface GoldenQuery {
+  id: string;
+  project_id: string;
+  query: string;
+  /** Expected files; any chunk of a file counts. */
+  paths?: string[];
+  /** Expected chunks by citation ID. */
+  chunk_ids?: string[];
+  created_at: string;
+}
+
+/** Matches Go domain/retrieval.QueryScore */
+export interface RetrievalQueryScore {
+  query_id: string;
+  query: string;
+  recall_at_k: number;
+  reciprocal_rank: number;
+  /** 1-based rank of the first expected result; unset if none is in the top k. */
+  rank?: number;
+  missing?: string[];
+}
+
+/** Matches Go domain/retrieval.Eval */
+export interface RetrievalEval {
+  id: string;
+  project_id: string;
+  trigger: "manual" | "reindex" | "migration";
+  k: number;
+  provider: string;
+  model: string;
+  dimensions: number;
+  chunks: number;
+  chunk_lines: number;
+  chunk_overlap: number;
+  recall_at_k: number;
+  mrr: number;
+  queries: RetrievalQueryScore[];
+  created_at: string;
+  /** Changes against the previous evaluation of the project. */
+  recall_change?: number;
+  mrr_change?: number;
+}
+
+/** Matches Go domain/retrieval.Embedding */
+export interface RetrievalEmbedding {
+  provider: string;
+  model: string;
+  dimensions?: number;
+}
+
+/** Matches Go domain/retrieval.MigrateRequest */
+export interface IndexMigrateRequest extends Partial<RetrievalEmbedding> {
+  /** Drop of recall@k against the old index the new one may show and still be switched to. */
+  max_recall_drop?: number;
+  /** Switch regardless of the evaluation. */
+  force?: boolean;
+}
+
+/** Matches Go domain/retrieval.Migration */
+export interface IndexMigration {
+  project_id: string;
+  from: RetrievalEmbedding;
+  to: RetrievalEmbedding;
+  phase: "building" | "evaluating" | "switched" | "rejected" | "failed";
+  chunks: number;
+  embedded: number;
+  before?: RetrievalEval;
+  after?: RetrievalEval;
+  error?: string;
+  started_at: string;
+  finished_at?: string;
+}
+
+/** Matches Go domain/retrieval.Citation */
+export interface Citation {
+  id: string;
+  /** Workspace file; unset for knowledge base documents. */
+  path?: string;
+  start_line?: number;
+  end_line?: number;
+  /** Knowledge base document. */
+  url?: string;
+  title?: string;
+  source?: string;
+}
+
+/** Matches Go domain/knowledge.Kind */
+export type KnowledgeBaseKind = "confluence" | "notion" | "url";
+
+/** Matches Go domain/knowledge.KnowledgeBase */
+export interface KnowledgeBase {
+  id: string;
+  tenant_id: string;
+  name: string;
+  kind: KnowledgeBaseKind;
+  config: Record<string, string>;
+  project_ids?: string[];
+  refresh_interval?: string;
+  provider?: string;
+  model?: string;
+  dimensions?: number;
+  documents: number;
+  chunks: number;
+  last_error?: string;
+  refreshed_at?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+/** Matches Go domain/knowledge.CreateRequest */
+export interface CreateKnowledgeBaseRequest {
+  name: string;
+  tenant_id?: string;
+  kind: KnowledgeBaseKind;
+  config: Record<string, string>;
+  project_ids?: string[];
+  refresh_interval?: string;
+}
+
+/** Matches Go domain/routing.TaskType */
+export type RoutingTaskType = "decompose" | "summarize" | "review" | "code";
+
+/** Matches Go domain/routing.Request */
+export interface RoutingRequest {
+  task_type: RoutingTaskType;
+  project_id?: string;
+  model?: string;
+}
+
+/** Matches Go domain/routing.Rule */
+export interface RoutingRule {
+  task_type: RoutingTaskType;
+  tier?: string;
+  models?: string[];
+}
+
+/** Matches Go domain/routing.Decision */
+export interface RoutingDecision {
+  task_type: RoutingTaskType;
+  level: "default" | "tenant" | "project" | "request";
+  rule?: RoutingRule;
+  candidates: { model: string; skipped?: string }[];
+  model?: string;
+}
+
+/** Matches Go domain/conversation.Conversation */
+export interface Conversation {
+  id: string;
+  project_id: string;
+  title: string;
+  model?: string;
+  mode?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+/** Matches Go domain/conversation.Role */
+export type ConversationRole = "user" | "assistant" | "summary" | "tool";
+
+/** Matches Go domain/conversation.ToolCall */
+export interface ConversationToolCall {
+  run_id: string;
+  call_id: string;
+  name: string;
+  args?: Record<string, string>;
+  success: boolean;
+  error?: string;
+  duration_ms?: number;
+  truncated?: boolean;
+  artifact_id?: string;
+}
+
+/** Matches Go domain/conversation.Message */
+export interface ConversationMessage {
+  id: string;
+  conversation_id: string;
+  seq: number;
+  role: ConversationRole;
+  content: string;
+  tokens: number;
+  covers?: number;
+  model?: string;
+  memories?: string[];
+  microagents?: string[];
+  tool_call?: ConversationToolCall;
+  prompt_tokens?: number;
+  completion_tokens?: number;
+  attachments?: string[];
+  created_at: string;
+}
+
+/** Matches Go domain/artifact.Artifact of kind "attachment" (without data) */
+export interface Attachment {
+  id: string;
+  project_id: string;
+  task_id?: string;
+  kind: "attachment";
+  name: string;
+  content_type: string;
+  size: number;
+  created_at: string;
+}
+
+/** Matches Go domain/workspace.Entry */
+export interface WorkspaceEntry {
+  path: string;
+  dir?: boolean;
+  size?: number;
+}
+
+/** Matches Go domain/workspace.Tree */
+export interface WorkspaceTree {
+  path: string;
+  entries: WorkspaceEntry[];
+  truncated?: boolean;
+}
+
+/** Matches Go domain/workspace.File */
+export interface WorkspaceFile {
+  path: string;
+  hash: string;
+  size: number;
+  lines: number;
+  start_line?: number;
+  end_line?: number;
+  content: string;
+  binary?: boolean;
+}
+
+/** Matches Go domain/workspace.Edit: replaces lines start_line..end_line (1-based, inclusive) */
+export interface WorkspaceEdit {
+  start_line: number;
+  end_line: number;
+  text: string;
+}
+
+/** WS event: piece of a streamed conversation reply */
+export interface ConversationDeltaEvent {
+  conversation_id: string;
+  project_id: string;
+  delta: string;
+}
+
+/** WS event: conversation reply stored */
+export interface ConversationMessageEvent {
+  conversation_id: string;
+  project_id: string;
+  message_id: string;
+  seq: number;
+  role: ConversationRole;
+  content: string;
+  tool?: string;
+  model?: string;
+  prompt_tokens?: number;
+  completion_tokens?: number;
+}
+
+/** Matches Go domain/experience.Entry */
+export interface Experience {
+  id: string;
+  project_id: string;
+  plan_id: string;
+  feature: string;
+  outcome: "success" | "failure";
+  steps: number;
+  duration_ms: number;
+  pitfalls?: string[];
+  useful: number;
+  not_useful: number;
+  created_at: string;
+}
+
+/** Matches Go domain/skill.Fixture */
+export interface SkillFixture {
+  name: string;
+  input: string;
+  expect?: string;
+}
+
+/** Matches Go domain/skill.Skill */
+export interface Skill {
+  id: string;
+  project_id: string;
+  name: string;
+  version: string;
+  description?: string;
+  prompt: string;
+  tools?: string[];
+  fixtures?: SkillFixture[];
+  signer?: string;
+  created_at: string;
+}
+
+/** Matches Go domain/skill.CreateRequest */
+export interface CreateSkillRequest {
+  name: string;
+  version?: string;
+  description?: string;
+  prompt: string;
+  tools?: string[];
+  fixtures?: SkillFixture[];
+}
+
+/** Matches Go domain/skill.IndexEntry */
+export interface SkillIndexEntry {
+  name: string;
+  version: string;
+  description?: string;
+  url: string;
+  sha256?: string;
+  signer?: string;
+}
+
+/** Matches Go domain/microagent.Trigger */
+export interface MicroagentTrigger {
+  file_patterns?: string[];
+  keywords?: string[];
+  events?: string[];
+}
+
+/** Matches Go domain/microagent.Microagent */
+export interface Microagent {
+  id: string;
+  project_id: string;
+  name: string;
+  description?: string;
+  knowledge: string;
+  trigger: MicroagentTrigger;
+  enabled: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+/** Matches Go domain/microagent.CreateRequest */
+export interface CreateMicroagentRequest {
+  name: string;
+  description?: string;
+  knowledge: string;
+  trigger: MicroagentTrigger;
+  enabled?: boolean;
+}
+
+/** Matches Go domain/run.PresetRequest */
+export interface RunPresetRequest {
+  name: string;
+  description?: string;
+  backend?: string;
+  mode?: string;
+  policy_profile?: string;
+  exec_mode?: string;
+  deliver_mode?: DeliverMode;
+  model?: string;
+  task_type?: RoutingTaskType;
+  isolate?: boolean;
+  context_budget?: number;
+}
+
+/** Matches Go domain/run.Preset */
+export interface RunPreset extends RunPresetRequest {
+  id: string;
+  project_id: string;
+  created_at: string;
+  updated_at: string;
+}
+
+/** Matches Go domain/mcpserver.OAuth */
+export interface McpOAuth {
+  token_url: string;
+  client_id: string;
+  scopes?: string[];
+  audience?: string;
+}
+
+/** Matches Go domain/mcpserver.Server */
+export interface McpServer {
+  id: string;
+  name: string;
+  url: string;
+  transport: "streamable_http";
+  auth: "none" | "oauth2";
+  oauth?: McpOAuth;
+  enabled: boolean;
+  token_expires_at?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+/** Matches Go domain/mcpserver.CreateRequest */
+export interface CreateMcpServerRequest {
+  name: string;
+  url: string;
+  transport?: "streamable_http";
+  auth?: "none" | "oauth2";
+  oauth?: McpOAuth;
+  client_secret?: string;
+  enabled?: boolean;
+}
+
+/** Matches Go domain/mcpserver.Tool */
+export interface McpTool {
+  name: string;
+  description?: string;
+  inputSchema?: Record<string, unknown>;
+}
+
+/** Matches Go domain/mcpserver.CallResult */
+export interface McpCallResult {
+  content: Record<string, unknown>[];
+  structuredContent?: unknown;
+  isError?: boolean;
+}
+
+/** Matches Go domain/cost.Summary */
+export interface CostSummary {
+  project_id: string;
+  project_name: string;
+  runs: number;
+  cost_usd: number;
+  tokens_in: number;
+  tokens_out: number;
+  as_of?: string;
+  exact: boolean;
+}
+
+/** Matches Go domain/cost.Day */
+export interface CostDay {
+  day: string;
+  runs: number;
+  cost_usd: number;
+  tokens_in: number;
+  tokens_out: number;
+  exact: boolean;
+}
+
+/** Matches Go domain/memory.Memory */
+export interface Memory {
+  id: string;
+  project_id: string;
+  content: string;
+  source?: string;
+  importance: number;
+  provider: string;
+  model: string;
+  created_at: string;
+}
+
+/** Matches Go domain/memory.CreateRequest */
+export interface CreateMemoryRequest {
+  content: string;
+  source?: string;
+  importance?: number;
+}
+
+/** Matches Go domain/memory.Recalled */
+export interface RecalledMemory extends Memory {
+  score: number;
+}
+
+/** Matches Go domain/context.TokenAccuracy */
+export interface TokenAccuracy {
+  model: string;
+  tokenizer: string;
+  samples: number;
+  actual_tokens: number;
+  heuristic_tokens: number;
+  counted_tokens: number;
+  heuristic_error: number;
+  counted_error: number;
+}
+
+/** Matches Go domain/roadmap.FeatureStatus */
+export type RoadmapFeatureStatus = "planned" | "in_progress" | "done" | "cancelled";
+
+/** Matches Go domain/roadmap.Feature */
+export interface RoadmapFeature {
+  id: string;
+  project_id: string;
+  title: string;
+  description: string;
+  status: RoadmapFeatureStatus;
+  labels: string[];
+  external_ids?: Record<string, string>;
+  version: number;
+  created_at: string;
+  updated_at: string;
+}
+
+/** Matches Go domain/roadmap.ImportResult */
+export interface RoadmapImportResult {
+  provider: string;
+  created: number;
+  updated: number;
+  unchanged: number;
+}
+
+/** Matches Go domain/review.Severity */
+export type ReviewSeverity = "critical" | "warning" | "info";
+
+/** Matches Go domain/review.Finding */
+export interface ReviewFinding {
+  path?: string;
+  line?: number;
+  severity: ReviewSeverity;
+  message: string;
+  suggestion?: string;
+}
+
+/** Matches Go domain/review.Review */
+export interface Review {
+  run_id: string;
+  project_id: string;
+  summary: string;
+  approved: boolean;
+  findings: ReviewFinding[];
+  created_at: string;
+}
+
+/** Matches Go domain/review.PublishRequest */
+export interface PublishReviewRequest {
+  pr_number: number;
+  key?: string;
+  require_tests?: boolean;
+  require_ci?: boolean;
+}
+
+/** Matches Go domain/review.PublishResult */
+export interface ReviewPublishResult {
+  pr_number: number;
+  key: string;
+  created: number;
+  updated: number;
+  unchanged: number;
+  resolved: number;
+  off_diff: number;
+  summary_id: string;
+  status?: string;
+  tests_passed?: boolean;
+  lint_blocking?: number;
+  ci_state?: string;
+}
+
+/** Matches Go domain/testreport.Case */
+export interface TestCase {
+  suite?: string;
+  name: string;
+  status: "passed" | "failed" | "skipped";
+  duration_ms?: number;
+  message?: string;
+}
+
+/** Matches Go domain/testreport.Report */
+export interface TestReport {
+  run_id: string;
+  project_id: string;
+  command?: string;
+  format: "junit" | "go-json" | "go" | "pytest" | "unknown";
+  passed: boolean;
+  total: number;
+  failed: number;
+  skipped: number;
+  duration_ms?: number;
+  cases: TestCase[];
+  output?: string;
+  created_at: string;
+}
+
+export type LintTool = "golangci-lint" | "ruff" | "eslint";
+
+/** Matches Go domain/lint.Finding */
+export interface LintFinding {
+  tool: LintTool;
+  rule?: string;
+  path: string;
+  line?: number;
+  column?: number;
+  severity: ReviewSeverity;
+  message: string;
+  new: boolean;
+}
+
+/** Matches Go domain/lint.ToolResult */
+export interface LintToolResult {
+  tool: LintTool;
+  command: string;
+  findings: number;
+  error?: string;
+}
+
+/** Matches Go domain/lint.Report */
+export interface LintReport {
+  run_id: string;
+  project_id: string;
+  tools: LintToolResult[];
+  findings: LintFinding[];
+  new: number;
+  blocking: number;
+  created_at: string;
+}
+
+/** Matches Go domain/benchmark.Case */
+export interface BenchmarkCase {
+  id: string;
+  repo: string;
+  prompt: string;
+  validate: string;
+}
+
+/** Matches Go domain/benchmark.Suite */
+export interface BenchmarkSuite {
+  name: string;
+  description?: string;
+  cases: BenchmarkCase[];
+}
+
+/** Matches Go domain/benchmark.Target */
+export interface BenchmarkTarget {
+  agent_id: string;
+  model?: string;
+}
+
+export type BenchmarkStatus = "running" | "completed";
+
+export type BenchmarkResultStatus = "pending" | "passed" | "failed" | "error";
+
+/** Matches Go domain/benchmark.Result */
+export interface BenchmarkResult {
+  id: string;
+  benchmark_run_id: string;
+  case_id: string;
+  agent_id: string;
+  model?: string;
+  plan_step_id?: string;
+  run_id?: string;
+  status: BenchmarkResultStatus;
+  output?: string;
+  cost_usd: number;
+  duration_ms: number;
+  updated_at: string;
+}
+
+/** Matches Go domain/benchmark.Run */
+export interface BenchmarkRun {
+  id: string;
+  suite: string;
+  status: BenchmarkStatus;
+  targets: BenchmarkTarget[];
+  results?: BenchmarkResult[];
+  created_at: string;
+  completed_at?: string;
+}
+
+/** Matches Go domain/benchmark.StartRequest */
+export interface StartBenchmarkRequest {
+  suite: string;
+  targets: BenchmarkTarget[];
+  cases?: string[];
+}
+
+/** Matches Go domain/benchmark.LeaderboardEntry */
+export interface LeaderboardEntry {
+  agent_id: string;
+  model?: string;
+  passed: number;
+  failed: number;
+  errors: number;
+  total: number;
+  pass_rate: number;
+  cost_usd: number;
+  avg_duration_ms: number;
+}
+
+/** Matches Go domain/codegraph.Symbol */
+export interface CodeSymbol {
+  name: string;
+  kind: string;
+  path: string;
+  line: number;
+}
+
+/** Matches Go domain/codegraph.ImpactRequest */
+export interface ImpactRequest {
+  files?: string[];
+  run_id?: string;
+  max_depth?: number;
+}
+
+/** Matches Go domain/codegraph.ImpactedFile */
+export interface ImpactedFile {
+  path: string;
+  depth: number;
+  via: string;
+  test?: boolean;
+}
+
+/** Matches Go domain/codegraph.TestTarget */
+export interface TestTarget {
+  target: string;
+  language: "go" | "python" | "typescript";
+  files: string[];
+  depth: number;
+}
+
+/** Matches Go domain/codegraph.Impact */
+export interface Impact {
+  changed: string[];
+  unknown?: string[];
+  files: ImpactedFile[];
+  symbols: CodeSymbol[];
+  tests: TestTarget[];
+  truncated?: boolean;
+}
+
+/** Matches Go domain/codegraph.MapFile */
+export interface RepoMapFile {
+  path: string;
+  /** Grammar language, or the lower-cased ctags language for tagged files */
+  language: string;
+  dependents: number;
+  symbols?: CodeSymbol[];
+}
+
+/** Matches Go domain/codegraph.RepoMap */
+export interface RepoMap {
+  files: RepoMapFile[];
+  total: number;
+  truncated?: boolean;
+}
+
+/** Matches Go domain/codegraph.LanguageStats */
+export interface LanguageStats {
+  language: string;
+  source: "grammar" | "ctags";
+  files: number;
+  symbols: number;
+}
+
+/** Matches Go domain/codegraph.RepoMapStatus */
+export interface RepoMapStatus {
+  grammars: string[];
+  ctags: boolean;
+  languages: LanguageStats[];
+}
+
+/** Matches Go domain/codegraph.State */
+export interface CodeGraphState {
+  project_id: string;
+  go_module?: string;
+  files: number;
+  edges: number;
+  built_at: string;
+  updated_at: string;
+}
+
+/** Matches Go domain/codegraph.Neighbors */
+export interface GraphNeighbors {
+  path: string;
+  /** Files the path directly depends on. */
+  dependencies: string[];
+  /** Files directly depending on the path. */
+  dependents: string[];
+}
+
+/** Matches Go domain/codegraph.DependencyPath */
+export interface DependencyPath {
+  from: string;
+  to: string;
+  /** Shortest chain of dependencies, both ends included; empty if from does not depend on to. */
+  path: string[];
+}
+
+/** Matches Go domain/convention.Language */
+export interface ConventionLanguage {
+  name: string;
+  files: number;
+  indent_style?: "tab" | "space";
+  indent_size?: number;
+  line_endings?: "lf" | "crlf";
+  file_naming?: string;
+  test_pattern?: string;
+  /** Directory tests live in; absent when next to the code. */
+  test_dir?: string;
+}
+
+/** Matches Go domain/convention.CommitStyle */
+export interface CommitStyle {
+  sampled: number;
+  /** Share of Conventional Commits subjects. */
+  conventional: number;
+  types?: string[];
+  scoped: number;
+  reference?: string;
+  subject_length: number;
+}
+
+/** Matches Go domain/convention.Conventions */
+export interface ProjectConventions {
+  project_id: string;
+  languages: ConventionLanguage[];
+  editorconfig?: { glob: string; settings: Record<string, string> }[];
+  linters?: { tool: string; config: string }[];
+  commits?: CommitStyle;
+  sampled_files: number;
+  commit?: string;
+  trigger: "manual" | "schedule" | "merge";
+  extracted_at: string;
+}
+
+/** Health endpoint response */
+export interface HealthStatus {
+  status: string;
+  postgres: string;
+  nats: string;
+  litellm: string;
+}
+
+/** Provider list response */
+export interface ProviderList {
+  providers: string[];
+}
+
+/** Backend list response */
+export interface BackendList {
+  backends: string[];
+}
+
+/** Matches Go port/agentbackend.Capabilities */
+export interface BackendCapabilities {
+  edit: boolean;
+  terminal: boolean;
+  browser: boolean;
+  planner: boolean;
+  review: boolean;
+  languages?: string[];
+  max_diff_lines?: number;
+  mcp_tools: boolean;
+  cost_tier: number;
 }
@@ -2,7 +2,9 @@ import { createReconnectingWS } from "@solid-primitives/websocket";
 import { createSignal, onCleanup } from "solid-js";
 
 export interface WSMessage {
+  id?: number;
   type: string;
+  topic?: string;
   payload: Record<string, unknown>;
 }
 
@@ -62,5 +64,46 @@ export function createCodeForgeWS() {
     };
   }
 
-  return { connected, onMessage } as const;
+  // Highest event ID seen, sent as last_event_id on resubscribe so the
+  // server replays the events missed while disconnected.
+  let lastEventID = 0;
+  const unsubscribeTracking = onMessage((msg) => {
+    if (msg.id && msg.id > lastEventID) lastEventID = msg.id;
+  });
+  onCleanup(unsubscribeTracking);
+
+  /**
+   * Subscribes to topics such as `project:<id>` or `run:<id>`. After a
+   * subscription the connection only receives events of its topics. The
+   * subscription is renewed on every reconnect.
+   */
+  function subscribe(topics: string[]): () => void {
+    let currentWS: WebSocket | undefined;
+    const send = (instance: WebSocket) => {
+      instance.send(
+        JSON.stringify({ action: "subscribe", topics, last_event_id: lastEventID || undefined }),
+      );
+    };
+
+    const check = setInterval(() => {
+      const instance = ws();
+      if (instance && instance !== currentWS) {
+        currentWS = instance;
+        if (instance.readyState === WebSocket.OPEN) {
+          send(instance);
+        } else {
+          instance.addEventListener("open", () => send(instance), { once: true });
+        }
+      }
+    }, 500);
+
+    return () => {
+      clearInterval(check);
+      if (currentWS?.readyState === WebSocket.OPEN) {
+        currentWS.send(JSON.stringify({ action: "unsubscribe", topics }));
+      }
+    };
+  }
+
+  return { connected, onMessage, subscribe } as const;
 }
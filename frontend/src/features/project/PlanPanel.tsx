@@ -48,6 +48,7 @@ const STEP_STATUS_COLORS: Record<PlanStepStatus, string> = {
   failed: "bg-red-100 text-red-700",
   skipped: "bg-gray-100 text-gray-500",
   cancelled: "bg-yellow-100 text-yellow-700",
+  waiting_approval: "bg-purple-100 text-purple-700",
 };
 
 export default function PlanPanel(props: PlanPanelProps) {
@@ -189,6 +190,22 @@ export default function PlanPanel(props: PlanPanelProps) {
     }
   };
 
+  const handleResolveApproval = async (planId: string, stepId: string, approve: boolean) => {
+    try {
+      const req = { approved_by: "web-ui" };
+      if (approve) {
+        await api.plans.approveStep(planId, stepId, req);
+      } else {
+        await api.plans.rejectStep(planId, stepId, req);
+      }
+      refetch();
+      setSelectedPlanId(null);
+      setSelectedPlanId(planId);
+    } catch (e) {
+      props.onError(e instanceof Error ? e.message : "Failed to resolve approval");
+    }
+  };
+
   const taskName = (id: string) => props.tasks.find((t) => t.id === id)?.title ?? id.slice(0, 8);
   const agentName = (id: string) => props.agents.find((a) => a.id === id)?.name ?? id.slice(0, 8);
 
@@ -471,9 +488,28 @@ export default function PlanPanel(props: PlanPanelProps) {
                     >
                       {step.status}
                     </span>
-                    <span class="text-gray-700">
-                      {taskName(step.task_id)} / {agentName(step.agent_id)}
-                    </span>
+                    <Show
+                      when={step.type !== "approval"}
+                      fallback={<span class="text-gray-700">Approval gate</span>}
+                    >
+                      <span class="text-gray-700">
+                        {taskName(step.task_id)} / {agentName(step.agent_id)}
+                      </span>
+                    </Show>
+                    <Show when={step.status === "waiting_approval"}>
+                      <button
+                        class="rounded bg-green-600 px-2 py-0.5 text-xs text-white hover:bg-green-700"
+                        onClick={() => handleResolveApproval(detail().id, step.id, true)}
+                      >
+                        Approve
+                      </button>
+                      <button
+                        class="rounded bg-red-600 px-2 py-0.5 text-xs text-white hover:bg-red-700"
+                        onClick={() => handleResolveApproval(detail().id, step.id, false)}
+                      >
+                        Reject
+                      </button>
+                    </Show>
                     <Show when={step.run_id}>
                       <span class="font-mono text-xs text-gray-400">
                         run: {step.run_id.slice(0, 8)}
@@ -17,6 +17,7 @@ const STATUS_COLORS: Record<RunStatus, string> = {
   cancelled: "bg-yellow-100 text-yellow-700",
   timeout: "bg-orange-100 text-orange-700",
   quality_gate: "bg-purple-100 text-purple-700",
+  failed_downstream: "bg-red-100 text-red-700",
 };
 
 const DELIVER_MODES: { value: DeliverMode; label: string }[] = [
@@ -25,6 +26,7 @@ const DELIVER_MODES: { value: DeliverMode; label: string }[] = [
   { value: "commit-local", label: "Commit (local)" },
   { value: "branch", label: "Branch" },
   { value: "pr", label: "Pull Request" },
+  { value: "mirror", label: "Mirror + patch bundle" },
 ];
 
 export default function RunPanel(props: RunPanelProps) {
@@ -212,6 +214,9 @@ export default function RunPanel(props: RunPanelProps) {
               <Show when={run().deliver_mode}>
                 <span>Deliver: {run().deliver_mode}</span>
               </Show>
+              <Show when={run().redactions > 0}>
+                <span class="text-amber-600">Redacted: {run().redactions}</span>
+              </Show>
             </div>
 
             {/* Tool Call Activity */}
@@ -0,0 +1,43 @@
+package a2aclient
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Strob0t/CodeForge/internal/domain/a2a"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/port/agentbackend"
+)
+
+// ErrPlanStepsOnly is returned when a task is dispatched to an A2A agent
+// directly. Remote agents are driven by the orchestrator as plan steps.
+var ErrPlanStepsOnly = errors.New("a2a agents run plan steps only")
+
+// Backend registers remote A2A agents as an agent backend, so agents can
+// be created with backend "a2a". It does not execute tasks itself.
+type Backend struct{}
+
+// Register registers the A2A backend factory.
+func Register() {
+	agentbackend.Register(a2a.BackendName, func(_ map[string]string) (agentbackend.Backend, error) {
+		return Backend{}, nil
+	})
+}
+
+// Name returns "a2a".
+func (Backend) Name() string { return a2a.BackendName }
+
+// Capabilities returns no local capabilities; they depend on the remote agent.
+func (Backend) Capabilities() agentbackend.Capabilities {
+	return agentbackend.Capabilities{}
+}
+
+// Execute always fails with ErrPlanStepsOnly.
+func (Backend) Execute(_ context.Context, _ *task.Task) (*task.Result, error) {
+	return nil, ErrPlanStepsOnly
+}
+
+// Stop always fails with ErrPlanStepsOnly.
+func (Backend) Stop(_ context.Context, _ string) error {
+	return ErrPlanStepsOnly
+}
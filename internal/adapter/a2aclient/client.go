@@ -0,0 +1,301 @@
+// Package a2aclient delegates tasks to remote agents over the A2A JSON-RPC
+// transport, with or without streaming.
+package a2aclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Strob0t/CodeForge/internal/domain/a2a"
+)
+
+// Response size limits.
+const (
+	maxCardBytes     = 1 << 20
+	maxResponseBytes = 16 << 20
+)
+
+// ErrUnauthorized is returned when the remote agent rejects the credentials.
+var ErrUnauthorized = errors.New("a2a agent rejected the credentials")
+
+// CardURL returns the agent card URL of an agent configured with url: url
+// itself if it names a JSON document, otherwise the well-known card path
+// below it.
+func CardURL(url string) string {
+	if strings.HasSuffix(url, ".json") {
+		return url
+	}
+	return strings.TrimSuffix(url, "/") + a2a.CardPath
+}
+
+// FetchCard loads an agent card. token may be empty.
+func FetchCard(ctx context.Context, hc *http.Client, cardURL, token string) (*a2a.AgentCard, error) {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cardURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch agent card: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if err := checkStatus(resp); err != nil {
+		return nil, fmt.Errorf("fetch agent card: %w", err)
+	}
+	var card a2a.AgentCard
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxCardBytes)).Decode(&card); err != nil {
+		return nil, fmt.Errorf("decode agent card: %w", err)
+	}
+	if card.URL == "" {
+		return nil, errors.New("agent card has no url")
+	}
+	if card.PreferredTransport != "" && !strings.EqualFold(card.PreferredTransport, a2a.TransportJSONRPC) {
+		return nil, fmt.Errorf("agent card: unsupported transport %q", card.PreferredTransport)
+	}
+	return &card, nil
+}
+
+// Client calls the JSON-RPC endpoint of one remote agent.
+type Client struct {
+	endpoint string
+	token    string // Bearer token; empty sends no Authorization header
+	http     *http.Client
+	nextID   atomic.Int64
+}
+
+// New creates a Client for endpoint. token may be empty.
+func New(endpoint, token string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{endpoint: endpoint, token: token, http: httpClient}
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int64  `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (r *rpcResponse) err(method string) error {
+	if r.Error != nil {
+		return fmt.Errorf("%s: %s (code %d)", method, r.Error.Message, r.Error.Code)
+	}
+	return nil
+}
+
+// sendParams asks the agent for text output and not to block until the
+// task ends; the caller polls or streams instead.
+func sendParams(msg *a2a.Message) map[string]any {
+	return map[string]any{
+		"message": msg,
+		"configuration": map[string]any{
+			"acceptedOutputModes": []string{"text/plain", "application/json"},
+			"blocking":            false,
+		},
+	}
+}
+
+// Send sends a message with message/send. The result is a task, or a
+// message if the agent answered directly.
+func (c *Client) Send(ctx context.Context, msg *a2a.Message) (*a2a.Event, error) {
+	var raw json.RawMessage
+	if err := c.call(ctx, "message/send", sendParams(msg), &raw); err != nil {
+		return nil, err
+	}
+	return decodeEvent(raw)
+}
+
+// Stream sends a message with message/stream and calls fn for each event
+// until the stream ends, fn returns an error or an event is final.
+func (c *Client) Stream(ctx context.Context, msg *a2a.Message, fn func(*a2a.Event) error) error {
+	const method = "message/stream"
+	resp, err := c.post(ctx, method, sendParams(msg), "text/event-stream")
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		// Agents may answer a stream request with a single JSON response.
+		var r rpcResponse
+		if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&r); err != nil {
+			return fmt.Errorf("%s: decode response: %w", method, err)
+		}
+		if err := r.err(method); err != nil {
+			return err
+		}
+		ev, err := decodeEvent(r.Result)
+		if err != nil {
+			return fmt.Errorf("%s: %w", method, err)
+		}
+		return fn(ev)
+	}
+
+	sc := bufio.NewScanner(io.LimitReader(resp.Body, maxResponseBytes))
+	sc.Buffer(make([]byte, 64<<10), maxResponseBytes)
+	var data strings.Builder
+	for sc.Scan() {
+		line := sc.Text()
+		if rest, ok := strings.CutPrefix(line, "data:"); ok {
+			data.WriteString(strings.TrimPrefix(rest, " "))
+			data.WriteByte('\n')
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+		var r rpcResponse
+		if err := json.Unmarshal([]byte(data.String()), &r); err != nil {
+			return fmt.Errorf("%s: decode event: %w", method, err)
+		}
+		data.Reset()
+		if err := r.err(method); err != nil {
+			return err
+		}
+		ev, err := decodeEvent(r.Result)
+		if err != nil {
+			return fmt.Errorf("%s: %w", method, err)
+		}
+		if err := fn(ev); err != nil {
+			return err
+		}
+		if ev.Final {
+			return nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("%s: read event stream: %w", method, err)
+	}
+	return nil
+}
+
+// GetTask returns the current state of a task.
+func (c *Client) GetTask(ctx context.Context, id string) (*a2a.Task, error) {
+	var t a2a.Task
+	if err := c.call(ctx, "tasks/get", map[string]any{"id": id}, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// CancelTask asks the agent to cancel a task.
+func (c *Client) CancelTask(ctx context.Context, id string) error {
+	var t a2a.Task
+	return c.call(ctx, "tasks/cancel", map[string]any{"id": id}, &t)
+}
+
+func (c *Client) call(ctx context.Context, method string, params, out any) error {
+	resp, err := c.post(ctx, method, params, "application/json")
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var r rpcResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&r); err != nil {
+		return fmt.Errorf("%s: decode response: %w", method, err)
+	}
+	if err := r.err(method); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(r.Result, out); err != nil {
+		return fmt.Errorf("%s: decode result: %w", method, err)
+	}
+	return nil
+}
+
+// post sends a JSON-RPC request and returns the successful response.
+func (c *Client) post(ctx context.Context, method string, params any, accept string) (*http.Response, error) {
+	body, err := json.Marshal(&rpcRequest{JSONRPC: "2.0", ID: c.nextID.Add(1), Method: method, Params: params})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", accept)
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStatus(resp); err != nil {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return ErrUnauthorized
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+}
+
+// decodeEvent decodes a message/send result or message/stream event.
+func decodeEvent(raw json.RawMessage) (*a2a.Event, error) {
+	var ev a2a.Event
+	if err := json.Unmarshal(raw, &ev); err != nil {
+		return nil, fmt.Errorf("decode event: %w", err)
+	}
+	switch ev.Kind {
+	case "task":
+		ev.Task = &a2a.Task{}
+		if err := json.Unmarshal(raw, ev.Task); err != nil {
+			return nil, fmt.Errorf("decode task: %w", err)
+		}
+		st := ev.Task.Status.State
+		ev.TaskID, ev.Status, ev.Final = ev.Task.ID, &ev.Task.Status, st.Terminal() || st.Interrupted()
+	case "message":
+		ev.Message = &a2a.Message{}
+		if err := json.Unmarshal(raw, ev.Message); err != nil {
+			return nil, fmt.Errorf("decode message: %w", err)
+		}
+		ev.TaskID, ev.Final = ev.Message.TaskID, true
+	case "status-update":
+		if ev.Status == nil {
+			return nil, errors.New("status-update without status")
+		}
+	case "artifact-update":
+		if ev.Artifact == nil {
+			return nil, errors.New("artifact-update without artifact")
+		}
+	default:
+		return nil, fmt.Errorf("unknown event kind %q", ev.Kind)
+	}
+	return &ev, nil
+}
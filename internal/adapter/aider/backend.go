@@ -36,8 +36,11 @@ func (b *Backend) Name() string { return backendName }
 // Capabilities returns what Aider supports.
 func (b *Backend) Capabilities() agentbackend.Capabilities {
 	return agentbackend.Capabilities{
-		Edit:    true,
-		Planner: true,
+		Edit:         true,
+		Planner:      true,
+		Languages:    []string{"python", "node", "go"},
+		MaxDiffLines: 500,
+		CostTier:     1,
 	}
 }
 
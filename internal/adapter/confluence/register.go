@@ -0,0 +1,19 @@
+package confluence
+
+import (
+	"fmt"
+
+	"github.com/Strob0t/CodeForge/internal/domain/knowledge"
+	"github.com/Strob0t/CodeForge/internal/port/docsource"
+)
+
+func init() {
+	docsource.Register(string(knowledge.KindConfluence), func(config map[string]string) (docsource.Source, error) {
+		for _, key := range []string{knowledge.ConfigURL, knowledge.ConfigSpace, knowledge.ConfigToken} {
+			if config[key] == "" {
+				return nil, fmt.Errorf("confluence: %s is required", key)
+			}
+		}
+		return NewSource(config[knowledge.ConfigURL], config[knowledge.ConfigSpace], config[knowledge.ConfigUser], config[knowledge.ConfigToken]), nil
+	})
+}
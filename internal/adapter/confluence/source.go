@@ -0,0 +1,131 @@
+// Package confluence implements the docsource.Source interface against the
+// Confluence REST API, fetching the pages of one space.
+package confluence
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/knowledge"
+)
+
+// pageSize is the number of pages requested at once; Confluence caps the
+// page size when bodies are expanded.
+const pageSize = 50
+
+// Source fetches the current pages of a Confluence space.
+type Source struct {
+	baseURL    string
+	space      string
+	user       string
+	token      string
+	httpClient *http.Client
+}
+
+// NewSource creates a Source for the space at the wiki baseURL, e.g.
+// https://acme.atlassian.net/wiki. With a user the token is an API token
+// sent with basic auth (Confluence Cloud), otherwise a personal access
+// token sent as bearer token (Confluence Data Center).
+func NewSource(baseURL, space, user, token string) *Source {
+	return &Source{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		space:   space,
+		user:    user,
+		token:   token,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+type contentPage struct {
+	Results []struct {
+		Title string `json:"title"`
+		Body  struct {
+			Storage struct {
+				Value string `json:"value"`
+			} `json:"storage"`
+		} `json:"body"`
+		Links struct {
+			WebUI string `json:"webui"`
+		} `json:"_links"`
+	} `json:"results"`
+	Links struct {
+		Base string `json:"base"`
+		Next string `json:"next"`
+	} `json:"_links"`
+}
+
+// Fetch implements docsource.Source, following the pagination links of the
+// content API until limit pages are read.
+func (s *Source) Fetch(ctx context.Context, limit int) ([]knowledge.Document, error) {
+	q := url.Values{
+		"spaceKey": {s.space},
+		"type":     {"page"},
+		"status":   {"current"},
+		"expand":   {"body.storage"},
+		"limit":    {strconv.Itoa(pageSize)},
+	}
+	next := "/rest/api/content?" + q.Encode()
+	var docs []knowledge.Document
+	for next != "" && len(docs) < limit {
+		var page contentPage
+		if err := s.get(ctx, s.baseURL+next, &page); err != nil {
+			return nil, err
+		}
+		base := page.Links.Base
+		if base == "" {
+			base = s.baseURL
+		}
+		for _, r := range page.Results {
+			_, text := knowledge.HTMLText(r.Body.Storage.Value)
+			if text == "" {
+				continue
+			}
+			docs = append(docs, knowledge.Document{URL: base + r.Links.WebUI, Title: r.Title, Content: text})
+			if len(docs) == limit {
+				break
+			}
+		}
+		next = page.Links.Next
+	}
+	return docs, nil
+}
+
+func (s *Source) get(ctx context.Context, u string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("confluence: create request: %w", err)
+	}
+	if s.user != "" {
+		req.SetBasicAuth(s.user, s.token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("confluence: http request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("confluence: read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("confluence: API error %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("confluence: unmarshal response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package confluence_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	_ "github.com/Strob0t/CodeForge/internal/adapter/confluence"
+	"github.com/Strob0t/CodeForge/internal/domain/knowledge"
+	"github.com/Strob0t/CodeForge/internal/port/docsource"
+)
+
+func TestFetch_Paginates(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/wiki/rest/api/content" || r.URL.Query().Get("spaceKey") != "ENG" {
+			t.Fatalf("unexpected request %s", r.URL)
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "bot@acme.com" || pass != "tok" {
+			t.Fatal("expected basic auth with user and token")
+		}
+		if r.URL.Query().Get("start") == "" {
+			_, _ = w.Write([]byte(`{"results": [
+				{"title": "Deploy", "body": {"storage": {"value": "<h1>Deploy</h1><p>Run <code>make deploy</code>.</p>"}}, "_links": {"webui": "/spaces/ENG/pages/1/Deploy"}},
+				{"title": "Empty", "body": {"storage": {"value": "<p></p>"}}, "_links": {"webui": "/spaces/ENG/pages/2/Empty"}}
+			], "_links": {"base": "` + srv.URL + `/wiki", "next": "/rest/api/content?spaceKey=ENG&start=2"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"results": [
+			{"title": "Rollback", "body": {"storage": {"value": "<p>Revert the release.</p>"}}, "_links": {"webui": "/spaces/ENG/pages/3/Rollback"}},
+			{"title": "Oncall", "body": {"storage": {"value": "<p>Page the SRE.</p>"}}, "_links": {"webui": "/spaces/ENG/pages/4/Oncall"}}
+		], "_links": {"base": "` + srv.URL + `/wiki"}}`))
+	}))
+	defer srv.Close()
+
+	src, err := docsource.New(string(knowledge.KindConfluence), map[string]string{
+		knowledge.ConfigURL: srv.URL + "/wiki/", knowledge.ConfigSpace: "ENG",
+		knowledge.ConfigUser: "bot@acme.com", knowledge.ConfigToken: "tok",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, err := src.Fetch(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("expected the limit of 2 documents, got %+v", docs)
+	}
+	want := knowledge.Document{URL: srv.URL + "/wiki/spaces/ENG/pages/1/Deploy", Title: "Deploy", Content: "Deploy\n\nRun make deploy."}
+	if docs[0] != want || docs[1].Title != "Rollback" {
+		t.Fatalf("unexpected documents %+v", docs)
+	}
+}
+
+func TestFetch_APIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer pat" {
+			t.Fatalf("expected bearer token, got %q", r.Header.Get("Authorization"))
+		}
+		http.Error(w, "no such space", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	src, _ := docsource.New(string(knowledge.KindConfluence), map[string]string{
+		knowledge.ConfigURL: srv.URL, knowledge.ConfigSpace: "ENG", knowledge.ConfigToken: "pat",
+	})
+	if _, err := src.Fetch(context.Background(), 10); err == nil {
+		t.Fatal("expected error for missing space")
+	}
+}
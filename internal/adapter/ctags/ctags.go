@@ -0,0 +1,102 @@
+// Package ctags implements the tagger port with Universal Ctags, the
+// fallback of the code graph for languages without a grammar.
+package ctags
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/port/tagger"
+)
+
+// maxLine limits the size of one line of ctags output.
+const maxLine = 1024 * 1024
+
+// Tagger runs the ctags binary on a list of files.
+type Tagger struct {
+	binary string
+}
+
+// New returns a Tagger for the ctags binary, looked up in PATH unless it is
+// a path. It fails if the binary is missing or is not Universal Ctags, the
+// only implementation with JSON output.
+func New(binary string) (*Tagger, error) {
+	bin, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, fmt.Errorf("ctags: %w", err)
+	}
+	out, err := exec.Command(bin, "--version").Output()
+	if err != nil {
+		return nil, fmt.Errorf("ctags: %s --version: %w", bin, err)
+	}
+	if !bytes.Contains(out, []byte("Universal Ctags")) {
+		return nil, fmt.Errorf("ctags: %s is not Universal Ctags", bin)
+	}
+	return &Tagger{binary: bin}, nil
+}
+
+// Tag implements tagger.Tagger. The files are passed on stdin, so the list
+// is not limited by the command line length.
+func (t *Tagger) Tag(ctx context.Context, root string, files []string) ([]tagger.Tag, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+	cmd := exec.CommandContext(ctx, t.binary,
+		"--output-format=json", "--fields=+nl", "--extras=-F", "-L", "-", "-f", "-")
+	cmd.Dir = root
+	cmd.Stdin = strings.NewReader(strings.Join(files, "\n") + "\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ctags: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return parseTags(bytes.NewReader(out))
+}
+
+// jsonTag is a line of ctags JSON output.
+type jsonTag struct {
+	Type     string `json:"_type"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Language string `json:"language"`
+	Kind     string `json:"kind"`
+	Line     int    `json:"line"`
+	Scope    string `json:"scope"`
+}
+
+// parseTags reads ctags JSON output, keeping the tags of top-level
+// declarations.
+func parseTags(r io.Reader) ([]tagger.Tag, error) {
+	var tags []tagger.Tag
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxLine)
+	for sc.Scan() {
+		var jt jsonTag
+		if err := json.Unmarshal(sc.Bytes(), &jt); err != nil {
+			return nil, fmt.Errorf("ctags: parse output: %w", err)
+		}
+		if jt.Type != "tag" || jt.Scope != "" || jt.Name == "" {
+			continue
+		}
+		tags = append(tags, tagger.Tag{
+			Path:     path.Clean(filepath.ToSlash(jt.Path)),
+			Language: jt.Language,
+			Name:     jt.Name,
+			Kind:     jt.Kind,
+			Line:     jt.Line,
+		})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("ctags: read output: %w", err)
+	}
+	return tags, nil
+}
@@ -0,0 +1,36 @@
+package ctags
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/port/tagger"
+)
+
+func TestParseTags(t *testing.T) {
+	out := `{"_type": "ptag", "name": "JSON_OUTPUT_VERSION", "path": "0.0"}
+{"_type": "tag", "name": "Billing", "path": "./app/billing.rb", "pattern": "/^module Billing$/", "line": 1, "language": "Ruby", "kind": "module"}
+{"_type": "tag", "name": "charge", "path": "./app/billing.rb", "pattern": "/^  def charge$/", "line": 2, "language": "Ruby", "kind": "method", "scope": "Billing", "scopeKind": "module"}
+{"_type": "tag", "name": "deploy", "path": "scripts/deploy.sh", "pattern": "/^deploy() {$/", "line": 4, "language": "Sh", "kind": "function"}
+`
+	tags, err := parseTags(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tagger.Tag{
+		{Path: "app/billing.rb", Language: "Ruby", Name: "Billing", Kind: "module", Line: 1},
+		{Path: "scripts/deploy.sh", Language: "Sh", Name: "deploy", Kind: "function", Line: 4},
+	}
+	if len(tags) != len(want) {
+		t.Fatalf("got %+v, want %+v", tags, want)
+	}
+	for i := range want {
+		if tags[i] != want[i] {
+			t.Fatalf("got %+v, want %+v", tags, want)
+		}
+	}
+
+	if _, err := parseTags(strings.NewReader("not json\n")); err == nil {
+		t.Fatal("expected error for malformed output")
+	}
+}
@@ -0,0 +1,278 @@
+// Package docker implements the sandbox.Driver interface with the docker CLI.
+// Each sandbox is a detached container with cgroup limits and the run's
+// workspace bind-mounted at sandbox.WorkDir.
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/port/sandbox"
+)
+
+const driverName = "docker"
+
+// Config configures the Docker driver.
+type Config struct {
+	Binary string // docker CLI binary (default: "docker")
+	// StorageOpt enforces the storage limit with --storage-opt size, which
+	// needs a storage driver that supports it. False leaves storage unlimited.
+	StorageOpt bool
+	// Runtimes maps isolation levels stronger than a plain container to OCI
+	// runtimes, e.g. gVisor's runsc or Kata's Firecracker runtime.
+	Runtimes map[policy.Isolation]string
+	// EgressNetwork is the network egress proxy sidecars reach the outside
+	// through. Empty uses Docker's default bridge.
+	EgressNetwork string
+}
+
+// DefaultRuntimes are the OCI runtimes used for isolation levels without a
+// configured runtime.
+var DefaultRuntimes = map[policy.Isolation]string{
+	policy.IsolationGVisor:  "runsc",
+	policy.IsolationMicroVM: "kata-fc",
+}
+
+// Driver launches sandboxes as local Docker containers.
+type Driver struct {
+	cfg     Config
+	proxies sync.Map // map[containerID]string (egress proxy container)
+}
+
+// NewDriver creates a Driver with cfg.
+func NewDriver(cfg Config) *Driver {
+	if cfg.Binary == "" {
+		cfg.Binary = "docker"
+	}
+	runtimes := make(map[policy.Isolation]string, len(DefaultRuntimes))
+	for k, v := range DefaultRuntimes {
+		runtimes[k] = v
+	}
+	for k, v := range cfg.Runtimes {
+		runtimes[k] = v
+	}
+	cfg.Runtimes = runtimes
+	return &Driver{cfg: cfg}
+}
+
+// Name returns "docker".
+func (d *Driver) Name() string { return driverName }
+
+// Start runs the sandbox container detached and returns its container ID.
+//
+// With an egress policy, the sandbox's HTTP(S) traffic goes through a proxy
+// container that is attached to the sandbox network and the egress network.
+// The sandbox network must not route to the outside itself (e.g. a network
+// created with --internal), otherwise the proxy can be bypassed.
+func (d *Driver) Start(ctx context.Context, spec *sandbox.Spec) (string, error) {
+	if spec.Workspace.HostPath == "" {
+		return "", fmt.Errorf("docker: run %s has no host workspace to mount", spec.RunID)
+	}
+	env := spec.Env
+	if spec.Egress != nil {
+		if spec.NetworkMode == "" {
+			return "", fmt.Errorf("docker: run %s has an egress policy but no sandbox network", spec.RunID)
+		}
+		env = make(map[string]string, len(spec.Env)+6)
+		for k, v := range spec.Env {
+			env[k] = v
+		}
+		for k, v := range spec.ProxyEnv(proxyName(spec.RunID)) {
+			env[k] = v
+		}
+	}
+	args, err := d.runArgs(spec, env)
+	if err != nil {
+		return "", err
+	}
+	if spec.Egress != nil {
+		if err := d.startProxy(ctx, spec); err != nil {
+			return "", err
+		}
+	}
+	id, err := d.run(ctx, args, env)
+	if err != nil {
+		if spec.Egress != nil {
+			_ = d.rm(context.WithoutCancel(ctx), proxyName(spec.RunID))
+		}
+		return "", fmt.Errorf("docker: run: %w", err)
+	}
+	if spec.Egress != nil {
+		d.proxies.Store(id, proxyName(spec.RunID))
+	}
+	return id, nil
+}
+
+// startProxy starts the egress proxy of a sandbox on the egress network and
+// attaches it to the sandbox network, where it is reachable by its name.
+func (d *Driver) startProxy(ctx context.Context, spec *sandbox.Spec) error {
+	name := proxyName(spec.RunID)
+	env := spec.SidecarEnv()
+	args := []string{"run", "-d",
+		"--name", name,
+		"--label", "codeforge.run-id=" + spec.RunID,
+		"--label", "codeforge.project-id=" + spec.ProjectID,
+		"--label", "codeforge.role=egress-proxy",
+		"--memory", "128m",
+	}
+	if d.cfg.EgressNetwork != "" {
+		args = append(args, "--network", d.cfg.EgressNetwork)
+	}
+	args = append(append(args, envArgs(env)...), spec.Egress.Image)
+	if _, err := d.run(ctx, args, env); err != nil {
+		return fmt.Errorf("docker: run egress proxy: %w", err)
+	}
+	out, err := exec.CommandContext(ctx, d.cfg.Binary, "network", "connect", spec.NetworkMode, name).CombinedOutput()
+	if err != nil {
+		_ = d.rm(context.WithoutCancel(ctx), name)
+		return fmt.Errorf("docker: connect egress proxy: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
+
+// run executes a detached docker run and returns the container ID. Env
+// values are passed through the client's environment so that secrets do
+// not show up in the process list.
+func (d *Driver) run(ctx context.Context, args []string, env map[string]string) (string, error) {
+	cmd := exec.CommandContext(ctx, d.cfg.Binary, args...)
+	cmd.Env = os.Environ()
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %w", strings.TrimSpace(stderr.String()), err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// runArgs returns the docker run arguments of a sandbox with env.
+func (d *Driver) runArgs(spec *sandbox.Spec, env map[string]string) ([]string, error) {
+	args := []string{"run", "-d",
+		"--name", "codeforge-run-" + spec.RunID,
+		"--label", "codeforge.run-id=" + spec.RunID,
+		"--label", "codeforge.project-id=" + spec.ProjectID,
+		"-v", spec.Workspace.HostPath + ":" + sandbox.WorkDir,
+		"-w", sandbox.WorkDir,
+	}
+	l := spec.Limits
+	if l.MemoryMB > 0 {
+		args = append(args, "--memory", strconv.Itoa(l.MemoryMB)+"m", "--memory-swap", strconv.Itoa(l.MemoryMB)+"m")
+	}
+	if l.CPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(l.CPUs, 'f', -1, 64))
+	}
+	if l.PIDs > 0 {
+		args = append(args, "--pids-limit", strconv.Itoa(l.PIDs))
+	}
+	if l.StorageMB > 0 && d.cfg.StorageOpt {
+		args = append(args, "--storage-opt", "size="+strconv.Itoa(l.StorageMB)+"m")
+	}
+	if policy.IsolationStrength(spec.Isolation) > 0 {
+		rt := d.cfg.Runtimes[spec.Isolation]
+		if rt == "" {
+			return nil, fmt.Errorf("docker: no runtime configured for %s isolation", spec.Isolation)
+		}
+		args = append(args, "--runtime", rt)
+	}
+	if spec.NetworkMode != "" {
+		args = append(args, "--network", spec.NetworkMode)
+	}
+	if len(spec.Command) > 0 {
+		args = append(args, "--entrypoint", spec.Command[0])
+	}
+	args = append(append(args, envArgs(env)...), spec.Image)
+	if len(spec.Command) > 1 {
+		args = append(args, spec.Command[1:]...)
+	}
+	return args, nil
+}
+
+// envArgs returns "-e KEY" arguments for env, sorted by key.
+func envArgs(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	args := make([]string, 0, 2*len(keys))
+	for _, k := range keys {
+		args = append(args, "-e", k)
+	}
+	return args
+}
+
+// proxyName returns the container name of a run's egress proxy.
+func proxyName(runID string) string {
+	return "codeforge-egress-" + runID
+}
+
+// Logs follows the container output until the container exits.
+func (d *Driver) Logs(ctx context.Context, id string, fn func(sandbox.Line)) error {
+	cmd := exec.CommandContext(ctx, d.cfg.Binary, "logs", "-f", id)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("docker: logs: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("docker: logs: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("docker: logs: %w", err)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	scan := func(r io.Reader, stream string) {
+		defer wg.Done()
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 64*1024), 1024*1024)
+		for sc.Scan() {
+			mu.Lock()
+			fn(sandbox.Line{Stream: stream, Text: sc.Text()})
+			mu.Unlock()
+		}
+	}
+	wg.Add(2)
+	go scan(stdout, "stdout")
+	go scan(stderr, "stderr")
+	wg.Wait()
+
+	if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("docker: logs: %w", err)
+	}
+	return nil
+}
+
+// Remove force-removes the container and its egress proxy.
+func (d *Driver) Remove(ctx context.Context, id string) error {
+	if err := d.rm(ctx, id); err != nil {
+		return err
+	}
+	if proxy, ok := d.proxies.LoadAndDelete(id); ok {
+		return d.rm(ctx, proxy.(string))
+	}
+	return nil
+}
+
+// rm force-removes a container; removing an unknown container succeeds.
+func (d *Driver) rm(ctx context.Context, id string) error {
+	out, err := exec.CommandContext(ctx, d.cfg.Binary, "rm", "-f", "-v", id).CombinedOutput()
+	if err != nil && !strings.Contains(string(out), "No such container") {
+		return fmt.Errorf("docker: rm: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package docker_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/docker"
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/port/sandbox"
+)
+
+// fakeDocker writes a docker stand-in that records its arguments and the
+// secret env var it was given, and prints canned output.
+func fakeDocker(t *testing.T) (binary, argsFile string) {
+	t.Helper()
+	dir := t.TempDir()
+	argsFile = filepath.Join(dir, "args")
+	script := `#!/bin/sh
+echo "$@" >> ` + argsFile + `
+echo "env:$API_TOKEN" >> ` + argsFile + `
+case "$1" in
+run) echo "c0ffee" ;;
+logs) echo "worker started"; echo "boom" >&2 ;;
+rm) if [ "$4" = "gone" ]; then echo "Error: No such container: gone" >&2; exit 1; fi ;;
+esac
+`
+	binary = filepath.Join(dir, "docker")
+	if err := os.WriteFile(binary, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return binary, argsFile
+}
+
+func TestDriver(t *testing.T) {
+	binary, argsFile := fakeDocker(t)
+	d := docker.NewDriver(docker.Config{Binary: binary, StorageOpt: true})
+	ctx := context.Background()
+
+	spec := &sandbox.Spec{
+		RunID:       "run-1",
+		ProjectID:   "proj-1",
+		Image:       "codeforge-worker:latest",
+		Env:         map[string]string{"API_TOKEN": "s3cret"},
+		Workspace:   sandbox.Workspace{HostPath: "/data/ws/proj-1"},
+		Limits:      sandbox.Limits{MemoryMB: 512, CPUs: 1.5, PIDs: 128, StorageMB: 1024},
+		NetworkMode: "codeforge",
+		Isolation:   policy.IsolationGVisor,
+	}
+	id, err := d.Start(ctx, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "c0ffee" {
+		t.Fatalf("expected container ID, got %q", id)
+	}
+
+	var lines []sandbox.Line
+	if err := d.Logs(ctx, id, func(l sandbox.Line) { lines = append(lines, l) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines, got %+v", lines)
+	}
+	for _, l := range lines {
+		if (l.Stream == "stdout") != (l.Text == "worker started") {
+			t.Fatalf("line on wrong stream: %+v", l)
+		}
+	}
+
+	if err := d.Remove(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Remove(ctx, "gone"); err != nil {
+		t.Fatalf("removing an unknown container should succeed, got %v", err)
+	}
+
+	data, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	run, runEnv := calls[0], calls[1]
+	for _, want := range []string{
+		"--name codeforge-run-run-1", "--memory 512m", "--cpus 1.5", "--pids-limit 128",
+		"--storage-opt size=1024m", "--runtime runsc", "--network codeforge", "-v /data/ws/proj-1:/workspace", "-e API_TOKEN codeforge-worker:latest",
+	} {
+		if !strings.Contains(run, want) {
+			t.Errorf("run args %q missing %q", run, want)
+		}
+	}
+	if strings.Contains(run, "s3cret") || runEnv != "env:s3cret" {
+		t.Fatalf("secret should be passed via the environment, got %q / %q", run, runEnv)
+	}
+	if calls[4] != "rm -f -v c0ffee" {
+		t.Fatalf("unexpected rm call %q", calls[4])
+	}
+}
+
+func TestDriverRequiresWorkspace(t *testing.T) {
+	binary, _ := fakeDocker(t)
+	if _, err := docker.NewDriver(docker.Config{Binary: binary}).Start(context.Background(), &sandbox.Spec{RunID: "run-1", Image: "img"}); err == nil {
+		t.Fatal("expected error without a host workspace")
+	}
+}
+
+func TestDriverCommand(t *testing.T) {
+	binary, argsFile := fakeDocker(t)
+	spec := &sandbox.Spec{RunID: "run-1", Image: "img", Workspace: sandbox.Workspace{HostPath: "/ws"}, Command: []string{"/bin/sh", "-c", "make"}}
+	if _, err := docker.NewDriver(docker.Config{Binary: binary}).Start(context.Background(), spec); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(argsFile)
+	run := strings.Split(string(data), "\n")[0]
+	if !strings.Contains(run, "--entrypoint /bin/sh") || !strings.HasSuffix(run, " img -c make") {
+		t.Fatalf("expected the command to replace the entrypoint, got %q", run)
+	}
+}
+
+func TestDriverIsolationRuntime(t *testing.T) {
+	binary, argsFile := fakeDocker(t)
+	spec := &sandbox.Spec{RunID: "run-1", Image: "img", Workspace: sandbox.Workspace{HostPath: "/ws"}, Isolation: policy.IsolationMicroVM}
+
+	d := docker.NewDriver(docker.Config{Binary: binary, Runtimes: map[policy.Isolation]string{policy.IsolationMicroVM: "kata-qemu"}})
+	if _, err := d.Start(context.Background(), spec); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(argsFile)
+	if !strings.Contains(string(data), "--runtime kata-qemu") {
+		t.Fatalf("expected configured runtime, got %s", data)
+	}
+
+	// A level whose runtime is unset must not fall back to a plain container.
+	d = docker.NewDriver(docker.Config{Binary: binary, Runtimes: map[policy.Isolation]string{policy.IsolationMicroVM: ""}})
+	if _, err := d.Start(context.Background(), spec); err == nil {
+		t.Fatal("expected error for an isolation level without runtime")
+	}
+}
+
+func TestDriverEgressProxy(t *testing.T) {
+	binary, argsFile := fakeDocker(t)
+	d := docker.NewDriver(docker.Config{Binary: binary, EgressNetwork: "egress"})
+	ctx := context.Background()
+	spec := &sandbox.Spec{
+		RunID:     "run-1",
+		ProjectID: "proj-1",
+		Image:     "img",
+		Workspace: sandbox.Workspace{HostPath: "/ws"},
+		Egress: &sandbox.Egress{
+			Policy:  policy.EgressPolicy{AllowPackageRegistries: true},
+			Image:   "codeforge-egress:latest",
+			NoProxy: []string{"nats", "litellm"},
+		},
+	}
+	if _, err := d.Start(ctx, spec); err == nil {
+		t.Fatal("expected error without a sandbox network")
+	}
+
+	spec.NetworkMode = "sandbox"
+	id, err := d.Start(ctx, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Remove(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(argsFile)
+	var calls []string
+	for _, c := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if !strings.HasPrefix(c, "env:") {
+			calls = append(calls, c)
+		}
+	}
+	if len(calls) != 5 {
+		t.Fatalf("expected proxy run, network connect, run and two rm calls, got %q", calls)
+	}
+	for i, want := range []string{
+		"--name codeforge-egress-run-1",
+		"network connect sandbox codeforge-egress-run-1",
+		"-e HTTPS_PROXY -e HTTP_PROXY -e NO_PROXY",
+		"rm -f -v c0ffee",
+		"rm -f -v codeforge-egress-run-1",
+	} {
+		if !strings.Contains(calls[i], want) {
+			t.Errorf("call %d %q missing %q", i, calls[i], want)
+		}
+	}
+	if !strings.Contains(calls[0], "--network egress -e CODEFORGE_EGRESS_POLICY -e CODEFORGE_PROJECT_ID -e CODEFORGE_RUN_ID codeforge-egress:latest") {
+		t.Errorf("unexpected proxy run %q", calls[0])
+	}
+}
@@ -0,0 +1,30 @@
+package docker
+
+import (
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/port/sandbox"
+)
+
+func init() {
+	sandbox.Register(driverName, func(config map[string]string) (sandbox.Driver, error) {
+		return NewDriver(Config{
+			Binary:        config["binary"],
+			StorageOpt:    config["storage_opt"] == "true",
+			Runtimes:      runtimes(config),
+			EgressNetwork: config["egress_network"],
+		}), nil
+	})
+}
+
+// runtimes returns the "runtime_<isolation>" entries of a driver config.
+func runtimes(config map[string]string) map[policy.Isolation]string {
+	out := make(map[policy.Isolation]string)
+	for k, v := range config {
+		if level, ok := strings.CutPrefix(k, "runtime_"); ok && v != "" {
+			out[policy.Isolation(level)] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,218 @@
+// Package egress implements the HTTP/HTTPS forward proxy that runs as a
+// sidecar next to a sandbox and enforces the run's egress policy.
+//
+// Plain HTTP requests are forwarded and HTTPS is tunneled with CONNECT. A
+// destination is allowed if its host name is in the policy's domains, or if
+// all its addresses lie in the policy's CIDRs. Connections go to the address
+// that was checked, so DNS answers cannot change after the check.
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+)
+
+// Blocked describes a connection attempt denied by the egress policy.
+type Blocked struct {
+	Host   string `json:"host"`
+	Port   string `json:"port"`
+	Method string `json:"method"`
+	Reason string `json:"reason"`
+}
+
+// errBlocked marks a dial denied by the egress policy.
+type errBlocked struct {
+	reason string
+}
+
+func (e *errBlocked) Error() string { return "egress blocked: " + e.reason }
+
+// hopHeaders are removed from forwarded requests and responses.
+var hopHeaders = []string{
+	"Connection", "Proxy-Connection", "Keep-Alive", "Proxy-Authenticate",
+	"Proxy-Authorization", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// Proxy is an http.Handler that forwards requests allowed by an egress
+// policy and reports the others.
+type Proxy struct {
+	policy    *policy.EgressPolicy
+	onBlocked func(Blocked)
+	resolver  *net.Resolver
+	dialer    *net.Dialer
+	transport *http.Transport
+}
+
+// New creates a Proxy enforcing p. onBlocked, if set, is called for every
+// denied connection attempt.
+func New(p *policy.EgressPolicy, onBlocked func(Blocked)) *Proxy {
+	px := &Proxy{
+		policy:    p,
+		onBlocked: onBlocked,
+		resolver:  net.DefaultResolver,
+		dialer:    &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+	}
+	px.transport = &http.Transport{
+		DialContext:           px.dial,
+		MaxIdleConns:          16,
+		IdleConnTimeout:       90 * time.Second,
+		ResponseHeaderTimeout: 2 * time.Minute,
+	}
+	return px
+}
+
+// ServeHTTP handles CONNECT tunnels and absolute-URI HTTP requests.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+	if !r.URL.IsAbs() || r.URL.Scheme != "http" {
+		http.Error(w, "egress proxy: absolute http URL required", http.StatusBadRequest)
+		return
+	}
+
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	for _, h := range hopHeaders {
+		out.Header.Del(h)
+	}
+	resp, err := p.transport.RoundTrip(out)
+	if err != nil {
+		p.fail(w, r, r.URL.Host, err)
+		return
+	}
+	defer resp.Body.Close()
+	for _, h := range hopHeaders {
+		resp.Header.Del(h)
+	}
+	for k, vs := range resp.Header {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+// tunnel connects to the CONNECT target and relays bytes both ways.
+func (p *Proxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	upstream, err := p.dial(r.Context(), "tcp", r.Host)
+	if err != nil {
+		p.fail(w, r, r.Host, err)
+		return
+	}
+	defer upstream.Close()
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "egress proxy: hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buf, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	defer client.Close()
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		// Bytes the client sent after the CONNECT request are buffered.
+		_, _ = io.Copy(upstream, io.MultiReader(buf.Reader, client))
+		closeWrite(upstream)
+	}()
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(client, upstream)
+		closeWrite(client)
+	}()
+	wg.Wait()
+}
+
+// fail answers a request whose upstream connection failed, reporting it if
+// the policy denied it.
+func (p *Proxy) fail(w http.ResponseWriter, r *http.Request, hostport string, err error) {
+	var blocked *errBlocked
+	if !errors.As(err, &blocked) {
+		http.Error(w, "egress proxy: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	host, port := splitHostPort(hostport, r.URL.Scheme)
+	if p.onBlocked != nil {
+		p.onBlocked(Blocked{Host: host, Port: port, Method: r.Method, Reason: blocked.reason})
+	}
+	http.Error(w, "egress proxy: "+blocked.Error(), http.StatusForbidden)
+}
+
+// dial connects to addr if the egress policy allows it.
+func (p *Proxy) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	target, err := p.check(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	return p.dialer.DialContext(ctx, network, net.JoinHostPort(target.String(), port))
+}
+
+// check returns the address to connect to for host, or an *errBlocked.
+func (p *Proxy) check(ctx context.Context, host string) (netip.Addr, error) {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if !p.policy.AllowsAddr(ip) {
+			return netip.Addr{}, &errBlocked{reason: fmt.Sprintf("address %s not in egress allowlist", ip)}
+		}
+		return ip, nil
+	}
+	addrs, err := p.resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("resolve %s: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return netip.Addr{}, fmt.Errorf("resolve %s: no addresses", host)
+	}
+	if p.policy.AllowsDomain(host) {
+		return addrs[0], nil
+	}
+	for _, a := range addrs {
+		if !p.policy.AllowsAddr(a) {
+			return netip.Addr{}, &errBlocked{reason: fmt.Sprintf("host %s not in egress allowlist", host)}
+		}
+	}
+	return addrs[0], nil
+}
+
+// splitHostPort splits hostport, defaulting the port from the scheme.
+func splitHostPort(hostport, scheme string) (host, port string) {
+	host, port, err := net.SplitHostPort(hostport)
+	if err == nil {
+		return host, port
+	}
+	if strings.EqualFold(scheme, "http") {
+		return hostport, "80"
+	}
+	return hostport, "443"
+}
+
+// closeWrite half-closes c if it supports it, so the peer sees EOF.
+func closeWrite(c net.Conn) {
+	if cw, ok := c.(interface{ CloseWrite() error }); ok {
+		_ = cw.CloseWrite()
+	}
+}
@@ -0,0 +1,118 @@
+package egress_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/egress"
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+)
+
+func newProxy(t *testing.T, p *policy.EgressPolicy) (*http.Client, *httptest.Server, func() []egress.Blocked) {
+	t.Helper()
+	var mu sync.Mutex
+	var blocked []egress.Blocked
+	proxy := httptest.NewServer(egress.New(p, func(b egress.Blocked) {
+		mu.Lock()
+		defer mu.Unlock()
+		blocked = append(blocked, b)
+	}))
+	t.Cleanup(proxy.Close)
+	proxyURL, _ := url.Parse(proxy.URL)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	return client, proxy, func() []egress.Blocked {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]egress.Blocked(nil), blocked...)
+	}
+}
+
+func newBackend(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello from " + r.URL.Path))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestProxyHTTP(t *testing.T) {
+	backend := newBackend(t)
+	client, _, blocked := newProxy(t, &policy.EgressPolicy{AllowCIDRs: []string{"127.0.0.0/8"}})
+
+	resp, err := client.Get(backend.URL + "/pkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "hello from /pkg" {
+		t.Fatalf("unexpected response %d %q", resp.StatusCode, body)
+	}
+
+	client, _, blocked = newProxy(t, &policy.EgressPolicy{AllowDomains: []string{"example.com"}})
+	resp, err = client.Get(backend.URL + "/pkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", resp.StatusCode)
+	}
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(backend.URL, "http://"))
+	if b := blocked(); len(b) != 1 || b[0].Host != "127.0.0.1" || b[0].Port != port || b[0].Method != http.MethodGet {
+		t.Fatalf("unexpected blocked attempts %+v", b)
+	}
+}
+
+func TestProxyConnect(t *testing.T) {
+	backend := newBackend(t)
+	target := strings.TrimPrefix(backend.URL, "http://")
+	_, port, _ := net.SplitHostPort(target)
+
+	connect := func(proxy *httptest.Server, host string) (*http.Response, *bufio.Reader, net.Conn) {
+		t.Helper()
+		conn, err := net.Dial("tcp", strings.TrimPrefix(proxy.URL, "http://"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		_, _ = conn.Write([]byte("CONNECT " + host + " HTTP/1.1\r\nHost: " + host + "\r\n\r\n"))
+		br := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, br, conn
+	}
+
+	_, proxy, blocked := newProxy(t, &policy.EgressPolicy{AllowDomains: []string{"localhost"}})
+	resp, br, conn := connect(proxy, net.JoinHostPort("localhost", port))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected tunnel, got %d", resp.StatusCode)
+	}
+	_, _ = conn.Write([]byte("GET /tunnel HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+	tunneled, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(tunneled.Body)
+	if string(body) != "hello from /tunnel" {
+		t.Fatalf("unexpected tunneled body %q", body)
+	}
+
+	resp, _, _ = connect(proxy, target)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for an address outside the allowlist, got %d", resp.StatusCode)
+	}
+	if b := blocked(); len(b) != 1 || b[0].Method != http.MethodConnect || !strings.Contains(b[0].Reason, "127.0.0.1") {
+		t.Fatalf("unexpected blocked attempts %+v", b)
+	}
+}
@@ -0,0 +1,101 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/ci"
+)
+
+// actionsProviderName is the name GitHub Actions registers as a CI
+// provider under.
+const actionsProviderName = "github-actions"
+
+// Actions reads the workflow runs of a repository's commits from GitHub
+// Actions. It implements ciprovider.Provider.
+type Actions struct {
+	p *Provider
+}
+
+// NewActions creates an Actions for the repository of p.
+func NewActions(p *Provider) *Actions {
+	return &Actions{p: p}
+}
+
+// Name returns "github-actions".
+func (a *Actions) Name() string { return actionsProviderName }
+
+// workflowRun is the part of a workflow run we use.
+type workflowRun struct {
+	ID           int64      `json:"id"`
+	Name         string     `json:"name"`
+	HeadBranch   string     `json:"head_branch"`
+	HeadSHA      string     `json:"head_sha"`
+	Status       string     `json:"status"`
+	Conclusion   string     `json:"conclusion"`
+	HTMLURL      string     `json:"html_url"`
+	RunStartedAt *time.Time `json:"run_started_at"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+}
+
+// ListPipelines returns the workflow runs of a commit on a branch.
+func (a *Actions) ListPipelines(ctx context.Context, branch, sha string) ([]ci.Pipeline, error) {
+	query := url.Values{"per_page": {strconv.Itoa(perPage)}}
+	if branch != "" {
+		query.Set("branch", branch)
+	}
+	if sha != "" {
+		query.Set("head_sha", sha)
+	}
+	var resp struct {
+		WorkflowRuns []workflowRun `json:"workflow_runs"`
+	}
+	path := fmt.Sprintf("/repos/%s/actions/runs?%s", a.p.repo, query.Encode())
+	if _, err := a.p.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, fmt.Errorf("github: list workflow runs: %w", err)
+	}
+	out := make([]ci.Pipeline, 0, len(resp.WorkflowRuns))
+	for i := range resp.WorkflowRuns {
+		wr := &resp.WorkflowRuns[i]
+		pl := ci.Pipeline{
+			ID:        strconv.FormatInt(wr.ID, 10),
+			Name:      wr.Name,
+			Branch:    wr.HeadBranch,
+			SHA:       wr.HeadSHA,
+			State:     workflowState(wr.Status, wr.Conclusion),
+			URL:       wr.HTMLURL,
+			StartedAt: wr.RunStartedAt,
+		}
+		if pl.State.Done() {
+			pl.FinishedAt = wr.UpdatedAt
+		}
+		out = append(out, pl)
+	}
+	return out, nil
+}
+
+// workflowState maps the status and conclusion of a workflow run to a CI
+// state. Neutral runs pass; runs that timed out, need action or never
+// started fail.
+func workflowState(status, conclusion string) ci.State {
+	switch status {
+	case "completed":
+	case "in_progress":
+		return ci.StateRunning
+	default: // queued, requested, waiting, pending
+		return ci.StatePending
+	}
+	switch conclusion {
+	case "success", "neutral":
+		return ci.StateSuccess
+	case "skipped":
+		return ci.StateSkipped
+	case "cancelled":
+		return ci.StateCancelled
+	}
+	return ci.StateFailure
+}
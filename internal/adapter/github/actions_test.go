@@ -0,0 +1,49 @@
+package github_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/ci"
+	"github.com/Strob0t/CodeForge/internal/port/ciprovider"
+)
+
+func TestActionsListPipelines(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/webapp/actions/runs" || r.URL.Query().Get("head_sha") != "abc123" || r.URL.Query().Get("branch") != "codeforge/run-1" {
+			t.Fatalf("unexpected request %s?%s", r.URL.Path, r.URL.RawQuery)
+		}
+		_, _ = w.Write([]byte(`{"total_count": 3, "workflow_runs": [
+			{"id": 11, "name": "test", "head_branch": "codeforge/run-1", "head_sha": "abc123", "status": "completed", "conclusion": "success",
+			 "html_url": "https://github.com/acme/webapp/actions/runs/11", "run_started_at": "2026-10-17T10:00:00Z", "updated_at": "2026-10-17T10:05:00Z"},
+			{"id": 12, "name": "lint", "head_branch": "codeforge/run-1", "head_sha": "abc123", "status": "completed", "conclusion": "neutral"},
+			{"id": 13, "name": "e2e", "head_branch": "codeforge/run-1", "head_sha": "abc123", "status": "queued"}]}`))
+	}))
+	defer srv.Close()
+
+	prov, err := ciprovider.New("github-actions", map[string]string{"github_repo": "acme/webapp", "github_api_url": srv.URL, "token": "ghp_test"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	pipelines, err := prov.ListPipelines(context.Background(), "codeforge/run-1", "abc123")
+	if err != nil {
+		t.Fatalf("ListPipelines: %v", err)
+	}
+	if len(pipelines) != 3 {
+		t.Fatalf("expected 3 workflow runs, got %+v", pipelines)
+	}
+	if p := pipelines[0]; p.ID != "11" || p.Name != "test" || p.State != ci.StateSuccess || p.StartedAt == nil || p.FinishedAt == nil {
+		t.Errorf("unexpected workflow run %+v", p)
+	}
+	if pipelines[1].State != ci.StateSuccess || pipelines[2].State != ci.StatePending {
+		t.Errorf("unexpected states %q, %q", pipelines[1].State, pipelines[2].State)
+	}
+	if got := ci.Overall(pipelines); got != ci.StatePending {
+		t.Errorf("Overall = %q, want pending", got)
+	}
+	if _, err := ciprovider.New("github-actions", map[string]string{"github_repo": "acme"}); err == nil {
+		t.Error("expected an invalid repository to fail")
+	}
+}
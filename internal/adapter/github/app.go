@@ -0,0 +1,201 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+// appJWTLifetime is the lifetime of the JWTs an app signs; GitHub accepts
+// at most 10 minutes.
+const appJWTLifetime = 9 * time.Minute
+
+// App authenticates as a GitHub App and mints access tokens for its
+// installations.
+type App struct {
+	apiURL     string
+	id         int64
+	key        *rsa.PrivateKey
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewApp creates an App from its ID and PEM-encoded private key (PKCS#1 as
+// downloaded from GitHub, or PKCS#8). apiURL may be empty for github.com.
+func NewApp(apiURL string, id int64, privateKeyPEM []byte) (*App, error) {
+	if id <= 0 {
+		return nil, errors.New("github: app id must be positive")
+	}
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, errors.New("github: app private key is not PEM-encoded")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, err8 := x509.ParsePKCS8PrivateKey(block.Bytes)
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if err8 != nil || !ok {
+			return nil, fmt.Errorf("github: app private key must be an RSA key: %w", err)
+		}
+		key = rsaKey
+	}
+	if apiURL == "" {
+		apiURL = defaultAPIURL
+	}
+	return &App{
+		apiURL:     strings.TrimRight(apiURL, "/"),
+		id:         id,
+		key:        key,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		now:        time.Now,
+	}, nil
+}
+
+// InstallationToken is a short-lived token acting as an installation of
+// an app, usable like a personal access token for the API and git.
+type InstallationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// InstallationToken mints an access token for an installation of the app.
+func (a *App) InstallationToken(ctx context.Context, installationID int64) (*InstallationToken, error) {
+	jwt, err := a.jwt()
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", a.apiURL, installationID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("github: create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("github: installation token: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("github: read installation token: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("github: installation token for %d: API error %d: %s", installationID, resp.StatusCode, string(data))
+	}
+	var tok InstallationToken
+	if err := json.Unmarshal(data, &tok); err != nil {
+		return nil, fmt.Errorf("github: decode installation token: %w", err)
+	}
+	if tok.Token == "" {
+		return nil, errors.New("github: no installation token in response")
+	}
+	return &tok, nil
+}
+
+// jwt signs the RS256 JWT the app authenticates with. It is backdated a
+// minute against clock drift.
+func (a *App) jwt() (string, error) {
+	now := a.now()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": strconv.FormatInt(a.id, 10),
+	})
+	if err != nil {
+		return "", fmt.Errorf("github: marshal jwt claims: %w", err)
+	}
+	signed := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", fmt.Errorf("github: sign jwt: %w", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// InstallationEvent is a verified change to the repositories an app
+// installation can access. Installing the app adds all of its
+// repositories; uninstalling it removes them.
+type InstallationEvent struct {
+	InstallationID int64
+	Account        string   // User or organization the app is installed on
+	Added          []string // Repositories, e.g. acme/webapp
+	Removed        []string
+}
+
+type apiInstallationRepo struct {
+	FullName string `json:"full_name"`
+}
+
+type installationPayload struct {
+	Action       string `json:"action"`
+	Installation struct {
+		ID      int64   `json:"id"`
+		Account apiUser `json:"account"`
+	} `json:"installation"`
+	Repositories        []apiInstallationRepo `json:"repositories"`
+	RepositoriesAdded   []apiInstallationRepo `json:"repositories_added"`
+	RepositoriesRemoved []apiInstallationRepo `json:"repositories_removed"`
+}
+
+// ParseInstallationWebhook verifies the X-Hub-Signature-256 header of an
+// "installation" or "installation_repositories" delivery to an app's
+// webhook, signed with secret, and returns the repositories it added or
+// removed.
+func ParseInstallationWebhook(secret string, header http.Header, body []byte) (*InstallationEvent, error) {
+	if !validSignature(secret, header.Get("X-Hub-Signature-256"), body) {
+		return nil, gitprovider.ErrInvalidSignature
+	}
+	kind := header.Get("X-GitHub-Event")
+	if kind != "installation" && kind != "installation_repositories" {
+		return nil, gitprovider.ErrIgnoredEvent
+	}
+	var payload installationPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("github: decode %s event: %w", kind, err)
+	}
+	ev := &InstallationEvent{InstallationID: payload.Installation.ID, Account: payload.Installation.Account.Login}
+	switch {
+	case kind == "installation" && payload.Action == "created":
+		ev.Added = repoNames(payload.Repositories)
+	case kind == "installation" && payload.Action == "deleted":
+		ev.Removed = repoNames(payload.Repositories)
+	case kind == "installation_repositories" && (payload.Action == "added" || payload.Action == "removed"):
+		ev.Added, ev.Removed = repoNames(payload.RepositoriesAdded), repoNames(payload.RepositoriesRemoved)
+	default:
+		return nil, gitprovider.ErrIgnoredEvent
+	}
+	if ev.InstallationID == 0 {
+		return nil, gitprovider.ErrIgnoredEvent
+	}
+	return ev, nil
+}
+
+func repoNames(repos []apiInstallationRepo) []string {
+	names := make([]string, 0, len(repos))
+	for _, r := range repos {
+		if r.FullName != "" {
+			names = append(names, r.FullName)
+		}
+	}
+	return names
+}
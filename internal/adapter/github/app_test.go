@@ -0,0 +1,115 @@
+package github_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/github"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+func TestAppInstallationToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/app/installations/7/access_tokens" {
+			http.NotFound(w, r)
+			return
+		}
+		parts := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), ".")
+		if len(parts) != 3 {
+			http.Error(w, "no jwt", http.StatusUnauthorized)
+			return
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], sig) != nil {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		var claims struct {
+			Iss string `json:"iss"`
+			Iat int64  `json:"iat"`
+			Exp int64  `json:"exp"`
+		}
+		data, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		_ = json.Unmarshal(data, &claims)
+		if claims.Iss != "42" || claims.Exp-claims.Iat > 600 {
+			http.Error(w, "bad claims", http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{"token": "ghs_installation", "expires_at": expires})
+	}))
+	defer srv.Close()
+
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	app, err := github.NewApp(srv.URL, 42, pkcs1)
+	if err != nil {
+		t.Fatalf("NewApp failed: %v", err)
+	}
+	tok, err := app.InstallationToken(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("InstallationToken failed: %v", err)
+	}
+	if tok.Token != "ghs_installation" || !tok.ExpiresAt.Equal(expires) {
+		t.Fatalf("unexpected token %+v", tok)
+	}
+	if _, err := app.InstallationToken(context.Background(), 8); err == nil {
+		t.Fatal("expected an error for an unknown installation")
+	}
+
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	if _, err := github.NewApp("", 42, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})); err != nil {
+		t.Fatalf("expected a PKCS#8 key accepted, got %v", err)
+	}
+	if _, err := github.NewApp("", 42, []byte("not a key")); err == nil {
+		t.Fatal("expected an error for a key that is not PEM")
+	}
+}
+
+func TestParseInstallationWebhook(t *testing.T) {
+	created := []byte(`{"action":"created","installation":{"id":7,"account":{"login":"acme"}},
+		"repositories":[{"full_name":"acme/webapp"},{"full_name":"acme/api"}]}`)
+	ev, err := github.ParseInstallationWebhook("s3cret", signedHeader("s3cret", "installation", created), created)
+	if err != nil {
+		t.Fatalf("ParseInstallationWebhook failed: %v", err)
+	}
+	if ev.InstallationID != 7 || ev.Account != "acme" || !slices.Equal(ev.Added, []string{"acme/webapp", "acme/api"}) || len(ev.Removed) != 0 {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+
+	changed := []byte(`{"action":"removed","installation":{"id":7},"repositories_removed":[{"full_name":"acme/api"}]}`)
+	ev, err = github.ParseInstallationWebhook("s3cret", signedHeader("s3cret", "installation_repositories", changed), changed)
+	if err != nil || !slices.Equal(ev.Removed, []string{"acme/api"}) || len(ev.Added) != 0 {
+		t.Fatalf("unexpected event %+v, %v", ev, err)
+	}
+
+	if _, err := github.ParseInstallationWebhook("other", signedHeader("s3cret", "installation", created), created); !errors.Is(err, gitprovider.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+	suspended := []byte(`{"action":"suspend","installation":{"id":7}}`)
+	if _, err := github.ParseInstallationWebhook("s3cret", signedHeader("s3cret", "installation", suspended), suspended); !errors.Is(err, gitprovider.ErrIgnoredEvent) {
+		t.Fatalf("expected ErrIgnoredEvent for a suspension, got %v", err)
+	}
+	if _, err := github.ParseInstallationWebhook("s3cret", signedHeader("s3cret", "push", created), created); !errors.Is(err, gitprovider.ErrIgnoredEvent) {
+		t.Fatalf("expected ErrIgnoredEvent for a push, got %v", err)
+	}
+}
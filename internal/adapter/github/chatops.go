@@ -0,0 +1,114 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/chatops"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+// issueCommentPayload is the part of an "issue_comment" webhook delivery we
+// use. Comments on a pull request's conversation are issue comments.
+type issueCommentPayload struct {
+	Action string `json:"action"`
+	Issue  struct {
+		Number      int             `json:"number"`
+		PullRequest json.RawMessage `json:"pull_request"`
+	} `json:"issue"`
+	Comment    apiIssueComment `json:"comment"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// apiIssueComment is a comment on an issue or a pull request's conversation.
+type apiIssueComment struct {
+	ID                int64     `json:"id"`
+	Body              string    `json:"body"`
+	User              apiUser   `json:"user"`
+	AuthorAssociation string    `json:"author_association"`
+	HTMLURL           string    `json:"html_url"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// associationRoles maps GitHub author associations to commenter roles.
+var associationRoles = map[string]chatops.Role{
+	"OWNER":                  chatops.RoleOwner,
+	"MEMBER":                 chatops.RoleMember,
+	"COLLABORATOR":           chatops.RoleCollaborator,
+	"CONTRIBUTOR":            chatops.RoleContributor,
+	"FIRST_TIME_CONTRIBUTOR": chatops.RoleContributor,
+}
+
+// ParsePRCommentWebhook verifies the X-Hub-Signature-256 header of an
+// "issue_comment" delivery for a new comment on a pull request and looks up
+// the pull request's branches.
+func (p *Provider) ParsePRCommentWebhook(ctx context.Context, header http.Header, body []byte) (*gitprovider.PRCommentEvent, error) {
+	if !validSignature(p.webhookSecret, header.Get("X-Hub-Signature-256"), body) {
+		return nil, gitprovider.ErrInvalidSignature
+	}
+	if header.Get("X-GitHub-Event") != "issue_comment" {
+		return nil, gitprovider.ErrIgnoredEvent
+	}
+	var payload issueCommentPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("github: decode issue_comment event: %w", err)
+	}
+	if payload.Action != "created" || len(payload.Issue.PullRequest) == 0 ||
+		!strings.EqualFold(payload.Repository.FullName, p.repo) {
+		return nil, gitprovider.ErrIgnoredEvent
+	}
+	return p.prCommentEvent(ctx, payload.Issue.Number, &payload.Comment)
+}
+
+// prCommentEvent looks up the branches of the pull request a comment was
+// posted on. Pull requests from forks are ignored.
+func (p *Provider) prCommentEvent(ctx context.Context, number int, c *apiIssueComment) (*gitprovider.PRCommentEvent, error) {
+	var pr struct {
+		Head struct {
+			Ref  string `json:"ref"`
+			Repo *struct {
+				FullName string `json:"full_name"`
+			} `json:"repo"` // null once a fork is deleted
+		} `json:"head"`
+		Base struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+	}
+	if _, err := p.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/pulls/%d", p.repo, number), nil, &pr); err != nil {
+		return nil, fmt.Errorf("github: get pull request %d: %w", number, err)
+	}
+	if pr.Head.Repo == nil || !strings.EqualFold(pr.Head.Repo.FullName, p.repo) {
+		return nil, gitprovider.ErrIgnoredEvent
+	}
+
+	role, ok := associationRoles[c.AuthorAssociation]
+	if !ok {
+		role = chatops.RoleNone
+	}
+	return &gitprovider.PRCommentEvent{
+		Number:     number,
+		CommentID:  strconv.FormatInt(c.ID, 10),
+		Body:       c.Body,
+		Author:     c.User.Login,
+		AuthorRole: role,
+		Branch:     pr.Head.Ref,
+		BaseBranch: pr.Base.Ref,
+	}, nil
+}
+
+// ReplyToPR comments on the conversation of a pull request.
+func (p *Provider) ReplyToPR(ctx context.Context, number int, body string) (string, error) {
+	return p.CreateIssueComment(ctx, number, body)
+}
+
+// UpdatePRReply replaces the body of a conversation comment.
+func (p *Provider) UpdatePRReply(ctx context.Context, number int, commentID, body string) error {
+	return p.UpdateIssueComment(ctx, number, commentID, body)
+}
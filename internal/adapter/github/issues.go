@@ -0,0 +1,132 @@
+package github
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/domain/issuerun"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+// SetWebhookSecret sets the secret webhooks are signed with.
+func (p *Provider) SetWebhookSecret(secret string) {
+	p.webhookSecret = secret
+}
+
+type apiUser struct {
+	Login string `json:"login"`
+}
+
+type apiLabel struct {
+	Name string `json:"name"`
+}
+
+type apiIssue struct {
+	Number      int             `json:"number"`
+	Title       string          `json:"title"`
+	Body        string          `json:"body"`
+	HTMLURL     string          `json:"html_url"`
+	Labels      []apiLabel      `json:"labels"`
+	Assignees   []apiUser       `json:"assignees"`
+	PullRequest json.RawMessage `json:"pull_request"` // Set on pull requests, which are issues too
+}
+
+func (in *apiIssue) issue() issuerun.Issue {
+	issue := issuerun.Issue{
+		Number: in.Number,
+		Title:  in.Title,
+		Body:   in.Body,
+		URL:    in.HTMLURL,
+	}
+	for _, l := range in.Labels {
+		issue.Labels = append(issue.Labels, l.Name)
+	}
+	for _, a := range in.Assignees {
+		issue.Assignees = append(issue.Assignees, a.Login)
+	}
+	return issue
+}
+
+// issuesPayload is the part of an "issues" webhook delivery we use.
+type issuesPayload struct {
+	Action     string    `json:"action"`
+	Issue      apiIssue  `json:"issue"`
+	Label      *apiLabel `json:"label"`
+	Assignee   *apiUser  `json:"assignee"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// ParseIssueWebhook verifies the X-Hub-Signature-256 header of an "issues"
+// delivery and returns the labels or assignees it added.
+func (p *Provider) ParseIssueWebhook(header http.Header, body []byte) (*gitprovider.IssueEvent, error) {
+	if !validSignature(p.webhookSecret, header.Get("X-Hub-Signature-256"), body) {
+		return nil, gitprovider.ErrInvalidSignature
+	}
+	if header.Get("X-GitHub-Event") != "issues" {
+		return nil, gitprovider.ErrIgnoredEvent
+	}
+	var payload issuesPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("github: decode issues event: %w", err)
+	}
+	if !strings.EqualFold(payload.Repository.FullName, p.repo) || len(payload.Issue.PullRequest) > 0 {
+		return nil, gitprovider.ErrIgnoredEvent
+	}
+
+	ev := &gitprovider.IssueEvent{Issue: payload.Issue.issue()}
+	switch {
+	case payload.Action == "opened":
+		ev.AddedLabels, ev.AddedAssignees = ev.Issue.Labels, ev.Issue.Assignees
+	case payload.Action == "labeled" && payload.Label != nil:
+		ev.AddedLabels = []string{payload.Label.Name}
+	case payload.Action == "assigned" && payload.Assignee != nil:
+		ev.AddedAssignees = []string{payload.Assignee.Login}
+	default:
+		return nil, gitprovider.ErrIgnoredEvent
+	}
+	return ev, nil
+}
+
+// CreateIssueComment comments on an issue.
+func (p *Provider) CreateIssueComment(ctx context.Context, number int, body string) (string, error) {
+	var created apiComment
+	path := fmt.Sprintf("/repos/%s/issues/%d/comments", p.repo, number)
+	if _, err := p.do(ctx, http.MethodPost, path, map[string]string{"body": body}, &created); err != nil {
+		return "", fmt.Errorf("github: create issue comment: %w", err)
+	}
+	return strconv.FormatInt(created.ID, 10), nil
+}
+
+// UpdateIssueComment replaces the body of an issue comment.
+func (p *Provider) UpdateIssueComment(ctx context.Context, _ int, commentID, body string) error {
+	path := fmt.Sprintf("/repos/%s/issues/comments/%s", p.repo, commentID)
+	if _, err := p.do(ctx, http.MethodPatch, path, map[string]string{"body": body}, nil); err != nil {
+		return fmt.Errorf("github: update issue comment %s: %w", commentID, err)
+	}
+	return nil
+}
+
+// validSignature checks a "sha256=<hex>" HMAC of body. Without a secret
+// no delivery is valid.
+func validSignature(secret, signature string, body []byte) bool {
+	hexSum, ok := strings.CutPrefix(signature, "sha256=")
+	if secret == "" || !ok {
+		return false
+	}
+	got, err := hex.DecodeString(hexSum)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
@@ -0,0 +1,147 @@
+package github_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/github"
+	"github.com/Strob0t/CodeForge/internal/domain/chatops"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+func signedHeader(secret, event string, body []byte) http.Header {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	h := http.Header{}
+	h.Set("X-GitHub-Event", event)
+	h.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return h
+}
+
+func TestParseIssueWebhook(t *testing.T) {
+	p := github.NewProvider("", "acme/webapp", "ghp_test")
+	p.SetWebhookSecret("s3cret")
+	if !p.Capabilities().Webhook || !p.Capabilities().Issues {
+		t.Fatalf("expected webhook and issue capabilities, got %+v", p.Capabilities())
+	}
+
+	labeled := []byte(`{"action": "labeled", "label": {"name": "codeforge"},
+		"issue": {"number": 12, "title": "Fix login", "body": "It breaks", "html_url": "https://github.com/acme/webapp/issues/12",
+			"labels": [{"name": "bug"}, {"name": "codeforge"}], "assignees": [{"login": "alice"}]},
+		"repository": {"full_name": "acme/webapp"}}`)
+	ev, err := p.ParseIssueWebhook(signedHeader("s3cret", "issues", labeled), labeled)
+	if err != nil {
+		t.Fatalf("parse labeled: %v", err)
+	}
+	if ev.Issue.Number != 12 || ev.Issue.Title != "Fix login" || !slices.Equal(ev.AddedLabels, []string{"codeforge"}) || len(ev.AddedAssignees) != 0 {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	if !slices.Equal(ev.Issue.Labels, []string{"bug", "codeforge"}) || !slices.Equal(ev.Issue.Assignees, []string{"alice"}) {
+		t.Fatalf("unexpected issue %+v", ev.Issue)
+	}
+
+	opened := []byte(`{"action": "opened", "issue": {"number": 3, "title": "New", "labels": [], "assignees": [{"login": "codeforge-bot"}]},
+		"repository": {"full_name": "acme/webapp"}}`)
+	ev, err = p.ParseIssueWebhook(signedHeader("s3cret", "issues", opened), opened)
+	if err != nil || !slices.Equal(ev.AddedAssignees, []string{"codeforge-bot"}) {
+		t.Fatalf("parse opened = %+v, %v", ev, err)
+	}
+
+	otherRepo := []byte(`{"action": "opened", "repository": {"full_name": "acme/other"}}`)
+	pullRequest := []byte(`{"action": "opened", "issue": {"pull_request": {}}, "repository": {"full_name": "acme/webapp"}}`)
+	closed := []byte(`{"action": "closed", "repository": {"full_name": "acme/webapp"}}`)
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		want   error
+	}{
+		{"wrong secret", signedHeader("other", "issues", labeled), labeled, gitprovider.ErrInvalidSignature},
+		{"unsigned", http.Header{"X-Github-Event": {"issues"}}, labeled, gitprovider.ErrInvalidSignature},
+		{"other event", signedHeader("s3cret", "push", labeled), labeled, gitprovider.ErrIgnoredEvent},
+		{"other repo", signedHeader("s3cret", "issues", otherRepo), otherRepo, gitprovider.ErrIgnoredEvent},
+		{"pull request", signedHeader("s3cret", "issues", pullRequest), pullRequest, gitprovider.ErrIgnoredEvent},
+		{"closed", signedHeader("s3cret", "issues", closed), closed, gitprovider.ErrIgnoredEvent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := p.ParseIssueWebhook(tt.header, tt.body); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestIssueComments(t *testing.T) {
+	fake, srv := newFakeGitHub(t)
+	p := github.NewProvider(srv.URL, "acme/webapp", "ghp_test")
+	ctx := context.Background()
+
+	id, err := p.CreateIssueComment(ctx, 7, "started")
+	if err != nil || id == "" {
+		t.Fatalf("CreateIssueComment = %q, %v", id, err)
+	}
+	if err := p.UpdateIssueComment(ctx, 7, id, "done"); err != nil {
+		t.Fatalf("UpdateIssueComment: %v", err)
+	}
+	if len(fake.issue) != 1 || fake.issue[101]["body"] != "done" {
+		t.Fatalf("unexpected comments %v", fake.issue)
+	}
+}
+
+func TestParsePRCommentWebhook(t *testing.T) {
+	_, srv := newFakeGitHub(t)
+	p := github.NewProvider(srv.URL, "acme/webapp", "ghp_test")
+	p.SetWebhookSecret("s3cret")
+	ctx := context.Background()
+
+	created := []byte(`{"action": "created", "issue": {"number": 7, "pull_request": {"url": "x"}},
+		"comment": {"id": 55, "body": "/codeforge fix", "user": {"login": "alice"}, "author_association": "COLLABORATOR"},
+		"repository": {"full_name": "acme/webapp"}}`)
+	ev, err := p.ParsePRCommentWebhook(ctx, signedHeader("s3cret", "issue_comment", created), created)
+	if err != nil {
+		t.Fatalf("parse created: %v", err)
+	}
+	want := gitprovider.PRCommentEvent{Number: 7, CommentID: "55", Body: "/codeforge fix", Author: "alice",
+		AuthorRole: chatops.RoleCollaborator, Branch: "fix-login", BaseBranch: "main"}
+	if *ev != want {
+		t.Fatalf("expected %+v, got %+v", want, *ev)
+	}
+
+	stranger := []byte(`{"action": "created", "issue": {"number": 7, "pull_request": {}},
+		"comment": {"id": 56, "body": "/codeforge fix", "user": {"login": "bob"}, "author_association": "NONE"},
+		"repository": {"full_name": "acme/webapp"}}`)
+	if ev, err := p.ParsePRCommentWebhook(ctx, signedHeader("s3cret", "issue_comment", stranger), stranger); err != nil || ev.AuthorRole != chatops.RoleNone {
+		t.Fatalf("expected role none, got %+v, %v", ev, err)
+	}
+
+	fork := []byte(`{"action": "created", "issue": {"number": 8, "pull_request": {}}, "comment": {"id": 57},
+		"repository": {"full_name": "acme/webapp"}}`)
+	onIssue := []byte(`{"action": "created", "issue": {"number": 3}, "comment": {"id": 58}, "repository": {"full_name": "acme/webapp"}}`)
+	edited := []byte(`{"action": "edited", "issue": {"number": 7, "pull_request": {}}, "repository": {"full_name": "acme/webapp"}}`)
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		want   error
+	}{
+		{"wrong secret", signedHeader("other", "issue_comment", created), created, gitprovider.ErrInvalidSignature},
+		{"other event", signedHeader("s3cret", "issues", created), created, gitprovider.ErrIgnoredEvent},
+		{"fork", signedHeader("s3cret", "issue_comment", fork), fork, gitprovider.ErrIgnoredEvent},
+		{"issue", signedHeader("s3cret", "issue_comment", onIssue), onIssue, gitprovider.ErrIgnoredEvent},
+		{"edited", signedHeader("s3cret", "issue_comment", edited), edited, gitprovider.ErrIgnoredEvent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := p.ParsePRCommentWebhook(ctx, tt.header, tt.body); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/chatops"
+	"github.com/Strob0t/CodeForge/internal/domain/poll"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+// maxPollPages bounds the pages a poll reads; older changes of a long
+// outage are skipped.
+const maxPollPages = 10
+
+// apiIssueEvent is an event of the repository's issue timeline.
+type apiIssueEvent struct {
+	ID       int64     `json:"id"`
+	Event    string    `json:"event"`
+	Label    *apiLabel `json:"label"`
+	Assignee *apiUser  `json:"assignee"`
+	Issue    apiIssue  `json:"issue"`
+}
+
+// PollIssues reads the repository's issue events, newest first, back to
+// the last event ID recorded in the cursor and returns the labels and
+// assignees they added.
+func (p *Provider) PollIssues(ctx context.Context, cursor *poll.Cursor) ([]gitprovider.IssueEvent, error) {
+	start := cursor.Position == ""
+	var last int64
+	if !start {
+		var err error
+		if last, err = strconv.ParseInt(cursor.Position, 10, 64); err != nil {
+			return nil, fmt.Errorf("github: invalid issue events cursor %q", cursor.Position)
+		}
+	}
+
+	var (
+		events []apiIssueEvent
+		etag   string
+	)
+	for page := 1; page <= maxPollPages; page++ {
+		path := fmt.Sprintf("/repos/%s/issues/events?per_page=%d&page=%d", p.repo, perPage, page)
+		batch, changed, tag, err := pollPage[apiIssueEvent](ctx, p, path, page, cursor.ETag)
+		if err != nil {
+			return nil, fmt.Errorf("github: list issue events: %w", err)
+		}
+		if !changed {
+			return nil, nil
+		}
+		if page == 1 {
+			etag = tag
+		}
+		stop := start || len(batch) < perPage
+		for _, e := range batch {
+			if e.ID <= last {
+				stop = true
+				break
+			}
+			events = append(events, e)
+		}
+		if stop {
+			break
+		}
+	}
+
+	cursor.ETag = etag
+	switch {
+	case len(events) > 0:
+		cursor.Position = strconv.FormatInt(events[0].ID, 10)
+	case start:
+		cursor.Position = "0"
+	}
+	if start {
+		return nil, nil
+	}
+
+	var out []gitprovider.IssueEvent
+	for i := len(events) - 1; i >= 0; i-- {
+		e := &events[i]
+		if len(e.Issue.PullRequest) > 0 {
+			continue
+		}
+		ev := gitprovider.IssueEvent{Issue: e.Issue.issue()}
+		switch {
+		case e.Event == "labeled" && e.Label != nil:
+			ev.AddedLabels = []string{e.Label.Name}
+		case e.Event == "assigned" && e.Assignee != nil:
+			ev.AddedAssignees = []string{e.Assignee.Login}
+		default:
+			continue
+		}
+		out = append(out, ev)
+	}
+	return out, nil
+}
+
+// PollPRComments reads the issue and pull request comments created since
+// the time recorded in the cursor. Only comments on pull requests that
+// post a command are looked up and returned.
+func (p *Provider) PollPRComments(ctx context.Context, cursor *poll.Cursor) ([]gitprovider.PRCommentEvent, error) {
+	if cursor.Position == "" {
+		cursor.Position = time.Now().UTC().Format(time.RFC3339)
+		return nil, nil
+	}
+	since, err := time.Parse(time.RFC3339, cursor.Position)
+	if err != nil {
+		return nil, fmt.Errorf("github: invalid comments cursor %q", cursor.Position)
+	}
+
+	var (
+		comments []apiIssueComment
+		etag     string
+	)
+	for page := 1; page <= maxPollPages; page++ {
+		path := fmt.Sprintf("/repos/%s/issues/comments?sort=created&direction=asc&since=%s&per_page=%d&page=%d",
+			p.repo, url.QueryEscape(cursor.Position), perPage, page)
+		batch, changed, tag, err := pollPage[apiIssueComment](ctx, p, path, page, cursor.ETag)
+		if err != nil {
+			return nil, fmt.Errorf("github: list comments: %w", err)
+		}
+		if !changed {
+			return nil, nil
+		}
+		if page == 1 {
+			etag = tag
+		}
+		comments = append(comments, batch...)
+		if len(batch) < perPage {
+			break
+		}
+	}
+
+	// since matches comments updated after it; edits of older comments
+	// are not new commands.
+	latest := since
+	var out []gitprovider.PRCommentEvent
+	for i := range comments {
+		c := &comments[i]
+		if c.CreatedAt.Before(since) {
+			continue
+		}
+		if c.CreatedAt.After(latest) {
+			latest = c.CreatedAt
+		}
+		number, ok := pullNumber(c.HTMLURL)
+		if !ok {
+			continue
+		}
+		if _, found, _ := chatops.Parse(c.Body); !found {
+			continue
+		}
+		ev, err := p.prCommentEvent(ctx, number, c)
+		if errors.Is(err, gitprovider.ErrIgnoredEvent) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *ev)
+	}
+	cursor.Position, cursor.ETag = latest.UTC().Format(time.RFC3339), etag
+	return out, nil
+}
+
+// pollPage reads one page of a feed. The first page is conditional on
+// etag; an unchanged feed returns false.
+func pollPage[T any](ctx context.Context, p *Provider, path string, page int, etag string) (batch []T, changed bool, newETag string, err error) {
+	if page > 1 {
+		_, err = p.do(ctx, http.MethodGet, path, nil, &batch)
+		return batch, true, "", err
+	}
+	newETag, changed, err = p.getIfChanged(ctx, path, etag, &batch)
+	return batch, changed, newETag, err
+}
+
+// pullNumber returns the pull request number of a comment's URL, e.g.
+// https://github.com/acme/webapp/pull/7#issuecomment-1. Comments on issues
+// return false.
+func pullNumber(htmlURL string) (int, bool) {
+	_, rest, ok := strings.Cut(htmlURL, "/pull/")
+	if !ok {
+		return 0, false
+	}
+	rest, _, _ = strings.Cut(rest, "#")
+	n, err := strconv.Atoi(rest)
+	return n, err == nil
+}
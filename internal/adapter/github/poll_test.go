@@ -0,0 +1,106 @@
+package github_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/github"
+	"github.com/Strob0t/CodeForge/internal/domain/chatops"
+	"github.com/Strob0t/CodeForge/internal/domain/poll"
+)
+
+// newPollGitHub serves the feeds as bodies tagged with etag; requests with a
+// matching If-None-Match are answered "not modified".
+func newPollGitHub(t *testing.T, etag string, feeds map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/webapp/pulls/7":
+			_, _ = w.Write([]byte(`{"head": {"ref": "fix-login", "repo": {"full_name": "acme/webapp"}}, "base": {"ref": "main"}}`))
+			return
+		case "/repos/acme/webapp/pulls/8":
+			_, _ = w.Write([]byte(`{"head": {"ref": "patch-1", "repo": {"full_name": "mallory/webapp"}}, "base": {"ref": "main"}}`))
+			return
+		}
+		body, ok := feeds[r.URL.Path]
+		if !ok || r.URL.Query().Get("page") != "1" {
+			_, _ = w.Write([]byte(`[]`))
+			return
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestPollIssues(t *testing.T) {
+	srv := newPollGitHub(t, `"v2"`, map[string]string{"/repos/acme/webapp/issues/events": `[
+		{"id": 12, "event": "assigned", "assignee": {"login": "codeforge-bot"}, "issue": {"number": 43, "title": "Crash"}},
+		{"id": 11, "event": "labeled", "label": {"name": "codeforge"}, "issue": {"number": 7, "pull_request": {}}},
+		{"id": 10, "event": "labeled", "label": {"name": "codeforge"}, "issue": {"number": 42, "title": "Fix login", "labels": [{"name": "codeforge"}]}},
+		{"id": 9, "event": "closed", "issue": {"number": 41}}
+	]`})
+	p := github.NewProvider(srv.URL, "acme/webapp", "ghp_test")
+	ctx := context.Background()
+
+	// The first poll starts at the newest event.
+	cursor := &poll.Cursor{}
+	if evs, err := p.PollIssues(ctx, cursor); err != nil || len(evs) != 0 || cursor.Position != "12" || cursor.ETag != `"v2"` {
+		t.Fatalf("expected an empty first poll, got %+v, %+v, %v", evs, cursor, err)
+	}
+
+	cursor = &poll.Cursor{Position: "9", ETag: `"v1"`}
+	evs, err := p.PollIssues(ctx, cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 2 || evs[0].Issue.Number != 42 || !slices.Equal(evs[0].AddedLabels, []string{"codeforge"}) ||
+		evs[1].Issue.Number != 43 || !slices.Equal(evs[1].AddedAssignees, []string{"codeforge-bot"}) {
+		t.Fatalf("unexpected events %+v", evs)
+	}
+	if cursor.Position != "12" || cursor.ETag != `"v2"` {
+		t.Fatalf("cursor not advanced: %+v", cursor)
+	}
+
+	if evs, err := p.PollIssues(ctx, cursor); err != nil || len(evs) != 0 || cursor.Position != "12" {
+		t.Fatalf("expected an unchanged feed, got %+v, %+v, %v", evs, cursor, err)
+	}
+}
+
+func TestPollPRComments(t *testing.T) {
+	srv := newPollGitHub(t, `"c1"`, map[string]string{"/repos/acme/webapp/issues/comments": `[
+		{"id": 1, "body": "/codeforge fix", "user": {"login": "old"}, "html_url": "https://github.com/acme/webapp/pull/7#issuecomment-1", "created_at": "2026-10-16T09:00:00Z"},
+		{"id": 2, "body": "LGTM", "user": {"login": "bob"}, "html_url": "https://github.com/acme/webapp/pull/7#issuecomment-2", "created_at": "2026-10-17T10:00:00Z"},
+		{"id": 3, "body": "/codeforge explain", "user": {"login": "carol"}, "html_url": "https://github.com/acme/webapp/issues/42#issuecomment-3", "created_at": "2026-10-17T10:01:00Z"},
+		{"id": 4, "body": "/codeforge fix", "user": {"login": "mallory"}, "html_url": "https://github.com/acme/webapp/pull/8#issuecomment-4", "created_at": "2026-10-17T10:02:00Z"},
+		{"id": 5, "body": "/codeforge fix the test", "user": {"login": "alice"}, "author_association": "MEMBER", "html_url": "https://github.com/acme/webapp/pull/7#issuecomment-5", "created_at": "2026-10-17T10:03:00Z"}
+	]`})
+	p := github.NewProvider(srv.URL, "acme/webapp", "ghp_test")
+	ctx := context.Background()
+
+	cursor := &poll.Cursor{}
+	if evs, err := p.PollPRComments(ctx, cursor); err != nil || len(evs) != 0 || cursor.Position == "" {
+		t.Fatalf("expected an empty first poll, got %+v, %+v, %v", evs, cursor, err)
+	}
+
+	cursor = &poll.Cursor{Position: "2026-10-17T00:00:00Z"}
+	evs, err := p.PollPRComments(ctx, cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 1 || evs[0].Number != 7 || evs[0].CommentID != "5" || evs[0].Author != "alice" ||
+		evs[0].AuthorRole != chatops.RoleMember || evs[0].Branch != "fix-login" || evs[0].BaseBranch != "main" {
+		t.Fatalf("unexpected events %+v", evs)
+	}
+	if cursor.Position != "2026-10-17T10:03:00Z" || cursor.ETag != `"c1"` {
+		t.Fatalf("cursor not advanced: %+v", cursor)
+	}
+}
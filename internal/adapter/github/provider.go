@@ -0,0 +1,287 @@
+// Package github implements the gitprovider.Provider interface for
+// repositories hosted on GitHub. Local repository operations use the git
+// CLI, authenticated with the API token if there is one; pull request and
+// issue comments and commit statuses go through the GitHub REST API, and
+// issue and pull request comment webhooks are verified with their HMAC
+// signature. Projects without webhooks poll the same changes from the API.
+// An App mints installation tokens for projects that authenticate as a
+// GitHub App, and Actions reads GitHub Actions workflow runs as a CI
+// provider.
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/gitlocal"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+const (
+	providerName  = "github"
+	defaultAPIURL = "https://api.github.com"
+	perPage       = 100
+)
+
+// Provider works on clones of one GitHub repository.
+type Provider struct {
+	gitlocal.Provider
+
+	apiURL     string
+	repo       string // owner/name
+	token      string
+	httpClient *http.Client
+
+	webhookSecret string
+
+	mu    sync.Mutex
+	heads map[int]string // PR number -> head commit SHA
+}
+
+// NewProvider creates a Provider for repo ("owner/name"). apiURL may be
+// empty for github.com or point at a GitHub Enterprise API root.
+func NewProvider(apiURL, repo, token string) *Provider {
+	if apiURL == "" {
+		apiURL = defaultAPIURL
+	}
+	p := &Provider{
+		apiURL: strings.TrimRight(apiURL, "/"),
+		repo:   repo,
+		token:  token,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		heads: make(map[int]string),
+	}
+	// GitHub takes personal access and installation tokens as the
+	// password of any user.
+	p.SetToken("x-access-token", token)
+	return p
+}
+
+// Name returns "github".
+func (p *Provider) Name() string { return providerName }
+
+// Capabilities returns what the GitHub provider supports. Pull request,
+// issue and commit status operations need an API token; issue webhooks
+// need a webhook secret.
+func (p *Provider) Capabilities() gitprovider.Capabilities {
+	caps := p.Provider.Capabilities()
+	caps.PullRequest = p.token != ""
+	caps.CommitStatus = p.token != ""
+	caps.Issues = p.token != ""
+	caps.Webhook = p.webhookSecret != ""
+	return caps
+}
+
+// apiComment is a pull request review comment or an issue comment.
+type apiComment struct {
+	ID   int64  `json:"id"`
+	Path string `json:"path"`
+	Line int    `json:"line"` // null (0) once the comment is outdated
+	Body string `json:"body"`
+}
+
+// ListPRComments returns the review comments followed by the conversation
+// comments of a pull request.
+func (p *Provider) ListPRComments(ctx context.Context, number int) ([]gitprovider.PRComment, error) {
+	var out []gitprovider.PRComment
+	for _, kind := range []string{"pulls", "issues"} {
+		for page := 1; ; page++ {
+			var batch []apiComment
+			path := fmt.Sprintf("/repos/%s/%s/%d/comments?per_page=%d&page=%d", p.repo, kind, number, perPage, page)
+			if _, err := p.do(ctx, http.MethodGet, path, nil, &batch); err != nil {
+				return nil, fmt.Errorf("github: list %s comments: %w", kind, err)
+			}
+			for _, c := range batch {
+				pc := gitprovider.PRComment{ID: strconv.FormatInt(c.ID, 10), Body: c.Body}
+				if kind == "pulls" {
+					pc.Path, pc.Line = c.Path, c.Line
+				}
+				out = append(out, pc)
+			}
+			if len(batch) < perPage {
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// CreatePRComment posts an inline comment on the right side of the diff at
+// the pull request's head commit, or a conversation comment if c has no path.
+func (p *Provider) CreatePRComment(ctx context.Context, number int, c *gitprovider.PRComment) error {
+	var created apiComment
+	if c.Path == "" {
+		path := fmt.Sprintf("/repos/%s/issues/%d/comments", p.repo, number)
+		if _, err := p.do(ctx, http.MethodPost, path, map[string]string{"body": c.Body}, &created); err != nil {
+			return fmt.Errorf("github: create comment: %w", err)
+		}
+		c.ID = strconv.FormatInt(created.ID, 10)
+		return nil
+	}
+
+	head, err := p.headSHA(ctx, number)
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("/repos/%s/pulls/%d/comments", p.repo, number)
+	body := map[string]any{
+		"body":      c.Body,
+		"commit_id": head,
+		"path":      c.Path,
+		"line":      c.Line,
+		"side":      "RIGHT",
+	}
+	status, err := p.do(ctx, http.MethodPost, path, body, &created)
+	if status == http.StatusUnprocessableEntity {
+		return fmt.Errorf("%w: %s:%d", gitprovider.ErrNotInDiff, c.Path, c.Line)
+	}
+	if err != nil {
+		return fmt.Errorf("github: create review comment: %w", err)
+	}
+	c.ID = strconv.FormatInt(created.ID, 10)
+	return nil
+}
+
+// UpdatePRComment replaces the body of an existing comment.
+func (p *Provider) UpdatePRComment(ctx context.Context, _ int, c *gitprovider.PRComment) error {
+	kind := "issues"
+	if c.Path != "" {
+		kind = "pulls"
+	}
+	path := fmt.Sprintf("/repos/%s/%s/comments/%s", p.repo, kind, c.ID)
+	if _, err := p.do(ctx, http.MethodPatch, path, map[string]string{"body": c.Body}, nil); err != nil {
+		return fmt.Errorf("github: update comment %s: %w", c.ID, err)
+	}
+	return nil
+}
+
+// PRHeadSHA returns the head commit of a pull request.
+func (p *Provider) PRHeadSHA(ctx context.Context, number int) (string, error) {
+	return p.headSHA(ctx, number)
+}
+
+// SetCommitStatus creates a commit status; GitHub shows the latest status
+// per context.
+func (p *Provider) SetCommitStatus(ctx context.Context, sha string, st *gitprovider.CommitStatus) error {
+	body := map[string]string{
+		"state":       string(st.State),
+		"context":     st.Context,
+		"description": st.Description,
+		"target_url":  st.TargetURL,
+	}
+	if _, err := p.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/statuses/%s", p.repo, sha), body, nil); err != nil {
+		return fmt.Errorf("github: set commit status %s: %w", st.Context, err)
+	}
+	return nil
+}
+
+// headSHA returns the head commit of a pull request, cached per provider.
+func (p *Provider) headSHA(ctx context.Context, number int) (string, error) {
+	p.mu.Lock()
+	sha, ok := p.heads[number]
+	p.mu.Unlock()
+	if ok {
+		return sha, nil
+	}
+
+	var pr struct {
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	}
+	if _, err := p.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/pulls/%d", p.repo, number), nil, &pr); err != nil {
+		return "", fmt.Errorf("github: get pull request %d: %w", number, err)
+	}
+	p.mu.Lock()
+	p.heads[number] = pr.Head.SHA
+	p.mu.Unlock()
+	return pr.Head.SHA, nil
+}
+
+// --- HTTP ---
+
+// do sends an API request and decodes the response into out (if non-nil).
+// It returns the HTTP status code alongside any error.
+func (p *Provider) do(ctx context.Context, method, path string, in, out any) (int, error) {
+	status, _, err := p.send(ctx, method, path, nil, in, out)
+	return status, err
+}
+
+// getIfChanged sends a GET conditional on etag. It returns false if the
+// resource is unchanged, and its new ETag otherwise. Unchanged responses
+// do not count against the rate limit.
+func (p *Provider) getIfChanged(ctx context.Context, path, etag string, out any) (string, bool, error) {
+	header := http.Header{}
+	if etag != "" {
+		header.Set("If-None-Match", etag)
+	}
+	status, respHeader, err := p.send(ctx, http.MethodGet, path, header, nil, out)
+	if err != nil {
+		return "", false, err
+	}
+	if status == http.StatusNotModified {
+		return etag, false, nil
+	}
+	return respHeader.Get("ETag"), true, nil
+}
+
+// send sends an API request with extra headers and decodes the response
+// into out (if non-nil). It returns the status code and response headers
+// alongside any error.
+func (p *Provider) send(ctx context.Context, method, path string, header http.Header, in, out any) (int, http.Header, error) {
+	if p.token == "" {
+		return 0, nil, errors.New("no API token configured")
+	}
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return 0, nil, fmt.Errorf("marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.apiURL+path, body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+p.token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("http request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, resp.Header, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return resp.StatusCode, resp.Header, fmt.Errorf("API error %d: %s", resp.StatusCode, string(data))
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp.StatusCode, resp.Header, fmt.Errorf("unmarshal response: %w", err)
+		}
+	}
+	return resp.StatusCode, resp.Header, nil
+}
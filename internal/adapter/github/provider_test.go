@@ -0,0 +1,191 @@
+package github_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/github"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+// fakeGitHub serves the pull request comment and commit status endpoints
+// for acme/webapp#7.
+// Only lines 1-20 of main.go are part of the diff.
+type fakeGitHub struct {
+	mu       sync.Mutex
+	nextID   int64
+	review   map[int64]map[string]any
+	issue    map[int64]map[string]any
+	commitID string
+	statuses []map[string]any
+}
+
+func newFakeGitHub(t *testing.T) (*fakeGitHub, *httptest.Server) {
+	t.Helper()
+	f := &fakeGitHub{nextID: 100, review: map[int64]map[string]any{}, issue: map[int64]map[string]any{}}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve(t)))
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeGitHub) serve(t *testing.T) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer ghp_test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		const base = "/repos/acme/webapp"
+
+		switch path := strings.TrimPrefix(r.URL.Path, base); {
+		case r.Method == http.MethodGet && path == "/pulls/7":
+			_, _ = w.Write([]byte(`{"head": {"sha": "abc123", "ref": "fix-login", "repo": {"full_name": "acme/webapp"}}, "base": {"ref": "main"}}`))
+		case r.Method == http.MethodGet && path == "/pulls/8":
+			_, _ = w.Write([]byte(`{"head": {"sha": "def456", "ref": "patch-1", "repo": {"full_name": "mallory/webapp"}}, "base": {"ref": "main"}}`))
+		case r.Method == http.MethodGet && path == "/pulls/7/comments":
+			writeList(w, r, f.review)
+		case r.Method == http.MethodGet && path == "/issues/7/comments":
+			writeList(w, r, f.issue)
+		case r.Method == http.MethodPost && path == "/pulls/7/comments":
+			if line, _ := body["line"].(float64); body["path"] != "main.go" || line > 20 {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				_, _ = w.Write([]byte(`{"message": "Validation Failed"}`))
+				return
+			}
+			f.commitID, _ = body["commit_id"].(string)
+			f.create(w, f.review, body)
+		case r.Method == http.MethodPost && path == "/issues/7/comments":
+			f.create(w, f.issue, body)
+		case r.Method == http.MethodPost && path == "/statuses/abc123":
+			f.statuses = append(f.statuses, body)
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPatch && strings.HasPrefix(path, "/pulls/comments/"):
+			f.update(w, f.review, strings.TrimPrefix(path, "/pulls/comments/"), body)
+		case r.Method == http.MethodPatch && strings.HasPrefix(path, "/issues/comments/"):
+			f.update(w, f.issue, strings.TrimPrefix(path, "/issues/comments/"), body)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func writeList(w http.ResponseWriter, r *http.Request, comments map[int64]map[string]any) {
+	if r.URL.Query().Get("page") != "1" {
+		_, _ = w.Write([]byte(`[]`))
+		return
+	}
+	list := make([]map[string]any, 0, len(comments))
+	for _, c := range comments {
+		list = append(list, c)
+	}
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+func (f *fakeGitHub) create(w http.ResponseWriter, comments map[int64]map[string]any, body map[string]any) {
+	f.nextID++
+	body["id"] = f.nextID
+	comments[f.nextID] = body
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func (f *fakeGitHub) update(w http.ResponseWriter, comments map[int64]map[string]any, id string, body map[string]any) {
+	n, _ := strconv.ParseInt(id, 10, 64)
+	c, ok := comments[n]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	c["body"] = body["body"]
+	_ = json.NewEncoder(w).Encode(c)
+}
+
+func TestPRComments(t *testing.T) {
+	fake, srv := newFakeGitHub(t)
+	p := github.NewProvider(srv.URL, "acme/webapp", "ghp_test")
+	ctx := context.Background()
+
+	inline := &gitprovider.PRComment{Path: "main.go", Line: 12, Body: "check this"}
+	if err := p.CreatePRComment(ctx, 7, inline); err != nil {
+		t.Fatalf("create inline: %v", err)
+	}
+	if inline.ID == "" || fake.commitID != "abc123" {
+		t.Fatalf("expected ID and head commit, got %q / %q", inline.ID, fake.commitID)
+	}
+	summary := &gitprovider.PRComment{Body: "summary"}
+	if err := p.CreatePRComment(ctx, 7, summary); err != nil {
+		t.Fatalf("create summary: %v", err)
+	}
+
+	outside := &gitprovider.PRComment{Path: "main.go", Line: 99, Body: "far away"}
+	if err := p.CreatePRComment(ctx, 7, outside); !errors.Is(err, gitprovider.ErrNotInDiff) {
+		t.Fatalf("expected ErrNotInDiff, got %v", err)
+	}
+
+	inline.Body = "check this again"
+	if err := p.UpdatePRComment(ctx, 7, inline); err != nil {
+		t.Fatalf("update inline: %v", err)
+	}
+	summary.Body = "summary v2"
+	if err := p.UpdatePRComment(ctx, 7, summary); err != nil {
+		t.Fatalf("update summary: %v", err)
+	}
+
+	comments, err := p.ListPRComments(ctx, 7)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	got := make(map[string]string)
+	for _, c := range comments {
+		got[fmt.Sprintf("%s:%d", c.Path, c.Line)] = c.Body
+	}
+	if len(got) != 2 || got["main.go:12"] != "check this again" || got[":0"] != "summary v2" {
+		t.Fatalf("unexpected comments %v", got)
+	}
+}
+
+func TestCommitStatus(t *testing.T) {
+	fake, srv := newFakeGitHub(t)
+	p := github.NewProvider(srv.URL, "acme/webapp", "ghp_test")
+	ctx := context.Background()
+
+	sha, err := p.PRHeadSHA(ctx, 7)
+	if err != nil || sha != "abc123" {
+		t.Fatalf("PRHeadSHA = %q, %v", sha, err)
+	}
+	st := &gitprovider.CommitStatus{Context: "codeforge/review", State: gitprovider.StatusSuccess, TargetURL: "http://ui/runs/r1"}
+	if err := p.SetCommitStatus(ctx, sha, st); err != nil {
+		t.Fatalf("SetCommitStatus: %v", err)
+	}
+	if len(fake.statuses) != 1 || fake.statuses[0]["state"] != "success" || fake.statuses[0]["context"] != "codeforge/review" {
+		t.Fatalf("unexpected statuses %v", fake.statuses)
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	if _, err := gitprovider.New("github", map[string]string{"github_repo": "webapp"}); err == nil {
+		t.Fatal("expected error for repo without owner")
+	}
+	p, err := gitprovider.New("github", map[string]string{"github_repo": "acme/webapp"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if p.Name() != "github" || !p.Capabilities().Clone || p.Capabilities().PullRequest {
+		t.Fatalf("expected clone without pull requests (no token), got %s %+v", p.Name(), p.Capabilities())
+	}
+	if _, ok := p.(gitprovider.PRCommenter); !ok {
+		t.Fatal("expected github provider to implement PRCommenter")
+	}
+}
@@ -0,0 +1,44 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/port/ciprovider"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+// Project config keys read by the GitHub provider, next to the API token
+// and webhook secret resolved by services that call the GitHub API.
+const (
+	ConfigRepo   = "github_repo"    // Repository, e.g. acme/webapp
+	ConfigAPIURL = "github_api_url" // API root, defaults to https://api.github.com
+	// ConfigInstallationID names the installation of the tenant's GitHub
+	// App the project authenticates as instead of a token secret; services
+	// mint its installation tokens into the API token.
+	ConfigInstallationID = "github_installation_id"
+)
+
+func init() {
+	gitprovider.Register(providerName, func(config map[string]string) (gitprovider.Provider, error) {
+		return fromConfig(config)
+	})
+	ciprovider.Register(actionsProviderName, func(config map[string]string) (ciprovider.Provider, error) {
+		p, err := fromConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		return NewActions(p), nil
+	})
+}
+
+// fromConfig creates the Provider a project config names.
+func fromConfig(config map[string]string) (*Provider, error) {
+	repo := config[ConfigRepo]
+	if owner, name, ok := strings.Cut(repo, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("github: %s must be owner/name, got %q", ConfigRepo, repo)
+	}
+	p := NewProvider(config[ConfigAPIURL], repo, config[gitprovider.ConfigToken])
+	p.SetWebhookSecret(config[gitprovider.ConfigWebhookKey])
+	return p, nil
+}
@@ -0,0 +1,118 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/domain/seatbelt"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+// failedConclusions are the check run conclusions seatbelt mode treats as
+// failures. Cancelled and skipped checks say nothing about the changes.
+var failedConclusions = map[string]bool{"failure": true, "timed_out": true}
+
+// checkRunPayload is the part of a "check_run" webhook delivery we use.
+type checkRunPayload struct {
+	Action   string `json:"action"`
+	CheckRun struct {
+		Name       string `json:"name"`
+		HeadSHA    string `json:"head_sha"`
+		Conclusion string `json:"conclusion"`
+		HTMLURL    string `json:"html_url"`
+		Output     struct {
+			Title   string `json:"title"`
+			Summary string `json:"summary"`
+			Text    string `json:"text"`
+		} `json:"output"`
+		CheckSuite struct {
+			HeadBranch string `json:"head_branch"`
+		} `json:"check_suite"`
+	} `json:"check_run"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// pushPayload is the part of a "push" webhook delivery we use.
+type pushPayload struct {
+	Ref     string `json:"ref"`
+	After   string `json:"after"`
+	Commits []struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+		URL     string `json:"url"`
+	} `json:"commits"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// ParseDeliveryWebhook verifies the X-Hub-Signature-256 header of a
+// "check_run" delivery for a failed check or a "push" delivery with revert
+// commits.
+func (p *Provider) ParseDeliveryWebhook(header http.Header, body []byte) (*seatbelt.Signal, error) {
+	if !validSignature(p.webhookSecret, header.Get("X-Hub-Signature-256"), body) {
+		return nil, gitprovider.ErrInvalidSignature
+	}
+	switch header.Get("X-GitHub-Event") {
+	case "check_run":
+		return p.checkRunSignal(body)
+	case "push":
+		return p.pushSignal(body)
+	}
+	return nil, gitprovider.ErrIgnoredEvent
+}
+
+func (p *Provider) checkRunSignal(body []byte) (*seatbelt.Signal, error) {
+	var payload checkRunPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("github: decode check_run event: %w", err)
+	}
+	cr := &payload.CheckRun
+	if payload.Action != "completed" || !failedConclusions[cr.Conclusion] ||
+		!strings.EqualFold(payload.Repository.FullName, p.repo) {
+		return nil, gitprovider.ErrIgnoredEvent
+	}
+	var log []string
+	for _, part := range []string{cr.Output.Title, cr.Output.Summary, cr.Output.Text} {
+		if part = strings.TrimSpace(part); part != "" {
+			log = append(log, part)
+		}
+	}
+	return &seatbelt.Signal{
+		Kind:   seatbelt.KindCheckFailed,
+		Branch: cr.CheckSuite.HeadBranch,
+		SHA:    cr.HeadSHA,
+		Check:  cr.Name,
+		URL:    cr.HTMLURL,
+		Log:    strings.Join(log, "\n\n"),
+	}, nil
+}
+
+func (p *Provider) pushSignal(body []byte) (*seatbelt.Signal, error) {
+	var payload pushPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("github: decode push event: %w", err)
+	}
+	branch, ok := strings.CutPrefix(payload.Ref, "refs/heads/")
+	if !ok || !strings.EqualFold(payload.Repository.FullName, p.repo) {
+		return nil, gitprovider.ErrIgnoredEvent
+	}
+	sig := &seatbelt.Signal{Kind: seatbelt.KindRevert, Branch: branch, SHA: payload.After}
+	for _, c := range payload.Commits {
+		commits, requests := seatbelt.ParseRevert(c.Message)
+		if len(commits) == 0 && len(requests) == 0 {
+			continue
+		}
+		sig.RevertedCommits = append(sig.RevertedCommits, commits...)
+		sig.RevertedRequests = append(sig.RevertedRequests, requests...)
+		sig.SHA, sig.URL = c.ID, c.URL
+	}
+	if len(sig.RevertedCommits) == 0 && len(sig.RevertedRequests) == 0 {
+		return nil, gitprovider.ErrIgnoredEvent
+	}
+	return sig, nil
+}
@@ -0,0 +1,69 @@
+package github_test
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/github"
+	"github.com/Strob0t/CodeForge/internal/domain/seatbelt"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+func TestParseDeliveryWebhook(t *testing.T) {
+	p := github.NewProvider("", "acme/webapp", "ghp_test")
+	p.SetWebhookSecret("s3cret")
+
+	failed := []byte(`{"action": "completed", "check_run": {"name": "test", "head_sha": "abc123", "conclusion": "failure",
+		"html_url": "https://github.com/acme/webapp/runs/1", "output": {"title": "2 tests failed", "summary": "", "text": "FAIL TestLogin"},
+		"check_suite": {"head_branch": "codeforge/run-1"}}, "repository": {"full_name": "acme/webapp"}}`)
+	sig, err := p.ParseDeliveryWebhook(signedHeader("s3cret", "check_run", failed), failed)
+	if err != nil {
+		t.Fatalf("parse check_run: %v", err)
+	}
+	want := seatbelt.Signal{
+		Kind: seatbelt.KindCheckFailed, Branch: "codeforge/run-1", SHA: "abc123", Check: "test",
+		URL: "https://github.com/acme/webapp/runs/1", Log: "2 tests failed\n\nFAIL TestLogin",
+	}
+	if !reflect.DeepEqual(*sig, want) {
+		t.Fatalf("signal = %+v, want %+v", *sig, want)
+	}
+
+	revert := []byte(`{"ref": "refs/heads/main", "after": "def456", "repository": {"full_name": "acme/webapp"}, "commits": [
+		{"id": "ccc111", "message": "Bump deps"},
+		{"id": "def456", "message": "Revert \"Fix login\"\n\nThis reverts commit abc1234.", "url": "https://github.com/acme/webapp/commit/def456"}]}`)
+	sig, err = p.ParseDeliveryWebhook(signedHeader("s3cret", "push", revert), revert)
+	if err != nil {
+		t.Fatalf("parse push: %v", err)
+	}
+	if sig.Kind != seatbelt.KindRevert || sig.Branch != "main" || sig.SHA != "def456" || !slices.Equal(sig.RevertedCommits, []string{"abc1234"}) {
+		t.Fatalf("unexpected signal %+v", sig)
+	}
+
+	passed := []byte(`{"action": "completed", "check_run": {"conclusion": "success"}, "repository": {"full_name": "acme/webapp"}}`)
+	otherRepo := []byte(`{"action": "completed", "check_run": {"conclusion": "failure"}, "repository": {"full_name": "acme/other"}}`)
+	plainPush := []byte(`{"ref": "refs/heads/main", "repository": {"full_name": "acme/webapp"}, "commits": [{"id": "a", "message": "Fix"}]}`)
+	tag := []byte(`{"ref": "refs/tags/v1", "repository": {"full_name": "acme/webapp"}, "commits": []}`)
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		want   error
+	}{
+		{"wrong secret", signedHeader("other", "check_run", failed), failed, gitprovider.ErrInvalidSignature},
+		{"other event", signedHeader("s3cret", "issues", failed), failed, gitprovider.ErrIgnoredEvent},
+		{"passed check", signedHeader("s3cret", "check_run", passed), passed, gitprovider.ErrIgnoredEvent},
+		{"other repo", signedHeader("s3cret", "check_run", otherRepo), otherRepo, gitprovider.ErrIgnoredEvent},
+		{"push without reverts", signedHeader("s3cret", "push", plainPush), plainPush, gitprovider.ErrIgnoredEvent},
+		{"tag push", signedHeader("s3cret", "push", tag), tag, gitprovider.ErrIgnoredEvent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := p.ParseDeliveryWebhook(tt.header, tt.body); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/vcsaccount"
+)
+
+// tokenExpirationLayouts are the formats of the
+// GitHub-Authentication-Token-Expiration header.
+var tokenExpirationLayouts = []string{"2006-01-02 15:04:05 MST", "2006-01-02 15:04:05 -0700"}
+
+// InspectToken reads the repository with the token: its permissions tell
+// whether the token can push, and the response headers carry the scopes
+// of classic personal access tokens, the expiry of expiring ones and the
+// rate limit. The login is read from /user, which tokens of apps cannot
+// read; they have no login.
+func (p *Provider) InspectToken(ctx context.Context) (*vcsaccount.TokenInfo, error) {
+	var repo struct {
+		Permissions struct {
+			Push bool `json:"push"`
+		} `json:"permissions"`
+	}
+	_, header, err := p.send(ctx, http.MethodGet, "/repos/"+p.repo, nil, nil, &repo)
+	if err != nil {
+		return nil, fmt.Errorf("github: read repository %s: %w", p.repo, err)
+	}
+	info := &vcsaccount.TokenInfo{CanPush: repo.Permissions.Push}
+	if raw := header.Get("X-OAuth-Scopes"); raw != "" {
+		for _, scope := range strings.Split(raw, ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				info.Scopes = append(info.Scopes, scope)
+			}
+		}
+	}
+	if raw := header.Get("GitHub-Authentication-Token-Expiration"); raw != "" {
+		for _, layout := range tokenExpirationLayouts {
+			if t, err := time.Parse(layout, raw); err == nil {
+				info.ExpiresAt = &t
+				break
+			}
+		}
+	}
+	info.RateLimit, _ = strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	info.RateRemaining, _ = strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		t := time.Unix(reset, 0).UTC()
+		info.RateReset = &t
+	}
+
+	var user struct {
+		Login string `json:"login"`
+	}
+	if _, err := p.do(ctx, http.MethodGet, "/user", nil, &user); err == nil {
+		info.Login = user.Login
+	}
+	return info, nil
+}
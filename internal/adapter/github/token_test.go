@@ -0,0 +1,54 @@
+package github_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/github"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+func TestInspectToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("Authorization") != "Bearer ghp_test":
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message": "Bad credentials"}`))
+		case r.URL.Path == "/repos/acme/webapp":
+			w.Header().Set("X-OAuth-Scopes", "repo, workflow")
+			w.Header().Set("GitHub-Authentication-Token-Expiration", "2026-11-01 12:00:00 UTC")
+			w.Header().Set("X-RateLimit-Limit", "5000")
+			w.Header().Set("X-RateLimit-Remaining", "4990")
+			w.Header().Set("X-RateLimit-Reset", "1792238400")
+			_, _ = w.Write([]byte(`{"full_name": "acme/webapp", "permissions": {"pull": true, "push": true}}`))
+		case r.URL.Path == "/user":
+			_, _ = w.Write([]byte(`{"login": "codeforge-bot"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	ctx := context.Background()
+
+	var p gitprovider.TokenInspector = github.NewProvider(srv.URL, "acme/webapp", "ghp_test")
+	info, err := p.InspectToken(ctx)
+	if err != nil {
+		t.Fatalf("InspectToken: %v", err)
+	}
+	want := time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)
+	if info.Login != "codeforge-bot" || !info.CanPush || len(info.Scopes) != 2 || info.Scopes[1] != "workflow" ||
+		info.ExpiresAt == nil || !info.ExpiresAt.Equal(want) || info.RateLimit != 5000 || info.RateRemaining != 4990 ||
+		info.RateReset == nil {
+		t.Fatalf("unexpected token info %+v", info)
+	}
+
+	if _, err := github.NewProvider(srv.URL, "acme/webapp", "ghp_revoked").InspectToken(ctx); err == nil {
+		t.Fatal("expected an error for an invalid token")
+	}
+	if _, err := github.NewProvider(srv.URL, "acme/gone", "ghp_test").InspectToken(ctx); err == nil {
+		t.Fatal("expected an error for an inaccessible repository")
+	}
+}
@@ -0,0 +1,125 @@
+package gitlab
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Strob0t/CodeForge/internal/domain/chatops"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+// notePayload is the part of a "Note Hook" delivery we use.
+type notePayload struct {
+	ObjectKind string `json:"object_kind"`
+	User       struct {
+		ID       int64  `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	Project struct {
+		ID                int64  `json:"id"`
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		ID           int64  `json:"id"`
+		Note         string `json:"note"`
+		NoteableType string `json:"noteable_type"`
+		Action       string `json:"action"` // Missing on older GitLab versions
+	} `json:"object_attributes"`
+	MergeRequest struct {
+		IID             int    `json:"iid"`
+		SourceBranch    string `json:"source_branch"`
+		TargetBranch    string `json:"target_branch"`
+		SourceProjectID int64  `json:"source_project_id"`
+		TargetProjectID int64  `json:"target_project_id"`
+	} `json:"merge_request"`
+}
+
+// ParsePRCommentWebhook verifies the X-Gitlab-Token header of a "Note Hook"
+// delivery for a new note on a merge request and looks up the author's
+// access level in the project.
+func (p *Provider) ParsePRCommentWebhook(ctx context.Context, header http.Header, body []byte) (*gitprovider.PRCommentEvent, error) {
+	token := header.Get("X-Gitlab-Token")
+	if p.webhookSecret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(p.webhookSecret)) != 1 {
+		return nil, gitprovider.ErrInvalidSignature
+	}
+	if header.Get("X-Gitlab-Event") != "Note Hook" {
+		return nil, gitprovider.ErrIgnoredEvent
+	}
+	var payload notePayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("gitlab: decode note event: %w", err)
+	}
+	attrs, mr := &payload.ObjectAttributes, &payload.MergeRequest
+	if payload.ObjectKind != "note" || attrs.NoteableType != "MergeRequest" ||
+		(attrs.Action != "" && attrs.Action != "create") ||
+		!p.isProject(payload.Project.ID, payload.Project.PathWithNamespace) ||
+		mr.SourceProjectID != mr.TargetProjectID {
+		return nil, gitprovider.ErrIgnoredEvent
+	}
+
+	role, err := p.memberRole(ctx, payload.User.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &gitprovider.PRCommentEvent{
+		Number:     mr.IID,
+		CommentID:  strconv.FormatInt(attrs.ID, 10),
+		Body:       attrs.Note,
+		Author:     payload.User.Username,
+		AuthorRole: role,
+		Branch:     mr.SourceBranch,
+		BaseBranch: mr.TargetBranch,
+	}, nil
+}
+
+// memberRole maps a user's access level in the project, including
+// inherited membership, to a commenter role.
+func (p *Provider) memberRole(ctx context.Context, userID int64) (chatops.Role, error) {
+	var member struct {
+		AccessLevel int `json:"access_level"`
+	}
+	err := p.do(ctx, http.MethodGet, fmt.Sprintf("/members/all/%d", userID), nil, &member)
+	if errors.Is(err, errNotFound) {
+		return chatops.RoleNone, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("gitlab: get member %d: %w", userID, err)
+	}
+	switch {
+	case member.AccessLevel >= 50:
+		return chatops.RoleOwner, nil
+	case member.AccessLevel >= 40:
+		return chatops.RoleMember, nil
+	case member.AccessLevel >= 30:
+		return chatops.RoleCollaborator, nil
+	case member.AccessLevel >= 20:
+		return chatops.RoleContributor, nil
+	default:
+		return chatops.RoleNone, nil
+	}
+}
+
+// ReplyToPR adds a note to a merge request (by IID).
+func (p *Provider) ReplyToPR(ctx context.Context, number int, body string) (string, error) {
+	var note struct {
+		ID int64 `json:"id"`
+	}
+	if err := p.do(ctx, http.MethodPost, fmt.Sprintf("/merge_requests/%d/notes", number), map[string]string{"body": body}, &note); err != nil {
+		return "", fmt.Errorf("gitlab: create merge request note: %w", err)
+	}
+	return strconv.FormatInt(note.ID, 10), nil
+}
+
+// UpdatePRReply replaces the body of a note on a merge request.
+func (p *Provider) UpdatePRReply(ctx context.Context, number int, commentID, body string) error {
+	path := fmt.Sprintf("/merge_requests/%d/notes/%s", number, commentID)
+	if err := p.do(ctx, http.MethodPut, path, map[string]string{"body": body}, nil); err != nil {
+		return fmt.Errorf("gitlab: update merge request note %s: %w", commentID, err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package gitlab
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/domain/issuerun"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+// SetWebhookSecret sets the token webhooks are sent with.
+func (p *Provider) SetWebhookSecret(secret string) {
+	p.webhookSecret = secret
+}
+
+type apiUser struct {
+	Username string `json:"username"`
+}
+
+type apiLabel struct {
+	Title string `json:"title"`
+}
+
+// issuePayload is the part of an "Issue Hook" delivery we use.
+type issuePayload struct {
+	ObjectKind string `json:"object_kind"`
+	Project    struct {
+		ID                int64  `json:"id"`
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		IID         int    `json:"iid"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		URL         string `json:"url"`
+		Action      string `json:"action"`
+	} `json:"object_attributes"`
+	Labels    []apiLabel `json:"labels"`
+	Assignees []apiUser  `json:"assignees"`
+	Changes   struct {
+		Labels *struct {
+			Previous []apiLabel `json:"previous"`
+			Current  []apiLabel `json:"current"`
+		} `json:"labels"`
+		Assignees *struct {
+			Previous []apiUser `json:"previous"`
+			Current  []apiUser `json:"current"`
+		} `json:"assignees"`
+	} `json:"changes"`
+}
+
+// ParseIssueWebhook verifies the X-Gitlab-Token header of an "Issue Hook"
+// delivery and returns the labels or assignees it added.
+func (p *Provider) ParseIssueWebhook(header http.Header, body []byte) (*gitprovider.IssueEvent, error) {
+	token := header.Get("X-Gitlab-Token")
+	if p.webhookSecret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(p.webhookSecret)) != 1 {
+		return nil, gitprovider.ErrInvalidSignature
+	}
+	if header.Get("X-Gitlab-Event") != "Issue Hook" {
+		return nil, gitprovider.ErrIgnoredEvent
+	}
+	var payload issuePayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("gitlab: decode issue event: %w", err)
+	}
+	if payload.ObjectKind != "issue" || !p.isProject(payload.Project.ID, payload.Project.PathWithNamespace) {
+		return nil, gitprovider.ErrIgnoredEvent
+	}
+
+	attrs := &payload.ObjectAttributes
+	ev := &gitprovider.IssueEvent{Issue: issuerun.Issue{
+		Number:    attrs.IID,
+		Title:     attrs.Title,
+		Body:      attrs.Description,
+		URL:       attrs.URL,
+		Labels:    labelTitles(payload.Labels),
+		Assignees: usernames(payload.Assignees),
+	}}
+	switch attrs.Action {
+	case "open":
+		ev.AddedLabels, ev.AddedAssignees = ev.Issue.Labels, ev.Issue.Assignees
+	case "update":
+		if c := payload.Changes.Labels; c != nil {
+			ev.AddedLabels = added(labelTitles(c.Previous), labelTitles(c.Current))
+		}
+		if c := payload.Changes.Assignees; c != nil {
+			ev.AddedAssignees = added(usernames(c.Previous), usernames(c.Current))
+		}
+	}
+	if len(ev.AddedLabels) == 0 && len(ev.AddedAssignees) == 0 {
+		return nil, gitprovider.ErrIgnoredEvent
+	}
+	return ev, nil
+}
+
+// CreateIssueComment adds a note to an issue (by IID).
+func (p *Provider) CreateIssueComment(ctx context.Context, number int, body string) (string, error) {
+	var note struct {
+		ID int64 `json:"id"`
+	}
+	if err := p.do(ctx, http.MethodPost, fmt.Sprintf("/issues/%d/notes", number), map[string]string{"body": body}, &note); err != nil {
+		return "", fmt.Errorf("gitlab: create issue note: %w", err)
+	}
+	return strconv.FormatInt(note.ID, 10), nil
+}
+
+// UpdateIssueComment replaces the body of a note on an issue.
+func (p *Provider) UpdateIssueComment(ctx context.Context, number int, commentID, body string) error {
+	path := fmt.Sprintf("/issues/%d/notes/%s", number, commentID)
+	if err := p.do(ctx, http.MethodPut, path, map[string]string{"body": body}, nil); err != nil {
+		return fmt.Errorf("gitlab: update issue note %s: %w", commentID, err)
+	}
+	return nil
+}
+
+// isProject reports whether a webhook names the provider's project, which
+// is configured as a path or a numeric ID.
+func (p *Provider) isProject(id int64, path string) bool {
+	return strings.EqualFold(path, p.project) || strconv.FormatInt(id, 10) == p.project
+}
+
+func labelTitles(labels []apiLabel) []string {
+	out := make([]string, 0, len(labels))
+	for _, l := range labels {
+		out = append(out, l.Title)
+	}
+	return out
+}
+
+func usernames(users []apiUser) []string {
+	out := make([]string, 0, len(users))
+	for _, u := range users {
+		out = append(out, u.Username)
+	}
+	return out
+}
+
+// added returns the names in current that are not in previous.
+func added(previous, current []string) []string {
+	var out []string
+	for _, c := range current {
+		found := false
+		for _, p := range previous {
+			if strings.EqualFold(p, c) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, c)
+		}
+	}
+	return out
+}
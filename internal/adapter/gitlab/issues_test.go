@@ -0,0 +1,198 @@
+package gitlab_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/gitlab"
+	"github.com/Strob0t/CodeForge/internal/domain/chatops"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+func issueHeader(token string) http.Header {
+	h := http.Header{}
+	h.Set("X-Gitlab-Event", "Issue Hook")
+	h.Set("X-Gitlab-Token", token)
+	return h
+}
+
+func TestParseIssueWebhook(t *testing.T) {
+	p := gitlab.NewProvider("", "group/webapp", "glpat")
+	p.SetWebhookSecret("s3cret")
+	if !p.Capabilities().Webhook || !p.Capabilities().Issues {
+		t.Fatalf("expected webhook and issue capabilities, got %+v", p.Capabilities())
+	}
+
+	updated := []byte(`{"object_kind": "issue", "project": {"id": 42, "path_with_namespace": "group/webapp"},
+		"object_attributes": {"iid": 9, "title": "Fix login", "description": "It breaks", "url": "https://gitlab.com/group/webapp/-/issues/9", "action": "update"},
+		"labels": [{"title": "bug"}, {"title": "codeforge"}], "assignees": [{"username": "alice"}],
+		"changes": {"labels": {"previous": [{"title": "bug"}], "current": [{"title": "bug"}, {"title": "codeforge"}]}}}`)
+	ev, err := p.ParseIssueWebhook(issueHeader("s3cret"), updated)
+	if err != nil {
+		t.Fatalf("parse update: %v", err)
+	}
+	if ev.Issue.Number != 9 || ev.Issue.Body != "It breaks" || !slices.Equal(ev.AddedLabels, []string{"codeforge"}) || len(ev.AddedAssignees) != 0 {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+
+	byID := gitlab.NewProvider("", "42", "glpat")
+	byID.SetWebhookSecret("s3cret")
+	opened := []byte(`{"object_kind": "issue", "project": {"id": 42, "path_with_namespace": "group/webapp"},
+		"object_attributes": {"iid": 10, "title": "New", "action": "open"}, "assignees": [{"username": "codeforge-bot"}]}`)
+	ev, err = byID.ParseIssueWebhook(issueHeader("s3cret"), opened)
+	if err != nil || !slices.Equal(ev.AddedAssignees, []string{"codeforge-bot"}) {
+		t.Fatalf("parse open = %+v, %v", ev, err)
+	}
+
+	otherProject := []byte(`{"object_kind": "issue", "project": {"id": 7, "path_with_namespace": "group/other"}, "object_attributes": {"action": "open"}}`)
+	unchanged := []byte(`{"object_kind": "issue", "project": {"id": 42, "path_with_namespace": "group/webapp"},
+		"object_attributes": {"iid": 9, "action": "update"}, "changes": {"title": {"previous": "a", "current": "b"}}}`)
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		want   error
+	}{
+		{"wrong token", issueHeader("other"), updated, gitprovider.ErrInvalidSignature},
+		{"no token", issueHeader(""), updated, gitprovider.ErrInvalidSignature},
+		{"other event", http.Header{"X-Gitlab-Event": {"Push Hook"}, "X-Gitlab-Token": {"s3cret"}}, updated, gitprovider.ErrIgnoredEvent},
+		{"other project", issueHeader("s3cret"), otherProject, gitprovider.ErrIgnoredEvent},
+		{"nothing added", issueHeader("s3cret"), unchanged, gitprovider.ErrIgnoredEvent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := p.ParseIssueWebhook(tt.header, tt.body); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestIssueComments(t *testing.T) {
+	notes := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case r.Method == http.MethodPost && r.URL.EscapedPath() == "/api/v4/projects/group%2Fwebapp/issues/9/notes":
+			notes["501"] = body["body"]
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id": 501}`))
+		case r.Method == http.MethodPut && r.URL.EscapedPath() == "/api/v4/projects/group%2Fwebapp/issues/9/notes/501":
+			notes["501"] = body["body"]
+			_, _ = w.Write([]byte(`{"id": 501}`))
+		default:
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL.EscapedPath())
+		}
+	}))
+	defer srv.Close()
+
+	p := gitlab.NewProvider(srv.URL+"/api/v4", "group/webapp", "glpat")
+	ctx := context.Background()
+	id, err := p.CreateIssueComment(ctx, 9, "started")
+	if err != nil || id != "501" {
+		t.Fatalf("CreateIssueComment = %q, %v", id, err)
+	}
+	if err := p.UpdateIssueComment(ctx, 9, id, "done"); err != nil {
+		t.Fatalf("UpdateIssueComment: %v", err)
+	}
+	if notes["501"] != "done" {
+		t.Fatalf("unexpected notes %v", notes)
+	}
+}
+
+func TestParsePRCommentWebhook(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/api/v4/projects/group%2Fwebapp/members/all/3":
+			_, _ = w.Write([]byte(`{"id": 3, "access_level": 30}`))
+		case "/api/v4/projects/group%2Fwebapp/members/all/4":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "404 Not found"}`))
+		default:
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL.EscapedPath())
+		}
+	}))
+	defer srv.Close()
+	p := gitlab.NewProvider(srv.URL+"/api/v4", "group/webapp", "glpat")
+	p.SetWebhookSecret("s3cret")
+	ctx := context.Background()
+	header := http.Header{"X-Gitlab-Event": {"Note Hook"}, "X-Gitlab-Token": {"s3cret"}}
+
+	note := func(userID, sourceProject int, noteable string) []byte {
+		return []byte(fmt.Sprintf(`{"object_kind": "note", "user": {"id": %d, "username": "alice"},
+			"project": {"id": 42, "path_with_namespace": "group/webapp"},
+			"object_attributes": {"id": 77, "note": "/codeforge rerun", "noteable_type": %q},
+			"merge_request": {"iid": 5, "source_branch": "fix-login", "target_branch": "main", "source_project_id": %d, "target_project_id": 42}}`,
+			userID, noteable, sourceProject))
+	}
+	ev, err := p.ParsePRCommentWebhook(ctx, header, note(3, 42, "MergeRequest"))
+	if err != nil {
+		t.Fatalf("parse note: %v", err)
+	}
+	want := gitprovider.PRCommentEvent{Number: 5, CommentID: "77", Body: "/codeforge rerun", Author: "alice",
+		AuthorRole: chatops.RoleCollaborator, Branch: "fix-login", BaseBranch: "main"}
+	if *ev != want {
+		t.Fatalf("expected %+v, got %+v", want, *ev)
+	}
+	if ev, err := p.ParsePRCommentWebhook(ctx, header, note(4, 42, "MergeRequest")); err != nil || ev.AuthorRole != chatops.RoleNone {
+		t.Fatalf("expected role none for a non-member, got %+v, %v", ev, err)
+	}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		want   error
+	}{
+		{"wrong token", http.Header{"X-Gitlab-Event": {"Note Hook"}, "X-Gitlab-Token": {"other"}}, note(3, 42, "MergeRequest"), gitprovider.ErrInvalidSignature},
+		{"issue note", header, note(3, 42, "Issue"), gitprovider.ErrIgnoredEvent},
+		{"fork", header, note(3, 99, "MergeRequest"), gitprovider.ErrIgnoredEvent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := p.ParsePRCommentWebhook(ctx, tt.header, tt.body); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestPRReplies(t *testing.T) {
+	notes := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case r.Method == http.MethodPost && r.URL.EscapedPath() == "/api/v4/projects/group%2Fwebapp/merge_requests/5/notes":
+			notes["601"] = body["body"]
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id": 601}`))
+		case r.Method == http.MethodPut && r.URL.EscapedPath() == "/api/v4/projects/group%2Fwebapp/merge_requests/5/notes/601":
+			notes["601"] = body["body"]
+			_, _ = w.Write([]byte(`{"id": 601}`))
+		default:
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL.EscapedPath())
+		}
+	}))
+	defer srv.Close()
+
+	p := gitlab.NewProvider(srv.URL+"/api/v4", "group/webapp", "glpat")
+	ctx := context.Background()
+	id, err := p.ReplyToPR(ctx, 5, "running")
+	if err != nil || id != "601" {
+		t.Fatalf("ReplyToPR = %q, %v", id, err)
+	}
+	if err := p.UpdatePRReply(ctx, 5, id, "done"); err != nil {
+		t.Fatalf("UpdatePRReply: %v", err)
+	}
+	if notes["601"] != "done" {
+		t.Fatalf("unexpected notes %v", notes)
+	}
+}
@@ -0,0 +1,94 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/ci"
+)
+
+// ciProviderName is the name GitLab CI registers as a CI provider under.
+const ciProviderName = "gitlab-ci"
+
+// Pipelines reads the pipelines of a project's commits from GitLab CI. It
+// implements ciprovider.Provider.
+type Pipelines struct {
+	p *Provider
+}
+
+// NewPipelines creates a Pipelines for the project of p.
+func NewPipelines(p *Provider) *Pipelines {
+	return &Pipelines{p: p}
+}
+
+// Name returns "gitlab-ci".
+func (c *Pipelines) Name() string { return ciProviderName }
+
+// apiPipeline is the part of a pipeline we use.
+type apiPipeline struct {
+	ID        int64      `json:"id"`
+	Ref       string     `json:"ref"`
+	SHA       string     `json:"sha"`
+	Status    string     `json:"status"`
+	Source    string     `json:"source"`
+	WebURL    string     `json:"web_url"`
+	CreatedAt *time.Time `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+// ListPipelines returns the pipelines of a commit on a branch.
+func (c *Pipelines) ListPipelines(ctx context.Context, branch, sha string) ([]ci.Pipeline, error) {
+	query := url.Values{}
+	if branch != "" {
+		query.Set("ref", branch)
+	}
+	if sha != "" {
+		query.Set("sha", sha)
+	}
+	pipelines, err := list[apiPipeline](ctx, c.p, "/pipelines", query)
+	if err != nil {
+		return nil, fmt.Errorf("gitlab: list pipelines: %w", err)
+	}
+	out := make([]ci.Pipeline, 0, len(pipelines))
+	for i := range pipelines {
+		ap := &pipelines[i]
+		pl := ci.Pipeline{
+			ID:        strconv.FormatInt(ap.ID, 10),
+			Name:      fmt.Sprintf("pipeline #%d", ap.ID),
+			Branch:    ap.Ref,
+			SHA:       ap.SHA,
+			State:     pipelineState(ap.Status),
+			URL:       ap.WebURL,
+			StartedAt: ap.CreatedAt,
+		}
+		if ap.Source != "" {
+			pl.Name += " (" + ap.Source + ")"
+		}
+		if pl.State.Done() {
+			pl.FinishedAt = ap.UpdatedAt
+		}
+		out = append(out, pl)
+	}
+	return out, nil
+}
+
+// pipelineState maps the status of a pipeline to a CI state. Pipelines
+// blocked on a manual job count as skipped, as GitLab does not fail them.
+func pipelineState(status string) ci.State {
+	switch status {
+	case "running":
+		return ci.StateRunning
+	case "success":
+		return ci.StateSuccess
+	case "failed":
+		return ci.StateFailure
+	case "canceled":
+		return ci.StateCancelled
+	case "skipped", "manual":
+		return ci.StateSkipped
+	}
+	return ci.StatePending // created, waiting_for_resource, preparing, pending, scheduled
+}
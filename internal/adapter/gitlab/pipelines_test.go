@@ -0,0 +1,45 @@
+package gitlab_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/ci"
+	"github.com/Strob0t/CodeForge/internal/port/ciprovider"
+)
+
+func TestListPipelines(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.EscapedPath() != "/api/v4/projects/group%2Fwebapp/pipelines" || q.Get("ref") != "codeforge/run-1" || q.Get("sha") != "abc123" {
+			t.Fatalf("unexpected request %s?%s", r.URL.EscapedPath(), r.URL.RawQuery)
+		}
+		_, _ = w.Write([]byte(`[
+			{"id": 78, "ref": "codeforge/run-1", "sha": "abc123", "status": "running", "source": "push", "web_url": "https://gitlab.com/group/webapp/-/pipelines/78"},
+			{"id": 77, "ref": "codeforge/run-1", "sha": "abc123", "status": "failed", "source": "push", "updated_at": "2026-10-17T10:00:00Z"}]`))
+	}))
+	defer srv.Close()
+
+	prov, err := ciprovider.New("gitlab-ci", map[string]string{"gitlab_project": "group/webapp", "gitlab_api_url": srv.URL + "/api/v4", "token": "glpat"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	pipelines, err := prov.ListPipelines(context.Background(), "codeforge/run-1", "abc123")
+	if err != nil {
+		t.Fatalf("ListPipelines: %v", err)
+	}
+	if len(pipelines) != 2 {
+		t.Fatalf("expected 2 pipelines, got %+v", pipelines)
+	}
+	if p := pipelines[0]; p.ID != "78" || p.Name != "pipeline #78 (push)" || p.State != ci.StateRunning || p.FinishedAt != nil {
+		t.Errorf("unexpected running pipeline %+v", p)
+	}
+	if p := pipelines[1]; p.State != ci.StateFailure || p.FinishedAt == nil {
+		t.Errorf("unexpected failed pipeline %+v", p)
+	}
+	if got := ci.Overall(pipelines); got != ci.StateFailure {
+		t.Errorf("Overall = %q, want failure", got)
+	}
+}
@@ -0,0 +1,216 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/chatops"
+	"github.com/Strob0t/CodeForge/internal/domain/issuerun"
+	"github.com/Strob0t/CodeForge/internal/domain/poll"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+const (
+	pollPerPage = 100
+	// maxPollPages bounds the pages a poll reads; older changes of a long
+	// outage are skipped.
+	maxPollPages = 10
+)
+
+// list reads the pages of a list endpoint below the project; query must
+// not set per_page or page.
+func list[T any](ctx context.Context, p *Provider, path string, query url.Values) ([]T, error) {
+	var out []T
+	for page := 1; page <= maxPollPages; page++ {
+		query.Set("per_page", strconv.Itoa(pollPerPage))
+		query.Set("page", strconv.Itoa(page))
+		var batch []T
+		if err := p.do(ctx, http.MethodGet, path+"?"+query.Encode(), nil, &batch); err != nil {
+			return nil, err
+		}
+		out = append(out, batch...)
+		if len(batch) < pollPerPage {
+			break
+		}
+	}
+	return out, nil
+}
+
+// pollStart parses the cursor position. A cursor without a position is
+// moved to the present and returns false.
+func pollStart(cursor *poll.Cursor) (time.Time, bool, error) {
+	if cursor.Position == "" {
+		cursor.Position = time.Now().UTC().Format(time.RFC3339Nano)
+		return time.Time{}, false, nil
+	}
+	since, err := time.Parse(time.RFC3339Nano, cursor.Position)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("gitlab: invalid cursor %q", cursor.Position)
+	}
+	return since, true, nil
+}
+
+// PollIssues reads the issues updated since the time recorded in the
+// cursor. Issues opened since then add all of their labels and assignees;
+// for older issues the labels added since then are read from their label
+// events. GitLab keeps no such events for assignees, so assigning an
+// existing issue is only seen by webhooks.
+func (p *Provider) PollIssues(ctx context.Context, cursor *poll.Cursor) ([]gitprovider.IssueEvent, error) {
+	since, ok, err := pollStart(cursor)
+	if !ok || err != nil {
+		return nil, err
+	}
+	type apiIssue struct {
+		IID         int       `json:"iid"`
+		Title       string    `json:"title"`
+		Description string    `json:"description"`
+		WebURL      string    `json:"web_url"`
+		Labels      []string  `json:"labels"`
+		Assignees   []apiUser `json:"assignees"`
+		CreatedAt   time.Time `json:"created_at"`
+		UpdatedAt   time.Time `json:"updated_at"`
+	}
+	issues, err := list[apiIssue](ctx, p, "/issues", url.Values{
+		"updated_after": {cursor.Position},
+		"order_by":      {"updated_at"},
+		"sort":          {"asc"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gitlab: list issues: %w", err)
+	}
+
+	latest := since
+	var out []gitprovider.IssueEvent
+	for i := range issues {
+		in := &issues[i]
+		if in.UpdatedAt.After(latest) {
+			latest = in.UpdatedAt
+		}
+		ev := gitprovider.IssueEvent{Issue: issuerun.Issue{
+			Number:    in.IID,
+			Title:     in.Title,
+			Body:      in.Description,
+			URL:       in.WebURL,
+			Labels:    in.Labels,
+			Assignees: usernames(in.Assignees),
+		}}
+		if in.CreatedAt.After(since) {
+			ev.AddedLabels, ev.AddedAssignees = ev.Issue.Labels, ev.Issue.Assignees
+		} else {
+			type labelEvent struct {
+				Action string `json:"action"`
+				Label  *struct {
+					Name string `json:"name"`
+				} `json:"label"` // null once the label is deleted
+				CreatedAt time.Time `json:"created_at"`
+			}
+			events, err := list[labelEvent](ctx, p, fmt.Sprintf("/issues/%d/resource_label_events", in.IID), url.Values{})
+			if err != nil {
+				return nil, fmt.Errorf("gitlab: list label events of issue %d: %w", in.IID, err)
+			}
+			for _, e := range events {
+				if e.Action == "add" && e.Label != nil && e.CreatedAt.After(since) {
+					ev.AddedLabels = append(ev.AddedLabels, e.Label.Name)
+				}
+			}
+		}
+		if len(ev.AddedLabels) > 0 || len(ev.AddedAssignees) > 0 {
+			out = append(out, ev)
+		}
+	}
+	cursor.Position = latest.UTC().Format(time.RFC3339Nano)
+	return out, nil
+}
+
+// PollPRComments reads the notes created since the time recorded in the
+// cursor on the merge requests updated since then, and looks up the
+// access level of the authors of commands.
+func (p *Provider) PollPRComments(ctx context.Context, cursor *poll.Cursor) ([]gitprovider.PRCommentEvent, error) {
+	since, ok, err := pollStart(cursor)
+	if !ok || err != nil {
+		return nil, err
+	}
+	type apiMergeRequest struct {
+		IID             int       `json:"iid"`
+		SourceBranch    string    `json:"source_branch"`
+		TargetBranch    string    `json:"target_branch"`
+		SourceProjectID int64     `json:"source_project_id"`
+		TargetProjectID int64     `json:"target_project_id"`
+		UpdatedAt       time.Time `json:"updated_at"`
+	}
+	mrs, err := list[apiMergeRequest](ctx, p, "/merge_requests", url.Values{
+		"updated_after": {cursor.Position},
+		"order_by":      {"updated_at"},
+		"sort":          {"asc"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gitlab: list merge requests: %w", err)
+	}
+
+	type apiNote struct {
+		ID     int64  `json:"id"`
+		Body   string `json:"body"`
+		System bool   `json:"system"`
+		Author struct {
+			ID       int64  `json:"id"`
+			Username string `json:"username"`
+		} `json:"author"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	type found struct {
+		ev gitprovider.PRCommentEvent
+		at time.Time
+	}
+	latest := since
+	var events []found
+	for i := range mrs {
+		mr := &mrs[i]
+		if mr.UpdatedAt.After(latest) {
+			latest = mr.UpdatedAt
+		}
+		if mr.SourceProjectID != mr.TargetProjectID {
+			continue
+		}
+		notes, err := list[apiNote](ctx, p, fmt.Sprintf("/merge_requests/%d/notes", mr.IID), url.Values{
+			"order_by": {"created_at"},
+			"sort":     {"asc"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("gitlab: list notes of merge request %d: %w", mr.IID, err)
+		}
+		for _, n := range notes {
+			if n.System || !n.CreatedAt.After(since) {
+				continue
+			}
+			if _, ok, _ := chatops.Parse(n.Body); !ok {
+				continue
+			}
+			role, err := p.memberRole(ctx, n.Author.ID)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, found{at: n.CreatedAt, ev: gitprovider.PRCommentEvent{
+				Number:     mr.IID,
+				CommentID:  strconv.FormatInt(n.ID, 10),
+				Body:       n.Body,
+				Author:     n.Author.Username,
+				AuthorRole: role,
+				Branch:     mr.SourceBranch,
+				BaseBranch: mr.TargetBranch,
+			}})
+		}
+	}
+	slices.SortStableFunc(events, func(a, b found) int { return a.at.Compare(b.at) })
+
+	out := make([]gitprovider.PRCommentEvent, 0, len(events))
+	for _, f := range events {
+		out = append(out, f.ev)
+	}
+	cursor.Position = latest.UTC().Format(time.RFC3339Nano)
+	return out, nil
+}
@@ -0,0 +1,103 @@
+package gitlab_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/gitlab"
+	"github.com/Strob0t/CodeForge/internal/domain/chatops"
+	"github.com/Strob0t/CodeForge/internal/domain/poll"
+)
+
+// newPollGitLab serves the first page of each list endpoint of group/webapp.
+func newPollGitLab(t *testing.T, pages map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "glpat" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		path := strings.TrimPrefix(r.URL.Path, "/projects/group/webapp")
+		if path == "/members/all/5" {
+			_, _ = w.Write([]byte(`{"access_level": 40}`))
+			return
+		}
+		body, ok := pages[path]
+		if !ok {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("page") != "1" {
+			body = `[]`
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestPollIssues(t *testing.T) {
+	srv := newPollGitLab(t, map[string]string{
+		"/issues": `[
+			{"iid": 9, "title": "Fix login", "labels": ["bug", "codeforge"], "created_at": "2026-10-01T08:00:00Z", "updated_at": "2026-10-17T09:00:00Z"},
+			{"iid": 10, "title": "New", "labels": [], "assignees": [{"username": "codeforge-bot"}], "created_at": "2026-10-17T09:30:00.5Z", "updated_at": "2026-10-17T09:30:00.5Z"}
+		]`,
+		"/issues/9/resource_label_events": `[
+			{"action": "add", "label": {"name": "bug"}, "created_at": "2026-10-01T08:00:00Z"},
+			{"action": "add", "label": {"name": "codeforge"}, "created_at": "2026-10-17T09:00:00Z"}
+		]`,
+	})
+	p := gitlab.NewProvider(srv.URL, "group/webapp", "glpat")
+
+	cursor := &poll.Cursor{}
+	if evs, err := p.PollIssues(context.Background(), cursor); err != nil || len(evs) != 0 || cursor.Position == "" {
+		t.Fatalf("expected an empty first poll, got %+v, %+v, %v", evs, cursor, err)
+	}
+
+	cursor = &poll.Cursor{Position: "2026-10-17T00:00:00Z"}
+	evs, err := p.PollIssues(context.Background(), cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 2 || evs[0].Issue.Number != 9 || !slices.Equal(evs[0].AddedLabels, []string{"codeforge"}) ||
+		evs[1].Issue.Number != 10 || !slices.Equal(evs[1].AddedAssignees, []string{"codeforge-bot"}) {
+		t.Fatalf("unexpected events %+v", evs)
+	}
+	if cursor.Position != "2026-10-17T09:30:00.5Z" {
+		t.Fatalf("cursor not advanced: %+v", cursor)
+	}
+}
+
+func TestPollPRComments(t *testing.T) {
+	srv := newPollGitLab(t, map[string]string{
+		"/merge_requests": `[
+			{"iid": 3, "source_branch": "fix-login", "target_branch": "main", "source_project_id": 42, "target_project_id": 42, "updated_at": "2026-10-17T10:00:00Z"},
+			{"iid": 4, "source_branch": "patch-1", "target_branch": "main", "source_project_id": 99, "target_project_id": 42, "updated_at": "2026-10-17T10:05:00Z"}
+		]`,
+		"/merge_requests/3/notes": `[
+			{"id": 70, "body": "/codeforge explain", "author": {"id": 5, "username": "alice"}, "created_at": "2026-10-16T10:00:00Z"},
+			{"id": 71, "body": "added 1 commit", "system": true, "created_at": "2026-10-17T09:00:00Z"},
+			{"id": 72, "body": "LGTM", "author": {"id": 5, "username": "alice"}, "created_at": "2026-10-17T09:10:00Z"},
+			{"id": 73, "body": "/codeforge fix", "author": {"id": 5, "username": "alice"}, "created_at": "2026-10-17T10:00:00Z"}
+		]`,
+	})
+	p := gitlab.NewProvider(srv.URL, "group/webapp", "glpat")
+
+	cursor := &poll.Cursor{Position: "2026-10-17T00:00:00Z"}
+	evs, err := p.PollPRComments(context.Background(), cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 1 || evs[0].Number != 3 || evs[0].CommentID != "73" || evs[0].AuthorRole != chatops.RoleMember ||
+		evs[0].Branch != "fix-login" || evs[0].BaseBranch != "main" {
+		t.Fatalf("unexpected events %+v", evs)
+	}
+	if cursor.Position != "2026-10-17T10:05:00Z" {
+		t.Fatalf("cursor not advanced: %+v", cursor)
+	}
+}
@@ -0,0 +1,176 @@
+// Package gitlab implements the gitprovider.Provider interface for
+// repositories hosted on GitLab. Local repository operations use the git
+// CLI; commit statuses and issue and merge request notes go through the
+// GitLab REST API v4, and issue and note webhooks are verified with their
+// secret token. Projects without webhooks poll the same changes from the
+// API. Pipelines reads GitLab CI pipelines as a CI provider.
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/gitlocal"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+const (
+	providerName  = "gitlab"
+	defaultAPIURL = "https://gitlab.com/api/v4"
+)
+
+// Provider works on clones of one GitLab project.
+type Provider struct {
+	gitlocal.Provider
+
+	apiURL     string
+	project    string // path with namespace, e.g. group/webapp, or numeric ID
+	token      string
+	httpClient *http.Client
+
+	webhookSecret string
+}
+
+// NewProvider creates a Provider for project. apiURL may be empty for
+// gitlab.com or point at the /api/v4 root of a self-managed instance.
+func NewProvider(apiURL, project, token string) *Provider {
+	if apiURL == "" {
+		apiURL = defaultAPIURL
+	}
+	return &Provider{
+		apiURL:  strings.TrimRight(apiURL, "/"),
+		project: project,
+		token:   token,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Name returns "gitlab".
+func (p *Provider) Name() string { return providerName }
+
+// Capabilities returns what the GitLab provider supports. Commit statuses
+// and issue and merge request notes need an API token; webhooks need a
+// webhook secret.
+func (p *Provider) Capabilities() gitprovider.Capabilities {
+	caps := p.Provider.Capabilities()
+	caps.PullRequest = p.token != ""
+	caps.CommitStatus = p.token != ""
+	caps.Issues = p.token != ""
+	caps.Webhook = p.webhookSecret != ""
+	return caps
+}
+
+// PRHeadSHA returns the head commit of a merge request (by IID).
+func (p *Provider) PRHeadSHA(ctx context.Context, number int) (string, error) {
+	var mr struct {
+		SHA string `json:"sha"`
+	}
+	if err := p.do(ctx, http.MethodGet, fmt.Sprintf("/merge_requests/%d", number), nil, &mr); err != nil {
+		return "", fmt.Errorf("gitlab: get merge request %d: %w", number, err)
+	}
+	return mr.SHA, nil
+}
+
+// SetCommitStatus creates a commit status named st.Context. GitLab rejects
+// posting the state a status already has, which is treated as success.
+func (p *Provider) SetCommitStatus(ctx context.Context, sha string, st *gitprovider.CommitStatus) error {
+	body := map[string]string{
+		"state":       stateFor(st.State),
+		"name":        st.Context,
+		"description": st.Description,
+		"target_url":  st.TargetURL,
+	}
+	err := p.do(ctx, http.MethodPost, "/statuses/"+url.PathEscape(sha), body, nil)
+	if err != nil && !errors.Is(err, errSameState) {
+		return fmt.Errorf("gitlab: set commit status %s: %w", st.Context, err)
+	}
+	return nil
+}
+
+// stateFor maps a commit status state to GitLab's state names.
+func stateFor(s gitprovider.StatusState) string {
+	switch s {
+	case gitprovider.StatusSuccess:
+		return "success"
+	case gitprovider.StatusFailure, gitprovider.StatusError:
+		return "failed"
+	default:
+		return "pending"
+	}
+}
+
+// --- HTTP ---
+
+var (
+	// errSameState marks GitLab's rejection of a no-op status transition.
+	errSameState = errors.New("status already in that state")
+	errNotFound  = errors.New("not found")
+)
+
+// do sends a request for a path below the project and decodes the response
+// into out (if non-nil).
+func (p *Provider) do(ctx context.Context, method, path string, in, out any) error {
+	_, err := p.send(ctx, method, "/projects/"+url.PathEscape(p.project)+path, in, out)
+	return err
+}
+
+// send sends a request for a path below the API root and decodes the
+// response into out (if non-nil). It returns the response headers
+// alongside any error.
+func (p *Provider) send(ctx context.Context, method, path string, in, out any) (http.Header, error) {
+	if p.token == "" {
+		return nil, errors.New("no API token configured")
+	}
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, fmt.Errorf("marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.apiURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("PRIVATE-TOKEN", p.token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.Header, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode == http.StatusBadRequest && bytes.Contains(data, []byte("Cannot transition status")) {
+		return resp.Header, errSameState
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return resp.Header, fmt.Errorf("API error %d: %w", resp.StatusCode, errNotFound)
+	}
+	if resp.StatusCode >= 400 {
+		return resp.Header, fmt.Errorf("API error %d: %s", resp.StatusCode, string(data))
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp.Header, fmt.Errorf("unmarshal response: %w", err)
+		}
+	}
+	return resp.Header, nil
+}
@@ -0,0 +1,70 @@
+package gitlab_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/gitlab"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+func TestCommitStatus(t *testing.T) {
+	var posted []map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "glpat" {
+			t.Fatal("expected private token header")
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.EscapedPath() == "/api/v4/projects/group%2Fwebapp/merge_requests/5":
+			_, _ = w.Write([]byte(`{"iid": 5, "sha": "def456"}`))
+		case r.Method == http.MethodPost && r.URL.EscapedPath() == "/api/v4/projects/group%2Fwebapp/statuses/def456":
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if len(posted) > 0 && posted[len(posted)-1]["state"] == body["state"] {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"message": "Cannot transition status via :run from :running"}`))
+				return
+			}
+			posted = append(posted, body)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL.EscapedPath())
+		}
+	}))
+	defer srv.Close()
+
+	p := gitlab.NewProvider(srv.URL+"/api/v4", "group/webapp", "glpat")
+	ctx := context.Background()
+	sha, err := p.PRHeadSHA(ctx, 5)
+	if err != nil || sha != "def456" {
+		t.Fatalf("PRHeadSHA = %q, %v", sha, err)
+	}
+	for _, state := range []gitprovider.StatusState{gitprovider.StatusPending, gitprovider.StatusPending, gitprovider.StatusFailure} {
+		st := &gitprovider.CommitStatus{Context: "codeforge/review", State: state, TargetURL: "http://ui/runs/r1"}
+		if err := p.SetCommitStatus(ctx, sha, st); err != nil {
+			t.Fatalf("SetCommitStatus(%s): %v", state, err)
+		}
+	}
+	if len(posted) != 2 || posted[0]["state"] != "pending" || posted[1]["state"] != "failed" || posted[1]["name"] != "codeforge/review" {
+		t.Fatalf("unexpected statuses %v", posted)
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	if _, err := gitprovider.New("gitlab", nil); err == nil {
+		t.Fatal("expected error when project is missing")
+	}
+	p, err := gitprovider.New("gitlab", map[string]string{"gitlab_project": "group/webapp", "token": "glpat"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if p.Name() != "gitlab" || !p.Capabilities().CommitStatus {
+		t.Fatalf("expected gitlab with commit statuses, got %s %+v", p.Name(), p.Capabilities())
+	}
+	if _, ok := p.(gitprovider.StatusReporter); !ok {
+		t.Fatal("expected gitlab provider to implement StatusReporter")
+	}
+}
@@ -0,0 +1,38 @@
+package gitlab
+
+import (
+	"fmt"
+
+	"github.com/Strob0t/CodeForge/internal/port/ciprovider"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+// Project config keys read by the GitLab provider, next to the API token
+// and webhook secret resolved by services that call the GitLab API.
+const (
+	configProject = "gitlab_project" // Project path, e.g. group/webapp, or numeric ID
+	configAPIURL  = "gitlab_api_url" // API root, defaults to https://gitlab.com/api/v4
+)
+
+func init() {
+	gitprovider.Register(providerName, func(config map[string]string) (gitprovider.Provider, error) {
+		return fromConfig(config)
+	})
+	ciprovider.Register(ciProviderName, func(config map[string]string) (ciprovider.Provider, error) {
+		p, err := fromConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		return NewPipelines(p), nil
+	})
+}
+
+// fromConfig creates the Provider a project config names.
+func fromConfig(config map[string]string) (*Provider, error) {
+	if config[configProject] == "" {
+		return nil, fmt.Errorf("gitlab: %s is required", configProject)
+	}
+	p := NewProvider(config[configAPIURL], config[configProject], config[gitprovider.ConfigToken])
+	p.SetWebhookSecret(config[gitprovider.ConfigWebhookKey])
+	return p, nil
+}
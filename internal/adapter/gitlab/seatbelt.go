@@ -0,0 +1,136 @@
+package gitlab
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/domain/seatbelt"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+// webhookProject identifies the project of a webhook delivery.
+type webhookProject struct {
+	ID                int64  `json:"id"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	WebURL            string `json:"web_url"`
+}
+
+// pipelinePayload is the part of a "Pipeline Hook" delivery we use.
+type pipelinePayload struct {
+	ObjectKind       string         `json:"object_kind"`
+	Project          webhookProject `json:"project"`
+	ObjectAttributes struct {
+		ID     int64  `json:"id"`
+		Ref    string `json:"ref"`
+		Tag    bool   `json:"tag"`
+		SHA    string `json:"sha"`
+		Status string `json:"status"`
+		URL    string `json:"url"`
+		Name   string `json:"name"`
+	} `json:"object_attributes"`
+	Builds []struct {
+		Name          string `json:"name"`
+		Stage         string `json:"stage"`
+		Status        string `json:"status"`
+		FailureReason string `json:"failure_reason"`
+		AllowFailure  bool   `json:"allow_failure"`
+	} `json:"builds"`
+}
+
+// pushPayload is the part of a "Push Hook" delivery we use.
+type pushPayload struct {
+	ObjectKind string         `json:"object_kind"`
+	Ref        string         `json:"ref"`
+	After      string         `json:"after"`
+	Project    webhookProject `json:"project"`
+	Commits    []struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+		URL     string `json:"url"`
+	} `json:"commits"`
+}
+
+// ParseDeliveryWebhook verifies the X-Gitlab-Token header of a "Pipeline
+// Hook" delivery for a failed pipeline or a "Push Hook" delivery with
+// revert commits.
+func (p *Provider) ParseDeliveryWebhook(header http.Header, body []byte) (*seatbelt.Signal, error) {
+	token := header.Get("X-Gitlab-Token")
+	if p.webhookSecret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(p.webhookSecret)) != 1 {
+		return nil, gitprovider.ErrInvalidSignature
+	}
+	switch header.Get("X-Gitlab-Event") {
+	case "Pipeline Hook":
+		return p.pipelineSignal(body)
+	case "Push Hook":
+		return p.pushSignal(body)
+	}
+	return nil, gitprovider.ErrIgnoredEvent
+}
+
+func (p *Provider) pipelineSignal(body []byte) (*seatbelt.Signal, error) {
+	var payload pipelinePayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("gitlab: decode pipeline event: %w", err)
+	}
+	attrs := &payload.ObjectAttributes
+	if payload.ObjectKind != "pipeline" || attrs.Status != "failed" || attrs.Tag ||
+		!p.isProject(payload.Project.ID, payload.Project.PathWithNamespace) {
+		return nil, gitprovider.ErrIgnoredEvent
+	}
+
+	var log []string
+	for _, b := range payload.Builds {
+		if b.Status != "failed" || b.AllowFailure {
+			continue
+		}
+		line := fmt.Sprintf("Job %q (stage %s) failed", b.Name, b.Stage)
+		if b.FailureReason != "" {
+			line += ": " + b.FailureReason
+		}
+		log = append(log, line)
+	}
+	name := attrs.Name
+	if name == "" {
+		name = fmt.Sprintf("pipeline #%d", attrs.ID)
+	}
+	url := attrs.URL
+	if url == "" && payload.Project.WebURL != "" {
+		url = fmt.Sprintf("%s/-/pipelines/%d", payload.Project.WebURL, attrs.ID)
+	}
+	return &seatbelt.Signal{
+		Kind:   seatbelt.KindCheckFailed,
+		Branch: attrs.Ref,
+		SHA:    attrs.SHA,
+		Check:  name,
+		URL:    url,
+		Log:    strings.Join(log, "\n"),
+	}, nil
+}
+
+func (p *Provider) pushSignal(body []byte) (*seatbelt.Signal, error) {
+	var payload pushPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("gitlab: decode push event: %w", err)
+	}
+	branch, ok := strings.CutPrefix(payload.Ref, "refs/heads/")
+	if payload.ObjectKind != "push" || !ok || !p.isProject(payload.Project.ID, payload.Project.PathWithNamespace) {
+		return nil, gitprovider.ErrIgnoredEvent
+	}
+	sig := &seatbelt.Signal{Kind: seatbelt.KindRevert, Branch: branch, SHA: payload.After}
+	for _, c := range payload.Commits {
+		commits, requests := seatbelt.ParseRevert(c.Message)
+		if len(commits) == 0 && len(requests) == 0 {
+			continue
+		}
+		sig.RevertedCommits = append(sig.RevertedCommits, commits...)
+		sig.RevertedRequests = append(sig.RevertedRequests, requests...)
+		sig.SHA, sig.URL = c.ID, c.URL
+	}
+	if len(sig.RevertedCommits) == 0 && len(sig.RevertedRequests) == 0 {
+		return nil, gitprovider.ErrIgnoredEvent
+	}
+	return sig, nil
+}
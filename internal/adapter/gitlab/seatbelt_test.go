@@ -0,0 +1,78 @@
+package gitlab_test
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/gitlab"
+	"github.com/Strob0t/CodeForge/internal/domain/seatbelt"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+func hookHeader(event, token string) http.Header {
+	h := http.Header{}
+	h.Set("X-Gitlab-Event", event)
+	h.Set("X-Gitlab-Token", token)
+	return h
+}
+
+func TestParseDeliveryWebhook(t *testing.T) {
+	p := gitlab.NewProvider("", "group/webapp", "glpat")
+	p.SetWebhookSecret("s3cret")
+
+	failed := []byte(`{"object_kind": "pipeline",
+		"project": {"id": 42, "path_with_namespace": "group/webapp", "web_url": "https://gitlab.com/group/webapp"},
+		"object_attributes": {"id": 77, "ref": "codeforge/run-1", "tag": false, "sha": "abc123", "status": "failed"},
+		"builds": [
+			{"name": "lint", "stage": "test", "status": "success"},
+			{"name": "unit", "stage": "test", "status": "failed", "failure_reason": "script_failure"},
+			{"name": "flaky", "stage": "test", "status": "failed", "allow_failure": true}]}`)
+	sig, err := p.ParseDeliveryWebhook(hookHeader("Pipeline Hook", "s3cret"), failed)
+	if err != nil {
+		t.Fatalf("parse pipeline: %v", err)
+	}
+	want := seatbelt.Signal{
+		Kind: seatbelt.KindCheckFailed, Branch: "codeforge/run-1", SHA: "abc123", Check: "pipeline #77",
+		URL: "https://gitlab.com/group/webapp/-/pipelines/77", Log: `Job "unit" (stage test) failed: script_failure`,
+	}
+	if !reflect.DeepEqual(*sig, want) {
+		t.Fatalf("signal = %+v, want %+v", *sig, want)
+	}
+
+	revert := []byte(`{"object_kind": "push", "ref": "refs/heads/main", "after": "def456",
+		"project": {"id": 42, "path_with_namespace": "group/webapp"},
+		"commits": [{"id": "def456", "message": "Revert \"Fix login\"\n\nThis reverts merge request !9", "url": "https://gitlab.com/group/webapp/-/commit/def456"}]}`)
+	sig, err = p.ParseDeliveryWebhook(hookHeader("Push Hook", "s3cret"), revert)
+	if err != nil {
+		t.Fatalf("parse push: %v", err)
+	}
+	if sig.Kind != seatbelt.KindRevert || sig.Branch != "main" || sig.SHA != "def456" || !slices.Equal(sig.RevertedRequests, []int{9}) {
+		t.Fatalf("unexpected signal %+v", sig)
+	}
+
+	passed := []byte(`{"object_kind": "pipeline", "project": {"id": 42}, "object_attributes": {"status": "success"}}`)
+	otherProject := []byte(`{"object_kind": "pipeline", "project": {"id": 7, "path_with_namespace": "group/other"}, "object_attributes": {"status": "failed"}}`)
+	plainPush := []byte(`{"object_kind": "push", "ref": "refs/heads/main", "project": {"id": 42}, "commits": [{"id": "a", "message": "Fix"}]}`)
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		want   error
+	}{
+		{"wrong token", hookHeader("Pipeline Hook", "other"), failed, gitprovider.ErrInvalidSignature},
+		{"other event", hookHeader("Issue Hook", "s3cret"), failed, gitprovider.ErrIgnoredEvent},
+		{"passed pipeline", hookHeader("Pipeline Hook", "s3cret"), passed, gitprovider.ErrIgnoredEvent},
+		{"other project", hookHeader("Pipeline Hook", "s3cret"), otherProject, gitprovider.ErrIgnoredEvent},
+		{"push without reverts", hookHeader("Push Hook", "s3cret"), plainPush, gitprovider.ErrIgnoredEvent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := p.ParseDeliveryWebhook(tt.header, tt.body); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/vcsaccount"
+)
+
+// developerAccess is the lowest access level that can push.
+const developerAccess = 30
+
+// InspectToken reads the project with the token: its access levels tell
+// whether the token can push, and the response headers carry the rate
+// limit. The scopes and expiry are read from /personal_access_tokens/self,
+// which covers personal, project and group access tokens; other tokens
+// have none. The login is the username of /user.
+func (p *Provider) InspectToken(ctx context.Context) (*vcsaccount.TokenInfo, error) {
+	type access struct {
+		AccessLevel int `json:"access_level"`
+	}
+	var proj struct {
+		Permissions struct {
+			ProjectAccess *access `json:"project_access"`
+			GroupAccess   *access `json:"group_access"`
+		} `json:"permissions"`
+	}
+	header, err := p.send(ctx, http.MethodGet, "/projects/"+url.PathEscape(p.project), nil, &proj)
+	if err != nil {
+		return nil, fmt.Errorf("gitlab: read project %s: %w", p.project, err)
+	}
+	info := &vcsaccount.TokenInfo{}
+	for _, a := range []*access{proj.Permissions.ProjectAccess, proj.Permissions.GroupAccess} {
+		if a != nil && a.AccessLevel >= developerAccess {
+			info.CanPush = true
+		}
+	}
+	info.RateLimit, _ = strconv.Atoi(header.Get("RateLimit-Limit"))
+	info.RateRemaining, _ = strconv.Atoi(header.Get("RateLimit-Remaining"))
+	if reset, err := strconv.ParseInt(header.Get("RateLimit-Reset"), 10, 64); err == nil {
+		t := time.Unix(reset, 0).UTC()
+		info.RateReset = &t
+	}
+
+	var token struct {
+		Scopes    []string `json:"scopes"`
+		ExpiresAt string   `json:"expires_at"` // Date; the token expires at its start (UTC)
+	}
+	if _, err := p.send(ctx, http.MethodGet, "/personal_access_tokens/self", nil, &token); err == nil {
+		info.Scopes = token.Scopes
+		if t, err := time.Parse(time.DateOnly, token.ExpiresAt); err == nil {
+			info.ExpiresAt = &t
+		}
+	}
+	var user struct {
+		Username string `json:"username"`
+	}
+	if _, err := p.send(ctx, http.MethodGet, "/user", nil, &user); err == nil {
+		info.Login = user.Username
+	}
+	return info, nil
+}
@@ -0,0 +1,53 @@
+package gitlab_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/gitlab"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+func TestInspectToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("PRIVATE-TOKEN") != "glpat-test":
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message": "401 Unauthorized"}`))
+		case r.URL.EscapedPath() == "/projects/group%2Fwebapp":
+			w.Header().Set("RateLimit-Limit", "2000")
+			w.Header().Set("RateLimit-Remaining", "1999")
+			w.Header().Set("RateLimit-Reset", "1792238400")
+			_, _ = w.Write([]byte(`{"permissions": {"project_access": null, "group_access": {"access_level": 30}}}`))
+		case r.URL.Path == "/personal_access_tokens/self":
+			_, _ = w.Write([]byte(`{"scopes": ["api", "write_repository"], "expires_at": "2026-11-01"}`))
+		case r.URL.Path == "/user":
+			_, _ = w.Write([]byte(`{"username": "project_7_bot"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	ctx := context.Background()
+
+	var p gitprovider.TokenInspector = gitlab.NewProvider(srv.URL, "group/webapp", "glpat-test")
+	info, err := p.InspectToken(ctx)
+	if err != nil {
+		t.Fatalf("InspectToken: %v", err)
+	}
+	want := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	if info.Login != "project_7_bot" || !info.CanPush || len(info.Scopes) != 2 || info.ExpiresAt == nil ||
+		!info.ExpiresAt.Equal(want) || info.RateLimit != 2000 || info.RateRemaining != 1999 || info.RateReset == nil {
+		t.Fatalf("unexpected token info %+v", info)
+	}
+
+	if _, err := gitlab.NewProvider(srv.URL, "group/webapp", "glpat-revoked").InspectToken(ctx); err == nil {
+		t.Fatal("expected an error for an invalid token")
+	}
+	if _, err := gitlab.NewProvider(srv.URL, "group/gone", "glpat-test").InspectToken(ctx); err == nil {
+		t.Fatal("expected an error for an inaccessible project")
+	}
+}
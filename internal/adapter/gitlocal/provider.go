@@ -4,7 +4,9 @@ package gitlocal
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"fmt"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
@@ -16,7 +18,30 @@ import (
 const providerName = "local"
 
 // Provider interacts with local git repositories via the git CLI.
-type Provider struct{}
+type Provider struct {
+	env []string // Extra environment of git commands, e.g. credentials
+}
+
+// SetToken makes git send token as the password of username on HTTPS
+// requests, e.g. for clones and pushes of private repositories.
+func (p *Provider) SetToken(username, token string) {
+	p.env = AuthEnv(username, token)
+}
+
+// AuthEnv returns the environment that makes git send a token as HTTP basic
+// credentials. Unlike a token in the remote URL, it is never written to the
+// repository's config. An empty token needs no environment.
+func AuthEnv(username, token string) []string {
+	if token == "" {
+		return nil
+	}
+	cred := base64.StdEncoding.EncodeToString([]byte(username + ":" + token))
+	return []string{
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http.extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic " + cred,
+	}
+}
 
 // Name returns "local".
 func (p *Provider) Name() string { return providerName }
@@ -24,7 +49,8 @@ func (p *Provider) Name() string { return providerName }
 // Capabilities returns what the local git provider supports.
 func (p *Provider) Capabilities() gitprovider.Capabilities {
 	return gitprovider.Capabilities{
-		Clone: true,
+		Clone:    true,
+		Worktree: true,
 	}
 }
 
@@ -45,7 +71,7 @@ func (p *Provider) Clone(ctx context.Context, url, destPath string) error {
 		return fmt.Errorf("gitlocal: resolve path: %w", err)
 	}
 
-	if _, execErr := runGit(ctx, "", "clone", url, absPath); execErr != nil {
+	if _, execErr := p.runGit(ctx, "", "clone", url, absPath); execErr != nil {
 		return fmt.Errorf("gitlocal: clone: %w", execErr)
 	}
 	return nil
@@ -56,14 +82,14 @@ func (p *Provider) Status(ctx context.Context, repoPath string) (*project.GitSta
 	status := &project.GitStatus{}
 
 	// Current branch
-	branch, err := runGit(ctx, repoPath, "rev-parse", "--abbrev-ref", "HEAD")
+	branch, err := p.runGit(ctx, repoPath, "rev-parse", "--abbrev-ref", "HEAD")
 	if err != nil {
 		return nil, fmt.Errorf("gitlocal: get branch: %w", err)
 	}
 	status.Branch = strings.TrimSpace(branch)
 
 	// Latest commit hash and message
-	logOut, err := runGit(ctx, repoPath, "log", "-1", "--format=%H%n%s")
+	logOut, err := p.runGit(ctx, repoPath, "log", "-1", "--format=%H%n%s")
 	if err != nil {
 		return nil, fmt.Errorf("gitlocal: get log: %w", err)
 	}
@@ -76,7 +102,7 @@ func (p *Provider) Status(ctx context.Context, repoPath string) (*project.GitSta
 	}
 
 	// Porcelain status for modified/untracked
-	porcelain, err := runGit(ctx, repoPath, "status", "--porcelain")
+	porcelain, err := p.runGit(ctx, repoPath, "status", "--porcelain")
 	if err != nil {
 		return nil, fmt.Errorf("gitlocal: porcelain status: %w", err)
 	}
@@ -95,7 +121,7 @@ func (p *Provider) Status(ctx context.Context, repoPath string) (*project.GitSta
 	status.Dirty = len(status.Modified) > 0 || len(status.Untracked) > 0
 
 	// Ahead/behind tracking branch
-	revList, _ := runGit(ctx, repoPath, "rev-list", "--left-right", "--count", "@{upstream}...HEAD")
+	revList, _ := p.runGit(ctx, repoPath, "rev-list", "--left-right", "--count", "@{upstream}...HEAD")
 	revList = strings.TrimSpace(revList)
 	if revList != "" {
 		parts := strings.Fields(revList)
@@ -110,7 +136,7 @@ func (p *Provider) Status(ctx context.Context, repoPath string) (*project.GitSta
 
 // Pull fetches and merges updates for the given repository.
 func (p *Provider) Pull(ctx context.Context, repoPath string) error {
-	if _, err := runGit(ctx, repoPath, "pull"); err != nil {
+	if _, err := p.runGit(ctx, repoPath, "pull"); err != nil {
 		return fmt.Errorf("gitlocal: pull: %w", err)
 	}
 	return nil
@@ -118,7 +144,7 @@ func (p *Provider) Pull(ctx context.Context, repoPath string) error {
 
 // ListBranches returns all branches of a local repository.
 func (p *Provider) ListBranches(ctx context.Context, repoPath string) ([]project.Branch, error) {
-	out, err := runGit(ctx, repoPath, "branch", "--list")
+	out, err := p.runGit(ctx, repoPath, "branch", "--list")
 	if err != nil {
 		return nil, fmt.Errorf("gitlocal: list branches: %w", err)
 	}
@@ -144,18 +170,99 @@ func (p *Provider) ListBranches(ctx context.Context, repoPath string) ([]project
 
 // Checkout switches to the specified branch.
 func (p *Provider) Checkout(ctx context.Context, repoPath, branch string) error {
-	if _, err := runGit(ctx, repoPath, "checkout", branch); err != nil {
+	if _, err := p.runGit(ctx, repoPath, "checkout", branch); err != nil {
 		return fmt.Errorf("gitlocal: checkout %s: %w", branch, err)
 	}
 	return nil
 }
 
+// AddWorktree creates a detached worktree of the repository's HEAD.
+func (p *Provider) AddWorktree(ctx context.Context, repoPath, worktreePath string) error {
+	absPath, err := filepath.Abs(worktreePath)
+	if err != nil {
+		return fmt.Errorf("gitlocal: resolve path: %w", err)
+	}
+	if _, err := p.runGit(ctx, repoPath, "worktree", "add", "--detach", absPath, "HEAD"); err != nil {
+		return fmt.Errorf("gitlocal: add worktree: %w", err)
+	}
+	return nil
+}
+
+// AddBranchWorktree fetches branch from origin and creates a detached
+// worktree of its head.
+func (p *Provider) AddBranchWorktree(ctx context.Context, repoPath, worktreePath, branch string) error {
+	absPath, err := filepath.Abs(worktreePath)
+	if err != nil {
+		return fmt.Errorf("gitlocal: resolve path: %w", err)
+	}
+	if _, err := p.runGit(ctx, repoPath, "fetch", "origin", "--", branch); err != nil {
+		return fmt.Errorf("gitlocal: fetch %s: %w", branch, err)
+	}
+	if _, err := p.runGit(ctx, repoPath, "worktree", "add", "--detach", absPath, "FETCH_HEAD"); err != nil {
+		return fmt.Errorf("gitlocal: add worktree: %w", err)
+	}
+	return nil
+}
+
+// RemoveWorktree force-removes a worktree, discarding uncommitted changes,
+// and prunes stale worktree metadata.
+func (p *Provider) RemoveWorktree(ctx context.Context, repoPath, worktreePath string) error {
+	absPath, err := filepath.Abs(worktreePath)
+	if err != nil {
+		return fmt.Errorf("gitlocal: resolve path: %w", err)
+	}
+	// A worktree deleted from disk only needs its metadata pruned.
+	if _, statErr := os.Stat(absPath); statErr == nil {
+		if _, err := p.runGit(ctx, repoPath, "worktree", "remove", "--force", absPath); err != nil {
+			return fmt.Errorf("gitlocal: remove worktree: %w", err)
+		}
+	}
+	if _, err := p.runGit(ctx, repoPath, "worktree", "prune"); err != nil {
+		return fmt.Errorf("gitlocal: prune worktrees: %w", err)
+	}
+	return nil
+}
+
+// Init creates a repository in dir and commits its files. Without a
+// configured git identity, the commit is made as CodeForge.
+func (p *Provider) Init(ctx context.Context, dir, remoteURL, message string) error {
+	if _, err := p.runGit(ctx, dir, "init"); err != nil {
+		return fmt.Errorf("gitlocal: init: %w", err)
+	}
+	if _, err := p.runGit(ctx, dir, "config", "user.email"); err != nil {
+		for k, v := range map[string]string{"user.email": "codeforge@localhost", "user.name": "CodeForge"} {
+			if _, err := p.runGit(ctx, dir, "config", k, v); err != nil {
+				return fmt.Errorf("gitlocal: set %s: %w", k, err)
+			}
+		}
+	}
+	if _, err := p.runGit(ctx, dir, "add", "-A"); err != nil {
+		return fmt.Errorf("gitlocal: add: %w", err)
+	}
+	if _, err := p.runGit(ctx, dir, "commit", "-m", message); err != nil {
+		return fmt.Errorf("gitlocal: commit: %w", err)
+	}
+	if remoteURL == "" {
+		return nil
+	}
+	if _, err := p.runGit(ctx, dir, "remote", "add", "origin", remoteURL); err != nil {
+		return fmt.Errorf("gitlocal: add remote: %w", err)
+	}
+	if _, err := p.runGit(ctx, dir, "push", "-u", "origin", "HEAD"); err != nil {
+		return fmt.Errorf("gitlocal: push: %w", err)
+	}
+	return nil
+}
+
 // runGit executes a git command and returns its combined stdout.
-func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+func (p *Provider) runGit(ctx context.Context, dir string, args ...string) (string, error) {
 	cmd := exec.CommandContext(ctx, "git", args...)
 	if dir != "" {
 		cmd.Dir = dir
 	}
+	if len(p.env) > 0 {
+		cmd.Env = append(os.Environ(), p.env...)
+	}
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -0,0 +1,582 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// maxDepth limits how deeply selections may nest, so a single request
+// cannot fan out into an unbounded number of store queries.
+const maxDepth = 12
+
+// --- Schema ---
+
+// resolver returns the value of a field of src. It may return a thunk to
+// defer work until the loaders of the current execution wave have been
+// dispatched.
+type resolver func(ctx context.Context, src any, args args) (any, error)
+
+// thunk is a deferred field value, see resolver.
+type thunk func() (any, error)
+
+// object is an output type of the schema.
+type object struct {
+	name   string
+	fields []*fieldDef
+}
+
+// fieldDef describes a field of an object. typ names an object or scalar
+// type, in brackets for a list, e.g. "[Run]". A nil resolve reads the field
+// from the JSON encoding of the source value, so scalar fields of domain
+// types match the REST API.
+type fieldDef struct {
+	name    string
+	typ     string
+	args    []argDef
+	desc    string
+	resolve resolver
+}
+
+// argDef describes a field argument. A type ending in "!" is required.
+type argDef struct {
+	name string
+	typ  string
+}
+
+func (o *object) field(name string) *fieldDef {
+	for _, f := range o.fields {
+		if f.name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// schema is a set of object types with a Query root. Only query operations
+// are supported; all fields are nullable.
+type schema struct {
+	types   map[string]*object
+	scalars []string
+}
+
+func newSchema(scalars []string, types ...*object) *schema {
+	s := &schema{types: make(map[string]*object), scalars: scalars}
+	for _, t := range types {
+		s.types[t.name] = t
+	}
+	return s
+}
+
+// namedType strips list brackets and the non-null marker from a type.
+func namedType(typ string) string {
+	return strings.Trim(typ, "[]!")
+}
+
+// listElem returns the element type of a list type.
+func listElem(typ string) (string, bool) {
+	typ = strings.TrimSuffix(typ, "!")
+	if strings.HasPrefix(typ, "[") && strings.HasSuffix(typ, "]") {
+		return typ[1 : len(typ)-1], true
+	}
+	return "", false
+}
+
+// sdl renders the schema in the GraphQL schema definition language.
+func (s *schema) sdl() string {
+	var b strings.Builder
+	for _, sc := range s.scalars {
+		fmt.Fprintf(&b, "scalar %s\n\n", sc)
+	}
+	names := make([]string, 0, len(s.types))
+	for n := range s.types {
+		if n != "Query" {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	for _, n := range append([]string{"Query"}, names...) {
+		fmt.Fprintf(&b, "type %s {\n", n)
+		for _, f := range s.types[n].fields {
+			if f.desc != "" {
+				fmt.Fprintf(&b, "  %q\n", f.desc)
+			}
+			b.WriteString("  " + f.name)
+			if len(f.args) > 0 {
+				parts := make([]string, len(f.args))
+				for i, a := range f.args {
+					parts[i] = a.name + ": " + a.typ
+				}
+				b.WriteString("(" + strings.Join(parts, ", ") + ")")
+			}
+			b.WriteString(": " + f.typ + "\n")
+		}
+		b.WriteString("}\n\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// --- Request and response ---
+
+// request is a GraphQL request as sent over HTTP.
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// response is a GraphQL response. Data is nil if the request failed before
+// execution started.
+type response struct {
+	Data   *orderedMap `json:"data,omitempty"`
+	Errors []gqlError  `json:"errors,omitempty"`
+}
+
+type gqlError struct {
+	Message   string     `json:"message"`
+	Locations []location `json:"locations,omitempty"`
+	Path      []any      `json:"path,omitempty"`
+}
+
+type location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func errorAt(f *field, format string, a ...any) gqlError {
+	return gqlError{Message: fmt.Sprintf(format, a...), Locations: []location{{Line: f.line, Column: f.col}}}
+}
+
+// orderedMap is a JSON object that keeps the order of its keys, as GraphQL
+// responses follow the order of the selection set.
+type orderedMap struct {
+	keys []string
+	vals []any
+}
+
+func (m *orderedMap) set(key string, v any) int {
+	m.keys = append(m.keys, key)
+	m.vals = append(m.vals, v)
+	return len(m.keys) - 1
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		b.Write(key)
+		b.WriteByte(':')
+		val, err := json.Marshal(m.vals[i])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(val)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// --- Execution ---
+
+// execute parses, validates and runs a request.
+func (s *schema) execute(ctx context.Context, req *request) *response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		var se *syntaxError
+		if errors.As(err, &se) {
+			return &response{Errors: []gqlError{{Message: se.Error(), Locations: []location{{Line: se.Line, Column: se.Column}}}}}
+		}
+		return &response{Errors: []gqlError{{Message: err.Error()}}}
+	}
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return &response{Errors: []gqlError{{Message: err.Error()}}}
+	}
+	if op.kind != "query" {
+		return &response{Errors: []gqlError{{Message: fmt.Sprintf("%s operations are not supported", op.kind)}}}
+	}
+	vars := make(map[string]any, len(op.variables))
+	for _, d := range op.variables {
+		if v, ok := req.Variables[d.name]; ok {
+			vars[d.name] = v
+		} else if d.set {
+			vars[d.name] = d.def
+		}
+	}
+	v := &validator{schema: s, doc: doc, vars: op.variables}
+	v.selection(s.types["Query"], op.selection, 1, nil)
+	if len(v.errs) > 0 {
+		return &response{Errors: v.errs}
+	}
+
+	e := &executor{ctx: ctx, schema: s, doc: doc, vars: vars}
+	data := e.object(s.types["Query"], &node{}, op.selection, nil)
+	for len(e.pending) > 0 {
+		wave := e.pending
+		e.pending = nil
+		for _, run := range wave {
+			run()
+		}
+	}
+	return &response{Data: data, Errors: e.errs}
+}
+
+// operation selects the operation to run.
+func (d *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(d.operations) > 1 {
+			return nil, errors.New("operationName is required for documents with several operations")
+		}
+		return d.operations[0], nil
+	}
+	for _, op := range d.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+// validator checks a selection set against the schema before execution.
+type validator struct {
+	schema *schema
+	doc    *document
+	vars   []variableDef
+	errs   []gqlError
+}
+
+func (v *validator) selection(obj *object, sels []selection, depth int, spreading []string) {
+	if depth > maxDepth {
+		v.errs = append(v.errs, gqlError{Message: fmt.Sprintf("query exceeds the maximum depth of %d", maxDepth)})
+		return
+	}
+	for _, sel := range sels {
+		for _, d := range sel.directives {
+			if d.name != "include" && d.name != "skip" {
+				v.errs = append(v.errs, gqlError{Message: fmt.Sprintf("unknown directive @%s", d.name)})
+			} else if _, ok := d.args["if"]; !ok {
+				v.errs = append(v.errs, gqlError{Message: fmt.Sprintf("directive @%s requires argument \"if\"", d.name)})
+			}
+			v.values(d.args)
+		}
+		switch {
+		case sel.spread != "":
+			frag, ok := v.doc.fragments[sel.spread]
+			switch {
+			case !ok:
+				v.errs = append(v.errs, gqlError{Message: fmt.Sprintf("unknown fragment %q", sel.spread)})
+			case contains(spreading, sel.spread):
+				v.errs = append(v.errs, gqlError{Message: fmt.Sprintf("fragment %q spreads itself", sel.spread)})
+			default:
+				v.selection(obj, frag.selection, depth, append(spreading, sel.spread))
+			}
+		case sel.inline != nil:
+			v.selection(obj, sel.inline, depth, spreading)
+		default:
+			v.field(obj, sel.field, depth, spreading)
+		}
+	}
+}
+
+func (v *validator) field(obj *object, f *field, depth int, spreading []string) {
+	if f.name == "__typename" {
+		return
+	}
+	def := obj.field(f.name)
+	if def == nil {
+		v.errs = append(v.errs, errorAt(f, "cannot query field %q on type %q", f.name, obj.name))
+		return
+	}
+	for name := range f.args {
+		if !hasArg(def, name) {
+			v.errs = append(v.errs, errorAt(f, "unknown argument %q on field %q", name, f.name))
+		}
+	}
+	for _, a := range def.args {
+		if _, ok := f.args[a.name]; !ok && strings.HasSuffix(a.typ, "!") {
+			v.errs = append(v.errs, errorAt(f, "field %q requires argument %q", f.name, a.name))
+		}
+	}
+	v.values(f.args)
+
+	sub := v.schema.types[namedType(def.typ)]
+	switch {
+	case sub == nil && f.selection != nil:
+		v.errs = append(v.errs, errorAt(f, "field %q of type %s has no subfields", f.name, def.typ))
+	case sub != nil && f.selection == nil:
+		v.errs = append(v.errs, errorAt(f, "field %q of type %s requires a selection of subfields", f.name, def.typ))
+	case sub != nil:
+		v.selection(sub, f.selection, depth+1, spreading)
+	}
+}
+
+// values checks that argument values only use declared variables.
+func (v *validator) values(args map[string]any) {
+	var walk func(any)
+	walk = func(val any) {
+		switch val := val.(type) {
+		case variable:
+			for _, d := range v.vars {
+				if d.name == string(val) {
+					return
+				}
+			}
+			v.errs = append(v.errs, gqlError{Message: fmt.Sprintf("variable $%s is not defined", val)})
+		case []any:
+			for _, x := range val {
+				walk(x)
+			}
+		case map[string]any:
+			for _, x := range val {
+				walk(x)
+			}
+		}
+	}
+	for _, a := range args {
+		walk(a)
+	}
+}
+
+func hasArg(def *fieldDef, name string) bool {
+	for _, a := range def.args {
+		if a.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// executor runs a validated operation. Field values are completed depth
+// first; thunks are queued and run in waves, so all loads requested by one
+// wave are batched together.
+type executor struct {
+	ctx     context.Context
+	schema  *schema
+	doc     *document
+	vars    map[string]any
+	errs    []gqlError
+	pending []func()
+}
+
+// node is an object value together with its lazily decoded JSON fields.
+type node struct {
+	v      any
+	fields map[string]any
+}
+
+func (n *node) field(name string) (any, error) {
+	if n.fields == nil {
+		data, err := json.Marshal(n.v)
+		if err != nil {
+			return nil, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&n.fields); err != nil {
+			return nil, err
+		}
+	}
+	return n.fields[name], nil
+}
+
+type fieldGroup struct {
+	key    string
+	fields []*field
+}
+
+// collect flattens fragments and applies directives, grouping fields by
+// response key in selection order.
+func (e *executor) collect(sels []selection, groups []fieldGroup) []fieldGroup {
+	for _, sel := range sels {
+		if !e.included(sel.directives) {
+			continue
+		}
+		switch {
+		case sel.spread != "":
+			groups = e.collect(e.doc.fragments[sel.spread].selection, groups)
+		case sel.inline != nil:
+			groups = e.collect(sel.inline, groups)
+		default:
+			key := sel.field.responseKey()
+			found := false
+			for i := range groups {
+				if groups[i].key == key {
+					groups[i].fields = append(groups[i].fields, sel.field)
+					found = true
+					break
+				}
+			}
+			if !found {
+				groups = append(groups, fieldGroup{key: key, fields: []*field{sel.field}})
+			}
+		}
+	}
+	return groups
+}
+
+func (e *executor) included(dirs []directive) bool {
+	for _, d := range dirs {
+		cond, _ := e.value(d.args["if"]).(bool)
+		if d.name == "include" && !cond || d.name == "skip" && cond {
+			return false
+		}
+	}
+	return true
+}
+
+// value substitutes variables in an argument value.
+func (e *executor) value(v any) any {
+	switch v := v.(type) {
+	case variable:
+		return e.vars[string(v)]
+	case enumValue:
+		return string(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, x := range v {
+			out[i] = e.value(x)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, x := range v {
+			out[k] = e.value(x)
+		}
+		return out
+	}
+	return v
+}
+
+func (e *executor) object(obj *object, src *node, sels []selection, path []any) *orderedMap {
+	out := &orderedMap{}
+	for _, g := range e.collect(sels, nil) {
+		f := g.fields[0]
+		if f.name == "__typename" {
+			out.set(g.key, obj.name)
+			continue
+		}
+		def := obj.field(f.name)
+		var sub []selection
+		for _, gf := range g.fields {
+			sub = append(sub, gf.selection...)
+		}
+		a := make(args, len(f.args))
+		for k, v := range f.args {
+			a[k] = e.value(v)
+		}
+		fieldPath := append(path[:len(path):len(path)], g.key)
+
+		idx := out.set(g.key, nil)
+		var v any
+		var err error
+		if def.resolve != nil {
+			v, err = def.resolve(e.ctx, src.v, a)
+		} else {
+			v, err = src.field(def.name)
+		}
+		if err != nil {
+			e.fail(f, fieldPath, err)
+			continue
+		}
+		e.complete(def.typ, v, f, sub, fieldPath, func(v any) { out.vals[idx] = v })
+	}
+	return out
+}
+
+// complete turns a resolved value into its response value and hands it to
+// set, possibly after the thunks of a later wave have run.
+func (e *executor) complete(typ string, v any, f *field, sub []selection, path []any, set func(any)) {
+	if th, ok := v.(thunk); ok {
+		e.pending = append(e.pending, func() {
+			v, err := th()
+			if err != nil {
+				e.fail(f, path, err)
+				return
+			}
+			e.complete(typ, v, f, sub, path, set)
+		})
+		return
+	}
+	if v == nil {
+		return
+	}
+	rv := reflect.ValueOf(v)
+	if elem, ok := listElem(typ); ok {
+		if rv.Kind() != reflect.Slice {
+			e.fail(f, path, fmt.Errorf("expected a list, got %T", v))
+			return
+		}
+		items := make([]any, rv.Len())
+		set(items)
+		for i := range items {
+			item := rv.Index(i)
+			if item.Kind() == reflect.Struct {
+				item = item.Addr()
+			}
+			e.complete(elem, item.Interface(), f, sub, append(path[:len(path):len(path)], i), func(v any) { items[i] = v })
+		}
+		return
+	}
+	if (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Map) && rv.IsNil() {
+		return
+	}
+	obj := e.schema.types[namedType(typ)]
+	if obj == nil {
+		set(v)
+		return
+	}
+	if rv.Kind() == reflect.Struct {
+		p := reflect.New(rv.Type())
+		p.Elem().Set(rv)
+		v = p.Interface()
+	}
+	set(e.object(obj, &node{v: v}, sub, path))
+}
+
+func (e *executor) fail(f *field, path []any, err error) {
+	ge := errorAt(f, "%s", err.Error())
+	ge.Path = path
+	e.errs = append(e.errs, ge)
+}
+
+// args holds the coerced arguments of a field.
+type args map[string]any
+
+// String returns a string argument, or "" if it is absent.
+func (a args) String(name string) string {
+	s, _ := a[name].(string)
+	return s
+}
+
+// Int returns an integer argument, or def if it is absent or not a number.
+func (a args) Int(name string, def int) int {
+	switch v := a[name].(type) {
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return int(n)
+		}
+	}
+	return def
+}
@@ -0,0 +1,69 @@
+// Package graphql serves a read-only GraphQL API over the store and the
+// event store, so dashboards can fetch a project with its tasks, runs,
+// costs and plan graph in one request instead of many REST calls.
+//
+// The package contains a small executor for the query subset of GraphQL
+// (no mutations, subscriptions or introspection; the schema is published
+// in SDL form instead). Related records are fetched through per-request
+// loaders that batch and deduplicate store queries across one level of the
+// query.
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/eventstore"
+)
+
+// maxBodyBytes limits the size of a request body.
+const maxBodyBytes = 1 << 20
+
+// Handler serves GraphQL requests.
+type Handler struct {
+	store  database.Store
+	events eventstore.Store
+	schema *schema
+}
+
+// NewHandler creates a Handler for the read model of store and events.
+func NewHandler(store database.Store, events eventstore.Store) *Handler {
+	return &Handler{store: store, events: events, schema: readModel(store)}
+}
+
+// ServeHTTP handles POST /api/v1/graphql with a JSON body of the form
+// {"query": "...", "variables": {...}, "operationName": "..."}. Requests
+// that fail to parse or validate get status 400; field errors are reported
+// next to the partial data with status 200.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.UseNumber()
+	if err := dec.Decode(&req); err != nil || req.Query == "" {
+		writeResponse(w, http.StatusBadRequest, &response{Errors: []gqlError{{Message: "request body must be a JSON object with a query"}}})
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), loadersKey{}, newLoaders(r.Context(), h.store, h.events))
+	resp := h.schema.execute(ctx, &req)
+	status := http.StatusOK
+	if resp.Data == nil {
+		status = http.StatusBadRequest
+	}
+	writeResponse(w, status, resp)
+}
+
+// ServeSchema handles GET /api/v1/graphql/schema and returns the schema in
+// the GraphQL schema definition language.
+func (h *Handler) ServeSchema(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(h.schema.sdl()))
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp *response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,249 @@
+package graphql_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/graphql"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/eventstore"
+)
+
+// fakeStore implements the read methods used by the GraphQL schema and
+// counts store calls by method.
+type fakeStore struct {
+	database.Store // unused methods
+	projects       []project.Project
+	tasks          []task.Task
+	runs           []run.Run
+	failRuns       bool
+	calls          map[string]int
+}
+
+func (s *fakeStore) ListProjects(_ context.Context) ([]project.Project, error) {
+	s.calls["ListProjects"]++
+	return s.projects, nil
+}
+
+func (s *fakeStore) GetProject(_ context.Context, id string) (*project.Project, error) {
+	s.calls["GetProject"]++
+	for i := range s.projects {
+		if s.projects[i].ID == id {
+			return &s.projects[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *fakeStore) GetTask(_ context.Context, id string) (*task.Task, error) {
+	s.calls["GetTask"]++
+	for i := range s.tasks {
+		if s.tasks[i].ID == id {
+			return &s.tasks[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (s *fakeStore) ListTasks(_ context.Context, projectID string) ([]task.Task, error) {
+	s.calls["ListTasks"]++
+	var out []task.Task
+	for _, t := range s.tasks {
+		if t.ProjectID == projectID {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeStore) ListRunsByTasks(_ context.Context, taskIDs []string) ([]run.Run, error) {
+	s.calls["ListRunsByTasks"]++
+	var out []run.Run
+	for _, r := range s.runs {
+		if slices.Contains(taskIDs, r.TaskID) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeStore) GetRuns(_ context.Context, ids []string) ([]run.Run, error) {
+	s.calls["GetRuns"]++
+	if s.failRuns {
+		return nil, errors.New("store unavailable")
+	}
+	var out []run.Run
+	for _, r := range s.runs {
+		if slices.Contains(ids, r.ID) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+type fakeEvents struct {
+	eventstore.Store // unused methods
+	byRun            map[string][]event.AgentEvent
+}
+
+func (e *fakeEvents) LoadByRun(_ context.Context, runID string) ([]event.AgentEvent, error) {
+	return e.byRun[runID], nil
+}
+
+func newTestHandler() (*fakeStore, *graphql.Handler) {
+	store := &fakeStore{
+		projects: []project.Project{{ID: "p1", Name: "alpha"}, {ID: "p2", Name: "beta"}},
+		tasks: []task.Task{
+			{ID: "t1", ProjectID: "p1", Title: "one", Status: task.StatusCompleted},
+			{ID: "t2", ProjectID: "p1", Title: "two", Status: task.StatusPending},
+			{ID: "t3", ProjectID: "p2", Title: "three", Status: task.StatusCompleted},
+		},
+		runs: []run.Run{
+			{ID: "r1", TaskID: "t1", ProjectID: "p1", CostUSD: 0.5, TokensIn: 100},
+			{ID: "r2", TaskID: "t1", ProjectID: "p1", CostUSD: 0.25, TokensIn: 50},
+			{ID: "r3", TaskID: "t3", ProjectID: "p2", CostUSD: 1},
+		},
+		calls: map[string]int{},
+	}
+	events := &fakeEvents{byRun: map[string][]event.AgentEvent{
+		"r1": {{ID: "e1"}, {ID: "e2"}, {ID: "e3"}},
+	}}
+	return store, graphql.NewHandler(store, events)
+}
+
+func post(t *testing.T, h http.Handler, body map[string]any) (int, map[string]any) {
+	t.Helper()
+	data, _ := json.Marshal(body)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/graphql", bytes.NewReader(data)))
+	var out map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode response %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, out
+}
+
+func TestDashboardQueryBatchesLoads(t *testing.T) {
+	store, h := newTestHandler()
+	code, out := post(t, h, map[string]any{"query": `{
+		projects {
+			name
+			tasks { id runs { id cost_usd } }
+			cost { total_usd tokens_in runs }
+		}
+	}`})
+	if code != http.StatusOK || out["errors"] != nil {
+		t.Fatalf("unexpected response %d %v", code, out)
+	}
+	got, _ := json.Marshal(out["data"])
+	want := `{"projects":[` +
+		`{"cost":{"runs":2,"tokens_in":150,"total_usd":0.75},"name":"alpha","tasks":[{"id":"t1","runs":[{"cost_usd":0.5,"id":"r1"},{"cost_usd":0.25,"id":"r2"}]},{"id":"t2","runs":[]}]},` +
+		`{"cost":{"runs":1,"tokens_in":0,"total_usd":1},"name":"beta","tasks":[{"id":"t3","runs":[{"cost_usd":1,"id":"r3"}]}]}]}`
+	if string(got) != want {
+		t.Fatalf("unexpected data\n got %s\nwant %s", got, want)
+	}
+	if store.calls["ListRunsByTasks"] != 1 || store.calls["ListTasks"] != 2 {
+		t.Fatalf("expected one batched run query and one task query per project, got %v", store.calls)
+	}
+}
+
+func TestQueryFeatures(t *testing.T) {
+	_, h := newTestHandler()
+	code, out := post(t, h, map[string]any{
+		"query": `query Dash($id: ID!, $done: String = "completed", $withRuns: Boolean!) {
+			p: project(id: $id) { __typename ...Head tasks(status: $done) { title runs @include(if: $withRuns) { id } } }
+			missing: project(id: "nope") { name }
+			run(id: "r1") { task { title project { name } } events(limit: 2) { id } }
+		}
+		fragment Head on Project { id name }`,
+		"variables": map[string]any{"id": "p1", "withRuns": false},
+	})
+	if code != http.StatusOK || out["errors"] != nil {
+		t.Fatalf("unexpected response %d %v", code, out)
+	}
+	got, _ := json.Marshal(out["data"])
+	want := `{"missing":null,"p":{"__typename":"Project","id":"p1","name":"alpha","tasks":[{"title":"one"}]},` +
+		`"run":{"events":[{"id":"e2"},{"id":"e3"}],"task":{"project":{"name":"alpha"},"title":"one"}}}`
+	if string(got) != want {
+		t.Fatalf("unexpected data\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestFieldErrorKeepsPartialData(t *testing.T) {
+	store, h := newTestHandler()
+	store.tasks = append(store.tasks, task.Task{ID: "t4", ProjectID: "gone"})
+	store.runs = append(store.runs, run.Run{ID: "r4", TaskID: "t4"})
+	code, out := post(t, h, map[string]any{"query": `{ run(id: "r4") { id task { project { name } } } }`})
+	if code != http.StatusOK {
+		t.Fatalf("expected 200 with partial data, got %d %v", code, out)
+	}
+	got, _ := json.Marshal(out)
+	// A project that does not exist is null, not an error.
+	if want := `{"data":{"run":{"id":"r4","task":{"project":null}}}}`; string(got) != want {
+		t.Fatalf("unexpected response\n got %s\nwant %s", got, want)
+	}
+
+	store.failRuns = true
+	_, out = post(t, h, map[string]any{"query": `{ a: run(id: "r1") { id } }`})
+	got, _ = json.Marshal(out)
+	if want := `{"data":{"a":null},"errors":[{"locations":[{"column":3,"line":1}],"message":"store unavailable","path":["a"]}]}`; string(got) != want {
+		t.Fatalf("unexpected response\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestRequestErrors(t *testing.T) {
+	_, h := newTestHandler()
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"syntax", `{ projects { name }`, "syntax error"},
+		{"unknown field", `{ projects { secret } }`, `cannot query field "secret" on type "Project"`},
+		{"missing argument", `{ project { name } }`, `requires argument "id"`},
+		{"leaf selection", `{ projects { name { x } } }`, "has no subfields"},
+		{"object without selection", `{ projects }`, "requires a selection of subfields"},
+		{"undefined variable", `{ project(id: $id) { name } }`, "variable $id is not defined"},
+		{"mutation", `mutation { projects { name } }`, "mutation operations are not supported"},
+		{"fragment cycle", `{ projects { ...A } } fragment A on Project { ...A }`, "spreads itself"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, out := post(t, h, map[string]any{"query": tt.query})
+			if code != http.StatusBadRequest || out["data"] != nil {
+				t.Fatalf("expected 400 without data, got %d %v", code, out)
+			}
+			var msgs []string
+			for _, e := range out["errors"].([]any) {
+				msgs = append(msgs, e.(map[string]any)["message"].(string))
+			}
+			if !strings.Contains(strings.Join(msgs, "; "), tt.want) {
+				t.Fatalf("expected error containing %q, got %q", tt.want, msgs)
+			}
+		})
+	}
+}
+
+func TestServeSchema(t *testing.T) {
+	_, h := newTestHandler()
+	rec := httptest.NewRecorder()
+	h.ServeSchema(rec, httptest.NewRequest(http.MethodGet, "/api/v1/graphql/schema", nil))
+	sdl := rec.Body.String()
+	for _, want := range []string{"scalar Time", "type Query {", "  project(id: ID!): Project", "  runs: [Run]"} {
+		if !strings.Contains(sdl, want) {
+			t.Fatalf("expected %q in schema:\n%s", want, sdl)
+		}
+	}
+}
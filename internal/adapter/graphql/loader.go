@@ -0,0 +1,102 @@
+package graphql
+
+import "context"
+
+// loader batches and caches loads of values by key for one request. Load
+// only queues the key; the returned thunk fetches all keys queued so far in
+// one call when it is first run. The executor runs thunks in waves, so the
+// keys requested by all fields of one level of the query share a batch.
+//
+// A loader is not safe for concurrent use; the executor is single-threaded.
+type loader[V any] struct {
+	ctx     context.Context
+	fetch   func(ctx context.Context, keys []string) (map[string]V, map[string]error)
+	queued  []string
+	queuedK map[string]bool
+	results map[string]loadResult[V]
+}
+
+type loadResult[V any] struct {
+	val V
+	err error
+}
+
+// newBatchLoader creates a loader for a store query that takes many keys.
+// Keys missing from the result load as the zero value.
+func newBatchLoader[V any](ctx context.Context, fetch func(ctx context.Context, keys []string) (map[string]V, error)) *loader[V] {
+	return &loader[V]{
+		ctx: ctx,
+		fetch: func(ctx context.Context, keys []string) (map[string]V, map[string]error) {
+			vals, err := fetch(ctx, keys)
+			if err == nil {
+				return vals, nil
+			}
+			errs := make(map[string]error, len(keys))
+			for _, k := range keys {
+				errs[k] = err
+			}
+			return nil, errs
+		},
+		queuedK: make(map[string]bool),
+		results: make(map[string]loadResult[V]),
+	}
+}
+
+// newLoader creates a loader for a store query that takes one key. It
+// still deduplicates and caches keys within the request.
+func newLoader[V any](ctx context.Context, get func(ctx context.Context, key string) (V, error)) *loader[V] {
+	return &loader[V]{
+		ctx: ctx,
+		fetch: func(ctx context.Context, keys []string) (map[string]V, map[string]error) {
+			vals := make(map[string]V, len(keys))
+			errs := make(map[string]error)
+			for _, k := range keys {
+				v, err := get(ctx, k)
+				if err != nil {
+					errs[k] = err
+					continue
+				}
+				vals[k] = v
+			}
+			return vals, errs
+		},
+		queuedK: make(map[string]bool),
+		results: make(map[string]loadResult[V]),
+	}
+}
+
+// load queues key and returns a thunk yielding its value.
+func (l *loader[V]) load(key string) thunk {
+	l.enqueue(key)
+	return func() (any, error) {
+		v, err := l.get(key)
+		return v, err
+	}
+}
+
+// get returns the value of key, fetching the queued batch if necessary.
+func (l *loader[V]) get(key string) (V, error) {
+	if _, done := l.results[key]; !done {
+		l.enqueue(key)
+		l.dispatch()
+	}
+	r := l.results[key]
+	return r.val, r.err
+}
+
+func (l *loader[V]) enqueue(key string) {
+	if _, done := l.results[key]; !done && !l.queuedK[key] {
+		l.queued = append(l.queued, key)
+		l.queuedK[key] = true
+	}
+}
+
+func (l *loader[V]) dispatch() {
+	keys := l.queued
+	l.queued = nil
+	clear(l.queuedK)
+	vals, errs := l.fetch(l.ctx, keys)
+	for _, k := range keys {
+		l.results[k] = loadResult[V]{val: vals[k], err: errs[k]}
+	}
+}
@@ -0,0 +1,667 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// This file implements a parser for the executable subset of the GraphQL
+// query language: operations with variables, fields with aliases and
+// arguments, named and inline fragments, and the @include/@skip directives.
+
+// document is a parsed request document.
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind      string // "query", "mutation" or "subscription"
+	name      string
+	variables []variableDef
+	selection []selection
+}
+
+type variableDef struct {
+	name string
+	def  any // Default value; nil if none
+	set  bool
+}
+
+type fragment struct {
+	name      string
+	selection []selection
+}
+
+// selection is a field, a fragment spread (spread != "") or an inline
+// fragment (inline != nil).
+type selection struct {
+	field      *field
+	spread     string
+	inline     []selection
+	directives []directive
+}
+
+type field struct {
+	alias     string
+	name      string
+	args      map[string]any
+	selection []selection
+	line, col int
+}
+
+// responseKey is the name of the field in the response.
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type directive struct {
+	name string
+	args map[string]any
+}
+
+// variable is a reference to a variable in an argument value.
+type variable string
+
+// enumValue is an unquoted enum literal in an argument value.
+type enumValue string
+
+// syntaxError reports a malformed request document.
+type syntaxError struct {
+	Line, Column int
+	Msg          string
+}
+
+func (e *syntaxError) Error() string {
+	return fmt.Sprintf("syntax error at %d:%d: %s", e.Line, e.Column, e.Msg)
+}
+
+// --- Lexer ---
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind      tokenKind
+	value     string
+	line, col int
+}
+
+type lexer struct {
+	src       string
+	pos       int
+	line, col int
+}
+
+func (l *lexer) errorf(format string, args ...any) error {
+	return &syntaxError{Line: l.line, Column: l.col, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (l *lexer) advance(n int) {
+	for range n {
+		if l.src[l.pos] == '\n' {
+			l.line++
+			l.col = 1
+		} else {
+			l.col++
+		}
+		l.pos++
+	}
+}
+
+// skipIgnored skips whitespace, commas and comments.
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.advance(1)
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.advance(1)
+			}
+		case strings.HasPrefix(l.src[l.pos:], "\ufeff"): // Byte order mark
+			l.pos += len("\ufeff")
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+	tok := token{line: l.line, col: l.col}
+	if l.pos >= len(l.src) {
+		tok.kind = tokEOF
+		return tok, nil
+	}
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		tok.kind, tok.value = tokPunct, "..."
+		l.advance(3)
+	case strings.IndexByte("!$():=@[]{}|", c) >= 0:
+		tok.kind, tok.value = tokPunct, string(c)
+		l.advance(1)
+	case c == '_' || isLetter(c):
+		start := l.pos
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.advance(1)
+		}
+		tok.kind, tok.value = tokName, l.src[start:l.pos]
+	case c == '-' || isDigit(c):
+		return l.number(tok)
+	case c == '"':
+		return l.string(tok)
+	default:
+		r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+		return tok, l.errorf("unexpected character %q", r)
+	}
+	return tok, nil
+}
+
+func (l *lexer) number(tok token) (token, error) {
+	start := l.pos
+	tok.kind = tokInt
+	if l.src[l.pos] == '-' {
+		l.advance(1)
+	}
+	digits := func() int {
+		n := 0
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.advance(1)
+			n++
+		}
+		return n
+	}
+	if digits() == 0 {
+		return tok, l.errorf("invalid number")
+	}
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		tok.kind = tokFloat
+		l.advance(1)
+		if digits() == 0 {
+			return tok, l.errorf("invalid number")
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		tok.kind = tokFloat
+		l.advance(1)
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.advance(1)
+		}
+		if digits() == 0 {
+			return tok, l.errorf("invalid number")
+		}
+	}
+	tok.value = l.src[start:l.pos]
+	return tok, nil
+}
+
+func (l *lexer) string(tok token) (token, error) {
+	tok.kind = tokString
+	if strings.HasPrefix(l.src[l.pos:], `"""`) {
+		l.advance(3)
+		end := strings.Index(l.src[l.pos:], `"""`)
+		if end < 0 {
+			return tok, l.errorf("unterminated block string")
+		}
+		tok.value = strings.TrimSpace(l.src[l.pos : l.pos+end])
+		l.advance(end + 3)
+		return tok, nil
+	}
+	l.advance(1)
+	var b strings.Builder
+	for {
+		if l.pos >= len(l.src) || l.src[l.pos] == '\n' {
+			return tok, l.errorf("unterminated string")
+		}
+		c := l.src[l.pos]
+		if c == '"' {
+			l.advance(1)
+			tok.value = b.String()
+			return tok, nil
+		}
+		if c != '\\' {
+			b.WriteByte(c)
+			l.advance(1)
+			continue
+		}
+		if l.pos+1 >= len(l.src) {
+			return tok, l.errorf("unterminated string")
+		}
+		esc := l.src[l.pos+1]
+		l.advance(2)
+		switch esc {
+		case '"', '\\', '/':
+			b.WriteByte(esc)
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			if l.pos+4 > len(l.src) {
+				return tok, l.errorf("invalid unicode escape")
+			}
+			n, err := strconv.ParseUint(l.src[l.pos:l.pos+4], 16, 32)
+			if err != nil {
+				return tok, l.errorf("invalid unicode escape")
+			}
+			b.WriteRune(rune(n))
+			l.advance(4)
+		default:
+			return tok, l.errorf("invalid escape \\%c", esc)
+		}
+	}
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+// --- Parser ---
+
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+// parse parses a request document.
+func parse(src string) (*document, error) {
+	p := &parser{lex: &lexer{src: src, line: 1, col: 1}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	doc := &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokEOF {
+		if p.tok.kind == tokName && p.tok.value == "fragment" {
+			f, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := doc.fragments[f.name]; dup {
+				return nil, p.errorf("duplicate fragment %q", f.name)
+			}
+			doc.fragments[f.name] = f
+			continue
+		}
+		op, err := p.operation()
+		if err != nil {
+			return nil, err
+		}
+		doc.operations = append(doc.operations, op)
+	}
+	if len(doc.operations) == 0 {
+		return nil, p.errorf("document contains no operation")
+	}
+	return doc, nil
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &syntaxError{Line: p.tok.line, Column: p.tok.col, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) peek(punct string) bool {
+	return p.tok.kind == tokPunct && p.tok.value == punct
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.peek(punct) {
+		return p.errorf("expected %q, got %q", punct, p.tok.value)
+	}
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokName {
+		return "", p.errorf("expected name, got %q", p.tok.value)
+	}
+	n := p.tok.value
+	return n, p.advance()
+}
+
+func (p *parser) operation() (*operation, error) {
+	op := &operation{kind: "query"}
+	if p.peek("{") {
+		sel, err := p.selectionSet()
+		op.selection = sel
+		return op, err
+	}
+	kind, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case "query", "mutation", "subscription":
+		op.kind = kind
+	default:
+		return nil, p.errorf("unknown operation type %q", kind)
+	}
+	if p.tok.kind == tokName {
+		if op.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek("(") {
+		if op.variables, err = p.variableDefs(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	op.selection, err = p.selectionSet()
+	return op, err
+}
+
+func (p *parser) variableDefs() ([]variableDef, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var defs []variableDef
+	for !p.peek(")") {
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		n, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if err := p.typeRef(); err != nil {
+			return nil, err
+		}
+		def := variableDef{name: n}
+		if p.peek("=") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if def.def, err = p.value(true); err != nil {
+				return nil, err
+			}
+			def.set = true
+		}
+		defs = append(defs, def)
+	}
+	return defs, p.advance()
+}
+
+// typeRef skips a type reference such as [ID!]!. Variable types are not
+// checked; argument values are coerced by the resolvers.
+func (p *parser) typeRef() error {
+	if p.peek("[") {
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if err := p.typeRef(); err != nil {
+			return err
+		}
+		if err := p.expect("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.name(); err != nil {
+		return err
+	}
+	if p.peek("!") {
+		return p.advance()
+	}
+	return nil
+}
+
+func (p *parser) fragment() (*fragment, error) {
+	if err := p.advance(); err != nil { // "fragment"
+		return nil, err
+	}
+	n, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if n == "on" {
+		return nil, p.errorf("fragment cannot be named \"on\"")
+	}
+	if err := p.typeCondition(); err != nil {
+		return nil, err
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	sel, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return &fragment{name: n, selection: sel}, nil
+}
+
+// typeCondition skips "on Type". All types of the schema are concrete, so
+// a fragment always applies where it validates.
+func (p *parser) typeCondition() error {
+	if p.tok.kind != tokName || p.tok.value != "on" {
+		return p.errorf("expected \"on\", got %q", p.tok.value)
+	}
+	if err := p.advance(); err != nil {
+		return err
+	}
+	_, err := p.name()
+	return err
+}
+
+func (p *parser) selectionSet() ([]selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var sels []selection
+	for !p.peek("}") {
+		if p.tok.kind == tokEOF {
+			return nil, p.errorf("unterminated selection set")
+		}
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+	}
+	if len(sels) == 0 {
+		return nil, p.errorf("empty selection set")
+	}
+	return sels, p.advance()
+}
+
+func (p *parser) selection() (selection, error) {
+	var sel selection
+	var err error
+	if p.peek("...") {
+		if err := p.advance(); err != nil {
+			return sel, err
+		}
+		if p.tok.kind == tokName && p.tok.value != "on" {
+			if sel.spread, err = p.name(); err != nil {
+				return sel, err
+			}
+			sel.directives, err = p.directives()
+			return sel, err
+		}
+		if p.tok.kind == tokName {
+			if err := p.typeCondition(); err != nil {
+				return sel, err
+			}
+		}
+		if sel.directives, err = p.directives(); err != nil {
+			return sel, err
+		}
+		sel.inline, err = p.selectionSet()
+		return sel, err
+	}
+
+	f := &field{line: p.tok.line, col: p.tok.col}
+	if f.name, err = p.name(); err != nil {
+		return sel, err
+	}
+	if p.peek(":") {
+		if err := p.advance(); err != nil {
+			return sel, err
+		}
+		f.alias = f.name
+		if f.name, err = p.name(); err != nil {
+			return sel, err
+		}
+	}
+	if p.peek("(") {
+		if f.args, err = p.arguments(); err != nil {
+			return sel, err
+		}
+	}
+	if sel.directives, err = p.directives(); err != nil {
+		return sel, err
+	}
+	if p.peek("{") {
+		if f.selection, err = p.selectionSet(); err != nil {
+			return sel, err
+		}
+	}
+	sel.field = f
+	return sel, nil
+}
+
+func (p *parser) arguments() (map[string]any, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	args := make(map[string]any)
+	for !p.peek(")") {
+		n, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if args[n], err = p.value(false); err != nil {
+			return nil, err
+		}
+	}
+	return args, p.advance()
+}
+
+func (p *parser) directives() ([]directive, error) {
+	var dirs []directive
+	for p.peek("@") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		n, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		d := directive{name: n}
+		if p.peek("(") {
+			if d.args, err = p.arguments(); err != nil {
+				return nil, err
+			}
+		}
+		dirs = append(dirs, d)
+	}
+	return dirs, nil
+}
+
+// value parses an argument value. In constant contexts (variable defaults)
+// variables are not allowed.
+func (p *parser) value(constant bool) (any, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokInt:
+		n, err := strconv.ParseInt(tok.value, 10, 64)
+		if err != nil {
+			return nil, p.errorf("invalid integer %s", tok.value)
+		}
+		return n, p.advance()
+	case tokFloat:
+		f, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, p.errorf("invalid float %s", tok.value)
+		}
+		return f, p.advance()
+	case tokString:
+		return tok.value, p.advance()
+	case tokName:
+		var v any
+		switch tok.value {
+		case "true":
+			v = true
+		case "false":
+			v = false
+		case "null":
+			v = nil
+		default:
+			v = enumValue(tok.value)
+		}
+		return v, p.advance()
+	}
+	switch {
+	case p.peek("$") && !constant:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		n, err := p.name()
+		return variable(n), err
+	case p.peek("["):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		list := []any{}
+		for !p.peek("]") {
+			v, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, p.advance()
+	case p.peek("{"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		obj := map[string]any{}
+		for !p.peek("}") {
+			n, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if obj[n], err = p.value(constant); err != nil {
+				return nil, err
+			}
+		}
+		return obj, p.advance()
+	}
+	return nil, p.errorf("unexpected %q in value", tok.value)
+}
@@ -0,0 +1,315 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/agent"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/roadmap"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/eventstore"
+)
+
+const (
+	defaultEventLimit = 100
+	maxEventLimit     = 1000
+)
+
+// loaders holds the per-request loaders of the read model.
+type loaders struct {
+	projects       *loader[*project.Project]
+	tasks          *loader[*task.Task]
+	runs           *loader[*run.Run]
+	agentsByProj   *loader[[]agent.Agent]
+	tasksByProj    *loader[[]task.Task]
+	plansByProj    *loader[[]plan.ExecutionPlan]
+	featuresByProj *loader[[]roadmap.Feature]
+	runsByTask     *loader[[]run.Run]
+	stepsByPlan    *loader[[]plan.Step]
+	artifactsByRun *loader[[]artifact.Artifact]
+	eventsByTask   *loader[[]event.AgentEvent]
+	eventsByRun    *loader[[]event.AgentEvent]
+}
+
+type loadersKey struct{}
+
+func newLoaders(ctx context.Context, store database.Store, events eventstore.Store) *loaders {
+	return &loaders{
+		projects: newLoader(ctx, store.GetProject),
+		tasks:    newLoader(ctx, store.GetTask),
+		runs: newBatchLoader(ctx, func(ctx context.Context, ids []string) (map[string]*run.Run, error) {
+			runs, err := store.GetRuns(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[string]*run.Run, len(runs))
+			for i := range runs {
+				byID[runs[i].ID] = &runs[i]
+			}
+			return byID, nil
+		}),
+		agentsByProj:   newLoader(ctx, store.ListAgents),
+		tasksByProj:    newLoader(ctx, store.ListTasks),
+		plansByProj:    newLoader(ctx, store.ListPlansByProject),
+		featuresByProj: newLoader(ctx, store.ListFeatures),
+		runsByTask: newBatchLoader(ctx, func(ctx context.Context, taskIDs []string) (map[string][]run.Run, error) {
+			runs, err := store.ListRunsByTasks(ctx, taskIDs)
+			if err != nil {
+				return nil, err
+			}
+			byTask := make(map[string][]run.Run, len(taskIDs))
+			for i := range runs {
+				byTask[runs[i].TaskID] = append(byTask[runs[i].TaskID], runs[i])
+			}
+			return byTask, nil
+		}),
+		stepsByPlan:    newLoader(ctx, store.ListPlanSteps),
+		artifactsByRun: newLoader(ctx, store.ListArtifactsByRun),
+		eventsByTask:   newLoader(ctx, events.LoadByTask),
+		eventsByRun:    newLoader(ctx, events.LoadByRun),
+	}
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// cost aggregates the runs of a project.
+type cost struct {
+	TotalUSD  float64 `json:"total_usd"`
+	TokensIn  int     `json:"tokens_in"`
+	TokensOut int     `json:"tokens_out"`
+	Runs      int     `json:"runs"`
+}
+
+// readModel returns the schema of the read-only API over the store and the
+// event store, whose per-request loaders are created by Handler.
+func readModel(store database.Store) *schema {
+	scalars := func(names ...string) []*fieldDef {
+		fields := make([]*fieldDef, len(names))
+		for i, n := range names {
+			fields[i] = &fieldDef{name: n, typ: "String"}
+		}
+		return fields
+	}
+	typed := func(fields []*fieldDef, types map[string]string) []*fieldDef {
+		for _, f := range fields {
+			if t, ok := types[f.name]; ok {
+				f.typ = t
+			}
+		}
+		return fields
+	}
+	common := map[string]string{
+		"id": "ID", "version": "Int", "created_at": "Time", "updated_at": "Time", "config": "JSON",
+		"cost_usd": "Float", "tokens_in": "Int", "tokens_out": "Int",
+	}
+	with := func(extra map[string]string) map[string]string {
+		m := make(map[string]string, len(common)+len(extra))
+		for k, v := range common {
+			m[k] = v
+		}
+		for k, v := range extra {
+			m[k] = v
+		}
+		return m
+	}
+	eventsField := func(load func(l *loaders, id string) thunk, id func(src any) string) *fieldDef {
+		return &fieldDef{
+			name: "events", typ: "[Event]", args: []argDef{{name: "limit", typ: "Int"}},
+			desc: fmt.Sprintf("The most recent events in chronological order (default %d, max %d)", defaultEventLimit, maxEventLimit),
+			resolve: func(ctx context.Context, src any, a args) (any, error) {
+				limit := min(max(a.Int("limit", defaultEventLimit), 0), maxEventLimit)
+				return then(load(loadersFrom(ctx), id(src)), func(v any) (any, error) {
+					evs := v.([]event.AgentEvent)
+					return evs[max(len(evs)-limit, 0):], nil
+				}), nil
+			},
+		}
+	}
+
+	query := &object{name: "Query", fields: []*fieldDef{
+		{name: "projects", typ: "[Project]", resolve: func(ctx context.Context, _ any, _ args) (any, error) {
+			return store.ListProjects(ctx)
+		}},
+		{name: "project", typ: "Project", args: []argDef{{name: "id", typ: "ID!"}}, resolve: func(ctx context.Context, _ any, a args) (any, error) {
+			return orNull(loadersFrom(ctx).projects.load(a.String("id"))), nil
+		}},
+		{name: "task", typ: "Task", args: []argDef{{name: "id", typ: "ID!"}}, resolve: func(ctx context.Context, _ any, a args) (any, error) {
+			return orNull(loadersFrom(ctx).tasks.load(a.String("id"))), nil
+		}},
+		{name: "run", typ: "Run", args: []argDef{{name: "id", typ: "ID!"}}, resolve: func(ctx context.Context, _ any, a args) (any, error) {
+			return loadersFrom(ctx).runs.load(a.String("id")), nil
+		}},
+		{name: "plan", typ: "Plan", args: []argDef{{name: "id", typ: "ID!"}}, resolve: func(ctx context.Context, _ any, a args) (any, error) {
+			p, err := store.GetPlan(ctx, a.String("id"))
+			if errors.Is(err, domain.ErrNotFound) {
+				return nil, nil
+			}
+			return p, err
+		}},
+	}}
+
+	proj := &object{name: "Project", fields: append(typed(scalars(
+		"id", "name", "description", "repo_url", "provider", "config", "version", "created_at", "updated_at"), common),
+		&fieldDef{name: "agents", typ: "[Agent]", resolve: func(ctx context.Context, src any, _ args) (any, error) {
+			return loadersFrom(ctx).agentsByProj.load(src.(*project.Project).ID), nil
+		}},
+		&fieldDef{name: "tasks", typ: "[Task]", args: []argDef{{name: "status", typ: "String"}}, resolve: func(ctx context.Context, src any, a args) (any, error) {
+			status := task.Status(a.String("status"))
+			return then(loadersFrom(ctx).tasksByProj.load(src.(*project.Project).ID), func(v any) (any, error) {
+				if status == "" {
+					return v, nil
+				}
+				var out []task.Task
+				for _, t := range v.([]task.Task) {
+					if t.Status == status {
+						out = append(out, t)
+					}
+				}
+				return out, nil
+			}), nil
+		}},
+		&fieldDef{name: "plans", typ: "[Plan]", resolve: func(ctx context.Context, src any, _ args) (any, error) {
+			return loadersFrom(ctx).plansByProj.load(src.(*project.Project).ID), nil
+		}},
+		&fieldDef{name: "features", typ: "[Feature]", resolve: func(ctx context.Context, src any, _ args) (any, error) {
+			return loadersFrom(ctx).featuresByProj.load(src.(*project.Project).ID), nil
+		}},
+		&fieldDef{name: "cost", typ: "Cost", desc: "Totals over all runs of the project's tasks", resolve: func(ctx context.Context, src any, _ args) (any, error) {
+			l := loadersFrom(ctx)
+			return then(l.tasksByProj.load(src.(*project.Project).ID), func(v any) (any, error) {
+				tasks := v.([]task.Task)
+				runs := make([]thunk, len(tasks))
+				for i := range tasks {
+					runs[i] = l.runsByTask.load(tasks[i].ID)
+				}
+				return thunk(func() (any, error) {
+					c := &cost{}
+					for _, th := range runs {
+						v, err := th()
+						if err != nil {
+							return nil, err
+						}
+						for _, r := range v.([]run.Run) {
+							c.TotalUSD += r.CostUSD
+							c.TokensIn += r.TokensIn
+							c.TokensOut += r.TokensOut
+							c.Runs++
+						}
+					}
+					return c, nil
+				}), nil
+			}), nil
+		}},
+	)}
+
+	agentType := &object{name: "Agent", fields: typed(scalars(
+		"id", "project_id", "name", "backend", "status", "config", "version", "created_at", "updated_at"), common)}
+
+	taskType := &object{name: "Task", fields: append(typed(scalars(
+		"id", "project_id", "agent_id", "title", "prompt", "status", "result", "cost_usd", "version", "created_at", "updated_at"),
+		with(map[string]string{"result": "JSON"})),
+		&fieldDef{name: "project", typ: "Project", resolve: func(ctx context.Context, src any, _ args) (any, error) {
+			return orNull(loadersFrom(ctx).projects.load(src.(*task.Task).ProjectID)), nil
+		}},
+		&fieldDef{name: "runs", typ: "[Run]", resolve: func(ctx context.Context, src any, _ args) (any, error) {
+			return loadersFrom(ctx).runsByTask.load(src.(*task.Task).ID), nil
+		}},
+		eventsField(func(l *loaders, id string) thunk { return l.eventsByTask.load(id) }, func(src any) string { return src.(*task.Task).ID }),
+	)}
+
+	runType := &object{name: "Run", fields: append(typed(scalars(
+		"id", "task_id", "agent_id", "project_id", "team_id", "policy_profile", "effective_policy", "exec_mode", "deliver_mode", "status",
+		"step_count", "cost_usd", "tokens_in", "tokens_out", "output", "error", "redactions", "events_archived_at",
+		"version", "started_at", "completed_at", "created_at", "updated_at"),
+		with(map[string]string{"step_count": "Int", "redactions": "Int", "events_archived_at": "Time", "started_at": "Time", "completed_at": "Time"})),
+		&fieldDef{name: "task", typ: "Task", resolve: func(ctx context.Context, src any, _ args) (any, error) {
+			return orNull(loadersFrom(ctx).tasks.load(src.(*run.Run).TaskID)), nil
+		}},
+		&fieldDef{name: "artifacts", typ: "[Artifact]", resolve: func(ctx context.Context, src any, _ args) (any, error) {
+			return loadersFrom(ctx).artifactsByRun.load(src.(*run.Run).ID), nil
+		}},
+		eventsField(func(l *loaders, id string) thunk { return l.eventsByRun.load(id) }, func(src any) string { return src.(*run.Run).ID }),
+	)}
+
+	artifactType := &object{name: "Artifact", fields: typed(scalars(
+		"id", "run_id", "project_id", "kind", "name", "content_type", "size", "metadata", "created_at"),
+		with(map[string]string{"size": "Int", "metadata": "JSON"}))}
+
+	planType := &object{name: "Plan", fields: append(typed(scalars(
+		"id", "project_id", "team_id", "name", "description", "protocol", "status", "max_parallel", "version", "created_at", "updated_at"),
+		with(map[string]string{"max_parallel": "Int"})),
+		&fieldDef{name: "steps", typ: "[PlanStep]", resolve: func(ctx context.Context, src any, _ args) (any, error) {
+			if p := src.(*plan.ExecutionPlan); len(p.Steps) > 0 {
+				return p.Steps, nil
+			}
+			return loadersFrom(ctx).stepsByPlan.load(src.(*plan.ExecutionPlan).ID), nil
+		}},
+	)}
+
+	stepType := &object{name: "PlanStep", fields: append(typed(scalars(
+		"id", "plan_id", "type", "task_id", "agent_id", "model", "policy_profile", "deliver_mode", "depends_on", "status",
+		"run_id", "round", "error", "retry", "attempt", "attempts", "retry_at", "approval", "when", "loop", "created_at", "updated_at"),
+		with(map[string]string{
+			"depends_on": "[ID]", "round": "Int", "attempt": "Int", "retry_at": "Time",
+			"retry": "JSON", "attempts": "JSON", "approval": "JSON", "when": "JSON", "loop": "JSON",
+		})),
+		&fieldDef{name: "task", typ: "Task", resolve: func(ctx context.Context, src any, _ args) (any, error) {
+			if id := src.(*plan.Step).TaskID; id != "" {
+				return orNull(loadersFrom(ctx).tasks.load(id)), nil
+			}
+			return nil, nil
+		}},
+		&fieldDef{name: "run", typ: "Run", resolve: func(ctx context.Context, src any, _ args) (any, error) {
+			if id := src.(*plan.Step).RunID; id != "" {
+				return loadersFrom(ctx).runs.load(id), nil
+			}
+			return nil, nil
+		}},
+	)}
+
+	featureType := &object{name: "Feature", fields: typed(scalars(
+		"id", "project_id", "title", "description", "status", "labels", "external_ids", "version", "created_at", "updated_at"),
+		with(map[string]string{"labels": "[String]", "external_ids": "JSON"}))}
+
+	eventType := &object{name: "Event", fields: typed(scalars(
+		"id", "agent_id", "task_id", "project_id", "run_id", "type", "payload", "request_id", "version", "created_at"),
+		with(map[string]string{"payload": "JSON"}))}
+
+	costType := &object{name: "Cost", fields: typed(scalars("total_usd", "tokens_in", "tokens_out", "runs"),
+		with(map[string]string{"total_usd": "Float", "runs": "Int"}))}
+
+	return newSchema([]string{"JSON", "Time"},
+		query, proj, agentType, taskType, runType, artifactType, planType, stepType, featureType, eventType, costType)
+}
+
+// then returns a thunk that applies f to the value of th.
+func then(th thunk, f func(any) (any, error)) thunk {
+	return func() (any, error) {
+		v, err := th()
+		if err != nil {
+			return nil, err
+		}
+		return f(v)
+	}
+}
+
+// orNull turns a not-found error of th into a null value.
+func orNull(th thunk) thunk {
+	return func() (any, error) {
+		v, err := th()
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, nil
+		}
+		return v, err
+	}
+}
@@ -1,26 +1,70 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/Strob0t/CodeForge/internal/adapter/graphql"
 	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
+	"github.com/Strob0t/CodeForge/internal/adapter/ws"
 	"github.com/Strob0t/CodeForge/internal/domain"
 	"github.com/Strob0t/CodeForge/internal/domain/agent"
+	"github.com/Strob0t/CodeForge/internal/domain/apikey"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/domain/audit"
+	"github.com/Strob0t/CodeForge/internal/domain/benchmark"
+	"github.com/Strob0t/CodeForge/internal/domain/chatops"
+	"github.com/Strob0t/CodeForge/internal/domain/codegraph"
 	cfcontext "github.com/Strob0t/CodeForge/internal/domain/context"
+	"github.com/Strob0t/CodeForge/internal/domain/convention"
+	"github.com/Strob0t/CodeForge/internal/domain/conversation"
+	"github.com/Strob0t/CodeForge/internal/domain/cost"
 	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/experience"
+	"github.com/Strob0t/CodeForge/internal/domain/featureflag"
+	"github.com/Strob0t/CodeForge/internal/domain/issuerun"
+	"github.com/Strob0t/CodeForge/internal/domain/knowledge"
+	"github.com/Strob0t/CodeForge/internal/domain/lsp"
+	"github.com/Strob0t/CodeForge/internal/domain/mcpserver"
+	"github.com/Strob0t/CodeForge/internal/domain/memory"
+	"github.com/Strob0t/CodeForge/internal/domain/microagent"
 	"github.com/Strob0t/CodeForge/internal/domain/mode"
+	"github.com/Strob0t/CodeForge/internal/domain/notification"
 	"github.com/Strob0t/CodeForge/internal/domain/plan"
 	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/domain/poll"
 	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/replay"
+	"github.com/Strob0t/CodeForge/internal/domain/research"
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+	"github.com/Strob0t/CodeForge/internal/domain/review"
+	"github.com/Strob0t/CodeForge/internal/domain/roadmap"
+	"github.com/Strob0t/CodeForge/internal/domain/routing"
 	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/sarif"
+	"github.com/Strob0t/CodeForge/internal/domain/scaffold"
+	"github.com/Strob0t/CodeForge/internal/domain/scope"
+	"github.com/Strob0t/CodeForge/internal/domain/secret"
+	"github.com/Strob0t/CodeForge/internal/domain/skill"
+	"github.com/Strob0t/CodeForge/internal/domain/snapshot"
 	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/domain/vcsaccount"
+	"github.com/Strob0t/CodeForge/internal/domain/workspace"
+	"github.com/Strob0t/CodeForge/internal/middleware"
 	"github.com/Strob0t/CodeForge/internal/port/agentbackend"
 	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/port/notifier"
+	"github.com/Strob0t/CodeForge/internal/port/pmprovider"
 	"github.com/Strob0t/CodeForge/internal/service"
 )
 
@@ -39,6 +83,53 @@ type Handlers struct {
 	ContextOptimizer *service.ContextOptimizerService
 	SharedContext    *service.SharedContextService
 	Modes            *service.ModeService
+	Research         *service.ResearchService
+	Artifacts        *service.ArtifactService
+	Snapshots        *service.SnapshotService
+	Replays          *service.ReplayService
+	VCSAccounts      *service.VCSAccountService
+	Secrets          *service.SecretService
+	Retention        *service.RetentionService
+	Benchmarks       *service.BenchmarkService
+	Templates        *service.TemplateService
+	Sync             *service.SyncService
+	IssueRuns        *service.IssueRunService
+	ChatOps          *service.ChatOpsService
+	Seatbelt         *service.SeatbeltService
+	CI               *service.CIService
+	Reviews          *service.ReviewService
+	Graph            *service.GraphService
+	LSP              *service.LSPService
+	Conventions      *service.ConventionService
+	Tests            *service.TestRunnerService
+	Lint             *service.LintService
+	Tenants          *service.TenantService
+	GitHubApps       *service.GitHubAppService
+	APIKeys          *service.APIKeyService
+	Audit            *service.AuditService
+	Notifications    *service.NotificationService
+	Config           *service.ConfigService
+	FeatureFlags     *service.FeatureFlagService
+	Routing          *service.RoutingService
+	Retrieval        *service.RetrievalService
+	Knowledge        *service.KnowledgeService
+	Scopes           *service.ScopeService
+	Tokenizers       *service.TokenizerService
+	Conversations    *service.ConversationService
+	Memories         *service.MemoryService
+	Experience       *service.ExperienceService
+	Skills           *service.SkillService
+	Microagents      *service.MicroagentService
+	RunPresets       *service.RunPresetService
+	Attachments      *service.AttachmentService
+	Costs            *service.CostService
+	MCPServers       *service.MCPService
+	Workspaces       *service.WorkspaceService
+	GraphQL          *graphql.Handler             // Optional read-only GraphQL API; nil disables it
+	DeadLetters      messagequeue.DeadLetterQueue // Dead-lettered queue messages; nil disables the endpoints
+	Idempotency      *middleware.Idempotency      // Idempotency-Key replay; nil when disabled
+	WorkerDedupe     *service.WorkerDedupe        // Replayed worker results dropped; nil when disabled
+	Hub              *ws.Hub                      // WebSocket hub of this server
 }
 
 // ListProjects handles GET /api/v1/projects
@@ -79,6 +170,10 @@ func (h *Handlers) CreateProject(w http.ResponseWriter, r *http.Request) {
 
 	p, err := h.Projects.Create(r.Context(), req)
 	if err != nil {
+		if errors.Is(err, domain.ErrQuotaExceeded) || errors.Is(err, domain.ErrNotFound) {
+			writeDomainError(w, err, "tenant not found")
+			return
+		}
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -127,6 +222,10 @@ func (h *Handlers) CreateTask(w http.ResponseWriter, r *http.Request) {
 
 	t, err := h.Tasks.Create(r.Context(), req)
 	if err != nil {
+		if errors.Is(err, service.ErrForeignSubProject) || errors.Is(err, domain.ErrNotFound) {
+			writeError(w, http.StatusBadRequest, "invalid sub_project_id: "+err.Error())
+			return
+		}
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -342,6 +441,13 @@ func (h *Handlers) ListGitProviders(w http.ResponseWriter, _ *http.Request) {
 	})
 }
 
+// ListPMProviders handles GET /api/v1/providers/pm
+func (h *Handlers) ListPMProviders(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string][]string{
+		"providers": pmprovider.Available(),
+	})
+}
+
 // ListAgentBackends handles GET /api/v1/providers/agent
 func (h *Handlers) ListAgentBackends(w http.ResponseWriter, _ *http.Request) {
 	writeJSON(w, http.StatusOK, map[string][]string{
@@ -349,6 +455,17 @@ func (h *Handlers) ListAgentBackends(w http.ResponseWriter, _ *http.Request) {
 	})
 }
 
+// ListAgentCapabilities handles GET /api/v1/providers/agent/capabilities
+func (h *Handlers) ListAgentCapabilities(w http.ResponseWriter, _ *http.Request) {
+	caps := make(map[string]agentbackend.Capabilities)
+	for _, name := range agentbackend.Available() {
+		if c, ok := agentbackend.Describe(name); ok {
+			caps[name] = c
+		}
+	}
+	writeJSON(w, http.StatusOK, caps)
+}
+
 // ListLLMModels handles GET /api/v1/llm/models
 func (h *Handlers) ListLLMModels(w http.ResponseWriter, r *http.Request) {
 	models, err := h.LiteLLM.ListModels(r.Context())
@@ -412,6 +529,11 @@ func (h *Handlers) LLMHealth(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": status})
 }
 
+// LLMCacheStats handles GET /api/v1/llm/cache
+func (h *Handlers) LLMCacheStats(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.LiteLLM.CacheStats())
+}
+
 // --- Policy Endpoints ---
 
 // ListPolicyProfiles handles GET /api/v1/policies
@@ -457,6 +579,105 @@ func (h *Handlers) EvaluatePolicy(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// SimulatePolicy handles POST /api/v1/policies/{name}/simulate
+// and replays a recorded run's tool calls through the named profile.
+func (h *Handlers) SimulatePolicy(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var req policy.SimulateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.RunID == "" {
+		writeError(w, http.StatusBadRequest, "run_id is required")
+		return
+	}
+	if _, ok := h.Policies.GetProfile(name); !ok {
+		writeError(w, http.StatusNotFound, "policy profile not found")
+		return
+	}
+
+	result, err := h.Runtime.SimulatePolicy(r.Context(), name, req.RunID)
+	if err != nil {
+		writeDomainError(w, err, "run not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// ResolvePolicy handles POST /api/v1/policies/resolve
+// and returns the effective policy layered for a project/agent run context.
+func (h *Handlers) ResolvePolicy(w http.ResponseWriter, r *http.Request) {
+	var rc policy.RunContext
+	if err := json.NewDecoder(r.Body).Decode(&rc); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if rc.PolicyProfile != "" {
+		if _, ok := h.Policies.GetProfile(rc.PolicyProfile); !ok {
+			writeError(w, http.StatusNotFound, "policy profile not found")
+			return
+		}
+	}
+
+	eff, err := h.Runtime.ResolvePolicy(r.Context(), &rc)
+	if err != nil {
+		writeDomainError(w, err, "project or agent not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, eff)
+}
+
+// ExplainRouting handles POST /api/v1/routing/explain
+// and shows which routing rule picks the model for a task type.
+func (h *Handlers) ExplainRouting(w http.ResponseWriter, r *http.Request) {
+	var req routing.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if !req.TaskType.Valid() {
+		writeError(w, http.StatusBadRequest, "unknown task_type")
+		return
+	}
+
+	d, err := h.Routing.Explain(r.Context(), req)
+	if err != nil {
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+// TokenizerDiagnostics handles GET /api/v1/tokenizers/diagnostics
+func (h *Handlers) TokenizerDiagnostics(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.Tokenizers.Diagnostics())
+}
+
+// ProbeTokenizer handles POST /api/v1/tokenizers/probe
+func (h *Handlers) ProbeTokenizer(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model string `json:"model"`
+		Text  string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Text == "" {
+		writeError(w, http.StatusBadRequest, "text is required")
+		return
+	}
+
+	sample, err := h.Tokenizers.Probe(r.Context(), req.Model, req.Text)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, sample)
+}
+
 // --- Run Endpoints ---
 
 // StartRun handles POST /api/v1/runs
@@ -470,13 +691,35 @@ func (h *Handlers) StartRun(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "task_id is required")
 		return
 	}
-	if req.AgentID == "" {
+	if req.AgentID == "" && req.Preset == "" {
 		writeError(w, http.StatusBadRequest, "agent_id is required")
 		return
 	}
 
 	result, err := h.Runtime.StartRun(r.Context(), &req)
 	if err != nil {
+		if errors.Is(err, domain.ErrQuotaExceeded) {
+			writeDomainError(w, err, "")
+			return
+		}
+		if errors.Is(err, service.ErrRemoteExecMode) || errors.Is(err, service.ErrUnknownPreset) ||
+			errors.Is(err, service.ErrPresetBackend) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, run.ErrDeliverModeNotAllowed) {
+			writeError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if errors.Is(err, vcsaccount.ErrBroken) || errors.Is(err, vcsaccount.ErrReadOnly) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrDraining) {
+			w.Header().Set("Retry-After", "5")
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -494,6 +737,35 @@ func (h *Handlers) GetRun(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, result)
 }
 
+// CompareRuns handles GET /api/v1/runs/compare?ids=a,b,c
+func (h *Handlers) CompareRuns(w http.ResponseWriter, r *http.Request) {
+	ids, err := run.ParseCompareIDs(r.URL.Query().Get("ids"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	result, err := h.Runtime.CompareRuns(r.Context(), ids)
+	if err != nil {
+		if errors.Is(err, run.ErrCompareMixedTasks) {
+			writeError(w, http.StatusBadRequest, run.ErrCompareMixedTasks.Error())
+			return
+		}
+		writeDomainError(w, err, "run not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// GetRunTimeline handles GET /api/v1/runs/{id}/timeline
+func (h *Handlers) GetRunTimeline(w http.ResponseWriter, r *http.Request) {
+	timeline, err := h.Runtime.RunTimeline(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "run not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, timeline)
+}
+
 // CancelRun handles POST /api/v1/runs/{id}/cancel
 func (h *Handlers) CancelRun(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -518,6 +790,20 @@ func (h *Handlers) ListTaskRuns(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, runs)
 }
 
+// ListActiveRuns handles GET /api/v1/runs/active
+// An optional project_id query parameter limits the runs to one project.
+func (h *Handlers) ListActiveRuns(w http.ResponseWriter, r *http.Request) {
+	runs, err := h.Runtime.ListActiveRuns(r.Context(), r.URL.Query().Get("project_id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if runs == nil {
+		runs = []run.Run{}
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
+
 // --- Execution Plan Endpoints ---
 
 // CreatePlan handles POST /api/v1/projects/{id}/plans
@@ -564,6 +850,37 @@ func (h *Handlers) GetPlan(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, p)
 }
 
+// GetPlanGraph handles GET /api/v1/plans/{id}/graph
+func (h *Handlers) GetPlanGraph(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	g, err := h.Orchestrator.GetPlanGraph(r.Context(), id)
+	if err != nil {
+		writeDomainError(w, err, "plan not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, g)
+}
+
+// GetPlanDebate handles GET /api/v1/plans/{id}/steps/{stepId}/debate
+func (h *Handlers) GetPlanDebate(w http.ResponseWriter, r *http.Request) {
+	d, err := h.Orchestrator.GetDebate(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "stepId"))
+	if err != nil {
+		writeDomainError(w, err, "debate not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+// ValidatePlan handles POST /api/v1/plans/{id}/validate
+func (h *Handlers) ValidatePlan(w http.ResponseWriter, r *http.Request) {
+	sim, err := h.Orchestrator.ValidatePlan(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "plan not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, sim)
+}
+
 // StartPlan handles POST /api/v1/plans/{id}/start
 func (h *Handlers) StartPlan(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -585,6 +902,90 @@ func (h *Handlers) CancelPlan(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "cancelled"})
 }
 
+// ListPendingApprovals handles GET /api/v1/approvals
+// It lists approval steps waiting for a human; an optional project_id
+// query parameter limits them to one project.
+func (h *Handlers) ListPendingApprovals(w http.ResponseWriter, r *http.Request) {
+	steps, err := h.Orchestrator.ListPendingApprovals(r.Context(), r.URL.Query().Get("project_id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if steps == nil {
+		steps = []plan.Step{}
+	}
+	writeJSON(w, http.StatusOK, steps)
+}
+
+// RerunPlan handles POST /api/v1/plans/{id}/rerun
+func (h *Handlers) RerunPlan(w http.ResponseWriter, r *http.Request) {
+	var req plan.RerunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	p, err := h.Orchestrator.RerunPlan(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, plan.ErrRerunMode), errors.Is(err, plan.ErrRerunStep):
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		case errors.Is(err, plan.ErrRerunNotFinished), errors.Is(err, plan.ErrNothingToRerun):
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		writeDomainError(w, err, "plan not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, p)
+}
+
+// ApprovePlanStep handles POST /api/v1/plans/{id}/steps/{stepId}/approve
+func (h *Handlers) ApprovePlanStep(w http.ResponseWriter, r *http.Request) {
+	h.resolvePlanApproval(w, r, true)
+}
+
+// RejectPlanStep handles POST /api/v1/plans/{id}/steps/{stepId}/reject
+func (h *Handlers) RejectPlanStep(w http.ResponseWriter, r *http.Request) {
+	h.resolvePlanApproval(w, r, false)
+}
+
+func (h *Handlers) resolvePlanApproval(w http.ResponseWriter, r *http.Request, approved bool) {
+	planID := chi.URLParam(r, "id")
+	stepID := chi.URLParam(r, "stepId")
+
+	var req plan.ResolveApprovalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	// The vote counts for whoever authenticated, not whoever the body names.
+	if err := req.Bind(middleware.Voter(r.Context())); err != nil {
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	step, err := h.Orchestrator.ResolveApproval(r.Context(), planID, stepID, approved, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrStepNotAwaitingApproval), errors.Is(err, plan.ErrAlreadyVoted):
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		case errors.Is(err, plan.ErrNotApprover):
+			writeError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		writeDomainError(w, err, "plan or step not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, step)
+}
+
 // --- Feature Decomposition (Meta-Agent) ---
 
 // DecomposeFeature handles POST /api/v1/projects/{id}/decompose
@@ -721,6 +1122,70 @@ func (h *Handlers) BuildContextPack(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusCreated, pack)
 }
 
+// PreviewContextPack handles POST /api/v1/tasks/{id}/context/preview
+func (h *Handlers) PreviewContextPack(w http.ResponseWriter, r *http.Request) {
+	taskID := chi.URLParam(r, "id")
+
+	var req struct {
+		ProjectID string `json:"project_id"`
+		TeamID    string `json:"team_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.ProjectID == "" {
+		writeError(w, http.StatusBadRequest, "project_id is required")
+		return
+	}
+
+	preview, err := h.ContextOptimizer.PreviewContextPack(r.Context(), taskID, req.ProjectID, req.TeamID)
+	if err != nil {
+		writeDomainError(w, err, "task not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, preview)
+}
+
+// GetContextCuration handles GET /api/v1/tasks/{id}/context/curation
+func (h *Handlers) GetContextCuration(w http.ResponseWriter, r *http.Request) {
+	c, err := h.ContextOptimizer.GetCuration(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "context curation not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// SetContextCuration handles PUT /api/v1/tasks/{id}/context/curation
+func (h *Handlers) SetContextCuration(w http.ResponseWriter, r *http.Request) {
+	var c cfcontext.Curation
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	c.TaskID = chi.URLParam(r, "id")
+
+	if err := h.ContextOptimizer.SetCuration(r.Context(), &c); err != nil {
+		if errors.Is(err, cfcontext.ErrInvalidCuration) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeDomainError(w, err, "task not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// DeleteContextCuration handles DELETE /api/v1/tasks/{id}/context/curation
+func (h *Handlers) DeleteContextCuration(w http.ResponseWriter, r *http.Request) {
+	if err := h.ContextOptimizer.DeleteCuration(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeDomainError(w, err, "context curation not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // --- Shared Context Endpoints ---
 
 // GetSharedContext handles GET /api/v1/teams/{id}/shared-context
@@ -789,10 +1254,3128 @@ func (h *Handlers) CreateMode(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusCreated, m)
 }
 
-// --- Helpers ---
+// --- Research & Artifact Endpoints ---
 
-type errorResponse struct {
-	Error string `json:"error"`
+// StartResearch handles POST /api/v1/projects/{id}/research
+func (h *Handlers) StartResearch(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "id")
+
+	var req research.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	req.ProjectID = projectID
+
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.Research.Available() {
+		writeError(w, http.StatusServiceUnavailable, service.ErrResearchUnavailable.Error())
+		return
+	}
+
+	report, err := h.Research.Run(r.Context(), &req)
+	if err != nil {
+		writeDomainError(w, err, "task or agent not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, report)
+}
+
+// ListRunArtifacts handles GET /api/v1/runs/{id}/artifacts
+func (h *Handlers) ListRunArtifacts(w http.ResponseWriter, r *http.Request) {
+	runID := chi.URLParam(r, "id")
+	arts, err := h.Artifacts.ListByRun(r.Context(), runID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if arts == nil {
+		arts = []artifact.Artifact{}
+	}
+	writeJSON(w, http.StatusOK, arts)
+}
+
+// GetArtifactContent handles GET /api/v1/artifacts/{id}/content
+// and returns the raw artifact data with its stored content type.
+func (h *Handlers) GetArtifactContent(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	a, err := h.Artifacts.Get(r.Context(), id)
+	if err != nil {
+		writeDomainError(w, err, "artifact not found")
+		return
+	}
+	w.Header().Set("Content-Type", a.ContentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(a.Data)
+}
+
+// ExportTrajectory handles GET /api/v1/runs/{id}/trajectory
+// It streams the run's events as JSON Lines, restoring archived runs from
+// their event archive.
+func (h *Handlers) ExportTrajectory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	events, err := h.Retention.LoadRunEvents(r.Context(), id)
+	if err != nil {
+		writeDomainError(w, err, "run not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="run-`+id+`.jsonl"`)
+	w.WriteHeader(http.StatusOK)
+	if err := event.WriteJSONL(w, events); err != nil {
+		slog.Warn("trajectory export interrupted", "run_id", id, "error", err)
+	}
+}
+
+// --- Workspace Snapshot Endpoints ---
+
+// CreateSnapshot handles POST /api/v1/runs/{id}/snapshots
+func (h *Handlers) CreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	runID := chi.URLParam(r, "id")
+
+	var req snapshot.CreateRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	snap, err := h.Snapshots.Create(r.Context(), runID, &req)
+	if err != nil {
+		writeDomainError(w, err, "run not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, snap)
+}
+
+// ListRunSnapshots handles GET /api/v1/runs/{id}/snapshots
+func (h *Handlers) ListRunSnapshots(w http.ResponseWriter, r *http.Request) {
+	runID := chi.URLParam(r, "id")
+	snaps, err := h.Snapshots.ListByRun(r.Context(), runID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, snaps)
+}
+
+// ListProjectSnapshots handles GET /api/v1/projects/{id}/snapshots
+func (h *Handlers) ListProjectSnapshots(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "id")
+	snaps, err := h.Snapshots.ListByProject(r.Context(), projectID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, snaps)
+}
+
+// RestoreSnapshot handles POST /api/v1/runs/{id}/restore
+// and resets the run's workspace to a snapshot from the same project.
+func (h *Handlers) RestoreSnapshot(w http.ResponseWriter, r *http.Request) {
+	runID := chi.URLParam(r, "id")
+
+	var req snapshot.RestoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	snap, err := h.Snapshots.Restore(r.Context(), runID, &req)
+	if err != nil {
+		if errors.Is(err, service.ErrWorkspaceBusy) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		writeDomainError(w, err, "run or snapshot not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, snap)
+}
+
+// --- Replay Session Endpoints ---
+
+// CreateReplaySession handles POST /api/v1/replay-sessions
+// and starts stepping through a run's events at the first one.
+func (h *Handlers) CreateReplaySession(w http.ResponseWriter, r *http.Request) {
+	var req replay.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	v, err := h.Replays.Create(r.Context(), &req)
+	if err != nil {
+		writeReplayError(w, err, "run not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, v)
+}
+
+// GetReplaySession handles GET /api/v1/replay-sessions/{id}
+func (h *Handlers) GetReplaySession(w http.ResponseWriter, r *http.Request) {
+	v, err := h.Replays.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeReplayError(w, err, "replay session not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+// StepReplaySession handles POST /api/v1/replay-sessions/{id}/step
+// An empty body advances by one event.
+func (h *Handlers) StepReplaySession(w http.ResponseWriter, r *http.Request) {
+	var req replay.SeekRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+	v, err := h.Replays.Step(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeReplayError(w, err, "replay session not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+// GetReplayState handles GET /api/v1/replay-sessions/{id}/state
+// and lists the files of the workspace checkpoint at the current step.
+func (h *Handlers) GetReplayState(w http.ResponseWriter, r *http.Request) {
+	st, err := h.Replays.State(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeReplayError(w, err, "replay session not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// GetReplayFile handles GET /api/v1/replay-sessions/{id}/files/{path}
+// and returns the file as it was in the checkpoint at the current step.
+func (h *Handlers) GetReplayFile(w http.ResponseWriter, r *http.Request) {
+	data, err := h.Replays.File(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "*"))
+	if err != nil {
+		writeReplayError(w, err, "replay session or file not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+// ForkReplaySession handles POST /api/v1/replay-sessions/{id}/fork
+// and starts a new run of the task from the checkpoint of a step.
+func (h *Handlers) ForkReplaySession(w http.ResponseWriter, r *http.Request) {
+	var req replay.ForkRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+	fork, err := h.Replays.Fork(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeReplayError(w, err, "replay session not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, fork)
+}
+
+// DeleteReplaySession handles DELETE /api/v1/replay-sessions/{id}
+func (h *Handlers) DeleteReplaySession(w http.ResponseWriter, r *http.Request) {
+	if err := h.Replays.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeReplayError(w, err, "replay session not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeReplayError maps replay errors to HTTP status codes.
+func writeReplayError(w http.ResponseWriter, err error, fallbackMsg string) {
+	switch {
+	case errors.Is(err, replay.ErrInvalidRequest), errors.Is(err, replay.ErrOutOfRange), errors.Is(err, replay.ErrNoEvents):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, replay.ErrNoCheckpoint):
+		writeError(w, http.StatusNotFound, err.Error())
+	default:
+		writeDomainError(w, err, fallbackMsg)
+	}
+}
+
+// --- VCS Account Endpoints ---
+
+// ListVCSAccounts handles GET /api/v1/vcs-accounts
+// and lists the checked git host accounts with the projects using them.
+func (h *Handlers) ListVCSAccounts(w http.ResponseWriter, r *http.Request) {
+	accounts, err := h.VCSAccounts.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if accounts == nil {
+		accounts = []vcsaccount.Account{}
+	}
+	writeJSON(w, http.StatusOK, accounts)
+}
+
+// GetProjectVCSAccount handles GET /api/v1/projects/{id}/vcs-account
+// and returns the latest check of the project's git host token.
+func (h *Handlers) GetProjectVCSAccount(w http.ResponseWriter, r *http.Request) {
+	health, err := h.VCSAccounts.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "project account not checked yet")
+		return
+	}
+	writeJSON(w, http.StatusOK, health)
+}
+
+// TestProjectVCSAccount handles POST /api/v1/projects/{id}/vcs-account/test
+// and checks the project's git host token now.
+func (h *Handlers) TestProjectVCSAccount(w http.ResponseWriter, r *http.Request) {
+	health, err := h.VCSAccounts.Test(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, service.ErrNoVCSAccount) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, health)
+}
+
+// --- Secret Endpoints ---
+
+// ListSecrets handles GET /api/v1/secrets (tenant-wide secrets)
+func (h *Handlers) ListSecrets(w http.ResponseWriter, r *http.Request) {
+	h.listSecrets(w, r, "")
+}
+
+// CreateSecret handles POST /api/v1/secrets (tenant-wide secret)
+func (h *Handlers) CreateSecret(w http.ResponseWriter, r *http.Request) {
+	h.createSecret(w, r, "")
+}
+
+// ListProjectSecrets handles GET /api/v1/projects/{id}/secrets
+func (h *Handlers) ListProjectSecrets(w http.ResponseWriter, r *http.Request) {
+	h.listSecrets(w, r, chi.URLParam(r, "id"))
+}
+
+// CreateProjectSecret handles POST /api/v1/projects/{id}/secrets
+func (h *Handlers) CreateProjectSecret(w http.ResponseWriter, r *http.Request) {
+	h.createSecret(w, r, chi.URLParam(r, "id"))
+}
+
+// UpdateSecret handles PUT /api/v1/secrets/{id} and rotates the value.
+func (h *Handlers) UpdateSecret(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req secret.UpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sec, err := h.Secrets.Update(r.Context(), id, &req)
+	if err != nil {
+		if errors.Is(err, service.ErrSecretsUnavailable) {
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		writeDomainError(w, err, "secret not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, sec)
+}
+
+// DeleteSecret handles DELETE /api/v1/secrets/{id}
+func (h *Handlers) DeleteSecret(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := h.Secrets.Delete(r.Context(), id); err != nil {
+		writeDomainError(w, err, "secret not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) listSecrets(w http.ResponseWriter, r *http.Request, projectID string) {
+	secs, err := h.Secrets.List(r.Context(), projectID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if secs == nil {
+		secs = []secret.Secret{}
+	}
+	writeJSON(w, http.StatusOK, secs)
+}
+
+func (h *Handlers) createSecret(w http.ResponseWriter, r *http.Request, projectID string) {
+	var req secret.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sec, err := h.Secrets.Create(r.Context(), projectID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSecretsUnavailable):
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+		case errors.Is(err, domain.ErrConflict):
+			writeError(w, http.StatusConflict, "secret already exists")
+		default:
+			writeDomainError(w, err, "project not found")
+		}
+		return
+	}
+	writeJSON(w, http.StatusCreated, sec)
+}
+
+// --- Tenant Endpoints ---
+
+// ListTenants handles GET /api/v1/tenants
+func (h *Handlers) ListTenants(w http.ResponseWriter, r *http.Request) {
+	tenants, err := h.Tenants.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if tenants == nil {
+		tenants = []tenant.Tenant{}
+	}
+	writeJSON(w, http.StatusOK, tenants)
+}
+
+// CreateTenant handles POST /api/v1/tenants
+func (h *Handlers) CreateTenant(w http.ResponseWriter, r *http.Request) {
+	var req tenant.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	t, err := h.Tenants.Create(r.Context(), &req)
+	if err != nil {
+		if errors.Is(err, domain.ErrConflict) {
+			writeError(w, http.StatusConflict, "tenant already exists")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, t)
+}
+
+// GetTenant handles GET /api/v1/tenants/{id}
+func (h *Handlers) GetTenant(w http.ResponseWriter, r *http.Request) {
+	t, err := h.Tenants.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "tenant not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// UpdateTenantQuota handles PUT /api/v1/tenants/{id}/quota
+func (h *Handlers) UpdateTenantQuota(w http.ResponseWriter, r *http.Request) {
+	var q tenant.Quota
+	if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := q.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	t, err := h.Tenants.SetQuota(r.Context(), chi.URLParam(r, "id"), q)
+	if err != nil {
+		writeDomainError(w, err, "tenant not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// TenantUsage handles GET /api/v1/tenants/{id}/usage
+func (h *Handlers) TenantUsage(w http.ResponseWriter, r *http.Request) {
+	u, err := h.Tenants.Usage(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "tenant not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+// SetTenantGitHubApp handles PUT /api/v1/tenants/{id}/github-app
+func (h *Handlers) SetTenantGitHubApp(w http.ResponseWriter, r *http.Request) {
+	var app tenant.GitHubApp
+	if err := json.NewDecoder(r.Body).Decode(&app); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	t, err := h.GitHubApps.Set(r.Context(), chi.URLParam(r, "id"), &app)
+	if errors.Is(err, tenant.ErrInvalidGitHubApp) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeDomainError(w, err, "tenant not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// DeleteTenantGitHubApp handles DELETE /api/v1/tenants/{id}/github-app
+func (h *Handlers) DeleteTenantGitHubApp(w http.ResponseWriter, r *http.Request) {
+	if err := h.GitHubApps.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeDomainError(w, err, "tenant not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyTenantIsolation handles GET /api/v1/tenancy/isolation
+func (h *Handlers) VerifyTenantIsolation(w http.ResponseWriter, r *http.Request) {
+	report, err := h.Tenants.VerifyIsolation(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// RekeySecrets handles POST /api/v1/tenancy/rekey and moves secrets still
+// encrypted with the shared key to their tenant's key.
+func (h *Handlers) RekeySecrets(w http.ResponseWriter, r *http.Request) {
+	n, err := h.Secrets.Rekey(r.Context())
+	if err != nil {
+		if errors.Is(err, service.ErrSecretsUnavailable) {
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"rekeyed": n})
+}
+
+// --- API Key Endpoints ---
+
+// ListAPIKeys handles GET /api/v1/auth/api-keys
+func (h *Handlers) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.APIKeys.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if keys == nil {
+		keys = []apikey.Key{}
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+// CreateAPIKey handles POST /api/v1/auth/api-keys
+func (h *Handlers) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req apikey.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	k, err := h.APIKeys.Create(r.Context(), &req)
+	if err != nil {
+		writeDomainError(w, err, "tenant not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, k)
+}
+
+// GetAPIKey handles GET /api/v1/auth/api-keys/{id}
+func (h *Handlers) GetAPIKey(w http.ResponseWriter, r *http.Request) {
+	k, err := h.APIKeys.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "api key not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, k)
+}
+
+// UpdateAPIKey handles PUT /api/v1/auth/api-keys/{id}
+func (h *Handlers) UpdateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req apikey.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	k, err := h.APIKeys.Update(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeDomainError(w, err, "api key or tenant not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, k)
+}
+
+// DeleteAPIKey handles DELETE /api/v1/auth/api-keys/{id}
+func (h *Handlers) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	if err := h.APIKeys.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeDomainError(w, err, "api key not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Audit Endpoints ---
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// ListAuditEntries handles GET /api/v1/audit/entries?after=&limit=
+func (h *Handlers) ListAuditEntries(w http.ResponseWriter, r *http.Request) {
+	var after int64
+	if v := r.URL.Query().Get("after"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "after must be a non-negative seq")
+			return
+		}
+		after = n
+	}
+	limit := defaultAuditLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAuditLimit {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		limit = n
+	}
+	entries, err := h.Audit.ListEntries(r.Context(), after, limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if entries == nil {
+		entries = []event.AuditEntry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// ListAuditSinks handles GET /api/v1/audit/sinks
+func (h *Handlers) ListAuditSinks(w http.ResponseWriter, r *http.Request) {
+	sinks, err := h.Audit.ListSinks(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if sinks == nil {
+		sinks = []audit.Sink{}
+	}
+	writeJSON(w, http.StatusOK, sinks)
+}
+
+// CreateAuditSink handles POST /api/v1/audit/sinks
+func (h *Handlers) CreateAuditSink(w http.ResponseWriter, r *http.Request) {
+	var req audit.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sink, err := h.Audit.CreateSink(r.Context(), &req)
+	if err != nil {
+		writeAuditSinkError(w, err, "tenant not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, sink)
+}
+
+// GetAuditSink handles GET /api/v1/audit/sinks/{id}
+func (h *Handlers) GetAuditSink(w http.ResponseWriter, r *http.Request) {
+	sink, err := h.Audit.GetSink(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "audit sink not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, sink)
+}
+
+// UpdateAuditSink handles PUT /api/v1/audit/sinks/{id}
+func (h *Handlers) UpdateAuditSink(w http.ResponseWriter, r *http.Request) {
+	var req audit.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sink, err := h.Audit.UpdateSink(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeAuditSinkError(w, err, "audit sink not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, sink)
+}
+
+// DeleteAuditSink handles DELETE /api/v1/audit/sinks/{id}
+func (h *Handlers) DeleteAuditSink(w http.ResponseWriter, r *http.Request) {
+	if err := h.Audit.DeleteSink(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeDomainError(w, err, "audit sink not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeAuditSinkError(w http.ResponseWriter, err error, fallbackMsg string) {
+	if errors.Is(err, domain.ErrConflict) {
+		writeError(w, http.StatusConflict, "an audit sink with this name already exists")
+		return
+	}
+	writeDomainError(w, err, fallbackMsg)
+}
+
+// --- Admin Endpoints ---
+
+// GetConfig handles GET /api/v1/admin/config
+func (h *Handlers) GetConfig(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.Config.Explain())
+}
+
+// ListFeatureFlags handles GET /api/v1/admin/feature-flags
+func (h *Handlers) ListFeatureFlags(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.FeatureFlags.List(r.Context()))
+}
+
+// SetFeatureFlag handles PUT /api/v1/admin/feature-flags/{key}
+func (h *Handlers) SetFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	key := featureflag.Key(chi.URLParam(r, "key"))
+	var req featureflag.SetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(key); err != nil {
+		writeFeatureFlagError(w, err)
+		return
+	}
+
+	f, err := h.FeatureFlags.Set(r.Context(), key, &req)
+	if err != nil {
+		writeDomainError(w, err, "tenant or project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, f)
+}
+
+// DeleteFeatureFlag handles DELETE /api/v1/admin/feature-flags/{key}?tenant_id=&project_id=
+func (h *Handlers) DeleteFeatureFlag(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	err := h.FeatureFlags.Delete(r.Context(), featureflag.Key(chi.URLParam(r, "key")), q.Get("tenant_id"), q.Get("project_id"))
+	if errors.Is(err, featureflag.ErrProjectOrTenant) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeDomainError(w, err, "feature flag override not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetProjectFeatureFlags handles GET /api/v1/projects/{id}/feature-flags
+func (h *Handlers) GetProjectFeatureFlags(w http.ResponseWriter, r *http.Request) {
+	states, err := h.FeatureFlags.Evaluate(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, states)
+}
+
+func writeFeatureFlagError(w http.ResponseWriter, err error) {
+	if errors.Is(err, featureflag.ErrUnknownKey) {
+		writeError(w, http.StatusNotFound, "feature flag not found")
+		return
+	}
+	writeError(w, http.StatusBadRequest, err.Error())
+}
+
+// --- Project Template Endpoints ---
+
+// ListTemplates handles GET /api/v1/templates
+func (h *Handlers) ListTemplates(w http.ResponseWriter, _ *http.Request) {
+	templates := h.Templates.List()
+	if templates == nil {
+		templates = []scaffold.Template{}
+	}
+	writeJSON(w, http.StatusOK, templates)
+}
+
+// GetTemplate handles GET /api/v1/templates/{name}
+func (h *Handlers) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	t, err := h.Templates.Get(chi.URLParam(r, "name"))
+	if err != nil {
+		writeDomainError(w, err, "project template not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// CreateProjectFromTemplate handles POST /api/v1/projects/from-template/{name}
+func (h *Handlers) CreateProjectFromTemplate(w http.ResponseWriter, r *http.Request) {
+	var req scaffold.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	result, err := h.Templates.Create(r.Context(), chi.URLParam(r, "name"), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, scaffold.ErrNameRequired),
+			errors.Is(err, scaffold.ErrMissingVariable),
+			errors.Is(err, scaffold.ErrUnknownVariable),
+			errors.Is(err, scaffold.ErrInvalidPath),
+			errors.Is(err, scaffold.ErrCannotInitialize):
+			writeError(w, http.StatusBadRequest, err.Error())
+		default:
+			writeDomainError(w, err, "project template or tenant not found")
+		}
+		return
+	}
+	writeJSON(w, http.StatusCreated, result)
+}
+
+// --- Benchmark Endpoints ---
+
+// ListBenchmarkSuites handles GET /api/v1/benchmarks/suites
+func (h *Handlers) ListBenchmarkSuites(w http.ResponseWriter, _ *http.Request) {
+	suites := h.Benchmarks.ListSuites()
+	if suites == nil {
+		suites = []benchmark.Suite{}
+	}
+	writeJSON(w, http.StatusOK, suites)
+}
+
+// StartBenchmark handles POST /api/v1/benchmarks/runs
+func (h *Handlers) StartBenchmark(w http.ResponseWriter, r *http.Request) {
+	var req benchmark.StartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	br, err := h.Benchmarks.Start(r.Context(), &req)
+	if err != nil {
+		if errors.Is(err, benchmark.ErrUnknownCase) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeDomainError(w, err, "benchmark suite or agent not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, br)
+}
+
+// ListBenchmarkRuns handles GET /api/v1/benchmarks/runs?suite=
+func (h *Handlers) ListBenchmarkRuns(w http.ResponseWriter, r *http.Request) {
+	runs, err := h.Benchmarks.List(r.Context(), r.URL.Query().Get("suite"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if runs == nil {
+		runs = []benchmark.Run{}
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
+
+// GetBenchmarkRun handles GET /api/v1/benchmarks/runs/{id}
+func (h *Handlers) GetBenchmarkRun(w http.ResponseWriter, r *http.Request) {
+	br, err := h.Benchmarks.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "benchmark run not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, br)
+}
+
+// GetBenchmarkLeaderboard handles GET /api/v1/benchmarks/suites/{name}/leaderboard
+func (h *Handlers) GetBenchmarkLeaderboard(w http.ResponseWriter, r *http.Request) {
+	board, err := h.Benchmarks.Leaderboard(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		writeDomainError(w, err, "benchmark suite not found")
+		return
+	}
+	if board == nil {
+		board = []benchmark.LeaderboardEntry{}
+	}
+	writeJSON(w, http.StatusOK, board)
+}
+
+// --- Sub-Project Endpoints ---
+
+// ListSubProjects handles GET /api/v1/projects/{id}/sub-projects
+func (h *Handlers) ListSubProjects(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.Projects.ListSubProjects(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if subs == nil {
+		subs = []project.SubProject{}
+	}
+	writeJSON(w, http.StatusOK, subs)
+}
+
+// CreateSubProject handles POST /api/v1/projects/{id}/sub-projects
+func (h *Handlers) CreateSubProject(w http.ResponseWriter, r *http.Request) {
+	var req project.CreateSubProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sp, err := h.Projects.CreateSubProject(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		if errors.Is(err, domain.ErrConflict) {
+			writeError(w, http.StatusConflict, "a sub-project with this name or path already exists")
+			return
+		}
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, sp)
+}
+
+// GetSubProject handles GET /api/v1/sub-projects/{id}
+func (h *Handlers) GetSubProject(w http.ResponseWriter, r *http.Request) {
+	sp, err := h.Projects.GetSubProject(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "sub-project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, sp)
+}
+
+// DeleteSubProject handles DELETE /api/v1/sub-projects/{id}
+func (h *Handlers) DeleteSubProject(w http.ResponseWriter, r *http.Request) {
+	if err := h.Projects.DeleteSubProject(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeDomainError(w, err, "sub-project not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GraphImpact handles POST /api/v1/projects/{id}/graph/impact
+func (h *Handlers) GraphImpact(w http.ResponseWriter, r *http.Request) {
+	var req codegraph.ImpactRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	impact, err := h.Graph.Impact(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		if errors.Is(err, codegraph.ErrImpactInput) || errors.Is(err, codegraph.ErrImpactDepth) || errors.Is(err, codegraph.ErrImpactFiles) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeDomainError(w, err, "project or run not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, impact)
+}
+
+// GetRepoMap handles GET /api/v1/projects/{id}/graph/repo-map?max_files=
+func (h *Handlers) GetRepoMap(w http.ResponseWriter, r *http.Request) {
+	maxFiles := 0
+	if v := r.URL.Query().Get("max_files"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > codegraph.MaxRepoMapFiles {
+			writeError(w, http.StatusBadRequest, "max_files must be between 1 and 1000")
+			return
+		}
+		maxFiles = n
+	}
+	m, err := h.Graph.RepoMap(r.Context(), chi.URLParam(r, "id"), maxFiles)
+	if err != nil {
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+// GetRepoMapStatus handles GET /api/v1/projects/{id}/graph/repo-map/status
+func (h *Handlers) GetRepoMapStatus(w http.ResponseWriter, r *http.Request) {
+	st, err := h.Graph.RepoMapStatus(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// BuildGraph handles POST /api/v1/projects/{id}/graph/build
+// It parses the workspace and persists the project's code graph.
+func (h *Handlers) BuildGraph(w http.ResponseWriter, r *http.Request) {
+	st, err := h.Graph.Build(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// GetGraph handles GET /api/v1/projects/{id}/graph
+func (h *Handlers) GetGraph(w http.ResponseWriter, r *http.Request) {
+	st, err := h.Graph.State(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "project has no code graph")
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// UpdateGraph handles POST /api/v1/projects/{id}/graph/update
+// It reparses the given files in the persisted code graph.
+func (h *Handlers) UpdateGraph(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Paths []string `json:"paths"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(req.Paths) == 0 || len(req.Paths) > codegraph.MaxImpactFiles {
+		writeError(w, http.StatusBadRequest, "paths must name between 1 and 500 files")
+		return
+	}
+	st, err := h.Graph.Update(r.Context(), chi.URLParam(r, "id"), req.Paths)
+	if err != nil {
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	if st == nil {
+		writeError(w, http.StatusNotFound, "project has no code graph")
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// GetGraphNeighbors handles GET /api/v1/projects/{id}/graph/neighbors?path=
+func (h *Handlers) GetGraphNeighbors(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Query().Get("path")
+	if p == "" {
+		writeError(w, http.StatusBadRequest, "path is required")
+		return
+	}
+	n, err := h.Graph.Neighbors(r.Context(), chi.URLParam(r, "id"), p)
+	if err != nil {
+		writeDomainError(w, err, "code graph or file not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, n)
+}
+
+// GetGraphPath handles GET /api/v1/projects/{id}/graph/path?from=&to=
+// It returns the shortest chain of dependencies between two files; the
+// path is empty if from does not depend on to.
+func (h *Handlers) GetGraphPath(w http.ResponseWriter, r *http.Request) {
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if from == "" || to == "" {
+		writeError(w, http.StatusBadRequest, "from and to are required")
+		return
+	}
+	dp, err := h.Graph.Path(r.Context(), chi.URLParam(r, "id"), from, to)
+	if err != nil {
+		writeDomainError(w, err, "code graph or file not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, dp)
+}
+
+// LSPDefinition handles POST /api/v1/projects/{id}/lsp/definition
+func (h *Handlers) LSPDefinition(w http.ResponseWriter, r *http.Request) {
+	var req lsp.PositionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	locs, err := h.LSP.Definition(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeLSPError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, locs)
+}
+
+// LSPReferences handles POST /api/v1/projects/{id}/lsp/references
+func (h *Handlers) LSPReferences(w http.ResponseWriter, r *http.Request) {
+	var req lsp.PositionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	locs, err := h.LSP.References(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeLSPError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, locs)
+}
+
+// LSPHover handles POST /api/v1/projects/{id}/lsp/hover
+// It returns null when there is nothing at the position.
+func (h *Handlers) LSPHover(w http.ResponseWriter, r *http.Request) {
+	var req lsp.PositionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	hover, err := h.LSP.Hover(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeLSPError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, hover)
+}
+
+// LSPSymbols handles GET /api/v1/projects/{id}/lsp/symbols?path=
+func (h *Handlers) LSPSymbols(w http.ResponseWriter, r *http.Request) {
+	syms, err := h.LSP.Symbols(r.Context(), chi.URLParam(r, "id"), r.URL.Query().Get("path"))
+	if err != nil {
+		writeLSPError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, syms)
+}
+
+// LSPRename handles POST /api/v1/projects/{id}/lsp/rename
+// The edit is written to the workspace when "apply" is set and the policy
+// profile allows editing every file it changes.
+func (h *Handlers) LSPRename(w http.ResponseWriter, r *http.Request) {
+	var req lsp.RenameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	res, err := h.LSP.Rename(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeLSPError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// LSPCodeActions handles POST /api/v1/projects/{id}/lsp/code-actions
+func (h *Handlers) LSPCodeActions(w http.ResponseWriter, r *http.Request) {
+	var req lsp.CodeActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	actions, err := h.LSP.CodeActions(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeLSPError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, actions)
+}
+
+// LSPApplyCodeAction handles POST /api/v1/projects/{id}/lsp/code-actions/apply
+func (h *Handlers) LSPApplyCodeAction(w http.ResponseWriter, r *http.Request) {
+	var req lsp.CodeActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	res, err := h.LSP.ApplyCodeAction(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeLSPError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// LSPFormat handles POST /api/v1/projects/{id}/lsp/format
+func (h *Handlers) LSPFormat(w http.ResponseWriter, r *http.Request) {
+	var req lsp.FormatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	res, err := h.LSP.Format(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeLSPError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func writeLSPError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, lsp.ErrInvalidRequest), errors.Is(err, lsp.ErrInvalidEdit), errors.Is(err, lsp.ErrNoServer):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, lsp.ErrDisabled), errors.Is(err, lsp.ErrEditDenied):
+		writeError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, lsp.ErrActionNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		writeError(w, http.StatusGatewayTimeout, "language server timed out")
+	default:
+		writeDomainError(w, err, "project or file not found")
+	}
+}
+
+// GetConventions handles GET /api/v1/projects/{id}/conventions
+func (h *Handlers) GetConventions(w http.ResponseWriter, r *http.Request) {
+	c, err := h.Conventions.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "project has no conventions")
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// ExtractConventions handles POST /api/v1/projects/{id}/conventions
+// It analyzes the workspace and commit history and replaces the project's
+// conventions.
+func (h *Handlers) ExtractConventions(w http.ResponseWriter, r *http.Request) {
+	c, err := h.Conventions.Extract(r.Context(), chi.URLParam(r, "id"), convention.TriggerManual)
+	if err != nil {
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// IndexProject handles POST /api/v1/projects/{id}/retrieval/index
+func (h *Handlers) IndexProject(w http.ResponseWriter, r *http.Request) {
+	idx, err := h.Retrieval.Index(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, idx)
+}
+
+// GetRetrievalIndex handles GET /api/v1/projects/{id}/retrieval/index
+func (h *Handlers) GetRetrievalIndex(w http.ResponseWriter, r *http.Request) {
+	idx, err := h.Retrieval.Status(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "project has no retrieval index")
+		return
+	}
+	writeJSON(w, http.StatusOK, idx)
+}
+
+// SearchRetrieval handles POST /api/v1/projects/{id}/retrieval/search
+func (h *Handlers) SearchRetrieval(w http.ResponseWriter, r *http.Request) {
+	var req retrieval.SearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	results, err := h.Retrieval.Search(r.Context(), chi.URLParam(r, "id"), &req)
+	switch {
+	case errors.Is(err, retrieval.ErrQueryRequired):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, retrieval.ErrNotIndexed):
+		writeError(w, http.StatusNotFound, retrieval.ErrNotIndexed.Error())
+	case errors.Is(err, domain.ErrConflict):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeDomainError(w, err, "project not found")
+	default:
+		writeJSON(w, http.StatusOK, results)
+	}
+}
+
+// ListGoldenQueries handles GET /api/v1/projects/{id}/retrieval/golden-queries
+func (h *Handlers) ListGoldenQueries(w http.ResponseWriter, r *http.Request) {
+	queries, err := h.Retrieval.GoldenQueries(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if queries == nil {
+		queries = []retrieval.GoldenQuery{}
+	}
+	writeJSON(w, http.StatusOK, queries)
+}
+
+// CreateGoldenQuery handles POST /api/v1/projects/{id}/retrieval/golden-queries
+func (h *Handlers) CreateGoldenQuery(w http.ResponseWriter, r *http.Request) {
+	var q retrieval.GoldenQuery
+	if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := q.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.Retrieval.AddGoldenQuery(r.Context(), chi.URLParam(r, "id"), &q); err != nil {
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, q)
+}
+
+// DeleteGoldenQuery handles DELETE /api/v1/retrieval/golden-queries/{id}
+func (h *Handlers) DeleteGoldenQuery(w http.ResponseWriter, r *http.Request) {
+	if err := h.Retrieval.DeleteGoldenQuery(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeDomainError(w, err, "golden query not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// maxEvalLimit caps the evaluations listed per request.
+const maxEvalLimit = 200
+
+// ListRetrievalEvals handles GET /api/v1/projects/{id}/retrieval/evals
+func (h *Handlers) ListRetrievalEvals(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxEvalLimit {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and 200")
+			return
+		}
+		limit = n
+	}
+	evals, err := h.Retrieval.Evals(r.Context(), chi.URLParam(r, "id"), limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if evals == nil {
+		evals = []retrieval.Eval{}
+	}
+	writeJSON(w, http.StatusOK, evals)
+}
+
+// EvaluateRetrieval handles POST /api/v1/projects/{id}/retrieval/evals
+func (h *Handlers) EvaluateRetrieval(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		K int `json:"k"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+	e, err := h.Retrieval.Evaluate(r.Context(), chi.URLParam(r, "id"), req.K, retrieval.EvalManual)
+	switch {
+	case errors.Is(err, retrieval.ErrNoGoldenQueries):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, retrieval.ErrNotIndexed):
+		writeError(w, http.StatusNotFound, retrieval.ErrNotIndexed.Error())
+	case errors.Is(err, domain.ErrConflict):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		writeJSON(w, http.StatusCreated, e)
+	}
+}
+
+// MigrateIndex handles POST /api/v1/projects/{id}/index/migrate
+// It starts moving the project's index to another embedding in the
+// background; without a body it migrates to the configured one.
+func (h *Handlers) MigrateIndex(w http.ResponseWriter, r *http.Request) {
+	var req retrieval.MigrateRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Provider != "" || req.Model != "" {
+		if err := req.Embedding.Validate(); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	m, err := h.Retrieval.MigrateIndex(r.Context(), chi.URLParam(r, "id"), &req)
+	switch {
+	case errors.Is(err, retrieval.ErrSameEmbedding):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, retrieval.ErrMigrationRunning):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, retrieval.ErrNotIndexed):
+		writeError(w, http.StatusNotFound, retrieval.ErrNotIndexed.Error())
+	case err != nil:
+		writeDomainError(w, err, "project not found")
+	default:
+		writeJSON(w, http.StatusAccepted, m)
+	}
+}
+
+// GetIndexMigration handles GET /api/v1/projects/{id}/index/migrate
+// It returns the running or last index migration of the project.
+func (h *Handlers) GetIndexMigration(w http.ResponseWriter, r *http.Request) {
+	m, err := h.Retrieval.Migration(chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "project has no index migration")
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+// GetRunCitations handles GET /api/v1/runs/{id}/citations
+// It resolves the citation markers in the run's final output to file
+// lines or knowledge base documents.
+func (h *Handlers) GetRunCitations(w http.ResponseWriter, r *http.Request) {
+	citations, err := h.Retrieval.RunCitations(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "run not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, citations)
+}
+
+// GetRunDelivery handles GET /api/v1/runs/{id}/delivery
+func (h *Handlers) GetRunDelivery(w http.ResponseWriter, r *http.Request) {
+	d, err := h.Seatbelt.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "delivery not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+// GetRunCI handles GET /api/v1/runs/{id}/ci
+func (h *Handlers) GetRunCI(w http.ResponseWriter, r *http.Request) {
+	st, err := h.CI.Status(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "ci status not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// --- Knowledge Base Endpoints ---
+
+// ListKnowledgeBases handles GET /api/v1/knowledge-bases
+func (h *Handlers) ListKnowledgeBases(w http.ResponseWriter, r *http.Request) {
+	kbs, err := h.Knowledge.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if kbs == nil {
+		kbs = []knowledge.KnowledgeBase{}
+	}
+	writeJSON(w, http.StatusOK, kbs)
+}
+
+// CreateKnowledgeBase handles POST /api/v1/knowledge-bases
+func (h *Handlers) CreateKnowledgeBase(w http.ResponseWriter, r *http.Request) {
+	var req knowledge.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	kb, err := h.Knowledge.Create(r.Context(), &req)
+	if err != nil {
+		writeKnowledgeBaseError(w, err, "tenant or project not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, kb)
+}
+
+// GetKnowledgeBase handles GET /api/v1/knowledge-bases/{id}
+func (h *Handlers) GetKnowledgeBase(w http.ResponseWriter, r *http.Request) {
+	kb, err := h.Knowledge.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "knowledge base not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, kb)
+}
+
+// UpdateKnowledgeBase handles PUT /api/v1/knowledge-bases/{id}
+func (h *Handlers) UpdateKnowledgeBase(w http.ResponseWriter, r *http.Request) {
+	var req knowledge.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	kb, err := h.Knowledge.Update(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeKnowledgeBaseError(w, err, "knowledge base or project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, kb)
+}
+
+// DeleteKnowledgeBase handles DELETE /api/v1/knowledge-bases/{id}
+func (h *Handlers) DeleteKnowledgeBase(w http.ResponseWriter, r *http.Request) {
+	if err := h.Knowledge.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeDomainError(w, err, "knowledge base not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RefreshKnowledgeBase handles POST /api/v1/knowledge-bases/{id}/refresh
+func (h *Handlers) RefreshKnowledgeBase(w http.ResponseWriter, r *http.Request) {
+	kb, err := h.Knowledge.Refresh(r.Context(), chi.URLParam(r, "id"))
+	switch {
+	case errors.Is(err, domain.ErrConflict):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeDomainError(w, err, "knowledge base not found")
+	default:
+		writeJSON(w, http.StatusOK, kb)
+	}
+}
+
+func writeKnowledgeBaseError(w http.ResponseWriter, err error, fallbackMsg string) {
+	if errors.Is(err, domain.ErrConflict) {
+		writeError(w, http.StatusConflict, "a knowledge base with this name already exists")
+		return
+	}
+	writeDomainError(w, err, fallbackMsg)
+}
+
+// --- Scope Endpoints ---
+
+// ListScopes handles GET /api/v1/scopes
+func (h *Handlers) ListScopes(w http.ResponseWriter, r *http.Request) {
+	scopes, err := h.Scopes.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if scopes == nil {
+		scopes = []scope.Scope{}
+	}
+	writeJSON(w, http.StatusOK, scopes)
+}
+
+// CreateScope handles POST /api/v1/scopes
+func (h *Handlers) CreateScope(w http.ResponseWriter, r *http.Request) {
+	var req scope.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sc, err := h.Scopes.Create(r.Context(), &req)
+	if err != nil {
+		writeScopeError(w, err, "tenant, project or knowledge base not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, sc)
+}
+
+// GetScope handles GET /api/v1/scopes/{id}
+func (h *Handlers) GetScope(w http.ResponseWriter, r *http.Request) {
+	sc, err := h.Scopes.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "scope not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, sc)
+}
+
+// UpdateScope handles PUT /api/v1/scopes/{id}
+func (h *Handlers) UpdateScope(w http.ResponseWriter, r *http.Request) {
+	var req scope.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sc, err := h.Scopes.Update(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeScopeError(w, err, "scope, project or knowledge base not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, sc)
+}
+
+// DeleteScope handles DELETE /api/v1/scopes/{id}
+func (h *Handlers) DeleteScope(w http.ResponseWriter, r *http.Request) {
+	if err := h.Scopes.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeDomainError(w, err, "scope not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SearchScope handles POST /api/v1/scopes/{id}/search
+// and ranks the chunks of the scope's projects and knowledge bases together.
+func (h *Handlers) SearchScope(w http.ResponseWriter, r *http.Request) {
+	var req retrieval.SearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	res, err := h.Scopes.Search(r.Context(), chi.URLParam(r, "id"), &req)
+	switch {
+	case errors.Is(err, retrieval.ErrQueryRequired):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case err != nil:
+		writeDomainError(w, err, "scope not found")
+	default:
+		writeJSON(w, http.StatusOK, res)
+	}
+}
+
+func writeScopeError(w http.ResponseWriter, err error, fallbackMsg string) {
+	if errors.Is(err, domain.ErrConflict) {
+		writeError(w, http.StatusConflict, "a scope with this name already exists")
+		return
+	}
+	writeDomainError(w, err, fallbackMsg)
+}
+
+// --- Conversation Endpoints ---
+
+// ListConversations handles GET /api/v1/projects/{id}/conversations
+func (h *Handlers) ListConversations(w http.ResponseWriter, r *http.Request) {
+	convs, err := h.Conversations.List(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if convs == nil {
+		convs = []conversation.Conversation{}
+	}
+	writeJSON(w, http.StatusOK, convs)
+}
+
+// CreateConversation handles POST /api/v1/projects/{id}/conversations
+func (h *Handlers) CreateConversation(w http.ResponseWriter, r *http.Request) {
+	var req conversation.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	c, err := h.Conversations.Create(r.Context(), chi.URLParam(r, "id"), req)
+	if err != nil {
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, c)
+}
+
+// GetConversation handles GET /api/v1/conversations/{id}
+func (h *Handlers) GetConversation(w http.ResponseWriter, r *http.Request) {
+	c, err := h.Conversations.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "conversation not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// ListConversationMessages handles GET /api/v1/conversations/{id}/messages.
+// With ?view=compressed only the messages sent to the LLM are returned.
+func (h *Handlers) ListConversationMessages(w http.ResponseWriter, r *http.Request) {
+	compressed := r.URL.Query().Get("view") == "compressed"
+	msgs, err := h.Conversations.Messages(r.Context(), chi.URLParam(r, "id"), compressed)
+	if err != nil {
+		writeDomainError(w, err, "conversation not found")
+		return
+	}
+	if msgs == nil {
+		msgs = []conversation.Message{}
+	}
+	writeJSON(w, http.StatusOK, msgs)
+}
+
+// SendConversationMessage handles POST /api/v1/conversations/{id}/messages
+func (h *Handlers) SendConversationMessage(w http.ResponseWriter, r *http.Request) {
+	var req conversation.SendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reply, err := h.Conversations.Send(r.Context(), chi.URLParam(r, "id"), req)
+	if err != nil {
+		writeAttachmentError(w, err, "conversation not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, reply)
+}
+
+// StreamConversationMessage handles POST /api/v1/conversations/{id}/messages/stream.
+// The reply is sent as server-sent events: a "delta" event per piece of
+// content, then a "message" event with the stored reply, or an "error"
+// event if the stream fails after it started. Errors before the first
+// piece are plain JSON error responses.
+func (h *Handlers) StreamConversationMessage(w http.ResponseWriter, r *http.Request) {
+	var req conversation.SendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	started := false
+	event := func(name string, data any) {
+		if !started {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		b, err := json.Marshal(data)
+		if err != nil {
+			slog.Error("marshal conversation stream event", "event", name, "error", err)
+			return
+		}
+		_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, b)
+		_ = rc.Flush()
+	}
+
+	reply, err := h.Conversations.Stream(r.Context(), chi.URLParam(r, "id"), req, func(delta string) {
+		event("delta", map[string]string{"content": delta})
+	})
+	if err != nil {
+		if !started {
+			writeAttachmentError(w, err, "conversation not found")
+			return
+		}
+		event("error", errorResponse{Error: err.Error()})
+		return
+	}
+	event("message", reply)
+}
+
+// --- Memory Endpoints ---
+
+// ListMemories handles GET /api/v1/projects/{id}/memories
+func (h *Handlers) ListMemories(w http.ResponseWriter, r *http.Request) {
+	memories, err := h.Memories.List(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if memories == nil {
+		memories = []memory.Memory{}
+	}
+	writeJSON(w, http.StatusOK, memories)
+}
+
+// CreateMemory handles POST /api/v1/projects/{id}/memories
+func (h *Handlers) CreateMemory(w http.ResponseWriter, r *http.Request) {
+	var req memory.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	m, err := h.Memories.Store(r.Context(), chi.URLParam(r, "id"), req)
+	if err != nil {
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, m)
+}
+
+// RecallMemories handles POST /api/v1/projects/{id}/memories/recall
+func (h *Handlers) RecallMemories(w http.ResponseWriter, r *http.Request) {
+	var req memory.RecallRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	recalled, err := h.Memories.Recall(r.Context(), chi.URLParam(r, "id"), req)
+	switch {
+	case errors.Is(err, memory.ErrContentRequired):
+		writeError(w, http.StatusBadRequest, "query is required")
+	case err != nil:
+		writeDomainError(w, err, "project not found")
+	case recalled == nil:
+		writeJSON(w, http.StatusOK, []memory.Recalled{})
+	default:
+		writeJSON(w, http.StatusOK, recalled)
+	}
+}
+
+// DeleteMemory handles DELETE /api/v1/memories/{id}
+func (h *Handlers) DeleteMemory(w http.ResponseWriter, r *http.Request) {
+	if err := h.Memories.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeDomainError(w, err, "memory not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Experience Pool Endpoints ---
+
+// ListExperiences handles GET /api/v1/projects/{id}/experiences
+func (h *Handlers) ListExperiences(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.Experience.List(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if entries == nil {
+		entries = []experience.Entry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// RateExperience handles POST /api/v1/experiences/{id}/rate
+func (h *Handlers) RateExperience(w http.ResponseWriter, r *http.Request) {
+	var req experience.RateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	e, err := h.Experience.Rate(r.Context(), chi.URLParam(r, "id"), req)
+	if err != nil {
+		writeDomainError(w, err, "experience not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+// --- Skill Endpoints ---
+
+// ListSkills handles GET /api/v1/projects/{id}/skills
+func (h *Handlers) ListSkills(w http.ResponseWriter, r *http.Request) {
+	skills, err := h.Skills.List(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if skills == nil {
+		skills = []skill.Skill{}
+	}
+	writeJSON(w, http.StatusOK, skills)
+}
+
+// CreateSkill handles POST /api/v1/projects/{id}/skills
+func (h *Handlers) CreateSkill(w http.ResponseWriter, r *http.Request) {
+	var req skill.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	sk, err := h.Skills.Create(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeSkillError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, sk)
+}
+
+// GetSkill handles GET /api/v1/skills/{id}
+func (h *Handlers) GetSkill(w http.ResponseWriter, r *http.Request) {
+	sk, err := h.Skills.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "skill not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, sk)
+}
+
+// DeleteSkill handles DELETE /api/v1/skills/{id}
+func (h *Handlers) DeleteSkill(w http.ResponseWriter, r *http.Request) {
+	if err := h.Skills.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeDomainError(w, err, "skill not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ExportSkill handles GET /api/v1/skills/{id}/export
+// It returns the skill as a signed bundle (gzipped tarball).
+func (h *Handlers) ExportSkill(w http.ResponseWriter, r *http.Request) {
+	data, sk, err := h.Skills.Export(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeSkillError(w, err, "skill not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+sk.Name+`-`+sk.Version+`.skill.tar.gz"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+// ImportSkill handles POST /api/v1/projects/{id}/skills/import
+// The request body is a bundle as returned by ExportSkill.
+func (h *Handlers) ImportSkill(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, int64(h.Skills.MaxBundleBytes())+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	sk, err := h.Skills.Import(r.Context(), chi.URLParam(r, "id"), data)
+	if err != nil {
+		writeSkillError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, sk)
+}
+
+// ListSkillRegistry handles GET /api/v1/skills/registry
+func (h *Handlers) ListSkillRegistry(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.Skills.Registry(r.Context())
+	if err != nil {
+		writeSkillError(w, err, "registry not found")
+		return
+	}
+	if entries == nil {
+		entries = []skill.IndexEntry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// InstallSkill handles POST /api/v1/projects/{id}/skills/install
+func (h *Handlers) InstallSkill(w http.ResponseWriter, r *http.Request) {
+	var req skill.InstallRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	sk, err := h.Skills.Install(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeSkillError(w, err, "skill not found in registry")
+		return
+	}
+	writeJSON(w, http.StatusCreated, sk)
+}
+
+// writeSkillError maps skill and bundle errors to HTTP statuses. Bundles
+// that fail verification are rejected with 422.
+func writeSkillError(w http.ResponseWriter, err error, fallbackMsg string) {
+	switch {
+	case errors.Is(err, skill.ErrInvalidName), errors.Is(err, skill.ErrPromptRequired),
+		errors.Is(err, skill.ErrInvalidFixture), errors.Is(err, skill.ErrInvalidBundle):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, skill.ErrUntrustedSigner), errors.Is(err, skill.ErrBadSignature),
+		errors.Is(err, service.ErrBundleChecksum):
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, service.ErrBundleTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, service.ErrSkillSigningUnavailable), errors.Is(err, service.ErrSkillRegistryUnavailable):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, domain.ErrConflict):
+		writeError(w, http.StatusConflict, "project already has a skill with this name")
+	default:
+		writeDomainError(w, err, fallbackMsg)
+	}
+}
+
+// --- Cost Endpoints ---
+
+// ListCosts handles GET /api/v1/costs
+func (h *Handlers) ListCosts(w http.ResponseWriter, r *http.Request) {
+	sums, err := h.Costs.Summaries(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if sums == nil {
+		sums = []cost.Summary{}
+	}
+	writeJSON(w, http.StatusOK, sums)
+}
+
+// GetProjectCosts handles GET /api/v1/projects/{id}/costs
+func (h *Handlers) GetProjectCosts(w http.ResponseWriter, r *http.Request) {
+	sum, err := h.Costs.Project(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, sum)
+}
+
+// ListDailyCosts handles GET /api/v1/costs/daily
+func (h *Handlers) ListDailyCosts(w http.ResponseWriter, r *http.Request) {
+	h.writeDailyCosts(w, r, "")
+}
+
+// GetProjectDailyCosts handles GET /api/v1/projects/{id}/costs/daily
+func (h *Handlers) GetProjectDailyCosts(w http.ResponseWriter, r *http.Request) {
+	h.writeDailyCosts(w, r, chi.URLParam(r, "id"))
+}
+
+// writeDailyCosts writes the daily totals of the last ?days (default 30).
+func (h *Handlers) writeDailyCosts(w http.ResponseWriter, r *http.Request, projectID string) {
+	days := service.DefaultCostDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > service.MaxCostDays {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("days must be between 1 and %d", service.MaxCostDays))
+			return
+		}
+		days = n
+	}
+	series, err := h.Costs.Daily(r.Context(), projectID, days)
+	if err != nil {
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	if series == nil {
+		series = []cost.Day{}
+	}
+	writeJSON(w, http.StatusOK, series)
+}
+
+// GetPlanStepCosts handles GET /api/v1/plans/{id}/costs/by-step
+func (h *Handlers) GetPlanStepCosts(w http.ResponseWriter, r *http.Request) {
+	steps, err := h.Costs.ByStep(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "plan not found")
+		return
+	}
+	if steps == nil {
+		steps = []cost.StepCost{}
+	}
+	writeJSON(w, http.StatusOK, steps)
+}
+
+// GetProjectToolCosts handles GET /api/v1/projects/{id}/costs/by-tool
+func (h *Handlers) GetProjectToolCosts(w http.ResponseWriter, r *http.Request) {
+	usage, err := h.Costs.ByTool(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	if usage == nil {
+		usage = []cost.Usage{}
+	}
+	writeJSON(w, http.StatusOK, usage)
+}
+
+// --- Microagent Endpoints ---
+
+// ListMicroagents handles GET /api/v1/projects/{id}/microagents
+func (h *Handlers) ListMicroagents(w http.ResponseWriter, r *http.Request) {
+	agents, err := h.Microagents.List(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if agents == nil {
+		agents = []microagent.Microagent{}
+	}
+	writeJSON(w, http.StatusOK, agents)
+}
+
+// CreateMicroagent handles POST /api/v1/projects/{id}/microagents
+func (h *Handlers) CreateMicroagent(w http.ResponseWriter, r *http.Request) {
+	var req microagent.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	m, err := h.Microagents.Create(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeMicroagentError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, m)
+}
+
+// GetMicroagent handles GET /api/v1/microagents/{id}
+func (h *Handlers) GetMicroagent(w http.ResponseWriter, r *http.Request) {
+	m, err := h.Microagents.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "microagent not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+// UpdateMicroagent handles PUT /api/v1/microagents/{id}
+func (h *Handlers) UpdateMicroagent(w http.ResponseWriter, r *http.Request) {
+	var req microagent.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	m, err := h.Microagents.Update(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeMicroagentError(w, err, "microagent not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+// DeleteMicroagent handles DELETE /api/v1/microagents/{id}
+func (h *Handlers) DeleteMicroagent(w http.ResponseWriter, r *http.Request) {
+	if err := h.Microagents.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeDomainError(w, err, "microagent not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeMicroagentError maps microagent validation errors to 400 and name
+// conflicts to 409.
+func writeMicroagentError(w http.ResponseWriter, err error, fallbackMsg string) {
+	switch {
+	case errors.Is(err, microagent.ErrNameRequired), errors.Is(err, microagent.ErrKnowledgeRequired),
+		errors.Is(err, microagent.ErrNoTrigger), errors.Is(err, microagent.ErrInvalidPattern):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrConflict):
+		writeError(w, http.StatusConflict, "project already has a microagent with this name")
+	default:
+		writeDomainError(w, err, fallbackMsg)
+	}
+}
+
+// --- Run Preset Endpoints ---
+
+// ListRunPresets handles GET /api/v1/projects/{id}/presets
+func (h *Handlers) ListRunPresets(w http.ResponseWriter, r *http.Request) {
+	presets, err := h.RunPresets.List(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if presets == nil {
+		presets = []run.Preset{}
+	}
+	writeJSON(w, http.StatusOK, presets)
+}
+
+// CreateRunPreset handles POST /api/v1/projects/{id}/presets
+func (h *Handlers) CreateRunPreset(w http.ResponseWriter, r *http.Request) {
+	var req run.PresetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	p, err := h.RunPresets.Create(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeRunPresetError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, p)
+}
+
+// GetRunPreset handles GET /api/v1/projects/{id}/presets/{name}
+func (h *Handlers) GetRunPreset(w http.ResponseWriter, r *http.Request) {
+	p, err := h.RunPresets.Get(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "name"))
+	if err != nil {
+		writeDomainError(w, err, "run preset not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// UpdateRunPreset handles PUT /api/v1/projects/{id}/presets/{name}
+func (h *Handlers) UpdateRunPreset(w http.ResponseWriter, r *http.Request) {
+	var req run.PresetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	p, err := h.RunPresets.Update(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "name"), &req)
+	if err != nil {
+		writeRunPresetError(w, err, "run preset not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// DeleteRunPreset handles DELETE /api/v1/projects/{id}/presets/{name}
+func (h *Handlers) DeleteRunPreset(w http.ResponseWriter, r *http.Request) {
+	if err := h.RunPresets.Delete(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "name")); err != nil {
+		writeDomainError(w, err, "run preset not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeRunPresetError maps preset validation errors to 400 and name
+// conflicts to 409.
+func writeRunPresetError(w http.ResponseWriter, err error, fallbackMsg string) {
+	switch {
+	case errors.Is(err, run.ErrInvalidPreset):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrConflict):
+		writeError(w, http.StatusConflict, "project already has a run preset with this name")
+	default:
+		writeDomainError(w, err, fallbackMsg)
+	}
+}
+
+// --- Notification Endpoints ---
+
+// ListNotifiers handles GET /api/v1/notifiers
+func (h *Handlers) ListNotifiers(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.Notifications.Kinds())
+}
+
+// ListNotificationRules handles GET /api/v1/projects/{id}/notification-rules
+func (h *Handlers) ListNotificationRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.Notifications.ListRules(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if rules == nil {
+		rules = []notification.Rule{}
+	}
+	writeJSON(w, http.StatusOK, rules)
+}
+
+// CreateNotificationRule handles POST /api/v1/projects/{id}/notification-rules
+func (h *Handlers) CreateNotificationRule(w http.ResponseWriter, r *http.Request) {
+	var req notification.CreateRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rule, err := h.Notifications.CreateRule(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeNotificationRuleError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, rule)
+}
+
+// GetNotificationRule handles GET /api/v1/notification-rules/{id}
+func (h *Handlers) GetNotificationRule(w http.ResponseWriter, r *http.Request) {
+	rule, err := h.Notifications.GetRule(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "notification rule not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, rule)
+}
+
+// UpdateNotificationRule handles PUT /api/v1/notification-rules/{id}
+func (h *Handlers) UpdateNotificationRule(w http.ResponseWriter, r *http.Request) {
+	var req notification.CreateRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rule, err := h.Notifications.UpdateRule(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeNotificationRuleError(w, err, "notification rule not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, rule)
+}
+
+// DeleteNotificationRule handles DELETE /api/v1/notification-rules/{id}
+func (h *Handlers) DeleteNotificationRule(w http.ResponseWriter, r *http.Request) {
+	if err := h.Notifications.DeleteRule(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeDomainError(w, err, "notification rule not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetNotificationSettings handles GET /api/v1/projects/{id}/notification-settings
+func (h *Handlers) GetNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	st, err := h.Notifications.GetSettings(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// SetNotificationSettings handles PUT /api/v1/projects/{id}/notification-settings
+func (h *Handlers) SetNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	var st notification.Settings
+	if err := json.NewDecoder(r.Body).Decode(&st); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := st.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	saved, err := h.Notifications.SetSettings(r.Context(), chi.URLParam(r, "id"), &st)
+	if err != nil {
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, saved)
+}
+
+func writeNotificationRuleError(w http.ResponseWriter, err error, fallbackMsg string) {
+	switch {
+	case errors.Is(err, service.ErrUnknownNotifier), errors.Is(err, service.ErrSlackNotInteractive):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrConflict):
+		writeError(w, http.StatusConflict, "project already has a notification rule with this name")
+	default:
+		writeDomainError(w, err, fallbackMsg)
+	}
+}
+
+// --- Attachment Endpoints ---
+
+// UploadAttachment handles POST /api/v1/projects/{id}/attachments
+// The file is sent in the multipart field "file"; the optional field
+// "task_id" attaches it to a task of the project. Files sent without a
+// specific content type are sniffed.
+func (h *Handlers) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	maxBytes := int64(h.Attachments.MaxBytes())
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes+1<<20) // Room for the other form fields
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, service.ErrAttachmentTooLarge.Error())
+			return
+		}
+		writeError(w, http.StatusBadRequest, `multipart field "file" is required`)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "read attachment: "+err.Error())
+		return
+	}
+	contentType := header.Header.Get("Content-Type")
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = http.DetectContentType(data)
+	}
+
+	a, err := h.Attachments.Upload(r.Context(), chi.URLParam(r, "id"), r.FormValue("task_id"), header.Filename, contentType, data)
+	if err != nil {
+		writeAttachmentError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, a)
+}
+
+// ListTaskAttachments handles GET /api/v1/tasks/{id}/attachments
+func (h *Handlers) ListTaskAttachments(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Attachments.ListByTask(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if list == nil {
+		list = []artifact.Artifact{}
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// writeAttachmentError maps attachment errors to 400 and 413, and others
+// like writeDomainError.
+func writeAttachmentError(w http.ResponseWriter, err error, fallbackMsg string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidAttachment), errors.Is(err, service.ErrUnknownAttachment):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrAttachmentTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+	default:
+		writeDomainError(w, err, fallbackMsg)
+	}
+}
+
+// --- Workspace File Endpoints ---
+
+// maxWorkspaceRequestBytes bounds the bodies of workspace writes and
+// patches, leaving room for JSON escaping of the file content.
+const maxWorkspaceRequestBytes = 8 << 20
+
+// ListWorkspaceFiles handles GET /api/v1/projects/{id}/files?path=&depth=&run_id=
+// depth defaults to 1 (the direct children of path); 0 lists the subtree.
+func (h *Handlers) ListWorkspaceFiles(w http.ResponseWriter, r *http.Request) {
+	depth := 1
+	if v := r.URL.Query().Get("depth"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "depth must be a non-negative integer")
+			return
+		}
+		depth = n
+	}
+	q := r.URL.Query()
+	tree, err := h.Workspaces.Tree(r.Context(), chi.URLParam(r, "id"), q.Get("run_id"), q.Get("path"), depth)
+	if err != nil {
+		writeWorkspaceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tree)
+}
+
+// ReadWorkspaceFile handles GET /api/v1/projects/{id}/files/{path}?start=&end=&run_id=
+// start and end select a range of lines (1-based, inclusive).
+func (h *Handlers) ReadWorkspaceFile(w http.ResponseWriter, r *http.Request) {
+	var lines [2]int
+	for i, key := range []string{"start", "end"} {
+		if v := r.URL.Query().Get(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				writeError(w, http.StatusBadRequest, key+" must be a positive line number")
+				return
+			}
+			lines[i] = n
+		}
+	}
+	f, err := h.Workspaces.Read(r.Context(), chi.URLParam(r, "id"), r.URL.Query().Get("run_id"), chi.URLParam(r, "*"), lines[0], lines[1])
+	if err != nil {
+		writeWorkspaceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, f)
+}
+
+// WriteWorkspaceFile handles PUT /api/v1/projects/{id}/files/{path}?run_id=
+// A write without base_hash creates the file and fails if it exists.
+func (h *Handlers) WriteWorkspaceFile(w http.ResponseWriter, r *http.Request) {
+	var req workspace.WriteRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWorkspaceRequestBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	f, err := h.Workspaces.Write(r.Context(), chi.URLParam(r, "id"), r.URL.Query().Get("run_id"), chi.URLParam(r, "*"), &req)
+	if err != nil {
+		writeWorkspaceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, f)
+}
+
+// PatchWorkspaceFile handles PATCH /api/v1/projects/{id}/files/{path}?run_id=
+func (h *Handlers) PatchWorkspaceFile(w http.ResponseWriter, r *http.Request) {
+	var req workspace.PatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWorkspaceRequestBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	f, err := h.Workspaces.Patch(r.Context(), chi.URLParam(r, "id"), r.URL.Query().Get("run_id"), chi.URLParam(r, "*"), &req)
+	if err != nil {
+		writeWorkspaceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, f)
+}
+
+// writeWorkspaceError maps workspace file errors to 400, 403, 409 and 413,
+// and others like writeDomainError.
+func writeWorkspaceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidPath), errors.Is(err, workspace.ErrInvalidEdit):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrWriteDenied):
+		writeError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrWorkspaceBusy), errors.Is(err, domain.ErrConflict):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrFileTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, domain.ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	default:
+		writeDomainError(w, err, "")
+	}
+}
+
+// --- MCP Server Endpoints ---
+
+// ListMCPServers handles GET /api/v1/mcp-servers
+func (h *Handlers) ListMCPServers(w http.ResponseWriter, r *http.Request) {
+	servers, err := h.MCPServers.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if servers == nil {
+		servers = []mcpserver.Server{}
+	}
+	writeJSON(w, http.StatusOK, servers)
+}
+
+// CreateMCPServer handles POST /api/v1/mcp-servers
+func (h *Handlers) CreateMCPServer(w http.ResponseWriter, r *http.Request) {
+	var req mcpserver.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	m, err := h.MCPServers.Create(r.Context(), &req)
+	if err != nil {
+		writeMCPServerError(w, err, "mcp server not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, m)
+}
+
+// GetMCPServer handles GET /api/v1/mcp-servers/{id}
+func (h *Handlers) GetMCPServer(w http.ResponseWriter, r *http.Request) {
+	m, err := h.MCPServers.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "mcp server not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+// UpdateMCPServer handles PUT /api/v1/mcp-servers/{id}
+func (h *Handlers) UpdateMCPServer(w http.ResponseWriter, r *http.Request) {
+	var req mcpserver.CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	m, err := h.MCPServers.Update(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeMCPServerError(w, err, "mcp server not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+// DeleteMCPServer handles DELETE /api/v1/mcp-servers/{id}
+func (h *Handlers) DeleteMCPServer(w http.ResponseWriter, r *http.Request) {
+	if err := h.MCPServers.Delete(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeDomainError(w, err, "mcp server not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListMCPServerTools handles GET /api/v1/mcp-servers/{id}/tools
+func (h *Handlers) ListMCPServerTools(w http.ResponseWriter, r *http.Request) {
+	tools, err := h.MCPServers.Tools(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeMCPServerError(w, err, "mcp server not found")
+		return
+	}
+	if tools == nil {
+		tools = []mcpserver.Tool{}
+	}
+	writeJSON(w, http.StatusOK, tools)
+}
+
+// CallMCPServerTool handles POST /api/v1/mcp-servers/{id}/tools/call
+func (h *Handlers) CallMCPServerTool(w http.ResponseWriter, r *http.Request) {
+	var req mcpserver.CallRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	res, err := h.MCPServers.CallTool(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeMCPServerError(w, err, "mcp server not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// writeMCPServerError maps validation errors to 400, name conflicts and
+// disabled servers to 409, a missing master key to 503 and failures of the
+// remote server or its authorization server to 502.
+func writeMCPServerError(w http.ResponseWriter, err error, fallbackMsg string) {
+	switch {
+	case errors.Is(err, mcpserver.ErrNameRequired), errors.Is(err, mcpserver.ErrInvalidURL),
+		errors.Is(err, mcpserver.ErrInvalidTransport), errors.Is(err, mcpserver.ErrInvalidAuth),
+		errors.Is(err, mcpserver.ErrOAuthIncomplete), errors.Is(err, mcpserver.ErrTooManyScopes),
+		errors.Is(err, mcpserver.ErrToolRequired):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrConflict):
+		writeError(w, http.StatusConflict, "an mcp server with this name already exists")
+	case errors.Is(err, service.ErrMCPServerDisabled):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrSecretsUnavailable):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	case errors.Is(err, domain.ErrNotFound):
+		writeDomainError(w, err, fallbackMsg)
+	default:
+		writeError(w, http.StatusBadGateway, err.Error())
+	}
+}
+
+// SetAgentSubProject handles PUT /api/v1/agents/{id}/sub-project
+func (h *Handlers) SetAgentSubProject(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		SubProjectID string `json:"sub_project_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	ag, err := h.Agents.SetSubProject(r.Context(), chi.URLParam(r, "id"), req.SubProjectID)
+	if err != nil {
+		if errors.Is(err, service.ErrForeignSubProject) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeDomainError(w, err, "agent or sub-project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, ag)
+}
+
+// --- Roadmap Endpoints ---
+
+// maxWebhookBytes limits the size of webhook deliveries.
+const maxWebhookBytes = 1 << 20
+
+// ListRoadmapFeatures handles GET /api/v1/projects/{id}/roadmap/features
+func (h *Handlers) ListRoadmapFeatures(w http.ResponseWriter, r *http.Request) {
+	features, err := h.Sync.ListFeatures(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	if features == nil {
+		features = []roadmap.Feature{}
+	}
+	writeJSON(w, http.StatusOK, features)
+}
+
+// ImportRoadmap handles POST /api/v1/projects/{id}/roadmap/import and
+// imports the items of the project's PM platform as roadmap features.
+func (h *Handlers) ImportRoadmap(w http.ResponseWriter, r *http.Request) {
+	res, err := h.Sync.Import(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeSyncError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// UpdateRoadmapFeatureStatus handles PUT /api/v1/roadmap/features/{id}/status
+func (h *Handlers) UpdateRoadmapFeatureStatus(w http.ResponseWriter, r *http.Request) {
+	var req roadmap.UpdateStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	f, err := h.Sync.UpdateFeatureStatus(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		writeSyncError(w, err, "feature not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, f)
+}
+
+// HandlePMWebhook handles POST /api/v1/webhooks/pm/{provider}
+func (h *Handlers) HandlePMWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	applied, err := h.Sync.HandleWebhook(r.Context(), chi.URLParam(r, "provider"), r.Header, body)
+	if err != nil {
+		if errors.Is(err, pmprovider.ErrInvalidSignature) {
+			writeError(w, http.StatusUnauthorized, "invalid webhook signature")
+			return
+		}
+		writeDomainError(w, err, "feature not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"applied": applied})
+}
+
+func writeSyncError(w http.ResponseWriter, err error, fallbackMsg string) {
+	switch {
+	case errors.Is(err, service.ErrNoPMProvider), errors.Is(err, service.ErrPMCapability):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrPMPush):
+		writeError(w, http.StatusBadGateway, err.Error())
+	case errors.Is(err, service.ErrSecretsUnavailable):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		writeDomainError(w, err, fallbackMsg)
+	}
+}
+
+// --- Issue Automation Endpoints ---
+
+// GetIssueAutomation handles GET /api/v1/projects/{id}/automation
+func (h *Handlers) GetIssueAutomation(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.IssueRuns.GetSettings(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// UpdateIssueAutomation handles PUT /api/v1/projects/{id}/automation
+func (h *Handlers) UpdateIssueAutomation(w http.ResponseWriter, r *http.Request) {
+	var settings issuerun.Settings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := h.IssueRuns.UpdateSettings(r.Context(), chi.URLParam(r, "id"), &settings); err != nil {
+		if errors.Is(err, issuerun.ErrInvalidDeliverMode) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeDomainError(w, err, "project or agent not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, settings)
+}
+
+// ListIssueRuns handles GET /api/v1/projects/{id}/issue-runs
+func (h *Handlers) ListIssueRuns(w http.ResponseWriter, r *http.Request) {
+	runs, err := h.IssueRuns.List(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	if runs == nil {
+		runs = []issuerun.IssueRun{}
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
+
+// HandleGitWebhook handles POST /api/v1/webhooks/git/{provider}
+func (h *Handlers) HandleGitWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	// Issue changes, pull request comments, CI results and pushes arrive on
+	// the same endpoint; each service ignores the others' events.
+	provider := chi.URLParam(r, "provider")
+	started, issueErr := h.IssueRuns.HandleWebhook(r.Context(), provider, r.Header, body)
+	commands, chatErr := h.ChatOps.HandleWebhook(r.Context(), provider, r.Header, body)
+	failed, seatbeltErr := h.Seatbelt.HandleWebhook(r.Context(), provider, r.Header, body)
+	if errors.Is(issueErr, gitprovider.ErrInvalidSignature) && errors.Is(chatErr, gitprovider.ErrInvalidSignature) &&
+		errors.Is(seatbeltErr, gitprovider.ErrInvalidSignature) {
+		writeError(w, http.StatusUnauthorized, "invalid webhook signature")
+		return
+	}
+	for _, err := range []error{issueErr, chatErr, seatbeltErr} {
+		if err != nil && !errors.Is(err, gitprovider.ErrInvalidSignature) {
+			writeDomainError(w, err, "project not found")
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"started": started, "commands": commands, "failed_downstream": failed})
+}
+
+// HandleGitHubAppWebhook handles POST /api/v1/webhooks/github-app/{tenant}
+func (h *Handlers) HandleGitHubAppWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	created, err := h.GitHubApps.HandleWebhook(r.Context(), chi.URLParam(r, "tenant"), r.Header, body)
+	if errors.Is(err, gitprovider.ErrInvalidSignature) {
+		writeError(w, http.StatusUnauthorized, "invalid webhook signature")
+		return
+	}
+	if err != nil {
+		writeDomainError(w, err, "tenant not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"created": created})
+}
+
+// HandleSlackInteraction handles POST /api/v1/webhooks/slack/interactions
+// Slack signs the request instead of sending an API key, so the route is
+// mounted outside of auth (see MountSlackRoutes).
+func (h *Handlers) HandleSlackInteraction(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	err = h.Notifications.HandleSlackInteraction(r.Context(), r.Header, body)
+	if errors.Is(err, notifier.ErrInvalidSignature) {
+		writeError(w, http.StatusUnauthorized, "invalid slack signature")
+		return
+	}
+	if err != nil {
+		writeDomainError(w, err, "plan not found")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// ListPRCommands handles GET /api/v1/projects/{id}/pr-commands
+func (h *Handlers) ListPRCommands(w http.ResponseWriter, r *http.Request) {
+	cmds, err := h.ChatOps.List(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	if cmds == nil {
+		cmds = []chatops.CommandRun{}
+	}
+	writeJSON(w, http.StatusOK, cmds)
+}
+
+// --- Polling Endpoints ---
+
+// ListPollCursors handles GET /api/v1/projects/{id}/poll
+func (h *Handlers) ListPollCursors(w http.ResponseWriter, r *http.Request) {
+	cursors, err := h.Sync.ListPollCursors(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "project not found")
+		return
+	}
+	if cursors == nil {
+		cursors = []poll.Cursor{}
+	}
+	writeJSON(w, http.StatusOK, cursors)
+}
+
+// PollProject handles POST /api/v1/projects/{id}/poll
+func (h *Handlers) PollProject(w http.ResponseWriter, r *http.Request) {
+	res, err := h.Sync.Poll(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeSyncError(w, err, "project not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// --- Review Endpoints ---
+
+// RecordRunReview handles POST /api/v1/runs/{id}/review
+func (h *Handlers) RecordRunReview(w http.ResponseWriter, r *http.Request) {
+	var rv review.Review
+	if err := json.NewDecoder(r.Body).Decode(&rv); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := rv.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stored, err := h.Reviews.Record(r.Context(), chi.URLParam(r, "id"), &rv)
+	if err != nil {
+		writeDomainError(w, err, "run not found")
+		return
+	}
+	writeJSON(w, http.StatusCreated, stored)
+}
+
+// GetRunReview handles GET /api/v1/runs/{id}/review
+func (h *Handlers) GetRunReview(w http.ResponseWriter, r *http.Request) {
+	rv, err := h.Reviews.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "review not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, rv)
+}
+
+// ExportReview handles GET /api/v1/reviews/{id}/export?format=sarif
+// A review is identified by the ID of its run. The export includes the
+// linter findings of the run.
+func (h *Handlers) ExportReview(w http.ResponseWriter, r *http.Request) {
+	if f := r.URL.Query().Get("format"); f != "" && f != "sarif" {
+		writeError(w, http.StatusBadRequest, "unsupported export format (supported: sarif)")
+		return
+	}
+	id := chi.URLParam(r, "id")
+	log, err := h.Reviews.ExportSARIF(r.Context(), id)
+	if err != nil {
+		writeDomainError(w, err, "review not found")
+		return
+	}
+	w.Header().Set("Content-Type", sarif.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="review-`+id+`.sarif"`)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(log); err != nil {
+		slog.Error("failed to write SARIF response", "error", err)
+	}
+}
+
+// PublishRunReview handles POST /api/v1/runs/{id}/review/publish
+func (h *Handlers) PublishRunReview(w http.ResponseWriter, r *http.Request) {
+	var req review.PublishRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	res, err := h.Reviews.Publish(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrReviewPublishUnsupported):
+			writeError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrReviewPublish):
+			writeError(w, http.StatusBadGateway, err.Error())
+		case errors.Is(err, service.ErrSecretsUnavailable):
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+		default:
+			writeDomainError(w, err, "review not found")
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// GetRunTests handles GET /api/v1/runs/{id}/tests
+func (h *Handlers) GetRunTests(w http.ResponseWriter, r *http.Request) {
+	rep, err := h.Tests.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "test report not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
+
+// RunTests handles POST /api/v1/runs/{id}/tests
+func (h *Handlers) RunTests(w http.ResponseWriter, r *http.Request) {
+	plan, err := h.Tests.Run(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNoTestCommand):
+			writeError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrTestsInProgress):
+			writeError(w, http.StatusConflict, err.Error())
+		default:
+			writeDomainError(w, err, "run not found")
+		}
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "requested", "command": plan.Command})
+}
+
+// GetRunLint handles GET /api/v1/runs/{id}/lint
+func (h *Handlers) GetRunLint(w http.ResponseWriter, r *http.Request) {
+	rep, err := h.Lint.Get(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		writeDomainError(w, err, "lint report not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, rep)
+}
+
+// RunLint handles POST /api/v1/runs/{id}/lint
+func (h *Handlers) RunLint(w http.ResponseWriter, r *http.Request) {
+	plan, err := h.Lint.Run(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNoLinters):
+			writeError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrLintInProgress):
+			writeError(w, http.StatusConflict, err.Error())
+		default:
+			writeDomainError(w, err, "run not found")
+		}
+		return
+	}
+	tools := make([]string, 0, len(plan.Linters))
+	for _, l := range plan.Linters {
+		tools = append(tools, l.Tool)
+	}
+	writeJSON(w, http.StatusAccepted, map[string]any{"status": "requested", "linters": tools})
+}
+
+// --- Dead-Letter Queue Endpoints ---
+
+const (
+	defaultDeadLetterLimit = 100
+	maxDeadLetterLimit     = 1000
+)
+
+// ListDeadLetters handles GET /api/v1/queue/dlq?subject=&limit=
+func (h *Handlers) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	limit := defaultDeadLetterLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxDeadLetterLimit {
+			writeError(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		limit = n
+	}
+	letters, err := h.DeadLetters.ListDeadLetters(r.Context(), r.URL.Query().Get("subject"), limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if letters == nil {
+		letters = []messagequeue.DeadLetter{}
+	}
+	writeJSON(w, http.StatusOK, letters)
+}
+
+// ReplayDeadLetter handles POST /api/v1/queue/dlq/{seq}/replay
+func (h *Handlers) ReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	seq, err := strconv.ParseUint(chi.URLParam(r, "seq"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid sequence number")
+		return
+	}
+	if err := h.DeadLetters.ReplayDeadLetter(r.Context(), seq); err != nil {
+		writeDomainError(w, err, "dead letter not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteDeadLetter handles DELETE /api/v1/queue/dlq/{seq}
+func (h *Handlers) DeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	seq, err := strconv.ParseUint(chi.URLParam(r, "seq"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid sequence number")
+		return
+	}
+	if err := h.DeadLetters.DeleteDeadLetter(r.Context(), seq); err != nil {
+		writeDomainError(w, err, "dead letter not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// IdempotencyStats handles GET /api/v1/idempotency
+func (h *Handlers) IdempotencyStats(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.Idempotency.Stats())
+}
+
+// WorkerDedupeStats handles GET /api/v1/queue/dedupe
+func (h *Handlers) WorkerDedupeStats(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.WorkerDedupe.Stats())
+}
+
+// WebSocketStats handles GET /api/v1/ws/stats
+func (h *Handlers) WebSocketStats(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.Hub.Stats())
+}
+
+// --- Helpers ---
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// quotaErrorResponse reports which tenant quota denied a request.
+type quotaErrorResponse struct {
+	Error string             `json:"error"`
+	Quota *tenant.QuotaError `json:"quota"`
 }
 
 func writeJSON(w http.ResponseWriter, status int, data any) {
@@ -807,8 +4390,17 @@ func writeError(w http.ResponseWriter, status int, message string) {
 	writeJSON(w, status, errorResponse{Error: message})
 }
 
+// writeDomainError maps domain errors to HTTP statuses. A quota that frees
+// up by itself yields 429, any other exceeded quota 403.
 func writeDomainError(w http.ResponseWriter, err error, fallbackMsg string) {
+	var quotaErr *tenant.QuotaError
 	switch {
+	case errors.As(err, &quotaErr):
+		status := http.StatusForbidden
+		if quotaErr.Retryable() {
+			status = http.StatusTooManyRequests
+		}
+		writeJSON(w, status, quotaErrorResponse{Error: quotaErr.Error(), Quota: quotaErr})
 	case errors.Is(err, domain.ErrNotFound):
 		writeError(w, http.StatusNotFound, fallbackMsg)
 	case errors.Is(err, domain.ErrConflict):
@@ -4,9 +4,12 @@ package http
 import (
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
 	"github.com/Strob0t/CodeForge/internal/logger"
+	"github.com/Strob0t/CodeForge/internal/port/database"
 )
 
 // CORS returns middleware that sets CORS headers for development.
@@ -15,7 +18,7 @@ func CORS(allowedOrigin string) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+litellm.CacheBypassHeader)
 
 			if r.Method == http.MethodOptions {
 				w.WriteHeader(http.StatusNoContent)
@@ -27,6 +30,26 @@ func CORS(allowedOrigin string) func(http.Handler) http.Handler {
 	}
 }
 
+// CacheBypass returns middleware that disables the LLM response cache for
+// requests carrying the "X-CodeForge-Cache: bypass" header.
+func CacheBypass(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(r.Header.Get(litellm.CacheBypassHeader), litellm.CacheBypassValue) {
+			r = r.WithContext(litellm.WithCacheBypass(r.Context()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// StaleReads returns middleware that lets the list and cost queries of a
+// request be served by read replicas. It is mounted on dashboard endpoints
+// whose callers tolerate data a few seconds old.
+func StaleReads(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(database.WithStaleReads(r.Context())))
+	})
+}
+
 // Logger returns middleware that logs HTTP requests using slog.
 func Logger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -54,3 +77,9 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.status = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush server-sent events.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -6,7 +6,15 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
-// MountRoutes registers all API routes on the given chi router.
+// MountSlackRoutes registers the interactivity URL of the Slack app that
+// interactive Slack notification rules post with. Slack signs its requests
+// instead of sending an API key, so it is mounted outside of auth.
+func MountSlackRoutes(r chi.Router, h *Handlers) {
+	r.Post("/api/v1/webhooks/slack/interactions", h.HandleSlackInteraction)
+}
+
+// MountRoutes registers all API routes on the given chi router. List and
+// cost endpoints read from replicas when configured (StaleReads).
 func MountRoutes(r chi.Router, h *Handlers) {
 	r.Route("/api/v1", func(r chi.Router) {
 		// Version
@@ -15,12 +23,23 @@ func MountRoutes(r chi.Router, h *Handlers) {
 			_, _ = w.Write([]byte(`{"version":"0.1.0"}`))
 		})
 
+		// GraphQL read API (optional)
+		if h.GraphQL != nil {
+			r.With(StaleReads).Post("/graphql", h.GraphQL.ServeHTTP)
+			r.Get("/graphql/schema", h.GraphQL.ServeSchema)
+		}
+
 		// Projects
-		r.Get("/projects", h.ListProjects)
+		r.With(StaleReads).Get("/projects", h.ListProjects)
 		r.Post("/projects", h.CreateProject)
 		r.Get("/projects/{id}", h.GetProject)
 		r.Delete("/projects/{id}", h.DeleteProject)
 
+		// Project templates (scaffolding plus default agents, modes and pipelines)
+		r.Get("/templates", h.ListTemplates)
+		r.Get("/templates/{name}", h.GetTemplate)
+		r.Post("/projects/from-template/{name}", h.CreateProjectFromTemplate)
+
 		// Git operations (nested under projects)
 		r.Post("/projects/{id}/clone", h.CloneProject)
 		r.Get("/projects/{id}/git/status", h.ProjectGitStatus)
@@ -28,46 +47,291 @@ func MountRoutes(r chi.Router, h *Handlers) {
 		r.Get("/projects/{id}/git/branches", h.ListProjectBranches)
 		r.Post("/projects/{id}/git/checkout", h.CheckoutBranch)
 
+		// Sub-projects (monorepo packages)
+		r.Get("/projects/{id}/sub-projects", h.ListSubProjects)
+		r.Post("/projects/{id}/sub-projects", h.CreateSubProject)
+		r.Get("/sub-projects/{id}", h.GetSubProject)
+		r.Delete("/sub-projects/{id}", h.DeleteSubProject)
+
+		// Code graph
+		r.Post("/projects/{id}/graph/impact", h.GraphImpact)
+		r.Get("/projects/{id}/graph/repo-map", h.GetRepoMap)
+		r.Get("/projects/{id}/graph/repo-map/status", h.GetRepoMapStatus)
+		r.Get("/projects/{id}/graph", h.GetGraph)
+		r.Post("/projects/{id}/graph/build", h.BuildGraph)
+		r.Post("/projects/{id}/graph/update", h.UpdateGraph)
+		r.Get("/projects/{id}/graph/neighbors", h.GetGraphNeighbors)
+		r.Get("/projects/{id}/graph/path", h.GetGraphPath)
+
+		// Language servers
+		r.Post("/projects/{id}/lsp/definition", h.LSPDefinition)
+		r.Post("/projects/{id}/lsp/references", h.LSPReferences)
+		r.Post("/projects/{id}/lsp/hover", h.LSPHover)
+		r.Get("/projects/{id}/lsp/symbols", h.LSPSymbols)
+		r.Post("/projects/{id}/lsp/rename", h.LSPRename)
+		r.Post("/projects/{id}/lsp/code-actions", h.LSPCodeActions)
+		r.Post("/projects/{id}/lsp/code-actions/apply", h.LSPApplyCodeAction)
+		r.Post("/projects/{id}/lsp/format", h.LSPFormat)
+
+		// Project conventions
+		r.Get("/projects/{id}/conventions", h.GetConventions)
+		r.Post("/projects/{id}/conventions", h.ExtractConventions)
+
+		// Feature flags in effect for a project
+		r.Get("/projects/{id}/feature-flags", h.GetProjectFeatureFlags)
+
+		// Retrieval index (embedded workspace chunks)
+		r.Post("/projects/{id}/retrieval/index", h.IndexProject)
+		r.Get("/projects/{id}/retrieval/index", h.GetRetrievalIndex)
+		r.Post("/projects/{id}/retrieval/search", h.SearchRetrieval)
+		r.Get("/projects/{id}/retrieval/golden-queries", h.ListGoldenQueries)
+		r.Post("/projects/{id}/retrieval/golden-queries", h.CreateGoldenQuery)
+		r.Delete("/retrieval/golden-queries/{id}", h.DeleteGoldenQuery)
+		r.With(StaleReads).Get("/projects/{id}/retrieval/evals", h.ListRetrievalEvals)
+		r.Post("/projects/{id}/retrieval/evals", h.EvaluateRetrieval)
+		r.Post("/projects/{id}/index/migrate", h.MigrateIndex)
+		r.Get("/projects/{id}/index/migrate", h.GetIndexMigration)
+
+		// Conversations (chat with rolling summaries)
+		r.With(StaleReads).Get("/projects/{id}/conversations", h.ListConversations)
+		r.Post("/projects/{id}/conversations", h.CreateConversation)
+		r.Get("/conversations/{id}", h.GetConversation)
+		r.Get("/conversations/{id}/messages", h.ListConversationMessages)
+		r.Post("/conversations/{id}/messages", h.SendConversationMessage)
+		r.Post("/conversations/{id}/messages/stream", h.StreamConversationMessage)
+
+		// Project memories (recalled into context packs and conversations)
+		r.Get("/projects/{id}/memories", h.ListMemories)
+		r.Post("/projects/{id}/memories", h.CreateMemory)
+		r.Post("/projects/{id}/memories/recall", h.RecallMemories)
+		r.Delete("/memories/{id}", h.DeleteMemory)
+
+		// Experience pool (outcomes of past plans, searched when planning)
+		r.Get("/projects/{id}/experiences", h.ListExperiences)
+		r.Post("/experiences/{id}/rate", h.RateExperience)
+
+		// Skills and signed skill bundles
+		r.Get("/projects/{id}/skills", h.ListSkills)
+		r.Post("/projects/{id}/skills", h.CreateSkill)
+		r.Post("/projects/{id}/skills/import", h.ImportSkill)
+		r.Post("/projects/{id}/skills/install", h.InstallSkill)
+		r.Get("/skills/registry", h.ListSkillRegistry)
+		r.Get("/skills/{id}", h.GetSkill)
+		r.Delete("/skills/{id}", h.DeleteSkill)
+		r.Get("/skills/{id}/export", h.ExportSkill)
+
+		// Cost summaries (run spend per project, plan step, tool and model)
+		r.With(StaleReads).Get("/costs", h.ListCosts)
+		r.With(StaleReads).Get("/projects/{id}/costs", h.GetProjectCosts)
+		r.With(StaleReads).Get("/projects/{id}/costs/by-tool", h.GetProjectToolCosts)
+		r.With(StaleReads).Get("/costs/daily", h.ListDailyCosts)
+		r.With(StaleReads).Get("/projects/{id}/costs/daily", h.GetProjectDailyCosts)
+		r.With(StaleReads).Get("/plans/{id}/costs/by-step", h.GetPlanStepCosts)
+
+		// Microagents (knowledge injected into runs and conversations on triggers)
+		r.Get("/projects/{id}/microagents", h.ListMicroagents)
+		r.Post("/projects/{id}/microagents", h.CreateMicroagent)
+		r.Get("/microagents/{id}", h.GetMicroagent)
+		r.Put("/microagents/{id}", h.UpdateMicroagent)
+		r.Delete("/microagents/{id}", h.DeleteMicroagent)
+
+		// Run presets (named run options referenced by StartRun)
+		r.Get("/projects/{id}/presets", h.ListRunPresets)
+		r.Post("/projects/{id}/presets", h.CreateRunPreset)
+		r.Get("/projects/{id}/presets/{name}", h.GetRunPreset)
+		r.Put("/projects/{id}/presets/{name}", h.UpdateRunPreset)
+		r.Delete("/projects/{id}/presets/{name}", h.DeleteRunPreset)
+
+		// Notification routing rules, quiet hours and digest time
+		r.Get("/notifiers", h.ListNotifiers)
+		r.Get("/projects/{id}/notification-rules", h.ListNotificationRules)
+		r.Post("/projects/{id}/notification-rules", h.CreateNotificationRule)
+		r.Get("/notification-rules/{id}", h.GetNotificationRule)
+		r.Put("/notification-rules/{id}", h.UpdateNotificationRule)
+		r.Delete("/notification-rules/{id}", h.DeleteNotificationRule)
+		r.Get("/projects/{id}/notification-settings", h.GetNotificationSettings)
+		r.Put("/projects/{id}/notification-settings", h.SetNotificationSettings)
+
+		// External MCP servers (Streamable HTTP, optional OAuth2)
+		r.Get("/mcp-servers", h.ListMCPServers)
+		r.Post("/mcp-servers", h.CreateMCPServer)
+		r.Get("/mcp-servers/{id}", h.GetMCPServer)
+		r.Put("/mcp-servers/{id}", h.UpdateMCPServer)
+		r.Delete("/mcp-servers/{id}", h.DeleteMCPServer)
+		r.Get("/mcp-servers/{id}/tools", h.ListMCPServerTools)
+		r.Post("/mcp-servers/{id}/tools/call", h.CallMCPServerTool)
+
+		// Knowledge bases of external documents, searched with the retrieval index
+		r.Get("/knowledge-bases", h.ListKnowledgeBases)
+		r.Post("/knowledge-bases", h.CreateKnowledgeBase)
+		r.Get("/knowledge-bases/{id}", h.GetKnowledgeBase)
+		r.Put("/knowledge-bases/{id}", h.UpdateKnowledgeBase)
+		r.Delete("/knowledge-bases/{id}", h.DeleteKnowledgeBase)
+		r.Post("/knowledge-bases/{id}/refresh", h.RefreshKnowledgeBase)
+
+		// Retrieval scopes (projects and knowledge bases searched together)
+		r.Get("/scopes", h.ListScopes)
+		r.Post("/scopes", h.CreateScope)
+		r.Get("/scopes/{id}", h.GetScope)
+		r.Put("/scopes/{id}", h.UpdateScope)
+		r.Delete("/scopes/{id}", h.DeleteScope)
+		r.Post("/scopes/{id}/search", h.SearchScope)
+
 		// Agents (nested under projects)
 		r.Post("/projects/{id}/agents", h.CreateAgent)
-		r.Get("/projects/{id}/agents", h.ListAgents)
+		r.With(StaleReads).Get("/projects/{id}/agents", h.ListAgents)
 
 		// Agents (direct access)
 		r.Get("/agents/{id}", h.GetAgent)
 		r.Delete("/agents/{id}", h.DeleteAgent)
 		r.Post("/agents/{id}/dispatch", h.DispatchTask)
 		r.Post("/agents/{id}/stop", h.StopAgentTask)
+		r.Put("/agents/{id}/sub-project", h.SetAgentSubProject)
 
 		// Tasks (nested under projects)
 		r.Post("/projects/{id}/tasks", h.CreateTask)
-		r.Get("/projects/{id}/tasks", h.ListTasks)
+		r.With(StaleReads).Get("/projects/{id}/tasks", h.ListTasks)
 
 		// Tasks (direct access)
 		r.Get("/tasks/{id}", h.GetTask)
 		r.Get("/tasks/{id}/events", h.ListTaskEvents)
-		r.Get("/tasks/{id}/runs", h.ListTaskRuns)
+		r.With(StaleReads).Get("/tasks/{id}/runs", h.ListTaskRuns)
+		r.Get("/tasks/{id}/attachments", h.ListTaskAttachments)
 		r.Get("/tasks/{id}/context", h.GetContextPack)
 		r.Post("/tasks/{id}/context", h.BuildContextPack)
+		r.Post("/tasks/{id}/context/preview", h.PreviewContextPack)
+		r.Get("/tasks/{id}/context/curation", h.GetContextCuration)
+		r.Put("/tasks/{id}/context/curation", h.SetContextCuration)
+		r.Delete("/tasks/{id}/context/curation", h.DeleteContextCuration)
 
 		// Runs
 		r.Post("/runs", h.StartRun)
+		r.Get("/runs/compare", h.CompareRuns)
+		r.Get("/runs/active", h.ListActiveRuns)
 		r.Get("/runs/{id}", h.GetRun)
 		r.Post("/runs/{id}/cancel", h.CancelRun)
+		r.Get("/runs/{id}/artifacts", h.ListRunArtifacts)
+		r.Get("/runs/{id}/trajectory", h.ExportTrajectory)
+		r.Get("/runs/{id}/timeline", h.GetRunTimeline)
+		r.Get("/runs/{id}/citations", h.GetRunCitations)
+		r.Get("/runs/{id}/delivery", h.GetRunDelivery)
+		r.Get("/runs/{id}/ci", h.GetRunCI)
+		r.Post("/runs/{id}/snapshots", h.CreateSnapshot)
+		r.Get("/runs/{id}/snapshots", h.ListRunSnapshots)
+		r.Post("/runs/{id}/restore", h.RestoreSnapshot)
+		r.Post("/runs/{id}/review", h.RecordRunReview)
+		r.Get("/runs/{id}/review", h.GetRunReview)
+		r.Post("/runs/{id}/review/publish", h.PublishRunReview)
+		r.Get("/reviews/{id}/export", h.ExportReview)
+		r.Get("/runs/{id}/tests", h.GetRunTests)
+		r.Post("/runs/{id}/tests", h.RunTests)
+		r.Get("/runs/{id}/lint", h.GetRunLint)
+		r.Post("/runs/{id}/lint", h.RunLint)
+
+		// Run artifacts (direct access)
+		r.Get("/artifacts/{id}/content", h.GetArtifactContent)
+
+		// Attachments (files for tasks and conversation messages; content via /artifacts/{id}/content)
+		r.Post("/projects/{id}/attachments", h.UploadAttachment)
+
+		// Workspace files (the project's, or a run's with ?run_id=)
+		r.Get("/projects/{id}/files", h.ListWorkspaceFiles)
+		r.Get("/projects/{id}/files/*", h.ReadWorkspaceFile)
+		r.Put("/projects/{id}/files/*", h.WriteWorkspaceFile)
+		r.Patch("/projects/{id}/files/*", h.PatchWorkspaceFile)
+
+		// Research runs (nested under projects)
+		r.Post("/projects/{id}/research", h.StartResearch)
+
+		// Workspace snapshots (nested under projects)
+		r.Get("/projects/{id}/snapshots", h.ListProjectSnapshots)
+
+		// Replay sessions (step through a run's events and fork from a checkpoint)
+		r.Post("/replay-sessions", h.CreateReplaySession)
+		r.Get("/replay-sessions/{id}", h.GetReplaySession)
+		r.Post("/replay-sessions/{id}/step", h.StepReplaySession)
+		r.Get("/replay-sessions/{id}/state", h.GetReplayState)
+		r.Get("/replay-sessions/{id}/files/*", h.GetReplayFile)
+		r.Post("/replay-sessions/{id}/fork", h.ForkReplaySession)
+		r.Delete("/replay-sessions/{id}", h.DeleteReplaySession)
+
+		// VCS accounts (git host token health)
+		r.Get("/vcs-accounts", h.ListVCSAccounts)
+		r.Get("/projects/{id}/vcs-account", h.GetProjectVCSAccount)
+		r.Post("/projects/{id}/vcs-account/test", h.TestProjectVCSAccount)
+
+		// Secrets (nested under projects)
+		r.Get("/projects/{id}/secrets", h.ListProjectSecrets)
+		r.Post("/projects/{id}/secrets", h.CreateProjectSecret)
+
+		// Secrets (tenant-wide and direct access)
+		r.Get("/secrets", h.ListSecrets)
+		r.Post("/secrets", h.CreateSecret)
+		r.Put("/secrets/{id}", h.UpdateSecret)
+		r.Delete("/secrets/{id}", h.DeleteSecret)
+
+		// Tenants and quotas
+		r.Get("/tenants", h.ListTenants)
+		r.Post("/tenants", h.CreateTenant)
+		r.Get("/tenants/{id}", h.GetTenant)
+		r.Put("/tenants/{id}/quota", h.UpdateTenantQuota)
+		r.Get("/tenants/{id}/usage", h.TenantUsage)
+		r.Put("/tenants/{id}/github-app", h.SetTenantGitHubApp)
+		r.Delete("/tenants/{id}/github-app", h.DeleteTenantGitHubApp)
+
+		// Tenant isolation
+		r.Get("/tenancy/isolation", h.VerifyTenantIsolation)
+		r.Post("/tenancy/rekey", h.RekeySecrets)
+
+		// Managed API keys
+		r.Get("/auth/api-keys", h.ListAPIKeys)
+		r.Post("/auth/api-keys", h.CreateAPIKey)
+		r.Get("/auth/api-keys/{id}", h.GetAPIKey)
+		r.Put("/auth/api-keys/{id}", h.UpdateAPIKey)
+		r.Delete("/auth/api-keys/{id}", h.DeleteAPIKey)
+
+		// Audit log and its export sinks
+		r.Get("/audit/entries", h.ListAuditEntries)
+		r.Get("/audit/sinks", h.ListAuditSinks)
+		r.Post("/audit/sinks", h.CreateAuditSink)
+		r.Get("/audit/sinks/{id}", h.GetAuditSink)
+		r.Put("/audit/sinks/{id}", h.UpdateAuditSink)
+		r.Delete("/audit/sinks/{id}", h.DeleteAuditSink)
+
+		// Effective configuration with the origin of each value
+		r.Get("/admin/config", h.GetConfig)
+
+		// Feature flags of experimental subsystems
+		r.Get("/admin/feature-flags", h.ListFeatureFlags)
+		r.Put("/admin/feature-flags/{key}", h.SetFeatureFlag)
+		r.Delete("/admin/feature-flags/{key}", h.DeleteFeatureFlag)
 
 		// LLM management (proxied to LiteLLM)
 		r.Get("/llm/models", h.ListLLMModels)
 		r.Post("/llm/models", h.AddLLMModel)
 		r.Post("/llm/models/delete", h.DeleteLLMModel)
 		r.Get("/llm/health", h.LLMHealth)
+		r.Get("/llm/cache", h.LLMCacheStats)
 
 		// Provider registries
 		r.Get("/providers/git", h.ListGitProviders)
 		r.Get("/providers/agent", h.ListAgentBackends)
+		r.Get("/providers/agent/capabilities", h.ListAgentCapabilities)
+		r.Get("/providers/pm", h.ListPMProviders)
 
 		// Policy profiles
 		r.Get("/policies", h.ListPolicyProfiles)
+		r.Post("/policies/resolve", h.ResolvePolicy)
 		r.Get("/policies/{name}", h.GetPolicyProfile)
 		r.Post("/policies/{name}/evaluate", h.EvaluatePolicy)
+		r.Post("/policies/{name}/simulate", h.SimulatePolicy)
+
+		// Model routing
+		r.Post("/routing/explain", h.ExplainRouting)
+
+		// Tokenizer accuracy
+		r.Get("/tokenizers/diagnostics", h.TokenizerDiagnostics)
+		r.Post("/tokenizers/probe", h.ProbeTokenizer)
 
 		// Feature Decomposition (Meta-Agent)
 		r.Post("/projects/{id}/decompose", h.DecomposeFeature)
@@ -77,12 +341,19 @@ func MountRoutes(r chi.Router, h *Handlers) {
 
 		// Execution Plans (nested under projects)
 		r.Post("/projects/{id}/plans", h.CreatePlan)
-		r.Get("/projects/{id}/plans", h.ListPlans)
+		r.With(StaleReads).Get("/projects/{id}/plans", h.ListPlans)
 
 		// Execution Plans (direct access)
 		r.Get("/plans/{id}", h.GetPlan)
+		r.Get("/plans/{id}/graph", h.GetPlanGraph)
+		r.Post("/plans/{id}/validate", h.ValidatePlan)
 		r.Post("/plans/{id}/start", h.StartPlan)
 		r.Post("/plans/{id}/cancel", h.CancelPlan)
+		r.Post("/plans/{id}/rerun", h.RerunPlan)
+		r.Post("/plans/{id}/steps/{stepId}/approve", h.ApprovePlanStep)
+		r.Post("/plans/{id}/steps/{stepId}/reject", h.RejectPlanStep)
+		r.Get("/plans/{id}/steps/{stepId}/debate", h.GetPlanDebate)
+		r.Get("/approvals", h.ListPendingApprovals)
 
 		// Agent Teams (nested under projects)
 		r.Post("/projects/{id}/teams", h.CreateTeam)
@@ -100,5 +371,52 @@ func MountRoutes(r chi.Router, h *Handlers) {
 		r.Get("/modes", h.ListModes)
 		r.Get("/modes/{id}", h.GetMode)
 		r.Post("/modes", h.CreateMode)
+
+		// Roadmap (synced with the project's PM platform)
+		r.With(StaleReads).Get("/projects/{id}/roadmap/features", h.ListRoadmapFeatures)
+		r.Post("/projects/{id}/roadmap/import", h.ImportRoadmap)
+		r.Put("/roadmap/features/{id}/status", h.UpdateRoadmapFeatureStatus)
+
+		// Issue automation (runs for issues labeled or assigned on the git host)
+		r.Get("/projects/{id}/automation", h.GetIssueAutomation)
+		r.Put("/projects/{id}/automation", h.UpdateIssueAutomation)
+		r.With(StaleReads).Get("/projects/{id}/issue-runs", h.ListIssueRuns)
+
+		// Pull request commands ("/codeforge fix" comments)
+		r.With(StaleReads).Get("/projects/{id}/pr-commands", h.ListPRCommands)
+
+		// Polling (for projects that cannot receive webhooks)
+		r.Get("/projects/{id}/poll", h.ListPollCursors)
+		r.Post("/projects/{id}/poll", h.PollProject)
+
+		// Webhooks
+		r.Post("/webhooks/pm/{provider}", h.HandlePMWebhook)
+		r.Post("/webhooks/git/{provider}", h.HandleGitWebhook)
+		r.Post("/webhooks/github-app/{tenant}", h.HandleGitHubAppWebhook)
+
+		// Benchmarks
+		r.Get("/benchmarks/suites", h.ListBenchmarkSuites)
+		r.Get("/benchmarks/suites/{name}/leaderboard", h.GetBenchmarkLeaderboard)
+		r.Post("/benchmarks/runs", h.StartBenchmark)
+		r.With(StaleReads).Get("/benchmarks/runs", h.ListBenchmarkRuns)
+		r.Get("/benchmarks/runs/{id}", h.GetBenchmarkRun)
+
+		// Dead-letter queue
+		if h.DeadLetters != nil {
+			r.Get("/queue/dlq", h.ListDeadLetters)
+			r.Post("/queue/dlq/{seq}/replay", h.ReplayDeadLetter)
+			r.Delete("/queue/dlq/{seq}", h.DeleteDeadLetter)
+		}
+
+		// Idempotency-Key replay counters
+		r.Get("/idempotency", h.IdempotencyStats)
+
+		// Replayed worker results dropped and fail-open applies
+		r.Get("/queue/dedupe", h.WorkerDedupeStats)
+
+		// WebSocket connections and fan-out counters of this server
+		if h.Hub != nil {
+			r.Get("/ws/stats", h.WebSocketStats)
+		}
 	})
 }
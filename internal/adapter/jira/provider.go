@@ -0,0 +1,325 @@
+// Package jira implements the pmprovider.Provider interface against the
+// Jira Cloud REST API v3.
+package jira
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/roadmap"
+	"github.com/Strob0t/CodeForge/internal/port/pmprovider"
+)
+
+const (
+	providerName = "jira"
+	pageSize     = 100
+
+	// signatureHeader carries "sha256=<hex HMAC of the body>" on webhook
+	// deliveries of webhooks registered with a secret.
+	signatureHeader = "X-Hub-Signature"
+)
+
+// issueFields are the fields requested for every issue.
+var issueFields = []string{"summary", "description", "status", "labels", "updated", "project"}
+
+// Provider syncs the issues of one Jira project.
+type Provider struct {
+	baseURL       string
+	project       string
+	email         string
+	token         string
+	webhookSecret string
+	httpClient    *http.Client
+}
+
+// NewProvider creates a Provider for the Jira site at baseURL, authenticating
+// with an Atlassian account email and API token.
+func NewProvider(baseURL, project, email, token, webhookSecret string) *Provider {
+	return &Provider{
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		project:       project,
+		email:         email,
+		token:         token,
+		webhookSecret: webhookSecret,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Name returns "jira".
+func (p *Provider) Name() string { return providerName }
+
+// Capabilities returns what the Jira provider supports. Webhooks are only
+// accepted when a webhook secret is configured to verify them with.
+func (p *Provider) Capabilities() pmprovider.Capabilities {
+	return pmprovider.Capabilities{
+		Import:     true,
+		StatusSync: true,
+		Webhook:    p.webhookSecret != "",
+	}
+}
+
+// ListItems returns all issues of the configured project.
+func (p *Provider) ListItems(ctx context.Context) ([]roadmap.Item, error) {
+	jql := fmt.Sprintf("project = %q ORDER BY key ASC", p.project)
+	var items []roadmap.Item
+	next := ""
+	for {
+		body := map[string]any{"jql": jql, "fields": issueFields, "maxResults": pageSize}
+		if next != "" {
+			body["nextPageToken"] = next
+		}
+		var page struct {
+			Issues        []issue `json:"issues"`
+			NextPageToken string  `json:"nextPageToken"`
+			IsLast        bool    `json:"isLast"`
+		}
+		if err := p.do(ctx, http.MethodPost, "/rest/api/3/search/jql", body, &page); err != nil {
+			return nil, err
+		}
+		for i := range page.Issues {
+			items = append(items, p.item(&page.Issues[i]))
+		}
+		if page.IsLast || page.NextPageToken == "" {
+			return items, nil
+		}
+		next = page.NextPageToken
+	}
+}
+
+// UpdateStatus transitions an issue into the status category matching
+// status. Cancelled features prefer a done transition named like
+// "Cancel" or "Won't Do" over a plain one.
+func (p *Provider) UpdateStatus(ctx context.Context, itemID string, status roadmap.FeatureStatus) error {
+	path := "/rest/api/3/issue/" + url.PathEscape(itemID) + "/transitions"
+	var resp struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+			To   struct {
+				StatusCategory statusCategory `json:"statusCategory"`
+			} `json:"to"`
+		} `json:"transitions"`
+	}
+	if err := p.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return err
+	}
+
+	category := categoryFor(status)
+	id := ""
+	for _, t := range resp.Transitions {
+		if t.To.StatusCategory.Key != category {
+			continue
+		}
+		if id == "" {
+			id = t.ID
+		}
+		name := strings.ToLower(t.Name)
+		if status == roadmap.FeatureStatusCancelled && (strings.Contains(name, "cancel") || strings.Contains(name, "won't")) {
+			id = t.ID
+			break
+		}
+	}
+	if id == "" {
+		return fmt.Errorf("jira: issue %s has no transition to %s", itemID, status)
+	}
+	return p.do(ctx, http.MethodPost, path, map[string]any{"transition": map[string]string{"id": id}}, nil)
+}
+
+// ParseWebhook verifies the signature of a Jira webhook delivery and
+// decodes issue events of the configured project.
+func (p *Provider) ParseWebhook(header http.Header, body []byte) (*pmprovider.WebhookEvent, error) {
+	if p.webhookSecret == "" || !validSignature(p.webhookSecret, header.Get(signatureHeader), body) {
+		return nil, pmprovider.ErrInvalidSignature
+	}
+
+	var payload struct {
+		WebhookEvent string `json:"webhookEvent"`
+		Issue        *issue `json:"issue"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("jira: unmarshal webhook: %w", err)
+	}
+
+	var action pmprovider.Action
+	switch payload.WebhookEvent {
+	case "jira:issue_created":
+		action = pmprovider.ActionCreated
+	case "jira:issue_updated":
+		action = pmprovider.ActionUpdated
+	case "jira:issue_deleted":
+		action = pmprovider.ActionDeleted
+	default:
+		return nil, pmprovider.ErrIgnoredEvent
+	}
+	if payload.Issue == nil || !strings.EqualFold(payload.Issue.Fields.Project.Key, p.project) {
+		return nil, pmprovider.ErrIgnoredEvent
+	}
+	return &pmprovider.WebhookEvent{Action: action, Item: p.item(payload.Issue)}, nil
+}
+
+func validSignature(secret, header string, body []byte) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// --- API types and mapping ---
+
+type statusCategory struct {
+	Key string `json:"key"` // "new", "indeterminate" or "done"
+}
+
+type issue struct {
+	ID     string `json:"id"`
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string          `json:"summary"`
+		Description json.RawMessage `json:"description"`
+		Status      struct {
+			StatusCategory statusCategory `json:"statusCategory"`
+		} `json:"status"`
+		Labels  []string `json:"labels"`
+		Updated string   `json:"updated"`
+		Project struct {
+			Key string `json:"key"`
+		} `json:"project"`
+	} `json:"fields"`
+}
+
+func (p *Provider) item(is *issue) roadmap.Item {
+	updated, _ := time.Parse("2006-01-02T15:04:05.000-0700", is.Fields.Updated)
+	return roadmap.Item{
+		ID:          is.ID,
+		Key:         is.Key,
+		Title:       is.Fields.Summary,
+		Description: docText(is.Fields.Description),
+		Status:      statusFor(is.Fields.Status.StatusCategory.Key),
+		Labels:      is.Fields.Labels,
+		URL:         p.baseURL + "/browse/" + is.Key,
+		UpdatedAt:   updated,
+	}
+}
+
+func statusFor(category string) roadmap.FeatureStatus {
+	switch category {
+	case "indeterminate":
+		return roadmap.FeatureStatusInProgress
+	case "done":
+		return roadmap.FeatureStatusDone
+	default:
+		return roadmap.FeatureStatusPlanned
+	}
+}
+
+func categoryFor(status roadmap.FeatureStatus) string {
+	switch status {
+	case roadmap.FeatureStatusInProgress:
+		return "indeterminate"
+	case roadmap.FeatureStatusDone, roadmap.FeatureStatusCancelled:
+		return "done"
+	default:
+		return "new"
+	}
+}
+
+// docNode is a node of an Atlassian Document Format tree.
+type docNode struct {
+	Type    string    `json:"type"`
+	Text    string    `json:"text"`
+	Content []docNode `json:"content"`
+}
+
+// docText flattens an Atlassian Document Format description to plain text,
+// one line per block.
+func docText(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var doc docNode
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return ""
+	}
+	var b strings.Builder
+	var walk func(n *docNode)
+	walk = func(n *docNode) {
+		switch n.Type {
+		case "text":
+			b.WriteString(n.Text)
+		case "hardBreak":
+			b.WriteByte('\n')
+		}
+		for i := range n.Content {
+			walk(&n.Content[i])
+		}
+		switch n.Type {
+		case "paragraph", "heading", "codeBlock", "blockquote", "rule":
+			b.WriteByte('\n')
+		}
+	}
+	walk(&doc)
+	return strings.TrimSpace(b.String())
+}
+
+// --- HTTP ---
+
+func (p *Provider) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader = http.NoBody
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("jira: marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("jira: create request: %w", err)
+	}
+	req.SetBasicAuth(p.email, p.token)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("jira: http request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("jira: read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("jira: API error %d: %s", resp.StatusCode, string(data))
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("jira: unmarshal response: %w", err)
+	}
+	return nil
+}
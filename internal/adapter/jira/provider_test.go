@@ -0,0 +1,171 @@
+package jira_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/jira"
+	"github.com/Strob0t/CodeForge/internal/domain/roadmap"
+	"github.com/Strob0t/CodeForge/internal/port/pmprovider"
+)
+
+const issueJSON = `{
+	"id": "10042",
+	"key": "ENG-42",
+	"fields": {
+		"summary": "Single sign-on",
+		"description": {"type": "doc", "content": [
+			{"type": "paragraph", "content": [{"type": "text", "text": "Support SAML."}]},
+			{"type": "paragraph", "content": [{"type": "text", "text": "Okta first."}]}
+		]},
+		"status": {"name": "In Review", "statusCategory": {"key": "indeterminate"}},
+		"labels": ["auth"],
+		"updated": "2024-05-01T10:00:00.000+0000",
+		"project": {"key": "ENG"}
+	}
+}`
+
+func TestListItems_Paginates(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/3/search/jql" || r.Method != http.MethodPost {
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "bot@acme.com" || pass != "tok" {
+			t.Fatal("expected basic auth with email and token")
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["jql"] != `project = "ENG" ORDER BY key ASC` {
+			t.Fatalf("unexpected jql %v", body["jql"])
+		}
+		calls++
+		if body["nextPageToken"] == nil {
+			_, _ = w.Write([]byte(`{"issues": [` + issueJSON + `], "nextPageToken": "p2"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"issues": [{"id": "10043", "key": "ENG-43", "fields": {"summary": "Audit log", "status": {"statusCategory": {"key": "new"}}}}], "isLast": true}`))
+	}))
+	defer srv.Close()
+
+	p := jira.NewProvider(srv.URL, "ENG", "bot@acme.com", "tok", "")
+	items, err := p.ListItems(context.Background())
+	if err != nil {
+		t.Fatalf("ListItems failed: %v", err)
+	}
+	if calls != 2 || len(items) != 2 {
+		t.Fatalf("expected 2 items over 2 pages, got %d items in %d calls", len(items), calls)
+	}
+	it := items[0]
+	if it.ID != "10042" || it.Key != "ENG-42" || it.Status != roadmap.FeatureStatusInProgress {
+		t.Fatalf("unexpected item %+v", it)
+	}
+	if it.Description != "Support SAML.\nOkta first." {
+		t.Fatalf("unexpected description %q", it.Description)
+	}
+	if it.URL != srv.URL+"/browse/ENG-42" || it.UpdatedAt.IsZero() {
+		t.Fatalf("expected url and updated time, got %+v", it)
+	}
+	if items[1].Status != roadmap.FeatureStatusPlanned || items[1].Description != "" {
+		t.Fatalf("unexpected second item %+v", items[1])
+	}
+}
+
+func TestUpdateStatus_PicksTransitionByCategory(t *testing.T) {
+	var chosen string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/3/issue/10042/transitions" {
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		}
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`{"transitions": [
+				{"id": "11", "name": "Start", "to": {"statusCategory": {"key": "indeterminate"}}},
+				{"id": "31", "name": "Done", "to": {"statusCategory": {"key": "done"}}},
+				{"id": "41", "name": "Won't Do", "to": {"statusCategory": {"key": "done"}}}
+			]}`))
+			return
+		}
+		var body struct {
+			Transition struct {
+				ID string `json:"id"`
+			} `json:"transition"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		chosen = body.Transition.ID
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	p := jira.NewProvider(srv.URL, "ENG", "bot@acme.com", "tok", "")
+	ctx := context.Background()
+	for status, want := range map[roadmap.FeatureStatus]string{
+		roadmap.FeatureStatusInProgress: "11",
+		roadmap.FeatureStatusDone:       "31",
+		roadmap.FeatureStatusCancelled:  "41",
+	} {
+		if err := p.UpdateStatus(ctx, "10042", status); err != nil {
+			t.Fatalf("%s: %v", status, err)
+		}
+		if chosen != want {
+			t.Errorf("%s: expected transition %s, got %s", status, want, chosen)
+		}
+	}
+	if err := p.UpdateStatus(ctx, "10042", roadmap.FeatureStatusPlanned); err == nil {
+		t.Fatal("expected error when no transition leads to the status")
+	}
+}
+
+func sign(secret string, body []byte) http.Header {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	h := http.Header{}
+	h.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return h
+}
+
+func TestParseWebhook(t *testing.T) {
+	p := jira.NewProvider("https://acme.atlassian.net", "ENG", "bot@acme.com", "tok", "s3cret")
+	body := []byte(`{"webhookEvent": "jira:issue_updated", "issue": ` + issueJSON + `}`)
+
+	ev, err := p.ParseWebhook(sign("s3cret", body), body)
+	if err != nil {
+		t.Fatalf("ParseWebhook failed: %v", err)
+	}
+	if ev.Action != pmprovider.ActionUpdated || ev.Item.Key != "ENG-42" {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+
+	if _, err := p.ParseWebhook(sign("wrong", body), body); !errors.Is(err, pmprovider.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+	comment := []byte(`{"webhookEvent": "comment_created"}`)
+	if _, err := p.ParseWebhook(sign("s3cret", comment), comment); !errors.Is(err, pmprovider.ErrIgnoredEvent) {
+		t.Fatalf("expected ErrIgnoredEvent for comment, got %v", err)
+	}
+	other := jira.NewProvider("https://acme.atlassian.net", "OPS", "bot@acme.com", "tok", "s3cret")
+	if _, err := other.ParseWebhook(sign("s3cret", body), body); !errors.Is(err, pmprovider.ErrIgnoredEvent) {
+		t.Fatalf("expected ErrIgnoredEvent for another project, got %v", err)
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	if _, err := pmprovider.New("jira", map[string]string{"jira_url": "https://acme.atlassian.net"}); err == nil {
+		t.Fatal("expected error when project, email and token are missing")
+	}
+	p, err := pmprovider.New("jira", map[string]string{
+		"jira_url": "https://acme.atlassian.net", "jira_project": "ENG", "jira_email": "bot@acme.com", "token": "tok",
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if p.Name() != "jira" || p.Capabilities().Webhook {
+		t.Fatalf("expected jira without webhooks (no secret), got %s %+v", p.Name(), p.Capabilities())
+	}
+}
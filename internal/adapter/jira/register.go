@@ -0,0 +1,32 @@
+package jira
+
+import (
+	"fmt"
+
+	"github.com/Strob0t/CodeForge/internal/port/pmprovider"
+)
+
+// Project config keys read by the Jira provider, next to the token and
+// webhook secret resolved by the sync service.
+const (
+	configURL     = "jira_url"     // Site URL, e.g. https://acme.atlassian.net
+	configProject = "jira_project" // Project key, e.g. ENG
+	configEmail   = "jira_email"   // Atlassian account the API token belongs to
+)
+
+func init() {
+	pmprovider.Register(providerName, func(config map[string]string) (pmprovider.Provider, error) {
+		for _, key := range []string{configURL, configProject, configEmail, pmprovider.ConfigToken} {
+			if config[key] == "" {
+				return nil, fmt.Errorf("jira: %s is required", key)
+			}
+		}
+		return NewProvider(
+			config[configURL],
+			config[configProject],
+			config[configEmail],
+			config[pmprovider.ConfigToken],
+			config[pmprovider.ConfigWebhookKey],
+		), nil
+	})
+}
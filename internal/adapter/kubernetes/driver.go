@@ -0,0 +1,469 @@
+// Package kubernetes implements the sandbox.Driver interface against the
+// Kubernetes API. Each sandbox is a batch/v1 Job with a single pod whose
+// container runs with the configured resource limits.
+//
+// The driver talks to the API server over plain REST so that the core does
+// not depend on client-go. Kubernetes has no per-pod process limit; PID
+// limits are enforced by the kubelet's podPidsLimit and recorded on the pod
+// as an annotation.
+package kubernetes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/port/sandbox"
+)
+
+const (
+	driverName = "kubernetes"
+
+	serviceAccountDir   = "/var/run/secrets/kubernetes.io/serviceaccount"
+	containerName       = "run"
+	egressContainerName = "egress"
+	// finishedTTL lets Kubernetes garbage-collect jobs the core failed to
+	// remove, e.g. because it restarted while the run was active.
+	finishedTTL = 3600
+)
+
+// Config configures the Kubernetes driver.
+type Config struct {
+	APIServer      string       // API server URL
+	Namespace      string       // Namespace of run jobs
+	Token          string       // Bearer token
+	WorkspaceClaim string       // PVC with the project workspaces; empty clones the repository
+	WorkspaceRoot  string       // Path the workspace claim is mounted at in the core
+	CloneImage     string       // Image of the git clone init container
+	HTTPClient     *http.Client // Client with the API server's CA
+	PollInterval   time.Duration
+	// RuntimeClasses maps isolation levels stronger than a plain container
+	// to RuntimeClasses, e.g. gVisor or Kata with Firecracker.
+	RuntimeClasses map[policy.Isolation]string
+}
+
+// DefaultRuntimeClasses are the RuntimeClasses used for isolation levels
+// without a configured class.
+var DefaultRuntimeClasses = map[policy.Isolation]string{
+	policy.IsolationGVisor:  "gvisor",
+	policy.IsolationMicroVM: "kata-fc",
+}
+
+// Driver launches sandboxes as Kubernetes jobs.
+type Driver struct {
+	cfg Config
+}
+
+// NewDriver creates a Driver with cfg.
+func NewDriver(cfg Config) *Driver {
+	cfg.APIServer = strings.TrimRight(cfg.APIServer, "/")
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 2 * time.Second
+	}
+	classes := make(map[policy.Isolation]string, len(DefaultRuntimeClasses))
+	for k, v := range DefaultRuntimeClasses {
+		classes[k] = v
+	}
+	for k, v := range cfg.RuntimeClasses {
+		classes[k] = v
+	}
+	cfg.RuntimeClasses = classes
+	return &Driver{cfg: cfg}
+}
+
+// Name returns "kubernetes".
+func (d *Driver) Name() string { return driverName }
+
+// Start creates the job of a sandbox and a secret with its env, owned by
+// the job. It returns the job name.
+func (d *Driver) Start(ctx context.Context, spec *sandbox.Spec) (string, error) {
+	name := jobName(spec.RunID)
+	job, err := d.job(name, spec)
+	if err != nil {
+		return "", err
+	}
+	var created struct {
+		Metadata struct {
+			UID string `json:"uid"`
+		} `json:"metadata"`
+	}
+	if err := d.do(ctx, http.MethodPost, d.batchPath("jobs"), job, &created); err != nil {
+		return "", fmt.Errorf("kubernetes: create job: %w", err)
+	}
+
+	// The pod waits for the secret; the kubelet retries until it exists.
+	secret := map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]any{
+			"name":   name,
+			"labels": labels(spec),
+			"ownerReferences": []map[string]any{{
+				"apiVersion": "batch/v1", "kind": "Job", "name": name, "uid": created.Metadata.UID,
+			}},
+		},
+		"stringData": spec.Env,
+	}
+	if err := d.do(ctx, http.MethodPost, d.corePath("secrets"), secret, nil); err != nil {
+		_ = d.Remove(ctx, name)
+		return "", fmt.Errorf("kubernetes: create secret: %w", err)
+	}
+	return name, nil
+}
+
+// job returns the job manifest of a sandbox.
+func (d *Driver) job(name string, spec *sandbox.Spec) (map[string]any, error) {
+	limits := map[string]string{}
+	if spec.Limits.MemoryMB > 0 {
+		limits["memory"] = strconv.Itoa(spec.Limits.MemoryMB) + "Mi"
+	}
+	if spec.Limits.CPUs > 0 {
+		limits["cpu"] = strconv.Itoa(int(spec.Limits.CPUs*1000)) + "m"
+	}
+	if spec.Limits.StorageMB > 0 {
+		limits["ephemeral-storage"] = strconv.Itoa(spec.Limits.StorageMB) + "Mi"
+	}
+	container := map[string]any{
+		"name":         containerName,
+		"image":        spec.Image,
+		"workingDir":   sandbox.WorkDir,
+		"envFrom":      []map[string]any{{"secretRef": map[string]any{"name": name}}},
+		"resources":    map[string]any{"limits": limits, "requests": limits},
+		"volumeMounts": []map[string]any{{"name": "workspace", "mountPath": sandbox.WorkDir}},
+		"securityContext": map[string]any{
+			"allowPrivilegeEscalation": false,
+		},
+	}
+	if len(spec.Command) > 0 {
+		container["command"] = spec.Command
+	}
+
+	podSpec := map[string]any{
+		"restartPolicy":                "Never",
+		"automountServiceAccountToken": false,
+		"containers":                   []map[string]any{container},
+	}
+	if policy.IsolationStrength(spec.Isolation) > 0 {
+		rc := d.cfg.RuntimeClasses[spec.Isolation]
+		if rc == "" {
+			return nil, fmt.Errorf("kubernetes: no RuntimeClass configured for %s isolation", spec.Isolation)
+		}
+		podSpec["runtimeClassName"] = rc
+	}
+	switch {
+	case d.cfg.WorkspaceClaim != "" && spec.Workspace.HostPath != "":
+		sub, err := d.subPath(spec.Workspace.HostPath)
+		if err != nil {
+			return nil, err
+		}
+		container["volumeMounts"] = []map[string]any{{"name": "workspace", "mountPath": sandbox.WorkDir, "subPath": sub}}
+		podSpec["volumes"] = []map[string]any{{
+			"name":                  "workspace",
+			"persistentVolumeClaim": map[string]any{"claimName": d.cfg.WorkspaceClaim},
+		}}
+	case spec.Workspace.RepoURL != "":
+		clone := []string{"git", "clone", "--depth", "1"}
+		if spec.Workspace.Ref != "" {
+			clone = append(clone, "--branch", spec.Workspace.Ref)
+		}
+		clone = append(clone, "--", spec.Workspace.RepoURL, sandbox.WorkDir)
+		podSpec["initContainers"] = []map[string]any{{
+			"name":         "clone",
+			"image":        d.cfg.CloneImage,
+			"command":      clone,
+			"volumeMounts": []map[string]any{{"name": "workspace", "mountPath": sandbox.WorkDir}},
+		}}
+		vol := map[string]any{}
+		if spec.Limits.StorageMB > 0 {
+			vol["sizeLimit"] = strconv.Itoa(spec.Limits.StorageMB) + "Mi"
+		}
+		podSpec["volumes"] = []map[string]any{{"name": "workspace", "emptyDir": vol}}
+	default:
+		return nil, fmt.Errorf("kubernetes: run %s has neither a workspace claim nor a repository to clone", spec.RunID)
+	}
+
+	// The egress proxy is a native sidecar: an init container that keeps
+	// running next to the run and is stopped once the run exits. It shares
+	// the pod's network, so direct egress must be blocked by the cluster's
+	// network policies for the proxy to be the only way out.
+	if spec.Egress != nil {
+		container["env"] = envList(spec.ProxyEnv("127.0.0.1"))
+		inits, _ := podSpec["initContainers"].([]map[string]any)
+		podSpec["initContainers"] = append(inits, map[string]any{
+			"name":          egressContainerName,
+			"image":         spec.Egress.Image,
+			"restartPolicy": "Always",
+			"env":           envList(spec.SidecarEnv()),
+			"resources":     map[string]any{"limits": map[string]string{"memory": "128Mi"}},
+		})
+	}
+
+	annotations := map[string]string{}
+	if spec.Limits.PIDs > 0 {
+		annotations["codeforge.dev/pids-limit"] = strconv.Itoa(spec.Limits.PIDs)
+	}
+	jobSpec := map[string]any{
+		"backoffLimit":            0,
+		"ttlSecondsAfterFinished": finishedTTL,
+		"template": map[string]any{
+			"metadata": map[string]any{"labels": labels(spec), "annotations": annotations},
+			"spec":     podSpec,
+		},
+	}
+	if spec.Timeout > 0 {
+		jobSpec["activeDeadlineSeconds"] = int(spec.Timeout.Seconds())
+	}
+	return map[string]any{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata":   map[string]any{"name": name, "labels": labels(spec)},
+		"spec":       jobSpec,
+	}, nil
+}
+
+// envList returns env as a container env list, sorted by name.
+func envList(env map[string]string) []map[string]any {
+	names := make([]string, 0, len(env))
+	for k := range env {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	out := make([]map[string]any, len(names))
+	for i, k := range names {
+		out[i] = map[string]any{"name": k, "value": env[k]}
+	}
+	return out
+}
+
+// subPath returns the path of a workspace within the workspace claim.
+func (d *Driver) subPath(hostPath string) (string, error) {
+	root, err := filepath.Abs(d.cfg.WorkspaceRoot)
+	if err != nil {
+		return "", fmt.Errorf("kubernetes: workspace root: %w", err)
+	}
+	abs, err := filepath.Abs(hostPath)
+	if err != nil {
+		return "", fmt.Errorf("kubernetes: workspace: %w", err)
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("kubernetes: workspace %s is not on the workspace claim (root %s)", hostPath, root)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// Logs waits for the job's pod to start and follows the output of its run
+// container until the container exits.
+func (d *Driver) Logs(ctx context.Context, id string, fn func(sandbox.Line)) error {
+	pod, err := d.waitForPod(ctx, id)
+	if err != nil {
+		return err
+	}
+	q := url.Values{}
+	q.Set("container", containerName)
+	q.Set("follow", "true")
+	req, err := d.request(ctx, http.MethodGet, d.corePath("pods/"+pod+"/log")+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := d.cfg.HTTPClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("kubernetes: pod logs: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 400 {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("kubernetes: pod logs: API error %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		fn(sandbox.Line{Stream: "stdout", Text: sc.Text()})
+	}
+	if err := sc.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("kubernetes: pod logs: %w", err)
+	}
+	return nil
+}
+
+// waitForPod polls until the job's pod has left the Pending phase and
+// returns its name. A pod that failed in an init container is reported as
+// an error.
+func (d *Driver) waitForPod(ctx context.Context, job string) (string, error) {
+	q := url.Values{}
+	q.Set("labelSelector", "job-name="+job)
+	for {
+		var pods struct {
+			Items []struct {
+				Metadata struct {
+					Name string `json:"name"`
+				} `json:"metadata"`
+				Status struct {
+					Phase                 string `json:"phase"`
+					InitContainerStatuses []struct {
+						Name  string `json:"name"`
+						State struct {
+							Terminated *struct {
+								ExitCode int    `json:"exitCode"`
+								Reason   string `json:"reason"`
+							} `json:"terminated"`
+						} `json:"state"`
+					} `json:"initContainerStatuses"`
+				} `json:"status"`
+			} `json:"items"`
+		}
+		if err := d.do(ctx, http.MethodGet, d.corePath("pods")+"?"+q.Encode(), nil, &pods); err != nil {
+			return "", fmt.Errorf("kubernetes: list pods: %w", err)
+		}
+		for _, p := range pods.Items {
+			for _, ic := range p.Status.InitContainerStatuses {
+				if t := ic.State.Terminated; t != nil && t.ExitCode != 0 {
+					return "", fmt.Errorf("kubernetes: init container %s of %s failed: %s (exit %d)", ic.Name, p.Metadata.Name, t.Reason, t.ExitCode)
+				}
+			}
+			if p.Status.Phase != "" && p.Status.Phase != "Pending" {
+				return p.Metadata.Name, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(d.cfg.PollInterval):
+		}
+	}
+}
+
+// Remove deletes the job with its pods; the secret is garbage-collected
+// with its owning job.
+func (d *Driver) Remove(ctx context.Context, id string) error {
+	err := d.do(ctx, http.MethodDelete, d.batchPath("jobs/"+id)+"?propagationPolicy=Background", nil, nil)
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("kubernetes: delete job: %w", err)
+	}
+	return nil
+}
+
+// apiError is an error response of the API server.
+type apiError struct {
+	status int
+	body   string
+}
+
+func (e *apiError) Error() string { return fmt.Sprintf("API error %d: %s", e.status, e.body) }
+
+func isNotFound(err error) bool {
+	ae, ok := err.(*apiError)
+	return ok && ae.status == http.StatusNotFound
+}
+
+func (d *Driver) batchPath(resource string) string {
+	return "/apis/batch/v1/namespaces/" + d.cfg.Namespace + "/" + resource
+}
+
+func (d *Driver) corePath(resource string) string {
+	return "/api/v1/namespaces/" + d.cfg.Namespace + "/" + resource
+}
+
+func (d *Driver) request(ctx context.Context, method, path string, body any) (*http.Request, error) {
+	var r io.Reader = http.NoBody
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("kubernetes: encode request: %w", err)
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, d.cfg.APIServer+path, r)
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes: create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if d.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+d.cfg.Token)
+	}
+	return req, nil
+}
+
+// do sends a request and decodes the JSON response into out, if not nil.
+func (d *Driver) do(ctx context.Context, method, path string, body, out any) error {
+	req, err := d.request(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	resp, err := d.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("http request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return &apiError{status: resp.StatusCode, body: strings.TrimSpace(string(data))}
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+// jobName returns the DNS-1123 name of a run's job.
+func jobName(runID string) string {
+	name := "codeforge-run-" + strings.ToLower(runID)
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return strings.TrimRight(name, "-")
+}
+
+func labels(spec *sandbox.Spec) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       "codeforge-sandbox",
+		"app.kubernetes.io/managed-by": "codeforge",
+		"codeforge.dev/run-id":         spec.RunID,
+		"codeforge.dev/project-id":     spec.ProjectID,
+	}
+}
+
+// newHTTPClient returns an HTTP client that trusts the CA in caFile, or
+// the default client if caFile is empty.
+func newHTTPClient(caFile string) (*http.Client, error) {
+	if caFile == "" {
+		return &http.Client{}, nil
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes: read CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("kubernetes: no certificates in %s", caFile)
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}}}, nil
+}
@@ -0,0 +1,199 @@
+package kubernetes_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/kubernetes"
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/port/sandbox"
+)
+
+// fakeAPIServer records the requests it receives. The run pod is pending
+// on the first list call and running afterwards.
+type fakeAPIServer struct {
+	mu       sync.Mutex
+	requests []string
+	bodies   map[string]map[string]any
+	lists    int
+}
+
+func (f *fakeAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	if r.Header.Get("Authorization") != "Bearer tok" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method == http.MethodPost {
+		var body map[string]any
+		data, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+		f.bodies[r.URL.Path] = body
+	}
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/jobs"):
+		_, _ = w.Write([]byte(`{"metadata":{"uid":"job-uid"}}`))
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/secrets"):
+		_, _ = w.Write([]byte(`{}`))
+	case r.URL.Path == "/api/v1/namespaces/ci/pods":
+		if r.URL.Query().Get("labelSelector") != "job-name=codeforge-run-run-1" {
+			http.Error(w, "bad selector", http.StatusBadRequest)
+			return
+		}
+		f.lists++
+		phase := "Pending"
+		if f.lists > 1 {
+			phase = "Running"
+		}
+		_, _ = w.Write([]byte(`{"items":[{"metadata":{"name":"codeforge-run-run-1-abc"},"status":{"phase":"` + phase + `"}}]}`))
+	case r.URL.Path == "/api/v1/namespaces/ci/pods/codeforge-run-run-1-abc/log":
+		if r.URL.Query().Get("follow") != "true" || r.URL.Query().Get("container") != "run" {
+			http.Error(w, "bad log query", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte("cloning\nworker started\n"))
+	case r.Method == http.MethodDelete && r.URL.Path == "/apis/batch/v1/namespaces/ci/jobs/codeforge-run-run-1":
+		if r.URL.Query().Get("propagationPolicy") != "Background" {
+			http.Error(w, "bad propagation", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	default:
+		http.Error(w, `{"reason":"NotFound"}`, http.StatusNotFound)
+	}
+}
+
+func newDriver(t *testing.T, claim string) (*kubernetes.Driver, *fakeAPIServer) {
+	t.Helper()
+	api := &fakeAPIServer{bodies: make(map[string]map[string]any)}
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+	return kubernetes.NewDriver(kubernetes.Config{
+		APIServer:      srv.URL,
+		Namespace:      "ci",
+		Token:          "tok",
+		WorkspaceClaim: claim,
+		WorkspaceRoot:  "/srv/codeforge/data",
+		CloneImage:     "alpine/git:latest",
+		PollInterval:   time.Millisecond,
+	}), api
+}
+
+func TestDriverCloneWorkspace(t *testing.T) {
+	d, api := newDriver(t, "")
+	ctx := context.Background()
+	spec := &sandbox.Spec{
+		RunID:     "run-1",
+		ProjectID: "proj-1",
+		Image:     "codeforge-worker:latest",
+		Env:       map[string]string{"API_TOKEN": "s3cret"},
+		Workspace: sandbox.Workspace{RepoURL: "https://github.com/acme/app.git", Ref: "main"},
+		Limits:    sandbox.Limits{MemoryMB: 512, CPUs: 1.5, PIDs: 128, StorageMB: 1024},
+		Timeout:   10 * time.Minute,
+		Isolation: policy.IsolationGVisor,
+	}
+	id, err := d.Start(ctx, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "codeforge-run-run-1" {
+		t.Fatalf("unexpected job name %q", id)
+	}
+
+	job, _ := json.Marshal(api.bodies["/apis/batch/v1/namespaces/ci/jobs"])
+	for _, want := range []string{
+		`"activeDeadlineSeconds":600`, `"backoffLimit":0`,
+		`"limits":{"cpu":"1500m","ephemeral-storage":"1024Mi","memory":"512Mi"}`,
+		`"command":["git","clone","--depth","1","--branch","main","--","https://github.com/acme/app.git","/workspace"]`,
+		`"emptyDir":{"sizeLimit":"1024Mi"}`, `"codeforge.dev/pids-limit":"128"`,
+		`"envFrom":[{"secretRef":{"name":"codeforge-run-run-1"}}]`, `"runtimeClassName":"gvisor"`,
+	} {
+		if !strings.Contains(string(job), want) {
+			t.Errorf("job manifest missing %s: %s", want, job)
+		}
+	}
+	if strings.Contains(string(job), "s3cret") {
+		t.Fatal("secret env leaked into the job manifest")
+	}
+	secret, _ := json.Marshal(api.bodies["/api/v1/namespaces/ci/secrets"])
+	if !strings.Contains(string(secret), `"stringData":{"API_TOKEN":"s3cret"}`) || !strings.Contains(string(secret), `"uid":"job-uid"`) {
+		t.Fatalf("unexpected secret %s", secret)
+	}
+
+	var lines []string
+	if err := d.Logs(ctx, id, func(l sandbox.Line) { lines = append(lines, l.Text) }); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(lines, "|") != "cloning|worker started" || api.lists != 2 {
+		t.Fatalf("unexpected logs %v after %d pod lists", lines, api.lists)
+	}
+
+	if err := d.Remove(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Remove(ctx, "codeforge-run-gone"); err != nil {
+		t.Fatalf("removing an unknown job should succeed, got %v", err)
+	}
+}
+
+func TestDriverWorkspaceClaim(t *testing.T) {
+	d, api := newDriver(t, "workspaces")
+	spec := &sandbox.Spec{
+		RunID:     "run-1",
+		Image:     "codeforge-worker:latest",
+		Workspace: sandbox.Workspace{HostPath: "/srv/codeforge/data/worktrees/proj-1/run-1"},
+	}
+	if _, err := d.Start(context.Background(), spec); err != nil {
+		t.Fatal(err)
+	}
+	job, _ := json.Marshal(api.bodies["/apis/batch/v1/namespaces/ci/jobs"])
+	if !strings.Contains(string(job), `"subPath":"worktrees/proj-1/run-1"`) || strings.Contains(string(job), "runtimeClassName") ||
+		!strings.Contains(string(job), `"persistentVolumeClaim":{"claimName":"workspaces"}`) ||
+		strings.Contains(string(job), "initContainers") {
+		t.Fatalf("unexpected job manifest %s", job)
+	}
+
+	spec.Workspace.HostPath = "/tmp/elsewhere"
+	if _, err := d.Start(context.Background(), spec); err == nil {
+		t.Fatal("expected error for a workspace outside the claim")
+	}
+}
+
+func TestDriverEgressSidecar(t *testing.T) {
+	d, api := newDriver(t, "workspaces")
+	spec := &sandbox.Spec{
+		RunID:     "run-1",
+		ProjectID: "proj-1",
+		Image:     "codeforge-worker:latest",
+		Env:       map[string]string{"NATS_URL": "nats://nats:4222"},
+		Workspace: sandbox.Workspace{HostPath: "/srv/codeforge/data/proj-1"},
+		Egress: &sandbox.Egress{
+			Policy:  policy.EgressPolicy{AllowDomains: []string{"github.com"}},
+			Image:   "codeforge-egress:latest",
+			NoProxy: []string{"nats"},
+		},
+	}
+	if _, err := d.Start(context.Background(), spec); err != nil {
+		t.Fatal(err)
+	}
+	job, _ := json.Marshal(api.bodies["/apis/batch/v1/namespaces/ci/jobs"])
+	for _, want := range []string{
+		`"image":"codeforge-egress:latest"`, `"restartPolicy":"Always"`,
+		`{"name":"CODEFORGE_RUN_ID","value":"run-1"}`, `{"name":"NATS_URL","value":"nats://nats:4222"}`,
+		`{"name":"HTTPS_PROXY","value":"http://127.0.0.1:3128"}`, `{"name":"NO_PROXY","value":"nats"}`,
+		`"CODEFORGE_EGRESS_POLICY","value":"{\"allow_domains\":[\"github.com\"]}"`,
+	} {
+		if !strings.Contains(string(job), want) {
+			t.Errorf("job manifest missing %s: %s", want, job)
+		}
+	}
+}
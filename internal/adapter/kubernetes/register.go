@@ -0,0 +1,62 @@
+package kubernetes
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/port/sandbox"
+)
+
+func init() {
+	sandbox.Register(driverName, func(config map[string]string) (sandbox.Driver, error) {
+		cfg := Config{
+			APIServer:      config["api_server"],
+			Namespace:      config["namespace"],
+			WorkspaceClaim: config["workspace_claim"],
+			WorkspaceRoot:  config["workspace_root"],
+			CloneImage:     config["clone_image"],
+			RuntimeClasses: make(map[policy.Isolation]string),
+		}
+		for k, v := range config {
+			if level, ok := strings.CutPrefix(k, "runtime_"); ok && v != "" {
+				cfg.RuntimeClasses[policy.Isolation(level)] = v
+			}
+		}
+		tokenFile, caFile := config["token_file"], config["ca_file"]
+
+		// Without an explicit API server, use the in-cluster service account.
+		if cfg.APIServer == "" {
+			host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+			if host == "" || port == "" {
+				return nil, fmt.Errorf("kubernetes: api_server is required outside a cluster")
+			}
+			cfg.APIServer = "https://" + net.JoinHostPort(host, port)
+			if tokenFile == "" {
+				tokenFile = filepath.Join(serviceAccountDir, "token")
+			}
+			if caFile == "" {
+				caFile = filepath.Join(serviceAccountDir, "ca.crt")
+			}
+		}
+		if cfg.Namespace == "" {
+			return nil, fmt.Errorf("kubernetes: namespace is required")
+		}
+		if tokenFile != "" {
+			token, err := os.ReadFile(tokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("kubernetes: read token: %w", err)
+			}
+			cfg.Token = strings.TrimSpace(string(token))
+		}
+		client, err := newHTTPClient(caFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.HTTPClient = client
+		return NewDriver(cfg), nil
+	})
+}
@@ -0,0 +1,366 @@
+// Package linear implements the pmprovider.Provider interface against the
+// Linear GraphQL API.
+package linear
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/roadmap"
+	"github.com/Strob0t/CodeForge/internal/port/pmprovider"
+)
+
+const (
+	providerName   = "linear"
+	defaultBaseURL = "https://api.linear.app"
+	pageSize       = 100
+
+	// signatureHeader carries the hex HMAC-SHA256 of the webhook body.
+	signatureHeader = "Linear-Signature"
+	// maxWebhookAge rejects replayed deliveries.
+	maxWebhookAge = 5 * time.Minute
+)
+
+// Provider syncs the issues of one Linear team, optionally narrowed to
+// one Linear project.
+type Provider struct {
+	baseURL       string
+	team          string
+	project       string
+	apiKey        string
+	webhookSecret string
+	httpClient    *http.Client
+}
+
+// NewProvider creates a Provider for the issues of team (its key, e.g.
+// "ENG") and, if project is set, only those of that Linear project ID.
+func NewProvider(baseURL, team, project, apiKey, webhookSecret string) *Provider {
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	return &Provider{
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		team:          team,
+		project:       project,
+		apiKey:        apiKey,
+		webhookSecret: webhookSecret,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Name returns "linear".
+func (p *Provider) Name() string { return providerName }
+
+// Capabilities returns what the Linear provider supports. Webhooks are
+// only accepted when a webhook secret is configured to verify them with.
+func (p *Provider) Capabilities() pmprovider.Capabilities {
+	return pmprovider.Capabilities{
+		Import:     true,
+		StatusSync: true,
+		Webhook:    p.webhookSecret != "",
+	}
+}
+
+const issuesQuery = `query Issues($filter: IssueFilter, $first: Int, $after: String) {
+  issues(filter: $filter, first: $first, after: $after) {
+    nodes { id identifier title description url updatedAt state { type } labels { nodes { name } } team { key } project { id } }
+    pageInfo { hasNextPage endCursor }
+  }
+}`
+
+// ListItems returns all issues of the configured team and project.
+func (p *Provider) ListItems(ctx context.Context) ([]roadmap.Item, error) {
+	filter := map[string]any{"team": map[string]any{"key": map[string]string{"eq": p.team}}}
+	if p.project != "" {
+		filter["project"] = map[string]any{"id": map[string]string{"eq": p.project}}
+	}
+
+	var items []roadmap.Item
+	var after *string
+	for {
+		var data struct {
+			Issues struct {
+				Nodes    []issue `json:"nodes"`
+				PageInfo struct {
+					HasNextPage bool   `json:"hasNextPage"`
+					EndCursor   string `json:"endCursor"`
+				} `json:"pageInfo"`
+			} `json:"issues"`
+		}
+		if err := p.query(ctx, issuesQuery, map[string]any{"filter": filter, "first": pageSize, "after": after}, &data); err != nil {
+			return nil, err
+		}
+		for i := range data.Issues.Nodes {
+			items = append(items, data.Issues.Nodes[i].item())
+		}
+		if !data.Issues.PageInfo.HasNextPage {
+			return items, nil
+		}
+		cursor := data.Issues.PageInfo.EndCursor
+		after = &cursor
+	}
+}
+
+const statesQuery = `query States($id: String!) {
+  issue(id: $id) { team { states { nodes { id type position } } } }
+}`
+
+const updateMutation = `mutation SetState($id: String!, $stateId: String!) {
+  issueUpdate(id: $id, input: { stateId: $stateId }) { success }
+}`
+
+// UpdateStatus moves an issue to the first workflow state (by position) of
+// its team whose type matches status.
+func (p *Provider) UpdateStatus(ctx context.Context, itemID string, status roadmap.FeatureStatus) error {
+	var data struct {
+		Issue struct {
+			Team struct {
+				States struct {
+					Nodes []struct {
+						ID       string  `json:"id"`
+						Type     string  `json:"type"`
+						Position float64 `json:"position"`
+					} `json:"nodes"`
+				} `json:"states"`
+			} `json:"team"`
+		} `json:"issue"`
+	}
+	if err := p.query(ctx, statesQuery, map[string]any{"id": itemID}, &data); err != nil {
+		return err
+	}
+
+	stateID := ""
+	for _, want := range stateTypesFor(status) {
+		var best float64
+		for _, st := range data.Issue.Team.States.Nodes {
+			if st.Type == want && (stateID == "" || st.Position < best) {
+				stateID, best = st.ID, st.Position
+			}
+		}
+		if stateID != "" {
+			break
+		}
+	}
+	if stateID == "" {
+		return fmt.Errorf("linear: team of issue %s has no state for %s", itemID, status)
+	}
+
+	var res struct {
+		IssueUpdate struct {
+			Success bool `json:"success"`
+		} `json:"issueUpdate"`
+	}
+	if err := p.query(ctx, updateMutation, map[string]any{"id": itemID, "stateId": stateID}, &res); err != nil {
+		return err
+	}
+	if !res.IssueUpdate.Success {
+		return fmt.Errorf("linear: update of issue %s was not applied", itemID)
+	}
+	return nil
+}
+
+// ParseWebhook verifies the signature and age of a Linear webhook delivery
+// and decodes issue events of the configured team and project.
+func (p *Provider) ParseWebhook(header http.Header, body []byte) (*pmprovider.WebhookEvent, error) {
+	if p.webhookSecret == "" || !validSignature(p.webhookSecret, header.Get(signatureHeader), body) {
+		return nil, pmprovider.ErrInvalidSignature
+	}
+
+	var payload struct {
+		Action           string `json:"action"`
+		Type             string `json:"type"`
+		Data             issue  `json:"data"`
+		WebhookTimestamp int64  `json:"webhookTimestamp"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("linear: unmarshal webhook: %w", err)
+	}
+	if age := time.Since(time.UnixMilli(payload.WebhookTimestamp)); age > maxWebhookAge || age < -maxWebhookAge {
+		return nil, pmprovider.ErrInvalidSignature
+	}
+
+	var action pmprovider.Action
+	switch payload.Action {
+	case "create":
+		action = pmprovider.ActionCreated
+	case "update":
+		action = pmprovider.ActionUpdated
+	case "remove":
+		action = pmprovider.ActionDeleted
+	default:
+		return nil, pmprovider.ErrIgnoredEvent
+	}
+	if payload.Type != "Issue" || !p.owns(&payload.Data) {
+		return nil, pmprovider.ErrIgnoredEvent
+	}
+	return &pmprovider.WebhookEvent{Action: action, Item: payload.Data.item()}, nil
+}
+
+func (p *Provider) owns(is *issue) bool {
+	if is.Team == nil || !strings.EqualFold(is.Team.Key, p.team) {
+		return false
+	}
+	if p.project == "" {
+		return true
+	}
+	return is.Project != nil && is.Project.ID == p.project
+}
+
+func validSignature(secret, header string, body []byte) bool {
+	got, err := hex.DecodeString(header)
+	if err != nil || len(got) == 0 {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// --- API types and mapping ---
+
+// issue is an issue as returned by queries and sent in webhook payloads.
+// Webhooks send labels as a plain list, queries as a connection.
+type issue struct {
+	ID          string    `json:"id"`
+	Identifier  string    `json:"identifier"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	URL         string    `json:"url"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	State       struct {
+		Type string `json:"type"`
+	} `json:"state"`
+	Labels labels `json:"labels"`
+	Team   *struct {
+		Key string `json:"key"`
+	} `json:"team"`
+	Project *struct {
+		ID string `json:"id"`
+	} `json:"project"`
+}
+
+type labels []string
+
+func (l *labels) UnmarshalJSON(data []byte) error {
+	type label struct {
+		Name string `json:"name"`
+	}
+	var conn struct {
+		Nodes []label `json:"nodes"`
+	}
+	var list []label
+	if err := json.Unmarshal(data, &list); err != nil {
+		if err := json.Unmarshal(data, &conn); err != nil {
+			return err
+		}
+		list = conn.Nodes
+	}
+	*l = nil
+	for _, lb := range list {
+		*l = append(*l, lb.Name)
+	}
+	return nil
+}
+
+func (is *issue) item() roadmap.Item {
+	return roadmap.Item{
+		ID:          is.ID,
+		Key:         is.Identifier,
+		Title:       is.Title,
+		Description: is.Description,
+		Status:      statusFor(is.State.Type),
+		Labels:      is.Labels,
+		URL:         is.URL,
+		UpdatedAt:   is.UpdatedAt,
+	}
+}
+
+func statusFor(stateType string) roadmap.FeatureStatus {
+	switch stateType {
+	case "started":
+		return roadmap.FeatureStatusInProgress
+	case "completed":
+		return roadmap.FeatureStatusDone
+	case "canceled":
+		return roadmap.FeatureStatusCancelled
+	default: // triage, backlog, unstarted
+		return roadmap.FeatureStatusPlanned
+	}
+}
+
+// stateTypesFor returns the workflow state types for a status, preferred
+// first.
+func stateTypesFor(status roadmap.FeatureStatus) []string {
+	switch status {
+	case roadmap.FeatureStatusInProgress:
+		return []string{"started"}
+	case roadmap.FeatureStatusDone:
+		return []string{"completed"}
+	case roadmap.FeatureStatusCancelled:
+		return []string{"canceled"}
+	default:
+		return []string{"unstarted", "backlog"}
+	}
+}
+
+// --- HTTP ---
+
+func (p *Provider) query(ctx context.Context, query string, vars map[string]any, out any) error {
+	data, err := json.Marshal(map[string]any{"query": query, "variables": vars})
+	if err != nil {
+		return fmt.Errorf("linear: marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/graphql", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("linear: create request: %w", err)
+	}
+	req.Header.Set("Authorization", p.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("linear: http request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("linear: read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("linear: API error %d: %s", resp.StatusCode, string(body))
+	}
+
+	var envelope struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("linear: unmarshal response: %w", err)
+	}
+	if len(envelope.Errors) > 0 {
+		msgs := make([]string, len(envelope.Errors))
+		for i, e := range envelope.Errors {
+			msgs[i] = e.Message
+		}
+		return errors.New("linear: " + strings.Join(msgs, "; "))
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("linear: unmarshal data: %w", err)
+	}
+	return nil
+}
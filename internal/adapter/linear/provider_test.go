@@ -0,0 +1,198 @@
+package linear_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/linear"
+	"github.com/Strob0t/CodeForge/internal/domain/roadmap"
+	"github.com/Strob0t/CodeForge/internal/port/pmprovider"
+)
+
+const issueJSON = `{
+	"id": "a1b2",
+	"identifier": "ENG-42",
+	"title": "Single sign-on",
+	"description": "Support SAML.",
+	"url": "https://linear.app/acme/issue/ENG-42",
+	"updatedAt": "2024-05-01T10:00:00.000Z",
+	"state": {"type": "started"},
+	"labels": {"nodes": [{"name": "auth"}]},
+	"team": {"key": "ENG"},
+	"project": {"id": "proj-1"}
+}`
+
+type gqlRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+func TestListItems_Paginates(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/graphql" || r.Method != http.MethodPost {
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "lin_api_key" {
+			t.Fatal("expected API key in Authorization header")
+		}
+		var req gqlRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		filter, _ := json.Marshal(req.Variables["filter"])
+		if string(filter) != `{"project":{"id":{"eq":"proj-1"}},"team":{"key":{"eq":"ENG"}}}` {
+			t.Fatalf("unexpected filter %s", filter)
+		}
+		calls++
+		if req.Variables["after"] == nil {
+			_, _ = w.Write([]byte(`{"data": {"issues": {"nodes": [` + issueJSON + `], "pageInfo": {"hasNextPage": true, "endCursor": "c1"}}}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"issues": {"nodes": [{"id": "c3d4", "identifier": "ENG-43", "title": "Audit log", "state": {"type": "backlog"}, "labels": {"nodes": []}}], "pageInfo": {"hasNextPage": false}}}}`))
+	}))
+	defer srv.Close()
+
+	p := linear.NewProvider(srv.URL, "ENG", "proj-1", "lin_api_key", "")
+	items, err := p.ListItems(context.Background())
+	if err != nil {
+		t.Fatalf("ListItems failed: %v", err)
+	}
+	if calls != 2 || len(items) != 2 {
+		t.Fatalf("expected 2 items over 2 pages, got %d items in %d calls", len(items), calls)
+	}
+	it := items[0]
+	if it.ID != "a1b2" || it.Key != "ENG-42" || it.Status != roadmap.FeatureStatusInProgress {
+		t.Fatalf("unexpected item %+v", it)
+	}
+	if len(it.Labels) != 1 || it.Labels[0] != "auth" || it.URL == "" || it.UpdatedAt.IsZero() {
+		t.Fatalf("expected labels, url and updated time, got %+v", it)
+	}
+	if items[1].Status != roadmap.FeatureStatusPlanned {
+		t.Fatalf("unexpected second item %+v", items[1])
+	}
+}
+
+func TestListItems_GraphQLError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"errors": [{"message": "Authentication required"}]}`))
+	}))
+	defer srv.Close()
+
+	p := linear.NewProvider(srv.URL, "ENG", "", "bad", "")
+	if _, err := p.ListItems(context.Background()); err == nil || !strings.Contains(err.Error(), "Authentication required") {
+		t.Fatalf("expected GraphQL error surfaced, got %v", err)
+	}
+}
+
+func TestUpdateStatus_PicksStateByType(t *testing.T) {
+	var chosen string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req gqlRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Variables["id"] != "a1b2" {
+			t.Fatalf("unexpected issue id %v", req.Variables["id"])
+		}
+		if strings.HasPrefix(req.Query, "query States") {
+			_, _ = w.Write([]byte(`{"data": {"issue": {"team": {"states": {"nodes": [
+				{"id": "s-backlog", "type": "backlog", "position": 0},
+				{"id": "s-review", "type": "started", "position": 3},
+				{"id": "s-progress", "type": "started", "position": 2},
+				{"id": "s-done", "type": "completed", "position": 4},
+				{"id": "s-canceled", "type": "canceled", "position": 5}
+			]}}}}}`))
+			return
+		}
+		chosen, _ = req.Variables["stateId"].(string)
+		_, _ = w.Write([]byte(`{"data": {"issueUpdate": {"success": true}}}`))
+	}))
+	defer srv.Close()
+
+	p := linear.NewProvider(srv.URL, "ENG", "", "lin_api_key", "")
+	ctx := context.Background()
+	for status, want := range map[roadmap.FeatureStatus]string{
+		roadmap.FeatureStatusPlanned:    "s-backlog",
+		roadmap.FeatureStatusInProgress: "s-progress",
+		roadmap.FeatureStatusDone:       "s-done",
+		roadmap.FeatureStatusCancelled:  "s-canceled",
+	} {
+		if err := p.UpdateStatus(ctx, "a1b2", status); err != nil {
+			t.Fatalf("%s: %v", status, err)
+		}
+		if chosen != want {
+			t.Errorf("%s: expected state %s, got %s", status, want, chosen)
+		}
+	}
+}
+
+func sign(secret string, body []byte) http.Header {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	h := http.Header{}
+	h.Set("Linear-Signature", hex.EncodeToString(mac.Sum(nil)))
+	return h
+}
+
+func delivery(action, typ string, sent time.Time) []byte {
+	data := strings.Replace(issueJSON, `{"nodes": [{"name": "auth"}]}`, `[{"name": "auth"}]`, 1)
+	return []byte(fmt.Sprintf(`{"action": %q, "type": %q, "data": %s, "webhookTimestamp": %d}`,
+		action, typ, data, sent.UnixMilli()))
+}
+
+func TestParseWebhook(t *testing.T) {
+	p := linear.NewProvider("", "ENG", "", "lin_api_key", "s3cret")
+	body := delivery("update", "Issue", time.Now())
+
+	ev, err := p.ParseWebhook(sign("s3cret", body), body)
+	if err != nil {
+		t.Fatalf("ParseWebhook failed: %v", err)
+	}
+	if ev.Action != pmprovider.ActionUpdated || ev.Item.Key != "ENG-42" || len(ev.Item.Labels) != 1 {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	removed := delivery("remove", "Issue", time.Now())
+	if ev, err := p.ParseWebhook(sign("s3cret", removed), removed); err != nil || ev.Action != pmprovider.ActionDeleted {
+		t.Fatalf("expected delete event, got %+v, %v", ev, err)
+	}
+
+	if _, err := p.ParseWebhook(sign("wrong", body), body); !errors.Is(err, pmprovider.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+	stale := delivery("update", "Issue", time.Now().Add(-time.Hour))
+	if _, err := p.ParseWebhook(sign("s3cret", stale), stale); !errors.Is(err, pmprovider.ErrInvalidSignature) {
+		t.Fatalf("expected stale delivery rejected, got %v", err)
+	}
+	comment := delivery("create", "Comment", time.Now())
+	if _, err := p.ParseWebhook(sign("s3cret", comment), comment); !errors.Is(err, pmprovider.ErrIgnoredEvent) {
+		t.Fatalf("expected ErrIgnoredEvent for comment, got %v", err)
+	}
+	for _, other := range []*linear.Provider{
+		linear.NewProvider("", "OPS", "", "lin_api_key", "s3cret"),
+		linear.NewProvider("", "ENG", "proj-2", "lin_api_key", "s3cret"),
+	} {
+		if _, err := other.ParseWebhook(sign("s3cret", body), body); !errors.Is(err, pmprovider.ErrIgnoredEvent) {
+			t.Fatalf("expected ErrIgnoredEvent for another team or project, got %v", err)
+		}
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	if _, err := pmprovider.New("linear", map[string]string{"token": "lin_api_key"}); err == nil {
+		t.Fatal("expected error when team is missing")
+	}
+	p, err := pmprovider.New("linear", map[string]string{"linear_team": "ENG", "token": "lin_api_key", "webhook_secret": "s3cret"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if p.Name() != "linear" || !p.Capabilities().Webhook {
+		t.Fatalf("expected linear with webhooks, got %s %+v", p.Name(), p.Capabilities())
+	}
+}
@@ -0,0 +1,32 @@
+package linear
+
+import (
+	"fmt"
+
+	"github.com/Strob0t/CodeForge/internal/port/pmprovider"
+)
+
+// Project config keys read by the Linear provider, next to the API key
+// (token) and webhook secret resolved by the sync service.
+const (
+	configURL     = "linear_url"     // API base URL, defaults to https://api.linear.app
+	configTeam    = "linear_team"    // Team key, e.g. ENG
+	configProject = "linear_project" // Optional Linear project ID to import from
+)
+
+func init() {
+	pmprovider.Register(providerName, func(config map[string]string) (pmprovider.Provider, error) {
+		for _, key := range []string{configTeam, pmprovider.ConfigToken} {
+			if config[key] == "" {
+				return nil, fmt.Errorf("linear: %s is required", key)
+			}
+		}
+		return NewProvider(
+			config[configURL],
+			config[configTeam],
+			config[configProject],
+			config[pmprovider.ConfigToken],
+			config[pmprovider.ConfigWebhookKey],
+		), nil
+	})
+}
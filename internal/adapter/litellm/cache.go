@@ -0,0 +1,160 @@
+package litellm
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// CacheBypassHeader is the request header that skips the response cache
+// for a single call when set to CacheBypassValue.
+const (
+	CacheBypassHeader = "X-CodeForge-Cache"
+	CacheBypassValue  = "bypass"
+)
+
+type cacheBypassKey struct{}
+
+// WithCacheBypass returns a context whose completion calls skip the
+// response cache: they neither read cached responses nor store new ones.
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// CacheBypassed reports whether the context requests a cache bypass.
+func CacheBypassed(ctx context.Context) bool {
+	v, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return v
+}
+
+// CacheStats is a point-in-time view of the response cache counters.
+type CacheStats struct {
+	Enabled        bool    `json:"enabled"`
+	Entries        int     `json:"entries"`
+	MaxEntries     int     `json:"max_entries"`
+	TTLSeconds     float64 `json:"ttl_seconds"`
+	Hits           int64   `json:"hits"`
+	Misses         int64   `json:"misses"`
+	Bypassed       int64   `json:"bypassed"`
+	Evictions      int64   `json:"evictions"`
+	TokensInSaved  int64   `json:"tokens_in_saved"`
+	TokensOutSaved int64   `json:"tokens_out_saved"`
+	HitRatio       float64 `json:"hit_ratio"`
+}
+
+// ResponseCache is an in-memory LRU cache of chat completion responses
+// keyed by a hash of the model and messages. Entries expire after a TTL.
+type ResponseCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	order      *list.List // front = most recently used
+	entries    map[string]*list.Element
+	stats      CacheStats
+}
+
+type cacheEntry struct {
+	key       string
+	resp      ChatCompletionResponse
+	expiresAt time.Time
+}
+
+// NewResponseCache creates a response cache. A ttl of zero keeps entries
+// until they are evicted; maxEntries must be positive.
+func NewResponseCache(ttl time.Duration, maxEntries int) *ResponseCache {
+	if maxEntries <= 0 {
+		maxEntries = 1
+	}
+	return &ResponseCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// CacheKey returns the cache key for a completion request: a SHA-256 over
+// the model, the sampling parameters and the full message list.
+func CacheKey(req *ChatCompletionRequest) string {
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns a cached response for key, if present and not expired.
+func (c *ResponseCache) Get(key string) (*ChatCompletionResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	e := el.Value.(*cacheEntry)
+	if c.ttl > 0 && time.Now().After(e.expiresAt) {
+		c.remove(el)
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.order.MoveToFront(el)
+	c.stats.Hits++
+	c.stats.TokensInSaved += int64(e.resp.TokensIn)
+	c.stats.TokensOutSaved += int64(e.resp.TokensOut)
+	resp := e.resp
+	return &resp, true
+}
+
+// Put stores a response under key, evicting the least recently used entry
+// when the cache is full.
+func (c *ResponseCache) Put(key string, resp *ChatCompletionResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := time.Now().Add(c.ttl)
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*cacheEntry)
+		e.resp = *resp
+		e.expiresAt = expires
+		c.order.MoveToFront(el)
+		return
+	}
+
+	for c.order.Len() >= c.maxEntries {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, resp: *resp, expiresAt: expires})
+}
+
+// Stats returns the current cache counters.
+func (c *ResponseCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.stats
+	s.Enabled = true
+	s.Entries = c.order.Len()
+	s.MaxEntries = c.maxEntries
+	s.TTLSeconds = c.ttl.Seconds()
+	if total := s.Hits + s.Misses; total > 0 {
+		s.HitRatio = float64(s.Hits) / float64(total)
+	}
+	return s
+}
+
+func (c *ResponseCache) recordBypass() {
+	c.mu.Lock()
+	c.stats.Bypassed++
+	c.mu.Unlock()
+}
+
+func (c *ResponseCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry).key)
+}
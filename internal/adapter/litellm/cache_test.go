@@ -0,0 +1,133 @@
+package litellm_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
+)
+
+func newCountingServer(t *testing.T, calls *atomic.Int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"plan"}}],"usage":{"prompt_tokens":100,"completion_tokens":20},"model":"gpt-4o"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func completionReq(content string) litellm.ChatCompletionRequest {
+	return litellm.ChatCompletionRequest{
+		Model:    "gpt-4o",
+		Messages: []litellm.ChatMessage{{Role: "user", Content: content}},
+	}
+}
+
+func TestChatCompletionCache(t *testing.T) {
+	var calls atomic.Int32
+	srv := newCountingServer(t, &calls)
+	client := litellm.NewClient(srv.URL, "")
+	client.SetCache(litellm.NewResponseCache(time.Hour, 10))
+	ctx := context.Background()
+
+	first, err := client.ChatCompletion(ctx, completionReq("decompose feature"))
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	if first.Cached {
+		t.Fatal("expected first response to be fresh")
+	}
+	second, err := client.ChatCompletion(ctx, completionReq("decompose feature"))
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	if !second.Cached || second.Content != "plan" {
+		t.Fatalf("expected cached response, got %+v", second)
+	}
+	if _, err := client.ChatCompletion(ctx, completionReq("other feature")); err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("expected 2 upstream calls, got %d", got)
+	}
+
+	// Bypass skips the cache entirely.
+	if _, err := client.ChatCompletion(litellm.WithCacheBypass(ctx), completionReq("decompose feature")); err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("expected bypass to reach upstream, got %d calls", got)
+	}
+
+	stats := client.CacheStats()
+	if !stats.Enabled || stats.Hits != 1 || stats.Misses != 2 || stats.Bypassed != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if stats.TokensInSaved != 100 || stats.TokensOutSaved != 20 {
+		t.Fatalf("unexpected saved tokens %+v", stats)
+	}
+}
+
+func TestUsageObserverSkipsCachedResponses(t *testing.T) {
+	var calls atomic.Int32
+	srv := newCountingServer(t, &calls)
+	client := litellm.NewClient(srv.URL, "")
+	client.SetCache(litellm.NewResponseCache(time.Hour, 10))
+	var observed []int
+	client.SetUsageObserver(func(req *litellm.ChatCompletionRequest, resp *litellm.ChatCompletionResponse) {
+		if req.Model != "gpt-4o" {
+			t.Errorf("unexpected model %q", req.Model)
+		}
+		observed = append(observed, resp.TokensIn)
+	})
+
+	for range 2 {
+		if _, err := client.ChatCompletion(context.Background(), completionReq("decompose feature")); err != nil {
+			t.Fatalf("ChatCompletion failed: %v", err)
+		}
+	}
+	if len(observed) != 1 || observed[0] != 100 {
+		t.Fatalf("expected one observed call with 100 prompt tokens, got %v", observed)
+	}
+}
+
+func TestResponseCacheEvictionAndTTL(t *testing.T) {
+	c := litellm.NewResponseCache(time.Hour, 2)
+	c.Put("a", &litellm.ChatCompletionResponse{Content: "a"})
+	c.Put("b", &litellm.ChatCompletionResponse{Content: "b"})
+	if _, ok := c.Get("a"); !ok { // a becomes most recently used
+		t.Fatal("expected hit for a")
+	}
+	c.Put("c", &litellm.ChatCompletionResponse{Content: "c"})
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("expected b evicted")
+	}
+	if s := c.Stats(); s.Entries != 2 || s.Evictions != 1 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+
+	short := litellm.NewResponseCache(time.Millisecond, 2)
+	short.Put("a", &litellm.ChatCompletionResponse{Content: "a"})
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := short.Get("a"); ok {
+		t.Fatal("expected expired entry")
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	a := completionReq("x")
+	b := completionReq("x")
+	if litellm.CacheKey(&a) != litellm.CacheKey(&b) {
+		t.Fatal("expected identical requests to share a key")
+	}
+	b.Temperature = 0.7
+	if litellm.CacheKey(&a) == litellm.CacheKey(&b) {
+		t.Fatal("expected sampling parameters to change the key")
+	}
+}
@@ -41,6 +41,8 @@ type Client struct {
 	masterKey  string
 	httpClient *http.Client
 	breaker    *resilience.Breaker
+	cache      *ResponseCache
+	observe    func(*ChatCompletionRequest, *ChatCompletionResponse)
 }
 
 // NewClient creates a new LiteLLM admin client.
@@ -59,6 +61,27 @@ func (c *Client) SetBreaker(b *resilience.Breaker) {
 	c.breaker = b
 }
 
+// SetCache enables prompt-level caching of chat completion responses.
+func (c *Client) SetCache(cache *ResponseCache) {
+	c.cache = cache
+}
+
+// SetUsageObserver registers fn to be called with every chat completion
+// answered by the proxy, e.g. to compare token estimates against the
+// reported usage. Cached responses are not observed.
+func (c *Client) SetUsageObserver(fn func(*ChatCompletionRequest, *ChatCompletionResponse)) {
+	c.observe = fn
+}
+
+// CacheStats returns the response cache counters. Enabled is false when
+// no cache is attached.
+func (c *Client) CacheStats() CacheStats {
+	if c.cache == nil {
+		return CacheStats{}
+	}
+	return c.cache.Stats()
+}
+
 // ListModels returns all configured models from LiteLLM.
 func (c *Client) ListModels(ctx context.Context) ([]Model, error) {
 	resp, err := c.doRequest(ctx, http.MethodGet, "/model/info", nil)
@@ -75,6 +98,22 @@ func (c *Client) ListModels(ctx context.Context) ([]Model, error) {
 	return result.Data, nil
 }
 
+// SupportsVision reports whether the model info of a model marks it as
+// accepting images. Models unknown to LiteLLM do not.
+func (c *Client) SupportsVision(ctx context.Context, model string) (bool, error) {
+	models, err := c.ListModels(ctx)
+	if err != nil {
+		return false, err
+	}
+	for i := range models {
+		if models[i].ModelName == model {
+			v, _ := models[i].ModelInfo["supports_vision"].(bool)
+			return v, nil
+		}
+	}
+	return false, nil
+}
+
 // AddModel adds a new model configuration to LiteLLM.
 func (c *Client) AddModel(ctx context.Context, req AddModelRequest) error {
 	body, err := json.Marshal(req)
@@ -116,10 +155,44 @@ type AddModelRequest struct {
 
 // --- Chat Completion (OpenAI-compatible) ---
 
-// ChatMessage represents a single message in a chat completion.
+// ChatMessage represents a single message in a chat completion. Images
+// are sent after the text as image_url content parts, which only
+// vision-capable models accept.
 type ChatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"-"` // Image URLs, e.g. "data:image/png;base64,..."
+}
+
+// chatContentPart is a part of a multi-part message content.
+type chatContentPart struct {
+	Type     string        `json:"type"` // "text" or "image_url"
+	Text     string        `json:"text,omitempty"`
+	ImageURL *chatImageURL `json:"image_url,omitempty"`
+}
+
+type chatImageURL struct {
+	URL string `json:"url"`
+}
+
+// MarshalJSON encodes the content as a string, or as a list of parts if the
+// message has images.
+func (m ChatMessage) MarshalJSON() ([]byte, error) {
+	if len(m.Images) == 0 {
+		type plain ChatMessage
+		return json.Marshal(plain(m))
+	}
+	parts := make([]chatContentPart, 0, len(m.Images)+1)
+	if m.Content != "" {
+		parts = append(parts, chatContentPart{Type: "text", Text: m.Content})
+	}
+	for _, url := range m.Images {
+		parts = append(parts, chatContentPart{Type: "image_url", ImageURL: &chatImageURL{URL: url}})
+	}
+	return json.Marshal(struct {
+		Role    string            `json:"role"`
+		Content []chatContentPart `json:"content"`
+	}{m.Role, parts})
 }
 
 // ChatCompletionRequest is the request body for /v1/chat/completions.
@@ -136,11 +209,36 @@ type ChatCompletionResponse struct {
 	TokensIn  int
 	TokensOut int
 	Model     string
+	Cached    bool // served from the response cache; no tokens were spent
 }
 
 // ChatCompletion sends a chat completion request to the LiteLLM Proxy's
-// OpenAI-compatible /v1/chat/completions endpoint.
+// OpenAI-compatible /v1/chat/completions endpoint. When a cache is
+// attached, identical requests are answered from it unless the context
+// carries a cache bypass.
 func (c *Client) ChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	if c.cache == nil {
+		return c.chatCompletion(ctx, &req)
+	}
+	if CacheBypassed(ctx) {
+		c.cache.recordBypass()
+		return c.chatCompletion(ctx, &req)
+	}
+
+	key := CacheKey(&req)
+	if cached, ok := c.cache.Get(key); ok {
+		cached.Cached = true
+		return cached, nil
+	}
+	resp, err := c.chatCompletion(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.Put(key, resp)
+	return resp, nil
+}
+
+func (c *Client) chatCompletion(ctx context.Context, req *ChatCompletionRequest) (*ChatCompletionResponse, error) {
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("marshal completion request: %w", err)
@@ -172,12 +270,16 @@ func (c *Client) ChatCompletion(ctx context.Context, req ChatCompletionRequest)
 		content = raw.Choices[0].Message.Content
 	}
 
-	return &ChatCompletionResponse{
+	resp := &ChatCompletionResponse{
 		Content:   content,
 		TokensIn:  raw.Usage.PromptTokens,
 		TokensOut: raw.Usage.CompletionTokens,
 		Model:     raw.Model,
-	}, nil
+	}
+	if c.observe != nil {
+		c.observe(req, resp)
+	}
+	return resp, nil
 }
 
 func (c *Client) doRequest(ctx context.Context, method, path string, body []byte) ([]byte, error) {
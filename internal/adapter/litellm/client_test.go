@@ -44,6 +44,47 @@ func TestListModels(t *testing.T) {
 	}
 }
 
+func TestSupportsVision(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		resp := map[string][]litellm.Model{
+			"data": {
+				{ModelName: "gpt-4o", ModelInfo: map[string]any{"supports_vision": true}},
+				{ModelName: "gpt-3.5-turbo", ModelInfo: map[string]any{"supports_vision": false}},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	client := litellm.NewClient(srv.URL, "test-key")
+	for model, want := range map[string]bool{"gpt-4o": true, "gpt-3.5-turbo": false, "unknown": false} {
+		got, err := client.SupportsVision(context.Background(), model)
+		if err != nil {
+			t.Fatalf("SupportsVision(%s) failed: %v", model, err)
+		}
+		if got != want {
+			t.Errorf("SupportsVision(%s) = %t, want %t", model, got, want)
+		}
+	}
+}
+
+func TestChatMessageImages(t *testing.T) {
+	plain, _ := json.Marshal(litellm.ChatMessage{Role: "user", Content: "hi"})
+	if string(plain) != `{"role":"user","content":"hi"}` {
+		t.Fatalf("unexpected plain message: %s", plain)
+	}
+
+	data, err := json.Marshal(litellm.ChatMessage{Role: "user", Content: "what is this?", Images: []string{"data:image/png;base64,AAAA"}})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `{"role":"user","content":[{"type":"text","text":"what is this?"},{"type":"image_url","image_url":{"url":"data:image/png;base64,AAAA"}}]}`
+	if string(data) != want {
+		t.Fatalf("got %s, want %s", data, want)
+	}
+}
+
 func TestAddModel(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/model/new" {
@@ -202,6 +243,68 @@ func TestChatCompletionHTTPError(t *testing.T) {
 	}
 }
 
+func TestChatCompletionStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Stream        bool `json:"stream"`
+			StreamOptions struct {
+				IncludeUsage bool `json:"include_usage"`
+			} `json:"stream_options"`
+			Model string `json:"model"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		if !body.Stream || !body.StreamOptions.IncludeUsage || body.Model != "gpt-4o-mini" {
+			t.Errorf("unexpected request: %+v", body)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"model":"gpt-4o-mini","choices":[{"delta":{"role":"assistant"}}]}`,
+			`{"model":"gpt-4o-mini","choices":[{"delta":{"content":"Hello"}}]}`,
+			`{"model":"gpt-4o-mini","choices":[{"delta":{"content":" world"}}]}`,
+			`{"model":"gpt-4o-mini","choices":[],"usage":{"prompt_tokens":10,"completion_tokens":2}}`,
+			`[DONE]`,
+		} {
+			_, _ = w.Write([]byte("data: " + chunk + "\n\n"))
+		}
+	}))
+	defer srv.Close()
+
+	client := litellm.NewClient(srv.URL, "")
+	var deltas []string
+	resp, err := client.ChatCompletionStream(context.Background(), litellm.ChatCompletionRequest{
+		Model:    "gpt-4o-mini",
+		Messages: []litellm.ChatMessage{{Role: "user", Content: "hi"}},
+	}, func(d string) { deltas = append(deltas, d) })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(deltas) != 2 || deltas[0] != "Hello" || deltas[1] != " world" {
+		t.Errorf("unexpected deltas: %q", deltas)
+	}
+	if resp.Content != "Hello world" || resp.TokensIn != 10 || resp.TokensOut != 2 || resp.Model != "gpt-4o-mini" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestChatCompletionStreamHTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error": "bad request"}`))
+	}))
+	defer srv.Close()
+
+	client := litellm.NewClient(srv.URL, "")
+	_, err := client.ChatCompletionStream(context.Background(), litellm.ChatCompletionRequest{
+		Model:    "test",
+		Messages: []litellm.ChatMessage{{Role: "user", Content: "hi"}},
+	}, nil)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
 func TestChatCompletionAuthHeader(t *testing.T) {
 	var gotAuth string
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -223,3 +326,40 @@ func TestChatCompletionAuthHeader(t *testing.T) {
 		t.Errorf("expected 'Bearer sk-secret', got %q", gotAuth)
 	}
 }
+
+func TestEmbeddings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		// Out of order on purpose: vectors are placed by index.
+		_, _ = w.Write([]byte(`{"data":[{"index":1,"embedding":[0.3,0.4]},{"index":0,"embedding":[0.1,0.2]}]}`))
+	}))
+	defer srv.Close()
+
+	vecs, err := litellm.Embedder{Client: litellm.NewClient(srv.URL, "")}.Embed(context.Background(), "text-embedding-3-small", []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(vecs) != 2 || vecs[0][0] != 0.1 || vecs[1][0] != 0.3 {
+		t.Fatalf("unexpected vectors: %v", vecs)
+	}
+}
+
+func TestRerank(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/rerank" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"results":[{"index":1,"relevance_score":0.8},{"index":0,"relevance_score":0.1}]}`))
+	}))
+	defer srv.Close()
+
+	scores, err := litellm.Reranker{Client: litellm.NewClient(srv.URL, "")}.Rerank(context.Background(), "cohere/rerank-english-v3.0", "q", []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Rerank failed: %v", err)
+	}
+	if len(scores) != 2 || scores[0] != 0.1 || scores[1] != 0.8 {
+		t.Fatalf("unexpected scores: %v", scores)
+	}
+}
@@ -0,0 +1,55 @@
+package litellm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Embeddings sends texts to the LiteLLM Proxy's OpenAI-compatible
+// /v1/embeddings endpoint and returns one vector per text, in order.
+func (c *Client) Embeddings(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]any{"model": model, "input": texts})
+	if err != nil {
+		return nil, fmt.Errorf("marshal embeddings request: %w", err)
+	}
+	data, err := c.doRequest(ctx, http.MethodPost, "/v1/embeddings", body)
+	if err != nil {
+		return nil, fmt.Errorf("embeddings: %w", err)
+	}
+
+	var raw struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("unmarshal embeddings response: %w", err)
+	}
+	if len(raw.Data) != len(texts) {
+		return nil, fmt.Errorf("embeddings: got %d vectors for %d inputs", len(raw.Data), len(texts))
+	}
+	out := make([][]float32, len(texts))
+	for _, d := range raw.Data {
+		if d.Index < 0 || d.Index >= len(out) {
+			return nil, fmt.Errorf("embeddings: index %d out of range", d.Index)
+		}
+		out[d.Index] = d.Embedding
+	}
+	return out, nil
+}
+
+// Embedder exposes the client as an embedding.Provider named "litellm".
+type Embedder struct {
+	*Client
+}
+
+// Name returns "litellm".
+func (e Embedder) Name() string { return "litellm" }
+
+// Embed calls Embeddings.
+func (e Embedder) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	return e.Embeddings(ctx, model, texts)
+}
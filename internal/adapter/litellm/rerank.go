@@ -0,0 +1,56 @@
+package litellm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Rerank sends documents to the LiteLLM Proxy's Cohere-compatible
+// /v1/rerank endpoint and returns one relevance score per document, in
+// order.
+func (c *Client) Rerank(ctx context.Context, model, query string, documents []string) ([]float64, error) {
+	body, err := json.Marshal(map[string]any{
+		"model":     model,
+		"query":     query,
+		"documents": documents,
+		"top_n":     len(documents),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal rerank request: %w", err)
+	}
+	data, err := c.doRequest(ctx, http.MethodPost, "/v1/rerank", body)
+	if err != nil {
+		return nil, fmt.Errorf("rerank: %w", err)
+	}
+
+	var raw struct {
+		Results []struct {
+			Index          int     `json:"index"`
+			RelevanceScore float64 `json:"relevance_score"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("unmarshal rerank response: %w", err)
+	}
+	if len(raw.Results) != len(documents) {
+		return nil, fmt.Errorf("rerank: got %d scores for %d documents", len(raw.Results), len(documents))
+	}
+	out := make([]float64, len(documents))
+	for _, r := range raw.Results {
+		if r.Index < 0 || r.Index >= len(out) {
+			return nil, fmt.Errorf("rerank: index %d out of range", r.Index)
+		}
+		out[r.Index] = r.RelevanceScore
+	}
+	return out, nil
+}
+
+// Reranker exposes the client as a reranker.Provider named "litellm".
+type Reranker struct {
+	*Client
+}
+
+// Name returns "litellm".
+func (r Reranker) Name() string { return "litellm" }
@@ -0,0 +1,132 @@
+package litellm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// streamRequest is a chat completion request that asks for server-sent
+// events and the token usage in the last of them.
+type streamRequest struct {
+	*ChatCompletionRequest
+	Stream        bool          `json:"stream"`
+	StreamOptions streamOptions `json:"stream_options"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// ChatCompletionStream sends a chat completion request with streaming
+// enabled and calls onDelta with each piece of content as the proxy
+// produces it. It returns the full response, with the token usage the
+// proxy reports in its last chunk, once the stream ends. Streamed
+// completions bypass the response cache and the client timeout; ctx
+// bounds the call.
+func (c *Client) ChatCompletionStream(ctx context.Context, req ChatCompletionRequest, onDelta func(string)) (*ChatCompletionResponse, error) {
+	body, err := json.Marshal(streamRequest{ChatCompletionRequest: &req, Stream: true, StreamOptions: streamOptions{IncludeUsage: true}})
+	if err != nil {
+		return nil, fmt.Errorf("marshal completion request: %w", err)
+	}
+
+	var resp *ChatCompletionResponse
+	call := func() error {
+		var err error
+		resp, err = c.stream(ctx, body, onDelta)
+		return err
+	}
+	if c.breaker != nil {
+		err = c.breaker.Execute(call)
+	} else {
+		err = call()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("chat completion stream: %w", err)
+	}
+	if c.observe != nil {
+		c.observe(&req, resp)
+	}
+	return resp, nil
+}
+
+// stream posts a streaming completion request and reads its server-sent
+// events up to the [DONE] marker.
+func (c *Client) stream(ctx context.Context, body []byte, onDelta func(string)) (*ChatCompletionResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	if c.masterKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.masterKey)
+	}
+
+	// The reply may take longer than the client timeout to complete.
+	hc := &http.Client{Transport: c.httpClient.Transport}
+	httpResp, err := hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http request: %w", err)
+	}
+	defer func() { _ = httpResp.Body.Close() }()
+
+	if httpResp.StatusCode >= 400 {
+		data, _ := io.ReadAll(io.LimitReader(httpResp.Body, 64<<10))
+		return nil, fmt.Errorf("litellm API error %d: %s", httpResp.StatusCode, string(data))
+	}
+
+	resp := &ChatCompletionResponse{}
+	var content strings.Builder
+	sc := bufio.NewScanner(httpResp.Body)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		data, ok := strings.CutPrefix(sc.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Usage *struct {
+				PromptTokens     int `json:"prompt_tokens"`
+				CompletionTokens int `json:"completion_tokens"`
+			} `json:"usage"`
+			Model string `json:"model"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("unmarshal completion chunk: %w", err)
+		}
+		if chunk.Model != "" {
+			resp.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			resp.TokensIn = chunk.Usage.PromptTokens
+			resp.TokensOut = chunk.Usage.CompletionTokens
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			delta := chunk.Choices[0].Delta.Content
+			content.WriteString(delta)
+			if onDelta != nil {
+				onDelta(delta)
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read completion stream: %w", err)
+	}
+	resp.Content = content.String()
+	return resp, nil
+}
@@ -1,29 +1,550 @@
-// Package lsp provides a stub for Language Server Protocol client integration.
-// This will be implemented in Phase 2 to provide code intelligence to AI agents.
+// Package lsp is a Language Server Protocol client. It starts a language
+// server (gopls, pyright, typescript-language-server, rust-analyzer, ...)
+// for a workspace root and talks JSON-RPC to it over stdio. Files are
+// opened on the server with their content on disk and synchronized before
+// every request; edits the server computes are returned, never applied.
 package lsp
 
-import "log/slog"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
-// Client is a placeholder for an LSP client that connects to language servers
-// (gopls, pyright, typescript-language-server, etc.) for diagnostics and completions.
+	cflsp "github.com/Strob0t/CodeForge/internal/domain/lsp"
+	"github.com/Strob0t/CodeForge/internal/port/langserver"
+)
+
+// shutdownTimeout bounds the shutdown of a server before it is killed.
+const shutdownTimeout = 5 * time.Second
+
+// languageIDs maps file extensions to LSP language identifiers. Other
+// files use their extension.
+var languageIDs = map[string]string{
+	".go":    "go",
+	".py":    "python",
+	".ts":    "typescript",
+	".tsx":   "typescriptreact",
+	".js":    "javascript",
+	".jsx":   "javascriptreact",
+	".rs":    "rust",
+	".java":  "java",
+	".kt":    "kotlin",
+	".swift": "swift",
+	".rb":    "ruby",
+	".c":     "c",
+	".h":     "c",
+	".cpp":   "cpp",
+	".cs":    "csharp",
+	".tf":    "terraform",
+}
+
+// Client is a running language server for one workspace root.
 type Client struct {
-	language string
+	root    string
+	rootURI string
+	cmd     *exec.Cmd
+	conn    *conn
+	exited  chan struct{}
+
+	resolveActions bool // Server resolves the edits of code actions lazily
+
+	mu        sync.Mutex
+	docs      map[string]*document       // Open documents by URI
+	diags     map[string]json.RawMessage // Last published diagnostics by URI
+	published map[string]int             // Number of diagnostics publishes by URI
+	update    chan struct{}              // Closed and replaced on every publish
 }
 
-// NewClient creates a new LSP client stub for the given language.
-func NewClient(language string) *Client {
-	return &Client{language: language}
+type document struct {
+	version int
+	text    string
 }
 
-// Start is a no-op stub. In Phase 2, this will start the LSP server process
-// and establish JSON-RPC communication.
-func (c *Client) Start() error {
-	slog.Info("lsp client stub: start called", "language", c.language)
-	return nil
+var _ langserver.Server = (*Client)(nil)
+
+// Start starts command in root and initializes it for the workspace. ctx
+// bounds the initialization only; the server runs until Close.
+func Start(ctx context.Context, command []string, root string) (langserver.Server, error) {
+	if len(command) == 0 {
+		return nil, errors.New("lsp: empty server command")
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		root:      root,
+		rootURI:   fileURI(root),
+		exited:    make(chan struct{}),
+		docs:      make(map[string]*document),
+		diags:     make(map[string]json.RawMessage),
+		published: make(map[string]int),
+		update:    make(chan struct{}),
+	}
+	c.cmd = exec.Command(command[0], command[1:]...) //nolint:gosec // Command comes from the server config
+	c.cmd.Dir = root
+	stdin, err := c.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	// Reading through a pipe of our own lets Wait finish copying the
+	// server's output before the reader sees EOF.
+	pr, pw := io.Pipe()
+	c.cmd.Stdout = pw
+	if err := c.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("lsp: start %s: %w", command[0], err)
+	}
+	go func() {
+		err := c.cmd.Wait()
+		if err == nil {
+			err = io.EOF
+		}
+		_ = pw.CloseWithError(err)
+		close(c.exited)
+	}()
+	c.conn = newConn(pr, stdin, c.handle)
+
+	if err := c.initialize(ctx); err != nil {
+		c.kill()
+		return nil, fmt.Errorf("lsp: initialize %s: %w", command[0], err)
+	}
+	return c, nil
+}
+
+func (c *Client) initialize(ctx context.Context) error {
+	params := map[string]any{
+		"processId":  os.Getpid(),
+		"clientInfo": map[string]string{"name": "codeforge"},
+		"rootUri":    c.rootURI,
+		"rootPath":   c.root,
+		"workspaceFolders": []map[string]string{
+			{"uri": c.rootURI, "name": filepath.Base(c.root)},
+		},
+		"capabilities": map[string]any{
+			"general": map[string]any{"positionEncodings": []string{"utf-16"}},
+			"workspace": map[string]any{
+				"workspaceFolders": true,
+				"configuration":    true,
+				"workspaceEdit":    map[string]any{"documentChanges": true},
+			},
+			"textDocument": map[string]any{
+				"synchronization": map[string]any{},
+				"definition":      map[string]any{"linkSupport": true},
+				"references":      map[string]any{},
+				"hover":           map[string]any{"contentFormat": []string{"markdown", "plaintext"}},
+				"documentSymbol":  map[string]any{"hierarchicalDocumentSymbolSupport": true},
+				"rename":          map[string]any{},
+				"formatting":      map[string]any{},
+				"codeAction": map[string]any{
+					"codeActionLiteralSupport": map[string]any{
+						"codeActionKind": map[string]any{"valueSet": []string{
+							"", "quickfix", "refactor", "refactor.extract", "refactor.inline",
+							"refactor.rewrite", "source", "source.organizeImports", "source.fixAll",
+						}},
+					},
+					"isPreferredSupport": true,
+					"dataSupport":        true,
+					"resolveSupport":     map[string]any{"properties": []string{"edit"}},
+				},
+				"publishDiagnostics": map[string]any{},
+			},
+		},
+	}
+	var result struct {
+		Capabilities struct {
+			CodeActionProvider json.RawMessage `json:"codeActionProvider"`
+		} `json:"capabilities"`
+	}
+	if err := c.conn.call(ctx, "initialize", params, &result); err != nil {
+		return err
+	}
+	var actions struct {
+		ResolveProvider bool `json:"resolveProvider"`
+	}
+	if json.Unmarshal(result.Capabilities.CodeActionProvider, &actions) == nil {
+		c.resolveActions = actions.ResolveProvider
+	}
+	return c.conn.notify("initialized", struct{}{})
 }
 
-// Stop is a no-op stub.
-func (c *Client) Stop() error {
-	slog.Info("lsp client stub: stop called", "language", c.language)
+// Close shuts the server down, killing it if it does not exit in time.
+func (c *Client) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := c.conn.call(ctx, "shutdown", nil, nil); err == nil {
+		_ = c.conn.notify("exit", nil)
+	}
+	_ = c.conn.close()
+	select {
+	case <-c.exited:
+	case <-ctx.Done():
+		c.kill()
+	}
 	return nil
 }
+
+func (c *Client) kill() {
+	if c.cmd.Process != nil {
+		_ = c.cmd.Process.Kill()
+	}
+	<-c.exited
+}
+
+// Definition returns where the symbol at pos is declared.
+func (c *Client) Definition(ctx context.Context, path string, pos cflsp.Position) ([]cflsp.Location, error) {
+	return c.locations(ctx, "textDocument/definition", path, pos, nil)
+}
+
+// References returns the uses of the symbol at pos, with its declaration.
+func (c *Client) References(ctx context.Context, path string, pos cflsp.Position) ([]cflsp.Location, error) {
+	return c.locations(ctx, "textDocument/references", path, pos, map[string]bool{"includeDeclaration": true})
+}
+
+func (c *Client) locations(ctx context.Context, method, path string, pos cflsp.Position, refContext any) ([]cflsp.Location, error) {
+	uri, err := c.sync(path)
+	if err != nil {
+		return nil, err
+	}
+	params := struct {
+		textDocumentPositionParams
+		Context any `json:"context,omitempty"`
+	}{textDocumentPositionParams{textDocumentIdentifier{uri}, toPosition(pos)}, refContext}
+	var raw json.RawMessage
+	if err := c.conn.call(ctx, method, params, &raw); err != nil {
+		return nil, err
+	}
+	links, err := decodeLocations(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	locs := make([]cflsp.Location, 0, len(links))
+	for _, l := range links {
+		if l.TargetURI != "" && l.TargetSelectionRange != nil {
+			l.URI, l.Range = l.TargetURI, *l.TargetSelectionRange
+		}
+		locs = append(locs, cflsp.Location{Path: c.relPath(l.URI), Range: fromRange(l.Range)})
+	}
+	return locs, nil
+}
+
+// Hover returns the documentation of the symbol at pos, or nil.
+func (c *Client) Hover(ctx context.Context, path string, pos cflsp.Position) (*cflsp.Hover, error) {
+	uri, err := c.sync(path)
+	if err != nil {
+		return nil, err
+	}
+	var h *hover
+	if err := c.conn.call(ctx, "textDocument/hover", textDocumentPositionParams{textDocumentIdentifier{uri}, toPosition(pos)}, &h); err != nil {
+		return nil, err
+	}
+	if h == nil {
+		return nil, nil
+	}
+	out := &cflsp.Hover{Contents: hoverText(h.Contents)}
+	if h.Range != nil {
+		r := fromRange(*h.Range)
+		out.Range = &r
+	}
+	return out, nil
+}
+
+// Symbols returns the declarations of a file, nested ones with their
+// container.
+func (c *Client) Symbols(ctx context.Context, path string) ([]cflsp.Symbol, error) {
+	uri, err := c.sync(path)
+	if err != nil {
+		return nil, err
+	}
+	var syms []documentSymbol
+	if err := c.conn.call(ctx, "textDocument/documentSymbol", map[string]any{"textDocument": textDocumentIdentifier{uri}}, &syms); err != nil {
+		return nil, err
+	}
+	var out []cflsp.Symbol
+	var walk func(syms []documentSymbol, container string)
+	walk = func(syms []documentSymbol, container string) {
+		for i := range syms {
+			s := &syms[i]
+			sym := cflsp.Symbol{Name: s.Name, Kind: symbolKind(s.Kind), Container: container, Range: fromRange(s.Range)}
+			if s.Location != nil {
+				sym.Container, sym.Range = s.ContainerName, fromRange(s.Location.Range)
+			}
+			out = append(out, sym)
+			walk(s.Children, s.Name)
+		}
+	}
+	walk(syms, "")
+	return out, nil
+}
+
+// Rename returns the edit renaming the symbol at pos to newName.
+func (c *Client) Rename(ctx context.Context, path string, pos cflsp.Position, newName string) (*cflsp.WorkspaceEdit, error) {
+	uri, err := c.sync(path)
+	if err != nil {
+		return nil, err
+	}
+	params := struct {
+		textDocumentPositionParams
+		NewName string `json:"newName"`
+	}{textDocumentPositionParams{textDocumentIdentifier{uri}, toPosition(pos)}, newName}
+	var edit *workspaceEdit
+	if err := c.conn.call(ctx, "textDocument/rename", params, &edit); err != nil {
+		return nil, err
+	}
+	return c.workspaceEdit(edit)
+}
+
+// CodeActions returns the code actions for rng with their edits, given
+// the diagnostics the server published for it.
+func (c *Client) CodeActions(ctx context.Context, path string, rng cflsp.Range) ([]cflsp.CodeAction, error) {
+	uri, err := c.sync(path)
+	if err != nil {
+		return nil, err
+	}
+	params := map[string]any{
+		"textDocument": textDocumentIdentifier{uri},
+		"range":        toRange(rng),
+		"context":      map[string]any{"diagnostics": c.diagnosticsIn(uri, toRange(rng))},
+	}
+	var actions []codeAction
+	if err := c.conn.call(ctx, "textDocument/codeAction", params, &actions); err != nil {
+		return nil, err
+	}
+	out := make([]cflsp.CodeAction, 0, len(actions))
+	for i := range actions {
+		a := &actions[i]
+		if a.Edit == nil && c.resolveActions && len(a.Data) > 0 {
+			var resolved codeAction
+			if err := c.conn.call(ctx, "codeAction/resolve", a, &resolved); err != nil {
+				return nil, err
+			}
+			a = &resolved
+		}
+		action := cflsp.CodeAction{Title: a.Title, Kind: a.Kind, Preferred: a.IsPreferred}
+		if a.Edit != nil {
+			edit, err := c.workspaceEdit(a.Edit)
+			if err != nil {
+				return nil, err
+			}
+			action.Edit = edit
+		}
+		out = append(out, action)
+	}
+	return out, nil
+}
+
+// Format returns the edits formatting a file.
+func (c *Client) Format(ctx context.Context, path string) ([]cflsp.TextEdit, error) {
+	uri, err := c.sync(path)
+	if err != nil {
+		return nil, err
+	}
+	params := map[string]any{
+		"textDocument": textDocumentIdentifier{uri},
+		"options":      map[string]any{"tabSize": 4, "insertSpaces": true},
+	}
+	var edits []textEdit
+	if err := c.conn.call(ctx, "textDocument/formatting", params, &edits); err != nil {
+		return nil, err
+	}
+	return fromEdits(edits), nil
+}
+
+// workspaceEdit converts an edit to the domain's, merging the edits of a
+// file. File operations (create, rename, delete) are not supported.
+func (c *Client) workspaceEdit(e *workspaceEdit) (*cflsp.WorkspaceEdit, error) {
+	out := &cflsp.WorkspaceEdit{}
+	if e == nil {
+		return out, nil
+	}
+	index := make(map[string]int)
+	add := func(uri string, edits []textEdit) {
+		p := c.relPath(uri)
+		i, ok := index[p]
+		if !ok {
+			i = len(out.Files)
+			index[p] = i
+			out.Files = append(out.Files, cflsp.FileEdit{Path: p})
+		}
+		out.Files[i].Edits = append(out.Files[i].Edits, fromEdits(edits)...)
+	}
+	for _, dc := range e.DocumentChanges {
+		if dc.Kind != "" {
+			return nil, fmt.Errorf("lsp: workspace edit with unsupported %s file operation", dc.Kind)
+		}
+		add(dc.TextDocument.URI, dc.Edits)
+	}
+	if len(e.DocumentChanges) == 0 {
+		for uri, edits := range e.Changes {
+			add(uri, edits)
+		}
+	}
+	return out, nil
+}
+
+// sync opens path on the server with its content on disk, or sends the
+// new content if it changed since, and returns its URI.
+func (c *Client) sync(path string) (string, error) {
+	abs := filepath.Join(c.root, filepath.FromSlash(path))
+	content, err := os.ReadFile(abs)
+	if err != nil {
+		return "", err
+	}
+	uri, _, err := c.syncText(abs, string(content))
+	return uri, err
+}
+
+// syncText opens abs on the server with text, or sends text if the
+// document changed. It returns the URI and the number of diagnostics
+// publishes after which the server has reported on text.
+func (c *Client) syncText(abs, text string) (string, int, error) {
+	uri := fileURI(abs)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	doc := c.docs[uri]
+	switch {
+	case doc == nil:
+		c.docs[uri] = &document{version: 1, text: text}
+		return uri, c.published[uri] + 1, c.conn.notify("textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{"uri": uri, "languageId": languageID(abs), "version": 1, "text": text},
+		})
+	case doc.text != text:
+		doc.version++
+		doc.text = text
+		return uri, c.published[uri] + 1, c.conn.notify("textDocument/didChange", map[string]any{
+			"textDocument":   map[string]any{"uri": uri, "version": doc.version},
+			"contentChanges": []map[string]string{{"text": text}},
+		})
+	}
+	return uri, max(c.published[uri], 1), nil
+}
+
+// Diagnostics sends content, or the file on disk when nil, as the text of
+// path and waits for the server to publish its diagnostics.
+func (c *Client) Diagnostics(ctx context.Context, path string, content []byte) ([]cflsp.Diagnostic, error) {
+	abs := filepath.Join(c.root, filepath.FromSlash(path))
+	if content == nil {
+		var err error
+		if content, err = os.ReadFile(abs); err != nil {
+			return nil, err
+		}
+	}
+	uri, want, err := c.syncText(abs, string(content))
+	if err != nil {
+		return nil, err
+	}
+	for {
+		c.mu.Lock()
+		n, raw, update := c.published[uri], c.diags[uri], c.update
+		c.mu.Unlock()
+		if n >= want {
+			return decodeDiagnostics(path, raw), nil
+		}
+		select {
+		case <-update:
+		case <-c.conn.done:
+			return nil, c.conn.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// diagnosticsIn returns the diagnostics last published for uri that
+// overlap rng, as sent by the server.
+func (c *Client) diagnosticsIn(uri string, rng lspRange) []json.RawMessage {
+	c.mu.Lock()
+	raw := c.diags[uri]
+	c.mu.Unlock()
+	var all []json.RawMessage
+	_ = json.Unmarshal(raw, &all)
+	out := []json.RawMessage{}
+	for _, d := range all {
+		var v struct {
+			Range lspRange `json:"range"`
+		}
+		if json.Unmarshal(d, &v) == nil && !before(v.Range.End, rng.Start) && !before(rng.End, v.Range.Start) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func before(p, q position) bool {
+	return p.Line < q.Line || p.Line == q.Line && p.Character < q.Character
+}
+
+// handle answers the requests of the server and records the diagnostics it
+// publishes. The client has no settings and applies no edits itself.
+func (c *Client) handle(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "textDocument/publishDiagnostics":
+		var p struct {
+			URI         string          `json:"uri"`
+			Version     *int            `json:"version"`
+			Diagnostics json.RawMessage `json:"diagnostics"`
+		}
+		if json.Unmarshal(params, &p) != nil {
+			return nil, nil
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		// Diagnostics of an older version of the document are stale.
+		if doc := c.docs[p.URI]; doc != nil && p.Version != nil && *p.Version < doc.version {
+			return nil, nil
+		}
+		c.diags[p.URI] = p.Diagnostics
+		c.published[p.URI]++
+		close(c.update)
+		c.update = make(chan struct{})
+		return nil, nil
+	case "workspace/configuration":
+		var p struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		_ = json.Unmarshal(params, &p)
+		return make([]any, len(p.Items)), nil
+	case "workspace/workspaceFolders":
+		return []map[string]string{{"uri": c.rootURI, "name": filepath.Base(c.root)}}, nil
+	case "workspace/applyEdit":
+		return map[string]any{"applied": false, "failureReason": "the client applies no server edits"}, nil
+	case "window/workDoneProgress/create", "window/showMessageRequest",
+		"client/registerCapability", "client/unregisterCapability":
+		return nil, nil
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + method}
+}
+
+// relPath returns the path of a file URI relative to the root, or the
+// absolute path of files outside it.
+func (c *Client) relPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	p := filepath.FromSlash(u.Path)
+	if rel, err := filepath.Rel(c.root, p); err == nil && filepath.IsLocal(rel) {
+		return filepath.ToSlash(rel)
+	}
+	return p
+}
+
+func fileURI(abs string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+}
+
+func languageID(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if id, ok := languageIDs[ext]; ok {
+		return id
+	}
+	return strings.TrimPrefix(ext, ".")
+}
@@ -0,0 +1,326 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	cflsp "github.com/Strob0t/CodeForge/internal/domain/lsp"
+	"github.com/Strob0t/CodeForge/internal/port/langserver"
+)
+
+// The test binary doubles as a fake language server when fakeServerEnv is
+// set, so the client is tested against a real process speaking the
+// protocol over stdio.
+const fakeServerEnv = "CODEFORGE_FAKE_LSP"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeServerEnv) == "1" {
+		fakeServer()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeServer answers requests about a file with fixed results. Opening or
+// changing a file publishes an error diagnostic on its first line, and one
+// more on every line containing "bad".
+func fakeServer() {
+	var (
+		mu    sync.Mutex
+		texts = make(map[string]string)
+		c     *conn
+		exit  = make(chan struct{})
+	)
+	publish := func(uri, text string, version int) {
+		diags := []map[string]any{{
+			"range":    lspRange{End: position{Character: 4}},
+			"severity": 1,
+			"code":     7,
+			"message":  "undefined: total",
+		}}
+		for i, line := range strings.Split(text, "\n") {
+			if strings.Contains(line, "bad") {
+				diags = append(diags, map[string]any{
+					"range":   lspRange{Start: position{Line: i}, End: position{Line: i, Character: len(line)}},
+					"code":    "E1",
+					"source":  "fake",
+					"message": "bad line",
+				})
+			}
+		}
+		_ = c.notify("textDocument/publishDiagnostics", map[string]any{"uri": uri, "version": version, "diagnostics": diags})
+	}
+	uriOf := func(params json.RawMessage) string {
+		var p struct {
+			TextDocument textDocumentIdentifier `json:"textDocument"`
+		}
+		_ = json.Unmarshal(params, &p)
+		return p.TextDocument.URI
+	}
+	edit := func(uri string) workspaceEdit {
+		return workspaceEdit{DocumentChanges: []documentChange{{
+			TextDocument: textDocumentIdentifier{uri},
+			Edits:        []textEdit{{Range: lspRange{Start: position{0, 5}, End: position{0, 10}}, NewText: "sum"}},
+		}}}
+	}
+	c = newConn(os.Stdin, os.Stdout, func(method string, params json.RawMessage) (any, error) {
+		switch method {
+		case "initialize":
+			return map[string]any{"capabilities": map[string]any{"codeActionProvider": map[string]bool{"resolveProvider": true}}}, nil
+		case "initialized":
+			go func() {
+				var cfg []any
+				_ = c.call(context.Background(), "workspace/configuration", map[string]any{"items": []any{map[string]string{"section": "fake"}}}, &cfg)
+			}()
+		case "textDocument/didOpen", "textDocument/didChange":
+			var p struct {
+				TextDocument struct {
+					URI     string `json:"uri"`
+					Version int    `json:"version"`
+					Text    string `json:"text"`
+				} `json:"textDocument"`
+				ContentChanges []struct {
+					Text string `json:"text"`
+				} `json:"contentChanges"`
+			}
+			_ = json.Unmarshal(params, &p)
+			text := p.TextDocument.Text
+			if len(p.ContentChanges) > 0 {
+				text = p.ContentChanges[0].Text
+			}
+			mu.Lock()
+			texts[p.TextDocument.URI] = text
+			mu.Unlock()
+			publish(p.TextDocument.URI, text, p.TextDocument.Version)
+		case "textDocument/definition":
+			return []map[string]any{{
+				"targetUri":            uriOf(params),
+				"targetRange":          lspRange{End: position{2, 1}},
+				"targetSelectionRange": lspRange{Start: position{0, 5}, End: position{0, 10}},
+			}}, nil
+		case "textDocument/references":
+			return []map[string]any{
+				{"uri": uriOf(params), "range": lspRange{Start: position{0, 5}, End: position{0, 10}}},
+				{"uri": "file:///usr/lib/go/src/fmt/print.go", "range": lspRange{Start: position{9, 0}, End: position{9, 5}}},
+			}, nil
+		case "textDocument/hover":
+			mu.Lock()
+			text := texts[uriOf(params)]
+			mu.Unlock()
+			return map[string]any{"contents": map[string]string{"kind": "markdown", "value": "```go\n" + strings.TrimSpace(text) + "\n```"}}, nil
+		case "textDocument/documentSymbol":
+			return []map[string]any{{
+				"name": "Invoice", "kind": 23, "range": lspRange{End: position{3, 1}}, "selectionRange": lspRange{},
+				"children": []map[string]any{{"name": "total", "kind": 6, "range": lspRange{Start: position{1, 1}, End: position{1, 9}}, "selectionRange": lspRange{}}},
+			}}, nil
+		case "textDocument/rename":
+			return edit(uriOf(params)), nil
+		case "textDocument/codeAction":
+			var p struct {
+				Context struct {
+					Diagnostics []json.RawMessage `json:"diagnostics"`
+				} `json:"context"`
+			}
+			_ = json.Unmarshal(params, &p)
+			if len(p.Context.Diagnostics) == 0 {
+				return []any{}, nil
+			}
+			return []any{
+				map[string]any{"title": "Rename to sum", "kind": "quickfix", "isPreferred": true, "data": uriOf(params)},
+				map[string]any{"title": "Run generate", "command": "generate"},
+			}, nil
+		case "codeAction/resolve":
+			var a codeAction
+			_ = json.Unmarshal(params, &a)
+			var uri string
+			_ = json.Unmarshal(a.Data, &uri)
+			e := edit(uri)
+			a.Edit = &e
+			return a, nil
+		case "textDocument/formatting":
+			return []textEdit{{Range: lspRange{Start: position{0, 0}, End: position{0, 0}}, NewText: "// Formatted.\n"}}, nil
+		case "shutdown":
+			return nil, nil
+		case "exit":
+			close(exit)
+		}
+		return nil, nil
+	})
+	select {
+	case <-exit:
+	case <-c.done:
+	}
+}
+
+func startFake(t *testing.T) (langserver.Server, string) {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "invoice.go"), []byte("func total() {}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(fakeServerEnv, "1")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	srv, err := Start(ctx, []string{os.Args[0]}, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = srv.Close() })
+	return srv, root
+}
+
+func TestClient(t *testing.T) {
+	srv, root := startFake(t)
+	ctx := context.Background()
+	pos := cflsp.Position{Line: 1, Column: 6}
+	word := cflsp.Range{Start: cflsp.Position{Line: 1, Column: 6}, End: cflsp.Position{Line: 1, Column: 11}}
+
+	defs, err := srv.Definition(ctx, "invoice.go", pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(defs) != 1 || defs[0].Path != "invoice.go" || defs[0].Range != word {
+		t.Errorf("Definition = %+v", defs)
+	}
+
+	refs, err := srv.References(ctx, "invoice.go", pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 2 || refs[0].Path != "invoice.go" || refs[1].Path != filepath.FromSlash("/usr/lib/go/src/fmt/print.go") {
+		t.Errorf("References = %+v", refs)
+	}
+
+	h, err := srv.Hover(ctx, "invoice.go", pos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h == nil || h.Contents != "```go\nfunc total() {}\n```" {
+		t.Errorf("Hover = %+v", h)
+	}
+
+	syms, err := srv.Symbols(ctx, "invoice.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(syms) != 2 || syms[0].Kind != "struct" || syms[1].Name != "total" || syms[1].Kind != "method" || syms[1].Container != "Invoice" {
+		t.Errorf("Symbols = %+v", syms)
+	}
+
+	edit, err := srv.Rename(ctx, "invoice.go", pos, "sum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edit.Files) != 1 || edit.Files[0].Path != "invoice.go" || edit.Files[0].Edits[0].Range != word || edit.Files[0].Edits[0].NewText != "sum" {
+		t.Errorf("Rename = %+v", edit)
+	}
+
+	// Changes on disk reach the server before the next request.
+	if err := os.WriteFile(filepath.Join(root, "invoice.go"), []byte("func sum() {}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if h, err = srv.Hover(ctx, "invoice.go", pos); err != nil || h == nil || !strings.Contains(h.Contents, "func sum()") {
+		t.Errorf("Hover after change = %+v, %v", h, err)
+	}
+
+	formatting, err := srv.Format(ctx, "invoice.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(formatting) != 1 || formatting[0].NewText != "// Formatted.\n" || formatting[0].Range.Start != (cflsp.Position{Line: 1, Column: 1}) {
+		t.Errorf("Format = %+v", formatting)
+	}
+}
+
+func TestClientCodeActions(t *testing.T) {
+	srv, _ := startFake(t)
+	ctx := context.Background()
+	line := cflsp.Range{Start: cflsp.Position{Line: 1, Column: 1}, End: cflsp.Position{Line: 2, Column: 1}}
+
+	// Quick fixes need the diagnostics of the range, which the server
+	// publishes after opening the file.
+	var actions []cflsp.CodeAction
+	deadline := time.Now().Add(5 * time.Second)
+	for len(actions) == 0 && time.Now().Before(deadline) {
+		var err error
+		if actions, err = srv.CodeActions(ctx, "invoice.go", line); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(actions) != 2 {
+		t.Fatalf("CodeActions = %+v", actions)
+	}
+	fix := actions[0]
+	if fix.Title != "Rename to sum" || !fix.Preferred || fix.Edit == nil || len(fix.Edit.Files) != 1 || fix.Edit.Files[0].Path != "invoice.go" {
+		t.Errorf("resolved action = %+v", fix)
+	}
+	if actions[1].Title != "Run generate" || actions[1].Edit != nil {
+		t.Errorf("command action = %+v", actions[1])
+	}
+}
+
+func TestClientDiagnostics(t *testing.T) {
+	srv, _ := startFake(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	diags, err := srv.Diagnostics(ctx, "invoice.go", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := cflsp.Diagnostic{
+		Path:     "invoice.go",
+		Range:    cflsp.Range{Start: cflsp.Position{Line: 1, Column: 1}, End: cflsp.Position{Line: 1, Column: 5}},
+		Severity: cflsp.SeverityError,
+		Code:     "7",
+		Message:  "undefined: total",
+	}
+	if len(diags) != 1 || diags[0] != want {
+		t.Fatalf("Diagnostics = %+v", diags)
+	}
+
+	// Given content is checked in place of the file, which is synced
+	// again on the next call.
+	diags, err = srv.Diagnostics(ctx, "invoice.go", []byte("func total() {}\nbad()\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diags) != 2 || diags[1].Range.Start.Line != 2 || diags[1].Code != "E1" || diags[1].Source != "fake" || diags[1].Severity != cflsp.SeverityError {
+		t.Fatalf("Diagnostics of content = %+v", diags)
+	}
+	if diags, err = srv.Diagnostics(ctx, "invoice.go", nil); err != nil || len(diags) != 1 {
+		t.Fatalf("Diagnostics of the file = %+v, %v", diags, err)
+	}
+
+	// Unchanged content reuses the last publish.
+	if diags, err = srv.Diagnostics(ctx, "invoice.go", nil); err != nil || len(diags) != 1 {
+		t.Fatalf("Diagnostics again = %+v, %v", diags, err)
+	}
+	if _, err := srv.Diagnostics(ctx, "missing.go", nil); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
+
+func TestClientClose(t *testing.T) {
+	srv, _ := startFake(t)
+	if err := srv.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srv.Symbols(context.Background(), "invoice.go"); !errors.Is(err, langserver.ErrClosed) {
+		t.Errorf("Symbols after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestStartMissingBinary(t *testing.T) {
+	if _, err := Start(context.Background(), []string{"codeforge-no-such-language-server"}, t.TempDir()); err == nil {
+		t.Fatal("expected an error")
+	}
+}
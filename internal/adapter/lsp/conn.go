@@ -0,0 +1,226 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Strob0t/CodeForge/internal/port/langserver"
+)
+
+// maxMessageBytes limits the size of a message read from a server.
+const maxMessageBytes = 64 << 20
+
+// JSON-RPC error codes.
+const (
+	codeMethodNotFound   = -32601
+	codeRequestCancelled = -32800
+)
+
+// message is a JSON-RPC 2.0 request, notification or response.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("lsp error %d: %s", e.Code, e.Message)
+}
+
+// handler answers a request or handles a notification (id nil) of the
+// other side. Its result is ignored for notifications.
+type handler func(method string, params json.RawMessage) (any, error)
+
+// conn is a JSON-RPC 2.0 connection with the base protocol framing of
+// LSP: every message is preceded by a Content-Length header.
+type conn struct {
+	w      io.WriteCloser
+	handle handler
+
+	wmu sync.Mutex // Serializes writes
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan *message
+	err     error // Set when reading stopped
+	done    chan struct{}
+}
+
+// newConn starts reading messages from r. handle is called on the reading
+// goroutine for every request and notification.
+func newConn(r io.Reader, w io.WriteCloser, handle handler) *conn {
+	c := &conn{w: w, handle: handle, pending: make(map[int64]chan *message), done: make(chan struct{})}
+	go c.read(bufio.NewReader(r))
+	return c
+}
+
+// call sends a request and decodes its result into result, if not nil.
+// When ctx ends first, the request is cancelled on the server.
+func (c *conn) call(ctx context.Context, method string, params, result any) error {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan *message, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.write(&message{ID: json.RawMessage(strconv.FormatInt(id, 10)), Method: method}, params); err != nil {
+		return err
+	}
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("%s: decode result: %w", method, err)
+		}
+		return nil
+	case <-c.done:
+		return c.err
+	case <-ctx.Done():
+		_ = c.notify("$/cancelRequest", map[string]int64{"id": id})
+		return ctx.Err()
+	}
+}
+
+// notify sends a notification.
+func (c *conn) notify(method string, params any) error {
+	return c.write(&message{Method: method}, params)
+}
+
+func (c *conn) write(m *message, params any) error {
+	m.JSONRPC = "2.0"
+	if params != nil {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		m.Params = b
+	}
+	return c.send(m)
+}
+
+func (c *conn) send(m *message) error {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		return fmt.Errorf("%w: %w", langserver.ErrClosed, err)
+	}
+	return nil
+}
+
+// close closes the writing side; reading stops when the other side exits.
+func (c *conn) close() error {
+	return c.w.Close()
+}
+
+// read dispatches messages until r fails, then fails the pending calls.
+func (c *conn) read(r *bufio.Reader) {
+	var err error
+	for {
+		var m *message
+		if m, err = readMessage(r); err != nil {
+			break
+		}
+		switch {
+		case m.Method == "":
+			c.resolve(m)
+		case len(m.ID) == 0:
+			_, _ = c.handle(m.Method, m.Params)
+		default:
+			c.reply(m)
+		}
+	}
+	c.mu.Lock()
+	c.err = fmt.Errorf("%w: %w", langserver.ErrClosed, err)
+	c.mu.Unlock()
+	close(c.done)
+}
+
+func (c *conn) resolve(m *message) {
+	id, err := strconv.ParseInt(string(m.ID), 10, 64)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	ch := c.pending[id]
+	c.mu.Unlock()
+	if ch != nil {
+		ch <- m
+	}
+}
+
+// reply answers a request of the other side with the result of handle.
+func (c *conn) reply(m *message) {
+	resp := &message{JSONRPC: "2.0", ID: m.ID}
+	result, err := c.handle(m.Method, m.Params)
+	var rerr *rpcError
+	switch {
+	case errors.As(err, &rerr):
+		resp.Error = rerr
+	case err != nil:
+		resp.Error = &rpcError{Code: codeMethodNotFound, Message: err.Error()}
+	default:
+		b, err := json.Marshal(result)
+		if err != nil {
+			resp.Error = &rpcError{Code: codeMethodNotFound, Message: err.Error()}
+			break
+		}
+		resp.Result = b
+	}
+	_ = c.send(resp)
+}
+
+// readMessage reads one framed message.
+func readMessage(r *bufio.Reader) (*message, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+	if err != nil || n < 0 || n > maxMessageBytes {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	var m message
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("decode message: %w", err)
+	}
+	return &m, nil
+}
@@ -0,0 +1,215 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	cflsp "github.com/Strob0t/CodeForge/internal/domain/lsp"
+)
+
+// The protocol types below are the subset of LSP 3.17 the client uses.
+// Positions are 0-based with UTF-16 character offsets; the domain's are
+// 1-based.
+
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type textDocumentPositionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     position               `json:"position"`
+}
+
+type textEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type workspaceEdit struct {
+	Changes         map[string][]textEdit `json:"changes,omitempty"`
+	DocumentChanges []documentChange      `json:"documentChanges,omitempty"`
+}
+
+// documentChange is a TextDocumentEdit, or a file operation when Kind is
+// set.
+type documentChange struct {
+	Kind         string                 `json:"kind,omitempty"`
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Edits        []textEdit             `json:"edits"`
+}
+
+// codeAction is a CodeAction, or a Command when Command is a string.
+type codeAction struct {
+	Title       string          `json:"title"`
+	Kind        string          `json:"kind,omitempty"`
+	IsPreferred bool            `json:"isPreferred,omitempty"`
+	Edit        *workspaceEdit  `json:"edit,omitempty"`
+	Command     json.RawMessage `json:"command,omitempty"`
+	Data        json.RawMessage `json:"data,omitempty"`
+}
+
+// locationOrLink is a Location or a LocationLink.
+type locationOrLink struct {
+	URI                  string    `json:"uri"`
+	Range                lspRange  `json:"range"`
+	TargetURI            string    `json:"targetUri"`
+	TargetSelectionRange *lspRange `json:"targetSelectionRange"`
+}
+
+// documentSymbol is a DocumentSymbol, or a SymbolInformation when
+// Location is set.
+type documentSymbol struct {
+	Name           string           `json:"name"`
+	Kind           int              `json:"kind"`
+	Range          lspRange         `json:"range"`
+	SelectionRange lspRange         `json:"selectionRange"`
+	Children       []documentSymbol `json:"children"`
+	Location       *struct {
+		Range lspRange `json:"range"`
+	} `json:"location"`
+	ContainerName string `json:"containerName"`
+}
+
+type diagnostic struct {
+	Range    lspRange        `json:"range"`
+	Severity int             `json:"severity"`
+	Code     json.RawMessage `json:"code"`
+	Source   string          `json:"source"`
+	Message  string          `json:"message"`
+}
+
+type hover struct {
+	Contents json.RawMessage `json:"contents"`
+	Range    *lspRange       `json:"range"`
+}
+
+// symbolKinds names the SymbolKind values 1 to 26.
+var symbolKinds = []string{
+	"file", "module", "namespace", "package", "class", "method", "property", "field",
+	"constructor", "enum", "interface", "function", "variable", "constant", "string",
+	"number", "boolean", "array", "object", "key", "null", "enum_member", "struct",
+	"event", "operator", "type_parameter",
+}
+
+func symbolKind(k int) string {
+	if k < 1 || k > len(symbolKinds) {
+		return "unknown"
+	}
+	return symbolKinds[k-1]
+}
+
+func toPosition(p cflsp.Position) position {
+	return position{Line: p.Line - 1, Character: p.Column - 1}
+}
+
+func toRange(r cflsp.Range) lspRange {
+	return lspRange{Start: toPosition(r.Start), End: toPosition(r.End)}
+}
+
+func fromPosition(p position) cflsp.Position {
+	return cflsp.Position{Line: p.Line + 1, Column: p.Character + 1}
+}
+
+func fromRange(r lspRange) cflsp.Range {
+	return cflsp.Range{Start: fromPosition(r.Start), End: fromPosition(r.End)}
+}
+
+func fromEdits(edits []textEdit) []cflsp.TextEdit {
+	out := make([]cflsp.TextEdit, 0, len(edits))
+	for _, e := range edits {
+		out = append(out, cflsp.TextEdit{Range: fromRange(e.Range), NewText: e.NewText})
+	}
+	return out
+}
+
+// severities names the DiagnosticSeverity values 1 to 4. Diagnostics
+// without a severity are errors.
+var severities = []cflsp.Severity{cflsp.SeverityError, cflsp.SeverityWarning, cflsp.SeverityInformation, cflsp.SeverityHint}
+
+// decodeDiagnostics converts the diagnostics published for path. The code
+// is a string or a number.
+func decodeDiagnostics(path string, raw json.RawMessage) []cflsp.Diagnostic {
+	var diags []diagnostic
+	_ = json.Unmarshal(raw, &diags)
+	out := make([]cflsp.Diagnostic, 0, len(diags))
+	for _, d := range diags {
+		sev := cflsp.SeverityError
+		if d.Severity >= 1 && d.Severity <= len(severities) {
+			sev = severities[d.Severity-1]
+		}
+		code := string(bytes.TrimSpace(d.Code))
+		if code == "null" {
+			code = ""
+		}
+		var s string
+		if json.Unmarshal(d.Code, &s) == nil {
+			code = s
+		}
+		out = append(out, cflsp.Diagnostic{Path: path, Range: fromRange(d.Range), Severity: sev, Code: code, Source: d.Source, Message: d.Message})
+	}
+	return out
+}
+
+// decodeLocations decodes a Location, a Location array or a LocationLink
+// array. null yields none.
+func decodeLocations(raw json.RawMessage) ([]locationOrLink, error) {
+	raw = bytes.TrimSpace(raw)
+	switch {
+	case len(raw) == 0 || string(raw) == "null":
+		return nil, nil
+	case raw[0] == '{':
+		var l locationOrLink
+		err := json.Unmarshal(raw, &l)
+		return []locationOrLink{l}, err
+	}
+	var ls []locationOrLink
+	err := json.Unmarshal(raw, &ls)
+	return ls, err
+}
+
+// hoverText flattens MarkupContent, a MarkedString or an array of
+// MarkedStrings to text. Code snippets become fenced blocks.
+func hoverText(raw json.RawMessage) string {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return ""
+	}
+	switch raw[0] {
+	case '"':
+		var s string
+		_ = json.Unmarshal(raw, &s)
+		return s
+	case '[':
+		var parts []json.RawMessage
+		_ = json.Unmarshal(raw, &parts)
+		texts := make([]string, 0, len(parts))
+		for _, p := range parts {
+			if t := hoverText(p); t != "" {
+				texts = append(texts, t)
+			}
+		}
+		return strings.Join(texts, "\n\n")
+	}
+	var v struct {
+		Kind     string `json:"kind"`
+		Language string `json:"language"`
+		Value    string `json:"value"`
+	}
+	_ = json.Unmarshal(raw, &v)
+	if v.Language != "" {
+		return fmt.Sprintf("```%s\n%s\n```", v.Language, v.Value)
+	}
+	return v.Value
+}
@@ -1,28 +1,152 @@
-// Package mcp provides a stub for the Model Context Protocol server.
-// This will be implemented in Phase 2 to expose tools to AI agents.
+// Package mcp implements the Model Context Protocol server of CodeForge,
+// so external agents (Claude Desktop, IDE assistants) can drive it through
+// MCP tools: list projects, create tasks, start and read runs, search the
+// retrieval index, fetch repo maps, read costs, resolve plan approvals and
+// rename, fix and format code with the projects' language servers.
+//
+// The server speaks JSON-RPC 2.0 over the Streamable HTTP transport in its
+// plain JSON form: every POST carries one message and gets one JSON reply.
+// It is stateless and offers no server-initiated SSE stream.
 package mcp
 
-import "log/slog"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
 
-// Server is a placeholder for the MCP server that will expose
-// CodeForge tools (file edit, terminal, browser) to AI agents.
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+// maxBodyBytes limits the size of a request body.
+const maxBodyBytes = 1 << 20
+
+// protocolVersions are the MCP revisions the server accepts, newest first.
+var protocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// Services are the services the tools call. Costs, Retrieval, Scopes,
+// Graph and LSP may be nil, which hides their tools.
+type Services struct {
+	Projects     *service.ProjectService
+	Tasks        *service.TaskService
+	Runtime      *service.RuntimeService
+	Orchestrator *service.OrchestratorService
+	Retrieval    *service.RetrievalService
+	Scopes       *service.ScopeService
+	Graph        *service.GraphService
+	Costs        *service.CostService
+	LSP          *service.LSPService
+}
+
+// Server serves MCP requests.
 type Server struct {
-	addr string
+	tools []tool
+}
+
+// NewServer creates a Server exposing the tools of svc.
+func NewServer(svc Services) *Server {
+	return &Server{tools: newTools(&svc)}
+}
+
+// ServeHTTP handles POST /mcp. Requests get a JSON-RPC response;
+// notifications and responses are acknowledged with 202 Accepted. GET is
+// answered with 405, as the server offers no SSE stream.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req); err != nil || req.JSONRPC != "2.0" {
+		writeResponse(w, &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: codeParseError, Message: "body must be a single JSON-RPC 2.0 message"}})
+		return
+	}
+	if len(req.ID) == 0 || req.Method == "" {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	result, rerr := s.handle(r.Context(), &req)
+	resp := &response{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rerr}
+	if rerr != nil {
+		resp.Result = nil
+	}
+	writeResponse(w, resp)
+}
+
+func (s *Server) handle(ctx context.Context, req *request) (any, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		var p initializeParams
+		_ = json.Unmarshal(req.Params, &p)
+		version := protocolVersions[0]
+		if slices.Contains(protocolVersions, p.ProtocolVersion) {
+			version = p.ProtocolVersion
+		}
+		slog.Info("mcp client connected", "client", p.ClientInfo.Name, "version", p.ClientInfo.Version, "protocol", version)
+		return &initializeResult{
+			ProtocolVersion: version,
+			Capabilities:    map[string]any{"tools": map[string]any{}},
+			ServerInfo:      implementation{Name: "codeforge", Version: "0.1.0"},
+			Instructions:    "Create tasks in a project, start runs of them with an agent and poll get_run until the run ends.",
+		}, nil
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		list := make([]toolInfo, len(s.tools))
+		for i, t := range s.tools {
+			list[i] = toolInfo{Name: t.name, Description: t.description, InputSchema: t.schema}
+		}
+		return map[string]any{"tools": list}, nil
+	case "tools/call":
+		var p callParams
+		if err := json.Unmarshal(req.Params, &p); err != nil || p.Name == "" {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "params must name a tool"}
+		}
+		return s.call(ctx, &p)
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
 }
 
-// NewServer creates a new MCP server stub.
-func NewServer(addr string) *Server {
-	return &Server{addr: addr}
+// call runs a tool. Tool failures are reported in the result with isError
+// set, so the calling model sees them; unknown tools are protocol errors.
+func (s *Server) call(ctx context.Context, p *callParams) (any, *rpcError) {
+	i := slices.IndexFunc(s.tools, func(t tool) bool { return t.name == p.Name })
+	if i < 0 {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "unknown tool: " + p.Name}
+	}
+	args := p.Arguments
+	if len(args) == 0 || string(args) == "null" {
+		args = json.RawMessage("{}")
+	}
+	out, err := s.tools[i].call(ctx, args)
+	if err != nil {
+		var argErr *argError
+		if !errors.As(err, &argErr) {
+			slog.Warn("mcp tool failed", "tool", p.Name, "error", err)
+		}
+		return &callResult{Content: []content{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return nil, &rpcError{Code: codeInternalError, Message: err.Error()}
+	}
+	return &callResult{Content: []content{{Type: "text", Text: string(data)}}, StructuredContent: structured(data)}, nil
 }
 
-// Start is a no-op stub. In Phase 2, this will start the MCP server.
-func (s *Server) Start() error {
-	slog.Info("mcp server stub: start called", "addr", s.addr)
-	return nil
+// structured returns data as structured tool output, which must be an
+// object; lists are wrapped in {"items": [...]}.
+func structured(data json.RawMessage) any {
+	if len(data) > 0 && data[0] == '{' {
+		return data
+	}
+	return map[string]json.RawMessage{"items": data}
 }
 
-// Stop is a no-op stub. In Phase 2, this will gracefully stop the MCP server.
-func (s *Server) Stop() error {
-	slog.Info("mcp server stub: stop called")
-	return nil
+func writeResponse(w http.ResponseWriter, resp *response) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
 }
@@ -0,0 +1,234 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/cost"
+	"github.com/Strob0t/CodeForge/internal/domain/lsp"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/langserver"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+// fakeStore implements the store methods the tested tools use; the others
+// panic through the nil embedded interface.
+type fakeStore struct {
+	database.Store
+	tasks     []task.Task
+	workspace string
+}
+
+func (s *fakeStore) GetProject(_ context.Context, id string) (*project.Project, error) {
+	if id != "p1" {
+		return nil, fmt.Errorf("project %s: %w", id, domain.ErrNotFound)
+	}
+	return &project.Project{ID: "p1", Name: "alpha", WorkspacePath: s.workspace}, nil
+}
+
+func (s *fakeStore) ListProjects(context.Context) ([]project.Project, error) {
+	return []project.Project{{ID: "p1", Name: "alpha"}}, nil
+}
+
+func (s *fakeStore) CreateTask(_ context.Context, req task.CreateRequest) (*task.Task, error) {
+	t := task.Task{ID: fmt.Sprintf("t%d", len(s.tasks)+1), ProjectID: req.ProjectID, Title: req.Title, Prompt: req.Prompt}
+	s.tasks = append(s.tasks, t)
+	return &t, nil
+}
+
+func (s *fakeStore) GetRun(_ context.Context, id string) (*run.Run, error) {
+	return nil, fmt.Errorf("run %s: %w", id, domain.ErrNotFound)
+}
+
+func (s *fakeStore) ListCostSummaries(context.Context, string) ([]cost.Summary, error) {
+	return nil, nil
+}
+
+type fakeQueue struct{ messagequeue.Queue }
+
+func (fakeQueue) Publish(context.Context, string, []byte) error { return nil }
+
+func newTestServer() (*Server, *fakeStore) {
+	store := &fakeStore{}
+	runtime := service.NewRuntimeService(store, fakeQueue{}, nil, nil, nil, &config.Runtime{})
+	return NewServer(Services{
+		Projects:     service.NewProjectService(store),
+		Tasks:        service.NewTaskService(store, fakeQueue{}),
+		Runtime:      runtime,
+		Orchestrator: service.NewOrchestratorService(store, nil, nil, runtime, &config.Orchestrator{}),
+		Costs:        service.NewCostService(store, config.Defaults().Costs),
+	}), store
+}
+
+// rpc sends a JSON-RPC request and decodes the response.
+func rpc(t *testing.T, s *Server, method string, params any) response {
+	t.Helper()
+	body, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/mcp", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s: status %d", method, w.Code)
+	}
+	var resp response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%s: %v", method, err)
+	}
+	return resp
+}
+
+// callTool calls a tool and returns its text content.
+func callTool(t *testing.T, s *Server, name string, args any) (string, bool) {
+	t.Helper()
+	resp := rpc(t, s, "tools/call", map[string]any{"name": name, "arguments": args})
+	if resp.Error != nil {
+		t.Fatalf("%s: %s", name, resp.Error.Message)
+	}
+	data, _ := json.Marshal(resp.Result)
+	var res struct {
+		Content []content `json:"content"`
+		IsError bool      `json:"isError"`
+	}
+	_ = json.Unmarshal(data, &res)
+	return res.Content[0].Text, res.IsError
+}
+
+func TestInitializeAndListTools(t *testing.T) {
+	s, _ := newTestServer()
+	resp := rpc(t, s, "initialize", map[string]any{
+		"protocolVersion": "2025-03-26",
+		"clientInfo":      map[string]string{"name": "test", "version": "1"},
+	})
+	data, _ := json.Marshal(resp.Result)
+	if !strings.Contains(string(data), `"protocolVersion":"2025-03-26"`) || !strings.Contains(string(data), `"tools"`) {
+		t.Fatalf("unexpected initialize result %s", data)
+	}
+
+	resp = rpc(t, s, "tools/list", nil)
+	data, _ = json.Marshal(resp.Result)
+	for _, name := range []string{"list_projects", "create_task", "start_run", "get_run", "resolve_approval", "get_costs"} {
+		if !strings.Contains(string(data), `"name":"`+name+`"`) {
+			t.Errorf("tool %s not listed", name)
+		}
+	}
+	if strings.Contains(string(data), "search_code") || strings.Contains(string(data), "search_scope") || strings.Contains(string(data), "get_repo_map") {
+		t.Error("tools of missing services must be hidden")
+	}
+
+	if resp := rpc(t, s, "resources/list", nil); resp.Error == nil || resp.Error.Code != codeMethodNotFound {
+		t.Fatalf("expected method not found, got %+v", resp)
+	}
+}
+
+func TestCallTools(t *testing.T) {
+	s, store := newTestServer()
+
+	text, isErr := callTool(t, s, "list_projects", nil)
+	if isErr || !strings.Contains(text, `"alpha"`) {
+		t.Fatalf("unexpected list_projects result %q", text)
+	}
+
+	text, isErr = callTool(t, s, "create_task", map[string]string{"project_id": "p1", "title": "fix"})
+	if !isErr || text != "prompt is required" {
+		t.Fatalf("expected a missing prompt error, got %q", text)
+	}
+	_, isErr = callTool(t, s, "create_task", map[string]string{"project_id": "p1", "title": "fix", "prompt": "fix it"})
+	if isErr || len(store.tasks) != 1 || store.tasks[0].Prompt != "fix it" {
+		t.Fatalf("task not created: %+v", store.tasks)
+	}
+
+	text, isErr = callTool(t, s, "get_run", map[string]string{"run_id": "nope"})
+	if !isErr || !strings.Contains(text, "not found") {
+		t.Fatalf("expected not found, got %q", text)
+	}
+
+	text, isErr = callTool(t, s, "get_costs", nil)
+	if isErr || strings.TrimSpace(text) != "[]" {
+		t.Fatalf("expected an empty list, got %q", text)
+	}
+
+	if resp := rpc(t, s, "tools/call", map[string]any{"name": "rm_rf"}); resp.Error == nil || resp.Error.Code != codeInvalidParams {
+		t.Fatalf("expected invalid params for an unknown tool, got %+v", resp)
+	}
+}
+
+// fakeLangServer formats by prepending a comment; its other methods panic
+// through the nil embedded interface.
+type fakeLangServer struct{ langserver.Server }
+
+func (fakeLangServer) Format(context.Context, string) ([]lsp.TextEdit, error) {
+	return []lsp.TextEdit{{Range: lsp.Range{Start: lsp.Position{Line: 1, Column: 1}, End: lsp.Position{Line: 1, Column: 1}}, NewText: "// Formatted.\n"}}, nil
+}
+
+func TestLSPTools(t *testing.T) {
+	store := &fakeStore{workspace: t.TempDir()}
+	if err := os.WriteFile(filepath.Join(store.workspace, "main.go"), []byte("package main\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	svc := service.NewLSPService(store, config.Defaults().LSP, func(context.Context, []string, string) (langserver.Server, error) {
+		return fakeLangServer{}, nil
+	})
+	svc.SetPolicyService(service.NewPolicyService("headless-safe-sandbox", nil))
+	s := NewServer(Services{LSP: svc})
+
+	data, _ := json.Marshal(rpc(t, s, "tools/list", nil).Result)
+	for _, name := range []string{"lsp_rename", "lsp_code_action", "lsp_format"} {
+		if !strings.Contains(string(data), `"name":"`+name+`"`) {
+			t.Errorf("tool %s not listed", name)
+		}
+	}
+
+	text, isErr := callTool(t, s, "lsp_rename", map[string]any{"project_id": "p1", "path": "main.go", "line": 0, "column": 1, "new_name": "x"})
+	if !isErr || !strings.Contains(text, "1-based") {
+		t.Fatalf("expected an invalid position, got %q", text)
+	}
+	text, isErr = callTool(t, s, "lsp_format", map[string]any{"project_id": "p1", "path": "main.go"})
+	if isErr || !strings.Contains(text, `"applied": false`) {
+		t.Fatalf("expected a preview, got %q", text)
+	}
+	text, isErr = callTool(t, s, "lsp_format", map[string]any{"project_id": "p1", "path": "main.go", "apply": true, "policy_profile": "plan-readonly"})
+	if !isErr || !strings.Contains(text, "denied") {
+		t.Fatalf("expected the plan profile to deny the edit, got %q", text)
+	}
+	if _, isErr = callTool(t, s, "lsp_format", map[string]any{"project_id": "p1", "path": "main.go", "apply": true}); isErr {
+		t.Fatal("expected the default profile to allow formatting")
+	}
+	if b, _ := os.ReadFile(filepath.Join(store.workspace, "main.go")); string(b) != "// Formatted.\npackage main\n" {
+		t.Fatalf("unexpected formatted file %q", b)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	s, _ := newTestServer()
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("notification: status %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/mcp", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET: status %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`[{"jsonrpc":"2.0"}]`)))
+	if !strings.Contains(w.Body.String(), fmt.Sprint(codeParseError)) {
+		t.Fatalf("batch: unexpected response %s", w.Body.String())
+	}
+}
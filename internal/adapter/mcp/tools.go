@@ -0,0 +1,411 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Strob0t/CodeForge/internal/domain/codegraph"
+	"github.com/Strob0t/CodeForge/internal/domain/cost"
+	"github.com/Strob0t/CodeForge/internal/domain/lsp"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/middleware"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+// tool is an MCP tool. call receives the JSON arguments and returns a
+// value that is sent to the client as JSON.
+type tool struct {
+	name        string
+	description string
+	schema      map[string]any
+	call        func(ctx context.Context, args json.RawMessage) (any, error)
+}
+
+// argError is an invalid tool argument.
+type argError struct{ msg string }
+
+func (e *argError) Error() string { return e.msg }
+
+// prop is a property of a tool's input schema.
+type prop struct {
+	name, typ, desc string
+}
+
+// object returns a JSON schema of an object with props.
+func object(required []string, props ...prop) map[string]any {
+	properties := make(map[string]any, len(props))
+	for _, p := range props {
+		properties[p.name] = map[string]any{"type": p.typ, "description": p.desc}
+	}
+	s := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// decode unmarshals tool arguments into v.
+func decode(args json.RawMessage, v any) error {
+	if err := json.Unmarshal(args, v); err != nil {
+		return &argError{msg: "invalid arguments: " + err.Error()}
+	}
+	return nil
+}
+
+// require checks that string arguments are set. fields alternates names
+// and values.
+func require(fields ...string) error {
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i+1] == "" {
+			return &argError{msg: fields[i] + " is required"}
+		}
+	}
+	return nil
+}
+
+// newTools returns the tools backed by svc.
+func newTools(svc *Services) []tool {
+	tools := []tool{
+		{
+			name:        "list_projects",
+			description: "List the projects managed by CodeForge.",
+			schema:      object(nil),
+			call: func(ctx context.Context, _ json.RawMessage) (any, error) {
+				projects, err := svc.Projects.List(ctx)
+				if projects == nil {
+					projects = []project.Project{}
+				}
+				return projects, err
+			},
+		},
+		{
+			name:        "create_task",
+			description: "Create a task in a project. Start a run of it with start_run.",
+			schema: object([]string{"project_id", "title", "prompt"},
+				prop{"project_id", "string", "Project ID"},
+				prop{"title", "string", "Short title"},
+				prop{"prompt", "string", "What the agent should do"},
+				prop{"sub_project_id", "string", "Sub-project (monorepo package) the task is scoped to"},
+			),
+			call: func(ctx context.Context, args json.RawMessage) (any, error) {
+				var req task.CreateRequest
+				if err := decode(args, &req); err != nil {
+					return nil, err
+				}
+				if err := require("project_id", req.ProjectID, "title", req.Title, "prompt", req.Prompt); err != nil {
+					return nil, err
+				}
+				return svc.Tasks.Create(ctx, req)
+			},
+		},
+		{
+			name:        "start_run",
+			description: "Start a run of a task with an agent. The run continues in the background; poll get_run for its status and output.",
+			schema: object([]string{"project_id", "task_id", "agent_id"},
+				prop{"project_id", "string", "Project ID"},
+				prop{"task_id", "string", "Task ID"},
+				prop{"agent_id", "string", "Agent ID"},
+				prop{"policy_profile", "string", "Policy profile (default: the agent's)"},
+				prop{"model", "string", "Model override"},
+				prop{"isolate", "boolean", "Run in a dedicated git worktree"},
+			),
+			call: func(ctx context.Context, args json.RawMessage) (any, error) {
+				var req run.StartRequest
+				if err := decode(args, &req); err != nil {
+					return nil, err
+				}
+				if err := require("project_id", req.ProjectID, "task_id", req.TaskID, "agent_id", req.AgentID); err != nil {
+					return nil, err
+				}
+				return svc.Runtime.StartRun(ctx, &req)
+			},
+		},
+		{
+			name:        "get_run",
+			description: "Get a run: status, step count, cost, output and error.",
+			schema:      object([]string{"run_id"}, prop{"run_id", "string", "Run ID"}),
+			call: func(ctx context.Context, args json.RawMessage) (any, error) {
+				var a struct {
+					RunID string `json:"run_id"`
+				}
+				if err := decode(args, &a); err != nil {
+					return nil, err
+				}
+				if err := require("run_id", a.RunID); err != nil {
+					return nil, err
+				}
+				return svc.Runtime.GetRun(ctx, a.RunID)
+			},
+		},
+		{
+			name:        "list_pending_approvals",
+			description: "List plan steps waiting for a human approval.",
+			schema:      object(nil, prop{"project_id", "string", "Only steps of this project's plans"}),
+			call: func(ctx context.Context, args json.RawMessage) (any, error) {
+				var a struct {
+					ProjectID string `json:"project_id"`
+				}
+				if err := decode(args, &a); err != nil {
+					return nil, err
+				}
+				steps, err := svc.Orchestrator.ListPendingApprovals(ctx, a.ProjectID)
+				if steps == nil {
+					steps = []plan.Step{}
+				}
+				return steps, err
+			},
+		},
+		{
+			name:        "resolve_approval",
+			description: "Approve or reject a plan step waiting for approval. Rejection fails the step and its plan.",
+			schema: object([]string{"plan_id", "step_id", "approved"},
+				prop{"plan_id", "string", "Plan ID"},
+				prop{"step_id", "string", "Step ID"},
+				prop{"approved", "boolean", "true approves, false rejects"},
+				prop{"approved_by", "string", `Who decided; must match the caller's API key ID or name if given. Votes count for the caller, "anonymous" without auth`},
+				prop{"comment", "string", "Reason, shown with the decision"},
+			),
+			call: func(ctx context.Context, args json.RawMessage) (any, error) {
+				var a struct {
+					PlanID   string `json:"plan_id"`
+					StepID   string `json:"step_id"`
+					Approved *bool  `json:"approved"`
+					plan.ResolveApprovalRequest
+				}
+				if err := decode(args, &a); err != nil {
+					return nil, err
+				}
+				if err := require("plan_id", a.PlanID, "step_id", a.StepID); err != nil {
+					return nil, err
+				}
+				if a.Approved == nil {
+					return nil, &argError{msg: "approved is required"}
+				}
+				if err := a.Bind(middleware.Voter(ctx)); err != nil {
+					return nil, err
+				}
+				return svc.Orchestrator.ResolveApproval(ctx, a.PlanID, a.StepID, *a.Approved, &a.ResolveApprovalRequest)
+			},
+		},
+	}
+
+	if svc.Retrieval != nil {
+		tools = append(tools, tool{
+			name:        "search_code",
+			description: "Semantic search over a project's indexed workspace. Returns matching chunks with path and line range.",
+			schema: object([]string{"project_id", "query"},
+				prop{"project_id", "string", "Project ID"},
+				prop{"query", "string", "Natural-language or code query"},
+				prop{"limit", "integer", "Max results (default 10)"},
+			),
+			call: func(ctx context.Context, args json.RawMessage) (any, error) {
+				var a struct {
+					ProjectID string `json:"project_id"`
+					retrieval.SearchRequest
+				}
+				if err := decode(args, &a); err != nil {
+					return nil, err
+				}
+				if err := require("project_id", a.ProjectID, "query", a.Query); err != nil {
+					return nil, err
+				}
+				results, err := svc.Retrieval.Search(ctx, a.ProjectID, &a.SearchRequest)
+				if results == nil {
+					results = []retrieval.Result{}
+				}
+				return results, err
+			},
+		})
+	}
+	if svc.Scopes != nil {
+		tools = append(tools, tool{
+			name:        "search_scope",
+			description: "Semantic search across the projects and knowledge bases of a retrieval scope, such as an app and its shared SDK. Returns chunks ranked by weighted score with their source.",
+			schema: object([]string{"scope_id", "query"},
+				prop{"scope_id", "string", "Scope ID"},
+				prop{"query", "string", "Natural-language or code query"},
+				prop{"limit", "integer", "Max results (default 10)"},
+			),
+			call: func(ctx context.Context, args json.RawMessage) (any, error) {
+				var a struct {
+					ScopeID string `json:"scope_id"`
+					retrieval.SearchRequest
+				}
+				if err := decode(args, &a); err != nil {
+					return nil, err
+				}
+				if err := require("scope_id", a.ScopeID, "query", a.Query); err != nil {
+					return nil, err
+				}
+				return svc.Scopes.Search(ctx, a.ScopeID, &a.SearchRequest)
+			},
+		})
+	}
+	if svc.Graph != nil {
+		tools = append(tools, tool{
+			name:        "get_repo_map",
+			description: "Overview of a project's workspace: the most depended-on source files with their exported declarations.",
+			schema: object([]string{"project_id"},
+				prop{"project_id", "string", "Project ID"},
+				prop{"max_files", "integer", fmt.Sprintf("Max files (default %d, max %d)", codegraph.DefaultRepoMapFiles, codegraph.MaxRepoMapFiles)},
+			),
+			call: func(ctx context.Context, args json.RawMessage) (any, error) {
+				var a struct {
+					ProjectID string `json:"project_id"`
+					MaxFiles  int    `json:"max_files"`
+				}
+				if err := decode(args, &a); err != nil {
+					return nil, err
+				}
+				if err := require("project_id", a.ProjectID); err != nil {
+					return nil, err
+				}
+				if a.MaxFiles < 0 || a.MaxFiles > codegraph.MaxRepoMapFiles {
+					return nil, &argError{msg: fmt.Sprintf("max_files must be between 1 and %d", codegraph.MaxRepoMapFiles)}
+				}
+				return svc.Graph.RepoMap(ctx, a.ProjectID, a.MaxFiles)
+			},
+		})
+	}
+	if svc.LSP != nil {
+		tools = append(tools, lspTools(svc.LSP)...)
+	}
+	if svc.Costs != nil {
+		tools = append(tools, tool{
+			name:        "get_costs",
+			description: "Run costs and token usage per project, or of one project.",
+			schema:      object(nil, prop{"project_id", "string", "Only this project"}),
+			call: func(ctx context.Context, args json.RawMessage) (any, error) {
+				var a struct {
+					ProjectID string `json:"project_id"`
+				}
+				if err := decode(args, &a); err != nil {
+					return nil, err
+				}
+				if a.ProjectID != "" {
+					return svc.Costs.Project(ctx, a.ProjectID)
+				}
+				sums, err := svc.Costs.Summaries(ctx)
+				if sums == nil {
+					sums = []cost.Summary{}
+				}
+				return sums, err
+			},
+		})
+	}
+	return tools
+}
+
+// lspTools are the edits of the project's language servers. They preview
+// the edit, or write it with apply when the policy profile allows editing
+// every file it changes.
+func lspTools(svc *service.LSPService) []tool {
+	applyProp := prop{"apply", "boolean", "Write the edit to the workspace instead of only returning it"}
+	profileProp := prop{"policy_profile", "string", "Policy profile the edit is checked against (default: the server's default profile)"}
+	return []tool{
+		{
+			name:        "lsp_rename",
+			description: "Rename the symbol at a position across the project's workspace with its language server.",
+			schema: object([]string{"project_id", "path", "line", "column", "new_name"},
+				prop{"project_id", "string", "Project ID"},
+				prop{"path", "string", "File relative to the workspace"},
+				prop{"line", "integer", "Line of the symbol, 1-based"},
+				prop{"column", "integer", "Column of the symbol, 1-based, in UTF-16 code units"},
+				prop{"new_name", "string", "New name"},
+				applyProp, profileProp,
+			),
+			call: func(ctx context.Context, args json.RawMessage) (any, error) {
+				var a struct {
+					ProjectID string `json:"project_id"`
+					lsp.RenameRequest
+				}
+				if err := decode(args, &a); err != nil {
+					return nil, err
+				}
+				if err := require("project_id", a.ProjectID); err != nil {
+					return nil, err
+				}
+				return lspResult(svc.Rename(ctx, a.ProjectID, &a.RenameRequest))
+			},
+		},
+		{
+			name:        "lsp_code_action",
+			description: "List the code actions (quick fixes, refactorings, organize imports) the language server offers for lines of a file, or apply the one with the given title.",
+			schema: object([]string{"project_id", "path"},
+				prop{"project_id", "string", "Project ID"},
+				prop{"path", "string", "File relative to the workspace"},
+				prop{"line", "integer", "First line, 1-based (default: 1)"},
+				prop{"end_line", "integer", "Last line (default: line)"},
+				prop{"title", "string", "Title of the action to apply; without it the actions are listed"},
+				profileProp,
+			),
+			call: func(ctx context.Context, args json.RawMessage) (any, error) {
+				var a struct {
+					ProjectID     string `json:"project_id"`
+					Path          string `json:"path"`
+					Line          int    `json:"line"`
+					EndLine       int    `json:"end_line"`
+					Title         string `json:"title"`
+					PolicyProfile string `json:"policy_profile"`
+				}
+				if err := decode(args, &a); err != nil {
+					return nil, err
+				}
+				if err := require("project_id", a.ProjectID, "path", a.Path); err != nil {
+					return nil, err
+				}
+				a.Line = max(a.Line, 1)
+				a.EndLine = max(a.EndLine, a.Line)
+				req := &lsp.CodeActionRequest{
+					Path:          a.Path,
+					Range:         lsp.Range{Start: lsp.Position{Line: a.Line, Column: 1}, End: lsp.Position{Line: a.EndLine + 1, Column: 1}},
+					Title:         a.Title,
+					PolicyProfile: a.PolicyProfile,
+				}
+				if a.Title == "" {
+					return lspResult(svc.CodeActions(ctx, a.ProjectID, req))
+				}
+				return lspResult(svc.ApplyCodeAction(ctx, a.ProjectID, req))
+			},
+		},
+		{
+			name:        "lsp_format",
+			description: "Format a file of the project's workspace with its language server.",
+			schema: object([]string{"project_id", "path"},
+				prop{"project_id", "string", "Project ID"},
+				prop{"path", "string", "File relative to the workspace"},
+				applyProp, profileProp,
+			),
+			call: func(ctx context.Context, args json.RawMessage) (any, error) {
+				var a struct {
+					ProjectID string `json:"project_id"`
+					lsp.FormatRequest
+				}
+				if err := decode(args, &a); err != nil {
+					return nil, err
+				}
+				if err := require("project_id", a.ProjectID); err != nil {
+					return nil, err
+				}
+				return lspResult(svc.Format(ctx, a.ProjectID, &a.FormatRequest))
+			},
+		},
+	}
+}
+
+// lspResult reports invalid requests as argument errors.
+func lspResult[T any](v T, err error) (any, error) {
+	if errors.Is(err, lsp.ErrInvalidRequest) {
+		return nil, &argError{msg: err.Error()}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
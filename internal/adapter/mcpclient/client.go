@@ -0,0 +1,253 @@
+// Package mcpclient connects to external MCP servers over the Streamable
+// HTTP transport and obtains OAuth 2.0 tokens for them.
+package mcpclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Strob0t/CodeForge/internal/domain/mcpserver"
+)
+
+// ProtocolVersion is the MCP revision the client requests.
+const ProtocolVersion = "2025-06-18"
+
+// Response size limits.
+const (
+	maxResponseBytes = 8 << 20
+	maxToolPages     = 20
+)
+
+// ErrUnauthorized is returned when the server rejects the request's
+// credentials (HTTP 401), e.g. because the access token expired.
+var ErrUnauthorized = errors.New("mcp server rejected the credentials")
+
+// Client is a session with one MCP server. Call Initialize first and Close
+// when done.
+type Client struct {
+	endpoint string
+	token    string // Bearer token; empty sends no Authorization header
+	http     *http.Client
+	session  string // Mcp-Session-Id assigned by the server, if any
+	protocol string // Negotiated protocol version
+	nextID   atomic.Int64
+}
+
+// New creates a Client for endpoint. token may be empty.
+func New(endpoint, token string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{endpoint: endpoint, token: token, http: httpClient}
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      *int64 `json:"id,omitempty"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"` // Set on requests and notifications from the server
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Initialize negotiates the protocol version and opens the session.
+func (c *Client) Initialize(ctx context.Context) error {
+	var res struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	err := c.call(ctx, "initialize", map[string]any{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]string{"name": "codeforge", "version": "0.1.0"},
+	}, &res)
+	if err != nil {
+		return err
+	}
+	c.protocol = res.ProtocolVersion
+	return c.notify(ctx, "notifications/initialized")
+}
+
+// ListTools returns the tools of the server, following pagination.
+func (c *Client) ListTools(ctx context.Context) ([]mcpserver.Tool, error) {
+	var tools []mcpserver.Tool
+	cursor := ""
+	for range maxToolPages {
+		var params map[string]any
+		if cursor != "" {
+			params = map[string]any{"cursor": cursor}
+		}
+		var page struct {
+			Tools      []mcpserver.Tool `json:"tools"`
+			NextCursor string           `json:"nextCursor"`
+		}
+		if err := c.call(ctx, "tools/list", params, &page); err != nil {
+			return nil, err
+		}
+		tools = append(tools, page.Tools...)
+		if cursor = page.NextCursor; cursor == "" {
+			return tools, nil
+		}
+	}
+	return tools, fmt.Errorf("tools/list: more than %d pages", maxToolPages)
+}
+
+// CallTool calls a tool. Errors reported by the tool are returned in the
+// result with IsError set, not as an error.
+func (c *Client) CallTool(ctx context.Context, req *mcpserver.CallRequest) (*mcpserver.CallResult, error) {
+	args := req.Arguments
+	if args == nil {
+		args = map[string]any{}
+	}
+	var res mcpserver.CallResult
+	if err := c.call(ctx, "tools/call", map[string]any{"name": req.Name, "arguments": args}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Close ends the session, if the server assigned one.
+func (c *Client) Close(ctx context.Context) error {
+	if c.session == "" {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.endpoint, http.NoBody)
+	if err != nil {
+		return err
+	}
+	c.setHeaders(req)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	c.session = ""
+	return nil
+}
+
+func (c *Client) call(ctx context.Context, method string, params, out any) error {
+	id := c.nextID.Add(1)
+	resp, err := c.post(ctx, &rpcRequest{JSONRPC: "2.0", ID: &id, Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	msg, err := readResponse(resp, fmt.Sprint(id))
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	if msg.Error != nil {
+		return fmt.Errorf("%s: %s (code %d)", method, msg.Error.Message, msg.Error.Code)
+	}
+	if err := json.Unmarshal(msg.Result, out); err != nil {
+		return fmt.Errorf("%s: decode result: %w", method, err)
+	}
+	return nil
+}
+
+func (c *Client) notify(ctx context.Context, method string) error {
+	resp, err := c.post(ctx, &rpcRequest{JSONRPC: "2.0", Method: method})
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+	return resp.Body.Close()
+}
+
+// post sends a message and returns the successful response.
+func (c *Client) post(ctx context.Context, msg *rpcRequest) (*http.Response, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	c.setHeaders(req)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if s := resp.Header.Get("Mcp-Session-Id"); s != "" {
+		c.session = s
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	_ = resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, ErrUnauthorized
+	}
+	return nil, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+}
+
+func (c *Client) setHeaders(req *http.Request) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.session != "" {
+		req.Header.Set("Mcp-Session-Id", c.session)
+	}
+	if c.protocol != "" {
+		req.Header.Set("MCP-Protocol-Version", c.protocol)
+	}
+}
+
+// readResponse reads the response with the given ID from a JSON body or
+// an SSE stream. Other messages on the stream (server notifications and
+// requests) are skipped.
+func readResponse(resp *http.Response, id string) (*rpcResponse, error) {
+	body := io.LimitReader(resp.Body, maxResponseBytes)
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		var msg rpcResponse
+		if err := json.NewDecoder(body).Decode(&msg); err != nil {
+			return nil, fmt.Errorf("decode response: %w", err)
+		}
+		return &msg, nil
+	}
+
+	sc := bufio.NewScanner(body)
+	sc.Buffer(make([]byte, 64<<10), maxResponseBytes)
+	var data strings.Builder
+	for sc.Scan() {
+		line := sc.Text()
+		if rest, ok := strings.CutPrefix(line, "data:"); ok {
+			data.WriteString(strings.TrimPrefix(rest, " "))
+			data.WriteByte('\n')
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+		var msg rpcResponse
+		if json.Unmarshal([]byte(data.String()), &msg) == nil && msg.Method == "" && string(msg.ID) == id {
+			return &msg, nil
+		}
+		data.Reset()
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read event stream: %w", err)
+	}
+	return nil, errors.New("event stream ended without a response")
+}
@@ -0,0 +1,86 @@
+package mcpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/mcpserver"
+)
+
+// FetchToken requests an access token from the authorization server of o.
+// With a refresh token it uses the refresh_token grant, otherwise the
+// client_credentials grant. The client authenticates with HTTP Basic auth
+// (client_secret_basic). A refresh response without a new refresh token
+// keeps the old one.
+func FetchToken(ctx context.Context, hc *http.Client, o *mcpserver.OAuth, clientSecret, refreshToken string, now time.Time) (*mcpserver.Token, error) {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	form := url.Values{}
+	if refreshToken != "" {
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", refreshToken)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+	if len(o.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.Scopes, " "))
+	}
+	if o.Audience != "" {
+		form.Set("audience", o.Audience)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(o.ClientID), url.QueryEscape(clientSecret))
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		ExpiresIn        int64  `json:"expires_in"`
+		RefreshToken     string `json:"refresh_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("token request: status %d: decode response: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		msg := body.Error
+		if body.ErrorDescription != "" {
+			msg += ": " + body.ErrorDescription
+		}
+		return nil, fmt.Errorf("token request: status %d: %s", resp.StatusCode, msg)
+	}
+	if body.AccessToken == "" {
+		return nil, fmt.Errorf("token request: response has no access_token")
+	}
+	if body.TokenType != "" && !strings.EqualFold(body.TokenType, "bearer") {
+		return nil, fmt.Errorf("token request: unsupported token type %q", body.TokenType)
+	}
+
+	t := &mcpserver.Token{AccessToken: body.AccessToken, RefreshToken: body.RefreshToken}
+	if t.RefreshToken == "" {
+		t.RefreshToken = refreshToken
+	}
+	if body.ExpiresIn > 0 {
+		t.ExpiresAt = now.Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	return t, nil
+}
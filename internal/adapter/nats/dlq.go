@@ -0,0 +1,165 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+)
+
+const (
+	dlqStreamName = "CODEFORGE_DLQ"
+	// dlqPrefix is prepended to the original subject of a dead letter.
+	dlqPrefix = "dlq."
+
+	headerDLQError      = "CodeForge-DLQ-Error"
+	headerDLQDeliveries = "CodeForge-DLQ-Deliveries"
+	headerDLQTime       = "CodeForge-DLQ-Time"
+
+	// dlqFetchWait bounds how long ListDeadLetters waits for messages.
+	dlqFetchWait = 500 * time.Millisecond
+)
+
+var _ messagequeue.DeadLetterQueue = (*Queue)(nil)
+
+// moveToDLQ publishes a copy of msg to dlq.{subject} with the failure
+// recorded in headers, then acks the original. If the copy cannot be
+// published, the original is redelivered later instead of being lost.
+func (q *Queue) moveToDLQ(ctx context.Context, msg jetstream.Msg, deliveries int, cause error) {
+	dlqSubject := dlqPrefix + msg.Subject()
+	dlqMsg := &nats.Msg{
+		Subject: dlqSubject,
+		Data:    msg.Data(),
+		Header:  nats.Header{},
+	}
+	for k, v := range msg.Headers() {
+		dlqMsg.Header[k] = v
+	}
+	dlqMsg.Header.Set(headerDLQError, cause.Error())
+	dlqMsg.Header.Set(headerDLQDeliveries, strconv.Itoa(deliveries))
+	dlqMsg.Header.Set(headerDLQTime, time.Now().UTC().Format(time.RFC3339))
+
+	if _, err := q.js.PublishMsg(ctx, dlqMsg); err != nil {
+		slog.Error("failed to publish to DLQ",
+			"dlq_subject", dlqSubject,
+			"error", err,
+		)
+		if nakErr := msg.NakWithDelay(nakMaxDelay); nakErr != nil {
+			slog.Error("nats nak (dlq) failed", "error", nakErr)
+		}
+		return
+	}
+	slog.Warn("message moved to DLQ",
+		"subject", msg.Subject(),
+		"dlq_subject", dlqSubject,
+		"deliveries", deliveries,
+		"error", cause,
+	)
+
+	// Ack the original to remove it from the main stream
+	if ackErr := msg.Ack(); ackErr != nil {
+		slog.Error("nats ack (dlq) failed", "error", ackErr)
+	}
+}
+
+// ListDeadLetters returns up to limit dead letters, oldest first.
+func (q *Queue) ListDeadLetters(ctx context.Context, subject string, limit int) ([]messagequeue.DeadLetter, error) {
+	filter := dlqPrefix + ">"
+	if subject != "" {
+		filter = dlqPrefix + subject
+	}
+	cons, err := q.js.OrderedConsumer(ctx, dlqStreamName, jetstream.OrderedConsumerConfig{
+		FilterSubjects: []string{filter},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("dlq consumer: %w", err)
+	}
+	batch, err := cons.Fetch(limit, jetstream.FetchMaxWait(dlqFetchWait))
+	if err != nil {
+		return nil, fmt.Errorf("dlq fetch: %w", err)
+	}
+
+	letters := []messagequeue.DeadLetter{}
+	for msg := range batch.Messages() {
+		md, err := msg.Metadata()
+		if err != nil {
+			continue
+		}
+		letters = append(letters, deadLetter(md.Sequence.Stream, msg.Subject(), msg.Headers(), msg.Data()))
+	}
+	if err := batch.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) {
+		return nil, fmt.Errorf("dlq fetch: %w", err)
+	}
+	return letters, nil
+}
+
+// ReplayDeadLetter republishes a dead letter to its original subject and
+// deletes it from the dead-letter stream.
+func (q *Queue) ReplayDeadLetter(ctx context.Context, seq uint64) error {
+	stream, err := q.js.Stream(ctx, dlqStreamName)
+	if err != nil {
+		return fmt.Errorf("dlq stream: %w", err)
+	}
+	raw, err := stream.GetMsg(ctx, seq)
+	if err != nil {
+		return dlqError(seq, err)
+	}
+
+	msg := &nats.Msg{
+		Subject: strings.TrimPrefix(raw.Subject, dlqPrefix),
+		Data:    raw.Data,
+		Header:  nats.Header{},
+	}
+	for k, v := range raw.Header {
+		if !strings.HasPrefix(k, "CodeForge-DLQ-") && !strings.HasPrefix(k, "Nats-") {
+			msg.Header[k] = v
+		}
+	}
+	if _, err := q.js.PublishMsg(ctx, msg); err != nil {
+		return fmt.Errorf("replay dead letter %d: %w", seq, err)
+	}
+	slog.Info("dead letter replayed", "seq", seq, "subject", msg.Subject)
+	return q.DeleteDeadLetter(ctx, seq)
+}
+
+// DeleteDeadLetter removes a dead letter from the dead-letter stream.
+func (q *Queue) DeleteDeadLetter(ctx context.Context, seq uint64) error {
+	stream, err := q.js.Stream(ctx, dlqStreamName)
+	if err != nil {
+		return fmt.Errorf("dlq stream: %w", err)
+	}
+	if err := stream.DeleteMsg(ctx, seq); err != nil {
+		return dlqError(seq, err)
+	}
+	return nil
+}
+
+// deadLetter builds a DeadLetter from a message of the dead-letter stream.
+func deadLetter(seq uint64, subject string, hdrs nats.Header, data []byte) messagequeue.DeadLetter {
+	dl := messagequeue.DeadLetter{
+		Seq:       seq,
+		Subject:   strings.TrimPrefix(subject, dlqPrefix),
+		Data:      string(data),
+		Error:     hdrs.Get(headerDLQError),
+		RequestID: hdrs.Get(headerRequestID),
+	}
+	dl.Deliveries, _ = strconv.Atoi(hdrs.Get(headerDLQDeliveries))
+	dl.FailedAt, _ = time.Parse(time.RFC3339, hdrs.Get(headerDLQTime))
+	return dl
+}
+
+func dlqError(seq uint64, err error) error {
+	if errors.Is(err, jetstream.ErrMsgNotFound) {
+		return fmt.Errorf("dead letter %d: %w", seq, domain.ErrNotFound)
+	}
+	return fmt.Errorf("dead letter %d: %w", seq, err)
+}
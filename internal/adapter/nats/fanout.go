@@ -0,0 +1,38 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+)
+
+var _ messagequeue.FanOut = (*Queue)(nil)
+
+// PublishFanOut publishes a core NATS message, which bypasses JetStream
+// and reaches every server subscribed at the time.
+func (q *Queue) PublishFanOut(_ context.Context, subject string, data []byte) error {
+	if err := q.nc.Publish(subject, data); err != nil {
+		return fmt.Errorf("nats publish %s: %w", subject, err)
+	}
+	return nil
+}
+
+// SubscribeFanOut subscribes to a core NATS subject without a queue
+// group, so each subscribing server receives every message.
+func (q *Queue) SubscribeFanOut(subject string, fn func(subject string, data []byte)) (func(), error) {
+	sub, err := q.nc.Subscribe(subject, func(msg *nats.Msg) {
+		fn(msg.Subject, msg.Data)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("nats subscribe %s: %w", subject, err)
+	}
+	return func() {
+		if err := sub.Unsubscribe(); err != nil {
+			slog.Debug("nats unsubscribe", "subject", subject, "error", err)
+		}
+	}, nil
+}
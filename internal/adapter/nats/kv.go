@@ -0,0 +1,116 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+)
+
+var _ messagequeue.KeyValueStore = (*Queue)(nil)
+
+// KeyValue returns a JetStream key-value bucket, creating it if needed.
+// Buckets keep only the latest value of each key.
+func (q *Queue) KeyValue(ctx context.Context, bucket string) (messagequeue.KeyValue, error) {
+	kv, err := q.js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{Bucket: bucket, History: 1})
+	if err != nil {
+		return nil, fmt.Errorf("kv bucket %s: %w", bucket, err)
+	}
+	return &keyValue{kv: kv}, nil
+}
+
+// ExpiringKeyValue returns a JetStream key-value bucket whose values
+// expire ttl after their last put, creating or updating it if needed.
+func (q *Queue) ExpiringKeyValue(ctx context.Context, bucket string, ttl time.Duration) (messagequeue.KeyValue, error) {
+	kv, err := q.js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{Bucket: bucket, History: 1, TTL: ttl})
+	if err != nil {
+		return nil, fmt.Errorf("kv bucket %s: %w", bucket, err)
+	}
+	return &keyValue{kv: kv}, nil
+}
+
+// keyValue adapts a jetstream.KeyValue to messagequeue.KeyValue.
+type keyValue struct {
+	kv jetstream.KeyValue
+}
+
+func (b *keyValue) Get(ctx context.Context, key string) ([]byte, error) {
+	entry, err := b.kv.Get(ctx, key)
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("kv get %s: %w", key, err)
+	}
+	return entry.Value(), nil
+}
+
+func (b *keyValue) GetRevision(ctx context.Context, key string) ([]byte, uint64, error) {
+	entry, err := b.kv.Get(ctx, key)
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("kv get %s: %w", key, err)
+	}
+	return entry.Value(), entry.Revision(), nil
+}
+
+func (b *keyValue) Create(ctx context.Context, key string, value []byte) error {
+	if _, err := b.kv.Create(ctx, key, value); err != nil {
+		if errors.Is(err, jetstream.ErrKeyExists) {
+			return messagequeue.ErrKeyExists
+		}
+		return fmt.Errorf("kv create %s: %w", key, err)
+	}
+	return nil
+}
+
+func (b *keyValue) Put(ctx context.Context, key string, value []byte) error {
+	if _, err := b.kv.Put(ctx, key, value); err != nil {
+		return fmt.Errorf("kv put %s: %w", key, err)
+	}
+	return nil
+}
+
+func (b *keyValue) Update(ctx context.Context, key string, value []byte, revision uint64) error {
+	if _, err := b.kv.Update(ctx, key, value, revision); err != nil {
+		// A wrong last sequence is reported as ErrKeyExists.
+		if errors.Is(err, jetstream.ErrKeyExists) {
+			return messagequeue.ErrKeyChanged
+		}
+		return fmt.Errorf("kv update %s: %w", key, err)
+	}
+	return nil
+}
+
+func (b *keyValue) Delete(ctx context.Context, key string) error {
+	if err := b.kv.Delete(ctx, key); err != nil && !errors.Is(err, jetstream.ErrKeyNotFound) {
+		return fmt.Errorf("kv delete %s: %w", key, err)
+	}
+	return nil
+}
+
+func (b *keyValue) Watch(ctx context.Context, fn func(key string, value []byte)) (func(), error) {
+	w, err := b.kv.WatchAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("kv watch %s: %w", b.kv.Bucket(), err)
+	}
+	go func() {
+		for entry := range w.Updates() {
+			switch {
+			case entry == nil:
+				// Marks the end of the initial values.
+			case entry.Operation() == jetstream.KeyValuePut:
+				fn(entry.Key(), entry.Value())
+			default:
+				fn(entry.Key(), nil)
+			}
+		}
+	}()
+	return func() { _ = w.Stop() }, nil
+}
@@ -5,34 +5,37 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"strconv"
+	"strings"
 	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
 
+	"github.com/Strob0t/CodeForge/internal/config"
 	"github.com/Strob0t/CodeForge/internal/logger"
 	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
 	"github.com/Strob0t/CodeForge/internal/resilience"
 )
 
 const (
-	streamName       = "CODEFORGE"
-	headerRequestID  = "X-Request-ID"
-	headerRetryCount = "Retry-Count"
-	maxRetries       = 3
-	nakDelay         = 2 * time.Second
+	streamName      = "CODEFORGE"
+	headerRequestID = "X-Request-ID"
+	nakBaseDelay    = 2 * time.Second
+	nakMaxDelay     = time.Minute
 )
 
 // Queue implements messagequeue.Queue using NATS JetStream.
 type Queue struct {
 	nc      *nats.Conn
 	js      jetstream.JetStream
+	cfg     config.NATS
 	breaker *resilience.Breaker
 }
 
-// Connect establishes a connection to NATS and ensures the JetStream stream exists.
-func Connect(ctx context.Context, url string) (*Queue, error) {
+// Connect establishes a connection to NATS and ensures the JetStream
+// streams exist.
+func Connect(ctx context.Context, cfg config.NATS) (*Queue, error) {
+	url := cfg.URL
 	nc, err := nats.Connect(url)
 	if err != nil {
 		return nil, fmt.Errorf("nats connect: %w", err)
@@ -47,15 +50,25 @@ func Connect(ctx context.Context, url string) (*Queue, error) {
 	// Ensure the stream exists with subjects matching our topic patterns.
 	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
 		Name:     streamName,
-		Subjects: []string{"tasks.>", "agents.>"},
+		Subjects: []string{"tasks.>", "agents.>", "runs.>", "context.>"},
 	})
 	if err != nil {
 		nc.Close()
 		return nil, fmt.Errorf("jetstream stream create: %w", err)
 	}
 
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     dlqStreamName,
+		Subjects: []string{dlqPrefix + ">"},
+		MaxAge:   cfg.DLQMaxAge,
+	})
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("jetstream dlq stream create: %w", err)
+	}
+
 	slog.Info("nats connected", "url", url, "stream", streamName)
-	return &Queue{nc: nc, js: js}, nil
+	return &Queue{nc: nc, js: js, cfg: cfg}, nil
 }
 
 // SetBreaker attaches a circuit breaker to the publish path.
@@ -93,61 +106,29 @@ func (q *Queue) Publish(ctx context.Context, subject string, data []byte) error
 }
 
 // Subscribe registers a handler for messages on the given subject.
-// Messages are validated against known schemas before processing.
-// Failed messages are retried up to maxRetries times, then moved to a DLQ.
+//
+// Each subject is consumed by a durable consumer with explicit acks, so
+// messages survive restarts and are shared between instances. Delivery
+// pauses while cfg.MaxAckPending messages are unacknowledged. Messages
+// are validated against known schemas before processing; invalid messages
+// and messages whose handler fails cfg.MaxDeliver times are moved to the
+// dead-letter stream.
 func (q *Queue) Subscribe(ctx context.Context, subject string, handler messagequeue.Handler) (func(), error) {
 	consumer, err := q.js.CreateOrUpdateConsumer(ctx, streamName, jetstream.ConsumerConfig{
+		Durable:       durableName(subject),
 		FilterSubject: subject,
+		DeliverPolicy: jetstream.DeliverNewPolicy,
 		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       q.cfg.AckWait,
+		MaxAckPending: q.cfg.MaxAckPending,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("nats consumer create: %w", err)
 	}
 
 	cons, err := consumer.Consume(func(msg jetstream.Msg) {
-		// Extract request ID from NATS headers into context
-		msgCtx := ctx
-		hdrs := msg.Headers()
-		if hdrs != nil {
-			if reqID := hdrs.Get(headerRequestID); reqID != "" {
-				msgCtx = logger.WithRequestID(msgCtx, reqID)
-			}
-		}
-
-		// Schema validation — reject invalid messages immediately to DLQ
-		if err := messagequeue.Validate(msg.Subject(), msg.Data()); err != nil {
-			slog.Error("message validation failed",
-				"subject", msg.Subject(),
-				"request_id", logger.RequestID(msgCtx),
-				"error", err,
-			)
-			q.moveToDLQ(ctx, msg)
-			return
-		}
-
-		if err := handler(msgCtx, msg.Subject(), msg.Data()); err != nil {
-			retries := retryCount(hdrs)
-			slog.Error("message handler failed",
-				"subject", msg.Subject(),
-				"request_id", logger.RequestID(msgCtx),
-				"retry", retries,
-				"error", err,
-			)
-
-			if retries >= maxRetries {
-				q.moveToDLQ(ctx, msg)
-				return
-			}
-
-			if nakErr := msg.NakWithDelay(nakDelay); nakErr != nil {
-				slog.Error("nats nak failed", "error", nakErr)
-			}
-			return
-		}
-		if ackErr := msg.Ack(); ackErr != nil {
-			slog.Error("nats ack failed", "error", ackErr)
-		}
-	})
+		q.handle(ctx, msg, handler)
+	}, jetstream.PullMaxMessages(q.cfg.MaxAckPending))
 	if err != nil {
 		return nil, fmt.Errorf("nats consume: %w", err)
 	}
@@ -155,45 +136,102 @@ func (q *Queue) Subscribe(ctx context.Context, subject string, handler messagequ
 	return cons.Stop, nil
 }
 
-// moveToDLQ acks the original message and publishes a copy to {subject}.dlq.
-func (q *Queue) moveToDLQ(ctx context.Context, msg jetstream.Msg) {
-	dlqSubject := msg.Subject() + ".dlq"
-	dlqMsg := &nats.Msg{
-		Subject: dlqSubject,
-		Data:    msg.Data(),
-	}
+// handle processes one delivery and acks, naks or dead-letters it.
+func (q *Queue) handle(ctx context.Context, msg jetstream.Msg, handler messagequeue.Handler) {
+	// Extract request ID from NATS headers into context
+	msgCtx := ctx
 	if hdrs := msg.Headers(); hdrs != nil {
-		dlqMsg.Header = hdrs
+		if reqID := hdrs.Get(headerRequestID); reqID != "" {
+			msgCtx = logger.WithRequestID(msgCtx, reqID)
+		}
 	}
 
-	if _, err := q.js.PublishMsg(ctx, dlqMsg); err != nil {
-		slog.Error("failed to publish to DLQ",
-			"dlq_subject", dlqSubject,
+	deliveries := 1
+	if md, err := msg.Metadata(); err == nil {
+		deliveries = int(md.NumDelivered)
+	}
+
+	// A message redelivered after its last allowed attempt timed out (e.g.
+	// the process crashed while handling it) is not handled again.
+	if deliveries > q.cfg.MaxDeliver {
+		q.moveToDLQ(ctx, msg, deliveries, fmt.Errorf("not acknowledged after %d deliveries", deliveries-1))
+		return
+	}
+
+	// Schema validation — reject invalid messages immediately to DLQ
+	if err := messagequeue.Validate(msg.Subject(), msg.Data()); err != nil {
+		slog.Error("message validation failed",
+			"subject", msg.Subject(),
+			"request_id", logger.RequestID(msgCtx),
 			"error", err,
 		)
-	} else {
-		slog.Warn("message moved to DLQ",
+		q.moveToDLQ(ctx, msg, deliveries, err)
+		return
+	}
+
+	stop := keepAlive(msg, q.cfg.AckWait)
+	err := handler(msgCtx, msg.Subject(), msg.Data())
+	stop()
+	if err != nil {
+		slog.Error("message handler failed",
 			"subject", msg.Subject(),
-			"dlq_subject", dlqSubject,
+			"request_id", logger.RequestID(msgCtx),
+			"delivery", deliveries,
+			"error", err,
 		)
-	}
 
-	// Ack the original to remove it from the main stream
+		if deliveries >= q.cfg.MaxDeliver {
+			q.moveToDLQ(ctx, msg, deliveries, err)
+			return
+		}
+
+		if nakErr := msg.NakWithDelay(nakDelay(deliveries)); nakErr != nil {
+			slog.Error("nats nak failed", "error", nakErr)
+		}
+		return
+	}
 	if ackErr := msg.Ack(); ackErr != nil {
-		slog.Error("nats ack (dlq) failed", "error", ackErr)
+		slog.Error("nats ack failed", "error", ackErr)
 	}
 }
 
-func retryCount(hdrs nats.Header) int {
-	if hdrs == nil {
-		return 0
-	}
-	val := hdrs.Get(headerRetryCount)
-	if val == "" {
-		return 0
+// keepAlive extends the ack deadline of msg while a handler runs, so slow
+// handlers are not mistaken for lost deliveries. The returned function
+// stops it.
+func keepAlive(msg jetstream.Msg, ackWait time.Duration) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(ackWait / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := msg.InProgress(); err != nil {
+					slog.Debug("nats in-progress failed", "error", err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// nakDelay returns the redelivery delay after the given number of failed
+// deliveries: nakBaseDelay doubled per attempt, capped at nakMaxDelay.
+func nakDelay(deliveries int) time.Duration {
+	d := nakBaseDelay
+	for i := 1; i < deliveries && d < nakMaxDelay; i++ {
+		d *= 2
 	}
-	n, _ := strconv.Atoi(val)
-	return n
+	return min(d, nakMaxDelay)
+}
+
+// durableName returns the consumer name for a subject, e.g.
+// "codeforge-runs-toolcall-result" or "codeforge-tasks-agent-any".
+func durableName(subject string) string {
+	r := strings.NewReplacer(".", "-", "*", "any", ">", "all")
+	return "codeforge-" + r.Replace(subject)
 }
 
 // Drain gracefully drains all subscriptions, waits for pending messages,
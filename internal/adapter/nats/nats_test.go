@@ -0,0 +1,54 @@
+package nats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestDurableName(t *testing.T) {
+	tests := map[string]string{
+		"tasks.result":          "codeforge-tasks-result",
+		"runs.toolcall.request": "codeforge-runs-toolcall-request",
+		"tasks.agent.*":         "codeforge-tasks-agent-any",
+		"runs.>":                "codeforge-runs-all",
+	}
+	for subject, want := range tests {
+		if got := durableName(subject); got != want {
+			t.Errorf("durableName(%q) = %q, want %q", subject, got, want)
+		}
+	}
+}
+
+func TestNakDelay(t *testing.T) {
+	for deliveries, want := range map[int]time.Duration{
+		1:  2 * time.Second,
+		2:  4 * time.Second,
+		3:  8 * time.Second,
+		10: time.Minute,
+	} {
+		if got := nakDelay(deliveries); got != want {
+			t.Errorf("nakDelay(%d) = %s, want %s", deliveries, got, want)
+		}
+	}
+}
+
+func TestDeadLetterFromHeaders(t *testing.T) {
+	hdrs := nats.Header{}
+	hdrs.Set(headerRequestID, "req-1")
+	hdrs.Set(headerDLQError, "handler failed")
+	hdrs.Set(headerDLQDeliveries, "4")
+	hdrs.Set(headerDLQTime, "2026-01-02T03:04:05Z")
+
+	dl := deadLetter(7, "dlq.runs.complete", hdrs, []byte(`{"run_id":"r1"}`))
+	if dl.Seq != 7 || dl.Subject != "runs.complete" || dl.Data != `{"run_id":"r1"}` {
+		t.Fatalf("unexpected dead letter %+v", dl)
+	}
+	if dl.Error != "handler failed" || dl.Deliveries != 4 || dl.RequestID != "req-1" {
+		t.Fatalf("unexpected failure fields %+v", dl)
+	}
+	if want := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC); !dl.FailedAt.Equal(want) {
+		t.Fatalf("failed_at = %s, want %s", dl.FailedAt, want)
+	}
+}
@@ -0,0 +1,85 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/notification"
+)
+
+// discordMaxContent is the most characters Discord accepts in a message.
+const discordMaxContent = 2000
+
+// Discord posts messages to a Discord webhook as markdown.
+type Discord struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewDiscord creates a notifier for the webhook at url.
+func NewDiscord(url string) *Discord {
+	return &Discord{url: url, httpClient: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// discordMessage is the payload of a webhook execution.
+type discordMessage struct {
+	Content         string                 `json:"content"`
+	AllowedMentions discordAllowedMentions `json:"allowed_mentions"`
+}
+
+type discordAllowedMentions struct {
+	Parse []string `json:"parse"`
+}
+
+// Send posts the message. Only the mentions in the message notify anyone.
+func (d *Discord) Send(ctx context.Context, msg *notification.Message) error {
+	return postJSON(ctx, d.httpClient, d.url, discordMessage{
+		Content:         truncate(DiscordContent(msg), discordMaxContent),
+		AllowedMentions: discordAllowedMentions{Parse: []string{"users", "roles", "everyone"}},
+	})
+}
+
+// DiscordContent renders a message as Discord markdown: the mentions, the
+// title in bold, the text, the URL and a line per digest item.
+func DiscordContent(msg *notification.Message) string {
+	var b strings.Builder
+	for _, m := range msg.Mentions {
+		b.WriteString(discordMention(m) + " ")
+	}
+	b.WriteString("**" + msg.Title + "**")
+	if msg.Text != "" {
+		b.WriteString("\n" + msg.Text)
+	}
+	if msg.URL != "" {
+		b.WriteString("\n<" + msg.URL + ">")
+	}
+	for i := range msg.Items {
+		b.WriteString("\n- " + msg.Items[i].Title)
+		if msg.Items[i].URL != "" {
+			b.WriteString(" <" + msg.Items[i].URL + ">")
+		}
+	}
+	return b.String()
+}
+
+// discordMention renders user IDs as mentions, role IDs given as &<id> as
+// role mentions and here and everyone as the special mentions. Anything
+// else is shown as @name without notifying anyone.
+func discordMention(m string) string {
+	switch {
+	case m == "here" || m == "everyone":
+		return "@" + m
+	case discordID(m):
+		return "<@" + m + ">"
+	case strings.HasPrefix(m, "&") && discordID(m[1:]):
+		return "<@" + m + ">"
+	}
+	return "@" + m
+}
+
+// discordID reports whether s is a Discord snowflake ID.
+func discordID(s string) bool {
+	return s != "" && !strings.ContainsFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+}
@@ -0,0 +1,223 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/notification"
+)
+
+// emailHTML renders the HTML part of a notification email. Approval and
+// run messages link to the plan or run in the web UI, digests list their
+// events.
+var emailHTML = htmltemplate.Must(htmltemplate.New("email").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: -apple-system, Segoe UI, Helvetica, Arial, sans-serif; color: #1f2328; max-width: 640px">
+<h2 style="margin: 0 0 12px">{{.Title}}</h2>
+{{- if .Text}}
+<p style="white-space: pre-wrap">{{.Text}}</p>
+{{- end}}
+{{- if .Items}}
+<ul>
+{{- range .Items}}
+<li>{{if .URL}}<a href="{{.URL}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}{{if .Text}}: {{.Text}}{{end}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .URL}}
+<p><a href="{{.URL}}" style="display: inline-block; padding: 8px 16px; background: #1f6feb; color: #ffffff; border-radius: 6px; text-decoration: none">{{.Label}}</a></p>
+{{- end}}
+<p style="color: #656d76; font-size: 12px">Sent by CodeForge for a notification rule of your project.</p>
+</body>
+</html>
+`))
+
+// emailView is what emailHTML renders.
+type emailView struct {
+	*notification.Message
+	Label string
+}
+
+// Email sends messages as HTML email, with a plain text alternative,
+// through an SMTP relay. The connection is upgraded with STARTTLS when the
+// relay offers it; credentials are only sent over TLS or to localhost.
+type Email struct {
+	host     string
+	addr     string
+	username string
+	password string
+	from     *mail.Address
+	to       []string
+}
+
+// NewEmail creates an email notifier from the config of an email rule
+// with the server's SMTP settings added.
+func NewEmail(config map[string]string) (*Email, error) {
+	host := config[notification.ConfigSMTPHost]
+	if host == "" {
+		return nil, errors.New("email notifications need notify.smtp_host")
+	}
+	port, err := strconv.Atoi(config[notification.ConfigSMTPPort])
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp port: %w", err)
+	}
+	from, err := mail.ParseAddress(config[notification.ConfigSMTPFrom])
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp from address: %w", err)
+	}
+	to, err := notification.Recipients(config[notification.ConfigTo])
+	if err != nil {
+		return nil, err
+	}
+	return &Email{
+		host:     host,
+		addr:     net.JoinHostPort(host, strconv.Itoa(port)),
+		username: config[notification.ConfigSMTPUsername],
+		password: config[notification.ConfigSMTPPassword],
+		from:     from,
+		to:       to,
+	}, nil
+}
+
+// Send mails the message to the rule's recipients. The context's deadline
+// bounds the whole SMTP session.
+func (e *Email) Send(ctx context.Context, msg *notification.Message) error {
+	body, err := e.compose(msg)
+	if err != nil {
+		return err
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", e.addr)
+	if err != nil {
+		return fmt.Errorf("connect to mail server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, e.host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("connect to mail server: %w", err)
+	}
+	defer func() { _ = c.Close() }()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: e.host, MinVersion: tls.VersionTLS12}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if e.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.username, e.password, e.host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+	if err := c.Mail(e.from.Address); err != nil {
+		return fmt.Errorf("smtp mail from: %w", err)
+	}
+	for _, to := range e.to {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("smtp rcpt to %s: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	return c.Quit()
+}
+
+// compose builds the email: headers and a multipart/alternative body with
+// a plain text and an HTML part.
+func (e *Email) compose(msg *notification.Message) ([]byte, error) {
+	var html bytes.Buffer
+	if err := emailHTML.Execute(&html, emailView{Message: msg, Label: linkLabel(msg)}); err != nil {
+		return nil, fmt.Errorf("render email: %w", err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", emailText(msg)},
+		{"text/html; charset=utf-8", html.String()},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("compose email: %w", err)
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write([]byte(part.content)); err != nil {
+			return nil, fmt.Errorf("compose email: %w", err)
+		}
+		if err := qw.Close(); err != nil {
+			return nil, fmt.Errorf("compose email: %w", err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("compose email: %w", err)
+	}
+
+	at := msg.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+	var out bytes.Buffer
+	for _, h := range [][2]string{
+		{"From", e.from.String()},
+		{"To", strings.Join(e.to, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Title)},
+		{"Date", at.Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + mw.Boundary()},
+	} {
+		out.WriteString(h[0] + ": " + h[1] + "\r\n")
+	}
+	out.WriteString("\r\n")
+	out.Write(body.Bytes())
+	return out.Bytes(), nil
+}
+
+// emailText renders the plain text part of a message.
+func emailText(msg *notification.Message) string {
+	var b strings.Builder
+	b.WriteString(msg.Title + "\n")
+	if msg.Text != "" {
+		b.WriteString("\n" + msg.Text + "\n")
+	}
+	if len(msg.Items) > 0 {
+		b.WriteString("\n")
+	}
+	for i := range msg.Items {
+		b.WriteString("- " + msg.Items[i].Title)
+		if msg.Items[i].URL != "" {
+			b.WriteString(" <" + msg.Items[i].URL + ">")
+		}
+		b.WriteString("\n")
+	}
+	if msg.URL != "" {
+		b.WriteString("\n" + linkLabel(msg) + ": " + msg.URL + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,298 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/slack"
+	"github.com/Strob0t/CodeForge/internal/domain/notification"
+	"github.com/Strob0t/CodeForge/internal/port/notifier"
+)
+
+func testMessage() *notification.Message {
+	return &notification.Message{
+		EventType: "plan.approval.escalated",
+		Title:     "Approval overdue: release / gate",
+		Text:      "0 of 2 approval(s) so far: a <b> & c",
+		URL:       "https://codeforge.example/projects/p1?plan=pl1",
+		Mentions:  []string{"U024BE7LH", "S0614TZR7", "here", "lead"},
+	}
+}
+
+func TestSlack_Send(t *testing.T) {
+	var got slackMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	n, err := notifier.New(string(notification.KindSlack), map[string]string{notification.ConfigWebhookURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Send(context.Background(), testMessage()); err != nil {
+		t.Fatal(err)
+	}
+	want := "<@U024BE7LH> <!subteam^S0614TZR7> <!here> @lead " +
+		"*<https://codeforge.example/projects/p1?plan=pl1|Approval overdue: release / gate>*\n" +
+		"0 of 2 approval(s) so far: a &lt;b&gt; &amp; c"
+	if got.Text != want {
+		t.Fatalf("unexpected text:\n%s\nwant:\n%s", got.Text, want)
+	}
+}
+
+func TestDiscord_SendDigest(t *testing.T) {
+	var got discordMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	msg := &notification.Message{
+		Title:    "Daily digest: 2 notifications",
+		Mentions: []string{"80351110224678912", "&41771983423143936"},
+		Items: []notification.Message{
+			{Title: "Run completed: fix login", URL: "https://codeforge.example/projects/p1?run=r1"},
+			{Title: "Approval vote: release / gate"},
+		},
+	}
+	if err := NewDiscord(srv.URL).Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	want := "<@80351110224678912> <@&41771983423143936> **Daily digest: 2 notifications**\n" +
+		"- Run completed: fix login <https://codeforge.example/projects/p1?run=r1>\n" +
+		"- Approval vote: release / gate"
+	if got.Content != want {
+		t.Fatalf("unexpected content:\n%s\nwant:\n%s", got.Content, want)
+	}
+}
+
+func TestWebhook_ErrorHidesURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	err := NewSlack(srv.URL+"/services/T0/B0/secret", false).Send(context.Background(), testMessage())
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "invalid_token") {
+		t.Fatalf("expected the status and body in the error, got %v", err)
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Fatalf("expected the webhook URL left out of the error, got %v", err)
+	}
+
+	srv.Close()
+	if err := NewSlack(srv.URL+"/services/T0/B0/secret", false).Send(context.Background(), testMessage()); err == nil || strings.Contains(err.Error(), "secret") {
+		t.Fatalf("expected a connection error without the webhook URL, got %v", err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	// The limit is in bytes and never splits a character.
+	if got := truncate("héllo wörld", 8); got != "héll…" {
+		t.Fatalf("unexpected truncation %q", got)
+	}
+	if got := truncate("héllo", 5); got != "h…" {
+		t.Fatalf("unexpected truncation %q", got)
+	}
+}
+
+func TestTeams_Send(t *testing.T) {
+	var got teamsMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	msg := testMessage()
+	msg.Mentions = []string{"lead@contoso.com"}
+	if err := NewTeams(srv.URL).Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Attachments) != 1 || got.Attachments[0].ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Fatalf("expected one Adaptive Card, got %+v", got)
+	}
+	card := got.Attachments[0].Content
+	if len(card.Body) != 3 || card.Body[0].Text != "<at>lead@contoso.com</at>" || card.Body[1].Text != msg.Title || card.Body[2].Text != msg.Text {
+		t.Fatalf("unexpected card body %+v", card.Body)
+	}
+	if len(card.MSTeams.Entities) != 1 || card.MSTeams.Entities[0].Mentioned.ID != "lead@contoso.com" {
+		t.Fatalf("expected the mention as an entity, got %+v", card.MSTeams)
+	}
+	if len(card.Actions) != 1 || card.Actions[0].Title != "Review approval" || card.Actions[0].URL != msg.URL {
+		t.Fatalf("expected a button to the approval, got %+v", card.Actions)
+	}
+}
+
+// smtpServer accepts one SMTP session on a local port and sends the
+// recipients and the message data it received.
+func smtpServer(t *testing.T) (host, port string, got <-chan []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	ch := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		tp := textproto.NewConn(conn)
+		var received []string
+		_ = tp.PrintfLine("220 localhost ESMTP test")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch cmd {
+			case "EHLO", "HELO", "MAIL":
+				_ = tp.PrintfLine("250 OK")
+			case "RCPT":
+				received = append(received, strings.TrimPrefix(line, "RCPT TO:"))
+				_ = tp.PrintfLine("250 OK")
+			case "DATA":
+				_ = tp.PrintfLine("354 Go ahead")
+				data, err := tp.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				received = append(received, string(data))
+				_ = tp.PrintfLine("250 Queued")
+			case "QUIT":
+				_ = tp.PrintfLine("221 Bye")
+				ch <- received
+				return
+			default:
+				_ = tp.PrintfLine("502 Not implemented")
+			}
+		}
+	}()
+	host, port, _ = net.SplitHostPort(ln.Addr().String())
+	return host, port, ch
+}
+
+func TestEmail_Send(t *testing.T) {
+	host, port, got := smtpServer(t)
+	n, err := notifier.New(string(notification.KindEmail), map[string]string{
+		notification.ConfigTo:       "Ops <ops@example.com>, lead@example.com",
+		notification.ConfigSMTPHost: host,
+		notification.ConfigSMTPPort: port,
+		notification.ConfigSMTPFrom: "CodeForge <codeforge@example.com>",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	msg := testMessage()
+	msg.Title = "Approval requested: release / gate"
+	msg.EventType = "plan.approval.requested"
+	if err := n.Send(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	received := <-got
+	if len(received) != 3 || received[0] != "<ops@example.com>" || received[1] != "<lead@example.com>" {
+		t.Fatalf("expected both recipients, got %q", received)
+	}
+	m, err := mail.ReadMessage(strings.NewReader(received[2]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subj, _ := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject")); subj != msg.Title {
+		t.Errorf("unexpected subject %q", subj)
+	}
+	_, params, _ := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	var parts []string
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		data, _ := io.ReadAll(p)
+		parts = append(parts, string(data))
+	}
+	if len(parts) != 2 || !strings.Contains(parts[0], "Review approval: "+msg.URL) {
+		t.Fatalf("expected a text part linking the approval, got %q", parts)
+	}
+	if html := parts[1]; !strings.Contains(html, `href="https://codeforge.example/projects/p1?plan=pl1"`) ||
+		!strings.Contains(html, "a &lt;b&gt; &amp; c") || !strings.Contains(html, ">Review approval</a>") {
+		t.Fatalf("expected escaped HTML with an approval link, got %s", html)
+	}
+}
+
+func TestEmail_Config(t *testing.T) {
+	base := map[string]string{
+		notification.ConfigTo:       "ops@example.com",
+		notification.ConfigSMTPHost: "smtp.example.com",
+		notification.ConfigSMTPPort: "587",
+		notification.ConfigSMTPFrom: "codeforge@example.com",
+	}
+	if _, err := NewEmail(base); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{notification.ConfigTo, notification.ConfigSMTPHost, notification.ConfigSMTPPort, notification.ConfigSMTPFrom} {
+		cfg := maps.Clone(base)
+		delete(cfg, key)
+		if _, err := NewEmail(cfg); err == nil {
+			t.Errorf("expected an error without %s", key)
+		}
+	}
+}
+
+func TestSlack_InteractiveApproval(t *testing.T) {
+	var got slackMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	msg := testMessage()
+	msg.Approval = &notification.ApprovalRef{PlanID: "pl1", StepID: "st2"}
+	if err := NewSlack(srv.URL, true).Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Blocks) != 2 || got.Blocks[0].Text.Text != got.Text || len(got.Blocks[1].Elements) != 2 {
+		t.Fatalf("expected the text and two buttons, got %+v", got.Blocks)
+	}
+	if b := got.Blocks[1].Elements[0]; b.ActionID != slack.ActionApprove || b.Value != "pl1/st2" {
+		t.Fatalf("unexpected approve button %+v", b)
+	}
+
+	got = slackMessage{}
+	if err := NewSlack(srv.URL, false).Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Blocks) != 0 {
+		t.Fatalf("expected no buttons from a one-way notifier, got %+v", got.Blocks)
+	}
+}
@@ -0,0 +1,21 @@
+package notify
+
+import (
+	"github.com/Strob0t/CodeForge/internal/domain/notification"
+	"github.com/Strob0t/CodeForge/internal/port/notifier"
+)
+
+func init() {
+	notifier.Register(string(notification.KindSlack), func(config map[string]string) (notifier.Notifier, error) {
+		return NewSlack(config[notification.ConfigWebhookURL], config[notification.ConfigInteractive] == "true"), nil
+	})
+	notifier.Register(string(notification.KindDiscord), func(config map[string]string) (notifier.Notifier, error) {
+		return NewDiscord(config[notification.ConfigWebhookURL]), nil
+	})
+	notifier.Register(string(notification.KindTeams), func(config map[string]string) (notifier.Notifier, error) {
+		return NewTeams(config[notification.ConfigWebhookURL]), nil
+	})
+	notifier.Register(string(notification.KindEmail), func(config map[string]string) (notifier.Notifier, error) {
+		return NewEmail(config)
+	})
+}
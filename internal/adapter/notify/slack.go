@@ -0,0 +1,123 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/slack"
+	"github.com/Strob0t/CodeForge/internal/domain/notification"
+)
+
+// Slack posts messages to a Slack incoming webhook as mrkdwn text.
+// Interactive notifiers add Approve and Reject buttons to messages asking
+// to decide an approval step; the clicks arrive at the Slack app's
+// interactivity URL (see slack.ParseInteraction).
+type Slack struct {
+	url         string
+	interactive bool
+	httpClient  *http.Client
+}
+
+// NewSlack creates a notifier for the incoming webhook at url.
+func NewSlack(url string, interactive bool) *Slack {
+	return &Slack{url: url, interactive: interactive, httpClient: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// slackSectionMax is the most characters Slack accepts in a section block.
+const slackSectionMax = 3000
+
+// slackMessage is the payload of an incoming webhook. Text is the
+// notification fallback when there are blocks.
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks,omitempty"`
+}
+
+type slackBlock struct {
+	Type     string         `json:"type"`
+	Text     *slackText     `json:"text,omitempty"`
+	Elements []slackElement `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackElement struct {
+	Type     string    `json:"type"`
+	Text     slackText `json:"text"`
+	ActionID string    `json:"action_id"`
+	Value    string    `json:"value"`
+	Style    string    `json:"style,omitempty"`
+}
+
+// Send posts the message.
+func (s *Slack) Send(ctx context.Context, msg *notification.Message) error {
+	payload := slackMessage{Text: SlackText(msg)}
+	if s.interactive && msg.Approval != nil {
+		value := msg.Approval.PlanID + "/" + msg.Approval.StepID
+		payload.Blocks = []slackBlock{
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: truncate(payload.Text, slackSectionMax)}},
+			{Type: "actions", Elements: []slackElement{
+				{Type: "button", Text: slackText{Type: "plain_text", Text: "Approve"}, ActionID: slack.ActionApprove, Value: value, Style: "primary"},
+				{Type: "button", Text: slackText{Type: "plain_text", Text: "Reject"}, ActionID: slack.ActionReject, Value: value, Style: "danger"},
+			}},
+		}
+	}
+	return postJSON(ctx, s.httpClient, s.url, payload)
+}
+
+// SlackText renders a message as Slack mrkdwn: the mentions, the title in
+// bold linked to the message's URL, the text and a line per digest item.
+func SlackText(msg *notification.Message) string {
+	var b strings.Builder
+	for _, m := range msg.Mentions {
+		b.WriteString(slackMention(m) + " ")
+	}
+	b.WriteString("*" + slackLink(msg.URL, msg.Title) + "*")
+	if msg.Text != "" {
+		b.WriteString("\n" + slackEscape(msg.Text))
+	}
+	for i := range msg.Items {
+		b.WriteString("\n• " + slackLink(msg.Items[i].URL, msg.Items[i].Title))
+	}
+	return b.String()
+}
+
+// slackMention renders user IDs (U…, W…) and user group IDs (S…) as
+// mentions, and here, channel and everyone as the special mentions.
+// Anything else is shown as @name without notifying anyone.
+func slackMention(m string) string {
+	switch {
+	case m == "here" || m == "channel" || m == "everyone":
+		return "<!" + m + ">"
+	case slackID(m, 'U') || slackID(m, 'W'):
+		return "<@" + m + ">"
+	case slackID(m, 'S'):
+		return "<!subteam^" + m + ">"
+	}
+	return "@" + slackEscape(m)
+}
+
+// slackID reports whether s is a Slack ID with the given prefix.
+func slackID(s string, prefix byte) bool {
+	if len(s) < 2 || s[0] != prefix {
+		return false
+	}
+	return !strings.ContainsFunc(s[1:], func(r rune) bool { return (r < 'A' || r > 'Z') && (r < '0' || r > '9') })
+}
+
+func slackLink(url, text string) string {
+	if url == "" {
+		return slackEscape(text)
+	}
+	return "<" + url + "|" + slackEscape(text) + ">"
+}
+
+// slackEscape escapes the characters Slack reserves for links and mentions.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,122 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/notification"
+)
+
+// Teams posts messages to a Microsoft Teams incoming webhook (a Workflows
+// webhook of a channel) as Adaptive Cards.
+type Teams struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewTeams creates a notifier for the incoming webhook at url.
+func NewTeams(url string) *Teams {
+	return &Teams{url: url, httpClient: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// teamsMessage is the payload of an incoming webhook: a message with one
+// Adaptive Card attachment.
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string         `json:"$schema"`
+	Type    string         `json:"type"`
+	Version string         `json:"version"`
+	Body    []teamsBlock   `json:"body"`
+	Actions []teamsAction  `json:"actions,omitempty"`
+	MSTeams teamsCardProps `json:"msteams"`
+}
+
+type teamsBlock struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Wrap   bool   `json:"wrap"`
+	Weight string `json:"weight,omitempty"`
+	Size   string `json:"size,omitempty"`
+}
+
+type teamsAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+type teamsCardProps struct {
+	Width    string         `json:"width"`
+	Entities []teamsMention `json:"entities,omitempty"`
+}
+
+type teamsMention struct {
+	Type      string           `json:"type"`
+	Text      string           `json:"text"`
+	Mentioned teamsMentionedID `json:"mentioned"`
+}
+
+type teamsMentionedID struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Send posts the message.
+func (t *Teams) Send(ctx context.Context, msg *notification.Message) error {
+	return postJSON(ctx, t.httpClient, t.url, teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content:     newTeamsCard(msg),
+		}},
+	})
+}
+
+// newTeamsCard renders a message as an Adaptive Card: the mentions, the title
+// in bold, the text, a line per digest item and a button opening the
+// message's URL. Mentions are Entra ID object IDs or user principal names
+// (user@contoso.com).
+func newTeamsCard(msg *notification.Message) teamsCard {
+	card := teamsCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		MSTeams: teamsCardProps{Width: "Full"},
+	}
+	if len(msg.Mentions) > 0 {
+		tags := make([]string, len(msg.Mentions))
+		for i, m := range msg.Mentions {
+			tags[i] = "<at>" + m + "</at>"
+			card.MSTeams.Entities = append(card.MSTeams.Entities, teamsMention{
+				Type: "mention", Text: tags[i], Mentioned: teamsMentionedID{ID: m, Name: m},
+			})
+		}
+		card.Body = append(card.Body, teamsBlock{Type: "TextBlock", Text: strings.Join(tags, " "), Wrap: true})
+	}
+	card.Body = append(card.Body, teamsBlock{Type: "TextBlock", Text: msg.Title, Wrap: true, Weight: "Bolder", Size: "Medium"})
+	if msg.Text != "" {
+		card.Body = append(card.Body, teamsBlock{Type: "TextBlock", Text: msg.Text, Wrap: true})
+	}
+	for i := range msg.Items {
+		item := "- " + msg.Items[i].Title
+		if msg.Items[i].URL != "" {
+			item = "- [" + msg.Items[i].Title + "](" + msg.Items[i].URL + ")"
+		}
+		card.Body = append(card.Body, teamsBlock{Type: "TextBlock", Text: item, Wrap: true})
+	}
+	if msg.URL != "" {
+		card.Actions = []teamsAction{{Type: "Action.OpenUrl", Title: linkLabel(msg), URL: msg.URL}}
+	}
+	return card
+}
@@ -0,0 +1,73 @@
+// Package notify implements the notifier port for chat webhooks and email.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Strob0t/CodeForge/internal/domain/notification"
+)
+
+// maxErrorBody limits how much of an error response is kept.
+const maxErrorBody = 1024
+
+// postJSON sends v to a webhook. Responses other than 2xx are errors.
+// Webhook URLs carry their credentials, so errors leave the URL out.
+func postJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode message: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post to webhook: %w", redactURL(err, url))
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return fmt.Errorf("post to webhook: status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// redactURL removes url from the message of err.
+func redactURL(err error, url string) error {
+	return fmt.Errorf("%s", strings.ReplaceAll(err.Error(), url, "<webhook>"))
+}
+
+// truncate shortens s to at most n bytes without splitting a rune.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[:n-len("…")]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s + "…"
+}
+
+// linkLabel names what the URL of a message opens, for systems that show
+// links as buttons.
+func linkLabel(msg *notification.Message) string {
+	switch {
+	case strings.HasPrefix(msg.EventType, "plan.approval."):
+		return "Review approval"
+	case strings.HasPrefix(msg.EventType, "run."):
+		return "View run"
+	}
+	return "Open in CodeForge"
+}
@@ -0,0 +1,19 @@
+package notion
+
+import (
+	"fmt"
+
+	"github.com/Strob0t/CodeForge/internal/domain/knowledge"
+	"github.com/Strob0t/CodeForge/internal/port/docsource"
+)
+
+func init() {
+	docsource.Register(string(knowledge.KindNotion), func(config map[string]string) (docsource.Source, error) {
+		for _, key := range []string{knowledge.ConfigPages, knowledge.ConfigToken} {
+			if config[key] == "" {
+				return nil, fmt.Errorf("notion: %s is required", key)
+			}
+		}
+		return NewSource(APIURL, knowledge.List(config[knowledge.ConfigPages]), knowledge.MaxDepthOf(config), config[knowledge.ConfigToken]), nil
+	})
+}
@@ -0,0 +1,204 @@
+// Package notion implements the docsource.Source interface against the
+// Notion API, fetching pages and their child pages.
+package notion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/knowledge"
+)
+
+const (
+	// APIURL is the base URL of the Notion API.
+	APIURL = "https://api.notion.com"
+
+	apiVersion = "2022-06-28"
+	pageSize   = 100
+)
+
+// Source fetches Notion pages and their child pages, as deep as maxDepth.
+type Source struct {
+	baseURL    string
+	pages      []string
+	maxDepth   int
+	token      string
+	httpClient *http.Client
+}
+
+// NewSource creates a Source for the page IDs, authenticating with the
+// token of an internal integration the pages are shared with.
+func NewSource(baseURL string, pages []string, maxDepth int, token string) *Source {
+	return &Source{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		pages:    pages,
+		maxDepth: maxDepth,
+		token:    token,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+type page struct {
+	URL        string `json:"url"`
+	Properties map[string]struct {
+		Type  string     `json:"type"`
+		Title []richText `json:"title"`
+	} `json:"properties"`
+}
+
+type richText struct {
+	PlainText string `json:"plain_text"`
+}
+
+type block struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	HasChildren bool   `json:"has_children"`
+}
+
+// Fetch implements docsource.Source. Pages are read breadth-first, so the
+// configured pages come before their child pages when limit is reached.
+func (s *Source) Fetch(ctx context.Context, limit int) ([]knowledge.Document, error) {
+	type queued struct {
+		id    string
+		depth int
+	}
+	var queue []queued
+	for _, id := range s.pages {
+		queue = append(queue, queued{id, 0})
+	}
+	seen := make(map[string]bool)
+	var docs []knowledge.Document
+	for len(queue) > 0 && len(docs) < limit {
+		q := queue[0]
+		queue = queue[1:]
+		if seen[q.id] {
+			continue
+		}
+		seen[q.id] = true
+
+		var p page
+		if err := s.get(ctx, "/v1/pages/"+url.PathEscape(q.id), &p); err != nil {
+			return nil, err
+		}
+		var text strings.Builder
+		children, err := s.blockText(ctx, q.id, &text)
+		if err != nil {
+			return nil, err
+		}
+		if q.depth < s.maxDepth {
+			for _, c := range children {
+				queue = append(queue, queued{c, q.depth + 1})
+			}
+		}
+		if content := strings.TrimSpace(text.String()); content != "" {
+			docs = append(docs, knowledge.Document{URL: p.URL, Title: p.title(), Content: content})
+		}
+	}
+	return docs, nil
+}
+
+// title returns the page's title property.
+func (p *page) title() string {
+	for _, prop := range p.Properties {
+		if prop.Type == "title" {
+			return plain(prop.Title)
+		}
+	}
+	return ""
+}
+
+// blockText writes the text of a block's children to b, one line per
+// block, descending into nested blocks. It returns the IDs of the child
+// pages, which are documents of their own.
+func (s *Source) blockText(ctx context.Context, id string, b *strings.Builder) ([]string, error) {
+	var pages []string
+	cursor := ""
+	for {
+		path := fmt.Sprintf("/v1/blocks/%s/children?page_size=%d", url.PathEscape(id), pageSize)
+		if cursor != "" {
+			path += "&start_cursor=" + url.QueryEscape(cursor)
+		}
+		var resp struct {
+			Results    []json.RawMessage `json:"results"`
+			HasMore    bool              `json:"has_more"`
+			NextCursor string            `json:"next_cursor"`
+		}
+		if err := s.get(ctx, path, &resp); err != nil {
+			return nil, err
+		}
+		for _, raw := range resp.Results {
+			var blk block
+			if err := json.Unmarshal(raw, &blk); err != nil {
+				return nil, fmt.Errorf("notion: unmarshal block: %w", err)
+			}
+			if blk.Type == "child_page" {
+				pages = append(pages, blk.ID)
+				continue
+			}
+			var content map[string]struct {
+				RichText []richText `json:"rich_text"`
+			}
+			_ = json.Unmarshal(raw, &content)
+			if line := plain(content[blk.Type].RichText); line != "" {
+				b.WriteString(line)
+				b.WriteByte('\n')
+			}
+			if blk.HasChildren {
+				nested, err := s.blockText(ctx, blk.ID, b)
+				if err != nil {
+					return nil, err
+				}
+				pages = append(pages, nested...)
+			}
+		}
+		if !resp.HasMore || resp.NextCursor == "" {
+			return pages, nil
+		}
+		cursor = resp.NextCursor
+	}
+}
+
+func plain(rt []richText) string {
+	var b strings.Builder
+	for _, t := range rt {
+		b.WriteString(t.PlainText)
+	}
+	return b.String()
+}
+
+func (s *Source) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+path, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("notion: create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	req.Header.Set("Notion-Version", apiVersion)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("notion: http request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("notion: read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("notion: API error %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("notion: unmarshal response: %w", err)
+	}
+	return nil
+}
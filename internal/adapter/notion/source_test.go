@@ -0,0 +1,66 @@
+package notion_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/notion"
+)
+
+func TestFetch_ChildPages(t *testing.T) {
+	pages := map[string]string{
+		"/v1/pages/root":  `{"url": "https://notion.so/Runbooks-root", "properties": {"Name": {"type": "title", "title": [{"plain_text": "Runbooks"}]}}}`,
+		"/v1/pages/child": `{"url": "https://notion.so/Deploy-child", "properties": {"title": {"type": "title", "title": [{"plain_text": "Deploy"}]}}}`,
+	}
+	blocks := map[string]string{
+		"root": `{"results": [
+			{"id": "b1", "type": "heading_1", "heading_1": {"rich_text": [{"plain_text": "On call"}]}},
+			{"id": "b2", "type": "toggle", "has_children": true, "toggle": {"rich_text": [{"plain_text": "Escalation"}]}},
+			{"id": "child", "type": "child_page", "child_page": {"title": "Deploy"}}
+		], "has_more": true, "next_cursor": "c2"}`,
+		"root/c2": `{"results": [{"id": "b3", "type": "paragraph", "paragraph": {"rich_text": [{"plain_text": "Page the "}, {"plain_text": "SRE."}]}}]}`,
+		"b2":      `{"results": [{"id": "b4", "type": "bulleted_list_item", "bulleted_list_item": {"rich_text": [{"plain_text": "Call the lead"}]}}]}`,
+		"child":   `{"results": [{"id": "b5", "type": "code", "code": {"rich_text": [{"plain_text": "make deploy"}]}}]}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("Notion-Version") == "" {
+			t.Fatal("expected bearer token and Notion-Version")
+		}
+		if body, ok := pages[r.URL.Path]; ok {
+			_, _ = w.Write([]byte(body))
+			return
+		}
+		id, ok := strings.CutPrefix(strings.TrimSuffix(r.URL.Path, "/children"), "/v1/blocks/")
+		if !ok {
+			t.Fatalf("unexpected request %s", r.URL)
+		}
+		if c := r.URL.Query().Get("start_cursor"); c != "" {
+			id += "/" + c
+		}
+		_, _ = w.Write([]byte(blocks[id]))
+	}))
+	defer srv.Close()
+
+	docs, err := notion.NewSource(srv.URL, []string{"root"}, 1, "secret").Fetch(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("expected the page and its child page, got %+v", docs)
+	}
+	if docs[0].Title != "Runbooks" || docs[0].URL != "https://notion.so/Runbooks-root" ||
+		docs[0].Content != "On call\nEscalation\nCall the lead\nPage the SRE." {
+		t.Fatalf("unexpected page %+v", docs[0])
+	}
+	if docs[1].Title != "Deploy" || docs[1].Content != "make deploy" {
+		t.Fatalf("unexpected child page %+v", docs[1])
+	}
+
+	docs, _ = notion.NewSource(srv.URL, []string{"root"}, 0, "secret").Fetch(context.Background(), 10)
+	if len(docs) != 1 {
+		t.Fatalf("expected no child pages at depth 0, got %+v", docs)
+	}
+}
@@ -0,0 +1,75 @@
+// Package ollama implements the embedding.Provider interface against an
+// Ollama server's native /api/embed endpoint, so workspaces can be indexed
+// without an LLM gateway.
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const providerName = "ollama"
+
+// Embedder calls POST /api/embed on an Ollama server.
+type Embedder struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewEmbedder creates an Embedder for the Ollama server at baseURL.
+// Embedding batches on CPU-only hosts are slow, hence the long timeout.
+func NewEmbedder(baseURL string) *Embedder {
+	return &Embedder{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 5 * time.Minute,
+		},
+	}
+}
+
+// Name returns "ollama".
+func (e *Embedder) Name() string { return providerName }
+
+// Embed sends texts as one batch and returns their vectors in order.
+func (e *Embedder) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]any{"model": model, "input": texts})
+	if err != nil {
+		return nil, fmt.Errorf("ollama: marshal embed request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/api/embed", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("ollama: create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ollama: http request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ollama: read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("ollama: API error %d: %s", resp.StatusCode, string(data))
+	}
+
+	var raw struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("ollama: unmarshal embed response: %w", err)
+	}
+	if len(raw.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("ollama: got %d embeddings for %d inputs", len(raw.Embeddings), len(texts))
+	}
+	return raw.Embeddings, nil
+}
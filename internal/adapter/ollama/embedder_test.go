@@ -0,0 +1,61 @@
+package ollama_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/ollama"
+)
+
+func TestEmbed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" || r.Method != http.MethodPost {
+			t.Fatalf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if req.Model != "nomic-embed-text" || len(req.Input) != 2 {
+			t.Fatalf("unexpected body: %+v", req)
+		}
+		_, _ = w.Write([]byte(`{"model":"nomic-embed-text","embeddings":[[0.1,0.2],[0.3,0.4]]}`))
+	}))
+	defer srv.Close()
+
+	vecs, err := ollama.NewEmbedder(srv.URL+"/").Embed(context.Background(), "nomic-embed-text", []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(vecs) != 2 || vecs[1][1] != 0.4 {
+		t.Fatalf("unexpected vectors: %v", vecs)
+	}
+}
+
+func TestEmbedAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, `{"error":"model \"x\" not found, try pulling it first"}`, http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	if _, err := ollama.NewEmbedder(srv.URL).Embed(context.Background(), "x", []string{"a"}); err == nil {
+		t.Fatal("expected error for 404 response")
+	}
+}
+
+func TestEmbedCountMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"embeddings":[[0.1]]}`))
+	}))
+	defer srv.Close()
+
+	if _, err := ollama.NewEmbedder(srv.URL).Embed(context.Background(), "x", []string{"a", "b"}); err == nil {
+		t.Fatal("expected error when fewer embeddings than inputs are returned")
+	}
+}
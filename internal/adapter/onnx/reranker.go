@@ -0,0 +1,86 @@
+// Package onnx implements the reranker.Provider interface against a local
+// cross-encoder server running an ONNX model, such as Hugging Face
+// text-embeddings-inference, through its /rerank endpoint. Documents stay
+// on the host and no LLM gateway is needed.
+package onnx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const providerName = "onnx"
+
+// Reranker calls POST /rerank on a local cross-encoder server.
+type Reranker struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewReranker creates a Reranker for the server at baseURL. Searches bound
+// the latency of each call with their own deadline.
+func NewReranker(baseURL string) *Reranker {
+	return &Reranker{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Name returns "onnx".
+func (r *Reranker) Name() string { return providerName }
+
+// Rerank scores documents against query. The server serves a single model,
+// so model is ignored.
+func (r *Reranker) Rerank(ctx context.Context, _, query string, documents []string) ([]float64, error) {
+	body, err := json.Marshal(map[string]any{"query": query, "texts": documents, "truncate": true})
+	if err != nil {
+		return nil, fmt.Errorf("onnx: marshal rerank request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.baseURL+"/rerank", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("onnx: create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("onnx: http request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("onnx: read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("onnx: API error %d: %s", resp.StatusCode, string(data))
+	}
+
+	// Results are sorted by score; each carries the index of its document.
+	var raw []struct {
+		Index int     `json:"index"`
+		Score float64 `json:"score"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("onnx: unmarshal rerank response: %w", err)
+	}
+	if len(raw) != len(documents) {
+		return nil, fmt.Errorf("onnx: got %d scores for %d documents", len(raw), len(documents))
+	}
+	out := make([]float64, len(documents))
+	for _, s := range raw {
+		if s.Index < 0 || s.Index >= len(out) {
+			return nil, fmt.Errorf("onnx: index %d out of range", s.Index)
+		}
+		out[s.Index] = s.Score
+	}
+	return out, nil
+}
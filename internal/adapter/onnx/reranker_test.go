@@ -0,0 +1,40 @@
+package onnx_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/onnx"
+)
+
+func TestRerank(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rerank" || r.Method != http.MethodPost {
+			t.Fatalf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		var req struct {
+			Query string   `json:"query"`
+			Texts []string `json:"texts"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if req.Query != "retry policy" || len(req.Texts) != 2 {
+			t.Fatalf("unexpected body: %+v", req)
+		}
+		// Sorted by score, as the server returns them.
+		_, _ = w.Write([]byte(`[{"index":1,"score":0.9},{"index":0,"score":0.2}]`))
+	}))
+	defer srv.Close()
+
+	scores, err := onnx.NewReranker(srv.URL+"/").Rerank(context.Background(), "", "retry policy", []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Rerank failed: %v", err)
+	}
+	if len(scores) != 2 || scores[0] != 0.2 || scores[1] != 0.9 {
+		t.Fatalf("unexpected scores: %v", scores)
+	}
+}
@@ -3,7 +3,9 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/Strob0t/CodeForge/internal/domain/event"
@@ -19,55 +21,156 @@ func NewEventStore(pool *pgxpool.Pool) *EventStore {
 	return &EventStore{pool: pool}
 }
 
-// Append inserts a new event into the agent_events table.
+// Append inserts a new event into the agent_events table. A zero
+// CreatedAt is set by the database.
 func (s *EventStore) Append(ctx context.Context, ev *event.AgentEvent) error {
 	_, err := s.pool.Exec(ctx,
-		`INSERT INTO agent_events (agent_id, task_id, project_id, event_type, payload, request_id, version)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
-		ev.AgentID, ev.TaskID, ev.ProjectID, string(ev.Type), ev.Payload, ev.RequestID, ev.Version)
+		`INSERT INTO agent_events (agent_id, task_id, project_id, run_id, event_type, payload, request_id, version, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE($9, now()))`,
+		ev.AgentID, ev.TaskID, ev.ProjectID, nullIfEmpty(ev.RunID), string(ev.Type), ev.Payload, ev.RequestID, ev.Version, nullIfZeroTime(ev.CreatedAt))
 	if err != nil {
 		return fmt.Errorf("append event: %w", err)
 	}
 	return nil
 }
 
+// AppendBatch copies the events into the agent_events table in one
+// statement; either all events are stored or none. Events with a zero
+// CreatedAt get the time of the call.
+func (s *EventStore) AppendBatch(ctx context.Context, evs []event.AgentEvent) error {
+	if len(evs) == 0 {
+		return nil
+	}
+	now := time.Now()
+	_, err := s.pool.CopyFrom(ctx,
+		pgx.Identifier{"agent_events"},
+		[]string{"agent_id", "task_id", "project_id", "run_id", "event_type", "payload", "request_id", "version", "created_at"},
+		pgx.CopyFromSlice(len(evs), func(i int) ([]any, error) {
+			ev := &evs[i]
+			createdAt := ev.CreatedAt
+			if createdAt.IsZero() {
+				createdAt = now
+			}
+			return []any{ev.AgentID, ev.TaskID, ev.ProjectID, nullIfEmpty(ev.RunID), string(ev.Type), ev.Payload, ev.RequestID, ev.Version, createdAt}, nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("copy %d events: %w", len(evs), err)
+	}
+	return nil
+}
+
+func nullIfZeroTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
 // LoadByTask returns all events for the given task, ordered by version ascending.
 func (s *EventStore) LoadByTask(ctx context.Context, taskID string) ([]event.AgentEvent, error) {
 	rows, err := s.pool.Query(ctx,
-		`SELECT id, agent_id, task_id, project_id, event_type, payload, request_id, version, created_at
+		`SELECT id, agent_id, task_id, project_id, run_id, event_type, payload, request_id, version, created_at
 		 FROM agent_events WHERE task_id = $1 ORDER BY version ASC`, taskID)
 	if err != nil {
 		return nil, fmt.Errorf("load events by task %s: %w", taskID, err)
 	}
-	defer rows.Close()
-
-	var events []event.AgentEvent
-	for rows.Next() {
-		var ev event.AgentEvent
-		if err := rows.Scan(&ev.ID, &ev.AgentID, &ev.TaskID, &ev.ProjectID, &ev.Type, &ev.Payload, &ev.RequestID, &ev.Version, &ev.CreatedAt); err != nil {
-			return nil, fmt.Errorf("scan event: %w", err)
-		}
-		events = append(events, ev)
-	}
-	return events, rows.Err()
+	return scanEvents(rows)
 }
 
 // LoadByAgent returns all events for the given agent, ordered by version ascending.
 func (s *EventStore) LoadByAgent(ctx context.Context, agentID string) ([]event.AgentEvent, error) {
 	rows, err := s.pool.Query(ctx,
-		`SELECT id, agent_id, task_id, project_id, event_type, payload, request_id, version, created_at
+		`SELECT id, agent_id, task_id, project_id, run_id, event_type, payload, request_id, version, created_at
 		 FROM agent_events WHERE agent_id = $1 ORDER BY version ASC`, agentID)
 	if err != nil {
 		return nil, fmt.Errorf("load events by agent %s: %w", agentID, err)
 	}
+	return scanEvents(rows)
+}
+
+// LoadByRun returns all events recorded for the given run, ordered by creation time.
+func (s *EventStore) LoadByRun(ctx context.Context, runID string) ([]event.AgentEvent, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT id, agent_id, task_id, project_id, run_id, event_type, payload, request_id, version, created_at
+		 FROM agent_events WHERE run_id = $1 ORDER BY created_at ASC, id ASC`, runID)
+	if err != nil {
+		return nil, fmt.Errorf("load events by run %s: %w", runID, err)
+	}
+	return scanEvents(rows)
+}
+
+// DeleteByRun removes all events recorded for the given run. It is the only
+// delete path and is reserved for retention after the events were archived.
+func (s *EventStore) DeleteByRun(ctx context.Context, runID string) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM agent_events WHERE run_id = $1`, runID)
+	if err != nil {
+		return 0, fmt.Errorf("delete events by run %s: %w", runID, err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// AppendAudit inserts an audit entry and sets its seq and creation time.
+func (s *EventStore) AppendAudit(ctx context.Context, e *event.AuditEntry) error {
+	detail := e.Detail
+	if detail == nil {
+		detail = map[string]string{}
+	}
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO audit_entries (tenant_id, actor, method, path, status, request_id, remote_addr, action, detail)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 RETURNING seq, created_at`,
+		e.TenantID, e.Actor, e.Method, e.Path, e.Status, e.RequestID, e.RemoteAddr, e.Action, detail,
+	).Scan(&e.Seq, &e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("append audit entry: %w", err)
+	}
+	return nil
+}
+
+// LoadAudit returns up to limit audit entries with a seq above afterSeq,
+// ordered by seq. An empty tenantID returns the entries of all tenants
+// visible to the session; a non-zero until skips entries created after it.
+func (s *EventStore) LoadAudit(ctx context.Context, tenantID string, afterSeq int64, until time.Time, limit int) ([]event.AuditEntry, error) {
+	var untilArg *time.Time
+	if !until.IsZero() {
+		untilArg = &until
+	}
+	rows, err := s.pool.Query(ctx,
+		`SELECT seq, tenant_id, actor, method, path, status, request_id, remote_addr, action, detail, created_at
+		 FROM audit_entries
+		 WHERE seq > $1 AND ($2 = '' OR tenant_id = $2) AND ($3::timestamptz IS NULL OR created_at <= $3)
+		 ORDER BY seq ASC
+		 LIMIT $4`, afterSeq, tenantID, untilArg, limit)
+	if err != nil {
+		return nil, fmt.Errorf("load audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []event.AuditEntry
+	for rows.Next() {
+		var e event.AuditEntry
+		if err := rows.Scan(&e.Seq, &e.TenantID, &e.Actor, &e.Method, &e.Path, &e.Status, &e.RequestID, &e.RemoteAddr, &e.Action, &e.Detail, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan audit entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func scanEvents(rows pgx.Rows) ([]event.AgentEvent, error) {
 	defer rows.Close()
 
 	var events []event.AgentEvent
 	for rows.Next() {
 		var ev event.AgentEvent
-		if err := rows.Scan(&ev.ID, &ev.AgentID, &ev.TaskID, &ev.ProjectID, &ev.Type, &ev.Payload, &ev.RequestID, &ev.Version, &ev.CreatedAt); err != nil {
+		var runID *string
+		if err := rows.Scan(&ev.ID, &ev.AgentID, &ev.TaskID, &ev.ProjectID, &runID, &ev.Type, &ev.Payload, &ev.RequestID, &ev.Version, &ev.CreatedAt); err != nil {
 			return nil, fmt.Errorf("scan event: %w", err)
 		}
+		if runID != nil {
+			ev.RunID = *runID
+		}
 		events = append(events, ev)
 	}
 	return events, rows.Err()
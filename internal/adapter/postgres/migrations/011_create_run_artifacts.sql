@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE run_artifacts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    run_id UUID NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    name TEXT NOT NULL,
+    content_type TEXT NOT NULL DEFAULT 'application/octet-stream',
+    data BYTEA NOT NULL,
+    size BIGINT NOT NULL DEFAULT 0,
+    metadata JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_run_artifacts_run_id ON run_artifacts(run_id);
+CREATE INDEX idx_run_artifacts_project_kind ON run_artifacts(project_id, kind);
+
+-- +goose Down
+DROP TABLE IF EXISTS run_artifacts;
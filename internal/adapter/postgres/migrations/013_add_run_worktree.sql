@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS worktree_path TEXT NOT NULL DEFAULT '';
+CREATE INDEX idx_runs_worktree ON runs (completed_at) WHERE worktree_path <> '';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_runs_worktree;
+ALTER TABLE runs DROP COLUMN IF EXISTS worktree_path;
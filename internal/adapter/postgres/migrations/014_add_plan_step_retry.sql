@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE plan_steps ADD COLUMN IF NOT EXISTS retry_policy JSONB;
+ALTER TABLE plan_steps ADD COLUMN IF NOT EXISTS attempt INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE plan_steps ADD COLUMN IF NOT EXISTS attempts JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE plan_steps ADD COLUMN IF NOT EXISTS retry_at TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE plan_steps DROP COLUMN IF EXISTS retry_at;
+ALTER TABLE plan_steps DROP COLUMN IF EXISTS attempts;
+ALTER TABLE plan_steps DROP COLUMN IF EXISTS attempt;
+ALTER TABLE plan_steps DROP COLUMN IF EXISTS retry_policy;
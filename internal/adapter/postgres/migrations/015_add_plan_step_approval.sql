@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE plan_steps ADD COLUMN IF NOT EXISTS step_type TEXT NOT NULL DEFAULT 'run';
+ALTER TABLE plan_steps ADD COLUMN IF NOT EXISTS approval JSONB;
+-- Approval steps have no task or agent.
+ALTER TABLE plan_steps ALTER COLUMN task_id DROP NOT NULL;
+ALTER TABLE plan_steps ALTER COLUMN agent_id DROP NOT NULL;
+
+-- +goose Down
+DELETE FROM plan_steps WHERE step_type = 'approval';
+ALTER TABLE plan_steps ALTER COLUMN agent_id SET NOT NULL;
+ALTER TABLE plan_steps ALTER COLUMN task_id SET NOT NULL;
+ALTER TABLE plan_steps DROP COLUMN IF EXISTS approval;
+ALTER TABLE plan_steps DROP COLUMN IF EXISTS step_type;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE secrets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    ciphertext BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Tenant-wide secrets have no project; names are unique per scope.
+CREATE UNIQUE INDEX idx_secrets_project_name ON secrets(project_id, name) WHERE project_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_secrets_tenant_name ON secrets(name) WHERE project_id IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS secrets;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS redactions INT NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE runs DROP COLUMN IF EXISTS redactions;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS events_archived_at TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE runs DROP COLUMN IF EXISTS events_archived_at;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS tokens_in INT NOT NULL DEFAULT 0;
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS tokens_out INT NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE runs DROP COLUMN IF EXISTS tokens_out;
+ALTER TABLE runs DROP COLUMN IF EXISTS tokens_in;
@@ -0,0 +1,36 @@
+-- +goose Up
+ALTER TABLE plan_steps ADD COLUMN IF NOT EXISTS model TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE benchmark_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    suite TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'running',
+    targets JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_benchmark_runs_suite ON benchmark_runs(suite, created_at DESC);
+
+CREATE TABLE benchmark_results (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    benchmark_run_id UUID NOT NULL REFERENCES benchmark_runs(id) ON DELETE CASCADE,
+    case_id TEXT NOT NULL,
+    agent_id UUID NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    model TEXT NOT NULL DEFAULT '',
+    plan_step_id UUID REFERENCES plan_steps(id) ON DELETE SET NULL,
+    run_id UUID REFERENCES runs(id) ON DELETE SET NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    output TEXT NOT NULL DEFAULT '',
+    cost_usd NUMERIC(12,6) NOT NULL DEFAULT 0,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_benchmark_results_run ON benchmark_results(benchmark_run_id);
+CREATE INDEX idx_benchmark_results_step ON benchmark_results(plan_step_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS benchmark_results;
+DROP TABLE IF EXISTS benchmark_runs;
+ALTER TABLE plan_steps DROP COLUMN IF EXISTS model;
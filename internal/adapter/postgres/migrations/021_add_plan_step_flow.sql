@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE plan_steps ADD COLUMN IF NOT EXISTS step_condition JSONB;
+ALTER TABLE plan_steps ADD COLUMN IF NOT EXISTS step_loop JSONB;
+
+-- +goose Down
+ALTER TABLE plan_steps DROP COLUMN IF EXISTS step_loop;
+ALTER TABLE plan_steps DROP COLUMN IF EXISTS step_condition;
@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE roadmap_features (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'planned',
+    labels TEXT[] NOT NULL DEFAULT '{}',
+    external_ids JSONB NOT NULL DEFAULT '{}',
+    version INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_roadmap_features_project ON roadmap_features(project_id);
+
+-- Webhooks look features up by the PM item they mirror.
+CREATE INDEX idx_roadmap_features_external_ids ON roadmap_features USING GIN (external_ids);
+
+CREATE TRIGGER trg_roadmap_features_version
+    BEFORE UPDATE ON roadmap_features
+    FOR EACH ROW EXECUTE FUNCTION increment_version();
+
+CREATE TRIGGER trg_roadmap_features_updated_at
+    BEFORE UPDATE ON roadmap_features
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
+
+-- +goose Down
+DROP TABLE IF EXISTS roadmap_features;
@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE sub_projects (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    path TEXT NOT NULL,
+    language TEXT NOT NULL DEFAULT '',
+    build_command TEXT NOT NULL DEFAULT '',
+    test_command TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (project_id, name),
+    UNIQUE (project_id, path)
+);
+
+CREATE TRIGGER trg_sub_projects_updated_at
+    BEFORE UPDATE ON sub_projects
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
+
+-- Tasks and agents may target one sub-project of their project.
+ALTER TABLE tasks ADD COLUMN sub_project_id UUID REFERENCES sub_projects(id) ON DELETE SET NULL;
+ALTER TABLE agents ADD COLUMN sub_project_id UUID REFERENCES sub_projects(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE agents DROP COLUMN IF EXISTS sub_project_id;
+ALTER TABLE tasks DROP COLUMN IF EXISTS sub_project_id;
+DROP TABLE IF EXISTS sub_projects;
@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE tenants (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    max_concurrent_runs INT NOT NULL DEFAULT 0,
+    max_projects INT NOT NULL DEFAULT 0,
+    monthly_token_budget BIGINT NOT NULL DEFAULT 0,
+    max_storage_mb BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TRIGGER trg_tenants_updated_at
+    BEFORE UPDATE ON tenants
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
+
+-- Existing projects belong to the unlimited default tenant.
+INSERT INTO tenants (id, name) VALUES ('default', 'Default');
+
+ALTER TABLE projects ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+CREATE INDEX idx_projects_tenant_id ON projects (tenant_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_projects_tenant_id;
+ALTER TABLE projects DROP COLUMN IF EXISTS tenant_id;
+DROP TABLE IF EXISTS tenants;
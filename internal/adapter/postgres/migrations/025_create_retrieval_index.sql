@@ -0,0 +1,28 @@
+-- +goose Up
+CREATE TABLE retrieval_indexes (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    provider TEXT NOT NULL,
+    model TEXT NOT NULL,
+    dimensions INT NOT NULL,
+    files INT NOT NULL DEFAULT 0,
+    chunks INT NOT NULL DEFAULT 0,
+    indexed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Vectors are stored as plain arrays and ranked in the core, so no vector
+-- extension is needed on air-gapped installs.
+CREATE TABLE retrieval_chunks (
+    id BIGSERIAL PRIMARY KEY,
+    project_id UUID NOT NULL REFERENCES retrieval_indexes(project_id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    start_line INT NOT NULL,
+    end_line INT NOT NULL,
+    content TEXT NOT NULL,
+    embedding REAL[] NOT NULL
+);
+
+CREATE INDEX idx_retrieval_chunks_project_id ON retrieval_chunks (project_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS retrieval_chunks;
+DROP TABLE IF EXISTS retrieval_indexes;
@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE memories (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    source TEXT NOT NULL DEFAULT '',
+    importance REAL NOT NULL DEFAULT 0.5,
+    provider TEXT NOT NULL,
+    model TEXT NOT NULL,
+    embedding REAL[] NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_memories_project_id ON memories (project_id, created_at DESC);
+
+-- Conversations pick a mode (which may disable memory recall), and replies
+-- record the memories recalled for them.
+ALTER TABLE conversations ADD COLUMN mode TEXT NOT NULL DEFAULT '';
+ALTER TABLE conversation_messages ADD COLUMN memory_ids TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE conversation_messages DROP COLUMN IF EXISTS memory_ids;
+ALTER TABLE conversations DROP COLUMN IF EXISTS mode;
+DROP TABLE IF EXISTS memories;
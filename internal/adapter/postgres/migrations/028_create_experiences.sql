@@ -0,0 +1,21 @@
+-- +goose Up
+-- One entry per finished execution plan; ratings weight entries when
+-- they are searched during planning.
+CREATE TABLE experiences (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    plan_id TEXT NOT NULL UNIQUE,
+    feature TEXT NOT NULL,
+    outcome TEXT NOT NULL,
+    steps INT NOT NULL DEFAULT 0,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    pitfalls TEXT[] NOT NULL DEFAULT '{}',
+    useful INT NOT NULL DEFAULT 0,
+    not_useful INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_experiences_project_id ON experiences (project_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS experiences;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- Skills are reusable prompts of a project. signer names the key of the
+-- bundle a skill was imported from; it is empty for local skills.
+CREATE TABLE skills (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    version TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    prompt TEXT NOT NULL,
+    tools TEXT[] NOT NULL DEFAULT '{}',
+    fixtures JSONB NOT NULL DEFAULT '[]',
+    signer TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (project_id, name)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS skills;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Microagents are project knowledge injected into prompts when their
+-- trigger (file patterns, keywords, event types) matches.
+CREATE TABLE microagents (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    knowledge TEXT NOT NULL,
+    trigger JSONB NOT NULL DEFAULT '{}',
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (project_id, name)
+);
+
+-- Replies record the microagents activated for them.
+ALTER TABLE conversation_messages ADD COLUMN microagent_ids TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE conversation_messages DROP COLUMN IF EXISTS microagent_ids;
+DROP TABLE IF EXISTS microagents;
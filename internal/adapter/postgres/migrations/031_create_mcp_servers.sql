@@ -0,0 +1,20 @@
+-- +goose Up
+-- External MCP servers reached over Streamable HTTP. The OAuth client
+-- secret and the current token are encrypted by the service (AES-GCM).
+CREATE TABLE mcp_servers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL UNIQUE,
+    url TEXT NOT NULL,
+    transport TEXT NOT NULL DEFAULT 'streamable_http',
+    auth TEXT NOT NULL DEFAULT 'none',
+    oauth JSONB,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    secret_ciphertext BYTEA,
+    token_ciphertext BYTEA,
+    token_expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS mcp_servers;
@@ -0,0 +1,96 @@
+-- +goose Up
+-- Tenant isolation with row-level security. The server sets the session
+-- variable app.tenant_id on every pooled connection from the request's
+-- tenant (X-Tenant-ID); sessions without a tenant stay unscoped, which
+-- background workers rely on. FORCE makes the policies apply to the table
+-- owner too; superusers and BYPASSRLS roles still bypass them.
+
+-- Tenant-wide secrets belong to a tenant; project secrets to the project's.
+ALTER TABLE secrets ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+UPDATE secrets s SET tenant_id = p.tenant_id FROM projects p WHERE s.project_id = p.id;
+DROP INDEX IF EXISTS idx_secrets_tenant_name;
+CREATE UNIQUE INDEX idx_secrets_tenant_name ON secrets(tenant_id, name) WHERE project_id IS NULL;
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION codeforge_tenant()
+RETURNS TEXT AS $$
+    SELECT NULLIF(current_setting('app.tenant_id', true), '');
+$$ LANGUAGE sql STABLE;
+-- +goose StatementEnd
+
+-- Projects are filtered by the projects policy, so a project is visible
+-- exactly when the session may see it.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION codeforge_project_visible(pid UUID)
+RETURNS BOOLEAN AS $$
+    SELECT codeforge_tenant() IS NULL OR EXISTS (SELECT 1 FROM projects WHERE id = pid);
+$$ LANGUAGE sql STABLE;
+-- +goose StatementEnd
+
+ALTER TABLE tenants ENABLE ROW LEVEL SECURITY;
+ALTER TABLE tenants FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON tenants
+    USING (codeforge_tenant() IS NULL OR id = codeforge_tenant())
+    WITH CHECK (codeforge_tenant() IS NULL OR id = codeforge_tenant());
+
+ALTER TABLE projects ENABLE ROW LEVEL SECURITY;
+ALTER TABLE projects FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON projects
+    USING (codeforge_tenant() IS NULL OR tenant_id = codeforge_tenant())
+    WITH CHECK (codeforge_tenant() IS NULL OR tenant_id = codeforge_tenant());
+
+ALTER TABLE secrets ENABLE ROW LEVEL SECURITY;
+ALTER TABLE secrets FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON secrets
+    USING (codeforge_tenant() IS NULL OR tenant_id = codeforge_tenant())
+    WITH CHECK (codeforge_tenant() IS NULL OR tenant_id = codeforge_tenant());
+
+-- Tables owned by a project. Their child tables (plan_steps, team_members,
+-- context_entries, shared_context_items, conversation_messages) are only
+-- reached through them.
+-- +goose StatementBegin
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY[
+        'agents', 'tasks', 'agent_events', 'runs', 'execution_plans', 'agent_teams',
+        'context_packs', 'shared_contexts', 'run_artifacts', 'roadmap_features',
+        'sub_projects', 'retrieval_indexes', 'retrieval_chunks', 'conversations',
+        'memories', 'experiences', 'skills', 'microagents'
+    ] LOOP
+        EXECUTE format('ALTER TABLE %I ENABLE ROW LEVEL SECURITY', t);
+        EXECUTE format('ALTER TABLE %I FORCE ROW LEVEL SECURITY', t);
+        EXECUTE format('CREATE POLICY tenant_isolation ON %I USING (codeforge_project_visible(project_id)) WITH CHECK (codeforge_project_visible(project_id))', t);
+    END LOOP;
+END;
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DO $$
+DECLARE
+    t TEXT;
+BEGIN
+    FOREACH t IN ARRAY ARRAY[
+        'tenants', 'projects', 'secrets',
+        'agents', 'tasks', 'agent_events', 'runs', 'execution_plans', 'agent_teams',
+        'context_packs', 'shared_contexts', 'run_artifacts', 'roadmap_features',
+        'sub_projects', 'retrieval_indexes', 'retrieval_chunks', 'conversations',
+        'memories', 'experiences', 'skills', 'microagents'
+    ] LOOP
+        EXECUTE format('DROP POLICY IF EXISTS tenant_isolation ON %I', t);
+        EXECUTE format('ALTER TABLE %I NO FORCE ROW LEVEL SECURITY', t);
+        EXECUTE format('ALTER TABLE %I DISABLE ROW LEVEL SECURITY', t);
+    END LOOP;
+END;
+$$;
+-- +goose StatementEnd
+
+DROP FUNCTION IF EXISTS codeforge_project_visible(UUID);
+DROP FUNCTION IF EXISTS codeforge_tenant();
+
+DROP INDEX IF EXISTS idx_secrets_tenant_name;
+ALTER TABLE secrets DROP COLUMN IF EXISTS tenant_id;
+CREATE UNIQUE INDEX idx_secrets_tenant_name ON secrets(name) WHERE project_id IS NULL;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Managed API keys. Only the SHA-256 hash of a token is stored.
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    hash BYTEA NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    tenant_id TEXT REFERENCES tenants(id) ON DELETE CASCADE,
+    rate_limit INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS api_keys;
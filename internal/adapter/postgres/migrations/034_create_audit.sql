@@ -0,0 +1,48 @@
+-- +goose Up
+-- Audit log of changes made through the API, and the sinks each tenant
+-- exports it to. A sink's checkpoint is the seq of the last entry it
+-- accepted.
+CREATE TABLE audit_entries (
+    seq BIGSERIAL PRIMARY KEY,
+    tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants(id) ON DELETE CASCADE,
+    actor TEXT NOT NULL DEFAULT '',
+    method TEXT NOT NULL,
+    path TEXT NOT NULL,
+    status INT NOT NULL,
+    request_id TEXT NOT NULL DEFAULT '',
+    remote_addr TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_audit_entries_tenant_seq ON audit_entries(tenant_id, seq);
+
+CREATE TABLE audit_sinks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id TEXT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    config JSONB NOT NULL DEFAULT '{}',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    checkpoint BIGINT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    last_delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (tenant_id, name)
+);
+
+ALTER TABLE audit_entries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE audit_entries FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON audit_entries
+    USING (codeforge_tenant() IS NULL OR tenant_id = codeforge_tenant())
+    WITH CHECK (codeforge_tenant() IS NULL OR tenant_id = codeforge_tenant());
+
+ALTER TABLE audit_sinks ENABLE ROW LEVEL SECURITY;
+ALTER TABLE audit_sinks FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON audit_sinks
+    USING (codeforge_tenant() IS NULL OR tenant_id = codeforge_tenant())
+    WITH CHECK (codeforge_tenant() IS NULL OR tenant_id = codeforge_tenant());
+
+-- +goose Down
+DROP TABLE IF EXISTS audit_sinks;
+DROP TABLE IF EXISTS audit_entries;
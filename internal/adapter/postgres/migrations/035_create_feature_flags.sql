@@ -0,0 +1,25 @@
+-- +goose Up
+-- Overrides of feature flags for all tenants (no tenant, no project), a
+-- tenant, or a project (with the project's tenant). Flags without an
+-- override have their default, which is defined in code.
+CREATE TABLE feature_flags (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    key TEXT NOT NULL,
+    tenant_id TEXT REFERENCES tenants(id) ON DELETE CASCADE,
+    project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE NULLS NOT DISTINCT (key, tenant_id, project_id),
+    CHECK (project_id IS NULL OR tenant_id IS NOT NULL)
+);
+
+-- Tenants see the global overrides but may only change their own.
+ALTER TABLE feature_flags ENABLE ROW LEVEL SECURITY;
+ALTER TABLE feature_flags FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON feature_flags
+    USING (codeforge_tenant() IS NULL OR tenant_id IS NULL OR tenant_id = codeforge_tenant())
+    WITH CHECK (codeforge_tenant() IS NULL OR tenant_id = codeforge_tenant());
+
+-- +goose Down
+DROP TABLE IF EXISTS feature_flags;
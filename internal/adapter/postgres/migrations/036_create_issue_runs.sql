@@ -0,0 +1,36 @@
+-- +goose Up
+-- Runs started by the issue automation ("assign the bot"). A record is
+-- created before the task so that duplicate webhook deliveries cannot start
+-- a second run for an issue that is still being worked on.
+CREATE TABLE issue_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    provider TEXT NOT NULL,
+    issue_number INTEGER NOT NULL,
+    issue_url TEXT NOT NULL DEFAULT '',
+    task_id UUID REFERENCES tasks(id) ON DELETE SET NULL,
+    run_id UUID REFERENCES runs(id) ON DELETE SET NULL,
+    comment_id TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'running',
+    pr_url TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX idx_issue_runs_running ON issue_runs (project_id, issue_number) WHERE status = 'running';
+CREATE INDEX idx_issue_runs_run_id ON issue_runs (run_id);
+CREATE INDEX idx_issue_runs_project_id ON issue_runs (project_id, created_at DESC);
+
+CREATE TRIGGER trg_issue_runs_updated_at
+    BEFORE UPDATE ON issue_runs
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
+
+ALTER TABLE issue_runs ENABLE ROW LEVEL SECURITY;
+ALTER TABLE issue_runs FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON issue_runs
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+-- +goose Down
+DROP TABLE IF EXISTS issue_runs;
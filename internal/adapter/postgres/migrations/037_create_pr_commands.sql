@@ -0,0 +1,44 @@
+-- +goose Up
+-- Runs may check out a remote branch (e.g. a pull request's head) into
+-- their worktree; push delivery commits back to it.
+ALTER TABLE runs ADD COLUMN branch TEXT NOT NULL DEFAULT '';
+
+-- Slash commands posted as pull request comments ("/codeforge fix"). The
+-- comment ID is unique per pull request so that redelivered webhooks do
+-- not run a command twice.
+CREATE TABLE pr_commands (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    provider TEXT NOT NULL,
+    pr_number INTEGER NOT NULL,
+    comment_id TEXT NOT NULL,
+    branch TEXT NOT NULL DEFAULT '',
+    command TEXT NOT NULL,
+    args TEXT NOT NULL DEFAULT '',
+    author TEXT NOT NULL DEFAULT '',
+    run_id UUID REFERENCES runs(id) ON DELETE SET NULL,
+    reply_id TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'running',
+    result TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (project_id, pr_number, comment_id)
+);
+
+CREATE INDEX idx_pr_commands_run_id ON pr_commands (run_id);
+CREATE INDEX idx_pr_commands_project_id ON pr_commands (project_id, created_at DESC);
+
+CREATE TRIGGER trg_pr_commands_updated_at
+    BEFORE UPDATE ON pr_commands
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
+
+ALTER TABLE pr_commands ENABLE ROW LEVEL SECURITY;
+ALTER TABLE pr_commands FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON pr_commands
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+-- +goose Down
+DROP TABLE IF EXISTS pr_commands;
+ALTER TABLE runs DROP COLUMN IF EXISTS branch;
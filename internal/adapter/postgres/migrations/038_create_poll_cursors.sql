@@ -0,0 +1,22 @@
+-- +goose Up
+-- How far each source of a polled project has been read. Projects whose
+-- git host or PM platform cannot deliver webhooks poll for the same
+-- changes instead (project config poll_interval).
+CREATE TABLE poll_cursors (
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    source TEXT NOT NULL,
+    position TEXT NOT NULL DEFAULT '',
+    etag TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    polled_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (project_id, source)
+);
+
+ALTER TABLE poll_cursors ENABLE ROW LEVEL SECURITY;
+ALTER TABLE poll_cursors FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON poll_cursors
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+-- +goose Down
+DROP TABLE IF EXISTS poll_cursors;
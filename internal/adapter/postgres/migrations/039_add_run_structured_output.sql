@@ -0,0 +1,6 @@
+-- +goose Up
+-- Final answer of a run parsed against its mode's output schema.
+ALTER TABLE runs ADD COLUMN structured_output JSONB;
+
+-- +goose Down
+ALTER TABLE runs DROP COLUMN IF EXISTS structured_output;
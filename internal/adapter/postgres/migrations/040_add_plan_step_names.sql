@@ -0,0 +1,9 @@
+-- +goose Up
+-- Step names let downstream step prompts reference a step's output
+-- ({{steps.<name>.output}}). Names are unique within a plan.
+ALTER TABLE plan_steps ADD COLUMN name TEXT NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX idx_plan_steps_name ON plan_steps (plan_id, name) WHERE name <> '';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_plan_steps_name;
+ALTER TABLE plan_steps DROP COLUMN IF EXISTS name;
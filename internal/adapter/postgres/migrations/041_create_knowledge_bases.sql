@@ -0,0 +1,53 @@
+-- +goose Up
+-- Knowledge bases of external documents (Confluence, Notion, crawled web
+-- pages) and their embedded chunks. Chunks carry the tenant so that row
+-- level security does not need a join.
+CREATE TABLE knowledge_bases (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id TEXT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    config JSONB NOT NULL DEFAULT '{}',
+    project_ids TEXT[] NOT NULL DEFAULT '{}',
+    refresh_interval TEXT NOT NULL DEFAULT '',
+    provider TEXT NOT NULL DEFAULT '',
+    model TEXT NOT NULL DEFAULT '',
+    dimensions INT NOT NULL DEFAULT 0,
+    documents INT NOT NULL DEFAULT 0,
+    chunks INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    refreshed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (tenant_id, name)
+);
+
+CREATE TABLE knowledge_chunks (
+    id BIGSERIAL PRIMARY KEY,
+    kb_id UUID NOT NULL REFERENCES knowledge_bases(id) ON DELETE CASCADE,
+    tenant_id TEXT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    start_line INT NOT NULL,
+    end_line INT NOT NULL,
+    content TEXT NOT NULL,
+    embedding REAL[] NOT NULL
+);
+
+CREATE INDEX idx_knowledge_chunks_kb_id ON knowledge_chunks (kb_id);
+
+ALTER TABLE knowledge_bases ENABLE ROW LEVEL SECURITY;
+ALTER TABLE knowledge_bases FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON knowledge_bases
+    USING (codeforge_tenant() IS NULL OR tenant_id = codeforge_tenant())
+    WITH CHECK (codeforge_tenant() IS NULL OR tenant_id = codeforge_tenant());
+
+ALTER TABLE knowledge_chunks ENABLE ROW LEVEL SECURITY;
+ALTER TABLE knowledge_chunks FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON knowledge_chunks
+    USING (codeforge_tenant() IS NULL OR tenant_id = codeforge_tenant())
+    WITH CHECK (codeforge_tenant() IS NULL OR tenant_id = codeforge_tenant());
+
+-- +goose Down
+DROP TABLE IF EXISTS knowledge_chunks;
+DROP TABLE IF EXISTS knowledge_bases;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- Source of snippets from the retrieval index or a knowledge base, to
+-- resolve the citations of run answers.
+ALTER TABLE context_entries ADD COLUMN citation JSONB;
+
+-- +goose Down
+ALTER TABLE context_entries DROP COLUMN IF EXISTS citation;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Token usage the LLM reported for assistant replies.
+ALTER TABLE conversation_messages
+    ADD COLUMN prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN completion_tokens INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE conversation_messages
+    DROP COLUMN IF EXISTS completion_tokens,
+    DROP COLUMN IF EXISTS prompt_tokens;
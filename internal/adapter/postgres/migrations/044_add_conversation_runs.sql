@@ -0,0 +1,12 @@
+-- +goose Up
+-- Runs started in a conversation record their tool calls and answer in it.
+ALTER TABLE runs ADD COLUMN conversation_id UUID REFERENCES conversations(id) ON DELETE SET NULL;
+CREATE INDEX idx_runs_conversation_id ON runs(conversation_id);
+
+-- Tool messages carry the invocation next to their (truncated) output.
+ALTER TABLE conversation_messages ADD COLUMN tool_call JSONB;
+
+-- +goose Down
+ALTER TABLE conversation_messages DROP COLUMN IF EXISTS tool_call;
+DROP INDEX IF EXISTS idx_runs_conversation_id;
+ALTER TABLE runs DROP COLUMN IF EXISTS conversation_id;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- Runs still active when a server shut down are marked for another server
+-- (or the restarted one) to resume.
+ALTER TABLE runs ADD COLUMN interrupted_at TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE runs DROP COLUMN IF EXISTS interrupted_at;
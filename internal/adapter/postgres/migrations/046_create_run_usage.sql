@@ -0,0 +1,31 @@
+-- +goose Up
+-- LLM and tool usage of a run per tool and model, recorded from the
+-- worker's tool call results. Runs of plan steps reference their step, so
+-- the usage of retried and looped steps adds up across runs.
+ALTER TABLE runs ADD COLUMN plan_step_id UUID REFERENCES plan_steps(id) ON DELETE SET NULL;
+CREATE INDEX idx_runs_plan_step_id ON runs (plan_step_id) WHERE plan_step_id IS NOT NULL;
+
+CREATE TABLE run_usage (
+    run_id     UUID NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    tool       TEXT NOT NULL,
+    model      TEXT NOT NULL DEFAULT '',
+    calls      INTEGER NOT NULL DEFAULT 0,
+    tokens_in  BIGINT NOT NULL DEFAULT 0,
+    tokens_out BIGINT NOT NULL DEFAULT 0,
+    cost_usd   NUMERIC(12,6) NOT NULL DEFAULT 0,
+    PRIMARY KEY (run_id, tool, model)
+);
+
+CREATE INDEX idx_run_usage_project_id ON run_usage (project_id);
+
+ALTER TABLE run_usage ENABLE ROW LEVEL SECURITY;
+ALTER TABLE run_usage FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON run_usage
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+-- +goose Down
+DROP TABLE IF EXISTS run_usage;
+DROP INDEX IF EXISTS idx_runs_plan_step_id;
+ALTER TABLE runs DROP COLUMN IF EXISTS plan_step_id;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Ephemeral agents cloned by automatic scaling point at the agent they
+-- were cloned from.
+ALTER TABLE agents ADD COLUMN template_id UUID REFERENCES agents(id) ON DELETE CASCADE;
+CREATE INDEX idx_agents_template_id ON agents (template_id) WHERE template_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_agents_template_id;
+ALTER TABLE agents DROP COLUMN IF EXISTS template_id;
@@ -0,0 +1,42 @@
+-- +goose Up
+-- Transcript of ping-pong plans: one turn per step and round, and the
+-- arbiter's summary of the resolution.
+CREATE TABLE plan_debate_turns (
+    step_id    UUID NOT NULL REFERENCES plan_steps(id) ON DELETE CASCADE,
+    round      INTEGER NOT NULL,
+    plan_id    UUID NOT NULL REFERENCES execution_plans(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    role       TEXT NOT NULL,
+    run_id     UUID REFERENCES runs(id) ON DELETE SET NULL,
+    agent_id   UUID REFERENCES agents(id) ON DELETE SET NULL,
+    status     TEXT NOT NULL,
+    output     TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (step_id, round)
+);
+
+CREATE INDEX idx_plan_debate_turns_plan_id ON plan_debate_turns (plan_id, created_at);
+
+CREATE TABLE plan_debate_summaries (
+    plan_id    UUID PRIMARY KEY REFERENCES execution_plans(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    summary    TEXT NOT NULL,
+    model      TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE plan_debate_turns ENABLE ROW LEVEL SECURITY;
+ALTER TABLE plan_debate_turns FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON plan_debate_turns
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+ALTER TABLE plan_debate_summaries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE plan_debate_summaries FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON plan_debate_summaries
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+-- +goose Down
+DROP TABLE IF EXISTS plan_debate_summaries;
+DROP TABLE IF EXISTS plan_debate_turns;
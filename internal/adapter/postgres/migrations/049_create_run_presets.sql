@@ -0,0 +1,29 @@
+-- +goose Up
+-- Named run presets of a project, referenced by name when starting runs.
+CREATE TABLE run_presets (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id     UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name           TEXT NOT NULL,
+    description    TEXT NOT NULL DEFAULT '',
+    backend        TEXT NOT NULL DEFAULT '',
+    mode           TEXT NOT NULL DEFAULT '',
+    policy_profile TEXT NOT NULL DEFAULT '',
+    exec_mode      TEXT NOT NULL DEFAULT '',
+    deliver_mode   TEXT NOT NULL DEFAULT '',
+    model          TEXT NOT NULL DEFAULT '',
+    task_type      TEXT NOT NULL DEFAULT '',
+    isolate        BOOLEAN NOT NULL DEFAULT false,
+    context_budget INTEGER NOT NULL DEFAULT 0,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (project_id, name)
+);
+
+ALTER TABLE run_presets ENABLE ROW LEVEL SECURITY;
+ALTER TABLE run_presets FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON run_presets
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+-- +goose Down
+DROP TABLE IF EXISTS run_presets;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Attachments are artifacts uploaded by users rather than produced by a
+-- run: they belong to a project and optionally to a task.
+ALTER TABLE run_artifacts ALTER COLUMN run_id DROP NOT NULL;
+ALTER TABLE run_artifacts ADD COLUMN task_id UUID REFERENCES tasks(id) ON DELETE CASCADE;
+CREATE INDEX idx_run_artifacts_task ON run_artifacts(task_id) WHERE task_id IS NOT NULL;
+
+ALTER TABLE conversation_messages ADD COLUMN attachment_ids TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE conversation_messages DROP COLUMN IF EXISTS attachment_ids;
+DROP INDEX IF EXISTS idx_run_artifacts_task;
+ALTER TABLE run_artifacts DROP COLUMN IF EXISTS task_id;
+DELETE FROM run_artifacts WHERE run_id IS NULL;
+ALTER TABLE run_artifacts ALTER COLUMN run_id SET NOT NULL;
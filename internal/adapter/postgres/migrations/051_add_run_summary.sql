@@ -0,0 +1,6 @@
+-- +goose Up
+-- Natural language summary of a finished run (what changed, why, risks, follow-ups).
+ALTER TABLE runs ADD COLUMN summary TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE runs DROP COLUMN IF EXISTS summary;
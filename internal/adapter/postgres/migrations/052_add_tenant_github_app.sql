@@ -0,0 +1,7 @@
+-- +goose Up
+-- GitHub App the tenant's projects may authenticate as (app ID and the
+-- names of the tenant-wide secrets holding its private and webhook keys).
+ALTER TABLE tenants ADD COLUMN github_app JSONB;
+
+-- +goose Down
+ALTER TABLE tenants DROP COLUMN IF EXISTS github_app;
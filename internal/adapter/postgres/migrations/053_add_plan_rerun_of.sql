@@ -0,0 +1,8 @@
+-- +goose Up
+-- Plan a rerun re-executes part of; the steps it kept point at the same runs.
+ALTER TABLE execution_plans ADD COLUMN rerun_of UUID REFERENCES execution_plans(id) ON DELETE SET NULL;
+CREATE INDEX idx_execution_plans_rerun_of ON execution_plans(rerun_of) WHERE rerun_of IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_execution_plans_rerun_of;
+ALTER TABLE execution_plans DROP COLUMN IF EXISTS rerun_of;
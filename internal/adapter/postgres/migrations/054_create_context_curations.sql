@@ -0,0 +1,18 @@
+-- +goose Up
+-- Developer overrides of the context packs built for a task: pinned,
+-- excluded and reprioritized files and chunks.
+CREATE TABLE context_curations (
+    task_id UUID PRIMARY KEY REFERENCES tasks(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    rules JSONB NOT NULL DEFAULT '[]',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE context_curations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE context_curations FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON context_curations
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+-- +goose Down
+DROP TABLE IF EXISTS context_curations;
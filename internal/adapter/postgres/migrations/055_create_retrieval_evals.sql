@@ -0,0 +1,48 @@
+-- +goose Up
+-- Golden queries with the files and chunks a good index returns for them,
+-- and the scores of each run of them against the project's index.
+CREATE TABLE retrieval_golden_queries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    query TEXT NOT NULL,
+    paths TEXT[] NOT NULL DEFAULT '{}',
+    chunk_ids TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_retrieval_golden_queries_project ON retrieval_golden_queries(project_id);
+
+ALTER TABLE retrieval_golden_queries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE retrieval_golden_queries FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON retrieval_golden_queries
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+CREATE TABLE retrieval_evals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    trigger TEXT NOT NULL,
+    k INT NOT NULL,
+    provider TEXT NOT NULL,
+    model TEXT NOT NULL,
+    dimensions INT NOT NULL,
+    chunks INT NOT NULL,
+    chunk_lines INT NOT NULL,
+    chunk_overlap INT NOT NULL,
+    recall DOUBLE PRECISION NOT NULL,
+    mrr DOUBLE PRECISION NOT NULL,
+    queries JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_retrieval_evals_project ON retrieval_evals(project_id, created_at DESC);
+
+ALTER TABLE retrieval_evals ENABLE ROW LEVEL SECURITY;
+ALTER TABLE retrieval_evals FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON retrieval_evals
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+-- +goose Down
+DROP TABLE IF EXISTS retrieval_evals;
+DROP TABLE IF EXISTS retrieval_golden_queries;
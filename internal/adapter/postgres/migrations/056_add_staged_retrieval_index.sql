@@ -0,0 +1,26 @@
+-- +goose Up
+-- A project has a staged retrieval index next to its live one while an
+-- embedding migration builds it. Switching flips the staged flag of the
+-- index row, which cascades to its chunks.
+ALTER TABLE retrieval_chunks DROP CONSTRAINT retrieval_chunks_project_id_fkey;
+ALTER TABLE retrieval_indexes DROP CONSTRAINT retrieval_indexes_pkey;
+ALTER TABLE retrieval_indexes ADD COLUMN staged BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE retrieval_indexes ADD PRIMARY KEY (project_id, staged);
+ALTER TABLE retrieval_chunks ADD COLUMN staged BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE retrieval_chunks ADD CONSTRAINT retrieval_chunks_index_fkey
+    FOREIGN KEY (project_id, staged) REFERENCES retrieval_indexes(project_id, staged)
+    ON DELETE CASCADE ON UPDATE CASCADE;
+DROP INDEX IF EXISTS idx_retrieval_chunks_project_id;
+CREATE INDEX idx_retrieval_chunks_project_id ON retrieval_chunks (project_id, staged);
+
+-- +goose Down
+DELETE FROM retrieval_indexes WHERE staged;
+DROP INDEX IF EXISTS idx_retrieval_chunks_project_id;
+CREATE INDEX idx_retrieval_chunks_project_id ON retrieval_chunks (project_id);
+ALTER TABLE retrieval_chunks DROP CONSTRAINT retrieval_chunks_index_fkey;
+ALTER TABLE retrieval_chunks DROP COLUMN staged;
+ALTER TABLE retrieval_indexes DROP CONSTRAINT retrieval_indexes_pkey;
+ALTER TABLE retrieval_indexes DROP COLUMN staged;
+ALTER TABLE retrieval_indexes ADD PRIMARY KEY (project_id);
+ALTER TABLE retrieval_chunks ADD CONSTRAINT retrieval_chunks_project_id_fkey
+    FOREIGN KEY (project_id) REFERENCES retrieval_indexes(project_id) ON DELETE CASCADE;
@@ -0,0 +1,58 @@
+-- +goose Up
+-- Persisted code graphs: the parsed files of a project's workspace with
+-- their imports and exported symbols, and the dependency edges between
+-- them, kept current by incremental updates of changed files.
+CREATE TABLE code_graphs (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    go_module TEXT NOT NULL DEFAULT '',
+    files INT NOT NULL DEFAULT 0,
+    edges INT NOT NULL DEFAULT 0,
+    built_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE code_graphs ENABLE ROW LEVEL SECURITY;
+ALTER TABLE code_graphs FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON code_graphs
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+CREATE TABLE code_graph_files (
+    project_id UUID NOT NULL REFERENCES code_graphs(project_id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    language TEXT NOT NULL,
+    test BOOLEAN NOT NULL DEFAULT FALSE,
+    tagged BOOLEAN NOT NULL DEFAULT FALSE,
+    imports TEXT[] NOT NULL DEFAULT '{}',
+    symbols JSONB NOT NULL DEFAULT '[]',
+    PRIMARY KEY (project_id, path)
+);
+
+ALTER TABLE code_graph_files ENABLE ROW LEVEL SECURITY;
+ALTER TABLE code_graph_files FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON code_graph_files
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+CREATE TABLE code_graph_edges (
+    project_id UUID NOT NULL,
+    from_path TEXT NOT NULL,
+    to_path TEXT NOT NULL,
+    PRIMARY KEY (project_id, from_path, to_path),
+    FOREIGN KEY (project_id, from_path) REFERENCES code_graph_files(project_id, path) ON DELETE CASCADE,
+    FOREIGN KEY (project_id, to_path) REFERENCES code_graph_files(project_id, path) ON DELETE CASCADE
+);
+
+-- Dependents of a file; its dependencies use the primary key.
+CREATE INDEX idx_code_graph_edges_to ON code_graph_edges(project_id, to_path);
+
+ALTER TABLE code_graph_edges ENABLE ROW LEVEL SECURITY;
+ALTER TABLE code_graph_edges FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON code_graph_edges
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+-- +goose Down
+DROP TABLE IF EXISTS code_graph_edges;
+DROP TABLE IF EXISTS code_graph_files;
+DROP TABLE IF EXISTS code_graphs;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- The conventions document of a project: formatting, linters, naming, test
+-- layout and commit style extracted from its workspace and history.
+CREATE TABLE project_conventions (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    document JSONB NOT NULL,
+    trigger TEXT NOT NULL,
+    commit_sha TEXT NOT NULL DEFAULT '',
+    extracted_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE project_conventions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE project_conventions FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON project_conventions
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+-- +goose Down
+DROP TABLE IF EXISTS project_conventions;
@@ -0,0 +1,36 @@
+-- +goose Up
+-- Where runs delivered their changes on the git host, for seatbelt mode:
+-- CI failures and reverts of a delivered branch or commit mark its run
+-- failed_downstream.
+CREATE TABLE run_deliveries (
+    run_id UUID PRIMARY KEY REFERENCES runs(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    task_id UUID REFERENCES tasks(id) ON DELETE SET NULL,
+    mode TEXT NOT NULL,
+    branch TEXT NOT NULL,
+    commit_sha TEXT NOT NULL DEFAULT '',
+    pr_url TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'delivered',
+    check_name TEXT NOT NULL DEFAULT '',
+    check_url TEXT NOT NULL DEFAULT '',
+    detail TEXT NOT NULL DEFAULT '',
+    follow_up_task_id UUID REFERENCES tasks(id) ON DELETE SET NULL,
+    delivered_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    failed_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_run_deliveries_project ON run_deliveries (project_id, delivered_at DESC);
+
+CREATE TRIGGER trg_run_deliveries_updated_at
+    BEFORE UPDATE ON run_deliveries
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at();
+
+ALTER TABLE run_deliveries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE run_deliveries FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON run_deliveries
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+-- +goose Down
+DROP TABLE IF EXISTS run_deliveries;
@@ -0,0 +1,25 @@
+-- +goose Up
+-- CI results of the commits runs delivered: the workflow runs or pipelines
+-- the project's CI provider reported for the delivered branch and commit.
+CREATE TABLE run_ci_statuses (
+    run_id UUID PRIMARY KEY REFERENCES runs(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    provider TEXT NOT NULL,
+    branch TEXT NOT NULL DEFAULT '',
+    commit_sha TEXT NOT NULL DEFAULT '',
+    state TEXT NOT NULL,
+    pipelines JSONB NOT NULL DEFAULT '[]',
+    error TEXT NOT NULL DEFAULT '',
+    checked_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_run_ci_statuses_project ON run_ci_statuses (project_id, checked_at DESC);
+
+ALTER TABLE run_ci_statuses ENABLE ROW LEVEL SECURITY;
+ALTER TABLE run_ci_statuses FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON run_ci_statuses
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+-- +goose Down
+DROP TABLE IF EXISTS run_ci_statuses;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Spec and pushed image of image steps, which build and push a container
+-- image of the previous run's workspace.
+ALTER TABLE plan_steps ADD COLUMN image JSONB;
+
+-- +goose Down
+DELETE FROM plan_steps WHERE step_type = 'image';
+ALTER TABLE plan_steps DROP COLUMN IF EXISTS image;
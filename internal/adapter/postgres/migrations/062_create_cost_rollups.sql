@@ -0,0 +1,56 @@
+-- +goose Up
+-- Daily cost rollups the cost endpoints read instead of aggregating runs:
+-- run totals per project and UTC day the runs started, and run usage per
+-- project, day, tool and model. The rollup job recomputes recent days and
+-- records when it last did in cost_rollup_state.
+CREATE TABLE cost_rollups (
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    day        DATE NOT NULL,
+    runs       INTEGER NOT NULL DEFAULT 0,
+    tokens_in  BIGINT NOT NULL DEFAULT 0,
+    tokens_out BIGINT NOT NULL DEFAULT 0,
+    cost_usd   NUMERIC(14,6) NOT NULL DEFAULT 0,
+    PRIMARY KEY (project_id, day)
+);
+
+CREATE INDEX idx_cost_rollups_day ON cost_rollups (day);
+
+CREATE TABLE usage_rollups (
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    day        DATE NOT NULL,
+    tool       TEXT NOT NULL,
+    model      TEXT NOT NULL DEFAULT '',
+    calls      INTEGER NOT NULL DEFAULT 0,
+    tokens_in  BIGINT NOT NULL DEFAULT 0,
+    tokens_out BIGINT NOT NULL DEFAULT 0,
+    cost_usd   NUMERIC(14,6) NOT NULL DEFAULT 0,
+    PRIMARY KEY (project_id, day, tool, model)
+);
+
+CREATE INDEX idx_usage_rollups_day ON usage_rollups (day);
+
+CREATE TABLE cost_rollup_state (
+    id           BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    refreshed_at TIMESTAMPTZ NOT NULL
+);
+
+-- Rollup passes select the runs started since the first recomputed day.
+CREATE INDEX idx_runs_created_at ON runs (created_at);
+
+ALTER TABLE cost_rollups ENABLE ROW LEVEL SECURITY;
+ALTER TABLE cost_rollups FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON cost_rollups
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+ALTER TABLE usage_rollups ENABLE ROW LEVEL SECURITY;
+ALTER TABLE usage_rollups FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON usage_rollups
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_runs_created_at;
+DROP TABLE IF EXISTS cost_rollup_state;
+DROP TABLE IF EXISTS usage_rollups;
+DROP TABLE IF EXISTS cost_rollups;
@@ -0,0 +1,55 @@
+-- +goose Up
+-- Row-level security for the tables created without it. MCP servers and
+-- benchmark runs belong to the tenant of the request that created them;
+-- API keys to their tenant_id, so a tenant-scoped session can neither
+-- see nor mint keys of other tenants or untenanted ones. Auth looks keys
+-- up before the request is scoped, so it still finds every key.
+-- cost_rollup_state stays global on purpose: it only holds the time the
+-- rollup job last ran over all tenants.
+ALTER TABLE mcp_servers ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants(id) ON DELETE CASCADE;
+ALTER TABLE mcp_servers DROP CONSTRAINT IF EXISTS mcp_servers_name_key;
+CREATE UNIQUE INDEX idx_mcp_servers_tenant_name ON mcp_servers(tenant_id, name);
+
+ALTER TABLE mcp_servers ENABLE ROW LEVEL SECURITY;
+ALTER TABLE mcp_servers FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON mcp_servers
+    USING (codeforge_tenant() IS NULL OR tenant_id = codeforge_tenant())
+    WITH CHECK (codeforge_tenant() IS NULL OR tenant_id = codeforge_tenant());
+
+ALTER TABLE api_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE api_keys FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON api_keys
+    USING (codeforge_tenant() IS NULL OR tenant_id = codeforge_tenant())
+    WITH CHECK (codeforge_tenant() IS NULL OR tenant_id = codeforge_tenant());
+
+ALTER TABLE benchmark_runs ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES tenants(id) ON DELETE CASCADE;
+UPDATE benchmark_runs b SET tenant_id = p.tenant_id
+    FROM agents a JOIN projects p ON p.id = a.project_id
+    WHERE a.id = ((b.targets->0)->>'agent_id')::uuid;
+
+ALTER TABLE benchmark_runs ENABLE ROW LEVEL SECURITY;
+ALTER TABLE benchmark_runs FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON benchmark_runs
+    USING (codeforge_tenant() IS NULL OR tenant_id = codeforge_tenant())
+    WITH CHECK (codeforge_tenant() IS NULL OR tenant_id = codeforge_tenant());
+
+-- Results are visible with their benchmark run.
+ALTER TABLE benchmark_results ENABLE ROW LEVEL SECURITY;
+ALTER TABLE benchmark_results FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON benchmark_results
+    USING (EXISTS (SELECT 1 FROM benchmark_runs r WHERE r.id = benchmark_run_id))
+    WITH CHECK (EXISTS (SELECT 1 FROM benchmark_runs r WHERE r.id = benchmark_run_id));
+
+-- +goose Down
+DROP POLICY IF EXISTS tenant_isolation ON benchmark_results;
+ALTER TABLE benchmark_results DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON benchmark_runs;
+ALTER TABLE benchmark_runs DISABLE ROW LEVEL SECURITY;
+ALTER TABLE benchmark_runs DROP COLUMN IF EXISTS tenant_id;
+DROP POLICY IF EXISTS tenant_isolation ON api_keys;
+ALTER TABLE api_keys DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON mcp_servers;
+ALTER TABLE mcp_servers DISABLE ROW LEVEL SECURITY;
+DROP INDEX IF EXISTS idx_mcp_servers_tenant_name;
+ALTER TABLE mcp_servers DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE mcp_servers ADD CONSTRAINT mcp_servers_name_key UNIQUE (name);
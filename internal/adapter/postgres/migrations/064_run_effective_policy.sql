@@ -0,0 +1,12 @@
+-- +goose Up
+-- policy_profile keeps the profile a run was started with; the composite
+-- of the tenant, project, agent and requested layers its tool calls are
+-- evaluated against is recorded apart. Runs that stored the composite in
+-- policy_profile keep its most specific layer there.
+ALTER TABLE runs ADD COLUMN effective_policy TEXT NOT NULL DEFAULT '';
+UPDATE runs SET effective_policy = policy_profile, policy_profile = regexp_replace(policy_profile, '^.*>', '')
+    WHERE policy_profile LIKE '%>%';
+
+-- +goose Down
+UPDATE runs SET policy_profile = effective_policy WHERE effective_policy <> '';
+ALTER TABLE runs DROP COLUMN IF EXISTS effective_policy;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Roles of an API key's holder, matched against the approver_roles of
+-- approval policies, and entries for decisions made outside a request
+-- (approval votes, timeouts, escalations) in the audit log: action names
+-- what was decided and detail holds its fields.
+ALTER TABLE api_keys ADD COLUMN roles TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE audit_entries ADD COLUMN action TEXT NOT NULL DEFAULT '';
+ALTER TABLE audit_entries ADD COLUMN detail JSONB NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE audit_entries DROP COLUMN IF EXISTS detail;
+ALTER TABLE audit_entries DROP COLUMN IF EXISTS action;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS roles;
@@ -0,0 +1,64 @@
+-- +goose Up
+-- Routing rules that send a project's events to chat webhooks, the
+-- project's quiet hours and digest time, and the outbox of notifications
+-- waiting to be sent: right away, after quiet hours or with a digest.
+CREATE TABLE notification_rules (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project_id  UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name        TEXT NOT NULL,
+    events      TEXT[] NOT NULL DEFAULT '{}',
+    kind        TEXT NOT NULL,
+    config      JSONB NOT NULL DEFAULT '{}',
+    mentions    TEXT[] NOT NULL DEFAULT '{}',
+    digest      BOOLEAN NOT NULL DEFAULT false,
+    enabled     BOOLEAN NOT NULL DEFAULT true,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (project_id, name)
+);
+
+CREATE TABLE notification_settings (
+    project_id  UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    quiet_start TEXT NOT NULL DEFAULT '',
+    quiet_end   TEXT NOT NULL DEFAULT '',
+    timezone    TEXT NOT NULL DEFAULT '',
+    digest_at   TEXT NOT NULL DEFAULT '',
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE notification_outbox (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    rule_id     UUID NOT NULL REFERENCES notification_rules(id) ON DELETE CASCADE,
+    project_id  UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    message     JSONB NOT NULL,
+    digest      BOOLEAN NOT NULL DEFAULT false,
+    send_at     TIMESTAMPTZ NOT NULL,
+    attempts    INTEGER NOT NULL DEFAULT 0,
+    last_error  TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_notification_outbox_send_at ON notification_outbox(send_at);
+
+ALTER TABLE notification_rules ENABLE ROW LEVEL SECURITY;
+ALTER TABLE notification_rules FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON notification_rules
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+ALTER TABLE notification_settings ENABLE ROW LEVEL SECURITY;
+ALTER TABLE notification_settings FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON notification_settings
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+ALTER TABLE notification_outbox ENABLE ROW LEVEL SECURITY;
+ALTER TABLE notification_outbox FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON notification_outbox
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+-- +goose Down
+DROP TABLE IF EXISTS notification_outbox;
+DROP TABLE IF EXISTS notification_settings;
+DROP TABLE IF EXISTS notification_rules;
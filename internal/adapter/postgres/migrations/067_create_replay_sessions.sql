@@ -0,0 +1,21 @@
+-- +goose Up
+-- Step-through replay sessions: a cursor over the recorded events of a run.
+CREATE TABLE replay_sessions (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    run_id      UUID NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+    project_id  UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    position    INTEGER NOT NULL DEFAULT 0,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_replay_sessions_run_id ON replay_sessions(run_id);
+
+ALTER TABLE replay_sessions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE replay_sessions FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON replay_sessions
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+-- +goose Down
+DROP TABLE IF EXISTS replay_sessions;
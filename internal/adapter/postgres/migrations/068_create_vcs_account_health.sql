@@ -0,0 +1,29 @@
+-- +goose Up
+-- The latest check of each project's git host token: what the host
+-- reported about it and whether it is ok, expiring or broken.
+CREATE TABLE vcs_account_health (
+    project_id      UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    account         TEXT NOT NULL,
+    provider        TEXT NOT NULL,
+    login           TEXT NOT NULL DEFAULT '',
+    scopes          TEXT[] NOT NULL DEFAULT '{}',
+    expires_at      TIMESTAMPTZ,
+    can_push        BOOLEAN NOT NULL DEFAULT false,
+    rate_limit      INTEGER NOT NULL DEFAULT 0,
+    rate_remaining  INTEGER NOT NULL DEFAULT 0,
+    rate_reset      TIMESTAMPTZ,
+    status          TEXT NOT NULL,
+    error           TEXT NOT NULL DEFAULT '',
+    checked_at      TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_vcs_account_health_account ON vcs_account_health(account);
+
+ALTER TABLE vcs_account_health ENABLE ROW LEVEL SECURITY;
+ALTER TABLE vcs_account_health FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON vcs_account_health
+    USING (codeforge_project_visible(project_id))
+    WITH CHECK (codeforge_project_visible(project_id));
+
+-- +goose Down
+DROP TABLE IF EXISTS vcs_account_health;
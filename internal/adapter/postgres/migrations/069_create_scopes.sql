@@ -0,0 +1,24 @@
+-- +goose Up
+-- Retrieval scopes: groups of a tenant's projects and knowledge bases that
+-- are searched together. Sources are {id, weight} lists; sources deleted
+-- since are skipped by searches.
+CREATE TABLE scopes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id TEXT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    projects JSONB NOT NULL DEFAULT '[]',
+    knowledge_bases JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (tenant_id, name)
+);
+
+ALTER TABLE scopes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE scopes FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON scopes
+    USING (codeforge_tenant() IS NULL OR tenant_id = codeforge_tenant())
+    WITH CHECK (codeforge_tenant() IS NULL OR tenant_id = codeforge_tenant());
+
+-- +goose Down
+DROP TABLE IF EXISTS scopes;
@@ -5,11 +5,16 @@ import (
 	"context"
 	"embed"
 	"fmt"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pressly/goose/v3"
 
 	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
 )
 
 //go:embed migrations/*.sql
@@ -17,28 +22,102 @@ var migrations embed.FS
 
 // NewPool creates a pgxpool connection pool from a config.Postgres struct.
 func NewPool(ctx context.Context, cfg config.Postgres) (*pgxpool.Pool, error) {
-	poolCfg, err := pgxpool.ParseConfig(cfg.DSN)
+	pool, err := newPool(ctx, cfg.DSN, cfg, cfg.MaxConns, cfg.QueryTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("ping: %w", err)
+	}
+
+	return pool, nil
+}
+
+// newPool creates a pool of at most maxConns connections to dsn with the
+// pool, statement cache and timeout settings of cfg. It connects lazily.
+func newPool(ctx context.Context, dsn string, cfg config.Postgres, maxConns int32, queryTimeout time.Duration) (*pgxpool.Pool, error) {
+	poolCfg, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("parse dsn: %w", err)
 	}
 
-	poolCfg.MaxConns = cfg.MaxConns
-	poolCfg.MinConns = cfg.MinConns
+	poolCfg.MaxConns = maxConns
+	poolCfg.MinConns = min(cfg.MinConns, maxConns)
 	poolCfg.MaxConnLifetime = cfg.MaxConnLifetime
 	poolCfg.MaxConnIdleTime = cfg.MaxConnIdleTime
 	poolCfg.HealthCheckPeriod = cfg.HealthCheck
+	configureStatements(poolCfg.ConnConfig, cfg.StatementCache, cfg.StatementCacheSize)
+	// A statement_timeout in the DSN takes precedence.
+	if _, ok := poolCfg.ConnConfig.RuntimeParams["statement_timeout"]; !ok && queryTimeout > 0 {
+		poolCfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(queryTimeout.Milliseconds(), 10)
+	}
+
+	sessions := &tenantSessions{byConn: make(map[*pgx.Conn]string)}
+	poolCfg.PrepareConn = sessions.prepare
+	poolCfg.BeforeClose = sessions.forget
 
 	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
 	if err != nil {
 		return nil, fmt.Errorf("create pool: %w", err)
 	}
+	return pool, nil
+}
 
-	if err := pool.Ping(ctx); err != nil {
-		pool.Close()
-		return nil, fmt.Errorf("ping: %w", err)
+// configureStatements sets how connections execute queries: prepare
+// caches prepared statements, describe only their descriptions, which
+// works behind poolers that do not keep prepared statements across
+// transactions, and exec and simple cache nothing. A size of 0 disables
+// caching.
+func configureStatements(cc *pgx.ConnConfig, mode string, size int) {
+	cc.StatementCacheCapacity = 0
+	cc.DescriptionCacheCapacity = 0
+	switch {
+	case mode == "prepare" && size > 0:
+		cc.DefaultQueryExecMode = pgx.QueryExecModeCacheStatement
+		cc.StatementCacheCapacity = size
+	case mode == "describe" && size > 0:
+		cc.DefaultQueryExecMode = pgx.QueryExecModeCacheDescribe
+		cc.DescriptionCacheCapacity = size
+	case mode == "simple":
+		cc.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+	default:
+		cc.DefaultQueryExecMode = pgx.QueryExecModeExec
 	}
+}
 
-	return pool, nil
+// tenantSessions scopes each acquired connection to the tenant of the
+// acquiring context by setting the app.tenant_id session variable that the
+// row-level security policies read (migration 032). An empty value leaves
+// the session unscoped. The variable is only set when it changes.
+type tenantSessions struct {
+	mu     sync.Mutex
+	byConn map[*pgx.Conn]string
+}
+
+func (t *tenantSessions) prepare(ctx context.Context, conn *pgx.Conn) (bool, error) {
+	id := tenant.FromContext(ctx)
+	t.mu.Lock()
+	cur, ok := t.byConn[conn]
+	t.mu.Unlock()
+	if ok && cur == id {
+		return true, nil
+	}
+	if _, err := conn.Exec(ctx, `SELECT set_config('app.tenant_id', $1, false)`, id); err != nil {
+		// The session state is unknown; drop the connection.
+		return false, fmt.Errorf("set tenant: %w", err)
+	}
+	t.mu.Lock()
+	t.byConn[conn] = id
+	t.mu.Unlock()
+	return true, nil
+}
+
+func (t *tenantSessions) forget(conn *pgx.Conn) {
+	t.mu.Lock()
+	delete(t.byConn, conn)
+	t.mu.Unlock()
 }
 
 // RunMigrations applies all pending goose migrations from the embedded SQL files.
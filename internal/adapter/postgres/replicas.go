@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+)
+
+// replicaLagQuery returns how far a server lags behind the primary: zero
+// for a primary or a replica that replayed all WAL it received, else the
+// age of the last replayed transaction. NULL means the lag is unknown.
+const replicaLagQuery = `SELECT CASE
+	WHEN NOT pg_is_in_recovery() THEN 0
+	WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())::float8
+END`
+
+// Replicas routes reads that tolerate staleness to read replicas. Each
+// replica's lag is checked periodically; replicas that lag more than the
+// tolerance, or whose check failed, are skipped until a check succeeds.
+type Replicas struct {
+	replicas []*replica
+	maxLag   time.Duration
+	check    time.Duration
+	next     atomic.Uint64
+}
+
+type replica struct {
+	host   string
+	pool   *pgxpool.Pool
+	usable atomic.Bool
+}
+
+// NewReplicas creates a connection pool per replica DSN of cfg. Replicas
+// are unused until the first lag check.
+func NewReplicas(ctx context.Context, cfg config.Postgres) (*Replicas, error) {
+	r := &Replicas{maxLag: cfg.ReplicaMaxLag, check: cfg.ReplicaCheck}
+	for i, dsn := range cfg.ReplicaDSNs {
+		pool, err := newPool(ctx, dsn, cfg, cfg.ReplicaMaxConns, cfg.ReplicaQueryTimeout)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("replica %d: %w", i, err)
+		}
+		r.replicas = append(r.replicas, &replica{host: pool.Config().ConnConfig.Host, pool: pool})
+	}
+	return r, nil
+}
+
+// Pool returns the next usable replica in turn, or nil if none is usable.
+func (r *Replicas) Pool() *pgxpool.Pool {
+	if r == nil {
+		return nil
+	}
+	n := uint64(len(r.replicas))
+	start := r.next.Add(1)
+	for i := range n {
+		if rep := r.replicas[(start+i)%n]; rep.usable.Load() {
+			return rep.pool
+		}
+	}
+	return nil
+}
+
+// Start checks the lag of the replicas now and every check interval until
+// the context is cancelled or the returned cancel function is called.
+func (r *Replicas) Start(ctx context.Context) (cancel func()) {
+	ctx, cancel = context.WithCancel(ctx)
+	r.CheckLag(ctx)
+	go func() {
+		ticker := time.NewTicker(r.check)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.CheckLag(ctx)
+			}
+		}
+	}()
+	return cancel
+}
+
+// CheckLag measures the lag of every replica and updates which are
+// usable. Changes are logged.
+func (r *Replicas) CheckLag(ctx context.Context) {
+	for _, rep := range r.replicas {
+		lag, err := rep.lag(ctx, r.check)
+		usable := err == nil && lag <= r.maxLag
+		if rep.usable.Swap(usable) == usable {
+			continue
+		}
+		switch {
+		case usable:
+			slog.Info("postgres replica usable", "host", rep.host, "lag", lag)
+		case err != nil:
+			slog.Warn("postgres replica unusable", "host", rep.host, "error", err)
+		default:
+			slog.Warn("postgres replica lags behind", "host", rep.host, "lag", lag, "max_lag", r.maxLag)
+		}
+	}
+}
+
+func (rep *replica) lag(ctx context.Context, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var secs *float64
+	if err := rep.pool.QueryRow(ctx, replicaLagQuery).Scan(&secs); err != nil {
+		return 0, fmt.Errorf("check lag: %w", err)
+	}
+	if secs == nil {
+		return 0, errors.New("check lag: no transaction replayed")
+	}
+	return time.Duration(*secs * float64(time.Second)), nil
+}
+
+// Close closes the replica pools.
+func (r *Replicas) Close() {
+	for _, rep := range r.replicas {
+		rep.pool.Close()
+	}
+}
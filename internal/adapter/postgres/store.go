@@ -1,26 +1,59 @@
 package postgres
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/Strob0t/CodeForge/internal/domain"
 	"github.com/Strob0t/CodeForge/internal/domain/agent"
+	"github.com/Strob0t/CodeForge/internal/domain/apikey"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/domain/audit"
+	"github.com/Strob0t/CodeForge/internal/domain/benchmark"
+	"github.com/Strob0t/CodeForge/internal/domain/chatops"
+	"github.com/Strob0t/CodeForge/internal/domain/ci"
+	"github.com/Strob0t/CodeForge/internal/domain/codegraph"
 	cfcontext "github.com/Strob0t/CodeForge/internal/domain/context"
+	"github.com/Strob0t/CodeForge/internal/domain/convention"
+	"github.com/Strob0t/CodeForge/internal/domain/conversation"
+	"github.com/Strob0t/CodeForge/internal/domain/cost"
+	"github.com/Strob0t/CodeForge/internal/domain/experience"
+	"github.com/Strob0t/CodeForge/internal/domain/featureflag"
+	"github.com/Strob0t/CodeForge/internal/domain/issuerun"
+	"github.com/Strob0t/CodeForge/internal/domain/knowledge"
+	"github.com/Strob0t/CodeForge/internal/domain/mcpserver"
+	"github.com/Strob0t/CodeForge/internal/domain/memory"
+	"github.com/Strob0t/CodeForge/internal/domain/microagent"
+	"github.com/Strob0t/CodeForge/internal/domain/notification"
 	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/poll"
 	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/replay"
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+	"github.com/Strob0t/CodeForge/internal/domain/roadmap"
 	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/scope"
+	"github.com/Strob0t/CodeForge/internal/domain/seatbelt"
+	"github.com/Strob0t/CodeForge/internal/domain/secret"
+	"github.com/Strob0t/CodeForge/internal/domain/skill"
 	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/domain/vcsaccount"
+	"github.com/Strob0t/CodeForge/internal/port/database"
 )
 
 // Store implements database.Store using PostgreSQL.
 type Store struct {
-	pool *pgxpool.Pool
+	pool     *pgxpool.Pool
+	replicas *Replicas
 }
 
 // NewStore creates a new Store backed by the given connection pool.
@@ -28,11 +61,28 @@ func NewStore(pool *pgxpool.Pool) *Store {
 	return &Store{pool: pool}
 }
 
+// SetReplicas routes the list and cost queries of contexts that tolerate
+// stale reads (see database.WithStaleReads) to read replicas.
+func (s *Store) SetReplicas(r *Replicas) {
+	s.replicas = r
+}
+
+// reader returns the pool a read-only query runs on: a usable replica if
+// the context tolerates stale reads, else the primary.
+func (s *Store) reader(ctx context.Context) *pgxpool.Pool {
+	if database.StaleReadsAllowed(ctx) {
+		if p := s.replicas.Pool(); p != nil {
+			return p
+		}
+	}
+	return s.pool
+}
+
 // --- Projects ---
 
 func (s *Store) ListProjects(ctx context.Context) ([]project.Project, error) {
-	rows, err := s.pool.Query(ctx,
-		`SELECT id, name, description, repo_url, provider, workspace_path, config, version, created_at, updated_at
+	rows, err := s.reader(ctx).Query(ctx,
+		`SELECT id, tenant_id, name, description, repo_url, provider, workspace_path, config, version, created_at, updated_at
 		 FROM projects ORDER BY created_at DESC`)
 	if err != nil {
 		return nil, fmt.Errorf("list projects: %w", err)
@@ -52,7 +102,7 @@ func (s *Store) ListProjects(ctx context.Context) ([]project.Project, error) {
 
 func (s *Store) GetProject(ctx context.Context, id string) (*project.Project, error) {
 	row := s.pool.QueryRow(ctx,
-		`SELECT id, name, description, repo_url, provider, workspace_path, config, version, created_at, updated_at
+		`SELECT id, tenant_id, name, description, repo_url, provider, workspace_path, config, version, created_at, updated_at
 		 FROM projects WHERE id = $1`, id)
 
 	p, err := scanProject(row)
@@ -70,12 +120,16 @@ func (s *Store) CreateProject(ctx context.Context, req project.CreateRequest) (*
 	if err != nil {
 		return nil, fmt.Errorf("marshal config: %w", err)
 	}
+	tenantID := req.TenantID
+	if tenantID == "" {
+		tenantID = tenant.DefaultID
+	}
 
 	row := s.pool.QueryRow(ctx,
-		`INSERT INTO projects (name, description, repo_url, provider, config)
-		 VALUES ($1, $2, $3, $4, $5)
-		 RETURNING id, name, description, repo_url, provider, workspace_path, config, version, created_at, updated_at`,
-		req.Name, req.Description, req.RepoURL, req.Provider, configJSON)
+		`INSERT INTO projects (tenant_id, name, description, repo_url, provider, config)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id, tenant_id, name, description, repo_url, provider, workspace_path, config, version, created_at, updated_at`,
+		tenantID, req.Name, req.Description, req.RepoURL, req.Provider, configJSON)
 
 	p, err := scanProject(row)
 	if err != nil {
@@ -114,11 +168,74 @@ func (s *Store) DeleteProject(ctx context.Context, id string) error {
 	return nil
 }
 
+// --- Sub-Projects ---
+
+const subProjectColumns = `id, project_id, name, path, language, build_command, test_command, created_at, updated_at`
+
+func (s *Store) CreateSubProject(ctx context.Context, sp *project.SubProject) error {
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO sub_projects (project_id, name, path, language, build_command, test_command)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id, created_at, updated_at`,
+		sp.ProjectID, sp.Name, sp.Path, sp.Language, sp.BuildCommand, sp.TestCommand,
+	).Scan(&sp.ID, &sp.CreatedAt, &sp.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("create sub-project %s: %w", sp.Name, domain.ErrConflict)
+		}
+		return fmt.Errorf("create sub-project %s: %w", sp.Name, err)
+	}
+	return nil
+}
+
+func (s *Store) GetSubProject(ctx context.Context, id string) (*project.SubProject, error) {
+	row := s.pool.QueryRow(ctx, `SELECT `+subProjectColumns+` FROM sub_projects WHERE id = $1`, id)
+	sp, err := scanSubProject(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get sub-project %s: %w", id, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get sub-project %s: %w", id, err)
+	}
+	return &sp, nil
+}
+
+func (s *Store) ListSubProjects(ctx context.Context, projectID string) ([]project.SubProject, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT `+subProjectColumns+` FROM sub_projects WHERE project_id = $1 ORDER BY path`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list sub-projects: %w", err)
+	}
+	defer rows.Close()
+
+	var result []project.SubProject
+	for rows.Next() {
+		sp, err := scanSubProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, sp)
+	}
+	return result, rows.Err()
+}
+
+func (s *Store) DeleteSubProject(ctx context.Context, id string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM sub_projects WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete sub-project %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete sub-project %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
 // --- Agents ---
 
 func (s *Store) ListAgents(ctx context.Context, projectID string) ([]agent.Agent, error) {
-	rows, err := s.pool.Query(ctx,
-		`SELECT id, project_id, name, backend, status, config, version, created_at, updated_at
+	rows, err := s.reader(ctx).Query(ctx,
+		`SELECT id, project_id, COALESCE(sub_project_id::text, ''), name, backend, status, config, COALESCE(template_id::text, ''), version, created_at, updated_at
 		 FROM agents WHERE project_id = $1 ORDER BY created_at DESC`, projectID)
 	if err != nil {
 		return nil, fmt.Errorf("list agents: %w", err)
@@ -138,7 +255,7 @@ func (s *Store) ListAgents(ctx context.Context, projectID string) ([]agent.Agent
 
 func (s *Store) GetAgent(ctx context.Context, id string) (*agent.Agent, error) {
 	row := s.pool.QueryRow(ctx,
-		`SELECT id, project_id, name, backend, status, config, version, created_at, updated_at
+		`SELECT id, project_id, COALESCE(sub_project_id::text, ''), name, backend, status, config, COALESCE(template_id::text, ''), version, created_at, updated_at
 		 FROM agents WHERE id = $1`, id)
 
 	a, err := scanAgent(row)
@@ -160,7 +277,7 @@ func (s *Store) CreateAgent(ctx context.Context, projectID, name, backend string
 	row := s.pool.QueryRow(ctx,
 		`INSERT INTO agents (project_id, name, backend, config)
 		 VALUES ($1, $2, $3, $4)
-		 RETURNING id, project_id, name, backend, status, config, version, created_at, updated_at`,
+		 RETURNING id, project_id, COALESCE(sub_project_id::text, ''), name, backend, status, config, COALESCE(template_id::text, ''), version, created_at, updated_at`,
 		projectID, name, backend, configJSON)
 
 	a, err := scanAgent(row)
@@ -170,6 +287,48 @@ func (s *Store) CreateAgent(ctx context.Context, projectID, name, backend string
 	return &a, nil
 }
 
+// CloneAgent creates an ephemeral copy of an agent with the given name:
+// same project, sub-project, backend and config.
+func (s *Store) CloneAgent(ctx context.Context, templateID, name string) (*agent.Agent, error) {
+	row := s.pool.QueryRow(ctx,
+		`INSERT INTO agents (project_id, sub_project_id, name, backend, config, template_id)
+		 SELECT project_id, sub_project_id, $2, backend, config, id FROM agents WHERE id = $1
+		 RETURNING id, project_id, COALESCE(sub_project_id::text, ''), name, backend, status, config, COALESCE(template_id::text, ''), version, created_at, updated_at`,
+		templateID, name)
+
+	a, err := scanAgent(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("clone agent %s: %w", templateID, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("clone agent %s: %w", templateID, err)
+	}
+	return &a, nil
+}
+
+// ListAgentClones returns the ephemeral clones of an agent, oldest first,
+// or those of all agents if templateID is empty.
+func (s *Store) ListAgentClones(ctx context.Context, templateID string) ([]agent.Agent, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT id, project_id, COALESCE(sub_project_id::text, ''), name, backend, status, config, COALESCE(template_id::text, ''), version, created_at, updated_at
+		 FROM agents WHERE template_id IS NOT NULL AND ($1 = '' OR template_id::text = $1)
+		 ORDER BY created_at`, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("list agent clones: %w", err)
+	}
+	defer rows.Close()
+
+	var agents []agent.Agent
+	for rows.Next() {
+		a, err := scanAgent(rows)
+		if err != nil {
+			return nil, err
+		}
+		agents = append(agents, a)
+	}
+	return agents, rows.Err()
+}
+
 func (s *Store) UpdateAgentStatus(ctx context.Context, id string, status agent.Status) error {
 	tag, err := s.pool.Exec(ctx, `UPDATE agents SET status = $2 WHERE id = $1`, id, string(status))
 	if err != nil {
@@ -181,6 +340,17 @@ func (s *Store) UpdateAgentStatus(ctx context.Context, id string, status agent.S
 	return nil
 }
 
+func (s *Store) SetAgentSubProject(ctx context.Context, id, subProjectID string) error {
+	tag, err := s.pool.Exec(ctx, `UPDATE agents SET sub_project_id = $2 WHERE id = $1`, id, nullIfEmpty(subProjectID))
+	if err != nil {
+		return fmt.Errorf("set agent sub-project %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("set agent sub-project %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
 func (s *Store) DeleteAgent(ctx context.Context, id string) error {
 	tag, err := s.pool.Exec(ctx, `DELETE FROM agents WHERE id = $1`, id)
 	if err != nil {
@@ -195,8 +365,8 @@ func (s *Store) DeleteAgent(ctx context.Context, id string) error {
 // --- Tasks ---
 
 func (s *Store) ListTasks(ctx context.Context, projectID string) ([]task.Task, error) {
-	rows, err := s.pool.Query(ctx,
-		`SELECT id, project_id, agent_id, title, prompt, status, result, cost_usd, version, created_at, updated_at
+	rows, err := s.reader(ctx).Query(ctx,
+		`SELECT id, project_id, agent_id, COALESCE(sub_project_id::text, ''), title, prompt, status, result, cost_usd, version, created_at, updated_at
 		 FROM tasks WHERE project_id = $1 ORDER BY created_at DESC`, projectID)
 	if err != nil {
 		return nil, fmt.Errorf("list tasks: %w", err)
@@ -216,7 +386,7 @@ func (s *Store) ListTasks(ctx context.Context, projectID string) ([]task.Task, e
 
 func (s *Store) GetTask(ctx context.Context, id string) (*task.Task, error) {
 	row := s.pool.QueryRow(ctx,
-		`SELECT id, project_id, agent_id, title, prompt, status, result, cost_usd, version, created_at, updated_at
+		`SELECT id, project_id, agent_id, COALESCE(sub_project_id::text, ''), title, prompt, status, result, cost_usd, version, created_at, updated_at
 		 FROM tasks WHERE id = $1`, id)
 
 	t, err := scanTask(row)
@@ -231,10 +401,10 @@ func (s *Store) GetTask(ctx context.Context, id string) (*task.Task, error) {
 
 func (s *Store) CreateTask(ctx context.Context, req task.CreateRequest) (*task.Task, error) {
 	row := s.pool.QueryRow(ctx,
-		`INSERT INTO tasks (project_id, title, prompt)
-		 VALUES ($1, $2, $3)
-		 RETURNING id, project_id, agent_id, title, prompt, status, result, cost_usd, version, created_at, updated_at`,
-		req.ProjectID, req.Title, req.Prompt)
+		`INSERT INTO tasks (project_id, sub_project_id, title, prompt)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING id, project_id, agent_id, COALESCE(sub_project_id::text, ''), title, prompt, status, result, cost_usd, version, created_at, updated_at`,
+		req.ProjectID, nullIfEmpty(req.SubProjectID), req.Title, req.Prompt)
 
 	t, err := scanTask(row)
 	if err != nil {
@@ -275,18 +445,18 @@ func (s *Store) UpdateTaskResult(ctx context.Context, id string, result task.Res
 
 func (s *Store) CreateRun(ctx context.Context, r *run.Run) error {
 	row := s.pool.QueryRow(ctx,
-		`INSERT INTO runs (task_id, agent_id, project_id, team_id, policy_profile, exec_mode, deliver_mode, status, output)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`INSERT INTO runs (task_id, agent_id, project_id, team_id, conversation_id, plan_step_id, policy_profile, effective_policy, exec_mode, deliver_mode, branch, status, output)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		 RETURNING id, started_at, created_at, updated_at, version`,
-		r.TaskID, r.AgentID, r.ProjectID, nullIfEmpty(r.TeamID), r.PolicyProfile, string(r.ExecMode), string(r.DeliverMode), string(r.Status), r.Output)
+		r.TaskID, r.AgentID, r.ProjectID, nullIfEmpty(r.TeamID), nullIfEmpty(r.ConversationID), nullIfEmpty(r.PlanStepID), r.PolicyProfile, r.EffectivePolicy, string(r.ExecMode), string(r.DeliverMode), r.Branch, string(r.Status), r.Output)
 
 	return row.Scan(&r.ID, &r.StartedAt, &r.CreatedAt, &r.UpdatedAt, &r.Version)
 }
 
 func (s *Store) GetRun(ctx context.Context, id string) (*run.Run, error) {
 	row := s.pool.QueryRow(ctx,
-		`SELECT id, task_id, agent_id, project_id, COALESCE(team_id::text, ''), policy_profile, exec_mode, deliver_mode, status,
-		        step_count, cost_usd, output, error, version, started_at, completed_at, created_at, updated_at
+		`SELECT id, task_id, agent_id, project_id, COALESCE(team_id::text, ''), COALESCE(conversation_id::text, ''), COALESCE(plan_step_id::text, ''), policy_profile, effective_policy, exec_mode, deliver_mode, worktree_path, branch, status,
+		        step_count, cost_usd, tokens_in, tokens_out, output, error, redactions, structured_output, summary, events_archived_at, interrupted_at, version, started_at, completed_at, created_at, updated_at
 		 FROM runs WHERE id = $1`, id)
 
 	r, err := scanRun(row)
@@ -313,25 +483,35 @@ func (s *Store) UpdateRunStatus(ctx context.Context, id string, status run.Statu
 	return nil
 }
 
+// CompleteRun finishes a pending, running or quality-gate run. Completing
+// a run that already finished changes nothing and returns run.ErrFinished,
+// so a replayed completion cannot overwrite its status, output or cost.
 func (s *Store) CompleteRun(ctx context.Context, id string, status run.Status, output, errMsg string, costUSD float64, stepCount int) error {
 	tag, err := s.pool.Exec(ctx,
 		`UPDATE runs SET status = $2, output = $3, error = $4, cost_usd = $5, step_count = $6, completed_at = now(), updated_at = now()
-		 WHERE id = $1`,
+		 WHERE id = $1 AND status IN ('pending', 'running', 'quality_gate')`,
 		id, string(status), output, errMsg, costUSD, stepCount)
 
 	if err != nil {
 		return fmt.Errorf("complete run %s: %w", id, err)
 	}
 	if tag.RowsAffected() == 0 {
+		var exists bool
+		if err := s.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM runs WHERE id = $1)`, id).Scan(&exists); err != nil {
+			return fmt.Errorf("complete run %s: %w", id, err)
+		}
+		if exists {
+			return fmt.Errorf("complete run %s: %w", id, run.ErrFinished)
+		}
 		return fmt.Errorf("complete run %s: %w", id, domain.ErrNotFound)
 	}
 	return nil
 }
 
 func (s *Store) ListRunsByTask(ctx context.Context, taskID string) ([]run.Run, error) {
-	rows, err := s.pool.Query(ctx,
-		`SELECT id, task_id, agent_id, project_id, COALESCE(team_id::text, ''), policy_profile, exec_mode, deliver_mode, status,
-		        step_count, cost_usd, output, error, version, started_at, completed_at, created_at, updated_at
+	rows, err := s.reader(ctx).Query(ctx,
+		`SELECT id, task_id, agent_id, project_id, COALESCE(team_id::text, ''), COALESCE(conversation_id::text, ''), COALESCE(plan_step_id::text, ''), policy_profile, effective_policy, exec_mode, deliver_mode, worktree_path, branch, status,
+		        step_count, cost_usd, tokens_in, tokens_out, output, error, redactions, structured_output, summary, events_archived_at, interrupted_at, version, started_at, completed_at, created_at, updated_at
 		 FROM runs WHERE task_id = $1 ORDER BY created_at DESC`, taskID)
 	if err != nil {
 		return nil, fmt.Errorf("list runs by task: %w", err)
@@ -349,648 +529,4490 @@ func (s *Store) ListRunsByTask(ctx context.Context, taskID string) ([]run.Run, e
 	return runs, rows.Err()
 }
 
-// --- Agent Teams ---
-
-func (s *Store) CreateTeam(ctx context.Context, req agent.CreateTeamRequest) (*agent.Team, error) {
-	tx, err := s.pool.Begin(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("begin tx: %w", err)
-	}
-	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
-
-	var t agent.Team
-	err = tx.QueryRow(ctx,
-		`INSERT INTO agent_teams (project_id, name, protocol)
-		 VALUES ($1, $2, $3)
-		 RETURNING id, project_id, name, protocol, status, version, created_at, updated_at`,
-		req.ProjectID, req.Name, req.Protocol,
-	).Scan(&t.ID, &t.ProjectID, &t.Name, &t.Protocol, &t.Status, &t.Version, &t.CreatedAt, &t.UpdatedAt)
-	if err != nil {
-		return nil, fmt.Errorf("insert team: %w", err)
-	}
-
-	for _, m := range req.Members {
-		var member agent.TeamMember
-		err = tx.QueryRow(ctx,
-			`INSERT INTO team_members (team_id, agent_id, role)
-			 VALUES ($1, $2, $3)
-			 RETURNING id, team_id, agent_id, role`,
-			t.ID, m.AgentID, string(m.Role),
-		).Scan(&member.ID, &member.TeamID, &member.AgentID, &member.Role)
-		if err != nil {
-			return nil, fmt.Errorf("insert team member: %w", err)
-		}
-		t.Members = append(t.Members, member)
-	}
+// ListRunsByTasks returns the runs of several tasks, newest first.
+func (s *Store) ListRunsByTasks(ctx context.Context, taskIDs []string) ([]run.Run, error) {
+	return s.queryRuns(ctx, "list runs by tasks",
+		`SELECT id, task_id, agent_id, project_id, COALESCE(team_id::text, ''), COALESCE(conversation_id::text, ''), COALESCE(plan_step_id::text, ''), policy_profile, effective_policy, exec_mode, deliver_mode, worktree_path, branch, status,
+		        step_count, cost_usd, tokens_in, tokens_out, output, error, redactions, structured_output, summary, events_archived_at, interrupted_at, version, started_at, completed_at, created_at, updated_at
+		 FROM runs WHERE task_id = ANY($1) ORDER BY created_at DESC`, taskIDs)
+}
 
-	if err := tx.Commit(ctx); err != nil {
-		return nil, fmt.Errorf("commit team: %w", err)
-	}
-	return &t, nil
+// ListActiveRuns returns the pending, running and quality-gate runs,
+// oldest first. A non-empty projectID limits them to that project.
+func (s *Store) ListActiveRuns(ctx context.Context, projectID string) ([]run.Run, error) {
+	return s.queryRuns(ctx, "list active runs",
+		`SELECT id, task_id, agent_id, project_id, COALESCE(team_id::text, ''), COALESCE(conversation_id::text, ''), COALESCE(plan_step_id::text, ''), policy_profile, effective_policy, exec_mode, deliver_mode, worktree_path, branch, status,
+		        step_count, cost_usd, tokens_in, tokens_out, output, error, redactions, structured_output, summary, events_archived_at, interrupted_at, version, started_at, completed_at, created_at, updated_at
+		 FROM runs WHERE status IN ('pending', 'running', 'quality_gate') AND ($1 = '' OR project_id::text = $1)
+		 ORDER BY created_at ASC`, projectID)
 }
 
-func (s *Store) GetTeam(ctx context.Context, id string) (*agent.Team, error) {
-	row := s.pool.QueryRow(ctx,
-		`SELECT id, project_id, name, protocol, status, version, created_at, updated_at
-		 FROM agent_teams WHERE id = $1`, id)
+// GetRuns returns the runs with the given IDs. Unknown IDs are skipped.
+func (s *Store) GetRuns(ctx context.Context, ids []string) ([]run.Run, error) {
+	return s.queryRuns(ctx, "get runs",
+		`SELECT id, task_id, agent_id, project_id, COALESCE(team_id::text, ''), COALESCE(conversation_id::text, ''), COALESCE(plan_step_id::text, ''), policy_profile, effective_policy, exec_mode, deliver_mode, worktree_path, branch, status,
+		        step_count, cost_usd, tokens_in, tokens_out, output, error, redactions, structured_output, summary, events_archived_at, interrupted_at, version, started_at, completed_at, created_at, updated_at
+		 FROM runs WHERE id = ANY($1)`, ids)
+}
 
-	t, err := scanTeam(row)
+// ListCostSummaries totals the daily cost rollups of each project, most
+// expensive first. A non-empty projectID limits the result to that project.
+func (s *Store) ListCostSummaries(ctx context.Context, projectID string) ([]cost.Summary, error) {
+	rows, err := s.reader(ctx).Query(ctx,
+		`SELECT p.id, p.name, COALESCE(SUM(c.runs), 0), COALESCE(SUM(c.cost_usd), 0), COALESCE(SUM(c.tokens_in), 0), COALESCE(SUM(c.tokens_out), 0),
+		        st.refreshed_at,
+		        st.refreshed_at IS NOT NULL AND COALESCE(bool_and(c.day < (st.refreshed_at AT TIME ZONE 'UTC')::date), TRUE)
+		 FROM projects p LEFT JOIN cost_rollups c ON c.project_id = p.id
+		 LEFT JOIN cost_rollup_state st ON TRUE
+		 WHERE $1 = '' OR p.id::text = $1
+		 GROUP BY p.id, p.name, st.refreshed_at
+		 ORDER BY 4 DESC, p.name`, projectID)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, fmt.Errorf("get team %s: %w", id, domain.ErrNotFound)
-		}
-		return nil, fmt.Errorf("get team %s: %w", id, err)
+		return nil, fmt.Errorf("list cost summaries: %w", err)
 	}
+	defer rows.Close()
 
-	members, err := s.listTeamMembers(ctx, t.ID)
-	if err != nil {
-		return nil, err
+	var result []cost.Summary
+	for rows.Next() {
+		var c cost.Summary
+		if err := rows.Scan(&c.ProjectID, &c.ProjectName, &c.Runs, &c.CostUSD, &c.TokensIn, &c.TokensOut, &c.AsOf, &c.Exact); err != nil {
+			return nil, fmt.Errorf("scan cost summary: %w", err)
+		}
+		result = append(result, c)
 	}
-	t.Members = members
-	return &t, nil
+	return result, rows.Err()
 }
 
-func (s *Store) ListTeamsByProject(ctx context.Context, projectID string) ([]agent.Team, error) {
-	rows, err := s.pool.Query(ctx,
-		`SELECT id, project_id, name, protocol, status, version, created_at, updated_at
-		 FROM agent_teams WHERE project_id = $1 ORDER BY created_at DESC`, projectID)
+// ListDailyCosts totals the daily cost rollups since a day, oldest first.
+// A non-empty projectID limits them to that project.
+func (s *Store) ListDailyCosts(ctx context.Context, projectID string, since time.Time) ([]cost.Day, error) {
+	rows, err := s.reader(ctx).Query(ctx,
+		`SELECT to_char(c.day, 'YYYY-MM-DD'), SUM(c.runs), SUM(c.cost_usd), SUM(c.tokens_in), SUM(c.tokens_out),
+		        COALESCE(c.day < (MAX(st.refreshed_at) AT TIME ZONE 'UTC')::date, FALSE)
+		 FROM cost_rollups c LEFT JOIN cost_rollup_state st ON TRUE
+		 WHERE ($1 = '' OR c.project_id::text = $1) AND c.day >= $2::date
+		 GROUP BY c.day
+		 ORDER BY c.day`, projectID, since.UTC().Truncate(24*time.Hour))
 	if err != nil {
-		return nil, fmt.Errorf("list teams: %w", err)
+		return nil, fmt.Errorf("list daily costs: %w", err)
 	}
 	defer rows.Close()
 
-	var teams []agent.Team
+	result := []cost.Day{}
 	for rows.Next() {
-		t, err := scanTeam(rows)
-		if err != nil {
-			return nil, err
+		var d cost.Day
+		if err := rows.Scan(&d.Day, &d.Runs, &d.CostUSD, &d.TokensIn, &d.TokensOut, &d.Exact); err != nil {
+			return nil, fmt.Errorf("scan daily cost: %w", err)
 		}
-		teams = append(teams, t)
+		result = append(result, d)
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+	return result, rows.Err()
+}
+
+// RefreshCostRollups recomputes the cost and usage rollups of the UTC days
+// from the day of from on, replacing their previous totals, and records
+// the pass. A zero from recomputes every day.
+func (s *Store) RefreshCostRollups(ctx context.Context, from time.Time) error {
+	day := from.UTC().Truncate(24 * time.Hour)
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin cost rollup: %w", err)
 	}
+	defer func() { _ = tx.Rollback(ctx) }()
 
-	// Load members for each team
-	for i := range teams {
-		members, err := s.listTeamMembers(ctx, teams[i].ID)
-		if err != nil {
-			return nil, err
+	for _, q := range []string{
+		`DELETE FROM cost_rollups WHERE day >= $1::date`,
+		`INSERT INTO cost_rollups (project_id, day, runs, tokens_in, tokens_out, cost_usd)
+		 SELECT project_id, (created_at AT TIME ZONE 'UTC')::date, COUNT(*), SUM(tokens_in), SUM(tokens_out), SUM(cost_usd)
+		 FROM runs WHERE created_at >= $1::date::timestamp AT TIME ZONE 'UTC'
+		 GROUP BY 1, 2`,
+		`DELETE FROM usage_rollups WHERE day >= $1::date`,
+		`INSERT INTO usage_rollups (project_id, day, tool, model, calls, tokens_in, tokens_out, cost_usd)
+		 SELECT u.project_id, (r.created_at AT TIME ZONE 'UTC')::date, u.tool, u.model, SUM(u.calls), SUM(u.tokens_in), SUM(u.tokens_out), SUM(u.cost_usd)
+		 FROM run_usage u JOIN runs r ON r.id = u.run_id
+		 WHERE r.created_at >= $1::date::timestamp AT TIME ZONE 'UTC'
+		 GROUP BY 1, 2, 3, 4`,
+	} {
+		if _, err := tx.Exec(ctx, q, day); err != nil {
+			return fmt.Errorf("refresh cost rollups: %w", err)
 		}
-		teams[i].Members = members
 	}
-	return teams, nil
-}
-
-func (s *Store) UpdateTeamStatus(ctx context.Context, id string, status agent.TeamStatus) error {
-	tag, err := s.pool.Exec(ctx,
-		`UPDATE agent_teams SET status = $2 WHERE id = $1`,
-		id, string(status))
-	if err != nil {
-		return fmt.Errorf("update team status %s: %w", id, err)
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO cost_rollup_state (id, refreshed_at) VALUES (TRUE, now())
+		 ON CONFLICT (id) DO UPDATE SET refreshed_at = EXCLUDED.refreshed_at`); err != nil {
+		return fmt.Errorf("record cost rollup: %w", err)
 	}
-	if tag.RowsAffected() == 0 {
-		return fmt.Errorf("update team status %s: %w", id, domain.ErrNotFound)
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit cost rollup: %w", err)
 	}
 	return nil
 }
 
-func (s *Store) DeleteTeam(ctx context.Context, id string) error {
-	tag, err := s.pool.Exec(ctx, `DELETE FROM agent_teams WHERE id = $1`, id)
+// AddRunUsage adds calls, tokens and cost to a run's usage of a tool and
+// model.
+func (s *Store) AddRunUsage(ctx context.Context, runID, projectID string, u cost.Usage) error {
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO run_usage (run_id, project_id, tool, model, calls, tokens_in, tokens_out, cost_usd)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 ON CONFLICT (run_id, tool, model) DO UPDATE SET
+		     calls = run_usage.calls + EXCLUDED.calls,
+		     tokens_in = run_usage.tokens_in + EXCLUDED.tokens_in,
+		     tokens_out = run_usage.tokens_out + EXCLUDED.tokens_out,
+		     cost_usd = run_usage.cost_usd + EXCLUDED.cost_usd`,
+		runID, projectID, u.Tool, u.Model, u.Calls, u.TokensIn, u.TokensOut, u.CostUSD)
 	if err != nil {
-		return fmt.Errorf("delete team %s: %w", id, err)
-	}
-	if tag.RowsAffected() == 0 {
-		return fmt.Errorf("delete team %s: %w", id, domain.ErrNotFound)
+		return fmt.Errorf("add run usage %s: %w", runID, err)
 	}
 	return nil
 }
 
-func (s *Store) listTeamMembers(ctx context.Context, teamID string) ([]agent.TeamMember, error) {
-	rows, err := s.pool.Query(ctx,
-		`SELECT id, team_id, agent_id, role FROM team_members WHERE team_id = $1`, teamID)
+// ListStepCosts totals the runs of each step of a plan, most expensive
+// first, with their usage per tool and model.
+func (s *Store) ListStepCosts(ctx context.Context, planID string) ([]cost.StepCost, error) {
+	db := s.reader(ctx)
+	rows, err := db.Query(ctx,
+		`SELECT st.id, st.name, COALESCE(st.task_id::text, ''), st.status, COUNT(r.id),
+		        COALESCE(SUM(r.cost_usd), 0), COALESCE(SUM(r.tokens_in), 0), COALESCE(SUM(r.tokens_out), 0)
+		 FROM plan_steps st LEFT JOIN runs r ON r.plan_step_id = st.id
+		 WHERE st.plan_id = $1
+		 GROUP BY st.id
+		 ORDER BY 6 DESC, st.created_at`, planID)
 	if err != nil {
-		return nil, fmt.Errorf("list team members: %w", err)
+		return nil, fmt.Errorf("list step costs: %w", err)
 	}
 	defer rows.Close()
 
-	var members []agent.TeamMember
+	var result []cost.StepCost
+	index := make(map[string]int)
 	for rows.Next() {
-		var m agent.TeamMember
-		if err := rows.Scan(&m.ID, &m.TeamID, &m.AgentID, &m.Role); err != nil {
-			return nil, fmt.Errorf("scan team member: %w", err)
+		c := cost.StepCost{Usage: []cost.Usage{}}
+		if err := rows.Scan(&c.StepID, &c.Name, &c.TaskID, &c.Status, &c.Runs, &c.CostUSD, &c.TokensIn, &c.TokensOut); err != nil {
+			return nil, fmt.Errorf("scan step cost: %w", err)
 		}
-		members = append(members, m)
+		index[c.StepID] = len(result)
+		result = append(result, c)
 	}
-	return members, rows.Err()
-}
-
-// --- Execution Plans ---
-
-func (s *Store) CreatePlan(ctx context.Context, p *plan.ExecutionPlan) error {
-	tx, err := s.pool.Begin(ctx)
-	if err != nil {
-		return fmt.Errorf("begin tx: %w", err)
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
-	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op // rollback after commit is a no-op
 
-	// Insert plan row
-	err = tx.QueryRow(ctx,
-		`INSERT INTO execution_plans (project_id, team_id, name, description, protocol, status, max_parallel)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7)
-		 RETURNING id, version, created_at, updated_at`,
-		p.ProjectID, nullIfEmpty(p.TeamID), p.Name, p.Description, string(p.Protocol), string(p.Status), p.MaxParallel,
-	).Scan(&p.ID, &p.Version, &p.CreatedAt, &p.UpdatedAt)
+	usage, err := db.Query(ctx,
+		`SELECT r.plan_step_id::text, u.tool, u.model, SUM(u.calls), SUM(u.cost_usd), SUM(u.tokens_in), SUM(u.tokens_out)
+		 FROM run_usage u JOIN runs r ON r.id = u.run_id
+		 JOIN plan_steps st ON st.id = r.plan_step_id
+		 WHERE st.plan_id = $1
+		 GROUP BY r.plan_step_id, u.tool, u.model
+		 ORDER BY 5 DESC, u.tool, u.model`, planID)
 	if err != nil {
-		return fmt.Errorf("insert plan: %w", err)
+		return nil, fmt.Errorf("list step usage: %w", err)
 	}
+	defer usage.Close()
 
-	// Insert steps, building index→UUID map for dependency remapping
-	idMap := make(map[int]string, len(p.Steps))
-	for i := range p.Steps {
-		step := &p.Steps[i]
-		step.PlanID = p.ID
-		err = tx.QueryRow(ctx,
-			`INSERT INTO plan_steps (plan_id, task_id, agent_id, policy_profile, deliver_mode, depends_on, status, round)
-			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-			 RETURNING id, created_at, updated_at`,
-			step.PlanID, step.TaskID, step.AgentID, step.PolicyProfile, step.DeliverMode,
-			step.DependsOn, string(step.Status), step.Round,
-		).Scan(&step.ID, &step.CreatedAt, &step.UpdatedAt)
-		if err != nil {
-			return fmt.Errorf("insert step %d: %w", i, err)
+	for usage.Next() {
+		var stepID string
+		var u cost.Usage
+		if err := usage.Scan(&stepID, &u.Tool, &u.Model, &u.Calls, &u.CostUSD, &u.TokensIn, &u.TokensOut); err != nil {
+			return nil, fmt.Errorf("scan step usage: %w", err)
+		}
+		if i, ok := index[stepID]; ok {
+			result[i].Usage = append(result[i].Usage, u)
 		}
-		idMap[i] = step.ID
 	}
-
-	return tx.Commit(ctx)
+	return result, usage.Err()
 }
 
-func (s *Store) GetPlan(ctx context.Context, id string) (*plan.ExecutionPlan, error) {
-	row := s.pool.QueryRow(ctx,
-		`SELECT id, project_id, COALESCE(team_id::text, ''), name, description, protocol, status, max_parallel, version, created_at, updated_at
-		 FROM execution_plans WHERE id = $1`, id)
-
-	p, err := scanPlan(row)
+// ListToolCosts totals the daily usage rollups of a project per tool and
+// model, most expensive first.
+func (s *Store) ListToolCosts(ctx context.Context, projectID string) ([]cost.Usage, error) {
+	rows, err := s.reader(ctx).Query(ctx,
+		`SELECT tool, model, SUM(calls), SUM(cost_usd), SUM(tokens_in), SUM(tokens_out)
+		 FROM usage_rollups WHERE project_id = $1
+		 GROUP BY tool, model
+		 ORDER BY 4 DESC, tool, model`, projectID)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, fmt.Errorf("get plan %s: %w", id, domain.ErrNotFound)
-		}
-		return nil, fmt.Errorf("get plan %s: %w", id, err)
+		return nil, fmt.Errorf("list tool costs: %w", err)
 	}
+	defer rows.Close()
 
-	steps, err := s.ListPlanSteps(ctx, p.ID)
-	if err != nil {
-		return nil, err
+	result := []cost.Usage{}
+	for rows.Next() {
+		var u cost.Usage
+		if err := rows.Scan(&u.Tool, &u.Model, &u.Calls, &u.CostUSD, &u.TokensIn, &u.TokensOut); err != nil {
+			return nil, fmt.Errorf("scan tool cost: %w", err)
+		}
+		result = append(result, u)
 	}
-	p.Steps = steps
-	return &p, nil
+	return result, rows.Err()
 }
 
-func (s *Store) ListPlansByProject(ctx context.Context, projectID string) ([]plan.ExecutionPlan, error) {
-	rows, err := s.pool.Query(ctx,
-		`SELECT id, project_id, COALESCE(team_id::text, ''), name, description, protocol, status, max_parallel, version, created_at, updated_at
-		 FROM execution_plans WHERE project_id = $1 ORDER BY created_at DESC`, projectID)
+func (s *Store) queryRuns(ctx context.Context, op, query string, args ...any) ([]run.Run, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("list plans: %w", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 	defer rows.Close()
 
-	var plans []plan.ExecutionPlan
+	var runs []run.Run
 	for rows.Next() {
-		p, err := scanPlan(rows)
+		r, err := scanRun(rows)
 		if err != nil {
 			return nil, err
 		}
-		plans = append(plans, p)
+		runs = append(runs, r)
 	}
-	return plans, rows.Err()
+	return runs, rows.Err()
 }
 
-func (s *Store) UpdatePlanStatus(ctx context.Context, id string, status plan.Status) error {
+func (s *Store) SetRunWorktree(ctx context.Context, id, path string) error {
 	tag, err := s.pool.Exec(ctx,
-		`UPDATE execution_plans SET status = $2 WHERE id = $1`,
-		id, string(status))
+		`UPDATE runs SET worktree_path = $2, updated_at = now() WHERE id = $1`, id, path)
 	if err != nil {
-		return fmt.Errorf("update plan status %s: %w", id, err)
+		return fmt.Errorf("set run worktree %s: %w", id, err)
 	}
 	if tag.RowsAffected() == 0 {
-		return fmt.Errorf("update plan status %s: %w", id, domain.ErrNotFound)
+		return fmt.Errorf("set run worktree %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// AddRunRedactions increments the number of credentials masked in a run's output.
+func (s *Store) AddRunRedactions(ctx context.Context, id string, n int) error {
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE runs SET redactions = redactions + $2, updated_at = now() WHERE id = $1`, id, n)
+	if err != nil {
+		return fmt.Errorf("add run redactions %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("add run redactions %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// AddRunTokens increments the LLM tokens consumed by a run.
+func (s *Store) AddRunTokens(ctx context.Context, id string, tokensIn, tokensOut int) error {
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE runs SET tokens_in = tokens_in + $2, tokens_out = tokens_out + $3, updated_at = now() WHERE id = $1`,
+		id, tokensIn, tokensOut)
+	if err != nil {
+		return fmt.Errorf("add run tokens %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("add run tokens %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// SetRunStructuredOutput stores the final answer of a run parsed against
+// its mode's output schema.
+func (s *Store) SetRunStructuredOutput(ctx context.Context, id string, output json.RawMessage) error {
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE runs SET structured_output = $2, updated_at = now() WHERE id = $1`, id, []byte(output))
+	if err != nil {
+		return fmt.Errorf("set run structured output %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("set run structured output %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// SetRunSummary stores the natural language summary of a run.
+func (s *Store) SetRunSummary(ctx context.Context, id, summary string) error {
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE runs SET summary = $2, updated_at = now() WHERE id = $1`, id, summary)
+	if err != nil {
+		return fmt.Errorf("set run summary %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("set run summary %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// ListRunsWithWorktree returns finished runs that still hold a worktree and
+// completed before the given time, oldest first.
+func (s *Store) ListRunsWithWorktree(ctx context.Context, completedBefore time.Time) ([]run.Run, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT id, task_id, agent_id, project_id, COALESCE(team_id::text, ''), COALESCE(conversation_id::text, ''), COALESCE(plan_step_id::text, ''), policy_profile, effective_policy, exec_mode, deliver_mode, worktree_path, branch, status,
+		        step_count, cost_usd, tokens_in, tokens_out, output, error, redactions, structured_output, summary, events_archived_at, interrupted_at, version, started_at, completed_at, created_at, updated_at
+		 FROM runs WHERE worktree_path <> '' AND completed_at IS NOT NULL AND completed_at < $1
+		 ORDER BY completed_at`, completedBefore)
+	if err != nil {
+		return nil, fmt.Errorf("list runs with worktree: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []run.Run
+	for rows.Next() {
+		r, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+// ListRunsForEventArchival returns finished runs of a project whose events
+// have not been archived and that completed before the given time, oldest first.
+func (s *Store) ListRunsForEventArchival(ctx context.Context, projectID string, completedBefore time.Time, limit int) ([]run.Run, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT id, task_id, agent_id, project_id, COALESCE(team_id::text, ''), COALESCE(conversation_id::text, ''), COALESCE(plan_step_id::text, ''), policy_profile, effective_policy, exec_mode, deliver_mode, worktree_path, branch, status,
+		        step_count, cost_usd, tokens_in, tokens_out, output, error, redactions, structured_output, summary, events_archived_at, interrupted_at, version, started_at, completed_at, created_at, updated_at
+		 FROM runs WHERE project_id = $1 AND events_archived_at IS NULL AND completed_at IS NOT NULL AND completed_at < $2
+		 ORDER BY completed_at LIMIT $3`, projectID, completedBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list runs for event archival: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []run.Run
+	for rows.Next() {
+		r, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+// SetRunEventsArchived records that a run's events were moved to an archive.
+func (s *Store) SetRunEventsArchived(ctx context.Context, id string) error {
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE runs SET events_archived_at = now(), updated_at = now() WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("set run events archived %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("set run events archived %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// SetRunInterrupted marks a run as interrupted by a server shutdown, to be
+// resumed by another server, or clears the mark. Servers resuming runs
+// claim them with ClaimInterruptedRun instead.
+func (s *Store) SetRunInterrupted(ctx context.Context, id string, interrupted bool) error {
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE runs SET interrupted_at = CASE WHEN $2 THEN now() END, updated_at = now() WHERE id = $1`, id, interrupted)
+	if err != nil {
+		return fmt.Errorf("set run interrupted %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("set run interrupted %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// ClaimInterruptedRun clears the interrupted mark of a run if it is still
+// set and reports whether it did, so of the servers starting at the same
+// time only one resumes the run.
+func (s *Store) ClaimInterruptedRun(ctx context.Context, id string) (bool, error) {
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE runs SET interrupted_at = NULL, updated_at = now() WHERE id = $1 AND interrupted_at IS NOT NULL`, id)
+	if err != nil {
+		return false, fmt.Errorf("claim interrupted run %s: %w", id, err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// --- Agent Teams ---
+
+func (s *Store) CreateTeam(ctx context.Context, req agent.CreateTeamRequest) (*agent.Team, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
+
+	var t agent.Team
+	err = tx.QueryRow(ctx,
+		`INSERT INTO agent_teams (project_id, name, protocol)
+		 VALUES ($1, $2, $3)
+		 RETURNING id, project_id, name, protocol, status, version, created_at, updated_at`,
+		req.ProjectID, req.Name, req.Protocol,
+	).Scan(&t.ID, &t.ProjectID, &t.Name, &t.Protocol, &t.Status, &t.Version, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("insert team: %w", err)
+	}
+
+	for _, m := range req.Members {
+		var member agent.TeamMember
+		err = tx.QueryRow(ctx,
+			`INSERT INTO team_members (team_id, agent_id, role)
+			 VALUES ($1, $2, $3)
+			 RETURNING id, team_id, agent_id, role`,
+			t.ID, m.AgentID, string(m.Role),
+		).Scan(&member.ID, &member.TeamID, &member.AgentID, &member.Role)
+		if err != nil {
+			return nil, fmt.Errorf("insert team member: %w", err)
+		}
+		t.Members = append(t.Members, member)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit team: %w", err)
+	}
+	return &t, nil
+}
+
+func (s *Store) GetTeam(ctx context.Context, id string) (*agent.Team, error) {
+	row := s.pool.QueryRow(ctx,
+		`SELECT id, project_id, name, protocol, status, version, created_at, updated_at
+		 FROM agent_teams WHERE id = $1`, id)
+
+	t, err := scanTeam(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get team %s: %w", id, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get team %s: %w", id, err)
+	}
+
+	members, err := s.listTeamMembers(ctx, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	t.Members = members
+	return &t, nil
+}
+
+func (s *Store) ListTeamsByProject(ctx context.Context, projectID string) ([]agent.Team, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT id, project_id, name, protocol, status, version, created_at, updated_at
+		 FROM agent_teams WHERE project_id = $1 ORDER BY created_at DESC`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list teams: %w", err)
+	}
+	defer rows.Close()
+
+	var teams []agent.Team
+	for rows.Next() {
+		t, err := scanTeam(rows)
+		if err != nil {
+			return nil, err
+		}
+		teams = append(teams, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Load members for each team
+	for i := range teams {
+		members, err := s.listTeamMembers(ctx, teams[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		teams[i].Members = members
+	}
+	return teams, nil
+}
+
+func (s *Store) UpdateTeamStatus(ctx context.Context, id string, status agent.TeamStatus) error {
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE agent_teams SET status = $2 WHERE id = $1`,
+		id, string(status))
+	if err != nil {
+		return fmt.Errorf("update team status %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("update team status %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Store) DeleteTeam(ctx context.Context, id string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM agent_teams WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete team %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete team %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Store) listTeamMembers(ctx context.Context, teamID string) ([]agent.TeamMember, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT id, team_id, agent_id, role FROM team_members WHERE team_id = $1`, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("list team members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []agent.TeamMember
+	for rows.Next() {
+		var m agent.TeamMember
+		if err := rows.Scan(&m.ID, &m.TeamID, &m.AgentID, &m.Role); err != nil {
+			return nil, fmt.Errorf("scan team member: %w", err)
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// --- Execution Plans ---
+
+func (s *Store) CreatePlan(ctx context.Context, p *plan.ExecutionPlan) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op // rollback after commit is a no-op
+
+	// Insert plan row
+	err = tx.QueryRow(ctx,
+		`INSERT INTO execution_plans (project_id, team_id, name, description, protocol, status, max_parallel, rerun_of)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 RETURNING id, version, created_at, updated_at`,
+		p.ProjectID, nullIfEmpty(p.TeamID), p.Name, p.Description, string(p.Protocol), string(p.Status), p.MaxParallel, nullIfEmpty(p.RerunOf),
+	).Scan(&p.ID, &p.Version, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("insert plan: %w", err)
+	}
+
+	// Insert steps first; references between them are stored once every
+	// step has its UUID. Steps a rerun kept carry their run, approval and
+	// image.
+	for i := range p.Steps {
+		step := &p.Steps[i]
+		step.PlanID = p.ID
+		err = tx.QueryRow(ctx,
+			`INSERT INTO plan_steps (plan_id, step_type, name, task_id, agent_id, model, policy_profile, deliver_mode, depends_on, status, round, retry_policy,
+			                         run_id, attempt, approval, image)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, '{}', $9, $10, $11, $12, $13, $14, $15)
+			 RETURNING id, created_at, updated_at`,
+			step.PlanID, stepType(step.Type), step.Name, nullIfEmpty(step.TaskID), nullIfEmpty(step.AgentID), step.Model, step.PolicyProfile, step.DeliverMode,
+			string(step.Status), step.Round, retryPolicyJSON(step.Retry), nullIfEmpty(step.RunID), step.Attempt, jsonOrNil(step.Approval),
+			jsonOrNil(step.Image),
+		).Scan(&step.ID, &step.CreatedAt, &step.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("insert step %d: %w", i, err)
+		}
+	}
+
+	// Remap step indices in dependencies, conditions and loops to UUIDs
+	plan.RemapStepRefs(p.Steps)
+	for i := range p.Steps {
+		step := &p.Steps[i]
+		if len(step.DependsOn) == 0 && step.When == nil && step.Loop == nil {
+			continue
+		}
+		_, err = tx.Exec(ctx,
+			`UPDATE plan_steps SET depends_on = $2, step_condition = $3, step_loop = $4 WHERE id = $1`,
+			step.ID, step.DependsOn, jsonOrNil(step.When), jsonOrNil(step.Loop))
+		if err != nil {
+			return fmt.Errorf("link step %d: %w", i, err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *Store) GetPlan(ctx context.Context, id string) (*plan.ExecutionPlan, error) {
+	row := s.pool.QueryRow(ctx,
+		`SELECT id, project_id, COALESCE(team_id::text, ''), name, description, protocol, status, max_parallel, COALESCE(rerun_of::text, ''),
+		        version, created_at, updated_at
+		 FROM execution_plans WHERE id = $1`, id)
+
+	p, err := scanPlan(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get plan %s: %w", id, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get plan %s: %w", id, err)
+	}
+
+	steps, err := s.ListPlanSteps(ctx, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	p.Steps = steps
+	return &p, nil
+}
+
+func (s *Store) ListPlansByProject(ctx context.Context, projectID string) ([]plan.ExecutionPlan, error) {
+	rows, err := s.reader(ctx).Query(ctx,
+		`SELECT id, project_id, COALESCE(team_id::text, ''), name, description, protocol, status, max_parallel, COALESCE(rerun_of::text, ''),
+		        version, created_at, updated_at
+		 FROM execution_plans WHERE project_id = $1 ORDER BY created_at DESC`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list plans: %w", err)
+	}
+	defer rows.Close()
+
+	var plans []plan.ExecutionPlan
+	for rows.Next() {
+		p, err := scanPlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, p)
+	}
+	return plans, rows.Err()
+}
+
+func (s *Store) UpdatePlanStatus(ctx context.Context, id string, status plan.Status) error {
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE execution_plans SET status = $2 WHERE id = $1`,
+		id, string(status))
+	if err != nil {
+		return fmt.Errorf("update plan status %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("update plan status %s: %w", id, domain.ErrNotFound)
 	}
 	return nil
 }
 
 func (s *Store) CreatePlanStep(ctx context.Context, step *plan.Step) error {
 	return s.pool.QueryRow(ctx,
-		`INSERT INTO plan_steps (plan_id, task_id, agent_id, policy_profile, deliver_mode, depends_on, status, round)
+		`INSERT INTO plan_steps (plan_id, step_type, name, task_id, agent_id, model, policy_profile, deliver_mode, depends_on, status, round, retry_policy,
+		                         step_condition, step_loop, image)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		 RETURNING id, created_at, updated_at`,
+		step.PlanID, stepType(step.Type), step.Name, nullIfEmpty(step.TaskID), nullIfEmpty(step.AgentID), step.Model, step.PolicyProfile, step.DeliverMode,
+		step.DependsOn, string(step.Status), step.Round, retryPolicyJSON(step.Retry), jsonOrNil(step.When), jsonOrNil(step.Loop), jsonOrNil(step.Image),
+	).Scan(&step.ID, &step.CreatedAt, &step.UpdatedAt)
+}
+
+func (s *Store) ListPlanSteps(ctx context.Context, planID string) ([]plan.Step, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT id, plan_id, step_type, name, COALESCE(task_id::text, ''), COALESCE(agent_id::text, ''), model, policy_profile, deliver_mode,
+		        depends_on, status, run_id, round, error, retry_policy, attempt, attempts, retry_at, approval, step_condition, step_loop, image,
+		        created_at, updated_at
+		 FROM plan_steps WHERE plan_id = $1 ORDER BY created_at ASC`, planID)
+	if err != nil {
+		return nil, fmt.Errorf("list plan steps: %w", err)
+	}
+	defer rows.Close()
+
+	var steps []plan.Step
+	for rows.Next() {
+		st, err := scanPlanStep(rows)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, st)
+	}
+	return steps, rows.Err()
+}
+
+// ListWaitingApprovals returns the approval steps waiting for a human,
+// oldest first. A non-empty projectID limits them to that project's plans.
+func (s *Store) ListWaitingApprovals(ctx context.Context, projectID string) ([]plan.Step, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT s.id, s.plan_id, s.step_type, s.name, COALESCE(s.task_id::text, ''), COALESCE(s.agent_id::text, ''), s.model, s.policy_profile, s.deliver_mode,
+		        s.depends_on, s.status, s.run_id, s.round, s.error, s.retry_policy, s.attempt, s.attempts, s.retry_at, s.approval, s.step_condition, s.step_loop, s.image,
+		        s.created_at, s.updated_at
+		 FROM plan_steps s JOIN execution_plans p ON p.id = s.plan_id
+		 WHERE s.status = 'waiting_approval' AND ($1 = '' OR p.project_id::text = $1)
+		 ORDER BY s.updated_at ASC`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list waiting approvals: %w", err)
+	}
+	defer rows.Close()
+
+	var steps []plan.Step
+	for rows.Next() {
+		st, err := scanPlanStep(rows)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, st)
+	}
+	return steps, rows.Err()
+}
+
+func (s *Store) UpdatePlanStepStatus(ctx context.Context, stepID string, status plan.StepStatus, runID, errMsg string) error {
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE plan_steps SET status = $2, run_id = CASE WHEN $3 = '' THEN run_id ELSE $3::uuid END, error = $4
+		 WHERE id = $1`,
+		stepID, string(status), runID, errMsg)
+	if err != nil {
+		return fmt.Errorf("update plan step status %s: %w", stepID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("update plan step status %s: %w", stepID, domain.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Store) GetPlanStepByRunID(ctx context.Context, runID string) (*plan.Step, error) {
+	row := s.pool.QueryRow(ctx,
+		`SELECT id, plan_id, step_type, name, COALESCE(task_id::text, ''), COALESCE(agent_id::text, ''), model, policy_profile, deliver_mode,
+		        depends_on, status, run_id, round, error, retry_policy, attempt, attempts, retry_at, approval, step_condition, step_loop, image,
+		        created_at, updated_at
+		 FROM plan_steps WHERE run_id = $1`, runID)
+
+	st, err := scanPlanStep(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get plan step by run %s: %w", runID, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get plan step by run %s: %w", runID, err)
+	}
+	return &st, nil
+}
+
+func (s *Store) UpdatePlanStepRound(ctx context.Context, stepID string, round int) error {
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE plan_steps SET round = $2 WHERE id = $1`,
+		stepID, round)
+	if err != nil {
+		return fmt.Errorf("update plan step round %s: %w", stepID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("update plan step round %s: %w", stepID, domain.ErrNotFound)
+	}
+	return nil
+}
+
+func (s *Store) UpdatePlanStepAttempts(ctx context.Context, stepID string, attempt int, attempts []plan.StepAttempt, retryAt *time.Time) error {
+	if attempts == nil {
+		attempts = []plan.StepAttempt{}
+	}
+	attemptsJSON, err := json.Marshal(attempts)
+	if err != nil {
+		return fmt.Errorf("marshal step attempts: %w", err)
+	}
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE plan_steps SET attempt = $2, attempts = $3, retry_at = $4 WHERE id = $1`,
+		stepID, attempt, attemptsJSON, retryAt)
+	if err != nil {
+		return fmt.Errorf("update plan step attempts %s: %w", stepID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("update plan step attempts %s: %w", stepID, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// SetPlanStepApproval writes a step's approval and bumps its version. It
+// fails with domain.ErrConflict if the stored approval is no longer the
+// version a was read at, so concurrent votes cannot overwrite each other.
+func (s *Store) SetPlanStepApproval(ctx context.Context, stepID string, a *plan.Approval) error {
+	next := *a
+	next.Version++
+	data, err := json.Marshal(&next)
+	if err != nil {
+		return fmt.Errorf("marshal step approval: %w", err)
+	}
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE plan_steps SET approval = $2
+		 WHERE id = $1 AND COALESCE((approval->>'version')::int, 0) = $3`,
+		stepID, data, a.Version)
+	if err != nil {
+		return fmt.Errorf("set plan step approval %s: %w", stepID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		var exists bool
+		if err := s.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM plan_steps WHERE id = $1)`, stepID).Scan(&exists); err != nil {
+			return fmt.Errorf("set plan step approval %s: %w", stepID, err)
+		}
+		if exists {
+			return fmt.Errorf("set plan step approval %s: %w", stepID, domain.ErrConflict)
+		}
+		return fmt.Errorf("set plan step approval %s: %w", stepID, domain.ErrNotFound)
+	}
+	a.Version = next.Version
+	return nil
+}
+
+func (s *Store) SetPlanStepImage(ctx context.Context, stepID string, b *plan.ImageBuild) error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return fmt.Errorf("marshal step image: %w", err)
+	}
+	tag, err := s.pool.Exec(ctx, `UPDATE plan_steps SET image = $2 WHERE id = $1`, stepID, data)
+	if err != nil {
+		return fmt.Errorf("set plan step image %s: %w", stepID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("set plan step image %s: %w", stepID, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// --- Plan Debates ---
+
+// AddDebateTurn records a finished turn of a ping-pong step, replacing an
+// earlier record of the same round.
+func (s *Store) AddDebateTurn(ctx context.Context, planID, projectID string, t *plan.DebateTurn) error {
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO plan_debate_turns (step_id, round, plan_id, project_id, role, run_id, agent_id, status, output)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 ON CONFLICT (step_id, round) DO UPDATE SET
+		     role = EXCLUDED.role, run_id = EXCLUDED.run_id, agent_id = EXCLUDED.agent_id,
+		     status = EXCLUDED.status, output = EXCLUDED.output, created_at = now()`,
+		t.StepID, t.Round, planID, projectID, string(t.Role), nullIfEmpty(t.RunID), nullIfEmpty(t.AgentID), string(t.Status), t.Output)
+	if err != nil {
+		return fmt.Errorf("add debate turn %s/%d: %w", t.StepID, t.Round, err)
+	}
+	return nil
+}
+
+// ListDebateTurns returns the turns of a plan's debate, oldest first.
+func (s *Store) ListDebateTurns(ctx context.Context, planID string) ([]plan.DebateTurn, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT step_id, round, role, COALESCE(run_id::text, ''), COALESCE(agent_id::text, ''), status, output, created_at
+		 FROM plan_debate_turns WHERE plan_id = $1 ORDER BY created_at, round`, planID)
+	if err != nil {
+		return nil, fmt.Errorf("list debate turns: %w", err)
+	}
+	defer rows.Close()
+
+	var turns []plan.DebateTurn
+	for rows.Next() {
+		var t plan.DebateTurn
+		if err := rows.Scan(&t.StepID, &t.Round, &t.Role, &t.RunID, &t.AgentID, &t.Status, &t.Output, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan debate turn: %w", err)
+		}
+		turns = append(turns, t)
+	}
+	return turns, rows.Err()
+}
+
+// SetDebateSummary stores the arbiter's summary of a plan's debate.
+func (s *Store) SetDebateSummary(ctx context.Context, planID, projectID string, sum *plan.DebateSummary) error {
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO plan_debate_summaries (plan_id, project_id, summary, model)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (plan_id) DO UPDATE SET summary = EXCLUDED.summary, model = EXCLUDED.model, created_at = now()`,
+		planID, projectID, sum.Text, sum.Model)
+	if err != nil {
+		return fmt.Errorf("set debate summary %s: %w", planID, err)
+	}
+	return nil
+}
+
+// GetDebateSummary returns the arbiter's summary of a plan's debate.
+func (s *Store) GetDebateSummary(ctx context.Context, planID string) (*plan.DebateSummary, error) {
+	var sum plan.DebateSummary
+	err := s.pool.QueryRow(ctx,
+		`SELECT summary, model, created_at FROM plan_debate_summaries WHERE plan_id = $1`, planID).
+		Scan(&sum.Text, &sum.Model, &sum.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("debate summary %s: %w", planID, domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get debate summary %s: %w", planID, err)
+	}
+	return &sum, nil
+}
+
+// --- Scanners ---
+
+type scannable interface {
+	Scan(dest ...any) error
+}
+
+func scanAgent(row scannable) (agent.Agent, error) {
+	var a agent.Agent
+	var configJSON []byte
+	err := row.Scan(&a.ID, &a.ProjectID, &a.SubProjectID, &a.Name, &a.Backend, &a.Status, &configJSON, &a.TemplateID, &a.Version, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return a, err
+	}
+	if configJSON != nil {
+		if err := json.Unmarshal(configJSON, &a.Config); err != nil {
+			return a, fmt.Errorf("unmarshal agent config: %w", err)
+		}
+	}
+	return a, nil
+}
+
+func scanSubProject(row scannable) (project.SubProject, error) {
+	var sp project.SubProject
+	err := row.Scan(&sp.ID, &sp.ProjectID, &sp.Name, &sp.Path, &sp.Language, &sp.BuildCommand, &sp.TestCommand, &sp.CreatedAt, &sp.UpdatedAt)
+	return sp, err
+}
+
+func scanProject(row scannable) (project.Project, error) {
+	var p project.Project
+	var configJSON []byte
+	err := row.Scan(&p.ID, &p.TenantID, &p.Name, &p.Description, &p.RepoURL, &p.Provider, &p.WorkspacePath, &configJSON, &p.Version, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return p, err
+	}
+	if configJSON != nil {
+		if err := json.Unmarshal(configJSON, &p.Config); err != nil {
+			return p, fmt.Errorf("unmarshal config: %w", err)
+		}
+	}
+	return p, nil
+}
+
+func scanRun(row scannable) (run.Run, error) {
+	var r run.Run
+	err := row.Scan(
+		&r.ID, &r.TaskID, &r.AgentID, &r.ProjectID, &r.TeamID, &r.ConversationID, &r.PlanStepID, &r.PolicyProfile, &r.EffectivePolicy,
+		&r.ExecMode, &r.DeliverMode, &r.WorktreePath, &r.Branch, &r.Status, &r.StepCount, &r.CostUSD, &r.TokensIn, &r.TokensOut, &r.Output, &r.Error,
+		&r.Redactions, &r.StructuredOutput, &r.Summary, &r.EventsArchivedAt, &r.InterruptedAt, &r.Version, &r.StartedAt, &r.CompletedAt, &r.CreatedAt, &r.UpdatedAt,
+	)
+	return r, err
+}
+
+func scanTask(row scannable) (task.Task, error) {
+	var t task.Task
+	var agentID *string
+	var resultJSON []byte
+	err := row.Scan(&t.ID, &t.ProjectID, &agentID, &t.SubProjectID, &t.Title, &t.Prompt, &t.Status, &resultJSON, &t.CostUSD, &t.Version, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return t, err
+	}
+	if agentID != nil {
+		t.AgentID = *agentID
+	}
+	if resultJSON != nil {
+		var r task.Result
+		if err := json.Unmarshal(resultJSON, &r); err != nil {
+			return t, fmt.Errorf("unmarshal result: %w", err)
+		}
+		t.Result = &r
+	}
+	return t, nil
+}
+
+func scanTeam(row scannable) (agent.Team, error) {
+	var t agent.Team
+	err := row.Scan(&t.ID, &t.ProjectID, &t.Name, &t.Protocol, &t.Status, &t.Version, &t.CreatedAt, &t.UpdatedAt)
+	return t, err
+}
+
+func scanPlan(row scannable) (plan.ExecutionPlan, error) {
+	var p plan.ExecutionPlan
+	err := row.Scan(&p.ID, &p.ProjectID, &p.TeamID, &p.Name, &p.Description, &p.Protocol, &p.Status,
+		&p.MaxParallel, &p.RerunOf, &p.Version, &p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
+
+func scanPlanStep(row scannable) (plan.Step, error) {
+	var st plan.Step
+	var runID *string
+	var retryJSON, attemptsJSON, approvalJSON, whenJSON, loopJSON, imageJSON []byte
+	err := row.Scan(&st.ID, &st.PlanID, &st.Type, &st.Name, &st.TaskID, &st.AgentID, &st.Model, &st.PolicyProfile, &st.DeliverMode,
+		&st.DependsOn, &st.Status, &runID, &st.Round, &st.Error,
+		&retryJSON, &st.Attempt, &attemptsJSON, &st.RetryAt, &approvalJSON, &whenJSON, &loopJSON, &imageJSON, &st.CreatedAt, &st.UpdatedAt)
+	if err != nil {
+		return st, err
+	}
+	if runID != nil {
+		st.RunID = *runID
+	}
+	if retryJSON != nil {
+		if err := json.Unmarshal(retryJSON, &st.Retry); err != nil {
+			return st, fmt.Errorf("unmarshal retry policy: %w", err)
+		}
+	}
+	if attemptsJSON != nil {
+		if err := json.Unmarshal(attemptsJSON, &st.Attempts); err != nil {
+			return st, fmt.Errorf("unmarshal step attempts: %w", err)
+		}
+	}
+	if approvalJSON != nil {
+		if err := json.Unmarshal(approvalJSON, &st.Approval); err != nil {
+			return st, fmt.Errorf("unmarshal step approval: %w", err)
+		}
+	}
+	if whenJSON != nil {
+		if err := json.Unmarshal(whenJSON, &st.When); err != nil {
+			return st, fmt.Errorf("unmarshal step condition: %w", err)
+		}
+	}
+	if loopJSON != nil {
+		if err := json.Unmarshal(loopJSON, &st.Loop); err != nil {
+			return st, fmt.Errorf("unmarshal step loop: %w", err)
+		}
+	}
+	if imageJSON != nil {
+		if err := json.Unmarshal(imageJSON, &st.Image); err != nil {
+			return st, fmt.Errorf("unmarshal step image: %w", err)
+		}
+	}
+	return st, nil
+}
+
+// stepType defaults an empty step type to a run step.
+func stepType(t plan.StepType) string {
+	if t == "" {
+		return string(plan.StepTypeRun)
+	}
+	return string(t)
+}
+
+// jsonOrNil encodes a nullable JSONB value, mapping nil pointers to NULL.
+func jsonOrNil[T any](v *T) []byte {
+	if v == nil {
+		return nil
+	}
+	data, _ := json.Marshal(v)
+	return data
+}
+
+// retryPolicyJSON encodes a retry policy for the nullable retry_policy column.
+func retryPolicyJSON(r *plan.RetryPolicy) []byte {
+	if r == nil {
+		return nil
+	}
+	data, _ := json.Marshal(r)
+	return data
+}
+
+// --- Context Packs ---
+
+// CreateContextPack inserts a context pack and its entries in a transaction.
+func (s *Store) CreateContextPack(ctx context.Context, pack *cfcontext.ContextPack) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
+
+	err = tx.QueryRow(ctx,
+		`INSERT INTO context_packs (task_id, project_id, token_budget, tokens_used)
+		 VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
+		pack.TaskID, pack.ProjectID, pack.TokenBudget, pack.TokensUsed,
+	).Scan(&pack.ID, &pack.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("insert context_pack: %w", err)
+	}
+
+	for i := range pack.Entries {
+		e := &pack.Entries[i]
+		e.PackID = pack.ID
+		err = tx.QueryRow(ctx,
+			`INSERT INTO context_entries (pack_id, kind, path, content, tokens, priority, citation)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+			e.PackID, e.Kind, e.Path, e.Content, e.Tokens, e.Priority, jsonOrNil(e.Citation),
+		).Scan(&e.ID)
+		if err != nil {
+			return fmt.Errorf("insert context_entry %d: %w", i, err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetContextPack returns a context pack by ID with all entries.
+func (s *Store) GetContextPack(ctx context.Context, id string) (*cfcontext.ContextPack, error) {
+	var p cfcontext.ContextPack
+	err := s.pool.QueryRow(ctx,
+		`SELECT id, task_id, project_id, token_budget, tokens_used, created_at
+		 FROM context_packs WHERE id = $1`, id,
+	).Scan(&p.ID, &p.TaskID, &p.ProjectID, &p.TokenBudget, &p.TokensUsed, &p.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, fmt.Errorf("get context_pack: %w", err)
+	}
+
+	entries, err := s.loadContextEntries(ctx, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	p.Entries = entries
+	return &p, nil
+}
+
+// GetContextPackByTask returns the context pack for a task.
+func (s *Store) GetContextPackByTask(ctx context.Context, taskID string) (*cfcontext.ContextPack, error) {
+	var p cfcontext.ContextPack
+	err := s.pool.QueryRow(ctx,
+		`SELECT id, task_id, project_id, token_budget, tokens_used, created_at
+		 FROM context_packs WHERE task_id = $1 ORDER BY created_at DESC LIMIT 1`, taskID,
+	).Scan(&p.ID, &p.TaskID, &p.ProjectID, &p.TokenBudget, &p.TokensUsed, &p.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, fmt.Errorf("get context_pack by task: %w", err)
+	}
+
+	entries, err := s.loadContextEntries(ctx, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	p.Entries = entries
+	return &p, nil
+}
+
+// DeleteContextPack removes a context pack and its entries (CASCADE).
+func (s *Store) DeleteContextPack(ctx context.Context, id string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM context_packs WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete context_pack: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) loadContextEntries(ctx context.Context, packID string) ([]cfcontext.ContextEntry, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT id, pack_id, kind, path, content, tokens, priority, citation
+		 FROM context_entries WHERE pack_id = $1 ORDER BY priority DESC`, packID)
+	if err != nil {
+		return nil, fmt.Errorf("load context_entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []cfcontext.ContextEntry
+	for rows.Next() {
+		var e cfcontext.ContextEntry
+		var citationJSON []byte
+		if err := rows.Scan(&e.ID, &e.PackID, &e.Kind, &e.Path, &e.Content, &e.Tokens, &e.Priority, &citationJSON); err != nil {
+			return nil, fmt.Errorf("scan context_entry: %w", err)
+		}
+		if citationJSON != nil {
+			if err := json.Unmarshal(citationJSON, &e.Citation); err != nil {
+				return nil, fmt.Errorf("unmarshal context_entry citation: %w", err)
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// GetContextCuration returns the context curation of a task.
+func (s *Store) GetContextCuration(ctx context.Context, taskID string) (*cfcontext.Curation, error) {
+	c := cfcontext.Curation{TaskID: taskID}
+	var rulesJSON []byte
+	err := s.pool.QueryRow(ctx,
+		`SELECT project_id, rules, updated_at FROM context_curations WHERE task_id = $1`, taskID,
+	).Scan(&c.ProjectID, &rulesJSON, &c.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, fmt.Errorf("get context_curation: %w", err)
+	}
+	if err := json.Unmarshal(rulesJSON, &c.Rules); err != nil {
+		return nil, fmt.Errorf("unmarshal context_curation rules: %w", err)
+	}
+	return &c, nil
+}
+
+// SaveContextCuration creates or replaces the context curation of a task.
+func (s *Store) SaveContextCuration(ctx context.Context, c *cfcontext.Curation) error {
+	rules, err := json.Marshal(c.Rules)
+	if err != nil {
+		return fmt.Errorf("marshal context_curation rules: %w", err)
+	}
+	return s.pool.QueryRow(ctx,
+		`INSERT INTO context_curations (task_id, project_id, rules)
+		 VALUES ($1, $2, $3)
+		 ON CONFLICT (task_id) DO UPDATE SET rules = EXCLUDED.rules, updated_at = now()
+		 RETURNING updated_at`,
+		c.TaskID, c.ProjectID, rules,
+	).Scan(&c.UpdatedAt)
+}
+
+// DeleteContextCuration removes the context curation of a task.
+func (s *Store) DeleteContextCuration(ctx context.Context, taskID string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM context_curations WHERE task_id = $1`, taskID)
+	if err != nil {
+		return fmt.Errorf("delete context_curation: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// --- Shared Context ---
+
+// CreateSharedContext inserts a new shared context for a team.
+func (s *Store) CreateSharedContext(ctx context.Context, sc *cfcontext.SharedContext) error {
+	return s.pool.QueryRow(ctx,
+		`INSERT INTO shared_contexts (team_id, project_id) VALUES ($1, $2)
+		 RETURNING id, version, created_at, updated_at`,
+		sc.TeamID, sc.ProjectID,
+	).Scan(&sc.ID, &sc.Version, &sc.CreatedAt, &sc.UpdatedAt)
+}
+
+// GetSharedContext returns a shared context by ID with all items.
+func (s *Store) GetSharedContext(ctx context.Context, id string) (*cfcontext.SharedContext, error) {
+	var sc cfcontext.SharedContext
+	err := s.pool.QueryRow(ctx,
+		`SELECT id, team_id, project_id, version, created_at, updated_at
+		 FROM shared_contexts WHERE id = $1`, id,
+	).Scan(&sc.ID, &sc.TeamID, &sc.ProjectID, &sc.Version, &sc.CreatedAt, &sc.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, fmt.Errorf("get shared_context: %w", err)
+	}
+
+	items, err := s.loadSharedContextItems(ctx, sc.ID)
+	if err != nil {
+		return nil, err
+	}
+	sc.Items = items
+	return &sc, nil
+}
+
+// GetSharedContextByTeam returns the shared context for a team.
+func (s *Store) GetSharedContextByTeam(ctx context.Context, teamID string) (*cfcontext.SharedContext, error) {
+	var sc cfcontext.SharedContext
+	err := s.pool.QueryRow(ctx,
+		`SELECT id, team_id, project_id, version, created_at, updated_at
+		 FROM shared_contexts WHERE team_id = $1`, teamID,
+	).Scan(&sc.ID, &sc.TeamID, &sc.ProjectID, &sc.Version, &sc.CreatedAt, &sc.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, fmt.Errorf("get shared_context by team: %w", err)
+	}
+
+	items, err := s.loadSharedContextItems(ctx, sc.ID)
+	if err != nil {
+		return nil, err
+	}
+	sc.Items = items
+	return &sc, nil
+}
+
+// AddSharedContextItem inserts a new item and bumps the shared context version.
+func (s *Store) AddSharedContextItem(ctx context.Context, req cfcontext.AddSharedItemRequest) (*cfcontext.SharedContextItem, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
+
+	// Resolve shared context ID from team ID.
+	var sharedID string
+	err = tx.QueryRow(ctx,
+		`SELECT id FROM shared_contexts WHERE team_id = $1`, req.TeamID,
+	).Scan(&sharedID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, fmt.Errorf("resolve shared_context: %w", err)
+	}
+
+	tokens := req.Tokens
+	if tokens <= 0 {
+		tokens = cfcontext.EstimateTokens(req.Value)
+	}
+	var item cfcontext.SharedContextItem
+	err = tx.QueryRow(ctx,
+		`INSERT INTO shared_context_items (shared_id, key, value, author, tokens)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (shared_id, key) DO UPDATE SET value = EXCLUDED.value, author = EXCLUDED.author, tokens = EXCLUDED.tokens
+		 RETURNING id, shared_id, key, value, author, tokens, created_at`,
+		sharedID, req.Key, req.Value, req.Author, tokens,
+	).Scan(&item.ID, &item.SharedID, &item.Key, &item.Value, &item.Author, &item.Tokens, &item.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("upsert shared_context_item: %w", err)
+	}
+
+	// Bump version.
+	if _, err := tx.Exec(ctx,
+		`UPDATE shared_contexts SET version = version + 1 WHERE id = $1`, sharedID,
+	); err != nil {
+		return nil, fmt.Errorf("bump shared_context version: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return &item, nil
+}
+
+// DeleteSharedContext removes a shared context and its items (CASCADE).
+func (s *Store) DeleteSharedContext(ctx context.Context, id string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM shared_contexts WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete shared_context: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// --- Run Artifacts ---
+
+// CreateArtifact inserts a run artifact or attachment. Size is derived
+// from Data.
+func (s *Store) CreateArtifact(ctx context.Context, a *artifact.Artifact) error {
+	metaJSON, err := json.Marshal(a.Metadata)
+	if err != nil {
+		return fmt.Errorf("marshal artifact metadata: %w", err)
+	}
+	if a.ContentType == "" {
+		a.ContentType = "application/octet-stream"
+	}
+	a.Size = int64(len(a.Data))
+
+	err = s.pool.QueryRow(ctx,
+		`INSERT INTO run_artifacts (run_id, task_id, project_id, kind, name, content_type, data, size, metadata)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 RETURNING id, created_at`,
+		nullIfEmpty(a.RunID), nullIfEmpty(a.TaskID), a.ProjectID, string(a.Kind), a.Name, a.ContentType, a.Data, a.Size, metaJSON,
+	).Scan(&a.ID, &a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create artifact: %w", err)
+	}
+	return nil
+}
+
+// GetArtifact returns a run artifact by ID, including its data.
+func (s *Store) GetArtifact(ctx context.Context, id string) (*artifact.Artifact, error) {
+	row := s.pool.QueryRow(ctx,
+		`SELECT id, COALESCE(run_id::text, ''), COALESCE(task_id::text, ''), project_id, kind, name, content_type, data, size, metadata, created_at
+		 FROM run_artifacts WHERE id = $1`, id)
+
+	var a artifact.Artifact
+	var metaJSON []byte
+	err := row.Scan(&a.ID, &a.RunID, &a.TaskID, &a.ProjectID, &a.Kind, &a.Name, &a.ContentType, &a.Data, &a.Size, &metaJSON, &a.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get artifact %s: %w", id, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get artifact %s: %w", id, err)
+	}
+	if err := unmarshalArtifactMetadata(metaJSON, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// ListArtifactsByRun returns artifact metadata (without data) for a run.
+func (s *Store) ListArtifactsByRun(ctx context.Context, runID string) ([]artifact.Artifact, error) {
+	return s.queryArtifacts(ctx,
+		`SELECT id, COALESCE(run_id::text, ''), COALESCE(task_id::text, ''), project_id, kind, name, content_type, size, metadata, created_at
+		 FROM run_artifacts WHERE run_id = $1 ORDER BY created_at`, runID)
+}
+
+// ListArtifactsByProject returns artifact metadata (without data) of the given kind
+// for a project, newest first.
+func (s *Store) ListArtifactsByProject(ctx context.Context, projectID string, kind artifact.Kind) ([]artifact.Artifact, error) {
+	return s.queryArtifacts(ctx,
+		`SELECT id, COALESCE(run_id::text, ''), COALESCE(task_id::text, ''), project_id, kind, name, content_type, size, metadata, created_at
+		 FROM run_artifacts WHERE project_id = $1 AND kind = $2 ORDER BY created_at DESC`, projectID, string(kind))
+}
+
+// ListArtifactsByTask returns the metadata (without data) of a task's
+// attachments, oldest first.
+func (s *Store) ListArtifactsByTask(ctx context.Context, taskID string) ([]artifact.Artifact, error) {
+	return s.queryArtifacts(ctx,
+		`SELECT id, COALESCE(run_id::text, ''), COALESCE(task_id::text, ''), project_id, kind, name, content_type, size, metadata, created_at
+		 FROM run_artifacts WHERE task_id = $1 ORDER BY created_at`, taskID)
+}
+
+func (s *Store) queryArtifacts(ctx context.Context, query string, args ...any) ([]artifact.Artifact, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list artifacts: %w", err)
+	}
+	defer rows.Close()
+
+	var result []artifact.Artifact
+	for rows.Next() {
+		var a artifact.Artifact
+		var metaJSON []byte
+		if err := rows.Scan(&a.ID, &a.RunID, &a.TaskID, &a.ProjectID, &a.Kind, &a.Name, &a.ContentType, &a.Size, &metaJSON, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan artifact: %w", err)
+		}
+		if err := unmarshalArtifactMetadata(metaJSON, &a); err != nil {
+			return nil, err
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
+
+func unmarshalArtifactMetadata(data []byte, a *artifact.Artifact) error {
+	if data == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, &a.Metadata); err != nil {
+		return fmt.Errorf("unmarshal artifact metadata: %w", err)
+	}
+	return nil
+}
+
+// --- Secrets ---
+
+// CreateSecret inserts an encrypted secret. A secret with the same name in
+// the same scope yields domain.ErrConflict.
+func (s *Store) CreateSecret(ctx context.Context, sec *secret.Secret) error {
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO secrets (tenant_id, project_id, name, ciphertext)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING id, created_at, updated_at`,
+		ownerTenant(ctx, sec.TenantID), nullIfEmpty(sec.ProjectID), sec.Name, sec.Ciphertext,
+	).Scan(&sec.ID, &sec.CreatedAt, &sec.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("create secret %s: %w", sec.Name, domain.ErrConflict)
+		}
+		return fmt.Errorf("create secret %s: %w", sec.Name, err)
+	}
+	return nil
+}
+
+// GetSecret returns a secret by ID, including its ciphertext.
+func (s *Store) GetSecret(ctx context.Context, id string) (*secret.Secret, error) {
+	row := s.pool.QueryRow(ctx,
+		`SELECT `+secretColumns+` FROM secrets WHERE id = $1`, id)
+
+	var sec secret.Secret
+	if err := row.Scan(&sec.ID, &sec.TenantID, &sec.ProjectID, &sec.Name, &sec.Ciphertext, &sec.CreatedAt, &sec.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get secret %s: %w", id, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get secret %s: %w", id, err)
+	}
+	return &sec, nil
+}
+
+// ListSecrets returns the secrets of a project, or the tenant-wide secrets
+// of the context's tenant (the default tenant if unscoped) if projectID is
+// empty, ordered by name.
+func (s *Store) ListSecrets(ctx context.Context, projectID string) ([]secret.Secret, error) {
+	var (
+		rows pgx.Rows
+		err  error
+	)
+	if projectID == "" {
+		rows, err = s.pool.Query(ctx,
+			`SELECT `+secretColumns+` FROM secrets WHERE project_id IS NULL AND tenant_id = $1 ORDER BY name`,
+			ownerTenant(ctx, ""))
+	} else {
+		rows, err = s.pool.Query(ctx,
+			`SELECT `+secretColumns+` FROM secrets WHERE project_id = $1 ORDER BY name`, projectID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("list secrets: %w", err)
+	}
+	defer rows.Close()
+
+	var result []secret.Secret
+	for rows.Next() {
+		var sec secret.Secret
+		if err := rows.Scan(&sec.ID, &sec.TenantID, &sec.ProjectID, &sec.Name, &sec.Ciphertext, &sec.CreatedAt, &sec.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan secret: %w", err)
+		}
+		result = append(result, sec)
+	}
+	return result, rows.Err()
+}
+
+// UpdateSecretValue replaces the ciphertext of a secret.
+func (s *Store) UpdateSecretValue(ctx context.Context, id string, ciphertext []byte) error {
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE secrets SET ciphertext = $2, updated_at = now() WHERE id = $1`, id, ciphertext)
+	if err != nil {
+		return fmt.Errorf("update secret %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("update secret %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// DeleteSecret removes a secret.
+func (s *Store) DeleteSecret(ctx context.Context, id string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM secrets WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete secret %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete secret %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+const secretColumns = `id, tenant_id, COALESCE(project_id::text, ''), name, ciphertext, created_at, updated_at`
+
+// secretTenant returns id, or else the context's tenant, or else the
+// default tenant.
+func ownerTenant(ctx context.Context, id string) string {
+	if id == "" {
+		id = tenant.FromContext(ctx)
+	}
+	if id == "" {
+		id = tenant.DefaultID
+	}
+	return id
+}
+
+// --- Benchmarks ---
+
+// CreateBenchmarkRun inserts a benchmark run together with its pending results.
+func (s *Store) CreateBenchmarkRun(ctx context.Context, r *benchmark.Run) error {
+	targetsJSON, err := json.Marshal(r.Targets)
+	if err != nil {
+		return fmt.Errorf("marshal benchmark targets: %w", err)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
+
+	// Unscoped callers run benchmarks for the tenant of the first target's
+	// agent.
+	err = tx.QueryRow(ctx,
+		`INSERT INTO benchmark_runs (tenant_id, suite, status, targets)
+		 VALUES (COALESCE(NULLIF($1, ''),
+		                  (SELECT p.tenant_id FROM agents a JOIN projects p ON p.id = a.project_id
+		                   WHERE a.id::text = $3::jsonb->0->>'agent_id'),
+		                  $5),
+		         $2, $4, $3)
+		 RETURNING id, tenant_id, created_at`,
+		cmp.Or(r.TenantID, tenant.FromContext(ctx)), r.Suite, targetsJSON, string(r.Status), tenant.DefaultID,
+	).Scan(&r.ID, &r.TenantID, &r.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("insert benchmark run: %w", err)
+	}
+
+	for i := range r.Results {
+		res := &r.Results[i]
+		res.BenchRunID = r.ID
+		err = tx.QueryRow(ctx,
+			`INSERT INTO benchmark_results (benchmark_run_id, case_id, agent_id, model, plan_step_id, status)
+			 VALUES ($1, $2, $3, $4, $5, $6)
+			 RETURNING id, updated_at`,
+			res.BenchRunID, res.CaseID, res.AgentID, res.Model, nullIfEmpty(res.PlanStepID), string(res.Status),
+		).Scan(&res.ID, &res.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("insert benchmark result %d: %w", i, err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetBenchmarkRun returns a benchmark run with its results.
+func (s *Store) GetBenchmarkRun(ctx context.Context, id string) (*benchmark.Run, error) {
+	row := s.pool.QueryRow(ctx,
+		`SELECT id, tenant_id, suite, status, targets, created_at, completed_at FROM benchmark_runs WHERE id = $1`, id)
+	r, err := scanBenchmarkRun(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get benchmark run %s: %w", id, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get benchmark run %s: %w", id, err)
+	}
+	if r.Results, err = s.listBenchmarkResults(ctx, r.ID); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ListBenchmarkRuns returns benchmark runs with their results, newest first.
+// An empty suite lists the runs of all suites.
+func (s *Store) ListBenchmarkRuns(ctx context.Context, suite string) ([]benchmark.Run, error) {
+	rows, err := s.reader(ctx).Query(ctx,
+		`SELECT id, tenant_id, suite, status, targets, created_at, completed_at FROM benchmark_runs
+		 WHERE $1 = '' OR suite = $1 ORDER BY created_at DESC`, suite)
+	if err != nil {
+		return nil, fmt.Errorf("list benchmark runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []benchmark.Run
+	for rows.Next() {
+		r, err := scanBenchmarkRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan benchmark run: %w", err)
+		}
+		runs = append(runs, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range runs {
+		if runs[i].Results, err = s.listBenchmarkResults(ctx, runs[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return runs, nil
+}
+
+// UpdateBenchmarkRunStatus sets the status of a benchmark run, recording the
+// completion time once it is completed.
+func (s *Store) UpdateBenchmarkRunStatus(ctx context.Context, id string, status benchmark.Status) error {
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE benchmark_runs SET status = $2,
+		        completed_at = CASE WHEN $2 = 'completed' THEN now() ELSE completed_at END
+		 WHERE id = $1`, id, string(status))
+	if err != nil {
+		return fmt.Errorf("update benchmark run %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("update benchmark run %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// GetBenchmarkResultByStep returns the result scored by the given plan step.
+func (s *Store) GetBenchmarkResultByStep(ctx context.Context, planStepID string) (*benchmark.Result, error) {
+	row := s.pool.QueryRow(ctx,
+		`SELECT `+benchmarkResultColumns+` FROM benchmark_results WHERE plan_step_id = $1`, planStepID)
+	res, err := scanBenchmarkResult(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get benchmark result for step %s: %w", planStepID, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get benchmark result for step %s: %w", planStepID, err)
+	}
+	return &res, nil
+}
+
+// UpdateBenchmarkResult stores the score of a benchmark result.
+func (s *Store) UpdateBenchmarkResult(ctx context.Context, res *benchmark.Result) error {
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE benchmark_results SET run_id = $2, status = $3, output = $4, cost_usd = $5, duration_ms = $6, updated_at = now()
+		 WHERE id = $1`,
+		res.ID, nullIfEmpty(res.RunID), string(res.Status), res.Output, res.CostUSD, res.DurationMS)
+	if err != nil {
+		return fmt.Errorf("update benchmark result %s: %w", res.ID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("update benchmark result %s: %w", res.ID, domain.ErrNotFound)
+	}
+	return nil
+}
+
+const benchmarkResultColumns = `id, benchmark_run_id, case_id, agent_id, model, COALESCE(plan_step_id::text, ''),
+	COALESCE(run_id::text, ''), status, output, cost_usd, duration_ms, updated_at`
+
+func (s *Store) listBenchmarkResults(ctx context.Context, benchRunID string) ([]benchmark.Result, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT `+benchmarkResultColumns+` FROM benchmark_results
+		 WHERE benchmark_run_id = $1 ORDER BY case_id, agent_id, model`, benchRunID)
+	if err != nil {
+		return nil, fmt.Errorf("list benchmark results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []benchmark.Result
+	for rows.Next() {
+		res, err := scanBenchmarkResult(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan benchmark result: %w", err)
+		}
+		results = append(results, res)
+	}
+	return results, rows.Err()
+}
+
+func scanBenchmarkRun(row scannable) (benchmark.Run, error) {
+	var r benchmark.Run
+	var targetsJSON []byte
+	if err := row.Scan(&r.ID, &r.TenantID, &r.Suite, &r.Status, &targetsJSON, &r.CreatedAt, &r.CompletedAt); err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(targetsJSON, &r.Targets); err != nil {
+		return r, fmt.Errorf("unmarshal benchmark targets: %w", err)
+	}
+	return r, nil
+}
+
+func scanBenchmarkResult(row scannable) (benchmark.Result, error) {
+	var res benchmark.Result
+	err := row.Scan(&res.ID, &res.BenchRunID, &res.CaseID, &res.AgentID, &res.Model, &res.PlanStepID,
+		&res.RunID, &res.Status, &res.Output, &res.CostUSD, &res.DurationMS, &res.UpdatedAt)
+	return res, err
+}
+
+// --- Roadmap ---
+
+const featureColumns = `id, project_id, title, description, status, labels, external_ids, version, created_at, updated_at`
+
+// CreateFeature inserts a roadmap feature.
+func (s *Store) CreateFeature(ctx context.Context, f *roadmap.Feature) error {
+	extJSON, err := json.Marshal(externalIDs(f.ExternalIDs))
+	if err != nil {
+		return fmt.Errorf("marshal external ids: %w", err)
+	}
+	err = s.pool.QueryRow(ctx,
+		`INSERT INTO roadmap_features (project_id, title, description, status, labels, external_ids)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id, version, created_at, updated_at`,
+		f.ProjectID, f.Title, f.Description, f.Status, labelsOrEmpty(f.Labels), extJSON,
+	).Scan(&f.ID, &f.Version, &f.CreatedAt, &f.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create feature: %w", err)
+	}
+	return nil
+}
+
+// GetFeature returns a roadmap feature by ID.
+func (s *Store) GetFeature(ctx context.Context, id string) (*roadmap.Feature, error) {
+	f, err := scanFeature(s.pool.QueryRow(ctx,
+		`SELECT `+featureColumns+` FROM roadmap_features WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get feature %s: %w", id, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get feature %s: %w", id, err)
+	}
+	return &f, nil
+}
+
+// GetFeatureByExternalID returns the feature of a project that mirrors the
+// given item of a PM provider.
+func (s *Store) GetFeatureByExternalID(ctx context.Context, projectID, provider, externalID string) (*roadmap.Feature, error) {
+	f, err := scanFeature(s.pool.QueryRow(ctx,
+		`SELECT `+featureColumns+` FROM roadmap_features
+		 WHERE project_id = $1 AND external_ids @> jsonb_build_object($2::text, $3::text)`,
+		projectID, provider, externalID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get feature %s/%s: %w", provider, externalID, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get feature %s/%s: %w", provider, externalID, err)
+	}
+	return &f, nil
+}
+
+// ListFeatures returns the roadmap features of a project, oldest first.
+func (s *Store) ListFeatures(ctx context.Context, projectID string) ([]roadmap.Feature, error) {
+	rows, err := s.reader(ctx).Query(ctx,
+		`SELECT `+featureColumns+` FROM roadmap_features WHERE project_id = $1 ORDER BY created_at`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list features: %w", err)
+	}
+	defer rows.Close()
+
+	var result []roadmap.Feature
+	for rows.Next() {
+		f, err := scanFeature(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan feature: %w", err)
+		}
+		result = append(result, f)
+	}
+	return result, rows.Err()
+}
+
+// UpdateFeature writes a feature's mutable fields. It fails with
+// domain.ErrConflict if the feature changed since it was read.
+func (s *Store) UpdateFeature(ctx context.Context, f *roadmap.Feature) error {
+	extJSON, err := json.Marshal(externalIDs(f.ExternalIDs))
+	if err != nil {
+		return fmt.Errorf("marshal external ids: %w", err)
+	}
+	err = s.pool.QueryRow(ctx,
+		`UPDATE roadmap_features SET title = $2, description = $3, status = $4, labels = $5, external_ids = $6
+		 WHERE id = $1 AND version = $7
+		 RETURNING version, updated_at`,
+		f.ID, f.Title, f.Description, f.Status, labelsOrEmpty(f.Labels), extJSON, f.Version,
+	).Scan(&f.Version, &f.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("update feature %s: %w", f.ID, domain.ErrConflict)
+		}
+		return fmt.Errorf("update feature %s: %w", f.ID, err)
+	}
+	return nil
+}
+
+func scanFeature(row scannable) (roadmap.Feature, error) {
+	var f roadmap.Feature
+	var extJSON []byte
+	if err := row.Scan(&f.ID, &f.ProjectID, &f.Title, &f.Description, &f.Status, &f.Labels,
+		&extJSON, &f.Version, &f.CreatedAt, &f.UpdatedAt); err != nil {
+		return f, err
+	}
+	if err := json.Unmarshal(extJSON, &f.ExternalIDs); err != nil {
+		return f, fmt.Errorf("unmarshal external ids: %w", err)
+	}
+	return f, nil
+}
+
+// --- Tenants ---
+
+// CreateTenant inserts a tenant. An existing ID yields domain.ErrConflict.
+func (s *Store) CreateTenant(ctx context.Context, t *tenant.Tenant) error {
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO tenants (id, name, max_concurrent_runs, max_projects, monthly_token_budget, max_storage_mb)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING created_at, updated_at`,
+		t.ID, t.Name, t.Quota.MaxConcurrentRuns, t.Quota.MaxProjects, t.Quota.MonthlyTokenBudget, t.Quota.MaxStorageMB,
+	).Scan(&t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("create tenant %s: %w", t.ID, domain.ErrConflict)
+		}
+		return fmt.Errorf("create tenant %s: %w", t.ID, err)
+	}
+	return nil
+}
+
+// GetTenant returns a tenant by ID.
+func (s *Store) GetTenant(ctx context.Context, id string) (*tenant.Tenant, error) {
+	row := s.pool.QueryRow(ctx, `SELECT `+tenantColumns+` FROM tenants WHERE id = $1`, id)
+	t, err := scanTenant(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get tenant %s: %w", id, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get tenant %s: %w", id, err)
+	}
+	return &t, nil
+}
+
+// ListTenants returns all tenants ordered by ID.
+func (s *Store) ListTenants(ctx context.Context) ([]tenant.Tenant, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+tenantColumns+` FROM tenants ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("list tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var result []tenant.Tenant
+	for rows.Next() {
+		t, err := scanTenant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan tenant: %w", err)
+		}
+		result = append(result, t)
+	}
+	return result, rows.Err()
+}
+
+// UpdateTenantQuota replaces the quota of a tenant.
+func (s *Store) UpdateTenantQuota(ctx context.Context, id string, q tenant.Quota) error {
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE tenants SET max_concurrent_runs = $2, max_projects = $3, monthly_token_budget = $4, max_storage_mb = $5
+		 WHERE id = $1`,
+		id, q.MaxConcurrentRuns, q.MaxProjects, q.MonthlyTokenBudget, q.MaxStorageMB)
+	if err != nil {
+		return fmt.Errorf("update tenant quota %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("update tenant quota %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// SetTenantGitHubApp replaces the GitHub App of a tenant; nil removes it.
+func (s *Store) SetTenantGitHubApp(ctx context.Context, id string, app *tenant.GitHubApp) error {
+	data, err := json.Marshal(app)
+	if err != nil {
+		return fmt.Errorf("marshal github app: %w", err)
+	}
+	tag, err := s.pool.Exec(ctx, `UPDATE tenants SET github_app = $2 WHERE id = $1`, id, data)
+	if err != nil {
+		return fmt.Errorf("set tenant github app %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("set tenant github app %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// GetTenantUsage counts the active runs and projects of a tenant and the
+// tokens its runs created since the given time used. Storage is measured
+// on disk by the caller.
+func (s *Store) GetTenantUsage(ctx context.Context, id string, since time.Time) (*tenant.Usage, error) {
+	u := tenant.Usage{TenantID: id, PeriodStart: since}
+	err := s.pool.QueryRow(ctx,
+		`SELECT
+		   (SELECT count(*) FROM runs r JOIN projects p ON p.id = r.project_id
+		     WHERE p.tenant_id = $1 AND r.status IN ('pending', 'running', 'quality_gate')),
+		   (SELECT count(*) FROM projects WHERE tenant_id = $1),
+		   (SELECT COALESCE(sum(r.tokens_in + r.tokens_out), 0)::bigint FROM runs r JOIN projects p ON p.id = r.project_id
+		     WHERE p.tenant_id = $1 AND r.created_at >= $2)`,
+		id, since,
+	).Scan(&u.ConcurrentRuns, &u.Projects, &u.MonthlyTokens)
+	if err != nil {
+		return nil, fmt.Errorf("get tenant usage %s: %w", id, err)
+	}
+	return &u, nil
+}
+
+// tenantIsolatedTables are the tables the migrations put under row-level
+// security. cost_rollup_state is deliberately left out: it only holds the
+// time the rollup job last ran over all tenants.
+var tenantIsolatedTables = []string{
+	"tenants", "projects", "secrets",
+	"agents", "tasks", "agent_events", "runs", "execution_plans", "agent_teams",
+	"context_packs", "shared_contexts", "run_artifacts", "roadmap_features",
+	"sub_projects", "retrieval_indexes", "retrieval_chunks", "conversations",
+	"memories", "experiences", "skills", "microagents",
+	"audit_entries", "audit_sinks", "knowledge_bases", "knowledge_chunks",
+	"feature_flags", "issue_runs", "pr_commands", "poll_cursors", "run_usage",
+	"plan_debate_turns", "plan_debate_summaries", "run_presets", "context_curations",
+	"retrieval_golden_queries", "retrieval_evals", "code_graphs", "code_graph_files",
+	"code_graph_edges", "project_conventions", "run_deliveries", "run_ci_statuses",
+	"cost_rollups", "usage_rollups",
+	"mcp_servers", "api_keys", "benchmark_runs", "benchmark_results",
+}
+
+// GetTenantIsolation reports whether the current database role bypasses
+// row-level security and the RLS state of every tenant-owned table.
+func (s *Store) GetTenantIsolation(ctx context.Context) (*tenant.IsolationReport, error) {
+	var r tenant.IsolationReport
+	err := s.pool.QueryRow(ctx,
+		`SELECT rolname, rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user`,
+	).Scan(&r.Role, &r.BypassesRLS)
+	if err != nil {
+		return nil, fmt.Errorf("get database role: %w", err)
+	}
+
+	rows, err := s.pool.Query(ctx,
+		`SELECT c.relname, c.relrowsecurity, c.relforcerowsecurity,
+		        (SELECT count(*) FROM pg_policies p WHERE p.schemaname = n.nspname AND p.tablename = c.relname)
+		 FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		 WHERE n.nspname = current_schema() AND c.relkind = 'r' AND c.relname = ANY($1)`,
+		tenantIsolatedTables)
+	if err != nil {
+		return nil, fmt.Errorf("get row-level security: %w", err)
+	}
+	defer rows.Close()
+
+	found := make(map[string]tenant.TableIsolation, len(tenantIsolatedTables))
+	for rows.Next() {
+		var t tenant.TableIsolation
+		if err := rows.Scan(&t.Table, &t.Enabled, &t.Forced, &t.Policies); err != nil {
+			return nil, fmt.Errorf("scan row-level security: %w", err)
+		}
+		found[t.Table] = t
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, name := range tenantIsolatedTables {
+		t, ok := found[name]
+		if !ok {
+			t.Table = name
+		}
+		r.Tables = append(r.Tables, t)
+	}
+	return &r, nil
+}
+
+const tenantColumns = `id, name, max_concurrent_runs, max_projects, monthly_token_budget, max_storage_mb, github_app, created_at, updated_at`
+
+func scanTenant(row scannable) (tenant.Tenant, error) {
+	var t tenant.Tenant
+	var appJSON []byte
+	err := row.Scan(&t.ID, &t.Name, &t.Quota.MaxConcurrentRuns, &t.Quota.MaxProjects,
+		&t.Quota.MonthlyTokenBudget, &t.Quota.MaxStorageMB, &appJSON, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return t, err
+	}
+	if appJSON != nil {
+		if err := json.Unmarshal(appJSON, &t.GitHubApp); err != nil {
+			return t, fmt.Errorf("unmarshal github app: %w", err)
+		}
+	}
+	return t, nil
+}
+
+// --- Retrieval Index ---
+
+// ReplaceRetrievalIndex stores the embedding index of a project, replacing
+// its previous index and chunks in one transaction. A staged index
+// replaces the previous staged one and leaves the live one alone.
+func (s *Store) ReplaceRetrievalIndex(ctx context.Context, idx *retrieval.Index, chunks []retrieval.Chunk) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
+
+	if _, err := tx.Exec(ctx, `DELETE FROM retrieval_indexes WHERE project_id = $1 AND staged = $2`,
+		idx.ProjectID, idx.Staged); err != nil {
+		return fmt.Errorf("delete retrieval index %s: %w", idx.ProjectID, err)
+	}
+	err = tx.QueryRow(ctx,
+		`INSERT INTO retrieval_indexes (project_id, staged, provider, model, dimensions, files, chunks)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING indexed_at`,
+		idx.ProjectID, idx.Staged, idx.Provider, idx.Model, idx.Dimensions, idx.Files, idx.Chunks,
+	).Scan(&idx.IndexedAt)
+	if err != nil {
+		return fmt.Errorf("insert retrieval index %s: %w", idx.ProjectID, err)
+	}
+
+	_, err = tx.CopyFrom(ctx,
+		pgx.Identifier{"retrieval_chunks"},
+		[]string{"project_id", "staged", "path", "start_line", "end_line", "content", "embedding"},
+		pgx.CopyFromSlice(len(chunks), func(i int) ([]any, error) {
+			c := &chunks[i]
+			return []any{idx.ProjectID, idx.Staged, c.Path, c.StartLine, c.EndLine, c.Content, c.Embedding}, nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("copy retrieval chunks %s: %w", idx.ProjectID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// GetRetrievalIndex returns the live embedding index of a project.
+func (s *Store) GetRetrievalIndex(ctx context.Context, projectID string) (*retrieval.Index, error) {
+	return s.getRetrievalIndex(ctx, projectID, false)
+}
+
+// GetStagedRetrievalIndex returns the staged embedding index of a project.
+func (s *Store) GetStagedRetrievalIndex(ctx context.Context, projectID string) (*retrieval.Index, error) {
+	return s.getRetrievalIndex(ctx, projectID, true)
+}
+
+func (s *Store) getRetrievalIndex(ctx context.Context, projectID string, staged bool) (*retrieval.Index, error) {
+	idx := retrieval.Index{Staged: staged}
+	err := s.pool.QueryRow(ctx,
+		`SELECT project_id, provider, model, dimensions, files, chunks, indexed_at
+		 FROM retrieval_indexes WHERE project_id = $1 AND staged = $2`, projectID, staged,
+	).Scan(&idx.ProjectID, &idx.Provider, &idx.Model, &idx.Dimensions, &idx.Files, &idx.Chunks, &idx.IndexedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get retrieval index %s: %w", projectID, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get retrieval index %s: %w", projectID, err)
+	}
+	return &idx, nil
+}
+
+// ListRetrievalChunks returns the chunks of a project's live index with
+// their embeddings.
+func (s *Store) ListRetrievalChunks(ctx context.Context, projectID string) ([]retrieval.Chunk, error) {
+	return s.listRetrievalChunks(ctx, projectID, false)
+}
+
+// ListStagedRetrievalChunks returns the chunks of a project's staged index
+// with their embeddings.
+func (s *Store) ListStagedRetrievalChunks(ctx context.Context, projectID string) ([]retrieval.Chunk, error) {
+	return s.listRetrievalChunks(ctx, projectID, true)
+}
+
+func (s *Store) listRetrievalChunks(ctx context.Context, projectID string, staged bool) ([]retrieval.Chunk, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT path, start_line, end_line, content, embedding
+		 FROM retrieval_chunks WHERE project_id = $1 AND staged = $2 ORDER BY id`, projectID, staged)
+	if err != nil {
+		return nil, fmt.Errorf("list retrieval chunks %s: %w", projectID, err)
+	}
+	defer rows.Close()
+
+	var result []retrieval.Chunk
+	for rows.Next() {
+		var c retrieval.Chunk
+		if err := rows.Scan(&c.Path, &c.StartLine, &c.EndLine, &c.Content, &c.Embedding); err != nil {
+			return nil, fmt.Errorf("scan retrieval chunk: %w", err)
+		}
+		result = append(result, c)
+	}
+	return result, rows.Err()
+}
+
+// PromoteRetrievalIndex replaces the live index of a project with its
+// staged one in one transaction.
+func (s *Store) PromoteRetrievalIndex(ctx context.Context, projectID string) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
+
+	if _, err := tx.Exec(ctx, `DELETE FROM retrieval_indexes WHERE project_id = $1 AND NOT staged`, projectID); err != nil {
+		return fmt.Errorf("delete retrieval index %s: %w", projectID, err)
+	}
+	tag, err := tx.Exec(ctx,
+		`UPDATE retrieval_indexes SET staged = false WHERE project_id = $1 AND staged`, projectID)
+	if err != nil {
+		return fmt.Errorf("promote retrieval index %s: %w", projectID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("promote retrieval index %s: %w", projectID, domain.ErrNotFound)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// DeleteStagedRetrievalIndex removes the staged index of a project, if any.
+func (s *Store) DeleteStagedRetrievalIndex(ctx context.Context, projectID string) error {
+	if _, err := s.pool.Exec(ctx,
+		`DELETE FROM retrieval_indexes WHERE project_id = $1 AND staged`, projectID); err != nil {
+		return fmt.Errorf("delete staged retrieval index %s: %w", projectID, err)
+	}
+	return nil
+}
+
+// --- Retrieval Evaluations ---
+
+// CreateGoldenQuery inserts a golden query.
+func (s *Store) CreateGoldenQuery(ctx context.Context, q *retrieval.GoldenQuery) error {
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO retrieval_golden_queries (project_id, query, paths, chunk_ids)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING id, created_at`,
+		q.ProjectID, q.Query, labelsOrEmpty(q.Paths), labelsOrEmpty(q.ChunkIDs),
+	).Scan(&q.ID, &q.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create golden query: %w", err)
+	}
+	return nil
+}
+
+// ListGoldenQueries returns the golden queries of a project, oldest first.
+func (s *Store) ListGoldenQueries(ctx context.Context, projectID string) ([]retrieval.GoldenQuery, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT id, project_id, query, paths, chunk_ids, created_at
+		 FROM retrieval_golden_queries WHERE project_id = $1 ORDER BY created_at, id`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list golden queries %s: %w", projectID, err)
+	}
+	defer rows.Close()
+
+	var result []retrieval.GoldenQuery
+	for rows.Next() {
+		var q retrieval.GoldenQuery
+		if err := rows.Scan(&q.ID, &q.ProjectID, &q.Query, &q.Paths, &q.ChunkIDs, &q.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan golden query: %w", err)
+		}
+		result = append(result, q)
+	}
+	return result, rows.Err()
+}
+
+// DeleteGoldenQuery deletes a golden query.
+func (s *Store) DeleteGoldenQuery(ctx context.Context, id string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM retrieval_golden_queries WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete golden query %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete golden query %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// CreateRetrievalEval inserts a retrieval evaluation with its query scores.
+func (s *Store) CreateRetrievalEval(ctx context.Context, e *retrieval.Eval) error {
+	queries, err := json.Marshal(e.Queries)
+	if err != nil {
+		return fmt.Errorf("marshal retrieval eval queries: %w", err)
+	}
+	err = s.pool.QueryRow(ctx,
+		`INSERT INTO retrieval_evals (project_id, trigger, k, provider, model, dimensions, chunks,
+		     chunk_lines, chunk_overlap, recall, mrr, queries)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		 RETURNING id, created_at`,
+		e.ProjectID, e.Trigger, e.K, e.Provider, e.Model, e.Dimensions, e.Chunks,
+		e.ChunkLines, e.ChunkOverlap, e.Recall, e.MRR, queries,
+	).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create retrieval eval: %w", err)
+	}
+	return nil
+}
+
+// ListRetrievalEvals returns the latest limit evaluations of a project,
+// newest first.
+func (s *Store) ListRetrievalEvals(ctx context.Context, projectID string, limit int) ([]retrieval.Eval, error) {
+	rows, err := s.reader(ctx).Query(ctx,
+		`SELECT id, project_id, trigger, k, provider, model, dimensions, chunks,
+		     chunk_lines, chunk_overlap, recall, mrr, queries, created_at
+		 FROM retrieval_evals WHERE project_id = $1
+		 ORDER BY created_at DESC, id DESC LIMIT $2`, projectID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list retrieval evals %s: %w", projectID, err)
+	}
+	defer rows.Close()
+
+	var result []retrieval.Eval
+	for rows.Next() {
+		var e retrieval.Eval
+		var queries []byte
+		if err := rows.Scan(&e.ID, &e.ProjectID, &e.Trigger, &e.K, &e.Provider, &e.Model, &e.Dimensions,
+			&e.Chunks, &e.ChunkLines, &e.ChunkOverlap, &e.Recall, &e.MRR, &queries, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan retrieval eval: %w", err)
+		}
+		if err := json.Unmarshal(queries, &e.Queries); err != nil {
+			return nil, fmt.Errorf("unmarshal retrieval eval queries: %w", err)
+		}
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
+
+// --- Code Graphs ---
+
+// GetCodeGraph returns the state of a project's persisted code graph.
+func (s *Store) GetCodeGraph(ctx context.Context, projectID string) (*codegraph.State, error) {
+	var st codegraph.State
+	err := s.pool.QueryRow(ctx,
+		`SELECT project_id, go_module, files, edges, built_at, updated_at
+		 FROM code_graphs WHERE project_id = $1`, projectID,
+	).Scan(&st.ProjectID, &st.GoModule, &st.Files, &st.Edges, &st.BuiltAt, &st.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get code graph %s: %w", projectID, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get code graph %s: %w", projectID, err)
+	}
+	return &st, nil
+}
+
+// ReplaceCodeGraph replaces the persisted code graph of a project with
+// files and edges in a single transaction.
+func (s *Store) ReplaceCodeGraph(ctx context.Context, st *codegraph.State, files []*codegraph.File, edges []codegraph.Edge) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
+
+	if _, err := tx.Exec(ctx, `DELETE FROM code_graphs WHERE project_id = $1`, st.ProjectID); err != nil {
+		return fmt.Errorf("delete code graph %s: %w", st.ProjectID, err)
+	}
+	err = tx.QueryRow(ctx,
+		`INSERT INTO code_graphs (project_id, go_module, files, edges)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING built_at, updated_at`,
+		st.ProjectID, st.GoModule, st.Files, st.Edges,
+	).Scan(&st.BuiltAt, &st.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("insert code graph %s: %w", st.ProjectID, err)
+	}
+
+	_, err = tx.CopyFrom(ctx,
+		pgx.Identifier{"code_graph_files"},
+		[]string{"project_id", "path", "language", "test", "tagged", "imports", "symbols"},
+		pgx.CopyFromSlice(len(files), func(i int) ([]any, error) {
+			f := files[i]
+			symbols, err := json.Marshal(symbolsOrEmpty(f.Symbols))
+			if err != nil {
+				return nil, fmt.Errorf("marshal symbols of %s: %w", f.Path, err)
+			}
+			return []any{st.ProjectID, f.Path, string(f.Language), f.Test, f.Tagged, labelsOrEmpty(f.Imports), symbols}, nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("copy code graph files %s: %w", st.ProjectID, err)
+	}
+	_, err = tx.CopyFrom(ctx,
+		pgx.Identifier{"code_graph_edges"},
+		[]string{"project_id", "from_path", "to_path"},
+		pgx.CopyFromSlice(len(edges), func(i int) ([]any, error) {
+			return []any{st.ProjectID, edges[i].From, edges[i].To}, nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("copy code graph edges %s: %w", st.ProjectID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// UpdateCodeGraph applies an incremental update to a persisted code graph
+// in a single transaction: removed edges and deleted files go first,
+// reparsed files are upserted, then the added edges are inserted.
+func (s *Store) UpdateCodeGraph(ctx context.Context, st *codegraph.State, u *codegraph.Update) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
+
+	from, to := edgeColumns(u.Removed)
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM code_graph_edges
+		 WHERE project_id = $1 AND (from_path, to_path) IN (SELECT * FROM unnest($2::text[], $3::text[]))`,
+		st.ProjectID, from, to); err != nil {
+		return fmt.Errorf("delete code graph edges %s: %w", st.ProjectID, err)
+	}
+	if _, err := tx.Exec(ctx,
+		`DELETE FROM code_graph_files WHERE project_id = $1 AND path = ANY($2)`,
+		st.ProjectID, labelsOrEmpty(u.Deleted)); err != nil {
+		return fmt.Errorf("delete code graph files %s: %w", st.ProjectID, err)
+	}
+	for _, f := range u.Files {
+		symbols, err := json.Marshal(symbolsOrEmpty(f.Symbols))
+		if err != nil {
+			return fmt.Errorf("marshal symbols of %s: %w", f.Path, err)
+		}
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO code_graph_files (project_id, path, language, test, tagged, imports, symbols)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7)
+			 ON CONFLICT (project_id, path) DO UPDATE SET
+			     language = EXCLUDED.language, test = EXCLUDED.test, tagged = EXCLUDED.tagged,
+			     imports = EXCLUDED.imports, symbols = EXCLUDED.symbols`,
+			st.ProjectID, f.Path, string(f.Language), f.Test, f.Tagged, labelsOrEmpty(f.Imports), symbols); err != nil {
+			return fmt.Errorf("upsert code graph file %s: %w", f.Path, err)
+		}
+	}
+	from, to = edgeColumns(u.Added)
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO code_graph_edges (project_id, from_path, to_path)
+		 SELECT $1, f, t FROM unnest($2::text[], $3::text[]) AS e(f, t)
+		 ON CONFLICT DO NOTHING`,
+		st.ProjectID, from, to); err != nil {
+		return fmt.Errorf("insert code graph edges %s: %w", st.ProjectID, err)
+	}
+	err = tx.QueryRow(ctx,
+		`UPDATE code_graphs SET go_module = $2, files = $3, edges = $4, updated_at = now()
+		 WHERE project_id = $1
+		 RETURNING built_at, updated_at`,
+		st.ProjectID, st.GoModule, st.Files, st.Edges,
+	).Scan(&st.BuiltAt, &st.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("update code graph %s: %w", st.ProjectID, domain.ErrNotFound)
+		}
+		return fmt.Errorf("update code graph %s: %w", st.ProjectID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// ListCodeGraphFiles returns the files of a project's persisted code graph
+// ordered by path.
+func (s *Store) ListCodeGraphFiles(ctx context.Context, projectID string) ([]*codegraph.File, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT path, language, test, tagged, imports, symbols
+		 FROM code_graph_files WHERE project_id = $1 ORDER BY path`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list code graph files %s: %w", projectID, err)
+	}
+	defer rows.Close()
+
+	var result []*codegraph.File
+	for rows.Next() {
+		var f codegraph.File
+		var lang string
+		var symbols []byte
+		if err := rows.Scan(&f.Path, &lang, &f.Test, &f.Tagged, &f.Imports, &symbols); err != nil {
+			return nil, fmt.Errorf("scan code graph file: %w", err)
+		}
+		f.Language = codegraph.Language(lang)
+		if err := json.Unmarshal(symbols, &f.Symbols); err != nil {
+			return nil, fmt.Errorf("unmarshal symbols of %s: %w", f.Path, err)
+		}
+		if len(f.Imports) == 0 {
+			f.Imports = nil
+		}
+		if len(f.Symbols) == 0 {
+			f.Symbols = nil
+		}
+		result = append(result, &f)
+	}
+	return result, rows.Err()
+}
+
+// ListCodeGraphEdges returns the edges of a project's persisted code graph
+// ordered by source and target.
+func (s *Store) ListCodeGraphEdges(ctx context.Context, projectID string) ([]codegraph.Edge, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT from_path, to_path FROM code_graph_edges
+		 WHERE project_id = $1 ORDER BY from_path, to_path`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list code graph edges %s: %w", projectID, err)
+	}
+	defer rows.Close()
+
+	var result []codegraph.Edge
+	for rows.Next() {
+		var e codegraph.Edge
+		if err := rows.Scan(&e.From, &e.To); err != nil {
+			return nil, fmt.Errorf("scan code graph edge: %w", err)
+		}
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
+
+// GetCodeGraphNeighbors returns the direct dependencies and dependents of
+// a file in a project's persisted code graph.
+func (s *Store) GetCodeGraphNeighbors(ctx context.Context, projectID, path string) (*codegraph.Neighbors, error) {
+	n := codegraph.Neighbors{Path: path}
+	err := s.pool.QueryRow(ctx,
+		`SELECT
+		     COALESCE((SELECT array_agg(to_path ORDER BY to_path) FROM code_graph_edges
+		               WHERE project_id = f.project_id AND from_path = f.path), '{}'),
+		     COALESCE((SELECT array_agg(from_path ORDER BY from_path) FROM code_graph_edges
+		               WHERE project_id = f.project_id AND to_path = f.path), '{}')
+		 FROM code_graph_files f WHERE f.project_id = $1 AND f.path = $2`, projectID, path,
+	).Scan(&n.Dependencies, &n.Dependents)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get code graph file %s: %w", path, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get code graph neighbors %s: %w", path, err)
+	}
+	return &n, nil
+}
+
+// edgeColumns splits edges into their source and target paths.
+func edgeColumns(edges []codegraph.Edge) (from, to []string) {
+	from, to = make([]string, len(edges)), make([]string, len(edges))
+	for i, e := range edges {
+		from[i], to[i] = e.From, e.To
+	}
+	return from, to
+}
+
+// symbolsOrEmpty returns an empty slice instead of nil, so symbols are
+// stored as an empty JSON array.
+func symbolsOrEmpty(symbols []codegraph.Symbol) []codegraph.Symbol {
+	if symbols == nil {
+		return []codegraph.Symbol{}
+	}
+	return symbols
+}
+
+// --- Project Conventions ---
+
+// GetProjectConventions returns the conventions document of a project.
+func (s *Store) GetProjectConventions(ctx context.Context, projectID string) (*convention.Conventions, error) {
+	var doc []byte
+	var c convention.Conventions
+	var trigger, commit string
+	var extractedAt time.Time
+	err := s.pool.QueryRow(ctx,
+		`SELECT document, trigger, commit_sha, extracted_at FROM project_conventions WHERE project_id = $1`, projectID,
+	).Scan(&doc, &trigger, &commit, &extractedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get project conventions %s: %w", projectID, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get project conventions: %w", err)
+	}
+	if err := json.Unmarshal(doc, &c); err != nil {
+		return nil, fmt.Errorf("unmarshal project conventions: %w", err)
+	}
+	c.ProjectID, c.Trigger, c.Commit, c.ExtractedAt = projectID, trigger, commit, extractedAt
+	return &c, nil
+}
+
+// SaveProjectConventions creates or replaces the conventions document of a
+// project and sets its extraction time.
+func (s *Store) SaveProjectConventions(ctx context.Context, c *convention.Conventions) error {
+	doc, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("marshal project conventions: %w", err)
+	}
+	err = s.pool.QueryRow(ctx,
+		`INSERT INTO project_conventions (project_id, document, trigger, commit_sha)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (project_id) DO UPDATE SET document = EXCLUDED.document,
+		     trigger = EXCLUDED.trigger, commit_sha = EXCLUDED.commit_sha, extracted_at = now()
+		 RETURNING extracted_at`,
+		c.ProjectID, doc, c.Trigger, c.Commit,
+	).Scan(&c.ExtractedAt)
+	if err != nil {
+		return fmt.Errorf("save project conventions: %w", err)
+	}
+	return nil
+}
+
+// --- Conversations ---
+
+const conversationColumns = `id, project_id, title, model, mode, created_at, updated_at`
+
+// CreateConversation inserts a conversation.
+func (s *Store) CreateConversation(ctx context.Context, c *conversation.Conversation) error {
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO conversations (project_id, title, model, mode)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING id, created_at, updated_at`,
+		c.ProjectID, c.Title, c.Model, c.Mode,
+	).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create conversation: %w", err)
+	}
+	return nil
+}
+
+// GetConversation returns a conversation by ID.
+func (s *Store) GetConversation(ctx context.Context, id string) (*conversation.Conversation, error) {
+	var c conversation.Conversation
+	err := s.pool.QueryRow(ctx,
+		`SELECT `+conversationColumns+` FROM conversations WHERE id = $1`, id,
+	).Scan(&c.ID, &c.ProjectID, &c.Title, &c.Model, &c.Mode, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get conversation %s: %w", id, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get conversation %s: %w", id, err)
+	}
+	return &c, nil
+}
+
+// ListConversations returns the conversations of a project, most recently
+// active first.
+func (s *Store) ListConversations(ctx context.Context, projectID string) ([]conversation.Conversation, error) {
+	rows, err := s.reader(ctx).Query(ctx,
+		`SELECT `+conversationColumns+` FROM conversations WHERE project_id = $1 ORDER BY updated_at DESC`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list conversations: %w", err)
+	}
+	defer rows.Close()
+
+	var result []conversation.Conversation
+	for rows.Next() {
+		var c conversation.Conversation
+		if err := rows.Scan(&c.ID, &c.ProjectID, &c.Title, &c.Model, &c.Mode, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan conversation: %w", err)
+		}
+		result = append(result, c)
+	}
+	return result, rows.Err()
+}
+
+// AppendConversationMessage inserts a message with the next sequence number
+// of its conversation and touches the conversation. Concurrent appends to
+// the same conversation fail with domain.ErrConflict.
+func (s *Store) AppendConversationMessage(ctx context.Context, m *conversation.Message) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
+
+	err = tx.QueryRow(ctx,
+		`INSERT INTO conversation_messages (conversation_id, seq, role, content, tokens, covers, model, memory_ids, microagent_ids, tool_call, prompt_tokens, completion_tokens, attachment_ids)
+		 VALUES ($1, (SELECT COALESCE(MAX(seq), 0) + 1 FROM conversation_messages WHERE conversation_id = $1), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		 RETURNING id, seq, created_at`,
+		m.ConversationID, m.Role, m.Content, m.Tokens, m.Covers, m.Model, labelsOrEmpty(m.Memories), labelsOrEmpty(m.Microagents), jsonOrNil(m.ToolCall), m.PromptTokens, m.CompletionTokens, labelsOrEmpty(m.Attachments),
+	).Scan(&m.ID, &m.Seq, &m.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("append conversation message: %w", domain.ErrConflict)
+		}
+		return fmt.Errorf("append conversation message: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE conversations SET updated_at = now() WHERE id = $1`, m.ConversationID); err != nil {
+		return fmt.Errorf("touch conversation: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// ListConversationMessages returns all messages of a conversation,
+// summaries included, ordered by sequence number.
+func (s *Store) ListConversationMessages(ctx context.Context, conversationID string) ([]conversation.Message, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT id, conversation_id, seq, role, content, tokens, covers, model, memory_ids, microagent_ids, tool_call, prompt_tokens, completion_tokens, attachment_ids, created_at
+		 FROM conversation_messages WHERE conversation_id = $1 ORDER BY seq`, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("list conversation messages: %w", err)
+	}
+	defer rows.Close()
+
+	var result []conversation.Message
+	for rows.Next() {
+		var m conversation.Message
+		var toolCall []byte
+		if err := rows.Scan(&m.ID, &m.ConversationID, &m.Seq, &m.Role, &m.Content, &m.Tokens, &m.Covers, &m.Model, &m.Memories, &m.Microagents, &toolCall, &m.PromptTokens, &m.CompletionTokens, &m.Attachments, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan conversation message: %w", err)
+		}
+		if toolCall != nil {
+			m.ToolCall = &conversation.ToolCall{}
+			if err := json.Unmarshal(toolCall, m.ToolCall); err != nil {
+				return nil, fmt.Errorf("unmarshal tool call: %w", err)
+			}
+		}
+		result = append(result, m)
+	}
+	return result, rows.Err()
+}
+
+// --- Memories ---
+
+const memoryColumns = `id, project_id, content, source, importance, provider, model, embedding, created_at`
+
+// CreateMemory inserts a memory with its embedding.
+func (s *Store) CreateMemory(ctx context.Context, m *memory.Memory) error {
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO memories (project_id, content, source, importance, provider, model, embedding)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING id, created_at`,
+		m.ProjectID, m.Content, m.Source, m.Importance, m.Provider, m.Model, m.Embedding,
+	).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create memory: %w", err)
+	}
+	return nil
+}
+
+// ListMemories returns the memories of a project with their embeddings,
+// newest first.
+func (s *Store) ListMemories(ctx context.Context, projectID string) ([]memory.Memory, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT `+memoryColumns+` FROM memories WHERE project_id = $1 ORDER BY created_at DESC`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list memories: %w", err)
+	}
+	defer rows.Close()
+
+	var result []memory.Memory
+	for rows.Next() {
+		var m memory.Memory
+		if err := rows.Scan(&m.ID, &m.ProjectID, &m.Content, &m.Source, &m.Importance,
+			&m.Provider, &m.Model, &m.Embedding, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan memory: %w", err)
+		}
+		result = append(result, m)
+	}
+	return result, rows.Err()
+}
+
+// DeleteMemory removes a memory.
+func (s *Store) DeleteMemory(ctx context.Context, id string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM memories WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete memory %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete memory %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// --- Experience Pool ---
+
+const experienceColumns = `id, project_id, plan_id, feature, outcome, steps, duration_ms, pitfalls, useful, not_useful, created_at`
+
+func scanExperience(row pgx.Row) (experience.Entry, error) {
+	var e experience.Entry
+	err := row.Scan(&e.ID, &e.ProjectID, &e.PlanID, &e.Feature, &e.Outcome, &e.Steps,
+		&e.DurationMs, &e.Pitfalls, &e.Useful, &e.NotUseful, &e.CreatedAt)
+	return e, err
+}
+
+// CreateExperience records the outcome of a plan. A plan is recorded once;
+// recording it again fails with domain.ErrConflict.
+func (s *Store) CreateExperience(ctx context.Context, e *experience.Entry) error {
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO experiences (project_id, plan_id, feature, outcome, steps, duration_ms, pitfalls)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING id, created_at`,
+		e.ProjectID, e.PlanID, e.Feature, e.Outcome, e.Steps, e.DurationMs, labelsOrEmpty(e.Pitfalls),
+	).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("create experience for plan %s: %w", e.PlanID, domain.ErrConflict)
+		}
+		return fmt.Errorf("create experience: %w", err)
+	}
+	return nil
+}
+
+// ListExperiences returns the experience entries of a project, newest first.
+func (s *Store) ListExperiences(ctx context.Context, projectID string) ([]experience.Entry, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT `+experienceColumns+` FROM experiences WHERE project_id = $1 ORDER BY created_at DESC`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list experiences: %w", err)
+	}
+	defer rows.Close()
+
+	var result []experience.Entry
+	for rows.Next() {
+		e, err := scanExperience(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan experience: %w", err)
+		}
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
+
+// RateExperience counts a usefulness rating of an entry and returns it.
+func (s *Store) RateExperience(ctx context.Context, id string, useful bool) (*experience.Entry, error) {
+	e, err := scanExperience(s.pool.QueryRow(ctx,
+		`UPDATE experiences
+		 SET useful = useful + CASE WHEN $2 THEN 1 ELSE 0 END,
+		     not_useful = not_useful + CASE WHEN $2 THEN 0 ELSE 1 END
+		 WHERE id = $1
+		 RETURNING `+experienceColumns, id, useful))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("rate experience %s: %w", id, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("rate experience %s: %w", id, err)
+	}
+	return &e, nil
+}
+
+// --- Skills ---
+
+const skillColumns = `id, project_id, name, version, description, prompt, tools, fixtures, signer, created_at`
+
+func scanSkill(row pgx.Row) (skill.Skill, error) {
+	var sk skill.Skill
+	var fixturesJSON []byte
+	if err := row.Scan(&sk.ID, &sk.ProjectID, &sk.Name, &sk.Version, &sk.Description, &sk.Prompt,
+		&sk.Tools, &fixturesJSON, &sk.Signer, &sk.CreatedAt); err != nil {
+		return sk, err
+	}
+	if err := json.Unmarshal(fixturesJSON, &sk.Fixtures); err != nil {
+		return sk, fmt.Errorf("unmarshal skill fixtures: %w", err)
+	}
+	return sk, nil
+}
+
+// CreateSkill stores a skill. Skill names are unique per project; a
+// duplicate fails with domain.ErrConflict.
+func (s *Store) CreateSkill(ctx context.Context, sk *skill.Skill) error {
+	fixtures := sk.Fixtures
+	if fixtures == nil {
+		fixtures = []skill.Fixture{}
+	}
+	fixturesJSON, err := json.Marshal(fixtures)
+	if err != nil {
+		return fmt.Errorf("marshal skill fixtures: %w", err)
+	}
+	err = s.pool.QueryRow(ctx,
+		`INSERT INTO skills (project_id, name, version, description, prompt, tools, fixtures, signer)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 RETURNING id, created_at`,
+		sk.ProjectID, sk.Name, sk.Version, sk.Description, sk.Prompt, labelsOrEmpty(sk.Tools), fixturesJSON, sk.Signer,
+	).Scan(&sk.ID, &sk.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("create skill %s: %w", sk.Name, domain.ErrConflict)
+		}
+		return fmt.Errorf("create skill: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) GetSkill(ctx context.Context, id string) (*skill.Skill, error) {
+	sk, err := scanSkill(s.pool.QueryRow(ctx, `SELECT `+skillColumns+` FROM skills WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get skill %s: %w", id, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get skill %s: %w", id, err)
+	}
+	return &sk, nil
+}
+
+func (s *Store) ListSkills(ctx context.Context, projectID string) ([]skill.Skill, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT `+skillColumns+` FROM skills WHERE project_id = $1 ORDER BY name`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list skills: %w", err)
+	}
+	defer rows.Close()
+
+	var result []skill.Skill
+	for rows.Next() {
+		sk, err := scanSkill(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan skill: %w", err)
+		}
+		result = append(result, sk)
+	}
+	return result, rows.Err()
+}
+
+func (s *Store) DeleteSkill(ctx context.Context, id string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM skills WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete skill %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete skill %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// --- Microagents ---
+
+const microagentColumns = `id, project_id, name, description, knowledge, trigger, enabled, created_at, updated_at`
+
+func scanMicroagent(row pgx.Row) (microagent.Microagent, error) {
+	var m microagent.Microagent
+	var triggerJSON []byte
+	if err := row.Scan(&m.ID, &m.ProjectID, &m.Name, &m.Description, &m.Knowledge, &triggerJSON,
+		&m.Enabled, &m.CreatedAt, &m.UpdatedAt); err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(triggerJSON, &m.Trigger); err != nil {
+		return m, fmt.Errorf("unmarshal microagent trigger: %w", err)
+	}
+	return m, nil
+}
+
+// CreateMicroagent stores a microagent. Names are unique per project; a
+// duplicate fails with domain.ErrConflict.
+func (s *Store) CreateMicroagent(ctx context.Context, m *microagent.Microagent) error {
+	triggerJSON, err := json.Marshal(m.Trigger)
+	if err != nil {
+		return fmt.Errorf("marshal microagent trigger: %w", err)
+	}
+	err = s.pool.QueryRow(ctx,
+		`INSERT INTO microagents (project_id, name, description, knowledge, trigger, enabled)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id, created_at, updated_at`,
+		m.ProjectID, m.Name, m.Description, m.Knowledge, triggerJSON, m.Enabled,
+	).Scan(&m.ID, &m.CreatedAt, &m.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("create microagent %s: %w", m.Name, domain.ErrConflict)
+		}
+		return fmt.Errorf("create microagent: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) GetMicroagent(ctx context.Context, id string) (*microagent.Microagent, error) {
+	m, err := scanMicroagent(s.pool.QueryRow(ctx, `SELECT `+microagentColumns+` FROM microagents WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get microagent %s: %w", id, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get microagent %s: %w", id, err)
+	}
+	return &m, nil
+}
+
+func (s *Store) ListMicroagents(ctx context.Context, projectID string) ([]microagent.Microagent, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT `+microagentColumns+` FROM microagents WHERE project_id = $1 ORDER BY name`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list microagents: %w", err)
+	}
+	defer rows.Close()
+
+	var result []microagent.Microagent
+	for rows.Next() {
+		m, err := scanMicroagent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan microagent: %w", err)
+		}
+		result = append(result, m)
+	}
+	return result, rows.Err()
+}
+
+func (s *Store) UpdateMicroagent(ctx context.Context, m *microagent.Microagent) error {
+	triggerJSON, err := json.Marshal(m.Trigger)
+	if err != nil {
+		return fmt.Errorf("marshal microagent trigger: %w", err)
+	}
+	err = s.pool.QueryRow(ctx,
+		`UPDATE microagents SET name = $2, description = $3, knowledge = $4, trigger = $5, enabled = $6, updated_at = now()
+		 WHERE id = $1
+		 RETURNING updated_at`,
+		m.ID, m.Name, m.Description, m.Knowledge, triggerJSON, m.Enabled,
+	).Scan(&m.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return fmt.Errorf("update microagent %s: %w", m.ID, domain.ErrNotFound)
+		case errors.As(err, &pgErr) && pgErr.Code == "23505":
+			return fmt.Errorf("update microagent %s: %w", m.ID, domain.ErrConflict)
+		}
+		return fmt.Errorf("update microagent %s: %w", m.ID, err)
+	}
+	return nil
+}
+
+func (s *Store) DeleteMicroagent(ctx context.Context, id string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM microagents WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete microagent %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete microagent %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// --- Run Presets ---
+
+const runPresetColumns = `id, project_id, name, description, backend, mode, policy_profile, exec_mode, deliver_mode,
+	model, task_type, isolate, context_budget, created_at, updated_at`
+
+func scanRunPreset(row pgx.Row) (run.Preset, error) {
+	var p run.Preset
+	err := row.Scan(&p.ID, &p.ProjectID, &p.Name, &p.Description, &p.Backend, &p.Mode, &p.PolicyProfile,
+		&p.ExecMode, &p.DeliverMode, &p.Model, &p.TaskType, &p.Isolate, &p.ContextBudget, &p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
+
+// CreateRunPreset stores a run preset. Names are unique per project; a
+// duplicate fails with domain.ErrConflict.
+func (s *Store) CreateRunPreset(ctx context.Context, p *run.Preset) error {
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO run_presets (project_id, name, description, backend, mode, policy_profile, exec_mode,
+		     deliver_mode, model, task_type, isolate, context_budget)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		 RETURNING id, created_at, updated_at`,
+		p.ProjectID, p.Name, p.Description, p.Backend, p.Mode, p.PolicyProfile, string(p.ExecMode),
+		string(p.DeliverMode), p.Model, p.TaskType, p.Isolate, p.ContextBudget,
+	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("create run preset %s: %w", p.Name, domain.ErrConflict)
+		}
+		return fmt.Errorf("create run preset: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) GetRunPreset(ctx context.Context, projectID, name string) (*run.Preset, error) {
+	p, err := scanRunPreset(s.pool.QueryRow(ctx,
+		`SELECT `+runPresetColumns+` FROM run_presets WHERE project_id = $1 AND name = $2`, projectID, name))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get run preset %s: %w", name, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get run preset %s: %w", name, err)
+	}
+	return &p, nil
+}
+
+func (s *Store) ListRunPresets(ctx context.Context, projectID string) ([]run.Preset, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT `+runPresetColumns+` FROM run_presets WHERE project_id = $1 ORDER BY name`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list run presets: %w", err)
+	}
+	defer rows.Close()
+
+	var result []run.Preset
+	for rows.Next() {
+		p, err := scanRunPreset(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan run preset: %w", err)
+		}
+		result = append(result, p)
+	}
+	return result, rows.Err()
+}
+
+func (s *Store) UpdateRunPreset(ctx context.Context, p *run.Preset) error {
+	err := s.pool.QueryRow(ctx,
+		`UPDATE run_presets SET name = $2, description = $3, backend = $4, mode = $5, policy_profile = $6,
+		     exec_mode = $7, deliver_mode = $8, model = $9, task_type = $10, isolate = $11, context_budget = $12,
+		     updated_at = now()
+		 WHERE id = $1
+		 RETURNING updated_at`,
+		p.ID, p.Name, p.Description, p.Backend, p.Mode, p.PolicyProfile, string(p.ExecMode),
+		string(p.DeliverMode), p.Model, p.TaskType, p.Isolate, p.ContextBudget,
+	).Scan(&p.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return fmt.Errorf("update run preset %s: %w", p.ID, domain.ErrNotFound)
+		case errors.As(err, &pgErr) && pgErr.Code == "23505":
+			return fmt.Errorf("update run preset %s: %w", p.ID, domain.ErrConflict)
+		}
+		return fmt.Errorf("update run preset %s: %w", p.ID, err)
+	}
+	return nil
+}
+
+func (s *Store) DeleteRunPreset(ctx context.Context, projectID, name string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM run_presets WHERE project_id = $1 AND name = $2`, projectID, name)
+	if err != nil {
+		return fmt.Errorf("delete run preset %s: %w", name, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete run preset %s: %w", name, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// --- MCP Servers ---
+
+const mcpServerColumns = `id, tenant_id, name, url, transport, auth, oauth, enabled, secret_ciphertext, token_ciphertext,
+	token_expires_at, created_at, updated_at`
+
+func scanMCPServer(row pgx.Row) (mcpserver.Server, error) {
+	var m mcpserver.Server
+	var oauthJSON []byte
+	if err := row.Scan(&m.ID, &m.TenantID, &m.Name, &m.URL, &m.Transport, &m.Auth, &oauthJSON, &m.Enabled,
+		&m.SecretCiphertext, &m.TokenCiphertext, &m.TokenExpiresAt, &m.CreatedAt, &m.UpdatedAt); err != nil {
+		return m, err
+	}
+	if len(oauthJSON) > 0 {
+		if err := json.Unmarshal(oauthJSON, &m.OAuth); err != nil {
+			return m, fmt.Errorf("unmarshal mcp server oauth: %w", err)
+		}
+	}
+	return m, nil
+}
+
+// CreateMCPServer stores an MCP server in its tenant (see ownerTenant).
+// Names are unique per tenant; a duplicate fails with domain.ErrConflict.
+func (s *Store) CreateMCPServer(ctx context.Context, m *mcpserver.Server) error {
+	oauthJSON, err := marshalOAuth(m.OAuth)
+	if err != nil {
+		return err
+	}
+	err = s.pool.QueryRow(ctx,
+		`INSERT INTO mcp_servers (tenant_id, name, url, transport, auth, oauth, enabled, secret_ciphertext, token_ciphertext, token_expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		 RETURNING id, tenant_id, created_at, updated_at`,
+		ownerTenant(ctx, m.TenantID), m.Name, m.URL, m.Transport, m.Auth, oauthJSON, m.Enabled, m.SecretCiphertext, m.TokenCiphertext, m.TokenExpiresAt,
+	).Scan(&m.ID, &m.TenantID, &m.CreatedAt, &m.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("create mcp server %s: %w", m.Name, domain.ErrConflict)
+		}
+		return fmt.Errorf("create mcp server: %w", err)
+	}
+	return nil
+}
+
+// GetMCPServer returns an MCP server by ID, including its ciphertexts.
+func (s *Store) GetMCPServer(ctx context.Context, id string) (*mcpserver.Server, error) {
+	m, err := scanMCPServer(s.pool.QueryRow(ctx, `SELECT `+mcpServerColumns+` FROM mcp_servers WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get mcp server %s: %w", id, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get mcp server %s: %w", id, err)
+	}
+	return &m, nil
+}
+
+// ListMCPServers returns all MCP servers ordered by name.
+func (s *Store) ListMCPServers(ctx context.Context) ([]mcpserver.Server, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+mcpServerColumns+` FROM mcp_servers ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("list mcp servers: %w", err)
+	}
+	defer rows.Close()
+
+	var result []mcpserver.Server
+	for rows.Next() {
+		m, err := scanMCPServer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan mcp server: %w", err)
+		}
+		result = append(result, m)
+	}
+	return result, rows.Err()
+}
+
+// UpdateMCPServer replaces all fields of an MCP server, including the
+// encrypted secret and token.
+func (s *Store) UpdateMCPServer(ctx context.Context, m *mcpserver.Server) error {
+	oauthJSON, err := marshalOAuth(m.OAuth)
+	if err != nil {
+		return err
+	}
+	err = s.pool.QueryRow(ctx,
+		`UPDATE mcp_servers SET name = $2, url = $3, transport = $4, auth = $5, oauth = $6, enabled = $7,
+		        secret_ciphertext = $8, token_ciphertext = $9, token_expires_at = $10, updated_at = now()
+		 WHERE id = $1
+		 RETURNING updated_at`,
+		m.ID, m.Name, m.URL, m.Transport, m.Auth, oauthJSON, m.Enabled, m.SecretCiphertext, m.TokenCiphertext, m.TokenExpiresAt,
+	).Scan(&m.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return fmt.Errorf("update mcp server %s: %w", m.ID, domain.ErrNotFound)
+		case errors.As(err, &pgErr) && pgErr.Code == "23505":
+			return fmt.Errorf("update mcp server %s: %w", m.ID, domain.ErrConflict)
+		}
+		return fmt.Errorf("update mcp server %s: %w", m.ID, err)
+	}
+	return nil
+}
+
+// DeleteMCPServer removes an MCP server.
+func (s *Store) DeleteMCPServer(ctx context.Context, id string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM mcp_servers WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete mcp server %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete mcp server %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// marshalOAuth encodes an OAuth config for the nullable oauth column.
+func marshalOAuth(o *mcpserver.OAuth) ([]byte, error) {
+	if o == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(o)
+	if err != nil {
+		return nil, fmt.Errorf("marshal mcp server oauth: %w", err)
+	}
+	return data, nil
+}
+
+// --- API Keys ---
+
+const apiKeyColumns = `id, name, prefix, hash, scopes, COALESCE(tenant_id, ''), rate_limit, roles, expires_at, last_used_at, created_at, updated_at`
+
+func scanAPIKey(row pgx.Row) (apikey.Key, error) {
+	var k apikey.Key
+	var scopes []string
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.Hash, &scopes, &k.TenantID, &k.RateLimit, &k.Roles,
+		&k.ExpiresAt, &k.LastUsedAt, &k.CreatedAt, &k.UpdatedAt)
+	for _, sc := range scopes {
+		k.Scopes = append(k.Scopes, apikey.Scope(sc))
+	}
+	return k, err
+}
+
+func scopeStrings(scopes []apikey.Scope) []string {
+	out := make([]string, len(scopes))
+	for i, sc := range scopes {
+		out[i] = string(sc)
+	}
+	return out
+}
+
+// CreateAPIKey stores an API key.
+func (s *Store) CreateAPIKey(ctx context.Context, k *apikey.Key) error {
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO api_keys (name, prefix, hash, scopes, tenant_id, rate_limit, roles, expires_at)
 		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		 RETURNING id, created_at, updated_at`,
-		step.PlanID, step.TaskID, step.AgentID, step.PolicyProfile, step.DeliverMode,
-		step.DependsOn, string(step.Status), step.Round,
-	).Scan(&step.ID, &step.CreatedAt, &step.UpdatedAt)
+		k.Name, k.Prefix, k.Hash, scopeStrings(k.Scopes), nullIfEmpty(k.TenantID), k.RateLimit, labelsOrEmpty(k.Roles), k.ExpiresAt,
+	).Scan(&k.ID, &k.CreatedAt, &k.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create api key: %w", err)
+	}
+	return nil
+}
+
+// GetAPIKey returns an API key by ID.
+func (s *Store) GetAPIKey(ctx context.Context, id string) (*apikey.Key, error) {
+	k, err := scanAPIKey(s.pool.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get api key %s: %w", id, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get api key %s: %w", id, err)
+	}
+	return &k, nil
+}
+
+// GetAPIKeyByHash returns the API key whose token has the given hash.
+func (s *Store) GetAPIKeyByHash(ctx context.Context, hash []byte) (*apikey.Key, error) {
+	k, err := scanAPIKey(s.pool.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE hash = $1`, hash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get api key by hash: %w", domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get api key by hash: %w", err)
+	}
+	return &k, nil
+}
+
+// ListAPIKeys returns all API keys ordered by name.
+func (s *Store) ListAPIKeys(ctx context.Context) ([]apikey.Key, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY name, created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var result []apikey.Key
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan api key: %w", err)
+		}
+		result = append(result, k)
+	}
+	return result, rows.Err()
+}
+
+// UpdateAPIKey replaces the name, scopes, tenant, rate limit, roles and
+// expiry of an API key.
+func (s *Store) UpdateAPIKey(ctx context.Context, k *apikey.Key) error {
+	err := s.pool.QueryRow(ctx,
+		`UPDATE api_keys SET name = $2, scopes = $3, tenant_id = $4, rate_limit = $5, roles = $6, expires_at = $7, updated_at = now()
+		 WHERE id = $1
+		 RETURNING updated_at`,
+		k.ID, k.Name, scopeStrings(k.Scopes), nullIfEmpty(k.TenantID), k.RateLimit, labelsOrEmpty(k.Roles), k.ExpiresAt,
+	).Scan(&k.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("update api key %s: %w", k.ID, domain.ErrNotFound)
+		}
+		return fmt.Errorf("update api key %s: %w", k.ID, err)
+	}
+	return nil
+}
+
+// TouchAPIKey records when an API key was last used.
+func (s *Store) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	if _, err := s.pool.Exec(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, id, at); err != nil {
+		return fmt.Errorf("touch api key %s: %w", id, err)
+	}
+	return nil
+}
+
+// DeleteAPIKey revokes an API key.
+func (s *Store) DeleteAPIKey(ctx context.Context, id string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM api_keys WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete api key %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete api key %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// --- Audit Sinks ---
+
+const auditSinkColumns = `id, tenant_id, name, kind, config, enabled, checkpoint, last_error, last_delivered_at, created_at, updated_at`
+
+func scanAuditSink(row pgx.Row) (audit.Sink, error) {
+	var a audit.Sink
+	var configJSON []byte
+	if err := row.Scan(&a.ID, &a.TenantID, &a.Name, &a.Kind, &configJSON, &a.Enabled, &a.Checkpoint,
+		&a.LastError, &a.LastDeliveredAt, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		return a, err
+	}
+	if err := json.Unmarshal(configJSON, &a.Config); err != nil {
+		return a, fmt.Errorf("unmarshal audit sink config: %w", err)
+	}
+	return a, nil
+}
+
+// CreateAuditSink stores an audit sink. Names are unique per tenant; a
+// duplicate fails with domain.ErrConflict.
+func (s *Store) CreateAuditSink(ctx context.Context, a *audit.Sink) error {
+	configJSON, err := json.Marshal(externalIDs(a.Config))
+	if err != nil {
+		return fmt.Errorf("marshal audit sink config: %w", err)
+	}
+	err = s.pool.QueryRow(ctx,
+		`INSERT INTO audit_sinks (tenant_id, name, kind, config, enabled)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING id, checkpoint, created_at, updated_at`,
+		a.TenantID, a.Name, string(a.Kind), configJSON, a.Enabled,
+	).Scan(&a.ID, &a.Checkpoint, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("create audit sink %s: %w", a.Name, domain.ErrConflict)
+		}
+		return fmt.Errorf("create audit sink: %w", err)
+	}
+	return nil
+}
+
+// GetAuditSink returns an audit sink by ID.
+func (s *Store) GetAuditSink(ctx context.Context, id string) (*audit.Sink, error) {
+	a, err := scanAuditSink(s.pool.QueryRow(ctx, `SELECT `+auditSinkColumns+` FROM audit_sinks WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get audit sink %s: %w", id, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get audit sink %s: %w", id, err)
+	}
+	return &a, nil
+}
+
+// ListAuditSinks returns the audit sinks visible to the session, ordered
+// by tenant and name.
+func (s *Store) ListAuditSinks(ctx context.Context) ([]audit.Sink, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+auditSinkColumns+` FROM audit_sinks ORDER BY tenant_id, name`)
+	if err != nil {
+		return nil, fmt.Errorf("list audit sinks: %w", err)
+	}
+	defer rows.Close()
+
+	var result []audit.Sink
+	for rows.Next() {
+		a, err := scanAuditSink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan audit sink: %w", err)
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
+
+// UpdateAuditSink replaces the name, kind, config and enabled flag of an
+// audit sink. The checkpoint is kept.
+func (s *Store) UpdateAuditSink(ctx context.Context, a *audit.Sink) error {
+	configJSON, err := json.Marshal(externalIDs(a.Config))
+	if err != nil {
+		return fmt.Errorf("marshal audit sink config: %w", err)
+	}
+	err = s.pool.QueryRow(ctx,
+		`UPDATE audit_sinks SET name = $2, kind = $3, config = $4, enabled = $5, updated_at = now()
+		 WHERE id = $1
+		 RETURNING updated_at`,
+		a.ID, a.Name, string(a.Kind), configJSON, a.Enabled,
+	).Scan(&a.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return fmt.Errorf("update audit sink %s: %w", a.ID, domain.ErrNotFound)
+		case errors.As(err, &pgErr) && pgErr.Code == "23505":
+			return fmt.Errorf("update audit sink %s: %w", a.ID, domain.ErrConflict)
+		}
+		return fmt.Errorf("update audit sink %s: %w", a.ID, err)
+	}
+	return nil
+}
+
+// SetAuditSinkProgress records a delivery to an audit sink: on success
+// (errMsg empty) it advances the checkpoint to seq and clears the last
+// error, otherwise it only records errMsg. The checkpoint never moves back.
+func (s *Store) SetAuditSinkProgress(ctx context.Context, id string, seq int64, errMsg string) error {
+	var err error
+	if errMsg == "" {
+		_, err = s.pool.Exec(ctx,
+			`UPDATE audit_sinks SET checkpoint = GREATEST(checkpoint, $2), last_error = '', last_delivered_at = now()
+			 WHERE id = $1`, id, seq)
+	} else {
+		_, err = s.pool.Exec(ctx, `UPDATE audit_sinks SET last_error = $2 WHERE id = $1`, id, errMsg)
+	}
+	if err != nil {
+		return fmt.Errorf("set audit sink progress %s: %w", id, err)
+	}
+	return nil
+}
+
+// DeleteAuditSink removes an audit sink.
+func (s *Store) DeleteAuditSink(ctx context.Context, id string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM audit_sinks WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete audit sink %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete audit sink %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
+
+// --- Knowledge Bases ---
+
+const knowledgeBaseColumns = `id, tenant_id, name, kind, config, project_ids, refresh_interval, provider, model, dimensions,
+	documents, chunks, last_error, refreshed_at, created_at, updated_at`
+
+func scanKnowledgeBase(row pgx.Row) (knowledge.KnowledgeBase, error) {
+	var kb knowledge.KnowledgeBase
+	var configJSON []byte
+	if err := row.Scan(&kb.ID, &kb.TenantID, &kb.Name, &kb.Kind, &configJSON, &kb.ProjectIDs, &kb.RefreshInterval,
+		&kb.Provider, &kb.Model, &kb.Dimensions, &kb.Documents, &kb.Chunks, &kb.LastError, &kb.RefreshedAt,
+		&kb.CreatedAt, &kb.UpdatedAt); err != nil {
+		return kb, err
+	}
+	if err := json.Unmarshal(configJSON, &kb.Config); err != nil {
+		return kb, fmt.Errorf("unmarshal knowledge base config: %w", err)
+	}
+	return kb, nil
+}
+
+// CreateKnowledgeBase stores a knowledge base without chunks. Names are
+// unique per tenant; a duplicate fails with domain.ErrConflict.
+func (s *Store) CreateKnowledgeBase(ctx context.Context, kb *knowledge.KnowledgeBase) error {
+	configJSON, err := json.Marshal(externalIDs(kb.Config))
+	if err != nil {
+		return fmt.Errorf("marshal knowledge base config: %w", err)
+	}
+	err = s.pool.QueryRow(ctx,
+		`INSERT INTO knowledge_bases (tenant_id, name, kind, config, project_ids, refresh_interval)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id, created_at, updated_at`,
+		kb.TenantID, kb.Name, string(kb.Kind), configJSON, labelsOrEmpty(kb.ProjectIDs), kb.RefreshInterval,
+	).Scan(&kb.ID, &kb.CreatedAt, &kb.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("create knowledge base %s: %w", kb.Name, domain.ErrConflict)
+		}
+		return fmt.Errorf("create knowledge base: %w", err)
+	}
+	return nil
+}
+
+// GetKnowledgeBase returns a knowledge base by ID.
+func (s *Store) GetKnowledgeBase(ctx context.Context, id string) (*knowledge.KnowledgeBase, error) {
+	kb, err := scanKnowledgeBase(s.pool.QueryRow(ctx, `SELECT `+knowledgeBaseColumns+` FROM knowledge_bases WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get knowledge base %s: %w", id, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get knowledge base %s: %w", id, err)
+	}
+	return &kb, nil
+}
+
+// ListKnowledgeBases returns the knowledge bases visible to the session,
+// ordered by tenant and name.
+func (s *Store) ListKnowledgeBases(ctx context.Context) ([]knowledge.KnowledgeBase, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+knowledgeBaseColumns+` FROM knowledge_bases ORDER BY tenant_id, name`)
+	if err != nil {
+		return nil, fmt.Errorf("list knowledge bases: %w", err)
+	}
+	defer rows.Close()
+
+	var result []knowledge.KnowledgeBase
+	for rows.Next() {
+		kb, err := scanKnowledgeBase(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan knowledge base: %w", err)
+		}
+		result = append(result, kb)
+	}
+	return result, rows.Err()
+}
+
+// UpdateKnowledgeBase replaces the name, kind, config, projects and refresh
+// interval of a knowledge base. Its chunks are kept until the next refresh.
+func (s *Store) UpdateKnowledgeBase(ctx context.Context, kb *knowledge.KnowledgeBase) error {
+	configJSON, err := json.Marshal(externalIDs(kb.Config))
+	if err != nil {
+		return fmt.Errorf("marshal knowledge base config: %w", err)
+	}
+	err = s.pool.QueryRow(ctx,
+		`UPDATE knowledge_bases SET name = $2, kind = $3, config = $4, project_ids = $5, refresh_interval = $6, updated_at = now()
+		 WHERE id = $1
+		 RETURNING updated_at`,
+		kb.ID, kb.Name, string(kb.Kind), configJSON, labelsOrEmpty(kb.ProjectIDs), kb.RefreshInterval,
+	).Scan(&kb.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return fmt.Errorf("update knowledge base %s: %w", kb.ID, domain.ErrNotFound)
+		case errors.As(err, &pgErr) && pgErr.Code == "23505":
+			return fmt.Errorf("update knowledge base %s: %w", kb.ID, domain.ErrConflict)
+		}
+		return fmt.Errorf("update knowledge base %s: %w", kb.ID, err)
+	}
+	return nil
+}
+
+// ReplaceKnowledgeChunks stores the chunks of a refreshed knowledge base,
+// replacing its previous chunks in one transaction, and records the
+// embedding and counts of kb. It clears the last error.
+func (s *Store) ReplaceKnowledgeChunks(ctx context.Context, kb *knowledge.KnowledgeBase, chunks []retrieval.Chunk) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
+
+	err = tx.QueryRow(ctx,
+		`UPDATE knowledge_bases SET provider = $2, model = $3, dimensions = $4, documents = $5, chunks = $6,
+		        last_error = '', refreshed_at = now()
+		 WHERE id = $1
+		 RETURNING refreshed_at`,
+		kb.ID, kb.Provider, kb.Model, kb.Dimensions, kb.Documents, kb.Chunks,
+	).Scan(&kb.RefreshedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("update knowledge base %s: %w", kb.ID, domain.ErrNotFound)
+		}
+		return fmt.Errorf("update knowledge base %s: %w", kb.ID, err)
+	}
+	kb.LastError = ""
+	if _, err := tx.Exec(ctx, `DELETE FROM knowledge_chunks WHERE kb_id = $1`, kb.ID); err != nil {
+		return fmt.Errorf("delete knowledge chunks %s: %w", kb.ID, err)
+	}
+
+	_, err = tx.CopyFrom(ctx,
+		pgx.Identifier{"knowledge_chunks"},
+		[]string{"kb_id", "tenant_id", "url", "title", "start_line", "end_line", "content", "embedding"},
+		pgx.CopyFromSlice(len(chunks), func(i int) ([]any, error) {
+			c := &chunks[i]
+			return []any{kb.ID, kb.TenantID, c.Path, c.Title, c.StartLine, c.EndLine, c.Content, c.Embedding}, nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("copy knowledge chunks %s: %w", kb.ID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
 }
 
-func (s *Store) ListPlanSteps(ctx context.Context, planID string) ([]plan.Step, error) {
+// SetKnowledgeBaseError records the error of a failed refresh.
+func (s *Store) SetKnowledgeBaseError(ctx context.Context, id, errMsg string) error {
+	if _, err := s.pool.Exec(ctx, `UPDATE knowledge_bases SET last_error = $2 WHERE id = $1`, id, errMsg); err != nil {
+		return fmt.Errorf("set knowledge base error %s: %w", id, err)
+	}
+	return nil
+}
+
+// ListKnowledgeChunks returns the chunks of a knowledge base with their
+// embeddings. Their path is the URL of their document.
+func (s *Store) ListKnowledgeChunks(ctx context.Context, kbID string) ([]retrieval.Chunk, error) {
 	rows, err := s.pool.Query(ctx,
-		`SELECT id, plan_id, task_id, agent_id, policy_profile, deliver_mode, depends_on, status, run_id, round, error, created_at, updated_at
-		 FROM plan_steps WHERE plan_id = $1 ORDER BY created_at ASC`, planID)
+		`SELECT url, title, start_line, end_line, content, embedding
+		 FROM knowledge_chunks WHERE kb_id = $1 ORDER BY id`, kbID)
 	if err != nil {
-		return nil, fmt.Errorf("list plan steps: %w", err)
+		return nil, fmt.Errorf("list knowledge chunks %s: %w", kbID, err)
 	}
 	defer rows.Close()
 
-	var steps []plan.Step
+	var result []retrieval.Chunk
 	for rows.Next() {
-		st, err := scanPlanStep(rows)
-		if err != nil {
-			return nil, err
+		var c retrieval.Chunk
+		if err := rows.Scan(&c.Path, &c.Title, &c.StartLine, &c.EndLine, &c.Content, &c.Embedding); err != nil {
+			return nil, fmt.Errorf("scan knowledge chunk: %w", err)
 		}
-		steps = append(steps, st)
+		result = append(result, c)
 	}
-	return steps, rows.Err()
+	return result, rows.Err()
 }
 
-func (s *Store) UpdatePlanStepStatus(ctx context.Context, stepID string, status plan.StepStatus, runID, errMsg string) error {
-	tag, err := s.pool.Exec(ctx,
-		`UPDATE plan_steps SET status = $2, run_id = CASE WHEN $3 = '' THEN run_id ELSE $3::uuid END, error = $4
-		 WHERE id = $1`,
-		stepID, string(status), runID, errMsg)
+// DeleteKnowledgeBase removes a knowledge base and its chunks.
+func (s *Store) DeleteKnowledgeBase(ctx context.Context, id string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM knowledge_bases WHERE id = $1`, id)
 	if err != nil {
-		return fmt.Errorf("update plan step status %s: %w", stepID, err)
+		return fmt.Errorf("delete knowledge base %s: %w", id, err)
 	}
 	if tag.RowsAffected() == 0 {
-		return fmt.Errorf("update plan step status %s: %w", stepID, domain.ErrNotFound)
+		return fmt.Errorf("delete knowledge base %s: %w", id, domain.ErrNotFound)
 	}
 	return nil
 }
 
-func (s *Store) GetPlanStepByRunID(ctx context.Context, runID string) (*plan.Step, error) {
-	row := s.pool.QueryRow(ctx,
-		`SELECT id, plan_id, task_id, agent_id, policy_profile, deliver_mode, depends_on, status, run_id, round, error, created_at, updated_at
-		 FROM plan_steps WHERE run_id = $1`, runID)
+// --- Feature Flags ---
 
-	st, err := scanPlanStep(row)
+// ListFeatureFlags returns the feature flag overrides visible to the
+// session, ordered by key and scope.
+func (s *Store) ListFeatureFlags(ctx context.Context) ([]featureflag.Flag, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT key, COALESCE(tenant_id, ''), COALESCE(project_id::text, ''), enabled, updated_at
+		 FROM feature_flags ORDER BY key, tenant_id NULLS FIRST, project_id NULLS FIRST`)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, fmt.Errorf("get plan step by run %s: %w", runID, domain.ErrNotFound)
+		return nil, fmt.Errorf("list feature flags: %w", err)
+	}
+	defer rows.Close()
+
+	var result []featureflag.Flag
+	for rows.Next() {
+		var f featureflag.Flag
+		if err := rows.Scan(&f.Key, &f.TenantID, &f.ProjectID, &f.Enabled, &f.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan feature flag: %w", err)
 		}
-		return nil, fmt.Errorf("get plan step by run %s: %w", runID, err)
+		result = append(result, f)
 	}
-	return &st, nil
+	return result, rows.Err()
 }
 
-func (s *Store) UpdatePlanStepRound(ctx context.Context, stepID string, round int) error {
+// SetFeatureFlag creates or replaces the override of a flag for its scope.
+func (s *Store) SetFeatureFlag(ctx context.Context, f *featureflag.Flag) error {
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO feature_flags (key, tenant_id, project_id, enabled)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (key, tenant_id, project_id)
+		 DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = now()
+		 RETURNING updated_at`,
+		string(f.Key), nullIfEmpty(f.TenantID), nullIfEmpty(f.ProjectID), f.Enabled,
+	).Scan(&f.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("set feature flag %s: %w", f.ID(), err)
+	}
+	return nil
+}
+
+// DeleteFeatureFlag removes the override of a flag for a scope.
+func (s *Store) DeleteFeatureFlag(ctx context.Context, key featureflag.Key, tenantID, projectID string) error {
 	tag, err := s.pool.Exec(ctx,
-		`UPDATE plan_steps SET round = $2 WHERE id = $1`,
-		stepID, round)
+		`DELETE FROM feature_flags
+		 WHERE key = $1 AND tenant_id IS NOT DISTINCT FROM $2 AND project_id IS NOT DISTINCT FROM $3::uuid`,
+		string(key), nullIfEmpty(tenantID), nullIfEmpty(projectID))
+	id := featureflag.ID(key, tenantID, projectID)
 	if err != nil {
-		return fmt.Errorf("update plan step round %s: %w", stepID, err)
+		return fmt.Errorf("delete feature flag %s: %w", id, err)
 	}
 	if tag.RowsAffected() == 0 {
-		return fmt.Errorf("update plan step round %s: %w", stepID, domain.ErrNotFound)
+		return fmt.Errorf("delete feature flag %s: %w", id, domain.ErrNotFound)
 	}
 	return nil
 }
 
-// --- Scanners ---
+// --- Issue Runs ---
 
-type scannable interface {
-	Scan(dest ...any) error
+const issueRunColumns = `id, project_id, provider, issue_number, issue_url, COALESCE(task_id::text, ''),
+	COALESCE(run_id::text, ''), comment_id, status, pr_url, error, created_at, updated_at`
+
+func scanIssueRun(row pgx.Row) (issuerun.IssueRun, error) {
+	var r issuerun.IssueRun
+	err := row.Scan(&r.ID, &r.ProjectID, &r.Provider, &r.IssueNumber, &r.IssueURL, &r.TaskID,
+		&r.RunID, &r.CommentID, &r.Status, &r.PRURL, &r.Error, &r.CreatedAt, &r.UpdatedAt)
+	return r, err
 }
 
-func scanAgent(row scannable) (agent.Agent, error) {
-	var a agent.Agent
-	var configJSON []byte
-	err := row.Scan(&a.ID, &a.ProjectID, &a.Name, &a.Backend, &a.Status, &configJSON, &a.Version, &a.CreatedAt, &a.UpdatedAt)
+// CreateIssueRun stores an issue run. A project has at most one running
+// issue run per issue; a second one fails with domain.ErrConflict.
+func (s *Store) CreateIssueRun(ctx context.Context, r *issuerun.IssueRun) error {
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO issue_runs (project_id, provider, issue_number, issue_url, task_id, run_id, comment_id, status, pr_url, error)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		 RETURNING id, created_at, updated_at`,
+		r.ProjectID, r.Provider, r.IssueNumber, r.IssueURL, nullIfEmpty(r.TaskID), nullIfEmpty(r.RunID),
+		r.CommentID, string(r.Status), r.PRURL, r.Error,
+	).Scan(&r.ID, &r.CreatedAt, &r.UpdatedAt)
 	if err != nil {
-		return a, err
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("create issue run for issue %d: %w", r.IssueNumber, domain.ErrConflict)
+		}
+		return fmt.Errorf("create issue run: %w", err)
 	}
-	if configJSON != nil {
-		if err := json.Unmarshal(configJSON, &a.Config); err != nil {
-			return a, fmt.Errorf("unmarshal agent config: %w", err)
+	return nil
+}
+
+// UpdateIssueRun stores the task, run, comment and outcome of an issue run.
+func (s *Store) UpdateIssueRun(ctx context.Context, r *issuerun.IssueRun) error {
+	err := s.pool.QueryRow(ctx,
+		`UPDATE issue_runs SET task_id = $2, run_id = $3, comment_id = $4, status = $5, pr_url = $6, error = $7
+		 WHERE id = $1
+		 RETURNING updated_at`,
+		r.ID, nullIfEmpty(r.TaskID), nullIfEmpty(r.RunID), r.CommentID, string(r.Status), r.PRURL, r.Error,
+	).Scan(&r.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("update issue run %s: %w", r.ID, domain.ErrNotFound)
 		}
+		return fmt.Errorf("update issue run %s: %w", r.ID, err)
 	}
-	return a, nil
+	return nil
 }
 
-func scanProject(row scannable) (project.Project, error) {
-	var p project.Project
-	var configJSON []byte
-	err := row.Scan(&p.ID, &p.Name, &p.Description, &p.RepoURL, &p.Provider, &p.WorkspacePath, &configJSON, &p.Version, &p.CreatedAt, &p.UpdatedAt)
+// GetIssueRunByRun returns the issue run that started a run.
+func (s *Store) GetIssueRunByRun(ctx context.Context, runID string) (*issuerun.IssueRun, error) {
+	r, err := scanIssueRun(s.pool.QueryRow(ctx, `SELECT `+issueRunColumns+` FROM issue_runs WHERE run_id = $1`, runID))
 	if err != nil {
-		return p, err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get issue run of run %s: %w", runID, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get issue run of run %s: %w", runID, err)
 	}
-	if configJSON != nil {
-		if err := json.Unmarshal(configJSON, &p.Config); err != nil {
-			return p, fmt.Errorf("unmarshal config: %w", err)
+	return &r, nil
+}
+
+// ListIssueRuns returns the issue runs of a project, newest first.
+func (s *Store) ListIssueRuns(ctx context.Context, projectID string) ([]issuerun.IssueRun, error) {
+	rows, err := s.reader(ctx).Query(ctx,
+		`SELECT `+issueRunColumns+` FROM issue_runs WHERE project_id = $1 ORDER BY created_at DESC`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list issue runs: %w", err)
+	}
+	defer rows.Close()
+
+	var result []issuerun.IssueRun
+	for rows.Next() {
+		r, err := scanIssueRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan issue run: %w", err)
 		}
+		result = append(result, r)
 	}
-	return p, nil
+	return result, rows.Err()
 }
 
-func scanRun(row scannable) (run.Run, error) {
-	var r run.Run
-	err := row.Scan(
-		&r.ID, &r.TaskID, &r.AgentID, &r.ProjectID, &r.TeamID, &r.PolicyProfile,
-		&r.ExecMode, &r.DeliverMode, &r.Status, &r.StepCount, &r.CostUSD, &r.Output, &r.Error,
-		&r.Version, &r.StartedAt, &r.CompletedAt, &r.CreatedAt, &r.UpdatedAt,
-	)
-	return r, err
+// --- Pull Request Commands ---
+
+const commandRunColumns = `id, project_id, provider, pr_number, comment_id, branch, command, args, author,
+	COALESCE(run_id::text, ''), reply_id, status, result, error, created_at, updated_at`
+
+func scanCommandRun(row pgx.Row) (chatops.CommandRun, error) {
+	var c chatops.CommandRun
+	err := row.Scan(&c.ID, &c.ProjectID, &c.Provider, &c.PRNumber, &c.CommentID, &c.Branch, &c.Command, &c.Args, &c.Author,
+		&c.RunID, &c.ReplyID, &c.Status, &c.Result, &c.Error, &c.CreatedAt, &c.UpdatedAt)
+	return c, err
 }
 
-func scanTask(row scannable) (task.Task, error) {
-	var t task.Task
-	var agentID *string
-	var resultJSON []byte
-	err := row.Scan(&t.ID, &t.ProjectID, &agentID, &t.Title, &t.Prompt, &t.Status, &resultJSON, &t.CostUSD, &t.Version, &t.CreatedAt, &t.UpdatedAt)
+// CreateCommandRun stores a pull request command. A comment runs at most
+// one command; a second one fails with domain.ErrConflict.
+func (s *Store) CreateCommandRun(ctx context.Context, c *chatops.CommandRun) error {
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO pr_commands (project_id, provider, pr_number, comment_id, branch, command, args, author, run_id, reply_id, status, result, error)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		 RETURNING id, created_at, updated_at`,
+		c.ProjectID, c.Provider, c.PRNumber, c.CommentID, c.Branch, string(c.Command), c.Args, c.Author,
+		nullIfEmpty(c.RunID), c.ReplyID, string(c.Status), c.Result, c.Error,
+	).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
 	if err != nil {
-		return t, err
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("create command for comment %s: %w", c.CommentID, domain.ErrConflict)
+		}
+		return fmt.Errorf("create command: %w", err)
 	}
-	if agentID != nil {
-		t.AgentID = *agentID
+	return nil
+}
+
+// UpdateCommandRun stores the run, reply and outcome of a pull request command.
+func (s *Store) UpdateCommandRun(ctx context.Context, c *chatops.CommandRun) error {
+	err := s.pool.QueryRow(ctx,
+		`UPDATE pr_commands SET run_id = $2, reply_id = $3, status = $4, result = $5, error = $6
+		 WHERE id = $1
+		 RETURNING updated_at`,
+		c.ID, nullIfEmpty(c.RunID), c.ReplyID, string(c.Status), c.Result, c.Error,
+	).Scan(&c.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("update command %s: %w", c.ID, domain.ErrNotFound)
+		}
+		return fmt.Errorf("update command %s: %w", c.ID, err)
 	}
-	if resultJSON != nil {
-		var r task.Result
-		if err := json.Unmarshal(resultJSON, &r); err != nil {
-			return t, fmt.Errorf("unmarshal result: %w", err)
+	return nil
+}
+
+// GetCommandRunByRun returns the pull request command that started a run.
+func (s *Store) GetCommandRunByRun(ctx context.Context, runID string) (*chatops.CommandRun, error) {
+	c, err := scanCommandRun(s.pool.QueryRow(ctx, `SELECT `+commandRunColumns+` FROM pr_commands WHERE run_id = $1`, runID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get command of run %s: %w", runID, domain.ErrNotFound)
 		}
-		t.Result = &r
+		return nil, fmt.Errorf("get command of run %s: %w", runID, err)
 	}
-	return t, nil
+	return &c, nil
 }
 
-func scanTeam(row scannable) (agent.Team, error) {
-	var t agent.Team
-	err := row.Scan(&t.ID, &t.ProjectID, &t.Name, &t.Protocol, &t.Status, &t.Version, &t.CreatedAt, &t.UpdatedAt)
-	return t, err
+// ListCommandRuns returns the pull request commands of a project, newest first.
+func (s *Store) ListCommandRuns(ctx context.Context, projectID string) ([]chatops.CommandRun, error) {
+	rows, err := s.reader(ctx).Query(ctx,
+		`SELECT `+commandRunColumns+` FROM pr_commands WHERE project_id = $1 ORDER BY created_at DESC`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list commands: %w", err)
+	}
+	defer rows.Close()
+
+	var result []chatops.CommandRun
+	for rows.Next() {
+		c, err := scanCommandRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan command: %w", err)
+		}
+		result = append(result, c)
+	}
+	return result, rows.Err()
 }
 
-func scanPlan(row scannable) (plan.ExecutionPlan, error) {
-	var p plan.ExecutionPlan
-	err := row.Scan(&p.ID, &p.ProjectID, &p.TeamID, &p.Name, &p.Description, &p.Protocol, &p.Status,
-		&p.MaxParallel, &p.Version, &p.CreatedAt, &p.UpdatedAt)
-	return p, err
+// --- Run Deliveries ---
+
+const runDeliveryColumns = `run_id, project_id, COALESCE(task_id::text, ''), mode, branch, commit_sha, pr_url, status,
+	check_name, check_url, detail, COALESCE(follow_up_task_id::text, ''), delivered_at, failed_at`
+
+func scanRunDelivery(row pgx.Row) (seatbelt.Delivery, error) {
+	var d seatbelt.Delivery
+	err := row.Scan(&d.RunID, &d.ProjectID, &d.TaskID, &d.Mode, &d.Branch, &d.CommitSHA, &d.PRURL, &d.Status,
+		&d.Check, &d.CheckURL, &d.Detail, &d.FollowUpTaskID, &d.DeliveredAt, &d.FailedAt)
+	return d, err
 }
 
-func scanPlanStep(row scannable) (plan.Step, error) {
-	var st plan.Step
-	var runID *string
-	err := row.Scan(&st.ID, &st.PlanID, &st.TaskID, &st.AgentID, &st.PolicyProfile, &st.DeliverMode,
-		&st.DependsOn, &st.Status, &runID, &st.Round, &st.Error, &st.CreatedAt, &st.UpdatedAt)
-	if runID != nil {
-		st.RunID = *runID
+// SaveRunDelivery records where a run delivered its changes, replacing an
+// earlier delivery of the run and its downstream state.
+func (s *Store) SaveRunDelivery(ctx context.Context, d *seatbelt.Delivery) error {
+	if d.Status == "" {
+		d.Status = seatbelt.StatusDelivered
+	}
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO run_deliveries (run_id, project_id, task_id, mode, branch, commit_sha, pr_url, status)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 ON CONFLICT (run_id) DO UPDATE SET mode = EXCLUDED.mode, branch = EXCLUDED.branch,
+		     commit_sha = EXCLUDED.commit_sha, pr_url = EXCLUDED.pr_url, status = EXCLUDED.status,
+		     check_name = '', check_url = '', detail = '', follow_up_task_id = NULL,
+		     delivered_at = now(), failed_at = NULL
+		 RETURNING delivered_at`,
+		d.RunID, d.ProjectID, nullIfEmpty(d.TaskID), string(d.Mode), d.Branch, d.CommitSHA, d.PRURL, string(d.Status),
+	).Scan(&d.DeliveredAt)
+	if err != nil {
+		return fmt.Errorf("save delivery of run %s: %w", d.RunID, err)
+	}
+	return nil
+}
+
+// UpdateRunDelivery stores the downstream state of a delivery.
+func (s *Store) UpdateRunDelivery(ctx context.Context, d *seatbelt.Delivery) error {
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE run_deliveries SET status = $2, check_name = $3, check_url = $4, detail = $5,
+		     follow_up_task_id = $6, failed_at = $7
+		 WHERE run_id = $1`,
+		d.RunID, string(d.Status), d.Check, d.CheckURL, d.Detail, nullIfEmpty(d.FollowUpTaskID), d.FailedAt)
+	if err != nil {
+		return fmt.Errorf("update delivery of run %s: %w", d.RunID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("update delivery of run %s: %w", d.RunID, domain.ErrNotFound)
 	}
-	return st, err
+	return nil
 }
 
-// --- Context Packs ---
+// GetRunDelivery returns the delivery of a run.
+func (s *Store) GetRunDelivery(ctx context.Context, runID string) (*seatbelt.Delivery, error) {
+	d, err := scanRunDelivery(s.pool.QueryRow(ctx, `SELECT `+runDeliveryColumns+` FROM run_deliveries WHERE run_id = $1`, runID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get delivery of run %s: %w", runID, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get delivery of run %s: %w", runID, err)
+	}
+	return &d, nil
+}
 
-// CreateContextPack inserts a context pack and its entries in a transaction.
-func (s *Store) CreateContextPack(ctx context.Context, pack *cfcontext.ContextPack) error {
-	tx, err := s.pool.Begin(ctx)
+// ListRunDeliveries returns the latest deliveries of a project, newest
+// first.
+func (s *Store) ListRunDeliveries(ctx context.Context, projectID string, limit int) ([]seatbelt.Delivery, error) {
+	rows, err := s.reader(ctx).Query(ctx,
+		`SELECT `+runDeliveryColumns+` FROM run_deliveries WHERE project_id = $1 ORDER BY delivered_at DESC LIMIT $2`,
+		projectID, limit)
 	if err != nil {
-		return fmt.Errorf("begin tx: %w", err)
+		return nil, fmt.Errorf("list run deliveries: %w", err)
 	}
-	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
+	defer rows.Close()
 
-	err = tx.QueryRow(ctx,
-		`INSERT INTO context_packs (task_id, project_id, token_budget, tokens_used)
-		 VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
-		pack.TaskID, pack.ProjectID, pack.TokenBudget, pack.TokensUsed,
-	).Scan(&pack.ID, &pack.CreatedAt)
+	var result []seatbelt.Delivery
+	for rows.Next() {
+		d, err := scanRunDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan run delivery: %w", err)
+		}
+		result = append(result, d)
+	}
+	return result, rows.Err()
+}
+
+// --- Run CI Statuses ---
+
+// GetRunCIStatus returns the latest CI check of the commit a run delivered.
+func (s *Store) GetRunCIStatus(ctx context.Context, runID string) (*ci.Status, error) {
+	st := ci.Status{RunID: runID}
+	var pipelines []byte
+	err := s.pool.QueryRow(ctx,
+		`SELECT project_id, provider, branch, commit_sha, state, pipelines, error, checked_at
+		 FROM run_ci_statuses WHERE run_id = $1`, runID,
+	).Scan(&st.ProjectID, &st.Provider, &st.Branch, &st.SHA, &st.State, &pipelines, &st.Error, &st.CheckedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get ci status of run %s: %w", runID, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get ci status of run %s: %w", runID, err)
+	}
+	if err := json.Unmarshal(pipelines, &st.Pipelines); err != nil {
+		return nil, fmt.Errorf("unmarshal ci pipelines: %w", err)
+	}
+	return &st, nil
+}
+
+// SaveRunCIStatus creates or replaces the CI check of a run.
+func (s *Store) SaveRunCIStatus(ctx context.Context, st *ci.Status) error {
+	if st.Pipelines == nil {
+		st.Pipelines = []ci.Pipeline{}
+	}
+	pipelines, err := json.Marshal(st.Pipelines)
+	if err != nil {
+		return fmt.Errorf("marshal ci pipelines: %w", err)
+	}
+	_, err = s.pool.Exec(ctx,
+		`INSERT INTO run_ci_statuses (run_id, project_id, provider, branch, commit_sha, state, pipelines, error, checked_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 ON CONFLICT (run_id) DO UPDATE SET provider = EXCLUDED.provider, branch = EXCLUDED.branch,
+		     commit_sha = EXCLUDED.commit_sha, state = EXCLUDED.state, pipelines = EXCLUDED.pipelines,
+		     error = EXCLUDED.error, checked_at = EXCLUDED.checked_at`,
+		st.RunID, st.ProjectID, st.Provider, st.Branch, st.SHA, string(st.State), pipelines, st.Error, st.CheckedAt)
+	if err != nil {
+		return fmt.Errorf("save ci status of run %s: %w", st.RunID, err)
+	}
+	return nil
+}
+
+// --- Poll Cursors ---
+
+// GetPollCursor returns how far a source of a project has been polled.
+func (s *Store) GetPollCursor(ctx context.Context, projectID string, source poll.Source) (*poll.Cursor, error) {
+	c := poll.Cursor{ProjectID: projectID, Source: source}
+	err := s.pool.QueryRow(ctx,
+		`SELECT position, etag, error, polled_at FROM poll_cursors WHERE project_id = $1 AND source = $2`,
+		projectID, string(source),
+	).Scan(&c.Position, &c.ETag, &c.Error, &c.PolledAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get poll cursor %s/%s: %w", projectID, source, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get poll cursor %s/%s: %w", projectID, source, err)
+	}
+	return &c, nil
+}
+
+// SetPollCursor creates or replaces the cursor of a source and sets its
+// poll time.
+func (s *Store) SetPollCursor(ctx context.Context, c *poll.Cursor) error {
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO poll_cursors (project_id, source, position, etag, error)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (project_id, source)
+		 DO UPDATE SET position = EXCLUDED.position, etag = EXCLUDED.etag, error = EXCLUDED.error, polled_at = now()
+		 RETURNING polled_at`,
+		c.ProjectID, string(c.Source), c.Position, c.ETag, c.Error,
+	).Scan(&c.PolledAt)
+	if err != nil {
+		return fmt.Errorf("set poll cursor %s/%s: %w", c.ProjectID, c.Source, err)
+	}
+	return nil
+}
+
+// ListPollCursors returns the cursors of a project by source.
+func (s *Store) ListPollCursors(ctx context.Context, projectID string) ([]poll.Cursor, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT project_id, source, position, etag, error, polled_at FROM poll_cursors
+		 WHERE project_id = $1 ORDER BY source`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list poll cursors: %w", err)
+	}
+	defer rows.Close()
+
+	var result []poll.Cursor
+	for rows.Next() {
+		var c poll.Cursor
+		if err := rows.Scan(&c.ProjectID, &c.Source, &c.Position, &c.ETag, &c.Error, &c.PolledAt); err != nil {
+			return nil, fmt.Errorf("scan poll cursor: %w", err)
+		}
+		result = append(result, c)
+	}
+	return result, rows.Err()
+}
+
+// --- Notifications ---
+
+const notificationRuleColumns = `id, project_id, name, events, kind, config, mentions, digest, enabled, created_at, updated_at`
+
+func scanNotificationRule(row pgx.Row) (notification.Rule, error) {
+	var r notification.Rule
+	var configJSON []byte
+	if err := row.Scan(&r.ID, &r.ProjectID, &r.Name, &r.Events, &r.Kind, &configJSON, &r.Mentions, &r.Digest,
+		&r.Enabled, &r.CreatedAt, &r.UpdatedAt); err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(configJSON, &r.Config); err != nil {
+		return r, fmt.Errorf("unmarshal notification rule config: %w", err)
+	}
+	return r, nil
+}
+
+// CreateNotificationRule stores a routing rule. Names are unique per
+// project; a duplicate fails with domain.ErrConflict.
+func (s *Store) CreateNotificationRule(ctx context.Context, r *notification.Rule) error {
+	configJSON, err := json.Marshal(externalIDs(r.Config))
+	if err != nil {
+		return fmt.Errorf("marshal notification rule config: %w", err)
+	}
+	err = s.pool.QueryRow(ctx,
+		`INSERT INTO notification_rules (project_id, name, events, kind, config, mentions, digest, enabled)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 RETURNING id, created_at, updated_at`,
+		r.ProjectID, r.Name, labelsOrEmpty(r.Events), string(r.Kind), configJSON, labelsOrEmpty(r.Mentions), r.Digest, r.Enabled,
+	).Scan(&r.ID, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("create notification rule %s: %w", r.Name, domain.ErrConflict)
+		}
+		return fmt.Errorf("create notification rule: %w", err)
+	}
+	return nil
+}
+
+// GetNotificationRule returns a routing rule by ID.
+func (s *Store) GetNotificationRule(ctx context.Context, id string) (*notification.Rule, error) {
+	r, err := scanNotificationRule(s.pool.QueryRow(ctx,
+		`SELECT `+notificationRuleColumns+` FROM notification_rules WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get notification rule %s: %w", id, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get notification rule %s: %w", id, err)
+	}
+	return &r, nil
+}
+
+// ListNotificationRules returns the routing rules of a project by name.
+func (s *Store) ListNotificationRules(ctx context.Context, projectID string) ([]notification.Rule, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT `+notificationRuleColumns+` FROM notification_rules WHERE project_id = $1 ORDER BY name`, projectID)
 	if err != nil {
-		return fmt.Errorf("insert context_pack: %w", err)
+		return nil, fmt.Errorf("list notification rules: %w", err)
 	}
+	defer rows.Close()
 
-	for i := range pack.Entries {
-		e := &pack.Entries[i]
-		e.PackID = pack.ID
-		err = tx.QueryRow(ctx,
-			`INSERT INTO context_entries (pack_id, kind, path, content, tokens, priority)
-			 VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
-			e.PackID, e.Kind, e.Path, e.Content, e.Tokens, e.Priority,
-		).Scan(&e.ID)
+	var result []notification.Rule
+	for rows.Next() {
+		r, err := scanNotificationRule(rows)
 		if err != nil {
-			return fmt.Errorf("insert context_entry %d: %w", i, err)
+			return nil, fmt.Errorf("scan notification rule: %w", err)
 		}
+		result = append(result, r)
 	}
-
-	return tx.Commit(ctx)
+	return result, rows.Err()
 }
 
-// GetContextPack returns a context pack by ID with all entries.
-func (s *Store) GetContextPack(ctx context.Context, id string) (*cfcontext.ContextPack, error) {
-	var p cfcontext.ContextPack
-	err := s.pool.QueryRow(ctx,
-		`SELECT id, task_id, project_id, token_budget, tokens_used, created_at
-		 FROM context_packs WHERE id = $1`, id,
-	).Scan(&p.ID, &p.TaskID, &p.ProjectID, &p.TokenBudget, &p.TokensUsed, &p.CreatedAt)
+// UpdateNotificationRule replaces a routing rule's fields except its
+// project.
+func (s *Store) UpdateNotificationRule(ctx context.Context, r *notification.Rule) error {
+	configJSON, err := json.Marshal(externalIDs(r.Config))
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, domain.ErrNotFound
+		return fmt.Errorf("marshal notification rule config: %w", err)
+	}
+	err = s.pool.QueryRow(ctx,
+		`UPDATE notification_rules SET name = $2, events = $3, kind = $4, config = $5, mentions = $6, digest = $7,
+		     enabled = $8, updated_at = now()
+		 WHERE id = $1
+		 RETURNING updated_at`,
+		r.ID, r.Name, labelsOrEmpty(r.Events), string(r.Kind), configJSON, labelsOrEmpty(r.Mentions), r.Digest, r.Enabled,
+	).Scan(&r.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return fmt.Errorf("update notification rule %s: %w", r.ID, domain.ErrNotFound)
+		case errors.As(err, &pgErr) && pgErr.Code == "23505":
+			return fmt.Errorf("update notification rule %s: %w", r.ID, domain.ErrConflict)
 		}
-		return nil, fmt.Errorf("get context_pack: %w", err)
+		return fmt.Errorf("update notification rule %s: %w", r.ID, err)
 	}
+	return nil
+}
 
-	entries, err := s.loadContextEntries(ctx, p.ID)
+// DeleteNotificationRule removes a routing rule and its pending
+// notifications.
+func (s *Store) DeleteNotificationRule(ctx context.Context, id string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM notification_rules WHERE id = $1`, id)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("delete notification rule %s: %w", id, err)
 	}
-	p.Entries = entries
-	return &p, nil
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete notification rule %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
 }
 
-// GetContextPackByTask returns the context pack for a task.
-func (s *Store) GetContextPackByTask(ctx context.Context, taskID string) (*cfcontext.ContextPack, error) {
-	var p cfcontext.ContextPack
+// GetNotificationSettings returns the quiet hours and digest time of a
+// project.
+func (s *Store) GetNotificationSettings(ctx context.Context, projectID string) (*notification.Settings, error) {
+	st := notification.Settings{ProjectID: projectID}
 	err := s.pool.QueryRow(ctx,
-		`SELECT id, task_id, project_id, token_budget, tokens_used, created_at
-		 FROM context_packs WHERE task_id = $1 ORDER BY created_at DESC LIMIT 1`, taskID,
-	).Scan(&p.ID, &p.TaskID, &p.ProjectID, &p.TokenBudget, &p.TokensUsed, &p.CreatedAt)
+		`SELECT quiet_start, quiet_end, timezone, digest_at, updated_at FROM notification_settings WHERE project_id = $1`,
+		projectID,
+	).Scan(&st.QuietStart, &st.QuietEnd, &st.Timezone, &st.DigestAt, &st.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, domain.ErrNotFound
+			return nil, fmt.Errorf("get notification settings %s: %w", projectID, domain.ErrNotFound)
 		}
-		return nil, fmt.Errorf("get context_pack by task: %w", err)
+		return nil, fmt.Errorf("get notification settings %s: %w", projectID, err)
 	}
+	return &st, nil
+}
 
-	entries, err := s.loadContextEntries(ctx, p.ID)
+// SetNotificationSettings creates or replaces the settings of a project.
+func (s *Store) SetNotificationSettings(ctx context.Context, st *notification.Settings) error {
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO notification_settings (project_id, quiet_start, quiet_end, timezone, digest_at)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (project_id)
+		 DO UPDATE SET quiet_start = EXCLUDED.quiet_start, quiet_end = EXCLUDED.quiet_end,
+		     timezone = EXCLUDED.timezone, digest_at = EXCLUDED.digest_at, updated_at = now()
+		 RETURNING updated_at`,
+		st.ProjectID, st.QuietStart, st.QuietEnd, st.Timezone, st.DigestAt,
+	).Scan(&st.UpdatedAt)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("set notification settings %s: %w", st.ProjectID, err)
 	}
-	p.Entries = entries
-	return &p, nil
+	return nil
 }
 
-// DeleteContextPack removes a context pack and its entries (CASCADE).
-func (s *Store) DeleteContextPack(ctx context.Context, id string) error {
-	tag, err := s.pool.Exec(ctx, `DELETE FROM context_packs WHERE id = $1`, id)
+// EnqueueNotification adds a notification to the outbox.
+func (s *Store) EnqueueNotification(ctx context.Context, p *notification.Pending) error {
+	msgJSON, err := json.Marshal(p.Message)
 	if err != nil {
-		return fmt.Errorf("delete context_pack: %w", err)
+		return fmt.Errorf("marshal notification: %w", err)
 	}
-	if tag.RowsAffected() == 0 {
-		return domain.ErrNotFound
+	err = s.pool.QueryRow(ctx,
+		`INSERT INTO notification_outbox (rule_id, project_id, message, digest, send_at)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING id, created_at`,
+		p.RuleID, p.ProjectID, msgJSON, p.Digest, p.SendAt,
+	).Scan(&p.ID, &p.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("enqueue notification: %w", err)
 	}
 	return nil
 }
 
-func (s *Store) loadContextEntries(ctx context.Context, packID string) ([]cfcontext.ContextEntry, error) {
+// ListDueNotifications returns the oldest notifications to be sent by
+// until, of all projects visible to the request.
+func (s *Store) ListDueNotifications(ctx context.Context, until time.Time, limit int) ([]notification.Pending, error) {
 	rows, err := s.pool.Query(ctx,
-		`SELECT id, pack_id, kind, path, content, tokens, priority
-		 FROM context_entries WHERE pack_id = $1 ORDER BY priority DESC`, packID)
+		`SELECT id, rule_id, project_id, message, digest, send_at, attempts, last_error, created_at
+		 FROM notification_outbox WHERE send_at <= $1 ORDER BY send_at, created_at LIMIT $2`, until, limit)
 	if err != nil {
-		return nil, fmt.Errorf("load context_entries: %w", err)
+		return nil, fmt.Errorf("list due notifications: %w", err)
 	}
 	defer rows.Close()
 
-	var entries []cfcontext.ContextEntry
+	var result []notification.Pending
 	for rows.Next() {
-		var e cfcontext.ContextEntry
-		if err := rows.Scan(&e.ID, &e.PackID, &e.Kind, &e.Path, &e.Content, &e.Tokens, &e.Priority); err != nil {
-			return nil, fmt.Errorf("scan context_entry: %w", err)
+		var p notification.Pending
+		var msgJSON []byte
+		if err := rows.Scan(&p.ID, &p.RuleID, &p.ProjectID, &msgJSON, &p.Digest, &p.SendAt, &p.Attempts,
+			&p.LastError, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan notification: %w", err)
 		}
-		entries = append(entries, e)
+		if err := json.Unmarshal(msgJSON, &p.Message); err != nil {
+			return nil, fmt.Errorf("unmarshal notification %s: %w", p.ID, err)
+		}
+		result = append(result, p)
 	}
-	return entries, rows.Err()
+	return result, rows.Err()
 }
 
-// --- Shared Context ---
+// RetryNotification records a failed attempt to send a notification and
+// when to try again.
+func (s *Store) RetryNotification(ctx context.Context, id string, sendAt time.Time, errMsg string) error {
+	_, err := s.pool.Exec(ctx,
+		`UPDATE notification_outbox SET attempts = attempts + 1, send_at = $2, last_error = $3 WHERE id = $1`,
+		id, sendAt, errMsg)
+	if err != nil {
+		return fmt.Errorf("retry notification %s: %w", id, err)
+	}
+	return nil
+}
 
-// CreateSharedContext inserts a new shared context for a team.
-func (s *Store) CreateSharedContext(ctx context.Context, sc *cfcontext.SharedContext) error {
-	return s.pool.QueryRow(ctx,
-		`INSERT INTO shared_contexts (team_id, project_id) VALUES ($1, $2)
-		 RETURNING id, version, created_at, updated_at`,
-		sc.TeamID, sc.ProjectID,
-	).Scan(&sc.ID, &sc.Version, &sc.CreatedAt, &sc.UpdatedAt)
+// DeleteNotifications removes sent or dropped notifications from the
+// outbox.
+func (s *Store) DeleteNotifications(ctx context.Context, ids []string) error {
+	if _, err := s.pool.Exec(ctx, `DELETE FROM notification_outbox WHERE id = ANY($1)`, ids); err != nil {
+		return fmt.Errorf("delete notifications: %w", err)
+	}
+	return nil
 }
 
-// GetSharedContext returns a shared context by ID with all items.
-func (s *Store) GetSharedContext(ctx context.Context, id string) (*cfcontext.SharedContext, error) {
-	var sc cfcontext.SharedContext
-	err := s.pool.QueryRow(ctx,
-		`SELECT id, team_id, project_id, version, created_at, updated_at
-		 FROM shared_contexts WHERE id = $1`, id,
-	).Scan(&sc.ID, &sc.TeamID, &sc.ProjectID, &sc.Version, &sc.CreatedAt, &sc.UpdatedAt)
+func externalIDs(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
+func labelsOrEmpty(labels []string) []string {
+	if labels == nil {
+		return []string{}
+	}
+	return labels
+}
+
+// nullIfEmpty returns nil for empty strings (for nullable UUID columns).
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func (s *Store) loadSharedContextItems(ctx context.Context, sharedID string) ([]cfcontext.SharedContextItem, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT id, shared_id, key, value, author, tokens, created_at
+		 FROM shared_context_items WHERE shared_id = $1 ORDER BY created_at`, sharedID)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, domain.ErrNotFound
+		return nil, fmt.Errorf("load shared_context_items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []cfcontext.SharedContextItem
+	for rows.Next() {
+		var item cfcontext.SharedContextItem
+		if err := rows.Scan(&item.ID, &item.SharedID, &item.Key, &item.Value, &item.Author, &item.Tokens, &item.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan shared_context_item: %w", err)
 		}
-		return nil, fmt.Errorf("get shared_context: %w", err)
+		items = append(items, item)
 	}
+	return items, rows.Err()
+}
 
-	items, err := s.loadSharedContextItems(ctx, sc.ID)
+// --- Replay Sessions ---
+
+// CreateReplaySession stores a replay session.
+func (s *Store) CreateReplaySession(ctx context.Context, rs *replay.Session) error {
+	err := s.pool.QueryRow(ctx,
+		`INSERT INTO replay_sessions (run_id, project_id, position)
+		 VALUES ($1, $2, $3)
+		 RETURNING id, created_at, updated_at`,
+		rs.RunID, rs.ProjectID, rs.Position,
+	).Scan(&rs.ID, &rs.CreatedAt, &rs.UpdatedAt)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("create replay session: %w", err)
 	}
-	sc.Items = items
-	return &sc, nil
+	return nil
 }
 
-// GetSharedContextByTeam returns the shared context for a team.
-func (s *Store) GetSharedContextByTeam(ctx context.Context, teamID string) (*cfcontext.SharedContext, error) {
-	var sc cfcontext.SharedContext
+// GetReplaySession returns a replay session by ID.
+func (s *Store) GetReplaySession(ctx context.Context, id string) (*replay.Session, error) {
+	var rs replay.Session
 	err := s.pool.QueryRow(ctx,
-		`SELECT id, team_id, project_id, version, created_at, updated_at
-		 FROM shared_contexts WHERE team_id = $1`, teamID,
-	).Scan(&sc.ID, &sc.TeamID, &sc.ProjectID, &sc.Version, &sc.CreatedAt, &sc.UpdatedAt)
+		`SELECT id, run_id, project_id, position, created_at, updated_at FROM replay_sessions WHERE id = $1`, id,
+	).Scan(&rs.ID, &rs.RunID, &rs.ProjectID, &rs.Position, &rs.CreatedAt, &rs.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, domain.ErrNotFound
+			return nil, fmt.Errorf("get replay session %s: %w", id, domain.ErrNotFound)
 		}
-		return nil, fmt.Errorf("get shared_context by team: %w", err)
+		return nil, fmt.Errorf("get replay session %s: %w", id, err)
 	}
+	return &rs, nil
+}
 
-	items, err := s.loadSharedContextItems(ctx, sc.ID)
+// UpdateReplaySessionPosition moves a replay session to another event.
+func (s *Store) UpdateReplaySessionPosition(ctx context.Context, id string, position int) error {
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE replay_sessions SET position = $2, updated_at = now() WHERE id = $1`, id, position)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("update replay session %s: %w", id, err)
 	}
-	sc.Items = items
-	return &sc, nil
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("update replay session %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
 }
 
-// AddSharedContextItem inserts a new item and bumps the shared context version.
-func (s *Store) AddSharedContextItem(ctx context.Context, req cfcontext.AddSharedItemRequest) (*cfcontext.SharedContextItem, error) {
-	tx, err := s.pool.Begin(ctx)
+// DeleteReplaySession removes a replay session.
+func (s *Store) DeleteReplaySession(ctx context.Context, id string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM replay_sessions WHERE id = $1`, id)
 	if err != nil {
-		return nil, fmt.Errorf("begin tx: %w", err)
+		return fmt.Errorf("delete replay session %s: %w", id, err)
 	}
-	defer tx.Rollback(ctx) //nolint:errcheck // rollback after commit is a no-op
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete replay session %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
+}
 
-	// Resolve shared context ID from team ID.
-	var sharedID string
-	err = tx.QueryRow(ctx,
-		`SELECT id FROM shared_contexts WHERE team_id = $1`, req.TeamID,
-	).Scan(&sharedID)
+// --- VCS Account Health ---
+
+const vcsAccountHealthColumns = `project_id, account, provider, login, scopes, expires_at, can_push, rate_limit,
+	rate_remaining, rate_reset, status, error, checked_at`
+
+func scanVCSAccountHealth(row pgx.Row) (vcsaccount.Health, error) {
+	var h vcsaccount.Health
+	err := row.Scan(&h.ProjectID, &h.Account, &h.Provider, &h.Login, &h.Scopes, &h.ExpiresAt, &h.CanPush, &h.RateLimit,
+		&h.RateRemaining, &h.RateReset, &h.Status, &h.Error, &h.CheckedAt)
+	return h, err
+}
+
+// GetVCSAccountHealth returns the latest check of a project's git host
+// token.
+func (s *Store) GetVCSAccountHealth(ctx context.Context, projectID string) (*vcsaccount.Health, error) {
+	h, err := scanVCSAccountHealth(s.pool.QueryRow(ctx,
+		`SELECT `+vcsAccountHealthColumns+` FROM vcs_account_health WHERE project_id = $1`, projectID))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, domain.ErrNotFound
+			return nil, fmt.Errorf("get vcs account health %s: %w", projectID, domain.ErrNotFound)
 		}
-		return nil, fmt.Errorf("resolve shared_context: %w", err)
+		return nil, fmt.Errorf("get vcs account health %s: %w", projectID, err)
 	}
+	return &h, nil
+}
 
-	tokens := cfcontext.EstimateTokens(req.Value)
-	var item cfcontext.SharedContextItem
-	err = tx.QueryRow(ctx,
-		`INSERT INTO shared_context_items (shared_id, key, value, author, tokens)
-		 VALUES ($1, $2, $3, $4, $5)
-		 ON CONFLICT (shared_id, key) DO UPDATE SET value = EXCLUDED.value, author = EXCLUDED.author, tokens = EXCLUDED.tokens
-		 RETURNING id, shared_id, key, value, author, tokens, created_at`,
-		sharedID, req.Key, req.Value, req.Author, tokens,
-	).Scan(&item.ID, &item.SharedID, &item.Key, &item.Value, &item.Author, &item.Tokens, &item.CreatedAt)
+// SaveVCSAccountHealth replaces the latest check of a project's git host
+// token.
+func (s *Store) SaveVCSAccountHealth(ctx context.Context, h *vcsaccount.Health) error {
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO vcs_account_health (`+vcsAccountHealthColumns+`)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		 ON CONFLICT (project_id) DO UPDATE SET account = $2, provider = $3, login = $4, scopes = $5, expires_at = $6,
+		     can_push = $7, rate_limit = $8, rate_remaining = $9, rate_reset = $10, status = $11, error = $12, checked_at = $13`,
+		h.ProjectID, h.Account, h.Provider, h.Login, labelsOrEmpty(h.Scopes), h.ExpiresAt, h.CanPush, h.RateLimit,
+		h.RateRemaining, h.RateReset, string(h.Status), h.Error, h.CheckedAt)
 	if err != nil {
-		return nil, fmt.Errorf("upsert shared_context_item: %w", err)
+		return fmt.Errorf("save vcs account health %s: %w", h.ProjectID, err)
 	}
+	return nil
+}
 
-	// Bump version.
-	if _, err := tx.Exec(ctx,
-		`UPDATE shared_contexts SET version = version + 1 WHERE id = $1`, sharedID,
-	); err != nil {
-		return nil, fmt.Errorf("bump shared_context version: %w", err)
+// ListVCSAccountHealth returns the latest checks of all projects' git host
+// tokens by account.
+func (s *Store) ListVCSAccountHealth(ctx context.Context) ([]vcsaccount.Health, error) {
+	rows, err := s.pool.Query(ctx,
+		`SELECT `+vcsAccountHealthColumns+` FROM vcs_account_health ORDER BY account, project_id`)
+	if err != nil {
+		return nil, fmt.Errorf("list vcs account health: %w", err)
 	}
+	defer rows.Close()
 
-	if err := tx.Commit(ctx); err != nil {
-		return nil, fmt.Errorf("commit: %w", err)
+	var result []vcsaccount.Health
+	for rows.Next() {
+		h, err := scanVCSAccountHealth(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan vcs account health: %w", err)
+		}
+		result = append(result, h)
 	}
-	return &item, nil
+	return result, rows.Err()
 }
 
-// DeleteSharedContext removes a shared context and its items (CASCADE).
-func (s *Store) DeleteSharedContext(ctx context.Context, id string) error {
-	tag, err := s.pool.Exec(ctx, `DELETE FROM shared_contexts WHERE id = $1`, id)
+// --- Retrieval Scopes ---
+
+const scopeColumns = `id, tenant_id, name, description, projects, knowledge_bases, created_at, updated_at`
+
+func scanScope(row pgx.Row) (scope.Scope, error) {
+	var sc scope.Scope
+	var projectsJSON, kbsJSON []byte
+	if err := row.Scan(&sc.ID, &sc.TenantID, &sc.Name, &sc.Description, &projectsJSON, &kbsJSON,
+		&sc.CreatedAt, &sc.UpdatedAt); err != nil {
+		return sc, err
+	}
+	if err := json.Unmarshal(projectsJSON, &sc.Projects); err != nil {
+		return sc, fmt.Errorf("unmarshal scope projects: %w", err)
+	}
+	if err := json.Unmarshal(kbsJSON, &sc.KnowledgeBases); err != nil {
+		return sc, fmt.Errorf("unmarshal scope knowledge bases: %w", err)
+	}
+	return sc, nil
+}
+
+// scopeSources marshals the projects and knowledge bases of a scope.
+func scopeSources(sc *scope.Scope) (projects, kbs []byte, err error) {
+	if projects, err = json.Marshal(sourcesOrEmpty(sc.Projects)); err != nil {
+		return nil, nil, fmt.Errorf("marshal scope projects: %w", err)
+	}
+	if kbs, err = json.Marshal(sourcesOrEmpty(sc.KnowledgeBases)); err != nil {
+		return nil, nil, fmt.Errorf("marshal scope knowledge bases: %w", err)
+	}
+	return projects, kbs, nil
+}
+
+func sourcesOrEmpty(s []scope.Source) []scope.Source {
+	if s == nil {
+		return []scope.Source{}
+	}
+	return s
+}
+
+// CreateScope stores a scope. Names are unique per tenant; a duplicate
+// fails with domain.ErrConflict.
+func (s *Store) CreateScope(ctx context.Context, sc *scope.Scope) error {
+	projects, kbs, err := scopeSources(sc)
 	if err != nil {
-		return fmt.Errorf("delete shared_context: %w", err)
+		return err
 	}
-	if tag.RowsAffected() == 0 {
-		return domain.ErrNotFound
+	err = s.pool.QueryRow(ctx,
+		`INSERT INTO scopes (tenant_id, name, description, projects, knowledge_bases)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING id, created_at, updated_at`,
+		sc.TenantID, sc.Name, sc.Description, projects, kbs,
+	).Scan(&sc.ID, &sc.CreatedAt, &sc.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("create scope %s: %w", sc.Name, domain.ErrConflict)
+		}
+		return fmt.Errorf("create scope: %w", err)
 	}
 	return nil
 }
 
-// nullIfEmpty returns nil for empty strings (for nullable UUID columns).
-func nullIfEmpty(s string) *string {
-	if s == "" {
-		return nil
+// GetScope returns a scope by ID.
+func (s *Store) GetScope(ctx context.Context, id string) (*scope.Scope, error) {
+	sc, err := scanScope(s.pool.QueryRow(ctx, `SELECT `+scopeColumns+` FROM scopes WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("get scope %s: %w", id, domain.ErrNotFound)
+		}
+		return nil, fmt.Errorf("get scope %s: %w", id, err)
 	}
-	return &s
+	return &sc, nil
 }
 
-func (s *Store) loadSharedContextItems(ctx context.Context, sharedID string) ([]cfcontext.SharedContextItem, error) {
-	rows, err := s.pool.Query(ctx,
-		`SELECT id, shared_id, key, value, author, tokens, created_at
-		 FROM shared_context_items WHERE shared_id = $1 ORDER BY created_at`, sharedID)
+// ListScopes returns the scopes visible to the session, ordered by tenant
+// and name.
+func (s *Store) ListScopes(ctx context.Context) ([]scope.Scope, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+scopeColumns+` FROM scopes ORDER BY tenant_id, name`)
 	if err != nil {
-		return nil, fmt.Errorf("load shared_context_items: %w", err)
+		return nil, fmt.Errorf("list scopes: %w", err)
 	}
 	defer rows.Close()
 
-	var items []cfcontext.SharedContextItem
+	var result []scope.Scope
 	for rows.Next() {
-		var item cfcontext.SharedContextItem
-		if err := rows.Scan(&item.ID, &item.SharedID, &item.Key, &item.Value, &item.Author, &item.Tokens, &item.CreatedAt); err != nil {
-			return nil, fmt.Errorf("scan shared_context_item: %w", err)
+		sc, err := scanScope(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan scope: %w", err)
 		}
-		items = append(items, item)
+		result = append(result, sc)
 	}
-	return items, rows.Err()
+	return result, rows.Err()
+}
+
+// UpdateScope replaces the name, description and sources of a scope.
+func (s *Store) UpdateScope(ctx context.Context, sc *scope.Scope) error {
+	projects, kbs, err := scopeSources(sc)
+	if err != nil {
+		return err
+	}
+	err = s.pool.QueryRow(ctx,
+		`UPDATE scopes SET name = $2, description = $3, projects = $4, knowledge_bases = $5, updated_at = now()
+		 WHERE id = $1
+		 RETURNING updated_at`,
+		sc.ID, sc.Name, sc.Description, projects, kbs,
+	).Scan(&sc.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return fmt.Errorf("update scope %s: %w", sc.ID, domain.ErrNotFound)
+		case errors.As(err, &pgErr) && pgErr.Code == "23505":
+			return fmt.Errorf("update scope %s: %w", sc.ID, domain.ErrConflict)
+		}
+		return fmt.Errorf("update scope %s: %w", sc.ID, err)
+	}
+	return nil
+}
+
+// DeleteScope removes a scope.
+func (s *Store) DeleteScope(ctx context.Context, id string) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM scopes WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete scope %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete scope %s: %w", id, domain.ErrNotFound)
+	}
+	return nil
 }
@@ -0,0 +1,105 @@
+// Package searxng implements the websearch.Provider interface against a
+// self-hosted SearXNG instance using its JSON search API.
+package searxng
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/port/websearch"
+)
+
+const (
+	providerName = "searxng"
+	defaultLimit = 10
+)
+
+// Provider queries a SearXNG instance via GET /search?format=json.
+type Provider struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewProvider creates a Provider for the SearXNG instance at baseURL.
+func NewProvider(baseURL string) *Provider {
+	return &Provider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+	}
+}
+
+// Name returns "searxng".
+func (p *Provider) Name() string { return providerName }
+
+// Capabilities returns what the SearXNG provider supports.
+func (p *Provider) Capabilities() websearch.Capabilities {
+	return websearch.Capabilities{
+		Search:   true,
+		Snippets: true,
+	}
+}
+
+// Search runs a query against SearXNG and returns at most limit results.
+func (p *Provider) Search(ctx context.Context, query string, limit int) ([]websearch.Result, error) {
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("format", "json")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/search?"+params.Encode(), http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("searxng: create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("searxng: http request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("searxng: read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("searxng: API error %d: %s", resp.StatusCode, string(data))
+	}
+
+	var raw struct {
+		Results []struct {
+			Title   string  `json:"title"`
+			URL     string  `json:"url"`
+			Content string  `json:"content"`
+			Score   float64 `json:"score"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("searxng: unmarshal response: %w", err)
+	}
+
+	results := make([]websearch.Result, 0, min(limit, len(raw.Results)))
+	for _, r := range raw.Results {
+		if len(results) >= limit {
+			break
+		}
+		results = append(results, websearch.Result{
+			Title:   r.Title,
+			URL:     r.URL,
+			Snippet: r.Content,
+			Score:   r.Score,
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,72 @@
+package searxng_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/searxng"
+	"github.com/Strob0t/CodeForge/internal/port/websearch"
+)
+
+func TestSearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("q"); got != "go generics" {
+			t.Fatalf("unexpected query: %q", got)
+		}
+		if got := r.URL.Query().Get("format"); got != "json" {
+			t.Fatalf("unexpected format: %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"results":[
+			{"title":"Tutorial","url":"https://go.dev/doc/tutorial/generics","content":"Getting started with generics","score":2.5},
+			{"title":"Spec","url":"https://go.dev/ref/spec","content":"The Go spec","score":1.0},
+			{"title":"Blog","url":"https://go.dev/blog/intro-generics","content":"An introduction","score":0.5}
+		]}`))
+	}))
+	defer srv.Close()
+
+	p := searxng.NewProvider(srv.URL + "/")
+	results, err := p.Search(context.Background(), "go generics", 2)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].URL != "https://go.dev/doc/tutorial/generics" {
+		t.Fatalf("unexpected url: %q", results[0].URL)
+	}
+	if results[0].Snippet != "Getting started with generics" {
+		t.Fatalf("unexpected snippet: %q", results[0].Snippet)
+	}
+}
+
+func TestSearchAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	p := searxng.NewProvider(srv.URL)
+	if _, err := p.Search(context.Background(), "anything", 0); err == nil {
+		t.Fatal("expected error for 429 response")
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	if _, err := websearch.New("searxng", map[string]string{}); err == nil {
+		t.Fatal("expected error when url is missing")
+	}
+	p, err := websearch.New("searxng", map[string]string{"url": "http://localhost:8888"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if p.Name() != "searxng" {
+		t.Fatalf("expected searxng, got %s", p.Name())
+	}
+}
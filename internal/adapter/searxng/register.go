@@ -0,0 +1,17 @@
+package searxng
+
+import (
+	"fmt"
+
+	"github.com/Strob0t/CodeForge/internal/port/websearch"
+)
+
+func init() {
+	websearch.Register(providerName, func(config map[string]string) (websearch.Provider, error) {
+		baseURL := config["url"]
+		if baseURL == "" {
+			return nil, fmt.Errorf("searxng: url is required")
+		}
+		return NewProvider(baseURL), nil
+	})
+}
@@ -0,0 +1,251 @@
+// Package sentencepiece implements the tokenizer.Tokenizer interface for
+// SentencePiece models (tokenizer.model files, as used by Llama, Mistral
+// and Gemma), supporting the unigram and BPE model types.
+package sentencepiece
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Model types of a SentencePiece TrainerSpec.
+const (
+	TypeUnigram = 1
+	TypeBPE     = 2
+)
+
+// Piece types of a SentencePiece vocabulary entry.
+const (
+	pieceNormal      = 1
+	pieceUnknown     = 2
+	pieceControl     = 3
+	pieceUserDefined = 4
+	pieceByte        = 6
+)
+
+// space is the meta symbol SentencePiece replaces spaces with.
+const space = "▁"
+
+// unkPenalty lowers the score of unknown characters below every piece.
+const unkPenalty = 10.0
+
+// Model counts tokens with a SentencePiece vocabulary.
+type Model struct {
+	name             string
+	modelType        int
+	scores           map[string]float32 // Normal and user-defined pieces
+	maxPieceRunes    int
+	minScore         float32
+	byteFallback     bool
+	addDummyPrefix   bool
+	removeExtraSpace bool
+}
+
+// Load reads a SentencePiece model file.
+func Load(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("sentencepiece: %w", err)
+	}
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("sentencepiece: %s: %w", path, err)
+	}
+	m.name = strings.TrimSuffix(filepath.Base(path), ".model")
+	return m, nil
+}
+
+// Parse decodes a serialized SentencePiece ModelProto.
+func Parse(data []byte) (*Model, error) {
+	m := &Model{
+		modelType:        TypeUnigram,
+		scores:           make(map[string]float32),
+		minScore:         math.MaxFloat32,
+		addDummyPrefix:   true,
+		removeExtraSpace: true,
+	}
+	err := walk(data, func(field int, wire int, v uint64, b []byte) error {
+		switch {
+		case field == 1 && wire == wireBytes:
+			return m.addPiece(b)
+		case field == 2 && wire == wireBytes: // TrainerSpec
+			return walk(b, func(f, w int, v uint64, _ []byte) error {
+				switch {
+				case f == 3 && w == wireVarint:
+					m.modelType = int(v)
+				case f == 35 && w == wireVarint:
+					m.byteFallback = v != 0
+				}
+				return nil
+			})
+		case field == 3 && wire == wireBytes: // NormalizerSpec
+			return walk(b, func(f, w int, v uint64, _ []byte) error {
+				switch {
+				case f == 3 && w == wireVarint:
+					m.addDummyPrefix = v != 0
+				case f == 4 && w == wireVarint:
+					m.removeExtraSpace = v != 0
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(m.scores) == 0 {
+		return nil, errors.New("model has no pieces")
+	}
+	if m.modelType != TypeUnigram && m.modelType != TypeBPE {
+		return nil, fmt.Errorf("unsupported model type %d", m.modelType)
+	}
+	return m, nil
+}
+
+func (m *Model) addPiece(b []byte) error {
+	var piece string
+	var score float32
+	typ := pieceNormal
+	err := walk(b, func(f, w int, v uint64, raw []byte) error {
+		switch {
+		case f == 1 && w == wireBytes:
+			piece = string(raw)
+		case f == 2 && w == wireFixed32:
+			score = math.Float32frombits(uint32(v))
+		case f == 3 && w == wireVarint:
+			typ = int(v)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if typ != pieceNormal && typ != pieceUserDefined {
+		return nil // Unknown, control and byte pieces never match text
+	}
+	m.scores[piece] = score
+	m.maxPieceRunes = max(m.maxPieceRunes, utf8.RuneCountInString(piece))
+	m.minScore = min(m.minScore, score)
+	return nil
+}
+
+// Name returns "sentencepiece:<model file name>".
+func (m *Model) Name() string { return "sentencepiece:" + m.name }
+
+// Count normalizes text like SentencePiece, splits it at the space meta
+// symbol and counts the pieces of every word.
+func (m *Model) Count(text string) int {
+	if m.removeExtraSpace {
+		text = strings.Join(strings.Fields(text), " ")
+	}
+	if text == "" {
+		return 0
+	}
+	text = strings.ReplaceAll(text, " ", space)
+	if m.addDummyPrefix && !strings.HasPrefix(text, space) {
+		text = space + text
+	}
+
+	n := 0
+	for text != "" {
+		end := len(text)
+		if i := strings.Index(text[1:], space); i >= 0 {
+			end = i + 1
+		}
+		word := text[:end]
+		if m.modelType == TypeBPE {
+			n += m.countBPE(word)
+		} else {
+			n += m.countUnigram(word)
+		}
+		text = text[end:]
+	}
+	return n
+}
+
+// unknownTokens is the cost of a character missing from the vocabulary:
+// its UTF-8 bytes with byte fallback, otherwise one unknown token.
+func (m *Model) unknownTokens(r string) int {
+	if m.byteFallback {
+		return len(r)
+	}
+	return 1
+}
+
+// countUnigram finds the segmentation of word with the highest total
+// score (Viterbi) and returns its number of pieces.
+func (m *Model) countUnigram(word string) int {
+	runes := []rune(word)
+	best := make([]float64, len(runes)+1)
+	count := make([]int, len(runes)+1)
+	for i := 1; i <= len(runes); i++ {
+		best[i] = math.Inf(-1)
+	}
+	for i := 0; i < len(runes); i++ {
+		if math.IsInf(best[i], -1) {
+			continue
+		}
+		matched := false
+		for j := i + 1; j <= min(len(runes), i+m.maxPieceRunes); j++ {
+			score, ok := m.scores[string(runes[i:j])]
+			if !ok {
+				continue
+			}
+			matched = matched || j == i+1
+			if s := best[i] + float64(score); s > best[j] {
+				best[j], count[j] = s, count[i]+1
+			}
+		}
+		if !matched {
+			if s := best[i] + float64(m.minScore) - unkPenalty; s > best[i+1] {
+				best[i+1], count[i+1] = s, count[i]+m.unknownTokens(string(runes[i]))
+			}
+		}
+	}
+	return count[len(runes)]
+}
+
+// countBPE merges adjacent symbols of word, highest scoring merge first,
+// and returns the number of symbols left.
+func (m *Model) countBPE(word string) int {
+	var symbols []string
+	unknown := 0
+	for _, r := range word {
+		s := string(r)
+		if _, ok := m.scores[s]; !ok {
+			unknown += m.unknownTokens(s)
+			symbols = append(symbols, "") // Never merges
+			continue
+		}
+		symbols = append(symbols, s)
+	}
+	for {
+		bestScore, idx := float32(-math.MaxFloat32), -1
+		for i := 0; i+1 < len(symbols); i++ {
+			if symbols[i] == "" || symbols[i+1] == "" {
+				continue
+			}
+			if score, ok := m.scores[symbols[i]+symbols[i+1]]; ok && score > bestScore {
+				bestScore, idx = score, i
+			}
+		}
+		if idx < 0 {
+			break
+		}
+		symbols[idx] += symbols[idx+1]
+		symbols = append(symbols[:idx+1], symbols[idx+2:]...)
+	}
+	known := 0
+	for _, s := range symbols {
+		if s != "" {
+			known++
+		}
+	}
+	return known + unknown
+}
@@ -0,0 +1,102 @@
+package sentencepiece_test
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/sentencepiece"
+)
+
+// proto builds serialized protobuf fields for test models.
+type proto []byte
+
+func (p proto) varint(field int, v uint64) proto {
+	p = binary.AppendUvarint(p, uint64(field<<3))
+	return binary.AppendUvarint(p, v)
+}
+
+func (p proto) bytes(field int, b []byte) proto {
+	p = binary.AppendUvarint(p, uint64(field<<3|2))
+	p = binary.AppendUvarint(p, uint64(len(b)))
+	return append(p, b...)
+}
+
+func (p proto) float(field int, f float32) proto {
+	p = binary.AppendUvarint(p, uint64(field<<3|5))
+	return binary.LittleEndian.AppendUint32(p, math.Float32bits(f))
+}
+
+func model(modelType int, byteFallback bool, pieces map[string]float32) []byte {
+	var m proto
+	m = m.bytes(1, proto{}.bytes(1, []byte("<unk>")).float(2, 0).varint(3, 2))
+	for piece, score := range pieces {
+		m = m.bytes(1, proto{}.bytes(1, []byte(piece)).float(2, score))
+	}
+	fallback := uint64(0)
+	if byteFallback {
+		fallback = 1
+	}
+	return m.bytes(2, proto{}.varint(3, uint64(modelType)).varint(35, fallback))
+}
+
+func TestUnigramCount(t *testing.T) {
+	m, err := sentencepiece.Parse(model(sentencepiece.TypeUnigram, false, map[string]float32{
+		"▁hello": -1, "▁world": -1, "▁he": -2, "llo": -2, "▁": -3,
+		"h": -5, "e": -5, "l": -5, "o": -5, "w": -5, "r": -5, "d": -5,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for text, want := range map[string]int{
+		"":             0,
+		"hello world":  2,
+		" hello   wor": 5, // Extra spaces removed: ▁hello, ▁, w, o, r
+		"hex":          2, // ▁he and one unknown token
+	} {
+		if got := m.Count(text); got != want {
+			t.Errorf("Count(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
+
+func TestBPECountWithByteFallback(t *testing.T) {
+	m, err := sentencepiece.Parse(model(sentencepiece.TypeBPE, true, map[string]float32{
+		"ll": -1, "he": -2, "hell": -3, "▁hell": -4,
+		"▁": -10, "h": -10, "e": -10, "l": -10, "o": -10,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for text, want := range map[string]int{
+		"hello": 2, // ▁hell + o
+		"hé":    4, // ▁, h and the two UTF-8 bytes of é
+	} {
+		if got := m.Count(text); got != want {
+			t.Errorf("Count(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokenizer.model")
+	if err := os.WriteFile(path, model(sentencepiece.TypeUnigram, false, map[string]float32{"▁a": -1}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := sentencepiece.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name() != "sentencepiece:tokenizer" {
+		t.Fatalf("unexpected name %q", m.Name())
+	}
+
+	if _, err := sentencepiece.Parse([]byte{0x0a, 0x05, 0x01}); err == nil {
+		t.Fatal("expected error for truncated model")
+	}
+	if _, err := sentencepiece.Parse(model(3, false, map[string]float32{"a": -1})); err == nil {
+		t.Fatal("expected error for the unsupported word model type")
+	}
+}
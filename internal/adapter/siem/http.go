@@ -0,0 +1,130 @@
+package siem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/audit"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+)
+
+// maxErrorBody limits how much of an error response is kept.
+const maxErrorBody = 1024
+
+// HTTP POSTs each batch to a bulk endpoint as newline-delimited JSON, one
+// entry per line. In the splunk_hec format each line is a Splunk HTTP
+// Event Collector event wrapping the entry.
+type HTTP struct {
+	url        string
+	format     string
+	auth       string // Authorization header; empty without a token
+	hostname   string
+	httpClient *http.Client
+}
+
+// NewHTTP creates a sink for the bulk endpoint at url. token is sent as
+// "Authorization: <scheme> <token>"; scheme defaults to Bearer (Splunk
+// expects "Splunk").
+func NewHTTP(url, format, token, scheme string) *HTTP {
+	if format == "" {
+		format = audit.FormatNDJSON
+	}
+	hostname, _ := os.Hostname()
+	return &HTTP{
+		url:        url,
+		format:     format,
+		auth:       authorization(token, scheme),
+		hostname:   hostname,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// splunkEvent is an event of the Splunk HTTP Event Collector.
+type splunkEvent struct {
+	Time       float64           `json:"time"`
+	Host       string            `json:"host,omitempty"`
+	Source     string            `json:"source"`
+	Sourcetype string            `json:"sourcetype"`
+	Event      *event.AuditEntry `json:"event"`
+}
+
+// Send POSTs the entries and succeeds on any 2xx response.
+func (h *HTTP) Send(ctx context.Context, entries []event.AuditEntry) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for i := range entries {
+		var v any = &entries[i]
+		if h.format == audit.FormatSplunkHEC {
+			v = splunkEvent{
+				Time:       float64(entries[i].CreatedAt.UnixMilli()) / 1000,
+				Host:       h.hostname,
+				Source:     "codeforge",
+				Sourcetype: "codeforge:audit",
+				Event:      &entries[i],
+			}
+		}
+		if err := enc.Encode(v); err != nil {
+			return fmt.Errorf("http sink: encode entry %d: %w", entries[i].Seq, err)
+		}
+	}
+
+	contentType := "application/x-ndjson"
+	if h.format == audit.FormatSplunkHEC {
+		contentType = "application/json"
+	}
+	return post(ctx, h.httpClient, h.url, contentType, h.auth, &body, nil)
+}
+
+// Close releases idle connections.
+func (h *HTTP) Close() error {
+	h.httpClient.CloseIdleConnections()
+	return nil
+}
+
+func authorization(token, scheme string) string {
+	if token == "" {
+		return ""
+	}
+	if scheme == "" {
+		scheme = "Bearer"
+	}
+	return scheme + " " + token
+}
+
+// post sends body to url and, if out is set, decodes the response into it.
+// Responses other than 2xx are errors.
+func post(ctx context.Context, client *http.Client, url, contentType, auth string, body io.Reader, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post to %s: %w", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return fmt.Errorf("post to %s: status %d: %s", url, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response of %s: %w", url, err)
+	}
+	return nil
+}
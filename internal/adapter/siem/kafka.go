@@ -0,0 +1,81 @@
+package siem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+)
+
+// kafkaContentType is the embedded JSON format of the REST Proxy v2 API.
+const kafkaContentType = "application/vnd.kafka.json.v2+json"
+
+// Kafka produces each entry as a JSON record to a topic through a Kafka
+// REST Proxy (v2 API). Records are keyed by tenant so that a tenant's
+// entries stay in order within one partition.
+type Kafka struct {
+	endpoint   string
+	auth       string
+	httpClient *http.Client
+}
+
+// NewKafka creates a sink producing to topic through the REST Proxy at
+// baseURL.
+func NewKafka(baseURL, topic, token, scheme string) *Kafka {
+	return &Kafka{
+		endpoint:   strings.TrimRight(baseURL, "/") + "/topics/" + url.PathEscape(topic),
+		auth:       authorization(token, scheme),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type kafkaRecord struct {
+	Key   string            `json:"key"`
+	Value *event.AuditEntry `json:"value"`
+}
+
+type kafkaResponse struct {
+	Offsets []struct {
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+// Send produces the entries. It fails if the proxy reports an error for
+// any record.
+func (k *Kafka) Send(ctx context.Context, entries []event.AuditEntry) error {
+	records := make([]kafkaRecord, len(entries))
+	for i := range entries {
+		records[i] = kafkaRecord{Key: entries[i].TenantID, Value: &entries[i]}
+	}
+	body, err := json.Marshal(map[string]any{"records": records})
+	if err != nil {
+		return fmt.Errorf("kafka sink: encode records: %w", err)
+	}
+
+	var resp kafkaResponse
+	if err := post(ctx, k.httpClient, k.endpoint, kafkaContentType, k.auth, bytes.NewReader(body), &resp); err != nil {
+		return fmt.Errorf("kafka sink: %w", err)
+	}
+	if len(resp.Offsets) != len(entries) {
+		return fmt.Errorf("kafka sink: proxy acknowledged %d of %d records", len(resp.Offsets), len(entries))
+	}
+	for i, o := range resp.Offsets {
+		if o.ErrorCode != nil || o.Error != "" {
+			return fmt.Errorf("kafka sink: record %d (seq %d) failed: %s", i, entries[i].Seq, o.Error)
+		}
+	}
+	return nil
+}
+
+// Close releases idle connections.
+func (k *Kafka) Close() error {
+	k.httpClient.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,18 @@
+package siem
+
+import (
+	"github.com/Strob0t/CodeForge/internal/domain/audit"
+	"github.com/Strob0t/CodeForge/internal/port/auditsink"
+)
+
+func init() {
+	auditsink.Register(string(audit.KindSyslog), func(config map[string]string) (auditsink.Sink, error) {
+		return NewSyslog(config[audit.ConfigNetwork], config[audit.ConfigAddress], config[audit.ConfigAppName]), nil
+	})
+	auditsink.Register(string(audit.KindHTTP), func(config map[string]string) (auditsink.Sink, error) {
+		return NewHTTP(config[audit.ConfigURL], config[audit.ConfigFormat], config[audit.ConfigToken], config[audit.ConfigAuthScheme]), nil
+	})
+	auditsink.Register(string(audit.KindKafka), func(config map[string]string) (auditsink.Sink, error) {
+		return NewKafka(config[audit.ConfigURL], config[audit.ConfigTopic], config[audit.ConfigToken], config[audit.ConfigAuthScheme]), nil
+	})
+}
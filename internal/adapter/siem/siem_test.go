@@ -0,0 +1,162 @@
+package siem
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/audit"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/port/auditsink"
+)
+
+func testEntries() []event.AuditEntry {
+	at := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	return []event.AuditEntry{
+		{Seq: 1, TenantID: "acme", Actor: "server", Method: "POST", Path: "/api/v1/projects", Status: 201, CreatedAt: at},
+		{Seq: 2, TenantID: "acme", Actor: "api_key:k1", Method: "DELETE", Path: "/api/v1/projects/p1", Status: 404, CreatedAt: at},
+	}
+}
+
+func TestHTTP_SplunkHEC(t *testing.T) {
+	var auth string
+	var lines []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		lines = strings.Split(strings.TrimSpace(string(body)), "\n")
+		_, _ = w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer srv.Close()
+
+	sink, err := auditsink.New(string(audit.KindHTTP), map[string]string{
+		audit.ConfigURL: srv.URL, audit.ConfigFormat: audit.FormatSplunkHEC,
+		audit.ConfigToken: "hec-token", audit.ConfigAuthScheme: "Splunk",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(context.Background(), testEntries()); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Splunk hec-token" {
+		t.Fatalf("unexpected Authorization %q", auth)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected one event per line, got %q", lines)
+	}
+	var ev struct {
+		Time       float64          `json:"time"`
+		Sourcetype string           `json:"sourcetype"`
+		Event      event.AuditEntry `json:"event"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Sourcetype != "codeforge:audit" || ev.Event.Seq != 2 || ev.Time != float64(testEntries()[1].CreatedAt.Unix()) {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+}
+
+func TestHTTP_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "index is full", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	err := NewHTTP(srv.URL, "", "", "").Send(context.Background(), testEntries())
+	if err == nil || !strings.Contains(err.Error(), "503") || !strings.Contains(err.Error(), "index is full") {
+		t.Fatalf("expected the status and body in the error, got %v", err)
+	}
+}
+
+func TestKafka(t *testing.T) {
+	fail := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/topics/audit.events" || r.Header.Get("Content-Type") != kafkaContentType {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var body struct {
+			Records []kafkaRecord `json:"records"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		offsets := make([]string, len(body.Records))
+		for i, rec := range body.Records {
+			offsets[i] = `{"partition":0,"offset":` + strconv.FormatInt(rec.Value.Seq, 10) + `}`
+			if fail && i == 1 {
+				offsets[i] = `{"error_code":50002,"error":"broker unavailable"}`
+			}
+			if rec.Key != "acme" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+		_, _ = w.Write([]byte(`{"offsets":[` + strings.Join(offsets, ",") + `]}`))
+	}))
+	defer srv.Close()
+
+	sink := NewKafka(srv.URL+"/", "audit.events", "", "")
+	if err := sink.Send(context.Background(), testEntries()); err != nil {
+		t.Fatal(err)
+	}
+	fail = true
+	if err := sink.Send(context.Background(), testEntries()); err == nil || !strings.Contains(err.Error(), "broker unavailable") {
+		t.Fatalf("expected a record error, got %v", err)
+	}
+}
+
+func TestSyslog_TCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		var msgs []string
+		for {
+			n, err := r.ReadString(' ')
+			if err != nil {
+				break
+			}
+			size, _ := strconv.Atoi(strings.TrimSpace(n))
+			buf := make([]byte, size)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				break
+			}
+			msgs = append(msgs, string(buf))
+		}
+		received <- msgs
+	}()
+
+	sink := NewSyslog("tcp", ln.Addr().String(), "")
+	if err := sink.Send(context.Background(), testEntries()); err != nil {
+		t.Fatal(err)
+	}
+	msgs := <-received
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 framed messages, got %q", msgs)
+	}
+	// Facility log audit (13): info for successes, warning for failed requests.
+	if !strings.HasPrefix(msgs[0], "<110>1 2026-10-16T12:00:00Z ") || !strings.HasPrefix(msgs[1], "<108>1 ") {
+		t.Fatalf("unexpected headers %q", msgs)
+	}
+	if !strings.Contains(msgs[0], " codeforge - audit ") || !strings.HasSuffix(msgs[1], `"status":404,"created_at":"2026-10-16T12:00:00Z"}`) {
+		t.Fatalf("unexpected message %q", msgs)
+	}
+}
@@ -0,0 +1,114 @@
+// Package siem implements auditsink.Sink for the systems security teams
+// collect audit logs in: syslog receivers, HTTP bulk endpoints such as the
+// Splunk HTTP Event Collector, and Kafka (through a Kafka REST Proxy).
+package siem
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+)
+
+const (
+	// facilityLogAudit is the syslog facility "log audit" (13).
+	facilityLogAudit = 13
+	severityWarning  = 4
+	severityInfo     = 6
+)
+
+// Syslog sends each entry as an RFC 5424 message with the entry as JSON.
+// Over TCP and TLS messages are framed by octet counting (RFC 6587); over
+// UDP each message is one datagram. A connection is opened per batch.
+type Syslog struct {
+	network  string
+	address  string
+	appName  string
+	hostname string
+}
+
+// NewSyslog creates a syslog sink for the receiver at address. network is
+// udp, tcp or tls.
+func NewSyslog(network, address, appName string) *Syslog {
+	if network == "" {
+		network = "tcp"
+	}
+	if appName == "" {
+		appName = "codeforge"
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return &Syslog{network: network, address: address, appName: appName, hostname: hostname}
+}
+
+// Send writes the entries to the receiver. Syslog has no acknowledgements,
+// so entries count as delivered once written to the connection.
+func (s *Syslog) Send(ctx context.Context, entries []event.AuditEntry) error {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("syslog: connect %s: %w", s.address, err)
+	}
+	defer func() { _ = conn.Close() }()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	w := bufio.NewWriter(conn)
+	for i := range entries {
+		msg, err := s.format(&entries[i])
+		if err != nil {
+			return err
+		}
+		if s.network == "udp" {
+			if _, err := conn.Write(msg); err != nil {
+				return fmt.Errorf("syslog: write: %w", err)
+			}
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%d %s", len(msg), msg); err != nil {
+			return fmt.Errorf("syslog: write: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("syslog: write: %w", err)
+	}
+	return nil
+}
+
+// Close is a no-op; connections only live for one batch.
+func (s *Syslog) Close() error { return nil }
+
+func (s *Syslog) dial(ctx context.Context) (net.Conn, error) {
+	if s.network == "tls" {
+		d := &tls.Dialer{}
+		return d.DialContext(ctx, "tcp", s.address)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, s.network, s.address)
+}
+
+// format renders an entry as an RFC 5424 message. Failed requests are
+// logged with severity warning, others with info.
+func (s *Syslog) format(e *event.AuditEntry) ([]byte, error) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("syslog: encode entry %d: %w", e.Seq, err)
+	}
+	severity := severityInfo
+	if e.Status >= 400 {
+		severity = severityWarning
+	}
+	header := fmt.Sprintf("<%d>1 %s %s %s - audit [origin software=\"codeforge\"][meta sequenceId=\"%s\"] ",
+		facilityLogAudit*8+severity, e.CreatedAt.UTC().Format(time.RFC3339Nano), s.hostname, s.appName,
+		strconv.FormatInt(e.Seq, 10))
+	return append([]byte(header), body...), nil
+}
@@ -0,0 +1,89 @@
+// Package skillindex implements the skillregistry.Registry interface for
+// a static JSON index served over HTTP, e.g. from a Git forge or bucket.
+package skillindex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/skill"
+)
+
+// maxIndexBytes bounds the size of an index document.
+const maxIndexBytes = 4 << 20
+
+// Registry reads the index at a URL and downloads the bundles it lists.
+type Registry struct {
+	indexURL   string
+	httpClient *http.Client
+}
+
+// NewRegistry creates a Registry for the index at indexURL.
+func NewRegistry(indexURL string) *Registry {
+	return &Registry{
+		indexURL: indexURL,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Index fetches the index and resolves bundle URLs against it.
+func (r *Registry) Index(ctx context.Context) (*skill.Index, error) {
+	base, err := url.Parse(r.indexURL)
+	if err != nil {
+		return nil, fmt.Errorf("skillindex: parse index url: %w", err)
+	}
+	data, err := r.get(ctx, r.indexURL, maxIndexBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	var ix skill.Index
+	if err := json.Unmarshal(data, &ix); err != nil {
+		return nil, fmt.Errorf("skillindex: unmarshal index: %w", err)
+	}
+	for i := range ix.Skills {
+		ref, err := url.Parse(ix.Skills[i].URL)
+		if err != nil {
+			return nil, fmt.Errorf("skillindex: skill %s: parse url: %w", ix.Skills[i].Name, err)
+		}
+		ix.Skills[i].URL = base.ResolveReference(ref).String()
+	}
+	return &ix, nil
+}
+
+// Download fetches a bundle.
+func (r *Registry) Download(ctx context.Context, bundleURL string, maxBytes int64) ([]byte, error) {
+	return r.get(ctx, bundleURL, maxBytes)
+}
+
+func (r *Registry) get(ctx context.Context, target string, maxBytes int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("skillindex: create request: %w", err)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("skillindex: http request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("skillindex: GET %s: status %d", target, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("skillindex: read response: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, fmt.Errorf("skillindex: GET %s: larger than %d bytes", target, maxBytes)
+	}
+	return data, nil
+}
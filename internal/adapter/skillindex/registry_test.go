@@ -0,0 +1,51 @@
+package skillindex_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/skillindex"
+)
+
+func TestIndexResolvesBundleURLs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/skills/index.json":
+			_, _ = w.Write([]byte(`{"skills":[
+				{"name":"go-tests","version":"1.0.0","url":"bundles/go-tests-1.0.0.tar.gz"},
+				{"name":"lint","version":"0.1.0","url":"https://example.com/lint.tar.gz"}
+			]}`))
+		case "/skills/bundles/go-tests-1.0.0.tar.gz":
+			_, _ = w.Write([]byte("0123456789"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	reg := skillindex.NewRegistry(srv.URL + "/skills/index.json")
+	ix, err := reg.Index(context.Background())
+	if err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	if len(ix.Skills) != 2 {
+		t.Fatalf("expected 2 skills, got %d", len(ix.Skills))
+	}
+	if got := ix.Skills[0].URL; got != srv.URL+"/skills/bundles/go-tests-1.0.0.tar.gz" {
+		t.Fatalf("expected the relative URL resolved against the index, got %s", got)
+	}
+	if got := ix.Skills[1].URL; got != "https://example.com/lint.tar.gz" {
+		t.Fatalf("expected the absolute URL kept, got %s", got)
+	}
+
+	data, err := reg.Download(context.Background(), ix.Skills[0].URL, 10)
+	if err != nil || string(data) != "0123456789" {
+		t.Fatalf("Download = %q, %v", data, err)
+	}
+	if _, err := reg.Download(context.Background(), ix.Skills[0].URL, 9); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Fatalf("expected a size error, got %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+// Package slack handles the callbacks of the Slack app that interactive
+// Slack notification rules post with: signed interaction requests, such as
+// clicks on approval buttons, and the answers to them.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/port/notifier"
+)
+
+// Action IDs of the Approve and Reject buttons of approval requests. The
+// value of a button is "<plan ID>/<step ID>".
+const (
+	ActionApprove = "plan_approval_approve"
+	ActionReject  = "plan_approval_reject"
+)
+
+// maxSkew is how old a signed Slack request may be; older ones may be
+// replays.
+const maxSkew = 5 * time.Minute
+
+// Interaction is a click on the Approve or Reject button of an approval
+// request posted by an interactive Slack notifier.
+type Interaction struct {
+	UserID      string // Slack user ID of whoever clicked
+	UserName    string
+	Approve     bool
+	PlanID      string
+	StepID      string
+	Text        string // Text of the message clicked on
+	ResponseURL string // Where the message can be replaced or answered
+}
+
+// payload is the part of an interaction payload that is read.
+type payload struct {
+	Type string `json:"type"`
+	User struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+		Name     string `json:"name"`
+	} `json:"user"`
+	Actions []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+	Message struct {
+		Text string `json:"text"`
+	} `json:"message"`
+	ResponseURL string `json:"response_url"`
+}
+
+// ParseInteraction verifies the signature of a request to the Slack
+// app's interactivity URL with the app's signing secret and reads the
+// click on an approval button it carries. Requests signed more than five
+// minutes from now are rejected as replays. It returns
+// notifier.ErrInvalidSignature for requests that do not verify and
+// notifier.ErrIgnoredInteraction for other interactions.
+func ParseInteraction(signingSecret string, header http.Header, body []byte, now time.Time) (*Interaction, error) {
+	if !validSignature(signingSecret, header, body, now) {
+		return nil, notifier.ErrInvalidSignature
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("parse slack interaction: %w", err)
+	}
+	var p payload
+	if err := json.Unmarshal([]byte(form.Get("payload")), &p); err != nil {
+		return nil, fmt.Errorf("parse slack interaction: %w", err)
+	}
+	if p.Type != "block_actions" {
+		return nil, notifier.ErrIgnoredInteraction
+	}
+	for _, a := range p.Actions {
+		if a.ActionID != ActionApprove && a.ActionID != ActionReject {
+			continue
+		}
+		planID, stepID, ok := strings.Cut(a.Value, "/")
+		if !ok || planID == "" || stepID == "" {
+			return nil, fmt.Errorf("parse slack interaction: invalid approval step %q", a.Value)
+		}
+		return &Interaction{
+			UserID:      p.User.ID,
+			UserName:    p.User.Username,
+			Approve:     a.ActionID == ActionApprove,
+			PlanID:      planID,
+			StepID:      stepID,
+			Text:        p.Message.Text,
+			ResponseURL: p.ResponseURL,
+		}, nil
+	}
+	return nil, notifier.ErrIgnoredInteraction
+}
+
+// validSignature checks X-Slack-Signature, an HMAC-SHA256 of
+// "v0:<timestamp>:<body>" keyed with the signing secret.
+func validSignature(secret string, header http.Header, body []byte, now time.Time) bool {
+	if secret == "" {
+		return false
+	}
+	ts := header.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return false
+	}
+	if skew := now.Sub(time.Unix(sec, 0)); skew > maxSkew || skew < -maxSkew {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":"))
+	mac.Write(body)
+	want := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(header.Get("X-Slack-Signature")), []byte(want))
+}
+
+// response answers an interaction. Text replaces the message clicked on,
+// or is shown only to whoever clicked.
+type response struct {
+	Text            string `json:"text"`
+	ReplaceOriginal bool   `json:"replace_original"`
+	ResponseType    string `json:"response_type,omitempty"`
+}
+
+// Respond answers an interaction through its response URL: replace swaps
+// the message clicked on for text, without buttons; otherwise text is
+// shown only to whoever clicked.
+func Respond(ctx context.Context, responseURL, text string, replace bool) error {
+	r := response{Text: text, ReplaceOriginal: replace}
+	if !replace {
+		r.ResponseType = "ephemeral"
+	}
+	body, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("encode slack response: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, responseURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create slack response: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("post slack response: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("post slack response: status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package slack
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/port/notifier"
+)
+
+// signSlack signs body like Slack does with secret at ts.
+func signSlack(secret string, ts time.Time, body string) http.Header {
+	stamp := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + stamp + ":" + body))
+	return http.Header{
+		"X-Slack-Request-Timestamp": {stamp},
+		"X-Slack-Signature":         {"v0=" + hex.EncodeToString(mac.Sum(nil))},
+	}
+}
+
+func TestParseInteraction(t *testing.T) {
+	now := time.Now()
+	payload := `{"type":"block_actions","user":{"id":"U1","username":"alice"},"response_url":"https://hooks.slack.com/actions/T0/1/x",` +
+		`"message":{"text":"*Approval requested*"},"actions":[{"action_id":"plan_approval_reject","value":"pl1/st2"}]}`
+	body := url.Values{"payload": {payload}}.Encode()
+
+	in, err := ParseInteraction("s3cret", signSlack("s3cret", now, body), []byte(body), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in.Approve || in.PlanID != "pl1" || in.StepID != "st2" || in.UserID != "U1" || in.UserName != "alice" || in.Text != "*Approval requested*" {
+		t.Fatalf("unexpected interaction %+v", in)
+	}
+
+	for name, header := range map[string]http.Header{
+		"wrong secret": signSlack("other", now, body),
+		"replayed":     signSlack("s3cret", now.Add(-10*time.Minute), body),
+		"unsigned":     {},
+	} {
+		if _, err := ParseInteraction("s3cret", header, []byte(body), now); !errors.Is(err, notifier.ErrInvalidSignature) {
+			t.Errorf("%s: expected ErrInvalidSignature, got %v", name, err)
+		}
+	}
+
+	other := url.Values{"payload": {`{"type":"view_submission"}`}}.Encode()
+	if _, err := ParseInteraction("s3cret", signSlack("s3cret", now, other), []byte(other), now); !errors.Is(err, notifier.ErrIgnoredInteraction) {
+		t.Errorf("expected ErrIgnoredInteraction, got %v", err)
+	}
+}
+
+func TestRespond(t *testing.T) {
+	var got response
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = response{}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	if err := Respond(context.Background(), srv.URL, "Not yours", false); err != nil {
+		t.Fatal(err)
+	}
+	if got.Text != "Not yours" || got.ReplaceOriginal || got.ResponseType != "ephemeral" {
+		t.Fatalf("unexpected answer %+v", got)
+	}
+	if err := Respond(context.Background(), srv.URL, "Approved", true); err != nil {
+		t.Fatal(err)
+	}
+	if !got.ReplaceOriginal || got.ResponseType != "" {
+		t.Fatalf("unexpected replacement %+v", got)
+	}
+}
@@ -0,0 +1,168 @@
+// Package tiktoken implements the tokenizer.Tokenizer interface for
+// byte-level BPE encodings in tiktoken's rank file format (one
+// "<base64 token> <rank>" pair per line), as used by OpenAI models.
+package tiktoken
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Pre-tokenization patterns of the known encodings. tiktoken ends both
+// with `\s+(?!\S)|\s+`; RE2 has no lookahead, so the patterns end with
+// `\s+` and Count backs off the last whitespace character instead.
+const (
+	PatternCL100K = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`
+	PatternO200K  = `[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+`
+)
+
+// Encoding counts tokens with a BPE rank table.
+type Encoding struct {
+	name    string
+	ranks   map[string]int
+	pattern *regexp.Regexp
+}
+
+// New creates an Encoding from a rank table and a pre-tokenization
+// pattern in RE2 syntax.
+func New(name string, ranks map[string]int, pattern string) (*Encoding, error) {
+	re, err := regexp.Compile(`^(?:` + pattern + `)`)
+	if err != nil {
+		return nil, fmt.Errorf("tiktoken: compile pattern: %w", err)
+	}
+	return &Encoding{name: name, ranks: ranks, pattern: re}, nil
+}
+
+// Load reads a .tiktoken rank file. An empty pattern is chosen by file
+// name: o200k encodings use PatternO200K, all others PatternCL100K.
+func Load(path, pattern string) (*Encoding, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("tiktoken: %w", err)
+	}
+	ranks := make(map[string]int, bytes.Count(data, []byte("\n")))
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; sc.Scan(); line++ {
+		tok, rank, ok := strings.Cut(strings.TrimSpace(sc.Text()), " ")
+		if !ok {
+			continue
+		}
+		b, err := base64.StdEncoding.DecodeString(tok)
+		if err != nil {
+			return nil, fmt.Errorf("tiktoken: %s:%d: %w", path, line, err)
+		}
+		r, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("tiktoken: %s:%d: %w", path, line, err)
+		}
+		ranks[string(b)] = r
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("tiktoken: %w", err)
+	}
+	name := strings.TrimSuffix(filepath.Base(path), ".tiktoken")
+	if pattern == "" {
+		pattern = PatternCL100K
+		if strings.Contains(name, "o200k") {
+			pattern = PatternO200K
+		}
+	}
+	return New(name, ranks, pattern)
+}
+
+// Name returns "tiktoken:<encoding>".
+func (e *Encoding) Name() string { return "tiktoken:" + e.name }
+
+// Count splits text with the pre-tokenization pattern and counts the BPE
+// tokens of every piece.
+func (e *Encoding) Count(text string) int {
+	n := 0
+	for pos := 0; pos < len(text); {
+		loc := e.pattern.FindStringIndex(text[pos:])
+		end := pos + 1
+		if loc != nil && loc[1] > 0 {
+			end = pos + loc[1]
+		} else {
+			_, size := utf8.DecodeRuneInString(text[pos:])
+			end = pos + size
+		}
+		end = backOffWhitespace(text, pos, end)
+		n += e.countPiece([]byte(text[pos:end]))
+		pos = end
+	}
+	return n
+}
+
+// backOffWhitespace emulates `\s+(?!\S)`: a run of two or more
+// whitespace characters without a line break, followed by a non-space,
+// leaves its last character to the next piece.
+func backOffWhitespace(text string, start, end int) int {
+	if end >= len(text) {
+		return end
+	}
+	piece := text[start:end]
+	if strings.ContainsAny(piece, "\r\n") || strings.TrimSpace(piece) != "" {
+		return end
+	}
+	if next, _ := utf8.DecodeRuneInString(text[end:]); unicode.IsSpace(next) {
+		return end
+	}
+	_, last := utf8.DecodeLastRuneInString(piece)
+	if utf8.RuneCountInString(piece) < 2 {
+		return end
+	}
+	return end - last
+}
+
+// countPiece applies byte pair merges to a piece, lowest rank first, and
+// returns the number of parts left.
+func (e *Encoding) countPiece(b []byte) int {
+	if _, ok := e.ranks[string(b)]; ok {
+		return 1
+	}
+	type part struct{ start, rank int }
+	parts := make([]part, len(b)+1)
+	for i := range parts {
+		parts[i] = part{start: i, rank: math.MaxInt}
+	}
+	rankAt := func(i int) int {
+		if i+2 < len(parts) {
+			if r, ok := e.ranks[string(b[parts[i].start:parts[i+2].start])]; ok {
+				return r
+			}
+		}
+		return math.MaxInt
+	}
+	for i := 0; i < len(parts)-2; i++ {
+		parts[i].rank = rankAt(i)
+	}
+	for len(parts) > 2 {
+		minRank, idx := math.MaxInt, -1
+		for i := 0; i < len(parts)-2; i++ {
+			if parts[i].rank < minRank {
+				minRank, idx = parts[i].rank, i
+			}
+		}
+		if idx < 0 {
+			break
+		}
+		parts = append(parts[:idx+1], parts[idx+2:]...)
+		parts[idx].rank = rankAt(idx)
+		if idx > 0 {
+			parts[idx-1].rank = rankAt(idx - 1)
+		}
+	}
+	return len(parts) - 1
+}
@@ -0,0 +1,68 @@
+package tiktoken_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/tiktoken"
+)
+
+// writeRanks writes a rank file with every single byte plus the given
+// merged tokens, ranked in order after the bytes.
+func writeRanks(t *testing.T, name string, merged ...string) string {
+	t.Helper()
+	var sb strings.Builder
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	for i, tok := range merged {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(tok)), 256+i)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCount(t *testing.T) {
+	enc, err := tiktoken.Load(writeRanks(t, "test_base.tiktoken", "he", "ll", "hell", "hello", " w", " wo", " wor", " world"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc.Name() != "tiktoken:test_base" {
+		t.Fatalf("unexpected name %q", enc.Name())
+	}
+	for text, want := range map[string]int{
+		"":             0,
+		"hello":        1,
+		"hello world":  2,
+		"hellox":       2, // "hellox" is one piece: hello + x
+		"help":         3, // he + l + p
+		"hello  world": 3, // "hello", " ", " world": the last space joins the word
+		"a\n\nb":       4, // "a", "\n\n" (two unmerged bytes), "b"
+		"12345":        5, // digits are split in groups of three, then bytes
+		"日本":           6, // unmerged UTF-8 bytes
+	} {
+		if got := enc.Count(text); got != want {
+			t.Errorf("Count(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	if _, err := tiktoken.Load(filepath.Join(t.TempDir(), "missing.tiktoken"), ""); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+	bad := filepath.Join(t.TempDir(), "bad.tiktoken")
+	if err := os.WriteFile(bad, []byte("!!! 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tiktoken.Load(bad, ""); err == nil {
+		t.Fatal("expected error for invalid base64")
+	}
+}
@@ -0,0 +1,112 @@
+package treesitter
+
+import (
+	sitter "github.com/smacker/go-tree-sitter"
+
+	"github.com/Strob0t/CodeForge/internal/domain/codegraph"
+)
+
+// walkKotlin records the imports of a Kotlin file and its declarations
+// that are not private or internal: classes, interfaces, objects,
+// functions, properties and type aliases at the top level, and the
+// methods and nested types of the public ones (Invoice.total,
+// Invoice.Line). Members of companion objects belong to the enclosing
+// class.
+func walkKotlin(f *codegraph.File, root *sitter.Node, src []byte) {
+	for _, n := range children(root) {
+		if n.Type() == "import_list" {
+			for _, h := range children(n) {
+				if id := child(h, "identifier"); h.Type() == "import_header" && id != nil {
+					f.Imports = append(f.Imports, id.Content(src))
+				}
+			}
+		}
+	}
+	kotlinDecls(f, root, src, "")
+}
+
+// kotlinDecls records the declarations of a file or class body. Members
+// of a class (prefix "Invoice.") are recorded as methods and nested types.
+func kotlinDecls(f *codegraph.File, body *sitter.Node, src []byte, prefix string) {
+	for _, n := range children(body) {
+		if n.Type() == "ERROR" {
+			kotlinDecls(f, n, src, prefix)
+			continue
+		}
+		if !kotlinVisible(n, src, prefix != "") {
+			continue
+		}
+		switch n.Type() {
+		case "class_declaration", "object_declaration":
+			name := child(n, "type_identifier")
+			if name == nil {
+				continue
+			}
+			kind := "class"
+			switch {
+			case n.Type() == "object_declaration":
+				kind = "object"
+			case hasToken(n, "interface"):
+				kind = "interface"
+			}
+			addSymbol(f, n, prefix+name.Content(src), kind)
+			if b := child(n, "class_body", "enum_class_body"); b != nil {
+				kotlinDecls(f, b, src, prefix+name.Content(src)+".")
+			}
+		case "companion_object":
+			if b := child(n, "class_body"); b != nil && prefix != "" {
+				kotlinDecls(f, b, src, prefix)
+			}
+		case "function_declaration":
+			if name := child(n, "simple_identifier"); name != nil {
+				kind := "func"
+				if prefix != "" {
+					kind = "method"
+				}
+				addSymbol(f, n, prefix+name.Content(src), kind)
+			}
+		case "property_declaration":
+			kind := child(n, "binding_pattern_kind")
+			if prefix != "" || kind == nil {
+				continue
+			}
+			vars := []*sitter.Node{child(n, "variable_declaration")}
+			if multi := child(n, "multi_variable_declaration"); multi != nil {
+				vars = children(multi)
+			}
+			for _, v := range vars {
+				if v == nil {
+					continue
+				}
+				if name := child(v, "simple_identifier"); name != nil {
+					addSymbol(f, n, name.Content(src), kind.Content(src))
+				}
+			}
+		case "type_alias":
+			if name := child(n, "type_identifier"); name != nil && prefix == "" {
+				addSymbol(f, n, name.Content(src), "typealias")
+			}
+		}
+	}
+}
+
+// kotlinVisible reports whether a declaration is visible outside its
+// module: it is not private or internal and, for members, not protected.
+func kotlinVisible(n *sitter.Node, src []byte, member bool) bool {
+	mods := child(n, "modifiers")
+	if mods == nil {
+		return true
+	}
+	for _, m := range children(mods) {
+		if m.Type() != "visibility_modifier" {
+			continue
+		}
+		switch m.Content(src) {
+		case "private", "internal":
+			return false
+		case "protected":
+			return !member
+		}
+	}
+	return true
+}
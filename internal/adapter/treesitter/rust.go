@@ -0,0 +1,158 @@
+package treesitter
+
+import (
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+
+	"github.com/Strob0t/CodeForge/internal/domain/codegraph"
+)
+
+// rustKinds maps the Rust items recorded as declarations to their kinds.
+var rustKinds = map[string]string{
+	"function_item": "fn",
+	"struct_item":   "struct",
+	"enum_item":     "enum",
+	"union_item":    "union",
+	"trait_item":    "trait",
+	"type_item":     "type",
+	"const_item":    "const",
+	"static_item":   "static",
+	"mod_item":      "mod",
+}
+
+// walkRust records the use paths of a Rust file, with use trees expanded
+// (use a::{b, c::d} imports a::b and a::c::d), and its pub items. Items of
+// pub modules are qualified with the module path (inner::Thing), pub
+// methods of inherent impls with their type (Invoice.total), and
+// macro_rules! macros are recorded when marked #[macro_export].
+func walkRust(f *codegraph.File, root *sitter.Node, src []byte) {
+	rustItems(f, root, src, "", true)
+}
+
+// rustItems walks the items of a module body. Only use declarations are
+// recorded in modules that are not exported.
+func rustItems(f *codegraph.File, body *sitter.Node, src []byte, prefix string, exported bool) {
+	for _, n := range children(body) {
+		switch n.Type() {
+		case "ERROR":
+			rustItems(f, n, src, prefix, exported)
+			continue
+		case "use_declaration":
+			if arg := n.ChildByFieldName("argument"); arg != nil {
+				rustUse(f, arg, src, "")
+			}
+			continue
+		case "macro_definition":
+			if prev := n.PrevNamedSibling(); prev != nil && prev.Type() == "attribute_item" &&
+				strings.Contains(prev.Content(src), "macro_export") {
+				if name := n.ChildByFieldName("name"); name != nil {
+					addSymbol(f, n, name.Content(src), "macro")
+				}
+			}
+			continue
+		case "impl_item":
+			if exported && n.ChildByFieldName("trait") == nil {
+				rustMethods(f, n, src, prefix)
+			}
+			continue
+		}
+
+		kind, ok := rustKinds[n.Type()]
+		name := n.ChildByFieldName("name")
+		if !ok || name == nil {
+			continue
+		}
+		pub := rustPub(n, src)
+		if exported && pub {
+			addSymbol(f, n, prefix+name.Content(src), kind)
+		}
+		if n.Type() == "mod_item" {
+			if b := n.ChildByFieldName("body"); b != nil {
+				rustItems(f, b, src, prefix+name.Content(src)+"::", exported && pub)
+			}
+		}
+	}
+}
+
+// rustMethods records the pub functions of an inherent impl block as
+// methods of its type.
+func rustMethods(f *codegraph.File, impl *sitter.Node, src []byte, prefix string) {
+	typ := rustTypeName(impl.ChildByFieldName("type"), src)
+	body := impl.ChildByFieldName("body")
+	if typ == "" || body == nil {
+		return
+	}
+	for _, n := range children(body) {
+		if n.Type() != "function_item" || !rustPub(n, src) {
+			continue
+		}
+		if name := n.ChildByFieldName("name"); name != nil {
+			addSymbol(f, n, prefix+typ+"."+name.Content(src), "method")
+		}
+	}
+}
+
+// rustTypeName returns the name of the type of an impl block without its
+// path and type arguments, or "" for types without a name.
+func rustTypeName(n *sitter.Node, src []byte) string {
+	if n == nil {
+		return ""
+	}
+	switch n.Type() {
+	case "type_identifier":
+		return n.Content(src)
+	case "generic_type":
+		return rustTypeName(n.ChildByFieldName("type"), src)
+	case "scoped_type_identifier":
+		return rustTypeName(n.ChildByFieldName("name"), src)
+	}
+	return ""
+}
+
+// rustPub reports whether an item is declared pub, without a restriction
+// such as pub(crate).
+func rustPub(n *sitter.Node, src []byte) bool {
+	v := child(n, "visibility_modifier")
+	return v != nil && v.Content(src) == "pub"
+}
+
+// rustUse records the paths a use tree imports below prefix.
+func rustUse(f *codegraph.File, n *sitter.Node, src []byte, prefix string) {
+	join := func(p string) string {
+		if prefix == "" {
+			return p
+		}
+		return prefix + "::" + p
+	}
+	switch n.Type() {
+	case "use_list":
+		for _, c := range children(n) {
+			rustUse(f, c, src, prefix)
+		}
+	case "scoped_use_list":
+		p := prefix
+		if path := n.ChildByFieldName("path"); path != nil {
+			p = join(path.Content(src))
+		}
+		if list := n.ChildByFieldName("list"); list != nil {
+			rustUse(f, list, src, p)
+		}
+	case "use_as_clause":
+		if path := n.ChildByFieldName("path"); path != nil {
+			f.Imports = append(f.Imports, join(path.Content(src)))
+		}
+	case "use_wildcard":
+		if p := strings.TrimSuffix(strings.TrimSuffix(n.Content(src), "*"), "::"); p != "" {
+			f.Imports = append(f.Imports, join(p))
+		} else if prefix != "" {
+			f.Imports = append(f.Imports, prefix)
+		}
+	case "self":
+		if prefix != "" {
+			f.Imports = append(f.Imports, prefix)
+		}
+	case "identifier", "scoped_identifier", "crate", "super":
+		f.Imports = append(f.Imports, join(n.Content(src)))
+	}
+}
@@ -0,0 +1,92 @@
+package treesitter
+
+import (
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+
+	"github.com/Strob0t/CodeForge/internal/domain/codegraph"
+)
+
+// walkSwift records the imports of a Swift file and its declarations
+// that are not private or fileprivate: types, extensions, protocols,
+// functions, properties and type aliases at the top level, and the
+// methods and nested types of the visible types and extensions
+// (Invoice.pay, Invoice.Kind). Members of an extension belong to the
+// extended type.
+func walkSwift(f *codegraph.File, root *sitter.Node, src []byte) {
+	for _, n := range children(root) {
+		if n.Type() != "import_declaration" {
+			continue
+		}
+		if id := child(n, "identifier"); id != nil {
+			f.Imports = append(f.Imports, id.Content(src))
+		}
+	}
+	swiftDecls(f, root, src, "")
+}
+
+// swiftDecls records the declarations of a file or type body. Members of a
+// type (prefix "Invoice.") are recorded as methods and nested types.
+func swiftDecls(f *codegraph.File, body *sitter.Node, src []byte, prefix string) {
+	for _, n := range children(body) {
+		if n.Type() == "ERROR" {
+			swiftDecls(f, n, src, prefix)
+			continue
+		}
+		if !swiftVisible(n, src) {
+			continue
+		}
+		name := n.ChildByFieldName("name")
+		switch n.Type() {
+		case "class_declaration", "protocol_declaration":
+			kind := n.ChildByFieldName("declaration_kind")
+			if name == nil || kind == nil {
+				continue
+			}
+			typ, _, _ := strings.Cut(name.Content(src), "<")
+			addSymbol(f, n, prefix+typ, kind.Content(src))
+			if b := n.ChildByFieldName("body"); b != nil {
+				swiftDecls(f, b, src, prefix+typ+".")
+			}
+		case "function_declaration", "protocol_function_declaration":
+			if name == nil {
+				continue
+			}
+			kind := "func"
+			if prefix != "" {
+				kind = "method"
+			}
+			addSymbol(f, n, prefix+name.Content(src), kind)
+		case "property_declaration":
+			binding := child(n, "value_binding_pattern")
+			if prefix != "" || name == nil || binding == nil {
+				continue
+			}
+			if m := binding.ChildByFieldName("mutability"); m != nil {
+				addSymbol(f, n, name.Content(src), m.Content(src))
+			}
+		case "typealias_declaration":
+			if name != nil && prefix == "" {
+				addSymbol(f, n, name.Content(src), "typealias")
+			}
+		}
+	}
+}
+
+// swiftVisible reports whether a declaration is visible to its module,
+// that is, not private or fileprivate.
+func swiftVisible(n *sitter.Node, src []byte) bool {
+	mods := child(n, "modifiers")
+	if mods == nil {
+		return true
+	}
+	for _, m := range children(mods) {
+		if m.Type() == "visibility_modifier" {
+			if v := m.Content(src); strings.HasPrefix(v, "private") || strings.HasPrefix(v, "fileprivate") {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package treesitter
+
+import (
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+
+	"github.com/Strob0t/CodeForge/internal/domain/codegraph"
+)
+
+// walkTerraform records the top-level blocks of a Terraform file by their
+// address (aws_s3_bucket.logs, data.aws_iam_policy.read, module.vpc,
+// var.region, output.url), its locals (local.name) and the sources of its
+// modules as imports. Nested blocks such as lifecycle are not addresses
+// of their own and are skipped.
+func walkTerraform(f *codegraph.File, root *sitter.Node, src []byte) {
+	body := child(root, "body")
+	if body == nil {
+		return
+	}
+	for _, b := range children(body) {
+		typ := child(b, "identifier")
+		if b.Type() != "block" || typ == nil {
+			continue
+		}
+		var labels []string
+		for _, c := range children(b) {
+			if c.Type() == "string_lit" {
+				labels = append(labels, strings.Trim(c.Content(src), `"`))
+			}
+		}
+		kind := typ.Content(src)
+		var name string
+		switch {
+		case kind == "resource" && len(labels) == 2:
+			name = labels[0] + "." + labels[1]
+		case kind == "data" && len(labels) == 2:
+			name = "data." + labels[0] + "." + labels[1]
+		case kind == "variable" && len(labels) == 1:
+			name = "var." + labels[0]
+		case (kind == "module" || kind == "output") && len(labels) == 1:
+			name = kind + "." + labels[0]
+		case kind == "locals":
+			for _, a := range terraformAttributes(b) {
+				if id := child(a, "identifier"); id != nil {
+					addSymbol(f, a, "local."+id.Content(src), "local")
+				}
+			}
+			continue
+		default:
+			continue
+		}
+		addSymbol(f, b, name, kind)
+		if kind == "module" {
+			for _, a := range terraformAttributes(b) {
+				if id := child(a, "identifier"); id != nil && id.Content(src) == "source" {
+					if expr := child(a, "expression"); expr != nil {
+						f.Imports = append(f.Imports, strings.Trim(expr.Content(src), `"`))
+					}
+				}
+			}
+		}
+	}
+}
+
+// terraformAttributes returns the attributes of a block's body.
+func terraformAttributes(block *sitter.Node) []*sitter.Node {
+	body := child(block, "body")
+	if body == nil {
+		return nil
+	}
+	var attrs []*sitter.Node
+	for _, c := range children(body) {
+		if c.Type() == "attribute" {
+			attrs = append(attrs, c)
+		}
+	}
+	return attrs
+}
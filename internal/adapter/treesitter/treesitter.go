@@ -0,0 +1,102 @@
+// Package treesitter registers code graph grammars that parse with
+// tree-sitter: Rust, Kotlin, Swift and Terraform. Each walks the syntax
+// tree of a file for its imports and exported declarations, including
+// nested ones such as methods and the items of public modules.
+package treesitter
+
+import (
+	"context"
+	"path"
+	"slices"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/hcl"
+	"github.com/smacker/go-tree-sitter/kotlin"
+	"github.com/smacker/go-tree-sitter/rust"
+	"github.com/smacker/go-tree-sitter/swift"
+
+	"github.com/Strob0t/CodeForge/internal/domain/codegraph"
+)
+
+func init() {
+	codegraph.RegisterGrammar(&codegraph.Grammar{
+		Name: "rust", Language: codegraph.LangRust, Extensions: []string{".rs"},
+		Test: func(p string) bool {
+			return strings.HasPrefix(p, "tests/") || strings.Contains(p, "/tests/") || strings.HasSuffix(p, "_test.rs")
+		},
+		Extract: extractor(rust.GetLanguage(), walkRust),
+	})
+	codegraph.RegisterGrammar(&codegraph.Grammar{
+		Name: "kotlin", Language: codegraph.LangKotlin, Extensions: []string{".kt", ".kts"},
+		Test: func(p string) bool {
+			base := strings.TrimSuffix(path.Base(p), path.Ext(p))
+			return strings.Contains("/"+p, "/src/test/") || strings.HasSuffix(base, "Test") || strings.HasSuffix(base, "Tests")
+		},
+		Extract: extractor(kotlin.GetLanguage(), walkKotlin),
+	})
+	codegraph.RegisterGrammar(&codegraph.Grammar{
+		Name: "swift", Language: codegraph.LangSwift, Extensions: []string{".swift"},
+		Test: func(p string) bool {
+			base := strings.TrimSuffix(path.Base(p), ".swift")
+			return strings.Contains("/"+p, "/Tests/") || strings.HasSuffix(base, "Tests") || strings.HasSuffix(base, "Test")
+		},
+		Extract: extractor(swift.GetLanguage(), walkSwift),
+	})
+	codegraph.RegisterGrammar(&codegraph.Grammar{
+		Name: "terraform", Language: codegraph.LangTerraform, Extensions: []string{".tf"},
+		Extract: extractor(hcl.GetLanguage(), walkTerraform),
+	})
+}
+
+// extractor returns an Extract function that parses a file with lang and
+// hands the root of its syntax tree to walk. Syntax errors leave error
+// nodes in the tree; walk skips them with whatever it does not know.
+func extractor(lang *sitter.Language, walk func(f *codegraph.File, root *sitter.Node, src []byte)) func(f *codegraph.File, src []byte) {
+	return func(f *codegraph.File, src []byte) {
+		p := sitter.NewParser()
+		defer p.Close()
+		p.SetLanguage(lang)
+		tree, err := p.ParseCtx(context.Background(), nil, src)
+		if err != nil {
+			return
+		}
+		defer tree.Close()
+		walk(f, tree.RootNode(), src)
+	}
+}
+
+// children returns the named children of n.
+func children(n *sitter.Node) []*sitter.Node {
+	out := make([]*sitter.Node, 0, n.NamedChildCount())
+	for i := 0; i < int(n.NamedChildCount()); i++ {
+		out = append(out, n.NamedChild(i))
+	}
+	return out
+}
+
+// child returns the first named child of n of one of the types, or nil.
+func child(n *sitter.Node, types ...string) *sitter.Node {
+	for i := 0; i < int(n.NamedChildCount()); i++ {
+		if c := n.NamedChild(i); slices.Contains(types, c.Type()) {
+			return c
+		}
+	}
+	return nil
+}
+
+// hasToken reports whether n has an anonymous child token tok, such as a
+// keyword.
+func hasToken(n *sitter.Node, tok string) bool {
+	for i := 0; i < int(n.ChildCount()); i++ {
+		if c := n.Child(i); !c.IsNamed() && c.Type() == tok {
+			return true
+		}
+	}
+	return false
+}
+
+// addSymbol records a declaration at the line where node n starts.
+func addSymbol(f *codegraph.File, n *sitter.Node, name, kind string) {
+	f.Symbols = append(f.Symbols, codegraph.Symbol{Name: name, Kind: kind, Path: f.Path, Line: int(n.StartPoint().Row) + 1})
+}
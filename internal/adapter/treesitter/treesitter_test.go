@@ -0,0 +1,109 @@
+package treesitter_test
+
+import (
+	"slices"
+	"testing"
+
+	_ "github.com/Strob0t/CodeForge/internal/adapter/treesitter"
+	"github.com/Strob0t/CodeForge/internal/domain/codegraph"
+)
+
+func symbolNames(f *codegraph.File) []string {
+	var names []string
+	for _, s := range f.Symbols {
+		names = append(names, s.Kind+" "+s.Name)
+	}
+	return names
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		path    string
+		src     string
+		lang    codegraph.Language
+		test    bool
+		imports []string
+		symbols []string
+	}{
+		{
+			path: "src/billing.rs",
+			src: "use crate::store::Store;\nuse std::{fmt, io::{self, Read}};\n\n#[derive(Debug)]\npub struct Invoice {}\n\n" +
+				"pub(crate) fn helper() {}\n\npub async fn\ncharge() {}\n\n// pub fn commented() {}\nfn private() {}\n\n" +
+				"impl Invoice {\n    pub fn total(&self) -> u32 { 0 }\n    fn secret(&self) {}\n}\n\n" +
+				"impl fmt::Display for Invoice {\n    fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result { Ok(()) }\n}\n\n" +
+				"pub mod tax {\n    pub const RATE: u32 = 19;\n    mod hidden { pub fn h() {} }\n}\n\n" +
+				"#[macro_export]\nmacro_rules! invoice { () => {} }\n",
+			lang:    codegraph.LangRust,
+			imports: []string{"crate::store::Store", "std::fmt", "std::io", "std::io::Read"},
+			symbols: []string{"struct Invoice", "fn charge", "method Invoice.total", "mod tax", "const tax::RATE", "macro invoice"},
+		},
+		{path: "tests/billing.rs", src: "use app::billing;\n", lang: codegraph.LangRust, test: true, imports: []string{"app::billing"}},
+		{
+			path: "app/src/main/kotlin/Billing.kt",
+			src: "package app\n\nimport app.store.Store\nimport app.util.*\n\ndata class Invoice(val id: String) {\n" +
+				"    fun total(): Int = 0\n    private fun secret() {}\n    companion object { fun create() = Invoice(\"\") }\n    class Line\n}\n\n" +
+				"private fun helper() {}\n\nsuspend fun <T> String.charge(x: T) {}\n\nenum class Status { OPEN }\n\n" +
+				"internal object Cache\n\ninterface Repo { fun find(): Invoice }\n\nval version = \"1\"\n",
+			lang:    codegraph.LangKotlin,
+			imports: []string{"app.store.Store", "app.util"},
+			symbols: []string{
+				"class Invoice", "method Invoice.total", "method Invoice.create", "class Invoice.Line",
+				"func charge", "class Status", "interface Repo", "method Repo.find", "val version",
+			},
+		},
+		{path: "app/src/test/kotlin/BillingTest.kt", src: "class BillingTest\n", lang: codegraph.LangKotlin, test: true, symbols: []string{"class BillingTest"}},
+		{
+			path: "Sources/Billing/Invoice.swift",
+			src: "import Foundation\n@testable import Store\n\npublic struct Invoice {\n    public func pay() {}\n" +
+				"    fileprivate func hidden() {}\n    enum Kind { case a }\n}\n\nprivate func helper() {}\n\n" +
+				"@MainActor final class Biller {}\n\nextension Invoice {\n    func refund() {}\n}\n\n" +
+				"public protocol Charger { func charge() }\n",
+			lang:    codegraph.LangSwift,
+			imports: []string{"Foundation", "Store"},
+			symbols: []string{
+				"struct Invoice", "method Invoice.pay", "enum Invoice.Kind", "class Biller",
+				"extension Invoice", "method Invoice.refund", "protocol Charger", "method Charger.charge",
+			},
+		},
+		{
+			path: "infra/main.tf",
+			src: "variable \"region\" {}\n\nresource \"aws_s3_bucket\" \"logs\" {\n  bucket = \"logs\"\n  lifecycle_rule {\n    enabled = true\n  }\n}\n\n" +
+				"data \"aws_iam_policy\" \"read\" {}\n\nmodule \"vpc\" {\n  source = \"./modules/vpc\"\n}\n\n" +
+				"locals {\n  name = \"app\"\n  # resource \"fake\" \"x\" {}\n}\n\noutput \"url\" {}\n",
+			lang:    codegraph.LangTerraform,
+			imports: []string{"./modules/vpc"},
+			symbols: []string{
+				"variable var.region", "resource aws_s3_bucket.logs", "data data.aws_iam_policy.read",
+				"module module.vpc", "local local.name", "output output.url",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			f := codegraph.Parse(tt.path, []byte(tt.src))
+			if f == nil || f.Language != tt.lang || f.Test != tt.test {
+				t.Fatalf("unexpected file %+v", f)
+			}
+			if !slices.Equal(f.Imports, tt.imports) {
+				t.Fatalf("imports = %v, want %v", f.Imports, tt.imports)
+			}
+			if got := symbolNames(f); !slices.Equal(got, tt.symbols) {
+				t.Fatalf("symbols = %v, want %v", got, tt.symbols)
+			}
+		})
+	}
+}
+
+func TestParseLines(t *testing.T) {
+	f := codegraph.Parse("lib.rs", []byte("/// Docs.\n#[inline]\npub fn\nrun() {}\n"))
+	if len(f.Symbols) != 1 || f.Symbols[0].Line != 3 {
+		t.Fatalf("expected run on line 3, got %+v", f.Symbols)
+	}
+}
+
+func TestParseSyntaxError(t *testing.T) {
+	f := codegraph.Parse("lib.rs", []byte("pub struct Ok {}\n\npub fn broken( {\n\npub enum After {}\n"))
+	if got := symbolNames(f); !slices.Contains(got, "struct Ok") {
+		t.Fatalf("expected the declarations before the error, got %v", got)
+	}
+}
@@ -0,0 +1,18 @@
+package webcrawl
+
+import (
+	"fmt"
+
+	"github.com/Strob0t/CodeForge/internal/domain/knowledge"
+	"github.com/Strob0t/CodeForge/internal/port/docsource"
+)
+
+func init() {
+	docsource.Register(string(knowledge.KindURL), func(config map[string]string) (docsource.Source, error) {
+		start := knowledge.List(config[knowledge.ConfigURL])
+		if len(start) == 0 {
+			return nil, fmt.Errorf("webcrawl: %s is required", knowledge.ConfigURL)
+		}
+		return NewSource(start, knowledge.MaxDepthOf(config)), nil
+	})
+}
@@ -0,0 +1,139 @@
+// Package webcrawl implements the docsource.Source interface by crawling
+// web pages from start URLs.
+package webcrawl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/knowledge"
+)
+
+// maxBody limits the size of a crawled page.
+const maxBody = 5 * 1024 * 1024
+
+// Source crawls HTML and plain text pages breadth-first, following links
+// on the hosts of the start URLs up to maxDepth hops away.
+type Source struct {
+	start      []string
+	maxDepth   int
+	httpClient *http.Client
+}
+
+// NewSource creates a Source for the start URLs.
+func NewSource(start []string, maxDepth int) *Source {
+	return &Source{
+		start:    start,
+		maxDepth: maxDepth,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Fetch implements docsource.Source. Pages that fail to load are skipped;
+// it fails only if no start URL could be read.
+func (s *Source) Fetch(ctx context.Context, limit int) ([]knowledge.Document, error) {
+	type queued struct {
+		url   string
+		depth int
+	}
+	hosts := make(map[string]bool)
+	seen := make(map[string]bool)
+	var queue []queued
+	for _, raw := range s.start {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("webcrawl: start URL %q: %w", raw, err)
+		}
+		u.Fragment = ""
+		hosts[u.Host] = true
+		queue = append(queue, queued{u.String(), 0})
+		seen[u.String()] = true
+	}
+
+	var docs []knowledge.Document
+	var firstErr error
+	for len(queue) > 0 && len(docs) < limit {
+		q := queue[0]
+		queue = queue[1:]
+		doc, links, err := s.fetch(ctx, q.url)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			slog.Debug("webcrawl: skip page", "url", q.url, "error", err)
+			continue
+		}
+		if doc.Content != "" {
+			docs = append(docs, doc)
+		}
+		if q.depth >= s.maxDepth {
+			continue
+		}
+		for _, l := range links {
+			u, err := url.Parse(l)
+			if err != nil || !hosts[u.Host] || seen[l] {
+				continue
+			}
+			seen[l] = true
+			queue = append(queue, queued{l, q.depth + 1})
+		}
+	}
+	if len(docs) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return docs, nil
+}
+
+// fetch loads a page and returns it as a document with the links of HTML
+// pages. Other content types are an error.
+func (s *Source) fetch(ctx context.Context, u string) (knowledge.Document, []string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return knowledge.Document{}, nil, fmt.Errorf("webcrawl: create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/html, text/plain;q=0.9")
+	req.Header.Set("User-Agent", "CodeForge knowledge crawler")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return knowledge.Document{}, nil, fmt.Errorf("webcrawl: get %s: %w", u, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return knowledge.Document{}, nil, fmt.Errorf("webcrawl: get %s: status %d", u, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	if err != nil {
+		return knowledge.Document{}, nil, fmt.Errorf("webcrawl: read %s: %w", u, err)
+	}
+
+	// Redirects may have moved the page; links resolve against its final URL.
+	final := resp.Request.URL
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/html", "application/xhtml+xml":
+		title, text := knowledge.HTMLText(string(data))
+		if title == "" {
+			title = path.Base(final.Path)
+		}
+		return knowledge.Document{URL: final.String(), Title: title, Content: text}, knowledge.Links(final, string(data)), nil
+	case "text/plain", "text/markdown":
+		return knowledge.Document{URL: final.String(), Title: path.Base(final.Path), Content: strings.TrimSpace(string(data))}, nil, nil
+	default:
+		return knowledge.Document{}, nil, fmt.Errorf("webcrawl: get %s: unsupported content type %q", u, mediaType)
+	}
+}
@@ -0,0 +1,74 @@
+package webcrawl_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	_ "github.com/Strob0t/CodeForge/internal/adapter/webcrawl"
+	"github.com/Strob0t/CodeForge/internal/domain/knowledge"
+	"github.com/Strob0t/CodeForge/internal/port/docsource"
+)
+
+func TestFetch_Crawl(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("expected links to other hosts to be skipped")
+	}))
+	defer other.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/docs/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<title>Docs</title><p>Welcome.</p>
+<a href="install">Install</a> <a href="/notes.txt">Notes</a> <a href="/logo.png">Logo</a> <a href="` + other.URL + `">Elsewhere</a>`))
+	})
+	mux.HandleFunc("/docs/install", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<title>Install</title><p>Run the installer.</p><a href="/docs/deep">Deep</a>`))
+	})
+	mux.HandleFunc("/notes.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("Release notes\n"))
+	})
+	mux.HandleFunc("/logo.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+	})
+	mux.HandleFunc("/docs/deep", func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("expected the crawl to stop at max_depth")
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	src, err := docsource.New(string(knowledge.KindURL), map[string]string{knowledge.ConfigURL: srv.URL + "/docs/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, err := src.Fetch(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []knowledge.Document{
+		{URL: srv.URL + "/docs/", Title: "Docs", Content: "Welcome.\n\nInstall Notes Logo Elsewhere"},
+		{URL: srv.URL + "/docs/install", Title: "Install", Content: "Run the installer.\nDeep"},
+		{URL: srv.URL + "/notes.txt", Title: "notes.txt", Content: "Release notes"},
+	}
+	if len(docs) != len(want) {
+		t.Fatalf("got %+v, want %+v", docs, want)
+	}
+	for i := range want {
+		if docs[i] != want[i] {
+			t.Fatalf("document %d = %+v, want %+v", i, docs[i], want[i])
+		}
+	}
+}
+
+func TestFetch_StartUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	src, _ := docsource.New(string(knowledge.KindURL), map[string]string{knowledge.ConfigURL: srv.URL})
+	if _, err := src.Fetch(context.Background(), 10); err == nil {
+		t.Fatal("expected error when no page could be read")
+	}
+}
@@ -12,6 +12,9 @@ const (
 	EventTaskOutput  = "task.output"
 	EventAgentStatus = "agent.status"
 
+	// Automatic agent scaling
+	EventAgentLifecycle = "agent.lifecycle"
+
 	// Run protocol events (Phase 4B)
 	EventRunStatus      = "run.status"
 	EventToolCallStatus = "run.toolcall"
@@ -23,10 +26,21 @@ const (
 	// Phase 5A: orchestration plan events
 	EventPlanStatus     = "plan.status"
 	EventPlanStepStatus = "plan.step.status"
+	EventPlanApproval   = "plan.approval"
 
 	// Phase 5E: team + shared context events
 	EventTeamStatus          = "team.status"
 	EventSharedContextUpdate = "shared.updated"
+
+	// Research run events
+	EventResearchReport = "run.research"
+
+	// Conversation events
+	EventConversationDelta   = "conversation.delta"
+	EventConversationMessage = "conversation.message"
+
+	// Retrieval index migration events
+	EventIndexMigration = "retrieval.migration"
 )
 
 // TaskStatusEvent is broadcast when a task's status changes.
@@ -40,6 +54,7 @@ type TaskStatusEvent struct {
 // TaskOutputEvent is broadcast when a task produces streaming output.
 type TaskOutputEvent struct {
 	TaskID string `json:"task_id"`
+	RunID  string `json:"run_id,omitempty"`
 	Line   string `json:"line"`
 	Stream string `json:"stream"` // "stdout" or "stderr"
 }
@@ -51,6 +66,16 @@ type AgentStatusEvent struct {
 	Status    string `json:"status"`
 }
 
+// AgentLifecycleEvent is broadcast when automatic scaling spawns (creates
+// or revives) or retires a clone of an agent.
+type AgentLifecycleEvent struct {
+	AgentID    string `json:"agent_id"`
+	ProjectID  string `json:"project_id"`
+	TemplateID string `json:"template_id"` // Agent it was cloned from
+	Name       string `json:"name"`
+	Phase      string `json:"phase"` // "spawned", "retired"
+}
+
 // RunStatusEvent is broadcast when a run's status or metrics change.
 type RunStatusEvent struct {
 	RunID     string  `json:"run_id"`
@@ -59,15 +84,17 @@ type RunStatusEvent struct {
 	Status    string  `json:"status"`
 	StepCount int     `json:"step_count"`
 	CostUSD   float64 `json:"cost_usd,omitempty"`
+	Summary   string  `json:"summary,omitempty"` // Run summary, on completion
 }
 
 // ToolCallStatusEvent is broadcast for tool call lifecycle events.
 type ToolCallStatusEvent struct {
-	RunID    string `json:"run_id"`
-	CallID   string `json:"call_id"`
-	Tool     string `json:"tool"`
-	Decision string `json:"decision,omitempty"`
-	Phase    string `json:"phase"` // "requested", "approved", "denied", "result"
+	RunID     string `json:"run_id"`
+	ProjectID string `json:"project_id,omitempty"`
+	CallID    string `json:"call_id"`
+	Tool      string `json:"tool"`
+	Decision  string `json:"decision,omitempty"`
+	Phase     string `json:"phase"` // "requested", "approved", "denied", "result"
 }
 
 // QualityGateEvent is broadcast when a quality gate starts, passes, or fails.
@@ -83,16 +110,18 @@ type QualityGateEvent struct {
 
 // DeliveryEvent is broadcast when output delivery starts, completes, or fails.
 type DeliveryEvent struct {
-	RunID      string `json:"run_id"`
-	TaskID     string `json:"task_id"`
-	ProjectID  string `json:"project_id"`
-	Status     string `json:"status"` // "started", "completed", "failed"
-	Mode       string `json:"mode"`
-	PatchPath  string `json:"patch_path,omitempty"`
-	CommitHash string `json:"commit_hash,omitempty"`
-	BranchName string `json:"branch_name,omitempty"`
-	PRURL      string `json:"pr_url,omitempty"`
-	Error      string `json:"error,omitempty"`
+	RunID      string   `json:"run_id"`
+	TaskID     string   `json:"task_id"`
+	ProjectID  string   `json:"project_id"`
+	Status     string   `json:"status"` // "started", "completed", "failed", "failed_downstream"
+	Mode       string   `json:"mode"`
+	PatchPath  string   `json:"patch_path,omitempty"`
+	CommitHash string   `json:"commit_hash,omitempty"`
+	BranchName string   `json:"branch_name,omitempty"`
+	PRURL      string   `json:"pr_url,omitempty"`
+	Artifacts  []string `json:"artifacts,omitempty"` // Patch bundle artifact IDs of a mirror delivery
+	Warnings   []string `json:"warnings,omitempty"`  // Ways the commit and branch fail the project's git policy
+	Error      string   `json:"error,omitempty"`
 }
 
 // PlanStatusEvent is broadcast when an execution plan's status changes.
@@ -110,6 +139,23 @@ type PlanStepStatusEvent struct {
 	Status    string `json:"status"`
 	RunID     string `json:"run_id,omitempty"`
 	Error     string `json:"error,omitempty"`
+	Attempt   int    `json:"attempt,omitempty"`
+}
+
+// PlanApprovalEvent is broadcast when an approval step is reached or resolved.
+type PlanApprovalEvent struct {
+	PlanID    string `json:"plan_id"`
+	StepID    string `json:"step_id"`
+	ProjectID string `json:"project_id"`
+	Phase     string `json:"phase"` // "requested", "voted", "escalated", "approved", "rejected"
+	URL       string `json:"url,omitempty"`
+	By        string `json:"by,omitempty"`
+	Comment   string `json:"comment,omitempty"`
+	Approvals int    `json:"approvals,omitempty"` // Approving votes so far
+	Quorum    int    `json:"quorum,omitempty"`    // Approving votes needed
+
+	ExpiresAt  string   `json:"expires_at,omitempty"`  // RFC 3339 time the approval policy's timeout action applies
+	EscalateTo []string `json:"escalate_to,omitempty"` // Named by an escalation
 }
 
 // TeamStatusEvent is broadcast when a team's status changes.
@@ -128,6 +174,56 @@ type SharedContextUpdateEvent struct {
 	Version int    `json:"version"`
 }
 
+// ResearchReportEvent is broadcast when a research run stores its report.
+type ResearchReportEvent struct {
+	RunID          string `json:"run_id"`
+	ProjectID      string `json:"project_id"`
+	FollowUpTaskID string `json:"follow_up_task_id,omitempty"`
+	ArtifactID     string `json:"artifact_id,omitempty"`
+	Findings       int    `json:"findings"`
+	Denied         int    `json:"denied"`
+	Status         string `json:"status"`
+	Error          string `json:"error,omitempty"`
+}
+
+// ConversationDeltaEvent is broadcast for each piece of a conversation
+// reply while the model streams it.
+type ConversationDeltaEvent struct {
+	ConversationID string `json:"conversation_id"`
+	ProjectID      string `json:"project_id"`
+	Delta          string `json:"delta"`
+}
+
+// ConversationMessageEvent is broadcast when a conversation reply, or a
+// tool call or answer of a run started in the conversation, has been
+// stored.
+type ConversationMessageEvent struct {
+	ConversationID   string `json:"conversation_id"`
+	ProjectID        string `json:"project_id"`
+	MessageID        string `json:"message_id"`
+	Seq              int    `json:"seq"`
+	Role             string `json:"role"`
+	Content          string `json:"content"`
+	Tool             string `json:"tool,omitempty"` // Name of the tool of a tool message
+	Model            string `json:"model,omitempty"`
+	PromptTokens     int    `json:"prompt_tokens,omitempty"`
+	CompletionTokens int    `json:"completion_tokens,omitempty"`
+}
+
+// IndexMigrationEvent is broadcast when a retrieval index migration changes
+// phase and after each batch of chunks it embeds.
+type IndexMigrationEvent struct {
+	ProjectID    string   `json:"project_id"`
+	Phase        string   `json:"phase"`
+	Provider     string   `json:"provider"` // Target embedding
+	Model        string   `json:"model"`
+	Embedded     int      `json:"embedded"`
+	Chunks       int      `json:"chunks"`
+	RecallBefore *float64 `json:"recall_before,omitempty"`
+	RecallAfter  *float64 `json:"recall_after,omitempty"`
+	Error        string   `json:"error,omitempty"`
+}
+
 // BroadcastEvent is a convenience method that marshals a typed event and broadcasts it.
 func (h *Hub) BroadcastEvent(ctx context.Context, eventType string, payload any) {
 	data, err := json.Marshal(payload)
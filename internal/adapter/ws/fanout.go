@@ -0,0 +1,114 @@
+package ws
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+)
+
+// unscopedTenant ends the fan-out subject of events not scoped to a tenant.
+// It is not a valid tenant ID.
+const unscopedTenant = "_"
+
+// idRangeBits is the size of the event ID range of one server. With a
+// fan-out, servers number the same events differently; each starts at a
+// random multiple of 2^idRangeBits, so a last_event_id from another server
+// is not replayable and the client gets snapshots instead of wrong events.
+// IDs stay below 2^53 to survive JSON numbers in browsers.
+const idRangeBits = 40
+
+// fanOutEvent is an event relayed between servers.
+type fanOutEvent struct {
+	Server  string  `json:"server"`
+	Tenant  string  `json:"tenant,omitempty"`
+	Message Message `json:"message"`
+}
+
+// Stats are the counters of the hub of one server.
+type Stats struct {
+	Server      string `json:"server,omitempty"`
+	FanOut      bool   `json:"fan_out"`
+	Connections int    `json:"connections"`
+	LastEventID uint64 `json:"last_event_id"`
+	Local       int64  `json:"local"`        // Events broadcast by this server
+	Remote      int64  `json:"remote"`       // Events received from other servers
+	RelayErrors int64  `json:"relay_errors"` // Events delivered only here because the fan-out failed
+	Dropped     int64  `json:"dropped"`      // Clients disconnected for falling behind
+}
+
+// StartFanOut relays broadcasts through f on subject ws.events.{tenant} and
+// delivers the events of all servers to this hub's clients. server
+// identifies this server in the relayed events. Cancel stops relaying.
+func (h *Hub) StartFanOut(f messagequeue.FanOut, server string) (cancel func(), err error) {
+	stop, err := f.SubscribeFanOut(messagequeue.SubjectWSEvents+".>", h.receive)
+	if err != nil {
+		return nil, err
+	}
+
+	var b [2]byte
+	_, _ = rand.Read(b[:])
+	base := uint64(binary.BigEndian.Uint16(b[:])&0x1fff|1) << idRangeBits
+
+	h.mu.Lock()
+	h.fanout = f
+	h.server = server
+	h.seq = max(h.seq, base)
+	h.mu.Unlock()
+
+	return func() {
+		h.mu.Lock()
+		h.fanout = nil
+		h.mu.Unlock()
+		stop()
+	}, nil
+}
+
+// Stats returns the hub's counters.
+func (h *Hub) Stats() Stats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return Stats{
+		Server:      h.server,
+		FanOut:      h.fanout != nil,
+		Connections: len(h.conns),
+		LastEventID: h.seq,
+		Local:       h.local.Load(),
+		Remote:      h.remote.Load(),
+		RelayErrors: h.relayErrors.Load(),
+		Dropped:     h.dropped.Load(),
+	}
+}
+
+// fanOut returns the fan-out broadcasts are relayed through, if any.
+func (h *Hub) fanOut() messagequeue.FanOut {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.fanout
+}
+
+// relay publishes an event to all servers.
+func (h *Hub) relay(ctx context.Context, f messagequeue.FanOut, tenantID string, msg Message) error {
+	data, err := json.Marshal(fanOutEvent{Server: h.server, Tenant: tenantID, Message: msg})
+	if err != nil {
+		return err
+	}
+	return f.PublishFanOut(ctx, messagequeue.SubjectWSEvents+"."+cmp.Or(tenantID, unscopedTenant), data)
+}
+
+// receive delivers an event relayed by any server, this one included.
+func (h *Hub) receive(_ string, data []byte) {
+	var ev fanOutEvent
+	if err := json.Unmarshal(data, &ev); err != nil {
+		slog.Warn("websocket fan-out: invalid event", "error", err)
+		return
+	}
+	if ev.Server != h.server {
+		h.remote.Add(1)
+	}
+	h.deliver(ev.Tenant, ev.Message)
+}
@@ -0,0 +1,186 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+)
+
+// memoryFanOut is an in-memory messagequeue.FanOut that delivers
+// synchronously to all subscribers.
+type memoryFanOut struct {
+	mu   sync.Mutex
+	subs map[int]func(string, []byte)
+	next int
+	fail bool
+}
+
+func (f *memoryFanOut) PublishFanOut(_ context.Context, subject string, data []byte) error {
+	f.mu.Lock()
+	if f.fail {
+		f.mu.Unlock()
+		return errors.New("nats unavailable")
+	}
+	subs := make([]func(string, []byte), 0, len(f.subs))
+	for _, fn := range f.subs {
+		subs = append(subs, fn)
+	}
+	f.mu.Unlock()
+	for _, fn := range subs {
+		fn(subject, data)
+	}
+	return nil
+}
+
+func (f *memoryFanOut) SubscribeFanOut(_ string, fn func(string, []byte)) (func(), error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.subs == nil {
+		f.subs = make(map[int]func(string, []byte))
+	}
+	id := f.next
+	f.next++
+	f.subs[id] = fn
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.subs, id)
+	}, nil
+}
+
+func fanOutHub(t *testing.T, f *memoryFanOut, server string) *Hub {
+	t.Helper()
+	hub := NewHub()
+	cancel, err := hub.StartFanOut(f, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cancel)
+	return hub
+}
+
+// dialTenant connects a client scoped to a tenant.
+func dialTenant(t *testing.T, hub *Hub, tenantID string) *websocket.Conn {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hub.HandleWS(w, r.WithContext(tenant.NewContext(r.Context(), tenantID)))
+	}))
+	t.Cleanup(srv.Close)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	before := hub.ConnectionCount()
+	c, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = c.CloseNow() })
+	deadline := time.Now().Add(5 * time.Second)
+	for hub.ConnectionCount() == before && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return c
+}
+
+func TestFanOutDeliversEventsOfOtherServers(t *testing.T) {
+	f := &memoryFanOut{}
+	a := fanOutHub(t, f, "server-a")
+	b := fanOutHub(t, f, "server-b")
+	onB := dial(t, b)
+
+	a.BroadcastEvent(context.Background(), EventTaskOutput, runOutput("r1", "from a"))
+	if m := recv(t, onB); !strings.Contains(string(m.Payload), "from a") {
+		t.Fatalf("expected the event of server-a, got %s %s", m.Type, m.Payload)
+	}
+
+	sa, sb := a.Stats(), b.Stats()
+	if sa.Local != 1 || sa.Remote != 0 || sb.Remote != 1 || sb.Connections != 1 || !sb.FanOut {
+		t.Errorf("unexpected stats %+v %+v", sa, sb)
+	}
+}
+
+func TestFanOutScopesEventsToTenants(t *testing.T) {
+	f := &memoryFanOut{}
+	a := fanOutHub(t, f, "server-a")
+	b := fanOutHub(t, f, "server-b")
+	a.SetTenantSource(fakeTenants{"run:r3": "acme"})
+	acme := dialTenant(t, b, "acme")
+	globex := dialTenant(t, b, "globex")
+	operator := dial(t, b)
+
+	a.BroadcastEvent(tenant.NewContext(context.Background(), "globex"), EventTaskOutput, runOutput("r1", "globex only"))
+	a.BroadcastEvent(tenant.NewContext(context.Background(), "acme"), EventTaskOutput, runOutput("r2", "acme only"))
+	// Events without a tenant are scoped to the owner of their run; events
+	// of no known owner only reach unscoped clients.
+	a.BroadcastEvent(context.Background(), EventTaskOutput, runOutput("r3", "acme's run"))
+	a.BroadcastEvent(context.Background(), EventTaskOutput, runOutput("r4", "unscoped"))
+	a.BroadcastEvent(tenant.NewContext(context.Background(), "globex"), EventTaskOutput, runOutput("r5", "globex again"))
+
+	for _, want := range []string{"acme only", "acme's run"} {
+		if m := recv(t, acme); !strings.Contains(string(m.Payload), want) {
+			t.Fatalf("expected %q for acme, got %s", want, m.Payload)
+		}
+	}
+	for _, want := range []string{"globex only", "globex again"} {
+		if m := recv(t, globex); !strings.Contains(string(m.Payload), want) {
+			t.Fatalf("expected %q for globex, got %s", want, m.Payload)
+		}
+	}
+	for _, want := range []string{"globex only", "acme only", "acme's run", "unscoped", "globex again"} {
+		if m := recv(t, operator); !strings.Contains(string(m.Payload), want) {
+			t.Fatalf("expected %q for the unscoped client, got %s", want, m.Payload)
+		}
+	}
+}
+
+type fakeTenants map[string]string
+
+func (f fakeTenants) TenantOf(_ context.Context, kind, id string) (string, error) {
+	if v, ok := f[kind+":"+id]; ok {
+		return v, nil
+	}
+	return "", errors.New("not found")
+}
+
+func TestFanOutFailureDeliversLocally(t *testing.T) {
+	f := &memoryFanOut{}
+	a := fanOutHub(t, f, "server-a")
+	c := dial(t, a)
+
+	f.mu.Lock()
+	f.fail = true
+	f.mu.Unlock()
+	a.BroadcastEvent(context.Background(), EventTaskOutput, runOutput("r1", "local"))
+	if m := recv(t, c); !strings.Contains(string(m.Payload), "local") {
+		t.Fatalf("expected the event, got %s", m.Payload)
+	}
+	if s := a.Stats(); s.RelayErrors != 1 {
+		t.Errorf("expected 1 relay error, got %+v", s)
+	}
+}
+
+func TestFanOutEventIDsOfOtherServersAreNotReplayed(t *testing.T) {
+	f := &memoryFanOut{}
+	a := fanOutHub(t, f, "server-a")
+	b := fanOutHub(t, f, "server-b")
+	b.SetSnapshotSource(fakeSnapshots{"run:r1": map[string]string{"id": "r1"}})
+	a.BroadcastEvent(context.Background(), EventTaskOutput, runOutput("r1", "x"))
+	if a.Stats().LastEventID>>idRangeBits == b.Stats().LastEventID>>idRangeBits {
+		t.Skip("both servers drew the same ID range")
+	}
+
+	// A client of server-a reconnects to server-b with a-numbered IDs.
+	c := dial(t, b)
+	send(t, c, clientMessage{Action: ActionSubscribe, Topics: []string{"run:r1"}, LastEventID: a.Stats().LastEventID - 1})
+	if m := recv(t, c); m.Type != EventSnapshot {
+		t.Fatalf("expected a snapshot instead of a replay, got %s %s", m.Type, m.Payload)
+	}
+}
@@ -6,14 +6,36 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/coder/websocket"
+
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+)
+
+const (
+	// replayBufferSize is the number of recent events kept for replay.
+	replayBufferSize = 1024
+	// sendQueueSize is the number of messages queued per connection before
+	// the client is considered too slow and disconnected.
+	sendQueueSize = 256
+	// heartbeatInterval is the time between heartbeats.
+	heartbeatInterval = 30 * time.Second
+	// writeTimeout bounds a single write to a client.
+	writeTimeout = 10 * time.Second
 )
 
-// Message is the envelope for all WebSocket messages.
+// Message is the envelope for all WebSocket messages. Broadcast events
+// carry an increasing ID that clients pass back as last_event_id to replay
+// what they missed.
 type Message struct {
+	ID      uint64          `json:"id,omitempty"`
 	Type    string          `json:"type"`
+	Topic   string          `json:"topic,omitempty"` // Set on snapshots
 	Payload json.RawMessage `json:"payload"`
 }
 
@@ -21,21 +43,102 @@ type Message struct {
 type conn struct {
 	ws     *websocket.Conn
 	cancel context.CancelFunc
+	send   chan []byte
+	// tenant is the tenant the connection is scoped to; "" receives the
+	// events of all tenants.
+	tenant string
+	// subs holds the subscribed topics. A nil set means the client never
+	// subscribed and receives every event.
+	subs map[string]struct{}
 }
 
-// Hub manages all active WebSocket connections and broadcasts messages.
+// entry is a broadcast event kept for replay.
+type entry struct {
+	id     uint64
+	tenant string
+	topics []string
+	data   []byte
+}
+
+// Hub manages all active WebSocket connections and broadcasts messages to
+// the clients subscribed to their topics. With a fan-out (see StartFanOut)
+// broadcasts are relayed through it, so the clients of every server
+// receive the events of all of them.
 type Hub struct {
-	mu    sync.RWMutex
-	conns map[*conn]struct{}
+	mu        sync.RWMutex
+	conns     map[*conn]struct{}
+	seq       uint64
+	buffer    []entry // Last replayBufferSize events, oldest first
+	snapshots SnapshotSource
+	heartbeat time.Duration
+
+	tenants     TenantSource
+	tenantMu    sync.Mutex
+	tenantCache map[string]string // Tenant by topic
+
+	fanout messagequeue.FanOut
+	server string
+
+	local       atomic.Int64
+	remote      atomic.Int64
+	relayErrors atomic.Int64
+	dropped     atomic.Int64
 }
 
 // NewHub creates a new WebSocket hub.
 func NewHub() *Hub {
 	return &Hub{
-		conns: make(map[*conn]struct{}),
+		conns:     make(map[*conn]struct{}),
+		heartbeat: heartbeatInterval,
 	}
 }
 
+// SetSnapshotSource sets the source of the initial state sent to clients
+// that subscribe without a replayable last event ID.
+func (h *Hub) SetSnapshotSource(s SnapshotSource) {
+	h.snapshots = s
+}
+
+// SetTenantSource scopes events broadcast without a tenant to the tenant
+// owning the run, project, plan, task, team or conversation they refer to.
+func (h *Hub) SetTenantSource(s TenantSource) {
+	h.tenants = s
+}
+
+// tenantCacheSize bounds the topics whose tenant is remembered; the cache
+// is emptied when it is full.
+const tenantCacheSize = 10000
+
+// ownerTenant returns the tenant owning the subject of the first topic of
+// a payload the tenant source resolves, or "" if none does.
+func (h *Hub) ownerTenant(ctx context.Context, payload json.RawMessage) string {
+	if h.tenants == nil {
+		return ""
+	}
+	for _, topic := range topicsOf(payload) {
+		h.tenantMu.Lock()
+		id, ok := h.tenantCache[topic]
+		h.tenantMu.Unlock()
+		if ok {
+			return id
+		}
+		kind, subject, _ := strings.Cut(topic, ":")
+		id, err := h.tenants.TenantOf(ctx, kind, subject)
+		if err != nil {
+			slog.Debug("resolve event tenant", "topic", topic, "error", err)
+			continue
+		}
+		h.tenantMu.Lock()
+		if h.tenantCache == nil || len(h.tenantCache) >= tenantCacheSize {
+			h.tenantCache = make(map[string]string)
+		}
+		h.tenantCache[topic] = id
+		h.tenantMu.Unlock()
+		return id
+	}
+	return ""
+}
+
 // HandleWS returns an http.HandlerFunc that upgrades connections to WebSocket.
 func (h *Hub) HandleWS(w http.ResponseWriter, r *http.Request) {
 	ws, err := websocket.Accept(w, r, &websocket.AcceptOptions{
@@ -46,8 +149,9 @@ func (h *Hub) HandleWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx, cancel := context.WithCancel(r.Context())
-	c := &conn{ws: ws, cancel: cancel}
+	// The connection outlives the request context.
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	c := &conn{ws: ws, cancel: cancel, send: make(chan []byte, sendQueueSize), tenant: tenant.FromContext(r.Context())}
 
 	h.mu.Lock()
 	h.conns[c] = struct{}{}
@@ -55,36 +159,94 @@ func (h *Hub) HandleWS(w http.ResponseWriter, r *http.Request) {
 
 	slog.Info("websocket connected", "remote", r.RemoteAddr)
 
-	// Read loop (to detect disconnects and consume pings)
+	go h.writeLoop(ctx, c)
+
+	// Read loop: handles subscription requests and detects disconnects.
 	go func() {
 		defer func() {
 			h.remove(c)
 			_ = ws.Close(websocket.StatusNormalClosure, "")
 		}()
 		for {
-			_, _, err := ws.Read(ctx)
+			_, data, err := ws.Read(ctx)
 			if err != nil {
 				return
 			}
+			h.handleClientMessage(ctx, c, data)
 		}
 	}()
 }
 
-// Broadcast sends a message to all connected clients.
+// writeLoop sends queued messages and heartbeats to a client.
+func (h *Hub) writeLoop(ctx context.Context, c *conn) {
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+	for {
+		var data []byte
+		select {
+		case <-ctx.Done():
+			return
+		case data = <-c.send:
+		case <-ticker.C:
+			data = h.heartbeatMessage()
+		}
+		wctx, cancel := context.WithTimeout(ctx, writeTimeout)
+		err := c.ws.Write(wctx, websocket.MessageText, data)
+		cancel()
+		if err != nil {
+			slog.Debug("websocket write failed", "error", err)
+			h.remove(c)
+			return
+		}
+	}
+}
+
+// Broadcast sends msg to the clients of the tenant of ctx or, if ctx is
+// not scoped, of the tenant owning the event's subject (see
+// SetTenantSource). Events of no tenant only go to clients that are not
+// scoped to one. With a fan-out the event is relayed to every server,
+// including this one; if relaying fails it is only delivered here.
 func (h *Hub) Broadcast(ctx context.Context, msg Message) {
+	tenantID := tenant.FromContext(ctx)
+	if tenantID == "" {
+		tenantID = h.ownerTenant(ctx, msg.Payload)
+	}
+	h.local.Add(1)
+	if f := h.fanOut(); f != nil {
+		err := h.relay(ctx, f, tenantID, msg)
+		if err == nil {
+			return
+		}
+		h.relayErrors.Add(1)
+		slog.Warn("websocket fan-out failed, delivering locally", "error", err)
+	}
+	h.deliver(tenantID, msg)
+}
+
+// deliver assigns the next event ID to msg, keeps it for replay and
+// queues it for every client of the tenant subscribed to one of its
+// topics.
+func (h *Hub) deliver(tenantID string, msg Message) {
+	topics := topicsOf(msg.Payload)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	msg.ID = h.seq
 	data, err := json.Marshal(msg)
 	if err != nil {
 		slog.Error("websocket marshal failed", "error", err)
 		return
 	}
-
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+	h.buffer = append(h.buffer, entry{id: msg.ID, tenant: tenantID, topics: topics, data: data})
+	if len(h.buffer) > replayBufferSize {
+		h.buffer = h.buffer[len(h.buffer)-replayBufferSize:]
+	}
 
 	for c := range h.conns {
-		if err := c.ws.Write(ctx, websocket.MessageText, data); err != nil {
-			slog.Debug("websocket write failed", "error", err)
-			go h.remove(c)
+		if c.receives(tenantID) && c.wants(topics) {
+			h.enqueueLocked(c, data)
 		}
 	}
 }
@@ -96,10 +258,25 @@ func (h *Hub) ConnectionCount() int {
 	return len(h.conns)
 }
 
+// enqueueLocked queues data for c, disconnecting clients that cannot keep
+// up. h.mu must be held.
+func (h *Hub) enqueueLocked(c *conn, data []byte) {
+	select {
+	case c.send <- data:
+	default:
+		slog.Warn("websocket client too slow, disconnecting")
+		h.dropped.Add(1)
+		h.removeLocked(c)
+	}
+}
+
 func (h *Hub) remove(c *conn) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
+	h.removeLocked(c)
+}
 
+func (h *Hub) removeLocked(c *conn) {
 	if _, ok := h.conns[c]; ok {
 		c.cancel()
 		delete(h.conns, c)
@@ -0,0 +1,84 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Strob0t/CodeForge/internal/port/database"
+)
+
+// StoreSnapshots serves topic snapshots from the database: the run, project,
+// plan (with steps), task, team or conversation a topic refers to. It is
+// also the TenantSource of these topics.
+type StoreSnapshots struct {
+	store database.Store
+}
+
+// NewStoreSnapshots creates a SnapshotSource backed by store.
+func NewStoreSnapshots(store database.Store) *StoreSnapshots {
+	return &StoreSnapshots{store: store}
+}
+
+// Snapshot returns the current record of the topic's subject.
+func (s *StoreSnapshots) Snapshot(ctx context.Context, kind, id string) (any, error) {
+	switch kind {
+	case TopicRun:
+		return s.store.GetRun(ctx, id)
+	case TopicProject:
+		return s.store.GetProject(ctx, id)
+	case TopicPlan:
+		return s.store.GetPlan(ctx, id)
+	case TopicTask:
+		return s.store.GetTask(ctx, id)
+	case TopicTeam:
+		return s.store.GetTeam(ctx, id)
+	case TopicConversation:
+		return s.store.GetConversation(ctx, id)
+	}
+	return nil, fmt.Errorf("no snapshot for topic kind %q", kind)
+}
+
+// TenantOf returns the tenant of the project the topic's subject belongs to.
+func (s *StoreSnapshots) TenantOf(ctx context.Context, kind, id string) (string, error) {
+	projectID := id
+	switch kind {
+	case TopicProject:
+	case TopicRun:
+		r, err := s.store.GetRun(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		projectID = r.ProjectID
+	case TopicPlan:
+		p, err := s.store.GetPlan(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		projectID = p.ProjectID
+	case TopicTask:
+		t, err := s.store.GetTask(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		projectID = t.ProjectID
+	case TopicTeam:
+		t, err := s.store.GetTeam(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		projectID = t.ProjectID
+	case TopicConversation:
+		c, err := s.store.GetConversation(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		projectID = c.ProjectID
+	default:
+		return "", fmt.Errorf("no tenant for topic kind %q", kind)
+	}
+	p, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return "", err
+	}
+	return p.TenantID, nil
+}
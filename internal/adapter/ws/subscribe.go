@@ -0,0 +1,292 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Client actions of the subscription protocol. A client sends
+//
+//	{"action": "subscribe", "topics": ["run:<id>"], "last_event_id": 42, "request_id": "1"}
+//
+// and from then on only receives events of its topics. Clients that never
+// subscribe receive every event.
+const (
+	ActionSubscribe   = "subscribe"
+	ActionUnsubscribe = "unsubscribe"
+	ActionPing        = "ping"
+)
+
+// Server messages of the subscription protocol.
+const (
+	EventAck       = "ack"
+	EventError     = "error"
+	EventSnapshot  = "snapshot"
+	EventHeartbeat = "heartbeat"
+)
+
+// Topic kinds. A topic is "<kind>:<id>"; an event belongs to the topics of
+// the IDs in its payload (run_id, project_id, plan_id, task_id, team_id,
+// conversation_id).
+const (
+	TopicRun          = "run"
+	TopicProject      = "project"
+	TopicPlan         = "plan"
+	TopicTask         = "task"
+	TopicTeam         = "team"
+	TopicConversation = "conversation"
+)
+
+// maxTopics limits the subscriptions of one connection.
+const maxTopics = 64
+
+// SnapshotSource returns the current state of the subject of a topic,
+// e.g. the run for "run:<id>".
+type SnapshotSource interface {
+	Snapshot(ctx context.Context, kind, id string) (any, error)
+}
+
+// TenantSource returns the tenant owning the subject of a topic, e.g. the
+// tenant of the run's project for "run:<id>".
+type TenantSource interface {
+	TenantOf(ctx context.Context, kind, id string) (string, error)
+}
+
+// clientMessage is a request sent by a client.
+type clientMessage struct {
+	Action      string   `json:"action"`
+	Topics      []string `json:"topics"`
+	LastEventID uint64   `json:"last_event_id,omitempty"`
+	RequestID   string   `json:"request_id,omitempty"`
+}
+
+// AckPayload confirms a client request. LastEventID is the ID of the latest
+// event at the time of the acknowledgement.
+type AckPayload struct {
+	Action      string   `json:"action"`
+	RequestID   string   `json:"request_id,omitempty"`
+	Topics      []string `json:"topics,omitempty"`
+	Replayed    int      `json:"replayed,omitempty"`
+	LastEventID uint64   `json:"last_event_id"`
+}
+
+// ErrorPayload reports a rejected client request.
+type ErrorPayload struct {
+	RequestID string `json:"request_id,omitempty"`
+	Message   string `json:"message"`
+}
+
+// HeartbeatPayload is sent periodically so clients can detect stale
+// connections and missed events.
+type HeartbeatPayload struct {
+	LastEventID uint64 `json:"last_event_id"`
+}
+
+// topicsOf returns the topics of an event payload.
+func topicsOf(payload json.RawMessage) []string {
+	var ids struct {
+		RunID          string `json:"run_id"`
+		ProjectID      string `json:"project_id"`
+		PlanID         string `json:"plan_id"`
+		TaskID         string `json:"task_id"`
+		TeamID         string `json:"team_id"`
+		ConversationID string `json:"conversation_id"`
+	}
+	if err := json.Unmarshal(payload, &ids); err != nil {
+		return nil
+	}
+	var topics []string
+	for _, t := range [][2]string{
+		{TopicRun, ids.RunID}, {TopicProject, ids.ProjectID}, {TopicPlan, ids.PlanID},
+		{TopicTask, ids.TaskID}, {TopicTeam, ids.TeamID}, {TopicConversation, ids.ConversationID},
+	} {
+		if t[1] != "" {
+			topics = append(topics, t[0]+":"+t[1])
+		}
+	}
+	return topics
+}
+
+// parseTopic splits a topic into kind and ID.
+func parseTopic(topic string) (kind, id string, err error) {
+	kind, id, ok := strings.Cut(topic, ":")
+	if !ok || id == "" {
+		return "", "", fmt.Errorf("invalid topic %q: expected <kind>:<id>", topic)
+	}
+	switch kind {
+	case TopicRun, TopicProject, TopicPlan, TopicTask, TopicTeam, TopicConversation:
+		return kind, id, nil
+	}
+	return "", "", fmt.Errorf("invalid topic %q: unknown kind %q", topic, kind)
+}
+
+// receives reports whether c receives the events of a tenant. Events not
+// scoped to a tenant only go to connections that are not scoped either.
+func (c *conn) receives(tenantID string) bool {
+	return c.tenant == "" || c.tenant == tenantID
+}
+
+// wants reports whether c receives an event with the given topics.
+func (c *conn) wants(topics []string) bool {
+	if c.subs == nil {
+		return true
+	}
+	for _, t := range topics {
+		if _, ok := c.subs[t]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Hub) handleClientMessage(ctx context.Context, c *conn, data []byte) {
+	var m clientMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		h.reply(c, EventError, ErrorPayload{Message: "invalid message: " + err.Error()})
+		return
+	}
+	switch m.Action {
+	case ActionSubscribe:
+		h.subscribe(ctx, c, &m)
+	case ActionUnsubscribe:
+		h.mu.Lock()
+		if c.subs == nil {
+			c.subs = make(map[string]struct{})
+		}
+		for _, t := range m.Topics {
+			delete(c.subs, t)
+		}
+		h.enqueueLocked(c, h.ackLocked(&m, 0))
+		h.mu.Unlock()
+	case ActionPing:
+		h.mu.Lock()
+		h.enqueueLocked(c, h.ackLocked(&m, 0))
+		h.mu.Unlock()
+	default:
+		h.reply(c, EventError, ErrorPayload{RequestID: m.RequestID, Message: fmt.Sprintf("unknown action %q", m.Action)})
+	}
+}
+
+// subscribe adds topics to c. If the events after m.LastEventID are still
+// buffered they are replayed; otherwise the client gets a snapshot of each
+// topic followed by the events broadcast since the snapshot was taken.
+func (h *Hub) subscribe(ctx context.Context, c *conn, m *clientMessage) {
+	kinds := make([][2]string, len(m.Topics))
+	for i, t := range m.Topics {
+		kind, id, err := parseTopic(t)
+		if err != nil {
+			h.reply(c, EventError, ErrorPayload{RequestID: m.RequestID, Message: err.Error()})
+			return
+		}
+		kinds[i] = [2]string{kind, id}
+	}
+
+	h.mu.Lock()
+	if len(c.subs)+len(m.Topics) > maxTopics {
+		h.mu.Unlock()
+		h.reply(c, EventError, ErrorPayload{RequestID: m.RequestID, Message: fmt.Sprintf("too many topics (max %d)", maxTopics)})
+		return
+	}
+	if m.LastEventID > 0 && h.canReplayLocked(m.LastEventID) {
+		h.addTopicsLocked(c, m.Topics)
+		n := h.replayLocked(c, m.Topics, m.LastEventID)
+		h.enqueueLocked(c, h.ackLocked(m, n))
+		h.mu.Unlock()
+		return
+	}
+	since := h.seq
+	h.mu.Unlock()
+
+	var snapshots [][]byte
+	if h.snapshots != nil {
+		for i, t := range m.Topics {
+			v, err := h.snapshots.Snapshot(ctx, kinds[i][0], kinds[i][1])
+			if err != nil {
+				slog.Debug("websocket snapshot failed", "topic", t, "error", err)
+				h.reply(c, EventError, ErrorPayload{RequestID: m.RequestID, Message: fmt.Sprintf("snapshot of %s: %v", t, err)})
+				continue
+			}
+			payload, err := json.Marshal(v)
+			if err != nil {
+				continue
+			}
+			data, _ := json.Marshal(Message{Type: EventSnapshot, Topic: t, Payload: payload})
+			snapshots = append(snapshots, data)
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.addTopicsLocked(c, m.Topics)
+	for _, data := range snapshots {
+		h.enqueueLocked(c, data)
+	}
+	n := h.replayLocked(c, m.Topics, since)
+	h.enqueueLocked(c, h.ackLocked(m, n))
+}
+
+// canReplayLocked reports whether all events after id are still buffered.
+func (h *Hub) canReplayLocked(id uint64) bool {
+	if id >= h.seq {
+		return id == h.seq
+	}
+	return len(h.buffer) > 0 && h.buffer[0].id <= id+1
+}
+
+func (h *Hub) addTopicsLocked(c *conn, topics []string) {
+	if c.subs == nil {
+		c.subs = make(map[string]struct{}, len(topics))
+	}
+	for _, t := range topics {
+		c.subs[t] = struct{}{}
+	}
+}
+
+// replayLocked queues the buffered events after id that belong to one of
+// topics and returns how many were queued.
+func (h *Hub) replayLocked(c *conn, topics []string, id uint64) int {
+	want := &conn{subs: make(map[string]struct{}, len(topics))}
+	for _, t := range topics {
+		want.subs[t] = struct{}{}
+	}
+	n := 0
+	for _, e := range h.buffer {
+		if e.id > id && c.receives(e.tenant) && want.wants(e.topics) {
+			h.enqueueLocked(c, e.data)
+			n++
+		}
+	}
+	return n
+}
+
+func (h *Hub) ackLocked(m *clientMessage, replayed int) []byte {
+	return marshalMessage(EventAck, AckPayload{
+		Action:      m.Action,
+		RequestID:   m.RequestID,
+		Topics:      m.Topics,
+		Replayed:    replayed,
+		LastEventID: h.seq,
+	})
+}
+
+func (h *Hub) heartbeatMessage() []byte {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return marshalMessage(EventHeartbeat, HeartbeatPayload{LastEventID: h.seq})
+}
+
+// reply queues a protocol message for one client.
+func (h *Hub) reply(c *conn, msgType string, payload any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.enqueueLocked(c, marshalMessage(msgType, payload))
+}
+
+func marshalMessage(msgType string, payload any) []byte {
+	p, _ := json.Marshal(payload)
+	data, _ := json.Marshal(Message{Type: msgType, Payload: p})
+	return data
+}
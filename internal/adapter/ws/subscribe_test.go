@@ -0,0 +1,161 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+type fakeSnapshots map[string]any
+
+func (f fakeSnapshots) Snapshot(_ context.Context, kind, id string) (any, error) {
+	if v, ok := f[kind+":"+id]; ok {
+		return v, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func dial(t *testing.T, hub *Hub) *websocket.Conn {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(hub.HandleWS))
+	t.Cleanup(srv.Close)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = c.CloseNow() })
+	deadline := time.Now().Add(5 * time.Second)
+	for hub.ConnectionCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return c
+}
+
+func send(t *testing.T, c *websocket.Conn, v any) {
+	t.Helper()
+	data, _ := json.Marshal(v)
+	if err := c.Write(context.Background(), websocket.MessageText, data); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func recv(t *testing.T, c *websocket.Conn) Message {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, data, err := c.Read(ctx)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	var m Message
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("decode %s: %v", data, err)
+	}
+	return m
+}
+
+func runOutput(runID, line string) TaskOutputEvent {
+	return TaskOutputEvent{TaskID: "t-" + runID, RunID: runID, Line: line}
+}
+
+func TestSubscribeFiltersTopics(t *testing.T) {
+	hub := NewHub()
+	all := dial(t, hub)
+	sub := dial(t, hub)
+
+	send(t, sub, clientMessage{Action: ActionSubscribe, Topics: []string{"run:r1"}, RequestID: "1"})
+	if m := recv(t, sub); m.Type != EventAck || !strings.Contains(string(m.Payload), `"request_id":"1"`) {
+		t.Fatalf("expected ack, got %s %s", m.Type, m.Payload)
+	}
+
+	hub.BroadcastEvent(context.Background(), EventTaskOutput, runOutput("r2", "other"))
+	hub.BroadcastEvent(context.Background(), EventTaskOutput, runOutput("r1", "mine"))
+
+	if m := recv(t, sub); m.ID != 2 || !strings.Contains(string(m.Payload), "mine") {
+		t.Fatalf("expected only the r1 event, got %d %s", m.ID, m.Payload)
+	}
+	// Clients that never subscribed still receive everything.
+	if m := recv(t, all); m.ID != 1 {
+		t.Fatalf("expected event 1, got %d", m.ID)
+	}
+	if m := recv(t, all); m.ID != 2 {
+		t.Fatalf("expected event 2, got %d", m.ID)
+	}
+}
+
+func TestSubscribeReplaysMissedEvents(t *testing.T) {
+	hub := NewHub()
+	hub.SetSnapshotSource(fakeSnapshots{})
+	for i := range 3 {
+		hub.BroadcastEvent(context.Background(), EventTaskOutput, runOutput("r1", string(rune('a'+i))))
+	}
+	hub.BroadcastEvent(context.Background(), EventTaskOutput, runOutput("r2", "other"))
+
+	c := dial(t, hub)
+	send(t, c, clientMessage{Action: ActionSubscribe, Topics: []string{"run:r1"}, LastEventID: 1})
+	for _, want := range []uint64{2, 3} {
+		if m := recv(t, c); m.ID != want {
+			t.Fatalf("expected replay of event %d, got %d %s", want, m.ID, m.Type)
+		}
+	}
+	m := recv(t, c)
+	var ack AckPayload
+	_ = json.Unmarshal(m.Payload, &ack)
+	if m.Type != EventAck || ack.Replayed != 2 || ack.LastEventID != 4 {
+		t.Fatalf("unexpected ack %s %s", m.Type, m.Payload)
+	}
+}
+
+func TestSubscribeSendsSnapshot(t *testing.T) {
+	hub := NewHub()
+	hub.SetSnapshotSource(fakeSnapshots{"run:r1": map[string]string{"id": "r1", "status": "running"}})
+	c := dial(t, hub)
+
+	send(t, c, clientMessage{Action: ActionSubscribe, Topics: []string{"run:r1", "plan:missing"}})
+	if m := recv(t, c); m.Type != EventError || !strings.Contains(string(m.Payload), "plan:missing") {
+		t.Fatalf("expected snapshot error, got %s %s", m.Type, m.Payload)
+	}
+	if m := recv(t, c); m.Type != EventSnapshot || m.Topic != "run:r1" || !strings.Contains(string(m.Payload), "running") {
+		t.Fatalf("expected snapshot, got %s %s", m.Type, m.Payload)
+	}
+	if m := recv(t, c); m.Type != EventAck {
+		t.Fatalf("expected ack, got %s", m.Type)
+	}
+}
+
+func TestSubscribeRejectsInvalidTopic(t *testing.T) {
+	hub := NewHub()
+	c := dial(t, hub)
+
+	send(t, c, clientMessage{Action: ActionSubscribe, Topics: []string{"user:1"}, RequestID: "x"})
+	m := recv(t, c)
+	if m.Type != EventError || !strings.Contains(string(m.Payload), "unknown kind") {
+		t.Fatalf("expected error, got %s %s", m.Type, m.Payload)
+	}
+
+	send(t, c, map[string]string{"action": "dance"})
+	if m := recv(t, c); m.Type != EventError || !strings.Contains(string(m.Payload), "unknown action") {
+		t.Fatalf("expected error, got %s %s", m.Type, m.Payload)
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	hub := NewHub()
+	hub.heartbeat = 20 * time.Millisecond
+	hub.BroadcastEvent(context.Background(), EventTaskOutput, runOutput("r1", "a"))
+	c := dial(t, hub)
+
+	m := recv(t, c)
+	if m.Type != EventHeartbeat || !strings.Contains(string(m.Payload), `"last_event_id":1`) {
+		t.Fatalf("expected heartbeat, got %s %s", m.Type, m.Payload)
+	}
+}
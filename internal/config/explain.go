@@ -0,0 +1,133 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"time"
+)
+
+// Source is where a config value came from.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceYAML    Source = "yaml"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Origin is the source of a value and where exactly in it the value was
+// set: the file and line, the environment variable or the flag.
+type Origin struct {
+	Source Source
+	Detail string
+}
+
+// Provenance maps dotted keys such as "server.port" to the origin of their
+// value. Keys that are not in it have their default value.
+type Provenance map[string]Origin
+
+// redacted replaces secret values in explanations and diffs.
+const redacted = "[redacted]"
+
+// Value is an effective config value with its origin. Secrets, i.e. fields
+// tagged redact:"true", are replaced; redact:"url" only hides the password
+// of a URL.
+type Value struct {
+	Key    string `json:"key"`
+	Value  any    `json:"value"`
+	Source Source `json:"source"`
+	Origin string `json:"origin,omitempty"` // e.g. "codeforge.yaml:12", "CODEFORGE_PORT" or "-set"
+}
+
+// Change is a value that differs between two configs.
+type Change struct {
+	Key             string `json:"key"`
+	Old             any    `json:"old"`
+	New             any    `json:"new"`
+	RestartRequired bool   `json:"restart_required"` // Takes effect only after a restart
+}
+
+// Explain lists every value of cfg in declaration order.
+func Explain(cfg *Config, prov Provenance) []Value {
+	var values []Value
+	walk(reflect.ValueOf(cfg).Elem(), "", "", func(key, tag string, v reflect.Value) {
+		val := Value{Key: key, Value: render(v, tag), Source: SourceDefault}
+		if o, ok := prov[key]; ok {
+			val.Source, val.Origin = o.Source, o.Detail
+		}
+		values = append(values, val)
+	})
+	return values
+}
+
+// Diff returns the values that differ between from and to.
+func Diff(from, to *Config) []Change {
+	olds := make(map[string]reflect.Value)
+	walk(reflect.ValueOf(from).Elem(), "", "", func(key, _ string, v reflect.Value) {
+		olds[key] = v
+	})
+	var changes []Change
+	walk(reflect.ValueOf(to).Elem(), "", "", func(key, tag string, v reflect.Value) {
+		o := olds[key]
+		if reflect.DeepEqual(o.Interface(), v.Interface()) {
+			return
+		}
+		changes = append(changes, Change{Key: key, Old: render(o, tag), New: render(v, tag)})
+	})
+	return changes
+}
+
+// walk calls fn for every value (non-section field) below v.
+func walk(v reflect.Value, prefix, tag string, fn func(key, tag string, v reflect.Value)) {
+	if !isSection(v.Type()) {
+		fn(prefix, tag, v)
+		return
+	}
+	for i := range v.NumField() {
+		f := v.Type().Field(i)
+		key := yamlName(f)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		walk(v.Field(i), key, f.Tag.Get("redact"), fn)
+	}
+}
+
+// render returns v for JSON output: durations as strings and secrets
+// replaced.
+func render(v reflect.Value, tag string) any {
+	switch {
+	case tag == "url" && v.Kind() == reflect.String:
+		return redactURL(v.String())
+	case tag == "url" && v.Kind() == reflect.Slice && !v.IsNil():
+		urls := make([]string, v.Len())
+		for i := range urls {
+			urls[i] = redactURL(v.Index(i).String())
+		}
+		return urls
+	case tag != "" && v.IsZero():
+		return v.Interface()
+	case tag != "" && v.Kind() == reflect.Map:
+		m := make(map[string]string, v.Len())
+		for _, k := range v.MapKeys() {
+			m[k.String()] = redacted
+		}
+		return m
+	case tag != "":
+		return redacted
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String()
+	}
+	return v.Interface()
+}
+
+// redactURL hides the password of a URL, or all of it if it does not
+// parse.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return redacted
+	}
+	return u.Redacted()
+}
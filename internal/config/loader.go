@@ -3,8 +3,13 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net/mail"
 	"os"
+	"reflect"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -13,27 +18,40 @@ import (
 // DefaultConfigFile is the path checked for YAML configuration.
 const DefaultConfigFile = "codeforge.yaml"
 
-// Load returns a Config using the hierarchy: defaults < YAML < ENV.
-// YAML file is optional; missing file is not an error.
-func Load() (*Config, error) {
-	cfg := Defaults()
+// Options selects what Load reads besides the defaults and the environment.
+type Options struct {
+	File string   // YAML file (default: DefaultConfigFile); a missing file is not an error
+	Set  []string // "key=value" overrides from -set flags, e.g. "server.port=9090"
+}
 
-	if err := loadYAML(&cfg, DefaultConfigFile); err != nil {
-		return nil, fmt.Errorf("config yaml: %w", err)
+// LoadFrom returns a Config using the hierarchy: defaults < YAML < ENV <
+// flags, and the origin of every value that is not a default. The YAML file
+// is optional; a missing file is not an error. Unknown YAML keys,
+// unparsable env values and invalid combinations are all reported in one
+// error.
+func LoadFrom(opts Options) (*Config, Provenance, error) {
+	if opts.File == "" {
+		opts.File = DefaultConfigFile
 	}
+	cfg := Defaults()
+	prov := make(Provenance)
 
-	loadEnv(&cfg)
+	if err := loadYAML(&cfg, opts.File, prov); err != nil {
+		return nil, nil, fmt.Errorf("config yaml: %w", err)
+	}
 
-	if err := validate(&cfg); err != nil {
-		return nil, fmt.Errorf("config validate: %w", err)
+	errs := []error{loadEnv(&cfg, prov), applySet(&cfg, opts.Set, prov), validate(&cfg)}
+	if err := errors.Join(errs...); err != nil {
+		return nil, nil, fmt.Errorf("config validate: %w", err)
 	}
 
-	return &cfg, nil
+	return &cfg, prov, nil
 }
 
-// loadYAML reads the YAML file and unmarshals it over cfg.
+// loadYAML reads the YAML file and unmarshals it over cfg, recording the
+// line of every value in prov. Unknown keys are errors.
 // Returns nil if the file does not exist.
-func loadYAML(cfg *Config, path string) error {
+func loadYAML(cfg *Config, path string, prov Provenance) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -42,6 +60,13 @@ func loadYAML(cfg *Config, path string) error {
 		return fmt.Errorf("read %s: %w", path, err)
 	}
 
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := errors.Join(checkKeys(&doc, reflect.TypeFor[Config](), "", path, prov)...); err != nil {
+		return err
+	}
 	if err := yaml.Unmarshal(data, cfg); err != nil {
 		return fmt.Errorf("parse %s: %w", path, err)
 	}
@@ -49,104 +74,548 @@ func loadYAML(cfg *Config, path string) error {
 	return nil
 }
 
-// loadEnv overlays environment variables onto cfg.
-// Only non-empty env values override the current config.
-func loadEnv(cfg *Config) {
-	setString(&cfg.Server.Port, "CODEFORGE_PORT")
-	setString(&cfg.Server.CORSOrigin, "CODEFORGE_CORS_ORIGIN")
-	setString(&cfg.Postgres.DSN, "DATABASE_URL")
-	setInt32(&cfg.Postgres.MaxConns, "CODEFORGE_PG_MAX_CONNS")
-	setInt32(&cfg.Postgres.MinConns, "CODEFORGE_PG_MIN_CONNS")
-	setDuration(&cfg.Postgres.MaxConnLifetime, "CODEFORGE_PG_MAX_CONN_LIFETIME")
-	setDuration(&cfg.Postgres.MaxConnIdleTime, "CODEFORGE_PG_MAX_CONN_IDLE_TIME")
-	setDuration(&cfg.Postgres.HealthCheck, "CODEFORGE_PG_HEALTH_CHECK")
-	setString(&cfg.NATS.URL, "NATS_URL")
-	setString(&cfg.LiteLLM.URL, "LITELLM_URL")
-	setString(&cfg.LiteLLM.MasterKey, "LITELLM_MASTER_KEY")
-	setString(&cfg.Logging.Level, "CODEFORGE_LOG_LEVEL")
-	setString(&cfg.Logging.Service, "CODEFORGE_LOG_SERVICE")
-	setInt(&cfg.Breaker.MaxFailures, "CODEFORGE_BREAKER_MAX_FAILURES")
-	setDuration(&cfg.Breaker.Timeout, "CODEFORGE_BREAKER_TIMEOUT")
-	setFloat64(&cfg.Rate.RequestsPerSecond, "CODEFORGE_RATE_RPS")
-	setInt(&cfg.Rate.Burst, "CODEFORGE_RATE_BURST")
-	setString(&cfg.Policy.DefaultProfile, "CODEFORGE_POLICY_DEFAULT")
-	setString(&cfg.Policy.CustomDir, "CODEFORGE_POLICY_DIR")
-	setInt(&cfg.Runtime.StallThreshold, "CODEFORGE_STALL_THRESHOLD")
-	setDuration(&cfg.Runtime.QualityGateTimeout, "CODEFORGE_QG_TIMEOUT")
-	setString(&cfg.Runtime.DefaultDeliverMode, "CODEFORGE_DELIVER_MODE")
-	setString(&cfg.Runtime.DefaultTestCommand, "CODEFORGE_TEST_COMMAND")
-	setString(&cfg.Runtime.DefaultLintCommand, "CODEFORGE_LINT_COMMAND")
-	setString(&cfg.Runtime.DeliveryCommitPrefix, "CODEFORGE_COMMIT_PREFIX")
+// applySet applies "key=value" overrides. Values are YAML, so lists and
+// durations are written as in the config file.
+func applySet(cfg *Config, set []string, prov Provenance) error {
+	var errs []error
+	for _, kv := range set {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			errs = append(errs, fmt.Errorf("-set %s: expected key=value", kv))
+			continue
+		}
+		key = strings.TrimSpace(key)
+		field, err := lookup(cfg, key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("-set: %w", err))
+			continue
+		}
+		v := reflect.New(field.Type())
+		if err := yaml.Unmarshal([]byte(value), v.Interface()); err != nil {
+			errs = append(errs, fmt.Errorf("-set %s: %w", key, err))
+			continue
+		}
+		field.Set(v.Elem())
+		prov[key] = Origin{Source: SourceFlag, Detail: "-set"}
+	}
+	return errors.Join(errs...)
+}
+
+// loadEnv overlays environment variables onto cfg and records them in
+// prov. Only non-empty env values override the current config; values that
+// do not parse are errors.
+func loadEnv(cfg *Config, prov Provenance) error {
+	l := envLoader{keys: fieldKeys(cfg), prov: prov}
+	l.setString(&cfg.Server.Port, "CODEFORGE_PORT")
+	l.setString(&cfg.Server.CORSOrigin, "CODEFORGE_CORS_ORIGIN")
+	l.setString(&cfg.Server.PublicURL, "CODEFORGE_PUBLIC_URL")
+	l.setBool(&cfg.Server.GraphQL, "CODEFORGE_GRAPHQL")
+	l.setBool(&cfg.Server.MCP, "CODEFORGE_MCP")
+	l.setStrings(&cfg.Server.APIKeys, "CODEFORGE_API_KEYS")
+	l.setDuration(&cfg.Server.LeaderLease, "CODEFORGE_LEADER_LEASE")
+	l.setBool(&cfg.Server.WSFanOut, "CODEFORGE_WS_FANOUT")
+	l.setString(&cfg.Postgres.DSN, "DATABASE_URL")
+	l.setInt32(&cfg.Postgres.MaxConns, "CODEFORGE_PG_MAX_CONNS")
+	l.setInt32(&cfg.Postgres.MinConns, "CODEFORGE_PG_MIN_CONNS")
+	l.setDuration(&cfg.Postgres.MaxConnLifetime, "CODEFORGE_PG_MAX_CONN_LIFETIME")
+	l.setDuration(&cfg.Postgres.MaxConnIdleTime, "CODEFORGE_PG_MAX_CONN_IDLE_TIME")
+	l.setDuration(&cfg.Postgres.HealthCheck, "CODEFORGE_PG_HEALTH_CHECK")
+	l.setString(&cfg.Postgres.StatementCache, "CODEFORGE_PG_STATEMENT_CACHE")
+	l.setInt(&cfg.Postgres.StatementCacheSize, "CODEFORGE_PG_STATEMENT_CACHE_SIZE")
+	l.setDuration(&cfg.Postgres.QueryTimeout, "CODEFORGE_PG_QUERY_TIMEOUT")
+	l.setStrings(&cfg.Postgres.ReplicaDSNs, "CODEFORGE_PG_REPLICA_DSNS")
+	l.setInt32(&cfg.Postgres.ReplicaMaxConns, "CODEFORGE_PG_REPLICA_MAX_CONNS")
+	l.setDuration(&cfg.Postgres.ReplicaMaxLag, "CODEFORGE_PG_REPLICA_MAX_LAG")
+	l.setDuration(&cfg.Postgres.ReplicaCheck, "CODEFORGE_PG_REPLICA_CHECK")
+	l.setDuration(&cfg.Postgres.ReplicaQueryTimeout, "CODEFORGE_PG_REPLICA_QUERY_TIMEOUT")
+	l.setString(&cfg.NATS.URL, "NATS_URL")
+	l.setDuration(&cfg.NATS.AckWait, "CODEFORGE_NATS_ACK_WAIT")
+	l.setInt(&cfg.NATS.MaxDeliver, "CODEFORGE_NATS_MAX_DELIVER")
+	l.setInt(&cfg.NATS.MaxAckPending, "CODEFORGE_NATS_MAX_ACK_PENDING")
+	l.setDuration(&cfg.NATS.DLQMaxAge, "CODEFORGE_NATS_DLQ_MAX_AGE")
+	l.setDuration(&cfg.NATS.DedupeWindow, "CODEFORGE_NATS_DEDUPE_WINDOW")
+	l.setString(&cfg.LiteLLM.URL, "LITELLM_URL")
+	l.setString(&cfg.LiteLLM.MasterKey, "LITELLM_MASTER_KEY")
+	l.setBool(&cfg.LiteLLM.CacheEnabled, "CODEFORGE_LLM_CACHE_ENABLED")
+	l.setDuration(&cfg.LiteLLM.CacheTTL, "CODEFORGE_LLM_CACHE_TTL")
+	l.setInt(&cfg.LiteLLM.CacheMaxEntries, "CODEFORGE_LLM_CACHE_MAX_ENTRIES")
+	l.setString(&cfg.Logging.Level, "CODEFORGE_LOG_LEVEL")
+	l.setString(&cfg.Logging.Service, "CODEFORGE_LOG_SERVICE")
+	l.setInt(&cfg.Breaker.MaxFailures, "CODEFORGE_BREAKER_MAX_FAILURES")
+	l.setDuration(&cfg.Breaker.Timeout, "CODEFORGE_BREAKER_TIMEOUT")
+	l.setFloat64(&cfg.Rate.RequestsPerSecond, "CODEFORGE_RATE_RPS")
+	l.setInt(&cfg.Rate.Burst, "CODEFORGE_RATE_BURST")
+	l.setBool(&cfg.Rate.Distributed, "CODEFORGE_RATE_DISTRIBUTED")
+	l.setBool(&cfg.Idempotency.Enabled, "CODEFORGE_IDEMPOTENCY_ENABLED")
+	l.setDuration(&cfg.Idempotency.TTL, "CODEFORGE_IDEMPOTENCY_TTL")
+	l.setInt(&cfg.Idempotency.MaxBodyBytes, "CODEFORGE_IDEMPOTENCY_MAX_BODY_BYTES")
+	l.setString(&cfg.Policy.DefaultProfile, "CODEFORGE_POLICY_DEFAULT")
+	l.setString(&cfg.Policy.CustomDir, "CODEFORGE_POLICY_DIR")
+	l.setInt(&cfg.Runtime.StallThreshold, "CODEFORGE_STALL_THRESHOLD")
+	l.setDuration(&cfg.Runtime.QualityGateTimeout, "CODEFORGE_QG_TIMEOUT")
+	l.setString(&cfg.Runtime.DefaultDeliverMode, "CODEFORGE_DELIVER_MODE")
+	l.setString(&cfg.Runtime.DefaultTestCommand, "CODEFORGE_TEST_COMMAND")
+	l.setString(&cfg.Runtime.DefaultLintCommand, "CODEFORGE_LINT_COMMAND")
+	l.setString(&cfg.Runtime.DeliveryCommitPrefix, "CODEFORGE_COMMIT_PREFIX")
+	l.setString(&cfg.Runtime.SnapshotMode, "CODEFORGE_SNAPSHOT_MODE")
+	l.setInt(&cfg.Runtime.SnapshotMaxMB, "CODEFORGE_SNAPSHOT_MAX_MB")
+	l.setBool(&cfg.Runtime.WorktreeIsolation, "CODEFORGE_WORKTREE_ISOLATION")
+	l.setString(&cfg.Runtime.WorktreeRoot, "CODEFORGE_WORKTREE_ROOT")
+	l.setDuration(&cfg.Runtime.WorktreeRetention, "CODEFORGE_WORKTREE_RETENTION")
+	l.setDuration(&cfg.Runtime.DrainTimeout, "CODEFORGE_DRAIN_TIMEOUT")
+	l.setString(&cfg.Runtime.SummaryModel, "CODEFORGE_RUN_SUMMARY_MODEL")
+	l.setFloat64(&cfg.Runtime.SummaryMinBudget, "CODEFORGE_RUN_SUMMARY_MIN_BUDGET")
+	l.setString(&cfg.Runtime.Sandbox.Driver, "CODEFORGE_SANDBOX_DRIVER")
+	l.setString(&cfg.Runtime.Sandbox.Image, "CODEFORGE_SANDBOX_IMAGE")
+	l.setInt(&cfg.Runtime.Sandbox.MemoryMB, "CODEFORGE_SANDBOX_MEMORY_MB")
+	l.setFloat64(&cfg.Runtime.Sandbox.CPUs, "CODEFORGE_SANDBOX_CPUS")
+	l.setInt(&cfg.Runtime.Sandbox.PIDs, "CODEFORGE_SANDBOX_PIDS")
+	l.setInt(&cfg.Runtime.Sandbox.StorageMB, "CODEFORGE_SANDBOX_STORAGE_MB")
+	l.setString(&cfg.Runtime.Sandbox.NetworkMode, "CODEFORGE_SANDBOX_NETWORK")
+	l.setString(&cfg.Runtime.Sandbox.EgressImage, "CODEFORGE_SANDBOX_EGRESS_IMAGE")
+	l.setString(&cfg.Runtime.Sandbox.EgressNetwork, "CODEFORGE_SANDBOX_EGRESS_NETWORK")
+	l.setString(&cfg.Runtime.Sandbox.Build.DockerfileImage, "CODEFORGE_BUILD_DOCKERFILE_IMAGE")
+	l.setString(&cfg.Runtime.Sandbox.Build.BuildpacksImage, "CODEFORGE_BUILD_BUILDPACKS_IMAGE")
+	l.setDuration(&cfg.Runtime.Sandbox.Build.Timeout, "CODEFORGE_BUILD_TIMEOUT")
+	l.setString(&cfg.Runtime.Sandbox.Kubernetes.APIServer, "CODEFORGE_K8S_API_SERVER")
+	l.setString(&cfg.Runtime.Sandbox.Kubernetes.Namespace, "CODEFORGE_K8S_NAMESPACE")
+	l.setString(&cfg.Runtime.Sandbox.Kubernetes.WorkspaceClaim, "CODEFORGE_K8S_WORKSPACE_CLAIM")
 
 	// Orchestrator
-	setInt(&cfg.Orchestrator.MaxParallel, "CODEFORGE_ORCH_MAX_PARALLEL")
-	setInt(&cfg.Orchestrator.PingPongMaxRounds, "CODEFORGE_ORCH_PINGPONG_MAX_ROUNDS")
-	setInt(&cfg.Orchestrator.ConsensusQuorum, "CODEFORGE_ORCH_CONSENSUS_QUORUM")
-	setString(&cfg.Orchestrator.Mode, "CODEFORGE_ORCH_MODE")
-	setString(&cfg.Orchestrator.DecomposeModel, "CODEFORGE_ORCH_DECOMPOSE_MODEL")
-	setInt(&cfg.Orchestrator.DecomposeMaxTokens, "CODEFORGE_ORCH_DECOMPOSE_MAX_TOKENS")
-	setInt(&cfg.Orchestrator.MaxTeamSize, "CODEFORGE_ORCH_MAX_TEAM_SIZE")
-	setInt(&cfg.Orchestrator.DefaultContextBudget, "CODEFORGE_ORCH_CONTEXT_BUDGET")
-	setInt(&cfg.Orchestrator.PromptReserve, "CODEFORGE_ORCH_PROMPT_RESERVE")
-}
-
-// validate checks that required fields are set.
+	l.setInt(&cfg.Orchestrator.MaxParallel, "CODEFORGE_ORCH_MAX_PARALLEL")
+	l.setInt(&cfg.Orchestrator.PingPongMaxRounds, "CODEFORGE_ORCH_PINGPONG_MAX_ROUNDS")
+	l.setInt(&cfg.Orchestrator.ConsensusQuorum, "CODEFORGE_ORCH_CONSENSUS_QUORUM")
+	l.setString(&cfg.Orchestrator.Mode, "CODEFORGE_ORCH_MODE")
+	l.setString(&cfg.Orchestrator.DecomposeModel, "CODEFORGE_ORCH_DECOMPOSE_MODEL")
+	l.setInt(&cfg.Orchestrator.DecomposeMaxTokens, "CODEFORGE_ORCH_DECOMPOSE_MAX_TOKENS")
+	l.setInt(&cfg.Orchestrator.MaxTeamSize, "CODEFORGE_ORCH_MAX_TEAM_SIZE")
+	l.setInt(&cfg.Orchestrator.DefaultContextBudget, "CODEFORGE_ORCH_CONTEXT_BUDGET")
+	l.setInt(&cfg.Orchestrator.PromptReserve, "CODEFORGE_ORCH_PROMPT_RESERVE")
+	l.setInt(&cfg.Orchestrator.ExperienceLimit, "CODEFORGE_ORCH_EXPERIENCE_LIMIT")
+	l.setFloat64(&cfg.Orchestrator.ExperienceMinUsefulness, "CODEFORGE_ORCH_EXPERIENCE_MIN_USEFULNESS")
+	l.setDuration(&cfg.Orchestrator.ApprovalCheckInterval, "CODEFORGE_ORCH_APPROVAL_CHECK_INTERVAL")
+	l.setBool(&cfg.Orchestrator.AutoScaleAgents, "CODEFORGE_ORCH_AUTO_SCALE_AGENTS")
+	l.setDuration(&cfg.Orchestrator.AgentIdleTimeout, "CODEFORGE_ORCH_AGENT_IDLE_TIMEOUT")
+	l.setString(&cfg.Orchestrator.ArbiterModel, "CODEFORGE_ORCH_ARBITER_MODEL")
+
+	// Research
+	l.setString(&cfg.Research.Provider, "CODEFORGE_RESEARCH_PROVIDER")
+	l.setString(&cfg.Research.URL, "CODEFORGE_RESEARCH_URL")
+	l.setInt(&cfg.Research.MaxResults, "CODEFORGE_RESEARCH_MAX_RESULTS")
+	l.setString(&cfg.Research.SummaryModel, "CODEFORGE_RESEARCH_SUMMARY_MODEL")
+	l.setString(&cfg.Research.PolicyProfile, "CODEFORGE_RESEARCH_POLICY")
+	l.setInt(&cfg.Research.SummaryTimeout, "CODEFORGE_RESEARCH_SUMMARY_TIMEOUT")
+
+	// Secrets
+	l.setString(&cfg.Secrets.MasterKey, "CODEFORGE_SECRETS_KEY")
+
+	// Redaction
+	l.setBool(&cfg.Redaction.Enabled, "CODEFORGE_REDACT_ENABLED")
+	l.setFloat64(&cfg.Redaction.EntropyThreshold, "CODEFORGE_REDACT_ENTROPY")
+	l.setInt(&cfg.Redaction.MinTokenLength, "CODEFORGE_REDACT_MIN_TOKEN_LENGTH")
+
+	// Retention
+	l.setDuration(&cfg.Retention.EventWindow, "CODEFORGE_EVENT_RETENTION")
+	l.setDuration(&cfg.Retention.Interval, "CODEFORGE_RETENTION_INTERVAL")
+	l.setInt(&cfg.Retention.BatchSize, "CODEFORGE_RETENTION_BATCH_SIZE")
+
+	// Audit
+	l.setBool(&cfg.Audit.Enabled, "CODEFORGE_AUDIT_ENABLED")
+	l.setDuration(&cfg.Audit.ExportInterval, "CODEFORGE_AUDIT_EXPORT_INTERVAL")
+	l.setInt(&cfg.Audit.BatchSize, "CODEFORGE_AUDIT_BATCH_SIZE")
+	l.setDuration(&cfg.Audit.SendTimeout, "CODEFORGE_AUDIT_SEND_TIMEOUT")
+
+	// Notify
+	l.setDuration(&cfg.Notify.SendInterval, "CODEFORGE_NOTIFY_SEND_INTERVAL")
+	l.setDuration(&cfg.Notify.SendTimeout, "CODEFORGE_NOTIFY_SEND_TIMEOUT")
+	l.setInt(&cfg.Notify.MaxAttempts, "CODEFORGE_NOTIFY_MAX_ATTEMPTS")
+	l.setString(&cfg.Notify.SMTPHost, "CODEFORGE_NOTIFY_SMTP_HOST")
+	l.setInt(&cfg.Notify.SMTPPort, "CODEFORGE_NOTIFY_SMTP_PORT")
+	l.setString(&cfg.Notify.SMTPUsername, "CODEFORGE_NOTIFY_SMTP_USERNAME")
+	l.setString(&cfg.Notify.SMTPPassword, "CODEFORGE_NOTIFY_SMTP_PASSWORD")
+	l.setString(&cfg.Notify.SMTPFrom, "CODEFORGE_NOTIFY_SMTP_FROM")
+	l.setString(&cfg.Notify.SlackSigningSecret, "CODEFORGE_NOTIFY_SLACK_SIGNING_SECRET")
+
+	// Benchmark
+	l.setString(&cfg.Benchmark.SuitesDir, "CODEFORGE_BENCHMARK_SUITES_DIR")
+	l.setDuration(&cfg.Benchmark.ValidateTimeout, "CODEFORGE_BENCHMARK_VALIDATE_TIMEOUT")
+	l.setInt(&cfg.Benchmark.MaxParallel, "CODEFORGE_BENCHMARK_MAX_PARALLEL")
+
+	// Templates
+	l.setString(&cfg.Templates.Dir, "CODEFORGE_TEMPLATES_DIR")
+
+	// Code graph
+	l.setStrings(&cfg.CodeGraph.Grammars, "CODEFORGE_CODEGRAPH_GRAMMARS")
+	l.setString(&cfg.CodeGraph.Ctags, "CODEFORGE_CODEGRAPH_CTAGS")
+	l.setDuration(&cfg.LSP.RequestTimeout, "CODEFORGE_LSP_REQUEST_TIMEOUT")
+	l.setDuration(&cfg.LSP.IdleTimeout, "CODEFORGE_LSP_IDLE_TIMEOUT")
+
+	// Conventions
+	l.setDuration(&cfg.Conventions.CheckInterval, "CODEFORGE_CONVENTIONS_CHECK_INTERVAL")
+	l.setDuration(&cfg.Conventions.RefreshInterval, "CODEFORGE_CONVENTIONS_REFRESH_INTERVAL")
+	l.setInt(&cfg.Conventions.MergeFiles, "CODEFORGE_CONVENTIONS_MERGE_FILES")
+	l.setInt(&cfg.Conventions.SampleFiles, "CODEFORGE_CONVENTIONS_SAMPLE_FILES")
+	l.setInt(&cfg.Conventions.Commits, "CODEFORGE_CONVENTIONS_COMMITS")
+
+	// VCS accounts
+	l.setDuration(&cfg.VCSAccounts.CheckInterval, "CODEFORGE_VCS_ACCOUNTS_CHECK_INTERVAL")
+	l.setDuration(&cfg.VCSAccounts.ExpiryWarning, "CODEFORGE_VCS_ACCOUNTS_EXPIRY_WARNING")
+
+	// CI
+	l.setDuration(&cfg.CI.PollInterval, "CODEFORGE_CI_POLL_INTERVAL")
+	l.setDuration(&cfg.CI.WaitTimeout, "CODEFORGE_CI_WAIT_TIMEOUT")
+	l.setDuration(&cfg.CI.WatchWindow, "CODEFORGE_CI_WATCH_WINDOW")
+
+	// Costs
+	l.setDuration(&cfg.Costs.RollupInterval, "CODEFORGE_COST_ROLLUP_INTERVAL")
+	l.setDuration(&cfg.Costs.RollupWindow, "CODEFORGE_COST_ROLLUP_WINDOW")
+
+	// Events
+	l.setInt(&cfg.Events.BatchSize, "CODEFORGE_EVENT_BATCH_SIZE")
+	l.setDuration(&cfg.Events.FlushInterval, "CODEFORGE_EVENT_FLUSH_INTERVAL")
+
+	// Retrieval
+	l.setString(&cfg.Retrieval.EmbeddingProvider, "CODEFORGE_EMBEDDING_PROVIDER")
+	l.setString(&cfg.Retrieval.EmbeddingModel, "CODEFORGE_EMBEDDING_MODEL")
+	l.setInt(&cfg.Retrieval.Dimensions, "CODEFORGE_EMBEDDING_DIMENSIONS")
+	l.setString(&cfg.Retrieval.OllamaURL, "CODEFORGE_OLLAMA_URL")
+	l.setInt(&cfg.Retrieval.BatchSize, "CODEFORGE_EMBEDDING_BATCH_SIZE")
+	l.setString(&cfg.Retrieval.RerankProvider, "CODEFORGE_RERANK_PROVIDER")
+	l.setString(&cfg.Retrieval.RerankModel, "CODEFORGE_RERANK_MODEL")
+	l.setString(&cfg.Retrieval.RerankURL, "CODEFORGE_RERANK_URL")
+	l.setInt(&cfg.Retrieval.RerankTopN, "CODEFORGE_RERANK_TOP_N")
+	l.setFloat64(&cfg.Retrieval.RerankMinScore, "CODEFORGE_RERANK_MIN_SCORE")
+	l.setDuration(&cfg.Retrieval.RerankTimeout, "CODEFORGE_RERANK_TIMEOUT")
+
+	// Knowledge bases
+	l.setDuration(&cfg.Knowledge.CheckInterval, "CODEFORGE_KNOWLEDGE_CHECK_INTERVAL")
+	l.setInt(&cfg.Knowledge.MaxDocuments, "CODEFORGE_KNOWLEDGE_MAX_DOCUMENTS")
+	l.setDuration(&cfg.Knowledge.FetchTimeout, "CODEFORGE_KNOWLEDGE_FETCH_TIMEOUT")
+
+	// Conversations
+	l.setString(&cfg.Conversation.Model, "CODEFORGE_CONVERSATION_MODEL")
+	l.setInt(&cfg.Conversation.SummarizeAt, "CODEFORGE_CONVERSATION_SUMMARIZE_AT")
+	l.setInt(&cfg.Conversation.ToolOutputMax, "CODEFORGE_CONVERSATION_TOOL_OUTPUT_MAX")
+
+	// Attachments
+	l.setInt(&cfg.Attachments.MaxBytes, "CODEFORGE_ATTACHMENTS_MAX_BYTES")
+	l.setStrings(&cfg.Attachments.AllowedTypes, "CODEFORGE_ATTACHMENTS_ALLOWED_TYPES")
+
+	// Memory
+	l.setBool(&cfg.Memory.Enabled, "CODEFORGE_MEMORY_ENABLED")
+	l.setInt(&cfg.Memory.Limit, "CODEFORGE_MEMORY_LIMIT")
+	l.setFloat64(&cfg.Memory.MinScore, "CODEFORGE_MEMORY_MIN_SCORE")
+	l.setDuration(&cfg.Memory.HalfLife, "CODEFORGE_MEMORY_HALF_LIFE")
+
+	// Skills
+	l.setString(&cfg.Skills.SigningKey, "CODEFORGE_SKILLS_SIGNING_KEY")
+	l.setString(&cfg.Skills.KeyID, "CODEFORGE_SKILLS_KEY_ID")
+	l.setString(&cfg.Skills.RegistryURL, "CODEFORGE_SKILLS_REGISTRY_URL")
+
+	// Tokenizers
+	l.setString(&cfg.Tokenizers.Dir, "CODEFORGE_TOKENIZERS_DIR")
+	l.setString(&cfg.Tokenizers.DefaultModel, "CODEFORGE_TOKENIZERS_DEFAULT_MODEL")
+
+	return errors.Join(l.errs...)
+}
+
+// validate checks that required fields are set and that values fit
+// together. It reports every problem, not just the first.
 func validate(cfg *Config) error {
+	var errs []error
 	if cfg.Server.Port == "" {
-		return errors.New("server.port is required")
+		errs = append(errs, errors.New("server.port is required"))
 	}
 	if cfg.Postgres.DSN == "" {
-		return errors.New("postgres.dsn is required")
+		errs = append(errs, errors.New("postgres.dsn is required"))
 	}
 	if cfg.NATS.URL == "" {
-		return errors.New("nats.url is required")
+		errs = append(errs, errors.New("nats.url is required"))
+	}
+	if cfg.NATS.AckWait <= 0 || cfg.NATS.MaxDeliver < 1 || cfg.NATS.MaxAckPending < 1 {
+		errs = append(errs, errors.New("nats.ack_wait, nats.max_deliver and nats.max_ack_pending must be positive"))
+	}
+	if cfg.NATS.DedupeWindow < 0 {
+		errs = append(errs, errors.New("nats.dedupe_window must not be negative"))
 	}
 	if cfg.Postgres.MaxConns < 1 {
-		return errors.New("postgres.max_conns must be >= 1")
+		errs = append(errs, errors.New("postgres.max_conns must be >= 1"))
+	} else if cfg.Postgres.MinConns > cfg.Postgres.MaxConns {
+		errs = append(errs, fmt.Errorf("postgres.min_conns (%d) must not exceed postgres.max_conns (%d)", cfg.Postgres.MinConns, cfg.Postgres.MaxConns))
+	}
+	if cfg.Postgres.StatementCacheSize < 0 || cfg.Postgres.QueryTimeout < 0 || cfg.Postgres.ReplicaQueryTimeout < 0 {
+		errs = append(errs, errors.New("postgres.statement_cache_size, postgres.query_timeout and postgres.replica_query_timeout must not be negative"))
+	}
+	if len(cfg.Postgres.ReplicaDSNs) > 0 && (cfg.Postgres.ReplicaMaxConns < 1 || cfg.Postgres.ReplicaMaxLag <= 0 || cfg.Postgres.ReplicaCheck <= 0) {
+		errs = append(errs, errors.New("postgres.replica_max_conns, postgres.replica_max_lag and postgres.replica_check must be positive with replica_dsns"))
+	}
+	errs = append(errs,
+		oneOf("postgres.statement_cache", cfg.Postgres.StatementCache, "prepare", "describe", "exec", "simple"),
+		oneOf("logging.level", cfg.Logging.Level, "debug", "info", "warn", "warning", "error"),
+		oneOf("orchestrator.mode", cfg.Orchestrator.Mode, "manual", "semi_auto", "full_auto"),
+		oneOf("runtime.default_deliver_mode", cfg.Runtime.DefaultDeliverMode, "", "patch", "commit-local", "branch", "pr", "mirror"),
+		oneOf("runtime.snapshot_mode", cfg.Runtime.SnapshotMode, "", "on_complete", "on_tool_call"),
+	)
+	if cfg.Server.LeaderLease != 0 && cfg.Server.LeaderLease < time.Second {
+		errs = append(errs, errors.New("server.leader_lease must be 0 or at least 1s"))
+	}
+	if cfg.Runtime.DrainTimeout < 0 {
+		errs = append(errs, errors.New("runtime.drain_timeout must not be negative"))
+	}
+	if cfg.Runtime.SummaryMinBudget < 0 || cfg.Runtime.SummaryMinBudget > 1 {
+		errs = append(errs, errors.New("runtime.summary_min_budget must be between 0 and 1"))
+	}
+	if cfg.Orchestrator.ApprovalCheckInterval < 0 {
+		errs = append(errs, errors.New("orchestrator.approval_check_interval must not be negative"))
+	}
+	if cfg.Orchestrator.AutoScaleAgents && cfg.Orchestrator.AgentIdleTimeout <= 0 {
+		errs = append(errs, errors.New("orchestrator.agent_idle_timeout must be positive with auto_scale_agents"))
 	}
 	if cfg.Breaker.MaxFailures < 1 {
-		return errors.New("breaker.max_failures must be >= 1")
+		errs = append(errs, errors.New("breaker.max_failures must be >= 1"))
 	}
 	if cfg.Rate.Burst < 1 {
-		return errors.New("rate.burst must be >= 1")
+		errs = append(errs, errors.New("rate.burst must be >= 1"))
 	}
-	return nil
+	if cfg.Rate.RequestsPerSecond <= 0 {
+		errs = append(errs, errors.New("rate.requests_per_second must be positive"))
+	}
+	for name, tier := range cfg.Rate.Tiers {
+		if tier.RequestsPerSecond <= 0 || tier.Burst < 1 {
+			errs = append(errs, fmt.Errorf("rate.tiers.%s: requests_per_second must be positive and burst >= 1", name))
+		}
+	}
+	for id, name := range cfg.Rate.TenantTiers {
+		if _, ok := cfg.Rate.Tiers[name]; !ok {
+			errs = append(errs, fmt.Errorf("rate.tenant_tiers.%s: unknown tier %q", id, name))
+		}
+	}
+	if i := cfg.Idempotency; i.TTL <= 0 || i.MaxBodyBytes < 1 {
+		errs = append(errs, errors.New("idempotency.ttl and idempotency.max_body_bytes must be positive"))
+	}
+	for route, ttl := range cfg.Idempotency.Routes {
+		method, path, ok := strings.Cut(route, " ")
+		if !ok || method == "" || !strings.HasPrefix(path, "/") || ttl < 0 {
+			errs = append(errs, fmt.Errorf("idempotency.routes: %q must be \"METHOD /path\" with a TTL not negative", route))
+		}
+	}
+	if cfg.LiteLLM.CacheEnabled && cfg.LiteLLM.CacheMaxEntries < 1 {
+		errs = append(errs, errors.New("litellm.cache_max_entries must be >= 1 when the cache is enabled"))
+	}
+	if cfg.Secrets.MasterKey != "" && len(cfg.Secrets.MasterKey) < 32 {
+		errs = append(errs, errors.New("secrets.master_key must be at least 32 bytes"))
+	}
+	for i, p := range cfg.Redaction.Patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			errs = append(errs, fmt.Errorf("redaction.patterns[%d]: %w", i, err))
+		}
+	}
+	if cfg.Costs.RollupInterval <= 0 || cfg.Costs.RollupWindow < 0 {
+		errs = append(errs, errors.New("costs.rollup_interval must be positive and costs.rollup_window must not be negative"))
+	}
+	if cfg.Events.BatchSize < 1 || cfg.Events.FlushInterval <= 0 {
+		errs = append(errs, errors.New("events.batch_size and events.flush_interval must be positive"))
+	}
+	if cfg.Retention.EventWindow > 0 && (cfg.Retention.Interval <= 0 || cfg.Retention.BatchSize < 1) {
+		errs = append(errs, errors.New("retention.interval and retention.batch_size must be positive when event_window is set"))
+	}
+	if a := cfg.Audit; a.ExportInterval > 0 && (a.BatchSize < 1 || a.SendTimeout <= 0) {
+		errs = append(errs, errors.New("audit.batch_size and audit.send_timeout must be positive when export_interval is set"))
+	}
+	if n := cfg.Notify; n.SendInterval > 0 && (n.SendTimeout <= 0 || n.MaxAttempts < 1) {
+		errs = append(errs, errors.New("notify.send_timeout and notify.max_attempts must be positive when send_interval is set"))
+	}
+	if n := cfg.Notify; n.SMTPHost != "" {
+		if _, err := mail.ParseAddress(n.SMTPFrom); err != nil || n.SMTPPort < 1 {
+			errs = append(errs, errors.New("notify.smtp_from must be an email address and notify.smtp_port positive when smtp_host is set"))
+		}
+	}
+	if sb := cfg.Runtime.Sandbox; sb.Driver != "" {
+		if sb.Image == "" {
+			errs = append(errs, errors.New("runtime.sandbox.image is required when a sandbox driver is set"))
+		}
+		if sb.MemoryMB < 0 || sb.CPUs < 0 || sb.PIDs < 0 || sb.StorageMB < 0 {
+			errs = append(errs, errors.New("runtime.sandbox limits must not be negative"))
+		}
+		if sb.Build.Timeout <= 0 {
+			errs = append(errs, errors.New("runtime.sandbox.build.timeout must be positive"))
+		}
+	}
+	if cfg.Benchmark.ValidateTimeout <= 0 || cfg.Benchmark.MaxParallel < 0 {
+		errs = append(errs, errors.New("benchmark.validate_timeout must be positive and benchmark.max_parallel must not be negative"))
+	}
+	if r := cfg.Retrieval; r.BatchSize < 1 || r.ChunkLines < 1 || r.ChunkOverlap < 0 || r.ChunkOverlap >= r.ChunkLines || r.Dimensions < 0 {
+		errs = append(errs, errors.New("retrieval.batch_size and retrieval.chunk_lines must be positive, chunk_overlap below chunk_lines and dimensions not negative"))
+	}
+	if r := cfg.Retrieval; r.RerankProvider != "" {
+		if r.RerankProvider != "litellm" && r.RerankProvider != "onnx" {
+			errs = append(errs, fmt.Errorf("retrieval.rerank_provider must be litellm or onnx, got %q", r.RerankProvider))
+		}
+		if r.RerankProvider == "litellm" && r.RerankModel == "" {
+			errs = append(errs, errors.New("retrieval.rerank_model is required for the litellm reranker"))
+		}
+		if r.RerankTopN < 1 || r.RerankTimeout <= 0 {
+			errs = append(errs, errors.New("retrieval.rerank_top_n and retrieval.rerank_timeout must be positive"))
+		}
+	}
+	if k := cfg.Knowledge; k.CheckInterval < 0 || k.MaxDocuments < 1 || k.FetchTimeout <= 0 {
+		errs = append(errs, errors.New("knowledge.max_documents and knowledge.fetch_timeout must be positive and check_interval not negative"))
+	}
+	if c := cfg.Conventions; c.CheckInterval < 0 || c.RefreshInterval <= 0 || c.MergeFiles < 1 || c.SampleFiles < 1 || c.Commits < 1 {
+		errs = append(errs, errors.New("conventions.refresh_interval, merge_files, sample_files and commits must be positive and check_interval not negative"))
+	}
+	if c := cfg.VCSAccounts; c.CheckInterval < 0 || c.ExpiryWarning <= 0 {
+		errs = append(errs, errors.New("vcs_accounts.expiry_warning must be positive and check_interval not negative"))
+	}
+	if c := cfg.LSP; c.RequestTimeout <= 0 || c.IdleTimeout <= 0 {
+		errs = append(errs, errors.New("lsp.request_timeout and lsp.idle_timeout must be positive"))
+	}
+	if c := cfg.CI; c.PollInterval < 0 || c.WaitTimeout <= 0 || c.WatchWindow <= 0 {
+		errs = append(errs, errors.New("ci.wait_timeout and ci.watch_window must be positive and poll_interval not negative"))
+	}
+	if c := cfg.Conversation; c.Model == "" || c.SummarizeAt < 0 || c.KeepRecent < 1 || c.SummaryMaxTokens < 1 || c.ToolOutputMax < 1 {
+		errs = append(errs, errors.New("conversation.model is required, summarize_at must not be negative and keep_recent, summary_max_tokens and tool_output_max must be positive"))
+	}
+	if a := cfg.Attachments; a.MaxBytes < 1 || len(a.AllowedTypes) == 0 {
+		errs = append(errs, errors.New("attachments.max_bytes must be positive and attachments.allowed_types must not be empty"))
+	}
+	if m := cfg.Memory; m.Limit < 1 || m.HalfLife < 0 || m.SemanticWeight < 0 || m.RecencyWeight < 0 || m.ImportanceWeight < 0 {
+		errs = append(errs, errors.New("memory.limit must be positive and half_life and the weights must not be negative"))
+	}
+	if s := cfg.Skills; s.KeyID == "" || s.MaxBundleBytes < 1 {
+		errs = append(errs, errors.New("skills.key_id is required and skills.max_bundle_bytes must be positive"))
+	}
+	for i, f := range cfg.Tokenizers.Families {
+		if len(f.Prefixes) == 0 || f.File == "" {
+			errs = append(errs, fmt.Errorf("tokenizers.families[%d]: prefixes and file are required", i))
+		}
+		if f.Kind != "tiktoken" && f.Kind != "sentencepiece" {
+			errs = append(errs, fmt.Errorf("tokenizers.families[%d]: kind must be tiktoken or sentencepiece, got %q", i, f.Kind))
+		}
+		if f.Pattern != "" {
+			if _, err := regexp.Compile(f.Pattern); err != nil {
+				errs = append(errs, fmt.Errorf("tokenizers.families[%d].pattern: %w", i, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// oneOf returns an error if value is not one of allowed.
+func oneOf(key, value string, allowed ...string) error {
+	if slices.Contains(allowed, value) {
+		return nil
+	}
+	quoted := make([]string, len(allowed))
+	for i, a := range allowed {
+		quoted[i] = strconv.Quote(a)
+	}
+	return fmt.Errorf("%s must be one of %s, got %q", key, strings.Join(quoted, ", "), value)
+}
+
+// envLoader sets config fields from environment variables.
+type envLoader struct {
+	keys map[uintptr]string // Field address -> dotted key
+	prov Provenance
+	errs []error
 }
 
-func setString(dst *string, key string) {
-	if v := os.Getenv(key); v != "" {
+// fieldKeys maps the address of every value field of cfg to its key.
+func fieldKeys(cfg *Config) map[uintptr]string {
+	keys := make(map[uintptr]string)
+	walk(reflect.ValueOf(cfg).Elem(), "", "", func(key, _ string, v reflect.Value) {
+		keys[v.Addr().Pointer()] = key
+	})
+	return keys
+}
+
+// get returns the value of env var name, if set, and records it as the
+// origin of dst.
+func (l *envLoader) get(dst any, name string) (string, bool) {
+	v := os.Getenv(name)
+	if v == "" {
+		return "", false
+	}
+	if key, ok := l.keys[reflect.ValueOf(dst).Pointer()]; ok && l.prov != nil {
+		l.prov[key] = Origin{Source: SourceEnv, Detail: name}
+	}
+	return v, true
+}
+
+func (l *envLoader) fail(name, v, want string) {
+	l.errs = append(l.errs, fmt.Errorf("%s: %q is not %s", name, v, want))
+}
+
+func (l *envLoader) setString(dst *string, name string) {
+	if v, ok := l.get(dst, name); ok {
 		*dst = v
 	}
 }
 
-func setInt(dst *int, key string) {
-	if v := os.Getenv(key); v != "" {
-		if n, err := strconv.Atoi(v); err == nil {
-			*dst = n
+// setStrings sets dst from a comma-separated list.
+func (l *envLoader) setStrings(dst *[]string, name string) {
+	if v, ok := l.get(dst, name); ok {
+		var list []string
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+		*dst = list
+	}
+}
+
+func (l *envLoader) setInt(dst *int, name string) {
+	if v, ok := l.get(dst, name); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			l.fail(name, v, "an integer")
+			return
+		}
+		*dst = n
+	}
+}
+
+func (l *envLoader) setInt32(dst *int32, name string) {
+	if v, ok := l.get(dst, name); ok {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			l.fail(name, v, "a 32-bit integer")
+			return
 		}
+		*dst = int32(n)
 	}
 }
 
-func setInt32(dst *int32, key string) {
-	if v := os.Getenv(key); v != "" {
-		if n, err := strconv.ParseInt(v, 10, 32); err == nil {
-			*dst = int32(n)
+func (l *envLoader) setFloat64(dst *float64, name string) {
+	if v, ok := l.get(dst, name); ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			l.fail(name, v, "a number")
+			return
 		}
+		*dst = f
 	}
 }
 
-func setFloat64(dst *float64, key string) {
-	if v := os.Getenv(key); v != "" {
-		if f, err := strconv.ParseFloat(v, 64); err == nil {
-			*dst = f
+func (l *envLoader) setBool(dst *bool, name string) {
+	if v, ok := l.get(dst, name); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			l.fail(name, v, "a boolean (true or false)")
+			return
 		}
+		*dst = b
 	}
 }
 
-func setDuration(dst *time.Duration, key string) {
-	if v := os.Getenv(key); v != "" {
-		if d, err := time.ParseDuration(v); err == nil {
-			*dst = d
+func (l *envLoader) setDuration(dst *time.Duration, name string) {
+	if v, ok := l.get(dst, name); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			l.fail(name, v, `a duration such as "30s" or "1h"`)
+			return
 		}
+		*dst = d
 	}
 }
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// isSection reports whether t is a config section whose fields are keys of
+// their own, as opposed to a value such as a string, list or duration.
+func isSection(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != durationType
+}
+
+// yamlName returns the key of a struct field in YAML.
+func yamlName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if name == "" {
+		return strings.ToLower(f.Name)
+	}
+	return name
+}
+
+// checkKeys walks a YAML node against the type it is decoded into. It
+// reports keys no field takes, with the closest known key as a hint, and
+// records the file and line of every value it sets in prov.
+func checkKeys(node *yaml.Node, t reflect.Type, key, file string, prov Provenance) []error {
+	if node.Kind == yaml.DocumentNode {
+		var errs []error
+		for _, n := range node.Content {
+			errs = append(errs, checkKeys(n, t, key, file, prov)...)
+		}
+		return errs
+	}
+	if !isSection(t) {
+		if prov != nil && key != "" {
+			prov[key] = Origin{Source: SourceYAML, Detail: fmt.Sprintf("%s:%d", file, node.Line)}
+		}
+		// Lists and maps of sections, e.g. tokenizers.families, are checked
+		// item by item; their keys are not tracked separately.
+		var errs []error
+		switch {
+		case t.Kind() == reflect.Slice && isSection(t.Elem()) && node.Kind == yaml.SequenceNode:
+			for i, item := range node.Content {
+				errs = append(errs, checkKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", key, i), file, nil)...)
+			}
+		case t.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				sub := key + "." + node.Content[i].Value
+				errs = append(errs, checkKeys(node.Content[i+1], t.Elem(), sub, file, nil)...)
+			}
+		}
+		return errs
+	}
+	if node.Kind != yaml.MappingNode {
+		// Let the decoder report the type mismatch.
+		return nil
+	}
+
+	fields := make(map[string]reflect.StructField, t.NumField())
+	names := make([]string, 0, t.NumField())
+	for i := range t.NumField() {
+		f := t.Field(i)
+		fields[yamlName(f)] = f
+		names = append(names, yamlName(f))
+	}
+	var errs []error
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		k := node.Content[i]
+		sub := k.Value
+		if key != "" {
+			sub = key + "." + k.Value
+		}
+		f, ok := fields[k.Value]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s:%d: unknown key %s%s", file, k.Line, sub, hint(k.Value, names)))
+			continue
+		}
+		errs = append(errs, checkKeys(node.Content[i+1], f.Type, sub, file, prov)...)
+	}
+	return errs
+}
+
+// lookup returns the field of cfg at a dotted key such as "server.port".
+func lookup(cfg *Config, key string) (reflect.Value, error) {
+	v := reflect.ValueOf(cfg).Elem()
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		if !isSection(v.Type()) {
+			return reflect.Value{}, fmt.Errorf("unknown key %s: %s is not a section", key, strings.Join(parts[:i], "."))
+		}
+		var names []string
+		found := false
+		for j := range v.NumField() {
+			name := yamlName(v.Type().Field(j))
+			if name == part {
+				v, found = v.Field(j), true
+				break
+			}
+			names = append(names, name)
+		}
+		if !found {
+			return reflect.Value{}, fmt.Errorf("unknown key %s%s", key, hint(part, names))
+		}
+	}
+	if isSection(v.Type()) {
+		return reflect.Value{}, fmt.Errorf("%s is a section; set one of its keys", key)
+	}
+	return v, nil
+}
+
+// hint suggests the known name closest to an unknown one.
+func hint(name string, known []string) string {
+	best, bestDist := "", len(name)/2+1
+	for _, k := range known {
+		if d := editDistance(name, k); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	if best == "" {
+		slices.Sort(known)
+		return fmt.Sprintf(" (known keys: %s)", strings.Join(known, ", "))
+	}
+	return fmt.Sprintf(" (did you mean %s?)", best)
+}
+
+// editDistance is the Levenshtein distance of a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
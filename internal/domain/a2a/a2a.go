@@ -0,0 +1,154 @@
+// Package a2a defines the subset of the Agent2Agent (A2A) protocol used to
+// delegate plan steps to remote agents: agent cards, messages, tasks and
+// the events streamed while a task runs.
+package a2a
+
+import "strings"
+
+// BackendName is the agent backend of agents that delegate to a remote A2A
+// agent. Such agents are configured with the config keys below.
+const BackendName = "a2a"
+
+// Agent config keys.
+const (
+	ConfigURL         = "a2a_url"          // Base URL of the remote agent, or the URL of its agent card
+	ConfigTokenSecret = "a2a_token_secret" // Project secret sent as bearer token (optional)
+)
+
+// CardPath is where an agent publishes its card, relative to its base URL.
+const CardPath = "/.well-known/agent-card.json"
+
+// TransportJSONRPC is the only transport supported for delegation.
+const TransportJSONRPC = "JSONRPC"
+
+// AgentCard describes a remote agent.
+type AgentCard struct {
+	Name               string       `json:"name"`
+	Description        string       `json:"description,omitempty"`
+	URL                string       `json:"url"` // JSON-RPC endpoint
+	Version            string       `json:"version,omitempty"`
+	ProtocolVersion    string       `json:"protocolVersion,omitempty"`
+	PreferredTransport string       `json:"preferredTransport,omitempty"` // Default: JSONRPC
+	Capabilities       Capabilities `json:"capabilities"`
+}
+
+// Capabilities are the optional protocol features of an agent.
+type Capabilities struct {
+	Streaming bool `json:"streaming,omitempty"`
+}
+
+// TaskState is the lifecycle state of a remote task.
+type TaskState string
+
+const (
+	StateSubmitted     TaskState = "submitted"
+	StateWorking       TaskState = "working"
+	StateInputRequired TaskState = "input-required"
+	StateAuthRequired  TaskState = "auth-required"
+	StateCompleted     TaskState = "completed"
+	StateCanceled      TaskState = "canceled"
+	StateFailed        TaskState = "failed"
+	StateRejected      TaskState = "rejected"
+	StateUnknown       TaskState = "unknown"
+)
+
+// Terminal reports whether the task will not change state anymore.
+func (s TaskState) Terminal() bool {
+	switch s {
+	case StateCompleted, StateCanceled, StateFailed, StateRejected, StateUnknown:
+		return true
+	}
+	return false
+}
+
+// Interrupted reports whether the task waits for something a delegating
+// plan step cannot give it: more input from the user or credentials.
+func (s TaskState) Interrupted() bool {
+	return s == StateInputRequired || s == StateAuthRequired
+}
+
+// Part is one piece of a message or artifact. Only text parts are used as
+// run output; file and data parts are named in the output instead.
+type Part struct {
+	Kind string         `json:"kind"` // "text", "file" or "data"
+	Text string         `json:"text,omitempty"`
+	File map[string]any `json:"file,omitempty"`
+	Data any            `json:"data,omitempty"`
+}
+
+// TextPart returns a text part.
+func TextPart(text string) Part {
+	return Part{Kind: "text", Text: text}
+}
+
+// Text joins the text of parts, line by line.
+func Text(parts []Part) string {
+	var lines []string
+	for i := range parts {
+		switch parts[i].Kind {
+		case "text":
+			lines = append(lines, parts[i].Text)
+		case "file":
+			name, _ := parts[i].File["name"].(string)
+			if name == "" {
+				name, _ = parts[i].File["uri"].(string)
+			}
+			lines = append(lines, "[file "+name+"]")
+		case "data":
+			lines = append(lines, "[data]")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Message is a message between the client and the remote agent.
+type Message struct {
+	Kind      string `json:"kind"` // "message"
+	Role      string `json:"role"` // "user" or "agent"
+	MessageID string `json:"messageId"`
+	TaskID    string `json:"taskId,omitempty"`
+	ContextID string `json:"contextId,omitempty"`
+	Parts     []Part `json:"parts"`
+}
+
+// TaskStatus is the state of a task with the agent's latest message.
+type TaskStatus struct {
+	State     TaskState `json:"state"`
+	Message   *Message  `json:"message,omitempty"`
+	Timestamp string    `json:"timestamp,omitempty"`
+}
+
+// Artifact is an output of a task.
+type Artifact struct {
+	ArtifactID  string `json:"artifactId"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Parts       []Part `json:"parts"`
+}
+
+// Task is a unit of work on the remote agent.
+type Task struct {
+	Kind      string     `json:"kind"` // "task"
+	ID        string     `json:"id"`
+	ContextID string     `json:"contextId,omitempty"`
+	Status    TaskStatus `json:"status"`
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+}
+
+// Event is a message/stream event or a message/send result. Kind selects
+// which fields are set: "task" (Task), "message" (Message), "status-update"
+// (TaskID, Status, Final) or "artifact-update" (TaskID, Artifact, Append,
+// LastChunk).
+type Event struct {
+	Kind string `json:"kind"`
+
+	Task    *Task    `json:"-"`
+	Message *Message `json:"-"`
+
+	TaskID    string      `json:"taskId,omitempty"`
+	Status    *TaskStatus `json:"status,omitempty"`
+	Final     bool        `json:"final,omitempty"`
+	Artifact  *Artifact   `json:"artifact,omitempty"`
+	Append    bool        `json:"append,omitempty"`
+	LastChunk bool        `json:"lastChunk,omitempty"`
+}
@@ -15,13 +15,21 @@ const (
 
 // Agent represents an AI coding agent instance.
 type Agent struct {
-	ID        string            `json:"id"`
-	ProjectID string            `json:"project_id"`
-	Name      string            `json:"name"`
-	Backend   string            `json:"backend"`
-	Status    Status            `json:"status"`
-	Config    map[string]string `json:"config"`
-	Version   int               `json:"version"`
-	CreatedAt time.Time         `json:"created_at"`
-	UpdatedAt time.Time         `json:"updated_at"`
+	ID           string            `json:"id"`
+	ProjectID    string            `json:"project_id"`
+	SubProjectID string            `json:"sub_project_id,omitempty"` // Sub-project the agent works on by default
+	Name         string            `json:"name"`
+	Backend      string            `json:"backend"`
+	Status       Status            `json:"status"`
+	Config       map[string]string `json:"config"`
+	TemplateID   string            `json:"template_id,omitempty"` // Agent this one was cloned from by automatic scaling
+	Version      int               `json:"version"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// Ephemeral reports whether the agent is a clone that automatic scaling
+// created and retires when idle.
+func (a *Agent) Ephemeral() bool {
+	return a.TemplateID != ""
 }
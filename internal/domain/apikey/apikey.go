@@ -0,0 +1,186 @@
+// Package apikey defines managed API keys: bearer tokens with scopes, a
+// per-key rate limit and a tenant, for clients such as CI systems
+// that should only reach part of the API.
+package apikey
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+)
+
+// Scope grants access to a part of the API. Each scope includes the ones
+// below it: admin includes runs:write, which includes read.
+type Scope string
+
+const (
+	// ScopeRead allows reading: GET requests and read-only queries sent
+	// with POST (GraphQL, retrieval search, graph impact).
+	ScopeRead Scope = "read"
+	// ScopeRunsWrite also allows creating tasks and plans, and starting,
+	// cancelling and acting on runs and plans.
+	ScopeRunsWrite Scope = "runs:write"
+	// ScopeAdmin allows everything, including managing API keys and
+	// reading the audit log.
+	ScopeAdmin Scope = "admin"
+)
+
+var scopeLevel = map[Scope]int{ScopeRead: 1, ScopeRunsWrite: 2, ScopeAdmin: 3}
+
+// validRole matches a role name.
+var validRole = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,49}$`)
+
+// TokenPrefix starts every managed key, so that leaked keys are easy to
+// recognize.
+const TokenPrefix = "cf_"
+
+// MaxNameLen limits the length of a key name.
+const MaxNameLen = 100
+
+var (
+	ErrNameRequired  = errors.New("name is required")
+	ErrNameTooLong   = errors.New("name is too long (max 100 characters)")
+	ErrScopeRequired = errors.New("at least one scope is required")
+	ErrInvalidScope  = errors.New("scopes must be read, runs:write or admin")
+	ErrRateLimit     = errors.New("rate_limit must not be negative")
+	ErrExpired       = errors.New("expires_at must be in the future")
+	ErrInvalidRole   = errors.New("roles must be lowercase letters, digits, '-', '_' or '.' (max 50 characters)")
+
+	// ErrInvalid is returned when authenticating with an unknown or
+	// expired key.
+	ErrInvalid = errors.New("invalid or expired API key")
+)
+
+// Key is a managed API key. Only a SHA-256 hash of the token is stored;
+// the token itself is returned once, when the key is created.
+type Key struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // First characters of the token, to tell keys apart
+	Hash       []byte     `json:"-"`
+	Scopes     []Scope    `json:"scopes"`
+	TenantID   string     `json:"tenant_id,omitempty"` // Requests are scoped to this tenant; see Tenant
+	RateLimit  int        `json:"rate_limit"`          // Requests per minute; 0 applies only the server-wide limit
+	Roles      []string   `json:"roles,omitempty"`     // Roles of the key's holder, e.g. for approval policies' approver_roles
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Created is a new key with its token, which is only returned once.
+type Created struct {
+	Key
+	Token string `json:"token"`
+}
+
+// Allows reports whether one of the key's scopes includes required.
+func (k *Key) Allows(required Scope) bool {
+	for _, s := range k.Scopes {
+		if scopeLevel[s] >= scopeLevel[required] {
+			return true
+		}
+	}
+	return false
+}
+
+// Tenant returns the tenant the key's requests are scoped to: its own
+// tenant, or the default tenant for keys without one. Only admin keys
+// without a tenant act for all tenants, and Tenant returns "" for them.
+func (k *Key) Tenant() string {
+	if k.TenantID != "" || k.Allows(ScopeAdmin) {
+		return k.TenantID
+	}
+	return tenant.DefaultID
+}
+
+// HasRole reports whether the key holds one of roles.
+func (k *Key) HasRole(roles ...string) bool {
+	return slices.ContainsFunc(k.Roles, func(r string) bool { return slices.Contains(roles, r) })
+}
+
+// Expired reports whether the key has expired at now.
+func (k *Key) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// CreateRequest holds the fields for creating or updating a key.
+type CreateRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []Scope    `json:"scopes"`
+	TenantID  string     `json:"tenant_id,omitempty"`
+	RateLimit int        `json:"rate_limit"`
+	Roles     []string   `json:"roles,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Validate checks the name, scopes, tenant, rate limit, roles and expiry.
+func (r *CreateRequest) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return ErrNameRequired
+	}
+	if len(r.Name) > MaxNameLen {
+		return ErrNameTooLong
+	}
+	if len(r.Scopes) == 0 {
+		return ErrScopeRequired
+	}
+	for _, s := range r.Scopes {
+		if _, ok := scopeLevel[s]; !ok {
+			return ErrInvalidScope
+		}
+	}
+	if r.TenantID != "" && !tenant.ValidID(r.TenantID) {
+		return tenant.ErrInvalidID
+	}
+	if r.RateLimit < 0 {
+		return ErrRateLimit
+	}
+	for _, role := range r.Roles {
+		if !validRole.MatchString(role) {
+			return ErrInvalidRole
+		}
+	}
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(time.Now()) {
+		return ErrExpired
+	}
+	return nil
+}
+
+// readOnlyPosts are POST endpoints that only query.
+var readOnlyPosts = []string{"/retrieval/search", "/graph/impact"}
+
+// adminOnly reports whether all requests to path need ScopeAdmin: API key
+// management, the audit log and server administration.
+func adminOnly(path string) bool {
+	for _, p := range []string{"/api/v1/auth", "/api/v1/audit", "/api/v1/admin"} {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Required returns the scope a request needs.
+func Required(method, path string) Scope {
+	switch {
+	case adminOnly(path):
+		return ScopeAdmin
+	case method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions:
+		return ScopeRead
+	case method != http.MethodPost:
+		return ScopeAdmin
+	case path == "/api/v1/graphql" || slices.ContainsFunc(readOnlyPosts, func(s string) bool { return strings.HasSuffix(path, s) }):
+		return ScopeRead
+	case path == "/api/v1/runs" || strings.HasPrefix(path, "/api/v1/runs/") || strings.HasPrefix(path, "/api/v1/plans/"):
+		return ScopeRunsWrite
+	case strings.HasPrefix(path, "/api/v1/projects/") && (strings.HasSuffix(path, "/tasks") || strings.HasSuffix(path, "/plans")):
+		return ScopeRunsWrite
+	}
+	return ScopeAdmin
+}
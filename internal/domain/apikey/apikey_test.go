@@ -0,0 +1,72 @@
+package apikey_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/apikey"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+)
+
+func TestCreateRequest_Validate(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name    string
+		req     apikey.CreateRequest
+		wantErr error
+	}{
+		{"valid", apikey.CreateRequest{Name: "ci", Scopes: []apikey.Scope{apikey.ScopeRunsWrite}, RateLimit: 60}, nil},
+		{"no name", apikey.CreateRequest{Scopes: []apikey.Scope{apikey.ScopeRead}}, apikey.ErrNameRequired},
+		{"no scopes", apikey.CreateRequest{Name: "ci"}, apikey.ErrScopeRequired},
+		{"bad scope", apikey.CreateRequest{Name: "ci", Scopes: []apikey.Scope{"write"}}, apikey.ErrInvalidScope},
+		{"bad tenant", apikey.CreateRequest{Name: "ci", Scopes: []apikey.Scope{apikey.ScopeRead}, TenantID: "Acme"}, tenant.ErrInvalidID},
+		{"negative rate", apikey.CreateRequest{Name: "ci", Scopes: []apikey.Scope{apikey.ScopeRead}, RateLimit: -1}, apikey.ErrRateLimit},
+		{"expired", apikey.CreateRequest{Name: "ci", Scopes: []apikey.Scope{apikey.ScopeRead}, ExpiresAt: &past}, apikey.ErrExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.req.Validate(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRequired(t *testing.T) {
+	tests := []struct {
+		method, path string
+		want         apikey.Scope
+	}{
+		{"GET", "/api/v1/projects", apikey.ScopeRead},
+		{"GET", "/ws", apikey.ScopeRead},
+		{"POST", "/api/v1/graphql", apikey.ScopeRead},
+		{"POST", "/api/v1/projects/p1/retrieval/search", apikey.ScopeRead},
+		{"POST", "/api/v1/runs", apikey.ScopeRunsWrite},
+		{"POST", "/api/v1/runs/r1/cancel", apikey.ScopeRunsWrite},
+		{"POST", "/api/v1/plans/p1/steps/s1/approve", apikey.ScopeRunsWrite},
+		{"POST", "/api/v1/projects/p1/tasks", apikey.ScopeRunsWrite},
+		{"POST", "/api/v1/projects", apikey.ScopeAdmin},
+		{"DELETE", "/api/v1/runs/r1", apikey.ScopeAdmin},
+		{"GET", "/api/v1/auth/api-keys", apikey.ScopeAdmin},
+		{"GET", "/api/v1/audit/entries", apikey.ScopeAdmin},
+		{"GET", "/api/v1/admin/config", apikey.ScopeAdmin},
+	}
+	for _, tt := range tests {
+		if got := apikey.Required(tt.method, tt.path); got != tt.want {
+			t.Errorf("Required(%s %s) = %s, want %s", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestKey_Allows(t *testing.T) {
+	k := apikey.Key{Scopes: []apikey.Scope{apikey.ScopeRunsWrite}}
+	if !k.Allows(apikey.ScopeRead) || !k.Allows(apikey.ScopeRunsWrite) || k.Allows(apikey.ScopeAdmin) {
+		t.Fatal("runs:write must include read and exclude admin")
+	}
+	exp := time.Now()
+	k.ExpiresAt = &exp
+	if !k.Expired(exp) || k.Expired(exp.Add(-time.Second)) {
+		t.Fatal("unexpected expiry")
+	}
+}
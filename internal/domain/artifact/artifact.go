@@ -0,0 +1,77 @@
+// Package artifact defines the Artifact domain entity for files and
+// structured outputs produced by a run (reports, snapshots, patches) and
+// for files users attach to tasks and conversation messages.
+package artifact
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Kind classifies an artifact.
+type Kind string
+
+const (
+	KindResearchReport    Kind = "research_report"    // Structured findings from a research run
+	KindWorkspaceSnapshot Kind = "workspace_snapshot" // Gzipped tarball of a project workspace
+	KindEventArchive      Kind = "event_archive"      // Gzipped JSONL of a run's compacted agent events
+	KindReviewReport      Kind = "review_report"      // Structured findings from a review run
+	KindTestReport        Kind = "test_report"        // Parsed results of a run's test command
+	KindLintReport        Kind = "lint_report"        // Normalized linter findings of a run
+	KindToolOutput        Kind = "tool_output"        // Full output of a tool call truncated in a conversation
+	KindAttachment        Kind = "attachment"         // File uploaded by a user for a task or conversation message
+	KindPatchBundle       Kind = "patch_bundle"       // git format-patch series or git bundle of a mirror delivery
+	KindImageDigest       Kind = "image_digest"       // Container image an image plan step built from the run's workspace
+)
+
+// Artifact is an immutable blob attached to a run, or an attachment
+// uploaded to a project and optionally associated with a task.
+type Artifact struct {
+	ID          string            `json:"id"`
+	RunID       string            `json:"run_id,omitempty"`  // Empty for attachments
+	TaskID      string            `json:"task_id,omitempty"` // Attachments only
+	ProjectID   string            `json:"project_id"`
+	Kind        Kind              `json:"kind"`
+	Name        string            `json:"name"`
+	ContentType string            `json:"content_type"`
+	Data        []byte            `json:"data,omitempty"`
+	Size        int64             `json:"size"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// Validate checks that an Artifact is well-formed.
+func (a *Artifact) Validate() error {
+	if a.RunID == "" && a.Kind != KindAttachment {
+		return errors.New("run_id is required")
+	}
+	if a.ProjectID == "" {
+		return errors.New("project_id is required")
+	}
+	if a.Kind == "" {
+		return errors.New("kind is required")
+	}
+	if a.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+// IsImage reports whether the artifact is an image.
+func (a *Artifact) IsImage() bool {
+	return strings.HasPrefix(a.ContentType, "image/")
+}
+
+// IsText reports whether the artifact's data is text that can be read
+// inline, e.g. source files, logs and JSON.
+func (a *Artifact) IsText() bool {
+	ct, _, _ := strings.Cut(a.ContentType, ";")
+	switch ct = strings.TrimSpace(ct); {
+	case strings.HasPrefix(ct, "text/"):
+		return true
+	case ct == "application/json", ct == "application/xml", ct == "application/yaml", ct == "application/x-yaml":
+		return true
+	}
+	return false
+}
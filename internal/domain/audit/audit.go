@@ -0,0 +1,134 @@
+// Package audit defines the sinks that audit entries (event.AuditEntry)
+// are exported to, such as a SIEM. Each tenant configures its own sinks and
+// each sink receives that tenant's entries at least once, in order.
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+)
+
+// Kind is the type of system a sink delivers to.
+type Kind string
+
+const (
+	// KindSyslog sends RFC 5424 messages over UDP, TCP or TLS.
+	KindSyslog Kind = "syslog"
+	// KindHTTP POSTs batches of entries to a bulk endpoint, e.g. a Splunk
+	// HTTP Event Collector.
+	KindHTTP Kind = "http"
+	// KindKafka produces entries to a topic through a Kafka REST Proxy.
+	KindKafka Kind = "kafka"
+)
+
+// Config keys of sinks.
+const (
+	ConfigAddress     = "address"      // syslog: host:port
+	ConfigNetwork     = "network"      // syslog: udp, tcp (default) or tls
+	ConfigAppName     = "app_name"     // syslog: APP-NAME of messages (default: codeforge)
+	ConfigURL         = "url"          // http: bulk endpoint; kafka: REST Proxy base URL
+	ConfigFormat      = "format"       // http: ndjson (default) or splunk_hec
+	ConfigTopic       = "topic"        // kafka: topic to produce to
+	ConfigTokenSecret = "token_secret" // http, kafka: name of the tenant secret sent as credentials
+	ConfigAuthScheme  = "auth_scheme"  // http, kafka: scheme of the Authorization header (default: Bearer)
+
+	// ConfigToken carries the resolved token_secret to the sink adapter.
+	// It is never stored.
+	ConfigToken = "token"
+)
+
+// HTTP formats.
+const (
+	FormatNDJSON    = "ndjson"
+	FormatSplunkHEC = "splunk_hec"
+)
+
+// MaxNameLen limits the length of a sink name.
+const MaxNameLen = 100
+
+// configKeys are the required and optional config keys of each kind.
+var configKeys = map[Kind]struct{ required, optional []string }{
+	KindSyslog: {[]string{ConfigAddress}, []string{ConfigNetwork, ConfigAppName}},
+	KindHTTP:   {[]string{ConfigURL}, []string{ConfigFormat, ConfigTokenSecret, ConfigAuthScheme}},
+	KindKafka:  {[]string{ConfigURL, ConfigTopic}, []string{ConfigTokenSecret, ConfigAuthScheme}},
+}
+
+var (
+	ErrNameRequired = errors.New("name is required")
+	ErrNameTooLong  = errors.New("name is too long (max 100 characters)")
+	ErrInvalidKind  = errors.New("kind must be syslog, http or kafka")
+	ErrInvalidURL   = errors.New("url must be an absolute http or https URL")
+)
+
+// Sink is an export target of a tenant's audit entries.
+type Sink struct {
+	ID              string            `json:"id"`
+	TenantID        string            `json:"tenant_id"`
+	Name            string            `json:"name"` // Unique per tenant
+	Kind            Kind              `json:"kind"`
+	Config          map[string]string `json:"config"`
+	Enabled         bool              `json:"enabled"`
+	Checkpoint      int64             `json:"checkpoint"`           // Seq of the last entry the sink accepted
+	LastError       string            `json:"last_error,omitempty"` // Error of the last failed delivery; cleared on success
+	LastDeliveredAt *time.Time        `json:"last_delivered_at,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// CreateRequest holds the fields for creating or updating a sink. Updates
+// keep the tenant and the checkpoint.
+type CreateRequest struct {
+	Name     string            `json:"name"`
+	TenantID string            `json:"tenant_id,omitempty"` // Default: the request's tenant
+	Kind     Kind              `json:"kind"`
+	Config   map[string]string `json:"config"`
+	Enabled  *bool             `json:"enabled,omitempty"` // Default: true
+}
+
+// Validate checks the name, tenant, kind and the config keys of the kind.
+func (r *CreateRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return ErrNameRequired
+	}
+	if len(r.Name) > MaxNameLen {
+		return ErrNameTooLong
+	}
+	if r.TenantID != "" && !tenant.ValidID(r.TenantID) {
+		return tenant.ErrInvalidID
+	}
+	keys, ok := configKeys[r.Kind]
+	if !ok {
+		return ErrInvalidKind
+	}
+	for _, k := range keys.required {
+		if strings.TrimSpace(r.Config[k]) == "" {
+			return fmt.Errorf("%s sinks need config.%s", r.Kind, k)
+		}
+	}
+	for k := range r.Config {
+		if !slices.Contains(keys.required, k) && !slices.Contains(keys.optional, k) {
+			return fmt.Errorf("unknown config key %q for %s sinks", k, r.Kind)
+		}
+	}
+	switch r.Kind {
+	case KindSyslog:
+		if n := r.Config[ConfigNetwork]; n != "" && n != "udp" && n != "tcp" && n != "tls" {
+			return errors.New("config.network must be udp, tcp or tls")
+		}
+	case KindHTTP, KindKafka:
+		if u, err := url.Parse(r.Config[ConfigURL]); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrInvalidURL
+		}
+		if f := r.Config[ConfigFormat]; f != "" && f != FormatNDJSON && f != FormatSplunkHEC {
+			return errors.New("config.format must be ndjson or splunk_hec")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package audit_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/audit"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+)
+
+func TestCreateRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     audit.CreateRequest
+		wantErr error
+		wantOK  bool
+	}{
+		{"syslog", audit.CreateRequest{Name: "siem", Kind: audit.KindSyslog, Config: map[string]string{"address": "siem:6514", "network": "tls"}}, nil, true},
+		{"splunk", audit.CreateRequest{Name: "splunk", Kind: audit.KindHTTP, Config: map[string]string{"url": "https://splunk:8088/services/collector", "format": "splunk_hec", "token_secret": "HEC_TOKEN"}}, nil, true},
+		{"kafka", audit.CreateRequest{Name: "kafka", Kind: audit.KindKafka, Config: map[string]string{"url": "http://rest-proxy:8082", "topic": "audit"}}, nil, true},
+		{"no name", audit.CreateRequest{Name: " ", Kind: audit.KindSyslog, Config: map[string]string{"address": "siem:514"}}, audit.ErrNameRequired, false},
+		{"bad tenant", audit.CreateRequest{Name: "siem", TenantID: "Acme", Kind: audit.KindSyslog, Config: map[string]string{"address": "siem:514"}}, tenant.ErrInvalidID, false},
+		{"bad kind", audit.CreateRequest{Name: "siem", Kind: "s3"}, audit.ErrInvalidKind, false},
+		{"bad url", audit.CreateRequest{Name: "splunk", Kind: audit.KindHTTP, Config: map[string]string{"url": "ftp://splunk"}}, audit.ErrInvalidURL, false},
+		{"missing key", audit.CreateRequest{Name: "kafka", Kind: audit.KindKafka, Config: map[string]string{"url": "http://rest-proxy:8082"}}, nil, false},
+		{"unknown key", audit.CreateRequest{Name: "siem", Kind: audit.KindSyslog, Config: map[string]string{"address": "siem:514", "topic": "audit"}}, nil, false},
+		{"bad network", audit.CreateRequest{Name: "siem", Kind: audit.KindSyslog, Config: map[string]string{"address": "siem:514", "network": "quic"}}, nil, false},
+		{"bad format", audit.CreateRequest{Name: "splunk", Kind: audit.KindHTTP, Config: map[string]string{"url": "https://splunk", "format": "csv"}}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("got %v, want ok=%v", err, tt.wantOK)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
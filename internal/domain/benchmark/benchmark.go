@@ -0,0 +1,145 @@
+// Package benchmark defines benchmark suites (repositories, task prompts and
+// validation commands) and the results of running them across agents and
+// models, SWE-bench style.
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ConfigKeyProject marks projects created to host benchmark repositories.
+const ConfigKeyProject = "benchmark"
+
+// ErrUnknownCase is returned when a start request names a case the suite
+// does not define.
+var ErrUnknownCase = errors.New("unknown benchmark case")
+
+// Suite is a named set of benchmark cases, loaded from YAML.
+type Suite struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description,omitempty"`
+	Cases       []Case `yaml:"cases" json:"cases"`
+}
+
+// Case is a single task: a prompt run against a repository and a command
+// whose exit status decides whether the agent solved it.
+type Case struct {
+	ID       string `yaml:"id" json:"id"`
+	Repo     string `yaml:"repo" json:"repo"`         // Git URL cloned into a benchmark project
+	Prompt   string `yaml:"prompt" json:"prompt"`     // Task prompt given to the agent
+	Validate string `yaml:"validate" json:"validate"` // Shell command run in the run's workspace; exit 0 = pass
+}
+
+// Validate checks that a Suite is well-formed.
+func (s *Suite) Validate() error {
+	if s.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(s.Cases) == 0 {
+		return errors.New("at least one case is required")
+	}
+	seen := make(map[string]bool, len(s.Cases))
+	for i := range s.Cases {
+		c := &s.Cases[i]
+		if c.ID == "" || c.Repo == "" || c.Prompt == "" || c.Validate == "" {
+			return fmt.Errorf("case %d: id, repo, prompt and validate are required", i)
+		}
+		if seen[c.ID] {
+			return fmt.Errorf("duplicate case id %q", c.ID)
+		}
+		seen[c.ID] = true
+	}
+	return nil
+}
+
+// Target is an agent, optionally with a model override, evaluated by a benchmark.
+type Target struct {
+	AgentID string `json:"agent_id"`
+	Model   string `json:"model,omitempty"`
+}
+
+// Status is the lifecycle state of a benchmark run.
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+)
+
+// ResultStatus is the outcome of one case for one target.
+type ResultStatus string
+
+const (
+	ResultPending ResultStatus = "pending"
+	ResultPassed  ResultStatus = "passed"
+	ResultFailed  ResultStatus = "failed" // Validation command failed
+	ResultError   ResultStatus = "error"  // Agent run failed or could not be validated
+)
+
+// IsTerminal reports whether the result has been scored.
+func (s ResultStatus) IsTerminal() bool {
+	return s != ResultPending
+}
+
+// Run is one execution of a suite across a set of targets.
+type Run struct {
+	ID          string     `json:"id"`
+	TenantID    string     `json:"tenant_id,omitempty"`
+	Suite       string     `json:"suite"`
+	Status      Status     `json:"status"`
+	Targets     []Target   `json:"targets"`
+	Results     []Result   `json:"results,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Result is the score of one case for one target.
+type Result struct {
+	ID         string       `json:"id"`
+	BenchRunID string       `json:"benchmark_run_id"`
+	CaseID     string       `json:"case_id"`
+	AgentID    string       `json:"agent_id"`
+	Model      string       `json:"model,omitempty"`
+	PlanStepID string       `json:"plan_step_id,omitempty"`
+	RunID      string       `json:"run_id,omitempty"` // Agent run that attempted the case
+	Status     ResultStatus `json:"status"`
+	Output     string       `json:"output,omitempty"` // Tail of the validation output
+	CostUSD    float64      `json:"cost_usd"`
+	DurationMS int64        `json:"duration_ms"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+}
+
+// StartRequest starts a benchmark run of a suite.
+type StartRequest struct {
+	Suite   string   `json:"suite"`
+	Targets []Target `json:"targets"`
+	Cases   []string `json:"cases,omitempty"` // Subset of case IDs; empty runs all
+}
+
+// Validate checks that a StartRequest is well-formed.
+func (r *StartRequest) Validate() error {
+	if r.Suite == "" {
+		return errors.New("suite is required")
+	}
+	if len(r.Targets) == 0 {
+		return errors.New("at least one target is required")
+	}
+	for i := range r.Targets {
+		if r.Targets[i].AgentID == "" {
+			return fmt.Errorf("targets[%d]: agent_id is required", i)
+		}
+	}
+	return nil
+}
+
+// Done reports whether every result of the run has been scored.
+func (r *Run) Done() bool {
+	for i := range r.Results {
+		if !r.Results[i].Status.IsTerminal() {
+			return false
+		}
+	}
+	return true
+}
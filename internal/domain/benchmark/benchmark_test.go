@@ -0,0 +1,92 @@
+package benchmark_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/benchmark"
+)
+
+const suiteYAML = `name: go-basics
+description: Small Go fixes
+cases:
+  - id: nil-deref
+    repo: https://example.com/org/repo.git
+    prompt: Fix the nil pointer dereference in parser.Parse
+    validate: go test ./parser/...
+`
+
+func TestLoadFromDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.yaml"), []byte(suiteYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("ignored"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	suites, err := benchmark.LoadFromDirectory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suites) != 1 || suites[0].Name != "go-basics" || suites[0].Cases[0].Validate != "go test ./parser/..." {
+		t.Fatalf("unexpected suites %+v", suites)
+	}
+
+	// The same suite name twice is rejected
+	if err := os.WriteFile(filepath.Join(dir, "copy.yml"), []byte(suiteYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := benchmark.LoadFromDirectory(dir); err == nil || !strings.Contains(err.Error(), "defined in both") {
+		t.Fatalf("expected duplicate suite error, got %v", err)
+	}
+
+	if suites, err := benchmark.LoadFromDirectory(filepath.Join(dir, "missing")); err != nil || suites != nil {
+		t.Fatalf("expected no suites for missing dir, got %v, %v", suites, err)
+	}
+}
+
+func TestSuiteValidate(t *testing.T) {
+	c := benchmark.Case{ID: "a", Repo: "r", Prompt: "p", Validate: "true"}
+	tests := []struct {
+		name  string
+		suite benchmark.Suite
+		ok    bool
+	}{
+		{"valid", benchmark.Suite{Name: "s", Cases: []benchmark.Case{c}}, true},
+		{"no name", benchmark.Suite{Cases: []benchmark.Case{c}}, false},
+		{"no cases", benchmark.Suite{Name: "s"}, false},
+		{"missing validate", benchmark.Suite{Name: "s", Cases: []benchmark.Case{{ID: "a", Repo: "r", Prompt: "p"}}}, false},
+		{"duplicate ids", benchmark.Suite{Name: "s", Cases: []benchmark.Case{c, c}}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.suite.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: got %v", tt.name, err)
+		}
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	results := []benchmark.Result{
+		{AgentID: "a1", Status: benchmark.ResultPassed, CostUSD: 0.5, DurationMS: 1000},
+		{AgentID: "a1", Status: benchmark.ResultFailed, CostUSD: 0.5, DurationMS: 3000},
+		{AgentID: "a2", Model: "m", Status: benchmark.ResultPassed, CostUSD: 0.2},
+		{AgentID: "a2", Model: "m", Status: benchmark.ResultPassed, CostUSD: 0.2},
+		{AgentID: "a3", Status: benchmark.ResultPassed, CostUSD: 0.1},
+		{AgentID: "a3", Status: benchmark.ResultError},
+		{AgentID: "a4", Status: benchmark.ResultPending},
+	}
+	board := benchmark.Leaderboard(results)
+	if len(board) != 3 {
+		t.Fatalf("expected 3 entries, got %+v", board)
+	}
+	if board[0].AgentID != "a2" || board[0].PassRate != 1 || board[0].Model != "m" {
+		t.Fatalf("expected a2/m first, got %+v", board[0])
+	}
+	// a1 and a3 tie at 50%; a3 is cheaper
+	if board[1].AgentID != "a3" || board[1].Errors != 1 || board[2].AgentID != "a1" || board[2].AvgDurationMS != 2000 {
+		t.Fatalf("unexpected ranking %+v", board)
+	}
+}
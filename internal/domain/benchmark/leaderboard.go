@@ -0,0 +1,65 @@
+package benchmark
+
+import "sort"
+
+// LeaderboardEntry aggregates the scored results of one target.
+type LeaderboardEntry struct {
+	AgentID       string  `json:"agent_id"`
+	Model         string  `json:"model,omitempty"`
+	Passed        int     `json:"passed"`
+	Failed        int     `json:"failed"`
+	Errors        int     `json:"errors"`
+	Total         int     `json:"total"`
+	PassRate      float64 `json:"pass_rate"`
+	CostUSD       float64 `json:"cost_usd"`
+	AvgDurationMS int64   `json:"avg_duration_ms"`
+}
+
+// Leaderboard ranks targets by pass rate, breaking ties by lower total cost.
+// Pending results are ignored.
+func Leaderboard(results []Result) []LeaderboardEntry {
+	type key struct{ agent, model string }
+	byTarget := make(map[key]*LeaderboardEntry)
+	var order []key
+	durations := make(map[key]int64)
+
+	for i := range results {
+		res := &results[i]
+		if !res.Status.IsTerminal() {
+			continue
+		}
+		k := key{res.AgentID, res.Model}
+		e, ok := byTarget[k]
+		if !ok {
+			e = &LeaderboardEntry{AgentID: res.AgentID, Model: res.Model}
+			byTarget[k] = e
+			order = append(order, k)
+		}
+		e.Total++
+		switch res.Status {
+		case ResultPassed:
+			e.Passed++
+		case ResultFailed:
+			e.Failed++
+		default:
+			e.Errors++
+		}
+		e.CostUSD += res.CostUSD
+		durations[k] += res.DurationMS
+	}
+
+	entries := make([]LeaderboardEntry, 0, len(order))
+	for _, k := range order {
+		e := byTarget[k]
+		e.PassRate = float64(e.Passed) / float64(e.Total)
+		e.AvgDurationMS = durations[k] / int64(e.Total)
+		entries = append(entries, *e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].PassRate != entries[j].PassRate {
+			return entries[i].PassRate > entries[j].PassRate
+		}
+		return entries[i].CostUSD < entries[j].CostUSD
+	})
+	return entries
+}
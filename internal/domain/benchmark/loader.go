@@ -0,0 +1,66 @@
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadFromFile reads a single Suite from a YAML file.
+func LoadFromFile(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read benchmark suite %s: %w", path, err)
+	}
+
+	var s Suite
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse benchmark suite %s: %w", path, err)
+	}
+
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("validate benchmark suite %s: %w", path, err)
+	}
+
+	return &s, nil
+}
+
+// LoadFromDirectory reads all .yaml/.yml suites from a directory. Missing
+// directories return an empty slice (not an error).
+func LoadFromDirectory(dir string) ([]Suite, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read benchmark directory %s: %w", dir, err)
+	}
+
+	var suites []Suite
+	seen := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+
+		s, err := LoadFromFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := seen[s.Name]; ok {
+			return nil, fmt.Errorf("benchmark suite %q defined in both %s and %s", s.Name, prev, entry.Name())
+		}
+		seen[s.Name] = entry.Name()
+		suites = append(suites, *s)
+	}
+
+	return suites, nil
+}
@@ -0,0 +1,212 @@
+// Package chatops defines the slash commands users post as pull request
+// comments ("/codeforge fix") and the runs CodeForge starts for them.
+package chatops
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Prefix starts a command line in a pull request comment.
+const Prefix = "/codeforge"
+
+// Project config keys of the command interface.
+const (
+	ConfigRoles = "chatops_roles"    // Comma-separated roles allowed to run commands
+	ConfigAgent = "chatops_agent_id" // Agent running fix and explain commands; defaults to the project's first agent
+)
+
+// TriggerEvent is the trigger event of command runs; microagents listing it
+// are activated for them.
+const TriggerEvent = "git.pr.command"
+
+var (
+	ErrUnknownCommand = errors.New("unknown command")
+	ErrNotAllowed     = errors.New("commenter is not allowed to run commands")
+	ErrNoAgent        = errors.New("project has no agent to run commands")
+)
+
+// Command is a command users can post on a pull request.
+type Command string
+
+const (
+	CommandRerun   Command = "rerun"   // Review the pull request again and publish the review
+	CommandFix     Command = "fix"     // Fix the failing tests and push to the pull request branch
+	CommandExplain Command = "explain" // Explain the changes of the pull request
+	CommandHelp    Command = "help"    // List the commands
+)
+
+// Commands lists the commands with their descriptions, in help order.
+var Commands = []struct {
+	Command     Command
+	Description string
+}{
+	{CommandRerun, "review the pull request again and publish the review"},
+	{CommandFix, "fix the failing tests and push the fix to this branch"},
+	{CommandExplain, "explain the changes of this pull request"},
+	{CommandHelp, "list the commands"},
+}
+
+// Valid reports whether c is a known command.
+func (c Command) Valid() bool {
+	for _, known := range Commands {
+		if known.Command == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Invocation is a command found in a comment. Args is the rest of the
+// command line, e.g. extra instructions for the agent.
+type Invocation struct {
+	Command Command `json:"command"`
+	Args    string  `json:"args,omitempty"`
+}
+
+// Parse returns the first command line of a comment. It returns false if
+// the comment has none and ErrUnknownCommand for a command line with an
+// unknown or missing command.
+func Parse(comment string) (*Invocation, bool, error) {
+	for _, line := range strings.Split(comment, "\n") {
+		rest, ok := strings.CutPrefix(strings.TrimSpace(line), Prefix)
+		if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+			continue
+		}
+		name, args, _ := strings.Cut(strings.TrimSpace(rest), " ")
+		inv := &Invocation{Command: Command(strings.ToLower(name)), Args: strings.TrimSpace(args)}
+		if !inv.Command.Valid() {
+			return inv, true, fmt.Errorf("%w %q", ErrUnknownCommand, name)
+		}
+		return inv, true, nil
+	}
+	return nil, false, nil
+}
+
+// Help returns the reply listing the commands.
+func Help() string {
+	var b strings.Builder
+	b.WriteString("CodeForge commands:\n\n")
+	for _, c := range Commands {
+		fmt.Fprintf(&b, "- `%s %s`: %s\n", Prefix, c.Command, c.Description)
+	}
+	return b.String()
+}
+
+// Role is the role of a commenter in the repository, normalized across git
+// hosts.
+type Role string
+
+const (
+	RoleOwner        Role = "owner"
+	RoleMember       Role = "member"       // GitHub organization member, GitLab maintainer
+	RoleCollaborator Role = "collaborator" // GitHub collaborator, GitLab developer
+	RoleContributor  Role = "contributor"  // Previously contributed, GitLab reporter
+	RoleNone         Role = "none"
+)
+
+// DefaultRoles are allowed to run commands unless the project configures
+// chatops_roles.
+var DefaultRoles = []Role{RoleOwner, RoleMember, RoleCollaborator}
+
+// AllowedRoles returns the roles a project config allows to run commands.
+func AllowedRoles(cfg map[string]string) []Role {
+	raw := strings.TrimSpace(cfg[ConfigRoles])
+	if raw == "" {
+		return DefaultRoles
+	}
+	var roles []Role
+	for _, r := range strings.Split(raw, ",") {
+		if r = strings.ToLower(strings.TrimSpace(r)); r != "" {
+			roles = append(roles, Role(r))
+		}
+	}
+	return roles
+}
+
+// Allowed reports whether a commenter with role may run commands.
+func Allowed(cfg map[string]string, role Role) bool {
+	return role != "" && slices.Contains(AllowedRoles(cfg), role)
+}
+
+// Status is the state of a command run.
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+)
+
+// CommandRun links a command posted on a pull request to the run started
+// for it and to the reply that reports its result.
+type CommandRun struct {
+	ID        string    `json:"id"`
+	ProjectID string    `json:"project_id"`
+	Provider  string    `json:"provider"`
+	PRNumber  int       `json:"pr_number"`
+	CommentID string    `json:"comment_id"` // Comment that posted the command
+	Branch    string    `json:"branch"`
+	Command   Command   `json:"command"`
+	Args      string    `json:"args,omitempty"`
+	Author    string    `json:"author"`
+	RunID     string    `json:"run_id,omitempty"`
+	ReplyID   string    `json:"reply_id,omitempty"`
+	Status    Status    `json:"status"`
+	Result    string    `json:"result,omitempty"` // Reply text of a finished command
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Prompt returns the task prompt of the command's run. base is the branch
+// the pull request merges into.
+func (c *CommandRun) Prompt(base string) string {
+	var b strings.Builder
+	switch c.Command {
+	case CommandRerun:
+		fmt.Fprintf(&b, "Review pull request #%d: the changes of branch %s against %s (git diff origin/%s...HEAD).\n", c.PRNumber, c.Branch, base, base)
+		b.WriteString("Record your findings as the review of this run. Do not modify any files.\n")
+	case CommandFix:
+		fmt.Fprintf(&b, "The tests of pull request #%d (branch %s into %s) fail.\n", c.PRNumber, c.Branch, base)
+		b.WriteString("Run the test suite, find the cause of the failures and fix them without changing the intent of the pull request.\n")
+	case CommandExplain:
+		fmt.Fprintf(&b, "Explain the changes of pull request #%d: branch %s against %s (git diff origin/%s...HEAD).\n", c.PRNumber, c.Branch, base, base)
+		b.WriteString("Summarize what changed and why for a reviewer, in Markdown, as your final answer. Do not modify any files.\n")
+	}
+	if c.Args != "" {
+		fmt.Fprintf(&b, "\nInstructions from @%s: %s\n", c.Author, c.Args)
+	}
+	return b.String()
+}
+
+// maxResult keeps replies below the comment size limits of git hosts.
+const maxResult = 60000
+
+// Reply returns the reply to the command's comment. runLink is the web UI
+// link of the run and may be empty.
+func (c *CommandRun) Reply(runLink string) string {
+	cmd := "`" + Prefix + " " + string(c.Command) + "`"
+	ref := "`" + c.RunID + "`"
+	if runLink != "" {
+		ref = "[" + c.RunID + "](" + runLink + ")"
+	}
+	switch c.Status {
+	case StatusRunning:
+		return fmt.Sprintf("@%s CodeForge is running %s as run %s.", c.Author, cmd, ref)
+	case StatusCompleted:
+		result := c.Result
+		if r := []rune(result); len(r) > maxResult {
+			result = string(r[:maxResult]) + "\n\n(truncated)"
+		}
+		return fmt.Sprintf("@%s %s finished (run %s).\n\n%s", c.Author, cmd, ref, result)
+	default:
+		if c.RunID == "" {
+			return fmt.Sprintf("@%s CodeForge could not run %s: %s", c.Author, cmd, c.Error)
+		}
+		return fmt.Sprintf("@%s %s failed (run %s): %s", c.Author, cmd, ref, c.Error)
+	}
+}
@@ -0,0 +1,74 @@
+package chatops_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/chatops"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		comment string
+		want    *chatops.Invocation
+		found   bool
+		err     error
+	}{
+		{"rerun", "/codeforge rerun", &chatops.Invocation{Command: chatops.CommandRerun}, true, nil},
+		{"args", "Thanks!\n  /codeforge Fix the flaky login test  \nmore", &chatops.Invocation{Command: chatops.CommandFix, Args: "the flaky login test"}, true, nil},
+		{"first command wins", "/codeforge explain\n/codeforge fix", &chatops.Invocation{Command: chatops.CommandExplain}, true, nil},
+		{"no command", "LGTM, see /codeforge docs", nil, false, nil},
+		{"other prefix", "/codeforgex fix", nil, false, nil},
+		{"quoted", "> /codeforge fix was great", nil, false, nil},
+		{"unknown", "/codeforge deploy", &chatops.Invocation{Command: "deploy"}, true, chatops.ErrUnknownCommand},
+		{"missing", "/codeforge", &chatops.Invocation{}, true, chatops.ErrUnknownCommand},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found, err := chatops.Parse(tt.comment)
+			if found != tt.found || !errors.Is(err, tt.err) {
+				t.Fatalf("Parse = %+v, %v, %v", got, found, err)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	if !slices.Equal(chatops.AllowedRoles(nil), chatops.DefaultRoles) {
+		t.Fatalf("expected default roles, got %v", chatops.AllowedRoles(nil))
+	}
+	if !chatops.Allowed(nil, chatops.RoleCollaborator) || chatops.Allowed(nil, chatops.RoleContributor) || chatops.Allowed(nil, "") {
+		t.Fatal("unexpected default allowlist")
+	}
+	cfg := map[string]string{chatops.ConfigRoles: " Owner, contributor ,"}
+	if !slices.Equal(chatops.AllowedRoles(cfg), []chatops.Role{chatops.RoleOwner, chatops.RoleContributor}) {
+		t.Fatalf("unexpected roles %v", chatops.AllowedRoles(cfg))
+	}
+	if chatops.Allowed(cfg, chatops.RoleMember) || !chatops.Allowed(cfg, chatops.RoleContributor) {
+		t.Fatal("expected the configured allowlist")
+	}
+}
+
+func TestReply(t *testing.T) {
+	c := &chatops.CommandRun{Command: chatops.CommandFix, Author: "alice", RunID: "run-1", Status: chatops.StatusRunning}
+	if got := c.Reply("http://ui/run-1"); !strings.Contains(got, "@alice") || !strings.Contains(got, "[run-1](http://ui/run-1)") {
+		t.Fatalf("unexpected running reply %q", got)
+	}
+	c.Status, c.Result = chatops.StatusCompleted, "Pushed abc123."
+	if got := c.Reply(""); !strings.Contains(got, "finished") || !strings.HasSuffix(got, "Pushed abc123.") {
+		t.Fatalf("unexpected completed reply %q", got)
+	}
+	c.Status, c.RunID, c.Error = chatops.StatusFailed, "", "no agent"
+	if got := c.Reply(""); !strings.Contains(got, "could not run") || !strings.Contains(got, "no agent") {
+		t.Fatalf("unexpected failed reply %q", got)
+	}
+	if help := chatops.Help(); !strings.Contains(help, "/codeforge fix") {
+		t.Fatalf("unexpected help %q", help)
+	}
+}
@@ -0,0 +1,88 @@
+// Package ci models the CI results of the commits runs deliver: the
+// workflow runs or pipelines a CI provider reports for a branch and commit
+// and their overall state, which can gate review approval and delivery.
+package ci
+
+import "time"
+
+// Project config keys gating on CI results.
+const (
+	// ConfigGateReview ("true") keeps published reviews from approving a
+	// pull request until its CI is green.
+	ConfigGateReview = "ci_gate_review"
+	// ConfigGateDelivery ("true") fails branch, pull request and push
+	// deliveries whose pushed commit does not pass CI, and opens pull
+	// requests only once it did.
+	ConfigGateDelivery = "ci_gate_delivery"
+)
+
+// State is the state of a pipeline or of a commit's CI as a whole.
+type State string
+
+const (
+	StateNone      State = "none" // No pipeline ran for the commit
+	StatePending   State = "pending"
+	StateRunning   State = "running"
+	StateSuccess   State = "success"
+	StateFailure   State = "failure"
+	StateCancelled State = "cancelled"
+	StateSkipped   State = "skipped"
+)
+
+// Done reports whether the state is final.
+func (s State) Done() bool {
+	switch s {
+	case StateSuccess, StateFailure, StateCancelled, StateSkipped:
+		return true
+	}
+	return false
+}
+
+// Pipeline is one workflow run or pipeline of a commit.
+type Pipeline struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Branch     string     `json:"branch"`
+	SHA        string     `json:"sha"`
+	State      State      `json:"state"`
+	URL        string     `json:"url,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Status is the CI of the commit a run delivered.
+type Status struct {
+	RunID     string     `json:"run_id"`
+	ProjectID string     `json:"project_id"`
+	Provider  string     `json:"provider"`
+	Branch    string     `json:"branch"`
+	SHA       string     `json:"sha"`
+	State     State      `json:"state"`
+	Pipelines []Pipeline `json:"pipelines"`
+	CheckedAt time.Time  `json:"checked_at"`
+	// Error is why the latest check failed; the pipelines are then those
+	// of the check before.
+	Error string `json:"error,omitempty"`
+}
+
+// Green reports whether the commit passed CI.
+func (s *Status) Green() bool { return s.State == StateSuccess }
+
+// Overall returns the state of a commit's CI from its pipelines: failure
+// if any failed or was cancelled, pending while any has not finished,
+// success once all succeeded or were skipped, and none without pipelines.
+func Overall(pipelines []Pipeline) State {
+	if len(pipelines) == 0 {
+		return StateNone
+	}
+	state := StateSuccess
+	for i := range pipelines {
+		switch pipelines[i].State {
+		case StateFailure, StateCancelled:
+			return StateFailure
+		case StatePending, StateRunning:
+			state = StatePending
+		}
+	}
+	return state
+}
@@ -0,0 +1,43 @@
+package ci
+
+import "testing"
+
+func TestOverall(t *testing.T) {
+	p := func(states ...State) []Pipeline {
+		out := make([]Pipeline, len(states))
+		for i, s := range states {
+			out[i].State = s
+		}
+		return out
+	}
+	tests := []struct {
+		name      string
+		pipelines []Pipeline
+		want      State
+	}{
+		{"no pipelines", nil, StateNone},
+		{"all passed", p(StateSuccess, StateSkipped), StateSuccess},
+		{"one running", p(StateSuccess, StateRunning), StatePending},
+		{"one failed while another runs", p(StateRunning, StateFailure), StateFailure},
+		{"cancelled", p(StateSuccess, StateCancelled), StateFailure},
+	}
+	for _, tt := range tests {
+		if got := Overall(tt.pipelines); got != tt.want {
+			t.Errorf("%s: Overall = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStateDone(t *testing.T) {
+	for s, want := range map[State]bool{
+		StateNone:    false,
+		StatePending: false,
+		StateRunning: false,
+		StateSuccess: true,
+		StateFailure: true,
+	} {
+		if got := s.Done(); got != want {
+			t.Errorf("%q.Done() = %v, want %v", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package codegraph
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Grammar extracts the imports and exported declarations of the source
+// files of one language. Grammars register themselves by name; a
+// deployment enables a subset of them with codegraph.grammars. The
+// grammars of this package cover Go with go/parser, and Python and
+// TypeScript with regular expressions that only find top-level
+// declarations; adapter/treesitter registers the tree-sitter ones.
+type Grammar struct {
+	Name       string              // e.g. "rust"
+	Language   Language            // Language of the files it parses
+	Extensions []string            // File extensions with the dot, e.g. ".rs"
+	Test       func(p string) bool // Reports whether a file is a test; nil treats none as tests
+	Extract    func(f *File, src []byte)
+}
+
+var (
+	grammarMu    sync.RWMutex
+	grammars     = make(map[string]*Grammar)
+	grammarByExt = make(map[string]*Grammar)
+)
+
+// RegisterGrammar makes a grammar available by name. It is typically
+// called from an init() function; registering a name or an extension twice
+// panics.
+func RegisterGrammar(g *Grammar) {
+	grammarMu.Lock()
+	defer grammarMu.Unlock()
+
+	if _, exists := grammars[g.Name]; exists {
+		panic(fmt.Sprintf("codegraph: duplicate registration for grammar %q", g.Name))
+	}
+	for _, ext := range g.Extensions {
+		if other, exists := grammarByExt[ext]; exists {
+			panic(fmt.Sprintf("codegraph: extension %q of grammar %q is registered by %q", ext, g.Name, other.Name))
+		}
+	}
+	grammars[g.Name] = g
+	for _, ext := range g.Extensions {
+		grammarByExt[ext] = g
+	}
+}
+
+// Grammars returns the names of all registered grammars, sorted.
+func Grammars() []string {
+	grammarMu.RLock()
+	defer grammarMu.RUnlock()
+	return sortedNames()
+}
+
+// Parser parses the files of a set of enabled grammars.
+type Parser struct {
+	names []string
+	byExt map[string]*Grammar
+}
+
+// NewParser returns a Parser for the named grammars. No names enables all
+// registered grammars.
+func NewParser(names []string) (*Parser, error) {
+	grammarMu.RLock()
+	defer grammarMu.RUnlock()
+
+	if len(names) == 0 {
+		for name := range grammars {
+			names = append(names, name)
+		}
+	}
+	p := &Parser{byExt: make(map[string]*Grammar)}
+	for _, name := range names {
+		g, ok := grammars[name]
+		if !ok {
+			return nil, fmt.Errorf("codegraph: unknown grammar %q (available: %s)", name, strings.Join(sortedNames(), ", "))
+		}
+		if slices.Contains(p.names, name) {
+			continue
+		}
+		p.names = append(p.names, name)
+		for _, ext := range g.Extensions {
+			p.byExt[ext] = g
+		}
+	}
+	sort.Strings(p.names)
+	return p, nil
+}
+
+// sortedNames is Grammars for callers holding grammarMu.
+func sortedNames() []string {
+	names := make([]string, 0, len(grammars))
+	for name := range grammars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Grammars returns the names of the enabled grammars, sorted.
+func (p *Parser) Grammars() []string {
+	return p.names
+}
+
+// LanguageOf returns the language of a file path, or "" if no enabled
+// grammar handles it.
+func (p *Parser) LanguageOf(name string) Language {
+	if g := p.byExt[path.Ext(name)]; g != nil {
+		return g.Language
+	}
+	return ""
+}
+
+// Parse extracts the imports and exported declarations of a source file.
+// It returns nil if no enabled grammar handles it. Files with syntax errors
+// yield whatever could be parsed.
+func (p *Parser) Parse(name string, src []byte) *File {
+	return p.byExt[path.Ext(name)].parse(name, src)
+}
+
+// grammarFor returns the registered grammar of a file path, or nil.
+func grammarFor(name string) *Grammar {
+	grammarMu.RLock()
+	defer grammarMu.RUnlock()
+	return grammarByExt[path.Ext(name)]
+}
+
+// LanguageOf returns the language of a file path, or "" if no registered
+// grammar handles it.
+func LanguageOf(p string) Language {
+	if g := grammarFor(p); g != nil {
+		return g.Language
+	}
+	return ""
+}
+
+// Parse extracts the imports and exported declarations of a source file
+// with the registered grammar for its extension. It returns nil for
+// unsupported languages. Files with syntax errors yield whatever could be
+// parsed.
+func Parse(p string, src []byte) *File {
+	return grammarFor(p).parse(p, src)
+}
+
+// parse runs the grammar on a file; a nil grammar yields nil.
+func (g *Grammar) parse(p string, src []byte) *File {
+	if g == nil {
+		return nil
+	}
+	f := &File{Path: p, Language: g.Language}
+	if g.Test != nil {
+		f.Test = g.Test(p)
+	}
+	g.Extract(f, src)
+	return f
+}
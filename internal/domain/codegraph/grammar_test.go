@@ -0,0 +1,45 @@
+package codegraph_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/codegraph"
+)
+
+func TestParser(t *testing.T) {
+	p, err := codegraph.NewParser([]string{"python", "go", "python"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(p.Grammars(), []string{"go", "python"}) {
+		t.Fatalf("unexpected grammars %v", p.Grammars())
+	}
+	if p.LanguageOf("main.py") != codegraph.LangPython || p.Parse("main.ts", nil) != nil {
+		t.Fatal("expected only enabled grammars to parse")
+	}
+	if _, err := codegraph.NewParser([]string{"cobol"}); err == nil {
+		t.Fatal("expected error for unknown grammar")
+	}
+
+	all, _ := codegraph.NewParser(nil)
+	if !slices.Equal(all.Grammars(), codegraph.Grammars()) {
+		t.Fatalf("expected all grammars, got %v", all.Grammars())
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	g := buildGraph(t, "", map[string]string{
+		"main.go":      "package main\n\nfunc Run() {}\n\nfunc Stop() {}\n",
+		"main_test.go": "package main\n\nfunc TestRun() {}\n",
+		"lib.py":       "def run():\n    pass\n",
+		"util.py":      "import os\n",
+	})
+	want := []codegraph.LanguageStats{
+		{Language: codegraph.LangGo, Source: codegraph.SourceGrammar, Files: 1, Symbols: 2},
+		{Language: codegraph.LangPython, Source: codegraph.SourceGrammar, Files: 2, Symbols: 1},
+	}
+	if got := g.Languages(); !slices.Equal(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,233 @@
+// Package codegraph defines the file-level dependency graph of a workspace
+// and the impact analysis that finds what a set of changed files affects.
+package codegraph
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// Graph is the import graph of a workspace. Go files depend on the non-test
+// files of the packages they import and of their own package; Python and TypeScript files depend on the modules they import. Imports
+// that do not resolve to a workspace file (standard library, third-party
+// packages) are ignored, as are the imports of the other languages, whose
+// files are nodes without edges.
+type Graph struct {
+	files        map[string]*File
+	dependents   map[string][]string // File path -> paths of the files depending on it
+	dependencies map[string][]string // File path -> paths of the files it depends on
+}
+
+// New builds a Graph from parsed files. goModule is the module path of the
+// workspace's go.mod, or "" if there is none.
+func New(goModule string, files []*File) *Graph {
+	g := &Graph{
+		files:        make(map[string]*File, len(files)),
+		dependents:   make(map[string][]string),
+		dependencies: make(map[string][]string),
+	}
+	goPkgs := make(map[string][]string) // Directory -> Go files
+	pyMods := make(map[string][]string) // Dotted module suffix -> Python files
+	for _, f := range files {
+		g.files[f.Path] = f
+		switch f.Language {
+		case LangGo:
+			dir := path.Dir(f.Path)
+			goPkgs[dir] = append(goPkgs[dir], f.Path)
+		case LangPython:
+			for _, key := range moduleKeys(f.Path) {
+				pyMods[key] = append(pyMods[key], f.Path)
+			}
+		}
+	}
+
+	edges := make(map[[2]string]bool)
+	link := func(from, to string) {
+		if from == to || edges[[2]string{from, to}] {
+			return
+		}
+		edges[[2]string{from, to}] = true
+		g.dependents[to] = append(g.dependents[to], from)
+		g.dependencies[from] = append(g.dependencies[from], to)
+	}
+	for _, f := range files {
+		switch f.Language {
+		case LangGo:
+			for _, sibling := range goPkgs[path.Dir(f.Path)] {
+				if !g.files[sibling].Test {
+					link(f.Path, sibling)
+				}
+			}
+			for _, imp := range f.Imports {
+				if dir, ok := goPackageDir(goModule, imp); ok {
+					for _, target := range goPkgs[dir] {
+						if !g.files[target].Test {
+							link(f.Path, target)
+						}
+					}
+				}
+			}
+		case LangPython:
+			for _, imp := range f.Imports {
+				for _, target := range g.resolvePython(f.Path, imp, pyMods) {
+					link(f.Path, target)
+				}
+			}
+		case LangTypeScript:
+			for _, imp := range f.Imports {
+				if target := g.resolveTypeScript(f.Path, imp); target != "" {
+					link(f.Path, target)
+				}
+			}
+		}
+	}
+	for _, deps := range g.dependents {
+		sort.Strings(deps)
+	}
+	for _, deps := range g.dependencies {
+		sort.Strings(deps)
+	}
+	return g
+}
+
+// Len returns the number of files in the graph.
+func (g *Graph) Len() int {
+	return len(g.files)
+}
+
+// File returns the node of a path, or nil.
+func (g *Graph) File(p string) *File {
+	return g.files[p]
+}
+
+// Dependents returns the files that directly depend on p.
+func (g *Graph) Dependents(p string) []string {
+	return g.dependents[p]
+}
+
+// Dependencies returns the files p directly depends on.
+func (g *Graph) Dependencies(p string) []string {
+	return g.dependencies[p]
+}
+
+// Files returns the nodes of the graph ordered by path.
+func (g *Graph) Files() []*File {
+	out := make([]*File, 0, len(g.files))
+	for _, f := range g.files {
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+// Edges returns the dependencies of the graph ordered by source and target.
+func (g *Graph) Edges() []Edge {
+	var out []Edge
+	for from, deps := range g.dependencies {
+		for _, to := range deps {
+			out = append(out, Edge{From: from, To: to})
+		}
+	}
+	sortEdges(out)
+	return out
+}
+
+// goPackageDir maps an import path to a workspace directory.
+func goPackageDir(module, imp string) (string, bool) {
+	if module == "" {
+		return "", false
+	}
+	if imp == module {
+		return ".", true
+	}
+	if rest, ok := strings.CutPrefix(imp, module+"/"); ok {
+		return rest, true
+	}
+	return "", false
+}
+
+// moduleKeys returns the dotted names a Python file may be imported by:
+// every suffix of its dotted path, since the source root is not known
+// (workers/codeforge/models.py is codeforge.models).
+func moduleKeys(p string) []string {
+	p = strings.TrimSuffix(p, ".py")
+	p = strings.TrimSuffix(p, "/__init__")
+	if p == "__init__" {
+		return nil
+	}
+	parts := strings.Split(p, "/")
+	keys := make([]string, 0, len(parts))
+	for i := range parts {
+		keys = append(keys, strings.Join(parts[i:], "."))
+	}
+	return keys
+}
+
+// resolvePython returns the files an import of importer refers to. Among
+// several absolute candidates the shortest paths win, so a top-level
+// "utils" does not match every nested utils.py.
+func (g *Graph) resolvePython(importer, imp string, mods map[string][]string) []string {
+	if strings.HasPrefix(imp, ".") {
+		rest := strings.TrimLeft(imp, ".")
+		dir := path.Dir(importer)
+		for range len(imp) - len(rest) - 1 {
+			dir = path.Dir(dir)
+		}
+		base := path.Join(dir, strings.ReplaceAll(rest, ".", "/"))
+		cands := []string{base + "/__init__.py"}
+		if rest != "" {
+			cands = []string{base + ".py", base + "/__init__.py"}
+		}
+		for _, c := range cands {
+			if f, ok := g.files[c]; ok && f.Language == LangPython {
+				return []string{c}
+			}
+		}
+		return nil
+	}
+	cands := mods[imp]
+	if len(cands) <= 1 {
+		return cands
+	}
+	best := -1
+	var out []string
+	for _, c := range cands {
+		n := strings.Count(c, "/")
+		switch {
+		case best < 0 || n < best:
+			best, out = n, []string{c}
+		case n == best:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// tsExtensions are tried in order when resolving a relative specifier.
+var tsExtensions = []string{"", ".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs", "/index.ts", "/index.tsx", "/index.js", "/index.jsx"}
+
+// resolveTypeScript resolves a relative import specifier. Package imports
+// and path aliases are not resolved.
+func (g *Graph) resolveTypeScript(importer, spec string) string {
+	if !strings.HasPrefix(spec, "./") && !strings.HasPrefix(spec, "../") {
+		return ""
+	}
+	base := path.Join(path.Dir(importer), spec)
+	if strings.HasPrefix(base, "../") {
+		return ""
+	}
+	bases := []string{base}
+	// ESM TypeScript imports name the compiled .js file.
+	if trimmed, ok := strings.CutSuffix(base, ".js"); ok {
+		bases = append(bases, trimmed)
+	}
+	for _, b := range bases {
+		for _, ext := range tsExtensions {
+			if f, ok := g.files[b+ext]; ok && f.Language == LangTypeScript {
+				return b + ext
+			}
+		}
+	}
+	return ""
+}
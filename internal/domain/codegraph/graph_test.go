@@ -0,0 +1,232 @@
+package codegraph_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/codegraph"
+)
+
+func buildGraph(t *testing.T, module string, sources map[string]string) *codegraph.Graph {
+	t.Helper()
+	var files []*codegraph.File
+	for p, src := range sources {
+		if f := codegraph.Parse(p, []byte(src)); f != nil {
+			files = append(files, f)
+		}
+	}
+	return codegraph.New(module, files)
+}
+
+func TestParseGo(t *testing.T) {
+	f := codegraph.Parse("internal/store/store.go", []byte(`package store
+
+import (
+	"context"
+	"example.com/app/internal/domain"
+)
+
+type Store struct{}
+
+func New() *Store { return &Store{} }
+
+func (s *Store) Get(ctx context.Context) domain.Item { return domain.Item{} }
+
+func helper() {}
+
+const Version = "1"
+`))
+	if f.Language != codegraph.LangGo || f.Test {
+		t.Fatalf("unexpected file %+v", f)
+	}
+	if len(f.Imports) != 2 || f.Imports[1] != "example.com/app/internal/domain" {
+		t.Fatalf("unexpected imports %v", f.Imports)
+	}
+	var names []string
+	for _, s := range f.Symbols {
+		names = append(names, s.Name)
+	}
+	want := []string{"Store", "New", "Store.Get", "Version"}
+	if len(names) != len(want) {
+		t.Fatalf("symbols = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("symbols = %v, want %v", names, want)
+		}
+	}
+	if f.Symbols[2].Kind != "method" || f.Symbols[2].Line != 12 {
+		t.Fatalf("unexpected method symbol %+v", f.Symbols[2])
+	}
+}
+
+func TestParseUnsupported(t *testing.T) {
+	if f := codegraph.Parse("README.md", []byte("# hi")); f != nil {
+		t.Fatalf("expected nil, got %+v", f)
+	}
+}
+
+func TestImpactGo(t *testing.T) {
+	g := buildGraph(t, "example.com/app", map[string]string{
+		"internal/domain/item.go":       "package domain\n\ntype Item struct{}\n",
+		"internal/domain/other.go":      "package domain\n\nfunc Other() {}\n",
+		"internal/domain/item_test.go":  "package domain\n\nimport \"testing\"\n\nfunc TestItem(t *testing.T) {}\n",
+		"internal/service/svc.go":       "package service\n\nimport \"example.com/app/internal/domain\"\n\nvar _ domain.Item\n",
+		"internal/service/svc_test.go":  "package service\n\nimport \"testing\"\n\nfunc TestSvc(t *testing.T) {}\n",
+		"internal/adapter/http/http.go": "package http\n\nimport \"example.com/app/internal/service\"\n\nvar _ = service.X\n",
+		"internal/unrelated/u.go":       "package unrelated\n\nimport \"fmt\"\n\nvar _ = fmt.Sprint\n",
+	})
+
+	imp := g.Impact([]string{"internal/domain/item.go", "gone.go"}, codegraph.DefaultImpactDepth)
+	if len(imp.Unknown) != 1 || imp.Unknown[0] != "gone.go" {
+		t.Fatalf("unexpected unknown %v", imp.Unknown)
+	}
+	depths := make(map[string]int)
+	for _, f := range imp.Files {
+		depths[f.Path] = f.Depth
+	}
+	want := map[string]int{
+		"internal/domain/other.go":      1,
+		"internal/domain/item_test.go":  1,
+		"internal/service/svc.go":       1,
+		"internal/service/svc_test.go":  2,
+		"internal/adapter/http/http.go": 2,
+	}
+	if len(depths) != len(want) {
+		t.Fatalf("impacted files = %v, want %v", depths, want)
+	}
+	for p, d := range want {
+		if depths[p] != d {
+			t.Fatalf("depth of %s = %d, want %d (%v)", p, depths[p], d, depths)
+		}
+	}
+	if len(imp.Tests) != 2 || imp.Tests[0].Target != "./internal/domain" || imp.Tests[1].Target != "./internal/service" {
+		t.Fatalf("unexpected tests %+v", imp.Tests)
+	}
+	if imp.Symbols[0].Name != "Item" {
+		t.Fatalf("expected changed file's symbols first, got %+v", imp.Symbols)
+	}
+
+	shallow := g.Impact([]string{"internal/domain/item.go"}, 1)
+	for _, f := range shallow.Files {
+		if f.Depth > 1 {
+			t.Fatalf("file beyond max depth: %+v", f)
+		}
+	}
+}
+
+func TestImpactPythonAndTypeScript(t *testing.T) {
+	g := buildGraph(t, "", map[string]string{
+		"workers/codeforge/__init__.py":      "",
+		"workers/codeforge/models.py":        "class RunStartMessage:\n    pass\n\ndef _private():\n    pass\n",
+		"workers/codeforge/consumer.py":      "from codeforge.models import RunStartMessage\n",
+		"workers/codeforge/executor.py":      "from .consumer import run\n",
+		"workers/tests/test_consumer.py":     "from codeforge import consumer\n",
+		"frontend/src/api/types.ts":          "export interface Run { id: string }\n",
+		"frontend/src/api/client.ts":         "import type {\n  Run,\n} from \"./types\";\nexport const api = {};\n",
+		"frontend/src/features/RunView.tsx":  "import { api } from '../api/client';\nexport default function RunView() {}\n",
+		"frontend/src/api/client.test.ts":    "import { api } from './client.js';\n",
+		"frontend/src/features/unrelated.ts": "import { x } from 'solid-js';\n",
+	})
+
+	py := g.Impact([]string{"workers/codeforge/models.py"}, codegraph.DefaultImpactDepth)
+	paths := make(map[string]bool)
+	for _, f := range py.Files {
+		paths[f.Path] = true
+	}
+	for _, p := range []string{"workers/codeforge/consumer.py", "workers/codeforge/executor.py", "workers/tests/test_consumer.py"} {
+		if !paths[p] {
+			t.Fatalf("expected %s in python impact, got %+v", p, py.Files)
+		}
+	}
+	if len(py.Tests) != 1 || py.Tests[0].Target != "workers/tests/test_consumer.py" {
+		t.Fatalf("unexpected python tests %+v", py.Tests)
+	}
+	for _, s := range py.Symbols {
+		if s.Name == "_private" {
+			t.Fatal("private python symbol reported")
+		}
+	}
+
+	ts := g.Impact([]string{"frontend/src/api/types.ts"}, codegraph.DefaultImpactDepth)
+	paths = make(map[string]bool)
+	for _, f := range ts.Files {
+		paths[f.Path] = true
+	}
+	if len(paths) != 3 || !paths["frontend/src/api/client.ts"] || !paths["frontend/src/features/RunView.tsx"] || !paths["frontend/src/api/client.test.ts"] {
+		t.Fatalf("unexpected typescript impact %+v", ts.Files)
+	}
+	if len(ts.Tests) != 1 || ts.Tests[0].Depth != 2 {
+		t.Fatalf("unexpected typescript tests %+v", ts.Tests)
+	}
+}
+
+func TestImpactRequestValidate(t *testing.T) {
+	req := codegraph.ImpactRequest{}
+	if err := req.Validate(); err != codegraph.ErrImpactInput {
+		t.Fatalf("expected ErrImpactInput, got %v", err)
+	}
+	req = codegraph.ImpactRequest{Files: []string{"./a/../b.go"}, MaxDepth: 11}
+	if err := req.Validate(); err != codegraph.ErrImpactDepth {
+		t.Fatalf("expected ErrImpactDepth, got %v", err)
+	}
+	req.MaxDepth = 0
+	if err := req.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if req.Files[0] != "b.go" || req.MaxDepth != codegraph.DefaultImpactDepth {
+		t.Fatalf("not normalized: %+v", req)
+	}
+}
+
+func TestRepoMap(t *testing.T) {
+	g := buildGraph(t, "example.com/app", map[string]string{
+		"go.mod":          "module example.com/app\n",
+		"domain/item.go":  "package domain\n\ntype Item struct{}\n",
+		"store/store.go":  "package store\n\nimport \"example.com/app/domain\"\n\nfunc Get() domain.Item { return domain.Item{} }\n",
+		"api/api.go":      "package api\n\nimport (\n\t\"example.com/app/domain\"\n\t\"example.com/app/store\"\n)\n\nvar _ = store.Get\nvar _ domain.Item\n",
+		"api/api_test.go": "package api\n",
+		"cmd/main.go":     "package main\n\nimport \"example.com/app/api\"\n\nfunc main() {}\n",
+	})
+	m := g.RepoMap(3)
+	if m.Total != 4 || !m.Truncated || len(m.Files) != 3 {
+		t.Fatalf("unexpected repo map %+v", m)
+	}
+	if m.Files[0].Path != "domain/item.go" || m.Files[0].Dependents != 2 || m.Files[0].Symbols[0].Name != "Item" {
+		t.Fatalf("expected domain/item.go first, got %+v", m.Files[0])
+	}
+	for _, f := range m.Files {
+		if f.Path == "api/api_test.go" {
+			t.Fatal("test files must not be listed")
+		}
+	}
+	text := m.Text()
+	if !strings.HasPrefix(text, "domain/item.go (2 dependents)\n  type Item\n") || !strings.HasSuffix(text, "... 1 more files\n") {
+		t.Fatalf("unexpected text:\n%s", text)
+	}
+}
+
+func TestRepoMapFit(t *testing.T) {
+	g := buildGraph(t, "example.com/app", map[string]string{
+		"go.mod":         "module example.com/app\n",
+		"domain/item.go": "package domain\n\ntype Item struct{}\n",
+		"store/store.go": "package store\n\nimport \"example.com/app/domain\"\n\nfunc Get() domain.Item { return domain.Item{} }\n",
+		"cmd/main.go":    "package main\n\nimport \"example.com/app/store\"\n\nvar Get = store.Get\n",
+	})
+	lines := func(s string) int { return strings.Count(s, "\n") }
+
+	m := g.RepoMap(0)
+	if !m.Fit(100, lines) || len(m.Files) != 3 || m.Truncated {
+		t.Fatalf("expected a map within the budget to be kept, got %+v", m)
+	}
+	// Each file takes two lines; the rest is summed up in one.
+	if !m.Fit(5, lines) || len(m.Files) != 2 || !m.Truncated || !strings.HasSuffix(m.Text(), "... 1 more files\n") {
+		t.Fatalf("expected the least depended-on file dropped, got %+v", m)
+	}
+	if m.Files[0].Path != "domain/item.go" || m.Files[1].Path != "store/store.go" {
+		t.Fatalf("expected the most depended-on files kept, got %+v", m.Files)
+	}
+	if m.Fit(0, lines) || len(m.Files) != 0 {
+		t.Fatalf("expected no file to fit, got %+v", m)
+	}
+}
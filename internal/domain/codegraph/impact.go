@@ -0,0 +1,162 @@
+package codegraph
+
+import (
+	"errors"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Impact analysis limits.
+const (
+	DefaultImpactDepth = 3
+	MaxImpactDepth     = 10
+	MaxImpactFiles     = 500 // Changed files accepted per request
+	maxImpactSymbols   = 200
+)
+
+// Errors returned for invalid impact requests.
+var (
+	ErrImpactInput = errors.New("either files or run_id is required")
+	ErrImpactDepth = errors.New("max_depth must be between 0 and 10")
+	ErrImpactFiles = errors.New("too many files (max 500)")
+)
+
+// ImpactRequest names the changed files to analyze, either directly or as
+// the files touched by a run.
+type ImpactRequest struct {
+	Files    []string `json:"files,omitempty"`
+	RunID    string   `json:"run_id,omitempty"`
+	MaxDepth int      `json:"max_depth,omitempty"` // 0 = DefaultImpactDepth
+}
+
+// Validate checks the request and normalizes file paths and depth.
+func (r *ImpactRequest) Validate() error {
+	if len(r.Files) == 0 && r.RunID == "" {
+		return ErrImpactInput
+	}
+	if len(r.Files) > MaxImpactFiles {
+		return ErrImpactFiles
+	}
+	if r.MaxDepth < 0 || r.MaxDepth > MaxImpactDepth {
+		return ErrImpactDepth
+	}
+	if r.MaxDepth == 0 {
+		r.MaxDepth = DefaultImpactDepth
+	}
+	for i, f := range r.Files {
+		r.Files[i] = path.Clean(strings.TrimPrefix(strings.ReplaceAll(f, "\\", "/"), "./"))
+	}
+	return nil
+}
+
+// Impact lists what a set of changed files likely affects.
+type Impact struct {
+	Changed   []string       `json:"changed"`
+	Unknown   []string       `json:"unknown,omitempty"` // Changed paths not in the graph (deleted or unsupported)
+	Files     []ImpactedFile `json:"files"`             // Downstream files, nearest first
+	Symbols   []Symbol       `json:"symbols"`           // Exported declarations of the changed and downstream files
+	Tests     []TestTarget   `json:"tests"`             // Test targets to run, nearest first
+	Truncated bool           `json:"truncated,omitempty"`
+}
+
+// ImpactedFile is a file that depends on a changed file.
+type ImpactedFile struct {
+	Path  string `json:"path"`
+	Depth int    `json:"depth"` // Dependency hops from the nearest changed file
+	Via   string `json:"via"`   // The dependency through which it is affected
+	Test  bool   `json:"test,omitempty"`
+}
+
+// TestTarget is a unit tests can be run for: a Go package directory (as
+// "./dir") or a single Python or TypeScript test file.
+type TestTarget struct {
+	Target   string   `json:"target"`
+	Language Language `json:"language"`
+	Files    []string `json:"files"`
+	Depth    int      `json:"depth"`
+}
+
+// Impact walks the dependents of changed up to maxDepth hops.
+func (g *Graph) Impact(changed []string, maxDepth int) *Impact {
+	res := &Impact{Changed: changed, Files: []ImpactedFile{}, Symbols: []Symbol{}, Tests: []TestTarget{}}
+
+	depth := make(map[string]int)
+	var queue []string
+	for _, p := range changed {
+		if _, ok := g.files[p]; !ok {
+			res.Unknown = append(res.Unknown, p)
+			continue
+		}
+		if _, seen := depth[p]; !seen {
+			depth[p] = 0
+			queue = append(queue, p)
+		}
+	}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if depth[p] >= maxDepth {
+			continue
+		}
+		for _, dep := range g.dependents[p] {
+			if _, seen := depth[dep]; seen {
+				continue
+			}
+			depth[dep] = depth[p] + 1
+			queue = append(queue, dep)
+			res.Files = append(res.Files, ImpactedFile{Path: dep, Depth: depth[dep], Via: p, Test: g.files[dep].Test})
+		}
+	}
+	sort.SliceStable(res.Files, func(i, j int) bool {
+		if res.Files[i].Depth != res.Files[j].Depth {
+			return res.Files[i].Depth < res.Files[j].Depth
+		}
+		return res.Files[i].Path < res.Files[j].Path
+	})
+
+	// Symbols and tests in BFS order: changed files first.
+	ordered := make([]string, 0, len(depth))
+	for _, p := range changed {
+		if d, ok := depth[p]; ok && d == 0 {
+			ordered = append(ordered, p)
+		}
+	}
+	for _, f := range res.Files {
+		ordered = append(ordered, f.Path)
+	}
+	targets := make(map[string]int)
+	seen := make(map[string]bool)
+	for _, p := range ordered {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		f := g.files[p]
+		if f.Test {
+			target := p
+			if f.Language == LangGo {
+				target = "./" + path.Dir(p)
+				if target == "./." {
+					target = "."
+				}
+			}
+			i, ok := targets[target]
+			if !ok {
+				i = len(res.Tests)
+				targets[target] = i
+				res.Tests = append(res.Tests, TestTarget{Target: target, Language: f.Language, Depth: depth[p]})
+			}
+			res.Tests[i].Files = append(res.Tests[i].Files, p)
+			continue
+		}
+		for _, sym := range f.Symbols {
+			if len(res.Symbols) == maxImpactSymbols {
+				res.Truncated = true
+				break
+			}
+			res.Symbols = append(res.Symbols, sym)
+		}
+	}
+	return res
+}
@@ -0,0 +1,194 @@
+package codegraph
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Language is the source language of a file in the graph.
+type Language string
+
+const (
+	LangGo         Language = "go"
+	LangPython     Language = "python"
+	LangTypeScript Language = "typescript" // Also JavaScript
+	LangRust       Language = "rust"
+	LangKotlin     Language = "kotlin"
+	LangSwift      Language = "swift"
+	LangTerraform  Language = "terraform"
+)
+
+// Symbol is a top-level declaration of a file.
+type Symbol struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"` // "func", "method", "type", "class", "var", "const", ...
+	Path string `json:"path"`
+	Line int    `json:"line"`
+}
+
+// File is a node of the graph.
+type File struct {
+	Path     string   `json:"path"` // Slash-separated, relative to the workspace
+	Language Language `json:"language"`
+	Test     bool     `json:"test"`
+	Imports  []string `json:"imports,omitempty"` // Import specifiers as written; relative Python imports keep their dots
+	Symbols  []Symbol `json:"symbols,omitempty"` // Exported declarations only
+	Tagged   bool     `json:"tagged,omitempty"`  // Symbols come from the ctags fallback instead of a grammar
+}
+
+func init() {
+	RegisterGrammar(&Grammar{
+		Name: "go", Language: LangGo, Extensions: []string{".go"},
+		Test:    func(p string) bool { return strings.HasSuffix(p, "_test.go") },
+		Extract: parseGo,
+	})
+	RegisterGrammar(&Grammar{
+		Name: "python", Language: LangPython, Extensions: []string{".py"},
+		Test: func(p string) bool {
+			base := path.Base(p)
+			return strings.HasPrefix(base, "test_") || strings.HasSuffix(base, "_test.py")
+		},
+		Extract: func(f *File, src []byte) { parsePython(f, string(src)) },
+	})
+	RegisterGrammar(&Grammar{
+		Name: "typescript", Language: LangTypeScript, Extensions: []string{".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs"},
+		Test: func(p string) bool {
+			base := path.Base(p)
+			return strings.Contains(base, ".test.") || strings.Contains(base, ".spec.") ||
+				strings.Contains("/"+p, "/__tests__/")
+		},
+		Extract: func(f *File, src []byte) { parseTypeScript(f, string(src)) },
+	})
+}
+
+func parseGo(f *File, src []byte) {
+	fset := token.NewFileSet()
+	af, _ := parser.ParseFile(fset, f.Path, src, parser.SkipObjectResolution)
+	if af == nil {
+		return
+	}
+	for _, imp := range af.Imports {
+		f.Imports = append(f.Imports, strings.Trim(imp.Path.Value, "\"`"))
+	}
+	add := func(name *ast.Ident, kind string) {
+		if name.IsExported() {
+			f.Symbols = append(f.Symbols, Symbol{Name: name.Name, Kind: kind, Path: f.Path, Line: fset.Position(name.Pos()).Line})
+		}
+	}
+	for _, decl := range af.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				add(d.Name, "func")
+				continue
+			}
+			if recv := receiverName(d.Recv); recv != "" && ast.IsExported(recv) && d.Name.IsExported() {
+				f.Symbols = append(f.Symbols, Symbol{Name: recv + "." + d.Name.Name, Kind: "method", Path: f.Path, Line: fset.Position(d.Name.Pos()).Line})
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					add(s.Name, "type")
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						add(n, d.Tok.String())
+					}
+				}
+			}
+		}
+	}
+}
+
+// receiverName returns the type name of a method receiver.
+func receiverName(recv *ast.FieldList) string {
+	if len(recv.List) == 0 {
+		return ""
+	}
+	t := recv.List[0].Type
+	for {
+		switch x := t.(type) {
+		case *ast.StarExpr:
+			t = x.X
+		case *ast.IndexExpr:
+			t = x.X
+		case *ast.IndexListExpr:
+			t = x.X
+		case *ast.Ident:
+			return x.Name
+		default:
+			return ""
+		}
+	}
+}
+
+var (
+	pyImport     = regexp.MustCompile(`(?m)^[ \t]*import[ \t]+([\w., \t]+)`)
+	pyFromImport = regexp.MustCompile(`(?m)^[ \t]*from[ \t]+(\.*[\w.]*)[ \t]+import[ \t]+\(?([\w, \t]*)`)
+	pySymbol     = regexp.MustCompile(`(?m)^(?:async[ \t]+)?(def|class)[ \t]+([A-Za-z]\w*)`)
+)
+
+func parsePython(f *File, src string) {
+	for _, m := range pyImport.FindAllStringSubmatch(src, -1) {
+		for _, mod := range strings.Split(m[1], ",") {
+			if fields := strings.Fields(mod); len(fields) > 0 {
+				f.Imports = append(f.Imports, fields[0])
+			}
+		}
+	}
+	// "from a import b" may import the module a.b or the name b of a, so
+	// both are recorded; resolution drops what does not exist.
+	for _, m := range pyFromImport.FindAllStringSubmatch(src, -1) {
+		mod := m[1]
+		f.Imports = append(f.Imports, mod)
+		for _, name := range strings.Split(m[2], ",") {
+			fields := strings.Fields(name)
+			if len(fields) == 0 {
+				continue
+			}
+			sep := "."
+			if strings.HasSuffix(mod, ".") {
+				sep = ""
+			}
+			f.Imports = append(f.Imports, mod+sep+fields[0])
+		}
+	}
+	for _, m := range pySymbol.FindAllStringSubmatchIndex(src, -1) {
+		kind, name := src[m[2]:m[3]], src[m[4]:m[5]]
+		if kind == "def" {
+			kind = "func"
+		}
+		f.Symbols = append(f.Symbols, Symbol{Name: name, Kind: kind, Path: f.Path, Line: lineAt(src, m[0])})
+	}
+}
+
+var (
+	tsImport = regexp.MustCompile(`(?:\bimport|\bexport)\s[^'";]*?\bfrom\s*['"]([^'"]+)['"]` +
+		`|\bimport\s*\(?\s*['"]([^'"]+)['"]` +
+		`|\brequire\(\s*['"]([^'"]+)['"]\s*\)`)
+	tsSymbol = regexp.MustCompile(`(?m)^export\s+(?:default\s+)?(?:declare\s+)?(?:abstract\s+)?(?:async\s+)?` +
+		`(function\*?|class|const|let|var|interface|type|enum)\s+([A-Za-z_$][\w$]*)`)
+)
+
+func parseTypeScript(f *File, src string) {
+	for _, m := range tsImport.FindAllStringSubmatch(src, -1) {
+		for _, spec := range m[1:] {
+			if spec != "" {
+				f.Imports = append(f.Imports, spec)
+			}
+		}
+	}
+	for _, m := range tsSymbol.FindAllStringSubmatchIndex(src, -1) {
+		kind := strings.TrimSuffix(src[m[2]:m[3]], "*")
+		f.Symbols = append(f.Symbols, Symbol{Name: src[m[4]:m[5]], Kind: kind, Path: f.Path, Line: lineAt(src, m[0])})
+	}
+}
+
+// lineAt returns the 1-based line of byte offset i.
+func lineAt(src string, i int) int {
+	return strings.Count(src[:i], "\n") + 1
+}
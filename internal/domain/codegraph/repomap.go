@@ -0,0 +1,159 @@
+package codegraph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Repo map limits.
+const (
+	DefaultRepoMapFiles = 100
+	MaxRepoMapFiles     = 1000
+)
+
+// RepoMap is a compact overview of a workspace for agents: the non-test
+// files ranked by how many other non-test files depend on them, with their
+// exported declarations.
+type RepoMap struct {
+	Files     []MapFile `json:"files"`
+	Total     int       `json:"total"` // Non-test files in the graph
+	Truncated bool      `json:"truncated,omitempty"`
+}
+
+// MapFile is a file of a RepoMap.
+type MapFile struct {
+	Path       string   `json:"path"`
+	Language   Language `json:"language"`
+	Dependents int      `json:"dependents"`
+	Symbols    []Symbol `json:"symbols,omitempty"`
+}
+
+// RepoMap returns up to maxFiles of the most depended-on non-test files.
+// maxFiles <= 0 uses DefaultRepoMapFiles; it is capped at MaxRepoMapFiles.
+func (g *Graph) RepoMap(maxFiles int) *RepoMap {
+	if maxFiles <= 0 {
+		maxFiles = DefaultRepoMapFiles
+	}
+	maxFiles = min(maxFiles, MaxRepoMapFiles)
+
+	files := make([]MapFile, 0, len(g.files))
+	for p, f := range g.files {
+		if f.Test {
+			continue
+		}
+		n := 0
+		for _, d := range g.dependents[p] {
+			if !g.files[d].Test {
+				n++
+			}
+		}
+		files = append(files, MapFile{Path: p, Language: f.Language, Dependents: n, Symbols: f.Symbols})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Dependents != files[j].Dependents {
+			return files[i].Dependents > files[j].Dependents
+		}
+		return files[i].Path < files[j].Path
+	})
+
+	m := &RepoMap{Files: files, Total: len(files)}
+	if len(files) > maxFiles {
+		m.Files, m.Truncated = files[:maxFiles], true
+	}
+	return m
+}
+
+// Text renders the map for a prompt: one line per file with its number of
+// dependents, followed by its declarations.
+func (m *RepoMap) Text() string {
+	var b strings.Builder
+	for _, f := range m.Files {
+		fmt.Fprintf(&b, "%s (%d dependents)\n", f.Path, f.Dependents)
+		for _, sym := range f.Symbols {
+			fmt.Fprintf(&b, "  %s %s\n", sym.Kind, sym.Name)
+		}
+	}
+	if m.Truncated {
+		fmt.Fprintf(&b, "... %d more files\n", m.Total-len(m.Files))
+	}
+	return b.String()
+}
+
+// Fit drops the least depended-on files until the text of the map is at
+// most maxTokens long as counted by count, and reports whether a file is
+// left.
+func (m *RepoMap) Fit(maxTokens int, count func(string) int) bool {
+	if count(m.Text()) <= maxTokens {
+		return len(m.Files) > 0
+	}
+	all := m.Files
+	m.Truncated = true
+	// Fewer files never take more tokens, so search for the first count
+	// that does not fit.
+	n := sort.Search(len(all)+1, func(n int) bool {
+		m.Files = all[:n]
+		return count(m.Text()) > maxTokens
+	})
+	m.Files = all[:max(n-1, 0)]
+	return len(m.Files) > 0
+}
+
+// LanguageStats counts the non-test files of one language in a graph and
+// their exported declarations.
+type LanguageStats struct {
+	Language Language `json:"language"`
+	Source   string   `json:"source"` // "grammar" or "ctags"
+	Files    int      `json:"files"`
+	Symbols  int      `json:"symbols"`
+}
+
+// Symbol sources of LanguageStats.
+const (
+	SourceGrammar = "grammar"
+	SourceCtags   = "ctags"
+)
+
+// RepoMapStatus reports how the repo map of a workspace is built: the
+// enabled grammars, whether files no grammar handles were tagged with ctags,
+// and the files and declarations found per language.
+type RepoMapStatus struct {
+	Grammars  []string        `json:"grammars"`
+	Ctags     bool            `json:"ctags"`
+	Languages []LanguageStats `json:"languages"`
+}
+
+// Languages returns the per-language counts of the non-test files, most
+// declarations first.
+func (g *Graph) Languages() []LanguageStats {
+	idx := make(map[[2]string]int)
+	var out []LanguageStats
+	for _, f := range g.files {
+		if f.Test {
+			continue
+		}
+		source := SourceGrammar
+		if f.Tagged {
+			source = SourceCtags
+		}
+		key := [2]string{string(f.Language), source}
+		i, ok := idx[key]
+		if !ok {
+			i = len(out)
+			idx[key] = i
+			out = append(out, LanguageStats{Language: f.Language, Source: source})
+		}
+		out[i].Files++
+		out[i].Symbols += len(f.Symbols)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Symbols != out[j].Symbols {
+			return out[i].Symbols > out[j].Symbols
+		}
+		if out[i].Language != out[j].Language {
+			return out[i].Language < out[j].Language
+		}
+		return out[i].Source < out[j].Source
+	})
+	return out
+}
@@ -0,0 +1,121 @@
+package codegraph
+
+import (
+	"sort"
+	"time"
+)
+
+// State describes the persisted graph of a project. The files are stored
+// with their imports and symbols, the edges as resolved when the graph was
+// last built or updated.
+type State struct {
+	ProjectID string    `json:"project_id"`
+	GoModule  string    `json:"go_module,omitempty"`
+	Files     int       `json:"files"`
+	Edges     int       `json:"edges"`
+	BuiltAt   time.Time `json:"built_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Edge is a dependency of the file From on the file To.
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Update is an incremental change of a persisted graph: the files parsed
+// anew, which replace the stored files of the same path, the paths that
+// left the graph and the edges that changed with them.
+type Update struct {
+	Files   []*File
+	Deleted []string
+	Added   []Edge
+	Removed []Edge
+}
+
+// Neighbors are the direct dependencies and dependents of a file.
+type Neighbors struct {
+	Path         string   `json:"path"`
+	Dependencies []string `json:"dependencies"`
+	Dependents   []string `json:"dependents"`
+}
+
+// DependencyPath is the shortest chain of dependencies from one file to
+// another, both included. Path is empty if From does not depend on To.
+type DependencyPath struct {
+	From string   `json:"from"`
+	To   string   `json:"to"`
+	Path []string `json:"path"`
+}
+
+// DiffEdges returns the edges of next missing from prev and those of prev
+// missing from next, each ordered by source and target.
+func DiffEdges(prev, next []Edge) (added, removed []Edge) {
+	had := make(map[Edge]bool, len(prev))
+	for _, e := range prev {
+		had[e] = true
+	}
+	has := make(map[Edge]bool, len(next))
+	for _, e := range next {
+		has[e] = true
+		if !had[e] {
+			added = append(added, e)
+		}
+	}
+	for _, e := range prev {
+		if !has[e] {
+			removed = append(removed, e)
+		}
+	}
+	sortEdges(added)
+	sortEdges(removed)
+	return added, removed
+}
+
+// ShortestPath finds the shortest chain of dependencies from one file to
+// another over edges, breadth first. It returns nil if there is none.
+func ShortestPath(edges []Edge, from, to string) []string {
+	if from == to {
+		return []string{from}
+	}
+	next := make(map[string][]string)
+	for _, e := range edges {
+		next[e.From] = append(next[e.From], e.To)
+	}
+	for _, deps := range next {
+		sort.Strings(deps)
+	}
+	prev := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, n := range next[cur] {
+			if _, seen := prev[n]; seen {
+				continue
+			}
+			prev[n] = cur
+			if n == to {
+				var path []string
+				for p := to; p != ""; p = prev[p] {
+					path = append(path, p)
+				}
+				for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+					path[i], path[j] = path[j], path[i]
+				}
+				return path
+			}
+			queue = append(queue, n)
+		}
+	}
+	return nil
+}
+
+func sortEdges(edges []Edge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+}
@@ -0,0 +1,64 @@
+package codegraph_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/codegraph"
+)
+
+func TestGraphEdges(t *testing.T) {
+	g := buildGraph(t, "example.com/app", map[string]string{
+		"main.go":         "package main\n\nimport \"example.com/app/store\"\n",
+		"store/store.go":  "package store\n",
+		"store/helper.go": "package store\n",
+	})
+	want := []codegraph.Edge{
+		{From: "main.go", To: "store/helper.go"},
+		{From: "main.go", To: "store/store.go"},
+		{From: "store/helper.go", To: "store/store.go"},
+		{From: "store/store.go", To: "store/helper.go"},
+	}
+	if got := g.Edges(); !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if deps := g.Dependencies("main.go"); !slices.Equal(deps, []string{"store/helper.go", "store/store.go"}) {
+		t.Fatalf("unexpected dependencies %v", deps)
+	}
+	if files := g.Files(); len(files) != 3 || files[0].Path != "main.go" {
+		t.Fatalf("expected files ordered by path, got %v", files)
+	}
+}
+
+func TestDiffEdges(t *testing.T) {
+	prev := []codegraph.Edge{{From: "a", To: "b"}, {From: "b", To: "c"}}
+	next := []codegraph.Edge{{From: "b", To: "c"}, {From: "c", To: "a"}}
+	added, removed := codegraph.DiffEdges(prev, next)
+	if !slices.Equal(added, []codegraph.Edge{{From: "c", To: "a"}}) || !slices.Equal(removed, []codegraph.Edge{{From: "a", To: "b"}}) {
+		t.Fatalf("got added %v, removed %v", added, removed)
+	}
+	if added, removed := codegraph.DiffEdges(prev, prev); added != nil || removed != nil {
+		t.Fatalf("expected no changes, got %v, %v", added, removed)
+	}
+}
+
+func TestShortestPath(t *testing.T) {
+	edges := []codegraph.Edge{
+		{From: "a", To: "b"}, {From: "b", To: "c"}, {From: "c", To: "d"},
+		{From: "a", To: "x"}, {From: "x", To: "d"},
+	}
+	tests := []struct {
+		from, to string
+		want     []string
+	}{
+		{"a", "d", []string{"a", "x", "d"}},
+		{"b", "d", []string{"b", "c", "d"}},
+		{"a", "a", []string{"a"}},
+		{"d", "a", nil}, // Edges are directed
+	}
+	for _, tt := range tests {
+		if got := codegraph.ShortestPath(edges, tt.from, tt.to); !slices.Equal(got, tt.want) {
+			t.Errorf("%s -> %s: got %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
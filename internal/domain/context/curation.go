@@ -0,0 +1,111 @@
+package context
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CurationAction is what a curation rule does to the entries it matches.
+type CurationAction string
+
+const (
+	CurationPin      CurationAction = "pin"      // Always include, ahead of every other entry
+	CurationExclude  CurationAction = "exclude"  // Never include
+	CurationPriority CurationAction = "priority" // Replace the computed priority
+)
+
+// MaxCurationRules caps the rules of a task's curation.
+const MaxCurationRules = 100
+
+// ErrInvalidCuration is returned for curations with malformed rules.
+var ErrInvalidCuration = errors.New("invalid context curation")
+
+// Curation holds a developer's overrides of the context packs built for
+// a task.
+type Curation struct {
+	TaskID    string         `json:"task_id"`
+	ProjectID string         `json:"project_id"`
+	Rules     []CurationRule `json:"rules"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// CurationRule matches entries by the citation ID of a retrieved chunk
+// or by path: an exact entry path, or a directory when it ends in "/".
+type CurationRule struct {
+	Path     string         `json:"path,omitempty"`
+	ChunkID  string         `json:"chunk_id,omitempty"`
+	Action   CurationAction `json:"action"`
+	Priority int            `json:"priority,omitempty"` // 0-100, for the priority action
+}
+
+// DroppedEntry is a candidate entry a pack left out.
+type DroppedEntry struct {
+	Kind     EntryKind `json:"kind"`
+	Path     string    `json:"path"`
+	ChunkID  string    `json:"chunk_id,omitempty"`
+	Tokens   int       `json:"tokens"`
+	Priority int       `json:"priority"`
+	Reason   string    `json:"reason"` // DropExcluded or DropBudget
+}
+
+// Reasons entries are dropped from a pack.
+const (
+	DropExcluded = "excluded" // A curation rule excludes it
+	DropBudget   = "budget"   // It did not fit the token budget
+)
+
+// PackPreview is a context pack as it would be built for a task now,
+// with the candidates left out and the curation applied.
+type PackPreview struct {
+	Pack     *ContextPack   `json:"pack"` // Nil when no entry made it in
+	Dropped  []DroppedEntry `json:"dropped"`
+	Pinned   []string       `json:"pinned,omitempty"` // Paths or chunk IDs of pins no candidate or file matched
+	Curation *Curation      `json:"curation,omitempty"`
+}
+
+// Validate checks the rules of a curation.
+func (c *Curation) Validate() error {
+	if len(c.Rules) > MaxCurationRules {
+		return fmt.Errorf("%w: more than %d rules", ErrInvalidCuration, MaxCurationRules)
+	}
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		switch {
+		case (r.Path == "") == (r.ChunkID == ""):
+			return fmt.Errorf("%w: rule %d: set either path or chunk_id", ErrInvalidCuration, i)
+		case r.Action != CurationPin && r.Action != CurationExclude && r.Action != CurationPriority:
+			return fmt.Errorf("%w: rule %d: action must be pin, exclude or priority", ErrInvalidCuration, i)
+		case r.Priority < 0 || r.Priority > 100:
+			return fmt.Errorf("%w: rule %d: priority must be between 0 and 100", ErrInvalidCuration, i)
+		}
+	}
+	return nil
+}
+
+// Match returns the rule that applies to an entry: a chunk rule before a
+// path rule, an exact path before a directory, the longest directory
+// first. It returns nil if no rule matches.
+func (c *Curation) Match(e *ContextEntry) *CurationRule {
+	var best *CurationRule
+	bestRank := 0
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		rank := 0
+		switch {
+		case r.ChunkID != "":
+			if e.Citation != nil && e.Citation.ID == r.ChunkID {
+				rank = 1 << 20
+			}
+		case r.Path == e.Path:
+			rank = 1 << 19
+		case strings.HasSuffix(r.Path, "/") && strings.HasPrefix(e.Path, r.Path):
+			rank = len(r.Path)
+		}
+		if rank > bestRank {
+			best, bestRank = r, rank
+		}
+	}
+	return best
+}
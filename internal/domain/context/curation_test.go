@@ -0,0 +1,67 @@
+package context_test
+
+import (
+	"errors"
+	"testing"
+
+	cfctx "github.com/Strob0t/CodeForge/internal/domain/context"
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+)
+
+func TestCuration_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		rule cfctx.CurationRule
+		ok   bool
+	}{
+		{"pin path", cfctx.CurationRule{Path: "main.go", Action: cfctx.CurationPin}, true},
+		{"priority chunk", cfctx.CurationRule{ChunkID: "c1", Action: cfctx.CurationPriority, Priority: 40}, true},
+		{"no target", cfctx.CurationRule{Action: cfctx.CurationPin}, false},
+		{"both targets", cfctx.CurationRule{Path: "a.go", ChunkID: "c1", Action: cfctx.CurationExclude}, false},
+		{"bad action", cfctx.CurationRule{Path: "a.go", Action: "boost"}, false},
+		{"bad priority", cfctx.CurationRule{Path: "a.go", Action: cfctx.CurationPriority, Priority: 101}, false},
+	}
+	for _, tt := range tests {
+		c := cfctx.Curation{Rules: []cfctx.CurationRule{tt.rule}}
+		err := c.Validate()
+		if tt.ok && err != nil {
+			t.Errorf("%s: expected valid, got %v", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, cfctx.ErrInvalidCuration) {
+			t.Errorf("%s: expected ErrInvalidCuration, got %v", tt.name, err)
+		}
+	}
+
+	many := cfctx.Curation{Rules: make([]cfctx.CurationRule, cfctx.MaxCurationRules+1)}
+	if err := many.Validate(); !errors.Is(err, cfctx.ErrInvalidCuration) {
+		t.Fatalf("expected too many rules rejected, got %v", err)
+	}
+}
+
+func TestCuration_Match(t *testing.T) {
+	c := cfctx.Curation{Rules: []cfctx.CurationRule{
+		{Path: "internal/", Action: cfctx.CurationExclude},
+		{Path: "internal/service/", Action: cfctx.CurationPriority, Priority: 10},
+		{Path: "internal/service/run.go", Action: cfctx.CurationPin},
+		{ChunkID: "c1", Action: cfctx.CurationPriority, Priority: 90},
+	}}
+	tests := []struct {
+		entry cfctx.ContextEntry
+		want  int // Rule index, -1 for none
+	}{
+		{cfctx.ContextEntry{Path: "internal/domain/a.go"}, 0},
+		{cfctx.ContextEntry{Path: "internal/service/b.go"}, 1},
+		{cfctx.ContextEntry{Path: "internal/service/run.go"}, 2},
+		{cfctx.ContextEntry{Path: "internal/service/run.go", Citation: &retrieval.Citation{ID: "c1"}}, 3},
+		{cfctx.ContextEntry{Path: "internalx/a.go"}, -1},
+	}
+	for _, tt := range tests {
+		got := c.Match(&tt.entry)
+		switch {
+		case tt.want < 0 && got != nil:
+			t.Errorf("%s: expected no rule, got %+v", tt.entry.Path, *got)
+		case tt.want >= 0 && got != &c.Rules[tt.want]:
+			t.Errorf("%s: expected rule %d, got %+v", tt.entry.Path, tt.want, got)
+		}
+	}
+}
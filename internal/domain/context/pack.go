@@ -5,22 +5,27 @@ package context
 import (
 	"errors"
 	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
 )
 
 // EntryKind classifies a context entry.
 type EntryKind string
 
 const (
-	EntryFile    EntryKind = "file"    // Full file content
-	EntrySnippet EntryKind = "snippet" // Partial file / code excerpt
-	EntrySummary EntryKind = "summary" // Text summary of a larger body
-	EntryShared  EntryKind = "shared"  // Item from SharedContext
+	EntryFile        EntryKind = "file"        // Full file content
+	EntrySnippet     EntryKind = "snippet"     // Partial file / code excerpt
+	EntrySummary     EntryKind = "summary"     // Text summary of a larger body
+	EntryShared      EntryKind = "shared"      // Item from SharedContext
+	EntryResearch    EntryKind = "research"    // Findings from a research run
+	EntryMemory      EntryKind = "memory"      // Recalled project memory
+	EntryConventions EntryKind = "conventions" // The project's conventions document
 )
 
 // ValidEntryKind reports whether k is a known entry kind.
 func ValidEntryKind(k EntryKind) bool {
 	switch k {
-	case EntryFile, EntrySnippet, EntrySummary, EntryShared:
+	case EntryFile, EntrySnippet, EntrySummary, EntryShared, EntryResearch, EntryMemory, EntryConventions:
 		return true
 	}
 	return false
@@ -47,6 +52,10 @@ type ContextEntry struct {
 	Content  string    `json:"content"`  // actual content
 	Tokens   int       `json:"tokens"`   // estimated token count for this entry
 	Priority int       `json:"priority"` // 0-100, higher = more important
+
+	// Citation is set on snippets from the retrieval index or a knowledge
+	// base; their content starts with its citation marker.
+	Citation *retrieval.Citation `json:"citation,omitempty"`
 }
 
 // Validate checks that a ContextPack is well-formed.
@@ -97,7 +97,7 @@ func TestEstimateTokens(t *testing.T) {
 }
 
 func TestValidEntryKind(t *testing.T) {
-	valid := []cfctx.EntryKind{cfctx.EntryFile, cfctx.EntrySnippet, cfctx.EntrySummary, cfctx.EntryShared}
+	valid := []cfctx.EntryKind{cfctx.EntryFile, cfctx.EntrySnippet, cfctx.EntrySummary, cfctx.EntryShared, cfctx.EntryResearch, cfctx.EntryMemory, cfctx.EntryConventions}
 	for _, k := range valid {
 		if !cfctx.ValidEntryKind(k) {
 			t.Errorf("expected %q to be valid", k)
@@ -34,6 +34,7 @@ type AddSharedItemRequest struct {
 	Key    string `json:"key"`
 	Value  string `json:"value"`
 	Author string `json:"author"`
+	Tokens int    `json:"-"` // Token count set by the service; 0 falls back to EstimateTokens
 }
 
 // Validate checks that a SharedContext is well-formed.
@@ -0,0 +1,50 @@
+package context
+
+import "math"
+
+// Chat formatting overhead of OpenAI-style chat models: every message is
+// wrapped in role and separator tokens, and the reply is primed.
+const (
+	TokensPerMessage = 3
+	TokensPerReply   = 3
+)
+
+// ChatTokens returns the prompt tokens of a chat request whose message
+// contents count to contentTokens.
+func ChatTokens(contentTokens, messages int) int {
+	return contentTokens + messages*TokensPerMessage + TokensPerReply
+}
+
+// TokenAccuracy compares the token counts of a model's prompts against
+// the prompt_tokens the provider reported for them.
+type TokenAccuracy struct {
+	Model     string `json:"model"`
+	Tokenizer string `json:"tokenizer"` // Tokenizer counting for the model
+	Samples   int    `json:"samples"`
+	Actual    int    `json:"actual_tokens"`    // Sum of reported prompt tokens
+	Heuristic int    `json:"heuristic_tokens"` // Sum of EstimateTokens counts
+	Counted   int    `json:"counted_tokens"`   // Sum of tokenizer counts
+
+	// Mean absolute error relative to the reported count, e.g. 0.25 for
+	// counts off by a quarter on average.
+	HeuristicError float64 `json:"heuristic_error"`
+	CountedError   float64 `json:"counted_error"`
+
+	heuristicErrSum float64
+	countedErrSum   float64
+}
+
+// Add records one prompt. Samples without a reported count are ignored.
+func (a *TokenAccuracy) Add(actual, heuristic, counted int) {
+	if actual <= 0 {
+		return
+	}
+	a.Samples++
+	a.Actual += actual
+	a.Heuristic += heuristic
+	a.Counted += counted
+	a.heuristicErrSum += math.Abs(float64(heuristic-actual)) / float64(actual)
+	a.countedErrSum += math.Abs(float64(counted-actual)) / float64(actual)
+	a.HeuristicError = a.heuristicErrSum / float64(a.Samples)
+	a.CountedError = a.countedErrSum / float64(a.Samples)
+}
@@ -0,0 +1,430 @@
+package convention
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits of a conventions document.
+const (
+	maxLanguages   = 6
+	maxLinters     = 20
+	maxCommitTypes = 5
+)
+
+// Input is what the conventions of a workspace are derived from.
+type Input struct {
+	Files    []string          // Workspace files, relative and slash-separated
+	Contents map[string][]byte // Sampled source files and the files IsConfig selects
+	Subjects []string          // Subjects of recent commits
+}
+
+// languages maps file extensions to languages.
+var languages = map[string]string{
+	".go": "Go", ".py": "Python", ".ts": "TypeScript", ".tsx": "TypeScript",
+	".js": "JavaScript", ".jsx": "JavaScript", ".mjs": "JavaScript", ".cjs": "JavaScript",
+	".rs": "Rust", ".java": "Java", ".kt": "Kotlin", ".swift": "Swift",
+	".rb": "Ruby", ".php": "PHP", ".cs": "C#", ".scala": "Scala",
+	".c": "C", ".h": "C", ".cc": "C++", ".cpp": "C++", ".hpp": "C++",
+	".sh": "Shell", ".tf": "Terraform",
+}
+
+// LanguageOf returns the language of a source file, or "" for other files.
+func LanguageOf(p string) string {
+	return languages[strings.ToLower(path.Ext(p))]
+}
+
+// IsConfig reports whether the contents of a workspace file are needed
+// beyond its path: the root .editorconfig and pyproject.toml files.
+func IsConfig(p string) bool {
+	return p == ".editorconfig" || path.Base(p) == "pyproject.toml"
+}
+
+// Analyze derives the conventions of a workspace. Formatting is detected
+// from the sampled contents; naming and test layout from all files.
+func Analyze(in *Input) *Conventions {
+	c := &Conventions{
+		Languages: analyzeLanguages(in),
+		Linters:   detectLinters(in),
+	}
+	if data, ok := in.Contents[".editorconfig"]; ok {
+		c.EditorConfig = ParseEditorConfig(data)
+	}
+	if len(in.Subjects) > 0 {
+		c.Commits = AnalyzeCommits(in.Subjects)
+	}
+	for p := range in.Contents {
+		if LanguageOf(p) != "" {
+			c.SampledFiles++
+		}
+	}
+	return c
+}
+
+// analyzeLanguages returns the most used languages of a workspace with
+// their dominant patterns.
+func analyzeLanguages(in *Input) []Language {
+	byLang := make(map[string][]string)
+	for _, f := range in.Files {
+		if l := LanguageOf(f); l != "" {
+			byLang[l] = append(byLang[l], f)
+		}
+	}
+	langs := make([]Language, 0, len(byLang))
+	for name, files := range byLang {
+		l := Language{Name: name, Files: len(files), FileNaming: fileNaming(files)}
+		l.TestPattern, l.TestDir = testLayout(files)
+		var samples [][]byte
+		for _, f := range files {
+			if data, ok := in.Contents[f]; ok {
+				samples = append(samples, data)
+			}
+		}
+		l.IndentStyle, l.IndentSize = indentation(samples)
+		l.LineEndings = lineEndings(samples)
+		langs = append(langs, l)
+	}
+	slices.SortFunc(langs, func(a, b Language) int {
+		return cmp.Or(cmp.Compare(b.Files, a.Files), cmp.Compare(a.Name, b.Name))
+	})
+	return langs[:min(len(langs), maxLanguages)]
+}
+
+// indentation returns the dominant indent style of sampled files and, for
+// spaces, the most common indent step.
+func indentation(samples [][]byte) (style string, size int) {
+	styles := make(map[string]int)
+	sizes := make(map[int]int)
+	for _, data := range samples {
+		step := 0
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			switch {
+			case len(line) == 0:
+			case line[0] == '\t':
+				styles["tab"]++
+			case line[0] == ' ':
+				n := len(line) - len(bytes.TrimLeft(line, " "))
+				if n == len(bytes.TrimRight(line, "\r")) {
+					continue // Blank line
+				}
+				styles["space"]++
+				if n >= 2 && n <= 8 && (step == 0 || n < step) {
+					step = n
+				}
+			}
+		}
+		if step > 0 {
+			sizes[step]++
+		}
+	}
+	style, _ = dominant(styles, 0.8)
+	if style == "space" {
+		size, _ = dominant(sizes, 0.5)
+	}
+	return style, size
+}
+
+// lineEndings returns the dominant line endings of sampled files.
+func lineEndings(samples [][]byte) string {
+	endings := make(map[string]int)
+	for _, data := range samples {
+		switch {
+		case bytes.Contains(data, []byte("\r\n")):
+			endings["crlf"]++
+		case bytes.Contains(data, []byte("\n")):
+			endings["lf"]++
+		}
+	}
+	e, _ := dominant(endings, 0.8)
+	return e
+}
+
+// fileNaming returns the dominant naming style of the non-test files.
+// Single lowercase words fit every style and are not counted.
+func fileNaming(files []string) string {
+	styles := make(map[string]int)
+	for _, f := range files {
+		base := path.Base(f)
+		if testPattern(base) != "" {
+			continue
+		}
+		stem, _, _ := strings.Cut(base, ".")
+		if s := namingStyle(stem); s != "" {
+			styles[s]++
+		}
+	}
+	s, _ := dominant(styles, 2.0/3)
+	return s
+}
+
+// namingStyle classifies a file name without extensions.
+func namingStyle(stem string) string {
+	first, _ := utf8.DecodeRuneInString(stem)
+	hasUpper := strings.ToLower(stem) != stem
+	hasLower := strings.ToUpper(stem) != stem
+	switch {
+	case strings.Contains(stem, "_") && !hasUpper:
+		return "snake_case"
+	case strings.Contains(stem, "-") && !hasUpper:
+		return "kebab-case"
+	case strings.ContainsAny(stem, "_-"), !hasUpper, !hasLower:
+		return ""
+	case unicode.IsUpper(first):
+		return "PascalCase"
+	}
+	return "camelCase"
+}
+
+// testDirs are directory names that hold tests apart from the code.
+var testDirs = map[string]bool{"test": true, "tests": true, "__tests__": true, "spec": true}
+
+// testLayout returns the dominant pattern of a language's test files and
+// the directory name they live in, or "" when they sit next to the code.
+func testLayout(files []string) (pattern, dir string) {
+	patterns := make(map[string]int)
+	dirs := make(map[string]int)
+	tests := 0
+	for _, f := range files {
+		p := testPattern(path.Base(f))
+		if p == "" {
+			continue
+		}
+		tests++
+		patterns[p]++
+		for _, seg := range strings.Split(path.Dir(f), "/") {
+			if testDirs[seg] {
+				dirs[seg]++
+				break
+			}
+		}
+	}
+	if tests == 0 {
+		return "", ""
+	}
+	pattern, _ = dominant(patterns, 0)
+	inDirs := 0
+	for _, n := range dirs {
+		inDirs += n
+	}
+	if inDirs*2 > tests {
+		dir, _ = dominant(dirs, 0)
+	}
+	return pattern, dir
+}
+
+// testPattern returns the test file pattern a file name follows, or "".
+func testPattern(base string) string {
+	ext := path.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	if LanguageOf(base) == "" || stem == "" {
+		return ""
+	}
+	switch {
+	case strings.HasSuffix(stem, "_test"):
+		return "*_test" + ext
+	case strings.HasSuffix(stem, ".test"):
+		return "*.test" + ext
+	case strings.HasSuffix(stem, ".spec"):
+		return "*.spec" + ext
+	case strings.HasPrefix(stem, "test_"):
+		return "test_*" + ext
+	}
+	switch ext {
+	case ".java", ".kt", ".cs", ".scala", ".swift":
+		for _, suffix := range []string{"Tests", "Test"} {
+			if len(stem) > len(suffix) && strings.HasSuffix(stem, suffix) {
+				return "*" + suffix + ext
+			}
+		}
+	}
+	return ""
+}
+
+// linterFiles maps config file names to their tools.
+var linterFiles = map[string]string{
+	".golangci.yml": "golangci-lint", ".golangci.yaml": "golangci-lint", ".golangci.toml": "golangci-lint", ".golangci.json": "golangci-lint",
+	"ruff.toml": "ruff", ".ruff.toml": "ruff", ".flake8": "flake8", "mypy.ini": "mypy", ".mypy.ini": "mypy",
+	".pylintrc": "pylint", "pylintrc": "pylint", ".rubocop.yml": "rubocop",
+	"rustfmt.toml": "rustfmt", ".rustfmt.toml": "rustfmt", "clippy.toml": "clippy", ".clippy.toml": "clippy",
+	"biome.json": "biome", "biome.jsonc": "biome", ".clang-format": "clang-format",
+	".pre-commit-config.yaml": "pre-commit",
+}
+
+// linterPrefixes maps config file name prefixes to their tools.
+var linterPrefixes = []struct{ prefix, tool string }{
+	{".eslintrc", "eslint"}, {"eslint.config.", "eslint"},
+	{".prettierrc", "prettier"}, {"prettier.config.", "prettier"},
+	{".stylelintrc", "stylelint"}, {"stylelint.config.", "stylelint"},
+	{".markdownlint", "markdownlint"},
+}
+
+// pyprojectTools maps pyproject.toml sections to their tools.
+var pyprojectTools = []struct{ section, tool string }{
+	{"[tool.ruff", "ruff"}, {"[tool.black]", "black"}, {"[tool.isort]", "isort"},
+	{"[tool.mypy]", "mypy"}, {"[tool.pylint", "pylint"},
+}
+
+// detectLinters returns the lint and format tools configured in a
+// workspace, ordered by config path.
+func detectLinters(in *Input) []Linter {
+	var linters []Linter
+	for _, f := range in.Files {
+		base := path.Base(f)
+		if tool, ok := linterFiles[base]; ok {
+			linters = append(linters, Linter{Tool: tool, Config: f})
+			continue
+		}
+		for _, lp := range linterPrefixes {
+			if strings.HasPrefix(base, lp.prefix) {
+				linters = append(linters, Linter{Tool: lp.tool, Config: f})
+				break
+			}
+		}
+		if base == "pyproject.toml" {
+			data := in.Contents[f]
+			for _, pt := range pyprojectTools {
+				if bytes.Contains(data, []byte(pt.section)) {
+					linters = append(linters, Linter{Tool: pt.tool, Config: f})
+				}
+			}
+		}
+	}
+	slices.SortFunc(linters, func(a, b Linter) int {
+		return cmp.Or(cmp.Compare(a.Config, b.Config), cmp.Compare(a.Tool, b.Tool))
+	})
+	linters = slices.Compact(linters)
+	return linters[:min(len(linters), maxLinters)]
+}
+
+// editorKeys are the .editorconfig properties kept.
+var editorKeys = map[string]bool{
+	"indent_style": true, "indent_size": true, "tab_width": true, "end_of_line": true, "charset": true,
+	"insert_final_newline": true, "trim_trailing_whitespace": true, "max_line_length": true,
+}
+
+// ParseEditorConfig returns the sections of an .editorconfig file with
+// the formatting properties they set.
+func ParseEditorConfig(data []byte) []EditorSection {
+	var sections []EditorSection
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "", line[0] == '#', line[0] == ';':
+		case line[0] == '[' && line[len(line)-1] == ']':
+			sections = append(sections, EditorSection{Glob: line[1 : len(line)-1], Settings: map[string]string{}})
+		case len(sections) > 0:
+			k, v, ok := strings.Cut(line, "=")
+			k = strings.ToLower(strings.TrimSpace(k))
+			if ok && editorKeys[k] {
+				sections[len(sections)-1].Settings[k] = strings.ToLower(strings.TrimSpace(v))
+			}
+		}
+	}
+	return slices.DeleteFunc(sections, func(s EditorSection) bool { return len(s.Settings) == 0 })
+}
+
+var (
+	conventionalRe = regexp.MustCompile(`^([a-z]+)(\([^)]*\))?!?: \S`)
+	bracketRe      = regexp.MustCompile(`^\[[^\]]+\]`)
+	ticketRe       = regexp.MustCompile(`\b[A-Z][A-Z0-9]+-\d+\b`)
+	issueRe        = regexp.MustCompile(`#\d+\b`)
+)
+
+// references are the ways subjects reference tickets, checked in order.
+var references = []struct {
+	re   *regexp.Regexp
+	desc string
+}{
+	{bracketRe, "a bracketed prefix such as %s"},
+	{ticketRe, "ticket keys such as %s"},
+	{issueRe, "issue numbers such as %s"},
+}
+
+// AnalyzeCommits describes the style of commit subjects. A ticket
+// reference is reported when at least 30% of the subjects use it.
+func AnalyzeCommits(subjects []string) *CommitStyle {
+	cs := &CommitStyle{}
+	types := make(map[string]int)
+	refs := make([]int, len(references))
+	examples := make([]string, len(references))
+	var lengths []int
+	conventional, scoped := 0, 0
+	for _, s := range subjects {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		lengths = append(lengths, utf8.RuneCountInString(s))
+		if m := conventionalRe.FindStringSubmatch(s); m != nil {
+			conventional++
+			types[m[1]]++
+			if m[2] != "" {
+				scoped++
+			}
+		}
+		for i, r := range references {
+			if ref := r.re.FindString(s); ref != "" {
+				refs[i]++
+				if examples[i] == "" {
+					examples[i] = ref
+				}
+				break
+			}
+		}
+	}
+	cs.Sampled = len(lengths)
+	if cs.Sampled == 0 {
+		return cs
+	}
+	n := float64(cs.Sampled)
+	cs.Conventional = float64(conventional) / n
+	if conventional > 0 {
+		cs.Scoped = float64(scoped) / float64(conventional)
+	}
+	for t := range types {
+		cs.Types = append(cs.Types, t)
+	}
+	sort.Slice(cs.Types, func(i, j int) bool {
+		a, b := cs.Types[i], cs.Types[j]
+		return types[a] > types[b] || types[a] == types[b] && a < b
+	})
+	cs.Types = cs.Types[:min(len(cs.Types), maxCommitTypes)]
+	best := 0
+	for i := range refs {
+		if refs[i] > refs[best] {
+			best = i
+		}
+	}
+	if float64(refs[best]) >= 0.3*n {
+		cs.Reference = fmt.Sprintf(references[best].desc, examples[best])
+	}
+	slices.Sort(lengths)
+	cs.SubjectLength = lengths[len(lengths)/2]
+	return cs
+}
+
+// dominant returns the most counted key, ties broken by the smaller key,
+// if its share of all counts is at least minShare.
+func dominant[K cmp.Ordered](counts map[K]int, minShare float64) (K, bool) {
+	var best K
+	top, total := 0, 0
+	for k, n := range counts {
+		total += n
+		if n > top || n == top && k < best {
+			best, top = k, n
+		}
+	}
+	if total == 0 || float64(top) < minShare*float64(total) {
+		var zero K
+		return zero, false
+	}
+	return best, true
+}
@@ -0,0 +1,165 @@
+package convention
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeLanguages(t *testing.T) {
+	in := &Input{
+		Files: []string{
+			"cmd/main.go", "internal/store.go", "internal/run_state.go", "internal/run_state_test.go",
+			"internal/store_test.go", "internal/event_log.go",
+			"web/src/App.tsx", "web/src/UserList.tsx", "web/src/__tests__/App.test.tsx",
+			"README.md",
+		},
+		Contents: map[string][]byte{
+			"cmd/main.go":           []byte("package main\n\nfunc main() {\n\tif true {\n\t\tprintln()\n\t}\n}\n"),
+			"internal/store.go":     []byte("package internal\n\nfunc f() {\n\treturn\n}\n"),
+			"web/src/App.tsx":       []byte("export function App() {\r\n  return (\r\n    <div />\r\n  );\r\n}\r\n"),
+			"web/src/UserList.tsx":  []byte("export function UserList() {\r\n  const a = 1;\r\n\r\n  return a;\r\n}\r\n"),
+			"internal/event_log.go": []byte("package internal\n"),
+		},
+	}
+	c := Analyze(in)
+	want := []Language{
+		{Name: "Go", Files: 6, IndentStyle: "tab", LineEndings: "lf", FileNaming: "snake_case", TestPattern: "*_test.go"},
+		{Name: "TypeScript", Files: 3, IndentStyle: "space", IndentSize: 2, LineEndings: "crlf", FileNaming: "PascalCase", TestPattern: "*.test.tsx", TestDir: "__tests__"},
+	}
+	if !reflect.DeepEqual(c.Languages, want) {
+		t.Errorf("languages = %+v, want %+v", c.Languages, want)
+	}
+	if c.SampledFiles != 5 {
+		t.Errorf("sampled files = %d, want 5", c.SampledFiles)
+	}
+	if c.Commits != nil {
+		t.Errorf("commits = %+v, want nil without subjects", c.Commits)
+	}
+}
+
+func TestNamingStyle(t *testing.T) {
+	tests := map[string]string{
+		"run_state": "snake_case",
+		"run-state": "kebab-case",
+		"RunState":  "PascalCase",
+		"runState":  "camelCase",
+		"store":     "",
+		"README":    "",
+		"Run_State": "",
+	}
+	for stem, want := range tests {
+		if got := namingStyle(stem); got != want {
+			t.Errorf("namingStyle(%q) = %q, want %q", stem, got, want)
+		}
+	}
+}
+
+func TestTestPattern(t *testing.T) {
+	tests := map[string]string{
+		"store_test.go":     "*_test.go",
+		"test_store.py":     "test_*.py",
+		"App.spec.ts":       "*.spec.ts",
+		"StoreTest.java":    "*Test.java",
+		"StoreTests.cs":     "*Tests.cs",
+		"LoadTest.go":       "",
+		"test_data.json":    "",
+		"contest.py":        "",
+		"Test.java":         "",
+		"store.test.mjs":    "*.test.mjs",
+		"fixtures_test.txt": "",
+	}
+	for base, want := range tests {
+		if got := testPattern(base); got != want {
+			t.Errorf("testPattern(%q) = %q, want %q", base, got, want)
+		}
+	}
+}
+
+func TestDetectLinters(t *testing.T) {
+	in := &Input{
+		Files: []string{
+			".golangci.yml", "frontend/eslint.config.js", "frontend/.prettierrc.json",
+			"workers/pyproject.toml", "workers/app.py", "Makefile",
+		},
+		Contents: map[string][]byte{
+			"workers/pyproject.toml": []byte("[project]\nname = \"w\"\n\n[tool.ruff.lint]\nselect = [\"E\"]\n\n[tool.mypy]\nstrict = true\n"),
+		},
+	}
+	want := []Linter{
+		{Tool: "golangci-lint", Config: ".golangci.yml"},
+		{Tool: "prettier", Config: "frontend/.prettierrc.json"},
+		{Tool: "eslint", Config: "frontend/eslint.config.js"},
+		{Tool: "mypy", Config: "workers/pyproject.toml"},
+		{Tool: "ruff", Config: "workers/pyproject.toml"},
+	}
+	if got := detectLinters(in); !reflect.DeepEqual(got, want) {
+		t.Errorf("linters = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseEditorConfig(t *testing.T) {
+	data := []byte("root = true\n\n# All files\n[*]\nindent_style = space\nindent_size = 2\nend_of_line = LF\ncustom = x\n\n[Makefile]\nindent_style = tab\n\n[*.md]\n; nothing kept\nfoo = bar\n")
+	want := []EditorSection{
+		{Glob: "*", Settings: map[string]string{"indent_style": "space", "indent_size": "2", "end_of_line": "lf"}},
+		{Glob: "Makefile", Settings: map[string]string{"indent_style": "tab"}},
+	}
+	if got := ParseEditorConfig(data); !reflect.DeepEqual(got, want) {
+		t.Errorf("sections = %+v, want %+v", got, want)
+	}
+}
+
+func TestAnalyzeCommits(t *testing.T) {
+	cs := AnalyzeCommits([]string{
+		"feat(api): add conventions endpoint",
+		"fix: handle empty workspace",
+		"feat(ui): show conventions",
+		"docs: describe conventions",
+		"Merge branch 'main'",
+		"",
+	})
+	if cs.Sampled != 5 {
+		t.Errorf("sampled = %d, want 5", cs.Sampled)
+	}
+	if cs.Conventional != 0.8 {
+		t.Errorf("conventional = %v, want 0.8", cs.Conventional)
+	}
+	if cs.Scoped != 0.5 {
+		t.Errorf("scoped = %v, want 0.5", cs.Scoped)
+	}
+	if want := []string{"feat", "docs", "fix"}; !reflect.DeepEqual(cs.Types, want) {
+		t.Errorf("types = %v, want %v", cs.Types, want)
+	}
+	if cs.Reference != "" {
+		t.Errorf("reference = %q, want none", cs.Reference)
+	}
+	if cs.SubjectLength != 26 {
+		t.Errorf("subject length = %d, want 26", cs.SubjectLength)
+	}
+
+	cs = AnalyzeCommits([]string{"[PROJ-12] Fix login", "[PROJ-13] Add logout", "Bump deps (#40)"})
+	if want := "a bracketed prefix such as [PROJ-12]"; cs.Reference != want {
+		t.Errorf("reference = %q, want %q", cs.Reference, want)
+	}
+}
+
+func TestRender(t *testing.T) {
+	c := &Conventions{
+		Commit:    "0123456789abcdef",
+		Languages: []Language{{Name: "Go", Files: 3, IndentStyle: "tab", FileNaming: "snake_case", TestPattern: "*_test.go"}},
+		Linters:   []Linter{{Tool: "golangci-lint", Config: ".golangci.yml"}},
+		Commits:   &CommitStyle{Sampled: 4, Conventional: 1, Types: []string{"feat", "fix"}, Scoped: 0.75, SubjectLength: 40},
+	}
+	got := c.Render()
+	for _, want := range []string{
+		"at 0123456789ab;",
+		"- Go (3 files): tab indentation, snake_case file names, tests as *_test.go next to the code",
+		"- golangci-lint (`.golangci.yml`)",
+		"- 100% of subjects follow Conventional Commits (types: feat, fix), most with a scope",
+		"- Median subject length: 40 characters",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("render lacks %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Package convention defines the conventions document of a project: the
+// formatting, linters, file naming, test layout and commit message style
+// its repository follows. It is extracted from the workspace and its
+// history and injected into the prompts of agents so their changes fit in.
+package convention
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Triggers of an extraction.
+const (
+	TriggerManual   = "manual"   // Requested through the API
+	TriggerSchedule = "schedule" // The conventions were missing or too old
+	TriggerMerge    = "merge"    // Many files changed since the last extraction
+)
+
+// Conventions is the conventions document of a project.
+type Conventions struct {
+	ProjectID    string          `json:"project_id"`
+	Languages    []Language      `json:"languages"`
+	EditorConfig []EditorSection `json:"editorconfig,omitempty"`
+	Linters      []Linter        `json:"linters,omitempty"`
+	Commits      *CommitStyle    `json:"commits,omitempty"` // Nil without git history
+	SampledFiles int             `json:"sampled_files"`
+	Commit       string          `json:"commit,omitempty"` // HEAD the conventions were extracted at
+	Trigger      string          `json:"trigger"`
+	ExtractedAt  time.Time       `json:"extracted_at"`
+}
+
+// Language holds the dominant patterns of a language's files. Formatting
+// is detected from sampled files; empty fields had no clear majority.
+type Language struct {
+	Name        string `json:"name"`
+	Files       int    `json:"files"`
+	IndentStyle string `json:"indent_style,omitempty"` // "tab" or "space"
+	IndentSize  int    `json:"indent_size,omitempty"`  // Spaces per level
+	LineEndings string `json:"line_endings,omitempty"` // "lf" or "crlf"
+	FileNaming  string `json:"file_naming,omitempty"`  // snake_case, kebab-case, camelCase or PascalCase
+	TestPattern string `json:"test_pattern,omitempty"` // e.g. "*_test.go"
+	TestDir     string `json:"test_dir,omitempty"`     // Directory tests live in; empty when next to the code
+}
+
+// EditorSection is a section of the workspace's .editorconfig.
+type EditorSection struct {
+	Glob     string            `json:"glob"`
+	Settings map[string]string `json:"settings"`
+}
+
+// Linter is a lint or format tool configured in the workspace.
+type Linter struct {
+	Tool   string `json:"tool"`
+	Config string `json:"config"` // Path of its config file
+}
+
+// CommitStyle describes the subjects of recent commits.
+type CommitStyle struct {
+	Sampled       int      `json:"sampled"`
+	Conventional  float64  `json:"conventional"`        // Share of Conventional Commits subjects
+	Types         []string `json:"types,omitempty"`     // Most used Conventional Commits types
+	Scoped        float64  `json:"scoped"`              // Share of Conventional Commits subjects with a scope
+	Reference     string   `json:"reference,omitempty"` // How most subjects reference tickets
+	SubjectLength int      `json:"subject_length"`      // Median subject length in characters
+}
+
+// Render formats the conventions for a prompt. It has no heading of its
+// own, so it nests under the heading of a context entry or microagent.
+func (c *Conventions) Render() string {
+	var b strings.Builder
+	b.WriteString("Detected from the repository")
+	if c.Commit != "" {
+		fmt.Fprintf(&b, " at %s", shortCommit(c.Commit))
+	}
+	b.WriteString("; follow them in your changes.\n")
+
+	if len(c.Languages) > 0 {
+		b.WriteString("\n**Languages**\n")
+		for i := range c.Languages {
+			fmt.Fprintf(&b, "- %s\n", c.Languages[i].describe())
+		}
+	}
+	if len(c.EditorConfig) > 0 {
+		b.WriteString("\n**Formatting (.editorconfig)**\n")
+		for _, s := range c.EditorConfig {
+			keys := make([]string, 0, len(s.Settings))
+			for k := range s.Settings {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			pairs := make([]string, len(keys))
+			for i, k := range keys {
+				pairs[i] = k + "=" + s.Settings[k]
+			}
+			fmt.Fprintf(&b, "- `%s`: %s\n", s.Glob, strings.Join(pairs, ", "))
+		}
+	}
+	if len(c.Linters) > 0 {
+		b.WriteString("\n**Linters and formatters**\n")
+		for _, l := range c.Linters {
+			fmt.Fprintf(&b, "- %s (`%s`)\n", l.Tool, l.Config)
+		}
+	}
+	if cs := c.Commits; cs != nil && cs.Sampled > 0 {
+		b.WriteString("\n**Commit messages**\n")
+		if cs.Conventional >= 0.5 {
+			fmt.Fprintf(&b, "- %.0f%% of subjects follow Conventional Commits", cs.Conventional*100)
+			if len(cs.Types) > 0 {
+				fmt.Fprintf(&b, " (types: %s)", strings.Join(cs.Types, ", "))
+			}
+			if cs.Scoped >= 0.5 {
+				b.WriteString(", most with a scope")
+			}
+			b.WriteString("\n")
+		} else {
+			b.WriteString("- Subjects are free-form, not Conventional Commits\n")
+		}
+		if cs.Reference != "" {
+			fmt.Fprintf(&b, "- Subjects reference tickets with %s\n", cs.Reference)
+		}
+		fmt.Fprintf(&b, "- Median subject length: %d characters\n", cs.SubjectLength)
+	}
+	return b.String()
+}
+
+// describe summarizes a language's patterns on one line.
+func (l *Language) describe() string {
+	parts := []string{}
+	switch {
+	case l.IndentStyle == "tab":
+		parts = append(parts, "tab indentation")
+	case l.IndentStyle == "space" && l.IndentSize > 0:
+		parts = append(parts, fmt.Sprintf("%d-space indentation", l.IndentSize))
+	case l.IndentStyle == "space":
+		parts = append(parts, "space indentation")
+	}
+	if l.LineEndings != "" {
+		parts = append(parts, strings.ToUpper(l.LineEndings)+" line endings")
+	}
+	if l.FileNaming != "" {
+		parts = append(parts, l.FileNaming+" file names")
+	}
+	if l.TestPattern != "" {
+		where := "next to the code"
+		if l.TestDir != "" {
+			where = "in " + l.TestDir + "/ directories"
+		}
+		parts = append(parts, fmt.Sprintf("tests as %s %s", l.TestPattern, where))
+	}
+	s := fmt.Sprintf("%s (%d files)", l.Name, l.Files)
+	if len(parts) > 0 {
+		s += ": " + strings.Join(parts, ", ")
+	}
+	return s
+}
+
+// shortCommit abbreviates a commit hash.
+func shortCommit(h string) string {
+	if len(h) > 12 {
+		return h[:12]
+	}
+	return h
+}
@@ -0,0 +1,147 @@
+// Package conversation defines chat conversations with an LLM about a
+// project, and the rolling summaries that keep long conversations within
+// the model's context window.
+package conversation
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrContentRequired is returned for a message without content.
+var ErrContentRequired = errors.New("content is required")
+
+// Role identifies the author of a message.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+	RoleSummary   Role = "summary" // Compresses the messages up to Covers
+	RoleTool      Role = "tool"    // Tool call of a run started in the conversation
+)
+
+// Conversation is a chat about a project. Its messages are kept in full;
+// the LLM sees the compressed view (see View).
+type Conversation struct {
+	ID        string    `json:"id"`
+	ProjectID string    `json:"project_id"`
+	Title     string    `json:"title"`
+	Model     string    `json:"model,omitempty"` // Reply model; empty uses the configured default
+	Mode      string    `json:"mode,omitempty"`  // Agent mode; its skip_memories disables memory recall
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CreateRequest holds the fields for starting a conversation.
+type CreateRequest struct {
+	Title string `json:"title"`
+	Model string `json:"model,omitempty"`
+	Mode  string `json:"mode,omitempty"`
+}
+
+// Message is a turn of a conversation or a summary of earlier turns.
+type Message struct {
+	ID               string    `json:"id"`
+	ConversationID   string    `json:"conversation_id"`
+	Seq              int       `json:"seq"` // 1-based position in the conversation
+	Role             Role      `json:"role"`
+	Content          string    `json:"content"`
+	Tokens           int       `json:"tokens"`                      // Token count for the conversation's model
+	Covers           int       `json:"covers,omitempty"`            // Summary: seq of the last message it replaces
+	Model            string    `json:"model,omitempty"`             // Model that wrote an assistant message or summary
+	Memories         []string  `json:"memories,omitempty"`          // Assistant: IDs of the memories recalled for the reply
+	Microagents      []string  `json:"microagents,omitempty"`       // Assistant: IDs of the microagents activated for the reply
+	ToolCall         *ToolCall `json:"tool_call,omitempty"`         // Tool: the invocation; Content holds its (truncated) output
+	PromptTokens     int       `json:"prompt_tokens,omitempty"`     // Assistant: prompt tokens the LLM reported for the reply
+	CompletionTokens int       `json:"completion_tokens,omitempty"` // Assistant: completion tokens the LLM reported for the reply
+	Attachments      []string  `json:"attachments,omitempty"`       // User: IDs of the attachments sent with the message
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// ToolCall is a tool invocation of a run started in a conversation.
+type ToolCall struct {
+	RunID      string            `json:"run_id"`
+	CallID     string            `json:"call_id"`
+	Name       string            `json:"name"`
+	Args       map[string]string `json:"args,omitempty"` // e.g. command, path
+	Success    bool              `json:"success"`
+	Error      string            `json:"error,omitempty"`
+	DurationMS int64             `json:"duration_ms,omitempty"`
+	Truncated  bool              `json:"truncated,omitempty"`   // Content holds only the start of the output
+	ArtifactID string            `json:"artifact_id,omitempty"` // Artifact with the full output when truncated
+}
+
+// TruncateOutput cuts output to at most limit bytes, on a rune boundary,
+// and reports whether it was cut. A limit of 0 or less keeps it whole.
+func TruncateOutput(output string, limit int) (string, bool) {
+	if limit <= 0 || len(output) <= limit {
+		return output, false
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(output[cut]) {
+		cut--
+	}
+	return output[:cut], true
+}
+
+// SendRequest is a user message sent to a conversation.
+type SendRequest struct {
+	Content     string   `json:"content"`
+	Attachments []string `json:"attachments,omitempty"` // IDs of attachments of the conversation's project
+}
+
+// Validate checks that the message has content.
+func (r *SendRequest) Validate() error {
+	if strings.TrimSpace(r.Content) == "" {
+		return ErrContentRequired
+	}
+	return nil
+}
+
+// View returns the messages sent to the LLM: the latest summary followed
+// by the turns it does not cover. Tool calls are part of the timeline
+// only; the answer of their run is. messages must be ordered by Seq.
+func View(messages []Message) []Message {
+	var view []Message
+	covers := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == RoleSummary {
+			view = append(view, messages[i])
+			covers = messages[i].Covers
+			break
+		}
+	}
+	for i := range messages {
+		if messages[i].Role != RoleSummary && messages[i].Role != RoleTool && messages[i].Seq > covers {
+			view = append(view, messages[i])
+		}
+	}
+	return view
+}
+
+// Tokens returns the sum of the messages' token counts.
+func Tokens(messages []Message) int {
+	n := 0
+	for i := range messages {
+		n += messages[i].Tokens
+	}
+	return n
+}
+
+// Compaction returns the leading messages of a view to compress into a new
+// summary when the view exceeds maxTokens, always keeping the last
+// keepRecent messages verbatim. It returns nil when the view fits or
+// nothing but an existing summary could be compressed.
+func Compaction(view []Message, maxTokens, keepRecent int) []Message {
+	if maxTokens <= 0 || Tokens(view) <= maxTokens {
+		return nil
+	}
+	split := len(view) - max(keepRecent, 1)
+	if split <= 0 || (split == 1 && view[0].Role == RoleSummary) {
+		return nil
+	}
+	return view[:split]
+}
@@ -0,0 +1,93 @@
+package conversation_test
+
+import (
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/conversation"
+)
+
+func msg(seq int, role conversation.Role, tokens, covers int) conversation.Message {
+	return conversation.Message{Seq: seq, Role: role, Tokens: tokens, Covers: covers}
+}
+
+func seqs(messages []conversation.Message) []int {
+	out := make([]int, len(messages))
+	for i := range messages {
+		out[i] = messages[i].Seq
+	}
+	return out
+}
+
+func TestView(t *testing.T) {
+	messages := []conversation.Message{
+		msg(1, conversation.RoleUser, 10, 0),
+		msg(2, conversation.RoleAssistant, 10, 0),
+		msg(3, conversation.RoleUser, 10, 0),
+		msg(4, conversation.RoleSummary, 5, 2),
+		msg(5, conversation.RoleAssistant, 10, 0),
+		msg(6, conversation.RoleSummary, 5, 3),
+		msg(7, conversation.RoleUser, 10, 0),
+	}
+	got := seqs(conversation.View(messages))
+	want := []int{6, 5, 7}
+	if len(got) != len(want) {
+		t.Fatalf("View = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("View = %v, want %v", got, want)
+		}
+	}
+
+	if n := len(conversation.View(messages[:3])); n != 3 {
+		t.Fatalf("expected all 3 messages without a summary, got %d", n)
+	}
+
+	withTool := append(messages[:2:2], msg(3, conversation.RoleTool, 50, 0), msg(4, conversation.RoleAssistant, 10, 0))
+	if got := seqs(conversation.View(withTool)); len(got) != 3 || got[2] != 4 {
+		t.Fatalf("expected tool calls left out of the view, got %v", got)
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	if got, cut := conversation.TruncateOutput("short", 10); got != "short" || cut {
+		t.Fatalf("expected short output kept, got %q %t", got, cut)
+	}
+	if got, cut := conversation.TruncateOutput("abcdef", 0); got != "abcdef" || cut {
+		t.Fatalf("expected no limit with 0, got %q %t", got, cut)
+	}
+	// "ä" is two bytes; cutting inside it keeps the rune whole.
+	if got, cut := conversation.TruncateOutput("aäb", 2); got != "a" || !cut {
+		t.Fatalf("expected cut on a rune boundary, got %q %t", got, cut)
+	}
+}
+
+func TestCompaction(t *testing.T) {
+	view := []conversation.Message{
+		msg(4, conversation.RoleSummary, 5, 2),
+		msg(3, conversation.RoleUser, 10, 0),
+		msg(5, conversation.RoleAssistant, 10, 0),
+		msg(7, conversation.RoleUser, 10, 0),
+	}
+	if got := conversation.Compaction(view, 35, 2); got != nil {
+		t.Fatalf("expected no compaction within budget, got %v", seqs(got))
+	}
+	if got := seqs(conversation.Compaction(view, 20, 2)); len(got) != 2 || got[0] != 4 || got[1] != 3 {
+		t.Fatalf("expected summary and seq 3 compressed, got %v", got)
+	}
+	if got := conversation.Compaction(view, 20, 3); got != nil {
+		t.Fatalf("expected no compaction of a lone summary, got %v", seqs(got))
+	}
+	if got := conversation.Compaction(view, 0, 2); got != nil {
+		t.Fatal("expected a zero budget to disable compaction")
+	}
+}
+
+func TestSendRequestValidate(t *testing.T) {
+	if err := (&conversation.SendRequest{Content: "  "}).Validate(); err != conversation.ErrContentRequired {
+		t.Fatalf("expected ErrContentRequired, got %v", err)
+	}
+	if err := (&conversation.SendRequest{Content: "hi"}).Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
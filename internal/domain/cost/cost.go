@@ -0,0 +1,52 @@
+// Package cost defines summaries of the LLM spend of runs.
+package cost
+
+import "time"
+
+// Summary totals the cost and tokens of a project's runs from the daily
+// rollups.
+type Summary struct {
+	ProjectID   string     `json:"project_id"`
+	ProjectName string     `json:"project_name"`
+	Runs        int        `json:"runs"`
+	CostUSD     float64    `json:"cost_usd"`
+	TokensIn    int64      `json:"tokens_in"`
+	TokensOut   int64      `json:"tokens_out"`
+	AsOf        *time.Time `json:"as_of,omitempty"` // Last rollup pass; nil before the first
+	Exact       bool       `json:"exact"`           // False while it includes a day still open at AsOf
+}
+
+// Day totals the runs started on a UTC day.
+type Day struct {
+	Day       string  `json:"day"` // YYYY-MM-DD
+	Runs      int     `json:"runs"`
+	CostUSD   float64 `json:"cost_usd"`
+	TokensIn  int64   `json:"tokens_in"`
+	TokensOut int64   `json:"tokens_out"`
+	Exact     bool    `json:"exact"` // False for a day still open at the last rollup pass
+}
+
+// Usage totals the calls of a tool with a model. Tool calls other than LLM
+// calls have no model.
+type Usage struct {
+	Tool      string  `json:"tool"`
+	Model     string  `json:"model,omitempty"`
+	Calls     int     `json:"calls"`
+	CostUSD   float64 `json:"cost_usd"`
+	TokensIn  int64   `json:"tokens_in"`
+	TokensOut int64   `json:"tokens_out"`
+}
+
+// StepCost totals the runs of a plan step, including retries and loop
+// iterations.
+type StepCost struct {
+	StepID    string  `json:"step_id"`
+	Name      string  `json:"name,omitempty"`
+	TaskID    string  `json:"task_id"`
+	Status    string  `json:"status"`
+	Runs      int     `json:"runs"`
+	CostUSD   float64 `json:"cost_usd"`
+	TokensIn  int64   `json:"tokens_in"`
+	TokensOut int64   `json:"tokens_out"`
+	Usage     []Usage `json:"usage"` // Per tool and model, most expensive first
+}
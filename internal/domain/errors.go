@@ -8,3 +8,6 @@ var ErrNotFound = errors.New("not found")
 
 // ErrConflict indicates a concurrent modification conflict (optimistic locking).
 var ErrConflict = errors.New("conflict: resource was modified by another request")
+
+// ErrQuotaExceeded indicates that a tenant quota denies the operation.
+var ErrQuotaExceeded = errors.New("quota exceeded")
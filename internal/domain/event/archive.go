@@ -0,0 +1,86 @@
+package event
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ArchiveContentType is the content type of event archive artifacts.
+const ArchiveContentType = "application/gzip"
+
+// ArchiveName is the artifact name of a run's event archive.
+const ArchiveName = "events.jsonl.gz"
+
+// ConfigKeyRetention is the project config key overriding the event
+// retention window, as a Go duration (e.g. "2160h"; "0s" keeps events).
+const ConfigKeyRetention = "event_retention"
+
+// RetentionWindow returns the project's retention override, falling back to
+// def when the key is absent or not a valid duration.
+func RetentionWindow(projectConfig map[string]string, def time.Duration) time.Duration {
+	v, ok := projectConfig[ConfigKeyRetention]
+	if !ok {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return def
+	}
+	return d
+}
+
+// EncodeArchive writes events as gzip-compressed JSON Lines, one event per line.
+func EncodeArchive(events []AgentEvent) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := WriteJSONL(zw, events); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("close archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeArchive reads events written by EncodeArchive.
+func DecodeArchive(data []byte) ([]AgentEvent, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	defer zr.Close()
+
+	var events []AgentEvent
+	sc := bufio.NewScanner(zr)
+	sc.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var ev AgentEvent
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			return nil, fmt.Errorf("decode archived event %d: %w", len(events)+1, err)
+		}
+		events = append(events, ev)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read archive: %w", err)
+	}
+	return events, nil
+}
+
+// WriteJSONL writes events as JSON Lines, the trajectory export format.
+func WriteJSONL(w io.Writer, events []AgentEvent) error {
+	enc := json.NewEncoder(w)
+	for i := range events {
+		if err := enc.Encode(&events[i]); err != nil {
+			return fmt.Errorf("encode event %s: %w", events[i].ID, err)
+		}
+	}
+	return nil
+}
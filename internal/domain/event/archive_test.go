@@ -0,0 +1,49 @@
+package event_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+)
+
+func TestArchive_RoundTrip(t *testing.T) {
+	events := []event.AgentEvent{
+		{ID: "1", RunID: "run-1", Type: event.TypeRunStarted, Payload: json.RawMessage(`{"a":"b"}`), Version: 1},
+		{ID: "2", RunID: "run-1", Type: event.TypeRunCompleted, Payload: json.RawMessage(`{}`), Version: 2},
+	}
+	data, err := event.EncodeArchive(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := event.DecodeArchive(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != "1" || string(got[0].Payload) != `{"a":"b"}` || got[1].Type != event.TypeRunCompleted {
+		t.Fatalf("unexpected events %+v", got)
+	}
+	if _, err := event.DecodeArchive([]byte("not gzip")); err == nil {
+		t.Fatal("expected error for invalid archive")
+	}
+}
+
+func TestRetentionWindow(t *testing.T) {
+	def := 720 * time.Hour
+	tests := []struct {
+		cfg  map[string]string
+		want time.Duration
+	}{
+		{nil, def},
+		{map[string]string{event.ConfigKeyRetention: "24h"}, 24 * time.Hour},
+		{map[string]string{event.ConfigKeyRetention: "0s"}, 0},
+		{map[string]string{event.ConfigKeyRetention: "soon"}, def},
+		{map[string]string{event.ConfigKeyRetention: "-1h"}, def},
+	}
+	for _, tt := range tests {
+		if got := event.RetentionWindow(tt.cfg, def); got != tt.want {
+			t.Errorf("RetentionWindow(%v) = %v, want %v", tt.cfg, got, tt.want)
+		}
+	}
+}
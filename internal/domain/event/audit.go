@@ -0,0 +1,22 @@
+package event
+
+import "time"
+
+// AuditEntry records a change made through the API: who sent which
+// request, in which tenant, and with what result. Decisions such as
+// approval votes, and those made outside a request such as approval
+// timeouts, get an entry of their own with an Action and its Detail; Path
+// then names the resource decided on. Entries are append-only.
+type AuditEntry struct {
+	Seq        int64             `json:"seq"` // Increases with every entry; export sinks are checkpointed by it
+	TenantID   string            `json:"tenant_id"`
+	Actor      string            `json:"actor,omitempty"` // "server" for server.api_keys, "api_key:<id>" for managed keys; empty without auth
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Status     int               `json:"status"`
+	RequestID  string            `json:"request_id,omitempty"`
+	RemoteAddr string            `json:"remote_addr,omitempty"`
+	Action     string            `json:"action,omitempty"` // What was decided, e.g. "plan.approval.voted"; empty for plain requests
+	Detail     map[string]string `json:"detail,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
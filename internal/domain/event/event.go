@@ -20,6 +20,9 @@ const (
 	// Run protocol events (Phase 4B)
 	TypeRunStarted        Type = "run.started"
 	TypeRunCompleted      Type = "run.completed"
+	TypeRunInterrupted    Type = "run.interrupted" // Still active when its server shut down
+	TypeRunResumed        Type = "run.resumed"     // Taken over by a server after an interruption
+	TypeRunForked         Type = "run.forked"      // Started from a checkpoint of a replayed run
 	TypeToolCallRequested Type = "run.toolcall.requested"
 	TypeToolCallApproved  Type = "run.toolcall.approved"
 	TypeToolCallDenied    Type = "run.toolcall.denied"
@@ -32,7 +35,11 @@ const (
 	TypeDeliveryStarted    Type = "run.delivery.started"
 	TypeDeliveryCompleted  Type = "run.delivery.completed"
 	TypeDeliveryFailed     Type = "run.delivery.failed"
+	TypeDeliveryWarning    Type = "run.delivery.warning"           // The commit or branch fails the project's git policy
+	TypeDeliveryDownstream Type = "run.delivery.downstream_failed" // CI failed on or someone reverted the delivered changes
 	TypeStallDetected      Type = "run.stall_detected"
+	TypeRunDiffStat        Type = "run.diffstat"
+	TypeEgressBlocked      Type = "run.egress.blocked"
 
 	// Phase 5A: orchestration plan events
 	TypePlanCreated   Type = "plan.created"
@@ -40,6 +47,37 @@ const (
 	TypePlanCompleted Type = "plan.completed"
 	TypePlanFailed    Type = "plan.failed"
 	TypePlanCancelled Type = "plan.cancelled"
+	TypePlanStepRetry Type = "plan.step.retry"
+	TypePlanStepSkip  Type = "plan.step.skipped"
+	TypePlanLoop      Type = "plan.loop.iteration"
+
+	TypePlanApprovalRequested Type = "plan.approval.requested"
+	TypePlanApprovalApproved  Type = "plan.approval.approved"
+	TypePlanApprovalRejected  Type = "plan.approval.rejected"
+	TypePlanApprovalVoted     Type = "plan.approval.voted"     // An approval short of its quorum
+	TypePlanApprovalEscalated Type = "plan.approval.escalated" // Still waiting after the policy's escalation delay
+
+	TypePlanDebateSummarized Type = "plan.debate.summarized" // The arbiter summarized a finished ping_pong debate
+	TypePlanHumanEdit        Type = "plan.human_edit"        // A human changed the workspace between steps
+	TypePlanImageBuilt       Type = "plan.image.built"       // An image step pushed the image it built
+
+	// Research run events
+	TypeResearchStarted   Type = "run.research.started"
+	TypeResearchCompleted Type = "run.research.completed"
+	TypeResearchFailed    Type = "run.research.failed"
+
+	// Context assembly events
+	TypeMemoriesRecalled     Type = "run.memory.recalled"
+	TypeMicroagentsActivated Type = "run.microagent.activated"
+
+	// Structured output events
+	TypeOutputValidated Type = "run.output.validated"
+	TypeOutputInvalid   Type = "run.output.invalid"
+
+	// Remote (A2A) delegation events
+	TypeA2ADelegated  Type = "run.a2a.delegated"
+	TypeA2ATaskStatus Type = "run.a2a.status"
+	TypeA2AArtifact   Type = "run.a2a.artifact"
 )
 
 // AgentEvent represents a single immutable event in an agent's execution trajectory.
@@ -48,6 +86,7 @@ type AgentEvent struct {
 	AgentID   string          `json:"agent_id"`
 	TaskID    string          `json:"task_id"`
 	ProjectID string          `json:"project_id"`
+	RunID     string          `json:"run_id,omitempty"`
 	Type      Type            `json:"type"`
 	Payload   json.RawMessage `json:"payload"`
 	RequestID string          `json:"request_id,omitempty"`
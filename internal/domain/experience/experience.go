@@ -0,0 +1,160 @@
+// Package experience defines the experience pool: the outcomes of past
+// execution plans, searched for similar features when planning new ones
+// and rated by users so unhelpful entries fade out.
+package experience
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+)
+
+// Outcome is how a plan ended.
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+)
+
+// MaxPitfalls caps the pitfalls recorded per plan.
+const MaxPitfalls = 5
+
+// MinSimilarity is the keyword overlap below which entries are not
+// considered similar to a feature.
+const MinSimilarity = 0.1
+
+// Entry records the outcome of an execution plan.
+type Entry struct {
+	ID         string    `json:"id"`
+	ProjectID  string    `json:"project_id"`
+	PlanID     string    `json:"plan_id"`
+	Feature    string    `json:"feature"` // Plan name and description
+	Outcome    Outcome   `json:"outcome"`
+	Steps      int       `json:"steps"`
+	DurationMs int64     `json:"duration_ms"`        // From plan creation to its end
+	Pitfalls   []string  `json:"pitfalls,omitempty"` // Errors of failed steps
+	Useful     int       `json:"useful"`             // Ratings as useful
+	NotUseful  int       `json:"not_useful"`         // Ratings as not useful
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Usefulness returns the smoothed share of useful ratings: 0.5 for an
+// unrated entry, falling towards 0 as it is rated not useful.
+func (e *Entry) Usefulness() float64 {
+	return float64(e.Useful+1) / float64(e.Useful+e.NotUseful+2)
+}
+
+// FromPlan builds the entry of a plan that ended with outcome at now.
+// Pitfalls are the distinct errors of failed attempts and steps.
+func FromPlan(p *plan.ExecutionPlan, outcome Outcome, now time.Time) *Entry {
+	e := &Entry{
+		ProjectID:  p.ProjectID,
+		PlanID:     p.ID,
+		Feature:    strings.TrimSpace(p.Name + "\n" + p.Description),
+		Outcome:    outcome,
+		Steps:      len(p.Steps),
+		DurationMs: now.Sub(p.CreatedAt).Milliseconds(),
+	}
+	seen := make(map[string]bool)
+	add := func(msg string) {
+		msg = firstLine(msg)
+		if msg != "" && !seen[msg] && len(e.Pitfalls) < MaxPitfalls {
+			seen[msg] = true
+			e.Pitfalls = append(e.Pitfalls, msg)
+		}
+	}
+	for i := range p.Steps {
+		for _, a := range p.Steps[i].Attempts {
+			add(a.Error)
+		}
+		if p.Steps[i].Status == plan.StepStatusFailed {
+			add(p.Steps[i].Error)
+		}
+	}
+	return e
+}
+
+// RateRequest rates an entry's usefulness for planning.
+type RateRequest struct {
+	Useful bool `json:"useful"`
+}
+
+// Match is an entry similar to a feature.
+type Match struct {
+	Entry
+	Similarity float64 `json:"similarity"`
+	Score      float64 `json:"score"` // Similarity weighted by usefulness
+}
+
+// Search returns the entries most similar to feature, weighted by their
+// usefulness. Entries below MinSimilarity or minUsefulness are skipped.
+func Search(entries []Entry, feature string, limit int, minUsefulness float64) []Match {
+	words := keywords(feature)
+	var out []Match
+	for i := range entries {
+		e := &entries[i]
+		if e.Usefulness() < minUsefulness {
+			continue
+		}
+		sim := jaccard(words, keywords(e.Feature))
+		if sim < MinSimilarity {
+			continue
+		}
+		out = append(out, Match{Entry: *e, Similarity: sim, Score: sim * e.Usefulness()})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// Render formats matches for a planning prompt.
+func Render(matches []Match) string {
+	var b strings.Builder
+	for i := range matches {
+		m := &matches[i]
+		fmt.Fprintf(&b, "- %s (%s, %d steps, %s)\n", firstLine(m.Feature), m.Outcome, m.Steps,
+			(time.Duration(m.DurationMs) * time.Millisecond).Round(time.Minute))
+		for _, p := range m.Pitfalls {
+			fmt.Fprintf(&b, "  pitfall: %s\n", firstLine(p))
+		}
+	}
+	return b.String()
+}
+
+func firstLine(s string) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	return s
+}
+
+// keywords returns the lowercase words of text with at least three letters.
+func keywords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(w) >= 3 {
+			words[w] = true
+		}
+	}
+	return words
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for w := range a {
+		if b[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
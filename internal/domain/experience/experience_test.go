@@ -0,0 +1,74 @@
+package experience_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/experience"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+)
+
+func TestUsefulness(t *testing.T) {
+	for _, tt := range []struct {
+		useful, notUseful int
+		want              float64
+	}{
+		{0, 0, 0.5},
+		{1, 0, 2.0 / 3},
+		{0, 2, 0.25},
+	} {
+		e := experience.Entry{Useful: tt.useful, NotUseful: tt.notUseful}
+		if got := e.Usefulness(); got != tt.want {
+			t.Errorf("Usefulness(%d, %d) = %f, want %f", tt.useful, tt.notUseful, got, tt.want)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	entries := []experience.Entry{
+		{ID: "auth", Feature: "Auth Feature\nImplement user authentication with JWT"},
+		{ID: "auth-rated-down", Feature: "User authentication with JWT tokens", NotUseful: 3},
+		{ID: "billing", Feature: "Billing export to CSV"},
+		{ID: "login", Feature: "Login page with JWT refresh", Useful: 2},
+	}
+
+	got := experience.Search(entries, "Add JWT user authentication", 5, 0.3)
+	if len(got) != 2 || got[0].ID != "auth" || got[1].ID != "login" {
+		t.Fatalf("unexpected matches %+v", got)
+	}
+	if got[0].Score != got[0].Similarity*0.5 {
+		t.Fatalf("expected score weighted by usefulness, got %+v", got[0])
+	}
+	if got := experience.Search(entries, "Add JWT user authentication", 1, 0); len(got) != 1 {
+		t.Fatalf("expected limit 1, got %d", len(got))
+	}
+}
+
+func TestFromPlan(t *testing.T) {
+	created := time.Now().Add(-time.Hour)
+	p := &plan.ExecutionPlan{
+		ID: "plan-1", ProjectID: "p1", Name: "Auth", Description: "JWT login", CreatedAt: created,
+		Steps: []plan.Step{
+			{Status: plan.StepStatusCompleted, Attempts: []plan.StepAttempt{{Error: "flaky test\nstack"}}},
+			{Status: plan.StepStatusFailed, Error: "flaky test", Attempts: []plan.StepAttempt{{Error: "build failed"}}},
+		},
+	}
+	e := experience.FromPlan(p, experience.OutcomeFailure, created.Add(time.Hour))
+	if e.Feature != "Auth\nJWT login" || e.Steps != 2 || e.DurationMs != time.Hour.Milliseconds() {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+	if len(e.Pitfalls) != 2 || e.Pitfalls[0] != "flaky test" || e.Pitfalls[1] != "build failed" {
+		t.Fatalf("expected distinct first-line pitfalls, got %q", e.Pitfalls)
+	}
+}
+
+func TestRender(t *testing.T) {
+	out := experience.Render([]experience.Match{{Entry: experience.Entry{
+		Feature: "Auth Feature\ndetails", Outcome: experience.OutcomeFailure, Steps: 2,
+		DurationMs: 600000, Pitfalls: []string{"tests timed out"},
+	}}})
+	if !strings.Contains(out, "- Auth Feature (failure, 2 steps, 10m0s)") || !strings.Contains(out, "pitfall: tests timed out") {
+		t.Fatalf("unexpected render %q", out)
+	}
+}
@@ -0,0 +1,142 @@
+// Package featureflag defines runtime switches for experimental subsystems.
+// A flag is overridden for all tenants, for a tenant or for a project; the
+// most specific override wins, and without one the flag has its default.
+package featureflag
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+)
+
+// Key names a flag.
+type Key string
+
+const (
+	// GraphRAG adds the repo map of the project's code graph to run
+	// context packs.
+	GraphRAG Key = "graph_rag"
+	// LSP asks workers to start language servers for runs (run config
+	// "lsp": "true") and enables the project's LSP endpoints and tools.
+	LSP Key = "lsp"
+	// AgenticConversations lets conversations recall project memories and
+	// activate microagents; without it they are plain chats.
+	AgenticConversations Key = "agentic_conversations"
+)
+
+// Definition describes a flag the server checks.
+type Definition struct {
+	Key         Key    `json:"key"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+}
+
+// Definitions lists all flags.
+var Definitions = []Definition{
+	{Key: GraphRAG, Description: "Add the repo map of the code graph to run context packs", Default: false},
+	{Key: LSP, Description: "Start language servers in workers for runs and enable the LSP endpoints and tools", Default: false},
+	{Key: AgenticConversations, Description: "Recall memories and activate microagents in conversations", Default: true},
+}
+
+// Lookup returns the definition of key.
+func Lookup(key Key) (Definition, bool) {
+	i := slices.IndexFunc(Definitions, func(d Definition) bool { return d.Key == key })
+	if i < 0 {
+		return Definition{}, false
+	}
+	return Definitions[i], true
+}
+
+// Scope is the level an override applies to.
+type Scope string
+
+const (
+	ScopeDefault Scope = "default" // No override
+	ScopeGlobal  Scope = "global"  // All tenants
+	ScopeTenant  Scope = "tenant"
+	ScopeProject Scope = "project"
+)
+
+var (
+	ErrUnknownKey      = errors.New("unknown feature flag")
+	ErrProjectOrTenant = errors.New("set either tenant_id or project_id, not both")
+)
+
+// Flag is an override of a flag.
+type Flag struct {
+	Key       Key       `json:"key"`
+	TenantID  string    `json:"tenant_id,omitempty"`  // Set for tenant and project overrides
+	ProjectID string    `json:"project_id,omitempty"` // Set for project overrides
+	Enabled   bool      `json:"enabled"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Scope returns the level the override applies to.
+func (f *Flag) Scope() Scope {
+	switch {
+	case f.ProjectID != "":
+		return ScopeProject
+	case f.TenantID != "":
+		return ScopeTenant
+	default:
+		return ScopeGlobal
+	}
+}
+
+// ID identifies the override among all others, e.g. "graph_rag.tenant.acme".
+// It is also its key in the propagation bucket.
+func (f *Flag) ID() string {
+	return ID(f.Key, f.TenantID, f.ProjectID)
+}
+
+// ID returns the ID of the override of key for a project, else a tenant,
+// else all tenants.
+func ID(key Key, tenantID, projectID string) string {
+	switch {
+	case projectID != "":
+		return string(key) + ".project." + projectID
+	case tenantID != "":
+		return string(key) + ".tenant." + tenantID
+	default:
+		return string(key) + ".global"
+	}
+}
+
+// SetRequest overrides a flag. Without tenant and project the override
+// applies to all tenants.
+type SetRequest struct {
+	TenantID  string `json:"tenant_id,omitempty"`
+	ProjectID string `json:"project_id,omitempty"`
+	Enabled   bool   `json:"enabled"`
+}
+
+// Validate checks the key and the scope.
+func (r *SetRequest) Validate(key Key) error {
+	if _, ok := Lookup(key); !ok {
+		return ErrUnknownKey
+	}
+	r.TenantID, r.ProjectID = strings.TrimSpace(r.TenantID), strings.TrimSpace(r.ProjectID)
+	if r.TenantID != "" && r.ProjectID != "" {
+		return ErrProjectOrTenant
+	}
+	if r.TenantID != "" && !tenant.ValidID(r.TenantID) {
+		return tenant.ErrInvalidID
+	}
+	return nil
+}
+
+// State is the value of a flag for a project and the scope it comes from.
+type State struct {
+	Key     Key   `json:"key"`
+	Enabled bool  `json:"enabled"`
+	Scope   Scope `json:"scope"`
+}
+
+// Status is a flag with its overrides.
+type Status struct {
+	Definition
+	Overrides []Flag `json:"overrides"`
+}
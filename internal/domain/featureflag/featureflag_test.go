@@ -0,0 +1,56 @@
+package featureflag_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/featureflag"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+)
+
+func TestSetRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     featureflag.Key
+		req     featureflag.SetRequest
+		wantErr error
+		wantOK  bool
+	}{
+		{"global", featureflag.GraphRAG, featureflag.SetRequest{Enabled: true}, nil, true},
+		{"tenant", featureflag.LSP, featureflag.SetRequest{TenantID: " acme "}, nil, true},
+		{"project", featureflag.AgenticConversations, featureflag.SetRequest{ProjectID: "p1"}, nil, true},
+		{"unknown key", "warp_drive", featureflag.SetRequest{}, featureflag.ErrUnknownKey, false},
+		{"both scopes", featureflag.LSP, featureflag.SetRequest{TenantID: "acme", ProjectID: "p1"}, featureflag.ErrProjectOrTenant, false},
+		{"bad tenant", featureflag.LSP, featureflag.SetRequest{TenantID: "Acme"}, tenant.ErrInvalidID, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate(tt.key)
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("got %v, want ok=%v", err, tt.wantOK)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFlag_ID(t *testing.T) {
+	for _, tt := range []struct {
+		flag  featureflag.Flag
+		id    string
+		scope featureflag.Scope
+	}{
+		{featureflag.Flag{Key: featureflag.LSP}, "lsp.global", featureflag.ScopeGlobal},
+		{featureflag.Flag{Key: featureflag.LSP, TenantID: "acme"}, "lsp.tenant.acme", featureflag.ScopeTenant},
+		{featureflag.Flag{Key: featureflag.LSP, TenantID: "acme", ProjectID: "p1"}, "lsp.project.p1", featureflag.ScopeProject},
+	} {
+		if got := tt.flag.ID(); got != tt.id {
+			t.Errorf("ID() = %q, want %q", got, tt.id)
+		}
+		if got := tt.flag.Scope(); got != tt.scope {
+			t.Errorf("Scope() = %q, want %q", got, tt.scope)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+// Package gitpolicy defines a project's conventions for the commits and
+// branches CodeForge creates: the commit message style, ticket IDs that
+// commits must reference and the template branch names are built from.
+package gitpolicy
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Project config keys.
+const (
+	ConfigCommitStyle    = "commit_style"    // "" (prefix, task title and run) or "conventional"
+	ConfigCommitType     = "commit_type"     // Type of conventional commits; derived from the task title when empty
+	ConfigTicketPattern  = "ticket_pattern"  // Regexp of the ticket IDs commits reference, e.g. [A-Z]+-[0-9]+
+	ConfigBranchTemplate = "branch_template" // Template of delivery branch names, e.g. codeforge/{task-slug}-{run-shortid}
+)
+
+// Commit styles.
+const (
+	StyleDefault      = ""
+	StyleConventional = "conventional"
+)
+
+// DefaultBranchTemplate names delivery branches after their run.
+const DefaultBranchTemplate = "codeforge/{run-shortid}"
+
+// maxSlugLength bounds the {task-slug} of branch names.
+const maxSlugLength = 40
+
+// conventionalTypes are the commit types of the Conventional Commits
+// specification as adopted by commitlint's conventional config.
+var conventionalTypes = []string{"build", "chore", "ci", "docs", "feat", "fix", "perf", "refactor", "revert", "style", "test"}
+
+var (
+	conventionalSubject = regexp.MustCompile(`^([a-z]+)(\([^()]+\))?!?: \S`)
+	typePrefix          = regexp.MustCompile(`^([a-z]+)(?:\([^()]+\))?!?: `)
+	placeholder         = regexp.MustCompile(`\{[a-z-]+\}`)
+	nonSlug             = regexp.MustCompile(`[^a-z0-9]+`)
+	invalidRef          = regexp.MustCompile(`[\x00-\x20~^:?*\[\\\x7f]|\.\.|//|@\{|\.lock(/|$)|/\.|^[./-]|[./]$`)
+)
+
+// placeholders are the variables of branch templates.
+var placeholders = map[string]bool{
+	"{task-slug}": true, "{task-id}": true, "{run-id}": true, "{run-shortid}": true, "{ticket}": true,
+}
+
+// typeWords map the first word of a task title to a conventional commit
+// type.
+var typeWords = map[string]string{
+	"fix": "fix", "fixes": "fix", "bug": "fix", "repair": "fix", "resolve": "fix", "handle": "fix",
+	"add": "feat", "adds": "feat", "implement": "feat", "support": "feat", "create": "feat", "introduce": "feat", "allow": "feat",
+	"doc": "docs", "docs": "docs", "document": "docs",
+	"refactor": "refactor", "rename": "refactor", "restructure": "refactor", "extract": "refactor", "move": "refactor", "simplify": "refactor",
+	"test": "test", "tests": "test",
+	"speed": "perf", "optimize": "perf",
+	"revert": "revert",
+}
+
+// Policy holds a project's commit and branch conventions.
+type Policy struct {
+	CommitStyle    string
+	CommitType     string
+	TicketPattern  *regexp.Regexp // nil requires no ticket
+	BranchTemplate string
+}
+
+// FromConfig reads the policy of a project config. Unset keys keep the
+// defaults; invalid settings are an error.
+func FromConfig(cfg map[string]string) (*Policy, error) {
+	p := &Policy{
+		CommitStyle:    strings.TrimSpace(cfg[ConfigCommitStyle]),
+		CommitType:     strings.TrimSpace(cfg[ConfigCommitType]),
+		BranchTemplate: strings.TrimSpace(cfg[ConfigBranchTemplate]),
+	}
+	if p.CommitStyle != StyleDefault && p.CommitStyle != StyleConventional {
+		return nil, fmt.Errorf("%s must be empty or %q, got %q", ConfigCommitStyle, StyleConventional, p.CommitStyle)
+	}
+	if p.CommitType != "" && !isConventionalType(p.CommitType) {
+		return nil, fmt.Errorf("%s %q is not a conventional commit type", ConfigCommitType, p.CommitType)
+	}
+	if pattern := strings.TrimSpace(cfg[ConfigTicketPattern]); pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ConfigTicketPattern, err)
+		}
+		p.TicketPattern = re
+	}
+	if p.BranchTemplate == "" {
+		p.BranchTemplate = DefaultBranchTemplate
+	}
+	for _, ph := range placeholder.FindAllString(p.BranchTemplate, -1) {
+		if !placeholders[ph] {
+			return nil, fmt.Errorf("%s: unknown placeholder %s", ConfigBranchTemplate, ph)
+		}
+	}
+	return p, nil
+}
+
+// Vars are the values commit messages and branch names are built from.
+type Vars struct {
+	TaskID    string
+	TaskTitle string
+	RunID     string
+	Prefix    string // Commit prefix of the default style, e.g. "codeforge:"
+}
+
+// shortID returns the first 8 characters of id.
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// Commit is a generated commit message. Title is its subject without the
+// run reference, e.g. for pull request titles.
+type Commit struct {
+	Title    string
+	Message  string
+	Warnings []string // Ways the message fails the policy
+}
+
+// Commit builds the commit message of a delivery. A ticket ID in the task
+// title is moved to the front of the subject, or into the scope of a
+// conventional commit.
+func (p *Policy) Commit(v Vars) *Commit {
+	ticket, title := p.ticket(v.TaskTitle)
+	c := &Commit{}
+	switch p.CommitStyle {
+	case StyleConventional:
+		typ, desc := p.commitType(title)
+		scope := ""
+		if ticket != "" {
+			scope = "(" + ticket + ")"
+		}
+		c.Title = typ + scope + ": " + desc
+		c.Message = c.Title + "\n\nCodeForge run " + v.RunID
+	default:
+		c.Title = strings.TrimSpace(v.Prefix + " " + title)
+		if ticket != "" {
+			c.Title = ticket + " " + c.Title
+		}
+		c.Message = fmt.Sprintf("%s [run %s]", c.Title, shortID(v.RunID))
+	}
+	c.Warnings = p.CheckCommit(c.Title)
+	return c
+}
+
+// CheckCommit returns the ways a commit subject fails the policy.
+func (p *Policy) CheckCommit(subject string) []string {
+	var warnings []string
+	if p.CommitStyle == StyleConventional {
+		m := conventionalSubject.FindStringSubmatch(subject)
+		switch {
+		case m == nil:
+			warnings = append(warnings, fmt.Sprintf("commit subject %q is not a conventional commit", subject))
+		case !isConventionalType(m[1]):
+			warnings = append(warnings, fmt.Sprintf("commit type %q is not a conventional commit type", m[1]))
+		}
+	}
+	if p.TicketPattern != nil {
+		loc := p.TicketPattern.FindStringIndex(subject)
+		switch {
+		case loc == nil:
+			warnings = append(warnings, fmt.Sprintf("commit subject %q references no ticket matching %s", subject, p.TicketPattern))
+		case p.CommitStyle == StyleDefault && loc[0] != 0:
+			warnings = append(warnings, fmt.Sprintf("commit subject %q does not start with its ticket", subject))
+		}
+	}
+	return warnings
+}
+
+// Branch builds the name of a delivery branch from the template. A name
+// that is not a valid git ref falls back to the default template.
+func (p *Policy) Branch(v Vars) (string, []string) {
+	ticket, title := p.ticket(v.TaskTitle)
+	var warnings []string
+	if ticket == "" && strings.Contains(p.BranchTemplate, "{ticket}") {
+		warnings = append(warnings, "branch template uses {ticket}, but the task title references no ticket")
+	}
+	name := expand(p.BranchTemplate, map[string]string{
+		"{task-slug}":   slug(title),
+		"{task-id}":     v.TaskID,
+		"{run-id}":      v.RunID,
+		"{run-shortid}": shortID(v.RunID),
+		"{ticket}":      ticket,
+	})
+	if w := CheckBranch(name); w != "" {
+		warnings = append(warnings, w)
+		name = expand(DefaultBranchTemplate, map[string]string{"{run-shortid}": shortID(v.RunID)})
+	}
+	return name, warnings
+}
+
+// CheckBranch returns why name is not a valid branch name, or "".
+func CheckBranch(name string) string {
+	if name == "" || invalidRef.MatchString(name) {
+		return fmt.Sprintf("branch name %q is not a valid git ref", name)
+	}
+	return ""
+}
+
+// expand fills the placeholders of a branch template. Separators left
+// around empty values are collapsed.
+func expand(template string, vals map[string]string) string {
+	name := placeholder.ReplaceAllStringFunc(template, func(ph string) string { return vals[ph] })
+	for _, sep := range []string{"--", "__", "//"} {
+		for strings.Contains(name, sep) {
+			name = strings.ReplaceAll(name, sep, sep[:1])
+		}
+	}
+	for _, pair := range []string{"/-", "-/", "/_", "_/"} {
+		name = strings.ReplaceAll(name, pair, "/")
+	}
+	return strings.Trim(name, "-_/")
+}
+
+// ticket extracts the first ticket ID from a task title and returns it
+// with the rest of the title.
+func (p *Policy) ticket(title string) (string, string) {
+	if p.TicketPattern == nil {
+		return "", title
+	}
+	loc := p.TicketPattern.FindStringIndex(title)
+	if loc == nil || loc[0] == loc[1] {
+		return "", title
+	}
+	rest := strings.NewReplacer("[]", "", "()", "").Replace(title[:loc[0]] + title[loc[1]:])
+	return title[loc[0]:loc[1]], strings.Join(strings.Fields(strings.Trim(rest, " :-#")), " ")
+}
+
+// commitType returns the conventional type of a commit for a task title
+// and the title without a type prefix it already had. The configured
+// commit_type wins over the title.
+func (p *Policy) commitType(title string) (string, string) {
+	typ := ""
+	if m := typePrefix.FindStringSubmatch(title); m != nil && isConventionalType(m[1]) {
+		typ, title = m[1], title[len(m[0]):]
+	}
+	if p.CommitType != "" {
+		return p.CommitType, title
+	}
+	if typ != "" {
+		return typ, title
+	}
+	first, _, _ := strings.Cut(strings.ToLower(title), " ")
+	if typ, ok := typeWords[strings.TrimFunc(first, func(r rune) bool { return !unicode.IsLetter(r) })]; ok {
+		return typ, title
+	}
+	return "chore", title
+}
+
+func isConventionalType(t string) bool {
+	return slices.Contains(conventionalTypes, t)
+}
+
+// slug turns a task title into a branch name segment.
+func slug(title string) string {
+	s := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(s) > maxSlugLength {
+		s = strings.TrimRight(s[:maxSlugLength], "-")
+	}
+	return s
+}
@@ -0,0 +1,115 @@
+package gitpolicy_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/gitpolicy"
+)
+
+func mustPolicy(t *testing.T, cfg map[string]string) *gitpolicy.Policy {
+	t.Helper()
+	p, err := gitpolicy.FromConfig(cfg)
+	if err != nil {
+		t.Fatalf("FromConfig(%v): %v", cfg, err)
+	}
+	return p
+}
+
+func TestFromConfig_Invalid(t *testing.T) {
+	for _, cfg := range []map[string]string{
+		{gitpolicy.ConfigCommitStyle: "gitmoji"},
+		{gitpolicy.ConfigCommitType: "feature"},
+		{gitpolicy.ConfigTicketPattern: "[A-Z+"},
+		{gitpolicy.ConfigBranchTemplate: "codeforge/{task-title}"},
+	} {
+		if _, err := gitpolicy.FromConfig(cfg); err == nil {
+			t.Errorf("expected an error for %v", cfg)
+		}
+	}
+}
+
+func TestCommit(t *testing.T) {
+	vars := gitpolicy.Vars{TaskTitle: "Fix null pointer in login", RunID: "run-abcd1234-5678", Prefix: "codeforge:"}
+	tests := []struct {
+		name      string
+		cfg       map[string]string
+		title     string
+		wantTitle string
+		wantWarn  string
+	}{
+		{"default", nil, "", "codeforge: Fix null pointer in login", ""},
+		{"conventional derives the type", map[string]string{"commit_style": "conventional"}, "", "fix: Fix null pointer in login", ""},
+		{"conventional keeps the title's type", map[string]string{"commit_style": "conventional"}, "docs: explain the login flow", "docs: explain the login flow", ""},
+		{"conventional with a configured type", map[string]string{"commit_style": "conventional", "commit_type": "chore"}, "feat: bump deps", "chore: bump deps", ""},
+		{"conventional ticket scope", map[string]string{"commit_style": "conventional", "ticket_pattern": `[A-Z]+-[0-9]+`}, "[AUTH-42] Add SSO", "feat(AUTH-42): Add SSO", ""},
+		{"ticket prefix", map[string]string{"ticket_pattern": `[A-Z]+-[0-9]+`}, "Add SSO (AUTH-42)", "AUTH-42 codeforge: Add SSO", ""},
+		{"missing ticket", map[string]string{"ticket_pattern": `[A-Z]+-[0-9]+`}, "", "codeforge: Fix null pointer in login", "references no ticket"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := vars
+			if tt.title != "" {
+				v.TaskTitle = tt.title
+			}
+			c := mustPolicy(t, tt.cfg).Commit(v)
+			if c.Title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", c.Title, tt.wantTitle)
+			}
+			if !strings.HasPrefix(c.Message, c.Title) || !strings.Contains(c.Message, "run-abcd") {
+				t.Errorf("expected the message to reference the run, got %q", c.Message)
+			}
+			switch {
+			case tt.wantWarn == "" && len(c.Warnings) > 0:
+				t.Errorf("unexpected warnings %q", c.Warnings)
+			case tt.wantWarn != "" && (len(c.Warnings) != 1 || !strings.Contains(c.Warnings[0], tt.wantWarn)):
+				t.Errorf("expected a warning containing %q, got %q", tt.wantWarn, c.Warnings)
+			}
+		})
+	}
+}
+
+func TestBranch(t *testing.T) {
+	vars := gitpolicy.Vars{TaskID: "task-1", TaskTitle: "AUTH-42: Add SSO via OIDC!", RunID: "run-abcd1234-5678"}
+	tests := []struct {
+		template string
+		want     string
+		warnings int
+	}{
+		{"", "codeforge/run-abcd", 0},
+		{"codeforge/{task-slug}-{run-shortid}", "codeforge/add-sso-via-oidc-run-abcd", 0},
+		{"feature/{ticket}/{task-slug}", "feature/AUTH-42/add-sso-via-oidc", 0},
+		{"bot..{task-id}", "codeforge/run-abcd", 1},
+	}
+	for _, tt := range tests {
+		p := mustPolicy(t, map[string]string{"branch_template": tt.template, "ticket_pattern": `[A-Z]+-[0-9]+`})
+		got, warnings := p.Branch(vars)
+		if got != tt.want || len(warnings) != tt.warnings {
+			t.Errorf("Branch(%q) = %q, %q; want %q with %d warnings", tt.template, got, warnings, tt.want, tt.warnings)
+		}
+	}
+
+	// Without a ticket in the title, {ticket} is dropped with a warning.
+	p := mustPolicy(t, map[string]string{"branch_template": "feature/{ticket}-{task-slug}", "ticket_pattern": `[A-Z]+-[0-9]+`})
+	got, warnings := p.Branch(gitpolicy.Vars{TaskTitle: "Add SSO", RunID: "run-1"})
+	if got != "feature/add-sso" || len(warnings) != 1 {
+		t.Errorf("expected feature/add-sso with a warning, got %q, %q", got, warnings)
+	}
+}
+
+func TestCheckBranch(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"codeforge/run-1": true,
+		"feature/a.b":     true,
+		"-leading":        false,
+		"a..b":            false,
+		"a b":             false,
+		"refs.lock":       false,
+		"trailing/":       false,
+		"a//b":            false,
+	} {
+		if got := gitpolicy.CheckBranch(name) == ""; got != valid {
+			t.Errorf("CheckBranch(%q) valid = %v, want %v", name, got, valid)
+		}
+	}
+}
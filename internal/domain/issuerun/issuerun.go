@@ -0,0 +1,188 @@
+// Package issuerun defines the issue-to-run automation ("assign the bot"):
+// when an issue on the project's git host gets the trigger label or
+// assignee, CodeForge creates a task, runs it with the project's agent and
+// comments the progress back on the issue.
+package issuerun
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+)
+
+// Project config keys holding the automation settings.
+const (
+	ConfigLabel       = "issue_trigger_label"    // Label that hands an issue to CodeForge
+	ConfigAssignee    = "issue_trigger_assignee" // Assignee (bot user) that hands an issue to CodeForge
+	ConfigAgent       = "issue_agent_id"         // Agent running issue tasks; defaults to the project's first agent
+	ConfigDeliverMode = "issue_deliver_mode"     // How results are delivered; defaults to "pr"
+)
+
+// TriggerEvent is the trigger event of issue runs; microagents listing it
+// are activated for them.
+const TriggerEvent = "git.issue.triggered"
+
+var (
+	ErrInvalidDeliverMode = errors.New("invalid deliver_mode")
+	ErrNoAgent            = errors.New("project has no agent to run issues")
+)
+
+// Settings configure the automation of a project. Without a label and an
+// assignee the automation is off.
+type Settings struct {
+	Label       string          `json:"label"`
+	Assignee    string          `json:"assignee"`
+	AgentID     string          `json:"agent_id,omitempty"`
+	DeliverMode run.DeliverMode `json:"deliver_mode"`
+}
+
+// SettingsFromConfig reads the settings from a project config.
+func SettingsFromConfig(cfg map[string]string) Settings {
+	s := Settings{
+		Label:       cfg[ConfigLabel],
+		Assignee:    cfg[ConfigAssignee],
+		AgentID:     cfg[ConfigAgent],
+		DeliverMode: run.DeliverMode(cfg[ConfigDeliverMode]),
+	}
+	if s.DeliverMode == "" {
+		s.DeliverMode = run.DeliverModePR
+	}
+	return s
+}
+
+// Validate normalizes and checks the settings.
+func (s *Settings) Validate() error {
+	s.Label = strings.TrimSpace(s.Label)
+	s.Assignee = strings.TrimPrefix(strings.TrimSpace(s.Assignee), "@")
+	s.AgentID = strings.TrimSpace(s.AgentID)
+	if s.DeliverMode == "" {
+		s.DeliverMode = run.DeliverModePR
+	}
+	if !s.DeliverMode.Valid() {
+		return fmt.Errorf("%w %q", ErrInvalidDeliverMode, s.DeliverMode)
+	}
+	return nil
+}
+
+// Enabled reports whether any issue can trigger a run.
+func (s *Settings) Enabled() bool {
+	return s.Label != "" || s.Assignee != ""
+}
+
+// Apply returns cfg with the settings written into it. Empty settings
+// remove their key.
+func (s *Settings) Apply(cfg map[string]string) map[string]string {
+	out := maps.Clone(cfg)
+	if out == nil {
+		out = make(map[string]string)
+	}
+	for k, v := range map[string]string{
+		ConfigLabel:       s.Label,
+		ConfigAssignee:    s.Assignee,
+		ConfigAgent:       s.AgentID,
+		ConfigDeliverMode: string(s.DeliverMode),
+	} {
+		if v == "" {
+			delete(out, k)
+		} else {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// Triggers reports whether adding labels or assignees to an issue hands it
+// to CodeForge. Names compare case-insensitively.
+func (s *Settings) Triggers(addedLabels, addedAssignees []string) bool {
+	for _, l := range addedLabels {
+		if s.Label != "" && strings.EqualFold(l, s.Label) {
+			return true
+		}
+	}
+	for _, a := range addedAssignees {
+		if s.Assignee != "" && strings.EqualFold(a, s.Assignee) {
+			return true
+		}
+	}
+	return false
+}
+
+// Issue is an issue of the project's repository.
+type Issue struct {
+	Number    int      `json:"number"` // Per-repository number (GitLab: IID)
+	Title     string   `json:"title"`
+	Body      string   `json:"body"`
+	URL       string   `json:"url"`
+	Labels    []string `json:"labels"`
+	Assignees []string `json:"assignees"`
+}
+
+// Prompt returns the prompt of the task created for the issue.
+func (i *Issue) Prompt() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Resolve issue #%d: %s\n", i.Number, i.Title)
+	if i.URL != "" {
+		fmt.Fprintf(&b, "Issue: %s\n", i.URL)
+	}
+	if body := strings.TrimSpace(i.Body); body != "" {
+		b.WriteString("\n")
+		b.WriteString(body)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Status is the state of an issue run.
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+)
+
+// IssueRun links an issue to the task and run created for it and to the
+// comment that reports their progress. A project has at most one running
+// issue run per issue.
+type IssueRun struct {
+	ID          string    `json:"id"`
+	ProjectID   string    `json:"project_id"`
+	Provider    string    `json:"provider"`
+	IssueNumber int       `json:"issue_number"`
+	IssueURL    string    `json:"issue_url,omitempty"`
+	TaskID      string    `json:"task_id"`
+	RunID       string    `json:"run_id,omitempty"`
+	CommentID   string    `json:"comment_id,omitempty"`
+	Status      Status    `json:"status"`
+	PRURL       string    `json:"pr_url,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Comment returns the progress comment for the issue. runLink is the web
+// UI link of the run and may be empty.
+func (r *IssueRun) Comment(runLink string) string {
+	ref := "`" + r.RunID + "`"
+	if runLink != "" {
+		ref = "[" + r.RunID + "](" + runLink + ")"
+	}
+	switch r.Status {
+	case StatusRunning:
+		return fmt.Sprintf("CodeForge picked up this issue and started run %s.", ref)
+	case StatusCompleted:
+		if r.PRURL != "" {
+			return fmt.Sprintf("CodeForge run %s completed. Pull request: %s", ref, r.PRURL)
+		}
+		return fmt.Sprintf("CodeForge run %s completed.", ref)
+	default:
+		if r.RunID == "" {
+			return "CodeForge could not start a run for this issue: " + r.Error
+		}
+		return fmt.Sprintf("CodeForge run %s failed: %s", ref, r.Error)
+	}
+}
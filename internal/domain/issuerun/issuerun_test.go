@@ -0,0 +1,71 @@
+package issuerun_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/issuerun"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+)
+
+func TestSettings(t *testing.T) {
+	s := issuerun.Settings{Label: " codeforge ", Assignee: "@CodeForge-Bot"}
+	if err := s.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Label != "codeforge" || s.Assignee != "CodeForge-Bot" || s.DeliverMode != run.DeliverModePR || !s.Enabled() {
+		t.Fatalf("unexpected settings %+v", s)
+	}
+	bad := issuerun.Settings{Label: "x", DeliverMode: "email"}
+	if err := bad.Validate(); !errors.Is(err, issuerun.ErrInvalidDeliverMode) {
+		t.Fatalf("expected ErrInvalidDeliverMode, got %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		labels    []string
+		assignees []string
+		want      bool
+	}{
+		{"label", []string{"bug", "CODEFORGE"}, nil, true},
+		{"assignee", nil, []string{"codeforge-bot"}, true},
+		{"other", []string{"bug"}, []string{"alice"}, false},
+		{"none", nil, nil, false},
+	}
+	for _, tt := range tests {
+		if got := s.Triggers(tt.labels, tt.assignees); got != tt.want {
+			t.Errorf("%s: Triggers = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	off := issuerun.Settings{}
+	if off.Enabled() || off.Triggers([]string{""}, []string{""}) {
+		t.Fatal("expected empty settings to trigger nothing")
+	}
+
+	cfg := s.Apply(map[string]string{"other": "kept", issuerun.ConfigAgent: "old"})
+	if cfg["other"] != "kept" || cfg[issuerun.ConfigLabel] != "codeforge" || cfg[issuerun.ConfigDeliverMode] != "pr" {
+		t.Fatalf("unexpected config %v", cfg)
+	}
+	if _, ok := cfg[issuerun.ConfigAgent]; ok {
+		t.Fatal("expected the unset agent to be removed")
+	}
+	if got := issuerun.SettingsFromConfig(cfg); got != s {
+		t.Fatalf("round trip: expected %+v, got %+v", s, got)
+	}
+}
+
+func TestComment(t *testing.T) {
+	ir := issuerun.IssueRun{RunID: "r1", Status: issuerun.StatusRunning}
+	if c := ir.Comment("http://ui/runs/r1"); !strings.Contains(c, "[r1](http://ui/runs/r1)") {
+		t.Fatalf("expected a run link, got %q", c)
+	}
+	ir.Status, ir.PRURL = issuerun.StatusCompleted, "https://git.example/pulls/5"
+	if c := ir.Comment(""); !strings.Contains(c, "`r1`") || !strings.Contains(c, ir.PRURL) {
+		t.Fatalf("expected the pull request, got %q", c)
+	}
+	notStarted := issuerun.IssueRun{Status: issuerun.StatusFailed, Error: "no agent"}
+	if c := notStarted.Comment(""); !strings.Contains(c, "could not start") || !strings.Contains(c, "no agent") {
+		t.Fatalf("unexpected failure comment %q", c)
+	}
+}
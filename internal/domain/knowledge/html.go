@@ -0,0 +1,57 @@
+package knowledge
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	htmlTitle   = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	htmlSkipped = regexp.MustCompile(`(?is)<(script|style|noscript|svg|head|template|title)\b[^>]*>.*?</(?:script|style|noscript|svg|head|template|title)>|<!--.*?-->`)
+	htmlBlock   = regexp.MustCompile(`(?i)</?(?:p|div|br|hr|li|ul|ol|tr|table|h[1-6]|pre|blockquote|section|article|header|footer|nav|dt|dd)\b[^>]*>`)
+	htmlTag     = regexp.MustCompile(`(?s)<[^>]*>`)
+	htmlHref    = regexp.MustCompile(`(?i)<a\b[^>]*?\bhref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	spaces      = regexp.MustCompile(`[ \t\r\f\v]+`)
+	blankLines  = regexp.MustCompile(`\n{3,}`)
+)
+
+// HTMLText returns the title and the visible text of an HTML page, keeping
+// block elements on separate lines so chunks follow the page's structure.
+func HTMLText(page string) (title, text string) {
+	if m := htmlTitle.FindStringSubmatch(page); m != nil {
+		title = strings.TrimSpace(spaces.ReplaceAllString(html.UnescapeString(htmlTag.ReplaceAllString(m[1], "")), " "))
+	}
+	s := htmlSkipped.ReplaceAllString(page, "")
+	s = htmlBlock.ReplaceAllString(s, "\n")
+	s = html.UnescapeString(htmlTag.ReplaceAllString(s, ""))
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(spaces.ReplaceAllString(l, " "))
+	}
+	text = blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return title, strings.TrimSpace(text)
+}
+
+// Links returns the absolute http and https URLs an HTML page links to,
+// resolved against the page's URL and without fragments, in page order and
+// without duplicates.
+func Links(base *url.URL, page string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, m := range htmlHref.FindAllStringSubmatch(page, -1) {
+		ref, err := url.Parse(html.UnescapeString(strings.TrimSpace(m[1] + m[2] + m[3])))
+		if err != nil {
+			continue
+		}
+		u := base.ResolveReference(ref)
+		u.Fragment = ""
+		if (u.Scheme != "http" && u.Scheme != "https") || seen[u.String()] {
+			continue
+		}
+		seen[u.String()] = true
+		out = append(out, u.String())
+	}
+	return out
+}
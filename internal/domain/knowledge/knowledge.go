@@ -0,0 +1,203 @@
+// Package knowledge defines knowledge bases: documents from outside the
+// workspaces, such as Confluence spaces, Notion pages and crawled web
+// pages, embedded for the retrieval of the projects they are attached to.
+// Each tenant configures its own knowledge bases.
+package knowledge
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+)
+
+// Kind is the type of system a knowledge base ingests.
+type Kind string
+
+const (
+	// KindConfluence ingests the pages of a Confluence space.
+	KindConfluence Kind = "confluence"
+	// KindNotion ingests Notion pages and their child pages.
+	KindNotion Kind = "notion"
+	// KindURL crawls web pages from start URLs, staying on their hosts.
+	KindURL Kind = "url"
+)
+
+// Config keys of knowledge bases.
+const (
+	ConfigURL         = "url"          // confluence: wiki base URL; url: comma-separated start URLs
+	ConfigSpace       = "space"        // confluence: space key
+	ConfigUser        = "user"         // confluence: account for basic auth with the token; empty sends a bearer token
+	ConfigPages       = "pages"        // notion: comma-separated page IDs
+	ConfigMaxDepth    = "max_depth"    // notion: child page levels; url: link hops from the start URLs (default: 1)
+	ConfigTokenSecret = "token_secret" // confluence, notion: name of the tenant secret sent as credentials
+
+	// ConfigToken carries the resolved token_secret to the source adapter.
+	// It is never stored.
+	ConfigToken = "token"
+)
+
+// Limits of knowledge bases.
+const (
+	MaxNameLen         = 100
+	MaxDepth           = 5
+	MinRefreshInterval = 15 * time.Minute
+)
+
+// configKeys are the required and optional config keys of each kind.
+var configKeys = map[Kind]struct{ required, optional []string }{
+	KindConfluence: {[]string{ConfigURL, ConfigSpace, ConfigTokenSecret}, []string{ConfigUser}},
+	KindNotion:     {[]string{ConfigPages, ConfigTokenSecret}, []string{ConfigMaxDepth}},
+	KindURL:        {[]string{ConfigURL}, []string{ConfigMaxDepth}},
+}
+
+var (
+	ErrNameRequired    = errors.New("name is required")
+	ErrNameTooLong     = errors.New("name is too long (max 100 characters)")
+	ErrInvalidKind     = errors.New("kind must be confluence, notion or url")
+	ErrInvalidURL      = errors.New("url must be an absolute http or https URL")
+	ErrInvalidInterval = fmt.Errorf("refresh_interval must be a duration of at least %s", MinRefreshInterval)
+)
+
+// KnowledgeBase is a set of external documents embedded for retrieval.
+type KnowledgeBase struct {
+	ID              string            `json:"id"`
+	TenantID        string            `json:"tenant_id"`
+	Name            string            `json:"name"` // Unique per tenant
+	Kind            Kind              `json:"kind"`
+	Config          map[string]string `json:"config"`
+	ProjectIDs      []string          `json:"project_ids,omitempty"`      // Projects whose retrieval includes it; empty attaches every project of the tenant
+	RefreshInterval string            `json:"refresh_interval,omitempty"` // e.g. "24h"; empty refreshes on request only
+	Provider        string            `json:"provider,omitempty"`         // Embedding of the stored chunks
+	Model           string            `json:"model,omitempty"`
+	Dimensions      int               `json:"dimensions,omitempty"`
+	Documents       int               `json:"documents"`
+	Chunks          int               `json:"chunks"`
+	LastError       string            `json:"last_error,omitempty"` // Error of the last failed refresh; cleared on success
+	RefreshedAt     *time.Time        `json:"refreshed_at,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// Attached reports whether the knowledge base is part of a project's
+// retrieval. The caller checks that the project belongs to its tenant.
+func (kb *KnowledgeBase) Attached(projectID string) bool {
+	return len(kb.ProjectIDs) == 0 || slices.Contains(kb.ProjectIDs, projectID)
+}
+
+// Due reports whether a scheduled refresh is due at now, given the time of
+// the last refresh attempt (zero if none). Knowledge bases without a
+// refresh interval are never due; failed refreshes wait for the interval
+// like successful ones.
+func (kb *KnowledgeBase) Due(now, lastAttempt time.Time) bool {
+	d, err := time.ParseDuration(kb.RefreshInterval)
+	if kb.RefreshInterval == "" || err != nil {
+		return false
+	}
+	return lastAttempt.IsZero() || !now.Before(lastAttempt.Add(d))
+}
+
+// MaxDepthOf returns the max_depth of a config, 1 if unset.
+func MaxDepthOf(cfg map[string]string) int {
+	if n, err := strconv.Atoi(cfg[ConfigMaxDepth]); err == nil {
+		return n
+	}
+	return 1
+}
+
+// List splits a comma-separated config value.
+func List(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// CreateRequest holds the fields for creating or updating a knowledge base.
+// Updates keep the tenant and the stored chunks until the next refresh.
+type CreateRequest struct {
+	Name            string            `json:"name"`
+	TenantID        string            `json:"tenant_id,omitempty"` // Default: the request's tenant
+	Kind            Kind              `json:"kind"`
+	Config          map[string]string `json:"config"`
+	ProjectIDs      []string          `json:"project_ids,omitempty"`
+	RefreshInterval string            `json:"refresh_interval,omitempty"`
+}
+
+// Validate checks the name, tenant, kind, the config keys of the kind and
+// the refresh interval.
+func (r *CreateRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return ErrNameRequired
+	}
+	if len(r.Name) > MaxNameLen {
+		return ErrNameTooLong
+	}
+	if r.TenantID != "" && !tenant.ValidID(r.TenantID) {
+		return tenant.ErrInvalidID
+	}
+	keys, ok := configKeys[r.Kind]
+	if !ok {
+		return ErrInvalidKind
+	}
+	for _, k := range keys.required {
+		if strings.TrimSpace(r.Config[k]) == "" {
+			return fmt.Errorf("%s knowledge bases need config.%s", r.Kind, k)
+		}
+	}
+	for k := range r.Config {
+		if !slices.Contains(keys.required, k) && !slices.Contains(keys.optional, k) {
+			return fmt.Errorf("unknown config key %q for %s knowledge bases", k, r.Kind)
+		}
+	}
+	if v, ok := r.Config[ConfigMaxDepth]; ok {
+		if n, err := strconv.Atoi(v); err != nil || n < 0 || n > MaxDepth {
+			return fmt.Errorf("config.%s must be between 0 and %d", ConfigMaxDepth, MaxDepth)
+		}
+	}
+	switch r.Kind {
+	case KindConfluence:
+		if !validURL(r.Config[ConfigURL]) {
+			return ErrInvalidURL
+		}
+	case KindURL:
+		for _, u := range List(r.Config[ConfigURL]) {
+			if !validURL(u) {
+				return ErrInvalidURL
+			}
+		}
+	}
+	if r.RefreshInterval != "" {
+		d, err := time.ParseDuration(r.RefreshInterval)
+		if err != nil || d < MinRefreshInterval {
+			return ErrInvalidInterval
+		}
+	}
+	for _, id := range r.ProjectIDs {
+		if strings.TrimSpace(id) == "" {
+			return errors.New("project_ids must not contain empty IDs")
+		}
+	}
+	return nil
+}
+
+func validURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Document is a page fetched from a knowledge base's source, as plain text.
+type Document struct {
+	URL     string // Link to the page, cited in retrieval results
+	Title   string
+	Content string
+}
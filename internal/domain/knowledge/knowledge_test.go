@@ -0,0 +1,87 @@
+package knowledge_test
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/knowledge"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+)
+
+func TestCreateRequest_Validate(t *testing.T) {
+	confluence := map[string]string{"url": "https://acme.atlassian.net/wiki", "space": "ENG", "token_secret": "CONFLUENCE_TOKEN"}
+	tests := []struct {
+		name    string
+		req     knowledge.CreateRequest
+		wantErr error
+		wantOK  bool
+	}{
+		{"confluence", knowledge.CreateRequest{Name: "eng wiki", Kind: knowledge.KindConfluence, Config: confluence, RefreshInterval: "24h"}, nil, true},
+		{"notion", knowledge.CreateRequest{Name: "runbooks", Kind: knowledge.KindNotion, Config: map[string]string{"pages": "abc, def", "token_secret": "NOTION_TOKEN", "max_depth": "2"}}, nil, true},
+		{"url", knowledge.CreateRequest{Name: "docs", Kind: knowledge.KindURL, Config: map[string]string{"url": "https://docs.acme.dev, https://api.acme.dev"}, ProjectIDs: []string{"p1"}}, nil, true},
+		{"no name", knowledge.CreateRequest{Name: " ", Kind: knowledge.KindURL, Config: map[string]string{"url": "https://docs.acme.dev"}}, knowledge.ErrNameRequired, false},
+		{"bad tenant", knowledge.CreateRequest{Name: "docs", TenantID: "Acme", Kind: knowledge.KindURL, Config: map[string]string{"url": "https://docs.acme.dev"}}, tenant.ErrInvalidID, false},
+		{"bad kind", knowledge.CreateRequest{Name: "docs", Kind: "sharepoint"}, knowledge.ErrInvalidKind, false},
+		{"bad url", knowledge.CreateRequest{Name: "docs", Kind: knowledge.KindURL, Config: map[string]string{"url": "https://docs.acme.dev, file:///etc"}}, knowledge.ErrInvalidURL, false},
+		{"missing key", knowledge.CreateRequest{Name: "runbooks", Kind: knowledge.KindNotion, Config: map[string]string{"pages": "abc"}}, nil, false},
+		{"unknown key", knowledge.CreateRequest{Name: "docs", Kind: knowledge.KindURL, Config: map[string]string{"url": "https://docs.acme.dev", "space": "ENG"}}, nil, false},
+		{"bad depth", knowledge.CreateRequest{Name: "docs", Kind: knowledge.KindURL, Config: map[string]string{"url": "https://docs.acme.dev", "max_depth": "9"}}, nil, false},
+		{"short interval", knowledge.CreateRequest{Name: "eng wiki", Kind: knowledge.KindConfluence, Config: confluence, RefreshInterval: "1m"}, knowledge.ErrInvalidInterval, false},
+		{"empty project", knowledge.CreateRequest{Name: "docs", Kind: knowledge.KindURL, Config: map[string]string{"url": "https://docs.acme.dev"}, ProjectIDs: []string{""}}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("got %v, want ok=%v", err, tt.wantOK)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKnowledgeBase_AttachedAndDue(t *testing.T) {
+	kb := knowledge.KnowledgeBase{ProjectIDs: []string{"p1"}, RefreshInterval: "1h"}
+	if !kb.Attached("p1") || kb.Attached("p2") {
+		t.Fatal("expected only p1 to be attached")
+	}
+	if all := (knowledge.KnowledgeBase{}); !all.Attached("p2") {
+		t.Fatal("expected a knowledge base without projects to be attached to all")
+	}
+
+	now := time.Now()
+	if !kb.Due(now, time.Time{}) || kb.Due(now, now.Add(-time.Minute)) || !kb.Due(now, now.Add(-time.Hour)) {
+		t.Fatal("unexpected due times")
+	}
+	if manual := (knowledge.KnowledgeBase{}); manual.Due(now, time.Time{}) {
+		t.Fatal("expected no scheduled refresh without an interval")
+	}
+}
+
+func TestHTMLText(t *testing.T) {
+	page := `<html><head><title>Deploy &amp; Rollback</title><style>p{}</style></head>
+<body><nav><a href="/">Home</a></nav><h1>Deploy</h1><p>Run   <code>make deploy</code>.</p>
+<script>track()</script><ul><li>One</li><li>Two</li></ul></body></html>`
+	title, text := knowledge.HTMLText(page)
+	if title != "Deploy & Rollback" {
+		t.Fatalf("title = %q", title)
+	}
+	if want := "Home\n\nDeploy\n\nRun make deploy.\n\nOne\n\nTwo"; text != want {
+		t.Fatalf("text = %q, want %q", text, want)
+	}
+}
+
+func TestLinks(t *testing.T) {
+	base, _ := url.Parse("https://docs.acme.dev/guide/")
+	page := `<a href="install#step">Install</a> <a href='/api'>API</a> <a href=https://acme.dev>Home</a>
+<a href="mailto:ops@acme.dev">Mail</a> <a href="install">Again</a>`
+	want := []string{"https://docs.acme.dev/guide/install", "https://docs.acme.dev/api", "https://acme.dev"}
+	if got := knowledge.Links(base, page); !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+package lint
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Changes are the lines a run changed, per workspace-relative path.
+type Changes struct {
+	lines map[string][][2]int // Inclusive ranges of added or changed lines
+	files map[string]bool     // Files changed without line information
+}
+
+// hunkHeader matches the new-file range of a unified diff hunk.
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// ParseDiff collects the added and changed lines of a unified diff, as
+// produced by git diff -U0.
+func ParseDiff(diff string) *Changes {
+	c := &Changes{lines: make(map[string][][2]int), files: make(map[string]bool)}
+	path := ""
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			path = strings.TrimPrefix(strings.TrimSpace(line[4:]), "b/")
+			if path == "/dev/null" {
+				path = ""
+			}
+		case path != "":
+			m := hunkHeader.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			start, _ := strconv.Atoi(m[1])
+			count := 1
+			if m[2] != "" {
+				count, _ = strconv.Atoi(m[2])
+			}
+			if count > 0 {
+				c.lines[path] = append(c.lines[path], [2]int{start, start + count - 1})
+			}
+		}
+	}
+	return c
+}
+
+// AddFiles marks whole files as changed, for changes whose diff is not
+// available, e.g. because they were already committed.
+func (c *Changes) AddFiles(paths ...string) {
+	for _, p := range paths {
+		if _, ok := c.lines[p]; !ok && p != "" {
+			c.files[p] = true
+		}
+	}
+}
+
+// Contains reports whether line of path was changed. Line 0 refers to the
+// whole file.
+func (c *Changes) Contains(path string, line int) bool {
+	if c.files[path] {
+		return true
+	}
+	ranges, ok := c.lines[path]
+	if !ok {
+		return false
+	}
+	if line <= 0 {
+		return true
+	}
+	for _, r := range ranges {
+		if line >= r[0] && line <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkNew sets New on the findings in changed code.
+func MarkNew(findings []Finding, c *Changes) {
+	for i := range findings {
+		findings[i].New = c.Contains(findings[i].Path, findings[i].Line)
+	}
+}
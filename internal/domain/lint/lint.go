@@ -0,0 +1,131 @@
+// Package lint defines the normalized findings of static analysis tools
+// (golangci-lint, ruff, eslint) run on a run's workspace and how they are
+// matched against the run's diff.
+package lint
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/review"
+)
+
+// Project config keys for linting.
+const (
+	// ConfigKeyLinters lists the linters of a project, comma-separated.
+	// Empty detects them from the workspace stack; "none" disables linting.
+	ConfigKeyLinters = "linters"
+	// ConfigKeyCommandPrefix followed by a tool name overrides the command
+	// of that tool, e.g. "lint_command_eslint". The command must print the
+	// tool's JSON output.
+	ConfigKeyCommandPrefix = "lint_command_"
+)
+
+// Tool is a supported linter.
+type Tool string
+
+const (
+	ToolGolangciLint Tool = "golangci-lint"
+	ToolRuff         Tool = "ruff"
+	ToolESLint       Tool = "eslint"
+)
+
+// defaultCommands print each tool's findings as JSON on stdout.
+var defaultCommands = map[Tool]string{
+	ToolGolangciLint: "golangci-lint run --output.json.path=stdout --show-stats=false ./...",
+	ToolRuff:         "ruff check --output-format=json .",
+	ToolESLint:       "npx --no-install eslint --format=json .",
+}
+
+// Valid reports whether t is a supported linter.
+func (t Tool) Valid() bool {
+	_, ok := defaultCommands[t]
+	return ok
+}
+
+// DefaultCommand returns the command that runs t with JSON output.
+func (t Tool) DefaultCommand() string {
+	return defaultCommands[t]
+}
+
+// ParseTools parses the comma-separated linters config. Unknown names are
+// returned separately.
+func ParseTools(s string) (tools []Tool, unknown []string) {
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if t := Tool(name); t.Valid() {
+			tools = append(tools, t)
+		} else {
+			unknown = append(unknown, name)
+		}
+	}
+	return tools, unknown
+}
+
+// BlockingSeverity is the severity of new findings that block approval.
+const BlockingSeverity = review.SeverityCritical
+
+// Finding is a single normalized linter finding.
+type Finding struct {
+	Tool     Tool            `json:"tool"`
+	Rule     string          `json:"rule,omitempty"`
+	Path     string          `json:"path"` // Relative to the workspace root
+	Line     int             `json:"line,omitempty"`
+	Column   int             `json:"column,omitempty"`
+	Severity review.Severity `json:"severity"`
+	Message  string          `json:"message"`
+	New      bool            `json:"new"` // On a line or in a file the run changed
+}
+
+// Blocking reports whether f blocks the approval of the run.
+func (f *Finding) Blocking() bool {
+	return f.New && f.Severity == BlockingSeverity
+}
+
+// ToolResult is the outcome of one linter invocation.
+type ToolResult struct {
+	Tool     Tool   `json:"tool"`
+	Command  string `json:"command"`
+	Findings int    `json:"findings"`
+	Error    string `json:"error,omitempty"` // Output could not be parsed
+}
+
+// Report is the result of linting a run, stored as a run artifact.
+type Report struct {
+	RunID     string       `json:"run_id"`
+	ProjectID string       `json:"project_id"`
+	Tools     []ToolResult `json:"tools"`
+	Findings  []Finding    `json:"findings"`
+	New       int          `json:"new"`      // Findings in changed code
+	Blocking  int          `json:"blocking"` // New findings of BlockingSeverity
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// Add appends the findings of one tool and updates the counts.
+func (r *Report) Add(res ToolResult, findings []Finding) {
+	res.Findings = len(findings)
+	r.Tools = append(r.Tools, res)
+	for i := range findings {
+		if findings[i].New {
+			r.New++
+		}
+		if findings[i].Blocking() {
+			r.Blocking++
+		}
+	}
+	r.Findings = append(r.Findings, findings...)
+}
+
+// BlockingFindings returns the findings that block approval.
+func (r *Report) BlockingFindings() []Finding {
+	var out []Finding
+	for i := range r.Findings {
+		if r.Findings[i].Blocking() {
+			out = append(out, r.Findings[i])
+		}
+	}
+	return out
+}
@@ -0,0 +1,246 @@
+package lint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/domain/review"
+)
+
+// maxMessageLen bounds the message of a single finding.
+const maxMessageLen = 1024
+
+// ErrNoJSON is returned when a linter's output has no JSON document.
+var ErrNoJSON = errors.New("lint: output has no JSON report")
+
+// Parse normalizes the JSON output of tool. dir is the directory the tool
+// ran in and root the workspace root; paths of findings are made relative
+// to root with forward slashes.
+func Parse(tool Tool, output, dir, root string) ([]Finding, error) {
+	var (
+		findings []Finding
+		err      error
+	)
+	switch tool {
+	case ToolGolangciLint:
+		findings, err = parseGolangci(output)
+	case ToolRuff:
+		findings, err = parseRuff(output)
+	case ToolESLint:
+		findings, err = parseESLint(output)
+	default:
+		return nil, fmt.Errorf("lint: unsupported tool %q", tool)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", tool, err)
+	}
+	for i := range findings {
+		f := &findings[i]
+		f.Tool = tool
+		f.Path = relPath(f.Path, dir, root)
+		f.Message = truncate(strings.TrimSpace(f.Message))
+	}
+	return findings, nil
+}
+
+// extractJSON returns the JSON document in output, which may be preceded
+// or followed by log lines. open is '{' or '['.
+func extractJSON(output string, open, closing byte) (string, error) {
+	start := strings.IndexByte(output, open)
+	end := strings.LastIndexByte(output, closing)
+	if start < 0 || end < start {
+		return "", ErrNoJSON
+	}
+	return output[start : end+1], nil
+}
+
+// --- golangci-lint ---
+
+type golangciReport struct {
+	Issues []struct {
+		FromLinter string `json:"FromLinter"`
+		Text       string `json:"Text"`
+		Severity   string `json:"Severity"`
+		Pos        struct {
+			Filename string `json:"Filename"`
+			Line     int    `json:"Line"`
+			Column   int    `json:"Column"`
+		} `json:"Pos"`
+	} `json:"Issues"`
+}
+
+// golangciCritical are linters whose findings are critical without an
+// explicit severity: compile errors and security issues.
+var golangciCritical = map[string]bool{"typecheck": true, "gosec": true}
+
+func parseGolangci(output string) ([]Finding, error) {
+	doc, err := extractJSON(output, '{', '}')
+	if err != nil {
+		return nil, err
+	}
+	var rep golangciReport
+	if err := json.Unmarshal([]byte(doc), &rep); err != nil {
+		return nil, fmt.Errorf("decode report: %w", err)
+	}
+	findings := make([]Finding, 0, len(rep.Issues))
+	for _, is := range rep.Issues {
+		sev := severityOf(is.Severity)
+		if is.Severity == "" && golangciCritical[is.FromLinter] {
+			sev = review.SeverityCritical
+		}
+		findings = append(findings, Finding{
+			Rule:     is.FromLinter,
+			Path:     is.Pos.Filename,
+			Line:     is.Pos.Line,
+			Column:   is.Pos.Column,
+			Severity: sev,
+			Message:  is.Text,
+		})
+	}
+	return findings, nil
+}
+
+// severityOf maps a tool's severity name to a review severity. Unknown and
+// empty names are warnings.
+func severityOf(s string) review.Severity {
+	switch strings.ToLower(s) {
+	case "error", "critical", "high", "blocker":
+		return review.SeverityCritical
+	case "info", "low", "note", "hint":
+		return review.SeverityInfo
+	}
+	return review.SeverityWarning
+}
+
+// --- ruff ---
+
+type ruffFinding struct {
+	Code     *string `json:"code"` // null for syntax errors
+	Message  string  `json:"message"`
+	Filename string  `json:"filename"`
+	Location struct {
+		Row    int `json:"row"`
+		Column int `json:"column"`
+	} `json:"location"`
+}
+
+// ruffCritical are the rule prefixes of code that fails at runtime: syntax
+// errors, invalid comparisons, misplaced statements and undefined names.
+var ruffCritical = []string{"E9", "F63", "F7", "F82"}
+
+// ruffStyle are the rule prefixes of pure style and documentation checks.
+var ruffStyle = []string{"E1", "E2", "E3", "E5", "W", "D", "I", "N", "Q", "COM"}
+
+func parseRuff(output string) ([]Finding, error) {
+	doc, err := extractJSON(output, '[', ']')
+	if err != nil {
+		return nil, err
+	}
+	var items []ruffFinding
+	if err := json.Unmarshal([]byte(doc), &items); err != nil {
+		return nil, fmt.Errorf("decode report: %w", err)
+	}
+	findings := make([]Finding, 0, len(items))
+	for _, it := range items {
+		f := Finding{
+			Path:     it.Filename,
+			Line:     it.Location.Row,
+			Column:   it.Location.Column,
+			Severity: review.SeverityWarning,
+			Message:  it.Message,
+		}
+		switch {
+		case it.Code == nil || *it.Code == "":
+			f.Severity = review.SeverityCritical
+		case hasAnyPrefix(*it.Code, ruffCritical):
+			f.Rule, f.Severity = *it.Code, review.SeverityCritical
+		case hasAnyPrefix(*it.Code, ruffStyle):
+			f.Rule, f.Severity = *it.Code, review.SeverityInfo
+		default:
+			f.Rule = *it.Code
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// --- eslint ---
+
+type eslintFile struct {
+	FilePath string `json:"filePath"`
+	Messages []struct {
+		RuleID   *string `json:"ruleId"`
+		Severity int     `json:"severity"` // 1 = warning, 2 = error
+		Fatal    bool    `json:"fatal"`    // Parse error
+		Message  string  `json:"message"`
+		Line     int     `json:"line"`
+		Column   int     `json:"column"`
+	} `json:"messages"`
+}
+
+func parseESLint(output string) ([]Finding, error) {
+	doc, err := extractJSON(output, '[', ']')
+	if err != nil {
+		return nil, err
+	}
+	var files []eslintFile
+	if err := json.Unmarshal([]byte(doc), &files); err != nil {
+		return nil, fmt.Errorf("decode report: %w", err)
+	}
+	var findings []Finding
+	for _, file := range files {
+		for _, m := range file.Messages {
+			f := Finding{Path: file.FilePath, Line: m.Line, Column: m.Column, Message: m.Message}
+			if m.RuleID != nil {
+				f.Rule = *m.RuleID
+			}
+			switch {
+			case m.Fatal || m.Severity >= 2:
+				f.Severity = review.SeverityCritical
+			case m.Severity == 1:
+				f.Severity = review.SeverityWarning
+			default:
+				f.Severity = review.SeverityInfo
+			}
+			findings = append(findings, f)
+		}
+	}
+	return findings, nil
+}
+
+// relPath makes a finding's path relative to root. Relative paths are
+// relative to dir, the directory the tool ran in.
+func relPath(p, dir, root string) string {
+	if p == "" {
+		return ""
+	}
+	p = filepath.FromSlash(p)
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(dir, p)
+	}
+	if root != "" {
+		if rel, err := filepath.Rel(root, p); err == nil && !strings.HasPrefix(rel, "..") {
+			p = rel
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(p))
+}
+
+func truncate(s string) string {
+	if len(s) <= maxMessageLen {
+		return s
+	}
+	return s[:maxMessageLen] + "..."
+}
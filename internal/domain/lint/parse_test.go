@@ -0,0 +1,131 @@
+package lint_test
+
+import (
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/lint"
+	"github.com/Strob0t/CodeForge/internal/domain/review"
+)
+
+func TestParseGolangci(t *testing.T) {
+	out := `level=warning msg="[config_reader] deprecated option"
+{"Issues":[
+ {"FromLinter":"errcheck","Text":"Error return value is not checked","Severity":"","Pos":{"Filename":"internal/a.go","Line":12,"Column":9}},
+ {"FromLinter":"typecheck","Text":"undefined: foo","Severity":"","Pos":{"Filename":"internal/b.go","Line":3,"Column":2}},
+ {"FromLinter":"revive","Text":"exported func should have comment","Severity":"info","Pos":{"Filename":"internal/b.go","Line":7,"Column":1}}
+],"Report":{}}`
+	findings, err := lint.Parse(lint.ToolGolangciLint, out, "/ws/services/api", "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 3 {
+		t.Fatalf("expected 3 findings, got %+v", findings)
+	}
+	f := findings[0]
+	if f.Tool != lint.ToolGolangciLint || f.Rule != "errcheck" || f.Path != "services/api/internal/a.go" ||
+		f.Line != 12 || f.Severity != review.SeverityWarning {
+		t.Fatalf("unexpected finding %+v", f)
+	}
+	if findings[1].Severity != review.SeverityCritical || findings[2].Severity != review.SeverityInfo {
+		t.Fatalf("unexpected severities %+v", findings)
+	}
+
+	if _, err := lint.Parse(lint.ToolGolangciLint, "golangci-lint: command not found", "/ws", "/ws"); err == nil {
+		t.Fatal("expected error for output without JSON")
+	}
+}
+
+func TestParseRuff(t *testing.T) {
+	out := `[
+ {"code":"F401","message":"` + "`os`" + ` imported but unused","filename":"/ws/app/main.py","location":{"row":1,"column":8}},
+ {"code":"F821","message":"Undefined name ` + "`x`" + `","filename":"/ws/app/main.py","location":{"row":4,"column":5}},
+ {"code":"E501","message":"Line too long","filename":"/ws/app/main.py","location":{"row":9,"column":89}},
+ {"code":null,"message":"SyntaxError: Expected an expression","filename":"/ws/app/bad.py","location":{"row":2,"column":1}}
+]`
+	findings, err := lint.Parse(lint.ToolRuff, out, "/ws", "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []review.Severity{review.SeverityWarning, review.SeverityCritical, review.SeverityInfo, review.SeverityCritical}
+	for i, f := range findings {
+		if f.Severity != want[i] {
+			t.Fatalf("finding %d: expected %s, got %+v", i, want[i], f)
+		}
+	}
+	if findings[1].Path != "app/main.py" || findings[1].Rule != "F821" || findings[3].Rule != "" {
+		t.Fatalf("unexpected findings %+v", findings)
+	}
+}
+
+func TestParseESLint(t *testing.T) {
+	out := `[{"filePath":"/ws/web/src/a.ts","messages":[
+ {"ruleId":"no-undef","severity":2,"message":"'foo' is not defined.","line":3,"column":1},
+ {"ruleId":"prefer-const","severity":1,"message":"Use const.","line":5,"column":3}
+ ]},
+ {"filePath":"/ws/web/src/b.ts","messages":[{"ruleId":null,"fatal":true,"severity":2,"message":"Parsing error: Unexpected token","line":1,"column":7}]},
+ {"filePath":"/ws/web/src/c.ts","messages":[]}]`
+	findings, err := lint.Parse(lint.ToolESLint, out, "/ws/web", "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 3 || findings[0].Severity != review.SeverityCritical || findings[1].Severity != review.SeverityWarning ||
+		findings[2].Path != "web/src/b.ts" || findings[2].Rule != "" {
+		t.Fatalf("unexpected findings %+v", findings)
+	}
+}
+
+func TestMarkNew(t *testing.T) {
+	diff := `diff --git a/app/main.py b/app/main.py
+index 1111111..2222222 100644
+--- a/app/main.py
++++ b/app/main.py
+@@ -3,0 +4,2 @@ def run():
++    y = x
++    return y
+@@ -20 +22,0 @@
+-    old()
+diff --git a/app/new.py b/app/new.py
+new file mode 100644
+--- /dev/null
++++ b/app/new.py
+@@ -0,0 +1,3 @@
++import os
++
++print(1)
+diff --git a/app/gone.py b/app/gone.py
+deleted file mode 100644
+--- a/app/gone.py
++++ /dev/null
+@@ -1 +0,0 @@
+-x = 1
+`
+	changes := lint.ParseDiff(diff)
+	changes.AddFiles("app/committed.py", "app/main.py")
+	findings := []lint.Finding{
+		{Path: "app/main.py", Line: 4, Severity: review.SeverityCritical},
+		{Path: "app/main.py", Line: 22, Severity: review.SeverityCritical},
+		{Path: "app/new.py", Line: 1, Severity: review.SeverityWarning},
+		{Path: "app/committed.py", Line: 40, Severity: review.SeverityCritical},
+		{Path: "app/other.py", Line: 1, Severity: review.SeverityCritical},
+	}
+	lint.MarkNew(findings, changes)
+	want := []bool{true, false, true, true, false}
+	for i, f := range findings {
+		if f.New != want[i] {
+			t.Fatalf("finding %d: expected new=%t, got %+v", i, want[i], f)
+		}
+	}
+
+	var rep lint.Report
+	rep.Add(lint.ToolResult{Tool: lint.ToolRuff}, findings)
+	if rep.New != 3 || rep.Blocking != 2 || rep.Tools[0].Findings != 5 || len(rep.BlockingFindings()) != 2 {
+		t.Fatalf("unexpected report %+v", rep)
+	}
+}
+
+func TestParseTools(t *testing.T) {
+	tools, unknown := lint.ParseTools(" ruff, eslint,,pylint ")
+	if len(tools) != 2 || tools[0] != lint.ToolRuff || tools[1] != lint.ToolESLint || len(unknown) != 1 || unknown[0] != "pylint" {
+		t.Fatalf("unexpected tools %v, unknown %v", tools, unknown)
+	}
+}
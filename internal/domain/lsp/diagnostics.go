@@ -0,0 +1,136 @@
+package lsp
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ErrNewDiagnostics is returned when a delivery gated on diagnostics
+// introduces new errors.
+var ErrNewDiagnostics = errors.New("lsp: the delivery introduces new errors")
+
+// Severity is the severity of a diagnostic.
+type Severity string
+
+// Diagnostic severities, from the LSP DiagnosticSeverity values 1 to 4.
+const (
+	SeverityError       Severity = "error"
+	SeverityWarning     Severity = "warning"
+	SeverityInformation Severity = "information"
+	SeverityHint        Severity = "hint"
+)
+
+// Diagnostic is an error, warning or hint a language server reports for a
+// file.
+type Diagnostic struct {
+	Path     string   `json:"path"`
+	Range    Range    `json:"range"`
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code,omitempty"`
+	Source   string   `json:"source,omitempty"` // Tool of the server reporting it, e.g. "compiler"
+	Message  string   `json:"message"`
+}
+
+// NewDiagnostics returns the diagnostics of current that baseline does not
+// have. Diagnostics are compared by path, severity, code, source and
+// message but not by range, as edits move the unchanged ones; a message
+// reported more often than in the baseline counts as new that many times.
+func NewDiagnostics(baseline, current []Diagnostic) []Diagnostic {
+	type key struct {
+		path, code, source, message string
+		severity                    Severity
+	}
+	keyOf := func(d *Diagnostic) key {
+		return key{d.Path, d.Code, d.Source, d.Message, d.Severity}
+	}
+	seen := make(map[key]int, len(baseline))
+	for i := range baseline {
+		seen[keyOf(&baseline[i])]++
+	}
+	var out []Diagnostic
+	for i := range current {
+		k := keyOf(&current[i])
+		if seen[k] > 0 {
+			seen[k]--
+			continue
+		}
+		out = append(out, current[i])
+	}
+	return out
+}
+
+// Errors returns the diagnostics of error severity.
+func Errors(diags []Diagnostic) []Diagnostic {
+	var out []Diagnostic
+	for i := range diags {
+		if diags[i].Severity == SeverityError {
+			out = append(out, diags[i])
+		}
+	}
+	return out
+}
+
+// ConfigDiagnosticsGate is the project config key of the diagnostics gate
+// of deliveries: comma-separated branch=action rules, e.g.
+// "main=fail,release/*=draft". Before a delivery pushes to or opens a pull
+// request against a branch matching a rule (path.Match patterns, the first
+// matching rule applies), the changed files are checked with the language
+// servers, and new errors take the rule's action.
+const ConfigDiagnosticsGate = "lsp_diagnostics_gate"
+
+// GateAction is what a delivery does when it introduces new errors.
+type GateAction string
+
+const (
+	// GateFail fails the delivery.
+	GateFail GateAction = "fail"
+	// GateDraft opens the pull request as a draft. Deliveries without a
+	// pull request fail.
+	GateDraft GateAction = "draft"
+)
+
+// GateRule is the action of the diagnostics gate for the target branches
+// matching Branch.
+type GateRule struct {
+	Branch string     `json:"branch"`
+	Action GateAction `json:"action"`
+}
+
+// ParseGateRules parses the ConfigDiagnosticsGate value. Empty yields no
+// rules.
+func ParseGateRules(s string) ([]GateRule, error) {
+	var rules []GateRule
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		branch, action, ok := strings.Cut(part, "=")
+		branch, action = strings.TrimSpace(branch), strings.TrimSpace(action)
+		if !ok || branch == "" {
+			return nil, fmt.Errorf("%s: rule %q is not branch=action", ConfigDiagnosticsGate, part)
+		}
+		if _, err := path.Match(branch, ""); err != nil {
+			return nil, fmt.Errorf("%s: invalid branch pattern %q", ConfigDiagnosticsGate, branch)
+		}
+		switch a := GateAction(action); a {
+		case GateFail, GateDraft:
+			rules = append(rules, GateRule{Branch: branch, Action: a})
+		default:
+			return nil, fmt.Errorf("%s: unknown action %q, want fail or draft", ConfigDiagnosticsGate, action)
+		}
+	}
+	return rules, nil
+}
+
+// GateFor returns the rule applying to deliveries to branch, or nil.
+func GateFor(rules []GateRule, branch string) *GateRule {
+	for i := range rules {
+		if ok, _ := path.Match(rules[i].Branch, branch); ok {
+			return &rules[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package lsp_test
+
+import (
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/lsp"
+)
+
+func TestNewDiagnostics(t *testing.T) {
+	diag := func(line int, msg string) lsp.Diagnostic {
+		return lsp.Diagnostic{Path: "invoice.go", Range: lsp.Range{Start: lsp.Position{Line: line, Column: 1}}, Severity: lsp.SeverityError, Message: msg}
+	}
+	baseline := []lsp.Diagnostic{diag(3, "undefined: tax"), diag(9, "unused variable")}
+	current := []lsp.Diagnostic{
+		diag(5, "undefined: tax"), // Moved by the edit, not new
+		diag(7, "unused variable"),
+		diag(12, "unused variable"), // Once more than in the baseline
+		diag(14, "missing return"),
+	}
+
+	got := lsp.NewDiagnostics(baseline, current)
+	if len(got) != 2 || got[0].Range.Start.Line != 12 || got[1].Message != "missing return" {
+		t.Fatalf("NewDiagnostics = %+v", got)
+	}
+	if got := lsp.NewDiagnostics(current, baseline); len(got) != 0 {
+		t.Fatalf("expected no new diagnostics for fixes, got %+v", got)
+	}
+
+	warning := diag(1, "deprecated")
+	warning.Severity = lsp.SeverityWarning
+	if errs := lsp.Errors(append(got, warning)); len(errs) != 2 {
+		t.Fatalf("Errors = %+v", errs)
+	}
+}
+
+func TestGateRules(t *testing.T) {
+	rules, err := lsp.ParseGateRules(" main=fail, release/*=draft ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		branch string
+		want   lsp.GateAction
+	}{
+		{"main", lsp.GateFail},
+		{"release/1.2", lsp.GateDraft},
+		{"release/1.2/hotfix", ""},
+		{"feature/x", ""},
+	}
+	for _, tt := range tests {
+		var got lsp.GateAction
+		if r := lsp.GateFor(rules, tt.branch); r != nil {
+			got = r.Action
+		}
+		if got != tt.want {
+			t.Errorf("GateFor(%q) = %q, want %q", tt.branch, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"main", "=fail", "main=warn", "[=fail"} {
+		if _, err := lsp.ParseGateRules(bad); err == nil {
+			t.Errorf("ParseGateRules(%q): expected an error", bad)
+		}
+	}
+	if rules, err := lsp.ParseGateRules(""); err != nil || rules != nil {
+		t.Errorf("ParseGateRules(\"\") = %v, %v", rules, err)
+	}
+}
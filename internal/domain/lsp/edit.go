@@ -0,0 +1,77 @@
+package lsp
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"unicode/utf8"
+)
+
+// ApplyEdits applies edits to the content of a file. The edits must not
+// overlap; edits inserting at the same position are applied in order.
+func ApplyEdits(content []byte, edits []TextEdit) ([]byte, error) {
+	type span struct {
+		start, end int
+		text       string
+	}
+	lines := lineStarts(content)
+	spans := make([]span, 0, len(edits))
+	for i := range edits {
+		start, err := offset(content, lines, edits[i].Range.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := offset(content, lines, edits[i].Range.End)
+		if err != nil {
+			return nil, err
+		}
+		if end < start {
+			return nil, fmt.Errorf("%w: range ends before it starts", ErrInvalidEdit)
+		}
+		spans = append(spans, span{start, end, edits[i].NewText})
+	}
+	slices.SortStableFunc(spans, func(a, b span) int { return a.start - b.start })
+
+	var out bytes.Buffer
+	last := 0
+	for _, s := range spans {
+		if s.start < last {
+			return nil, fmt.Errorf("%w: edits overlap", ErrInvalidEdit)
+		}
+		out.Write(content[last:s.start])
+		out.WriteString(s.text)
+		last = s.end
+	}
+	out.Write(content[last:])
+	return out.Bytes(), nil
+}
+
+// lineStarts returns the byte offset of the start of every line.
+func lineStarts(content []byte) []int {
+	starts := []int{0}
+	for i, b := range content {
+		if b == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// offset converts a position to a byte offset. Columns past the end of a
+// line mean its end, as in the protocol.
+func offset(content []byte, lines []int, p Position) (int, error) {
+	if p.Line < 1 || p.Column < 1 || p.Line > len(lines) {
+		return 0, fmt.Errorf("%w: position %d:%d is outside the file", ErrInvalidEdit, p.Line, p.Column)
+	}
+	i := lines[p.Line-1]
+	for units := p.Column - 1; units > 0 && i < len(content) && content[i] != '\n'; {
+		r, size := utf8.DecodeRune(content[i:])
+		if r >= 0x10000 {
+			units -= 2
+		} else {
+			units--
+		}
+		i += size
+	}
+	return i, nil
+}
@@ -0,0 +1,79 @@
+package lsp_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/lsp"
+)
+
+func edit(l1, c1, l2, c2 int, text string) lsp.TextEdit {
+	return lsp.TextEdit{Range: lsp.Range{Start: lsp.Position{Line: l1, Column: c1}, End: lsp.Position{Line: l2, Column: c2}}, NewText: text}
+}
+
+func TestApplyEdits(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		edits   []lsp.TextEdit
+		want    string
+		err     error
+	}{
+		{"replace", "func total() {}\n", []lsp.TextEdit{edit(1, 6, 1, 11, "sum")}, "func sum() {}\n", nil},
+		{"several lines", "a\nb\nc\n", []lsp.TextEdit{edit(3, 1, 3, 2, "z"), edit(1, 1, 1, 2, "x")}, "x\nb\nz\n", nil},
+		{"delete lines", "a\nb\nc\n", []lsp.TextEdit{edit(1, 2, 3, 1, "\n")}, "a\nc\n", nil},
+		{"inserts in order", "ab", []lsp.TextEdit{edit(1, 2, 1, 2, "1"), edit(1, 2, 1, 2, "2")}, "a12b", nil},
+		{"append at end", "a\n", []lsp.TextEdit{edit(2, 1, 2, 1, "b\n")}, "a\nb\n", nil},
+		{"column past end", "ab\ncd\n", []lsp.TextEdit{edit(1, 1, 1, 99, "x")}, "x\ncd\n", nil},
+		{"utf-16 columns", "s := \"€😀\" + x\n", []lsp.TextEdit{edit(1, 14, 1, 15, "y")}, "s := \"€😀\" + y\n", nil},
+		{"no edits", "a\n", nil, "a\n", nil},
+		{"overlap", "abcdef", []lsp.TextEdit{edit(1, 1, 1, 4, "x"), edit(1, 3, 1, 5, "y")}, "", lsp.ErrInvalidEdit},
+		{"outside file", "a\n", []lsp.TextEdit{edit(5, 1, 5, 1, "x")}, "", lsp.ErrInvalidEdit},
+		{"reversed range", "abc", []lsp.TextEdit{edit(1, 3, 1, 1, "x")}, "", lsp.ErrInvalidEdit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lsp.ApplyEdits([]byte(tt.content), tt.edits)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		req  interface{ Validate() error }
+		ok   bool
+	}{
+		{"position", &lsp.PositionRequest{Path: "main.go", Line: 3, Column: 6}, true},
+		{"zero column", &lsp.PositionRequest{Path: "main.go", Line: 3}, false},
+		{"absolute path", &lsp.PositionRequest{Path: "/etc/passwd", Line: 1, Column: 1}, false},
+		{"escaping path", &lsp.FormatRequest{Path: "../other/main.go"}, false},
+		{"rename", &lsp.RenameRequest{PositionRequest: lsp.PositionRequest{Path: "a.go", Line: 1, Column: 1}, NewName: "sum"}, true},
+		{"rename without name", &lsp.RenameRequest{PositionRequest: lsp.PositionRequest{Path: "a.go", Line: 1, Column: 1}, NewName: " "}, false},
+		{"code actions without range", &lsp.CodeActionRequest{Path: "a.go"}, true},
+		{"code actions reversed", &lsp.CodeActionRequest{Path: "a.go", Range: lsp.Range{Start: lsp.Position{Line: 2, Column: 1}, End: lsp.Position{Line: 1, Column: 1}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if tt.ok && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, lsp.ErrInvalidRequest) {
+				t.Fatalf("error = %v, want ErrInvalidRequest", err)
+			}
+		})
+	}
+}
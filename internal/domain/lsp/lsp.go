@@ -0,0 +1,229 @@
+// Package lsp defines the code intelligence CodeForge gets from language
+// servers for project workspaces: definitions, references, hovers and
+// symbols, the edits of renames, code actions and formatting, which are
+// previewed or applied to the workspace, and the diagnostics that can gate
+// deliveries.
+package lsp
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrDisabled is returned when the lsp feature flag is off for the
+	// project.
+	ErrDisabled = errors.New("lsp is disabled for this project")
+	// ErrNoServer is returned for files of a language without a
+	// configured language server.
+	ErrNoServer = errors.New("no language server for this file")
+	// ErrInvalidRequest is returned for requests without a valid path or
+	// position.
+	ErrInvalidRequest = errors.New("invalid lsp request")
+	// ErrInvalidEdit is returned for edits outside the file or
+	// overlapping each other.
+	ErrInvalidEdit = errors.New("invalid text edit")
+	// ErrEditDenied is returned when the policy profile does not allow an
+	// edit of a file.
+	ErrEditDenied = errors.New("edit denied by policy")
+	// ErrActionNotFound is returned when no code action has the requested
+	// title.
+	ErrActionNotFound = errors.New("code action not found")
+)
+
+// PolicyTool is the policy engine tool of the edits. They are evaluated
+// per file with the operation (OpRename, OpCodeAction, OpFormat) as
+// command, so the rules for "Edit" apply to them and a sub-pattern such as
+// "lsp rename" targets one operation.
+const PolicyTool = "Edit"
+
+// Operations that edit files, as Command of their policy tool calls.
+const (
+	OpRename     = "lsp rename"
+	OpCodeAction = "lsp code-action"
+	OpFormat     = "lsp format"
+)
+
+// Position is a place in a file. Line and Column are 1-based; Column
+// counts UTF-16 code units, as language servers do.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Range is the text between two positions, End exclusive.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location is a range in a file. Path is relative to the workspace, or
+// absolute for files outside it such as dependencies.
+type Location struct {
+	Path  string `json:"path"`
+	Range Range  `json:"range"`
+}
+
+// Symbol is a declaration of a file.
+type Symbol struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`                // "function", "method", "class", ...
+	Container string `json:"container,omitempty"` // Enclosing symbol, e.g. the type of a method
+	Range     Range  `json:"range"`
+}
+
+// Hover is the documentation of the symbol at a position.
+type Hover struct {
+	Contents string `json:"contents"` // Markdown or plain text
+	Range    *Range `json:"range,omitempty"`
+}
+
+// TextEdit replaces a range of a file with NewText.
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"new_text"`
+}
+
+// FileEdit are the edits of one file.
+type FileEdit struct {
+	Path  string     `json:"path"`
+	Edits []TextEdit `json:"edits"`
+}
+
+// WorkspaceEdit is a change to the files of a workspace.
+type WorkspaceEdit struct {
+	Files []FileEdit `json:"files"`
+}
+
+// Paths returns the files the edit changes.
+func (e *WorkspaceEdit) Paths() []string {
+	paths := make([]string, 0, len(e.Files))
+	for i := range e.Files {
+		paths = append(paths, e.Files[i].Path)
+	}
+	return paths
+}
+
+// CodeAction is a fix or refactoring a language server offers for a range,
+// such as organizing imports. Actions without an edit only run a server
+// command and cannot be applied.
+type CodeAction struct {
+	Title     string         `json:"title"`
+	Kind      string         `json:"kind,omitempty"` // "quickfix", "refactor.extract", "source.organizeImports", ...
+	Preferred bool           `json:"preferred,omitempty"`
+	Edit      *WorkspaceEdit `json:"edit,omitempty"`
+}
+
+// EditResult is the edit of a rename, code action or formatting, and
+// whether it was written to the workspace.
+type EditResult struct {
+	Edit    WorkspaceEdit `json:"edit"`
+	Applied bool          `json:"applied"`
+}
+
+// PositionRequest asks about the symbol at a position of a file.
+type PositionRequest struct {
+	Path   string `json:"path"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// Position returns the position of the request.
+func (r *PositionRequest) Position() Position {
+	return Position{Line: r.Line, Column: r.Column}
+}
+
+// Validate checks the path and position.
+func (r *PositionRequest) Validate() error {
+	if err := validatePath(r.Path); err != nil {
+		return err
+	}
+	return r.Position().validate()
+}
+
+// RenameRequest renames the symbol at a position across the workspace. The
+// edit is only returned unless Apply is set.
+type RenameRequest struct {
+	PositionRequest
+	NewName       string `json:"new_name"`
+	Apply         bool   `json:"apply"`
+	PolicyProfile string `json:"policy_profile,omitempty"` // Profile the edit is checked against; empty uses the default
+}
+
+// Validate checks the position and new name.
+func (r *RenameRequest) Validate() error {
+	if err := r.PositionRequest.Validate(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(r.NewName) == "" {
+		return fmt.Errorf("%w: new_name is required", ErrInvalidRequest)
+	}
+	return nil
+}
+
+// CodeActionRequest lists the code actions for a range of a file, or
+// applies the one titled Title.
+type CodeActionRequest struct {
+	Path          string `json:"path"`
+	Range         Range  `json:"range"`
+	Title         string `json:"title,omitempty"` // Action to apply
+	PolicyProfile string `json:"policy_profile,omitempty"`
+}
+
+// Validate checks the path and range. A zero range is the whole first
+// line.
+func (r *CodeActionRequest) Validate() error {
+	if err := validatePath(r.Path); err != nil {
+		return err
+	}
+	if r.Range == (Range{}) {
+		r.Range = Range{Start: Position{Line: 1, Column: 1}, End: Position{Line: 2, Column: 1}}
+	}
+	if err := r.Range.Start.validate(); err != nil {
+		return err
+	}
+	if err := r.Range.End.validate(); err != nil {
+		return err
+	}
+	if r.Range.End.before(r.Range.Start) {
+		return fmt.Errorf("%w: range ends before it starts", ErrInvalidRequest)
+	}
+	return nil
+}
+
+// FormatRequest formats a file. The edits are only returned unless Apply
+// is set.
+type FormatRequest struct {
+	Path          string `json:"path"`
+	Apply         bool   `json:"apply"`
+	PolicyProfile string `json:"policy_profile,omitempty"`
+}
+
+// Validate checks the path.
+func (r *FormatRequest) Validate() error {
+	return validatePath(r.Path)
+}
+
+func (p Position) validate() error {
+	if p.Line < 1 || p.Column < 1 {
+		return fmt.Errorf("%w: line and column are 1-based", ErrInvalidRequest)
+	}
+	return nil
+}
+
+func (p Position) before(q Position) bool {
+	return p.Line < q.Line || p.Line == q.Line && p.Column < q.Column
+}
+
+// validatePath checks that p names a file inside the workspace.
+func validatePath(p string) error {
+	if p == "" {
+		return fmt.Errorf("%w: path is required", ErrInvalidRequest)
+	}
+	if !filepath.IsLocal(p) {
+		return fmt.Errorf("%w: path must be relative to the workspace", ErrInvalidRequest)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// Package mcpserver defines external MCP servers registered with CodeForge,
+// such as vendor-hosted tool servers, and how to authenticate to them.
+package mcpserver
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Transport is how CodeForge talks to an MCP server.
+type Transport string
+
+// TransportStreamableHTTP is the Streamable HTTP transport: JSON-RPC
+// messages are POSTed to one endpoint, which replies with JSON or an SSE
+// stream.
+const TransportStreamableHTTP Transport = "streamable_http"
+
+// AuthType is how requests to an MCP server are authorized.
+type AuthType string
+
+const (
+	AuthNone AuthType = "none"
+	// AuthOAuth2 obtains access tokens with the OAuth 2.0 client
+	// credentials grant and refreshes them before they expire.
+	AuthOAuth2 AuthType = "oauth2"
+)
+
+// MaxScopes limits the OAuth scopes of a server.
+const MaxScopes = 50
+
+var (
+	ErrNameRequired     = errors.New("name is required")
+	ErrInvalidURL       = errors.New("url must be an absolute http or https URL")
+	ErrInvalidTransport = errors.New("transport must be streamable_http")
+	ErrInvalidAuth      = errors.New("auth must be none or oauth2")
+	ErrOAuthIncomplete  = errors.New("oauth2 needs oauth.token_url, oauth.client_id and client_secret")
+	ErrTooManyScopes    = errors.New("too many scopes (max 50)")
+	ErrToolRequired     = errors.New("tool name is required")
+)
+
+// Server is a registered MCP server. The OAuth client secret and tokens
+// are stored encrypted and never returned by the API.
+type Server struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id,omitempty"`
+	Name      string    `json:"name"` // Unique within the tenant
+	URL       string    `json:"url"`  // Endpoint of the Streamable HTTP transport
+	Transport Transport `json:"transport"`
+	Auth      AuthType  `json:"auth"`
+	OAuth     *OAuth    `json:"oauth,omitempty"`
+	Enabled   bool      `json:"enabled"`
+
+	SecretCiphertext []byte     `json:"-"` // OAuth client secret
+	TokenCiphertext  []byte     `json:"-"` // JSON-encoded Token
+	TokenExpiresAt   *time.Time `json:"token_expires_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OAuth is the OAuth 2.0 client of a server.
+type OAuth struct {
+	TokenURL string   `json:"token_url"`
+	ClientID string   `json:"client_id"`
+	Scopes   []string `json:"scopes,omitempty"`   // Requested for this server only
+	Audience string   `json:"audience,omitempty"` // Sent as the audience parameter by providers that need it
+}
+
+// Token is an OAuth access token with its refresh token, if the
+// authorization server issued one.
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitzero"` // Zero if the server gave no lifetime
+}
+
+// Valid reports whether the token can still be used at now, leaving
+// leeway for the request to reach the server.
+func (t *Token) Valid(now time.Time, leeway time.Duration) bool {
+	return t != nil && t.AccessToken != "" && (t.ExpiresAt.IsZero() || now.Add(leeway).Before(t.ExpiresAt))
+}
+
+// CreateRequest registers a server. Updates replace all fields; an empty
+// client_secret keeps the stored one.
+type CreateRequest struct {
+	Name         string    `json:"name"`
+	URL          string    `json:"url"`
+	Transport    Transport `json:"transport,omitempty"` // Default: streamable_http
+	Auth         AuthType  `json:"auth,omitempty"`      // Default: none
+	OAuth        *OAuth    `json:"oauth,omitempty"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+	Enabled      *bool     `json:"enabled,omitempty"` // Default: true
+}
+
+// Validate checks the request and fills in defaults. hasSecret reports
+// whether a client secret is already stored.
+func (r *CreateRequest) Validate(hasSecret bool) error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return ErrNameRequired
+	}
+	if u, err := url.Parse(r.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL
+	}
+	if r.Transport == "" {
+		r.Transport = TransportStreamableHTTP
+	}
+	if r.Transport != TransportStreamableHTTP {
+		return ErrInvalidTransport
+	}
+	switch r.Auth {
+	case "":
+		r.Auth = AuthNone
+	case AuthNone, AuthOAuth2:
+	default:
+		return ErrInvalidAuth
+	}
+	if r.Auth == AuthNone {
+		r.OAuth, r.ClientSecret = nil, ""
+		return nil
+	}
+	o := r.OAuth
+	if o == nil || o.ClientID == "" || (r.ClientSecret == "" && !hasSecret) {
+		return ErrOAuthIncomplete
+	}
+	if u, err := url.Parse(o.TokenURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrOAuthIncomplete
+	}
+	if len(o.Scopes) > MaxScopes {
+		return ErrTooManyScopes
+	}
+	return nil
+}
+
+// Apply sets the fields of s from the request. The client secret is
+// encrypted by the service.
+func (r *CreateRequest) Apply(s *Server) {
+	s.Name, s.URL, s.Transport, s.Auth, s.OAuth = r.Name, r.URL, r.Transport, r.Auth, r.OAuth
+	s.Enabled = r.Enabled == nil || *r.Enabled
+}
+
+// Tool is a tool offered by an MCP server.
+type Tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"inputSchema,omitempty"`
+}
+
+// CallRequest calls a tool of a server.
+type CallRequest struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+}
+
+// CallResult is the result of a tool call: content blocks as returned by
+// the server, and whether the tool reported an error.
+type CallResult struct {
+	Content           []map[string]any `json:"content"`
+	StructuredContent any              `json:"structuredContent,omitempty"`
+	IsError           bool             `json:"isError,omitempty"`
+}
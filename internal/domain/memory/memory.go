@@ -0,0 +1,105 @@
+// Package memory defines project memories: short facts, decisions and
+// lessons stored with an embedding, and their recall by composite score
+// (semantic similarity, recency and importance).
+package memory
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+)
+
+var (
+	// ErrContentRequired is returned for a memory or recall without text.
+	ErrContentRequired = errors.New("content is required")
+	// ErrInvalidImportance is returned for an importance outside [0, 1].
+	ErrInvalidImportance = errors.New("importance must be between 0 and 1")
+)
+
+// Memory is a piece of project knowledge recalled into agent context.
+type Memory struct {
+	ID         string    `json:"id"`
+	ProjectID  string    `json:"project_id"`
+	Content    string    `json:"content"`
+	Source     string    `json:"source,omitempty"` // e.g. "user" or "run:<id>"
+	Importance float64   `json:"importance"`       // 0-1, weighted at recall
+	Provider   string    `json:"provider"`         // Embedding provider and model of Embedding
+	Model      string    `json:"model"`
+	Embedding  []float32 `json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CreateRequest holds the fields for storing a memory.
+type CreateRequest struct {
+	Content    string   `json:"content"`
+	Source     string   `json:"source,omitempty"`
+	Importance *float64 `json:"importance,omitempty"` // Default: 0.5
+}
+
+// Validate checks the content and importance.
+func (r *CreateRequest) Validate() error {
+	if strings.TrimSpace(r.Content) == "" {
+		return ErrContentRequired
+	}
+	if r.Importance != nil && (*r.Importance < 0 || *r.Importance > 1) {
+		return ErrInvalidImportance
+	}
+	return nil
+}
+
+// RecallRequest asks for the memories of a project matching a query.
+type RecallRequest struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit,omitempty"` // Max memories; 0 uses the configured limit
+}
+
+// Recalled is a memory with the composite score it was recalled with.
+type Recalled struct {
+	Memory
+	Score float64 `json:"score"`
+}
+
+// Weights configure the composite recall score. HalfLife is the age at
+// which the recency factor drops to 0.5.
+type Weights struct {
+	Semantic   float64
+	Recency    float64
+	Importance float64
+	HalfLife   time.Duration
+}
+
+// Score returns the composite score of m for a query vector at now.
+func (w Weights) Score(m *Memory, query []float32, now time.Time) float64 {
+	recency := 1.0
+	if w.HalfLife > 0 {
+		age := max(now.Sub(m.CreatedAt), 0)
+		recency = math.Exp2(-float64(age) / float64(w.HalfLife))
+	}
+	return w.Semantic*retrieval.Cosine(query, m.Embedding) + w.Recency*recency + w.Importance*m.Importance
+}
+
+// Recall scores memories against a query vector and returns the best
+// limit with a score of at least minScore. Memories embedded with a
+// different provider, model or size are skipped, since their vectors are
+// not comparable.
+func Recall(memories []Memory, e retrieval.Embedding, query []float32, w Weights, now time.Time, limit int, minScore float64) []Recalled {
+	var out []Recalled
+	for i := range memories {
+		m := &memories[i]
+		if m.Provider != e.Provider || m.Model != e.Model || len(m.Embedding) != len(query) {
+			continue
+		}
+		if s := w.Score(m, query, now); s >= minScore {
+			out = append(out, Recalled{Memory: *m, Score: s})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package memory_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/memory"
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+)
+
+func TestCreateRequestValidate(t *testing.T) {
+	high := 1.5
+	for _, tt := range []struct {
+		req  memory.CreateRequest
+		want error
+	}{
+		{memory.CreateRequest{Content: "use pgx"}, nil},
+		{memory.CreateRequest{Content: " "}, memory.ErrContentRequired},
+		{memory.CreateRequest{Content: "x", Importance: &high}, memory.ErrInvalidImportance},
+	} {
+		if err := tt.req.Validate(); err != tt.want {
+			t.Errorf("Validate(%+v) = %v, want %v", tt.req, err, tt.want)
+		}
+	}
+}
+
+func TestWeightsScore(t *testing.T) {
+	now := time.Now()
+	w := memory.Weights{Semantic: 0.5, Recency: 0.3, Importance: 0.2, HalfLife: 24 * time.Hour}
+	m := &memory.Memory{Embedding: []float32{1, 0}, Importance: 0.5, CreatedAt: now.Add(-24 * time.Hour)}
+
+	// 0.5*1 + 0.3*0.5 + 0.2*0.5
+	if got := w.Score(m, []float32{1, 0}, now); math.Abs(got-0.75) > 1e-9 {
+		t.Fatalf("expected 0.75, got %f", got)
+	}
+}
+
+func TestRecall(t *testing.T) {
+	now := time.Now()
+	e := retrieval.Embedding{Provider: "ollama", Model: "nomic"}
+	w := memory.Weights{Semantic: 1}
+	memories := []memory.Memory{
+		{ID: "close", Provider: "ollama", Model: "nomic", Embedding: []float32{1, 0.1}},
+		{ID: "far", Provider: "ollama", Model: "nomic", Embedding: []float32{0, 1}},
+		{ID: "closest", Provider: "ollama", Model: "nomic", Embedding: []float32{1, 0}},
+		{ID: "other-model", Provider: "litellm", Model: "nomic", Embedding: []float32{1, 0}},
+	}
+
+	got := memory.Recall(memories, e, []float32{1, 0}, w, now, 5, 0.5)
+	if len(got) != 2 || got[0].ID != "closest" || got[1].ID != "close" {
+		t.Fatalf("unexpected recall %+v", got)
+	}
+	if got := memory.Recall(memories, e, []float32{1, 0}, w, now, 1, 0); len(got) != 1 {
+		t.Fatalf("expected limit 1, got %d", len(got))
+	}
+}
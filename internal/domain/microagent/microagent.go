@@ -0,0 +1,215 @@
+// Package microagent defines microagents: small pieces of project
+// knowledge that activate on triggers — changed files, prompt keywords or
+// event types — and are then injected into the prompt of runs and
+// conversations.
+package microagent
+
+import (
+	"errors"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNameRequired is returned for a microagent without a name.
+	ErrNameRequired = errors.New("name is required")
+	// ErrKnowledgeRequired is returned for a microagent without knowledge.
+	ErrKnowledgeRequired = errors.New("knowledge is required")
+	// ErrNoTrigger is returned for a microagent that could never activate.
+	ErrNoTrigger = errors.New("trigger needs at least one file pattern, keyword or event")
+	// ErrInvalidPattern is returned for malformed file patterns.
+	ErrInvalidPattern = errors.New("invalid file pattern")
+)
+
+// Microagent is a piece of knowledge added to prompts its trigger matches.
+type Microagent struct {
+	ID          string    `json:"id"`
+	ProjectID   string    `json:"project_id"`
+	Name        string    `json:"name"` // Unique per project
+	Description string    `json:"description,omitempty"`
+	Knowledge   string    `json:"knowledge"` // Text injected into the prompt
+	Trigger     Trigger   `json:"trigger"`
+	Enabled     bool      `json:"enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Trigger holds the activation conditions of a microagent. Any matching
+// condition activates it.
+type Trigger struct {
+	FilePatterns []string `json:"file_patterns,omitempty"` // Globs on changed files; "**" spans directories, patterns without "/" match base names
+	Keywords     []string `json:"keywords,omitempty"`      // Case-insensitive words or phrases in the prompt
+	Events       []string `json:"events,omitempty"`        // Event types that started the run, e.g. "pm.issue.created"
+}
+
+// CreateRequest holds the fields of a microagent. Updates replace all
+// fields with those of a request.
+type CreateRequest struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Knowledge   string  `json:"knowledge"`
+	Trigger     Trigger `json:"trigger"`
+	Enabled     *bool   `json:"enabled,omitempty"` // Default: true
+}
+
+// Validate checks the name, knowledge and trigger.
+func (r *CreateRequest) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return ErrNameRequired
+	}
+	if strings.TrimSpace(r.Knowledge) == "" {
+		return ErrKnowledgeRequired
+	}
+	t := &r.Trigger
+	if len(t.FilePatterns) == 0 && len(t.Keywords) == 0 && len(t.Events) == 0 {
+		return ErrNoTrigger
+	}
+	for _, p := range t.FilePatterns {
+		if _, err := path.Match(strings.ReplaceAll(p, "**", "*"), ""); err != nil || p == "" {
+			return ErrInvalidPattern
+		}
+	}
+	return nil
+}
+
+// Apply sets the fields of m from the request.
+func (r *CreateRequest) Apply(m *Microagent) {
+	m.Name = strings.TrimSpace(r.Name)
+	m.Description = r.Description
+	m.Knowledge = r.Knowledge
+	m.Trigger = r.Trigger
+	m.Enabled = r.Enabled == nil || *r.Enabled
+}
+
+// Input is what a run or conversation offers for activation.
+type Input struct {
+	Prompt string
+	Files  []string // Changed files, relative to the workspace
+	Event  string
+}
+
+// Activation records why a microagent activated.
+type Activation struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"` // "file:<path>", "keyword:<keyword>" or "event:<type>"
+}
+
+// Match reports whether the trigger matches the input and why. Events are
+// checked first, then keywords, then files.
+func (t *Trigger) Match(in *Input) (string, bool) {
+	if in.Event != "" {
+		for _, e := range t.Events {
+			if e == in.Event {
+				return "event:" + e, true
+			}
+		}
+	}
+	if in.Prompt != "" {
+		prompt := strings.ToLower(in.Prompt)
+		for _, k := range t.Keywords {
+			if k = strings.ToLower(strings.TrimSpace(k)); k != "" && containsWord(prompt, k) {
+				return "keyword:" + k, true
+			}
+		}
+	}
+	for _, f := range in.Files {
+		for _, p := range t.FilePatterns {
+			if MatchPath(p, f) {
+				return "file:" + f, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Activate returns the enabled microagents whose trigger matches the
+// input, ordered by name, with the reason each activated.
+func Activate(agents []Microagent, in *Input) ([]Microagent, []Activation) {
+	var matched []Microagent
+	var acts []Activation
+	for i := range agents {
+		m := &agents[i]
+		if !m.Enabled {
+			continue
+		}
+		if reason, ok := m.Trigger.Match(in); ok {
+			matched = append(matched, *m)
+			acts = append(acts, Activation{ID: m.ID, Name: m.Name, Reason: reason})
+		}
+	}
+	sort.SliceStable(acts, func(i, j int) bool { return acts[i].Name < acts[j].Name })
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].Name < matched[j].Name })
+	return matched, acts
+}
+
+// Render formats the knowledge of activated microagents for a prompt.
+func Render(agents []Microagent) string {
+	var b strings.Builder
+	for i := range agents {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString("### ")
+		b.WriteString(agents[i].Name)
+		b.WriteString("\n")
+		b.WriteString(strings.TrimSpace(agents[i].Knowledge))
+	}
+	return b.String()
+}
+
+// MatchPath matches a slash-separated file path against a glob. "**"
+// matches any number of directories; a pattern without "/" matches the
+// file's base name in any directory.
+func MatchPath(pattern, file string) bool {
+	file = strings.TrimPrefix(file, "./")
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(file))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(file, "/"))
+}
+
+func matchSegments(pat, val []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			for i := 0; i <= len(val); i++ {
+				if matchSegments(pat[1:], val[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(val) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], val[0]); !ok {
+			return false
+		}
+		pat, val = pat[1:], val[1:]
+	}
+	return len(val) == 0
+}
+
+// containsWord reports whether text contains word with non-letter
+// boundaries on both sides, so "test" does not match "latest".
+func containsWord(text, word string) bool {
+	for start := 0; ; {
+		i := strings.Index(text[start:], word)
+		if i < 0 {
+			return false
+		}
+		i += start
+		end := i + len(word)
+		if (i == 0 || !isWordByte(text[i-1])) && (end == len(text) || !isWordByte(text[end])) {
+			return true
+		}
+		start = i + 1
+	}
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 0x80
+}
@@ -0,0 +1,70 @@
+package microagent_test
+
+import (
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/microagent"
+)
+
+func TestCreateRequestValidate(t *testing.T) {
+	for _, tt := range []struct {
+		req  microagent.CreateRequest
+		want error
+	}{
+		{microagent.CreateRequest{Name: "sql", Knowledge: "x", Trigger: microagent.Trigger{FilePatterns: []string{"**/*.sql"}}}, nil},
+		{microagent.CreateRequest{Knowledge: "x", Trigger: microagent.Trigger{Keywords: []string{"a"}}}, microagent.ErrNameRequired},
+		{microagent.CreateRequest{Name: "sql", Trigger: microagent.Trigger{Keywords: []string{"a"}}}, microagent.ErrKnowledgeRequired},
+		{microagent.CreateRequest{Name: "sql", Knowledge: "x"}, microagent.ErrNoTrigger},
+		{microagent.CreateRequest{Name: "sql", Knowledge: "x", Trigger: microagent.Trigger{FilePatterns: []string{"[a"}}}, microagent.ErrInvalidPattern},
+	} {
+		if err := tt.req.Validate(); err != tt.want {
+			t.Errorf("Validate(%+v) = %v, want %v", tt.req, err, tt.want)
+		}
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	for _, tt := range []struct {
+		pattern, file string
+		want          bool
+	}{
+		{"*.sql", "internal/adapter/postgres/migrations/001.sql", true},
+		{"migrations/*.sql", "internal/migrations/001.sql", false},
+		{"**/migrations/*.sql", "internal/migrations/001.sql", true},
+		{"internal/**", "internal/a/b.go", true},
+		{"frontend/**/*.tsx", "frontend/src/App.tsx", true},
+		{"frontend/**/*.tsx", "backend/src/App.tsx", false},
+	} {
+		if got := microagent.MatchPath(tt.pattern, tt.file); got != tt.want {
+			t.Errorf("MatchPath(%q, %q) = %v, want %v", tt.pattern, tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestActivate(t *testing.T) {
+	agents := []microagent.Microagent{
+		{ID: "m1", Name: "migrations", Enabled: true, Trigger: microagent.Trigger{FilePatterns: []string{"*.sql"}}},
+		{ID: "m2", Name: "deploys", Enabled: true, Trigger: microagent.Trigger{Keywords: []string{"Deploy"}, Events: []string{"push"}}},
+		{ID: "m3", Name: "disabled", Trigger: microagent.Trigger{Keywords: []string{"deploy"}}},
+		{ID: "m4", Name: "tests", Enabled: true, Trigger: microagent.Trigger{Keywords: []string{"test"}}},
+	}
+
+	matched, acts := microagent.Activate(agents, &microagent.Input{
+		Prompt: "Deploy the latest build",
+		Files:  []string{"db/002_users.sql", "main.go"},
+	})
+	if len(matched) != 2 || len(acts) != 2 {
+		t.Fatalf("expected 2 activations, got %+v", acts)
+	}
+	if acts[0].Name != "deploys" || acts[0].Reason != "keyword:deploy" {
+		t.Fatalf("unexpected first activation %+v", acts[0])
+	}
+	if acts[1].Name != "migrations" || acts[1].Reason != "file:db/002_users.sql" {
+		t.Fatalf("unexpected second activation %+v", acts[1])
+	}
+
+	_, acts = microagent.Activate(agents, &microagent.Input{Event: "push"})
+	if len(acts) != 1 || acts[0].Reason != "event:push" {
+		t.Fatalf("expected the event to activate deploys, got %+v", acts)
+	}
+}
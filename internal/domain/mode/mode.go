@@ -13,6 +13,11 @@ type Mode struct {
 	LLMScenario  string   `json:"llm_scenario" yaml:"llm_scenario"`
 	Autonomy     int      `json:"autonomy" yaml:"autonomy"`
 	PromptPrefix string   `json:"prompt_prefix" yaml:"prompt_prefix"`
+	SkipMemories bool     `json:"skip_memories" yaml:"skip_memories"` // Do not recall project memories into context
+
+	// OutputSchema, if set, is the structured final answer runs in this
+	// mode must produce; the parsed value is stored on the run.
+	OutputSchema *OutputSchema `json:"output_schema,omitempty" yaml:"output_schema"`
 }
 
 // Validate checks that a Mode has all required fields and valid values.
@@ -26,5 +31,10 @@ func (m *Mode) Validate() error {
 	if m.Autonomy < 1 || m.Autonomy > 5 {
 		return fmt.Errorf("autonomy must be between 1 and 5, got %d", m.Autonomy)
 	}
+	if m.OutputSchema != nil {
+		if err := m.OutputSchema.check("output_schema"); err != nil {
+			return err
+		}
+	}
 	return nil
 }
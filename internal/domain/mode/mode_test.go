@@ -48,8 +48,8 @@ func TestValidate_AutonomyBoundaries(t *testing.T) {
 
 func TestBuiltinModes_Count(t *testing.T) {
 	modes := BuiltinModes()
-	if len(modes) != 8 {
-		t.Fatalf("expected 8 built-in modes, got %d", len(modes))
+	if len(modes) != 9 {
+		t.Fatalf("expected 9 built-in modes, got %d", len(modes))
 	}
 }
 
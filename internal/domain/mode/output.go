@@ -0,0 +1,255 @@
+package mode
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// MaxOutputRepairs is how often a run whose final output violates its
+// mode's output schema is sent back to the agent with a repair prompt
+// before it fails.
+const MaxOutputRepairs = 2
+
+// ErrNoStructuredOutput is returned when a run's output holds no JSON value.
+var ErrNoStructuredOutput = errors.New("output contains no JSON value")
+
+// Schema types of an OutputSchema.
+const (
+	TypeObject  = "object"
+	TypeArray   = "array"
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+	TypeNull    = "null"
+)
+
+// OutputSchema is the structured output a mode expects as the final answer
+// of its runs. It is the subset of JSON Schema that describes the shape of
+// a value: type, properties, required, items, enum and
+// additionalProperties. An empty type accepts any value.
+type OutputSchema struct {
+	Type                 string                   `json:"type,omitempty" yaml:"type"`
+	Description          string                   `json:"description,omitempty" yaml:"description"`
+	Properties           map[string]*OutputSchema `json:"properties,omitempty" yaml:"properties"`
+	Required             []string                 `json:"required,omitempty" yaml:"required"`
+	AdditionalProperties *bool                    `json:"additionalProperties,omitempty" yaml:"additionalProperties"` // false rejects properties not listed
+	Items                *OutputSchema            `json:"items,omitempty" yaml:"items"`
+	Enum                 []any                    `json:"enum,omitempty" yaml:"enum"`
+}
+
+// check reports the first malformed part of the schema.
+func (s *OutputSchema) check(path string) error {
+	switch s.Type {
+	case "", TypeObject, TypeArray, TypeString, TypeNumber, TypeInteger, TypeBoolean, TypeNull:
+	default:
+		return fmt.Errorf("%s: unknown type %q", path, s.Type)
+	}
+	if s.Type != TypeObject && (len(s.Properties) > 0 || len(s.Required) > 0 || s.AdditionalProperties != nil) {
+		return fmt.Errorf("%s: properties require type object", path)
+	}
+	if s.Type != TypeArray && s.Items != nil {
+		return fmt.Errorf("%s: items require type array", path)
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.Properties)) {
+		p := s.Properties[name]
+		if p == nil {
+			return fmt.Errorf("%s.%s: schema is empty", path, name)
+		}
+		if err := p.check(path + "." + name); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.check(path + "[]")
+	}
+	return nil
+}
+
+// Validate returns the ways value, as decoded by ParseOutput, violates the
+// schema, each prefixed with the JSON path of the offending value.
+func (s *OutputSchema) Validate(value any) []string {
+	var out []string
+	s.validate("$", value, &out)
+	return out
+}
+
+func (s *OutputSchema) validate(path string, v any, out *[]string) {
+	if s.Type != "" && !hasType(v, s.Type) {
+		*out = append(*out, fmt.Sprintf("%s: expected %s, got %s", path, s.Type, typeOf(v)))
+		return
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return sameValue(e, v) }) {
+		allowed, _ := json.Marshal(s.Enum)
+		*out = append(*out, fmt.Sprintf("%s: must be one of %s", path, allowed))
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*out = append(*out, fmt.Sprintf("%s: missing required property %q", path, name))
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(v)) {
+			if p, ok := s.Properties[name]; ok {
+				p.validate(path+"."+name, v[name], out)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				*out = append(*out, fmt.Sprintf("%s: unexpected property %q", path, name))
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, out)
+			}
+		}
+	}
+}
+
+// Instructions tells the agent how to format its final answer.
+func (s *OutputSchema) Instructions() string {
+	schema, _ := json.MarshalIndent(s, "", "  ")
+	return "## Output format\n\nEnd your work with a final answer that is a single JSON value " +
+		"in a ```json code block, matching this JSON schema:\n\n```json\n" + string(schema) + "\n```"
+}
+
+// RepairPrompt asks the agent to restate its final answer after it
+// violated the schema.
+func (s *OutputSchema) RepairPrompt(problems []string) string {
+	var b strings.Builder
+	b.WriteString("## Output repair\n\nYour previous final answer did not match the required output format:\n\n")
+	for _, p := range problems {
+		b.WriteString("- " + p + "\n")
+	}
+	b.WriteString("\nDo not redo the work. Reply with the corrected final answer only.\n\n")
+	b.WriteString(s.Instructions())
+	return b.String()
+}
+
+// ParseOutput extracts the structured answer from a run's final output:
+// the whole output if it is JSON, otherwise the last fenced code block
+// holding JSON, otherwise the text from the first brace or bracket to the
+// last one. It returns the value compacted and decoded, with numbers as
+// json.Number.
+func ParseOutput(output string) (json.RawMessage, any, error) {
+	candidates := []string{strings.TrimSpace(output)}
+	blocks := fencedBlocks(output)
+	for i := len(blocks) - 1; i >= 0; i-- {
+		candidates = append(candidates, blocks[i])
+	}
+	for _, delim := range [][2]string{{"{", "}"}, {"[", "]"}} {
+		if i, j := strings.Index(output, delim[0]), strings.LastIndex(output, delim[1]); i >= 0 && j > i {
+			candidates = append(candidates, output[i:j+1])
+		}
+	}
+
+	var lastErr error = ErrNoStructuredOutput
+	for _, c := range candidates {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		dec := json.NewDecoder(strings.NewReader(c))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			lastErr = fmt.Errorf("%w: %v", ErrNoStructuredOutput, err)
+			continue
+		}
+		if dec.More() {
+			lastErr = fmt.Errorf("%w: trailing data after JSON value", ErrNoStructuredOutput)
+			continue
+		}
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, []byte(c)); err != nil {
+			continue
+		}
+		return json.RawMessage(buf.Bytes()), v, nil
+	}
+	return nil, nil, lastErr
+}
+
+// fencedBlocks returns the contents of the Markdown code blocks in text
+// that are untagged or tagged json.
+func fencedBlocks(text string) []string {
+	var (
+		blocks []string
+		body   []string
+		inside bool
+		keep   bool
+	)
+	for _, line := range strings.Split(text, "\n") {
+		fence, ok := strings.CutPrefix(strings.TrimSpace(line), "```")
+		switch {
+		case ok && !inside:
+			inside, keep, body = true, fence == "" || strings.EqualFold(fence, "json"), nil
+		case ok && inside:
+			if keep {
+				blocks = append(blocks, strings.Join(body, "\n"))
+			}
+			inside = false
+		case inside:
+			body = append(body, line)
+		}
+	}
+	return blocks
+}
+
+func hasType(v any, typ string) bool {
+	switch typ {
+	case TypeInteger:
+		n, ok := v.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := n.Int64()
+		return err == nil
+	case TypeNumber:
+		_, ok := v.(json.Number)
+		return ok
+	default:
+		return typeOf(v) == typ
+	}
+}
+
+func typeOf(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return TypeObject
+	case []any:
+		return TypeArray
+	case string:
+		return TypeString
+	case json.Number, float64:
+		return TypeNumber
+	case bool:
+		return TypeBoolean
+	case nil:
+		return TypeNull
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// sameValue compares an enum entry with a decoded value; numbers compare
+// by value.
+func sameValue(a, b any) bool {
+	norm := func(v any) any {
+		if n, ok := v.(json.Number); ok {
+			f, _ := n.Float64()
+			return f
+		}
+		if i, ok := v.(int); ok { // enums read from YAML
+			return float64(i)
+		}
+		return v
+	}
+	x, _ := json.Marshal(norm(a))
+	y, _ := json.Marshal(norm(b))
+	return bytes.Equal(x, y)
+}
@@ -0,0 +1,95 @@
+package mode
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func reviewSchema() *OutputSchema {
+	no := false
+	return &OutputSchema{
+		Type:     TypeObject,
+		Required: []string{"verdict", "issues"},
+		Properties: map[string]*OutputSchema{
+			"verdict": {Type: TypeString, Enum: []any{"approved", "changes-requested"}},
+			"score":   {Type: TypeInteger},
+			"issues": {Type: TypeArray, Items: &OutputSchema{
+				Type:       TypeObject,
+				Required:   []string{"file"},
+				Properties: map[string]*OutputSchema{"file": {Type: TypeString}, "line": {Type: TypeInteger}},
+			}},
+		},
+		AdditionalProperties: &no,
+	}
+}
+
+func TestOutputSchema_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{name: "valid", output: `{"verdict": "approved", "score": 9, "issues": []}`},
+		{name: "violations", output: `{"verdict": "maybe", "score": 1.5, "issues": [{"line": "3"}], "extra": true}`, want: []string{
+			`$: unexpected property "extra"`,
+			`$.issues[0]: missing required property "file"`,
+			`$.issues[0].line: expected integer, got string`,
+			`$.score: expected integer, got number`,
+			`$.verdict: must be one of ["approved","changes-requested"]`,
+		}},
+		{name: "wrong type", output: `["approved"]`, want: []string{`$: expected object, got array`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, v, err := ParseOutput(tt.output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := reviewSchema().Validate(v)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{name: "plain", output: " {\"a\": 1}\n", want: `{"a":1}`},
+		{name: "last fenced block", output: "Done.\n```json\n{\"a\": 1}\n```\nFixed:\n```json\n{\"a\": 2}\n```\n", want: `{"a":2}`},
+		{name: "other blocks skipped", output: "```go\nfunc main() {}\n```\n```\n[1, 2]\n```", want: `[1,2]`},
+		{name: "embedded", output: "Result: {\"ok\": true} as requested", want: `{"ok":true}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, _, err := ParseOutput(tt.output)
+			if err != nil || string(raw) != tt.want {
+				t.Fatalf("got %s, %v; want %s", raw, err, tt.want)
+			}
+		})
+	}
+
+	if _, _, err := ParseOutput("All done, no JSON here."); !errors.Is(err, ErrNoStructuredOutput) {
+		t.Fatalf("expected ErrNoStructuredOutput, got %v", err)
+	}
+}
+
+func TestValidate_OutputSchema(t *testing.T) {
+	m := Mode{ID: "test", Name: "Test", Autonomy: 3, OutputSchema: reviewSchema()}
+	if err := m.Validate(); err != nil {
+		t.Fatalf("expected valid mode, got error: %v", err)
+	}
+	m.OutputSchema.Properties["score"] = &OutputSchema{Type: "float"}
+	if err := m.Validate(); err == nil {
+		t.Fatal("expected error for unknown type")
+	}
+	m.OutputSchema = &OutputSchema{Type: TypeString, Required: []string{"a"}}
+	if err := m.Validate(); err == nil {
+		t.Fatal("expected error for required on a string")
+	}
+}
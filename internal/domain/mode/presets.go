@@ -92,5 +92,16 @@ func BuiltinModes() []Mode {
 			PromptPrefix: "You are a security auditor. Identify vulnerabilities, insecure patterns, " +
 				"and compliance issues. Recommend concrete mitigations following OWASP guidelines.",
 		},
+		{
+			ID:          "researcher",
+			Name:        "Researcher",
+			Description: "Gathers external information via web search and summarizes sources for a follow-up task.",
+			Builtin:     true,
+			Tools:       []string{"Read", "Glob", "Grep", "WebSearch", "WebFetch"},
+			LLMScenario: "think",
+			Autonomy:    2,
+			PromptPrefix: "You are a technical researcher. Search for authoritative sources, " +
+				"cite every claim with its URL, and summarize findings concisely for the engineer who implements the task.",
+		},
 	}
 }
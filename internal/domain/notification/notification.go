@@ -0,0 +1,254 @@
+// Package notification defines how CodeForge notifies people about events
+// of a project, such as plan approvals and finished runs: per-project
+// routing rules send events of given types to a chat webhook with a list
+// of mentions or to email recipients, quiet hours hold notifications back, and a daily digest
+// batches low-priority events into one summary message.
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Kind is the type of system a rule notifies.
+type Kind string
+
+const (
+	// KindSlack posts to a Slack incoming webhook.
+	KindSlack Kind = "slack"
+	// KindDiscord posts to a Discord webhook.
+	KindDiscord Kind = "discord"
+	// KindTeams posts to a Microsoft Teams incoming webhook.
+	KindTeams Kind = "teams"
+	// KindEmail sends HTML email through the server's SMTP relay.
+	KindEmail Kind = "email"
+)
+
+// Config keys of rules.
+const (
+	ConfigWebhookURL    = "webhook_url"    // slack, discord, teams: incoming webhook URL
+	ConfigWebhookSecret = "webhook_secret" // slack, discord, teams: name of the project secret holding the webhook URL instead
+	ConfigTo            = "to"             // email: comma-separated recipient addresses
+	ConfigInteractive   = "interactive"    // slack: "true" adds Approve/Reject buttons to approval requests
+)
+
+// Config keys the server adds to the config of email rules from its
+// notify settings; rules cannot set them.
+const (
+	ConfigSMTPHost     = "smtp_host"
+	ConfigSMTPPort     = "smtp_port"
+	ConfigSMTPUsername = "smtp_username"
+	ConfigSMTPPassword = "smtp_password"
+	ConfigSMTPFrom     = "smtp_from"
+)
+
+// Priority orders notifications by urgency.
+type Priority string
+
+const (
+	// PriorityLow notifications go into the daily digest of rules with
+	// digest mode.
+	PriorityLow Priority = "low"
+	// PriorityNormal notifications are sent right away, or when the
+	// project's quiet hours end.
+	PriorityNormal Priority = "normal"
+	// PriorityHigh notifications are sent right away, even in quiet hours.
+	PriorityHigh Priority = "high"
+)
+
+// MaxNameLen limits the length of a rule name.
+const MaxNameLen = 100
+
+// configKeys are the config keys of each kind.
+var configKeys = map[Kind][]string{
+	KindSlack:   {ConfigWebhookURL, ConfigWebhookSecret, ConfigInteractive},
+	KindDiscord: {ConfigWebhookURL, ConfigWebhookSecret},
+	KindTeams:   {ConfigWebhookURL, ConfigWebhookSecret},
+	KindEmail:   {ConfigTo},
+}
+
+var (
+	ErrNameRequired  = errors.New("name is required")
+	ErrNameTooLong   = errors.New("name is too long (max 100 characters)")
+	ErrInvalidKind   = errors.New("kind must be slack, discord, teams or email")
+	ErrInvalidURL    = errors.New("webhook_url must be an absolute https URL")
+	ErrInvalidEvent  = errors.New("events must be event types, optionally ending in .* to match a prefix, or *")
+	ErrInvalidTime   = errors.New("times must be HH:MM")
+	ErrQuietHours    = errors.New("quiet_start and quiet_end must be set together and differ")
+	ErrInvalidZone   = errors.New("timezone must be an IANA time zone name")
+	ErrWebhookSource = errors.New("set either webhook_url or webhook_secret")
+	ErrInvalidTo     = errors.New("to must be a comma-separated list of email addresses")
+	ErrInteractive   = errors.New("interactive must be true or false")
+)
+
+// Message is a notification about an event of a project. Adapters render
+// Title as the heading, Text below it, URL as a link and Mentions so that
+// the chat system notifies those people. A digest carries its events as
+// Items.
+type Message struct {
+	EventType string       `json:"event_type"`
+	ProjectID string       `json:"project_id"`
+	Priority  Priority     `json:"priority"`
+	Title     string       `json:"title"`
+	Text      string       `json:"text,omitempty"`
+	URL       string       `json:"url,omitempty"`
+	Mentions  []string     `json:"mentions,omitempty"`
+	Items     []Message    `json:"items,omitempty"`
+	Approval  *ApprovalRef `json:"approval,omitempty"` // Set when the message asks to decide an approval step
+	At        time.Time    `json:"at"`
+}
+
+// ApprovalRef identifies the plan approval step a message asks to decide.
+type ApprovalRef struct {
+	PlanID string `json:"plan_id"`
+	StepID string `json:"step_id"`
+}
+
+// Rule routes a project's events to a chat system.
+type Rule struct {
+	ID        string            `json:"id"`
+	ProjectID string            `json:"project_id"`
+	Name      string            `json:"name"`   // Unique per project
+	Events    []string          `json:"events"` // Event types; "plan.approval.*" matches a prefix; empty or "*" matches all
+	Kind      Kind              `json:"kind"`
+	Config    map[string]string `json:"config"`
+	Mentions  []string          `json:"mentions"` // Chat user or group IDs, or "here"/"channel"; unused by email
+	Digest    bool              `json:"digest"`   // Low-priority events go into the daily digest
+	Enabled   bool              `json:"enabled"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Matches reports whether the rule routes events of type t.
+func (r *Rule) Matches(t string) bool {
+	if len(r.Events) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(r.Events, func(e string) bool {
+		if prefix, ok := strings.CutSuffix(e, "*"); ok {
+			return strings.HasPrefix(t, prefix)
+		}
+		return e == t
+	})
+}
+
+// CreateRuleRequest holds the fields for creating or updating a rule.
+type CreateRuleRequest struct {
+	Name     string            `json:"name"`
+	Events   []string          `json:"events"`
+	Kind     Kind              `json:"kind"`
+	Config   map[string]string `json:"config"`
+	Mentions []string          `json:"mentions"`
+	Digest   bool              `json:"digest"`
+	Enabled  *bool             `json:"enabled,omitempty"` // Default: true
+}
+
+// Validate checks the name, events, kind and the config keys of the kind.
+func (r *CreateRuleRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return ErrNameRequired
+	}
+	if len(r.Name) > MaxNameLen {
+		return ErrNameTooLong
+	}
+	for _, e := range r.Events {
+		if !validEvent(e) {
+			return ErrInvalidEvent
+		}
+	}
+	keys, ok := configKeys[r.Kind]
+	if !ok {
+		return ErrInvalidKind
+	}
+	for k := range r.Config {
+		if !slices.Contains(keys, k) {
+			return fmt.Errorf("unknown config key %q for %s rules", k, r.Kind)
+		}
+	}
+	if r.Kind == KindEmail {
+		if _, err := Recipients(r.Config[ConfigTo]); err != nil {
+			return err
+		}
+	} else if err := validWebhook(r.Config); err != nil {
+		return err
+	}
+	if v, ok := r.Config[ConfigInteractive]; ok && v != "true" && v != "false" {
+		return ErrInteractive
+	}
+	for _, m := range r.Mentions {
+		if strings.TrimSpace(m) == "" {
+			return errors.New("mentions must not be empty")
+		}
+	}
+	return nil
+}
+
+// validWebhook checks that config has either an https webhook URL or the
+// name of a secret holding one.
+func validWebhook(config map[string]string) error {
+	hasURL, hasSecret := config[ConfigWebhookURL] != "", config[ConfigWebhookSecret] != ""
+	if hasURL == hasSecret {
+		return ErrWebhookSource
+	}
+	if hasURL {
+		if u, err := url.Parse(config[ConfigWebhookURL]); err != nil || u.Scheme != "https" || u.Host == "" {
+			return ErrInvalidURL
+		}
+	}
+	return nil
+}
+
+// Recipients parses the to config of an email rule into addresses.
+func Recipients(to string) ([]string, error) {
+	if strings.TrimSpace(to) == "" {
+		return nil, ErrInvalidTo
+	}
+	list, err := mail.ParseAddressList(to)
+	if err != nil {
+		return nil, ErrInvalidTo
+	}
+	out := make([]string, len(list))
+	for i, a := range list {
+		out[i] = a.Address
+	}
+	return out, nil
+}
+
+// validEvent reports whether e is an event type, a prefix pattern or "*".
+func validEvent(e string) bool {
+	if e == "*" {
+		return true
+	}
+	e = strings.TrimSuffix(e, ".*")
+	if e == "" {
+		return false
+	}
+	for _, part := range strings.Split(e, ".") {
+		if part == "" || strings.ContainsFunc(part, func(r rune) bool {
+			return (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '_'
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// Pending is a notification waiting to be sent for a rule: right away,
+// after quiet hours or with the rule's next digest.
+type Pending struct {
+	ID        string    `json:"id"`
+	RuleID    string    `json:"rule_id"`
+	ProjectID string    `json:"project_id"`
+	Message   Message   `json:"message"`
+	Digest    bool      `json:"digest"`
+	SendAt    time.Time `json:"send_at"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
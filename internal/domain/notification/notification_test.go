@@ -0,0 +1,112 @@
+package notification_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/notification"
+)
+
+func TestCreateRuleRequest_Validate(t *testing.T) {
+	hook := map[string]string{"webhook_url": "https://hooks.slack.com/services/T0/B0/x"}
+	tests := []struct {
+		name    string
+		req     notification.CreateRuleRequest
+		wantErr error
+		wantOK  bool
+	}{
+		{"slack", notification.CreateRuleRequest{Name: "approvals", Events: []string{"plan.approval.*"}, Kind: notification.KindSlack, Config: hook, Mentions: []string{"U123"}}, nil, true},
+		{"discord secret", notification.CreateRuleRequest{Name: "runs", Events: []string{"run.failed", "*"}, Kind: notification.KindDiscord, Config: map[string]string{"webhook_secret": "DISCORD_HOOK"}}, nil, true},
+		{"no name", notification.CreateRuleRequest{Name: " ", Kind: notification.KindSlack, Config: hook}, notification.ErrNameRequired, false},
+		{"bad kind", notification.CreateRuleRequest{Name: "x", Kind: "pager", Config: hook}, notification.ErrInvalidKind, false},
+		{"bad event", notification.CreateRuleRequest{Name: "x", Events: []string{"Plan Approval"}, Kind: notification.KindSlack, Config: hook}, notification.ErrInvalidEvent, false},
+		{"no webhook", notification.CreateRuleRequest{Name: "x", Kind: notification.KindSlack}, notification.ErrWebhookSource, false},
+		{"both webhooks", notification.CreateRuleRequest{Name: "x", Kind: notification.KindSlack, Config: map[string]string{"webhook_url": "https://h", "webhook_secret": "S"}}, notification.ErrWebhookSource, false},
+		{"http webhook", notification.CreateRuleRequest{Name: "x", Kind: notification.KindSlack, Config: map[string]string{"webhook_url": "http://h"}}, notification.ErrInvalidURL, false},
+		{"teams", notification.CreateRuleRequest{Name: "x", Kind: notification.KindTeams, Config: map[string]string{"webhook_url": "https://contoso.webhook.office.com/webhookb2/x"}}, nil, true},
+		{"email", notification.CreateRuleRequest{Name: "x", Kind: notification.KindEmail, Config: map[string]string{"to": "Ops <ops@example.com>, lead@example.com"}}, nil, true},
+		{"email without to", notification.CreateRuleRequest{Name: "x", Kind: notification.KindEmail}, notification.ErrInvalidTo, false},
+		{"email bad to", notification.CreateRuleRequest{Name: "x", Kind: notification.KindEmail, Config: map[string]string{"to": "ops@"}}, notification.ErrInvalidTo, false},
+		{"email webhook", notification.CreateRuleRequest{Name: "x", Kind: notification.KindEmail, Config: map[string]string{"to": "ops@example.com", "webhook_url": "https://h"}}, nil, false},
+		{"unknown key", notification.CreateRuleRequest{Name: "x", Kind: notification.KindSlack, Config: map[string]string{"webhook_url": "https://h", "channel": "#ops"}}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("got %v, want ok=%v", err, tt.wantOK)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRule_Matches(t *testing.T) {
+	r := notification.Rule{Events: []string{"plan.approval.*", "run.failed"}}
+	for typ, want := range map[string]bool{
+		"plan.approval.requested": true,
+		"plan.approval":           false,
+		"run.failed":              true,
+		"run.completed":           false,
+	} {
+		if got := r.Matches(typ); got != want {
+			t.Errorf("Matches(%q) = %v, want %v", typ, got, want)
+		}
+	}
+	if all := (notification.Rule{}); !all.Matches("run.completed") {
+		t.Error("expected a rule without events to match every type")
+	}
+}
+
+func TestSettings_QuietHours(t *testing.T) {
+	st := notification.Settings{QuietStart: "22:00", QuietEnd: "07:00", Timezone: "Europe/Berlin"}
+	if err := st.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+
+	// 23:30 in Berlin is quiet until 07:00 the next morning.
+	until, quiet := st.QuietUntil(time.Date(2026, 10, 16, 23, 30, 0, 0, berlin))
+	if want := time.Date(2026, 10, 17, 7, 0, 0, 0, berlin); !quiet || !until.Equal(want) {
+		t.Fatalf("expected quiet until %v, got %v %v", want, until, quiet)
+	}
+	// 06:00 is quiet until 07:00 the same day.
+	until, quiet = st.QuietUntil(time.Date(2026, 10, 17, 6, 0, 0, 0, berlin))
+	if want := time.Date(2026, 10, 17, 7, 0, 0, 0, berlin); !quiet || !until.Equal(want) {
+		t.Fatalf("expected quiet until %v, got %v %v", want, until, quiet)
+	}
+	if _, quiet := st.QuietUntil(time.Date(2026, 10, 17, 12, 0, 0, 0, berlin)); quiet {
+		t.Error("expected noon not to be quiet")
+	}
+	if _, quiet := (&notification.Settings{}).QuietUntil(time.Now()); quiet {
+		t.Error("expected no quiet hours by default")
+	}
+}
+
+func TestSettings_NextDigest(t *testing.T) {
+	at := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	if got, want := (&notification.Settings{}).NextDigest(at), time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("expected the default digest at %v, got %v", want, got)
+	}
+	st := notification.Settings{DigestAt: "07:30"}
+	if got, want := st.NextDigest(at), time.Date(2026, 10, 18, 7, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("expected the next day's digest at %v, got %v", want, got)
+	}
+}
+
+func TestSettings_Validate(t *testing.T) {
+	for _, st := range []notification.Settings{
+		{QuietStart: "22:00"},
+		{QuietStart: "22:00", QuietEnd: "22:00"},
+		{QuietStart: "25:00", QuietEnd: "07:00"},
+		{DigestAt: "9am"},
+		{Timezone: "Mars/Olympus"},
+	} {
+		if err := st.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", st)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package notification
+
+import (
+	"time"
+)
+
+// DefaultDigestAt is when the daily digest is sent if a project sets no
+// time.
+const DefaultDigestAt = "09:00"
+
+// Settings holds the quiet hours and digest time of a project. Times are
+// wall clock times (HH:MM) in Timezone.
+type Settings struct {
+	ProjectID  string    `json:"project_id"`
+	QuietStart string    `json:"quiet_start,omitempty"` // Start of quiet hours; empty for none
+	QuietEnd   string    `json:"quiet_end,omitempty"`   // End of quiet hours; may be on the next day
+	Timezone   string    `json:"timezone,omitempty"`    // IANA name (default: UTC)
+	DigestAt   string    `json:"digest_at,omitempty"`   // Time of the daily digest (default: 09:00)
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Validate checks the times and the time zone.
+func (s *Settings) Validate() error {
+	for _, t := range []string{s.QuietStart, s.QuietEnd, s.DigestAt} {
+		if _, err := parseClock(t); t != "" && err != nil {
+			return ErrInvalidTime
+		}
+	}
+	if (s.QuietStart == "") != (s.QuietEnd == "") || (s.QuietStart != "" && s.QuietStart == s.QuietEnd) {
+		return ErrQuietHours
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return ErrInvalidZone
+	}
+	return nil
+}
+
+// QuietUntil returns when the quiet hours around t end, and false if t is
+// not within quiet hours.
+func (s *Settings) QuietUntil(t time.Time) (time.Time, bool) {
+	start, err1 := parseClock(s.QuietStart)
+	end, err2 := parseClock(s.QuietEnd)
+	if err1 != nil || err2 != nil {
+		return time.Time{}, false
+	}
+	local := t.In(s.location())
+	now := clockOf(local)
+	var quiet bool
+	if start < end {
+		quiet = now >= start && now < end
+	} else { // Spans midnight
+		quiet = now >= start || now < end
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+	return next(local, end), true
+}
+
+// NextDigest returns the first digest time after t.
+func (s *Settings) NextDigest(t time.Time) time.Time {
+	at, err := parseClock(s.DigestAt)
+	if err != nil {
+		at, _ = parseClock(DefaultDigestAt)
+	}
+	return next(t.In(s.location()), at)
+}
+
+func (s *Settings) location() *time.Location {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// parseClock parses HH:MM into the time since midnight.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// clockOf returns the time since midnight of t.
+func clockOf(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
+
+// next returns the first time after t at wall clock time at of t's
+// location.
+func next(t time.Time, at time.Duration) time.Time {
+	y, m, d := t.Date()
+	h, mins := int(at/time.Hour), int(at%time.Hour/time.Minute)
+	n := time.Date(y, m, d, h, mins, 0, 0, t.Location())
+	if !n.After(t) {
+		n = time.Date(y, m, d+1, h, mins, 0, 0, t.Location())
+	}
+	return n
+}
@@ -0,0 +1,155 @@
+package plan
+
+import (
+	"cmp"
+	"errors"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+)
+
+// StepType distinguishes steps that execute a run from control steps.
+type StepType string
+
+const (
+	StepTypeRun      StepType = "run"      // Executes the step's task with its agent (default)
+	StepTypeApproval StepType = "approval" // Pauses the plan until a human approves or rejects
+	StepTypeImage    StepType = "image"    // Builds and pushes a container image of the previous run's workspace
+)
+
+var (
+	ErrInvalidStepType   = errors.New("invalid step type: must be run, approval or image")
+	ErrApprovalProtocol  = errors.New("approval steps require the sequential or parallel protocol")
+	ErrApprovalMissingBy = errors.New("approved_by is required")
+	ErrNotApprover       = errors.New("not an approver of this step")
+	ErrAlreadyVoted      = errors.New("already voted on this step")
+	ErrApproverMismatch  = errors.New("approved_by does not match the authenticated caller")
+)
+
+// ApprovalTimeout is the By of approvals resolved by their policy's
+// timeout action.
+const ApprovalTimeout = "timeout"
+
+// ApprovalAnonymous is the By of votes made without auth. Anonymous voters
+// cannot be told apart, so their vote counts once and never satisfies an
+// approver or role list.
+const ApprovalAnonymous = "anonymous"
+
+// IsApproval reports whether the step is a human-approval gate.
+func (s *Step) IsApproval() bool {
+	return s.Type == StepTypeApproval
+}
+
+// Approval records the votes on an approval step and how it was resolved.
+// It is created when the step starts waiting, with the approval policy in
+// effect at that time.
+type Approval struct {
+	Approved    bool                   `json:"approved"`
+	By          string                 `json:"by"`             // Deciding voter (see Voter.ID), or ApprovalTimeout
+	Name        string                 `json:"name,omitempty"` // Name of the deciding voter's API key
+	Comment     string                 `json:"comment,omitempty"`
+	ResolvedAt  *time.Time             `json:"resolved_at,omitempty"`
+	RequestedAt *time.Time             `json:"requested_at,omitempty"`
+	Policy      *policy.ApprovalPolicy `json:"policy,omitempty"`
+	Votes       []ApprovalVote         `json:"votes,omitempty"` // Oldest first
+	EscalatedAt *time.Time             `json:"escalated_at,omitempty"`
+	Expired     bool                   `json:"expired,omitempty"` // Resolved by the policy's timeout action
+	Version     int                    `json:"version,omitempty"` // Bumped by every write; a write based on an older version fails
+}
+
+// ApprovalVote is one approver's decision.
+type ApprovalVote struct {
+	Approved bool      `json:"approved"`
+	By       string    `json:"by"`             // See Voter.ID
+	Name     string    `json:"name,omitempty"` // Name of the voter's API key
+	Comment  string    `json:"comment,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// Resolved reports whether the approval has been decided.
+func (a *Approval) Resolved() bool {
+	return a != nil && a.ResolvedAt != nil
+}
+
+// Voted reports whether by has already voted.
+func (a *Approval) Voted(by string) bool {
+	for i := range a.Votes {
+		if a.Votes[i].By == by {
+			return true
+		}
+	}
+	return false
+}
+
+// Approvals returns the number of approving votes.
+func (a *Approval) Approvals() int {
+	n := 0
+	for i := range a.Votes {
+		if a.Votes[i].Approved {
+			n++
+		}
+	}
+	return n
+}
+
+// Expires returns when the approval times out, and false if it never does.
+func (a *Approval) Expires() (time.Time, bool) {
+	if a.RequestedAt == nil {
+		return time.Time{}, false
+	}
+	return a.Policy.Deadline(*a.RequestedAt)
+}
+
+// EscalationDue reports whether the approval should be escalated at now.
+func (a *Approval) EscalationDue(now time.Time) bool {
+	if a.RequestedAt == nil || a.EscalatedAt != nil {
+		return false
+	}
+	at, ok := a.Policy.EscalationAt(*a.RequestedAt)
+	return ok && !now.Before(at)
+}
+
+// ResolveApprovalRequest is the body for approving or rejecting an approval step.
+type ResolveApprovalRequest struct {
+	By      string   `json:"approved_by"` // Optional; must name the caller (see Bind)
+	Comment string   `json:"comment,omitempty"`
+	Name    string   `json:"-"` // Set by Bind
+	Roles   []string `json:"-"` // Set by Bind
+}
+
+// Voter is the caller a vote is recorded under.
+type Voter struct {
+	ID    string   // "server" for the configured keys, "api_key:<id>" for managed keys, "slack:<user id>" for Slack buttons; "" without auth
+	Name  string   // Name of the managed key or Slack user; not unique, so only shown
+	Roles []string // Roles of the managed key
+}
+
+// Bind records the vote under the authenticated voter. A given By must be
+// the voter's ID or key name, or Bind fails with ErrApproverMismatch; the
+// vote is recorded under the ID either way. Without auth nobody can be
+// identified and the vote is recorded as ApprovalAnonymous, whatever the
+// body claims.
+func (r *ResolveApprovalRequest) Bind(v Voter) error {
+	if v.ID == "" {
+		r.By, r.Name, r.Roles = ApprovalAnonymous, "", nil
+		return nil
+	}
+	if r.By != "" && r.By != v.ID && r.By != v.Name {
+		return ErrApproverMismatch
+	}
+	r.By, r.Name, r.Roles = v.ID, v.Name, v.Roles
+	return nil
+}
+
+// Voter returns how the voter is shown: the key name, or By.
+func (r *ResolveApprovalRequest) Voter() string {
+	return cmp.Or(r.Name, r.By)
+}
+
+// Validate checks that the resolving user is known.
+func (r *ResolveApprovalRequest) Validate() error {
+	if r.By == "" {
+		return ErrApprovalMissingBy
+	}
+	return nil
+}
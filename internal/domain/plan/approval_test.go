@@ -0,0 +1,41 @@
+package plan_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+)
+
+func TestResolveApprovalRequest_Bind(t *testing.T) {
+	key := plan.Voter{ID: "api_key:k1", Name: "alice", Roles: []string{"release"}}
+	tests := []struct {
+		name    string
+		by      string
+		voter   plan.Voter
+		wantBy  string
+		wantErr error
+	}{
+		{"anonymous ignores the body", "alice", plan.Voter{}, plan.ApprovalAnonymous, nil},
+		{"empty takes the key", "", key, "api_key:k1", nil},
+		{"key name", "alice", key, "api_key:k1", nil},
+		{"key id", "api_key:k1", key, "api_key:k1", nil},
+		{"someone else", "bob", key, "", plan.ErrApproverMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &plan.ResolveApprovalRequest{By: tt.by}
+			err := req.Bind(tt.voter)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && req.By != tt.wantBy {
+				t.Errorf("expected the vote recorded as %q, got %q", tt.wantBy, req.By)
+			}
+			if err == nil && tt.voter.ID != "" && (req.Voter() != "alice" || !slices.Equal(req.Roles, key.Roles)) {
+				t.Errorf("expected the key's name and roles, got %q %v", req.Voter(), req.Roles)
+			}
+		})
+	}
+}
@@ -1,17 +1,30 @@
 package plan
 
-// ReadySteps returns the IDs of steps that are pending and have all dependencies completed.
+import "time"
+
+// ReadySteps returns the IDs of steps that are pending, have all dependencies
+// completed, have their condition's source step finished and are not
+// waiting for a scheduled retry. Steps whose condition does not hold are
+// expected to have been skipped before.
 func ReadySteps(steps []Step) []string {
+	now := time.Now()
 	completed := make(map[string]bool, len(steps))
+	finished := make(map[string]bool, len(steps))
 	for i := range steps {
 		if steps[i].Status == StepStatusCompleted {
 			completed[steps[i].ID] = true
 		}
+		if steps[i].Status.IsTerminal() {
+			finished[steps[i].ID] = true
+		}
 	}
 
 	var ready []string
 	for i := range steps {
-		if steps[i].Status != StepStatusPending {
+		if steps[i].Status != StepStatusPending || !steps[i].RetryDue(now) {
+			continue
+		}
+		if w := steps[i].When; w != nil && !finished[w.Step] {
 			continue
 		}
 		allDepsComplete := true
@@ -28,11 +41,12 @@ func ReadySteps(steps []Step) []string {
 	return ready
 }
 
-// RunningCount returns the number of steps currently running.
+// RunningCount returns the number of steps currently running or paused
+// waiting for an approval.
 func RunningCount(steps []Step) int {
 	count := 0
 	for i := range steps {
-		if steps[i].Status == StepStatusRunning {
+		if steps[i].Status == StepStatusRunning || steps[i].Status == StepStatusWaitingApproval {
 			count++
 		}
 	}
@@ -49,10 +63,11 @@ func AllTerminal(steps []Step) bool {
 	return true
 }
 
-// AnyFailed returns true if at least one step has failed.
+// AnyFailed returns true if at least one step has failed, not counting
+// failures routed to another step by a condition.
 func AnyFailed(steps []Step) bool {
 	for i := range steps {
-		if steps[i].Status == StepStatusFailed {
+		if steps[i].Status == StepStatusFailed && !FailureRouted(steps, steps[i].ID) {
 			return true
 		}
 	}
@@ -0,0 +1,53 @@
+package plan
+
+import "time"
+
+// DebateRole is what a turn of a ping-pong plan contributes to the debate
+// between its two steps.
+type DebateRole string
+
+const (
+	DebateProposal DebateRole = "proposal" // First turn of the first step
+	DebateCritique DebateRole = "critique" // Turns of the second step
+	DebateRevision DebateRole = "revision" // Later turns of the first step
+)
+
+// TurnRole returns the role of the turn that the step at index (0 or 1)
+// of a ping-pong plan takes in round.
+func TurnRole(index, round int) DebateRole {
+	switch {
+	case index == 1:
+		return DebateCritique
+	case round <= 1:
+		return DebateProposal
+	}
+	return DebateRevision
+}
+
+// DebateTurn is one finished turn of a ping-pong step.
+type DebateTurn struct {
+	StepID    string     `json:"step_id"`
+	Round     int        `json:"round"`
+	Role      DebateRole `json:"role"`
+	RunID     string     `json:"run_id,omitempty"`
+	AgentID   string     `json:"agent_id,omitempty"`
+	Status    StepStatus `json:"status"`
+	Output    string     `json:"output"` // Run output, or its error if the turn failed
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// DebateSummary is the arbiter's summary of how a debate was resolved.
+type DebateSummary struct {
+	Text      string    `json:"text"`
+	Model     string    `json:"model"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Debate is the transcript of a ping-pong plan's step pair, oldest turn
+// first.
+type Debate struct {
+	PlanID  string         `json:"plan_id"`
+	StepIDs []string       `json:"step_ids"` // Proposing step, then critiquing step
+	Turns   []DebateTurn   `json:"turns"`
+	Summary *DebateSummary `json:"summary,omitempty"`
+}
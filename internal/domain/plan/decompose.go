@@ -48,6 +48,7 @@ type DecomposeRequest struct {
 	Context   string `json:"context,omitempty"` // Optional additional context (repo structure, TODOs, etc.)
 	Model     string `json:"model,omitempty"`   // LLM model override (empty = use config default)
 	AutoStart bool   `json:"auto_start"`        // Start plan immediately regardless of orchestrator mode
+	Backend   string `json:"backend,omitempty"` // Assign every step to an agent of this backend instead of selecting one
 }
 
 // Validate checks that the decompose request is well-formed.
@@ -76,6 +77,9 @@ type SubtaskDefinition struct {
 	Prompt    string `json:"prompt"`
 	DependsOn []int  `json:"depends_on"` // indices into the Subtasks array
 	AgentHint string `json:"agent_hint"` // optional: preferred backend (e.g. "aider", "openhands")
+
+	EstimatedLines int  `json:"estimated_lines,omitempty"` // optional: expected size of the change
+	MCPTools       bool `json:"mcp_tools,omitempty"`       // optional: the subtask needs MCP server tools
 }
 
 // PlanFeatureRequest holds the input for context-optimized feature planning.
@@ -87,6 +91,7 @@ type PlanFeatureRequest struct {
 	Model     string `json:"model,omitempty"`   // LLM model override
 	AutoStart bool   `json:"auto_start"`        // Start plan immediately
 	AutoTeam  bool   `json:"auto_team"`         // Auto-assemble team based on strategy
+	Backend   string `json:"backend,omitempty"` // Assign every step to an agent of this backend
 }
 
 // Validate checks that a PlanFeatureRequest is well-formed.
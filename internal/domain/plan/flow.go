@@ -0,0 +1,299 @@
+package plan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaxLoopIterations caps the passes a loop may make through its body.
+const MaxLoopIterations = 10
+
+// Verdicts a step can reach. Run steps report one with a "VERDICT: <value>"
+// line in their output; approval steps carry the human decision.
+const (
+	VerdictApproved         = "approved"
+	VerdictChangesRequested = "changes-requested"
+	VerdictRejected         = "rejected"
+)
+
+var (
+	ErrFlowProtocol      = errors.New("conditions and loops require the sequential or parallel protocol")
+	ErrConditionEmpty    = errors.New("condition must test a verdict, status, output or field")
+	ErrConditionEquals   = errors.New("condition equals requires a field")
+	ErrConditionStatus   = errors.New("condition status must be completed or failed")
+	ErrConditionInvalid  = errors.New("condition step references invalid index")
+	ErrLoopIterations    = errors.New("loop max_iterations must be between 2 and 10")
+	ErrLoopFrom          = errors.New("loop from must reference the step itself or a step it depends on")
+	ErrLoopUntilWithStep = errors.New("loop until tests the looping step itself and takes no step")
+)
+
+// Condition gates a step on the outcome of another step. All fields that
+// are set must match.
+type Condition struct {
+	Step           string     `json:"step,omitempty"`            // Source step: index at creation time, ID once stored
+	Verdict        string     `json:"verdict,omitempty"`         // Source verdict must equal this
+	Status         StepStatus `json:"status,omitempty"`          // Source must have ended completed or failed
+	OutputContains string     `json:"output_contains,omitempty"` // Source run output must contain this
+	Field          string     `json:"field,omitempty"`           // Dotted path into the source run's structured output, e.g. "issues.0.file"
+	Equals         string     `json:"equals,omitempty"`          // Field must equal this; without it, set and not false, null or ""
+}
+
+// Loop repeats the steps from From up to the step carrying the loop until
+// that step's outcome satisfies Until, at most MaxIterations times.
+type Loop struct {
+	From          string     `json:"from"`            // First step of the body: index at creation time, ID once stored
+	MaxIterations int        `json:"max_iterations"`  // Passes through the body including the first
+	Until         *Condition `json:"until,omitempty"` // Exit test on the looping step; default: it completed
+}
+
+// Outcome is what a finished step produced, as seen by conditions.
+type Outcome struct {
+	Status     StepStatus
+	Verdict    string
+	Output     string
+	Structured json.RawMessage // Run output parsed against its mode's output schema
+}
+
+var verdictLine = regexp.MustCompile(`(?im)^\s*verdict:\s*([a-z_-]+)\s*$`)
+
+// ParseVerdict returns the last verdict reported in a run's output,
+// normalized to lower case with dashes, or "" if there is none.
+func ParseVerdict(output string) string {
+	m := verdictLine.FindAllStringSubmatch(output, -1)
+	if m == nil {
+		return ""
+	}
+	return strings.ReplaceAll(strings.ToLower(m[len(m)-1][1]), "_", "-")
+}
+
+// Matches reports whether an outcome satisfies the condition.
+func (c *Condition) Matches(o Outcome) bool {
+	if c.Status != "" && o.Status != c.Status {
+		return false
+	}
+	if c.Verdict != "" && o.Verdict != c.Verdict {
+		return false
+	}
+	if c.OutputContains != "" && !strings.Contains(o.Output, c.OutputContains) {
+		return false
+	}
+	if c.Field != "" {
+		v, ok := Field(o.Structured, c.Field)
+		if !ok {
+			return false
+		}
+		if c.Equals != "" {
+			return v == c.Equals
+		}
+		return v != "false" && v != "null" && v != ""
+	}
+	return true
+}
+
+// Field returns the value at a dotted path in a structured output: strings
+// unquoted, other values as JSON. Array elements are addressed by index.
+func Field(structured json.RawMessage, path string) (string, bool) {
+	if len(structured) == 0 {
+		return "", false
+	}
+	var v any
+	if err := json.Unmarshal(structured, &v); err != nil {
+		return "", false
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch cur := v.(type) {
+		case map[string]any:
+			next, ok := cur[key]
+			if !ok {
+				return "", false
+			}
+			v = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(cur) {
+				return "", false
+			}
+			v = cur[i]
+		default:
+			return "", false
+		}
+	}
+	if s, ok := v.(string); ok {
+		return s, true
+	}
+	data, err := json.Marshal(v)
+	return string(data), err == nil
+}
+
+// Done reports whether the loop may exit with the given outcome of its step.
+func (l *Loop) Done(o Outcome) bool {
+	if l.Until == nil {
+		return o.Status == StepStatusCompleted
+	}
+	return l.Until.Matches(o)
+}
+
+func (c *Condition) validate() error {
+	if c.Equals != "" && c.Field == "" {
+		return ErrConditionEquals
+	}
+	if c.Verdict == "" && c.Status == "" && c.OutputContains == "" && c.Field == "" {
+		return ErrConditionEmpty
+	}
+	if c.Status != "" && c.Status != StepStatusCompleted && c.Status != StepStatusFailed {
+		return ErrConditionStatus
+	}
+	return nil
+}
+
+// validateFlow checks the conditions and loops of a plan's steps.
+func validateFlow(protocol Protocol, steps []CreateStepRequest) error {
+	n := len(steps)
+	for i := range steps {
+		s := &steps[i]
+		if s.When == nil && s.Loop == nil {
+			continue
+		}
+		if protocol != ProtocolSequential && protocol != ProtocolParallel {
+			return ErrFlowProtocol
+		}
+		if s.When != nil {
+			idx, err := strconv.Atoi(s.When.Step)
+			if err != nil || idx < 0 || idx >= n || idx == i {
+				return fmt.Errorf("step %d: %w", i, ErrConditionInvalid)
+			}
+			if err := s.When.validate(); err != nil {
+				return fmt.Errorf("step %d: %w", i, err)
+			}
+		}
+		if s.Loop != nil {
+			if s.Loop.MaxIterations < 2 || s.Loop.MaxIterations > MaxLoopIterations {
+				return fmt.Errorf("step %d: %w", i, ErrLoopIterations)
+			}
+			idx, err := strconv.Atoi(s.Loop.From)
+			if err != nil || idx < 0 || idx >= n || !ancestorIndex(steps, i, idx) {
+				return fmt.Errorf("step %d: %w", i, ErrLoopFrom)
+			}
+			if u := s.Loop.Until; u != nil {
+				if u.Step != "" {
+					return fmt.Errorf("step %d: %w", i, ErrLoopUntilWithStep)
+				}
+				if err := u.validate(); err != nil {
+					return fmt.Errorf("step %d: %w", i, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ancestorIndex reports whether step from is step i itself or reachable
+// from it by following dependencies and condition sources backwards.
+func ancestorIndex(steps []CreateStepRequest, i, from int) bool {
+	seen := make(map[int]bool)
+	stack := []int{i}
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if cur == from {
+			return true
+		}
+		if seen[cur] {
+			continue
+		}
+		seen[cur] = true
+		for _, ref := range upstreamRefs(steps[cur].DependsOn, steps[cur].When) {
+			if idx, err := strconv.Atoi(ref); err == nil && idx >= 0 && idx < len(steps) {
+				stack = append(stack, idx)
+			}
+		}
+	}
+	return false
+}
+
+// upstreamRefs returns the steps a step waits for: its dependencies and
+// the source of its condition.
+func upstreamRefs(dependsOn []string, when *Condition) []string {
+	if when == nil {
+		return dependsOn
+	}
+	return append(append([]string{}, dependsOn...), when.Step)
+}
+
+// RemapStepRefs replaces the creation-time step indices in dependencies,
+// conditions and loops with the IDs of the stored steps.
+func RemapStepRefs(steps []Step) {
+	id := func(ref string) string {
+		if idx, err := strconv.Atoi(ref); err == nil && idx >= 0 && idx < len(steps) {
+			return steps[idx].ID
+		}
+		return ref
+	}
+	for i := range steps {
+		st := &steps[i]
+		for j, dep := range st.DependsOn {
+			st.DependsOn[j] = id(dep)
+		}
+		if st.When != nil {
+			st.When.Step = id(st.When.Step)
+		}
+		if st.Loop != nil {
+			st.Loop.From = id(st.Loop.From)
+		}
+	}
+}
+
+// LoopBody returns the IDs of the steps a loop re-runs: the steps on a
+// path from the loop's first step to the looping step, both included.
+func LoopBody(steps []Step, loopStep *Step) []string {
+	byID := make(map[string]*Step, len(steps))
+	for i := range steps {
+		byID[steps[i].ID] = &steps[i]
+	}
+	// Walk upstream from the looping step; a step is in the body if the
+	// loop's first step is upstream of it (or it is the first step).
+	memo := make(map[string]bool)
+	var reaches func(id string) bool
+	reaches = func(id string) bool {
+		if v, ok := memo[id]; ok {
+			return v
+		}
+		memo[id] = false // guards against malformed cycles
+		st, ok := byID[id]
+		if !ok {
+			return false
+		}
+		in := id == loopStep.Loop.From
+		for _, ref := range upstreamRefs(st.DependsOn, st.When) {
+			if reaches(ref) {
+				in = true
+			}
+		}
+		memo[id] = in
+		return in
+	}
+	reaches(loopStep.ID)
+
+	var body []string
+	for i := range steps {
+		if memo[steps[i].ID] {
+			body = append(body, steps[i].ID)
+		}
+	}
+	return body
+}
+
+// FailureRouted reports whether a failed step's failure is handled by a
+// condition on another step, so it does not fail the plan.
+func FailureRouted(steps []Step, stepID string) bool {
+	for i := range steps {
+		if w := steps[i].When; w != nil && w.Step == stepID && w.Status == StepStatusFailed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package plan_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+)
+
+func TestParseVerdict(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"no verdict here", ""},
+		{"Looks fine.\nVERDICT: approved", "approved"},
+		{"verdict: CHANGES_REQUESTED\n", "changes-requested"},
+		{"Verdict: rejected\nlater...\n  VERDICT: approved  ", "approved"},
+		{"the verdict: approved is inline", ""},
+	}
+	for _, tt := range tests {
+		if got := plan.ParseVerdict(tt.output); got != tt.want {
+			t.Errorf("ParseVerdict(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestCondition_Matches(t *testing.T) {
+	out := plan.Outcome{
+		Status:     plan.StepStatusCompleted,
+		Verdict:    plan.VerdictChangesRequested,
+		Output:     "2 tests failed",
+		Structured: []byte(`{"failed": 2, "flaky": false, "tests": [{"name": "TestLogin"}]}`),
+	}
+	tests := []struct {
+		name string
+		cond plan.Condition
+		want bool
+	}{
+		{"verdict", plan.Condition{Verdict: plan.VerdictChangesRequested}, true},
+		{"other verdict", plan.Condition{Verdict: plan.VerdictApproved}, false},
+		{"status", plan.Condition{Status: plan.StepStatusFailed}, false},
+		{"output", plan.Condition{OutputContains: "tests failed"}, true},
+		{"all fields", plan.Condition{Status: plan.StepStatusCompleted, Verdict: plan.VerdictChangesRequested, OutputContains: "failed"}, true},
+		{"field set", plan.Condition{Field: "failed"}, true},
+		{"field false", plan.Condition{Field: "flaky"}, false},
+		{"field missing", plan.Condition{Field: "skipped"}, false},
+		{"field equals", plan.Condition{Field: "failed", Equals: "2"}, true},
+		{"nested field", plan.Condition{Field: "tests.0.name", Equals: "TestLogin"}, true},
+		{"field out of range", plan.Condition{Field: "tests.1.name"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.cond.Matches(out); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	loop := plan.Loop{}
+	if !loop.Done(plan.Outcome{Status: plan.StepStatusCompleted}) || loop.Done(plan.Outcome{Status: plan.StepStatusFailed}) {
+		t.Error("expected loop without until to exit on completion only")
+	}
+}
+
+func TestValidate_Flow(t *testing.T) {
+	steps := func(when *plan.Condition, loop *plan.Loop) []plan.CreateStepRequest {
+		return []plan.CreateStepRequest{
+			{TaskID: "t1", AgentID: "a1"},
+			{TaskID: "t2", AgentID: "a2", DependsOn: []string{"0"}},
+			{TaskID: "t3", AgentID: "a3", DependsOn: []string{"1"}, When: when, Loop: loop},
+		}
+	}
+	tests := []struct {
+		name     string
+		protocol plan.Protocol
+		when     *plan.Condition
+		loop     *plan.Loop
+		wantErr  error
+	}{
+		{"valid condition", plan.ProtocolSequential, &plan.Condition{Step: "0", Verdict: plan.VerdictChangesRequested}, nil, nil},
+		{"valid loop", plan.ProtocolParallel, nil, &plan.Loop{From: "0", MaxIterations: 3}, nil},
+		{"ping-pong", plan.ProtocolPingPong, &plan.Condition{Step: "0", Verdict: "approved"}, nil, plan.ErrFlowProtocol},
+		{"self condition", plan.ProtocolSequential, &plan.Condition{Step: "2", Verdict: "approved"}, nil, plan.ErrConditionInvalid},
+		{"empty condition", plan.ProtocolSequential, &plan.Condition{Step: "0"}, nil, plan.ErrConditionEmpty},
+		{"equals without field", plan.ProtocolSequential, &plan.Condition{Step: "0", Equals: "2"}, nil, plan.ErrConditionEquals},
+		{"bad status", plan.ProtocolSequential, &plan.Condition{Step: "0", Status: plan.StepStatusSkipped}, nil, plan.ErrConditionStatus},
+		{"too many iterations", plan.ProtocolParallel, nil, &plan.Loop{From: "0", MaxIterations: 11}, plan.ErrLoopIterations},
+		{"unrelated from", plan.ProtocolParallel, nil, &plan.Loop{From: "5", MaxIterations: 3}, plan.ErrLoopFrom},
+		{"until with step", plan.ProtocolParallel, nil, &plan.Loop{From: "2", MaxIterations: 3, Until: &plan.Condition{Step: "0", Verdict: "approved"}}, plan.ErrLoopUntilWithStep},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := plan.CreatePlanRequest{Name: "flow", Protocol: tt.protocol, Steps: steps(tt.when, tt.loop)}
+			if tt.protocol == plan.ProtocolPingPong {
+				req.Steps = req.Steps[1:]
+				req.Steps[0].DependsOn, req.Steps[1].DependsOn = nil, nil
+			}
+			err := req.Validate()
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("expected valid, got %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidate_ConditionCycle(t *testing.T) {
+	req := plan.CreatePlanRequest{
+		Name:     "cycle",
+		Protocol: plan.ProtocolParallel,
+		Steps: []plan.CreateStepRequest{
+			{TaskID: "t1", AgentID: "a1", When: &plan.Condition{Step: "1", Status: plan.StepStatusFailed}},
+			{TaskID: "t2", AgentID: "a2", DependsOn: []string{"0"}},
+		},
+	}
+	if err := req.Validate(); !errors.Is(err, plan.ErrDAGCycle) {
+		t.Fatalf("expected ErrDAGCycle, got %v", err)
+	}
+}
+
+func TestRemapStepRefsAndLoopBody(t *testing.T) {
+	steps := []plan.Step{
+		{ID: "plan"},
+		{ID: "impl", DependsOn: []string{"0"}},
+		{ID: "lint", DependsOn: []string{"1"}},
+		{ID: "docs", DependsOn: []string{"0"}},
+		{ID: "test", DependsOn: []string{"2"}, When: &plan.Condition{Step: "3", Status: plan.StepStatusCompleted}, Loop: &plan.Loop{From: "1", MaxIterations: 3}},
+	}
+	plan.RemapStepRefs(steps)
+	if steps[1].DependsOn[0] != "plan" || steps[4].When.Step != "docs" || steps[4].Loop.From != "impl" {
+		t.Fatalf("expected refs remapped to IDs, got %+v", steps)
+	}
+
+	body := plan.LoopBody(steps, &steps[4])
+	if want := []string{"impl", "lint", "test"}; !slices.Equal(body, want) {
+		t.Fatalf("LoopBody = %v, want %v", body, want)
+	}
+}
+
+func TestReadySteps_WaitsForConditionSource(t *testing.T) {
+	steps := []plan.Step{
+		{ID: "review", Status: plan.StepStatusRunning},
+		{ID: "fix", Status: plan.StepStatusPending, When: &plan.Condition{Step: "review", Verdict: plan.VerdictChangesRequested}},
+	}
+	if ready := plan.ReadySteps(steps); len(ready) != 0 {
+		t.Fatalf("expected no ready steps while the condition source runs, got %v", ready)
+	}
+	steps[0].Status = plan.StepStatusCompleted
+	if ready := plan.ReadySteps(steps); len(ready) != 1 || ready[0] != "fix" {
+		t.Fatalf("expected fix ready, got %v", ready)
+	}
+}
+
+func TestAnyFailed_IgnoresRoutedFailure(t *testing.T) {
+	steps := []plan.Step{
+		{ID: "test", Status: plan.StepStatusFailed},
+		{ID: "triage", Status: plan.StepStatusCompleted, When: &plan.Condition{Step: "test", Status: plan.StepStatusFailed}},
+	}
+	if plan.AnyFailed(steps) {
+		t.Fatal("expected failure routed to triage not to fail the plan")
+	}
+	steps[1].When.Status = plan.StepStatusCompleted
+	if !plan.AnyFailed(steps) {
+		t.Fatal("expected unrouted failure to fail the plan")
+	}
+}
@@ -0,0 +1,96 @@
+package plan
+
+import "time"
+
+// Graph is the node/edge view of an execution plan used by the frontend
+// to render the DAG together with each step's retry history.
+type Graph struct {
+	PlanID   string      `json:"plan_id"`
+	Protocol Protocol    `json:"protocol"`
+	Status   Status      `json:"status"`
+	Nodes    []GraphNode `json:"nodes"`
+	Edges    []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a single step in the plan graph.
+type GraphNode struct {
+	StepID      string        `json:"step_id"`
+	Type        StepType      `json:"type,omitempty"`
+	TaskID      string        `json:"task_id"`
+	AgentID     string        `json:"agent_id"`
+	Status      StepStatus    `json:"status"`
+	RunID       string        `json:"run_id,omitempty"`
+	Round       int           `json:"round"`
+	Attempt     int           `json:"attempt"`
+	MaxAttempts int           `json:"max_attempts"`
+	Attempts    []StepAttempt `json:"attempts"`
+	RetryAt     *time.Time    `json:"retry_at,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	Approval    *Approval     `json:"approval,omitempty"`
+	Image       *ImageBuild   `json:"image,omitempty"`
+	When        *Condition    `json:"when,omitempty"`
+	Loop        *Loop         `json:"loop,omitempty"`
+}
+
+// Edge kinds besides plain dependencies.
+const (
+	EdgeCondition = "condition" // Source step's outcome gates the target
+	EdgeLoop      = "loop"      // Looping step jumps back to the loop's first step
+)
+
+// GraphEdge points from a dependency to the step depending on it.
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind,omitempty"` // Empty for dependencies
+}
+
+// BuildGraph converts a plan and its steps into a Graph.
+func BuildGraph(p *ExecutionPlan) Graph {
+	g := Graph{
+		PlanID:   p.ID,
+		Protocol: p.Protocol,
+		Status:   p.Status,
+		Nodes:    make([]GraphNode, 0, len(p.Steps)),
+		Edges:    []GraphEdge{},
+	}
+	for i := range p.Steps {
+		st := &p.Steps[i]
+		maxAttempts := 1
+		if st.Retry != nil && st.Retry.MaxAttempts > 1 {
+			maxAttempts = st.Retry.MaxAttempts
+		}
+		attempts := st.Attempts
+		if attempts == nil {
+			attempts = []StepAttempt{}
+		}
+		g.Nodes = append(g.Nodes, GraphNode{
+			StepID:      st.ID,
+			Type:        st.Type,
+			TaskID:      st.TaskID,
+			AgentID:     st.AgentID,
+			Status:      st.Status,
+			RunID:       st.RunID,
+			Round:       st.Round,
+			Attempt:     st.Attempt,
+			MaxAttempts: maxAttempts,
+			Attempts:    attempts,
+			RetryAt:     st.RetryAt,
+			Error:       st.Error,
+			Approval:    st.Approval,
+			Image:       st.Image,
+			When:        st.When,
+			Loop:        st.Loop,
+		})
+		for _, dep := range st.DependsOn {
+			g.Edges = append(g.Edges, GraphEdge{From: dep, To: st.ID})
+		}
+		if st.When != nil {
+			g.Edges = append(g.Edges, GraphEdge{From: st.When.Step, To: st.ID, Kind: EdgeCondition})
+		}
+		if st.Loop != nil {
+			g.Edges = append(g.Edges, GraphEdge{From: st.ID, To: st.Loop.From, Kind: EdgeLoop})
+		}
+	}
+	return g
+}
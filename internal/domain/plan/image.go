@@ -0,0 +1,166 @@
+package plan
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ImageBuilder selects how an image step builds its image.
+type ImageBuilder string
+
+const (
+	ImageBuilderDockerfile ImageBuilder = "dockerfile" // Builds the context's Dockerfile (default)
+	ImageBuilderBuildpacks ImageBuilder = "buildpacks" // Builds the context with Cloud Native Buildpacks
+)
+
+var (
+	ErrImageProtocol   = errors.New("image steps require the sequential or parallel protocol")
+	ErrImageSpec       = errors.New("image steps require an image spec")
+	ErrImageSource     = errors.New("image steps must follow a run step whose workspace they build")
+	ErrImageBuilder    = errors.New("invalid image builder: must be dockerfile or buildpacks")
+	ErrImageRepository = errors.New("image repository must be a registry repository without tag or digest, e.g. ghcr.io/acme/web")
+	ErrImageTag        = errors.New("invalid image tag")
+	ErrImagePath       = errors.New("image context and dockerfile must be relative paths inside the workspace")
+	ErrImageBuildArg   = errors.New("invalid image build arg name")
+	ErrImageUsername   = errors.New("registry_username requires registry_secret")
+)
+
+// DefaultImageTag is the tag pushed when an image spec names none.
+const DefaultImageTag = "latest"
+
+var (
+	imageRepository = regexp.MustCompile(`^[a-z0-9]+([._-][a-z0-9]+)*(:[0-9]+)?(/[a-z0-9]+([._-]+[a-z0-9]+)*)+$`)
+	imageTag        = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+	imageBuildArg   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// ImageSpec configures the container image an image step builds from the
+// workspace of the run before it and pushes to a registry.
+type ImageSpec struct {
+	Builder          ImageBuilder      `json:"builder,omitempty"`           // Default dockerfile
+	Context          string            `json:"context,omitempty"`           // Build context in the workspace, default "."
+	Dockerfile       string            `json:"dockerfile,omitempty"`        // Relative to the context, default "Dockerfile"; dockerfile builds only
+	Repository       string            `json:"repository"`                  // e.g. ghcr.io/acme/web
+	Tags             []string          `json:"tags,omitempty"`              // Default DefaultImageTag
+	BuildArgs        map[string]string `json:"build_args,omitempty"`        // Dockerfile builds only
+	RegistryUsername string            `json:"registry_username,omitempty"` // Default "codeforge" for token registries
+	RegistrySecret   string            `json:"registry_secret,omitempty"`   // Project secret holding the registry password or token
+}
+
+// Validate checks the spec and fills in its defaults.
+func (s *ImageSpec) Validate() error {
+	switch s.Builder {
+	case "":
+		s.Builder = ImageBuilderDockerfile
+	case ImageBuilderDockerfile, ImageBuilderBuildpacks:
+	default:
+		return ErrImageBuilder
+	}
+	if !imageRepository.MatchString(s.Repository) {
+		return fmt.Errorf("%w: %q", ErrImageRepository, s.Repository)
+	}
+	if s.Context == "" {
+		s.Context = "."
+	}
+	if s.Builder == ImageBuilderDockerfile && s.Dockerfile == "" {
+		s.Dockerfile = "Dockerfile"
+	}
+	for _, p := range []string{s.Context, s.Dockerfile} {
+		if p != "" && (path.IsAbs(p) || !isLocal(p)) {
+			return fmt.Errorf("%w: %q", ErrImagePath, p)
+		}
+	}
+	if len(s.Tags) == 0 {
+		s.Tags = []string{DefaultImageTag}
+	}
+	for _, t := range s.Tags {
+		if !imageTag.MatchString(t) {
+			return fmt.Errorf("%w: %q", ErrImageTag, t)
+		}
+	}
+	for k := range s.BuildArgs {
+		if !imageBuildArg.MatchString(k) {
+			return fmt.Errorf("%w: %q", ErrImageBuildArg, k)
+		}
+	}
+	if s.RegistryUsername != "" && s.RegistrySecret == "" {
+		return ErrImageUsername
+	}
+	return nil
+}
+
+// isLocal reports whether a slash-separated path stays inside the
+// directory it is relative to.
+func isLocal(p string) bool {
+	p = path.Clean(p)
+	return p != ".." && !strings.HasPrefix(p, "../")
+}
+
+// Registry returns the host of the registry the spec pushes to, docker.io
+// for Docker Hub repositories, which name none.
+func (s *ImageSpec) Registry() string {
+	host, _, _ := strings.Cut(s.Repository, "/")
+	if strings.ContainsAny(host, ".:") || host == "localhost" {
+		return host
+	}
+	return "docker.io"
+}
+
+// References returns the tagged references the spec pushes.
+func (s *ImageSpec) References() []string {
+	refs := make([]string, len(s.Tags))
+	for i, t := range s.Tags {
+		refs[i] = s.Repository + ":" + t
+	}
+	return refs
+}
+
+// ImageBuild records the spec of an image step and the image it pushed.
+type ImageBuild struct {
+	ImageSpec
+	SourceRunID string     `json:"source_run_id,omitempty"` // Run whose workspace was built
+	Digest      string     `json:"digest,omitempty"`        // e.g. sha256:…
+	BuiltAt     *time.Time `json:"built_at,omitempty"`
+}
+
+// Reference returns the digest reference of the pushed image, or "" if it
+// has not been built.
+func (b *ImageBuild) Reference() string {
+	if b == nil || b.Digest == "" {
+		return ""
+	}
+	return b.Repository + "@" + b.Digest
+}
+
+// IsImage reports whether the step builds and pushes a container image.
+func (s *Step) IsImage() bool {
+	return s.Type == StepTypeImage
+}
+
+// ImageSource returns the step whose run an image step builds: the last
+// completed dependency with a run or, without dependencies, the last
+// earlier step that completed one. It returns nil if there is none.
+func (p *ExecutionPlan) ImageSource(step *Step) *Step {
+	var src *Step
+	for i := range p.Steps {
+		s := &p.Steps[i]
+		if s.ID == step.ID {
+			if len(step.DependsOn) == 0 {
+				break
+			}
+			continue
+		}
+		if len(step.DependsOn) > 0 && !slices.Contains(step.DependsOn, s.ID) {
+			continue
+		}
+		if s.Status == StepStatusCompleted && s.RunID != "" {
+			src = s
+		}
+	}
+	return src
+}
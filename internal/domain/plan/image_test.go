@@ -0,0 +1,99 @@
+package plan_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+)
+
+func TestImageSpecValidate(t *testing.T) {
+	spec := plan.ImageSpec{Repository: "ghcr.io/acme/web"}
+	if err := spec.Validate(); err != nil {
+		t.Fatalf("expected valid, got %v", err)
+	}
+	if spec.Builder != plan.ImageBuilderDockerfile || spec.Context != "." || spec.Dockerfile != "Dockerfile" ||
+		!slices.Equal(spec.References(), []string{"ghcr.io/acme/web:latest"}) {
+		t.Fatalf("defaults not applied: %+v", spec)
+	}
+
+	tests := []struct {
+		name string
+		spec plan.ImageSpec
+		want error
+	}{
+		{"unknown builder", plan.ImageSpec{Builder: "bazel", Repository: "acme/web"}, plan.ErrImageBuilder},
+		{"missing repository", plan.ImageSpec{}, plan.ErrImageRepository},
+		{"tagged repository", plan.ImageSpec{Repository: "ghcr.io/acme/web:v1"}, plan.ErrImageRepository},
+		{"bad tag", plan.ImageSpec{Repository: "acme/web", Tags: []string{"-v1"}}, plan.ErrImageTag},
+		{"context outside workspace", plan.ImageSpec{Repository: "acme/web", Context: "../other"}, plan.ErrImagePath},
+		{"absolute dockerfile", plan.ImageSpec{Repository: "acme/web", Dockerfile: "/etc/Dockerfile"}, plan.ErrImagePath},
+		{"bad build arg", plan.ImageSpec{Repository: "acme/web", BuildArgs: map[string]string{"A=B": "c"}}, plan.ErrImageBuildArg},
+		{"username without secret", plan.ImageSpec{Repository: "acme/web", RegistryUsername: "bot"}, plan.ErrImageUsername},
+	}
+	for _, tt := range tests {
+		if err := tt.spec.Validate(); !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestImageSpecRegistry(t *testing.T) {
+	for repo, want := range map[string]string{
+		"ghcr.io/acme/web":          "ghcr.io",
+		"localhost:5000/web/api":    "localhost:5000",
+		"acme/web":                  "docker.io",
+		"registry.gitlab.com/a/b/c": "registry.gitlab.com",
+	} {
+		spec := plan.ImageSpec{Repository: repo}
+		if got := spec.Registry(); got != want {
+			t.Errorf("Registry(%q) = %q, want %q", repo, got, want)
+		}
+	}
+}
+
+func TestValidate_ImageStep(t *testing.T) {
+	req := validSequentialRequest()
+	req.Steps = append(req.Steps, plan.CreateStepRequest{Type: plan.StepTypeImage, Image: &plan.ImageSpec{Repository: "ghcr.io/acme/web"}})
+	if err := req.Validate(); err != nil {
+		t.Fatalf("expected valid, got %v", err)
+	}
+
+	req.Steps[2].Image = nil
+	if err := req.Validate(); !errors.Is(err, plan.ErrImageSpec) {
+		t.Fatalf("expected ErrImageSpec, got %v", err)
+	}
+
+	first := plan.CreatePlanRequest{
+		Name:     "image only",
+		Protocol: plan.ProtocolParallel,
+		Steps: []plan.CreateStepRequest{
+			{TaskID: "t1", AgentID: "a1"},
+			{Type: plan.StepTypeImage, Image: &plan.ImageSpec{Repository: "ghcr.io/acme/web"}},
+		},
+	}
+	if err := first.Validate(); !errors.Is(err, plan.ErrImageSource) {
+		t.Fatalf("expected ErrImageSource without a dependency, got %v", err)
+	}
+}
+
+func TestImageSource(t *testing.T) {
+	p := plan.ExecutionPlan{Steps: []plan.Step{
+		{ID: "build", Status: plan.StepStatusCompleted, RunID: "run-1"},
+		{ID: "gate", Type: plan.StepTypeApproval, Status: plan.StepStatusCompleted},
+		{ID: "image", Type: plan.StepTypeImage},
+		{ID: "later", Status: plan.StepStatusCompleted, RunID: "run-3"},
+	}}
+	if src := p.ImageSource(&p.Steps[2]); src == nil || src.RunID != "run-1" {
+		t.Fatalf("expected the last earlier run, got %+v", src)
+	}
+	p.Steps[2].DependsOn = []string{"later"}
+	if src := p.ImageSource(&p.Steps[2]); src == nil || src.RunID != "run-3" {
+		t.Fatalf("expected the dependency's run, got %+v", src)
+	}
+	p.Steps[2].DependsOn = []string{"gate"}
+	if src := p.ImageSource(&p.Steps[2]); src != nil {
+		t.Fatalf("expected no source among dependencies without a run, got %+v", src)
+	}
+}
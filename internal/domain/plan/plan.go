@@ -34,6 +34,8 @@ const (
 	StepStatusFailed    StepStatus = "failed"
 	StepStatusSkipped   StepStatus = "skipped"
 	StepStatusCancelled StepStatus = "cancelled"
+
+	StepStatusWaitingApproval StepStatus = "waiting_approval" // Approval step paused for a human decision
 )
 
 // IsTerminal returns true if the step is in a final state.
@@ -56,6 +58,7 @@ type ExecutionPlan struct {
 	Status      Status    `json:"status"`
 	MaxParallel int       `json:"max_parallel"`
 	Steps       []Step    `json:"steps"`
+	RerunOf     string    `json:"rerun_of,omitempty"` // Plan this one re-executes part of
 	Version     int       `json:"version"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
@@ -63,19 +66,30 @@ type ExecutionPlan struct {
 
 // Step represents one unit of work in an execution plan, mapping to a single Run.
 type Step struct {
-	ID            string     `json:"id"`
-	PlanID        string     `json:"plan_id"`
-	TaskID        string     `json:"task_id"`
-	AgentID       string     `json:"agent_id"`
-	PolicyProfile string     `json:"policy_profile"`
-	DeliverMode   string     `json:"deliver_mode"`
-	DependsOn     []string   `json:"depends_on"`
-	Status        StepStatus `json:"status"`
-	RunID         string     `json:"run_id,omitempty"`
-	Round         int        `json:"round"`
-	Error         string     `json:"error,omitempty"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
+	ID            string        `json:"id"`
+	PlanID        string        `json:"plan_id"`
+	Type          StepType      `json:"type,omitempty"` // Empty means StepTypeRun
+	Name          string        `json:"name,omitempty"` // Referenced by other steps' prompts as {{steps.<name>.output}}
+	TaskID        string        `json:"task_id"`
+	AgentID       string        `json:"agent_id"`
+	Model         string        `json:"model,omitempty"` // Overrides the agent's configured model
+	PolicyProfile string        `json:"policy_profile"`
+	DeliverMode   string        `json:"deliver_mode"`
+	DependsOn     []string      `json:"depends_on"`
+	Status        StepStatus    `json:"status"`
+	RunID         string        `json:"run_id,omitempty"`
+	Round         int           `json:"round"`
+	Error         string        `json:"error,omitempty"`
+	Retry         *RetryPolicy  `json:"retry,omitempty"`
+	Attempt       int           `json:"attempt"`            // Attempts started so far
+	Attempts      []StepAttempt `json:"attempts,omitempty"` // Failed attempts, oldest first
+	RetryAt       *time.Time    `json:"retry_at,omitempty"` // Set while a retry is scheduled
+	Approval      *Approval     `json:"approval,omitempty"` // Resolution of an approval step
+	Image         *ImageBuild   `json:"image,omitempty"`    // Spec and pushed image of an image step
+	When          *Condition    `json:"when,omitempty"`     // Run only if another step's outcome matches
+	Loop          *Loop         `json:"loop,omitempty"`     // Re-run a span of steps until this one succeeds
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
 }
 
 // CreatePlanRequest holds the fields for creating a new execution plan.
@@ -91,9 +105,16 @@ type CreatePlanRequest struct {
 
 // CreateStepRequest holds the fields for creating a step within a plan.
 type CreateStepRequest struct {
-	TaskID        string   `json:"task_id"`
-	AgentID       string   `json:"agent_id"`
-	PolicyProfile string   `json:"policy_profile,omitempty"`
-	DeliverMode   string   `json:"deliver_mode,omitempty"`
-	DependsOn     []string `json:"depends_on,omitempty"` // step indices ("0", "1") at creation time
+	Type          StepType     `json:"type,omitempty"`
+	Name          string       `json:"name,omitempty"`
+	TaskID        string       `json:"task_id"`
+	AgentID       string       `json:"agent_id"`
+	Model         string       `json:"model,omitempty"`
+	PolicyProfile string       `json:"policy_profile,omitempty"`
+	DeliverMode   string       `json:"deliver_mode,omitempty"`
+	DependsOn     []string     `json:"depends_on,omitempty"` // step indices ("0", "1") at creation time
+	Retry         *RetryPolicy `json:"retry,omitempty"`
+	When          *Condition   `json:"when,omitempty"` // when.step is a step index at creation time
+	Loop          *Loop        `json:"loop,omitempty"` // loop.from is a step index at creation time
+	Image         *ImageSpec   `json:"image,omitempty"`
 }
@@ -0,0 +1,129 @@
+package plan
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+var (
+	ErrStepNameInvalid   = errors.New("step name must start with a letter and contain only letters, digits, '-' and '_'")
+	ErrStepNameDuplicate = errors.New("step name is used by another step")
+	ErrStepRefProtocol   = errors.New("step references require the sequential or parallel protocol")
+	ErrStepRefUnknown    = errors.New("prompt references an unknown step")
+	ErrStepRefUnresolved = errors.New("step reference cannot be resolved")
+)
+
+var (
+	stepNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+	stepRefPattern  = regexp.MustCompile(`\{\{\s*steps\.([A-Za-z][A-Za-z0-9_-]*)\.output((?:\.[A-Za-z0-9_-]+)*)\s*\}\}`)
+)
+
+// StepRef is a reference in a step's prompt to the output of another
+// step: {{steps.<name>.output}} for its output text,
+// {{steps.<name>.output.<path>}} for a field of its structured output.
+type StepRef struct {
+	Step  string // Name of the referenced step
+	Field string // Dotted path into the structured output; empty for the output text
+}
+
+func (r StepRef) String() string {
+	if r.Field == "" {
+		return "steps." + r.Step + ".output"
+	}
+	return "steps." + r.Step + ".output." + r.Field
+}
+
+// ParseRefs returns the step references in a prompt in order of appearance.
+func ParseRefs(prompt string) []StepRef {
+	var refs []StepRef
+	for _, m := range stepRefPattern.FindAllStringSubmatch(prompt, -1) {
+		field := m[2]
+		if field != "" {
+			field = field[1:]
+		}
+		refs = append(refs, StepRef{Step: m[1], Field: field})
+	}
+	return refs
+}
+
+// Interpolate replaces the step references in a prompt with the values
+// resolve returns for them. The first error aborts the interpolation.
+func Interpolate(prompt string, resolve func(StepRef) (string, error)) (string, error) {
+	var firstErr error
+	out := stepRefPattern.ReplaceAllStringFunc(prompt, func(match string) string {
+		if firstErr != nil {
+			return match
+		}
+		v, err := resolve(ParseRefs(match)[0])
+		if err != nil {
+			firstErr = err
+			return match
+		}
+		return v
+	})
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return out, nil
+}
+
+// LinkRefs checks the step references in the prompts of a plan's steps,
+// prompts[i] being the prompt of step i, and makes each referenced step a
+// dependency of the step referencing it. Validate rejects the cycles this
+// may create.
+func (r *CreatePlanRequest) LinkRefs(prompts []string) error {
+	if err := validateStepNames(r.Steps); err != nil {
+		return err
+	}
+	names := make(map[string]int, len(r.Steps))
+	for i := range r.Steps {
+		if r.Steps[i].Name != "" {
+			names[r.Steps[i].Name] = i
+		}
+	}
+	for i, prompt := range prompts {
+		refs := ParseRefs(prompt)
+		if len(refs) == 0 || i >= len(r.Steps) {
+			continue
+		}
+		if r.Protocol != ProtocolSequential && r.Protocol != ProtocolParallel {
+			return fmt.Errorf("step %d: %w", i, ErrStepRefProtocol)
+		}
+		s := &r.Steps[i]
+		for _, ref := range refs {
+			idx, ok := names[ref.Step]
+			if !ok {
+				return fmt.Errorf("step %d: %w: %q", i, ErrStepRefUnknown, ref.Step)
+			}
+			if idx == i {
+				return fmt.Errorf("step %d references itself: %w", i, ErrDAGCycle)
+			}
+			if dep := strconv.Itoa(idx); !slices.Contains(s.DependsOn, dep) {
+				s.DependsOn = append(s.DependsOn, dep)
+			}
+		}
+	}
+	return nil
+}
+
+// validateStepNames checks that step names are well-formed and unique.
+func validateStepNames(steps []CreateStepRequest) error {
+	seen := make(map[string]bool, len(steps))
+	for i := range steps {
+		name := steps[i].Name
+		if name == "" {
+			continue
+		}
+		if !stepNamePattern.MatchString(name) {
+			return fmt.Errorf("step %d: %w", i, ErrStepNameInvalid)
+		}
+		if seen[name] {
+			return fmt.Errorf("step %d: %w: %q", i, ErrStepNameDuplicate, name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package plan_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+)
+
+func TestParseRefs(t *testing.T) {
+	got := plan.ParseRefs("Use {{steps.design.output}} and {{ steps.design.output.api_spec.paths.0 }}, not {{steps.design}} or {{ .Name }}")
+	want := []plan.StepRef{{Step: "design"}, {Step: "design", Field: "api_spec.paths.0"}}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	values := map[string]string{"steps.a.output": "text", "steps.a.output.n": "2"}
+	resolve := func(ref plan.StepRef) (string, error) {
+		v, ok := values[ref.String()]
+		if !ok {
+			return "", plan.ErrStepRefUnresolved
+		}
+		return v, nil
+	}
+	got, err := plan.Interpolate("{{steps.a.output}} x{{steps.a.output.n}}", resolve)
+	if err != nil || got != "text x2" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	if _, err := plan.Interpolate("{{steps.a.output.missing}}", resolve); !errors.Is(err, plan.ErrStepRefUnresolved) {
+		t.Fatalf("expected ErrStepRefUnresolved, got %v", err)
+	}
+}
+
+func TestLinkRefs(t *testing.T) {
+	req := plan.CreatePlanRequest{
+		Name:     "refs",
+		Protocol: plan.ProtocolSequential,
+		Steps: []plan.CreateStepRequest{
+			{Name: "design", TaskID: "t1", AgentID: "a1"},
+			{Name: "build", TaskID: "t2", AgentID: "a1"},
+			{TaskID: "t3", AgentID: "a1", DependsOn: []string{"1"}},
+		},
+	}
+	prompts := []string{"", "Build {{steps.design.output}}", "Test {{steps.build.output}} per {{steps.design.output}}"}
+	if err := req.LinkRefs(prompts); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(req.Steps[1].DependsOn, []string{"0"}) || !slices.Equal(req.Steps[2].DependsOn, []string{"1", "0"}) {
+		t.Fatalf("unexpected dependencies %v, %v", req.Steps[1].DependsOn, req.Steps[2].DependsOn)
+	}
+	if err := req.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	req.Protocol = plan.ProtocolConsensus
+	if err := req.LinkRefs(prompts); !errors.Is(err, plan.ErrStepRefProtocol) {
+		t.Fatalf("expected ErrStepRefProtocol, got %v", err)
+	}
+	req.Protocol = plan.ProtocolSequential
+	req.Steps[0].Name = "1st"
+	if err := req.LinkRefs(prompts); !errors.Is(err, plan.ErrStepNameInvalid) {
+		t.Fatalf("expected ErrStepNameInvalid, got %v", err)
+	}
+}
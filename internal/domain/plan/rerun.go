@@ -0,0 +1,164 @@
+package plan
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+)
+
+// RerunMode selects the steps a rerun executes again.
+type RerunMode string
+
+const (
+	RerunFailed RerunMode = "failed" // Steps that did not complete, and the steps downstream of them
+	RerunStep   RerunMode = "step"   // One step and the steps downstream of it
+	RerunFrom   RerunMode = "from"   // One step and every step after it in plan order
+)
+
+var (
+	ErrRerunMode        = errors.New("invalid rerun mode: must be failed, step or from")
+	ErrRerunStep        = errors.New("rerun step is not a step of the plan")
+	ErrRerunNotFinished = errors.New("only finished plans can be rerun")
+	ErrNothingToRerun   = errors.New("every step of the plan completed")
+)
+
+// RerunRequest asks for a new plan that re-executes part of a finished one.
+type RerunRequest struct {
+	Mode   RerunMode `json:"mode"`
+	StepID string    `json:"step_id,omitempty"` // Required by the step and from modes
+}
+
+// Rerun returns a pending copy of a finished plan, linked to it by RerunOf,
+// in which the steps the request selects start over. The other steps stay
+// completed with their runs, so later steps can use their outputs.
+// Ping-pong and consensus plans rerun all their steps. Step
+// references are creation-time indices, as CreatePlan expects them.
+func Rerun(p *ExecutionPlan, req *RerunRequest) (*ExecutionPlan, error) {
+	switch p.Status {
+	case StatusCompleted, StatusFailed, StatusCancelled:
+	default:
+		return nil, ErrRerunNotFinished
+	}
+	rerun, err := rerunSteps(p.Protocol, p.Steps, req)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]string, len(p.Steps))
+	for i := range p.Steps {
+		index[p.Steps[i].ID] = strconv.Itoa(i)
+	}
+	ref := func(id string) string {
+		if i, ok := index[id]; ok {
+			return i
+		}
+		return id
+	}
+
+	np := &ExecutionPlan{
+		ProjectID:   p.ProjectID,
+		TeamID:      p.TeamID,
+		Name:        p.Name,
+		Description: p.Description,
+		Protocol:    p.Protocol,
+		Status:      StatusPending,
+		MaxParallel: p.MaxParallel,
+		RerunOf:     p.ID,
+		Steps:       make([]Step, len(p.Steps)),
+	}
+	for i := range p.Steps {
+		src := &p.Steps[i]
+		st := Step{
+			Type:          src.Type,
+			Name:          src.Name,
+			TaskID:        src.TaskID,
+			AgentID:       src.AgentID,
+			Model:         src.Model,
+			PolicyProfile: src.PolicyProfile,
+			DeliverMode:   src.DeliverMode,
+			Status:        StepStatusPending,
+			Retry:         src.Retry,
+		}
+		for _, dep := range src.DependsOn {
+			st.DependsOn = append(st.DependsOn, ref(dep))
+		}
+		if src.When != nil {
+			w := *src.When
+			w.Step = ref(w.Step)
+			st.When = &w
+		}
+		if src.Loop != nil {
+			l := *src.Loop
+			l.From = ref(l.From)
+			st.Loop = &l
+		}
+		if src.Image != nil {
+			st.Image = &ImageBuild{ImageSpec: src.Image.ImageSpec}
+		}
+		if !rerun[src.ID] {
+			st.Status = StepStatusCompleted
+			st.RunID = src.RunID
+			st.Round = src.Round
+			st.Attempt = src.Attempt
+			st.Approval = src.Approval
+			st.Image = src.Image
+		}
+		np.Steps[i] = st
+	}
+	return np, nil
+}
+
+// rerunSteps returns the IDs of the steps a rerun request selects. Debates
+// and votes start over with all their steps.
+func rerunSteps(protocol Protocol, steps []Step, req *RerunRequest) (map[string]bool, error) {
+	start := slices.IndexFunc(steps, func(st Step) bool { return st.ID == req.StepID })
+	rerun := make(map[string]bool)
+	switch req.Mode {
+	case RerunFailed:
+		for i := range steps {
+			if steps[i].Status != StepStatusCompleted {
+				rerun[steps[i].ID] = true
+			}
+		}
+		if len(rerun) == 0 {
+			return nil, ErrNothingToRerun
+		}
+	case RerunStep, RerunFrom:
+		if start < 0 {
+			return nil, ErrRerunStep
+		}
+		rerun[req.StepID] = true
+		if req.Mode == RerunFrom {
+			for i := start; i < len(steps); i++ {
+				rerun[steps[i].ID] = true
+			}
+		}
+	default:
+		return nil, ErrRerunMode
+	}
+	if protocol == ProtocolPingPong || protocol == ProtocolConsensus {
+		for i := range steps {
+			rerun[steps[i].ID] = true
+		}
+	}
+
+	// Whatever waits on a rerun step runs again with its new outcome.
+	adj, _ := stepEdges(steps)
+	var queue []int
+	for i := range steps {
+		if rerun[steps[i].ID] {
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, m := range adj[n] {
+			if !rerun[steps[m].ID] {
+				rerun[steps[m].ID] = true
+				queue = append(queue, m)
+			}
+		}
+	}
+	return rerun, nil
+}
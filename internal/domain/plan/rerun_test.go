@@ -0,0 +1,101 @@
+package plan_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+)
+
+func newFailedPlan() *plan.ExecutionPlan {
+	return &plan.ExecutionPlan{
+		ID:       "p1",
+		Name:     "build",
+		Protocol: plan.ProtocolParallel,
+		Status:   plan.StatusFailed,
+		Steps: []plan.Step{
+			{ID: "s0", Status: plan.StepStatusCompleted, RunID: "r0", Attempt: 1},
+			{ID: "s1", Status: plan.StepStatusFailed, RunID: "r1", Attempt: 2, Error: "tests failed", DependsOn: []string{"s0"}},
+			{ID: "s2", Status: plan.StepStatusSkipped, DependsOn: []string{"s1"}},
+			{ID: "s3", Status: plan.StepStatusCompleted, RunID: "r3", Attempt: 1},
+		},
+	}
+}
+
+// rerunIndices returns the indices of the steps a rerun starts over.
+func rerunIndices(p *plan.ExecutionPlan) []int {
+	var idx []int
+	for i := range p.Steps {
+		if p.Steps[i].Status == plan.StepStatusPending {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+func TestRerun(t *testing.T) {
+	tests := []struct {
+		req  plan.RerunRequest
+		want []int
+	}{
+		{plan.RerunRequest{Mode: plan.RerunFailed}, []int{1, 2}},
+		{plan.RerunRequest{Mode: plan.RerunStep, StepID: "s0"}, []int{0, 1, 2}},
+		{plan.RerunRequest{Mode: plan.RerunFrom, StepID: "s2"}, []int{2, 3}},
+	}
+	for _, tt := range tests {
+		np, err := plan.Rerun(newFailedPlan(), &tt.req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.req.Mode, err)
+		}
+		if got := rerunIndices(np); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: expected steps %v to rerun, got %v", tt.req.Mode, tt.want, got)
+		}
+	}
+
+	np, err := plan.Rerun(newFailedPlan(), &plan.RerunRequest{Mode: plan.RerunFailed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if np.RerunOf != "p1" || np.Status != plan.StatusPending || np.Name != "build" {
+		t.Fatalf("unexpected plan %+v", np)
+	}
+	kept, rerun := np.Steps[0], np.Steps[1]
+	if kept.Status != plan.StepStatusCompleted || kept.RunID != "r0" || kept.Attempt != 1 {
+		t.Fatalf("expected the completed step kept with its run, got %+v", kept)
+	}
+	if rerun.RunID != "" || rerun.Attempt != 0 || rerun.Error != "" || !slices.Equal(rerun.DependsOn, []string{"0"}) {
+		t.Fatalf("expected the failed step reset with index references, got %+v", rerun)
+	}
+}
+
+func TestRerun_Errors(t *testing.T) {
+	running := newFailedPlan()
+	running.Status = plan.StatusRunning
+	completed := newFailedPlan()
+	for i := range completed.Steps {
+		completed.Steps[i].Status = plan.StepStatusCompleted
+	}
+	tests := []struct {
+		p    *plan.ExecutionPlan
+		req  plan.RerunRequest
+		want error
+	}{
+		{running, plan.RerunRequest{Mode: plan.RerunFailed}, plan.ErrRerunNotFinished},
+		{completed, plan.RerunRequest{Mode: plan.RerunFailed}, plan.ErrNothingToRerun},
+		{newFailedPlan(), plan.RerunRequest{Mode: plan.RerunStep, StepID: "nope"}, plan.ErrRerunStep},
+		{newFailedPlan(), plan.RerunRequest{Mode: "all"}, plan.ErrRerunMode},
+	}
+	for _, tt := range tests {
+		if _, err := plan.Rerun(tt.p, &tt.req); !errors.Is(err, tt.want) {
+			t.Errorf("%+v: expected %v, got %v", tt.req, tt.want, err)
+		}
+	}
+
+	debate := newFailedPlan()
+	debate.Protocol = plan.ProtocolPingPong
+	np, err := plan.Rerun(debate, &plan.RerunRequest{Mode: plan.RerunStep, StepID: "s3"})
+	if err != nil || len(rerunIndices(np)) != 4 {
+		t.Fatalf("expected a debate to rerun all its steps, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package plan
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// Retry limits.
+const (
+	MaxRetryAttempts = 10
+	MaxRetryBackoff  = time.Hour
+)
+
+var (
+	ErrRetryAttempts = errors.New("retry max_attempts must be between 0 and 10")
+	ErrRetryBackoff  = errors.New("retry backoff_seconds and backoff_factor must be >= 0")
+)
+
+// RetryPolicy controls how often a failed step is re-run before it counts
+// as failed for the plan, and with which agent and model.
+type RetryPolicy struct {
+	MaxAttempts    int      `json:"max_attempts"`              // Total attempts including the first; 0 or 1 disables retries
+	BackoffSeconds int      `json:"backoff_seconds,omitempty"` // Delay before the first retry
+	BackoffFactor  float64  `json:"backoff_factor,omitempty"`  // Delay multiplier per further retry; 0 keeps the delay constant
+	FallbackAgents []string `json:"fallback_agents,omitempty"` // Agents for retries 1..n; the last one is reused
+	FallbackModel  string   `json:"fallback_model,omitempty"`  // Model override for all retries
+}
+
+// Validate checks the retry policy for sane bounds.
+func (r *RetryPolicy) Validate() error {
+	if r.MaxAttempts < 0 || r.MaxAttempts > MaxRetryAttempts {
+		return ErrRetryAttempts
+	}
+	if r.BackoffSeconds < 0 || r.BackoffFactor < 0 {
+		return ErrRetryBackoff
+	}
+	return nil
+}
+
+// CanRetry reports whether another attempt is allowed after the given
+// number of attempts has been made.
+func (r *RetryPolicy) CanRetry(attempts int) bool {
+	return r != nil && attempts < r.MaxAttempts
+}
+
+// Backoff returns the delay before the given retry (1 = first retry).
+// The delay grows by BackoffFactor per retry and is capped at MaxRetryBackoff.
+func (r *RetryPolicy) Backoff(retry int) time.Duration {
+	if r == nil || r.BackoffSeconds == 0 || retry < 1 {
+		return 0
+	}
+	factor := r.BackoffFactor
+	if factor == 0 {
+		factor = 1
+	}
+	d := time.Duration(float64(r.BackoffSeconds) * math.Pow(factor, float64(retry-1)) * float64(time.Second))
+	if d > MaxRetryBackoff || d < 0 {
+		return MaxRetryBackoff
+	}
+	return d
+}
+
+// AgentFor returns the agent for the given attempt (1 = first attempt).
+// The first attempt uses the step's own agent; retries walk the fallback list.
+func (r *RetryPolicy) AgentFor(attempt int, agentID string) string {
+	if r == nil || attempt < 2 || len(r.FallbackAgents) == 0 {
+		return agentID
+	}
+	i := min(attempt-2, len(r.FallbackAgents)-1)
+	return r.FallbackAgents[i]
+}
+
+// ModelFor returns the model override for the given attempt, if any.
+func (r *RetryPolicy) ModelFor(attempt int) string {
+	if r == nil || attempt < 2 {
+		return ""
+	}
+	return r.FallbackModel
+}
+
+// ModelFor returns the model for the given attempt of the step: the retry
+// policy's fallback model on retries, otherwise the step's own override.
+func (s *Step) ModelFor(attempt int) string {
+	if m := s.Retry.ModelFor(attempt); m != "" {
+		return m
+	}
+	return s.Model
+}
+
+// StepAttempt records a failed attempt of a step.
+type StepAttempt struct {
+	Attempt  int       `json:"attempt"`
+	RunID    string    `json:"run_id"`
+	AgentID  string    `json:"agent_id"`
+	Model    string    `json:"model,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// RetryDue reports whether a step awaiting a retry may start at now.
+// Steps without a scheduled retry are always due.
+func (s *Step) RetryDue(now time.Time) bool {
+	return s.RetryAt == nil || !now.Before(*s.RetryAt)
+}
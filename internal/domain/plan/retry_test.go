@@ -0,0 +1,102 @@
+package plan_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	r := &plan.RetryPolicy{MaxAttempts: 4, BackoffSeconds: 10, BackoffFactor: 2}
+	tests := []struct {
+		retry int
+		want  time.Duration
+	}{
+		{0, 0},
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := r.Backoff(tt.retry); got != tt.want {
+			t.Errorf("Backoff(%d) = %s, want %s", tt.retry, got, tt.want)
+		}
+	}
+
+	constant := &plan.RetryPolicy{BackoffSeconds: 5}
+	if got := constant.Backoff(3); got != 5*time.Second {
+		t.Errorf("expected constant backoff, got %s", got)
+	}
+	huge := &plan.RetryPolicy{BackoffSeconds: 3600, BackoffFactor: 10}
+	if got := huge.Backoff(5); got != plan.MaxRetryBackoff {
+		t.Errorf("expected capped backoff, got %s", got)
+	}
+}
+
+func TestRetryPolicy_AgentAndModel(t *testing.T) {
+	r := &plan.RetryPolicy{MaxAttempts: 4, FallbackAgents: []string{"b", "c"}, FallbackModel: "gpt-4o"}
+	for attempt, want := range map[int]string{1: "a", 2: "b", 3: "c", 4: "c"} {
+		if got := r.AgentFor(attempt, "a"); got != want {
+			t.Errorf("AgentFor(%d) = %q, want %q", attempt, got, want)
+		}
+	}
+	if r.ModelFor(1) != "" || r.ModelFor(2) != "gpt-4o" {
+		t.Error("expected model override only on retries")
+	}
+
+	var none *plan.RetryPolicy
+	if none.CanRetry(0) || none.AgentFor(2, "a") != "a" {
+		t.Error("nil policy must not retry")
+	}
+	if !r.CanRetry(3) || r.CanRetry(4) {
+		t.Error("expected retries up to max_attempts")
+	}
+}
+
+func TestValidate_RetryPolicy(t *testing.T) {
+	req := validSequentialRequest()
+	req.Steps[0].Retry = &plan.RetryPolicy{MaxAttempts: 11}
+	if err := req.Validate(); !errors.Is(err, plan.ErrRetryAttempts) {
+		t.Fatalf("expected ErrRetryAttempts, got %v", err)
+	}
+	req.Steps[0].Retry = &plan.RetryPolicy{MaxAttempts: 3, BackoffSeconds: -1}
+	if err := req.Validate(); !errors.Is(err, plan.ErrRetryBackoff) {
+		t.Fatalf("expected ErrRetryBackoff, got %v", err)
+	}
+}
+
+func TestReadySteps_WaitsForRetry(t *testing.T) {
+	later := time.Now().Add(time.Minute)
+	steps := []plan.Step{
+		{ID: "s1", Status: plan.StepStatusPending, RetryAt: &later},
+		{ID: "s2", Status: plan.StepStatusPending},
+	}
+	ready := plan.ReadySteps(steps)
+	if len(ready) != 1 || ready[0] != "s2" {
+		t.Fatalf("expected [s2], got %v", ready)
+	}
+}
+
+func TestBuildGraph(t *testing.T) {
+	p := &plan.ExecutionPlan{
+		ID:     "p1",
+		Status: plan.StatusRunning,
+		Steps: []plan.Step{
+			{ID: "s1", Status: plan.StepStatusCompleted, Attempt: 2, Retry: &plan.RetryPolicy{MaxAttempts: 3},
+				Attempts: []plan.StepAttempt{{Attempt: 1, RunID: "r1", Error: "boom"}}},
+			{ID: "s2", Status: plan.StepStatusPending, DependsOn: []string{"s1"}},
+		},
+	}
+	g := plan.BuildGraph(p)
+	if len(g.Nodes) != 2 || len(g.Edges) != 1 || g.Edges[0].From != "s1" || g.Edges[0].To != "s2" {
+		t.Fatalf("unexpected graph %+v", g)
+	}
+	if g.Nodes[0].MaxAttempts != 3 || len(g.Nodes[0].Attempts) != 1 {
+		t.Fatalf("expected retry history on node, got %+v", g.Nodes[0])
+	}
+	if g.Nodes[1].MaxAttempts != 1 || g.Nodes[1].Attempts == nil {
+		t.Fatalf("expected defaults on node without retry, got %+v", g.Nodes[1])
+	}
+}
@@ -0,0 +1,248 @@
+package plan
+
+import (
+	"slices"
+	"time"
+)
+
+// Severities of validation findings. Errors keep a plan from running as
+// intended; warnings point at likely surprises.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Codes of validation findings.
+const (
+	FindingCycle        = "cycle"         // Step is part of a dependency cycle
+	FindingUnreachable  = "unreachable"   // Step waits on a step that never finishes
+	FindingMissingAgent = "missing_agent" // Step's agent does not exist
+	FindingMissingTask  = "missing_task"  // Step's task does not exist
+	FindingPolicy       = "policy"        // Step's policy profile is unknown or conflicts with its mode
+	FindingDeliverMode  = "deliver_mode"  // Step's deliver mode is not allowed or has nothing to deliver
+	FindingBudget       = "budget"        // Step's estimated cost exceeds its budget
+	FindingNoEstimate   = "no_estimate"   // No history to estimate the step's duration from
+)
+
+// Basis of a step estimate.
+const (
+	EstimateTask    = "task"    // Average of earlier runs of the step's task
+	EstimateProject = "project" // Average run cost of the project; no duration
+	EstimateNone    = "none"    // No history, or an approval step
+)
+
+// Finding is a problem found validating a plan.
+type Finding struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	StepID   string `json:"step_id,omitempty"`
+	Message  string `json:"message"`
+}
+
+// StepEstimate is the predicted cost and duration of one attempt of a step.
+type StepEstimate struct {
+	StepID   string        `json:"step_id"`
+	Name     string        `json:"name,omitempty"`
+	Basis    string        `json:"basis"`
+	CostUSD  float64       `json:"cost_usd"`
+	Tokens   int64         `json:"tokens"`
+	Duration time.Duration `json:"duration_ns"`
+	Start    time.Duration `json:"start_ns"` // Predicted offset from the plan's start
+}
+
+// Simulation is the outcome of validating a plan before it starts.
+type Simulation struct {
+	PlanID       string         `json:"plan_id"`
+	Valid        bool           `json:"valid"` // No finding is an error
+	Findings     []Finding      `json:"findings"`
+	Steps        []StepEstimate `json:"steps"`
+	CostUSD      float64        `json:"cost_usd"`
+	Tokens       int64          `json:"tokens"`
+	CriticalPath []string       `json:"critical_path"` // Step IDs whose durations add up to Duration
+	Duration     time.Duration  `json:"duration_ns"`   // Predicted wall-clock time
+}
+
+// Add records a finding and clears Valid for errors.
+func (s *Simulation) Add(severity, code, stepID, message string) {
+	s.Findings = append(s.Findings, Finding{Severity: severity, Code: code, StepID: stepID, Message: message})
+	if severity == SeverityError {
+		s.Valid = false
+	}
+}
+
+// upstream returns the IDs of the steps a stored step waits on: its
+// dependencies and its condition's source.
+func (st *Step) upstream() []string {
+	ids := st.DependsOn
+	if st.When != nil && st.When.Step != "" {
+		ids = append(slices.Clone(ids), st.When.Step)
+	}
+	return ids
+}
+
+// CheckGraph reports the steps of a stored plan that are part of a
+// dependency cycle and those that wait, directly or not, on a missing step
+// or a cycle, so they would never start.
+func CheckGraph(steps []Step) (cycle, unreachable []string) {
+	adj, missing := stepEdges(steps)
+	order, sorted := topoOrder(adj)
+
+	// A step is on a cycle if it can reach itself.
+	onCycle := make([]bool, len(steps))
+	for _, i := range order[sorted:] {
+		if reaches(adj, i, i) {
+			onCycle[i] = true
+		}
+	}
+
+	// Everything downstream of a missing step or a cycle is stuck.
+	var stuck []int
+	for i := range steps {
+		if missing[i] || onCycle[i] {
+			stuck = append(stuck, i)
+		}
+	}
+	seen := make([]bool, len(steps))
+	for len(stuck) > 0 {
+		n := stuck[0]
+		stuck = stuck[1:]
+		if !seen[n] {
+			seen[n] = true
+			stuck = append(stuck, adj[n]...)
+		}
+	}
+	for i := range steps {
+		switch {
+		case onCycle[i]:
+			cycle = append(cycle, steps[i].ID)
+		case seen[i]:
+			unreachable = append(unreachable, steps[i].ID)
+		}
+	}
+	return cycle, unreachable
+}
+
+// reaches reports whether target is reachable from a successor of from.
+func reaches(adj [][]int, from, target int) bool {
+	seen := make([]bool, len(adj))
+	stack := slices.Clone(adj[from])
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if n == target {
+			return true
+		}
+		if !seen[n] {
+			seen[n] = true
+			stack = append(stack, adj[n]...)
+		}
+	}
+	return false
+}
+
+// Schedule predicts when each step starts under the plan's protocol and
+// returns the critical path and the plan's wall-clock duration. Sequential
+// and ping-pong plans run one step after another in order; the other
+// protocols start a step once its upstream steps finished, without regard
+// to max_parallel. Steps on or behind a cycle start after their scheduled
+// upstream steps.
+func Schedule(p *ExecutionPlan, est []StepEstimate) (path []string, total time.Duration) {
+	if len(p.Steps) == 0 {
+		return []string{}, 0
+	}
+	end := func(i int) time.Duration { return est[i].Start + est[i].Duration }
+	prev := make([]int, len(p.Steps)) // Step finishing last before each one starts
+	if p.Protocol == ProtocolSequential || p.Protocol == ProtocolPingPong {
+		for i := range p.Steps {
+			prev[i] = i - 1
+			if i > 0 {
+				est[i].Start = end(i - 1)
+			}
+		}
+	} else {
+		index := make(map[string]int, len(p.Steps))
+		for i := range p.Steps {
+			index[p.Steps[i].ID] = i
+		}
+		adj, _ := stepEdges(p.Steps)
+		order, _ := topoOrder(adj)
+		done := make([]bool, len(p.Steps))
+		for _, i := range order {
+			prev[i] = -1
+			for _, dep := range p.Steps[i].upstream() {
+				if j, ok := index[dep]; ok && done[j] && (prev[i] < 0 || end(j) > end(prev[i])) {
+					prev[i] = j
+				}
+			}
+			if prev[i] >= 0 {
+				est[i].Start = end(prev[i])
+			}
+			done[i] = true
+		}
+	}
+
+	last := 0
+	for i := range est {
+		if end(i) > end(last) {
+			last = i
+		}
+	}
+	for i := last; i >= 0; i = prev[i] {
+		path = append(path, p.Steps[i].ID)
+	}
+	slices.Reverse(path)
+	return path, end(last)
+}
+
+// stepEdges returns, for each step index, the indices of the steps waiting
+// on it, and which steps wait on a step the plan lacks.
+func stepEdges(steps []Step) (adj [][]int, missing []bool) {
+	index := make(map[string]int, len(steps))
+	for i := range steps {
+		index[steps[i].ID] = i
+	}
+	adj = make([][]int, len(steps))
+	missing = make([]bool, len(steps))
+	for i := range steps {
+		for _, dep := range steps[i].upstream() {
+			if j, ok := index[dep]; ok {
+				adj[j] = append(adj[j], i)
+			} else {
+				missing[i] = true
+			}
+		}
+	}
+	return adj, missing
+}
+
+// topoOrder sorts the nodes of a graph with Kahn's algorithm. The first
+// sorted nodes come after all their predecessors; the rest, on or behind a
+// cycle, follow in index order.
+func topoOrder(adj [][]int) (order []int, sorted int) {
+	inDegree := make([]int, len(adj))
+	for _, next := range adj {
+		for _, m := range next {
+			inDegree[m]++
+		}
+	}
+	order = make([]int, 0, len(adj))
+	for i, d := range inDegree {
+		if d == 0 {
+			order = append(order, i)
+		}
+	}
+	for k := 0; k < len(order); k++ {
+		for _, m := range adj[order[k]] {
+			if inDegree[m]--; inDegree[m] == 0 {
+				order = append(order, m)
+			}
+		}
+	}
+	sorted = len(order)
+	for i, d := range inDegree {
+		if d > 0 {
+			order = append(order, i)
+		}
+	}
+	return order, sorted
+}
@@ -0,0 +1,67 @@
+package plan_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+)
+
+func TestCheckGraph(t *testing.T) {
+	steps := []plan.Step{
+		{ID: "s1"},
+		{ID: "s2", DependsOn: []string{"s1", "s3"}},
+		{ID: "s3", DependsOn: []string{"s2"}},
+		{ID: "s4", DependsOn: []string{"s3"}},
+		{ID: "s5", When: &plan.Condition{Step: "gone"}},
+		{ID: "s6", DependsOn: []string{"s1"}},
+	}
+	cycle, unreachable := plan.CheckGraph(steps)
+	if !slices.Equal(cycle, []string{"s2", "s3"}) {
+		t.Errorf("expected cycle [s2 s3], got %v", cycle)
+	}
+	if !slices.Equal(unreachable, []string{"s4", "s5"}) {
+		t.Errorf("expected unreachable [s4 s5], got %v", unreachable)
+	}
+
+	cycle, unreachable = plan.CheckGraph(steps[:1])
+	if cycle != nil || unreachable != nil {
+		t.Errorf("expected a clean graph, got %v, %v", cycle, unreachable)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	steps := []plan.Step{
+		{ID: "a"},
+		{ID: "b", DependsOn: []string{"a"}},
+		{ID: "c", DependsOn: []string{"a"}},
+		{ID: "d", DependsOn: []string{"b", "c"}},
+	}
+	durations := []time.Duration{time.Minute, 5 * time.Minute, 2 * time.Minute, time.Minute}
+	estimates := func() []plan.StepEstimate {
+		est := make([]plan.StepEstimate, len(steps))
+		for i := range est {
+			est[i] = plan.StepEstimate{StepID: steps[i].ID, Duration: durations[i]}
+		}
+		return est
+	}
+
+	est := estimates()
+	path, total := plan.Schedule(&plan.ExecutionPlan{Protocol: plan.ProtocolParallel, Steps: steps}, est)
+	if !slices.Equal(path, []string{"a", "b", "d"}) || total != 7*time.Minute {
+		t.Fatalf("expected critical path a-b-d of 7m, got %v of %s", path, total)
+	}
+	if est[2].Start != time.Minute || est[3].Start != 6*time.Minute {
+		t.Fatalf("unexpected starts %+v", est)
+	}
+
+	path, total = plan.Schedule(&plan.ExecutionPlan{Protocol: plan.ProtocolSequential, Steps: steps}, estimates())
+	if len(path) != 4 || total != 9*time.Minute {
+		t.Fatalf("expected all steps in turn for 9m, got %v of %s", path, total)
+	}
+
+	if path, total := plan.Schedule(&plan.ExecutionPlan{Protocol: plan.ProtocolParallel}, nil); len(path) != 0 || total != 0 {
+		t.Fatalf("expected an empty schedule, got %v of %s", path, total)
+	}
+}
@@ -41,12 +41,41 @@ func (r *CreatePlanRequest) Validate() error {
 	}
 
 	for i, s := range r.Steps {
+		switch s.Type {
+		case "", StepTypeRun:
+		case StepTypeApproval:
+			if r.Protocol != ProtocolSequential && r.Protocol != ProtocolParallel {
+				return fmt.Errorf("step %d: %w", i, ErrApprovalProtocol)
+			}
+			continue // approval steps have no task or agent
+		case StepTypeImage:
+			if r.Protocol != ProtocolSequential && r.Protocol != ProtocolParallel {
+				return fmt.Errorf("step %d: %w", i, ErrImageProtocol)
+			}
+			if s.Image == nil {
+				return fmt.Errorf("step %d: %w", i, ErrImageSpec)
+			}
+			if len(s.DependsOn) == 0 && (r.Protocol != ProtocolSequential || i == 0) {
+				return fmt.Errorf("step %d: %w", i, ErrImageSource)
+			}
+			if err := s.Image.Validate(); err != nil {
+				return fmt.Errorf("step %d: %w", i, err)
+			}
+			continue // image steps build the workspace of the run before them
+		default:
+			return fmt.Errorf("step %d: %w", i, ErrInvalidStepType)
+		}
 		if s.TaskID == "" {
 			return fmt.Errorf("step %d: %w", i, ErrStepMissingTask)
 		}
 		if s.AgentID == "" {
 			return fmt.Errorf("step %d: %w", i, ErrStepMissingAgent)
 		}
+		if s.Retry != nil {
+			if err := s.Retry.Validate(); err != nil {
+				return fmt.Errorf("step %d: %w", i, err)
+			}
+		}
 	}
 
 	// Protocol-specific checks
@@ -67,17 +96,24 @@ func (r *CreatePlanRequest) Validate() error {
 		}
 	}
 
+	if err := validateStepNames(r.Steps); err != nil {
+		return err
+	}
+	if err := validateFlow(r.Protocol, r.Steps); err != nil {
+		return err
+	}
 	return validateDAG(r.Steps)
 }
 
-// validateDAG checks that step dependencies form a valid DAG using Kahn's algorithm.
+// validateDAG checks that step dependencies, including condition sources,
+// form a valid DAG using Kahn's algorithm.
 func validateDAG(steps []CreateStepRequest) error {
 	n := len(steps)
 	inDegree := make([]int, n)
 	adj := make([][]int, n)
 
 	for i, s := range steps {
-		for _, dep := range s.DependsOn {
+		for _, dep := range upstreamRefs(s.DependsOn, s.When) {
 			idx, err := strconv.Atoi(dep)
 			if err != nil || idx < 0 || idx >= n {
 				return fmt.Errorf("step %d depends on %q: %w", i, dep, ErrDAGInvalidRef)
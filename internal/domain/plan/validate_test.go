@@ -227,3 +227,28 @@ func TestValidate_MaxParallelNegative(t *testing.T) {
 		t.Fatalf("expected ErrMaxParallelNegative, got %v", err)
 	}
 }
+
+func TestValidate_ApprovalStep(t *testing.T) {
+	req := validSequentialRequest()
+	req.Steps = append(req.Steps, plan.CreateStepRequest{Type: plan.StepTypeApproval, DependsOn: []string{"1"}})
+	if err := req.Validate(); err != nil {
+		t.Fatalf("expected approval step without task/agent to be valid, got %v", err)
+	}
+
+	req.Steps[2].Type = "review"
+	if err := req.Validate(); !errors.Is(err, plan.ErrInvalidStepType) {
+		t.Fatalf("expected ErrInvalidStepType, got %v", err)
+	}
+
+	consensus := plan.CreatePlanRequest{
+		Name:     "vote",
+		Protocol: plan.ProtocolConsensus,
+		Steps: []plan.CreateStepRequest{
+			{TaskID: "t1", AgentID: "a1"},
+			{Type: plan.StepTypeApproval},
+		},
+	}
+	if err := consensus.Validate(); !errors.Is(err, plan.ErrApprovalProtocol) {
+		t.Fatalf("expected ErrApprovalProtocol, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package policy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ApprovalAction resolves an approval that nobody decided in time.
+type ApprovalAction string
+
+const (
+	ApprovalReject  ApprovalAction = "reject"  // Fail the approval step (default)
+	ApprovalApprove ApprovalAction = "approve" // Complete the approval step
+)
+
+// ApproverServer is the approver of votes made with the configured keys
+// (server.api_keys); managed keys are named ApproverKeyPrefix + key ID and
+// Slack users voting with the buttons of interactive Slack notifications
+// ApproverSlackPrefix + Slack user ID.
+const (
+	ApproverServer      = "server"
+	ApproverKeyPrefix   = "api_key:"
+	ApproverSlackPrefix = "slack:"
+)
+
+// ApprovalPolicy governs the approval steps of plans under a profile: who
+// may decide, how many approvals are needed, and what happens when the
+// decision takes too long. Voters are identified by the credential they
+// authenticated with, so approvers are API key IDs, not key names, which
+// need not be unique.
+type ApprovalPolicy struct {
+	Approvers            []string       `json:"approvers,omitempty" yaml:"approvers,omitempty"`                           // Credentials that may decide: "api_key:<id>", "slack:<user id>" or "server"
+	ApproverRoles        []string       `json:"approver_roles,omitempty" yaml:"approver_roles,omitempty"`                 // Roles of API keys that may decide; with approvers, either qualifies
+	Quorum               int            `json:"quorum,omitempty" yaml:"quorum,omitempty"`                                 // Approvals needed; 0 needs one. A single rejection rejects
+	TimeoutSeconds       int            `json:"timeout_seconds,omitempty" yaml:"timeout_seconds,omitempty"`               // Apply TimeoutAction after this long; 0 waits indefinitely
+	TimeoutAction        ApprovalAction `json:"timeout_action,omitempty" yaml:"timeout_action,omitempty"`                 // Default: reject
+	EscalateAfterSeconds int            `json:"escalate_after_seconds,omitempty" yaml:"escalate_after_seconds,omitempty"` // Announce the approval as escalated after this long; 0 never
+	EscalateTo           []string       `json:"escalate_to,omitempty" yaml:"escalate_to,omitempty"`                       // Who the escalation names
+}
+
+// Validate checks that an ApprovalPolicy is well-formed.
+func (a *ApprovalPolicy) Validate() error {
+	if a.Quorum < 0 {
+		return fmt.Errorf("quorum must be >= 0")
+	}
+	for _, by := range a.Approvers {
+		if by != ApproverServer && !prefixedID(by, ApproverKeyPrefix) && !prefixedID(by, ApproverSlackPrefix) {
+			return fmt.Errorf("approver %q must be %q, an API key as %q or a Slack user as %q",
+				by, ApproverServer, ApproverKeyPrefix+"<id>", ApproverSlackPrefix+"<user id>")
+		}
+	}
+	if len(a.Approvers) > 0 && len(a.ApproverRoles) == 0 && a.Quorum > len(a.Approvers) {
+		return fmt.Errorf("quorum %d exceeds the %d approvers", a.Quorum, len(a.Approvers))
+	}
+	if a.TimeoutSeconds < 0 || a.EscalateAfterSeconds < 0 {
+		return fmt.Errorf("timeout_seconds and escalate_after_seconds must be >= 0")
+	}
+	switch a.TimeoutAction {
+	case "", ApprovalReject, ApprovalApprove:
+	default:
+		return fmt.Errorf("invalid timeout_action %q", a.TimeoutAction)
+	}
+	if a.TimeoutSeconds > 0 && a.EscalateAfterSeconds >= a.TimeoutSeconds {
+		return fmt.Errorf("escalate_after_seconds must be less than timeout_seconds")
+	}
+	return nil
+}
+
+// Required returns the number of approvals needed. A nil policy needs one.
+func (a *ApprovalPolicy) Required() int {
+	if a == nil {
+		return 1
+	}
+	return max(a.Quorum, 1)
+}
+
+// Restricted reports whether only some voters may decide.
+func (a *ApprovalPolicy) Restricted() bool {
+	return a != nil && (len(a.Approvers) > 0 || len(a.ApproverRoles) > 0)
+}
+
+// Allows reports whether the voter authenticated as by, holding roles, may
+// decide: it is one of the approvers or holds one of the approver roles. A
+// nil or unrestricted policy allows anyone.
+func (a *ApprovalPolicy) Allows(by string, roles []string) bool {
+	if !a.Restricted() {
+		return true
+	}
+	return slices.Contains(a.Approvers, by) ||
+		slices.ContainsFunc(roles, func(r string) bool { return slices.Contains(a.ApproverRoles, r) })
+}
+
+// Action returns the action applied on timeout.
+func (a *ApprovalPolicy) Action() ApprovalAction {
+	if a == nil || a.TimeoutAction == "" {
+		return ApprovalReject
+	}
+	return a.TimeoutAction
+}
+
+// Deadline returns when an approval requested at requested times out, and
+// false if it never does.
+func (a *ApprovalPolicy) Deadline(requested time.Time) (time.Time, bool) {
+	if a == nil || a.TimeoutSeconds == 0 {
+		return time.Time{}, false
+	}
+	return requested.Add(time.Duration(a.TimeoutSeconds) * time.Second), true
+}
+
+// EscalationAt returns when an approval requested at requested is
+// escalated, and false if it never is.
+func (a *ApprovalPolicy) EscalationAt(requested time.Time) (time.Time, bool) {
+	if a == nil || a.EscalateAfterSeconds == 0 {
+		return time.Time{}, false
+	}
+	return requested.Add(time.Duration(a.EscalateAfterSeconds) * time.Second), true
+}
+
+// prefixedID reports whether s is prefix followed by an ID.
+func prefixedID(s, prefix string) bool {
+	return strings.HasPrefix(s, prefix) && len(s) > len(prefix)
+}
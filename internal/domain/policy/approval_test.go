@@ -0,0 +1,58 @@
+package policy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApprovalPolicyValidate(t *testing.T) {
+	for _, a := range []ApprovalPolicy{
+		{Quorum: -1},
+		{Approvers: []string{"api_key:k1"}, Quorum: 2},
+		{Approvers: []string{"alice"}}, // Key names are not unique
+		{Approvers: []string{"slack:"}},
+		{TimeoutSeconds: -1},
+		{TimeoutAction: "ignore"},
+		{TimeoutSeconds: 60, EscalateAfterSeconds: 60},
+	} {
+		if err := a.Validate(); err == nil {
+			t.Errorf("expected error for %+v", a)
+		}
+	}
+	valid := ApprovalPolicy{
+		Approvers: []string{"api_key:k1", "server", "slack:U024BE7LH"}, Quorum: 2,
+		TimeoutSeconds: 3600, TimeoutAction: ApprovalApprove, EscalateAfterSeconds: 600,
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	// With roles, more voters than the listed approvers may qualify.
+	roles := ApprovalPolicy{Approvers: []string{"api_key:k1"}, ApproverRoles: []string{"release"}, Quorum: 2}
+	if err := roles.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApprovalPolicyNil(t *testing.T) {
+	var a *ApprovalPolicy
+	if a.Required() != 1 || !a.Allows("anyone", nil) || a.Action() != ApprovalReject {
+		t.Error("expected a nil policy to let the first vote from anyone decide")
+	}
+	if _, ok := a.Deadline(time.Now()); ok {
+		t.Error("expected a nil policy never to time out")
+	}
+}
+
+func TestApprovalPolicyDeadlines(t *testing.T) {
+	a := &ApprovalPolicy{Approvers: []string{"api_key:k1"}, ApproverRoles: []string{"release"}, TimeoutSeconds: 60, EscalateAfterSeconds: 30}
+	if a.Allows("api_key:k2", []string{"dev"}) || !a.Allows("api_key:k1", nil) || !a.Allows("api_key:k3", []string{"dev", "release"}) {
+		t.Error("expected only listed approvers and holders of approver roles to be allowed")
+	}
+	requested := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if d, ok := a.Deadline(requested); !ok || !d.Equal(requested.Add(time.Minute)) {
+		t.Errorf("unexpected deadline %v %v", d, ok)
+	}
+	if e, ok := a.EscalationAt(requested); !ok || !e.Equal(requested.Add(30*time.Second)) {
+		t.Errorf("unexpected escalation %v %v", e, ok)
+	}
+}
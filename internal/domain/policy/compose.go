@@ -0,0 +1,75 @@
+package policy
+
+import "strings"
+
+// Level identifies where a policy layer comes from. Layers are ordered from
+// least to most specific: tenant, project, agent, run. An explicit run
+// profile is layered on top.
+type Level string
+
+const (
+	LevelTenant  Level = "tenant"  // Service-wide default profile
+	LevelProject Level = "project" // Project config override
+	LevelAgent   Level = "agent"   // Agent config override
+	LevelRun     Level = "run"     // Explicit profile on the run request
+)
+
+// ConfigKeyProfile is the project/agent config key holding a policy override.
+const ConfigKeyProfile = "policy_profile"
+
+// CompositeSeparator joins layer profile names into a composite profile name,
+// e.g. "headless-safe-sandbox>plan-readonly".
+const CompositeSeparator = ">"
+
+// Layer is a single named profile at a given level.
+type Layer struct {
+	Level   Level  `json:"level"`
+	Profile string `json:"profile"`
+}
+
+// RunContext identifies the project and agent whose overrides are layered on
+// top of the tenant default when resolving an effective policy.
+type RunContext struct {
+	ProjectID     string `json:"project_id,omitempty"`
+	AgentID       string `json:"agent_id,omitempty"`
+	PolicyProfile string `json:"policy_profile,omitempty"` // Explicit profile layered on top
+}
+
+// EffectivePolicy is the result of merging policy layers.
+// Tool calls are evaluated per layer (first match within a layer) and the
+// most specific layer with a matching rule decides, so an override can
+// loosen or tighten the layers below it. Mode, quality gate and termination
+// come from the most specific layer; isolation is the strongest of all
+// layers and egress the intersection of all layers, so no override can
+// weaken them. The approval policy comes from the most specific layer that
+// has one.
+type EffectivePolicy struct {
+	Name        string               `json:"name"`
+	Layers      []Layer              `json:"layers"`
+	Mode        PermissionMode       `json:"mode"`
+	QualityGate QualityGate          `json:"quality_gate"`
+	Termination TerminationCondition `json:"termination"`
+	Isolation   Isolation            `json:"isolation,omitempty"`
+	Egress      *EgressPolicy        `json:"egress,omitempty"`
+	Approval    *ApprovalPolicy      `json:"approval,omitempty"`
+}
+
+// CompositeName joins layer profile names into a composite profile name.
+// A single layer yields the plain profile name.
+func CompositeName(layers []Layer) string {
+	names := make([]string, len(layers))
+	for i := range layers {
+		names[i] = layers[i].Profile
+	}
+	return strings.Join(names, CompositeSeparator)
+}
+
+// IsComposite reports whether name refers to more than one layer.
+func IsComposite(name string) bool {
+	return strings.Contains(name, CompositeSeparator)
+}
+
+// SplitComposite returns the profile names of a composite name, least specific first.
+func SplitComposite(name string) []string {
+	return strings.Split(name, CompositeSeparator)
+}
@@ -0,0 +1,160 @@
+package policy
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// PackageRegistries are the hosts allowed by EgressPolicy.AllowPackageRegistries.
+var PackageRegistries = []string{
+	"pypi.org",
+	"files.pythonhosted.org",
+	"registry.npmjs.org",
+	"registry.yarnpkg.com",
+	"proxy.golang.org",
+	"sum.golang.org",
+	"crates.io",
+	"static.crates.io",
+	"index.crates.io",
+	"rubygems.org",
+	"repo.maven.apache.org",
+}
+
+// EgressPolicy restricts the network destinations a sandboxed run may reach.
+// A profile with an egress policy denies all egress except the allowed
+// domains, CIDRs and, optionally, the well-known package registries.
+type EgressPolicy struct {
+	AllowDomains           []string `json:"allow_domains,omitempty" yaml:"allow_domains,omitempty"` // Exact host or "*.example.com" for its subdomains
+	AllowCIDRs             []string `json:"allow_cidrs,omitempty" yaml:"allow_cidrs,omitempty"`
+	AllowPackageRegistries bool     `json:"allow_package_registries,omitempty" yaml:"allow_package_registries,omitempty"`
+}
+
+// Validate checks that an EgressPolicy is well-formed.
+func (e *EgressPolicy) Validate() error {
+	for _, d := range e.AllowDomains {
+		if strings.TrimPrefix(d, "*.") == "" || strings.ContainsAny(d, "/: ") {
+			return fmt.Errorf("invalid domain %q", d)
+		}
+	}
+	for _, c := range e.AllowCIDRs {
+		if _, err := netip.ParsePrefix(c); err != nil {
+			return fmt.Errorf("invalid cidr %q", c)
+		}
+	}
+	return nil
+}
+
+// AllowsDomain reports whether host may be reached by name.
+func (e *EgressPolicy) AllowsDomain(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, d := range e.domains() {
+		if matchDomain(strings.ToLower(d), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsAddr reports whether addr lies in one of the allowed CIDRs.
+func (e *EgressPolicy) AllowsAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, c := range e.AllowCIDRs {
+		if p, err := netip.ParsePrefix(c); err == nil && p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *EgressPolicy) domains() []string {
+	if !e.AllowPackageRegistries {
+		return e.AllowDomains
+	}
+	return append(append([]string(nil), e.AllowDomains...), PackageRegistries...)
+}
+
+// matchDomain matches host against an exact domain or a "*.suffix" pattern,
+// which matches subdomains of suffix only.
+func matchDomain(pattern, host string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return pattern == host
+}
+
+// coversDomain reports whether every host matched by pattern is allowed.
+func (e *EgressPolicy) coversDomain(pattern string) bool {
+	suffix, ok := strings.CutPrefix(pattern, "*.")
+	if !ok {
+		return e.AllowsDomain(pattern)
+	}
+	for _, d := range e.domains() {
+		if d == pattern {
+			return true
+		}
+		if parent, ok := strings.CutPrefix(d, "*."); ok && strings.HasSuffix(suffix, "."+parent) {
+			return true
+		}
+	}
+	return false
+}
+
+// coversPrefix reports whether every address in prefix is allowed.
+func (e *EgressPolicy) coversPrefix(prefix netip.Prefix) bool {
+	for _, c := range e.AllowCIDRs {
+		if p, err := netip.ParsePrefix(c); err == nil && p.Bits() <= prefix.Bits() && p.Contains(prefix.Addr()) {
+			return true
+		}
+	}
+	return false
+}
+
+// IntersectEgress returns the egress policy allowing only destinations that
+// all given policies allow. Nil policies are unrestricted; the result is
+// nil if all are.
+func IntersectEgress(policies ...*EgressPolicy) *EgressPolicy {
+	var out *EgressPolicy
+	for _, e := range policies {
+		if e == nil {
+			continue
+		}
+		if out == nil {
+			c := *e
+			out = &c
+			continue
+		}
+		out = intersect(out, e)
+	}
+	return out
+}
+
+func intersect(a, b *EgressPolicy) *EgressPolicy {
+	out := &EgressPolicy{AllowPackageRegistries: a.AllowPackageRegistries && b.AllowPackageRegistries}
+	seen := make(map[string]bool)
+	addDomain := func(d string) {
+		if !seen[d] {
+			seen[d] = true
+			out.AllowDomains = append(out.AllowDomains, d)
+		}
+	}
+	for _, d := range a.AllowDomains {
+		if b.coversDomain(d) {
+			addDomain(d)
+		}
+	}
+	for _, d := range b.domains() {
+		if a.coversDomain(d) && !out.AllowsDomain(d) {
+			addDomain(d)
+		}
+	}
+	for _, pair := range [][2]*EgressPolicy{{a, b}, {b, a}} {
+		for _, c := range pair[0].AllowCIDRs {
+			p, err := netip.ParsePrefix(c)
+			if err == nil && pair[1].coversPrefix(p) && !out.coversPrefix(p) {
+				out.AllowCIDRs = append(out.AllowCIDRs, c)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+package policy
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestEgressPolicyAllows(t *testing.T) {
+	e := &EgressPolicy{
+		AllowDomains:           []string{"github.com", "*.githubusercontent.com"},
+		AllowCIDRs:             []string{"10.0.0.0/8"},
+		AllowPackageRegistries: true,
+	}
+	for host, want := range map[string]bool{
+		"github.com":                            true,
+		"GitHub.com.":                           true,
+		"api.github.com":                        false,
+		"raw.githubusercontent.com":             true,
+		"githubusercontent.com":                 false,
+		"pypi.org":                              true,
+		"evil.com":                              false,
+		"github.com.evil.com":                   false,
+		"objects.githubusercontent.com.evil.io": false,
+	} {
+		if got := e.AllowsDomain(host); got != want {
+			t.Errorf("AllowsDomain(%q) = %v, want %v", host, got, want)
+		}
+	}
+	if !e.AllowsAddr(netip.MustParseAddr("10.1.2.3")) || e.AllowsAddr(netip.MustParseAddr("192.168.1.1")) {
+		t.Error("unexpected CIDR decision")
+	}
+	if !e.AllowsAddr(netip.MustParseAddr("::ffff:10.0.0.1")) {
+		t.Error("expected IPv4-mapped address to match")
+	}
+}
+
+func TestEgressPolicyValidate(t *testing.T) {
+	for _, e := range []EgressPolicy{
+		{AllowDomains: []string{"*."}},
+		{AllowDomains: []string{"https://github.com"}},
+		{AllowCIDRs: []string{"10.0.0.0/33"}},
+	} {
+		if err := e.Validate(); err == nil {
+			t.Errorf("expected error for %+v", e)
+		}
+	}
+	if err := (&EgressPolicy{AllowDomains: []string{"*.example.com"}, AllowCIDRs: []string{"fd00::/8"}}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIntersectEgress(t *testing.T) {
+	if IntersectEgress(nil, nil) != nil {
+		t.Fatal("expected nil for unrestricted layers")
+	}
+	tenant := &EgressPolicy{AllowDomains: []string{"*.example.com", "github.com"}, AllowCIDRs: []string{"10.0.0.0/8"}, AllowPackageRegistries: true}
+	if got := IntersectEgress(nil, tenant); got == tenant || !slices.Equal(got.AllowDomains, tenant.AllowDomains) {
+		t.Fatalf("expected a copy of the only restricting layer, got %+v", got)
+	}
+
+	project := &EgressPolicy{AllowDomains: []string{"api.example.com", "*.b.example.com", "gitlab.com", "pypi.org"}, AllowCIDRs: []string{"10.1.0.0/16", "192.168.0.0/16"}}
+	got := IntersectEgress(tenant, project)
+	for host, want := range map[string]bool{
+		"api.example.com":    true,
+		"x.b.example.com":    true,
+		"www.example.com":    false,
+		"github.com":         false,
+		"gitlab.com":         false,
+		"pypi.org":           true,
+		"registry.npmjs.org": false,
+	} {
+		if got.AllowsDomain(host) != want {
+			t.Errorf("intersection AllowsDomain(%q) = %v, want %v", host, !want, want)
+		}
+	}
+	if !got.AllowsAddr(netip.MustParseAddr("10.1.0.1")) || got.AllowsAddr(netip.MustParseAddr("10.2.0.1")) || got.AllowsAddr(netip.MustParseAddr("192.168.0.1")) {
+		t.Errorf("unexpected intersected CIDRs %v", got.AllowCIDRs)
+	}
+}
@@ -37,6 +37,8 @@ type PermissionRule struct {
 	PathDeny     []string      `json:"path_deny,omitempty" yaml:"path_deny,omitempty"`
 	CommandAllow []string      `json:"command_allow,omitempty" yaml:"command_allow,omitempty"`
 	CommandDeny  []string      `json:"command_deny,omitempty" yaml:"command_deny,omitempty"`
+	DomainAllow  []string      `json:"domain_allow,omitempty" yaml:"domain_allow,omitempty"`
+	DomainDeny   []string      `json:"domain_deny,omitempty" yaml:"domain_deny,omitempty"`
 }
 
 // QualityGate defines the "Definition of Done" for a task.
@@ -46,6 +48,45 @@ type QualityGate struct {
 	RollbackOnGateFail bool `json:"rollback_on_gate_fail" yaml:"rollback_on_gate_fail"`
 }
 
+// Isolation is the sandbox isolation a profile requires for its runs.
+type Isolation string
+
+const (
+	IsolationContainer Isolation = "container" // Plain container; the default
+	IsolationGVisor    Isolation = "gvisor"    // gVisor user-space kernel (runsc)
+	IsolationMicroVM   Isolation = "microvm"   // Firecracker microVM
+)
+
+// IsolationStrength ranks isolation levels: microvm > gvisor > container.
+// An empty level counts as container.
+func IsolationStrength(i Isolation) int {
+	switch i {
+	case IsolationGVisor:
+		return 1
+	case IsolationMicroVM:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// StrongestIsolation returns the strongest of the given isolation levels.
+func StrongestIsolation(levels ...Isolation) Isolation {
+	var out Isolation
+	for _, l := range levels {
+		if out == "" || IsolationStrength(l) > IsolationStrength(out) {
+			out = l
+		}
+	}
+	return out
+}
+
+// Untrusted reports whether runs under the profile require stronger
+// isolation than a plain container.
+func (p *PolicyProfile) Untrusted() bool {
+	return IsolationStrength(p.Isolation) > 0
+}
+
 // TerminationCondition defines when an agent run should stop.
 type TerminationCondition struct {
 	MaxSteps       int     `json:"max_steps,omitempty" yaml:"max_steps,omitempty"`
@@ -63,6 +104,9 @@ type PolicyProfile struct {
 	Rules       []PermissionRule     `json:"rules" yaml:"rules"`
 	QualityGate QualityGate          `json:"quality_gate" yaml:"quality_gate"`
 	Termination TerminationCondition `json:"termination" yaml:"termination"`
+	Isolation   Isolation            `json:"isolation,omitempty" yaml:"isolation,omitempty"`
+	Egress      *EgressPolicy        `json:"egress,omitempty" yaml:"egress,omitempty"`     // Nil leaves sandbox egress unrestricted
+	Approval    *ApprovalPolicy      `json:"approval,omitempty" yaml:"approval,omitempty"` // Governs plan approval steps; nil lets anyone decide alone
 }
 
 // ToolCall represents a request to use a tool, submitted to the policy evaluator.
@@ -70,4 +114,5 @@ type ToolCall struct {
 	Tool    string `json:"tool"`
 	Command string `json:"command,omitempty"`
 	Path    string `json:"path,omitempty"`
+	URL     string `json:"url,omitempty"`
 }
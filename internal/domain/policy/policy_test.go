@@ -63,6 +63,16 @@ func TestPolicyProfileValidateErrors(t *testing.T) {
 			modify: func(p *PolicyProfile) { p.Termination.MaxCost = -0.5 },
 			errStr: "max_cost must be >= 0",
 		},
+		{
+			name:   "invalid isolation",
+			modify: func(p *PolicyProfile) { p.Isolation = "vm" },
+			errStr: "invalid isolation",
+		},
+		{
+			name:   "invalid egress cidr",
+			modify: func(p *PolicyProfile) { p.Egress = &EgressPolicy{AllowCIDRs: []string{"10.0.0.0"}} },
+			errStr: "egress: invalid cidr",
+		},
 	}
 
 	for _, tt := range tests {
@@ -113,3 +123,20 @@ func TestIsValidDecision(t *testing.T) {
 		t.Error("expected 'maybe' to be invalid")
 	}
 }
+
+func TestStrongestIsolation(t *testing.T) {
+	tests := []struct {
+		levels []Isolation
+		want   Isolation
+	}{
+		{nil, ""},
+		{[]Isolation{"", IsolationContainer}, IsolationContainer},
+		{[]Isolation{IsolationGVisor, IsolationContainer}, IsolationGVisor},
+		{[]Isolation{IsolationGVisor, "", IsolationMicroVM}, IsolationMicroVM},
+	}
+	for _, tt := range tests {
+		if got := StrongestIsolation(tt.levels...); got != tt.want {
+			t.Errorf("StrongestIsolation(%v) = %q, want %q", tt.levels, got, tt.want)
+		}
+	}
+}
@@ -98,6 +98,18 @@ func PresetHeadlessPermissiveSandbox() PolicyProfile {
 	}
 }
 
+// PresetHeadlessUntrustedSandbox returns the "headless-untrusted-sandbox" preset.
+// Untrusted code, e.g. public repositories: safe-sandbox rules in a gVisor
+// sandbox instead of a plain container.
+func PresetHeadlessUntrustedSandbox() PolicyProfile {
+	p := PresetHeadlessSafeSandbox()
+	p.Name = "headless-untrusted-sandbox"
+	p.Description = "Safe sandbox with gVisor isolation and registry-only egress for untrusted code such as public repositories."
+	p.Isolation = IsolationGVisor
+	p.Egress = &EgressPolicy{AllowPackageRegistries: true}
+	return p
+}
+
 // PresetTrustedMountAutonomous returns the "trusted-mount-autonomous" preset.
 // Power-user: direct mount, all local tools allowed, minimal restrictions.
 func PresetTrustedMountAutonomous() PolicyProfile {
@@ -131,13 +143,49 @@ func PresetTrustedMountAutonomous() PolicyProfile {
 	}
 }
 
+// PresetResearchWeb returns the "research-web" preset.
+// Research runs: read-only workspace access plus web search, with page
+// fetches restricted to an allowlist of documentation domains.
+func PresetResearchWeb() PolicyProfile {
+	return PolicyProfile{
+		Name:        "research-web",
+		Description: "Read-only research with web search. Fetches limited to allowlisted documentation domains.",
+		Mode:        ModePlan,
+		Rules: []PermissionRule{
+			{Specifier: ToolSpecifier{Tool: "Read"}, Decision: DecisionAllow},
+			{Specifier: ToolSpecifier{Tool: "Glob"}, Decision: DecisionAllow},
+			{Specifier: ToolSpecifier{Tool: "Grep"}, Decision: DecisionAllow},
+			{Specifier: ToolSpecifier{Tool: "WebSearch"}, Decision: DecisionAllow},
+			{
+				Specifier: ToolSpecifier{Tool: "WebFetch"},
+				Decision:  DecisionAllow,
+				DomainAllow: []string{
+					"*.go.dev", "docs.python.org", "*.mozilla.org", "*.github.com",
+					"*.readthedocs.io", "*.rust-lang.org", "stackoverflow.com", "*.wikipedia.org",
+				},
+			},
+			{Specifier: ToolSpecifier{Tool: "WebFetch"}, Decision: DecisionDeny},
+			{Specifier: ToolSpecifier{Tool: "Edit"}, Decision: DecisionDeny},
+			{Specifier: ToolSpecifier{Tool: "Write"}, Decision: DecisionDeny},
+			{Specifier: ToolSpecifier{Tool: "Bash"}, Decision: DecisionDeny},
+		},
+		Termination: TerminationCondition{
+			MaxSteps:       40,
+			TimeoutSeconds: 600,
+			MaxCost:        2.0,
+		},
+	}
+}
+
 // PresetNames returns the names of all built-in presets.
 func PresetNames() []string {
 	return []string{
 		"plan-readonly",
 		"headless-safe-sandbox",
 		"headless-permissive-sandbox",
+		"headless-untrusted-sandbox",
 		"trusted-mount-autonomous",
+		"research-web",
 	}
 }
 
@@ -150,8 +198,12 @@ func PresetByName(name string) (PolicyProfile, bool) {
 		return PresetHeadlessSafeSandbox(), true
 	case "headless-permissive-sandbox":
 		return PresetHeadlessPermissiveSandbox(), true
+	case "headless-untrusted-sandbox":
+		return PresetHeadlessUntrustedSandbox(), true
 	case "trusted-mount-autonomous":
 		return PresetTrustedMountAutonomous(), true
+	case "research-web":
+		return PresetResearchWeb(), true
 	default:
 		return PolicyProfile{}, false
 	}
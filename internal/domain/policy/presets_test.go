@@ -37,6 +37,22 @@ func TestPresetHeadlessSafeSandbox(t *testing.T) {
 	}
 }
 
+func TestPresetHeadlessUntrustedSandbox(t *testing.T) {
+	p := PresetHeadlessUntrustedSandbox()
+	if p.Name != "headless-untrusted-sandbox" {
+		t.Errorf("expected name 'headless-untrusted-sandbox', got %q", p.Name)
+	}
+	if p.Isolation != IsolationGVisor || !p.Untrusted() {
+		t.Errorf("expected gvisor isolation, got %q", p.Isolation)
+	}
+	if p.Egress == nil || !p.Egress.AllowPackageRegistries || len(p.Egress.AllowDomains) != 0 {
+		t.Errorf("expected registry-only egress, got %+v", p.Egress)
+	}
+	if safe := PresetHeadlessSafeSandbox(); len(p.Rules) != len(safe.Rules) || safe.Untrusted() {
+		t.Error("expected the safe sandbox rules with stronger isolation")
+	}
+}
+
 func TestPresetHeadlessPermissiveSandbox(t *testing.T) {
 	p := PresetHeadlessPermissiveSandbox()
 	if p.Name != "headless-permissive-sandbox" {
@@ -72,6 +88,26 @@ func TestPresetTrustedMountAutonomous(t *testing.T) {
 	}
 }
 
+func TestPresetResearchWeb(t *testing.T) {
+	p := PresetResearchWeb()
+	if p.Name != "research-web" {
+		t.Errorf("expected name 'research-web', got %q", p.Name)
+	}
+	if p.Mode != ModePlan {
+		t.Errorf("expected mode %q, got %q", ModePlan, p.Mode)
+	}
+	var fetchAllow *PermissionRule
+	for i := range p.Rules {
+		if p.Rules[i].Specifier.Tool == "WebFetch" && p.Rules[i].Decision == DecisionAllow {
+			fetchAllow = &p.Rules[i]
+			break
+		}
+	}
+	if fetchAllow == nil || len(fetchAllow.DomainAllow) == 0 {
+		t.Fatal("expected WebFetch allow rule with a domain allowlist")
+	}
+}
+
 func TestPresetByName(t *testing.T) {
 	for _, name := range PresetNames() {
 		p, ok := PresetByName(name)
@@ -93,8 +129,8 @@ func TestPresetByNameUnknown(t *testing.T) {
 
 func TestPresetNames(t *testing.T) {
 	names := PresetNames()
-	if len(names) != 4 {
-		t.Fatalf("expected 4 preset names, got %d", len(names))
+	if len(names) != 6 {
+		t.Fatalf("expected 6 preset names, got %d", len(names))
 	}
 }
 
@@ -0,0 +1,45 @@
+package policy
+
+// SimulateRequest selects the recorded run whose tool calls are replayed.
+type SimulateRequest struct {
+	RunID string `json:"run_id"`
+}
+
+// SimulatedCall is one recorded tool call re-evaluated against a profile.
+type SimulatedCall struct {
+	CallID    string   `json:"call_id,omitempty"`
+	Call      ToolCall `json:"call"`
+	Original  Decision `json:"original,omitempty"` // Decision recorded at run time (empty if unknown)
+	Simulated Decision `json:"simulated"`
+	Changed   bool     `json:"changed"`
+}
+
+// SimulationResult summarizes how a profile would have treated a recorded run.
+type SimulationResult struct {
+	Profile string          `json:"profile"`
+	RunID   string          `json:"run_id"`
+	Total   int             `json:"total"`
+	Allowed int             `json:"allowed"`
+	Asked   int             `json:"asked"`
+	Denied  int             `json:"denied"`
+	Changed int             `json:"changed"`
+	Calls   []SimulatedCall `json:"calls"`
+}
+
+// Record adds a simulated call to the result and updates the counters.
+func (r *SimulationResult) Record(c SimulatedCall) {
+	c.Changed = c.Original != "" && c.Original != c.Simulated
+	r.Calls = append(r.Calls, c)
+	r.Total++
+	switch c.Simulated {
+	case DecisionAllow:
+		r.Allowed++
+	case DecisionAsk:
+		r.Asked++
+	case DecisionDeny:
+		r.Denied++
+	}
+	if c.Changed {
+		r.Changed++
+	}
+}
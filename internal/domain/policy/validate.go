@@ -24,6 +24,19 @@ func (p *PolicyProfile) Validate() error {
 	if p.Termination.MaxCost < 0 {
 		return fmt.Errorf("policy: max_cost must be >= 0")
 	}
+	if !isValidIsolation(p.Isolation) {
+		return fmt.Errorf("policy: invalid isolation %q", p.Isolation)
+	}
+	if p.Egress != nil {
+		if err := p.Egress.Validate(); err != nil {
+			return fmt.Errorf("policy: egress: %w", err)
+		}
+	}
+	if p.Approval != nil {
+		if err := p.Approval.Validate(); err != nil {
+			return fmt.Errorf("policy: approval: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -53,3 +66,11 @@ func isValidDecision(d Decision) bool {
 	}
 	return false
 }
+
+func isValidIsolation(i Isolation) bool {
+	switch i {
+	case "", IsolationContainer, IsolationGVisor, IsolationMicroVM:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+// Package poll defines the polling fallback for projects whose git host or
+// PM platform cannot deliver webhooks into CodeForge's network: the changes
+// webhooks would announce are fetched periodically instead, resuming from a
+// cursor per source.
+package poll
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Project config keys of the poller.
+const (
+	ConfigInterval = "poll_interval" // Duration between polls, e.g. "5m"; polling is off when empty
+	ConfigJitter   = "poll_jitter"   // Maximum random delay added to each interval; defaults to a tenth of it
+)
+
+// MinInterval keeps polling within the rate limits of hosting APIs.
+const MinInterval = time.Minute
+
+var ErrInvalidInterval = errors.New("invalid poll interval")
+
+// Settings configure how often a project is polled.
+type Settings struct {
+	Interval time.Duration `json:"interval"`
+	Jitter   time.Duration `json:"jitter"`
+}
+
+// SettingsFromConfig reads the settings from a project config. Without an
+// interval the settings are zero and polling is off.
+func SettingsFromConfig(cfg map[string]string) (Settings, error) {
+	var s Settings
+	raw := strings.TrimSpace(cfg[ConfigInterval])
+	if raw == "" {
+		return s, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < MinInterval {
+		return Settings{}, fmt.Errorf("%w %q: must be a duration of at least %s", ErrInvalidInterval, raw, MinInterval)
+	}
+	s.Interval, s.Jitter = d, d/10
+	if raw := strings.TrimSpace(cfg[ConfigJitter]); raw != "" {
+		j, err := time.ParseDuration(raw)
+		if err != nil || j < 0 {
+			return Settings{}, fmt.Errorf("%w: %s %q is not a duration", ErrInvalidInterval, ConfigJitter, raw)
+		}
+		s.Jitter = j
+	}
+	return s, nil
+}
+
+// Enabled reports whether the project is polled.
+func (s Settings) Enabled() bool {
+	return s.Interval > 0
+}
+
+// Delay returns the time until the next poll for a random value r in
+// [0, 1). Jitter keeps projects with equal intervals from polling at once.
+func (s Settings) Delay(r float64) time.Duration {
+	return s.Interval + time.Duration(r*float64(s.Jitter))
+}
+
+// Source is a feed of changes a project is polled for.
+type Source string
+
+const (
+	SourceIssues     Source = "issues"      // Issue label and assignee changes; start issue runs
+	SourcePRComments Source = "pr_comments" // Pull request comments; run pull request commands
+	SourcePMItems    Source = "pm_items"    // Items of the linked PM project; update roadmap features
+)
+
+// Cursor records how far a source has been read. Position and ETag are
+// set by the provider: Position is where the next poll resumes (e.g. the
+// last event ID or a timestamp), and ETag lets an unchanged feed answer
+// "not modified" without counting against rate limits.
+type Cursor struct {
+	ProjectID string    `json:"project_id"`
+	Source    Source    `json:"source"`
+	Position  string    `json:"position,omitempty"`
+	ETag      string    `json:"etag,omitempty"`
+	Error     string    `json:"error,omitempty"` // Error of the last poll
+	PolledAt  time.Time `json:"polled_at"`
+}
+
+// Result counts what a poll of a project started or changed.
+type Result struct {
+	IssueRuns int `json:"issue_runs"`
+	Commands  int `json:"commands"`
+	Features  int `json:"features"` // Roadmap features created or updated
+}
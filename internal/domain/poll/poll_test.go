@@ -0,0 +1,51 @@
+package poll_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/poll"
+)
+
+func TestSettingsFromConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  map[string]string
+		want poll.Settings
+		err  bool
+	}{
+		{name: "off", cfg: map[string]string{}},
+		{name: "default jitter", cfg: map[string]string{poll.ConfigInterval: "10m"},
+			want: poll.Settings{Interval: 10 * time.Minute, Jitter: time.Minute}},
+		{name: "jitter", cfg: map[string]string{poll.ConfigInterval: "5m", poll.ConfigJitter: "30s"},
+			want: poll.Settings{Interval: 5 * time.Minute, Jitter: 30 * time.Second}},
+		{name: "too short", cfg: map[string]string{poll.ConfigInterval: "10s"}, err: true},
+		{name: "not a duration", cfg: map[string]string{poll.ConfigInterval: "often"}, err: true},
+		{name: "bad jitter", cfg: map[string]string{poll.ConfigInterval: "5m", poll.ConfigJitter: "-1s"}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := poll.SettingsFromConfig(tt.cfg)
+			if tt.err {
+				if !errors.Is(err, poll.ErrInvalidInterval) {
+					t.Fatalf("expected ErrInvalidInterval, got %v", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("got %+v, %v; want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSettingsDelay(t *testing.T) {
+	s := poll.Settings{Interval: 10 * time.Minute, Jitter: time.Minute}
+	if d := s.Delay(0); d != 10*time.Minute {
+		t.Fatalf("Delay(0) = %s", d)
+	}
+	if d := s.Delay(0.5); d != 10*time.Minute+30*time.Second {
+		t.Fatalf("Delay(0.5) = %s", d)
+	}
+}
@@ -6,6 +6,7 @@ import "time"
 // Project represents a code repository managed by CodeForge.
 type Project struct {
 	ID            string            `json:"id"`
+	TenantID      string            `json:"tenant_id"`
 	Name          string            `json:"name"`
 	Description   string            `json:"description"`
 	RepoURL       string            `json:"repo_url"`
@@ -19,6 +20,7 @@ type Project struct {
 
 // CreateRequest holds the fields needed to create a new project.
 type CreateRequest struct {
+	TenantID    string            `json:"tenant_id,omitempty"` // Defaults to tenant.DefaultID
 	Name        string            `json:"name"`
 	Description string            `json:"description"`
 	RepoURL     string            `json:"repo_url"`
@@ -0,0 +1,69 @@
+package project
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrSubProjectName = errors.New("sub-project name is required")
+	ErrSubProjectPath = errors.New("sub-project path must be a relative directory inside the repository")
+)
+
+// SubProject scopes a part of a monorepo, e.g. one package or service.
+// Tasks and agents that target a sub-project get context from its
+// directory only, and quality gates run its commands inside it.
+type SubProject struct {
+	ID           string    `json:"id"`
+	ProjectID    string    `json:"project_id"`
+	Name         string    `json:"name"`
+	Path         string    `json:"path"` // Directory relative to the repository root, e.g. "services/api"
+	Language     string    `json:"language,omitempty"`
+	BuildCommand string    `json:"build_command,omitempty"`
+	TestCommand  string    `json:"test_command,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// CreateSubProjectRequest holds the fields for defining a sub-project.
+type CreateSubProjectRequest struct {
+	Name         string `json:"name"`
+	Path         string `json:"path"`
+	Language     string `json:"language"`
+	BuildCommand string `json:"build_command"`
+	TestCommand  string `json:"test_command"`
+}
+
+// Validate checks the name and normalizes the path to a clean,
+// slash-separated relative path.
+func (r *CreateSubProjectRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return ErrSubProjectName
+	}
+	p, ok := CleanSubProjectPath(r.Path)
+	if !ok {
+		return ErrSubProjectPath
+	}
+	r.Path = p
+	return nil
+}
+
+// CleanSubProjectPath normalizes a sub-project path and reports whether it
+// names a directory below the repository root.
+func CleanSubProjectPath(p string) (string, bool) {
+	p = path.Clean(strings.ReplaceAll(strings.TrimSpace(p), "\\", "/"))
+	if p == "." || p == "" || path.IsAbs(p) || p == ".." || strings.HasPrefix(p, "../") {
+		return "", false
+	}
+	return p, true
+}
+
+// Contains reports whether the repository-relative path rel lies inside
+// the sub-project.
+func (s *SubProject) Contains(rel string) bool {
+	rel = path.Clean(strings.ReplaceAll(rel, "\\", "/"))
+	return rel == s.Path || strings.HasPrefix(rel, s.Path+"/")
+}
@@ -0,0 +1,49 @@
+package project
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCreateSubProjectRequestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      CreateSubProjectRequest
+		wantPath string
+		wantErr  error
+	}{
+		{"nested", CreateSubProjectRequest{Name: "api", Path: "services/api/"}, "services/api", nil},
+		{"windows separators", CreateSubProjectRequest{Name: "web", Path: `apps\web`}, "apps/web", nil},
+		{"dot segments", CreateSubProjectRequest{Name: "lib", Path: "./libs/../libs/core"}, "libs/core", nil},
+		{"missing name", CreateSubProjectRequest{Name: " ", Path: "a"}, "", ErrSubProjectName},
+		{"root", CreateSubProjectRequest{Name: "root", Path: "."}, "", ErrSubProjectPath},
+		{"absolute", CreateSubProjectRequest{Name: "abs", Path: "/etc"}, "", ErrSubProjectPath},
+		{"escape", CreateSubProjectRequest{Name: "up", Path: "a/../../b"}, "", ErrSubProjectPath},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate() = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && tt.req.Path != tt.wantPath {
+				t.Fatalf("path = %q, want %q", tt.req.Path, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestSubProjectContains(t *testing.T) {
+	sp := SubProject{Path: "services/api"}
+	for rel, want := range map[string]bool{
+		"services/api":             true,
+		"services/api/main.go":     true,
+		"services/api-gateway/x":   false,
+		"services/web/main.go":     false,
+		"services/api/../web/x.go": false,
+	} {
+		if got := sp.Contains(rel); got != want {
+			t.Errorf("Contains(%q) = %v, want %v", rel, got, want)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Package replay defines step-through replay sessions of runs: a cursor
+// over a run's recorded events that advances event by event, shows the
+// workspace checkpoint taken after the latest tool call up to the cursor,
+// and forks a new run from it.
+package replay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/snapshot"
+)
+
+var (
+	// ErrInvalidRequest is returned for requests without a run or with
+	// an invalid deliver mode.
+	ErrInvalidRequest = errors.New("invalid replay request")
+	// ErrNoEvents is returned when replaying a run without recorded
+	// events.
+	ErrNoEvents = errors.New("run has no recorded events")
+	// ErrOutOfRange is returned when seeking before the first or past the
+	// last event.
+	ErrOutOfRange = errors.New("step out of range")
+	// ErrNoCheckpoint is returned when no workspace checkpoint was taken
+	// up to a step.
+	ErrNoCheckpoint = errors.New("no workspace checkpoint up to this step")
+)
+
+// Session is a cursor over the events of a run.
+type Session struct {
+	ID        string    `json:"id"`
+	RunID     string    `json:"run_id"`
+	ProjectID string    `json:"project_id"`
+	Position  int       `json:"position"` // 0-based index of the current event
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Step is a recorded event of the replayed run and the workspace
+// checkpoint in effect after it.
+type Step struct {
+	Index      int                `json:"index"`
+	Event      event.AgentEvent   `json:"event"`
+	Checkpoint *snapshot.Snapshot `json:"checkpoint,omitempty"`
+}
+
+// View is a session at its current step.
+type View struct {
+	Session
+	Steps   int   `json:"steps"` // Number of recorded events
+	Current *Step `json:"current"`
+}
+
+// State is the workspace at the checkpoint of a step.
+type State struct {
+	Step       int               `json:"step"`
+	Checkpoint snapshot.Snapshot `json:"checkpoint"`
+	Files      []snapshot.File   `json:"files"`
+}
+
+// CreateRequest starts a session at the first event of a run.
+type CreateRequest struct {
+	RunID string `json:"run_id"`
+}
+
+// Validate checks that a run is given.
+func (r *CreateRequest) Validate() error {
+	if r.RunID == "" {
+		return fmt.Errorf("%w: run_id is required", ErrInvalidRequest)
+	}
+	return nil
+}
+
+// SeekRequest moves a session by Steps events, negative to go back, or to
+// the event at index To. Neither advances by one event.
+type SeekRequest struct {
+	Steps int  `json:"steps,omitempty"`
+	To    *int `json:"to,omitempty"`
+}
+
+// Target returns the index a session at position among total events moves
+// to.
+func (r *SeekRequest) Target(position, total int) (int, error) {
+	target := position + 1
+	switch {
+	case r.To != nil:
+		target = *r.To
+	case r.Steps != 0:
+		target = position + r.Steps
+	}
+	if target < 0 || target >= total {
+		return 0, fmt.Errorf("%w: %d of %d events", ErrOutOfRange, target, total)
+	}
+	return target, nil
+}
+
+// ForkRequest starts a new run of the replayed run's task from the
+// checkpoint of a step.
+type ForkRequest struct {
+	Step        *int            `json:"step,omitempty"`         // Defaults to the session's current step
+	Prompt      string          `json:"prompt,omitempty"`       // Instructions for the fork, added to the task prompt
+	DeliverMode run.DeliverMode `json:"deliver_mode,omitempty"` // Empty delivers nothing
+}
+
+// Validate checks the deliver mode.
+func (r *ForkRequest) Validate() error {
+	if !r.DeliverMode.Valid() {
+		return fmt.Errorf("%w: deliver_mode %q", ErrInvalidRequest, r.DeliverMode)
+	}
+	return nil
+}
+
+// payload decodes the string payload of a run event.
+func payload(ev *event.AgentEvent) map[string]string {
+	var p map[string]string
+	_ = json.Unmarshal(ev.Payload, &p)
+	return p
+}
+
+// CheckpointAt returns the checkpoint taken after the latest tool call
+// whose result is at or before index, or nil. Snapshots other than
+// checkpoints are not tied to a step and are ignored.
+func CheckpointAt(events []event.AgentEvent, snaps []snapshot.Snapshot, index int) *snapshot.Snapshot {
+	byCall := make(map[string]*snapshot.Snapshot)
+	for i := range snaps {
+		if snaps[i].CallID != "" {
+			byCall[snaps[i].CallID] = &snaps[i]
+		}
+	}
+	var cp *snapshot.Snapshot
+	for i := 0; i <= index && i < len(events); i++ {
+		if events[i].Type != event.TypeToolCallResultEv {
+			continue
+		}
+		if s, ok := byCall[payload(&events[i])["call_id"]]; ok {
+			cp = s
+		}
+	}
+	return cp
+}
+
+// maxForkSteps caps the tool calls of the earlier run a fork prompt
+// lists; the most recent are kept.
+const maxForkSteps = 100
+
+// ForkPrompt is the prompt of a run forked after the event at index: the
+// task prompt, the tool calls the replayed run made up to there, and the
+// instructions of the fork.
+func ForkPrompt(taskPrompt, runID string, events []event.AgentEvent, index int, instructions string) string {
+	type call struct{ tool, detail string }
+	calls := make(map[string]call)
+	var steps []string
+	for i := 0; i <= index && i < len(events); i++ {
+		p := payload(&events[i])
+		c := call{tool: p["tool"], detail: p["command"]}
+		if c.detail == "" {
+			c.detail = p["path"]
+		}
+		switch events[i].Type {
+		case event.TypeToolCallApproved:
+			calls[p["call_id"]] = c
+		case event.TypeToolCallDenied:
+			steps = append(steps, strings.TrimSpace(c.tool+" "+c.detail)+" (denied)")
+		case event.TypeToolCallResultEv:
+			if known, ok := calls[p["call_id"]]; ok {
+				c = known
+			}
+			status := "ok"
+			if p["success"] != "true" {
+				status = "failed"
+			}
+			steps = append(steps, strings.TrimSpace(c.tool+" "+c.detail)+" ("+status+")")
+		}
+	}
+	if len(steps) > maxForkSteps {
+		steps = steps[len(steps)-maxForkSteps:]
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(taskPrompt))
+	fmt.Fprintf(&b, "\n\nThis run continues from run %s after step %d of its recorded events; the workspace is as it was then.", runID, index+1)
+	if len(steps) > 0 {
+		b.WriteString(" The earlier run had made these tool calls:\n")
+		for i, s := range steps {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, s)
+		}
+	}
+	if instructions = strings.TrimSpace(instructions); instructions != "" {
+		b.WriteString("\nInstructions for this attempt: " + instructions)
+	}
+	return strings.TrimSpace(b.String())
+}
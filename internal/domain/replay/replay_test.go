@@ -0,0 +1,76 @@
+package replay_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/replay"
+	"github.com/Strob0t/CodeForge/internal/domain/snapshot"
+)
+
+func ev(typ event.Type, p map[string]string) event.AgentEvent {
+	b, _ := json.Marshal(p)
+	return event.AgentEvent{Type: typ, Payload: b}
+}
+
+// trajectory edits main.go, runs the tests and is denied a push.
+var trajectory = []event.AgentEvent{
+	ev(event.TypeRunStarted, nil),
+	ev(event.TypeToolCallApproved, map[string]string{"call_id": "c1", "tool": "Edit", "path": "main.go"}),
+	ev(event.TypeToolCallResultEv, map[string]string{"call_id": "c1", "tool": "Edit", "success": "true"}),
+	ev(event.TypeToolCallApproved, map[string]string{"call_id": "c2", "tool": "Bash", "command": "go test ./..."}),
+	ev(event.TypeToolCallResultEv, map[string]string{"call_id": "c2", "tool": "Bash", "success": "false"}),
+	ev(event.TypeToolCallDenied, map[string]string{"call_id": "c3", "tool": "Bash", "command": "git push"}),
+}
+
+func TestSeekRequestTarget(t *testing.T) {
+	to := func(i int) *int { return &i }
+	tests := []struct {
+		req  replay.SeekRequest
+		want int
+		err  error
+	}{
+		{replay.SeekRequest{}, 3, nil},
+		{replay.SeekRequest{Steps: 2}, 4, nil},
+		{replay.SeekRequest{Steps: -2}, 0, nil},
+		{replay.SeekRequest{To: to(5)}, 5, nil},
+		{replay.SeekRequest{To: to(0), Steps: 3}, 0, nil},
+		{replay.SeekRequest{Steps: 4}, 0, replay.ErrOutOfRange},
+		{replay.SeekRequest{Steps: -3}, 0, replay.ErrOutOfRange},
+	}
+	for _, tt := range tests {
+		got, err := tt.req.Target(2, 6)
+		if !errors.Is(err, tt.err) || (tt.err == nil && got != tt.want) {
+			t.Errorf("Target(%+v) = %d, %v; want %d, %v", tt.req, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestCheckpointAt(t *testing.T) {
+	snaps := []snapshot.Snapshot{{ID: "on-request"}, {ID: "s1", CallID: "c1"}, {ID: "s2", CallID: "c2"}}
+	for index, want := range []string{"", "", "s1", "s1", "s2", "s2"} {
+		got := replay.CheckpointAt(trajectory, snaps, index)
+		if (got == nil && want != "") || (got != nil && got.ID != want) {
+			t.Errorf("CheckpointAt(%d) = %+v, want %q", index, got, want)
+		}
+	}
+}
+
+func TestForkPrompt(t *testing.T) {
+	got := replay.ForkPrompt("Fix the parser.", "run-1", trajectory, 5, " Skip the push. ")
+	for _, want := range []string{
+		"Fix the parser.\n\nThis run continues from run run-1 after step 6",
+		"1. Edit main.go (ok)\n2. Bash go test ./... (failed)\n3. Bash git push (denied)\n",
+		"Instructions for this attempt: Skip the push.",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ForkPrompt missing %q in:\n%s", want, got)
+		}
+	}
+	if got := replay.ForkPrompt("Fix the parser.", "run-1", trajectory, 0, ""); strings.Contains(got, "tool calls") {
+		t.Errorf("expected no tool calls before the first, got:\n%s", got)
+	}
+}
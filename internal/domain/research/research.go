@@ -0,0 +1,108 @@
+// Package research defines the domain model for research runs: web-backed
+// information gathering whose findings feed a follow-up implementation task.
+package research
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// MetaFollowUpTaskID is the artifact metadata key linking a research report
+// to the task whose context pack should receive its findings.
+const MetaFollowUpTaskID = "follow_up_task_id"
+
+// MaxQueries caps the number of search queries in a single research run.
+const MaxQueries = 10
+
+// Request holds the input for starting a research run.
+type Request struct {
+	ProjectID      string   `json:"project_id"`
+	TaskID         string   `json:"task_id"`
+	AgentID        string   `json:"agent_id"`
+	Queries        []string `json:"queries"`
+	FollowUpTaskID string   `json:"follow_up_task_id,omitempty"` // Task whose context pack receives the findings
+	PolicyProfile  string   `json:"policy_profile,omitempty"`
+	MaxResults     int      `json:"max_results,omitempty"` // Per query (0 = config default)
+}
+
+// Source identifies where a finding came from.
+type Source struct {
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+	Domain string `json:"domain"`
+}
+
+// Finding is a single summarized piece of external information.
+type Finding struct {
+	Query   string  `json:"query"`
+	Source  Source  `json:"source"`
+	Summary string  `json:"summary"`
+	Score   float64 `json:"score,omitempty"`
+}
+
+// Report is the structured output of a research run, stored as a run artifact.
+type Report struct {
+	RunID          string    `json:"run_id"`
+	TaskID         string    `json:"task_id"`
+	ProjectID      string    `json:"project_id"`
+	FollowUpTaskID string    `json:"follow_up_task_id,omitempty"`
+	Queries        []string  `json:"queries"`
+	Findings       []Finding `json:"findings"`
+	Denied         []string  `json:"denied,omitempty"` // Queries or URLs rejected by policy
+	Summary        string    `json:"summary,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Validate checks that a research Request is well-formed.
+func (r *Request) Validate() error {
+	if r.ProjectID == "" {
+		return errors.New("project_id is required")
+	}
+	if r.TaskID == "" {
+		return errors.New("task_id is required")
+	}
+	if r.AgentID == "" {
+		return errors.New("agent_id is required")
+	}
+	if len(r.Queries) == 0 {
+		return errors.New("at least one query is required")
+	}
+	if len(r.Queries) > MaxQueries {
+		return errors.New("too many queries")
+	}
+	for _, q := range r.Queries {
+		if strings.TrimSpace(q) == "" {
+			return errors.New("queries must not be empty")
+		}
+	}
+	if r.MaxResults < 0 {
+		return errors.New("max_results must be >= 0")
+	}
+	if r.FollowUpTaskID != "" && r.FollowUpTaskID == r.TaskID {
+		return errors.New("follow_up_task_id must differ from task_id")
+	}
+	return nil
+}
+
+// Render formats the report findings as plain text suitable for an agent context entry.
+func (r *Report) Render() string {
+	var b strings.Builder
+	if r.Summary != "" {
+		b.WriteString(r.Summary)
+		b.WriteString("\n\n")
+	}
+	for _, f := range r.Findings {
+		b.WriteString("- ")
+		b.WriteString(f.Source.Title)
+		b.WriteString(" (")
+		b.WriteString(f.Source.URL)
+		b.WriteString(")")
+		if f.Summary != "" {
+			b.WriteString(": ")
+			b.WriteString(f.Summary)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSpace(b.String())
+}
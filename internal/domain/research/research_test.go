@@ -0,0 +1,64 @@
+package research
+
+import (
+	"strings"
+	"testing"
+)
+
+func validRequest() Request {
+	return Request{
+		ProjectID: "p1",
+		TaskID:    "t1",
+		AgentID:   "a1",
+		Queries:   []string{"go generics constraints"},
+	}
+}
+
+func TestRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(r *Request)
+		wantErr bool
+	}{
+		{"valid", func(_ *Request) {}, false},
+		{"missing project", func(r *Request) { r.ProjectID = "" }, true},
+		{"missing task", func(r *Request) { r.TaskID = "" }, true},
+		{"missing agent", func(r *Request) { r.AgentID = "" }, true},
+		{"no queries", func(r *Request) { r.Queries = nil }, true},
+		{"blank query", func(r *Request) { r.Queries = []string{"  "} }, true},
+		{"too many queries", func(r *Request) { r.Queries = make([]string, MaxQueries+1) }, true},
+		{"negative max results", func(r *Request) { r.MaxResults = -1 }, true},
+		{"follow-up is self", func(r *Request) { r.FollowUpTaskID = "t1" }, true},
+		{"follow-up set", func(r *Request) { r.FollowUpTaskID = "t2" }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := validRequest()
+			tt.mutate(&r)
+			err := r.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReportRender(t *testing.T) {
+	r := Report{
+		Summary: "Use type sets.",
+		Findings: []Finding{
+			{Source: Source{Title: "Spec", URL: "https://go.dev/ref/spec"}, Summary: "Type parameters"},
+			{Source: Source{Title: "Blog", URL: "https://go.dev/blog"}},
+		},
+	}
+	out := r.Render()
+	if !strings.HasPrefix(out, "Use type sets.") {
+		t.Fatalf("expected summary first, got %q", out)
+	}
+	if !strings.Contains(out, "- Spec (https://go.dev/ref/spec): Type parameters") {
+		t.Fatalf("missing first finding: %q", out)
+	}
+	if !strings.Contains(out, "- Blog (https://go.dev/blog)") {
+		t.Fatalf("missing second finding: %q", out)
+	}
+}
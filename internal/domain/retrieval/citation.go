@@ -0,0 +1,128 @@
+package retrieval
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// idLen is the number of hex digits of a chunk ID.
+const idLen = 12
+
+// ChunkID returns the stable ID of a chunk: a hash of its path, line range
+// and content, so reindexing unchanged text keeps the ID agents cited.
+func ChunkID(path string, startLine, endLine int, content string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%d\x00%d\x00", path, startLine, endLine)
+	h.Write([]byte(content))
+	return hex.EncodeToString(h.Sum(nil))[:idLen]
+}
+
+// ID returns the stable ID of the chunk (see ChunkID).
+func (c *Chunk) ID() string {
+	return ChunkID(c.Path, c.StartLine, c.EndLine, c.Content)
+}
+
+// Citation is where a cited chunk comes from: a line range of a workspace
+// file, or a knowledge base document. A citation that resolves to nothing
+// has only its ID.
+type Citation struct {
+	ID        string `json:"id"`
+	Path      string `json:"path,omitempty"` // Workspace file
+	StartLine int    `json:"start_line,omitempty"`
+	EndLine   int    `json:"end_line,omitempty"`
+	URL       string `json:"url,omitempty"` // Knowledge base document
+	Title     string `json:"title,omitempty"`
+	Source    string `json:"source,omitempty"` // Knowledge base name
+}
+
+// Citation returns the citation of the chunk.
+func (c *Chunk) Citation() Citation {
+	return newCitation(c.ID(), c.Path, c.Title, c.Source, c.StartLine, c.EndLine)
+}
+
+// Citation returns the citation of the result.
+func (r *Result) Citation() Citation {
+	return newCitation(r.ID, r.Path, r.Title, r.Source, r.StartLine, r.EndLine)
+}
+
+func newCitation(id, path, title, source string, startLine, endLine int) Citation {
+	if source != "" {
+		return Citation{ID: id, URL: path, Title: title, Source: source}
+	}
+	return Citation{ID: id, Path: path, StartLine: startLine, EndLine: endLine}
+}
+
+// Label describes the cited location for a reader: "path:10-20" for a
+// file, the title and URL for a document.
+func (c *Citation) Label() string {
+	switch {
+	case c.URL != "" && c.Title != "":
+		return c.Title + " (" + c.URL + ")"
+	case c.URL != "":
+		return c.URL
+	case c.Path != "" && c.StartLine > 0:
+		return c.Path + ":" + strconv.Itoa(c.StartLine) + "-" + strconv.Itoa(c.EndLine)
+	}
+	return c.Path
+}
+
+// Cite returns the citation marker of a chunk ID, e.g. "[cite:0123456789ab]".
+func Cite(id string) string {
+	return "[cite:" + id + "]"
+}
+
+var citeRE = regexp.MustCompile(`\[cite:\s*([0-9a-f]{12})\s*\]`)
+
+// CitedIDs returns the chunk IDs cited in text, in order of first citation.
+func CitedIDs(text string) []string {
+	var ids []string
+	for _, m := range citeRE.FindAllStringSubmatch(text, -1) {
+		if !slices.Contains(ids, m[1]) {
+			ids = append(ids, m[1])
+		}
+	}
+	return ids
+}
+
+// CheckCitations returns the problems with the citations of an answer
+// given the IDs of the chunks it could cite: no citation at all, or
+// citations of chunks it was not given.
+func CheckCitations(answer string, citable []string) []string {
+	ids := CitedIDs(answer)
+	if len(ids) == 0 {
+		return []string{"the answer cites none of the retrieved context entries"}
+	}
+	var problems []string
+	for _, id := range ids {
+		if !slices.Contains(citable, id) {
+			problems = append(problems, Cite(id)+" is not a citation marker from your context")
+		}
+	}
+	return problems
+}
+
+// CitationInstructions asks an agent to cite the retrieved chunks its
+// final answer relies on.
+func CitationInstructions() string {
+	return "## Citations\n\nRetrieved context entries start with a citation marker such as " + Cite("0123456789ab") + ". " +
+		"Back every statement of your final answer that relies on one of them with its marker, right after the statement. " +
+		"Only use markers that appear in your context."
+}
+
+// CitationRepairPrompt asks the agent to restate its final answer after
+// its citations were missing or invalid.
+func CitationRepairPrompt(problems []string) string {
+	var b strings.Builder
+	b.WriteString("## Output repair\n\nYour previous final answer did not cite its sources correctly:\n\n")
+	for _, p := range problems {
+		b.WriteString("- " + p + "\n")
+	}
+	b.WriteString("\nDo not redo the work. Reply with the corrected final answer only.\n\n")
+	b.WriteString(CitationInstructions())
+	return b.String()
+}
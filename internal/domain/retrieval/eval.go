@@ -0,0 +1,145 @@
+package retrieval
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Triggers of a retrieval evaluation.
+const (
+	EvalManual  = "manual"  // Requested through the API
+	EvalReindex = "reindex" // Run after the project was reindexed
+)
+
+// DefaultEvalK is the number of results an evaluation scores per query
+// when none is given.
+const DefaultEvalK = 10
+
+// ErrNoGoldenQueries is returned when evaluating a project without golden
+// queries.
+var ErrNoGoldenQueries = errors.New("project has no golden queries")
+
+// GoldenQuery is a query with the workspace files or chunks a good index
+// returns for it. Evaluations search the project's index with the query
+// and score how many of them come back, and how early.
+type GoldenQuery struct {
+	ID        string    `json:"id"`
+	ProjectID string    `json:"project_id"`
+	Query     string    `json:"query"`
+	Paths     []string  `json:"paths,omitempty"`     // Expected files; any chunk of a file counts
+	ChunkIDs  []string  `json:"chunk_ids,omitempty"` // Expected chunks by citation ID
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks that a golden query has a query and expects something.
+func (q *GoldenQuery) Validate() error {
+	if strings.TrimSpace(q.Query) == "" {
+		return ErrQueryRequired
+	}
+	if len(q.Paths) == 0 && len(q.ChunkIDs) == 0 {
+		return errors.New("golden query needs expected paths or chunk_ids")
+	}
+	return nil
+}
+
+// Eval is a run of a project's golden queries against its index. Next to
+// the scores it records what the index was built with, so a change in
+// quality can be traced to a change of chunking or embedding model.
+type Eval struct {
+	ID           string       `json:"id"`
+	ProjectID    string       `json:"project_id"`
+	Trigger      string       `json:"trigger"` // EvalManual, EvalReindex or EvalMigration
+	K            int          `json:"k"`
+	Provider     string       `json:"provider"`
+	Model        string       `json:"model"`
+	Dimensions   int          `json:"dimensions"`
+	Chunks       int          `json:"chunks"`
+	ChunkLines   int          `json:"chunk_lines"`
+	ChunkOverlap int          `json:"chunk_overlap"`
+	Recall       float64      `json:"recall_at_k"` // Mean recall@k over the queries
+	MRR          float64      `json:"mrr"`         // Mean reciprocal rank of the first expected result
+	Queries      []QueryScore `json:"queries"`
+	CreatedAt    time.Time    `json:"created_at"`
+
+	// Changes against the project's previous evaluation, if any.
+	RecallChange *float64 `json:"recall_change,omitempty"`
+	MRRChange    *float64 `json:"mrr_change,omitempty"`
+}
+
+// QueryScore is how one golden query fared in an evaluation.
+type QueryScore struct {
+	QueryID        string   `json:"query_id"`
+	Query          string   `json:"query"`
+	Recall         float64  `json:"recall_at_k"`
+	ReciprocalRank float64  `json:"reciprocal_rank"`
+	Rank           int      `json:"rank,omitempty"`    // 1-based rank of the first expected result; 0 if none is in the top k
+	Missing        []string `json:"missing,omitempty"` // Expected paths and chunk IDs not in the top k
+}
+
+// Score rates the top k results of a golden query: the share of expected
+// paths and chunks among them and the reciprocal rank of the first one.
+func Score(q *GoldenQuery, results []Result, k int) QueryScore {
+	results = results[:min(k, len(results))]
+	s := QueryScore{QueryID: q.ID, Query: q.Query}
+	paths := make(map[string]bool, len(results))
+	ids := make(map[string]bool, len(results))
+	for i := range results {
+		paths[results[i].Path] = true
+		ids[results[i].ID] = true
+		if s.Rank == 0 && (slices.Contains(q.Paths, results[i].Path) || slices.Contains(q.ChunkIDs, results[i].ID)) {
+			s.Rank = i + 1
+			s.ReciprocalRank = 1 / float64(i+1)
+		}
+	}
+	found := 0
+	for _, p := range q.Paths {
+		if paths[p] {
+			found++
+		} else {
+			s.Missing = append(s.Missing, p)
+		}
+	}
+	for _, id := range q.ChunkIDs {
+		if ids[id] {
+			found++
+		} else {
+			s.Missing = append(s.Missing, id)
+		}
+	}
+	if total := len(q.Paths) + len(q.ChunkIDs); total > 0 {
+		s.Recall = float64(found) / float64(total)
+	}
+	return s
+}
+
+// Summarize sets the mean recall@k and MRR of an evaluation from its
+// query scores.
+func (e *Eval) Summarize() {
+	e.Recall, e.MRR = 0, 0
+	if len(e.Queries) == 0 {
+		return
+	}
+	for i := range e.Queries {
+		e.Recall += e.Queries[i].Recall
+		e.MRR += e.Queries[i].ReciprocalRank
+	}
+	n := float64(len(e.Queries))
+	e.Recall /= n
+	e.MRR /= n
+}
+
+// Compare sets the changes of an evaluation against an earlier one.
+func (e *Eval) Compare(prev *Eval) {
+	recall, mrr := e.Recall-prev.Recall, e.MRR-prev.MRR
+	e.RecallChange, e.MRRChange = &recall, &mrr
+}
+
+// Trend sets the changes of evaluations ordered newest first, each against
+// the one after it.
+func Trend(evals []Eval) {
+	for i := 0; i+1 < len(evals); i++ {
+		evals[i].Compare(&evals[i+1])
+	}
+}
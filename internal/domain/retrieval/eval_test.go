@@ -0,0 +1,49 @@
+package retrieval
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	results := []Result{
+		{ID: "c1", Path: "a.go"},
+		{ID: "c2", Path: "b.go"},
+		{ID: "c3", Path: "b.go"},
+		{ID: "c4", Path: "c.go"},
+	}
+	tests := []struct {
+		q          GoldenQuery
+		k          int
+		recall, rr float64
+		missing    []string
+	}{
+		{GoldenQuery{Paths: []string{"b.go"}}, 3, 1, 0.5, nil},
+		{GoldenQuery{Paths: []string{"b.go", "c.go"}}, 3, 0.5, 0.5, []string{"c.go"}},
+		{GoldenQuery{ChunkIDs: []string{"c3"}, Paths: []string{"a.go"}}, 10, 1, 1, nil},
+		{GoldenQuery{ChunkIDs: []string{"c4"}}, 2, 0, 0, []string{"c4"}},
+	}
+	for i, tt := range tests {
+		s := Score(&tt.q, results, tt.k)
+		if s.Recall != tt.recall || s.ReciprocalRank != tt.rr || !slices.Equal(s.Missing, tt.missing) {
+			t.Errorf("case %d: expected recall %v, rr %v, missing %v, got %+v", i, tt.recall, tt.rr, tt.missing, s)
+		}
+	}
+}
+
+func TestTrend(t *testing.T) {
+	evals := []Eval{
+		{Queries: []QueryScore{{Recall: 1, ReciprocalRank: 1}, {Recall: 0.5, ReciprocalRank: 0.5}}},
+		{Queries: []QueryScore{{Recall: 1, ReciprocalRank: 1}}},
+	}
+	for i := range evals {
+		evals[i].Summarize()
+	}
+	Trend(evals)
+	if evals[0].Recall != 0.75 || *evals[0].RecallChange != -0.25 || *evals[0].MRRChange != -0.25 {
+		t.Fatalf("unexpected newest evaluation %+v", evals[0])
+	}
+	if evals[1].RecallChange != nil {
+		t.Fatal("expected no change for the oldest evaluation")
+	}
+}
@@ -0,0 +1,79 @@
+package retrieval
+
+import (
+	"errors"
+	"time"
+)
+
+// Phases of an index migration.
+const (
+	MigrationBuilding   = "building"   // Embedding the workspace into the staged index
+	MigrationEvaluating = "evaluating" // Running the golden queries against both indexes
+	MigrationSwitched   = "switched"   // The staged index replaced the live one
+	MigrationRejected   = "rejected"   // The staged index scored worse; the live one was kept
+	MigrationFailed     = "failed"
+)
+
+// EvalMigration is the trigger of the evaluations an index migration runs.
+const EvalMigration = "migration"
+
+var (
+	// ErrMigrationRunning is returned when migrating a project whose index
+	// is already being migrated.
+	ErrMigrationRunning = errors.New("index migration already running")
+	// ErrSameEmbedding is returned when migrating an index to the embedding
+	// it was built with.
+	ErrSameEmbedding = errors.New("index already uses this embedding")
+)
+
+// MigrateRequest asks to move a project's index to another embedding. An
+// empty embedding migrates to the project's configured one, e.g. after
+// its embedding.* config keys were changed.
+type MigrateRequest struct {
+	Embedding
+
+	// MaxRecallDrop is the drop of recall@k the new index may show against
+	// the old one on the project's golden queries and still be switched to.
+	MaxRecallDrop float64 `json:"max_recall_drop,omitempty"`
+	Force         bool    `json:"force,omitempty"` // Switch regardless of the evaluation
+}
+
+// Validate checks the recall tolerance of a request.
+func (r *MigrateRequest) Validate() error {
+	if r.MaxRecallDrop < 0 || r.MaxRecallDrop > 1 {
+		return errors.New("max_recall_drop must be between 0 and 1")
+	}
+	return nil
+}
+
+// Migration is the state of a project's index migration. The new index
+// is built as a staged index next to the live one, which keeps answering
+// searches; refreshes of edited files go to both until the switch.
+type Migration struct {
+	ProjectID  string     `json:"project_id"`
+	From       Embedding  `json:"from"`
+	To         Embedding  `json:"to"`
+	Phase      string     `json:"phase"`
+	Chunks     int        `json:"chunks"`   // Chunks of the staged index
+	Embedded   int        `json:"embedded"` // Chunks embedded so far
+	Before     *Eval      `json:"before,omitempty"`
+	After      *Eval      `json:"after,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Done reports whether a migration has finished, successfully or not.
+func (m *Migration) Done() bool {
+	return m.Phase == MigrationSwitched || m.Phase == MigrationRejected || m.Phase == MigrationFailed
+}
+
+// Accept reports whether the evaluation of the staged index allows the
+// switch: its recall@k dropped by at most maxDrop against the live index.
+// Without both evaluations there is nothing to compare and it accepts.
+func (m *Migration) Accept(maxDrop float64) bool {
+	if m.Before == nil || m.After == nil {
+		return true
+	}
+	return m.After.Recall >= m.Before.Recall-maxDrop
+}
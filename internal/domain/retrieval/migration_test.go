@@ -0,0 +1,49 @@
+package retrieval
+
+import "testing"
+
+func TestMigrateRequestValidate(t *testing.T) {
+	for _, drop := range []float64{0, 0.1, 1} {
+		if err := (&MigrateRequest{MaxRecallDrop: drop}).Validate(); err != nil {
+			t.Errorf("max_recall_drop %v: unexpected error %v", drop, err)
+		}
+	}
+	for _, drop := range []float64{-0.1, 1.5} {
+		if err := (&MigrateRequest{MaxRecallDrop: drop}).Validate(); err == nil {
+			t.Errorf("max_recall_drop %v: expected an error", drop)
+		}
+	}
+}
+
+func TestMigrationAccept(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after *Eval
+		maxDrop       float64
+		want          bool
+	}{
+		{"no evaluation", nil, nil, 0, true},
+		{"better", &Eval{Recall: 0.5}, &Eval{Recall: 0.75}, 0, true},
+		{"equal", &Eval{Recall: 0.5}, &Eval{Recall: 0.5}, 0, true},
+		{"worse", &Eval{Recall: 0.75}, &Eval{Recall: 0.5}, 0, false},
+		{"worse within tolerance", &Eval{Recall: 0.75}, &Eval{Recall: 0.5}, 0.25, true},
+	}
+	for _, tt := range tests {
+		m := &Migration{Before: tt.before, After: tt.after}
+		if got := m.Accept(tt.maxDrop); got != tt.want {
+			t.Errorf("%s: Accept(%v) = %v, want %v", tt.name, tt.maxDrop, got, tt.want)
+		}
+	}
+}
+
+func TestEmbeddingApply(t *testing.T) {
+	cfg := map[string]string{"other": "x", ConfigKeyDimensions: "8"}
+	out := Embedding{Provider: "ollama", Model: "nomic-embed-text"}.Apply(cfg)
+	if out[ConfigKeyDimensions] != "0" || out["other"] != "x" || cfg[ConfigKeyDimensions] != "8" {
+		t.Fatalf("unexpected config %v (input %v)", out, cfg)
+	}
+	e, err := Embedding{Provider: "litellm", Model: "m", Dimensions: 8}.Override(out)
+	if err != nil || e.Provider != "ollama" || e.Model != "nomic-embed-text" || e.Dimensions != 0 {
+		t.Fatalf("expected Override to read the applied embedding, got %+v, %v", e, err)
+	}
+}
@@ -0,0 +1,247 @@
+// Package retrieval defines the embedding index of a project workspace:
+// text chunks with their embedding vectors, the embedding settings the
+// index was built with, and similarity search over it.
+package retrieval
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Project config keys selecting the embedding provider and model of a
+// project. They override the service-wide retrieval settings.
+const (
+	ConfigKeyProvider   = "embedding.provider"
+	ConfigKeyModel      = "embedding.model"
+	ConfigKeyDimensions = "embedding.dimensions"
+)
+
+var (
+	// ErrQueryRequired is returned for a search without a query.
+	ErrQueryRequired = errors.New("query is required")
+	// ErrNotIndexed is returned when searching a project without an index.
+	ErrNotIndexed = errors.New("project has no retrieval index")
+	// ErrDimensionMismatch is returned when an embedding is shorter than the
+	// configured dimensions or differs from the other vectors of an index.
+	ErrDimensionMismatch = errors.New("embedding dimension mismatch")
+)
+
+// Embedding selects the provider and model that embed a project's chunks.
+type Embedding struct {
+	Provider   string `json:"provider"`             // "litellm" or "ollama"
+	Model      string `json:"model"`                // Provider-specific model name
+	Dimensions int    `json:"dimensions,omitempty"` // Truncate vectors to this size; 0 keeps the model's size
+}
+
+// Override returns e with the fields set in a project config replaced.
+func (e Embedding) Override(cfg map[string]string) (Embedding, error) {
+	if v := cfg[ConfigKeyProvider]; v != "" {
+		e.Provider = v
+	}
+	if v := cfg[ConfigKeyModel]; v != "" {
+		e.Model = v
+	}
+	if v := cfg[ConfigKeyDimensions]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return e, fmt.Errorf("%s: invalid dimensions %q", ConfigKeyDimensions, v)
+		}
+		e.Dimensions = n
+	}
+	return e, nil
+}
+
+// Apply returns a copy of cfg with the project config keys set to e, so
+// that Override of the result yields e.
+func (e Embedding) Apply(cfg map[string]string) map[string]string {
+	out := make(map[string]string, len(cfg)+3)
+	for k, v := range cfg {
+		out[k] = v
+	}
+	out[ConfigKeyProvider] = e.Provider
+	out[ConfigKeyModel] = e.Model
+	out[ConfigKeyDimensions] = strconv.Itoa(e.Dimensions)
+	return out
+}
+
+// Validate checks that a provider and model are set.
+func (e Embedding) Validate() error {
+	if e.Provider == "" || e.Model == "" {
+		return errors.New("embedding provider and model are required")
+	}
+	if e.Dimensions < 0 {
+		return errors.New("embedding dimensions must not be negative")
+	}
+	return nil
+}
+
+// Chunk is a line range of a workspace file with its embedding. Chunks of
+// knowledge bases have the URL of their document as path.
+type Chunk struct {
+	Path      string    `json:"path"`
+	Title     string    `json:"title,omitempty"`  // Title of a knowledge base document
+	Source    string    `json:"source,omitempty"` // Knowledge base name; empty for workspace files
+	StartLine int       `json:"start_line"`       // 1-based, inclusive
+	EndLine   int       `json:"end_line"`         // Inclusive
+	Content   string    `json:"content"`
+	Embedding []float32 `json:"-"`
+}
+
+// Index describes the embedding index of a project. Searches embed the
+// query with the same provider and model, so changing either requires a
+// reindex.
+type Index struct {
+	ProjectID  string    `json:"project_id"`
+	Provider   string    `json:"provider"`
+	Model      string    `json:"model"`
+	Dimensions int       `json:"dimensions"` // Length of every stored vector
+	Files      int       `json:"files"`
+	Chunks     int       `json:"chunks"`
+	IndexedAt  time.Time `json:"indexed_at"`
+
+	// Staged is set on the index an embedding migration builds next to
+	// the live one until it replaces it.
+	Staged bool `json:"staged,omitempty"`
+}
+
+// Matches reports whether the index was built with the embedding e.
+func (i *Index) Matches(e Embedding) bool {
+	return i.Provider == e.Provider && i.Model == e.Model && (e.Dimensions == 0 || i.Dimensions == e.Dimensions)
+}
+
+// SearchRequest is a similarity query over a project's index.
+type SearchRequest struct {
+	Query  string `json:"query"`
+	Limit  int    `json:"limit,omitempty"`  // Max results (default: 10)
+	Rerank *bool  `json:"rerank,omitempty"` // Rerank with the cross-encoder (default: whenever one is configured)
+}
+
+// Result is a chunk matching a query, scored by cosine similarity.
+type Result struct {
+	ID        string  `json:"id"` // Stable chunk ID, cited as [cite:<id>]
+	Path      string  `json:"path"`
+	Title     string  `json:"title,omitempty"`
+	Source    string  `json:"source,omitempty"`
+	StartLine int     `json:"start_line"`
+	EndLine   int     `json:"end_line"`
+	Content   string  `json:"content"`
+	Score     float64 `json:"score"` // Embedding similarity
+
+	// RerankScore is the cross-encoder's relevance score, set when the
+	// results were reranked; they are then ordered by it.
+	RerankScore *float64 `json:"rerank_score,omitempty"`
+}
+
+// Split cuts content into chunks of at most size lines, each overlapping
+// the previous one by overlap lines. Blank chunks are dropped.
+func Split(path, content string, size, overlap int) []Chunk {
+	if size <= 0 {
+		size = 60
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	var chunks []Chunk
+	for start := 0; start < len(lines); start += size - overlap {
+		end := min(start+size, len(lines))
+		text := strings.Join(lines[start:end], "\n")
+		if strings.TrimSpace(text) != "" {
+			chunks = append(chunks, Chunk{Path: path, StartLine: start + 1, EndLine: end, Content: text})
+		}
+		if end == len(lines) {
+			break
+		}
+	}
+	return chunks
+}
+
+// Fit adapts a vector to dims dimensions. Longer vectors are truncated and
+// re-normalized, which keeps cosine similarity meaningful for models
+// trained with nested (Matryoshka) representations. dims 0 keeps vec.
+func Fit(vec []float32, dims int) ([]float32, error) {
+	switch {
+	case dims == 0 || len(vec) == dims:
+		return vec, nil
+	case len(vec) < dims:
+		return nil, fmt.Errorf("%w: got %d, want %d", ErrDimensionMismatch, len(vec), dims)
+	}
+	out := make([]float32, dims)
+	copy(out, vec[:dims])
+	var norm float64
+	for _, v := range out {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range out {
+			out[i] *= scale
+		}
+	}
+	return out, nil
+}
+
+// Cosine returns the cosine similarity of two vectors of equal length.
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// Rank scores chunks against a query vector and returns the best limit.
+func Rank(query []float32, chunks []Chunk, limit int) []Result {
+	results := make([]Result, 0, len(chunks))
+	for i := range chunks {
+		c := &chunks[i]
+		results = append(results, Result{
+			ID:        c.ID(),
+			Path:      c.Path,
+			Title:     c.Title,
+			Source:    c.Source,
+			StartLine: c.StartLine,
+			EndLine:   c.EndLine,
+			Content:   c.Content,
+			Score:     Cosine(query, c.Embedding),
+		})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// Rerank orders results by the cross-encoder scores given for them, in
+// order, drops those scoring below minScore and returns the best limit.
+// Each result keeps its embedding score next to its rerank score.
+func Rerank(results []Result, scores []float64, minScore float64, limit int) []Result {
+	out := make([]Result, 0, len(results))
+	for i := range results {
+		if scores[i] < minScore {
+			continue
+		}
+		r := results[i]
+		r.RerankScore = &scores[i]
+		out = append(out, r)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return *out[i].RerankScore > *out[j].RerankScore })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
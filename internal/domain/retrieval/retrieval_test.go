@@ -0,0 +1,116 @@
+package retrieval
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	chunks := Split("a.go", "1\n2\n3\n4\n5\n6\n7\n", 3, 1)
+	want := [][2]int{{1, 3}, {3, 5}, {5, 7}}
+	if len(chunks) != len(want) {
+		t.Fatalf("expected %d chunks, got %+v", len(want), chunks)
+	}
+	for i, w := range want {
+		if chunks[i].StartLine != w[0] || chunks[i].EndLine != w[1] || chunks[i].Path != "a.go" {
+			t.Errorf("chunk %d: got %d-%d, want %d-%d", i, chunks[i].StartLine, chunks[i].EndLine, w[0], w[1])
+		}
+	}
+	if got := Split("b.txt", "\n\n  \n", 2, 0); len(got) != 0 {
+		t.Fatalf("expected blank content to yield no chunks, got %+v", got)
+	}
+}
+
+func TestFit(t *testing.T) {
+	v, err := Fit([]float32{3, 4, 12}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v) != 2 || math.Abs(float64(v[0])-0.6) > 1e-6 || math.Abs(float64(v[1])-0.8) > 1e-6 {
+		t.Fatalf("expected truncated unit vector, got %v", v)
+	}
+	if v, _ := Fit([]float32{1, 2}, 0); len(v) != 2 {
+		t.Fatalf("expected dims 0 to keep the vector, got %v", v)
+	}
+	if _, err := Fit([]float32{1}, 2); !errors.Is(err, ErrDimensionMismatch) {
+		t.Fatalf("expected ErrDimensionMismatch, got %v", err)
+	}
+}
+
+func TestEmbeddingOverride(t *testing.T) {
+	base := Embedding{Provider: "litellm", Model: "text-embedding-3-small"}
+	e, err := base.Override(map[string]string{ConfigKeyProvider: "ollama", ConfigKeyModel: "nomic-embed-text", ConfigKeyDimensions: "256"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e != (Embedding{Provider: "ollama", Model: "nomic-embed-text", Dimensions: 256}) {
+		t.Fatalf("unexpected override %+v", e)
+	}
+	if e, _ := base.Override(nil); e != base {
+		t.Fatalf("expected no change without config, got %+v", e)
+	}
+	if _, err := base.Override(map[string]string{ConfigKeyDimensions: "-1"}); err == nil {
+		t.Fatal("expected error for negative dimensions")
+	}
+}
+
+func TestRank(t *testing.T) {
+	chunks := []Chunk{
+		{Path: "far", Embedding: []float32{0, 1}},
+		{Path: "near", Embedding: []float32{1, 0.1}},
+		{Path: "mid", Embedding: []float32{1, 1}},
+	}
+	results := Rank([]float32{1, 0}, chunks, 2)
+	if len(results) != 2 || results[0].Path != "near" || results[1].Path != "mid" {
+		t.Fatalf("unexpected ranking %+v", results)
+	}
+	if results[0].ID != chunks[1].ID() {
+		t.Fatalf("expected result ID %s, got %s", chunks[1].ID(), results[0].ID)
+	}
+}
+
+func TestRerankResults(t *testing.T) {
+	results := []Result{{Path: "a", Score: 0.9}, {Path: "b", Score: 0.8}, {Path: "c", Score: 0.7}}
+	got := Rerank(results, []float64{0.1, 0.95, 0.6}, 0.2, 5)
+	if len(got) != 2 || got[0].Path != "b" || got[1].Path != "c" {
+		t.Fatalf("unexpected reranking %+v", got)
+	}
+	if got[0].Score != 0.8 || *got[0].RerankScore != 0.95 {
+		t.Fatalf("expected both scores, got %+v", got[0])
+	}
+	if got := Rerank(results, []float64{0.1, 0.95, 0.6}, 0, 1); len(got) != 1 || got[0].Path != "b" {
+		t.Fatalf("expected the best result only, got %+v", got)
+	}
+}
+
+func TestCitations(t *testing.T) {
+	file := Chunk{Path: "a.go", StartLine: 3, EndLine: 9, Content: "alpha"}
+	doc := Chunk{Path: "https://wiki.example.com/x", Title: "Setup", Source: "wiki", StartLine: 1, EndLine: 4, Content: "beta"}
+	id := file.ID()
+	if len(id) != 12 || id != ChunkID("a.go", 3, 9, "alpha") || id == ChunkID("a.go", 3, 9, "alpha2") {
+		t.Fatalf("unexpected chunk ID %q", id)
+	}
+	if c := file.Citation(); c.Path != "a.go" || c.URL != "" || c.Label() != "a.go:3-9" {
+		t.Fatalf("unexpected file citation %+v", c)
+	}
+	if c := doc.Citation(); c.URL != doc.Path || c.Path != "" || c.StartLine != 0 || c.Label() != "Setup (https://wiki.example.com/x)" {
+		t.Fatalf("unexpected document citation %+v", c)
+	}
+
+	answer := "Use the wizard " + Cite(doc.ID()) + ", see " + Cite(id) + " and " + Cite(doc.ID()) + ". [cite:nothex]"
+	if got := CitedIDs(answer); !slices.Equal(got, []string{doc.ID(), id}) {
+		t.Fatalf("unexpected cited IDs %v", got)
+	}
+	if p := CheckCitations(answer, []string{id, doc.ID()}); len(p) != 0 {
+		t.Fatalf("expected valid citations, got %v", p)
+	}
+	if p := CheckCitations(answer, []string{id}); len(p) != 1 || !strings.Contains(p[0], doc.ID()) {
+		t.Fatalf("expected unknown citation, got %v", p)
+	}
+	if p := CheckCitations("no sources", []string{id}); len(p) != 1 {
+		t.Fatalf("expected missing citations, got %v", p)
+	}
+}
@@ -0,0 +1,152 @@
+package review
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SummaryAnchor is the anchor of the summary comment of a review.
+const SummaryAnchor = "summary"
+
+// markerPattern matches the hidden marker that ties a pull request comment
+// to a review key and anchor, so re-publishing can find and update it.
+var markerPattern = regexp.MustCompile(`<!-- codeforge-review key=(\S+) anchor=(\S+) -->`)
+
+// Marker returns the hidden marker for the comment at anchor.
+func Marker(key, anchor string) string {
+	return fmt.Sprintf("<!-- codeforge-review key=%s anchor=%s -->", key, anchor)
+}
+
+// ParseMarker returns the key and anchor of a comment body written by
+// CodeForge, or ok=false for any other comment.
+func ParseMarker(body string) (key, anchor string, ok bool) {
+	m := markerPattern.FindStringSubmatch(body)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// Comment is the inline comment for all findings on one line of a file.
+type Comment struct {
+	Anchor   string
+	Path     string
+	Line     int
+	Findings []Finding
+}
+
+// Comments groups the line-anchored findings by file and line, in order of
+// first appearance.
+func (r *Review) Comments() []Comment {
+	var out []Comment
+	index := make(map[string]int)
+	for _, f := range r.Findings {
+		if f.Line == 0 {
+			continue
+		}
+		anchor := url.PathEscape(f.Path) + ":" + strconv.Itoa(f.Line)
+		i, ok := index[anchor]
+		if !ok {
+			i = len(out)
+			index[anchor] = i
+			out = append(out, Comment{Anchor: anchor, Path: f.Path, Line: f.Line})
+		}
+		out[i].Findings = append(out[i].Findings, f)
+	}
+	return out
+}
+
+// FileFindings returns the findings not anchored to a line.
+func (r *Review) FileFindings() []Finding {
+	var out []Finding
+	for _, f := range r.Findings {
+		if f.Line == 0 {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// Body renders the inline comment, including its marker.
+func (c *Comment) Body(key string) string {
+	var b strings.Builder
+	b.WriteString(Marker(key, c.Anchor))
+	for i, f := range c.Findings {
+		if i > 0 {
+			b.WriteString("\n\n---")
+		}
+		b.WriteString("\n")
+		b.WriteString(severityLabel(f.Severity))
+		b.WriteString(" ")
+		b.WriteString(f.Message)
+		if f.Suggestion != "" {
+			b.WriteString("\n\n```suggestion\n")
+			b.WriteString(strings.TrimRight(f.Suggestion, "\n"))
+			b.WriteString("\n```")
+		}
+	}
+	return b.String()
+}
+
+// SummaryBody renders the summary comment, including its marker. extra
+// lists findings that are not shown inline, such as file-level findings and
+// findings on lines outside the diff.
+func (r *Review) SummaryBody(key string, extra []Finding) string {
+	var b strings.Builder
+	b.WriteString(Marker(key, SummaryAnchor))
+	b.WriteString("\n### CodeForge review: ")
+	if r.Approved {
+		b.WriteString("approved")
+	} else {
+		b.WriteString("changes requested")
+	}
+	if s := strings.TrimSpace(r.Summary); s != "" {
+		b.WriteString("\n\n")
+		b.WriteString(s)
+	}
+
+	counts := make(map[Severity]int)
+	for _, f := range r.Findings {
+		counts[f.Severity]++
+	}
+	fmt.Fprintf(&b, "\n\n**Findings:** %d critical, %d warning, %d info",
+		counts[SeverityCritical], counts[SeverityWarning], counts[SeverityInfo])
+
+	if len(extra) > 0 {
+		b.WriteString("\n\n#### Not shown inline\n")
+		for _, f := range extra {
+			b.WriteString("\n- ")
+			if f.Path != "" {
+				b.WriteString("`" + f.Path + "`")
+				if f.Line > 0 {
+					fmt.Fprintf(&b, " line %d", f.Line)
+				}
+				b.WriteString(": ")
+			}
+			b.WriteString(severityLabel(f.Severity))
+			b.WriteString(" ")
+			b.WriteString(f.Message)
+		}
+	}
+	return b.String()
+}
+
+// ResolvedBody renders an earlier inline comment whose findings no longer
+// appear in the review.
+func ResolvedBody(key, anchor string) string {
+	return Marker(key, anchor) + "\nResolved: no longer reported by the latest CodeForge review."
+}
+
+func severityLabel(s Severity) string {
+	switch s {
+	case SeverityCritical:
+		return "**Critical:**"
+	case SeverityWarning:
+		return "**Warning:**"
+	default:
+		return "**Info:**"
+	}
+}
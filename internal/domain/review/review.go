@@ -0,0 +1,124 @@
+// Package review defines the structured output of code review runs and how
+// it is published to pull requests as inline and summary comments.
+package review
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Severity ranks a review finding.
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityWarning  Severity = "warning"
+	SeverityInfo     Severity = "info"
+)
+
+// Valid reports whether s is a known severity.
+func (s Severity) Valid() bool {
+	switch s {
+	case SeverityCritical, SeverityWarning, SeverityInfo:
+		return true
+	}
+	return false
+}
+
+// Finding is a single problem or suggestion raised by a reviewer.
+type Finding struct {
+	Path       string   `json:"path,omitempty"`
+	Line       int      `json:"line,omitempty"` // 1-based line in the new file version; 0 = whole file
+	Severity   Severity `json:"severity"`
+	Message    string   `json:"message"`
+	Suggestion string   `json:"suggestion,omitempty"`
+}
+
+// Review is the structured output of a review run, stored as a run artifact.
+type Review struct {
+	RunID     string    `json:"run_id"`
+	ProjectID string    `json:"project_id"`
+	Summary   string    `json:"summary"`
+	Approved  bool      `json:"approved"`
+	Findings  []Finding `json:"findings"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks that a Review is well-formed.
+func (r *Review) Validate() error {
+	if strings.TrimSpace(r.Summary) == "" && len(r.Findings) == 0 {
+		return errors.New("summary or findings are required")
+	}
+	for i := range r.Findings {
+		f := &r.Findings[i]
+		if strings.TrimSpace(f.Message) == "" {
+			return fmt.Errorf("finding %d: message is required", i)
+		}
+		if !f.Severity.Valid() {
+			return fmt.Errorf("finding %d: invalid severity %q", i, f.Severity)
+		}
+		if f.Line < 0 {
+			return fmt.Errorf("finding %d: line must be >= 0", i)
+		}
+		if f.Line > 0 && f.Path == "" {
+			return fmt.Errorf("finding %d: path is required with a line", i)
+		}
+	}
+	return nil
+}
+
+// keyPattern restricts idempotency keys to characters that are safe inside
+// an HTML comment marker.
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// PublishRequest asks for a review to be posted to a pull request.
+type PublishRequest struct {
+	PRNumber int `json:"pr_number"`
+	// Key identifies the comments of one review thread on the pull request.
+	// Publishing again with the same key updates those comments instead of
+	// adding new ones. Defaults to the task ID of the review run.
+	Key string `json:"key,omitempty"`
+	// RequireTests reports the review as failed unless the latest test
+	// report of the run passed. Projects with review_require_tests set to
+	// "true" always require it.
+	RequireTests bool `json:"require_tests,omitempty"`
+	// RequireCI keeps the review from approving the pull request until CI
+	// of its head commit passed. Projects with ci_gate_review set to
+	// "true" always require it.
+	RequireCI bool `json:"require_ci,omitempty"`
+}
+
+// Validate checks that a PublishRequest is well-formed.
+func (r *PublishRequest) Validate() error {
+	if r.PRNumber <= 0 {
+		return errors.New("pr_number must be > 0")
+	}
+	if r.Key != "" && !keyPattern.MatchString(r.Key) {
+		return errors.New("key may only contain letters, digits and . _ : - (max 128)")
+	}
+	return nil
+}
+
+// PublishResult reports what publishing a review changed on the pull request.
+type PublishResult struct {
+	PRNumber  int    `json:"pr_number"`
+	Key       string `json:"key"`
+	Created   int    `json:"created"`
+	Updated   int    `json:"updated"`
+	Unchanged int    `json:"unchanged"`
+	Resolved  int    `json:"resolved"`         // Earlier inline comments with no finding left
+	OffDiff   int    `json:"off_diff"`         // Findings on lines outside the diff, listed in the summary
+	SummaryID string `json:"summary_id"`       // Provider ID of the summary comment
+	Status    string `json:"status,omitempty"` // Last commit status reported on the PR head, if any
+	// TestsPassed is set when passing tests were required.
+	TestsPassed *bool `json:"tests_passed,omitempty"`
+	// LintBlocking counts the new critical linter findings of the run,
+	// which fail the review even if it approved the changes.
+	LintBlocking int `json:"lint_blocking,omitempty"`
+	// CIState is the CI state of the pull request head when green CI was
+	// required.
+	CIState string `json:"ci_state,omitempty"`
+}
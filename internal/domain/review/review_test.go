@@ -0,0 +1,96 @@
+package review
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReviewValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		review  Review
+		wantErr bool
+	}{
+		{"summary only", Review{Summary: "LGTM", Approved: true}, false},
+		{"empty", Review{}, true},
+		{"finding", Review{Findings: []Finding{{Path: "a.go", Line: 3, Severity: SeverityWarning, Message: "x"}}}, false},
+		{"file finding", Review{Findings: []Finding{{Path: "a.go", Severity: SeverityInfo, Message: "x"}}}, false},
+		{"missing message", Review{Findings: []Finding{{Path: "a.go", Line: 3, Severity: SeverityInfo}}}, true},
+		{"bad severity", Review{Findings: []Finding{{Path: "a.go", Line: 3, Severity: "blocker", Message: "x"}}}, true},
+		{"line without path", Review{Findings: []Finding{{Line: 3, Severity: SeverityInfo, Message: "x"}}}, true},
+		{"negative line", Review{Findings: []Finding{{Path: "a.go", Line: -1, Severity: SeverityInfo, Message: "x"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.review.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPublishRequestValidate(t *testing.T) {
+	for _, tt := range []struct {
+		req     PublishRequest
+		wantErr bool
+	}{
+		{PublishRequest{PRNumber: 7}, false},
+		{PublishRequest{PRNumber: 7, Key: "task-1:v2"}, false},
+		{PublishRequest{}, true},
+		{PublishRequest{PRNumber: 7, Key: "a b"}, true},
+		{PublishRequest{PRNumber: 7, Key: "x-->"}, true},
+	} {
+		if err := tt.req.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.req, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCommentsGroupByLine(t *testing.T) {
+	r := Review{Findings: []Finding{
+		{Path: "cmd/main.go", Line: 10, Severity: SeverityWarning, Message: "unchecked error"},
+		{Path: "README.md", Severity: SeverityInfo, Message: "typo"},
+		{Path: "cmd/main.go", Line: 10, Severity: SeverityInfo, Message: "rename", Suggestion: "x := y\n"},
+		{Path: "my file.go", Line: 2, Severity: SeverityCritical, Message: "nil deref"},
+	}}
+
+	comments := r.Comments()
+	if len(comments) != 2 || len(comments[0].Findings) != 2 {
+		t.Fatalf("expected 2 comments, the first with 2 findings, got %+v", comments)
+	}
+	if comments[1].Anchor != "my%20file.go:2" {
+		t.Fatalf("expected escaped anchor, got %q", comments[1].Anchor)
+	}
+	if got := r.FileFindings(); len(got) != 1 || got[0].Path != "README.md" {
+		t.Fatalf("unexpected file findings %+v", got)
+	}
+
+	body := comments[0].Body("task-1")
+	key, anchor, ok := ParseMarker(body)
+	if !ok || key != "task-1" || anchor != "cmd%2Fmain.go:10" {
+		t.Fatalf("marker not round-tripped: %q %q %v", key, anchor, ok)
+	}
+	if !strings.Contains(body, "**Warning:** unchecked error") || !strings.Contains(body, "```suggestion\nx := y\n```") {
+		t.Fatalf("unexpected body:\n%s", body)
+	}
+}
+
+func TestSummaryBody(t *testing.T) {
+	r := Review{Summary: "Mostly fine.", Findings: []Finding{
+		{Path: "a.go", Line: 1, Severity: SeverityCritical, Message: "boom"},
+		{Path: "b.go", Severity: SeverityInfo, Message: "doc"},
+	}}
+	body := r.SummaryBody("k", r.FileFindings())
+	if _, anchor, ok := ParseMarker(body); !ok || anchor != SummaryAnchor {
+		t.Fatalf("expected summary marker, got %q", body)
+	}
+	for _, want := range []string{"changes requested", "Mostly fine.", "1 critical, 0 warning, 1 info", "`b.go`: **Info:** doc"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("summary missing %q:\n%s", want, body)
+		}
+	}
+	if _, _, ok := ParseMarker("a human comment"); ok {
+		t.Fatal("expected no marker in a plain comment")
+	}
+}
@@ -0,0 +1,90 @@
+// Package roadmap defines the roadmap features of a project. Features are
+// the CodeForge side of the bidirectional sync with PM platforms.
+package roadmap
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// FeatureStatus represents where a feature stands on the roadmap.
+type FeatureStatus string
+
+const (
+	FeatureStatusPlanned    FeatureStatus = "planned"
+	FeatureStatusInProgress FeatureStatus = "in_progress"
+	FeatureStatusDone       FeatureStatus = "done"
+	FeatureStatusCancelled  FeatureStatus = "cancelled"
+)
+
+// Valid reports whether s is a known feature status.
+func (s FeatureStatus) Valid() bool {
+	switch s {
+	case FeatureStatusPlanned, FeatureStatusInProgress, FeatureStatusDone, FeatureStatusCancelled:
+		return true
+	}
+	return false
+}
+
+var ErrInvalidStatus = errors.New("status must be planned, in_progress, done or cancelled")
+
+// Feature is a unit of product work on a project's roadmap.
+type Feature struct {
+	ID          string            `json:"id"`
+	ProjectID   string            `json:"project_id"`
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Status      FeatureStatus     `json:"status"`
+	Labels      []string          `json:"labels"`
+	ExternalIDs map[string]string `json:"external_ids,omitempty"` // PM provider name -> item ID, e.g. {"jira": "10042"}
+	Version     int               `json:"version"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// Item is a work item on a PM platform, mapped to roadmap terms by the
+// provider that fetched it.
+type Item struct {
+	ID          string        `json:"id"`  // Stable platform ID, stored in Feature.ExternalIDs
+	Key         string        `json:"key"` // Human-readable key, e.g. "ENG-42"
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	Status      FeatureStatus `json:"status"`
+	Labels      []string      `json:"labels"`
+	URL         string        `json:"url"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// Apply copies an item's fields onto the feature and reports whether
+// anything changed.
+func (f *Feature) Apply(it *Item) bool {
+	if f.Title == it.Title && f.Description == it.Description &&
+		f.Status == it.Status && slices.Equal(f.Labels, it.Labels) {
+		return false
+	}
+	f.Title, f.Description, f.Status = it.Title, it.Description, it.Status
+	f.Labels = append([]string(nil), it.Labels...)
+	return true
+}
+
+// UpdateStatusRequest changes the status of a feature.
+type UpdateStatusRequest struct {
+	Status FeatureStatus `json:"status"`
+}
+
+// Validate checks the requested status.
+func (r *UpdateStatusRequest) Validate() error {
+	if !r.Status.Valid() {
+		return ErrInvalidStatus
+	}
+	return nil
+}
+
+// ImportResult summarizes an import from a PM platform.
+type ImportResult struct {
+	Provider  string `json:"provider"`
+	Created   int    `json:"created"`
+	Updated   int    `json:"updated"`
+	Unchanged int    `json:"unchanged"`
+}
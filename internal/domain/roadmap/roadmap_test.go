@@ -0,0 +1,42 @@
+package roadmap_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/roadmap"
+)
+
+func TestFeature_Apply(t *testing.T) {
+	f := roadmap.Feature{Title: "Login", Status: roadmap.FeatureStatusPlanned, Labels: []string{"auth"}}
+	it := roadmap.Item{Title: "Login", Status: roadmap.FeatureStatusPlanned, Labels: []string{"auth"}}
+	if f.Apply(&it) {
+		t.Fatal("expected identical item to report no change")
+	}
+
+	it.Status = roadmap.FeatureStatusInProgress
+	it.Labels = append(it.Labels, "q3")
+	if !f.Apply(&it) {
+		t.Fatal("expected changed item to report a change")
+	}
+	if f.Status != roadmap.FeatureStatusInProgress || len(f.Labels) != 2 {
+		t.Fatalf("expected item copied onto feature, got %+v", f)
+	}
+	it.Labels[0] = "mutated"
+	if f.Labels[0] != "auth" {
+		t.Fatal("expected feature labels not to alias the item's")
+	}
+}
+
+func TestUpdateStatusRequest_Validate(t *testing.T) {
+	for _, s := range []roadmap.FeatureStatus{"planned", "in_progress", "done", "cancelled"} {
+		req := roadmap.UpdateStatusRequest{Status: s}
+		if err := req.Validate(); err != nil {
+			t.Errorf("%s: expected valid, got %v", s, err)
+		}
+	}
+	req := roadmap.UpdateStatusRequest{Status: "shipped"}
+	if err := req.Validate(); !errors.Is(err, roadmap.ErrInvalidStatus) {
+		t.Fatalf("expected ErrInvalidStatus, got %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+// Package routing defines the rules that pick an LLM model for a task type,
+// so expensive models serve the tasks that need them and cheap models the
+// rest.
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TaskType classifies the LLM work a model is routed for.
+type TaskType string
+
+const (
+	TaskDecompose TaskType = "decompose" // Feature decomposition into plans
+	TaskSummarize TaskType = "summarize" // Summaries of research findings
+	TaskReview    TaskType = "review"    // Code review runs
+	TaskCode      TaskType = "code"      // Code-writing runs
+)
+
+// TaskTypes lists the known task types.
+var TaskTypes = []TaskType{TaskDecompose, TaskSummarize, TaskReview, TaskCode}
+
+// Valid reports whether t is a known task type.
+func (t TaskType) Valid() bool {
+	for _, known := range TaskTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Tier is a cost tier naming an ordered list of models.
+type Tier string
+
+const (
+	TierFrontier Tier = "frontier"
+	TierMid      Tier = "mid"
+	TierCheap    Tier = "cheap"
+)
+
+// Level identifies the layer a routing decision came from.
+type Level string
+
+const (
+	LevelDefault Level = "default" // Service-wide rules and the caller's default model
+	LevelTenant  Level = "tenant"
+	LevelProject Level = "project"
+	LevelRequest Level = "request" // A model named explicitly by the request
+)
+
+// ConfigKeyPrefix prefixes the project config keys overriding the route of
+// a task type, e.g. "routing.summarize". The value is a tier name or a
+// comma-separated list of models.
+const ConfigKeyPrefix = "routing."
+
+// ConfigKey returns the project config key for a task type.
+func ConfigKey(t TaskType) string {
+	return ConfigKeyPrefix + string(t)
+}
+
+// ErrNoModel is returned when every model of a route is unavailable.
+var ErrNoModel = errors.New("routing: no model available")
+
+// Rule routes a task type to a tier or to explicit models. Models are tried
+// in order; the ones after the first are fallbacks.
+type Rule struct {
+	TaskType TaskType `json:"task_type" yaml:"task_type"`
+	Tier     Tier     `json:"tier,omitempty" yaml:"tier"`
+	Models   []string `json:"models,omitempty" yaml:"models"`
+}
+
+// Validate checks the rule against the known tiers.
+func (r *Rule) Validate(tiers map[Tier][]string) error {
+	if !r.TaskType.Valid() {
+		return fmt.Errorf("unknown task type %q", r.TaskType)
+	}
+	switch {
+	case r.Tier == "" && len(r.Models) == 0:
+		return fmt.Errorf("rule for %s needs a tier or models", r.TaskType)
+	case r.Tier != "" && len(r.Models) > 0:
+		return fmt.Errorf("rule for %s sets both tier and models", r.TaskType)
+	case r.Tier != "" && len(tiers[r.Tier]) == 0:
+		return fmt.Errorf("rule for %s uses undefined tier %q", r.TaskType, r.Tier)
+	}
+	for _, m := range r.Models {
+		if strings.TrimSpace(m) == "" {
+			return fmt.Errorf("rule for %s has an empty model", r.TaskType)
+		}
+	}
+	return nil
+}
+
+// ParseOverride parses a project config override: a tier name if it is
+// one, otherwise a comma-separated list of models.
+func ParseOverride(t TaskType, value string, tiers map[Tier][]string) Rule {
+	value = strings.TrimSpace(value)
+	if _, ok := tiers[Tier(value)]; ok {
+		return Rule{TaskType: t, Tier: Tier(value)}
+	}
+	var models []string
+	for _, m := range strings.Split(value, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			models = append(models, m)
+		}
+	}
+	return Rule{TaskType: t, Models: models}
+}
+
+// Request asks for the model of a task type.
+type Request struct {
+	TaskType  TaskType `json:"task_type"`
+	ProjectID string   `json:"project_id,omitempty"` // Adds the project's tenant and config layers
+	Model     string   `json:"model,omitempty"`      // Explicit model; bypasses the rules
+}
+
+// Layer holds the rules of one level.
+type Layer struct {
+	Level Level
+	Rules []Rule
+}
+
+// Candidate is a model of a route, with the reason it was skipped if it was.
+type Candidate struct {
+	Model   string `json:"model"`
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// Decision explains how a model was picked for a task type.
+type Decision struct {
+	TaskType   TaskType    `json:"task_type"`
+	Level      Level       `json:"level"`          // Layer whose rule fired
+	Rule       *Rule       `json:"rule,omitempty"` // Nil if no rule matched and the default model applies
+	Candidates []Candidate `json:"candidates"`
+	Model      string      `json:"model,omitempty"` // First candidate not skipped
+}
+
+// Resolve routes taskType through layers ordered from general to specific:
+// the rule of the most specific layer that has one fires. Without a rule
+// the default model is the only candidate.
+func Resolve(taskType TaskType, tiers map[Tier][]string, layers []Layer, defaultModel string) *Decision {
+	d := &Decision{TaskType: taskType, Level: LevelDefault}
+	for _, l := range layers {
+		for i := range l.Rules {
+			if l.Rules[i].TaskType == taskType && (l.Rules[i].Tier != "" || len(l.Rules[i].Models) > 0) {
+				rule := l.Rules[i]
+				d.Level, d.Rule = l.Level, &rule
+			}
+		}
+	}
+
+	var models []string
+	switch {
+	case d.Rule == nil:
+		if defaultModel != "" {
+			models = []string{defaultModel}
+		}
+	case d.Rule.Tier != "":
+		models = tiers[d.Rule.Tier]
+	default:
+		models = d.Rule.Models
+	}
+	d.Candidates = make([]Candidate, 0, len(models))
+	for _, m := range models {
+		d.Candidates = append(d.Candidates, Candidate{Model: m})
+	}
+	d.Pick()
+	return d
+}
+
+// Skip marks a candidate as skipped and picks the first remaining one.
+func (d *Decision) Skip(model, reason string) {
+	for i := range d.Candidates {
+		if d.Candidates[i].Model == model && d.Candidates[i].Skipped == "" {
+			d.Candidates[i].Skipped = reason
+		}
+	}
+	d.Pick()
+}
+
+// Pick sets Model to the first candidate not skipped, or clears it if
+// every candidate was skipped.
+func (d *Decision) Pick() {
+	d.Model = ""
+	for _, c := range d.Candidates {
+		if c.Skipped == "" {
+			d.Model = c.Model
+			return
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package routing
+
+import (
+	"slices"
+	"testing"
+)
+
+var testTiers = map[Tier][]string{
+	TierFrontier: {"anthropic/claude-opus", "openai/gpt-4o"},
+	TierCheap:    {"openai/gpt-4o-mini"},
+}
+
+func TestRuleValidate(t *testing.T) {
+	for _, r := range []Rule{
+		{TaskType: "chat", Tier: TierCheap},
+		{TaskType: TaskReview},
+		{TaskType: TaskReview, Tier: TierCheap, Models: []string{"m"}},
+		{TaskType: TaskReview, Tier: TierMid},
+		{TaskType: TaskReview, Models: []string{" "}},
+	} {
+		if err := r.Validate(testTiers); err == nil {
+			t.Errorf("expected error for %+v", r)
+		}
+	}
+	if err := (&Rule{TaskType: TaskDecompose, Tier: TierFrontier}).Validate(testTiers); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveLayers(t *testing.T) {
+	layers := []Layer{
+		{Level: LevelDefault, Rules: []Rule{{TaskType: TaskDecompose, Tier: TierFrontier}, {TaskType: TaskSummarize, Tier: TierCheap}}},
+		{Level: LevelTenant, Rules: []Rule{{TaskType: TaskSummarize, Models: []string{"ollama/llama3"}}}},
+		{Level: LevelProject},
+	}
+
+	d := Resolve(TaskDecompose, testTiers, layers, "fallback")
+	if d.Level != LevelDefault || d.Model != "anthropic/claude-opus" || len(d.Candidates) != 2 {
+		t.Fatalf("unexpected decompose decision %+v", d)
+	}
+	d = Resolve(TaskSummarize, testTiers, layers, "fallback")
+	if d.Level != LevelTenant || d.Model != "ollama/llama3" {
+		t.Fatalf("expected the tenant rule to win, got %+v", d)
+	}
+	d = Resolve(TaskReview, testTiers, layers, "fallback")
+	if d.Rule != nil || d.Level != LevelDefault || d.Model != "fallback" {
+		t.Fatalf("expected the default model without a rule, got %+v", d)
+	}
+	if d = Resolve(TaskReview, testTiers, layers, ""); d.Model != "" || d.Candidates == nil {
+		t.Fatalf("expected no model and an empty candidate list, got %+v", d)
+	}
+}
+
+func TestDecisionSkip(t *testing.T) {
+	d := Resolve(TaskDecompose, testTiers, []Layer{{Level: LevelDefault, Rules: []Rule{{TaskType: TaskDecompose, Tier: TierFrontier}}}}, "")
+	d.Skip("anthropic/claude-opus", "circuit breaker open")
+	if d.Model != "openai/gpt-4o" || d.Candidates[0].Skipped == "" {
+		t.Fatalf("expected fallback to the second model, got %+v", d)
+	}
+	d.Skip("openai/gpt-4o", "circuit breaker open")
+	if d.Model != "" {
+		t.Fatalf("expected no model once all are skipped, got %q", d.Model)
+	}
+}
+
+func TestParseOverride(t *testing.T) {
+	if r := ParseOverride(TaskReview, "cheap", testTiers); r.Tier != TierCheap || r.Models != nil {
+		t.Fatalf("expected tier override, got %+v", r)
+	}
+	r := ParseOverride(TaskReview, " a/b, ,c/d ", testTiers)
+	if r.Tier != "" || !slices.Equal(r.Models, []string{"a/b", "c/d"}) {
+		t.Fatalf("expected model list override, got %+v", r)
+	}
+}
@@ -0,0 +1,90 @@
+package run
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// MaxCompareRuns caps the number of runs in one comparison.
+const MaxCompareRuns = 10
+
+// Errors returned for invalid comparison requests.
+var (
+	ErrCompareCount      = errors.New("between 2 and 10 distinct run ids are required")
+	ErrCompareMixedTasks = errors.New("runs must belong to the same task")
+)
+
+// DiffStat summarizes the uncommitted changes a run left in its workspace.
+type DiffStat struct {
+	Files      int      `json:"files"`
+	Insertions int      `json:"insertions"`
+	Deletions  int      `json:"deletions"`
+	Paths      []string `json:"-"`
+}
+
+// Comparison is a side-by-side evaluation of runs of the same task, e.g.
+// the same prompt executed by different backends or models.
+type Comparison struct {
+	TaskID string            `json:"task_id"`
+	Runs   []ComparisonEntry `json:"runs"`
+}
+
+// ComparisonEntry holds the metrics of one run in a Comparison. Pointer
+// fields are nil when the run did not record the value.
+type ComparisonEntry struct {
+	RunID        string    `json:"run_id"`
+	AgentID      string    `json:"agent_id"`
+	Backend      string    `json:"backend,omitempty"`
+	Status       Status    `json:"status"`
+	CostUSD      float64   `json:"cost_usd"`
+	TokensIn     int       `json:"tokens_in"`
+	TokensOut    int       `json:"tokens_out"`
+	DurationMS   int64     `json:"duration_ms"` // 0 while the run is active
+	StepCount    int       `json:"step_count"`
+	FilesTouched []string  `json:"files_touched"`
+	Diff         *DiffStat `json:"diff,omitempty"`
+	QualityGate  string    `json:"quality_gate,omitempty"` // "passed", "failed" or empty when no gate ran
+	TestsPassed  *bool     `json:"tests_passed,omitempty"`
+	LintPassed   *bool     `json:"lint_passed,omitempty"`
+}
+
+// ParseCompareIDs splits a comma-separated list of run IDs, dropping blanks
+// and duplicates, and checks the count.
+func ParseCompareIDs(list string) ([]string, error) {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, id := range strings.Split(list, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if len(ids) < 2 || len(ids) > MaxCompareRuns {
+		return nil, ErrCompareCount
+	}
+	return ids, nil
+}
+
+// ParseNumstat parses `git diff --numstat` output. Binary files count as
+// changed files without line counts.
+func ParseNumstat(out string) DiffStat {
+	var ds DiffStat
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		ds.Files++
+		ds.Paths = append(ds.Paths, fields[2])
+		if n, err := strconv.Atoi(fields[0]); err == nil {
+			ds.Insertions += n
+		}
+		if n, err := strconv.Atoi(fields[1]); err == nil {
+			ds.Deletions += n
+		}
+	}
+	return ds
+}
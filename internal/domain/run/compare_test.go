@@ -0,0 +1,42 @@
+package run_test
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+)
+
+func TestParseCompareIDs(t *testing.T) {
+	ids, err := run.ParseCompareIDs(" a, b,,a ,c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(ids, ",") != "a,b,c" {
+		t.Fatalf("unexpected ids %v", ids)
+	}
+	tooMany := make([]string, run.MaxCompareRuns+1)
+	for i := range tooMany {
+		tooMany[i] = strconv.Itoa(i)
+	}
+	for _, list := range []string{"", "a", "a,a", strings.Join(tooMany, ",")} {
+		if _, err := run.ParseCompareIDs(list); !errors.Is(err, run.ErrCompareCount) {
+			t.Errorf("ParseCompareIDs(%q): expected ErrCompareCount, got %v", list, err)
+		}
+	}
+}
+
+func TestParseNumstat(t *testing.T) {
+	ds := run.ParseNumstat("10\t2\tmain.go\n-\t-\tlogo.png\n3\t0\tdocs/a b.md\n")
+	if ds.Files != 3 || ds.Insertions != 13 || ds.Deletions != 2 {
+		t.Fatalf("unexpected stat %+v", ds)
+	}
+	if len(ds.Paths) != 3 || ds.Paths[2] != "docs/a b.md" {
+		t.Fatalf("unexpected paths %v", ds.Paths)
+	}
+	if empty := run.ParseNumstat(""); empty.Files != 0 {
+		t.Fatalf("expected empty stat, got %+v", empty)
+	}
+}
@@ -0,0 +1,29 @@
+package run
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// Project config keys for delivery.
+const (
+	ConfigDeliverModes = "deliver_modes" // Comma-separated deliver modes the project's runs may use; all when empty
+	ConfigMirrorURL    = "mirror_url"    // Remote that mirror delivery pushes to, e.g. an internal Gitea
+)
+
+// ErrDeliverModeNotAllowed is returned for runs whose deliver mode is not
+// in their project's deliver_modes.
+var ErrDeliverModeNotAllowed = errors.New("deliver mode not allowed for project")
+
+// AllowsDeliverMode reports whether a project config permits runs to
+// deliver with m. Not delivering is always permitted.
+func AllowsDeliverMode(cfg map[string]string, m DeliverMode) bool {
+	allowed := strings.TrimSpace(cfg[ConfigDeliverModes])
+	if m == DeliverModeNone || allowed == "" {
+		return true
+	}
+	return slices.ContainsFunc(strings.Split(allowed, ","), func(a string) bool {
+		return DeliverMode(strings.TrimSpace(a)) == m
+	})
+}
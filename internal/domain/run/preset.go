@@ -0,0 +1,111 @@
+package run
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// presetNamePattern matches preset names: a letter, then letters, digits,
+// "-" and "_".
+var presetNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// ErrInvalidPreset is returned for malformed run presets.
+var ErrInvalidPreset = errors.New("invalid run preset")
+
+// Preset is a named combination of run options of a project, e.g.
+// "quick-fix" or "docs-only". A StartRequest naming a preset takes the
+// preset's options for the fields it leaves empty.
+type Preset struct {
+	ID            string      `json:"id"`
+	ProjectID     string      `json:"project_id"`
+	Name          string      `json:"name"` // Unique per project
+	Description   string      `json:"description,omitempty"`
+	Backend       string      `json:"backend,omitempty"` // Agent backend; picks an agent of the project if the request names none
+	Mode          string      `json:"mode,omitempty"`    // Agent mode, replacing the agent's configured mode
+	PolicyProfile string      `json:"policy_profile,omitempty"`
+	ExecMode      ExecMode    `json:"exec_mode,omitempty"`
+	DeliverMode   DeliverMode `json:"deliver_mode,omitempty"`
+	Model         string      `json:"model,omitempty"`
+	TaskType      string      `json:"task_type,omitempty"` // Routes the model by task type if no model is given
+	Isolate       bool        `json:"isolate,omitempty"`
+	ContextBudget int         `json:"context_budget,omitempty"` // Context pack token budget; 0 uses the default
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
+
+// PresetRequest holds the fields of a preset. Updates replace all fields
+// with those of a request.
+type PresetRequest struct {
+	Name          string      `json:"name"`
+	Description   string      `json:"description,omitempty"`
+	Backend       string      `json:"backend,omitempty"`
+	Mode          string      `json:"mode,omitempty"`
+	PolicyProfile string      `json:"policy_profile,omitempty"`
+	ExecMode      ExecMode    `json:"exec_mode,omitempty"`
+	DeliverMode   DeliverMode `json:"deliver_mode,omitempty"`
+	Model         string      `json:"model,omitempty"`
+	TaskType      string      `json:"task_type,omitempty"`
+	Isolate       bool        `json:"isolate,omitempty"`
+	ContextBudget int         `json:"context_budget,omitempty"`
+}
+
+// Validate checks the name and options of a preset.
+func (r *PresetRequest) Validate() error {
+	if !presetNamePattern.MatchString(r.Name) {
+		return fmt.Errorf("%w: name %q must start with a letter and contain only letters, digits, '-' and '_'", ErrInvalidPreset, r.Name)
+	}
+	if r.ExecMode == ExecModeRemote || (r.ExecMode != "" && !validExecModes[r.ExecMode]) {
+		return fmt.Errorf("%w: invalid exec_mode %q", ErrInvalidPreset, r.ExecMode)
+	}
+	if !validDeliverModes[r.DeliverMode] {
+		return fmt.Errorf("%w: invalid deliver_mode %q", ErrInvalidPreset, r.DeliverMode)
+	}
+	if r.DeliverMode == DeliverModePush {
+		return fmt.Errorf("%w: deliver_mode %q needs the run's branch", ErrInvalidPreset, r.DeliverMode)
+	}
+	if r.ContextBudget < 0 {
+		return fmt.Errorf("%w: context_budget must be >= 0", ErrInvalidPreset)
+	}
+	return nil
+}
+
+// ApplyTo sets the fields of p from the request.
+func (r *PresetRequest) ApplyTo(p *Preset) {
+	p.Name = r.Name
+	p.Description = r.Description
+	p.Backend = r.Backend
+	p.Mode = r.Mode
+	p.PolicyProfile = r.PolicyProfile
+	p.ExecMode = r.ExecMode
+	p.DeliverMode = r.DeliverMode
+	p.Model = r.Model
+	p.TaskType = r.TaskType
+	p.Isolate = r.Isolate
+	p.ContextBudget = r.ContextBudget
+}
+
+// Fill sets the fields req leaves empty to the preset's options. The
+// request's own fields win; the backend is left to agent selection.
+func (p *Preset) Fill(req *StartRequest) {
+	if req.Mode == "" {
+		req.Mode = p.Mode
+	}
+	if req.PolicyProfile == "" {
+		req.PolicyProfile = p.PolicyProfile
+	}
+	if req.ExecMode == "" {
+		req.ExecMode = p.ExecMode
+	}
+	if req.DeliverMode == "" {
+		req.DeliverMode = p.DeliverMode
+	}
+	if req.Model == "" && req.TaskType == "" {
+		req.Model, req.TaskType = p.Model, p.TaskType
+	}
+	if req.ContextBudget == 0 {
+		req.ContextBudget = p.ContextBudget
+	}
+	req.Isolate = req.Isolate || p.Isolate
+}
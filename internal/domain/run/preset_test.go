@@ -0,0 +1,46 @@
+package run_test
+
+import (
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+)
+
+func TestPresetFill(t *testing.T) {
+	p := &run.Preset{
+		Mode: "architect", PolicyProfile: "trusted-mount-autonomous", DeliverMode: run.DeliverModePR,
+		Model: "openai/gpt-4o", TaskType: "code", Isolate: true, ContextBudget: 8192,
+	}
+
+	req := &run.StartRequest{DeliverMode: run.DeliverModePatch, TaskType: "review"}
+	p.Fill(req)
+	if req.Mode != "architect" || req.PolicyProfile != "trusted-mount-autonomous" || !req.Isolate || req.ContextBudget != 8192 {
+		t.Errorf("expected the preset's options, got %+v", req)
+	}
+	if req.DeliverMode != run.DeliverModePatch {
+		t.Errorf("expected the request's deliver mode, got %q", req.DeliverMode)
+	}
+	// A model route in the request keeps the preset's model out.
+	if req.Model != "" || req.TaskType != "review" {
+		t.Errorf("expected the request's model route, got %q %q", req.Model, req.TaskType)
+	}
+}
+
+func TestPresetRequestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		req   run.PresetRequest
+		valid bool
+	}{
+		{run.PresetRequest{Name: "docs-only", DeliverMode: run.DeliverModeCommitLocal}, true},
+		{run.PresetRequest{Name: "deep_refactor2", ExecMode: run.ExecModeSandbox}, true},
+		{run.PresetRequest{Name: ""}, false},
+		{run.PresetRequest{Name: "-x"}, false},
+		{run.PresetRequest{Name: "x", ExecMode: "vm"}, false},
+		{run.PresetRequest{Name: "x", DeliverMode: "email"}, false},
+		{run.PresetRequest{Name: "x", ContextBudget: -1}, false},
+	} {
+		if err := tc.req.Validate(); (err == nil) != tc.valid {
+			t.Errorf("%+v: valid=%v, got %v", tc.req, tc.valid, err)
+		}
+	}
+}
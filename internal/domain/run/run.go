@@ -1,7 +1,14 @@
 // Package run defines the Run domain entity for agent execution attempts.
 package run
 
-import "time"
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrFinished is returned when completing a run that already finished.
+var ErrFinished = errors.New("run already finished")
 
 // Status represents the current state of a run.
 type Status string
@@ -14,14 +21,23 @@ const (
 	StatusCancelled   Status = "cancelled"
 	StatusTimeout     Status = "timeout"
 	StatusQualityGate Status = "quality_gate" // Quality gate check in progress
+	// StatusFailedDownstream marks a completed run whose delivered changes
+	// later failed CI or were reverted (seatbelt mode).
+	StatusFailedDownstream Status = "failed_downstream"
 )
 
+// Active reports whether a run in this status has not finished yet.
+func (s Status) Active() bool {
+	return s == StatusPending || s == StatusRunning || s == StatusQualityGate
+}
+
 // ExecMode defines how the agent accesses the project filesystem.
 type ExecMode string
 
 const (
 	ExecModeMount   ExecMode = "mount"   // Direct host filesystem access
 	ExecModeSandbox ExecMode = "sandbox" // Isolated container
+	ExecModeRemote  ExecMode = "remote"  // Executed by a remote agent (A2A); set by the orchestrator
 )
 
 // DeliverMode defines how the output of a successful run is delivered.
@@ -33,38 +49,83 @@ const (
 	DeliverModeCommitLocal DeliverMode = "commit-local" // Git commit locally (no push)
 	DeliverModeBranch      DeliverMode = "branch"       // Push to feature branch
 	DeliverModePR          DeliverMode = "pr"           // Create pull request
+	DeliverModePush        DeliverMode = "push"         // Commit and push to the run's branch (e.g. a pull request's head)
+	DeliverModeMirror      DeliverMode = "mirror"       // Push a branch to the project's internal mirror and attach a patch bundle
 )
 
 // Run represents a single execution attempt of a task by an agent under a specific policy.
 // One task can have multiple runs (retries, different agents, different policies).
 type Run struct {
-	ID            string      `json:"id"`
-	TaskID        string      `json:"task_id"`
-	AgentID       string      `json:"agent_id"`
-	ProjectID     string      `json:"project_id"`
-	TeamID        string      `json:"team_id,omitempty"`
-	PolicyProfile string      `json:"policy_profile"`
-	ExecMode      ExecMode    `json:"exec_mode"`
-	DeliverMode   DeliverMode `json:"deliver_mode,omitempty"`
-	Status        Status      `json:"status"`
-	StepCount     int         `json:"step_count"`
-	CostUSD       float64     `json:"cost_usd"`
-	Output        string      `json:"output,omitempty"`
-	Error         string      `json:"error,omitempty"`
-	Version       int         `json:"version"`
-	StartedAt     time.Time   `json:"started_at"`
-	CompletedAt   *time.Time  `json:"completed_at,omitempty"`
-	CreatedAt     time.Time   `json:"created_at"`
-	UpdatedAt     time.Time   `json:"updated_at"`
+	ID               string          `json:"id"`
+	TaskID           string          `json:"task_id"`
+	AgentID          string          `json:"agent_id"`
+	ProjectID        string          `json:"project_id"`
+	TeamID           string          `json:"team_id,omitempty"`
+	ConversationID   string          `json:"conversation_id,omitempty"`  // Conversation that records the run's tool calls and answer
+	PlanStepID       string          `json:"plan_step_id,omitempty"`     // Plan step the run executes
+	PolicyProfile    string          `json:"policy_profile"`             // Requested profile, or the most specific one configured
+	EffectivePolicy  string          `json:"effective_policy,omitempty"` // Composite name of the layers tool calls are evaluated against; see Policy
+	ExecMode         ExecMode        `json:"exec_mode"`
+	DeliverMode      DeliverMode     `json:"deliver_mode,omitempty"`
+	WorktreePath     string          `json:"worktree_path,omitempty"` // Isolated git worktree; empty uses the project workspace
+	Branch           string          `json:"branch,omitempty"`        // Remote branch checked out in the worktree
+	Status           Status          `json:"status"`
+	StepCount        int             `json:"step_count"`
+	CostUSD          float64         `json:"cost_usd"`
+	TokensIn         int             `json:"tokens_in"`
+	TokensOut        int             `json:"tokens_out"`
+	Output           string          `json:"output,omitempty"`
+	Error            string          `json:"error,omitempty"`
+	Redactions       int             `json:"redactions"`                   // Credentials masked in the run's output
+	StructuredOutput json.RawMessage `json:"structured_output,omitempty"`  // Final answer parsed against the mode's output schema
+	Summary          string          `json:"summary,omitempty"`            // Natural language summary of the finished run: what changed, why, risks, follow-ups
+	EventsArchivedAt *time.Time      `json:"events_archived_at,omitempty"` // Events moved to an archive artifact
+	InterruptedAt    *time.Time      `json:"interrupted_at,omitempty"`     // Still active when its server shut down; cleared once resumed
+	Version          int             `json:"version"`
+	StartedAt        time.Time       `json:"started_at"`
+	CompletedAt      *time.Time      `json:"completed_at,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
+// Workspace returns the directory the run operates in: its worktree when
+// isolated, otherwise the shared project workspace.
+func (r *Run) Workspace(projectPath string) string {
+	if r.WorktreePath != "" {
+		return r.WorktreePath
+	}
+	return projectPath
+}
+
+// Policy returns the name of the policy the run's tool calls are evaluated
+// against: its effective composite policy, or its profile for runs started
+// before effective policies were recorded.
+func (r *Run) Policy() string {
+	if r.EffectivePolicy != "" {
+		return r.EffectivePolicy
+	}
+	return r.PolicyProfile
 }
 
 // StartRequest holds the fields needed to start a new run.
 type StartRequest struct {
-	TaskID        string      `json:"task_id"`
-	AgentID       string      `json:"agent_id"`
-	ProjectID     string      `json:"project_id"`
-	TeamID        string      `json:"team_id,omitempty"`
-	PolicyProfile string      `json:"policy_profile,omitempty"`
-	ExecMode      ExecMode    `json:"exec_mode,omitempty"`
-	DeliverMode   DeliverMode `json:"deliver_mode,omitempty"`
+	TaskID         string      `json:"task_id"`
+	AgentID        string      `json:"agent_id"`
+	ProjectID      string      `json:"project_id"`
+	TeamID         string      `json:"team_id,omitempty"`
+	PolicyProfile  string      `json:"policy_profile,omitempty"`
+	ExecMode       ExecMode    `json:"exec_mode,omitempty"`
+	DeliverMode    DeliverMode `json:"deliver_mode,omitempty"`
+	Isolate        bool        `json:"isolate,omitempty"`         // Run in a dedicated git worktree
+	Branch         string      `json:"branch,omitempty"`          // Run in a worktree of this remote branch; implies Isolate
+	Model          string      `json:"model,omitempty"`           // Overrides the agent's configured model
+	TaskType       string      `json:"task_type,omitempty"`       // Routes the model by task type (e.g. "review") if no model is given
+	TriggerEvent   string      `json:"trigger_event,omitempty"`   // Event type that started the run (e.g. "pm.issue.created"); activates microagents
+	Prompt         string      `json:"prompt,omitempty"`          // Replaces the task prompt (e.g. a plan step's prompt with references resolved)
+	ConversationID string      `json:"conversation_id,omitempty"` // Records the run's tool calls and answer in this conversation of the project
+	PlanStepID     string      `json:"plan_step_id,omitempty"`    // Attributes the run's usage to this plan step
+	Preset         string      `json:"preset,omitempty"`          // Run preset of the project filling the fields left empty
+	Mode           string      `json:"mode,omitempty"`            // Overrides the agent's configured mode
+	ContextBudget  int         `json:"context_budget,omitempty"`  // Context pack token budget; 0 uses the default
+	FromSnapshot   string      `json:"from_snapshot,omitempty"`   // Workspace snapshot of the project restored into the run's worktree before it starts; implies Isolate
 }
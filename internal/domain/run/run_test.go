@@ -152,6 +152,21 @@ func TestStartRequestValidate_EmptyExecMode(t *testing.T) {
 	}
 }
 
+func TestStartRequestValidate_Branch(t *testing.T) {
+	push := &run.StartRequest{TaskID: "t", AgentID: "a", ProjectID: "p", DeliverMode: run.DeliverModePush}
+	if err := push.Validate(); err == nil {
+		t.Fatal("expected error for push delivery without a branch")
+	}
+	push.Branch = "feature/login"
+	if err := push.Validate(); err != nil {
+		t.Fatalf("expected valid push delivery, got: %v", err)
+	}
+	push.ExecMode = run.ExecModeRemote
+	if err := push.Validate(); err == nil {
+		t.Fatal("expected error for a branch on a remote run")
+	}
+}
+
 func TestAllStatuses(t *testing.T) {
 	statuses := []run.Status{
 		run.StatusPending,
@@ -178,3 +193,33 @@ func TestAllExecModes(t *testing.T) {
 		}
 	}
 }
+
+func TestWorkspace(t *testing.T) {
+	r := &run.Run{}
+	if got := r.Workspace("/ws/proj"); got != "/ws/proj" {
+		t.Errorf("expected project workspace, got %q", got)
+	}
+	r.WorktreePath = "/worktrees/proj/run-1"
+	if got := r.Workspace("/ws/proj"); got != "/worktrees/proj/run-1" {
+		t.Errorf("expected worktree, got %q", got)
+	}
+}
+
+func TestAllowsDeliverMode(t *testing.T) {
+	tests := []struct {
+		allowed string
+		mode    run.DeliverMode
+		want    bool
+	}{
+		{"", run.DeliverModePR, true},
+		{"mirror, patch", run.DeliverModePatch, true},
+		{"mirror,patch", run.DeliverModePR, false},
+		{"mirror", run.DeliverModeNone, true},
+	}
+	for _, tt := range tests {
+		cfg := map[string]string{run.ConfigDeliverModes: tt.allowed}
+		if got := run.AllowsDeliverMode(cfg, tt.mode); got != tt.want {
+			t.Errorf("AllowsDeliverMode(%q, %q) = %v, want %v", tt.allowed, tt.mode, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package run
+
+import (
+	"sort"
+	"time"
+)
+
+// SpanKind classifies what a run spent a span of time on.
+type SpanKind string
+
+const (
+	SpanContext     SpanKind = "context"      // Building the prompt context before the agent starts
+	SpanLLM         SpanKind = "llm"          // An LLM call (tool "LLM")
+	SpanTool        SpanKind = "tool"         // A tool call other than LLM calls and commands
+	SpanExec        SpanKind = "exec"         // A command executed in the workspace or sandbox
+	SpanApproval    SpanKind = "approval"     // A tool call held for a human decision ("ask")
+	SpanQualityGate SpanKind = "quality_gate" // Tests and lint after the agent finished
+	SpanDelivery    SpanKind = "delivery"     // Patch, commit, push or pull request
+)
+
+// Span statuses.
+const (
+	SpanOK     = "ok"
+	SpanFailed = "failed"
+	SpanDenied = "denied"
+	SpanOpen   = "open" // Not finished when the run ended or the timeline was built
+)
+
+// Span is a segment of a run's execution. Spans may overlap, e.g. tool
+// calls executed in parallel.
+type Span struct {
+	Kind       SpanKind  `json:"kind"`
+	Name       string    `json:"name"`              // Tool name or phase
+	CallID     string    `json:"call_id,omitempty"` // Tool call of the span
+	Detail     string    `json:"detail,omitempty"`  // Command or path
+	Status     string    `json:"status"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	OffsetMS   int64     `json:"offset_ms"` // Start relative to the run's start
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// Timeline is a run's execution segmented into spans, for rendering as a
+// Gantt or flame chart.
+type Timeline struct {
+	RunID      string    `json:"run_id"`
+	Status     Status    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	EndedAt    time.Time `json:"ended_at"` // Completion, or now while the run is active
+	DurationMS int64     `json:"duration_ms"`
+	Spans      []Span    `json:"spans"`
+	// TotalsMS is the time spent per kind. Overlapping spans count in full.
+	TotalsMS map[SpanKind]int64 `json:"totals_ms"`
+	// UnaccountedMS is the time covered by no span, e.g. queueing and the
+	// agent's own processing between calls.
+	UnaccountedMS int64 `json:"unaccounted_ms"`
+}
+
+// Finish sorts the spans, closes the open ones at the end of the run and
+// computes offsets, durations and totals.
+func (t *Timeline) Finish() {
+	t.DurationMS = t.EndedAt.Sub(t.StartedAt).Milliseconds()
+	t.TotalsMS = make(map[SpanKind]int64)
+	for i := range t.Spans {
+		s := &t.Spans[i]
+		if s.End.IsZero() {
+			s.End = t.EndedAt
+			s.Status = SpanOpen
+		}
+		if s.End.Before(s.Start) {
+			s.End = s.Start
+		}
+		s.OffsetMS = s.Start.Sub(t.StartedAt).Milliseconds()
+		s.DurationMS = s.End.Sub(s.Start).Milliseconds()
+		t.TotalsMS[s.Kind] += s.DurationMS
+	}
+	sort.SliceStable(t.Spans, func(i, j int) bool { return t.Spans[i].Start.Before(t.Spans[j].Start) })
+
+	// Subtract the union of the spans from the run's duration.
+	covered := time.Duration(0)
+	var end time.Time
+	for i := range t.Spans {
+		s := &t.Spans[i]
+		start := s.Start
+		if start.Before(end) {
+			start = end
+		}
+		if s.End.After(start) {
+			covered += s.End.Sub(start)
+			end = s.End
+		}
+	}
+	t.UnaccountedMS = max(t.DurationMS-covered.Milliseconds(), 0)
+}
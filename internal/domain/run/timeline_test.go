@@ -0,0 +1,39 @@
+package run_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+)
+
+func TestTimelineFinish(t *testing.T) {
+	start := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	at := func(sec int) time.Time { return start.Add(time.Duration(sec) * time.Second) }
+	tl := &run.Timeline{
+		StartedAt: start,
+		EndedAt:   at(60),
+		Spans: []run.Span{
+			{Kind: run.SpanExec, Status: run.SpanOK, Start: at(20), End: at(30)},
+			// Parallel tool calls overlap.
+			{Kind: run.SpanTool, Status: run.SpanOK, Start: at(25), End: at(35)},
+			{Kind: run.SpanLLM, Status: run.SpanOK, Start: at(0), End: at(10)},
+			{Kind: run.SpanLLM, Start: at(50)},
+		},
+	}
+	tl.Finish()
+
+	if tl.DurationMS != 60_000 {
+		t.Fatalf("expected 60s, got %dms", tl.DurationMS)
+	}
+	if tl.Spans[0].Kind != run.SpanLLM || tl.Spans[3].Status != run.SpanOpen || tl.Spans[3].DurationMS != 10_000 {
+		t.Fatalf("expected spans sorted and the open span closed at the end, got %+v", tl.Spans)
+	}
+	if tl.TotalsMS[run.SpanLLM] != 20_000 || tl.TotalsMS[run.SpanTool] != 10_000 {
+		t.Errorf("unexpected totals %v", tl.TotalsMS)
+	}
+	// Covered: 0-10, 20-35, 50-60.
+	if tl.UnaccountedMS != 25_000 {
+		t.Errorf("expected 25s unaccounted, got %dms", tl.UnaccountedMS)
+	}
+}
@@ -4,13 +4,14 @@ import "fmt"
 
 // validStatuses enumerates all valid run statuses.
 var validStatuses = map[Status]bool{
-	StatusPending:     true,
-	StatusRunning:     true,
-	StatusCompleted:   true,
-	StatusFailed:      true,
-	StatusCancelled:   true,
-	StatusTimeout:     true,
-	StatusQualityGate: true,
+	StatusPending:          true,
+	StatusRunning:          true,
+	StatusCompleted:        true,
+	StatusFailed:           true,
+	StatusCancelled:        true,
+	StatusTimeout:          true,
+	StatusQualityGate:      true,
+	StatusFailedDownstream: true,
 }
 
 // validDeliverModes enumerates all valid delivery modes.
@@ -20,12 +21,15 @@ var validDeliverModes = map[DeliverMode]bool{
 	DeliverModeCommitLocal: true,
 	DeliverModeBranch:      true,
 	DeliverModePR:          true,
+	DeliverModePush:        true,
+	DeliverModeMirror:      true,
 }
 
 // validExecModes enumerates all valid execution modes.
 var validExecModes = map[ExecMode]bool{
 	ExecModeMount:   true,
 	ExecModeSandbox: true,
+	ExecModeRemote:  true,
 }
 
 // Validate checks that a Run has all required fields and valid values.
@@ -54,6 +58,12 @@ func (r *Run) Validate() error {
 	if r.DeliverMode != "" && !validDeliverModes[r.DeliverMode] {
 		return fmt.Errorf("invalid deliver_mode %q", r.DeliverMode)
 	}
+	if r.DeliverMode == DeliverModePush && r.Branch == "" {
+		return fmt.Errorf("deliver_mode %q requires a branch", r.DeliverMode)
+	}
+	if r.Branch != "" && r.ExecMode == ExecModeRemote {
+		return fmt.Errorf("branch is not supported for exec_mode %q", r.ExecMode)
+	}
 	return nil
 }
 
@@ -74,5 +84,22 @@ func (r *StartRequest) Validate() error {
 	if r.DeliverMode != "" && !validDeliverModes[r.DeliverMode] {
 		return fmt.Errorf("invalid deliver_mode %q", r.DeliverMode)
 	}
+	if r.DeliverMode == DeliverModePush && r.Branch == "" {
+		return fmt.Errorf("deliver_mode %q requires a branch", r.DeliverMode)
+	}
+	if r.Branch != "" && r.ExecMode == ExecModeRemote {
+		return fmt.Errorf("branch is not supported for exec_mode %q", r.ExecMode)
+	}
+	if r.FromSnapshot != "" && r.ExecMode == ExecModeRemote {
+		return fmt.Errorf("from_snapshot is not supported for exec_mode %q", r.ExecMode)
+	}
+	if r.ContextBudget < 0 {
+		return fmt.Errorf("context_budget must be non-negative")
+	}
 	return nil
 }
+
+// Valid reports whether m is a known delivery mode; the empty mode is valid.
+func (m DeliverMode) Valid() bool {
+	return validDeliverModes[m]
+}
@@ -0,0 +1,254 @@
+// Package sarif converts review and linter findings into SARIF 2.1.0 logs,
+// the format consumed by GitHub code scanning, DefectDojo and most other
+// static analysis tooling.
+package sarif
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/domain/lint"
+	"github.com/Strob0t/CodeForge/internal/domain/review"
+)
+
+const (
+	Version     = "2.1.0"
+	Schema      = "https://json.schemastore.org/sarif-2.1.0.json"
+	ContentType = "application/sarif+json"
+
+	// FingerprintKey names the partial fingerprint that identifies a
+	// finding across exports independently of its line.
+	FingerprintKey = "codeforgeFinding/v1"
+
+	reviewDriver   = "CodeForge Review"
+	informationURI = "https://github.com/Strob0t/CodeForge"
+)
+
+// Log is a SARIF log file.
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []Run  `json:"runs"`
+}
+
+// Run is the output of one analysis tool.
+type Run struct {
+	Tool              Tool               `json:"tool"`
+	AutomationDetails *AutomationDetails `json:"automationDetails,omitempty"`
+	Results           []Result           `json:"results"`
+}
+
+// Tool describes the analysis tool of a run.
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+// Driver is the analysis tool and the rules it reported.
+type Driver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri,omitempty"`
+	Rules          []Rule `json:"rules"`
+}
+
+// AutomationDetails identifies the category of a run; consumers replace
+// the results of an earlier upload with the same category.
+type AutomationDetails struct {
+	ID string `json:"id"`
+}
+
+// Rule is a reporting descriptor.
+type Rule struct {
+	ID                   string          `json:"id"`
+	DefaultConfiguration *Configuration  `json:"defaultConfiguration,omitempty"`
+	Properties           *RuleProperties `json:"properties,omitempty"`
+}
+
+// Configuration is the default configuration of a rule.
+type Configuration struct {
+	Level string `json:"level"`
+}
+
+// RuleProperties carries the tags and the security severity GitHub code
+// scanning uses to rank security alerts.
+type RuleProperties struct {
+	Tags             []string `json:"tags,omitempty"`
+	SecuritySeverity string   `json:"security-severity,omitempty"`
+}
+
+// Result is a single finding.
+type Result struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             Message           `json:"message"`
+	Locations           []Location        `json:"locations,omitempty"`
+	PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+	Properties          map[string]any    `json:"properties,omitempty"`
+}
+
+// Message is a plain text message.
+type Message struct {
+	Text string `json:"text"`
+}
+
+// Location is the place of a result in the repository.
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+// PhysicalLocation is a file and an optional region within it.
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+// ArtifactLocation is a file path relative to the repository root.
+type ArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+// Region is a position in a file.
+type Region struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// Level maps a review severity to a SARIF result level.
+func Level(s review.Severity) string {
+	switch s {
+	case review.SeverityCritical:
+		return "error"
+	case review.SeverityInfo:
+		return "note"
+	}
+	return "warning"
+}
+
+// securitySeverity is the CVSS-like score GitHub code scanning expects for
+// security rules.
+var securitySeverity = map[review.Severity]string{
+	review.SeverityCritical: "8.0",
+	review.SeverityWarning:  "5.0",
+	review.SeverityInfo:     "2.0",
+}
+
+// New builds a SARIF log with one run for the review, if not nil, and one
+// run per linter of the lint report, if not nil. Each run has its own
+// automation category so that repeated uploads replace earlier results.
+func New(rv *review.Review, lr *lint.Report) *Log {
+	l := &Log{Schema: Schema, Version: Version, Runs: []Run{}}
+	if rv != nil {
+		b := newRunBuilder(reviewDriver, "codeforge/review/")
+		for i := range rv.Findings {
+			f := &rv.Findings[i]
+			res := b.result("codeforge/review/"+string(f.Severity), f.Severity, false, f.Message, f.Path, f.Line, 0)
+			if f.Suggestion != "" {
+				res.Properties = map[string]any{"suggestion": f.Suggestion}
+			}
+		}
+		l.Runs = append(l.Runs, b.run)
+	}
+	if lr != nil {
+		byTool := make(map[lint.Tool]*runBuilder)
+		var order []lint.Tool
+		for _, t := range lr.Tools {
+			if _, ok := byTool[t.Tool]; !ok {
+				byTool[t.Tool] = newRunBuilder(string(t.Tool), "codeforge/lint/"+string(t.Tool)+"/")
+				order = append(order, t.Tool)
+			}
+		}
+		for i := range lr.Findings {
+			f := &lr.Findings[i]
+			b, ok := byTool[f.Tool]
+			if !ok {
+				b = newRunBuilder(string(f.Tool), "codeforge/lint/"+string(f.Tool)+"/")
+				byTool[f.Tool] = b
+				order = append(order, f.Tool)
+			}
+			ruleID := f.Rule
+			if ruleID == "" {
+				ruleID = string(f.Tool) + "/" + string(f.Severity)
+			}
+			res := b.result(ruleID, f.Severity, isSecurity(f), f.Message, f.Path, f.Line, f.Column)
+			res.Properties = map[string]any{"new": f.New}
+		}
+		for _, t := range order {
+			l.Runs = append(l.Runs, byTool[t].run)
+		}
+	}
+	return l
+}
+
+// isSecurity reports whether a linter finding comes from a security rule.
+func isSecurity(f *lint.Finding) bool {
+	switch f.Tool {
+	case lint.ToolGolangciLint:
+		return f.Rule == "gosec"
+	case lint.ToolRuff:
+		return strings.HasPrefix(f.Rule, "S")
+	case lint.ToolESLint:
+		return strings.HasPrefix(f.Rule, "security/")
+	}
+	return false
+}
+
+// runBuilder collects the results and rules of one run.
+type runBuilder struct {
+	run   Run
+	rules map[string]int
+}
+
+func newRunBuilder(driver, category string) *runBuilder {
+	d := Driver{Name: driver, Rules: []Rule{}}
+	if driver == reviewDriver {
+		d.InformationURI = informationURI
+	}
+	return &runBuilder{
+		run: Run{
+			Tool:              Tool{Driver: d},
+			AutomationDetails: &AutomationDetails{ID: category},
+			Results:           []Result{},
+		},
+		rules: make(map[string]int),
+	}
+}
+
+// result appends a result, registering its rule on first use. It returns
+// the appended result for further changes.
+func (b *runBuilder) result(ruleID string, sev review.Severity, security bool, msg, path string, line, col int) *Result {
+	idx, ok := b.rules[ruleID]
+	if !ok {
+		rule := Rule{ID: ruleID, DefaultConfiguration: &Configuration{Level: Level(sev)}}
+		if security {
+			rule.Properties = &RuleProperties{Tags: []string{"security"}, SecuritySeverity: securitySeverity[sev]}
+		}
+		idx = len(b.run.Tool.Driver.Rules)
+		b.run.Tool.Driver.Rules = append(b.run.Tool.Driver.Rules, rule)
+		b.rules[ruleID] = idx
+	}
+	res := Result{
+		RuleID:              ruleID,
+		RuleIndex:           idx,
+		Level:               Level(sev),
+		Message:             Message{Text: msg},
+		PartialFingerprints: map[string]string{FingerprintKey: fingerprint(b.run.Tool.Driver.Name, ruleID, path, msg)},
+	}
+	if path != "" {
+		loc := Location{PhysicalLocation: PhysicalLocation{ArtifactLocation: ArtifactLocation{URI: path, URIBaseID: "%SRCROOT%"}}}
+		if line > 0 {
+			loc.PhysicalLocation.Region = &Region{StartLine: line, StartColumn: col}
+		}
+		res.Locations = []Location{loc}
+	}
+	b.run.Results = append(b.run.Results, res)
+	return &b.run.Results[len(b.run.Results)-1]
+}
+
+// fingerprint identifies a finding independently of its line, so that
+// consumers track it across exports when surrounding code moves.
+func fingerprint(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,82 @@
+package sarif_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/lint"
+	"github.com/Strob0t/CodeForge/internal/domain/review"
+	"github.com/Strob0t/CodeForge/internal/domain/sarif"
+)
+
+func TestNew(t *testing.T) {
+	rv := &review.Review{Summary: "two problems", Findings: []review.Finding{
+		{Path: "api/handler.go", Line: 42, Severity: review.SeverityCritical, Message: "SQL built from user input", Suggestion: "db.Query(q, id)"},
+		{Severity: review.SeverityInfo, Message: "Consider splitting this change"},
+		{Path: "api/handler.go", Line: 50, Severity: review.SeverityCritical, Message: "Missing auth check"},
+	}}
+	lr := &lint.Report{}
+	lr.Add(lint.ToolResult{Tool: lint.ToolGolangciLint}, []lint.Finding{
+		{Tool: lint.ToolGolangciLint, Rule: "gosec", Path: "api/db.go", Line: 7, Column: 3, Severity: review.SeverityCritical, Message: "G201: SQL string formatting", New: true},
+		{Tool: lint.ToolGolangciLint, Rule: "errcheck", Path: "api/db.go", Line: 9, Severity: review.SeverityWarning, Message: "unchecked error"},
+	})
+	lr.Add(lint.ToolResult{Tool: lint.ToolRuff}, nil)
+
+	log := sarif.New(rv, lr)
+	if log.Version != "2.1.0" || len(log.Runs) != 3 {
+		t.Fatalf("unexpected log %+v", log)
+	}
+
+	rev := log.Runs[0]
+	if rev.Tool.Driver.Name != "CodeForge Review" || rev.AutomationDetails.ID != "codeforge/review/" || len(rev.Results) != 3 {
+		t.Fatalf("unexpected review run %+v", rev)
+	}
+	if len(rev.Tool.Driver.Rules) != 2 || rev.Results[2].RuleIndex != 0 || rev.Results[1].RuleID != "codeforge/review/info" {
+		t.Fatalf("unexpected review rules %+v", rev.Tool.Driver.Rules)
+	}
+	first := rev.Results[0]
+	if first.Level != "error" || first.Locations[0].PhysicalLocation.ArtifactLocation.URI != "api/handler.go" ||
+		first.Locations[0].PhysicalLocation.Region.StartLine != 42 || first.Properties["suggestion"] != "db.Query(q, id)" {
+		t.Fatalf("unexpected result %+v", first)
+	}
+	if rev.Results[1].Locations != nil || rev.Results[1].Level != "note" {
+		t.Fatalf("file-less finding should have no location: %+v", rev.Results[1])
+	}
+
+	golangci := log.Runs[1]
+	if golangci.Tool.Driver.Name != "golangci-lint" || golangci.AutomationDetails.ID != "codeforge/lint/golangci-lint/" {
+		t.Fatalf("unexpected lint run %+v", golangci)
+	}
+	rule := golangci.Tool.Driver.Rules[0]
+	if rule.ID != "gosec" || rule.Properties == nil || rule.Properties.SecuritySeverity != "8.0" || rule.Properties.Tags[0] != "security" {
+		t.Fatalf("expected security rule, got %+v", rule)
+	}
+	if golangci.Tool.Driver.Rules[1].Properties != nil || golangci.Results[0].Properties["new"] != true {
+		t.Fatalf("unexpected lint results %+v", golangci)
+	}
+
+	// Linters without findings still produce a run so that uploads clear
+	// earlier alerts.
+	if ruff := log.Runs[2]; ruff.Tool.Driver.Name != "ruff" || ruff.Results == nil || len(ruff.Results) != 0 {
+		t.Fatalf("unexpected empty run %+v", ruff)
+	}
+
+	data, err := json.Marshal(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil || raw["$schema"] != sarif.Schema {
+		t.Fatalf("unexpected JSON %s", data)
+	}
+}
+
+func TestFingerprintIgnoresLine(t *testing.T) {
+	at := func(line int) string {
+		rv := &review.Review{Findings: []review.Finding{{Path: "a.go", Line: line, Severity: review.SeverityWarning, Message: "m"}}}
+		return sarif.New(rv, nil).Runs[0].Results[0].PartialFingerprints[sarif.FingerprintKey]
+	}
+	if at(3) == "" || at(3) != at(30) {
+		t.Fatal("fingerprint should be stable across line changes")
+	}
+}
@@ -0,0 +1,66 @@
+package scaffold
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadFromFile reads a single Template from a YAML file.
+func LoadFromFile(path string) (*Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read project template %s: %w", path, err)
+	}
+
+	var t Template
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("parse project template %s: %w", path, err)
+	}
+
+	if err := t.Validate(); err != nil {
+		return nil, fmt.Errorf("validate project template %s: %w", path, err)
+	}
+
+	return &t, nil
+}
+
+// LoadFromDirectory reads all .yaml/.yml templates from a directory.
+// Missing directories return an empty slice (not an error).
+func LoadFromDirectory(dir string) ([]Template, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read template directory %s: %w", dir, err)
+	}
+
+	var templates []Template
+	seen := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+
+		t, err := LoadFromFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := seen[t.Name]; ok {
+			return nil, fmt.Errorf("project template %q defined in both %s and %s", t.Name, prev, entry.Name())
+		}
+		seen[t.Name] = entry.Name()
+		templates = append(templates, *t)
+	}
+
+	return templates, nil
+}
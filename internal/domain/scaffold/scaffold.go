@@ -0,0 +1,307 @@
+// Package scaffold defines project templates: the files of a new
+// repository and the agents, modes, policies and pipelines wired into the
+// project created from it.
+package scaffold
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/Strob0t/CodeForge/internal/domain/agent"
+	"github.com/Strob0t/CodeForge/internal/domain/lint"
+	"github.com/Strob0t/CodeForge/internal/domain/mode"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/testreport"
+)
+
+var (
+	ErrInvalidName      = errors.New("template name must be lowercase letters, digits and dashes")
+	ErrNoFiles          = errors.New("template has no files")
+	ErrInvalidPath      = errors.New("file path must be relative and stay inside the repository")
+	ErrNameRequired     = errors.New("project name is required")
+	ErrMissingVariable  = errors.New("missing template variable")
+	ErrUnknownVariable  = errors.New("unknown template variable")
+	ErrCannotInitialize = errors.New("git provider cannot initialize repositories")
+)
+
+var (
+	namePattern     = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+	variablePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Template describes a new project.
+type Template struct {
+	Name        string            `json:"name" yaml:"name"` // Used in the URL, e.g. "go-service"
+	Description string            `json:"description" yaml:"description"`
+	Stack       Stack             `json:"stack" yaml:"stack"`
+	Variables   []Variable        `json:"variables,omitempty" yaml:"variables"`
+	Files       []File            `json:"files" yaml:"files"`
+	Config      map[string]string `json:"config,omitempty" yaml:"config"` // Project config, e.g. policy_profile
+	Modes       []mode.Mode       `json:"modes,omitempty" yaml:"modes"`   // Custom modes the agents may use
+	Agents      []Agent           `json:"agents,omitempty" yaml:"agents"`
+	Pipelines   []Pipeline        `json:"pipelines,omitempty" yaml:"pipelines"`
+}
+
+// Stack is the language stack of a template. Its commands become project
+// config so that quality gates do not have to detect them.
+type Stack struct {
+	Language    string   `json:"language" yaml:"language"`
+	TestCommand string   `json:"test_command,omitempty" yaml:"test_command"`
+	Linters     []string `json:"linters,omitempty" yaml:"linters"`
+}
+
+// Variable is a value the files of a template are rendered with.
+type Variable struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description"`
+	Default     string `json:"default,omitempty" yaml:"default"` // Empty makes the variable required
+}
+
+// File is a file of the new repository. Path and Content are Go templates
+// rendered with .Project (the project name) and .Vars.
+type File struct {
+	Path       string `json:"path" yaml:"path"` // Slash-separated, relative to the repository root
+	Content    string `json:"content" yaml:"content"`
+	Executable bool   `json:"executable,omitempty" yaml:"executable"`
+}
+
+// Agent is an agent created for the project.
+type Agent struct {
+	Name          string            `json:"name" yaml:"name"`
+	Backend       string            `json:"backend" yaml:"backend"`
+	Mode          string            `json:"mode,omitempty" yaml:"mode"`
+	Model         string            `json:"model,omitempty" yaml:"model"`
+	PolicyProfile string            `json:"policy_profile,omitempty" yaml:"policy_profile"`
+	Config        map[string]string `json:"config,omitempty" yaml:"config"`
+}
+
+// AgentConfig returns the config of the created agent.
+func (a *Agent) AgentConfig() map[string]string {
+	cfg := maps.Clone(a.Config)
+	if cfg == nil {
+		cfg = map[string]string{}
+	}
+	for k, v := range map[string]string{"mode": a.Mode, "model": a.Model, policy.ConfigKeyProfile: a.PolicyProfile} {
+		if v != "" {
+			cfg[k] = v
+		}
+	}
+	return cfg
+}
+
+// Pipeline is an execution plan created for the project, e.g. to build
+// the first version of the service.
+type Pipeline struct {
+	Name        string        `json:"name" yaml:"name"`
+	Description string        `json:"description,omitempty" yaml:"description"`
+	Protocol    plan.Protocol `json:"protocol" yaml:"protocol"`
+	Steps       []Step        `json:"steps" yaml:"steps"`
+}
+
+// Step is a task of a pipeline and the template agent that runs it.
+type Step struct {
+	Name      string `json:"name,omitempty" yaml:"name,omitempty"` // Lets later prompts use {{steps.<name>.output}}
+	Title     string `json:"title" yaml:"title"`
+	Prompt    string `json:"prompt" yaml:"prompt"`
+	Agent     string `json:"agent" yaml:"agent"`                               // Name of a template agent
+	DependsOn []int  `json:"depends_on,omitempty" yaml:"depends_on,omitempty"` // Indices of earlier steps
+}
+
+// Validate checks the template, including that its files are valid Go
+// templates and that agents and steps only reference known modes and
+// agents.
+func (t *Template) Validate() error {
+	if !namePattern.MatchString(t.Name) {
+		return ErrInvalidName
+	}
+	if len(t.Files) == 0 {
+		return ErrNoFiles
+	}
+	for _, l := range t.Stack.Linters {
+		if !lint.Tool(l).Valid() {
+			return fmt.Errorf("stack: unknown linter %q", l)
+		}
+	}
+	for _, v := range t.Variables {
+		if !variablePattern.MatchString(v.Name) {
+			return fmt.Errorf("variable %q: name must be an identifier", v.Name)
+		}
+	}
+	for i, f := range t.Files {
+		if f.Path == "" || strings.HasPrefix(f.Path, "/") || strings.HasPrefix(path.Clean(f.Path), "..") {
+			return fmt.Errorf("file %d %q: %w", i, f.Path, ErrInvalidPath)
+		}
+		if _, err := parse(f.Path, f.Content); err != nil {
+			return fmt.Errorf("file %q: %w", f.Path, err)
+		}
+	}
+
+	modes := make(map[string]bool)
+	for _, m := range mode.BuiltinModes() {
+		modes[m.ID] = true
+	}
+	for i := range t.Modes {
+		if err := t.Modes[i].Validate(); err != nil {
+			return fmt.Errorf("mode %q: %w", t.Modes[i].ID, err)
+		}
+		modes[t.Modes[i].ID] = true
+	}
+	agents := make(map[string]bool)
+	for _, a := range t.Agents {
+		switch {
+		case a.Name == "" || a.Backend == "":
+			return fmt.Errorf("agent %q: name and backend are required", a.Name)
+		case agents[a.Name]:
+			return fmt.Errorf("agent %q is defined twice", a.Name)
+		case a.Mode != "" && !modes[a.Mode]:
+			return fmt.Errorf("agent %q: unknown mode %q", a.Name, a.Mode)
+		}
+		agents[a.Name] = true
+	}
+	for _, p := range t.Pipelines {
+		if p.Name == "" || len(p.Steps) == 0 {
+			return fmt.Errorf("pipeline %q: name and steps are required", p.Name)
+		}
+		switch p.Protocol {
+		case plan.ProtocolSequential, plan.ProtocolParallel, plan.ProtocolPingPong, plan.ProtocolConsensus:
+		default:
+			return fmt.Errorf("pipeline %q: %w", p.Name, plan.ErrInvalidProtocol)
+		}
+		for i, s := range p.Steps {
+			if s.Title == "" || s.Prompt == "" {
+				return fmt.Errorf("pipeline %q step %d: title and prompt are required", p.Name, i)
+			}
+			if !agents[s.Agent] {
+				return fmt.Errorf("pipeline %q step %d: unknown agent %q", p.Name, i, s.Agent)
+			}
+			for _, d := range s.DependsOn {
+				if d < 0 || d >= i {
+					return fmt.Errorf("pipeline %q step %d: depends_on must name earlier steps", p.Name, i)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ProjectConfig returns the config of a project created from the template
+// with the request's config on top.
+func (t *Template) ProjectConfig(extra map[string]string) map[string]string {
+	cfg := make(map[string]string, len(t.Config)+len(extra)+2)
+	if t.Stack.TestCommand != "" {
+		cfg[testreport.ConfigKeyCommand] = t.Stack.TestCommand
+	}
+	if len(t.Stack.Linters) > 0 {
+		cfg[lint.ConfigKeyLinters] = strings.Join(t.Stack.Linters, ",")
+	}
+	maps.Copy(cfg, t.Config)
+	maps.Copy(cfg, extra)
+	return cfg
+}
+
+// Render returns the files of the template rendered for a project, with
+// the defaults of variables the request does not set.
+func (t *Template) Render(projectName string, vars map[string]string) ([]File, error) {
+	data := struct {
+		Project string
+		Vars    map[string]string
+	}{projectName, make(map[string]string, len(t.Variables))}
+	for _, v := range t.Variables {
+		data.Vars[v.Name] = v.Default
+		if val, ok := vars[v.Name]; ok {
+			data.Vars[v.Name] = val
+		}
+	}
+
+	files := make([]File, 0, len(t.Files))
+	seen := make(map[string]bool, len(t.Files))
+	for _, f := range t.Files {
+		tmpl, err := parse(f.Path, f.Content)
+		if err != nil {
+			return nil, fmt.Errorf("file %q: %w", f.Path, err)
+		}
+		var p, content bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&p, "path", data); err != nil {
+			return nil, fmt.Errorf("render path %q: %w", f.Path, err)
+		}
+		if err := tmpl.ExecuteTemplate(&content, "content", data); err != nil {
+			return nil, fmt.Errorf("render file %q: %w", f.Path, err)
+		}
+		clean := path.Clean(p.String())
+		if clean == "." || path.IsAbs(clean) || strings.HasPrefix(clean, "..") || seen[clean] {
+			return nil, fmt.Errorf("file %q renders to %q: %w", f.Path, clean, ErrInvalidPath)
+		}
+		seen[clean] = true
+		files = append(files, File{Path: clean, Content: content.String(), Executable: f.Executable})
+	}
+	return files, nil
+}
+
+// parse parses the path and content of a file as the templates "path" and
+// "content". Missing variables are errors, not "<no value>".
+func parse(p, content string) (*template.Template, error) {
+	tmpl := template.New("path").Option("missingkey=error")
+	if _, err := tmpl.Parse(p); err != nil {
+		return nil, err
+	}
+	if _, err := tmpl.New("content").Parse(content); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// CreateRequest creates a project from a template.
+type CreateRequest struct {
+	TenantID    string            `json:"tenant_id,omitempty"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Provider    string            `json:"provider,omitempty"` // Git provider; defaults to "local"
+	RepoURL     string            `json:"repo_url,omitempty"` // Empty remote the first commit is pushed to; empty keeps the repository local
+	Config      map[string]string `json:"config,omitempty"`   // Added to the template's project config
+	Variables   map[string]string `json:"variables,omitempty"`
+	AutoStart   bool              `json:"auto_start,omitempty"` // Start the template's pipelines right away
+}
+
+// Validate checks the request against the template: every variable it
+// sets must exist and every variable without a default must be set.
+func (r *CreateRequest) Validate(t *Template) error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return ErrNameRequired
+	}
+	if r.Provider == "" {
+		r.Provider = "local"
+	}
+	known := make(map[string]bool, len(t.Variables))
+	for _, v := range t.Variables {
+		known[v.Name] = true
+		if v.Default == "" && r.Variables[v.Name] == "" {
+			return fmt.Errorf("%w %s", ErrMissingVariable, v.Name)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(r.Variables)) {
+		if !known[name] {
+			return fmt.Errorf("%w %s", ErrUnknownVariable, name)
+		}
+	}
+	return nil
+}
+
+// Result is a project created from a template with everything wired into
+// it.
+type Result struct {
+	Template string               `json:"template"`
+	Project  *project.Project     `json:"project"`
+	Files    []string             `json:"files"`
+	Agents   []agent.Agent        `json:"agents"`
+	Plans    []plan.ExecutionPlan `json:"plans"`
+}
@@ -0,0 +1,162 @@
+package scaffold_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/scaffold"
+)
+
+func validTemplate() scaffold.Template {
+	return scaffold.Template{
+		Name:      "go-service",
+		Stack:     scaffold.Stack{Language: "go", TestCommand: "go test ./...", Linters: []string{"golangci-lint"}},
+		Variables: []scaffold.Variable{{Name: "module"}, {Name: "go_version", Default: "1.23"}},
+		Files: []scaffold.File{
+			{Path: "go.mod", Content: "module {{ .Vars.module }}\n\ngo {{ .Vars.go_version }}\n"},
+			{Path: "cmd/{{ .Project }}/main.go", Content: "package main\n"},
+		},
+		Agents: []scaffold.Agent{{Name: "coder", Backend: "aider", Mode: "coder"}},
+		Pipelines: []scaffold.Pipeline{{
+			Name: "first", Protocol: plan.ProtocolSequential,
+			Steps: []scaffold.Step{{Title: "a", Prompt: "do a", Agent: "coder"}, {Title: "b", Prompt: "do b", Agent: "coder", DependsOn: []int{0}}},
+		}},
+	}
+}
+
+func TestTemplateValidate(t *testing.T) {
+	tmpl := validTemplate()
+	if err := tmpl.Validate(); err != nil {
+		t.Fatalf("expected valid template, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*scaffold.Template)
+		want   string
+	}{
+		{"bad name", func(t *scaffold.Template) { t.Name = "Go Service" }, "template name"},
+		{"no files", func(t *scaffold.Template) { t.Files = nil }, "no files"},
+		{"escaping path", func(t *scaffold.Template) { t.Files[0].Path = "../go.mod" }, "inside the repository"},
+		{"absolute path", func(t *scaffold.Template) { t.Files[0].Path = "/etc/passwd" }, "inside the repository"},
+		{"bad template", func(t *scaffold.Template) { t.Files[0].Content = "{{ .Vars.module" }, "go.mod"},
+		{"unknown linter", func(t *scaffold.Template) { t.Stack.Linters = []string{"jslint"} }, "unknown linter"},
+		{"unknown mode", func(t *scaffold.Template) { t.Agents[0].Mode = "poet" }, "unknown mode"},
+		{"duplicate agent", func(t *scaffold.Template) { t.Agents = append(t.Agents, t.Agents[0]) }, "defined twice"},
+		{"unknown step agent", func(t *scaffold.Template) { t.Pipelines[0].Steps[1].Agent = "tester" }, "unknown agent"},
+		{"forward dependency", func(t *scaffold.Template) { t.Pipelines[0].Steps[0].DependsOn = []int{1} }, "earlier steps"},
+		{"bad protocol", func(t *scaffold.Template) { t.Pipelines[0].Protocol = "round_robin" }, "invalid protocol"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := validTemplate()
+			tt.mutate(&tmpl)
+			err := tmpl.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestTemplateRender(t *testing.T) {
+	tmpl := validTemplate()
+	files, err := tmpl.Render("billing", map[string]string{"module": "example.com/billing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[1].Path != "cmd/billing/main.go" {
+		t.Fatalf("unexpected files %+v", files)
+	}
+	if files[0].Content != "module example.com/billing\n\ngo 1.23\n" {
+		t.Fatalf("unexpected go.mod %q", files[0].Content)
+	}
+
+	// Undeclared variables are errors, not "<no value>".
+	tmpl.Files[0].Content = "{{ .Vars.missing }}"
+	if _, err := tmpl.Render("billing", nil); err == nil {
+		t.Fatal("expected error for undeclared variable")
+	}
+
+	// A path that renders outside the repository is rejected.
+	tmpl = validTemplate()
+	tmpl.Files[1].Path = "{{ .Project }}/x"
+	if _, err := tmpl.Render("..", nil); !errors.Is(err, scaffold.ErrInvalidPath) {
+		t.Fatalf("expected ErrInvalidPath, got %v", err)
+	}
+}
+
+func TestTemplateProjectConfig(t *testing.T) {
+	tmpl := validTemplate()
+	tmpl.Config = map[string]string{"policy_profile": "plan-readonly"}
+	cfg := tmpl.ProjectConfig(map[string]string{"policy_profile": "headless-safe-sandbox"})
+	if cfg["test_command"] != "go test ./..." || cfg["linters"] != "golangci-lint" {
+		t.Fatalf("expected stack in config, got %v", cfg)
+	}
+	if cfg["policy_profile"] != "headless-safe-sandbox" {
+		t.Fatalf("expected request config to win, got %v", cfg)
+	}
+}
+
+func TestCreateRequestValidate(t *testing.T) {
+	tmpl := validTemplate()
+
+	req := scaffold.CreateRequest{Name: " billing ", Variables: map[string]string{"module": "example.com/billing"}}
+	if err := req.Validate(&tmpl); err != nil {
+		t.Fatal(err)
+	}
+	if req.Name != "billing" || req.Provider != "local" {
+		t.Fatalf("expected trimmed name and local provider, got %+v", req)
+	}
+
+	req = scaffold.CreateRequest{Name: "billing"}
+	if err := req.Validate(&tmpl); !errors.Is(err, scaffold.ErrMissingVariable) {
+		t.Fatalf("expected ErrMissingVariable, got %v", err)
+	}
+	req = scaffold.CreateRequest{Name: "billing", Variables: map[string]string{"module": "m", "port": "80"}}
+	if err := req.Validate(&tmpl); !errors.Is(err, scaffold.ErrUnknownVariable) {
+		t.Fatalf("expected ErrUnknownVariable, got %v", err)
+	}
+	req = scaffold.CreateRequest{}
+	if err := req.Validate(&tmpl); !errors.Is(err, scaffold.ErrNameRequired) {
+		t.Fatalf("expected ErrNameRequired, got %v", err)
+	}
+}
+
+func TestLoadFromDirectory(t *testing.T) {
+	// The shipped templates are valid.
+	templates, err := scaffold.LoadFromDirectory(filepath.Join("..", "..", "..", "templates"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) == 0 {
+		t.Fatal("expected shipped templates")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("ignored"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join("..", "..", "..", "templates", "go-service.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.yaml", "b.yml"} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := scaffold.LoadFromDirectory(dir); err == nil || !strings.Contains(err.Error(), "defined in both") {
+		t.Fatalf("expected duplicate template error, got %v", err)
+	}
+
+	// A missing directory has no templates.
+	templates, err = scaffold.LoadFromDirectory(filepath.Join(dir, "missing"))
+	if err != nil || templates != nil {
+		t.Fatalf("expected no templates, got %v, %v", templates, err)
+	}
+}
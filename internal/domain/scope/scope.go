@@ -0,0 +1,182 @@
+// Package scope defines retrieval scopes: named groups of a tenant's
+// projects and knowledge bases that are searched together, so an agent
+// working on one repository finds answers in the others, such as a shared
+// SDK. Each source of a scope weighs its results in the merged ranking.
+package scope
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+)
+
+// Limits of scopes.
+const (
+	MaxNameLen = 100
+	MaxWeight  = 10
+)
+
+var (
+	ErrNameRequired    = errors.New("name is required")
+	ErrNameTooLong     = errors.New("name is too long (max 100 characters)")
+	ErrNoProjects      = errors.New("a scope needs at least one project")
+	ErrInvalidWeight   = fmt.Errorf("weight must be between 0 and %d", MaxWeight)
+	ErrDuplicateSource = errors.New("a project or knowledge base is listed twice")
+)
+
+// Source is a member project or knowledge base of a scope.
+type Source struct {
+	ID     string  `json:"id"`
+	Weight float64 `json:"weight,omitempty"` // Multiplies the scores of the source's results; 0 means 1
+}
+
+// EffectiveWeight returns the weight of the source, 1 if unset.
+func (s *Source) EffectiveWeight() float64 {
+	if s.Weight == 0 {
+		return 1
+	}
+	return s.Weight
+}
+
+// Scope is a group of a tenant's projects searched together. Searches
+// include the knowledge bases attached to its projects and those listed
+// in KnowledgeBases.
+type Scope struct {
+	ID             string    `json:"id"`
+	TenantID       string    `json:"tenant_id"`
+	Name           string    `json:"name"` // Unique per tenant
+	Description    string    `json:"description,omitempty"`
+	Projects       []Source  `json:"projects"`
+	KnowledgeBases []Source  `json:"knowledge_bases,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// KnowledgeWeight returns the weight of a knowledge base in the scope's
+// searches given the projects it is attached to: its own weight if the
+// scope lists it, otherwise the highest weight of those of the scope's
+// projects it is attached to. ok is false if it is not part of the scope.
+func (s *Scope) KnowledgeWeight(kbID string, attached func(projectID string) bool) (weight float64, ok bool) {
+	for i := range s.KnowledgeBases {
+		if s.KnowledgeBases[i].ID == kbID {
+			return s.KnowledgeBases[i].EffectiveWeight(), true
+		}
+	}
+	for i := range s.Projects {
+		if w := s.Projects[i].EffectiveWeight(); attached(s.Projects[i].ID) && (!ok || w > weight) {
+			weight, ok = w, true
+		}
+	}
+	return weight, ok
+}
+
+// CreateRequest holds the fields for creating or updating a scope. Updates
+// keep the tenant.
+type CreateRequest struct {
+	Name           string   `json:"name"`
+	TenantID       string   `json:"tenant_id,omitempty"` // Default: the request's tenant
+	Description    string   `json:"description,omitempty"`
+	Projects       []Source `json:"projects"`
+	KnowledgeBases []Source `json:"knowledge_bases,omitempty"`
+}
+
+// Validate checks the name, tenant and sources.
+func (r *CreateRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return ErrNameRequired
+	}
+	if len(r.Name) > MaxNameLen {
+		return ErrNameTooLong
+	}
+	if r.TenantID != "" && !tenant.ValidID(r.TenantID) {
+		return tenant.ErrInvalidID
+	}
+	if len(r.Projects) == 0 {
+		return ErrNoProjects
+	}
+	for _, sources := range [][]Source{r.Projects, r.KnowledgeBases} {
+		seen := make(map[string]bool, len(sources))
+		for _, s := range sources {
+			switch {
+			case strings.TrimSpace(s.ID) == "":
+				return errors.New("sources must have an id")
+			case s.Weight < 0 || s.Weight > MaxWeight:
+				return ErrInvalidWeight
+			case seen[s.ID]:
+				return ErrDuplicateSource
+			}
+			seen[s.ID] = true
+		}
+	}
+	return nil
+}
+
+// Result is a chunk found by a scope search, with the source it comes
+// from and its weighted score.
+type Result struct {
+	retrieval.Result
+	ProjectID       string  `json:"project_id,omitempty"`        // Project of a workspace chunk
+	KnowledgeBaseID string  `json:"knowledge_base_id,omitempty"` // Knowledge base of a document chunk
+	Weight          float64 `json:"weight"`
+
+	// RankScore orders the merged results: the weight of the source times
+	// the rerank score if the results were reranked, else times the
+	// embedding score.
+	RankScore float64 `json:"rank_score"`
+}
+
+// Skipped is a source a scope search could not include, such as a
+// project without an index.
+type Skipped struct {
+	ProjectID       string `json:"project_id,omitempty"`
+	KnowledgeBaseID string `json:"knowledge_base_id,omitempty"`
+	Reason          string `json:"reason"`
+}
+
+// SearchResult is the merged ranking of a scope search.
+type SearchResult struct {
+	Results []Result  `json:"results"`
+	Skipped []Skipped `json:"skipped,omitempty"`
+}
+
+// Weigh returns results of a source with their weighted scores.
+func Weigh(results []retrieval.Result, projectID, kbID string, weight float64) []Result {
+	out := make([]Result, len(results))
+	for i := range results {
+		score := results[i].Score
+		if results[i].RerankScore != nil {
+			score = *results[i].RerankScore
+		}
+		out[i] = Result{Result: results[i], ProjectID: projectID, KnowledgeBaseID: kbID, Weight: weight, RankScore: weight * score}
+	}
+	return out
+}
+
+// Merge orders the results of all sources by rank score and returns the
+// best limit. Chunks found in several sources, such as a file vendored
+// into two projects, are kept once with their best score.
+func Merge(results []Result, limit int) []Result {
+	best := make(map[string]int, len(results))
+	out := make([]Result, 0, len(results))
+	for i := range results {
+		j, ok := best[results[i].ID]
+		switch {
+		case !ok:
+			best[results[i].ID] = len(out)
+			out = append(out, results[i])
+		case results[i].RankScore > out[j].RankScore:
+			out[j] = results[i]
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].RankScore > out[j].RankScore })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+package scope_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+	"github.com/Strob0t/CodeForge/internal/domain/scope"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+)
+
+func TestCreateRequest_Validate(t *testing.T) {
+	app := []scope.Source{{ID: "app"}, {ID: "sdk", Weight: 0.5}}
+	tests := []struct {
+		name    string
+		req     scope.CreateRequest
+		wantErr error
+		wantOK  bool
+	}{
+		{"projects", scope.CreateRequest{Name: "platform", Projects: app}, nil, true},
+		{"knowledge bases", scope.CreateRequest{Name: "platform", Projects: app, KnowledgeBases: []scope.Source{{ID: "kb-1", Weight: 2}}}, nil, true},
+		{"no name", scope.CreateRequest{Name: " ", Projects: app}, scope.ErrNameRequired, false},
+		{"bad tenant", scope.CreateRequest{Name: "platform", TenantID: "Acme", Projects: app}, tenant.ErrInvalidID, false},
+		{"no projects", scope.CreateRequest{Name: "platform", KnowledgeBases: []scope.Source{{ID: "kb-1"}}}, scope.ErrNoProjects, false},
+		{"negative weight", scope.CreateRequest{Name: "platform", Projects: []scope.Source{{ID: "app", Weight: -1}}}, scope.ErrInvalidWeight, false},
+		{"large weight", scope.CreateRequest{Name: "platform", Projects: []scope.Source{{ID: "app", Weight: 11}}}, scope.ErrInvalidWeight, false},
+		{"duplicate", scope.CreateRequest{Name: "platform", Projects: []scope.Source{{ID: "app"}, {ID: "app"}}}, scope.ErrDuplicateSource, false},
+		{"empty id", scope.CreateRequest{Name: "platform", Projects: []scope.Source{{ID: ""}}}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (err == nil) != tt.wantOK {
+				t.Fatalf("got %v, want ok=%v", err, tt.wantOK)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScope_KnowledgeWeight(t *testing.T) {
+	sc := &scope.Scope{
+		Projects:       []scope.Source{{ID: "app"}, {ID: "sdk", Weight: 3}},
+		KnowledgeBases: []scope.Source{{ID: "listed", Weight: 0.5}},
+	}
+	attachedTo := func(ids ...string) func(string) bool {
+		return func(id string) bool { return slices.Contains(ids, id) }
+	}
+	tests := []struct {
+		kb       string
+		attached func(string) bool
+		want     float64
+		wantOK   bool
+	}{
+		{"listed", attachedTo("sdk"), 0.5, true},
+		{"both", attachedTo("app", "sdk"), 3, true},
+		{"app only", attachedTo("app"), 1, true},
+		{"elsewhere", attachedTo("other"), 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := sc.KnowledgeWeight(tt.kb, tt.attached)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s: got %v, %v, want %v, %v", tt.kb, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	rerank := 0.9
+	app := scope.Weigh([]retrieval.Result{{ID: "a", Score: 0.8}, {ID: "shared", Score: 0.5}}, "app", "", 1)
+	sdk := scope.Weigh([]retrieval.Result{{ID: "s", Score: 0.6}, {ID: "shared", Score: 0.5}}, "sdk", "", 2)
+	kb := scope.Weigh([]retrieval.Result{{ID: "k", Score: 0.1, RerankScore: &rerank}}, "", "kb-1", 0.5)
+
+	got := scope.Merge(slices.Concat(app, sdk, kb), 3)
+	var ids []string
+	for _, r := range got {
+		ids = append(ids, r.ID)
+	}
+	// s: 1.2, shared (from sdk): 1.0, a: 0.8, k: 0.45 (its rerank score).
+	if !slices.Equal(ids, []string{"s", "shared", "a"}) {
+		t.Fatalf("unexpected order %v", ids)
+	}
+	if got[1].ProjectID != "sdk" || got[1].Weight != 2 {
+		t.Fatalf("expected the shared chunk from its best source, got %+v", got[1])
+	}
+	if all := scope.Merge(kb, 0); all[0].RankScore != 0.45 {
+		t.Fatalf("expected the rerank score weighted, got %v", all[0].RankScore)
+	}
+}
@@ -0,0 +1,218 @@
+// Package seatbelt defines seatbelt mode: CodeForge records where a run's
+// changes were delivered on the git host and keeps watching them. When a
+// CI check on the delivered branch fails or the delivered commit is
+// reverted, the run is marked "failed_downstream" and, if the project asks
+// for it, a follow-up task to fix the failure is created.
+package seatbelt
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+)
+
+// ConfigMode is the project config key holding the seatbelt mode.
+const ConfigMode = "seatbelt"
+
+// Mode is how a project reacts to downstream failures of its deliveries.
+type Mode string
+
+const (
+	ModeOff      Mode = ""          // Deliveries are recorded but not watched
+	ModeMark     Mode = "mark"      // Failing runs are marked failed_downstream
+	ModeFollowUp Mode = "follow-up" // As mark, plus a follow-up task for failed checks
+)
+
+// ErrInvalidMode is returned for unknown seatbelt modes.
+var ErrInvalidMode = errors.New("invalid seatbelt mode")
+
+// ModeFromConfig reads the seatbelt mode from a project config.
+func ModeFromConfig(cfg map[string]string) (Mode, error) {
+	m := Mode(strings.TrimSpace(cfg[ConfigMode]))
+	switch m {
+	case ModeOff, ModeMark, ModeFollowUp:
+		return m, nil
+	}
+	return ModeOff, fmt.Errorf("%w %q", ErrInvalidMode, m)
+}
+
+// Status is the downstream state of a delivery.
+type Status string
+
+const (
+	StatusDelivered Status = "delivered" // No failure seen
+	StatusFailed    Status = "failed"    // A CI check of the delivered commit or branch failed
+	StatusReverted  Status = "reverted"  // The delivered commit or pull request was reverted
+)
+
+// Delivery records a run's changes on the git host.
+type Delivery struct {
+	RunID          string          `json:"run_id"`
+	ProjectID      string          `json:"project_id"`
+	TaskID         string          `json:"task_id"`
+	Mode           run.DeliverMode `json:"mode"`
+	Branch         string          `json:"branch"`
+	CommitSHA      string          `json:"commit_sha"`
+	PRURL          string          `json:"pr_url,omitempty"`
+	Status         Status          `json:"status"`
+	Check          string          `json:"check,omitempty"`     // Failed check or pipeline
+	CheckURL       string          `json:"check_url,omitempty"` // Page of the failed check or revert commit
+	Detail         string          `json:"detail,omitempty"`    // Failure summary or revert commit
+	FollowUpTaskID string          `json:"follow_up_task_id,omitempty"`
+	DeliveredAt    time.Time       `json:"delivered_at"`
+	FailedAt       *time.Time      `json:"failed_at,omitempty"`
+}
+
+// Watched reports whether deliveries with mode leave commits on the git
+// host that CI runs on and others can revert.
+func Watched(m run.DeliverMode) bool {
+	return m == run.DeliverModeBranch || m == run.DeliverModePR || m == run.DeliverModePush
+}
+
+// RequestNumber returns the number of the delivery's pull (or merge)
+// request, taken from the last segment of its URL, or 0 without one.
+func (d *Delivery) RequestNumber() int {
+	u, err := url.Parse(d.PRURL)
+	if d.PRURL == "" || err != nil {
+		return 0
+	}
+	n, err := strconv.Atoi(path.Base(strings.TrimSuffix(u.Path, "/")))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// Kinds of signals.
+const (
+	KindCheckFailed = "check_failed" // A check run or pipeline failed
+	KindRevert      = "revert"       // Pushed commits revert earlier ones
+)
+
+// Signal is a verified webhook event about commits on the git host that
+// can concern a delivery.
+type Signal struct {
+	Kind             string   `json:"kind"`
+	Branch           string   `json:"branch"`
+	SHA              string   `json:"sha"`             // Commit checked, or the revert commit
+	Check            string   `json:"check,omitempty"` // Check run or pipeline name
+	URL              string   `json:"url,omitempty"`
+	Log              string   `json:"log,omitempty"`               // Output of the failed check
+	RevertedCommits  []string `json:"reverted_commits,omitempty"`  // Commits a revert backs out
+	RevertedRequests []int    `json:"reverted_requests,omitempty"` // Pull or merge requests a revert backs out
+}
+
+// Matches reports whether s is a failure of d. A failed check matches the
+// delivered commit, or for branch and pr deliveries any later commit of
+// the branch CodeForge created; push deliveries go to branches others
+// push to as well, so only their own commit counts. A revert matches a
+// delivery that is not reverted yet by commit or request number.
+func (s *Signal) Matches(d *Delivery) bool {
+	switch s.Kind {
+	case KindCheckFailed:
+		if d.Status != StatusDelivered {
+			return false
+		}
+		if s.SHA != "" && s.SHA == d.CommitSHA {
+			return true
+		}
+		return d.Mode != run.DeliverModePush && s.Branch != "" && s.Branch == d.Branch
+	case KindRevert:
+		if d.Status == StatusReverted {
+			return false
+		}
+		if n := d.RequestNumber(); n > 0 && slices.Contains(s.RevertedRequests, n) {
+			return true
+		}
+		return slices.ContainsFunc(s.RevertedCommits, func(c string) bool {
+			return sameCommit(c, d.CommitSHA)
+		})
+	}
+	return false
+}
+
+// sameCommit reports whether two hashes, either possibly abbreviated to
+// at least 7 characters, name the same commit.
+func sameCommit(a, b string) bool {
+	if len(a) < 7 || len(b) < 7 {
+		return false
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	return strings.HasPrefix(strings.ToLower(b), strings.ToLower(a))
+}
+
+var (
+	// git revert: "This reverts commit <sha>."
+	revertCommitRE = regexp.MustCompile(`(?i)This reverts commit ([0-9a-f]{7,40})`)
+	// GitLab's revert button: "This reverts merge request !12"
+	revertMergeRequestRE = regexp.MustCompile(`(?i)This reverts merge request !(\d+)`)
+	// GitHub's revert button: "Reverts owner/repo#12"
+	revertPullRequestRE = regexp.MustCompile(`(?i)Reverts [\w.-]+/[\w.-]+#(\d+)`)
+	// Reverted GitHub merge commit: `Revert "Merge pull request #12 from ...`
+	revertMergeCommitRE = regexp.MustCompile(`(?i)^Revert "Merge pull request #(\d+)`)
+)
+
+// ParseRevert returns the commits and pull or merge requests a commit
+// message reverts.
+func ParseRevert(message string) (commits []string, requests []int) {
+	for _, m := range revertCommitRE.FindAllStringSubmatch(message, -1) {
+		commits = append(commits, strings.ToLower(m[1]))
+	}
+	for _, re := range []*regexp.Regexp{revertMergeRequestRE, revertPullRequestRE, revertMergeCommitRE} {
+		for _, m := range re.FindAllStringSubmatch(message, -1) {
+			if n, err := strconv.Atoi(m[1]); err == nil && !slices.Contains(requests, n) {
+				requests = append(requests, n)
+			}
+		}
+	}
+	return commits, requests
+}
+
+// Failure describes why a delivery failed, for its run's error.
+func (d *Delivery) Failure() string {
+	switch d.Status {
+	case StatusFailed:
+		return fmt.Sprintf("check %q failed on %s", d.Check, d.Branch)
+	case StatusReverted:
+		return "delivered changes were reverted: " + d.Detail
+	}
+	return ""
+}
+
+// FollowUpPrompt returns the prompt of the task that fixes a failed check
+// of a delivery of the task titled title. The check's output is attached
+// to the task as logName.
+func FollowUpPrompt(d *Delivery, title, logName string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "The changes delivered for %q to branch `%s`", title, d.Branch)
+	if d.CommitSHA != "" {
+		fmt.Fprintf(&b, " (commit %s)", shortSHA(d.CommitSHA))
+	}
+	fmt.Fprintf(&b, " fail the CI check %q.", d.Check)
+	if d.CheckURL != "" {
+		fmt.Fprintf(&b, " See %s.", d.CheckURL)
+	}
+	b.WriteString("\n\nFind the cause of the failure and fix it on the same branch.")
+	if logName != "" {
+		fmt.Fprintf(&b, " The check's output is attached as %s.", logName)
+	}
+	return b.String()
+}
+
+// shortSHA abbreviates a commit hash.
+func shortSHA(h string) string {
+	if len(h) > 12 {
+		return h[:12]
+	}
+	return h
+}
@@ -0,0 +1,86 @@
+package seatbelt
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+)
+
+func TestParseRevert(t *testing.T) {
+	tests := []struct {
+		message  string
+		commits  []string
+		requests []int
+	}{
+		{"Revert \"Add login\"\n\nThis reverts commit 0123456789ABCDEF0123456789abcdef01234567.", []string{"0123456789abcdef0123456789abcdef01234567"}, nil},
+		{"Revert \"Add login\"\n\nThis reverts merge request !12", nil, []int{12}},
+		{"Revert \"Add login\" (#13)\n\nReverts acme/app#12", nil, []int{12}},
+		{"Revert \"Merge pull request #12 from acme/codeforge/run-1\"\n\nThis reverts commit abcdef1, reversing\nchanges made to 1234567.", []string{"abcdef1"}, []int{12}},
+		{"Fix login (#12)", nil, nil},
+	}
+	for _, tt := range tests {
+		commits, requests := ParseRevert(tt.message)
+		if !reflect.DeepEqual(commits, tt.commits) || !reflect.DeepEqual(requests, tt.requests) {
+			t.Errorf("ParseRevert(%q) = %v, %v; want %v, %v", tt.message, commits, requests, tt.commits, tt.requests)
+		}
+	}
+}
+
+func TestSignalMatches(t *testing.T) {
+	pr := &Delivery{Mode: run.DeliverModePR, Branch: "codeforge/run-1", CommitSHA: "0123456789abcdef", PRURL: "https://github.com/acme/app/pull/12", Status: StatusDelivered}
+	push := &Delivery{Mode: run.DeliverModePush, Branch: "feature", CommitSHA: "fedcba9876543210", Status: StatusDelivered}
+	tests := []struct {
+		name string
+		s    Signal
+		d    *Delivery
+		want bool
+	}{
+		{"check on the delivered commit", Signal{Kind: KindCheckFailed, SHA: "fedcba9876543210", Branch: "feature"}, push, true},
+		{"check on a later commit of a created branch", Signal{Kind: KindCheckFailed, SHA: "1111111", Branch: "codeforge/run-1"}, pr, true},
+		{"check on a later commit of a shared branch", Signal{Kind: KindCheckFailed, SHA: "1111111", Branch: "feature"}, push, false},
+		{"check on another branch", Signal{Kind: KindCheckFailed, SHA: "1111111", Branch: "main"}, pr, false},
+		{"revert of the commit", Signal{Kind: KindRevert, RevertedCommits: []string{"0123456"}}, pr, true},
+		{"revert of the pull request", Signal{Kind: KindRevert, RevertedRequests: []int{12}}, pr, true},
+		{"revert of another pull request", Signal{Kind: KindRevert, RevertedRequests: []int{13}, RevertedCommits: []string{"012345"}}, pr, false},
+	}
+	for _, tt := range tests {
+		if got := tt.s.Matches(tt.d); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	failed := *pr
+	failed.Status = StatusFailed
+	if (&Signal{Kind: KindCheckFailed, SHA: pr.CommitSHA}).Matches(&failed) {
+		t.Error("a failed delivery must not fail again")
+	}
+	if !(&Signal{Kind: KindRevert, RevertedRequests: []int{12}}).Matches(&failed) {
+		t.Error("a failed delivery can still be reverted")
+	}
+}
+
+func TestRequestNumber(t *testing.T) {
+	for u, want := range map[string]int{
+		"https://github.com/acme/app/pull/12":             12,
+		"https://gitlab.com/acme/app/-/merge_requests/7/": 7,
+		"https://github.com/acme/app/compare/main...x":    0,
+		"https://git.example/acme/app":                    0,
+	} {
+		if got := (&Delivery{PRURL: u}).RequestNumber(); got != want {
+			t.Errorf("RequestNumber(%q) = %d, want %d", u, got, want)
+		}
+	}
+}
+
+func TestModeFromConfig(t *testing.T) {
+	if m, err := ModeFromConfig(map[string]string{ConfigMode: " follow-up "}); err != nil || m != ModeFollowUp {
+		t.Errorf("got %q, %v; want follow-up", m, err)
+	}
+	if m, err := ModeFromConfig(nil); err != nil || m != ModeOff {
+		t.Errorf("got %q, %v; want off", m, err)
+	}
+	if _, err := ModeFromConfig(map[string]string{ConfigMode: "rollback"}); err == nil {
+		t.Error("expected an unknown mode to fail")
+	}
+}
@@ -0,0 +1,166 @@
+// Package secret defines project- and tenant-scoped secrets that are
+// injected into agent runs as environment variables.
+package secret
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConfigKey is the agent config key listing the secrets (comma-separated
+// names) injected into the agent's runs.
+const ConfigKey = "secrets"
+
+// MaxValueBytes limits the size of a single secret value.
+const MaxValueBytes = 64 << 10
+
+var namePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,127}$`)
+
+var (
+	ErrInvalidName  = errors.New("secret name must be an upper-case env var name (A-Z, 0-9, _)")
+	ErrEmptyValue   = errors.New("secret value is required")
+	ErrValueTooLong = errors.New("secret value exceeds 64 KiB")
+)
+
+// Secret is an encrypted secret. The plaintext value never leaves the
+// service layer; the API only exposes metadata.
+type Secret struct {
+	ID         string    `json:"id"`
+	TenantID   string    `json:"tenant_id"`            // Owner of the secret and of its encryption key
+	ProjectID  string    `json:"project_id,omitempty"` // Empty for tenant-wide secrets
+	Name       string    `json:"name"`
+	Ciphertext []byte    `json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// CreateRequest holds the fields for creating a secret.
+type CreateRequest struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Validate checks the secret name and value.
+func (r *CreateRequest) Validate() error {
+	if !namePattern.MatchString(r.Name) {
+		return ErrInvalidName
+	}
+	return validateValue(r.Value)
+}
+
+// UpdateRequest rotates the value of a secret.
+type UpdateRequest struct {
+	Value string `json:"value"`
+}
+
+// Validate checks the new value.
+func (r *UpdateRequest) Validate() error {
+	return validateValue(r.Value)
+}
+
+func validateValue(v string) error {
+	if v == "" {
+		return ErrEmptyValue
+	}
+	if len(v) > MaxValueBytes {
+		return ErrValueTooLong
+	}
+	return nil
+}
+
+// ParseNames splits an agent's ConfigKey value into secret names.
+func ParseNames(list string) []string {
+	var names []string
+	for _, n := range strings.Split(list, ",") {
+		if n = strings.TrimSpace(n); n != "" && !slices.Contains(names, n) {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// envDumpCommands print the whole environment when called without arguments.
+var envDumpCommands = []string{"env", "printenv", "set", "export", "declare -x"}
+
+// Guard detects tool calls that would reveal injected secrets.
+type Guard struct {
+	names []string
+}
+
+// NewGuard creates a guard for the given secret names.
+func NewGuard(names []string) *Guard {
+	return &Guard{names: names}
+}
+
+// Check returns a denial reason if the command reads a secret variable or
+// dumps the environment. An empty string means the command is allowed.
+func (g *Guard) Check(command string) string {
+	if g == nil || len(g.names) == 0 {
+		return ""
+	}
+	for _, n := range g.names {
+		if strings.Contains(command, "$"+n) || strings.Contains(command, "${"+n) ||
+			strings.Contains(command, "%"+n+"%") || strings.Contains(command, "environ['"+n) ||
+			strings.Contains(command, "getenv(\""+n) || strings.Contains(command, "printenv "+n) {
+			return "command references secret " + n
+		}
+	}
+	trimmed := strings.TrimSpace(command)
+	for _, c := range envDumpCommands {
+		if trimmed == c || strings.HasPrefix(trimmed, c+" |") || strings.HasPrefix(trimmed, c+">") || strings.HasPrefix(trimmed, c+" >") {
+			return "command dumps the environment of a run with secrets"
+		}
+	}
+	return ""
+}
+
+// Redactor masks secret values in text.
+type Redactor struct {
+	values []string
+	masks  map[string]string
+}
+
+// NewRedactor creates a redactor for the given name→value map. Longer
+// values are replaced first so overlapping secrets are fully masked.
+func NewRedactor(secrets map[string]string) *Redactor {
+	r := &Redactor{masks: make(map[string]string, len(secrets))}
+	for name, v := range secrets {
+		if v == "" {
+			continue
+		}
+		r.values = append(r.values, v)
+		r.masks[v] = "[secret:" + name + "]"
+	}
+	sort.Slice(r.values, func(i, j int) bool { return len(r.values[i]) > len(r.values[j]) })
+	return r
+}
+
+// Redact replaces every secret value in s with a mask naming the secret.
+func (r *Redactor) Redact(s string) string {
+	s, _ = r.RedactCount(s)
+	return s
+}
+
+// RedactCount is Redact that also returns the number of masked occurrences.
+func (r *Redactor) RedactCount(s string) (string, int) {
+	if r == nil {
+		return s, 0
+	}
+	n := 0
+	for _, v := range r.values {
+		if c := strings.Count(s, v); c > 0 {
+			s = strings.ReplaceAll(s, v, r.masks[v])
+			n += c
+		}
+	}
+	return s, n
+}
+
+// Empty reports whether the redactor has nothing to mask.
+func (r *Redactor) Empty() bool {
+	return r == nil || len(r.values) == 0
+}
@@ -0,0 +1,278 @@
+package skill
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BundleFormat is the version of the bundle layout written by Pack.
+const BundleFormat = 1
+
+// A bundle is a gzipped tarball of two files: the manifest and the
+// base64 Ed25519 signature of the manifest's exact bytes.
+const (
+	manifestFile  = "skill.json"
+	signatureFile = "skill.sig"
+)
+
+var (
+	// ErrInvalidBundle is returned for archives that are not skill bundles.
+	ErrInvalidBundle = errors.New("invalid skill bundle")
+	// ErrUntrustedSigner is returned for bundles signed by an unknown key.
+	ErrUntrustedSigner = errors.New("bundle signer is not trusted")
+	// ErrBadSignature is returned for bundles whose signature does not match.
+	ErrBadSignature = errors.New("bundle signature does not match")
+)
+
+// Manifest is the content of a bundle: everything needed to recreate the
+// skill in another project.
+type Manifest struct {
+	Format      int       `json:"format"`
+	Name        string    `json:"name"`
+	Version     string    `json:"version"`
+	Description string    `json:"description,omitempty"`
+	Prompt      string    `json:"prompt"`
+	Tools       []string  `json:"tools,omitempty"`
+	Fixtures    []Fixture `json:"fixtures,omitempty"`
+	Signer      string    `json:"signer"` // Key ID of the signing key
+	ExportedAt  time.Time `json:"exported_at"`
+}
+
+// ManifestOf returns the manifest of a skill exported at now.
+func ManifestOf(s *Skill, now time.Time) *Manifest {
+	return &Manifest{
+		Format:      BundleFormat,
+		Name:        s.Name,
+		Version:     s.Version,
+		Description: s.Description,
+		Prompt:      s.Prompt,
+		Tools:       s.Tools,
+		Fixtures:    s.Fixtures,
+		ExportedAt:  now.UTC(),
+	}
+}
+
+// Request returns the create request of the manifest's skill.
+func (m *Manifest) Request() *CreateRequest {
+	return &CreateRequest{
+		Name:        m.Name,
+		Version:     m.Version,
+		Description: m.Description,
+		Prompt:      m.Prompt,
+		Tools:       m.Tools,
+		Fixtures:    m.Fixtures,
+	}
+}
+
+// Pack signs the manifest with key as keyID and returns the bundle.
+func Pack(m *Manifest, keyID string, key ed25519.PrivateKey) ([]byte, error) {
+	m.Signer = keyID
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal manifest: %w", err)
+	}
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(key, data))
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{{manifestFile, data}, {signatureFile, []byte(sig + "\n")}} {
+		hdr := &tar.Header{Name: f.name, Mode: 0o644, Size: int64(len(f.data)), ModTime: m.ExportedAt}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unpack reads a bundle, verifies its signature against the trusted keys
+// by key ID and returns its manifest.
+func Unpack(data []byte, trusted map[string]ed25519.PublicKey) (*Manifest, error) {
+	files, err := readBundle(data)
+	if err != nil {
+		return nil, err
+	}
+	raw, sig := files[manifestFile], files[signatureFile]
+	if raw == nil || sig == nil {
+		return nil, fmt.Errorf("%w: missing %s or %s", ErrInvalidBundle, manifestFile, signatureFile)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	key, ok := trusted[m.Signer]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUntrustedSigner, m.Signer)
+	}
+	sigBytes, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil || !ed25519.Verify(key, raw, sigBytes) {
+		return nil, ErrBadSignature
+	}
+
+	if m.Format != BundleFormat {
+		return nil, fmt.Errorf("%w: unsupported format %d", ErrInvalidBundle, m.Format)
+	}
+	if err := m.Request().Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	return &m, nil
+}
+
+// readBundle returns the files of a bundle by name. Bundles hold only the
+// manifest and signature, so anything else is rejected.
+func readBundle(data []byte) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	files := make(map[string][]byte)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+		name := strings.TrimPrefix(hdr.Name, "./")
+		if hdr.Typeflag != tar.TypeReg || (name != manifestFile && name != signatureFile) || files[name] != nil {
+			return nil, fmt.Errorf("%w: unexpected entry %q", ErrInvalidBundle, hdr.Name)
+		}
+		// The archive is already size-limited by the caller, so reading
+		// a whole entry is bounded by it.
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+		files[name] = b
+	}
+}
+
+// ParsePrivateKey decodes a base64 Ed25519 seed (32 bytes) or private key
+// (64 bytes).
+func ParsePrivateKey(s string) (ed25519.PrivateKey, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("decode signing key: %w", err)
+	}
+	switch len(b) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(b), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(b), nil
+	default:
+		return nil, fmt.Errorf("signing key must be %d or %d bytes, got %d", ed25519.SeedSize, ed25519.PrivateKeySize, len(b))
+	}
+}
+
+// ParsePublicKey decodes a base64 Ed25519 public key.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("decode public key: %w", err)
+	}
+	if len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be %d bytes, got %d", ed25519.PublicKeySize, len(b))
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// Index is the JSON document a skill registry serves.
+type Index struct {
+	Skills []IndexEntry `json:"skills"`
+}
+
+// IndexEntry is a bundle listed in a registry.
+type IndexEntry struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url"`              // Bundle location, absolute or relative to the index
+	SHA256      string `json:"sha256,omitempty"` // Hex digest of the bundle, checked before import
+	Signer      string `json:"signer,omitempty"`
+}
+
+// InstallRequest picks a registry bundle to import into a project.
+type InstallRequest struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"` // Default: the highest listed version
+}
+
+// Find returns the entry of name at version, or its highest version if
+// version is empty.
+func (ix *Index) Find(name, version string) (*IndexEntry, bool) {
+	var best *IndexEntry
+	for i := range ix.Skills {
+		e := &ix.Skills[i]
+		if e.Name != name {
+			continue
+		}
+		if version != "" {
+			if e.Version == version {
+				return e, true
+			}
+			continue
+		}
+		if best == nil || CompareVersions(e.Version, best.Version) > 0 {
+			best = e
+		}
+	}
+	return best, best != nil
+}
+
+// CompareVersions compares dotted versions part by part, numerically
+// where both parts are numbers. It returns -1, 0 or 1.
+func CompareVersions(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y string
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		nx, errX := strconv.Atoi(x)
+		ny, errY := strconv.Atoi(y)
+		switch {
+		case errX == nil && errY == nil && nx != ny:
+			if nx < ny {
+				return -1
+			}
+			return 1
+		case (errX != nil || errY != nil) && x != y:
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
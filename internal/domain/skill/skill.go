@@ -0,0 +1,94 @@
+// Package skill defines project skills — reusable prompts with the tools
+// they need and fixtures to test them — and the signed bundles that move
+// skills between projects, tenants and installs.
+package skill
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultVersion is the version of skills created without one.
+const DefaultVersion = "1.0.0"
+
+var (
+	// ErrInvalidName is returned for a missing or malformed skill name.
+	ErrInvalidName = errors.New("name must be 1-64 lowercase letters, digits, '.', '_' or '-'")
+	// ErrPromptRequired is returned for a skill without a prompt.
+	ErrPromptRequired = errors.New("prompt is required")
+	// ErrInvalidFixture is returned for fixtures without a unique name or an input.
+	ErrInvalidFixture = errors.New("fixtures need a unique name and an input")
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// Skill is a reusable agent prompt of a project.
+type Skill struct {
+	ID          string    `json:"id"`
+	ProjectID   string    `json:"project_id"`
+	Name        string    `json:"name"` // Unique per project
+	Version     string    `json:"version"`
+	Description string    `json:"description,omitempty"`
+	Prompt      string    `json:"prompt"`
+	Tools       []string  `json:"tools,omitempty"` // Tools the prompt relies on
+	Fixtures    []Fixture `json:"fixtures,omitempty"`
+	Signer      string    `json:"signer,omitempty"` // Key ID of the bundle it was imported from; empty if created here
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Fixture is a test case of a skill: an input and text the output of an
+// agent using the skill is expected to contain.
+type Fixture struct {
+	Name   string `json:"name"`
+	Input  string `json:"input"`
+	Expect string `json:"expect,omitempty"`
+}
+
+// CreateRequest holds the fields for creating a skill.
+type CreateRequest struct {
+	Name        string    `json:"name"`
+	Version     string    `json:"version,omitempty"` // Default: DefaultVersion
+	Description string    `json:"description,omitempty"`
+	Prompt      string    `json:"prompt"`
+	Tools       []string  `json:"tools,omitempty"`
+	Fixtures    []Fixture `json:"fixtures,omitempty"`
+}
+
+// Validate checks the name, prompt and fixtures.
+func (r *CreateRequest) Validate() error {
+	if !namePattern.MatchString(r.Name) {
+		return ErrInvalidName
+	}
+	if strings.TrimSpace(r.Prompt) == "" {
+		return ErrPromptRequired
+	}
+	seen := make(map[string]bool, len(r.Fixtures))
+	for i := range r.Fixtures {
+		f := &r.Fixtures[i]
+		if f.Name == "" || seen[f.Name] || strings.TrimSpace(f.Input) == "" {
+			return fmt.Errorf("fixture %d: %w", i, ErrInvalidFixture)
+		}
+		seen[f.Name] = true
+	}
+	return nil
+}
+
+// New builds the skill of a validated request.
+func New(projectID string, r *CreateRequest) *Skill {
+	version := r.Version
+	if version == "" {
+		version = DefaultVersion
+	}
+	return &Skill{
+		ProjectID:   projectID,
+		Name:        r.Name,
+		Version:     version,
+		Description: r.Description,
+		Prompt:      r.Prompt,
+		Tools:       r.Tools,
+		Fixtures:    r.Fixtures,
+	}
+}
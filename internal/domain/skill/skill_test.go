@@ -0,0 +1,75 @@
+package skill_test
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/skill"
+)
+
+func TestCreateRequestValidate(t *testing.T) {
+	for _, tt := range []struct {
+		req  skill.CreateRequest
+		want error
+	}{
+		{skill.CreateRequest{Name: "go-tests", Prompt: "write tests"}, nil},
+		{skill.CreateRequest{Name: "Go Tests", Prompt: "x"}, skill.ErrInvalidName},
+		{skill.CreateRequest{Name: "go-tests", Prompt: " "}, skill.ErrPromptRequired},
+		{skill.CreateRequest{Name: "go-tests", Prompt: "x", Fixtures: []skill.Fixture{{Name: "a", Input: "1"}, {Name: "a", Input: "2"}}}, skill.ErrInvalidFixture},
+	} {
+		if err := tt.req.Validate(); !errors.Is(err, tt.want) {
+			t.Errorf("Validate(%+v) = %v, want %v", tt.req, err, tt.want)
+		}
+	}
+}
+
+func TestPackUnpack(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(nil)
+	other, _, _ := ed25519.GenerateKey(nil)
+	s := skill.New("p1", &skill.CreateRequest{
+		Name: "go-tests", Prompt: "write table-driven tests", Tools: []string{"Read", "Edit"},
+		Fixtures: []skill.Fixture{{Name: "add", Input: "func Add", Expect: "TestAdd"}},
+	})
+
+	data, err := skill.Pack(skill.ManifestOf(s, time.Now()), "team", key)
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+
+	m, err := skill.Unpack(data, map[string]ed25519.PublicKey{"team": pub})
+	if err != nil {
+		t.Fatalf("Unpack failed: %v", err)
+	}
+	if m.Name != "go-tests" || m.Version != skill.DefaultVersion || m.Signer != "team" || len(m.Fixtures) != 1 || len(m.Tools) != 2 {
+		t.Fatalf("unexpected manifest %+v", m)
+	}
+
+	if _, err := skill.Unpack(data, map[string]ed25519.PublicKey{"other": pub}); !errors.Is(err, skill.ErrUntrustedSigner) {
+		t.Fatalf("expected ErrUntrustedSigner, got %v", err)
+	}
+	if _, err := skill.Unpack(data, map[string]ed25519.PublicKey{"team": other}); !errors.Is(err, skill.ErrBadSignature) {
+		t.Fatalf("expected ErrBadSignature, got %v", err)
+	}
+	if _, err := skill.Unpack([]byte("not a bundle"), nil); !errors.Is(err, skill.ErrInvalidBundle) {
+		t.Fatalf("expected ErrInvalidBundle, got %v", err)
+	}
+}
+
+func TestIndexFind(t *testing.T) {
+	ix := skill.Index{Skills: []skill.IndexEntry{
+		{Name: "go-tests", Version: "1.10.0"},
+		{Name: "go-tests", Version: "1.9.2"},
+		{Name: "lint", Version: "0.1.0"},
+	}}
+	if e, ok := ix.Find("go-tests", ""); !ok || e.Version != "1.10.0" {
+		t.Fatalf("expected highest version 1.10.0, got %+v", e)
+	}
+	if e, ok := ix.Find("go-tests", "1.9.2"); !ok || e.Version != "1.9.2" {
+		t.Fatalf("expected 1.9.2, got %+v", e)
+	}
+	if _, ok := ix.Find("missing", ""); ok {
+		t.Fatal("expected no entry for an unknown skill")
+	}
+}
@@ -0,0 +1,133 @@
+// Package snapshot defines workspace snapshots: full tarballs of a project
+// workspace stored as run artifacts, including untracked and generated files
+// that git-based history does not capture.
+package snapshot
+
+import (
+	"errors"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+)
+
+// Artifact metadata keys used by snapshots.
+const (
+	MetaFiles  = "files"   // Number of regular files in the archive
+	MetaIgnore = "ignore"  // Comma-separated ignore patterns applied at capture
+	MetaCallID = "call_id" // Tool call after which a checkpoint was taken
+)
+
+// ContentType is the content type of snapshot artifacts.
+const ContentType = "application/gzip"
+
+// Mode controls automatic snapshots.
+type Mode string
+
+const (
+	ModeOff        Mode = ""             // Snapshots are taken on request only
+	ModeOnComplete Mode = "on_complete"  // Snapshot the workspace when a run finishes
+	ModeOnToolCall Mode = "on_tool_call" // Also checkpoint it after every tool call that can change files
+)
+
+// readOnlyTools are the tools whose calls change no files, so they get no
+// checkpoint.
+var readOnlyTools = map[string]bool{"LLM": true, "Read": true, "Glob": true, "Grep": true}
+
+// Checkpoints reports whether the mode takes a checkpoint after a
+// successful call of tool.
+func (m Mode) Checkpoints(tool string) bool {
+	return m == ModeOnToolCall && !readOnlyTools[tool]
+}
+
+// OnComplete reports whether the mode snapshots finished runs.
+func (m Mode) OnComplete() bool {
+	return m == ModeOnComplete || m == ModeOnToolCall
+}
+
+// CreateRequest is the input for taking a snapshot.
+type CreateRequest struct {
+	Name   string   `json:"name,omitempty"`
+	Ignore []string `json:"ignore,omitempty"` // Added to the configured ignore patterns
+}
+
+// RestoreRequest selects the snapshot to restore into a run's workspace.
+type RestoreRequest struct {
+	SnapshotID string `json:"snapshot_id"`
+}
+
+// Validate checks that a RestoreRequest is well-formed.
+func (r *RestoreRequest) Validate() error {
+	if r.SnapshotID == "" {
+		return errors.New("snapshot_id is required")
+	}
+	return nil
+}
+
+// Snapshot describes a stored workspace snapshot.
+type Snapshot struct {
+	ID        string    `json:"id"`
+	RunID     string    `json:"run_id"`
+	ProjectID string    `json:"project_id"`
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	Files     int       `json:"files"`
+	Ignore    []string  `json:"ignore,omitempty"`
+	CallID    string    `json:"call_id,omitempty"` // Set for checkpoints taken after a tool call
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// File is a regular file of a snapshot.
+type File struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// FromArtifact converts a workspace snapshot artifact into a Snapshot.
+func FromArtifact(a *artifact.Artifact) Snapshot {
+	s := Snapshot{
+		ID:        a.ID,
+		RunID:     a.RunID,
+		ProjectID: a.ProjectID,
+		Name:      a.Name,
+		Size:      a.Size,
+		CreatedAt: a.CreatedAt,
+	}
+	s.Files, _ = strconv.Atoi(a.Metadata[MetaFiles])
+	s.CallID = a.Metadata[MetaCallID]
+	if v := a.Metadata[MetaIgnore]; v != "" {
+		s.Ignore = strings.Split(v, ",")
+	}
+	return s
+}
+
+// Ignored reports whether a slash-separated path relative to the workspace
+// root matches any ignore pattern. Patterns without a slash match any path
+// element (e.g. "node_modules", "*.log"); patterns with a slash match the
+// path from the root and everything below it (e.g. "build/cache").
+func Ignored(patterns []string, rel string) bool {
+	parts := strings.Split(rel, "/")
+	for _, p := range patterns {
+		p = strings.TrimSuffix(p, "/")
+		if p == "" {
+			continue
+		}
+		if !strings.Contains(p, "/") {
+			for _, part := range parts {
+				if ok, _ := path.Match(p, part); ok {
+					return true
+				}
+			}
+			continue
+		}
+		p = strings.TrimPrefix(p, "/")
+		for i := range parts {
+			if ok, _ := path.Match(p, strings.Join(parts[:i+1], "/")); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+)
+
+func TestIgnored(t *testing.T) {
+	patterns := []string{"node_modules", "*.log", "build/cache/"}
+	tests := []struct {
+		rel  string
+		want bool
+	}{
+		{"main.go", false},
+		{"node_modules", true},
+		{"web/node_modules/react/index.js", true},
+		{"logs/app.log", true},
+		{"build/cache", true},
+		{"build/cache/obj/a.o", true},
+		{"build/out/a.o", false},
+		{"src/build/cache/a", false},
+	}
+	for _, tt := range tests {
+		if got := Ignored(patterns, tt.rel); got != tt.want {
+			t.Errorf("Ignored(%q) = %v, want %v", tt.rel, got, tt.want)
+		}
+	}
+}
+
+func TestFromArtifact(t *testing.T) {
+	s := FromArtifact(&artifact.Artifact{
+		ID:       "a1",
+		RunID:    "r1",
+		Name:     "before-refactor",
+		Size:     42,
+		Metadata: map[string]string{MetaFiles: "7", MetaIgnore: "node_modules,*.log"},
+	})
+	if s.Files != 7 || len(s.Ignore) != 2 || s.Name != "before-refactor" {
+		t.Fatalf("unexpected snapshot %+v", s)
+	}
+}
+
+func TestRestoreRequestValidate(t *testing.T) {
+	if err := (&RestoreRequest{}).Validate(); err == nil {
+		t.Fatal("expected error for missing snapshot_id")
+	}
+	if err := (&RestoreRequest{SnapshotID: "a1"}).Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestModeCheckpoints(t *testing.T) {
+	if !ModeOnToolCall.Checkpoints("Edit") || !ModeOnToolCall.Checkpoints("Bash") {
+		t.Error("expected checkpoints after edits and commands")
+	}
+	if ModeOnToolCall.Checkpoints("Read") || ModeOnToolCall.Checkpoints("LLM") || ModeOnComplete.Checkpoints("Edit") {
+		t.Error("expected no checkpoint after read-only calls or without on_tool_call")
+	}
+	if !ModeOnToolCall.OnComplete() || !ModeOnComplete.OnComplete() || ModeOff.OnComplete() {
+		t.Error("unexpected OnComplete")
+	}
+	if s := FromArtifact(&artifact.Artifact{Metadata: map[string]string{MetaCallID: "call-3"}}); s.CallID != "call-3" {
+		t.Errorf("expected the call ID of a checkpoint, got %+v", s)
+	}
+}
@@ -17,17 +17,18 @@ const (
 
 // Task represents a unit of work assigned to an agent.
 type Task struct {
-	ID        string    `json:"id"`
-	ProjectID string    `json:"project_id"`
-	AgentID   string    `json:"agent_id,omitempty"`
-	Title     string    `json:"title"`
-	Prompt    string    `json:"prompt"`
-	Status    Status    `json:"status"`
-	Result    *Result   `json:"result,omitempty"`
-	CostUSD   float64   `json:"cost_usd"`
-	Version   int       `json:"version"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           string    `json:"id"`
+	ProjectID    string    `json:"project_id"`
+	AgentID      string    `json:"agent_id,omitempty"`
+	SubProjectID string    `json:"sub_project_id,omitempty"`
+	Title        string    `json:"title"`
+	Prompt       string    `json:"prompt"`
+	Status       Status    `json:"status"`
+	Result       *Result   `json:"result,omitempty"`
+	CostUSD      float64   `json:"cost_usd"`
+	Version      int       `json:"version"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 // Result holds the output of a completed task.
@@ -41,7 +42,8 @@ type Result struct {
 
 // CreateRequest holds the fields needed to create a new task.
 type CreateRequest struct {
-	ProjectID string `json:"project_id"`
-	Title     string `json:"title"`
-	Prompt    string `json:"prompt"`
+	ProjectID    string `json:"project_id"`
+	SubProjectID string `json:"sub_project_id,omitempty"` // Optional sub-project the task is scoped to
+	Title        string `json:"title"`
+	Prompt       string `json:"prompt"`
 }
@@ -0,0 +1,23 @@
+package tenant
+
+import "context"
+
+type ctxKey struct{}
+
+// NewContext returns a context scoped to the tenant id. Database sessions
+// opened with it only see the tenant's rows (see IsolationReport).
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the tenant a context is scoped to, or "" if it is not
+// scoped and sees all tenants.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// ValidID reports whether id is a well-formed tenant ID.
+func ValidID(id string) bool {
+	return idPattern.MatchString(id)
+}
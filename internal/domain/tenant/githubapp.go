@@ -0,0 +1,30 @@
+package tenant
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidGitHubApp is returned for malformed GitHub App settings.
+var ErrInvalidGitHubApp = errors.New("invalid github app")
+
+// GitHubApp is the GitHub App the projects of a tenant may authenticate as
+// instead of personal access tokens. Its private key and webhook secret
+// are tenant-wide secrets, referenced by name.
+type GitHubApp struct {
+	AppID            int64  `json:"app_id"`
+	PrivateKeySecret string `json:"private_key_secret"`       // Secret holding the app's PEM private key
+	WebhookSecret    string `json:"webhook_secret,omitempty"` // Secret the app's webhooks are signed with; empty ignores installation webhooks
+	APIURL           string `json:"api_url,omitempty"`        // GitHub Enterprise API root; empty for github.com
+}
+
+// Validate checks that the app and its private key are named.
+func (a *GitHubApp) Validate() error {
+	if a.AppID <= 0 {
+		return fmt.Errorf("%w: app_id must be positive", ErrInvalidGitHubApp)
+	}
+	if a.PrivateKeySecret == "" {
+		return fmt.Errorf("%w: private_key_secret is required", ErrInvalidGitHubApp)
+	}
+	return nil
+}
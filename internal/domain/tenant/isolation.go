@@ -0,0 +1,50 @@
+package tenant
+
+// TableIsolation is the row-level security state of one table.
+type TableIsolation struct {
+	Table    string `json:"table"`
+	Enabled  bool   `json:"enabled"`  // ENABLE ROW LEVEL SECURITY
+	Forced   bool   `json:"forced"`   // FORCE ROW LEVEL SECURITY (applies to the table owner too)
+	Policies int    `json:"policies"` // Number of policies on the table
+}
+
+// Isolated reports whether the table's rows are filtered by tenant.
+func (t *TableIsolation) Isolated() bool {
+	return t.Enabled && t.Forced && t.Policies > 0
+}
+
+// IsolationReport is the result of verifying tenant isolation: that every
+// tenant-owned table enforces row-level security for the database role in
+// use, and that every secret is encrypted with its tenant's key.
+type IsolationReport struct {
+	Role        string           `json:"role"`
+	BypassesRLS bool             `json:"bypasses_rls"` // The role is a superuser or has BYPASSRLS
+	Tables      []TableIsolation `json:"tables"`
+
+	Secrets           int `json:"secrets"`
+	LegacySecrets     int `json:"legacy_secrets"`     // Still encrypted with the shared key; fixed by a rekey
+	UnreadableSecrets int `json:"unreadable_secrets"` // Open with no known key
+
+	OK       bool     `json:"ok"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// Evaluate sets OK and Problems from the other fields.
+func (r *IsolationReport) Evaluate() {
+	r.Problems = nil
+	if r.BypassesRLS {
+		r.Problems = append(r.Problems, "database role "+r.Role+" bypasses row-level security")
+	}
+	for i := range r.Tables {
+		if !r.Tables[i].Isolated() {
+			r.Problems = append(r.Problems, "table "+r.Tables[i].Table+" is not isolated by row-level security")
+		}
+	}
+	if r.LegacySecrets > 0 {
+		r.Problems = append(r.Problems, "secrets are encrypted with the shared key; run a rekey")
+	}
+	if r.UnreadableSecrets > 0 {
+		r.Problems = append(r.Problems, "secrets cannot be decrypted with the configured master key")
+	}
+	r.OK = len(r.Problems) == 0
+}
@@ -0,0 +1,150 @@
+// Package tenant defines tenants, the owners of projects, and the resource
+// quotas admission control enforces for them.
+package tenant
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+)
+
+// DefaultID is the tenant that owns projects created without a tenant.
+const DefaultID = "default"
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+var (
+	ErrInvalidID     = errors.New("tenant id must be lower-case letters, digits and dashes")
+	ErrNameRequired  = errors.New("tenant name is required")
+	ErrNegativeQuota = errors.New("quota limits must not be negative")
+)
+
+// Resource identifies a quota-limited resource.
+type Resource string
+
+const (
+	ResourceConcurrentRuns Resource = "concurrent_runs"
+	ResourceProjects       Resource = "projects"
+	ResourceMonthlyTokens  Resource = "monthly_tokens"
+	ResourceStorage        Resource = "storage_mb"
+)
+
+// Quota holds the resource limits of a tenant. Zero leaves a resource
+// unlimited.
+type Quota struct {
+	MaxConcurrentRuns  int   `json:"max_concurrent_runs"`
+	MaxProjects        int   `json:"max_projects"`
+	MonthlyTokenBudget int64 `json:"monthly_token_budget"` // LLM tokens (in + out) per calendar month (UTC)
+	MaxStorageMB       int64 `json:"max_storage_mb"`       // Size of the tenant's project workspaces
+}
+
+// Validate checks that no limit is negative.
+func (q *Quota) Validate() error {
+	if q.MaxConcurrentRuns < 0 || q.MaxProjects < 0 || q.MonthlyTokenBudget < 0 || q.MaxStorageMB < 0 {
+		return ErrNegativeQuota
+	}
+	return nil
+}
+
+// Tenant owns projects and shares one quota across them.
+type Tenant struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Quota     Quota      `json:"quota"`
+	GitHubApp *GitHubApp `json:"github_app,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// CreateRequest holds the fields for creating a tenant.
+type CreateRequest struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Quota Quota  `json:"quota"`
+}
+
+// Validate checks the tenant ID, name and quota.
+func (r *CreateRequest) Validate() error {
+	if !ValidID(r.ID) {
+		return ErrInvalidID
+	}
+	if r.Name == "" {
+		return ErrNameRequired
+	}
+	return r.Quota.Validate()
+}
+
+// Usage is a tenant's current consumption of its quota.
+type Usage struct {
+	TenantID       string     `json:"tenant_id"`
+	Quota          Quota      `json:"quota"`
+	PeriodStart    time.Time  `json:"period_start"` // Start of the month MonthlyTokens counts from
+	ConcurrentRuns int        `json:"concurrent_runs"`
+	Projects       int        `json:"projects"`
+	MonthlyTokens  int64      `json:"monthly_tokens"`
+	StorageMB      int64      `json:"storage_mb"`
+	Exceeded       []Resource `json:"exceeded,omitempty"` // Resources at or above their limit
+}
+
+// Check returns a *QuotaError if resource is at or above its limit, so
+// that nothing more of it may be admitted.
+func (u *Usage) Check(resource Resource) error {
+	var used, limit int64
+	switch resource {
+	case ResourceConcurrentRuns:
+		used, limit = int64(u.ConcurrentRuns), int64(u.Quota.MaxConcurrentRuns)
+	case ResourceProjects:
+		used, limit = int64(u.Projects), int64(u.Quota.MaxProjects)
+	case ResourceMonthlyTokens:
+		used, limit = u.MonthlyTokens, u.Quota.MonthlyTokenBudget
+	case ResourceStorage:
+		used, limit = u.StorageMB, u.Quota.MaxStorageMB
+	default:
+		return nil
+	}
+	if limit > 0 && used >= limit {
+		return &QuotaError{TenantID: u.TenantID, Resource: resource, Limit: limit, Used: used}
+	}
+	return nil
+}
+
+// Evaluate fills Exceeded with the resources at or above their limit.
+func (u *Usage) Evaluate() {
+	u.Exceeded = nil
+	for _, r := range []Resource{ResourceConcurrentRuns, ResourceProjects, ResourceMonthlyTokens, ResourceStorage} {
+		if u.Check(r) != nil {
+			u.Exceeded = append(u.Exceeded, r)
+		}
+	}
+}
+
+// QuotaError reports an admission denied by a tenant quota. It matches
+// domain.ErrQuotaExceeded with errors.Is.
+type QuotaError struct {
+	TenantID string   `json:"tenant_id"`
+	Resource Resource `json:"resource"`
+	Limit    int64    `json:"limit"`
+	Used     int64    `json:"used"`
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("tenant %s: %s quota exceeded (%d of %d used)", e.TenantID, e.Resource, e.Used, e.Limit)
+}
+
+func (e *QuotaError) Unwrap() error { return domain.ErrQuotaExceeded }
+
+// Retryable reports whether the quota frees up without intervention: runs
+// finish and the token budget resets each month. Project and storage
+// limits need projects removed or the quota raised.
+func (e *QuotaError) Retryable() bool {
+	return e.Resource == ResourceConcurrentRuns || e.Resource == ResourceMonthlyTokens
+}
+
+// MonthStart returns the start of the calendar month (UTC) containing t.
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
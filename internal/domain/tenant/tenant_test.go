@@ -0,0 +1,96 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+)
+
+func TestCreateRequestValidate(t *testing.T) {
+	for _, req := range []CreateRequest{
+		{ID: "", Name: "A"},
+		{ID: "Acme", Name: "A"},
+		{ID: "-acme", Name: "A"},
+		{ID: "acme"},
+		{ID: "acme", Name: "A", Quota: Quota{MaxProjects: -1}},
+	} {
+		if err := req.Validate(); err == nil {
+			t.Errorf("expected error for %+v", req)
+		}
+	}
+	if err := (&CreateRequest{ID: "acme-2", Name: "Acme", Quota: Quota{MaxConcurrentRuns: 2}}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUsageCheck(t *testing.T) {
+	u := Usage{
+		TenantID:       "acme",
+		Quota:          Quota{MaxConcurrentRuns: 2, MaxProjects: 3, MonthlyTokenBudget: 1000},
+		ConcurrentRuns: 2,
+		Projects:       1,
+		MonthlyTokens:  999,
+		StorageMB:      5000,
+	}
+	if err := u.Check(ResourceProjects); err != nil {
+		t.Fatalf("projects below limit: %v", err)
+	}
+	if err := u.Check(ResourceStorage); err != nil {
+		t.Fatalf("storage is unlimited: %v", err)
+	}
+	err := u.Check(ResourceConcurrentRuns)
+	var qe *QuotaError
+	if !errors.As(err, &qe) || !errors.Is(err, domain.ErrQuotaExceeded) {
+		t.Fatalf("expected a quota error, got %v", err)
+	}
+	if qe.Limit != 2 || qe.Used != 2 || !qe.Retryable() {
+		t.Fatalf("unexpected quota error %+v", qe)
+	}
+
+	u.MonthlyTokens = 1000
+	u.Evaluate()
+	if want := []Resource{ResourceConcurrentRuns, ResourceMonthlyTokens}; !slices.Equal(u.Exceeded, want) {
+		t.Fatalf("Exceeded = %v, want %v", u.Exceeded, want)
+	}
+	if (&QuotaError{Resource: ResourceProjects}).Retryable() {
+		t.Fatal("project quota should not be retryable")
+	}
+}
+
+func TestMonthStart(t *testing.T) {
+	got := MonthStart(time.Date(2026, 3, 31, 23, 30, 0, 0, time.FixedZone("x", -2*3600)))
+	if want := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("MonthStart = %v, want %v", got, want)
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	if FromContext(ctx) != "" {
+		t.Fatal("unscoped context must have no tenant")
+	}
+	if got := FromContext(NewContext(ctx, "acme")); got != "acme" {
+		t.Fatalf("FromContext = %q", got)
+	}
+}
+
+func TestIsolationReportEvaluate(t *testing.T) {
+	r := IsolationReport{Role: "codeforge", Tables: []TableIsolation{
+		{Table: "projects", Enabled: true, Forced: true, Policies: 1},
+		{Table: "runs", Enabled: true, Policies: 1},
+	}}
+	r.Evaluate()
+	if r.OK || len(r.Problems) != 1 || !strings.Contains(r.Problems[0], "runs") {
+		t.Fatalf("unexpected report %+v", r)
+	}
+	r.Tables[1].Forced = true
+	r.Evaluate()
+	if !r.OK {
+		t.Fatalf("expected OK, got %v", r.Problems)
+	}
+}
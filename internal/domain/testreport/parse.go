@@ -0,0 +1,361 @@
+package testreport
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parse limits.
+const (
+	maxCases       = 5000
+	maxMessageLen  = 2048
+	maxOutputBytes = 16 * 1024
+)
+
+// Parse builds a report from the output of a test command and whether the
+// command exited successfully. junit, if not empty, is a JUnit XML document
+// the command wrote and takes precedence over the output. Output that no
+// parser recognizes yields a FormatUnknown report without cases.
+func Parse(output, junit string, exitOK bool) *Report {
+	rep := &Report{Format: FormatUnknown, Cases: []Case{}}
+	switch {
+	case junit != "" && parseJUnit(rep, junit):
+	case strings.Contains(output, "<testsuite") && parseJUnit(rep, output[strings.Index(output, "<"):]):
+	case parseGoJSON(rep, output):
+	case parsePytest(rep, output):
+	case parseGoText(rep, output):
+	}
+
+	if len(rep.Cases) > 0 {
+		rep.Total, rep.Failed, rep.Skipped = 0, 0, 0
+		for _, c := range rep.Cases {
+			if c.Name != "" {
+				rep.Total++
+			}
+			switch c.Status {
+			case StatusFailed:
+				rep.Failed++
+			case StatusSkipped:
+				rep.Skipped++
+			}
+		}
+	}
+	if len(rep.Cases) > maxCases {
+		rep.Cases = rep.Cases[:maxCases]
+	}
+	rep.Passed = exitOK && rep.Failed == 0
+	rep.Output = tail(output, maxOutputBytes)
+	return rep
+}
+
+// --- JUnit XML ---
+
+type junitSuite struct {
+	Name   string       `xml:"name,attr"`
+	Time   string       `xml:"time,attr"`
+	Cases  []junitCase  `xml:"testcase"`
+	Suites []junitSuite `xml:"testsuite"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure"`
+	Error     *junitMessage `xml:"error"`
+	Skipped   *junitMessage `xml:"skipped"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// parseJUnit parses a <testsuites> or <testsuite> document.
+func parseJUnit(rep *Report, doc string) bool {
+	var root junitSuite // <testsuites> has the same shape as a nested suite
+	if err := xml.Unmarshal([]byte(doc), &root); err != nil {
+		return false
+	}
+	rep.Format = FormatJUnit
+	var walk func(s *junitSuite)
+	walk = func(s *junitSuite) {
+		for _, jc := range s.Cases {
+			c := Case{Suite: jc.Classname, Name: jc.Name, Status: StatusPassed, DurationMS: seconds(jc.Time)}
+			if c.Suite == "" {
+				c.Suite = s.Name
+			}
+			switch {
+			case jc.Failure != nil:
+				c.Status, c.Message = StatusFailed, jc.Failure.text()
+			case jc.Error != nil:
+				c.Status, c.Message = StatusFailed, jc.Error.text()
+			case jc.Skipped != nil:
+				c.Status, c.Message = StatusSkipped, jc.Skipped.text()
+			}
+			rep.Cases = append(rep.Cases, c)
+		}
+		for i := range s.Suites {
+			walk(&s.Suites[i])
+		}
+	}
+	walk(&root)
+	rep.DurationMS = seconds(root.Time)
+	if rep.DurationMS == 0 {
+		for i := range root.Suites {
+			rep.DurationMS += seconds(root.Suites[i].Time)
+		}
+	}
+	return true
+}
+
+func (m *junitMessage) text() string {
+	s := strings.TrimSpace(m.Message)
+	if t := strings.TrimSpace(m.Text); t != "" {
+		if s != "" {
+			s += "\n"
+		}
+		s += t
+	}
+	return truncate(s)
+}
+
+// --- go test -json ---
+
+type goEvent struct {
+	Action  string  `json:"Action"`
+	Package string  `json:"Package"`
+	Test    string  `json:"Test"`
+	Elapsed float64 `json:"Elapsed"`
+	Output  string  `json:"Output"`
+}
+
+// parseGoJSON parses the test2json stream of go test -json.
+func parseGoJSON(rep *Report, output string) bool {
+	found := false
+	outputs := make(map[[2]string]*strings.Builder)
+	failedTests := make(map[string]bool) // Packages with a failed test
+	var pkgFails []Case
+	sc := bufio.NewScanner(strings.NewReader(output))
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var ev goEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil || ev.Action == "" {
+			continue
+		}
+		found = true
+		key := [2]string{ev.Package, ev.Test}
+		switch ev.Action {
+		case "output":
+			b := outputs[key]
+			if b == nil {
+				b = &strings.Builder{}
+				outputs[key] = b
+			}
+			if b.Len() < maxMessageLen {
+				b.WriteString(ev.Output)
+			}
+		case "pass", "fail", "skip":
+			d := int64(ev.Elapsed * 1000)
+			if ev.Test == "" {
+				rep.DurationMS += d
+				if ev.Action == "fail" {
+					pkgFails = append(pkgFails, Case{Suite: ev.Package, Status: StatusFailed, DurationMS: d, Message: outputOf(outputs[key])})
+				}
+				continue
+			}
+			c := Case{Suite: ev.Package, Name: ev.Test, Status: goStatus(ev.Action), DurationMS: d}
+			if c.Status == StatusFailed {
+				c.Message = outputOf(outputs[key])
+				failedTests[ev.Package] = true
+			}
+			rep.Cases = append(rep.Cases, c)
+		}
+	}
+	if !found {
+		return false
+	}
+	// A failed package without failed tests did not build or panicked
+	// outside a test.
+	for _, c := range pkgFails {
+		if !failedTests[c.Suite] {
+			rep.Cases = append(rep.Cases, c)
+		}
+	}
+	rep.Format = FormatGoJSON
+	return true
+}
+
+func goStatus(action string) Status {
+	switch action {
+	case "fail", "FAIL":
+		return StatusFailed
+	case "skip", "SKIP":
+		return StatusSkipped
+	}
+	return StatusPassed
+}
+
+func outputOf(b *strings.Builder) string {
+	if b == nil {
+		return ""
+	}
+	return truncate(strings.TrimSpace(b.String()))
+}
+
+// --- go test text ---
+
+var (
+	goResultLine = regexp.MustCompile(`^\s*--- (PASS|FAIL|SKIP): (\S+) \((\d+(?:\.\d+)?)s\)`)
+	goPkgLine    = regexp.MustCompile(`^(ok|FAIL|\?) *\t(\S+)(?:\s+(?:(\d+(?:\.\d+)?)s|\(cached\)|\[([^\]]+)\]))?`)
+)
+
+// parseGoText parses go test output with or without -v. Without -v only
+// failing tests are listed, so passing packages count as one case each.
+func parseGoText(rep *Report, output string) bool {
+	found := false
+	var pending []Case // Cases of the package whose summary line is not seen yet
+	last := -1         // Index in pending of the case whose output follows
+	for _, line := range strings.Split(output, "\n") {
+		if m := goResultLine.FindStringSubmatch(line); m != nil {
+			found = true
+			pending = append(pending, Case{Name: m[2], Status: goStatus(m[1]), DurationMS: seconds(m[3])})
+			last = len(pending) - 1
+			continue
+		}
+		if m := goPkgLine.FindStringSubmatch(line); m != nil {
+			found = true
+			last = -1
+			if m[1] == "?" {
+				continue // No test files
+			}
+			for i := range pending {
+				pending[i].Suite = m[2]
+			}
+			switch {
+			case len(pending) > 0:
+				rep.Cases = append(rep.Cases, pending...)
+			case m[1] == "ok":
+				rep.Cases = append(rep.Cases, Case{Suite: m[2], Name: m[2], Status: StatusPassed, DurationMS: seconds(m[3])})
+			default:
+				rep.Cases = append(rep.Cases, Case{Suite: m[2], Status: StatusFailed, Message: m[4]})
+			}
+			rep.DurationMS += seconds(m[3])
+			pending = nil
+			continue
+		}
+		if last >= 0 && pending[last].Status == StatusFailed && strings.HasPrefix(line, "    ") && len(pending[last].Message) < maxMessageLen {
+			c := &pending[last]
+			c.Message = truncate(strings.TrimPrefix(c.Message+"\n"+strings.TrimSpace(line), "\n"))
+		}
+	}
+	rep.Cases = append(rep.Cases, pending...)
+	if found {
+		rep.Format = FormatGo
+	}
+	return found
+}
+
+// --- pytest ---
+
+var (
+	pytestSummary = regexp.MustCompile(`(?m)^=+ (.*\b(?:passed|failed|error|errors|skipped|deselected|no tests ran)\b.*) in (\d+(?:\.\d+)?)s(?: \([^)]*\))? =+\s*$`)
+	pytestShort   = regexp.MustCompile(`^(PASSED|FAILED|ERROR|SKIPPED|XFAIL|XPASS) (\S+)(?: - (.*))?$`)
+	pytestVerbose = regexp.MustCompile(`^(\S+::\S+) (PASSED|FAILED|ERROR|SKIPPED|XFAIL|XPASS)\b`)
+	pytestCount   = regexp.MustCompile(`(\d+) (passed|failed|errors?|skipped|xfailed|xpassed)`)
+)
+
+// parsePytest parses pytest output. Individual results come from verbose
+// lines (-v) and the short test summary (-rA); without them only the
+// counts of the final summary line are known.
+func parsePytest(rep *Report, output string) bool {
+	summary := pytestSummary.FindAllStringSubmatch(output, -1)
+	if summary == nil && !strings.Contains(output, "test session starts") {
+		return false
+	}
+	rep.Format = FormatPytest
+
+	index := make(map[string]int)
+	add := func(nodeID, outcome, msg string) {
+		suite, name, _ := strings.Cut(nodeID, "::")
+		c := Case{Suite: suite, Name: name, Status: pytestStatus(outcome), Message: truncate(msg)}
+		if i, ok := index[nodeID]; ok {
+			if c.Message == "" {
+				c.Message = rep.Cases[i].Message
+			}
+			rep.Cases[i] = c
+			return
+		}
+		index[nodeID] = len(rep.Cases)
+		rep.Cases = append(rep.Cases, c)
+	}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := pytestShort.FindStringSubmatch(line); m != nil && strings.Contains(m[2], "::") {
+			add(m[2], m[1], m[3])
+		} else if m := pytestVerbose.FindStringSubmatch(line); m != nil {
+			add(m[1], m[2], "")
+		}
+	}
+
+	if summary != nil {
+		last := summary[len(summary)-1]
+		rep.DurationMS = seconds(last[2])
+		if len(rep.Cases) == 0 {
+			for _, m := range pytestCount.FindAllStringSubmatch(last[1], -1) {
+				n, _ := strconv.Atoi(m[1])
+				rep.Total += n
+				switch m[2] {
+				case "failed", "error", "errors":
+					rep.Failed += n
+				case "skipped", "xfailed":
+					rep.Skipped += n
+				}
+			}
+		}
+	}
+	return true
+}
+
+func pytestStatus(outcome string) Status {
+	switch outcome {
+	case "FAILED", "ERROR":
+		return StatusFailed
+	case "SKIPPED", "XFAIL":
+		return StatusSkipped
+	}
+	return StatusPassed
+}
+
+// seconds converts a decimal number of seconds to milliseconds.
+func seconds(s string) int64 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0
+	}
+	return (time.Duration(f * float64(time.Second))).Milliseconds()
+}
+
+func truncate(s string) string {
+	if len(s) <= maxMessageLen {
+		return s
+	}
+	return s[:maxMessageLen] + "..."
+}
+
+// tail returns the last n bytes of s.
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return "..." + s[len(s)-n:]
+}
@@ -0,0 +1,125 @@
+package testreport_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/testreport"
+)
+
+func TestParseJUnit(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="utf-8"?>
+<testsuites time="1.5">
+  <testsuite name="pytest">
+    <testcase classname="tests.test_api" name="test_ok" time="0.010"/>
+    <testcase classname="tests.test_api" name="test_bad" time="0.020">
+      <failure message="assert 1 == 2">tests/test_api.py:12: AssertionError</failure>
+    </testcase>
+    <testcase classname="tests.test_api" name="test_later" time="0">
+      <skipped message="not yet"/>
+    </testcase>
+  </testsuite>
+</testsuites>`
+	rep := testreport.Parse("3 tests ran", doc, false)
+	if rep.Format != testreport.FormatJUnit || rep.Total != 3 || rep.Failed != 1 || rep.Skipped != 1 || rep.Passed {
+		t.Fatalf("unexpected report %+v", rep)
+	}
+	if rep.DurationMS != 1500 {
+		t.Fatalf("expected 1500ms, got %d", rep.DurationMS)
+	}
+	bad := rep.FailedCases()[0]
+	if bad.Suite != "tests.test_api" || bad.Name != "test_bad" || !strings.Contains(bad.Message, "AssertionError") {
+		t.Fatalf("unexpected failed case %+v", bad)
+	}
+
+	// JUnit XML printed to stdout after other output.
+	inline := testreport.Parse("running\n"+doc, "", false)
+	if inline.Format != testreport.FormatJUnit || inline.Total != 3 {
+		t.Fatalf("unexpected inline report %+v", inline)
+	}
+}
+
+func TestParseGoJSON(t *testing.T) {
+	out := strings.Join([]string{
+		`{"Action":"run","Package":"example.com/a","Test":"TestOK"}`,
+		`{"Action":"pass","Package":"example.com/a","Test":"TestOK","Elapsed":0.01}`,
+		`{"Action":"run","Package":"example.com/a","Test":"TestBad"}`,
+		`{"Action":"output","Package":"example.com/a","Test":"TestBad","Output":"    a_test.go:9: want 2, got 1\n"}`,
+		`{"Action":"fail","Package":"example.com/a","Test":"TestBad","Elapsed":0.02}`,
+		`{"Action":"fail","Package":"example.com/a","Elapsed":0.5}`,
+		`{"Action":"output","Package":"example.com/b","Output":"# example.com/b\nb.go:3: undefined: x\n"}`,
+		`{"Action":"fail","Package":"example.com/b","Elapsed":0}`,
+		`{"Action":"skip","Package":"example.com/c","Test":"TestSkip","Elapsed":0}`,
+	}, "\n")
+	rep := testreport.Parse(out, "", false)
+	if rep.Format != testreport.FormatGoJSON || rep.Total != 3 || rep.Failed != 2 || rep.Skipped != 1 {
+		t.Fatalf("unexpected report %+v", rep)
+	}
+	failed := rep.FailedCases()
+	if failed[0].Name != "TestBad" || !strings.Contains(failed[0].Message, "want 2, got 1") {
+		t.Fatalf("unexpected test failure %+v", failed[0])
+	}
+	if failed[1].Suite != "example.com/b" || failed[1].Name != "" || !strings.Contains(failed[1].Message, "undefined: x") {
+		t.Fatalf("expected build failure of example.com/b, got %+v", failed[1])
+	}
+}
+
+func TestParseGoText(t *testing.T) {
+	out := "=== RUN   TestOK\n--- PASS: TestOK (0.00s)\n=== RUN   TestBad\n--- FAIL: TestBad (0.01s)\n    a_test.go:9: want 2, got 1\nFAIL\nFAIL\texample.com/a\t0.012s\n" +
+		"ok  \texample.com/c\t(cached)\n" +
+		"?   \texample.com/d\t[no test files]\n" +
+		"FAIL\texample.com/b [build failed]\n"
+	rep := testreport.Parse(out, "", false)
+	if rep.Format != testreport.FormatGo || rep.Total != 3 || rep.Failed != 2 {
+		t.Fatalf("unexpected report %+v", rep)
+	}
+	bad := rep.Cases[1]
+	if bad.Suite != "example.com/a" || bad.Name != "TestBad" || bad.Message != "a_test.go:9: want 2, got 1" {
+		t.Fatalf("unexpected failed case %+v", bad)
+	}
+	if c := rep.Cases[3]; c.Suite != "example.com/b" || c.Status != testreport.StatusFailed || c.Message != "build failed" {
+		t.Fatalf("expected build failure case, got %+v", c)
+	}
+}
+
+func TestParsePytest(t *testing.T) {
+	out := `============================= test session starts ==============================
+collected 3 items
+
+tests/test_api.py::test_ok PASSED                                        [ 33%]
+tests/test_api.py::test_bad FAILED                                       [ 66%]
+tests/test_api.py::test_later SKIPPED (not yet)                          [100%]
+
+=========================== short test summary info ============================
+PASSED tests/test_api.py::test_ok
+FAILED tests/test_api.py::test_bad - assert 1 == 2
+==================== 1 failed, 1 passed, 1 skipped in 0.12s ====================
+`
+	rep := testreport.Parse(out, "", false)
+	if rep.Format != testreport.FormatPytest || rep.Total != 3 || rep.Failed != 1 || rep.Skipped != 1 || rep.DurationMS != 120 {
+		t.Fatalf("unexpected report %+v", rep)
+	}
+	bad := rep.FailedCases()[0]
+	if bad.Suite != "tests/test_api.py" || bad.Name != "test_bad" || bad.Message != "assert 1 == 2" {
+		t.Fatalf("unexpected failed case %+v", bad)
+	}
+
+	// Quiet output only has the summary counts.
+	quiet := testreport.Parse("..F\n========= 1 failed, 2 passed in 0.05s =========\n", "", false)
+	if quiet.Total != 3 || quiet.Failed != 1 || len(quiet.Cases) != 0 {
+		t.Fatalf("unexpected quiet report %+v", quiet)
+	}
+}
+
+func TestParseUnknown(t *testing.T) {
+	rep := testreport.Parse("all good", "", true)
+	if rep.Format != testreport.FormatUnknown || !rep.Passed || rep.Total != 0 {
+		t.Fatalf("unexpected report %+v", rep)
+	}
+	if testreport.Parse("boom", "", false).Passed {
+		t.Fatal("failed command reported as passed")
+	}
+	if testreport.Parse("<not xml", "<testsuite", true).Format != testreport.FormatUnknown {
+		t.Fatal("expected invalid JUnit XML to fall back")
+	}
+}
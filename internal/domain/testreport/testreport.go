@@ -0,0 +1,75 @@
+// Package testreport defines the structured results of a run's test
+// command, parsed from JUnit XML, go test or pytest output.
+package testreport
+
+import "time"
+
+// Project config keys for test execution.
+const (
+	// ConfigKeyCommand overrides the detected test command of a project.
+	ConfigKeyCommand = "test_command"
+	// ConfigKeyReportPath names a JUnit XML file, relative to the test
+	// directory, that the test command writes and that is parsed instead of
+	// its output.
+	ConfigKeyReportPath = "test_report_path"
+	// ConfigKeyReviewRequireTests ("true") makes published reviews fail
+	// unless the reviewed run's tests passed.
+	ConfigKeyReviewRequireTests = "review_require_tests"
+)
+
+// Format is the output format a report was parsed from.
+type Format string
+
+const (
+	FormatJUnit   Format = "junit"
+	FormatGoJSON  Format = "go-json" // go test -json
+	FormatGo      Format = "go"      // go test (-v) text output
+	FormatPytest  Format = "pytest"
+	FormatUnknown Format = "unknown" // Only the exit status is known
+)
+
+// Status is the outcome of a single test case.
+type Status string
+
+const (
+	StatusPassed  Status = "passed"
+	StatusFailed  Status = "failed"
+	StatusSkipped Status = "skipped"
+)
+
+// Case is a single test result.
+type Case struct {
+	Suite      string `json:"suite,omitempty"` // Go package, pytest file or JUnit suite/class
+	Name       string `json:"name"`            // Empty for suite-level failures such as build errors
+	Status     Status `json:"status"`
+	DurationMS int64  `json:"duration_ms,omitempty"`
+	Message    string `json:"message,omitempty"` // Failure message or output, truncated
+}
+
+// Report is the parsed result of one test command execution, stored as a
+// run artifact.
+type Report struct {
+	RunID      string    `json:"run_id"`
+	ProjectID  string    `json:"project_id"`
+	Command    string    `json:"command,omitempty"`
+	Format     Format    `json:"format"`
+	Passed     bool      `json:"passed"` // Command succeeded and no case failed
+	Total      int       `json:"total"`
+	Failed     int       `json:"failed"`
+	Skipped    int       `json:"skipped"`
+	DurationMS int64     `json:"duration_ms,omitempty"`
+	Cases      []Case    `json:"cases"`
+	Output     string    `json:"output,omitempty"` // Tail of the raw output
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// FailedCases returns the failed cases of the report.
+func (r *Report) FailedCases() []Case {
+	var out []Case
+	for _, c := range r.Cases {
+		if c.Status == StatusFailed {
+			out = append(out, c)
+		}
+	}
+	return out
+}
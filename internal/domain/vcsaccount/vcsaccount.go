@@ -0,0 +1,149 @@
+// Package vcsaccount defines the health of the git host accounts projects
+// use: what the host reports about a project's API token, checked in the
+// background so broken or expiring tokens surface before a run clones or
+// delivers with them.
+package vcsaccount
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrBroken is returned when starting a run that needs the git host
+	// for a project whose account failed its latest check.
+	ErrBroken = errors.New("the project's VCS account failed its latest check")
+	// ErrReadOnly is returned when starting a run that pushes to the git
+	// host for a project whose token cannot push.
+	ErrReadOnly = errors.New("the project's VCS token cannot push to the repository")
+)
+
+// Status is the outcome of an account check.
+type Status string
+
+const (
+	StatusOK       Status = "ok"
+	StatusExpiring Status = "expiring" // The token expires within the warning period
+	StatusBroken   Status = "broken"   // The token is missing, invalid, expired or cannot read the repository
+)
+
+// Notification event types of account checks.
+const (
+	EventExpiring  = "vcs.account.expiring"
+	EventBroken    = "vcs.account.broken"
+	EventRecovered = "vcs.account.recovered"
+)
+
+// TokenInfo is what the git host reports about an API token.
+type TokenInfo struct {
+	Login         string     `json:"login,omitempty"`      // Account the token acts as, if the host reports it
+	Scopes        []string   `json:"scopes,omitempty"`     // Granted scopes; empty for token types without scopes, e.g. GitHub fine-grained and app tokens
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"` // Nil for tokens that do not expire or are refreshed automatically
+	CanPush       bool       `json:"can_push"`             // The token may push to the project's repository
+	RateLimit     int        `json:"rate_limit"`           // Requests per window; 0 if the host reports none
+	RateRemaining int        `json:"rate_remaining"`
+	RateReset     *time.Time `json:"rate_reset,omitempty"`
+}
+
+// Health is the result of the latest check of a project's account.
+type Health struct {
+	ProjectID string `json:"project_id"`
+	Account   string `json:"account"` // Provider and token fingerprint; projects sharing a token share it
+	Provider  string `json:"provider"`
+	TokenInfo
+	Status    Status    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Record sets the token details, status and error of a check at now from
+// what the host reported about the token, or the error inspecting it.
+// Tokens expiring within warn are expiring; expired ones are broken.
+func (h *Health) Record(info *TokenInfo, err error, warn time.Duration, now time.Time) {
+	h.CheckedAt, h.TokenInfo, h.Status, h.Error = now, TokenInfo{}, StatusOK, ""
+	if err != nil {
+		h.Status, h.Error = StatusBroken, err.Error()
+		return
+	}
+	h.TokenInfo = *info
+	switch {
+	case info.ExpiresAt != nil && !now.Before(*info.ExpiresAt):
+		h.Status, h.Error = StatusBroken, "the token expired at "+info.ExpiresAt.UTC().Format(time.RFC3339)
+	case info.ExpiresAt != nil && now.Add(warn).After(*info.ExpiresAt):
+		h.Status = StatusExpiring
+	}
+}
+
+// Transition returns the notification event of a change from the previous
+// check, or "". The first check notifies unless it is ok.
+func Transition(prev *Health, cur *Health) string {
+	was := StatusOK
+	if prev != nil {
+		was = prev.Status
+	}
+	switch {
+	case cur.Status == was:
+		return ""
+	case cur.Status == StatusBroken:
+		return EventBroken
+	case cur.Status == StatusExpiring && was == StatusOK:
+		return EventExpiring
+	case cur.Status == StatusOK && was == StatusBroken:
+		return EventRecovered
+	}
+	return ""
+}
+
+// Usable returns ErrBroken if the account failed its check, or ErrReadOnly
+// if push is set and the token cannot push.
+func (h *Health) Usable(push bool) error {
+	switch {
+	case h.Status == StatusBroken:
+		return ErrBroken
+	case push && !h.CanPush:
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// Account is an account and the projects using it, as of their latest
+// checks. Its token details are those of the first project whose check
+// did not fail, as the projects share the token.
+type Account struct {
+	Account   string     `json:"account"`
+	Provider  string     `json:"provider"`
+	Login     string     `json:"login,omitempty"`
+	Scopes    []string   `json:"scopes,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Status    Status     `json:"status"` // The worst status of its projects
+	Projects  []Health   `json:"projects"`
+}
+
+// severity orders statuses from best to worst.
+var severity = map[Status]int{StatusOK: 0, StatusExpiring: 1, StatusBroken: 2}
+
+// Group groups the health of projects by account, in the order accounts
+// first appear.
+func Group(healths []Health) []Account {
+	var out []Account
+	index := make(map[string]int)
+	inspected := make(map[string]bool)
+	for _, h := range healths {
+		i, ok := index[h.Account]
+		if !ok {
+			i = len(out)
+			index[h.Account] = i
+			out = append(out, Account{Account: h.Account, Provider: h.Provider, Status: h.Status})
+		}
+		a := &out[i]
+		a.Projects = append(a.Projects, h)
+		if severity[h.Status] > severity[a.Status] {
+			a.Status = h.Status
+		}
+		if h.Status != StatusBroken && !inspected[h.Account] {
+			inspected[h.Account] = true
+			a.Login, a.Scopes, a.ExpiresAt = h.Login, h.Scopes, h.ExpiresAt
+		}
+	}
+	return out
+}
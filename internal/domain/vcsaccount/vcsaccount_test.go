@@ -0,0 +1,89 @@
+package vcsaccount_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/vcsaccount"
+)
+
+func TestHealthRecord(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time { t := now.Add(d); return &t }
+	warn := 7 * 24 * time.Hour
+
+	tests := []struct {
+		name   string
+		info   *vcsaccount.TokenInfo
+		err    error
+		status vcsaccount.Status
+	}{
+		{"no expiry", &vcsaccount.TokenInfo{Login: "bot"}, nil, vcsaccount.StatusOK},
+		{"far expiry", &vcsaccount.TokenInfo{ExpiresAt: at(30 * 24 * time.Hour)}, nil, vcsaccount.StatusOK},
+		{"expiring", &vcsaccount.TokenInfo{ExpiresAt: at(48 * time.Hour)}, nil, vcsaccount.StatusExpiring},
+		{"expired", &vcsaccount.TokenInfo{ExpiresAt: at(-time.Minute)}, nil, vcsaccount.StatusBroken},
+		{"invalid", nil, errors.New("API error 401: Bad credentials"), vcsaccount.StatusBroken},
+	}
+	for _, tt := range tests {
+		h := vcsaccount.Health{Status: vcsaccount.StatusBroken, Error: "stale"}
+		h.Record(tt.info, tt.err, warn, now)
+		if h.Status != tt.status || h.CheckedAt != now || (tt.status == vcsaccount.StatusBroken) == (h.Error == "") {
+			t.Errorf("%s: got %+v", tt.name, h)
+		}
+	}
+}
+
+func TestTransition(t *testing.T) {
+	health := func(s vcsaccount.Status) *vcsaccount.Health { return &vcsaccount.Health{Status: s} }
+	tests := []struct {
+		prev *vcsaccount.Health
+		cur  vcsaccount.Status
+		want string
+	}{
+		{nil, vcsaccount.StatusOK, ""},
+		{nil, vcsaccount.StatusExpiring, vcsaccount.EventExpiring},
+		{nil, vcsaccount.StatusBroken, vcsaccount.EventBroken},
+		{health(vcsaccount.StatusOK), vcsaccount.StatusExpiring, vcsaccount.EventExpiring},
+		{health(vcsaccount.StatusExpiring), vcsaccount.StatusExpiring, ""},
+		{health(vcsaccount.StatusExpiring), vcsaccount.StatusBroken, vcsaccount.EventBroken},
+		{health(vcsaccount.StatusBroken), vcsaccount.StatusOK, vcsaccount.EventRecovered},
+		{health(vcsaccount.StatusBroken), vcsaccount.StatusExpiring, ""},
+		{health(vcsaccount.StatusExpiring), vcsaccount.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		if got := vcsaccount.Transition(tt.prev, health(tt.cur)); got != tt.want {
+			t.Errorf("Transition(%+v, %s) = %q, want %q", tt.prev, tt.cur, got, tt.want)
+		}
+	}
+}
+
+func TestGroup(t *testing.T) {
+	expires := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	accounts := vcsaccount.Group([]vcsaccount.Health{
+		{ProjectID: "p1", Account: "github:a", Status: vcsaccount.StatusBroken, Error: "not found"},
+		{ProjectID: "p2", Account: "gitlab:b", Status: vcsaccount.StatusOK},
+		{ProjectID: "p3", Account: "github:a", Status: vcsaccount.StatusExpiring,
+			TokenInfo: vcsaccount.TokenInfo{Login: "bot", ExpiresAt: &expires}},
+	})
+	if len(accounts) != 2 || len(accounts[0].Projects) != 2 || len(accounts[1].Projects) != 1 {
+		t.Fatalf("unexpected grouping %+v", accounts)
+	}
+	if a := accounts[0]; a.Status != vcsaccount.StatusBroken || a.Login != "bot" || a.ExpiresAt == nil {
+		t.Fatalf("expected the worst status and the token details of p3, got %+v", a)
+	}
+}
+
+func TestHealthUsable(t *testing.T) {
+	ok := &vcsaccount.Health{Status: vcsaccount.StatusExpiring}
+	if err := ok.Usable(false); err != nil {
+		t.Fatalf("expected a read-only token usable without pushing, got %v", err)
+	}
+	if err := ok.Usable(true); !errors.Is(err, vcsaccount.ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+	broken := &vcsaccount.Health{Status: vcsaccount.StatusBroken, TokenInfo: vcsaccount.TokenInfo{CanPush: true}}
+	if err := broken.Usable(false); !errors.Is(err, vcsaccount.ErrBroken) {
+		t.Fatalf("expected ErrBroken, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+// Package workspace defines the file view of a project or run workspace
+// that the web UI browses and that humans touch up between plan steps.
+package workspace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidEdit is returned for patches whose edits are out of range or
+// overlap.
+var ErrInvalidEdit = errors.New("invalid edit")
+
+// Entry is a file or directory of a workspace tree.
+type Entry struct {
+	Path string `json:"path"` // Slash-separated, relative to the workspace root
+	Dir  bool   `json:"dir,omitempty"`
+	Size int64  `json:"size,omitempty"`
+}
+
+// Tree lists a directory of a workspace.
+type Tree struct {
+	Path      string  `json:"path"` // Listed directory; "" is the workspace root
+	Entries   []Entry `json:"entries"`
+	Truncated bool    `json:"truncated,omitempty"` // More entries than the listing limit
+}
+
+// File is a file of a workspace, or a range of its lines.
+type File struct {
+	Path      string `json:"path"`
+	Hash      string `json:"hash"` // SHA-256 of the whole file; base_hash of writes and patches
+	Size      int64  `json:"size"`
+	Lines     int    `json:"lines"`                // Line count of the whole file
+	StartLine int    `json:"start_line,omitempty"` // First line of Content, 1-based
+	EndLine   int    `json:"end_line,omitempty"`   // Last line of Content
+	Content   string `json:"content"`
+	Binary    bool   `json:"binary,omitempty"` // Not UTF-8 text; Content is empty
+}
+
+// WriteRequest replaces or creates a file.
+type WriteRequest struct {
+	Content  string `json:"content"`
+	BaseHash string `json:"base_hash,omitempty"` // Hash of the file the write is based on; empty creates a new file
+}
+
+// Edit replaces the lines StartLine to EndLine (1-based, inclusive) with
+// Text, which carries its own line endings. EndLine StartLine-1 inserts
+// Text before StartLine.
+type Edit struct {
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Text      string `json:"text"`
+}
+
+// PatchRequest applies line edits to a file.
+type PatchRequest struct {
+	BaseHash string `json:"base_hash"` // Hash of the file the edits are based on
+	Edits    []Edit `json:"edits"`
+}
+
+// Hash returns the hash of a file's content.
+func Hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// SplitLines splits content into lines that keep their line endings.
+func SplitLines(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// ApplyEdits applies edits, ordered by line and not overlapping, to
+// content.
+func ApplyEdits(content string, edits []Edit) (string, error) {
+	lines := SplitLines(content)
+	var prev Edit
+	for i, e := range edits {
+		if e.StartLine < 1 || e.StartLine > len(lines)+1 || e.EndLine < e.StartLine-1 || e.EndLine > len(lines) {
+			return "", fmt.Errorf("%w: edit %d: lines %d-%d out of range 1-%d", ErrInvalidEdit, i, e.StartLine, e.EndLine, len(lines))
+		}
+		if i > 0 && (e.StartLine <= prev.StartLine || e.StartLine <= prev.EndLine) {
+			return "", fmt.Errorf("%w: edit %d overlaps or precedes edit %d", ErrInvalidEdit, i, i-1)
+		}
+		prev = e
+	}
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		tail := append([]string{e.Text}, lines[e.EndLine:]...)
+		lines = append(lines[:e.StartLine-1], tail...)
+	}
+	return strings.Join(lines, ""), nil
+}
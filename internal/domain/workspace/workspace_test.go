@@ -0,0 +1,55 @@
+package workspace
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSplitLines(t *testing.T) {
+	tests := map[string][]string{
+		"":           nil,
+		"a":          {"a"},
+		"a\nb\n":     {"a\n", "b\n"},
+		"a\r\nb":     {"a\r\n", "b"},
+		"\n\nlast\n": {"\n", "\n", "last\n"},
+	}
+	for content, want := range tests {
+		if got := SplitLines(content); !slices.Equal(got, want) {
+			t.Errorf("SplitLines(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
+
+func TestApplyEdits(t *testing.T) {
+	const content = "one\ntwo\nthree\n"
+	tests := []struct {
+		name  string
+		edits []Edit
+		want  string
+	}{
+		{"replace", []Edit{{StartLine: 2, EndLine: 2, Text: "TWO\n"}}, "one\nTWO\nthree\n"},
+		{"insert", []Edit{{StartLine: 1, EndLine: 0, Text: "zero\n"}}, "zero\none\ntwo\nthree\n"},
+		{"append", []Edit{{StartLine: 4, EndLine: 3, Text: "four\n"}}, "one\ntwo\nthree\nfour\n"},
+		{"delete", []Edit{{StartLine: 1, EndLine: 2}}, "three\n"},
+		{"several", []Edit{{StartLine: 1, EndLine: 1, Text: "1\n"}, {StartLine: 3, EndLine: 3, Text: "3\n"}}, "1\ntwo\n3\n"},
+	}
+	for _, tt := range tests {
+		got, err := ApplyEdits(content, tt.edits)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: ApplyEdits = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	for _, edits := range [][]Edit{
+		{{StartLine: 0, EndLine: 1}},
+		{{StartLine: 2, EndLine: 4}},
+		{{StartLine: 3, EndLine: 1}},
+		{{StartLine: 1, EndLine: 2}, {StartLine: 2, EndLine: 2}},
+		{{StartLine: 3, EndLine: 3}, {StartLine: 1, EndLine: 1}},
+	} {
+		if _, err := ApplyEdits(content, edits); !errors.Is(err, ErrInvalidEdit) {
+			t.Errorf("ApplyEdits(%v): expected ErrInvalidEdit, got %v", edits, err)
+		}
+	}
+}
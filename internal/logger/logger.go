@@ -9,10 +9,14 @@ import (
 	"github.com/Strob0t/CodeForge/internal/config"
 )
 
+// level is the minimum level of loggers created by New. It can change
+// while they are in use, e.g. after a config reload.
+var level = new(slog.LevelVar)
+
 // New creates a *slog.Logger from the given Logging config.
 // Output is JSON to stdout with a "service" attribute on every record.
 func New(cfg config.Logging) *slog.Logger {
-	level := parseLevel(cfg.Level)
+	SetLevel(cfg.Level)
 
 	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level: level,
@@ -21,6 +25,11 @@ func New(cfg config.Logging) *slog.Logger {
 	return slog.New(handler).With("service", cfg.Service)
 }
 
+// SetLevel changes the level of all loggers created by New.
+func SetLevel(s string) {
+	level.Set(parseLevel(s))
+}
+
 // parseLevel converts a string log level to slog.Level.
 func parseLevel(s string) slog.Level {
 	switch strings.ToLower(s) {
@@ -2,6 +2,7 @@ package logger
 
 import (
 	"context"
+	"log/slog"
 	"testing"
 
 	"github.com/Strob0t/CodeForge/internal/config"
@@ -15,6 +16,18 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestSetLevel(t *testing.T) {
+	l := New(config.Logging{Level: "info", Service: "test-svc"})
+	if l.Enabled(context.Background(), slog.LevelDebug) {
+		t.Fatal("debug must be off at info level")
+	}
+	SetLevel("debug")
+	t.Cleanup(func() { SetLevel("info") })
+	if !l.Enabled(context.Background(), slog.LevelDebug) {
+		t.Fatal("expected the existing logger to follow the new level")
+	}
+}
+
 func TestParseLevel(t *testing.T) {
 	tests := []struct {
 		input string
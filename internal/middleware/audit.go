@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/logger"
+)
+
+// AuditRecorder stores audit entries.
+type AuditRecorder interface {
+	Record(ctx context.Context, e *event.AuditEntry)
+}
+
+// Audit returns middleware that records every request that may change
+// state (POST, PUT, PATCH, DELETE) as an audit entry once it was handled,
+// with its actor, tenant and response status. It must run after Auth and
+// Tenant.
+func Audit(rec AuditRecorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			default:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r)
+
+			ctx := r.Context()
+			rec.Record(context.WithoutCancel(ctx), &event.AuditEntry{
+				TenantID:   tenant.FromContext(ctx),
+				Actor:      Actor(ctx),
+				Method:     r.Method,
+				Path:       r.URL.Path,
+				Status:     sw.status,
+				RequestID:  logger.RequestID(ctx),
+				RemoteAddr: r.RemoteAddr,
+			})
+		})
+	}
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	sw.status = code
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush server-sent events.
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
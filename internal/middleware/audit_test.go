@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/apikey"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+)
+
+type recordedEntries []event.AuditEntry
+
+func (r *recordedEntries) Record(_ context.Context, e *event.AuditEntry) {
+	*r = append(*r, *e)
+}
+
+func TestAudit(t *testing.T) {
+	var rec recordedEntries
+	handler := Auth([]string{"admin"}, fakeKeys{
+		"cf_ci": {ID: "k1", Scopes: []apikey.Scope{apikey.ScopeRunsWrite}, TenantID: "acme"},
+	})(Tenant(Audit(&rec)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))))
+
+	for _, tt := range []struct{ token, method, path string }{
+		{"cf_ci", http.MethodGet, "/api/v1/runs/r1"},
+		{"cf_ci", http.MethodPost, "/api/v1/runs"},
+		{"admin", http.MethodDelete, "/api/v1/projects/p1"},
+	} {
+		req := httptest.NewRequest(tt.method, tt.path, http.NoBody)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if len(rec) != 2 {
+		t.Fatalf("expected 2 entries (reads are not audited), got %d", len(rec))
+	}
+	if e := rec[0]; e.Actor != "api_key:k1" || e.TenantID != "acme" || e.Method != http.MethodPost || e.Status != http.StatusOK {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+	if e := rec[1]; e.Actor != ActorServer || e.TenantID != "" || e.Path != "/api/v1/projects/p1" || e.Status != http.StatusNotFound {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+}
@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Strob0t/CodeForge/internal/domain/apikey"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+)
+
+// KeyAuthenticator resolves managed API keys (apikey.Key) from tokens.
+type KeyAuthenticator interface {
+	// Authenticate returns the key of a token, or apikey.ErrInvalid.
+	Authenticate(ctx context.Context, token string) (*apikey.Key, error)
+	// HasKeys reports whether any unexpired managed key exists.
+	HasKeys(ctx context.Context) (bool, error)
+}
+
+// Auth returns middleware that requires an API key as a bearer token
+// ("Authorization: Bearer <key>"): one of the configured keys, which may
+// do everything, or a managed key resolved by managed (may be nil). A
+// managed key must have the scope the request needs and scopes the
+// request to its tenant (apikey.Key.Tenant), so
+// the Tenant middleware rejects a header naming another one. The
+// configured keys and admin keys without a tenant act for all tenants and
+// may narrow a request to one with the header. Auth is
+// enforced as soon as a configured or an unexpired managed key exists; only while
+// there are neither is every request allowed. If it cannot be told whether
+// managed keys exist, requests are refused.
+func Auth(keys []string, managed KeyAuthenticator) func(http.Handler) http.Handler {
+	sums := make([][sha256.Size]byte, 0, len(keys))
+	for _, k := range keys {
+		if k != "" {
+			sums = append(sums, sha256.Sum256([]byte(k)))
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		if len(sums) == 0 && managed == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(sums) == 0 {
+				has, err := managed.HasKeys(r.Context())
+				if err != nil {
+					slog.Error("check for api keys", "error", err)
+					writeAuthError(w, http.StatusServiceUnavailable, "could not verify API key")
+					return
+				}
+				if !has {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if ok && validKey(sums, key) {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), actorKey{}, ActorServer)))
+				return
+			}
+			if !ok || managed == nil {
+				unauthorized(w)
+				return
+			}
+
+			k, err := managed.Authenticate(r.Context(), key)
+			if errors.Is(err, apikey.ErrInvalid) {
+				unauthorized(w)
+				return
+			}
+			if err != nil {
+				slog.Error("authenticate api key", "error", err)
+				writeAuthError(w, http.StatusServiceUnavailable, "could not verify API key")
+				return
+			}
+			if required := apikey.Required(r.Method, r.URL.Path); !k.Allows(required) {
+				writeAuthError(w, http.StatusForbidden, fmt.Sprintf("API key lacks the %s scope", required))
+				return
+			}
+			ctx := context.WithValue(r.Context(), actorKey{}, "api_key:"+k.ID)
+			ctx = context.WithValue(ctx, apiKeyKey{}, k)
+			if id := k.Tenant(); id != "" {
+				ctx = tenant.NewContext(ctx, id)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ActorServer is the actor of requests made with one of the configured
+// keys (server.api_keys).
+const ActorServer = "server"
+
+type actorKey struct{}
+
+// Actor returns who authenticated a request: ActorServer, "api_key:<id>"
+// for managed keys, or "" if auth is disabled.
+func Actor(ctx context.Context) string {
+	a, _ := ctx.Value(actorKey{}).(string)
+	return a
+}
+
+// Voter returns who votes on approval steps in a request: the credential
+// it authenticated with, and the name and roles of its managed key.
+func Voter(ctx context.Context) plan.Voter {
+	v := plan.Voter{ID: Actor(ctx)}
+	if k := APIKey(ctx); k != nil {
+		v.Name, v.Roles = k.Name, k.Roles
+	}
+	return v
+}
+
+type apiKeyKey struct{}
+
+// APIKey returns the managed API key a request was authenticated with, or
+// nil.
+func APIKey(ctx context.Context) *apikey.Key {
+	k, _ := ctx.Value(apiKeyKey{}).(*apikey.Key)
+	return k
+}
+
+// KeyRateLimit holds requests made with a managed key to the key's own rate
+// limit on this server. It runs after Auth. With distributed rate limiting
+// the shared limiter applies the key's limit across all servers instead.
+func KeyRateLimit(next http.Handler) http.Handler {
+	limits := &keyLimits{byKey: make(map[string]*RateLimiter)}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if k := APIKey(r.Context()); k != nil && k.RateLimit > 0 && !limits.get(k).limit(w, k.ID) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// keyLimits holds a rate limiter per managed key and rate.
+type keyLimits struct {
+	mu    sync.Mutex
+	byKey map[string]*RateLimiter
+}
+
+// get returns the limiter of a key, replacing it when the key's rate
+// limit changed.
+func (l *keyLimits) get(k *apikey.Key) *RateLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rl, ok := l.byKey[k.ID]
+	if !ok || rl.burst != k.RateLimit {
+		rl = NewRateLimiter(float64(k.RateLimit)/60, k.RateLimit)
+		l.byKey[k.ID] = rl
+	}
+	return rl
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="codeforge"`)
+	writeAuthError(w, http.StatusUnauthorized, "missing or invalid API key")
+}
+
+func writeAuthError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, `{"error":%q}`, msg)
+}
+
+// validKey compares the key's digest with each configured digest in
+// constant time.
+func validKey(sums [][sha256.Size]byte, key string) bool {
+	sum := sha256.Sum256([]byte(key))
+	valid := 0
+	for i := range sums {
+		valid |= subtle.ConstantTimeCompare(sum[:], sums[i][:])
+	}
+	return valid == 1
+}
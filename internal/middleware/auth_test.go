@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/apikey"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+)
+
+func TestAuth(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := Auth([]string{"key-a", "key-b"}, nil)(ok)
+
+	for _, tt := range []struct {
+		header string
+		want   int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"key-a", http.StatusUnauthorized},
+		{"Bearer key-a", http.StatusOK},
+		{"Bearer key-b", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("Authorization %q: expected %d, got %d", tt.header, tt.want, rec.Code)
+		}
+	}
+}
+
+func TestAuthWithoutKeys(t *testing.T) {
+	handler := Auth(nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected requests to pass without keys, got %d", rec.Code)
+	}
+}
+
+type fakeKeys map[string]*apikey.Key
+
+func (f fakeKeys) HasKeys(context.Context) (bool, error) {
+	return len(f) > 0, nil
+}
+
+func (f fakeKeys) Authenticate(_ context.Context, token string) (*apikey.Key, error) {
+	if k, ok := f[token]; ok {
+		return k, nil
+	}
+	return nil, apikey.ErrInvalid
+}
+
+func TestAuth_ManagedKeys(t *testing.T) {
+	var gotTenant string
+	handler := Auth([]string{"admin"}, fakeKeys{
+		"cf_reader": {ID: "k1", Scopes: []apikey.Scope{apikey.ScopeRead}},
+		"cf_ci":     {ID: "k2", Scopes: []apikey.Scope{apikey.ScopeRunsWrite}, TenantID: "acme"},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTenant = tenant.FromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range []struct {
+		token, method, path string
+		want                int
+	}{
+		{"cf_unknown", http.MethodGet, "/api/v1/projects", http.StatusUnauthorized},
+		{"cf_reader", http.MethodGet, "/api/v1/projects", http.StatusOK},
+		{"cf_reader", http.MethodPost, "/api/v1/runs", http.StatusForbidden},
+		{"cf_ci", http.MethodPost, "/api/v1/runs", http.StatusOK},
+		{"cf_ci", http.MethodDelete, "/api/v1/projects/p1", http.StatusForbidden},
+		{"cf_ci", http.MethodGet, "/api/v1/auth/api-keys", http.StatusForbidden},
+		{"admin", http.MethodGet, "/api/v1/auth/api-keys", http.StatusOK},
+	} {
+		gotTenant = ""
+		req := httptest.NewRequest(tt.method, tt.path, http.NoBody)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s %s: expected %d, got %d", tt.token, tt.method, tt.path, tt.want, rec.Code)
+		}
+		if tt.token == "cf_ci" && rec.Code == http.StatusOK && gotTenant != "acme" {
+			t.Errorf("expected the key's tenant in the context, got %q", gotTenant)
+		}
+	}
+}
+
+func TestAuth_ManagedKeysWithoutConfiguredKeys(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	serve := func(handler http.Handler, token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/projects", http.NoBody)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Managed keys are enforced even when no keys are configured.
+	handler := Auth(nil, fakeKeys{"cf_reader": {ID: "k1", Scopes: []apikey.Scope{apikey.ScopeRead}}})(ok)
+	if code := serve(handler, ""); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a key, got %d", code)
+	}
+	if code := serve(handler, "cf_reader"); code != http.StatusOK {
+		t.Errorf("expected the managed key to be accepted, got %d", code)
+	}
+
+	// Until the first managed key exists, requests pass.
+	if code := serve(Auth(nil, fakeKeys{})(ok), ""); code != http.StatusOK {
+		t.Errorf("expected requests to pass without any keys, got %d", code)
+	}
+}
+
+func TestAuth_KeyTenant(t *testing.T) {
+	var gotTenant string
+	handler := Auth([]string{"static"}, fakeKeys{
+		"cf_reader": {ID: "k1", Scopes: []apikey.Scope{apikey.ScopeRead}},
+		"cf_admin":  {ID: "k2", Scopes: []apikey.Scope{apikey.ScopeAdmin}},
+	})(Tenant(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTenant = tenant.FromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	for _, tt := range []struct {
+		token, header string
+		want          int
+		wantTenant    string
+	}{
+		// A key without a tenant belongs to the default tenant.
+		{"cf_reader", "", http.StatusOK, tenant.DefaultID},
+		{"cf_reader", tenant.DefaultID, http.StatusOK, tenant.DefaultID},
+		{"cf_reader", "acme", http.StatusForbidden, ""},
+		// Credentials acting for all tenants may narrow to one.
+		{"cf_admin", "", http.StatusOK, ""},
+		{"cf_admin", "acme", http.StatusOK, "acme"},
+		{"static", "acme", http.StatusOK, "acme"},
+	} {
+		gotTenant = ""
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/projects", http.NoBody)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		if tt.header != "" {
+			req.Header.Set("X-Tenant-ID", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want || gotTenant != tt.wantTenant {
+			t.Errorf("%s with X-Tenant-ID %q: expected %d in %q, got %d in %q", tt.token, tt.header, tt.want, tt.wantTenant, rec.Code, gotTenant)
+		}
+	}
+}
+
+func TestAuth_KeyRateLimit(t *testing.T) {
+	handler := Auth([]string{"admin"}, fakeKeys{
+		"cf_ci": {ID: "k1", Scopes: []apikey.Scope{apikey.ScopeRead}, RateLimit: 2},
+	})(KeyRateLimit(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	codes := make([]int, 0, 3)
+	for range 3 {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/projects", http.NoBody)
+		req.Header.Set("Authorization", "Bearer cf_ci")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Fatalf("expected two requests then 429, got %v", codes)
+	}
+}
+
+func TestAuth_LeavesKeyRateLimitToLimiter(t *testing.T) {
+	// With distributed rate limiting the shared limiter applies the key's
+	// limit, so Auth alone must not hold the key to it a second time.
+	handler := Auth([]string{"admin"}, fakeKeys{
+		"cf_ci": {ID: "k1", Scopes: []apikey.Scope{apikey.ScopeRead}, RateLimit: 1},
+	})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := range 3 {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/projects", http.NoBody)
+		req.Header.Set("Authorization", "Bearer cf_ci")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200 without KeyRateLimit, got %d", i, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+)
+
+// Idempotency headers. A replayed response carries IdempotentReplayedHeader.
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// IdempotencyBucket is the key-value bucket of the idempotency middleware.
+const IdempotencyBucket = "codeforge_idempotency"
+
+// maxIdempotencyKey is the maximum length of an Idempotency-Key.
+const maxIdempotencyKey = 255
+
+// idempotencyLock is how long a key stays claimed by a request that never
+// finished, e.g. because the server stopped while handling it.
+const idempotencyLock = time.Minute
+
+// replayedHeaders are the response headers stored and replayed.
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+// IdempotencyStats is a point-in-time view of the idempotency counters.
+type IdempotencyStats struct {
+	Enabled    bool  `json:"enabled"`
+	Stored     int64 `json:"stored"`     // Responses stored for replay
+	Replayed   int64 `json:"replayed"`   // Retries answered with a stored response
+	InFlight   int64 `json:"in_flight"`  // Retries rejected while the first request was running
+	Mismatches int64 `json:"mismatches"` // Keys reused for a different request
+	Errors     int64 `json:"errors"`     // Requests passed through because the store failed
+}
+
+// Idempotency replays the response of a POST, PUT, PATCH or DELETE request
+// to retries with the same Idempotency-Key header, so a client retrying
+// after a network failure gets the original result instead of repeating
+// the change. Keys are scoped to the tenant and actor and stored in a
+// key-value bucket with the request's fingerprint (method, path, body)
+// and, once handled, its status, headers and body. Server errors are not
+// stored so they can be retried.
+type Idempotency struct {
+	kv      messagequeue.KeyValue
+	ttl     time.Duration
+	maxBody int
+	routes  map[string]time.Duration // "METHOD /path" pattern -> TTL
+
+	stored, replayed, inFlight, mismatches, errs atomic.Int64
+}
+
+// idempotencyRecord is the stored state of a key. Status is 0 while the
+// first request is being handled.
+type idempotencyRecord struct {
+	Fingerprint string            `json:"fingerprint"`
+	Status      int               `json:"status,omitempty"`
+	Header      map[string]string `json:"header,omitempty"`
+	Body        []byte            `json:"body,omitempty"`
+	BodySHA256  string            `json:"body_sha256,omitempty"`
+	ExpiresAt   time.Time         `json:"expires_at"`
+}
+
+// NewIdempotency creates the middleware. Responses are replayed for ttl,
+// or the TTL of the longest matching route pattern ("METHOD /path", *
+// matches one path segment; 0 exempts the route); bodies larger than
+// maxBody bytes are replayed as status and headers only. The key-value
+// bucket is set with SetKeyValue before the middleware serves requests.
+func NewIdempotency(ttl time.Duration, maxBody int, routes map[string]time.Duration) *Idempotency {
+	return &Idempotency{ttl: ttl, maxBody: maxBody, routes: routes}
+}
+
+// SetKeyValue sets the bucket keys and responses are stored in. It must
+// keep values for at least MaxTTL.
+func (m *Idempotency) SetKeyValue(kv messagequeue.KeyValue) {
+	m.kv = kv
+}
+
+// MaxTTL returns the longest TTL of the middleware, the age its key-value
+// bucket must keep values for.
+func (m *Idempotency) MaxTTL() time.Duration {
+	longest := m.ttl
+	for _, ttl := range m.routes {
+		longest = max(longest, ttl)
+	}
+	return longest
+}
+
+// Stats returns the idempotency counters.
+func (m *Idempotency) Stats() IdempotencyStats {
+	if m == nil {
+		return IdempotencyStats{}
+	}
+	return IdempotencyStats{
+		Enabled:    true,
+		Stored:     m.stored.Load(),
+		Replayed:   m.replayed.Load(),
+		InFlight:   m.inFlight.Load(),
+		Mismatches: m.mismatches.Load(),
+		Errors:     m.errs.Load(),
+	}
+}
+
+// Handler returns the middleware. It must run after Auth and Tenant.
+func (m *Idempotency) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			key = ""
+		}
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			writeIdempotencyError(w, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+			return
+		}
+		ttl := m.routeTTL(r.Method, r.URL.Path)
+		if ttl <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeIdempotencyError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := r.Context()
+		storeKey := idempotencyStoreKey(tenant.FromContext(ctx), Actor(ctx), key)
+		rec := idempotencyRecord{
+			Fingerprint: idempotencyFingerprint(r.Method, r.URL.RequestURI(), body),
+			ExpiresAt:   time.Now().Add(idempotencyLock),
+		}
+		data, _ := json.Marshal(rec)
+
+		err = m.kv.Create(ctx, storeKey, data)
+		if errors.Is(err, messagequeue.ErrKeyExists) {
+			if m.answer(ctx, w, storeKey, rec.Fingerprint) {
+				return
+			}
+			// The stored record expired: claim the key again.
+			err = m.kv.Put(ctx, storeKey, data)
+		}
+		if err != nil {
+			m.errs.Add(1)
+			slog.Warn("idempotency store unavailable, handling request without replay", "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rw := &recordingWriter{ResponseWriter: w, status: http.StatusOK, max: m.maxBody, hash: sha256.New()}
+		completed := false
+		defer func() {
+			// Release the key if the handler panicked or failed, so the
+			// request can be retried.
+			if !completed || rw.status >= http.StatusInternalServerError {
+				if err := m.kv.Delete(context.WithoutCancel(ctx), storeKey); err != nil {
+					slog.Warn("release idempotency key", "error", err)
+				}
+			}
+		}()
+		next.ServeHTTP(rw, r)
+		completed = true
+		if rw.status >= http.StatusInternalServerError {
+			return
+		}
+
+		rec.Status = rw.status
+		rec.ExpiresAt = time.Now().Add(ttl)
+		rec.Header = make(map[string]string, len(replayedHeaders))
+		for _, h := range replayedHeaders {
+			if v := w.Header().Get(h); v != "" {
+				rec.Header[h] = v
+			}
+		}
+		rec.BodySHA256 = hex.EncodeToString(rw.hash.Sum(nil))
+		if !rw.overflow {
+			rec.Body = rw.body.Bytes()
+		}
+		data, _ = json.Marshal(rec)
+		if err := m.kv.Put(context.WithoutCancel(ctx), storeKey, data); err != nil {
+			m.errs.Add(1)
+			slog.Warn("store idempotent response", "error", err)
+			return
+		}
+		m.stored.Add(1)
+	})
+}
+
+// answer responds to a retry from the stored record of its key. It returns
+// false if the record expired or vanished.
+func (m *Idempotency) answer(ctx context.Context, w http.ResponseWriter, storeKey, fingerprint string) bool {
+	data, err := m.kv.Get(ctx, storeKey)
+	if err != nil || data == nil {
+		return false
+	}
+	var rec idempotencyRecord
+	if err := json.Unmarshal(data, &rec); err != nil || time.Now().After(rec.ExpiresAt) {
+		return false
+	}
+
+	switch {
+	case rec.Fingerprint != fingerprint:
+		m.mismatches.Add(1)
+		writeIdempotencyError(w, http.StatusUnprocessableEntity, "Idempotency-Key was used for a different request")
+	case rec.Status == 0:
+		m.inFlight.Add(1)
+		w.Header().Set("Retry-After", "1")
+		writeIdempotencyError(w, http.StatusConflict, "a request with this Idempotency-Key is still being handled")
+	default:
+		m.replayed.Add(1)
+		for h, v := range rec.Header {
+			w.Header().Set(h, v)
+		}
+		w.Header().Set(IdempotentReplayedHeader, "true")
+		w.WriteHeader(rec.Status)
+		_, _ = w.Write(rec.Body)
+	}
+	return true
+}
+
+// routeTTL returns the TTL of the longest route pattern matching a
+// request, or the default TTL.
+func (m *Idempotency) routeTTL(method, urlPath string) time.Duration {
+	ttl, longest := m.ttl, -1
+	for route, routeTTL := range m.routes {
+		routeMethod, pattern, _ := strings.Cut(route, " ")
+		if !strings.EqualFold(routeMethod, method) || len(pattern) <= longest {
+			continue
+		}
+		if ok, _ := path.Match(pattern, urlPath); ok {
+			ttl, longest = routeTTL, len(pattern)
+		}
+	}
+	return ttl
+}
+
+// idempotencyStoreKey scopes a client key to its tenant and actor. The
+// hash keeps it within the characters bucket keys allow.
+func idempotencyStoreKey(tenantID, actor, key string) string {
+	sum := sha256.Sum256([]byte(tenantID + "\x00" + actor + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// idempotencyFingerprint identifies a request by method, URI and body.
+func idempotencyFingerprint(method, uri string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + uri + "\x00"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeIdempotencyError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	data, _ := json.Marshal(map[string]string{"error": msg})
+	_, _ = w.Write(data)
+}
+
+// recordingWriter keeps the status, the hash and up to max bytes of the
+// body of a response while writing it.
+type recordingWriter struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	hash     hash.Hash
+	max      int
+	overflow bool
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	rw.status = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(p []byte) (int, error) {
+	rw.hash.Write(p)
+	if !rw.overflow {
+		if rw.body.Len()+len(p) > rw.max {
+			rw.overflow = true
+		} else {
+			rw.body.Write(p)
+		}
+	}
+	return rw.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush server-sent events.
+func (rw *recordingWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,281 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+)
+
+// memoryKV is an in-memory messagequeue.KeyValue.
+type memoryKV struct {
+	mu     sync.Mutex
+	values map[string][]byte
+	revs   map[string]uint64
+	rev    uint64
+}
+
+func newMemoryKV() *memoryKV {
+	return &memoryKV{values: make(map[string][]byte), revs: make(map[string]uint64)}
+}
+
+func (kv *memoryKV) Put(_ context.Context, key string, value []byte) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.set(key, value)
+	return nil
+}
+
+func (kv *memoryKV) set(key string, value []byte) {
+	kv.values[key] = value
+	kv.rev++
+	kv.revs[key] = kv.rev
+}
+
+func (kv *memoryKV) GetRevision(_ context.Context, key string) ([]byte, uint64, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	return kv.values[key], kv.revs[key], nil
+}
+
+func (kv *memoryKV) Update(_ context.Context, key string, value []byte, revision uint64) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	if kv.revs[key] != revision {
+		return messagequeue.ErrKeyChanged
+	}
+	kv.set(key, value)
+	return nil
+}
+
+func (kv *memoryKV) Get(_ context.Context, key string) ([]byte, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	return kv.values[key], nil
+}
+
+func (kv *memoryKV) Create(_ context.Context, key string, value []byte) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	if _, ok := kv.values[key]; ok {
+		return messagequeue.ErrKeyExists
+	}
+	kv.set(key, value)
+	return nil
+}
+
+func (kv *memoryKV) Delete(_ context.Context, key string) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	delete(kv.values, key)
+	delete(kv.revs, key)
+	return nil
+}
+
+func (kv *memoryKV) Watch(context.Context, func(string, []byte)) (func(), error) {
+	return func() {}, nil
+}
+
+func newTestIdempotency(maxBody int, routes map[string]time.Duration) (*Idempotency, *memoryKV) {
+	kv := newMemoryKV()
+	m := NewIdempotency(time.Hour, maxBody, routes)
+	m.SetKeyValue(kv)
+	return m, kv
+}
+
+func idempotentRequest(method, target, key, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	return req
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	m, _ := newTestIdempotency(1<<20, nil)
+	calls := 0
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/api/v1/runs/r1")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"r1"}`))
+	}))
+
+	for i := range 2 {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, idempotentRequest(http.MethodPost, "/api/v1/runs", "k1", `{"task":"t1"}`))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("request %d: expected 201, got %d", i+1, rec.Code)
+		}
+		if rec.Body.String() != `{"id":"r1"}` {
+			t.Errorf("request %d: unexpected body %q", i+1, rec.Body.String())
+		}
+		if rec.Header().Get("Location") != "/api/v1/runs/r1" {
+			t.Errorf("request %d: expected Location header, got %q", i+1, rec.Header().Get("Location"))
+		}
+		replayed := rec.Header().Get(IdempotentReplayedHeader) == "true"
+		if replayed != (i == 1) {
+			t.Errorf("request %d: replayed = %v", i+1, replayed)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected handler to run once, ran %d times", calls)
+	}
+	stats := m.Stats()
+	if stats.Stored != 1 || stats.Replayed != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestIdempotencyRejectsReuseForDifferentRequest(t *testing.T) {
+	m, _ := newTestIdempotency(1<<20, nil)
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest(http.MethodPost, "/api/v1/runs", "k1", `{"task":"t1"}`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, idempotentRequest(http.MethodPost, "/api/v1/runs", "k1", `{"task":"t2"}`))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422, got %d", rec.Code)
+	}
+	if m.Stats().Mismatches != 1 {
+		t.Errorf("expected 1 mismatch, got %d", m.Stats().Mismatches)
+	}
+}
+
+func TestIdempotencyRejectsRetryWhileInFlight(t *testing.T) {
+	m, _ := newTestIdempotency(1<<20, nil)
+	var rec *httptest.ResponseRecorder
+	var handler http.Handler
+	handler = m.Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// Retry while the first request is still being handled.
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, idempotentRequest(http.MethodPost, "/api/v1/runs", "k1", `{}`))
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	outer := httptest.NewRecorder()
+	handler.ServeHTTP(outer, idempotentRequest(http.MethodPost, "/api/v1/runs", "k1", `{}`))
+	if outer.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", outer.Code)
+	}
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for the retry, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+}
+
+func TestIdempotencyDoesNotStoreServerErrors(t *testing.T) {
+	m, kv := newTestIdempotency(1<<20, nil)
+	calls := 0
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+
+	for range 2 {
+		handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest(http.MethodPost, "/api/v1/runs", "k1", `{}`))
+	}
+	if calls != 2 {
+		t.Errorf("expected handler to run twice, ran %d times", calls)
+	}
+	if len(kv.values) != 0 {
+		t.Errorf("expected key to be released, got %d stored", len(kv.values))
+	}
+}
+
+func TestIdempotencyReleasesKeyOnPanic(t *testing.T) {
+	m, kv := newTestIdempotency(1<<20, nil)
+	handler := m.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}))
+
+	func() {
+		defer func() { _ = recover() }()
+		handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest(http.MethodPost, "/api/v1/runs", "k1", `{}`))
+	}()
+	if len(kv.values) != 0 {
+		t.Errorf("expected key to be released, got %d stored", len(kv.values))
+	}
+}
+
+func TestIdempotencyIgnoresRequestsWithoutKey(t *testing.T) {
+	m, kv := newTestIdempotency(1<<20, nil)
+	calls := 0
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest(http.MethodPost, "/api/v1/runs", "", `{}`))
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest(http.MethodGet, "/api/v1/runs", "k1", ""))
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest(http.MethodGet, "/api/v1/runs", "k1", ""))
+	if calls != 3 {
+		t.Errorf("expected 3 handler calls, got %d", calls)
+	}
+	if len(kv.values) != 0 {
+		t.Errorf("expected nothing stored, got %d", len(kv.values))
+	}
+}
+
+func TestIdempotencyRouteTTL(t *testing.T) {
+	m, kv := newTestIdempotency(1<<20, map[string]time.Duration{
+		"POST /api/v1/runs/*":        48 * time.Hour,
+		"POST /api/v1/runs/*/cancel": 0,
+	})
+	if got := m.routeTTL(http.MethodPost, "/api/v1/runs/r1"); got != 48*time.Hour {
+		t.Errorf("expected 48h, got %v", got)
+	}
+	if got := m.routeTTL(http.MethodPost, "/api/v1/projects"); got != time.Hour {
+		t.Errorf("expected default TTL, got %v", got)
+	}
+	if got := m.MaxTTL(); got != 48*time.Hour {
+		t.Errorf("expected max TTL 48h, got %v", got)
+	}
+
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest(http.MethodPost, "/api/v1/runs/r1/cancel", "k1", ""))
+	if len(kv.values) != 0 {
+		t.Errorf("expected exempt route not to be stored, got %d", len(kv.values))
+	}
+}
+
+func TestIdempotencyOmitsLargeBodies(t *testing.T) {
+	m, _ := newTestIdempotency(4, nil)
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("too large"))
+	}))
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, idempotentRequest(http.MethodPost, "/api/v1/runs", "k1", `{}`))
+	if first.Body.String() != "too large" {
+		t.Fatalf("expected full body on first request, got %q", first.Body.String())
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, idempotentRequest(http.MethodPost, "/api/v1/runs", "k1", `{}`))
+	if rec.Code != http.StatusCreated {
+		t.Errorf("expected replayed 201, got %d", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("expected empty replayed body, got %q", rec.Body.String())
+	}
+}
+
+func TestIdempotencyStatsNil(t *testing.T) {
+	var m *Idempotency
+	if m.Stats().Enabled {
+		t.Error("expected disabled stats for nil middleware")
+	}
+}
@@ -35,23 +35,35 @@ func NewRateLimiter(rate float64, burst int) *RateLimiter {
 // Handler returns HTTP middleware that enforces per-IP rate limiting.
 func (rl *RateLimiter) Handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := r.RemoteAddr
+		if rl.limit(w, r.RemoteAddr) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
 
-		remaining, retryAfter, allowed := rl.allow(ip)
+// limit takes a token from the bucket of key and sets the rate limit
+// headers. If the bucket is empty it answers 429 and returns false.
+func (rl *RateLimiter) limit(w http.ResponseWriter, key string) bool {
+	remaining, retryAfter, allowed := rl.allow(key)
 
-		w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", remaining))
-		w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", time.Now().Add(time.Second).Unix()))
+	w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", rl.burst))
+	w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", remaining))
+	w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", time.Now().Add(time.Second).Unix()))
 
-		if !allowed {
-			w.Header().Set("Retry-After", fmt.Sprintf("%.0f", math.Ceil(retryAfter)))
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusTooManyRequests)
-			_, _ = w.Write([]byte(`{"error":"rate limit exceeded"}`))
-			return
-		}
+	if !allowed {
+		writeRateLimited(w, retryAfter)
+		return false
+	}
+	return true
+}
 
-		next.ServeHTTP(w, r)
-	})
+// writeRateLimited answers 429, asking the client to retry after the given
+// number of seconds.
+func writeRateLimited(w http.ResponseWriter, retryAfter float64) {
+	w.Header().Set("Retry-After", fmt.Sprintf("%.0f", math.Ceil(retryAfter)))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	_, _ = w.Write([]byte(`{"error":"rate limit exceeded"}`))
 }
 
 // allow checks whether a request from the given IP is allowed.
@@ -0,0 +1,228 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+)
+
+// RateLimitBucket is the key-value bucket of the distributed rate limiter.
+const RateLimitBucket = "codeforge_rate_limits"
+
+// maxRateLimitAttempts bounds the retries of a bucket update that raced
+// with another server. A request losing every attempt is allowed, like one
+// the store failed for: it may still have had tokens.
+const maxRateLimitAttempts = 5
+
+// rateLimitRetryJitter is the longest wait before retrying a bucket update
+// that raced, so servers contending for a hot bucket spread out.
+const rateLimitRetryJitter = 5 * time.Millisecond
+
+// errRateLimitContended is returned when a bucket update lost every attempt.
+var errRateLimitContended = errors.New("rate limit bucket contended")
+
+// RateTier is a sustained request rate with a burst allowance.
+type RateTier struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// refill returns how long an empty bucket of the tier takes to fill.
+func (t RateTier) refill() time.Duration {
+	return time.Duration(float64(t.Burst) / t.RequestsPerSecond * float64(time.Second))
+}
+
+// DistributedRateLimiter is token bucket rate limiting middleware whose
+// buckets live in a key-value bucket shared by all servers, so replicas
+// enforce one limit together. IPHandler runs ahead of Auth and limits every
+// request per client IP at the default tier, so failed and unauthenticated
+// requests are limited too. Handler runs after Auth: a request made with a
+// managed API key takes a token from the key's bucket, if the key has a rate
+// limit, and from the bucket of the tenant the key is bound to, at the
+// tenant's tier. The X-Tenant-ID header never selects a bucket. Requests are
+// allowed if the store fails.
+type DistributedRateLimiter struct {
+	kv          messagequeue.KeyValue
+	def         RateTier
+	tiers       map[string]RateTier
+	tenantTiers map[string]string // Tenant ID -> tier name
+}
+
+// rateBucket is the stored state of a token bucket.
+type rateBucket struct {
+	Tokens    float64   `json:"tokens"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// rateLimit is one bucket a request is held to.
+type rateLimit struct {
+	key  string
+	tier RateTier
+}
+
+// NewDistributedRateLimiter creates the middleware. Tenants listed in
+// tenantTiers get the named tier, all other buckets def. The key-value
+// bucket is set with SetKeyValue before the middleware serves requests.
+func NewDistributedRateLimiter(def RateTier, tiers map[string]RateTier, tenantTiers map[string]string) *DistributedRateLimiter {
+	return &DistributedRateLimiter{def: def, tiers: tiers, tenantTiers: tenantTiers}
+}
+
+// SetKeyValue sets the bucket token buckets are stored in. Its values
+// should expire after MaxRefill.
+func (rl *DistributedRateLimiter) SetKeyValue(kv messagequeue.KeyValue) {
+	rl.kv = kv
+}
+
+// MaxRefill returns the longest time a bucket takes to fill. A bucket not
+// used for that long is full and its state can be dropped.
+func (rl *DistributedRateLimiter) MaxRefill() time.Duration {
+	// Managed API keys refill their per-minute limit in a minute.
+	longest := max(rl.def.refill(), time.Minute)
+	for _, tier := range rl.tiers {
+		longest = max(longest, tier.refill())
+	}
+	return longest
+}
+
+// IPHandler returns the middleware limiting requests per client IP. It must
+// run before Auth.
+func (rl *DistributedRateLimiter) IPHandler(next http.Handler) http.Handler {
+	return rl.limit(next, rl.ipLimits)
+}
+
+// Handler returns the middleware limiting requests per API key and tenant.
+// It must run after Auth.
+func (rl *DistributedRateLimiter) Handler(next http.Handler) http.Handler {
+	return rl.limit(next, rl.credentialLimits)
+}
+
+// limit returns middleware taking a token from each bucket limitsOf returns.
+func (rl *DistributedRateLimiter) limit(next http.Handler, limitsOf func(*http.Request) []rateLimit) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		now := time.Now()
+
+		var tightest *rateLimit
+		var remaining float64
+		for _, l := range limitsOf(r) {
+			tokens, allowed, err := rl.take(ctx, l, now)
+			if err != nil {
+				slog.Warn("distributed rate limit unavailable, allowing request", "error", err)
+				continue
+			}
+			if !allowed {
+				setRateLimitHeaders(w, l.tier, tokens, now)
+				writeRateLimited(w, (1-tokens)/l.tier.RequestsPerSecond)
+				return
+			}
+			if tightest == nil || tokens < remaining {
+				tightest, remaining = &l, tokens
+			}
+		}
+		if tightest != nil {
+			setRateLimitHeaders(w, tightest.tier, remaining, now)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ipLimits returns the bucket of the request's client IP.
+func (rl *DistributedRateLimiter) ipLimits(r *http.Request) []rateLimit {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return []rateLimit{{key: rateBucketKey("ip", ip), tier: rl.def}}
+}
+
+// credentialLimits returns the buckets of the request's managed API key and
+// of the tenant the key is bound to. Requests without a managed key are
+// only limited per client IP.
+func (rl *DistributedRateLimiter) credentialLimits(r *http.Request) []rateLimit {
+	k := APIKey(r.Context())
+	if k == nil {
+		return nil
+	}
+	var limits []rateLimit
+	if k.RateLimit > 0 {
+		limits = append(limits, rateLimit{
+			key:  rateBucketKey("key", k.ID),
+			tier: RateTier{RequestsPerSecond: float64(k.RateLimit) / 60, Burst: k.RateLimit},
+		})
+	}
+	if id := k.Tenant(); id != "" {
+		tier := rl.def
+		if t, ok := rl.tiers[rl.tenantTiers[id]]; ok {
+			tier = t
+		}
+		limits = append(limits, rateLimit{key: rateBucketKey("tenant", id), tier: tier})
+	}
+	return limits
+}
+
+// take takes a token from a bucket, retrying after a random wait when
+// another server updated it concurrently. It returns the tokens left and
+// whether one was taken, or errRateLimitContended if every attempt raced.
+func (rl *DistributedRateLimiter) take(ctx context.Context, l rateLimit, now time.Time) (float64, bool, error) {
+	for attempt := range maxRateLimitAttempts {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return 0, false, ctx.Err()
+			case <-time.After(rand.N(rateLimitRetryJitter)):
+			}
+		}
+		data, rev, err := rl.kv.GetRevision(ctx, l.key)
+		if err != nil {
+			return 0, false, err
+		}
+		b := rateBucket{Tokens: float64(l.tier.Burst)}
+		if data != nil && json.Unmarshal(data, &b) == nil {
+			// Clocks of other servers may be slightly ahead.
+			elapsed := max(now.Sub(b.UpdatedAt).Seconds(), 0)
+			b.Tokens = min(b.Tokens+elapsed*l.tier.RequestsPerSecond, float64(l.tier.Burst))
+		}
+		if b.Tokens < 1 {
+			return b.Tokens, false, nil
+		}
+
+		b.Tokens--
+		b.UpdatedAt = now
+		data, _ = json.Marshal(b)
+		err = rl.kv.Update(ctx, l.key, data, rev)
+		if errors.Is(err, messagequeue.ErrKeyChanged) {
+			continue
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		return b.Tokens, true, nil
+	}
+	return 0, false, errRateLimitContended
+}
+
+// setRateLimitHeaders reports the state of a bucket: its burst, the
+// tokens left and when it is full again.
+func setRateLimitHeaders(w http.ResponseWriter, tier RateTier, tokens float64, now time.Time) {
+	full := now.Add(time.Duration((float64(tier.Burst) - tokens) / tier.RequestsPerSecond * float64(time.Second)))
+	w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", tier.Burst))
+	w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", int(max(tokens, 0))))
+	w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", full.Unix()))
+}
+
+// rateBucketKey returns the key of a bucket. The hash keeps client
+// addresses and IDs within the characters bucket keys allow.
+func rateBucketKey(kind, id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return kind + "." + hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,198 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/apikey"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+)
+
+func newDistributedTestLimiter(kv *memoryKV) *DistributedRateLimiter {
+	rl := NewDistributedRateLimiter(
+		RateTier{RequestsPerSecond: 0.001, Burst: 2},
+		map[string]RateTier{"enterprise": {RequestsPerSecond: 0.001, Burst: 4}},
+		map[string]string{"acme": "enterprise"},
+	)
+	rl.SetKeyValue(kv)
+	return rl
+}
+
+func distributedCodes(handler http.Handler, n int, prepare func(*http.Request) *http.Request) []int {
+	codes := make([]int, 0, n)
+	for range n {
+		req := prepare(httptest.NewRequest(http.MethodGet, "/api/v1/projects", http.NoBody))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+	}
+	return codes
+}
+
+// withTenant authenticates the request with a managed key bound to the tenant.
+func withTenant(id string) func(*http.Request) *http.Request {
+	return func(r *http.Request) *http.Request {
+		key := &apikey.Key{ID: "key-" + id, TenantID: id}
+		ctx := context.WithValue(tenant.NewContext(r.Context(), id), apiKeyKey{}, key)
+		return r.WithContext(ctx)
+	}
+}
+
+func countOK(codes []int) int {
+	n := 0
+	for _, c := range codes {
+		if c == http.StatusOK {
+			n++
+		}
+	}
+	return n
+}
+
+func TestDistributedRateLimiterSharesBucketsBetweenServers(t *testing.T) {
+	kv := newMemoryKV()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	server1 := newDistributedTestLimiter(kv).Handler(ok)
+	server2 := newDistributedTestLimiter(kv).Handler(ok)
+
+	codes := append(distributedCodes(server1, 1, withTenant("t1")), distributedCodes(server2, 2, withTenant("t1"))...)
+	if countOK(codes) != 2 || codes[2] != http.StatusTooManyRequests {
+		t.Fatalf("expected two requests across servers then 429, got %v", codes)
+	}
+
+	// Other tenants have their own bucket.
+	if codes := distributedCodes(server1, 1, withTenant("t2")); codes[0] != http.StatusOK {
+		t.Errorf("expected another tenant to be allowed, got %v", codes)
+	}
+}
+
+func TestDistributedRateLimiterTenantTier(t *testing.T) {
+	handler := newDistributedTestLimiter(newMemoryKV()).Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	codes := distributedCodes(handler, 5, withTenant("acme"))
+	if countOK(codes) != 4 || codes[4] != http.StatusTooManyRequests {
+		t.Fatalf("expected the enterprise burst of 4 then 429, got %v", codes)
+	}
+}
+
+func TestDistributedRateLimiterAPIKey(t *testing.T) {
+	handler := newDistributedTestLimiter(newMemoryKV()).Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	key := &apikey.Key{ID: "k1", RateLimit: 1, TenantID: "acme"}
+	codes := distributedCodes(handler, 2, func(r *http.Request) *http.Request {
+		ctx := context.WithValue(tenant.NewContext(r.Context(), "acme"), apiKeyKey{}, key)
+		return r.WithContext(ctx)
+	})
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+		t.Fatalf("expected the key's limit of 1 to apply within the tenant's tier, got %v", codes)
+	}
+}
+
+func TestDistributedRateLimiterIgnoresUnboundTenant(t *testing.T) {
+	handler := newDistributedTestLimiter(newMemoryKV()).Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// A tenant named in the header only, with no key bound to it, has no
+	// bucket to exhaust or to pick a tier from.
+	codes := distributedCodes(handler, 5, func(r *http.Request) *http.Request {
+		return r.WithContext(tenant.NewContext(r.Context(), "acme"))
+	})
+	if countOK(codes) != 5 {
+		t.Fatalf("expected requests without a managed key to be left to the IP limit, got %v", codes)
+	}
+}
+
+func TestDistributedRateLimiterClientIP(t *testing.T) {
+	handler := newDistributedTestLimiter(newMemoryKV()).IPHandler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	fromIP := func(ip string) func(*http.Request) *http.Request {
+		return func(r *http.Request) *http.Request {
+			r.RemoteAddr = ip + ":1234"
+			return r
+		}
+	}
+	if codes := distributedCodes(handler, 3, fromIP("10.0.0.1")); codes[2] != http.StatusTooManyRequests {
+		t.Errorf("expected 429 after the default burst, got %v", codes)
+	}
+	if codes := distributedCodes(handler, 1, fromIP("10.0.0.2")); codes[0] != http.StatusOK {
+		t.Errorf("expected another client to be allowed, got %v", codes)
+	}
+}
+
+func TestDistributedRateLimiterHeaders(t *testing.T) {
+	handler := newDistributedTestLimiter(newMemoryKV()).Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := withTenant("acme")(httptest.NewRequest(http.MethodGet, "/api/v1/projects", http.NoBody))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got := rec.Header().Get("X-RateLimit-Limit"); got != "4" {
+		t.Errorf("expected X-RateLimit-Limit 4, got %q", got)
+	}
+	if got := rec.Header().Get("X-RateLimit-Remaining"); got != "3" {
+		t.Errorf("expected X-RateLimit-Remaining 3, got %q", got)
+	}
+	if rec.Header().Get("X-RateLimit-Reset") == "" {
+		t.Error("expected X-RateLimit-Reset header")
+	}
+}
+
+// failingKV fails every read.
+type failingKV struct{ memoryKV }
+
+func (*failingKV) GetRevision(context.Context, string) ([]byte, uint64, error) {
+	return nil, 0, errors.New("store unavailable")
+}
+
+func TestDistributedRateLimiterAllowsWhenStoreFails(t *testing.T) {
+	rl := NewDistributedRateLimiter(RateTier{RequestsPerSecond: 0.001, Burst: 1}, nil, nil)
+	rl.SetKeyValue(&failingKV{})
+	handler := rl.Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	if codes := distributedCodes(handler, 3, withTenant("t1")); countOK(codes) != 3 {
+		t.Errorf("expected requests to be allowed, got %v", codes)
+	}
+}
+
+// contendedKV loses every bucket update to another server.
+type contendedKV struct{ *memoryKV }
+
+func (*contendedKV) Update(context.Context, string, []byte, uint64) error {
+	return messagequeue.ErrKeyChanged
+}
+
+func TestDistributedRateLimiterAllowsWhenContended(t *testing.T) {
+	rl := NewDistributedRateLimiter(RateTier{RequestsPerSecond: 0.001, Burst: 1}, nil, nil)
+	rl.SetKeyValue(&contendedKV{newMemoryKV()})
+	handler := rl.Handler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	if codes := distributedCodes(handler, 3, withTenant("t1")); countOK(codes) != 3 {
+		t.Errorf("expected requests losing every update to be allowed, got %v", codes)
+	}
+}
+
+func TestDistributedRateLimiterMaxRefill(t *testing.T) {
+	rl := NewDistributedRateLimiter(
+		RateTier{RequestsPerSecond: 10, Burst: 100},
+		map[string]RateTier{"slow": {RequestsPerSecond: 1, Burst: 120}},
+		nil,
+	)
+	if got := rl.MaxRefill().Seconds(); got != 120 {
+		t.Errorf("expected 120s, got %vs", got)
+	}
+}
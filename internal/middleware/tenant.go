@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+)
+
+const headerTenantID = "X-Tenant-ID"
+
+// Tenant is HTTP middleware that scopes a request to the tenant named in
+// the X-Tenant-ID header. Database sessions of a scoped request only see
+// that tenant's rows. Auth already scopes requests made with a managed key
+// to the key's tenant, and such a request may only name that tenant. The
+// header can only narrow the credentials that act for all tenants (the
+// configured keys and untenanted admin keys) and requests while auth is
+// disabled; without it they see all tenants.
+func Tenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(headerTenantID)
+		if scoped := tenant.FromContext(r.Context()); scoped != "" && id != "" && id != scoped {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error":"API key is scoped to another tenant"}`))
+			return
+		}
+		if id == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !tenant.ValidID(id) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid X-Tenant-ID header"}`))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(tenant.NewContext(r.Context(), id)))
+	})
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+)
+
+func TestTenant(t *testing.T) {
+	var got string
+	handler := Tenant(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = tenant.FromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range []struct {
+		header string
+		want   int
+		tenant string
+	}{
+		{"", http.StatusOK, ""},
+		{"acme", http.StatusOK, "acme"},
+		{"Acme Corp", http.StatusBadRequest, ""},
+	} {
+		got = ""
+		req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		if tt.header != "" {
+			req.Header.Set("X-Tenant-ID", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want || got != tt.tenant {
+			t.Errorf("X-Tenant-ID %q: expected %d/%q, got %d/%q", tt.header, tt.want, tt.tenant, rec.Code, got)
+		}
+	}
+}
+
+func TestTenant_KeyScoped(t *testing.T) {
+	handler := Tenant(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for _, tt := range []struct {
+		header string
+		want   int
+	}{
+		{"", http.StatusOK},
+		{"acme", http.StatusOK},
+		{"globex", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		req = req.WithContext(tenant.NewContext(req.Context(), "acme"))
+		if tt.header != "" {
+			req.Header.Set("X-Tenant-ID", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("X-Tenant-ID %q: expected %d, got %d", tt.header, tt.want, rec.Code)
+		}
+	}
+}
@@ -3,17 +3,56 @@ package agentbackend
 
 import (
 	"context"
+	"slices"
 
 	"github.com/Strob0t/CodeForge/internal/domain/task"
 )
 
-// Capabilities declares which operations an agent backend supports.
+// Capabilities declares which operations an agent backend supports and
+// what work it suits, for automatic backend selection.
 type Capabilities struct {
 	Edit     bool `json:"edit"`
 	Terminal bool `json:"terminal"`
 	Browser  bool `json:"browser"`
 	Planner  bool `json:"planner"`
 	Review   bool `json:"review"`
+
+	Languages    []string `json:"languages,omitempty"`      // Stacks handled well ("go", "python", "node"); empty for none in particular
+	MaxDiffLines int      `json:"max_diff_lines,omitempty"` // Largest change it handles reliably; 0 for no limit
+	MCPTools     bool     `json:"mcp_tools"`                // Can call tools of MCP servers
+	CostTier     int      `json:"cost_tier"`                // 1 (cheap) to 3 (expensive)
+}
+
+// Requirements describe what a plan step needs from a backend.
+type Requirements struct {
+	Stacks    []string // Stacks detected in the project
+	DiffLines int      // Estimated size of the change; 0 if unknown
+	MCPTools  bool     // The step uses tools of MCP servers
+}
+
+// Fits reports whether a backend with these capabilities can take a step
+// with requirements r: it edits code, offers MCP tools if needed and
+// handles changes of the estimated size.
+func (c *Capabilities) Fits(r *Requirements) bool {
+	if !c.Edit || (r.MCPTools && !c.MCPTools) {
+		return false
+	}
+	return c.MaxDiffLines == 0 || r.DiffLines <= c.MaxDiffLines
+}
+
+// Score rates how well a fitting backend suits r: 2 per detected stack
+// among its languages (1 per stack if it lists none), minus its cost tier.
+func (c *Capabilities) Score(r *Requirements) int {
+	score := -c.CostTier
+	for _, st := range r.Stacks {
+		switch {
+		case len(c.Languages) == 0:
+			score++
+		case slices.Contains(c.Languages, st):
+			score += 2
+		}
+	}
+	return score
 }
 
 // Backend is the port interface for interacting with a coding agent backend.
@@ -0,0 +1,40 @@
+package agentbackend_test
+
+import (
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/port/agentbackend"
+)
+
+func TestCapabilitiesFits(t *testing.T) {
+	c := agentbackend.Capabilities{Edit: true, MaxDiffLines: 100}
+	for _, tc := range []struct {
+		name string
+		req  agentbackend.Requirements
+		want bool
+	}{
+		{"small change", agentbackend.Requirements{DiffLines: 50}, true},
+		{"unknown size", agentbackend.Requirements{}, true},
+		{"too large", agentbackend.Requirements{DiffLines: 101}, false},
+		{"needs MCP", agentbackend.Requirements{MCPTools: true}, false},
+	} {
+		if got := c.Fits(&tc.req); got != tc.want {
+			t.Errorf("%s: Fits = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+	if (&agentbackend.Capabilities{}).Fits(&agentbackend.Requirements{}) {
+		t.Error("expected a backend that cannot edit not to fit")
+	}
+}
+
+func TestCapabilitiesScore(t *testing.T) {
+	r := &agentbackend.Requirements{Stacks: []string{"go", "node"}}
+	specialist := agentbackend.Capabilities{Edit: true, Languages: []string{"go"}, CostTier: 2}
+	generalist := agentbackend.Capabilities{Edit: true, CostTier: 1}
+	if got := specialist.Score(r); got != 0 {
+		t.Errorf("specialist score = %d, want 0", got)
+	}
+	if got := generalist.Score(r); got != 1 {
+		t.Errorf("generalist score = %d, want 1", got)
+	}
+}
@@ -48,3 +48,13 @@ func Available() []string {
 	}
 	return names
 }
+
+// Describe returns the capabilities of a registered backend, created
+// without configuration, and false if it is unknown or cannot be created.
+func Describe(name string) (Capabilities, bool) {
+	b, err := New(name, nil)
+	if err != nil {
+		return Capabilities{}, false
+	}
+	return b.Capabilities(), true
+}
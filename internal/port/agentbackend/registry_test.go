@@ -52,3 +52,16 @@ func TestAvailable(t *testing.T) {
 		t.Fatal("expected test-agent in available backends")
 	}
 }
+
+func TestDescribe(t *testing.T) {
+	agentbackend.Register("describe-agent", func(_ map[string]string) (agentbackend.Backend, error) {
+		return &testBackend{name: "describe-agent"}, nil
+	})
+	caps, ok := agentbackend.Describe("describe-agent")
+	if !ok || !caps.Edit {
+		t.Fatalf("expected the backend's capabilities, got %+v %v", caps, ok)
+	}
+	if _, ok := agentbackend.Describe("nonexistent"); ok {
+		t.Error("expected no capabilities for an unknown backend")
+	}
+}
@@ -0,0 +1,50 @@
+package auditsink
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Factory creates a Sink from a sink's config (audit.Sink.Config).
+type Factory func(config map[string]string) (Sink, error)
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register makes a sink factory available for a sink kind.
+// It is typically called from an init() function in the adapter package.
+func Register(kind string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, exists := factories[kind]; exists {
+		panic(fmt.Sprintf("auditsink: duplicate registration for %q", kind))
+	}
+	factories[kind] = factory
+}
+
+// New creates a Sink by kind using the registered factory.
+func New(kind string, config map[string]string) (Sink, error) {
+	mu.RLock()
+	factory, ok := factories[kind]
+	mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("auditsink: unknown kind %q", kind)
+	}
+	return factory(config)
+}
+
+// Available returns all registered kinds.
+func Available() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	return names
+}
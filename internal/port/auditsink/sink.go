@@ -0,0 +1,20 @@
+// Package auditsink defines the port for exporting audit entries to
+// external systems such as a SIEM.
+package auditsink
+
+import (
+	"context"
+
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+)
+
+// Sink delivers audit entries to an external system.
+type Sink interface {
+	// Send delivers entries in order. It returns nil only when the
+	// system accepted all of them; on error the whole batch is sent again
+	// later, so the system may see entries more than once.
+	Send(ctx context.Context, entries []event.AuditEntry) error
+
+	// Close releases the sink's connections.
+	Close() error
+}
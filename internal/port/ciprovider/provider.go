@@ -0,0 +1,25 @@
+// Package ciprovider defines the CI provider port (interface) through which
+// the workflow runs and pipelines of the commits CodeForge pushes are read.
+package ciprovider
+
+import (
+	"context"
+
+	"github.com/Strob0t/CodeForge/internal/domain/ci"
+)
+
+// ConfigProvider is the project config key naming the registered CI
+// provider, e.g. "github-actions". Without it the CI provider of the
+// project's git host is used. Providers get the project config with the
+// git host's API token resolved, like hosted git providers.
+const ConfigProvider = "ci_provider"
+
+// Provider is the port interface for reading CI results from a CI system.
+type Provider interface {
+	// Name returns the unique identifier for this provider (e.g. "gitlab-ci").
+	Name() string
+
+	// ListPipelines returns the workflow runs or pipelines of a commit on
+	// a branch. Either may be empty to match any.
+	ListPipelines(ctx context.Context, branch, sha string) ([]ci.Pipeline, error)
+}
@@ -0,0 +1,42 @@
+package ciprovider_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/ci"
+	"github.com/Strob0t/CodeForge/internal/port/ciprovider"
+)
+
+type testProvider struct {
+	name string
+}
+
+func (p *testProvider) Name() string { return p.name }
+func (p *testProvider) ListPipelines(_ context.Context, _, _ string) ([]ci.Pipeline, error) {
+	return nil, nil
+}
+
+func TestRegisterAndNew(t *testing.T) {
+	ciprovider.Register("test-ci", func(_ map[string]string) (ciprovider.Provider, error) {
+		return &testProvider{name: "test-ci"}, nil
+	})
+
+	p, err := ciprovider.New("test-ci", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name() != "test-ci" {
+		t.Fatalf("expected test-ci, got %s", p.Name())
+	}
+	if !slices.Contains(ciprovider.Available(), "test-ci") {
+		t.Fatal("expected test-ci in available providers")
+	}
+}
+
+func TestNewUnknownProvider(t *testing.T) {
+	if _, err := ciprovider.New("nonexistent", nil); err == nil {
+		t.Fatal("expected error for unknown provider")
+	}
+}
@@ -0,0 +1,19 @@
+package database
+
+import "context"
+
+type staleReadsKey struct{}
+
+// WithStaleReads returns a context whose list and cost queries may be
+// served by a read replica, which can lag behind the primary by the
+// configured tolerance. Callers that act on what they read, such as run
+// bookkeeping, must not use it.
+func WithStaleReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, staleReadsKey{}, true)
+}
+
+// StaleReadsAllowed reports whether the context tolerates replica reads.
+func StaleReadsAllowed(ctx context.Context) bool {
+	v, _ := ctx.Value(staleReadsKey{}).(bool)
+	return v
+}
@@ -3,13 +3,43 @@ package database
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/Strob0t/CodeForge/internal/domain/agent"
+	"github.com/Strob0t/CodeForge/internal/domain/apikey"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/domain/audit"
+	"github.com/Strob0t/CodeForge/internal/domain/benchmark"
+	"github.com/Strob0t/CodeForge/internal/domain/chatops"
+	"github.com/Strob0t/CodeForge/internal/domain/ci"
+	"github.com/Strob0t/CodeForge/internal/domain/codegraph"
 	cfcontext "github.com/Strob0t/CodeForge/internal/domain/context"
+	"github.com/Strob0t/CodeForge/internal/domain/convention"
+	"github.com/Strob0t/CodeForge/internal/domain/conversation"
+	"github.com/Strob0t/CodeForge/internal/domain/cost"
+	"github.com/Strob0t/CodeForge/internal/domain/experience"
+	"github.com/Strob0t/CodeForge/internal/domain/featureflag"
+	"github.com/Strob0t/CodeForge/internal/domain/issuerun"
+	"github.com/Strob0t/CodeForge/internal/domain/knowledge"
+	"github.com/Strob0t/CodeForge/internal/domain/mcpserver"
+	"github.com/Strob0t/CodeForge/internal/domain/memory"
+	"github.com/Strob0t/CodeForge/internal/domain/microagent"
+	"github.com/Strob0t/CodeForge/internal/domain/notification"
 	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/poll"
 	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/replay"
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+	"github.com/Strob0t/CodeForge/internal/domain/roadmap"
 	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/scope"
+	"github.com/Strob0t/CodeForge/internal/domain/seatbelt"
+	"github.com/Strob0t/CodeForge/internal/domain/secret"
+	"github.com/Strob0t/CodeForge/internal/domain/skill"
 	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/domain/vcsaccount"
 )
 
 // Store is the port interface for database operations.
@@ -21,12 +51,21 @@ type Store interface {
 	UpdateProject(ctx context.Context, p *project.Project) error
 	DeleteProject(ctx context.Context, id string) error
 
+	// Sub-Projects
+	CreateSubProject(ctx context.Context, sp *project.SubProject) error
+	GetSubProject(ctx context.Context, id string) (*project.SubProject, error)
+	ListSubProjects(ctx context.Context, projectID string) ([]project.SubProject, error)
+	DeleteSubProject(ctx context.Context, id string) error
+
 	// Agents
 	ListAgents(ctx context.Context, projectID string) ([]agent.Agent, error)
 	GetAgent(ctx context.Context, id string) (*agent.Agent, error)
 	CreateAgent(ctx context.Context, projectID, name, backend string, config map[string]string) (*agent.Agent, error)
 	UpdateAgentStatus(ctx context.Context, id string, status agent.Status) error
+	SetAgentSubProject(ctx context.Context, id, subProjectID string) error
 	DeleteAgent(ctx context.Context, id string) error
+	CloneAgent(ctx context.Context, templateID, name string) (*agent.Agent, error)
+	ListAgentClones(ctx context.Context, templateID string) ([]agent.Agent, error)
 
 	// Tasks
 	ListTasks(ctx context.Context, projectID string) ([]task.Task, error)
@@ -41,6 +80,25 @@ type Store interface {
 	UpdateRunStatus(ctx context.Context, id string, status run.Status, stepCount int, costUSD float64) error
 	CompleteRun(ctx context.Context, id string, status run.Status, output, errMsg string, costUSD float64, stepCount int) error
 	ListRunsByTask(ctx context.Context, taskID string) ([]run.Run, error)
+	ListRunsByTasks(ctx context.Context, taskIDs []string) ([]run.Run, error)
+	ListActiveRuns(ctx context.Context, projectID string) ([]run.Run, error)
+	GetRuns(ctx context.Context, ids []string) ([]run.Run, error)
+	SetRunWorktree(ctx context.Context, id, path string) error
+	AddRunRedactions(ctx context.Context, id string, n int) error
+	AddRunTokens(ctx context.Context, id string, tokensIn, tokensOut int) error
+	SetRunStructuredOutput(ctx context.Context, id string, output json.RawMessage) error
+	SetRunSummary(ctx context.Context, id, summary string) error
+	ListRunsWithWorktree(ctx context.Context, completedBefore time.Time) ([]run.Run, error)
+	ListRunsForEventArchival(ctx context.Context, projectID string, completedBefore time.Time, limit int) ([]run.Run, error)
+	SetRunEventsArchived(ctx context.Context, id string) error
+	SetRunInterrupted(ctx context.Context, id string, interrupted bool) error
+	ClaimInterruptedRun(ctx context.Context, id string) (bool, error)
+	ListCostSummaries(ctx context.Context, projectID string) ([]cost.Summary, error)
+	AddRunUsage(ctx context.Context, runID, projectID string, u cost.Usage) error
+	ListStepCosts(ctx context.Context, planID string) ([]cost.StepCost, error)
+	ListToolCosts(ctx context.Context, projectID string) ([]cost.Usage, error)
+	RefreshCostRollups(ctx context.Context, from time.Time) error
+	ListDailyCosts(ctx context.Context, projectID string, since time.Time) ([]cost.Day, error)
 
 	// Agent Teams
 	CreateTeam(ctx context.Context, req agent.CreateTeamRequest) (*agent.Team, error)
@@ -59,12 +117,23 @@ type Store interface {
 	UpdatePlanStepStatus(ctx context.Context, stepID string, status plan.StepStatus, runID string, errMsg string) error
 	GetPlanStepByRunID(ctx context.Context, runID string) (*plan.Step, error)
 	UpdatePlanStepRound(ctx context.Context, stepID string, round int) error
+	UpdatePlanStepAttempts(ctx context.Context, stepID string, attempt int, attempts []plan.StepAttempt, retryAt *time.Time) error
+	SetPlanStepApproval(ctx context.Context, stepID string, a *plan.Approval) error
+	SetPlanStepImage(ctx context.Context, stepID string, b *plan.ImageBuild) error
+	ListWaitingApprovals(ctx context.Context, projectID string) ([]plan.Step, error)
+	AddDebateTurn(ctx context.Context, planID, projectID string, t *plan.DebateTurn) error
+	ListDebateTurns(ctx context.Context, planID string) ([]plan.DebateTurn, error)
+	SetDebateSummary(ctx context.Context, planID, projectID string, s *plan.DebateSummary) error
+	GetDebateSummary(ctx context.Context, planID string) (*plan.DebateSummary, error)
 
 	// Context Packs
 	CreateContextPack(ctx context.Context, pack *cfcontext.ContextPack) error
 	GetContextPack(ctx context.Context, id string) (*cfcontext.ContextPack, error)
 	GetContextPackByTask(ctx context.Context, taskID string) (*cfcontext.ContextPack, error)
 	DeleteContextPack(ctx context.Context, id string) error
+	GetContextCuration(ctx context.Context, taskID string) (*cfcontext.Curation, error)
+	SaveContextCuration(ctx context.Context, c *cfcontext.Curation) error
+	DeleteContextCuration(ctx context.Context, taskID string) error
 
 	// Shared Context
 	CreateSharedContext(ctx context.Context, sc *cfcontext.SharedContext) error
@@ -72,4 +141,204 @@ type Store interface {
 	GetSharedContextByTeam(ctx context.Context, teamID string) (*cfcontext.SharedContext, error)
 	AddSharedContextItem(ctx context.Context, req cfcontext.AddSharedItemRequest) (*cfcontext.SharedContextItem, error)
 	DeleteSharedContext(ctx context.Context, id string) error
+
+	// Run Artifacts
+	CreateArtifact(ctx context.Context, a *artifact.Artifact) error
+	GetArtifact(ctx context.Context, id string) (*artifact.Artifact, error)
+	ListArtifactsByRun(ctx context.Context, runID string) ([]artifact.Artifact, error)
+	ListArtifactsByProject(ctx context.Context, projectID string, kind artifact.Kind) ([]artifact.Artifact, error)
+	ListArtifactsByTask(ctx context.Context, taskID string) ([]artifact.Artifact, error)
+
+	// Secrets
+	CreateSecret(ctx context.Context, s *secret.Secret) error
+	GetSecret(ctx context.Context, id string) (*secret.Secret, error)
+	ListSecrets(ctx context.Context, projectID string) ([]secret.Secret, error)
+	UpdateSecretValue(ctx context.Context, id string, ciphertext []byte) error
+	DeleteSecret(ctx context.Context, id string) error
+
+	// Benchmarks
+	CreateBenchmarkRun(ctx context.Context, r *benchmark.Run) error
+	GetBenchmarkRun(ctx context.Context, id string) (*benchmark.Run, error)
+	ListBenchmarkRuns(ctx context.Context, suite string) ([]benchmark.Run, error)
+	UpdateBenchmarkRunStatus(ctx context.Context, id string, status benchmark.Status) error
+	GetBenchmarkResultByStep(ctx context.Context, planStepID string) (*benchmark.Result, error)
+	UpdateBenchmarkResult(ctx context.Context, res *benchmark.Result) error
+
+	// Roadmap
+	CreateFeature(ctx context.Context, f *roadmap.Feature) error
+	GetFeature(ctx context.Context, id string) (*roadmap.Feature, error)
+	GetFeatureByExternalID(ctx context.Context, projectID, provider, externalID string) (*roadmap.Feature, error)
+	ListFeatures(ctx context.Context, projectID string) ([]roadmap.Feature, error)
+	UpdateFeature(ctx context.Context, f *roadmap.Feature) error
+
+	// Tenants
+	CreateTenant(ctx context.Context, t *tenant.Tenant) error
+	GetTenant(ctx context.Context, id string) (*tenant.Tenant, error)
+	ListTenants(ctx context.Context) ([]tenant.Tenant, error)
+	UpdateTenantQuota(ctx context.Context, id string, q tenant.Quota) error
+	SetTenantGitHubApp(ctx context.Context, id string, app *tenant.GitHubApp) error
+	GetTenantUsage(ctx context.Context, id string, since time.Time) (*tenant.Usage, error)
+	GetTenantIsolation(ctx context.Context) (*tenant.IsolationReport, error)
+
+	// Retrieval Index
+	ReplaceRetrievalIndex(ctx context.Context, idx *retrieval.Index, chunks []retrieval.Chunk) error
+	GetRetrievalIndex(ctx context.Context, projectID string) (*retrieval.Index, error)
+	ListRetrievalChunks(ctx context.Context, projectID string) ([]retrieval.Chunk, error)
+	GetStagedRetrievalIndex(ctx context.Context, projectID string) (*retrieval.Index, error)
+	ListStagedRetrievalChunks(ctx context.Context, projectID string) ([]retrieval.Chunk, error)
+	PromoteRetrievalIndex(ctx context.Context, projectID string) error
+	DeleteStagedRetrievalIndex(ctx context.Context, projectID string) error
+
+	// Retrieval Evaluations
+	CreateGoldenQuery(ctx context.Context, q *retrieval.GoldenQuery) error
+	ListGoldenQueries(ctx context.Context, projectID string) ([]retrieval.GoldenQuery, error)
+	DeleteGoldenQuery(ctx context.Context, id string) error
+	CreateRetrievalEval(ctx context.Context, e *retrieval.Eval) error
+	ListRetrievalEvals(ctx context.Context, projectID string, limit int) ([]retrieval.Eval, error)
+
+	// Code Graphs
+	GetCodeGraph(ctx context.Context, projectID string) (*codegraph.State, error)
+	ReplaceCodeGraph(ctx context.Context, st *codegraph.State, files []*codegraph.File, edges []codegraph.Edge) error
+	UpdateCodeGraph(ctx context.Context, st *codegraph.State, u *codegraph.Update) error
+	ListCodeGraphFiles(ctx context.Context, projectID string) ([]*codegraph.File, error)
+	ListCodeGraphEdges(ctx context.Context, projectID string) ([]codegraph.Edge, error)
+	GetCodeGraphNeighbors(ctx context.Context, projectID, path string) (*codegraph.Neighbors, error)
+
+	// Project Conventions
+	GetProjectConventions(ctx context.Context, projectID string) (*convention.Conventions, error)
+	SaveProjectConventions(ctx context.Context, c *convention.Conventions) error
+
+	// Conversations
+	CreateConversation(ctx context.Context, c *conversation.Conversation) error
+	GetConversation(ctx context.Context, id string) (*conversation.Conversation, error)
+	ListConversations(ctx context.Context, projectID string) ([]conversation.Conversation, error)
+	AppendConversationMessage(ctx context.Context, m *conversation.Message) error
+	ListConversationMessages(ctx context.Context, conversationID string) ([]conversation.Message, error)
+
+	// Memories
+	CreateMemory(ctx context.Context, m *memory.Memory) error
+	ListMemories(ctx context.Context, projectID string) ([]memory.Memory, error)
+	DeleteMemory(ctx context.Context, id string) error
+
+	// Experience Pool
+	CreateExperience(ctx context.Context, e *experience.Entry) error
+	ListExperiences(ctx context.Context, projectID string) ([]experience.Entry, error)
+	RateExperience(ctx context.Context, id string, useful bool) (*experience.Entry, error)
+
+	// Skills
+	CreateSkill(ctx context.Context, sk *skill.Skill) error
+	GetSkill(ctx context.Context, id string) (*skill.Skill, error)
+	ListSkills(ctx context.Context, projectID string) ([]skill.Skill, error)
+	DeleteSkill(ctx context.Context, id string) error
+
+	// Run presets
+	CreateRunPreset(ctx context.Context, p *run.Preset) error
+	GetRunPreset(ctx context.Context, projectID, name string) (*run.Preset, error)
+	ListRunPresets(ctx context.Context, projectID string) ([]run.Preset, error)
+	UpdateRunPreset(ctx context.Context, p *run.Preset) error
+	DeleteRunPreset(ctx context.Context, projectID, name string) error
+
+	// Microagents
+	CreateMicroagent(ctx context.Context, m *microagent.Microagent) error
+	GetMicroagent(ctx context.Context, id string) (*microagent.Microagent, error)
+	ListMicroagents(ctx context.Context, projectID string) ([]microagent.Microagent, error)
+	UpdateMicroagent(ctx context.Context, m *microagent.Microagent) error
+	DeleteMicroagent(ctx context.Context, id string) error
+
+	// MCP servers
+	CreateMCPServer(ctx context.Context, m *mcpserver.Server) error
+	GetMCPServer(ctx context.Context, id string) (*mcpserver.Server, error)
+	ListMCPServers(ctx context.Context) ([]mcpserver.Server, error)
+	UpdateMCPServer(ctx context.Context, m *mcpserver.Server) error
+	DeleteMCPServer(ctx context.Context, id string) error
+
+	// API keys
+	CreateAPIKey(ctx context.Context, k *apikey.Key) error
+	GetAPIKey(ctx context.Context, id string) (*apikey.Key, error)
+	GetAPIKeyByHash(ctx context.Context, hash []byte) (*apikey.Key, error)
+	ListAPIKeys(ctx context.Context) ([]apikey.Key, error)
+	UpdateAPIKey(ctx context.Context, k *apikey.Key) error
+	TouchAPIKey(ctx context.Context, id string, at time.Time) error
+	DeleteAPIKey(ctx context.Context, id string) error
+
+	// Audit sinks
+	CreateAuditSink(ctx context.Context, a *audit.Sink) error
+	GetAuditSink(ctx context.Context, id string) (*audit.Sink, error)
+	ListAuditSinks(ctx context.Context) ([]audit.Sink, error)
+	UpdateAuditSink(ctx context.Context, a *audit.Sink) error
+	SetAuditSinkProgress(ctx context.Context, id string, seq int64, errMsg string) error
+	DeleteAuditSink(ctx context.Context, id string) error
+
+	// Knowledge bases
+	CreateKnowledgeBase(ctx context.Context, kb *knowledge.KnowledgeBase) error
+	GetKnowledgeBase(ctx context.Context, id string) (*knowledge.KnowledgeBase, error)
+	ListKnowledgeBases(ctx context.Context) ([]knowledge.KnowledgeBase, error)
+	UpdateKnowledgeBase(ctx context.Context, kb *knowledge.KnowledgeBase) error
+	ReplaceKnowledgeChunks(ctx context.Context, kb *knowledge.KnowledgeBase, chunks []retrieval.Chunk) error
+	SetKnowledgeBaseError(ctx context.Context, id, errMsg string) error
+	ListKnowledgeChunks(ctx context.Context, kbID string) ([]retrieval.Chunk, error)
+	DeleteKnowledgeBase(ctx context.Context, id string) error
+
+	// Feature flags
+	ListFeatureFlags(ctx context.Context) ([]featureflag.Flag, error)
+	SetFeatureFlag(ctx context.Context, f *featureflag.Flag) error
+	DeleteFeatureFlag(ctx context.Context, key featureflag.Key, tenantID, projectID string) error
+
+	// Issue runs
+	CreateIssueRun(ctx context.Context, r *issuerun.IssueRun) error
+	UpdateIssueRun(ctx context.Context, r *issuerun.IssueRun) error
+	GetIssueRunByRun(ctx context.Context, runID string) (*issuerun.IssueRun, error)
+	ListIssueRuns(ctx context.Context, projectID string) ([]issuerun.IssueRun, error)
+
+	// Pull request commands
+	CreateCommandRun(ctx context.Context, c *chatops.CommandRun) error
+	UpdateCommandRun(ctx context.Context, c *chatops.CommandRun) error
+	GetCommandRunByRun(ctx context.Context, runID string) (*chatops.CommandRun, error)
+	ListCommandRuns(ctx context.Context, projectID string) ([]chatops.CommandRun, error)
+
+	// Run deliveries (seatbelt mode)
+	SaveRunDelivery(ctx context.Context, d *seatbelt.Delivery) error
+	UpdateRunDelivery(ctx context.Context, d *seatbelt.Delivery) error
+	GetRunDelivery(ctx context.Context, runID string) (*seatbelt.Delivery, error)
+	ListRunDeliveries(ctx context.Context, projectID string, limit int) ([]seatbelt.Delivery, error)
+
+	// Run CI statuses
+	GetRunCIStatus(ctx context.Context, runID string) (*ci.Status, error)
+	SaveRunCIStatus(ctx context.Context, st *ci.Status) error
+
+	// Poll cursors
+	GetPollCursor(ctx context.Context, projectID string, source poll.Source) (*poll.Cursor, error)
+	SetPollCursor(ctx context.Context, c *poll.Cursor) error
+	ListPollCursors(ctx context.Context, projectID string) ([]poll.Cursor, error)
+
+	// Notifications
+	CreateNotificationRule(ctx context.Context, r *notification.Rule) error
+	GetNotificationRule(ctx context.Context, id string) (*notification.Rule, error)
+	ListNotificationRules(ctx context.Context, projectID string) ([]notification.Rule, error)
+	UpdateNotificationRule(ctx context.Context, r *notification.Rule) error
+	DeleteNotificationRule(ctx context.Context, id string) error
+	GetNotificationSettings(ctx context.Context, projectID string) (*notification.Settings, error)
+	SetNotificationSettings(ctx context.Context, st *notification.Settings) error
+	EnqueueNotification(ctx context.Context, p *notification.Pending) error
+	ListDueNotifications(ctx context.Context, until time.Time, limit int) ([]notification.Pending, error)
+	RetryNotification(ctx context.Context, id string, sendAt time.Time, errMsg string) error
+	DeleteNotifications(ctx context.Context, ids []string) error
+
+	// Replay sessions
+	CreateReplaySession(ctx context.Context, rs *replay.Session) error
+	GetReplaySession(ctx context.Context, id string) (*replay.Session, error)
+	UpdateReplaySessionPosition(ctx context.Context, id string, position int) error
+	DeleteReplaySession(ctx context.Context, id string) error
+
+	// VCS account health
+	GetVCSAccountHealth(ctx context.Context, projectID string) (*vcsaccount.Health, error)
+	SaveVCSAccountHealth(ctx context.Context, h *vcsaccount.Health) error
+	ListVCSAccountHealth(ctx context.Context) ([]vcsaccount.Health, error)
+
+	// Retrieval scopes
+	CreateScope(ctx context.Context, sc *scope.Scope) error
+	GetScope(ctx context.Context, id string) (*scope.Scope, error)
+	ListScopes(ctx context.Context) ([]scope.Scope, error)
+	UpdateScope(ctx context.Context, sc *scope.Scope) error
+	DeleteScope(ctx context.Context, id string) error
 }
@@ -0,0 +1,51 @@
+package docsource
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Factory creates a Source from a knowledge base's config
+// (knowledge.KnowledgeBase.Config), with the resolved token.
+type Factory func(config map[string]string) (Source, error)
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register makes a source factory available for a knowledge base kind.
+// It is typically called from an init() function in the adapter package.
+func Register(kind string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, exists := factories[kind]; exists {
+		panic(fmt.Sprintf("docsource: duplicate registration for %q", kind))
+	}
+	factories[kind] = factory
+}
+
+// New creates a Source by kind using the registered factory.
+func New(kind string, config map[string]string) (Source, error) {
+	mu.RLock()
+	factory, ok := factories[kind]
+	mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("docsource: unknown kind %q", kind)
+	}
+	return factory(config)
+}
+
+// Available returns all registered kinds.
+func Available() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	return names
+}
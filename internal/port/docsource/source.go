@@ -0,0 +1,17 @@
+// Package docsource defines the port for fetching documents from the
+// external systems knowledge bases ingest, such as Confluence or Notion.
+package docsource
+
+import (
+	"context"
+
+	"github.com/Strob0t/CodeForge/internal/domain/knowledge"
+)
+
+// Source fetches the documents of a knowledge base.
+type Source interface {
+	// Fetch returns up to limit documents as plain text. Documents that
+	// cannot be read are skipped; an error means the source as a whole
+	// failed, and the stored documents are kept.
+	Fetch(ctx context.Context, limit int) ([]knowledge.Document, error)
+}
@@ -0,0 +1,14 @@
+// Package embedding defines the embedding provider port (interface).
+package embedding
+
+import "context"
+
+// Provider turns texts into embedding vectors.
+type Provider interface {
+	// Name returns the unique identifier for this provider (e.g. "ollama").
+	Name() string
+
+	// Embed returns one vector per text, in order. Callers batch their
+	// inputs; a provider sends each call as a single request.
+	Embed(ctx context.Context, model string, texts []string) ([][]float32, error)
+}
@@ -3,18 +3,46 @@ package eventstore
 
 import (
 	"context"
+	"time"
 
 	"github.com/Strob0t/CodeForge/internal/domain/event"
 )
 
-// Store is the port interface for appending and loading agent events.
+// Store is the port interface for appending and loading agent events and
+// audit entries.
 type Store interface {
 	// Append persists a new event to the store.
 	Append(ctx context.Context, ev *event.AgentEvent) error
 
+	// AppendBatch persists the events in one write, in slice order. Either
+	// all events are stored or none.
+	AppendBatch(ctx context.Context, evs []event.AgentEvent) error
+
 	// LoadByTask returns all events for the given task, ordered by version.
 	LoadByTask(ctx context.Context, taskID string) ([]event.AgentEvent, error)
 
 	// LoadByAgent returns all events for the given agent, ordered by version.
 	LoadByAgent(ctx context.Context, agentID string) ([]event.AgentEvent, error)
+
+	// LoadByRun returns all events recorded for the given run, ordered by creation time.
+	LoadByRun(ctx context.Context, runID string) ([]event.AgentEvent, error)
+
+	// DeleteByRun removes all events recorded for the given run once they
+	// have been archived. It returns the number of events removed.
+	DeleteByRun(ctx context.Context, runID string) (int64, error)
+
+	// AppendAudit persists a new audit entry and sets its seq.
+	AppendAudit(ctx context.Context, e *event.AuditEntry) error
+
+	// LoadAudit returns up to limit audit entries with a seq above afterSeq,
+	// ordered by seq. An empty tenantID matches all tenants; a non-zero
+	// until skips entries created after it.
+	LoadAudit(ctx context.Context, tenantID string, afterSeq int64, until time.Time, limit int) ([]event.AuditEntry, error)
+}
+
+// Flusher is implemented by stores that buffer appended events before
+// writing them.
+type Flusher interface {
+	// Flush writes the buffered events.
+	Flush(ctx context.Context) error
 }
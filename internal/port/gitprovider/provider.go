@@ -3,17 +3,51 @@ package gitprovider
 
 import (
 	"context"
+	"errors"
+	"net/http"
 
+	"github.com/Strob0t/CodeForge/internal/domain/chatops"
+	"github.com/Strob0t/CodeForge/internal/domain/issuerun"
+	"github.com/Strob0t/CodeForge/internal/domain/poll"
 	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/seatbelt"
+	"github.com/Strob0t/CodeForge/internal/domain/vcsaccount"
+)
+
+// Project config keys shared by hosted git providers.
+const (
+	// ConfigTokenSecret names the secret holding the API token. Services
+	// that call the hosting API resolve it into ConfigToken.
+	ConfigTokenSecret = "git_token_secret"
+	// ConfigToken is the resolved API token passed to the provider factory.
+	ConfigToken = "token"
+	// ConfigWebhookSecret names the secret webhooks are signed with.
+	// Services resolve it into ConfigWebhookKey.
+	ConfigWebhookSecret = "git_webhook_secret"
+	// ConfigWebhookKey is the resolved webhook secret passed to the factory.
+	ConfigWebhookKey = "webhook_secret"
+)
+
+// ErrNotInDiff is returned when an inline comment targets a line that is
+// not part of the pull request diff.
+var ErrNotInDiff = errors.New("gitprovider: line is not part of the pull request diff")
+
+var (
+	ErrInvalidSignature = errors.New("gitprovider: invalid webhook signature")
+	// ErrIgnoredEvent is returned for webhook deliveries that are valid
+	// but irrelevant, e.g. for another repository or an unhandled action.
+	ErrIgnoredEvent = errors.New("gitprovider: webhook event ignored")
 )
 
 // Capabilities declares which operations a git provider supports.
 type Capabilities struct {
-	Clone       bool `json:"clone"`
-	Push        bool `json:"push"`
-	PullRequest bool `json:"pull_request"`
-	Webhook     bool `json:"webhook"`
-	Issues      bool `json:"issues"`
+	Clone        bool `json:"clone"`
+	Push         bool `json:"push"`
+	PullRequest  bool `json:"pull_request"`
+	Webhook      bool `json:"webhook"`
+	Issues       bool `json:"issues"`
+	Worktree     bool `json:"worktree"`
+	CommitStatus bool `json:"commit_status"`
 }
 
 // Provider is the port interface for interacting with a Git hosting platform.
@@ -44,4 +78,168 @@ type Provider interface {
 
 	// Checkout switches to the specified branch.
 	Checkout(ctx context.Context, repoPath, branch string) error
+
+	// AddWorktree creates a detached worktree of repoPath's HEAD at worktreePath.
+	AddWorktree(ctx context.Context, repoPath, worktreePath string) error
+
+	// RemoveWorktree deletes a worktree and prunes its administrative data.
+	RemoveWorktree(ctx context.Context, repoPath, worktreePath string) error
+}
+
+// BranchWorktree is implemented by providers that can check out a branch
+// of the remote repository into a worktree, e.g. a pull request's head.
+type BranchWorktree interface {
+	// AddBranchWorktree fetches branch from origin and creates a detached
+	// worktree of its head at worktreePath.
+	AddBranchWorktree(ctx context.Context, repoPath, worktreePath, branch string) error
+}
+
+// PRComment is a comment on a pull request. Comments with a Path are
+// anchored to a line of the diff; the others belong to the conversation.
+type PRComment struct {
+	ID   string `json:"id"`
+	Path string `json:"path,omitempty"`
+	Line int    `json:"line,omitempty"`
+	Body string `json:"body"`
+}
+
+// PRCommenter is implemented by providers that can comment on pull requests
+// of the project's repository (Capabilities.PullRequest).
+type PRCommenter interface {
+	// ListPRComments returns the inline and conversation comments of a pull request.
+	ListPRComments(ctx context.Context, number int) ([]PRComment, error)
+
+	// CreatePRComment posts c and sets its ID. Inline comments on lines
+	// outside the diff fail with ErrNotInDiff.
+	CreatePRComment(ctx context.Context, number int, c *PRComment) error
+
+	// UpdatePRComment replaces the body of the existing comment c.
+	UpdatePRComment(ctx context.Context, number int, c *PRComment) error
+}
+
+// StatusState is the state of a commit status.
+type StatusState string
+
+const (
+	StatusPending StatusState = "pending"
+	StatusSuccess StatusState = "success"
+	StatusFailure StatusState = "failure"
+	StatusError   StatusState = "error"
+)
+
+// CommitStatus is a named status attached to a commit. Hosts show it as a
+// check on pull requests, and branch protection rules can require it.
+type CommitStatus struct {
+	Context     string      `json:"context"` // e.g. "codeforge/review"
+	State       StatusState `json:"state"`
+	Description string      `json:"description,omitempty"`
+	TargetURL   string      `json:"target_url,omitempty"`
+}
+
+// StatusReporter is implemented by providers that can report commit
+// statuses to the project's repository (Capabilities.CommitStatus).
+type StatusReporter interface {
+	// PRHeadSHA returns the head commit of a pull (or merge) request.
+	PRHeadSHA(ctx context.Context, number int) (string, error)
+
+	// SetCommitStatus creates or replaces the status named st.Context on sha.
+	SetCommitStatus(ctx context.Context, sha string, st *CommitStatus) error
+}
+
+// TokenInspector is implemented by providers that can validate their API
+// token against the project's repository, for the VCS account checks.
+type TokenInspector interface {
+	// InspectToken returns what the host reports about the token. It fails
+	// if there is no token, or it is invalid or cannot read the repository.
+	InspectToken(ctx context.Context) (*vcsaccount.TokenInfo, error)
+}
+
+// Initializer is implemented by providers that can turn a directory into a
+// new repository, e.g. for projects created from a template.
+type Initializer interface {
+	// Init creates a repository in dir and commits all of its files with
+	// message. With a non-empty remoteURL, the commit is pushed to it as
+	// origin.
+	Init(ctx context.Context, dir, remoteURL, message string) error
+}
+
+// IssueEvent is a verified change to an issue: the labels and assignees it
+// added. Opening an issue adds all of its labels and assignees.
+type IssueEvent struct {
+	Issue          issuerun.Issue
+	AddedLabels    []string
+	AddedAssignees []string
+}
+
+// IssueTracker is implemented by providers that receive issue webhooks
+// (Capabilities.Webhook) and comment on issues (Capabilities.Issues).
+type IssueTracker interface {
+	// ParseIssueWebhook verifies a webhook delivery and decodes the issue
+	// change. It returns ErrInvalidSignature or ErrIgnoredEvent for
+	// deliveries that must not be acted on.
+	ParseIssueWebhook(header http.Header, body []byte) (*IssueEvent, error)
+
+	// CreateIssueComment comments on an issue and returns the comment's ID.
+	CreateIssueComment(ctx context.Context, number int, body string) (string, error)
+
+	// UpdateIssueComment replaces the body of a comment on an issue.
+	UpdateIssueComment(ctx context.Context, number int, commentID, body string) error
+}
+
+// PRCommentEvent is a verified new comment on a pull request of the
+// project's repository.
+type PRCommentEvent struct {
+	Number     int
+	CommentID  string
+	Body       string
+	Author     string
+	AuthorRole chatops.Role
+	Branch     string // Head branch of the pull request
+	BaseBranch string
+}
+
+// PRChat is implemented by providers that receive pull request comment
+// webhooks (Capabilities.Webhook) and reply to them
+// (Capabilities.PullRequest).
+type PRChat interface {
+	// ParsePRCommentWebhook verifies a webhook delivery and decodes the new
+	// comment, looking up the pull request's branches and the author's
+	// role. It returns ErrInvalidSignature or ErrIgnoredEvent for
+	// deliveries that must not be acted on, including pull requests from
+	// forks, whose branches the project cannot push to.
+	ParsePRCommentWebhook(ctx context.Context, header http.Header, body []byte) (*PRCommentEvent, error)
+
+	// ReplyToPR comments on the conversation of a pull request and returns
+	// the comment's ID.
+	ReplyToPR(ctx context.Context, number int, body string) (string, error)
+
+	// UpdatePRReply replaces the body of a conversation comment.
+	UpdatePRReply(ctx context.Context, number int, commentID, body string) error
+}
+
+// DeliveryWatcher is implemented by providers that receive CI and push
+// webhooks (Capabilities.Webhook), so seatbelt mode can trace failures of
+// delivered changes back to their runs.
+type DeliveryWatcher interface {
+	// ParseDeliveryWebhook verifies a webhook delivery and decodes a failed
+	// check run or pipeline, or pushed commits that revert others. It
+	// returns ErrInvalidSignature or ErrIgnoredEvent for deliveries that
+	// must not be acted on, including passing checks and pushes without
+	// reverts.
+	ParseDeliveryWebhook(header http.Header, body []byte) (*seatbelt.Signal, error)
+}
+
+// Poller is implemented by providers that can list the changes their
+// webhooks announce, for projects the git host cannot deliver webhooks to.
+// Both methods advance the cursor past the changes they return. A cursor
+// without a position starts at the present: its first poll returns no
+// changes. An unchanged feed returns no changes and keeps the cursor.
+type Poller interface {
+	// PollIssues returns the issue changes since the cursor, oldest first.
+	PollIssues(ctx context.Context, cursor *poll.Cursor) ([]IssueEvent, error)
+
+	// PollPRComments returns the new pull request comments since the
+	// cursor that post a command, oldest first. Like PRChat webhooks, it
+	// skips pull requests from forks.
+	PollPRComments(ctx context.Context, cursor *poll.Cursor) ([]PRCommentEvent, error)
 }
@@ -26,7 +26,9 @@ func (p *testProvider) Pull(_ context.Context, _ string) error { return nil }
 func (p *testProvider) ListBranches(_ context.Context, _ string) ([]project.Branch, error) {
 	return nil, nil
 }
-func (p *testProvider) Checkout(_ context.Context, _, _ string) error { return nil }
+func (p *testProvider) Checkout(_ context.Context, _, _ string) error       { return nil }
+func (p *testProvider) AddWorktree(_ context.Context, _, _ string) error    { return nil }
+func (p *testProvider) RemoveWorktree(_ context.Context, _, _ string) error { return nil }
 
 func TestRegisterAndNew(t *testing.T) {
 	gitprovider.Register("test-git", func(_ map[string]string) (gitprovider.Provider, error) {
@@ -0,0 +1,37 @@
+// Package langserver defines the language server port (interface) used to
+// answer code intelligence queries on workspaces.
+package langserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Strob0t/CodeForge/internal/domain/lsp"
+)
+
+// ErrClosed is returned by the calls of a Server whose process exited or
+// that was closed.
+var ErrClosed = errors.New("language server closed")
+
+// Server is a running language server for one workspace root. Paths are
+// relative to the root; files are read from disk on every call, so the
+// server sees changes made since the last one.
+type Server interface {
+	Definition(ctx context.Context, path string, pos lsp.Position) ([]lsp.Location, error)
+	References(ctx context.Context, path string, pos lsp.Position) ([]lsp.Location, error)
+	// Hover returns nil when there is nothing at pos.
+	Hover(ctx context.Context, path string, pos lsp.Position) (*lsp.Hover, error)
+	Symbols(ctx context.Context, path string) ([]lsp.Symbol, error)
+	Rename(ctx context.Context, path string, pos lsp.Position, newName string) (*lsp.WorkspaceEdit, error)
+	CodeActions(ctx context.Context, path string, rng lsp.Range) ([]lsp.CodeAction, error)
+	Format(ctx context.Context, path string) ([]lsp.TextEdit, error)
+	// Diagnostics checks content as the text of path, or the file on disk
+	// when content is nil, and returns the diagnostics the server
+	// publishes for it.
+	Diagnostics(ctx context.Context, path string, content []byte) ([]lsp.Diagnostic, error)
+	Close() error
+}
+
+// Starter starts the language server command (binary and arguments) for
+// the workspace root.
+type Starter func(ctx context.Context, command []string, root string) (Server, error)
@@ -0,0 +1,32 @@
+package messagequeue
+
+import (
+	"context"
+	"time"
+)
+
+// DeadLetter is a message that failed schema validation or exhausted its
+// deliveries and was moved out of the main stream.
+type DeadLetter struct {
+	Seq        uint64    `json:"seq"`
+	Subject    string    `json:"subject"` // Subject the message was published to
+	Data       string    `json:"data"`
+	Error      string    `json:"error"`
+	Deliveries int       `json:"deliveries"`
+	RequestID  string    `json:"request_id,omitempty"`
+	FailedAt   time.Time `json:"failed_at"`
+}
+
+// DeadLetterQueue gives access to dead-lettered messages.
+type DeadLetterQueue interface {
+	// ListDeadLetters returns up to limit dead letters, oldest first. An
+	// empty subject returns dead letters of all subjects.
+	ListDeadLetters(ctx context.Context, subject string, limit int) ([]DeadLetter, error)
+
+	// ReplayDeadLetter republishes a dead letter to its original subject
+	// and removes it from the dead-letter queue.
+	ReplayDeadLetter(ctx context.Context, seq uint64) error
+
+	// DeleteDeadLetter discards a dead letter.
+	DeleteDeadLetter(ctx context.Context, seq uint64) error
+}
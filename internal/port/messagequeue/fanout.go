@@ -0,0 +1,20 @@
+package messagequeue
+
+import "context"
+
+// SubjectWSEvents is the prefix of the subjects WebSocket events are fanned
+// out on between servers: ws.events.{tenant}, or ws.events._ for events
+// not scoped to a tenant.
+const SubjectWSEvents = "ws.events"
+
+// FanOut delivers every message to every subscriber, unlike Queue, whose
+// durable consumers share messages between servers. Messages are not
+// persisted; subscribers that are not connected miss them.
+type FanOut interface {
+	// PublishFanOut sends a message to the current subscribers of subject.
+	PublishFanOut(ctx context.Context, subject string, data []byte) error
+
+	// SubscribeFanOut calls fn for every message on subject, which may
+	// contain wildcards, until cancel is called.
+	SubscribeFanOut(subject string, fn func(subject string, data []byte)) (cancel func(), err error)
+}
@@ -0,0 +1,55 @@
+package messagequeue
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrKeyExists is returned by KeyValue.Create for a key that has a value.
+var ErrKeyExists = errors.New("key exists")
+
+// ErrKeyChanged is returned by KeyValue.Update for a key whose revision
+// changed since it was read.
+var ErrKeyChanged = errors.New("key changed")
+
+// KeyValue is a bucket of values replicated to every server, used to
+// propagate state changes without polling the database.
+type KeyValue interface {
+	// Get returns the value of key, or nil if it has none.
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// GetRevision returns the value of key and its revision, or nil and 0
+	// if it has none.
+	GetRevision(ctx context.Context, key string) ([]byte, uint64, error)
+
+	// Put sets the value of key.
+	Put(ctx context.Context, key string, value []byte) error
+
+	// Create sets the value of key unless it has one, in which case it
+	// returns ErrKeyExists.
+	Create(ctx context.Context, key string, value []byte) error
+
+	// Update sets the value of key if it is still at revision, as returned
+	// by GetRevision; revision 0 requires the key to have no value. It
+	// returns ErrKeyChanged otherwise.
+	Update(ctx context.Context, key string, value []byte, revision uint64) error
+
+	// Delete removes key.
+	Delete(ctx context.Context, key string) error
+
+	// Watch calls fn for every key in the bucket and then for every later
+	// change, with a nil value for deleted keys, until stop is called.
+	Watch(ctx context.Context, fn func(key string, value []byte)) (stop func(), err error)
+}
+
+// KeyValueStore opens key-value buckets.
+type KeyValueStore interface {
+	// KeyValue returns the bucket with the given name, creating it if
+	// needed.
+	KeyValue(ctx context.Context, bucket string) (KeyValue, error)
+
+	// ExpiringKeyValue is KeyValue for a bucket whose values expire ttl
+	// after they were last set.
+	ExpiringKeyValue(ctx context.Context, bucket string, ttl time.Duration) (KeyValue, error)
+}
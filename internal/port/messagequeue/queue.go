@@ -44,6 +44,7 @@ const (
 	SubjectRunComplete         = "runs.complete"          // Python → Go: run finished
 	SubjectRunCancel           = "runs.cancel"            // Go → Python: cancel a run
 	SubjectRunOutput           = "runs.output"            // Python → Go: streaming output
+	SubjectRunEgressBlocked    = "runs.egress.blocked"    // Egress proxy → Go: denied connection attempt
 
 	// Quality gate subjects (Phase 4C)
 	SubjectQualityGateRequest = "runs.qualitygate.request" // Go → Python: run tests/lint
@@ -10,15 +10,16 @@ type TaskCreatedPayload struct {
 
 // TaskResultPayload is the schema for tasks.result messages.
 type TaskResultPayload struct {
-	TaskID    string   `json:"task_id"`
-	ProjectID string   `json:"project_id"`
-	Status    string   `json:"status"`
-	Output    string   `json:"output"`
-	Files     []string `json:"files"`
-	Error     string   `json:"error"`
-	TokensIn  int      `json:"tokens_in"`
-	TokensOut int      `json:"tokens_out"`
-	CostUSD   float64  `json:"cost_usd"`
+	TaskID         string   `json:"task_id"`
+	ProjectID      string   `json:"project_id"`
+	Status         string   `json:"status"`
+	Output         string   `json:"output"`
+	Files          []string `json:"files"`
+	Error          string   `json:"error"`
+	TokensIn       int      `json:"tokens_in"`
+	TokensOut      int      `json:"tokens_out"`
+	CostUSD        float64  `json:"cost_usd"`
+	IdempotencyKey string   `json:"idempotency_key,omitempty"` // Same for every publish of this result
 }
 
 // TaskOutputPayload is the schema for tasks.output messages.
@@ -53,9 +54,31 @@ type RunStartPayload struct {
 	PolicyProfile string                `json:"policy_profile"`
 	ExecMode      string                `json:"exec_mode"`
 	DeliverMode   string                `json:"deliver_mode,omitempty"`
+	WorkspacePath string                `json:"workspace_path,omitempty"` // Isolated worktree; empty means the project workspace
+	SubProject    *SubProjectPayload    `json:"sub_project,omitempty"`    // Monorepo package the run is scoped to
 	Config        map[string]string     `json:"config"`
+	Env           map[string]string     `json:"env,omitempty"` // Resolved secrets injected as env vars into tool processes
 	Termination   TerminationPayload    `json:"termination"`
-	Context       []ContextEntryPayload `json:"context,omitempty"` // Pre-packed context entries (Phase 5D)
+	Context       []ContextEntryPayload `json:"context,omitempty"`     // Pre-packed context entries (Phase 5D)
+	Attachments   []AttachmentPayload   `json:"attachments,omitempty"` // Task attachments written into the workspace
+}
+
+// AttachmentPayload describes a task attachment written into a run's
+// workspace. Path is relative to the workspace root.
+type AttachmentPayload struct {
+	ID          string `json:"id"`
+	Path        string `json:"path"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// SubProjectPayload describes the monorepo sub-project a run works in.
+// Path is relative to the workspace root.
+type SubProjectPayload struct {
+	Path         string `json:"path"`
+	Language     string `json:"language,omitempty"`
+	BuildCommand string `json:"build_command,omitempty"`
+	TestCommand  string `json:"test_command,omitempty"`
 }
 
 // TerminationPayload carries the termination limits for a run.
@@ -84,25 +107,30 @@ type ToolCallResponsePayload struct {
 
 // ToolCallResultPayload is the schema for runs.toolcall.result messages.
 type ToolCallResultPayload struct {
-	RunID   string  `json:"run_id"`
-	CallID  string  `json:"call_id"`
-	Tool    string  `json:"tool"`
-	Success bool    `json:"success"`
-	Output  string  `json:"output"`
-	Error   string  `json:"error"`
-	CostUSD float64 `json:"cost_usd"`
+	RunID          string  `json:"run_id"`
+	CallID         string  `json:"call_id"`
+	Tool           string  `json:"tool"`
+	Success        bool    `json:"success"`
+	Output         string  `json:"output"`
+	Error          string  `json:"error"`
+	CostUSD        float64 `json:"cost_usd"`
+	TokensIn       int     `json:"tokens_in,omitempty"`
+	TokensOut      int     `json:"tokens_out,omitempty"`
+	Model          string  `json:"model,omitempty"`           // Model that served an LLM call
+	IdempotencyKey string  `json:"idempotency_key,omitempty"` // Same for every publish of this result
 }
 
 // RunCompletePayload is the schema for runs.complete messages.
 type RunCompletePayload struct {
-	RunID     string  `json:"run_id"`
-	TaskID    string  `json:"task_id"`
-	ProjectID string  `json:"project_id"`
-	Status    string  `json:"status"`
-	Output    string  `json:"output"`
-	Error     string  `json:"error"`
-	CostUSD   float64 `json:"cost_usd"`
-	StepCount int     `json:"step_count"`
+	RunID          string  `json:"run_id"`
+	TaskID         string  `json:"task_id"`
+	ProjectID      string  `json:"project_id"`
+	Status         string  `json:"status"`
+	Output         string  `json:"output"`
+	Error          string  `json:"error"`
+	CostUSD        float64 `json:"cost_usd"`
+	StepCount      int     `json:"step_count"`
+	IdempotencyKey string  `json:"idempotency_key,omitempty"` // Same for every publish of this completion
 }
 
 // RunOutputPayload is the schema for runs.output messages.
@@ -113,6 +141,17 @@ type RunOutputPayload struct {
 	Stream string `json:"stream"`
 }
 
+// EgressBlockedPayload is the schema for runs.egress.blocked messages,
+// published by a sandbox's egress proxy for each denied connection attempt.
+type EgressBlockedPayload struct {
+	RunID     string `json:"run_id"`
+	ProjectID string `json:"project_id"`
+	Host      string `json:"host"`
+	Port      string `json:"port"`
+	Method    string `json:"method"`
+	Reason    string `json:"reason"`
+}
+
 // --- Quality Gate payloads (Phase 4C) ---
 
 // QualityGateRequestPayload is published to request test/lint execution.
@@ -124,16 +163,38 @@ type QualityGateRequestPayload struct {
 	RunLint       bool   `json:"run_lint"`
 	TestCommand   string `json:"test_command,omitempty"`
 	LintCommand   string `json:"lint_command,omitempty"`
+	// TestReportPath is a JUnit XML file, relative to WorkspacePath, that
+	// the test command writes. The worker returns its content as TestReport.
+	TestReportPath string `json:"test_report_path,omitempty"`
+	// Linters run in WorkspacePath independently of RunLint; their JSON
+	// output is returned in LintResults and parsed by the core.
+	Linters []LinterPayload `json:"linters,omitempty"`
+}
+
+// LinterPayload is a linter command of a quality gate request.
+type LinterPayload struct {
+	Tool    string `json:"tool"`
+	Command string `json:"command"`
+}
+
+// LintResultPayload is the output of one requested linter.
+type LintResultPayload struct {
+	Tool   string `json:"tool"`
+	Output string `json:"output"`
 }
 
 // QualityGateResultPayload is published with the outcome of a quality gate execution.
 type QualityGateResultPayload struct {
-	RunID       string `json:"run_id"`
-	TestsPassed *bool  `json:"tests_passed,omitempty"`
-	LintPassed  *bool  `json:"lint_passed,omitempty"`
-	TestOutput  string `json:"test_output,omitempty"`
-	LintOutput  string `json:"lint_output,omitempty"`
-	Error       string `json:"error,omitempty"`
+	RunID          string `json:"run_id"`
+	TestsPassed    *bool  `json:"tests_passed,omitempty"`
+	LintPassed     *bool  `json:"lint_passed,omitempty"`
+	TestOutput     string `json:"test_output,omitempty"`
+	LintOutput     string `json:"lint_output,omitempty"`
+	TestReport     string `json:"test_report,omitempty"` // Content of TestReportPath, if written
+	Error          string `json:"error,omitempty"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"` // Same for every publish of this result
+
+	LintResults []LintResultPayload `json:"lint_results,omitempty"`
 }
 
 // --- Context payloads (Phase 5D) ---
@@ -0,0 +1,27 @@
+// Package notifier defines the port for sending notifications to chat and
+// mail systems such as Slack.
+package notifier
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Strob0t/CodeForge/internal/domain/notification"
+)
+
+var (
+	// ErrInvalidSignature is returned for callbacks from a chat system,
+	// such as clicks on Slack buttons, whose signature does not verify.
+	ErrInvalidSignature = errors.New("notifier: invalid callback signature")
+	// ErrIgnoredInteraction is returned for valid callbacks that are not
+	// about anything CodeForge acts on.
+	ErrIgnoredInteraction = errors.New("notifier: interaction ignored")
+)
+
+// Notifier delivers notification messages to an external system.
+type Notifier interface {
+	// Send delivers the message. It returns nil only when the system
+	// accepted it; on error it is sent again later, so the system may see
+	// it more than once.
+	Send(ctx context.Context, msg *notification.Message) error
+}
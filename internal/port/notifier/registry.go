@@ -0,0 +1,52 @@
+package notifier
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Factory creates a Notifier from a rule's config (notification.Rule.Config),
+// with a webhook_secret already resolved into webhook_url and, for email
+// rules, the server's SMTP settings added.
+type Factory func(config map[string]string) (Notifier, error)
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register makes a notifier factory available for a rule kind.
+// It is typically called from an init() function in the adapter package.
+func Register(kind string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, exists := factories[kind]; exists {
+		panic(fmt.Sprintf("notifier: duplicate registration for %q", kind))
+	}
+	factories[kind] = factory
+}
+
+// New creates a Notifier by kind using the registered factory.
+func New(kind string, config map[string]string) (Notifier, error) {
+	mu.RLock()
+	factory, ok := factories[kind]
+	mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("notifier: unknown kind %q", kind)
+	}
+	return factory(config)
+}
+
+// Available returns all registered kinds.
+func Available() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	return names
+}
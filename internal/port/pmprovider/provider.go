@@ -0,0 +1,76 @@
+// Package pmprovider defines the project management platform port
+// (interface) and capabilities.
+package pmprovider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Strob0t/CodeForge/internal/domain/roadmap"
+)
+
+// Project config keys linking a project to a PM platform. The remaining
+// keys are provider-specific and passed to the factory unchanged.
+const (
+	// ConfigProvider names the registered provider, e.g. "jira".
+	ConfigProvider = "pm_provider"
+	// ConfigTokenSecret names the secret holding the API token. It is
+	// resolved into the provider config as ConfigToken.
+	ConfigTokenSecret = "pm_token_secret"
+	// ConfigWebhookSecret names the secret webhook deliveries are signed
+	// with. It is resolved into the provider config as ConfigWebhookKey.
+	ConfigWebhookSecret = "pm_webhook_secret"
+
+	ConfigToken      = "token"
+	ConfigWebhookKey = "webhook_secret"
+)
+
+var (
+	ErrInvalidSignature = errors.New("pmprovider: invalid webhook signature")
+	// ErrIgnoredEvent is returned for webhook deliveries that are valid
+	// but irrelevant, e.g. for another project or an unhandled event type.
+	ErrIgnoredEvent = errors.New("pmprovider: webhook event ignored")
+)
+
+// Capabilities declares which operations a PM provider supports.
+type Capabilities struct {
+	Import     bool `json:"import"`      // ListItems
+	StatusSync bool `json:"status_sync"` // UpdateStatus
+	Webhook    bool `json:"webhook"`     // ParseWebhook
+}
+
+// Action is what happened to an item in a webhook event.
+type Action string
+
+const (
+	ActionCreated Action = "created"
+	ActionUpdated Action = "updated"
+	ActionDeleted Action = "deleted"
+)
+
+// WebhookEvent is a verified change to an item on the platform.
+type WebhookEvent struct {
+	Action Action
+	Item   roadmap.Item
+}
+
+// Provider is the port interface for syncing with a PM platform.
+type Provider interface {
+	// Name returns the unique identifier for this provider (e.g. "jira").
+	Name() string
+
+	// Capabilities returns what this provider supports.
+	Capabilities() Capabilities
+
+	// ListItems returns all items of the configured platform project.
+	ListItems(ctx context.Context) ([]roadmap.Item, error)
+
+	// UpdateStatus moves an item to the platform state matching status.
+	UpdateStatus(ctx context.Context, itemID string, status roadmap.FeatureStatus) error
+
+	// ParseWebhook verifies a webhook delivery and decodes the item change.
+	// It returns ErrInvalidSignature or ErrIgnoredEvent for deliveries
+	// that must not be applied.
+	ParseWebhook(header http.Header, body []byte) (*WebhookEvent, error)
+}
@@ -0,0 +1,50 @@
+package pmprovider_test
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/roadmap"
+	"github.com/Strob0t/CodeForge/internal/port/pmprovider"
+)
+
+type testProvider struct {
+	name string
+}
+
+func (p *testProvider) Name() string { return p.name }
+func (p *testProvider) Capabilities() pmprovider.Capabilities {
+	return pmprovider.Capabilities{Import: true}
+}
+func (p *testProvider) ListItems(_ context.Context) ([]roadmap.Item, error) { return nil, nil }
+func (p *testProvider) UpdateStatus(_ context.Context, _ string, _ roadmap.FeatureStatus) error {
+	return nil
+}
+func (p *testProvider) ParseWebhook(_ http.Header, _ []byte) (*pmprovider.WebhookEvent, error) {
+	return nil, pmprovider.ErrIgnoredEvent
+}
+
+func TestRegisterAndNew(t *testing.T) {
+	pmprovider.Register("test-pm", func(_ map[string]string) (pmprovider.Provider, error) {
+		return &testProvider{name: "test-pm"}, nil
+	})
+
+	p, err := pmprovider.New("test-pm", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name() != "test-pm" {
+		t.Fatalf("expected test-pm, got %s", p.Name())
+	}
+	if !slices.Contains(pmprovider.Available(), "test-pm") {
+		t.Fatal("expected test-pm in available providers")
+	}
+}
+
+func TestNewUnknownProvider(t *testing.T) {
+	if _, err := pmprovider.New("nonexistent", nil); err == nil {
+		t.Fatal("expected error for unknown provider")
+	}
+}
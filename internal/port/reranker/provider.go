@@ -0,0 +1,16 @@
+// Package reranker defines the reranking provider port (interface).
+package reranker
+
+import "context"
+
+// Provider scores documents against a query with a cross-encoder model,
+// which reads query and document together and ranks more precisely than
+// the similarity of separately computed embeddings.
+type Provider interface {
+	// Name returns the unique identifier for this provider (e.g. "onnx").
+	Name() string
+
+	// Rerank returns one relevance score per document, in order. Higher
+	// scores are more relevant; their scale depends on the model.
+	Rerank(ctx context.Context, model, query string, documents []string) ([]float64, error)
+}
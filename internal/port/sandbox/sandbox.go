@@ -0,0 +1,116 @@
+// Package sandbox defines the sandbox driver port (interface) that launches
+// isolated run environments (containers, pods) for sandbox-mode runs.
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+)
+
+// Limits are the resource limits of a sandbox. Zero values leave a limit to
+// the driver's default.
+type Limits struct {
+	MemoryMB  int     `json:"memory_mb,omitempty"`
+	CPUs      float64 `json:"cpus,omitempty"`
+	PIDs      int     `json:"pids,omitempty"`
+	StorageMB int     `json:"storage_mb,omitempty"`
+}
+
+// Workspace describes the repository a sandbox works on. Drivers mount
+// HostPath if they can reach it and otherwise clone RepoURL at Ref.
+type Workspace struct {
+	HostPath string `json:"host_path,omitempty"`
+	RepoURL  string `json:"repo_url,omitempty"`
+	Ref      string `json:"ref,omitempty"`
+}
+
+// Spec describes a sandbox to launch for a run.
+type Spec struct {
+	RunID       string            `json:"run_id"`
+	ProjectID   string            `json:"project_id"`
+	Image       string            `json:"image"`
+	Command     []string          `json:"command,omitempty"` // Replaces the image's entrypoint and command; empty runs its default command
+	Env         map[string]string `json:"env,omitempty"`
+	Workspace   Workspace         `json:"workspace"`
+	Limits      Limits            `json:"limits"`
+	NetworkMode string            `json:"network_mode,omitempty"`
+	Timeout     time.Duration     `json:"timeout,omitempty"` // Hard deadline after which the driver kills the sandbox; 0 = none
+	Isolation   policy.Isolation  `json:"isolation,omitempty"`
+	Egress      *Egress           `json:"egress,omitempty"` // Nil leaves egress to NetworkMode
+}
+
+// Egress routes a sandbox's HTTP and HTTPS traffic through a proxy sidecar
+// that enforces Policy and reports blocked attempts over NATS.
+type Egress struct {
+	Policy  policy.EgressPolicy `json:"policy"`
+	Image   string              `json:"image"`              // Proxy sidecar image
+	NoProxy []string            `json:"no_proxy,omitempty"` // Hosts the sandbox reaches directly, e.g. NATS and LiteLLM
+}
+
+// WorkDir is the path the workspace is available at inside a sandbox.
+const WorkDir = "/workspace"
+
+// Egress proxy sidecar settings.
+const (
+	EgressProxyPort  = 3128
+	EgressPolicyEnv  = "CODEFORGE_EGRESS_POLICY" // JSON-encoded policy.EgressPolicy
+	EgressRunEnv     = "CODEFORGE_RUN_ID"
+	EgressProjectEnv = "CODEFORGE_PROJECT_ID"
+)
+
+// SidecarEnv returns the env of the egress proxy sidecar of spec. The
+// sidecar publishes blocked attempts to the NATS_URL of the sandbox.
+func (s *Spec) SidecarEnv() map[string]string {
+	data, _ := json.Marshal(&s.Egress.Policy)
+	env := map[string]string{
+		EgressPolicyEnv:  string(data),
+		EgressRunEnv:     s.RunID,
+		EgressProjectEnv: s.ProjectID,
+	}
+	if url := s.Env["NATS_URL"]; url != "" {
+		env["NATS_URL"] = url
+	}
+	return env
+}
+
+// ProxyEnv returns the env that points the sandbox at the egress proxy on
+// host. Both spellings are set since tools disagree on the case.
+func (s *Spec) ProxyEnv(host string) map[string]string {
+	proxy := "http://" + net.JoinHostPort(host, strconv.Itoa(EgressProxyPort))
+	noProxy := strings.Join(s.Egress.NoProxy, ",")
+	return map[string]string{
+		"HTTP_PROXY": proxy, "http_proxy": proxy,
+		"HTTPS_PROXY": proxy, "https_proxy": proxy,
+		"NO_PROXY": noProxy, "no_proxy": noProxy,
+	}
+}
+
+// Line is a line of sandbox output.
+type Line struct {
+	Stream string // "stdout" or "stderr"; drivers that merge streams report "stdout"
+	Text   string
+}
+
+// Driver is the port interface for a sandbox backend.
+type Driver interface {
+	// Name returns the unique identifier for this driver (e.g. "docker").
+	Name() string
+
+	// Start launches a sandbox and returns its driver-specific ID. Drivers
+	// must fail rather than fall back to weaker isolation than the spec's.
+	Start(ctx context.Context, spec *Spec) (string, error)
+
+	// Logs follows the output of a sandbox, calling fn for each line, and
+	// returns once the sandbox has exited or ctx is done.
+	Logs(ctx context.Context, id string, fn func(Line)) error
+
+	// Remove stops the sandbox if it is still running and deletes it with
+	// its resources. Removing an unknown sandbox is not an error.
+	Remove(ctx context.Context, id string) error
+}
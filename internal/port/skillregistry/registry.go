@@ -0,0 +1,18 @@
+// Package skillregistry defines the skill registry port (interface).
+package skillregistry
+
+import (
+	"context"
+
+	"github.com/Strob0t/CodeForge/internal/domain/skill"
+)
+
+// Registry is a remote index of shared skill bundles.
+type Registry interface {
+	// Index returns the listed bundles. Entry URLs are absolute.
+	Index(ctx context.Context) (*skill.Index, error)
+
+	// Download returns the bundle at url, failing if it is larger than
+	// maxBytes.
+	Download(ctx context.Context, url string, maxBytes int64) ([]byte, error)
+}
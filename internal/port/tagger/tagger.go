@@ -0,0 +1,22 @@
+// Package tagger defines the tagger port (interface) used to extract the
+// declarations of source files in languages the code graph has no grammar
+// for.
+package tagger
+
+import "context"
+
+// Tag is a top-level declaration found by a Tagger.
+type Tag struct {
+	Path     string // Slash-separated, relative to the root passed to Tag
+	Language string // Language as named by the tagger, e.g. "Ruby"
+	Name     string
+	Kind     string // e.g. "function", "class"
+	Line     int
+}
+
+// Tagger extracts declarations with an external tool such as ctags.
+type Tagger interface {
+	// Tag returns the top-level declarations of files, given relative to
+	// root. Files in languages the tagger does not know yield no tags.
+	Tag(ctx context.Context, root string, files []string) ([]Tag, error)
+}
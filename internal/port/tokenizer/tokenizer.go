@@ -0,0 +1,13 @@
+// Package tokenizer defines the tokenizer port (interface) used to count
+// tokens against model context windows.
+package tokenizer
+
+// Tokenizer counts the tokens a model's tokenizer produces for a text.
+type Tokenizer interface {
+	// Name identifies the tokenizer, e.g. "tiktoken:cl100k_base".
+	Name() string
+
+	// Count returns the number of tokens of text. Special tokens are not
+	// recognized; their text is counted literally.
+	Count(text string) int
+}
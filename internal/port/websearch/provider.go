@@ -0,0 +1,30 @@
+// Package websearch defines the web search provider port (interface) and capabilities.
+package websearch
+
+import "context"
+
+// Capabilities declares which operations a web search provider supports.
+type Capabilities struct {
+	Search   bool `json:"search"`
+	Snippets bool `json:"snippets"`
+}
+
+// Result is a single hit returned by a web search provider.
+type Result struct {
+	Title   string  `json:"title"`
+	URL     string  `json:"url"`
+	Snippet string  `json:"snippet,omitempty"`
+	Score   float64 `json:"score,omitempty"`
+}
+
+// Provider is the port interface for querying an external web search engine.
+type Provider interface {
+	// Name returns the unique identifier for this provider (e.g. "searxng").
+	Name() string
+
+	// Capabilities returns what this provider supports.
+	Capabilities() Capabilities
+
+	// Search runs a query and returns at most limit results (0 = provider default).
+	Search(ctx context.Context, query string, limit int) ([]Result, error)
+}
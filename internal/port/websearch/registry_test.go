@@ -0,0 +1,54 @@
+package websearch_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/port/websearch"
+)
+
+type testProvider struct {
+	name string
+}
+
+func (p *testProvider) Name() string { return p.name }
+func (p *testProvider) Capabilities() websearch.Capabilities {
+	return websearch.Capabilities{Search: true}
+}
+func (p *testProvider) Search(_ context.Context, _ string, _ int) ([]websearch.Result, error) {
+	return nil, nil
+}
+
+func TestRegisterAndNew(t *testing.T) {
+	websearch.Register("test-search", func(_ map[string]string) (websearch.Provider, error) {
+		return &testProvider{name: "test-search"}, nil
+	})
+
+	p, err := websearch.New("test-search", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name() != "test-search" {
+		t.Fatalf("expected test-search, got %s", p.Name())
+	}
+}
+
+func TestNewUnknownProvider(t *testing.T) {
+	_, err := websearch.New("nonexistent", nil)
+	if err == nil {
+		t.Fatal("expected error for unknown provider")
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	names := websearch.Available()
+	found := false
+	for _, n := range names {
+		if n == "test-search" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected test-search in available providers")
+	}
+}
@@ -0,0 +1,157 @@
+// Package redact masks credentials in agent output before it is broadcast
+// or persisted. A Pipeline runs three stages in order: known secret
+// formats, configured regular expressions and a Shannon-entropy heuristic
+// for random-looking tokens.
+package redact
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// valueGroup is the capture group name that limits masking to part of a
+// match, e.g. the value of a "password=..." assignment.
+const valueGroup = "value"
+
+type rule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// knownFormats are credential formats with a recognizable shape.
+var knownFormats = []rule{
+	{"private-key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----(?s:.*?)(?:-----END [A-Z ]*PRIVATE KEY-----|$)`)},
+	{"aws-access-key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"github-token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"gitlab-token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`)},
+	{"slack-token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{"stripe-key", regexp.MustCompile(`\b[rs]k_(?:live|test)_[A-Za-z0-9]{16,}\b`)},
+	{"llm-api-key", regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}`)},
+	{"google-api-key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{"bearer-token", regexp.MustCompile(`(?i)\bbearer\s+(?P<value>[A-Za-z0-9._~+/=-]{16,})`)},
+	{"credential", regexp.MustCompile(`(?i)\b(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key)["']?\s*[:=]\s*["']?(?P<value>[^\s"'\[]{6,})`)},
+}
+
+// entropyToken matches token-like runs checked by the entropy stage. The
+// slash is excluded so file paths and URLs are split into segments.
+var entropyToken = regexp.MustCompile(`[A-Za-z0-9+=_-]+`)
+
+// Pipeline masks credentials in text. A nil Pipeline passes text through.
+type Pipeline struct {
+	rules            []rule
+	entropyThreshold float64
+	minTokenLength   int
+}
+
+// New creates a Pipeline with the built-in formats plus the given patterns.
+// entropyThreshold is the minimum Shannon entropy (bits per character) of
+// a token of at least minTokenLength characters to be masked; 0 disables
+// the entropy stage.
+func New(patterns []string, entropyThreshold float64, minTokenLength int) (*Pipeline, error) {
+	p := &Pipeline{
+		rules:            append([]rule{}, knownFormats...),
+		entropyThreshold: entropyThreshold,
+		minTokenLength:   minTokenLength,
+	}
+	for i, pat := range patterns {
+		re, err := regexp.Compile(pat)
+		if err != nil {
+			return nil, fmt.Errorf("redaction pattern %d: %w", i, err)
+		}
+		p.rules = append(p.rules, rule{name: "pattern", re: re})
+	}
+	return p, nil
+}
+
+// Redact returns text with credentials replaced by "[redacted:<kind>]"
+// and the number of replacements made.
+func (p *Pipeline) Redact(text string) (string, int) {
+	if p == nil || text == "" {
+		return text, 0
+	}
+	total := 0
+	for _, r := range p.rules {
+		var n int
+		text, n = replaceRule(text, r)
+		total += n
+	}
+	if p.entropyThreshold > 0 {
+		var n int
+		text, n = p.replaceHighEntropy(text)
+		total += n
+	}
+	return text, total
+}
+
+func replaceRule(text string, r rule) (string, int) {
+	matches := r.re.FindAllStringSubmatchIndex(text, -1)
+	if matches == nil {
+		return text, 0
+	}
+	group := r.re.SubexpIndex(valueGroup)
+	mask := "[redacted:" + r.name + "]"
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if group > 0 && m[2*group] >= 0 {
+			start, end = m[2*group], m[2*group+1]
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(mask)
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String(), len(matches)
+}
+
+func (p *Pipeline) replaceHighEntropy(text string) (string, int) {
+	n := 0
+	out := entropyToken.ReplaceAllStringFunc(text, func(tok string) string {
+		if len(tok) < p.minTokenLength || !mixedClasses(tok) || Entropy(tok) < p.entropyThreshold {
+			return tok
+		}
+		n++
+		return "[redacted:high-entropy]"
+	})
+	return out, n
+}
+
+// mixedClasses reports whether s contains upper-case letters, lower-case
+// letters and digits, which excludes words, hex hashes and numbers.
+func mixedClasses(s string) bool {
+	var upper, lower, digit bool
+	for _, c := range s {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= '0' && c <= '9':
+			digit = true
+		}
+	}
+	return upper && lower && digit
+}
+
+// Entropy returns the Shannon entropy of s in bits per character.
+func Entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	for _, c := range s {
+		counts[c]++
+	}
+	n := float64(len(s))
+	var h float64
+	for _, c := range counts {
+		f := float64(c) / n
+		h -= f * math.Log2(f)
+	}
+	return h
+}
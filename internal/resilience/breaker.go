@@ -62,6 +62,14 @@ func (b *Breaker) Execute(fn func() error) error {
 	return nil
 }
 
+// Open reports whether the breaker currently rejects calls. Unlike
+// Execute it does not move an expired open breaker to half-open.
+func (b *Breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state == stateOpen && b.now().Sub(b.openedAt) < b.timeout
+}
+
 func (b *Breaker) allowRequest() bool {
 	b.mu.Lock()
 	defer b.mu.Unlock()
@@ -132,3 +132,27 @@ func TestSuccessResetsFailureCount(t *testing.T) {
 		t.Fatal("expected fn to be called")
 	}
 }
+
+func TestOpenReportsWithoutTransition(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker(1, time.Second)
+	b.now = func() time.Time { return now }
+
+	if b.Open() {
+		t.Fatal("expected a new breaker to be closed")
+	}
+	_ = b.Execute(func() error { return errTest })
+	if !b.Open() {
+		t.Fatal("expected breaker to be open after max failures")
+	}
+
+	now = now.Add(2 * time.Second)
+	if b.Open() {
+		t.Fatal("expected an expired open breaker to accept a trial call")
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != stateOpen {
+		t.Fatalf("Open must not change the state, got %d", b.state)
+	}
+}
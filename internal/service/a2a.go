@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/a2aclient"
+	"github.com/Strob0t/CodeForge/internal/domain/a2a"
+	"github.com/Strob0t/CodeForge/internal/domain/agent"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+)
+
+const (
+	// a2aPollInterval is how often a delegated task is polled when the
+	// remote agent does not stream, and how often the run is checked for
+	// cancellation.
+	a2aPollInterval = 2 * time.Second
+	// a2aDefaultTimeout bounds delegated runs whose policy sets no timeout.
+	a2aDefaultTimeout = time.Hour
+)
+
+// ErrRemoteExecMode is returned when a run of an A2A agent is started
+// outside a plan, or a non-A2A agent is started in remote mode.
+var ErrRemoteExecMode = errors.New("a2a agents run only as plan steps, in exec mode remote")
+
+// delegateRun executes a remote run on the A2A agent ag: it sends the
+// task prompt, follows the remote task by streaming or polling, records
+// status changes and artifacts as run events and output, and completes the
+// run with the task's outcome. It returns when the run has finished.
+func (s *OrchestratorService) delegateRun(ctx context.Context, r *run.Run, ag *agent.Agent) {
+	timeout := a2aDefaultTimeout
+	if p, ok := s.runtime.policy.GetProfile(r.Policy()); ok && p.Termination.TimeoutSeconds > 0 {
+		timeout = time.Duration(p.Termination.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	// Stop following the remote task once the run is cancelled locally.
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	go s.watchRemoteRun(runCtx, stop, r.ID)
+
+	d := &delegation{s: s, run: r}
+	err := d.follow(runCtx, ag)
+
+	// Cancel the remote task if we stop following it before it ends.
+	if d.client != nil && d.taskID != "" && !d.state.Terminal() && !d.state.Interrupted() {
+		cctx, ccancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		if cerr := d.client.CancelTask(cctx, d.taskID); cerr != nil {
+			slog.Warn("cancel remote a2a task failed", "run_id", r.ID, "task_id", d.taskID, "error", cerr)
+		}
+		ccancel()
+	}
+
+	payload := &messagequeue.RunCompletePayload{RunID: r.ID, TaskID: r.TaskID, ProjectID: r.ProjectID}
+	switch {
+	case ctx.Err() != nil:
+		payload.Status, payload.Error = string(run.StatusTimeout), "remote agent did not finish in time"
+	case runCtx.Err() != nil:
+		return // Cancelled locally; the run is already complete
+	case err != nil:
+		payload.Status, payload.Error = string(run.StatusFailed), "a2a: "+err.Error()
+	default:
+		payload.Status, payload.Output, payload.Error = d.outcome()
+	}
+	if err := s.runtime.HandleRunComplete(context.WithoutCancel(ctx), payload); err != nil {
+		slog.Error("complete delegated run", "run_id", r.ID, "error", err)
+	}
+}
+
+// watchRemoteRun calls stop once the run is no longer running.
+func (s *OrchestratorService) watchRemoteRun(ctx context.Context, stop context.CancelFunc, runID string) {
+	ticker := time.NewTicker(a2aPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if r, err := s.store.GetRun(ctx, runID); err == nil && r.Status != run.StatusRunning {
+				stop()
+				return
+			}
+		}
+	}
+}
+
+// delegation is the state of one run delegated to a remote agent.
+type delegation struct {
+	s      *OrchestratorService
+	run    *run.Run
+	client *a2aclient.Client
+
+	taskID    string
+	state     a2a.TaskState
+	message   string              // Text of the agent's latest status message or direct reply
+	artifacts []string            // Artifact IDs in order of appearance
+	texts     map[string][]string // Text chunks per artifact ID
+}
+
+// follow sends the task to the remote agent and follows it until it ends,
+// is interrupted, or ctx is done.
+func (d *delegation) follow(ctx context.Context, ag *agent.Agent) error {
+	baseURL := ag.Config[a2a.ConfigURL]
+	if baseURL == "" {
+		return fmt.Errorf("agent %s has no %s", ag.Name, a2a.ConfigURL)
+	}
+	token := ""
+	if name := ag.Config[a2a.ConfigTokenSecret]; name != "" {
+		env, err := d.s.runtime.secrets.Resolve(ctx, d.run.ProjectID, []string{name})
+		if err != nil {
+			return fmt.Errorf("resolve token: %w", err)
+		}
+		token = env[name]
+	}
+	t, err := d.s.store.GetTask(ctx, d.run.TaskID)
+	if err != nil {
+		return fmt.Errorf("get task: %w", err)
+	}
+	prompt := t.Prompt
+	if prompt == "" {
+		prompt = t.Title
+	}
+
+	card, err := a2aclient.FetchCard(ctx, nil, a2aclient.CardURL(baseURL), token)
+	if err != nil {
+		return err
+	}
+	d.client = a2aclient.New(card.URL, token, nil)
+	d.s.runtime.appendRunEvent(ctx, event.TypeA2ADelegated, d.run, map[string]string{
+		"agent_name": card.Name,
+		"endpoint":   card.URL,
+		"streaming":  strconv.FormatBool(card.Capabilities.Streaming),
+	})
+
+	msg := &a2a.Message{Kind: "message", Role: "user", MessageID: d.run.ID, Parts: []a2a.Part{a2a.TextPart(prompt)}}
+	if card.Capabilities.Streaming {
+		err = d.client.Stream(ctx, msg, func(ev *a2a.Event) error {
+			d.handle(ctx, ev)
+			return nil
+		})
+	} else {
+		var ev *a2a.Event
+		if ev, err = d.client.Send(ctx, msg); err == nil {
+			d.handle(ctx, ev)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// Poll until the task ends, also when a stream closed early.
+	for !d.done() {
+		if d.taskID == "" {
+			return errors.New("remote agent returned no task")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(a2aPollInterval):
+		}
+		task, err := d.client.GetTask(ctx, d.taskID)
+		if err != nil {
+			return err
+		}
+		d.handle(ctx, &a2a.Event{Kind: "task", Task: task, TaskID: task.ID, Status: &task.Status})
+	}
+	return nil
+}
+
+func (d *delegation) done() bool {
+	return d.state.Terminal() || d.state.Interrupted()
+}
+
+// handle applies an event to the delegation and records it on the run.
+func (d *delegation) handle(ctx context.Context, ev *a2a.Event) {
+	if ev.TaskID != "" {
+		d.taskID = ev.TaskID
+	}
+	switch ev.Kind {
+	case "message":
+		// A direct reply without a task ends the exchange.
+		d.message = a2a.Text(ev.Message.Parts)
+		d.output(ctx, d.message)
+		d.setState(ctx, a2a.StateCompleted)
+	case "task":
+		for i := range ev.Task.Artifacts {
+			a := &ev.Task.Artifacts[i]
+			if text := a2a.Text(a.Parts); text != strings.Join(d.texts[a.ArtifactID], "") {
+				d.artifact(ctx, a, text, false)
+			}
+		}
+		d.status(ctx, ev.Status)
+	case "status-update":
+		d.status(ctx, ev.Status)
+	case "artifact-update":
+		d.artifact(ctx, ev.Artifact, a2a.Text(ev.Artifact.Parts), ev.Append)
+	}
+}
+
+func (d *delegation) status(ctx context.Context, st *a2a.TaskStatus) {
+	if st.Message != nil {
+		if text := a2a.Text(st.Message.Parts); text != "" && text != d.message {
+			d.message = text
+			d.output(ctx, text)
+		}
+	}
+	d.setState(ctx, st.State)
+}
+
+func (d *delegation) setState(ctx context.Context, state a2a.TaskState) {
+	if state == d.state {
+		return
+	}
+	d.state = state
+	d.s.runtime.appendRunEvent(ctx, event.TypeA2ATaskStatus, d.run, map[string]string{
+		"task_id": d.taskID,
+		"state":   string(state),
+	})
+	slog.Info("remote a2a task status", "run_id", d.run.ID, "task_id", d.taskID, "state", state)
+}
+
+// artifact stores an artifact, or a chunk appended to it, and streams the
+// text as run output.
+func (d *delegation) artifact(ctx context.Context, a *a2a.Artifact, text string, appendChunk bool) {
+	if d.texts == nil {
+		d.texts = make(map[string][]string)
+	}
+	if _, seen := d.texts[a.ArtifactID]; !seen {
+		d.artifacts = append(d.artifacts, a.ArtifactID)
+	}
+	if appendChunk {
+		d.texts[a.ArtifactID] = append(d.texts[a.ArtifactID], text)
+	} else {
+		d.texts[a.ArtifactID] = []string{text}
+	}
+	d.output(ctx, text)
+	d.s.runtime.appendRunEvent(ctx, event.TypeA2AArtifact, d.run, map[string]string{
+		"task_id":     d.taskID,
+		"artifact_id": a.ArtifactID,
+		"name":        a.Name,
+		"append":      strconv.FormatBool(appendChunk),
+		"bytes":       strconv.Itoa(len(text)),
+	})
+}
+
+// output streams text to the run's output subscribers.
+func (d *delegation) output(ctx context.Context, text string) {
+	if text == "" {
+		return
+	}
+	_ = d.s.runtime.HandleRunOutput(ctx, &messagequeue.RunOutputPayload{
+		RunID:  d.run.ID,
+		TaskID: d.run.TaskID,
+		Line:   text,
+		Stream: "a2a",
+	})
+}
+
+// outcome maps the final task state to a run status, output and error.
+// The output is the text of all artifacts, or the agent's last message if
+// there are none.
+func (d *delegation) outcome() (status, output, errMsg string) {
+	parts := make([]string, 0, len(d.artifacts))
+	for _, id := range d.artifacts {
+		parts = append(parts, strings.Join(d.texts[id], ""))
+	}
+	output = strings.Join(parts, "\n\n")
+	if output == "" {
+		output = d.message
+	}
+
+	switch d.state {
+	case a2a.StateCompleted:
+		return string(run.StatusCompleted), output, ""
+	case a2a.StateCanceled:
+		return string(run.StatusCancelled), output, "remote agent cancelled the task"
+	case a2a.StateInputRequired, a2a.StateAuthRequired:
+		return string(run.StatusFailed), output, fmt.Sprintf("remote agent is waiting (%s): %s", d.state, d.message)
+	default:
+		return string(run.StatusFailed), output, fmt.Sprintf("remote task %s: %s", d.state, d.message)
+	}
+}
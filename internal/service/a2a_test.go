@@ -0,0 +1,183 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/a2a"
+	"github.com/Strob0t/CodeForge/internal/domain/agent"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/secret"
+	"github.com/Strob0t/CodeForge/internal/service"
+	"github.com/Strob0t/CodeForge/internal/vault"
+)
+
+// newRemoteAgent serves an agent card and a JSON-RPC endpoint that
+// requires the bearer token "remote-token". rpc answers each request.
+func newRemoteAgent(t *testing.T, streaming bool, rpc func(w http.ResponseWriter, method string, id json.RawMessage)) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/agent-card.json", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(a2a.AgentCard{
+			Name: "remote-coder", URL: srv.URL + "/rpc", Capabilities: a2a.Capabilities{Streaming: streaming},
+		})
+	})
+	mux.HandleFunc("/rpc", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer remote-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		rpc(w, req.Method, req.ID)
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newA2ATestSetup(t *testing.T, url string) (*orchMockStore, *runtimeMockEventStore, *service.OrchestratorService, *service.RuntimeService) {
+	t.Helper()
+	store := &orchMockStore{}
+	store.projects = []project.Project{{ID: "proj-1", Name: "test"}}
+	store.agents = newIdleAgents("a1")
+	store.agents = append(store.agents, agent.Agent{
+		ID: "remote", Name: "remote", Backend: a2a.BackendName, Status: agent.StatusIdle,
+		Config: map[string]string{a2a.ConfigURL: url, a2a.ConfigTokenSecret: "REMOTE_TOKEN"},
+	})
+	store.tasks = newPendingTasks("t1", "t2")
+	es := &runtimeMockEventStore{}
+
+	runtimeSvc := service.NewRuntimeService(store, &runtimeMockQueue{}, &runtimeMockBroadcaster{}, es,
+		service.NewPolicyService("headless-safe-sandbox", nil), &config.Runtime{StallThreshold: 5})
+	v, err := vault.New("0123456789abcdef0123456789abcdef", "secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets := service.NewSecretService(store, v)
+	if _, err := secrets.Create(context.Background(), "proj-1", &secret.CreateRequest{Name: "REMOTE_TOKEN", Value: "remote-token"}); err != nil {
+		t.Fatal(err)
+	}
+	runtimeSvc.SetSecretService(secrets)
+
+	orchSvc := service.NewOrchestratorService(store, &runtimeMockBroadcaster{}, es, runtimeSvc,
+		&config.Orchestrator{MaxParallel: 4, PingPongMaxRounds: 3})
+	runtimeSvc.SetOnRunComplete(orchSvc.HandleRunCompleted)
+	return store, es, orchSvc, runtimeSvc
+}
+
+// waitForStep waits until the plan's step of a task leaves the running state.
+func waitForStep(t *testing.T, orchSvc *service.OrchestratorService, planID, taskID string) plan.Step {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		p, err := orchSvc.GetPlan(context.Background(), planID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range p.Steps {
+			if p.Steps[i].TaskID == taskID && p.Steps[i].Status != plan.StepStatusRunning && p.Steps[i].Status != plan.StepStatusPending {
+				return p.Steps[i]
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("step of task %s did not finish", taskID)
+	return plan.Step{}
+}
+
+func startA2APlan(t *testing.T, orchSvc *service.OrchestratorService) *plan.ExecutionPlan {
+	t.Helper()
+	ctx := context.Background()
+	p, err := orchSvc.CreatePlan(ctx, &plan.CreatePlanRequest{
+		Name: "delegate", ProjectID: "proj-1", Protocol: plan.ProtocolSequential,
+		Steps: []plan.CreateStepRequest{{TaskID: "t1", AgentID: "remote"}, {TaskID: "t2", AgentID: "a1"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orchSvc.StartPlan(ctx, p.ID); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestA2ADelegation_Streaming(t *testing.T) {
+	srv := newRemoteAgent(t, true, func(w http.ResponseWriter, method string, id json.RawMessage) {
+		if method != "message/stream" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, result := range []string{
+			`{"kind":"task","id":"rt-1","status":{"state":"submitted"}}`,
+			`{"kind":"status-update","taskId":"rt-1","status":{"state":"working"}}`,
+			`{"kind":"artifact-update","taskId":"rt-1","artifact":{"artifactId":"patch","name":"patch","parts":[{"kind":"text","text":"diff --git a/x "}]}}`,
+			`{"kind":"artifact-update","taskId":"rt-1","append":true,"lastChunk":true,"artifact":{"artifactId":"patch","parts":[{"kind":"text","text":"b/x"}]}}`,
+			`{"kind":"status-update","taskId":"rt-1","final":true,"status":{"state":"completed"}}`,
+		} {
+			fmt.Fprintf(w, "data: {\"jsonrpc\":\"2.0\",\"id\":%s,\"result\":%s}\n\n", id, result)
+		}
+	})
+	store, es, orchSvc, _ := newA2ATestSetup(t, srv.URL)
+	p := startA2APlan(t, orchSvc)
+
+	step := waitForStep(t, orchSvc, p.ID, "t1")
+	if step.Status != plan.StepStatusCompleted {
+		t.Fatalf("expected the delegated step to complete, got %s (%s)", step.Status, step.Error)
+	}
+	r, _ := store.GetRun(context.Background(), step.RunID)
+	if r.ExecMode != run.ExecModeRemote || r.Output != "diff --git a/x b/x" {
+		t.Fatalf("unexpected run %s %q", r.ExecMode, r.Output)
+	}
+
+	// The plan moved on to the local step.
+	p2, _ := orchSvc.GetPlan(context.Background(), p.ID)
+	if p2.Steps[1].Status != plan.StepStatusRunning {
+		t.Fatalf("expected the next step to run, got %s", p2.Steps[1].Status)
+	}
+
+	events, _ := es.LoadByRun(context.Background(), r.ID)
+	counts := map[event.Type]int{}
+	for i := range events {
+		counts[events[i].Type]++
+	}
+	if counts[event.TypeA2ADelegated] != 1 || counts[event.TypeA2AArtifact] != 2 || counts[event.TypeA2ATaskStatus] != 3 {
+		t.Fatalf("unexpected delegation events %v", counts)
+	}
+}
+
+func TestA2ADelegation_Failed(t *testing.T) {
+	srv := newRemoteAgent(t, false, func(w http.ResponseWriter, method string, id json.RawMessage) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"kind":"task","id":"rt-2","status":{"state":"failed","message":{"kind":"message","role":"agent","messageId":"m","parts":[{"kind":"text","text":"quota exceeded"}]}}}}`, id)
+	})
+	_, _, orchSvc, runtimeSvc := newA2ATestSetup(t, srv.URL)
+	p := startA2APlan(t, orchSvc)
+
+	step := waitForStep(t, orchSvc, p.ID, "t1")
+	if step.Status != plan.StepStatusFailed || !strings.Contains(step.Error, "quota exceeded") {
+		t.Fatalf("expected the step to fail with the remote message, got %s %q", step.Status, step.Error)
+	}
+
+	// A2A agents cannot be started outside a plan.
+	_, err := runtimeSvc.StartRun(context.Background(), &run.StartRequest{TaskID: "t2", AgentID: "remote", ProjectID: "proj-1"})
+	if !errors.Is(err, service.ErrRemoteExecMode) {
+		t.Fatalf("expected ErrRemoteExecMode, got %v", err)
+	}
+}
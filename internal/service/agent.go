@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strconv"
+	"sync"
 
 	"github.com/Strob0t/CodeForge/internal/adapter/ws"
 	"github.com/Strob0t/CodeForge/internal/domain/agent"
@@ -16,6 +18,7 @@ import (
 	"github.com/Strob0t/CodeForge/internal/port/database"
 	"github.com/Strob0t/CodeForge/internal/port/eventstore"
 	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/redact"
 )
 
 // AgentService handles agent lifecycle and task dispatch.
@@ -24,6 +27,11 @@ type AgentService struct {
 	queue  messagequeue.Queue
 	hub    broadcast.Broadcaster
 	events eventstore.Store
+	dedupe *WorkerDedupe
+
+	redaction  *redact.Pipeline
+	redactMu   sync.Mutex
+	redactions map[string]int // taskID -> masks in streamed output not yet reported
 }
 
 // NewAgentService creates a new AgentService.
@@ -36,6 +44,17 @@ func (s *AgentService) SetEventStore(es eventstore.Store) {
 	s.events = es
 }
 
+// SetWorkerDedupe sets the dedupe that drops worker messages published more
+// than once.
+func (s *AgentService) SetWorkerDedupe(d *WorkerDedupe) {
+	s.dedupe = d
+}
+
+// SetRedaction sets the pipeline masking credentials in task output.
+func (s *AgentService) SetRedaction(p *redact.Pipeline) {
+	s.redaction = p
+}
+
 // List returns all agents for a project.
 func (s *AgentService) List(ctx context.Context, projectID string) ([]agent.Agent, error) {
 	return s.store.ListAgents(ctx, projectID)
@@ -56,6 +75,23 @@ func (s *AgentService) Create(ctx context.Context, projectID, name, backend stri
 	return s.store.CreateAgent(ctx, projectID, name, backend, config)
 }
 
+// SetSubProject makes the agent work on a sub-project of its project by
+// default. An empty subProjectID clears the assignment.
+func (s *AgentService) SetSubProject(ctx context.Context, agentID, subProjectID string) (*agent.Agent, error) {
+	ag, err := s.store.GetAgent(ctx, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("get agent: %w", err)
+	}
+	if err := checkSubProject(ctx, s.store, ag.ProjectID, subProjectID); err != nil {
+		return nil, err
+	}
+	if err := s.store.SetAgentSubProject(ctx, agentID, subProjectID); err != nil {
+		return nil, err
+	}
+	ag.SubProjectID = subProjectID
+	return ag, nil
+}
+
 // Delete removes an agent.
 func (s *AgentService) Delete(ctx context.Context, id string) error {
 	return s.store.DeleteAgent(ctx, id)
@@ -161,6 +197,13 @@ func (s *AgentService) StopTask(ctx context.Context, agentID, taskID string) err
 
 // HandleResult processes a task result received from a worker.
 func (s *AgentService) HandleResult(ctx context.Context, result task.Result, taskID, projectID string, costUSD float64) error {
+	// Mask credentials before the result is persisted; the event reports
+	// all masks made for the task, including its streamed output.
+	var outN, errN int
+	result.Output, outN = s.redaction.Redact(result.Output)
+	result.Error, errN = s.redaction.Redact(result.Error)
+	redactions := s.takeRedactions(taskID) + outN + errN
+
 	if err := s.store.UpdateTaskResult(ctx, taskID, result, costUSD); err != nil {
 		return fmt.Errorf("update task result: %w", err)
 	}
@@ -174,10 +217,11 @@ func (s *AgentService) HandleResult(ctx context.Context, result task.Result, tas
 
 	// Record event (agentID not available here, use empty string)
 	s.appendEvent(ctx, evType, "", taskID, projectID, map[string]string{
-		"status": status,
-		"cost":   fmt.Sprintf("%.6f", costUSD),
-		"output": truncate(result.Output, 200),
-		"error":  result.Error,
+		"status":     status,
+		"cost":       fmt.Sprintf("%.6f", costUSD),
+		"output":     truncate(result.Output, 200),
+		"error":      result.Error,
+		"redactions": strconv.Itoa(redactions),
 	})
 
 	s.hub.BroadcastEvent(ctx, ws.EventTaskStatus, ws.TaskStatusEvent{
@@ -203,6 +247,8 @@ func (s *AgentService) StartResultSubscriber(ctx context.Context) (cancel func()
 			TokensIn  int      `json:"tokens_in"`
 			TokensOut int      `json:"tokens_out"`
 			CostUSD   float64  `json:"cost_usd"`
+
+			IdempotencyKey string `json:"idempotency_key"`
 		}
 
 		if err := json.Unmarshal(data, &result); err != nil {
@@ -217,7 +263,12 @@ func (s *AgentService) StartResultSubscriber(ctx context.Context) (cancel func()
 			TokensOut: result.TokensOut,
 		}
 
-		return s.HandleResult(msgCtx, taskResult, result.TaskID, result.ProjectID, result.CostUSD)
+		return s.dedupe.Apply(msgCtx, messagequeue.SubjectTaskResult, result.IdempotencyKey, func() error {
+			if err := s.HandleResult(msgCtx, taskResult, result.TaskID, result.ProjectID, result.CostUSD); err != nil {
+				return err
+			}
+			return flushEvents(msgCtx, s.events)
+		})
 	})
 }
 
@@ -228,12 +279,35 @@ func (s *AgentService) StartOutputSubscriber(ctx context.Context) (cancel func()
 		if err := json.Unmarshal(data, &output); err != nil {
 			return fmt.Errorf("unmarshal output: %w", err)
 		}
-
-		s.hub.BroadcastEvent(msgCtx, ws.EventTaskOutput, output)
+		s.HandleOutput(msgCtx, &output)
 		return nil
 	})
 }
 
+// HandleOutput masks credentials in a streamed output line and broadcasts it.
+func (s *AgentService) HandleOutput(ctx context.Context, output *ws.TaskOutputEvent) {
+	var n int
+	output.Line, n = s.redaction.Redact(output.Line)
+	if n > 0 {
+		s.redactMu.Lock()
+		if s.redactions == nil {
+			s.redactions = make(map[string]int)
+		}
+		s.redactions[output.TaskID] += n
+		s.redactMu.Unlock()
+	}
+	s.hub.BroadcastEvent(ctx, ws.EventTaskOutput, *output)
+}
+
+// takeRedactions returns and resets the streamed-output mask count of a task.
+func (s *AgentService) takeRedactions(taskID string) int {
+	s.redactMu.Lock()
+	defer s.redactMu.Unlock()
+	n := s.redactions[taskID]
+	delete(s.redactions, taskID)
+	return n
+}
+
 // LoadTaskEvents returns all events for a task from the event store.
 func (s *AgentService) LoadTaskEvents(ctx context.Context, taskID string) ([]event.AgentEvent, error) {
 	if s.events == nil {
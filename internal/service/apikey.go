@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/apikey"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+)
+
+// apiKeyTouchInterval is how often the last use of a key is written.
+const apiKeyTouchInterval = time.Minute
+
+// apiKeyPresenceTTL is how long HasKeys trusts its last answer. Keys
+// created or revoked on this server update it at once; on other servers
+// within the TTL.
+const apiKeyPresenceTTL = 10 * time.Second
+
+// APIKeyService manages API keys and authenticates requests made with them.
+type APIKeyService struct {
+	store database.Store
+	now   func() time.Time
+
+	mu        sync.Mutex
+	touched   map[string]time.Time // Last use written per key ID
+	hasKeys   bool                 // Whether any key existed at checkedAt
+	checkedAt time.Time
+}
+
+// NewAPIKeyService creates an APIKeyService.
+func NewAPIKeyService(store database.Store) *APIKeyService {
+	return &APIKeyService{store: store, now: time.Now, touched: make(map[string]time.Time)}
+}
+
+// Create generates a key and returns it with its token. Only a hash of the
+// token is stored, so it cannot be shown again.
+func (s *APIKeyService) Create(ctx context.Context, req *apikey.CreateRequest) (*apikey.Created, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validate api key: %w", err)
+	}
+	if err := s.bindTenant(ctx, req); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("generate api key: %w", err)
+	}
+	token := apikey.TokenPrefix + hex.EncodeToString(buf)
+	sum := sha256.Sum256([]byte(token))
+	k := &apikey.Key{
+		Name:      req.Name,
+		Prefix:    token[:len(apikey.TokenPrefix)+8],
+		Hash:      sum[:],
+		Scopes:    req.Scopes,
+		TenantID:  req.TenantID,
+		RateLimit: req.RateLimit,
+		Roles:     req.Roles,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := s.store.CreateAPIKey(ctx, k); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.hasKeys, s.checkedAt = true, s.now()
+	s.mu.Unlock()
+	slog.Info("api key created", "key_id", k.ID, "name", k.Name, "scopes", k.Scopes, "tenant_id", k.TenantID)
+	return &apikey.Created{Key: *k, Token: token}, nil
+}
+
+// Get returns a key by ID.
+func (s *APIKeyService) Get(ctx context.Context, id string) (*apikey.Key, error) {
+	return s.store.GetAPIKey(ctx, id)
+}
+
+// List returns all keys.
+func (s *APIKeyService) List(ctx context.Context) ([]apikey.Key, error) {
+	return s.store.ListAPIKeys(ctx)
+}
+
+// Update replaces the name, scopes, tenant, rate limit, roles and expiry of
+// a key.
+// The token stays the same.
+func (s *APIKeyService) Update(ctx context.Context, id string, req *apikey.CreateRequest) (*apikey.Key, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validate api key: %w", err)
+	}
+	if err := s.bindTenant(ctx, req); err != nil {
+		return nil, err
+	}
+	k, err := s.store.GetAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	k.Name, k.Scopes, k.TenantID, k.RateLimit, k.Roles, k.ExpiresAt = req.Name, req.Scopes, req.TenantID, req.RateLimit, req.Roles, req.ExpiresAt
+	if err := s.store.UpdateAPIKey(ctx, k); err != nil {
+		return nil, err
+	}
+	slog.Info("api key updated", "key_id", id, "scopes", k.Scopes, "tenant_id", k.TenantID)
+	return k, nil
+}
+
+// Delete revokes a key. Requests made with it fail from then on.
+func (s *APIKeyService) Delete(ctx context.Context, id string) error {
+	if err := s.store.DeleteAPIKey(ctx, id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.touched, id)
+	s.checkedAt = time.Time{} // The last key may be gone
+	s.mu.Unlock()
+	slog.Info("api key revoked", "key_id", id)
+	return nil
+}
+
+// Authenticate returns the key a token belongs to, or apikey.ErrInvalid if
+// it is unknown or expired. The key's last use is recorded at most once
+// per minute.
+func (s *APIKeyService) Authenticate(ctx context.Context, token string) (*apikey.Key, error) {
+	if !strings.HasPrefix(token, apikey.TokenPrefix) {
+		return nil, apikey.ErrInvalid
+	}
+	sum := sha256.Sum256([]byte(token))
+	k, err := s.store.GetAPIKeyByHash(ctx, sum[:])
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, apikey.ErrInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if k.Expired(now) {
+		return nil, apikey.ErrInvalid
+	}
+
+	s.mu.Lock()
+	due := now.Sub(s.touched[k.ID]) >= apiKeyTouchInterval
+	if due {
+		s.touched[k.ID] = now
+	}
+	s.mu.Unlock()
+	if due {
+		if err := s.store.TouchAPIKey(ctx, k.ID, now); err != nil {
+			slog.Warn("record api key use failed", "key_id", k.ID, "error", err)
+		}
+		k.LastUsedAt = &now
+	}
+	return k, nil
+}
+
+// HasKeys reports whether any unexpired key exists, so auth is enforced for
+// managed keys even without configured ones. Expired keys cannot
+// authenticate, so they do not count. The answer is cached for
+// apiKeyPresenceTTL.
+func (s *APIKeyService) HasKeys(ctx context.Context) (bool, error) {
+	now := s.now()
+	s.mu.Lock()
+	if !s.checkedAt.IsZero() && now.Sub(s.checkedAt) < apiKeyPresenceTTL {
+		has := s.hasKeys
+		s.mu.Unlock()
+		return has, nil
+	}
+	s.mu.Unlock()
+
+	keys, err := s.store.ListAPIKeys(ctx)
+	if err != nil {
+		return false, fmt.Errorf("list api keys: %w", err)
+	}
+	has := false
+	for i := range keys {
+		if !keys[i].Expired(now) {
+			has = true
+			break
+		}
+	}
+	s.mu.Lock()
+	s.hasKeys, s.checkedAt = has, now
+	s.mu.Unlock()
+	return has, nil
+}
+
+// bindTenant binds a key created or updated by a tenant-scoped request
+// without a tenant to the request's tenant, and checks that the tenant the
+// key is bound to exists. A scoped request cannot see other tenants, so it
+// cannot bind keys to them.
+func (s *APIKeyService) bindTenant(ctx context.Context, req *apikey.CreateRequest) error {
+	if req.TenantID == "" {
+		req.TenantID = tenant.FromContext(ctx)
+	}
+	if req.TenantID == "" {
+		return nil
+	}
+	if _, err := s.store.GetTenant(ctx, req.TenantID); err != nil {
+		return fmt.Errorf("get tenant: %w", err)
+	}
+	return nil
+}
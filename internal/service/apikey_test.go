@@ -0,0 +1,87 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/apikey"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestAPIKeyService(t *testing.T) {
+	_, store, _, _ := newRuntimeTestEnv()
+	store.tenants = []tenant.Tenant{{ID: "acme", Name: "Acme"}}
+	svc := service.NewAPIKeyService(store)
+	ctx := context.Background()
+
+	if has, err := svc.HasKeys(ctx); err != nil || has {
+		t.Fatalf("expected no keys, got %v, %v", has, err)
+	}
+	if _, err := svc.Create(ctx, &apikey.CreateRequest{Name: "ci", Scopes: []apikey.Scope{apikey.ScopeRead}, TenantID: "globex"}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected unknown tenant to fail, got %v", err)
+	}
+
+	created, err := svc.Create(ctx, &apikey.CreateRequest{
+		Name: "ci", Scopes: []apikey.Scope{apikey.ScopeRunsWrite}, TenantID: "acme", RateLimit: 60,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if has, _ := svc.HasKeys(ctx); !has {
+		t.Fatal("expected a created key to enforce auth at once")
+	}
+	if !strings.HasPrefix(created.Token, apikey.TokenPrefix) || !strings.HasPrefix(created.Token, created.Prefix) {
+		t.Fatalf("unexpected token %q with prefix %q", created.Token, created.Prefix)
+	}
+	if strings.Contains(string(store.apiKeys[0].Hash), created.Token) {
+		t.Fatal("the token must not be stored")
+	}
+
+	k, err := svc.Authenticate(ctx, created.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.ID != created.ID || k.TenantID != "acme" || k.LastUsedAt == nil {
+		t.Fatalf("unexpected key %+v", k)
+	}
+	if store.apiKeys[0].LastUsedAt == nil {
+		t.Fatal("expected the last use to be stored")
+	}
+	for _, token := range []string{"", "other", apikey.TokenPrefix + "0000"} {
+		if _, err := svc.Authenticate(ctx, token); !errors.Is(err, apikey.ErrInvalid) {
+			t.Fatalf("token %q: expected ErrInvalid, got %v", token, err)
+		}
+	}
+
+	updated, err := svc.Update(ctx, created.ID, &apikey.CreateRequest{Name: "ci", Scopes: []apikey.Scope{apikey.ScopeRead}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.TenantID != "" || updated.Allows(apikey.ScopeRunsWrite) {
+		t.Fatalf("unexpected updated key %+v", updated)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	store.apiKeys[0].ExpiresAt = &past
+	if _, err := svc.Authenticate(ctx, created.Token); !errors.Is(err, apikey.ErrInvalid) {
+		t.Fatalf("expected an expired key to fail, got %v", err)
+	}
+	if has, _ := service.NewAPIKeyService(store).HasKeys(ctx); has {
+		t.Fatal("expected only expired keys not to count as keys")
+	}
+
+	if err := svc.Delete(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Get(ctx, created.ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected the key to be gone, got %v", err)
+	}
+	if has, _ := svc.HasKeys(ctx); has {
+		t.Fatal("expected no keys after revoking the last one")
+	}
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+)
+
+// ArtifactService provides read access to run artifacts.
+type ArtifactService struct {
+	store database.Store
+}
+
+// NewArtifactService creates an ArtifactService.
+func NewArtifactService(store database.Store) *ArtifactService {
+	return &ArtifactService{store: store}
+}
+
+// Get returns an artifact including its data.
+func (s *ArtifactService) Get(ctx context.Context, id string) (*artifact.Artifact, error) {
+	return s.store.GetArtifact(ctx, id)
+}
+
+// ListByRun returns artifact metadata for a run.
+func (s *ArtifactService) ListByRun(ctx context.Context, runID string) ([]artifact.Artifact, error) {
+	return s.store.ListArtifactsByRun(ctx, runID)
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+)
+
+// AttachmentDir is the directory of the workspace, relative to its root,
+// that a run's task attachments are written to. It ignores itself so
+// delivery never commits the attachments.
+const AttachmentDir = ".codeforge/attachments"
+
+// ErrAttachmentTooLarge is returned for uploads beyond attachments.max_bytes.
+var ErrAttachmentTooLarge = errors.New("attachment too large")
+
+// ErrInvalidAttachment is returned for uploads without a file name or
+// whose content type is not in attachments.allowed_types.
+var ErrInvalidAttachment = errors.New("invalid attachment")
+
+// ErrUnknownAttachment is returned when a message references an attachment
+// its project does not have.
+var ErrUnknownAttachment = errors.New("unknown attachment")
+
+// AttachmentService stores the files users attach to tasks and conversation
+// messages as attachment artifacts of their project.
+type AttachmentService struct {
+	store database.Store
+	cfg   config.Attachments
+}
+
+// NewAttachmentService creates an AttachmentService enforcing the size and
+// type limits of cfg.
+func NewAttachmentService(store database.Store, cfg config.Attachments) *AttachmentService {
+	return &AttachmentService{store: store, cfg: cfg}
+}
+
+// MaxBytes returns the max size of an attachment.
+func (s *AttachmentService) MaxBytes() int {
+	return s.cfg.MaxBytes
+}
+
+// Upload stores a file as an attachment of a project and, if taskID is
+// set, of one of its tasks. The content type is checked against the
+// allowed types; the name is reduced to its base name.
+func (s *AttachmentService) Upload(ctx context.Context, projectID, taskID, name, contentType string, data []byte) (*artifact.Artifact, error) {
+	name = path.Base(filepath.ToSlash(strings.TrimSpace(name)))
+	if name == "." || name == ".." || name == "/" {
+		return nil, fmt.Errorf("%w: file name is required", ErrInvalidAttachment)
+	}
+	if len(data) > s.cfg.MaxBytes {
+		return nil, fmt.Errorf("%w: %d bytes exceeds %d", ErrAttachmentTooLarge, len(data), s.cfg.MaxBytes)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !s.allowed(mediaType) {
+		return nil, fmt.Errorf("%w: content type %q is not allowed", ErrInvalidAttachment, contentType)
+	}
+	if _, err := s.store.GetProject(ctx, projectID); err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+	if taskID != "" {
+		t, err := s.store.GetTask(ctx, taskID)
+		if err != nil {
+			return nil, fmt.Errorf("get task: %w", err)
+		}
+		if t.ProjectID != projectID {
+			return nil, fmt.Errorf("task %s in project %s: %w", taskID, projectID, domain.ErrNotFound)
+		}
+	}
+
+	a := &artifact.Artifact{
+		ProjectID:   projectID,
+		TaskID:      taskID,
+		Kind:        artifact.KindAttachment,
+		Name:        name,
+		ContentType: contentType,
+		Data:        data,
+	}
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.store.CreateArtifact(ctx, a); err != nil {
+		return nil, err
+	}
+	a.Data = nil
+	return a, nil
+}
+
+// Get returns an attachment with its data. Other artifacts are not found.
+func (s *AttachmentService) Get(ctx context.Context, id string) (*artifact.Artifact, error) {
+	a, err := s.store.GetArtifact(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if a.Kind != artifact.KindAttachment {
+		return nil, fmt.Errorf("attachment %s: %w", id, domain.ErrNotFound)
+	}
+	return a, nil
+}
+
+// ListByTask returns the attachments of a task, without their data.
+func (s *AttachmentService) ListByTask(ctx context.Context, taskID string) ([]artifact.Artifact, error) {
+	return s.store.ListArtifactsByTask(ctx, taskID)
+}
+
+// Resolve returns the attachments with the given IDs, with their data, in
+// order. Each must be an attachment of the project.
+func (s *AttachmentService) Resolve(ctx context.Context, projectID string, ids []string) ([]artifact.Artifact, error) {
+	out := make([]artifact.Artifact, 0, len(ids))
+	for _, id := range ids {
+		a, err := s.Get(ctx, id)
+		if errors.Is(err, domain.ErrNotFound) || (err == nil && a.ProjectID != projectID) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownAttachment, id)
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *a)
+	}
+	return out, nil
+}
+
+// Materialize writes the attachments of a task to AttachmentDir under dir
+// and returns them with their paths relative to dir. Files sharing a name
+// get a numeric prefix.
+func (s *AttachmentService) Materialize(ctx context.Context, taskID, dir string) ([]messagequeue.AttachmentPayload, error) {
+	list, err := s.store.ListArtifactsByTask(ctx, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("list attachments: %w", err)
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	target := filepath.Join(dir, filepath.FromSlash(AttachmentDir))
+	if err := os.MkdirAll(target, 0o755); err != nil {
+		return nil, fmt.Errorf("create attachment dir: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(target, ".gitignore"), []byte("*\n"), 0o644); err != nil {
+		return nil, fmt.Errorf("write attachment .gitignore: %w", err)
+	}
+
+	out := make([]messagequeue.AttachmentPayload, 0, len(list))
+	seen := map[string]bool{".gitignore": true}
+	for i := range list {
+		a, err := s.store.GetArtifact(ctx, list[i].ID)
+		if err != nil {
+			return nil, fmt.Errorf("get attachment %s: %w", list[i].ID, err)
+		}
+		name := a.Name
+		if seen[name] {
+			name = strconv.Itoa(i+1) + "-" + name
+		}
+		seen[name] = true
+		if err := os.WriteFile(filepath.Join(target, name), a.Data, 0o644); err != nil {
+			return nil, fmt.Errorf("write attachment %s: %w", a.Name, err)
+		}
+		out = append(out, messagequeue.AttachmentPayload{
+			ID:          a.ID,
+			Path:        AttachmentDir + "/" + name,
+			ContentType: a.ContentType,
+			Size:        a.Size,
+		})
+	}
+	return out, nil
+}
+
+// allowed reports whether a media type matches one of the allowed types.
+func (s *AttachmentService) allowed(mediaType string) bool {
+	for _, t := range s.cfg.AllowedTypes {
+		if family, ok := strings.CutSuffix(t, "/*"); ok {
+			if strings.HasPrefix(mediaType, family+"/") {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(t, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// materializeAttachments writes the attachments of a run's task into its
+// worktree, or the project workspace, and lists them in the start payload
+// and prompt. Failures are logged; the run starts without them.
+func (s *RuntimeService) materializeAttachments(ctx context.Context, r *run.Run, payload *messagequeue.RunStartPayload) {
+	dir := r.WorktreePath
+	if dir == "" {
+		proj, err := s.store.GetProject(ctx, r.ProjectID)
+		if err != nil || proj.WorkspacePath == "" {
+			return
+		}
+		dir = proj.WorkspacePath
+	}
+	files, err := s.attachments.Materialize(ctx, r.TaskID, dir)
+	if err != nil {
+		slog.Warn("materialize attachments failed", "run_id", r.ID, "error", err)
+		return
+	}
+	if len(files) == 0 {
+		return
+	}
+	payload.Attachments = files
+	payload.Prompt += "\n\n" + attachmentSection(files)
+}
+
+// attachmentSection lists the attachments written into a run's workspace
+// for its prompt.
+func attachmentSection(files []messagequeue.AttachmentPayload) string {
+	var b strings.Builder
+	b.WriteString("## Attached files\n\nThe task comes with these files, readable in the workspace:")
+	for i := range files {
+		fmt.Fprintf(&b, "\n- %s (%s, %d bytes)", files[i].Path, files[i].ContentType, files[i].Size)
+	}
+	return b.String()
+}
@@ -0,0 +1,170 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/conversation"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func newTestAttachmentService(store *runtimeMockStore) *service.AttachmentService {
+	return service.NewAttachmentService(store, config.Attachments{MaxBytes: 64, AllowedTypes: []string{"image/*", "text/plain"}})
+}
+
+func TestAttachmentService_UploadChecksLimits(t *testing.T) {
+	_, store, _, _ := newRuntimeTestEnv()
+	store.tasks = append(store.tasks, task.Task{ID: "task-other", ProjectID: "proj-other"})
+	svc := newTestAttachmentService(store)
+	ctx := context.Background()
+
+	if _, err := svc.Upload(ctx, "proj-1", "", "big.txt", "text/plain", make([]byte, 65)); !errors.Is(err, service.ErrAttachmentTooLarge) {
+		t.Fatalf("expected ErrAttachmentTooLarge, got %v", err)
+	}
+	if _, err := svc.Upload(ctx, "proj-1", "", "doc.pdf", "application/pdf", []byte("%PDF")); !errors.Is(err, service.ErrInvalidAttachment) {
+		t.Fatalf("expected ErrInvalidAttachment for a disallowed type, got %v", err)
+	}
+	if _, err := svc.Upload(ctx, "proj-1", "task-other", "a.txt", "text/plain", []byte("a")); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a task of another project, got %v", err)
+	}
+
+	a, err := svc.Upload(ctx, "proj-1", "task-1", "../../etc/notes.txt", "text/plain; charset=utf-8", []byte("hello"))
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if a.Name != "notes.txt" || a.TaskID != "task-1" || a.RunID != "" || a.Size != 5 || a.Data != nil {
+		t.Fatalf("unexpected attachment: %+v", a)
+	}
+	list, err := svc.ListByTask(ctx, "task-1")
+	if err != nil || len(list) != 1 || list[0].ID != a.ID {
+		t.Fatalf("expected the attachment listed for its task, got %+v, %v", list, err)
+	}
+	if _, err := svc.Resolve(ctx, "proj-2", []string{a.ID}); !errors.Is(err, service.ErrUnknownAttachment) {
+		t.Fatalf("expected ErrUnknownAttachment from another project, got %v", err)
+	}
+}
+
+func TestStartRun_MaterializesTaskAttachments(t *testing.T) {
+	svc, store, queue, _ := newRuntimeTestEnv()
+	workspace := t.TempDir()
+	store.projects[0].WorkspacePath = workspace
+	attachments := newTestAttachmentService(store)
+	svc.SetAttachmentService(attachments)
+	ctx := context.Background()
+
+	for _, name := range []string{"trace.txt", "trace.txt"} {
+		if _, err := attachments.Upload(ctx, "proj-1", "task-1", name, "text/plain", []byte("panic: "+name)); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+	}
+	if _, err := svc.StartRun(ctx, &run.StartRequest{TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1"}); err != nil {
+		t.Fatalf("StartRun failed: %v", err)
+	}
+
+	p := lastStart(t, queue)
+	if len(p.Attachments) != 2 || p.Attachments[0].Path != service.AttachmentDir+"/trace.txt" ||
+		p.Attachments[1].Path != service.AttachmentDir+"/2-trace.txt" {
+		t.Fatalf("expected both attachments in the payload, got %+v", p.Attachments)
+	}
+	if !strings.Contains(p.Prompt, "## Attached files") || !strings.Contains(p.Prompt, "2-trace.txt") {
+		t.Fatalf("expected the attachments listed in the prompt, got %q", p.Prompt)
+	}
+	dir := filepath.Join(workspace, filepath.FromSlash(service.AttachmentDir))
+	if data, err := os.ReadFile(filepath.Join(dir, "2-trace.txt")); err != nil || string(data) != "panic: trace.txt" {
+		t.Fatalf("expected the attachment in the workspace, got %q, %v", data, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, ".gitignore")); err != nil || string(data) != "*\n" {
+		t.Fatalf("expected the attachment dir to ignore itself, got %q, %v", data, err)
+	}
+}
+
+func TestConversationService_SendAttachments(t *testing.T) {
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/model/info" {
+			_ = json.NewEncoder(w).Encode(map[string][]litellm.Model{"data": {
+				{ModelName: "vision-model", ModelInfo: map[string]any{"supports_vision": true}},
+				{ModelName: "text-model"},
+			}})
+			return
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"content": "ok"}}},
+		})
+	}))
+	defer srv.Close()
+
+	_, store, _, _ := newRuntimeTestEnv()
+	attachments := newTestAttachmentService(store)
+	svc := service.NewConversationService(store, litellm.NewClient(srv.URL, ""), &config.Conversation{Model: "vision-model", MaxTokens: 100})
+	svc.SetAttachmentService(attachments)
+	ctx := context.Background()
+
+	img, err := attachments.Upload(ctx, "proj-1", "", "screen.png", "image/png", []byte{0x89, 'P', 'N', 'G'})
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	log, err := attachments.Upload(ctx, "proj-1", "", "build.log", "text/plain", []byte("undefined: foo"))
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	// lastContent returns the content of the last message sent to the LLM.
+	lastContent := func() any {
+		msgs := bodies[len(bodies)-1]["messages"].([]any)
+		return msgs[len(msgs)-1].(map[string]any)["content"]
+	}
+
+	vision, err := svc.Create(ctx, "proj-1", conversation.CreateRequest{Title: "ui"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := svc.Send(ctx, vision.ID, conversation.SendRequest{Content: "why?", Attachments: []string{img.ID, log.ID}}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	parts, ok := lastContent().([]any)
+	if !ok || len(parts) != 2 {
+		t.Fatalf("expected a text and an image part, got %#v", lastContent())
+	}
+	text := parts[0].(map[string]any)["text"].(string)
+	url := parts[1].(map[string]any)["image_url"].(map[string]any)["url"].(string)
+	if !strings.Contains(text, "Attached file build.log") || !strings.Contains(text, "undefined: foo") ||
+		!strings.HasPrefix(url, "data:image/png;base64,") {
+		t.Fatalf("unexpected parts: %q, %q", text, url)
+	}
+	msgs, _ := svc.Messages(ctx, vision.ID, false)
+	if len(msgs[0].Attachments) != 2 {
+		t.Fatalf("expected the attachments recorded on the user message, got %+v", msgs[0])
+	}
+
+	plain, err := svc.Create(ctx, "proj-1", conversation.CreateRequest{Title: "cli", Model: "text-model"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := svc.Send(ctx, plain.ID, conversation.SendRequest{Content: "why?", Attachments: []string{img.ID}}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if content, ok := lastContent().(string); !ok || !strings.Contains(content, "screen.png (image/png, 4 bytes) cannot be shown") {
+		t.Fatalf("expected the image described for a text model, got %#v", lastContent())
+	}
+
+	if _, err := svc.Send(ctx, plain.ID, conversation.SendRequest{Content: "and?", Attachments: []string{"missing"}}); !errors.Is(err, service.ErrUnknownAttachment) {
+		t.Fatalf("expected ErrUnknownAttachment, got %v", err)
+	}
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/audit"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/port/auditsink"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/eventstore"
+)
+
+const (
+	// auditSettle is how old an entry must be before it is exported.
+	// Entries get their seq on insert but become visible on commit, so a
+	// lower seq can show up after a higher one; waiting keeps a sink's
+	// checkpoint from moving past entries that are not visible yet.
+	auditSettle = 5 * time.Second
+	// auditMaxBatches bounds the batches sent to one sink per pass, so a
+	// sink with a large backlog does not hold up the others.
+	auditMaxBatches = 20
+	// auditMaxBackoff caps the wait before a failing sink is retried.
+	auditMaxBackoff = 5 * time.Minute
+)
+
+// AuditService records audit entries of API changes and exports them to
+// the sinks of each tenant. Delivery is at least once: a sink's checkpoint
+// only advances after it accepted a batch, and failed batches are sent
+// again with exponential backoff.
+type AuditService struct {
+	store   database.Store
+	events  eventstore.Store
+	secrets *SecretService
+	cfg     config.Audit
+	now     func() time.Time
+
+	mu       sync.Mutex
+	failures map[string]sinkFailure // Per sink ID
+}
+
+// sinkFailure tracks consecutive failed deliveries to a sink.
+type sinkFailure struct {
+	count   int
+	retryAt time.Time
+}
+
+// NewAuditService creates an AuditService.
+func NewAuditService(store database.Store, events eventstore.Store, cfg config.Audit) *AuditService {
+	return &AuditService{store: store, events: events, cfg: cfg, now: time.Now, failures: make(map[string]sinkFailure)}
+}
+
+// SetSecretService enables token_secret in sink configs.
+func (s *AuditService) SetSecretService(secrets *SecretService) {
+	s.secrets = secrets
+}
+
+// Record appends an audit entry; entries without a tenant belong to the
+// default tenant. Failures are logged, not returned, so that they do not
+// fail the audited request.
+func (s *AuditService) Record(ctx context.Context, e *event.AuditEntry) {
+	if !s.cfg.Enabled {
+		return
+	}
+	if e.TenantID == "" {
+		e.TenantID = tenant.DefaultID
+	}
+	if err := s.events.AppendAudit(ctx, e); err != nil {
+		slog.Error("record audit entry", "method", e.Method, "path", e.Path, "error", err)
+	}
+}
+
+// ListEntries returns up to limit entries with a seq above afterSeq, of the
+// request's tenant or, for unscoped requests, of all tenants.
+func (s *AuditService) ListEntries(ctx context.Context, afterSeq int64, limit int) ([]event.AuditEntry, error) {
+	return s.events.LoadAudit(ctx, tenant.FromContext(ctx), afterSeq, time.Time{}, limit)
+}
+
+// CreateSink registers a sink. It receives all of its tenant's entries,
+// starting with the oldest.
+func (s *AuditService) CreateSink(ctx context.Context, req *audit.CreateRequest) (*audit.Sink, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validate audit sink: %w", err)
+	}
+	tenantID := req.TenantID
+	scoped := tenant.FromContext(ctx)
+	switch {
+	case tenantID == "" && scoped != "":
+		tenantID = scoped
+	case tenantID == "":
+		tenantID = tenant.DefaultID
+	case scoped != "" && tenantID != scoped:
+		return nil, fmt.Errorf("tenant %s: %w", tenantID, domain.ErrNotFound)
+	}
+	if _, err := s.store.GetTenant(ctx, tenantID); err != nil {
+		return nil, fmt.Errorf("get tenant: %w", err)
+	}
+
+	sink := &audit.Sink{
+		TenantID: tenantID,
+		Name:     req.Name,
+		Kind:     req.Kind,
+		Config:   req.Config,
+		Enabled:  req.Enabled == nil || *req.Enabled,
+	}
+	if err := s.store.CreateAuditSink(ctx, sink); err != nil {
+		return nil, err
+	}
+	slog.Info("audit sink created", "sink_id", sink.ID, "tenant_id", tenantID, "kind", sink.Kind)
+	return sink, nil
+}
+
+// GetSink returns a sink by ID.
+func (s *AuditService) GetSink(ctx context.Context, id string) (*audit.Sink, error) {
+	return s.store.GetAuditSink(ctx, id)
+}
+
+// ListSinks returns the sinks of the request's tenant, or of all tenants
+// for unscoped requests.
+func (s *AuditService) ListSinks(ctx context.Context) ([]audit.Sink, error) {
+	return s.store.ListAuditSinks(ctx)
+}
+
+// UpdateSink replaces the name, kind, config and enabled flag of a sink.
+// Its tenant and checkpoint stay the same.
+func (s *AuditService) UpdateSink(ctx context.Context, id string, req *audit.CreateRequest) (*audit.Sink, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validate audit sink: %w", err)
+	}
+	sink, err := s.store.GetAuditSink(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	sink.Name, sink.Kind, sink.Config = req.Name, req.Kind, req.Config
+	sink.Enabled = req.Enabled == nil || *req.Enabled
+	if err := s.store.UpdateAuditSink(ctx, sink); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	delete(s.failures, id)
+	s.mu.Unlock()
+	return sink, nil
+}
+
+// DeleteSink removes a sink.
+func (s *AuditService) DeleteSink(ctx context.Context, id string) error {
+	if err := s.store.DeleteAuditSink(ctx, id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.failures, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// StartPublisher runs Publish every export interval until ctx is done or
+// cancel is called. It does nothing if the interval is 0.
+func (s *AuditService) StartPublisher(ctx context.Context) (cancel func()) {
+	ctx, cancel = context.WithCancel(ctx)
+	if s.cfg.ExportInterval <= 0 {
+		return cancel
+	}
+	go func() {
+		ticker := time.NewTicker(s.cfg.ExportInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.Publish(ctx); err != nil {
+					slog.Error("audit export failed", "error", err)
+				}
+			}
+		}
+	}()
+	return cancel
+}
+
+// Publish exports new entries to every enabled sink that is not backing
+// off and returns how many entries were delivered. A failing sink records
+// its error and does not stop the others.
+func (s *AuditService) Publish(ctx context.Context) (int, error) {
+	sinks, err := s.store.ListAuditSinks(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list audit sinks: %w", err)
+	}
+	total := 0
+	for i := range sinks {
+		sink := &sinks[i]
+		if !sink.Enabled || !s.due(sink.ID) {
+			continue
+		}
+		n, err := s.export(ctx, sink)
+		total += n
+		s.settle(sink.ID, err)
+		if err != nil {
+			slog.Warn("audit sink delivery failed", "sink_id", sink.ID, "tenant_id", sink.TenantID, "kind", sink.Kind, "error", err)
+			if perr := s.store.SetAuditSinkProgress(ctx, sink.ID, sink.Checkpoint, err.Error()); perr != nil {
+				slog.Error("record audit sink error", "sink_id", sink.ID, "error", perr)
+			}
+		}
+	}
+	return total, nil
+}
+
+// export sends the sink's entries after its checkpoint in batches,
+// advancing the checkpoint after each accepted batch.
+func (s *AuditService) export(ctx context.Context, sink *audit.Sink) (int, error) {
+	cfg, err := s.sinkConfig(ctx, sink)
+	if err != nil {
+		return 0, err
+	}
+	out, err := auditsink.New(string(sink.Kind), cfg)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = out.Close() }()
+
+	until := s.now().Add(-auditSettle)
+	sent := 0
+	for range auditMaxBatches {
+		entries, err := s.events.LoadAudit(ctx, sink.TenantID, sink.Checkpoint, until, s.cfg.BatchSize)
+		if err != nil {
+			return sent, fmt.Errorf("load audit entries: %w", err)
+		}
+		if len(entries) == 0 {
+			break
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, s.cfg.SendTimeout)
+		err = out.Send(sendCtx, entries)
+		cancel()
+		if err != nil {
+			return sent, err
+		}
+		last := entries[len(entries)-1].Seq
+		if err := s.store.SetAuditSinkProgress(ctx, sink.ID, last, ""); err != nil {
+			return sent, fmt.Errorf("checkpoint: %w", err)
+		}
+		sink.Checkpoint = last
+		sent += len(entries)
+		if len(entries) < s.cfg.BatchSize {
+			break
+		}
+	}
+	return sent, nil
+}
+
+// sinkConfig returns the sink's config with its token_secret resolved from
+// the tenant's secrets.
+func (s *AuditService) sinkConfig(ctx context.Context, sink *audit.Sink) (map[string]string, error) {
+	cfg := maps.Clone(sink.Config)
+	name := cfg[audit.ConfigTokenSecret]
+	if name == "" {
+		return cfg, nil
+	}
+	if s.secrets == nil {
+		return nil, errors.New("token_secret needs secrets.master_key")
+	}
+	env, err := s.secrets.Resolve(tenant.NewContext(ctx, sink.TenantID), "", []string{name})
+	if err != nil {
+		return nil, fmt.Errorf("resolve token: %w", err)
+	}
+	cfg[audit.ConfigToken] = env[name]
+	return cfg, nil
+}
+
+// due reports whether a sink is not backing off after failures.
+func (s *AuditService) due(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.now().Before(s.failures[id].retryAt)
+}
+
+// settle resets a sink's backoff after a success and doubles it, from the
+// export interval up to auditMaxBackoff, after a failure.
+func (s *AuditService) settle(id string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		delete(s.failures, id)
+		return
+	}
+	f := s.failures[id]
+	f.count++
+	wait := s.cfg.ExportInterval << min(f.count-1, 16)
+	if wait <= 0 || wait > auditMaxBackoff {
+		wait = auditMaxBackoff
+	}
+	f.retryAt = s.now().Add(wait)
+	s.failures[id] = f
+}
@@ -0,0 +1,139 @@
+package service_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/Strob0t/CodeForge/internal/adapter/siem"
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/audit"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/secret"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestAuditService_Publish(t *testing.T) {
+	_, store, _, _ := newRuntimeTestEnv()
+	store.tenants = []tenant.Tenant{{ID: "acme", Name: "Acme"}, {ID: "globex", Name: "Globex"}}
+	es := &runtimeMockEventStore{}
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var received []event.AuditEntry
+	var auth string
+	failing := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failing {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		auth = r.Header.Get("Authorization")
+		sc := bufio.NewScanner(r.Body)
+		for sc.Scan() {
+			var e event.AuditEntry
+			if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+				t.Error(err)
+			}
+			received = append(received, e)
+		}
+	}))
+	defer srv.Close()
+
+	// Entries must be older than the settle window to be exported.
+	old := time.Now().Add(-time.Minute)
+	for _, tid := range []string{"acme", "globex", "acme", "acme"} {
+		if err := es.AppendAudit(ctx, &event.AuditEntry{TenantID: tid, Method: "POST", Path: "/api/v1/projects", Status: 201, CreatedAt: old}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := es.AppendAudit(ctx, &event.AuditEntry{TenantID: "acme", Method: "DELETE", Path: "/api/v1/projects/p1", Status: 204}); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := service.NewAuditService(store, es, config.Audit{Enabled: true, ExportInterval: time.Minute, BatchSize: 2, SendTimeout: 5 * time.Second})
+	secrets := newTestSecretService(t, store)
+	svc.SetSecretService(secrets)
+	acmeCtx := tenant.NewContext(ctx, "acme")
+	if _, err := secrets.Create(acmeCtx, "", &secret.CreateRequest{Name: "HEC_TOKEN", Value: "hec-token"}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := &audit.CreateRequest{
+		Name: "splunk", Kind: audit.KindHTTP,
+		Config: map[string]string{audit.ConfigURL: srv.URL, audit.ConfigTokenSecret: "HEC_TOKEN", audit.ConfigAuthScheme: "Splunk"},
+	}
+	if _, err := svc.CreateSink(acmeCtx, &audit.CreateRequest{Name: "other", TenantID: "globex", Kind: req.Kind, Config: req.Config}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected another tenant to be hidden, got %v", err)
+	}
+	sink, err := svc.CreateSink(acmeCtx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sink.TenantID != "acme" || !sink.Enabled {
+		t.Fatalf("unexpected sink %+v", sink)
+	}
+
+	// A failed delivery keeps the checkpoint and backs off.
+	if n, err := svc.Publish(ctx); err != nil || n != 0 {
+		t.Fatalf("expected nothing delivered, got %d, %v", n, err)
+	}
+	got, _ := svc.GetSink(ctx, sink.ID)
+	if got.Checkpoint != 0 || got.LastError == "" {
+		t.Fatalf("expected the failure to be recorded, got %+v", got)
+	}
+	mu.Lock()
+	failing = false
+	mu.Unlock()
+	if n, _ := svc.Publish(ctx); n != 0 {
+		t.Fatalf("expected the sink to back off, delivered %d", n)
+	}
+
+	// Updating the sink retries it right away.
+	if _, err := svc.UpdateSink(ctx, sink.ID, req); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := svc.Publish(ctx); err != nil || n != 3 {
+		t.Fatalf("expected 3 entries delivered, got %d, %v", n, err)
+	}
+	got, _ = svc.GetSink(ctx, sink.ID)
+	if got.Checkpoint != 4 || got.LastError != "" || got.LastDeliveredAt == nil {
+		t.Fatalf("unexpected progress %+v", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if auth != "Splunk hec-token" {
+		t.Fatalf("unexpected Authorization %q", auth)
+	}
+	if len(received) != 3 || received[0].Seq != 1 || received[1].Seq != 3 || received[2].Seq != 4 {
+		t.Fatalf("expected acme's settled entries in order, got %+v", received)
+	}
+}
+
+func TestAuditService_RecordDisabled(t *testing.T) {
+	_, store, _, _ := newRuntimeTestEnv()
+	es := &runtimeMockEventStore{}
+	ctx := context.Background()
+
+	service.NewAuditService(store, es, config.Audit{}).Record(ctx, &event.AuditEntry{Method: "POST", Path: "/api/v1/projects"})
+	svc := service.NewAuditService(store, es, config.Audit{Enabled: true})
+	svc.Record(ctx, &event.AuditEntry{Method: "PUT", Path: "/api/v1/projects/p1"})
+
+	entries, err := svc.ListEntries(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Method != "PUT" || entries[0].TenantID != tenant.DefaultID {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+}
@@ -0,0 +1,370 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/benchmark"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+)
+
+// benchmarkGitProvider clones benchmark repositories.
+const benchmarkGitProvider = "local"
+
+// maxValidationOutput caps the validation output kept on a result.
+const maxValidationOutput = 4096
+
+// BenchmarkService runs benchmark suites across agents and models and
+// scores each case by running its validation command in the workspace the
+// agent left behind.
+type BenchmarkService struct {
+	store    database.Store
+	orch     *OrchestratorService
+	projects *ProjectService
+	cfg      config.Benchmark
+	suites   []benchmark.Suite
+}
+
+// NewBenchmarkService creates a BenchmarkService for the given suites.
+func NewBenchmarkService(
+	store database.Store,
+	orch *OrchestratorService,
+	projects *ProjectService,
+	cfg config.Benchmark,
+	suites []benchmark.Suite,
+) *BenchmarkService {
+	return &BenchmarkService{
+		store:    store,
+		orch:     orch,
+		projects: projects,
+		cfg:      cfg,
+		suites:   suites,
+	}
+}
+
+// ListSuites returns the loaded benchmark suites.
+func (s *BenchmarkService) ListSuites() []benchmark.Suite {
+	return s.suites
+}
+
+// GetSuite returns a suite by name.
+func (s *BenchmarkService) GetSuite(name string) (*benchmark.Suite, error) {
+	for i := range s.suites {
+		if s.suites[i].Name == name {
+			return &s.suites[i], nil
+		}
+	}
+	return nil, fmt.Errorf("benchmark suite %s: %w", name, domain.ErrNotFound)
+}
+
+// Start runs a suite across the requested targets. Cases are grouped by
+// repository; each repository gets a parallel execution plan with one step
+// per case and target, so every attempt runs in its own worktree. Targets
+// share a task per case, which makes their runs comparable side by side.
+func (s *BenchmarkService) Start(ctx context.Context, req *benchmark.StartRequest) (*benchmark.Run, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	suite, err := s.GetSuite(req.Suite)
+	if err != nil {
+		return nil, err
+	}
+	cases, err := selectCases(suite, req.Cases)
+	if err != nil {
+		return nil, err
+	}
+	for i := range req.Targets {
+		if _, err := s.store.GetAgent(ctx, req.Targets[i].AgentID); err != nil {
+			return nil, fmt.Errorf("get agent %s: %w", req.Targets[i].AgentID, err)
+		}
+	}
+
+	// Group cases by repository, keeping suite order.
+	var repos []string
+	byRepo := make(map[string][]benchmark.Case)
+	for i := range cases {
+		if _, ok := byRepo[cases[i].Repo]; !ok {
+			repos = append(repos, cases[i].Repo)
+		}
+		byRepo[cases[i].Repo] = append(byRepo[cases[i].Repo], cases[i])
+	}
+
+	br := &benchmark.Run{Suite: suite.Name, Status: benchmark.StatusRunning, Targets: req.Targets}
+	var planIDs []string
+	for _, repo := range repos {
+		p, results, err := s.createPlan(ctx, suite.Name, repo, byRepo[repo], req.Targets)
+		if err != nil {
+			return nil, err
+		}
+		planIDs = append(planIDs, p.ID)
+		br.Results = append(br.Results, results...)
+	}
+
+	if err := s.store.CreateBenchmarkRun(ctx, br); err != nil {
+		return nil, fmt.Errorf("store benchmark run: %w", err)
+	}
+	for _, id := range planIDs {
+		if _, err := s.orch.StartPlan(ctx, id); err != nil {
+			return nil, fmt.Errorf("start benchmark plan %s: %w", id, err)
+		}
+	}
+
+	slog.Info("benchmark started", "benchmark_run_id", br.ID, "suite", suite.Name,
+		"cases", len(cases), "targets", len(req.Targets))
+	return br, nil
+}
+
+// createPlan creates the tasks and the execution plan for the cases of one
+// repository and returns the pending results keyed by plan step.
+func (s *BenchmarkService) createPlan(
+	ctx context.Context, suite, repo string, cases []benchmark.Case, targets []benchmark.Target,
+) (*plan.ExecutionPlan, []benchmark.Result, error) {
+	proj, err := s.ensureProject(ctx, repo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req := &plan.CreatePlanRequest{
+		ProjectID:   proj.ID,
+		Name:        "benchmark: " + suite,
+		Protocol:    plan.ProtocolParallel,
+		MaxParallel: s.cfg.MaxParallel,
+	}
+	var results []benchmark.Result
+	for i := range cases {
+		t, err := s.store.CreateTask(ctx, task.CreateRequest{
+			ProjectID: proj.ID,
+			Title:     suite + "/" + cases[i].ID,
+			Prompt:    cases[i].Prompt,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("create task for case %s: %w", cases[i].ID, err)
+		}
+		for _, tg := range targets {
+			req.Steps = append(req.Steps, plan.CreateStepRequest{TaskID: t.ID, AgentID: tg.AgentID, Model: tg.Model})
+			results = append(results, benchmark.Result{
+				CaseID:  cases[i].ID,
+				AgentID: tg.AgentID,
+				Model:   tg.Model,
+				Status:  benchmark.ResultPending,
+			})
+		}
+	}
+
+	p, err := s.orch.CreatePlan(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range results {
+		results[i].PlanStepID = p.Steps[i].ID
+	}
+	return p, results, nil
+}
+
+// ensureProject returns the benchmark project hosting repo, creating and
+// cloning it on first use.
+func (s *BenchmarkService) ensureProject(ctx context.Context, repo string) (*project.Project, error) {
+	projects, err := s.store.ListProjects(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list projects: %w", err)
+	}
+	var proj *project.Project
+	for i := range projects {
+		if projects[i].RepoURL == repo && projects[i].Config[benchmark.ConfigKeyProject] == "true" {
+			proj = &projects[i]
+			break
+		}
+	}
+	if proj == nil {
+		proj, err = s.store.CreateProject(ctx, project.CreateRequest{
+			Name:        "benchmark: " + strings.TrimSuffix(path.Base(repo), ".git"),
+			Description: "Benchmark repository " + repo,
+			RepoURL:     repo,
+			Provider:    benchmarkGitProvider,
+			Config:      map[string]string{benchmark.ConfigKeyProject: "true"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("create benchmark project: %w", err)
+		}
+	}
+	if proj.WorkspacePath == "" {
+		if proj, err = s.projects.Clone(ctx, proj.ID); err != nil {
+			return nil, fmt.Errorf("clone benchmark repo %s: %w", repo, err)
+		}
+	}
+	return proj, nil
+}
+
+// selectCases returns the cases of suite named by ids, or all when ids is empty.
+func selectCases(suite *benchmark.Suite, ids []string) ([]benchmark.Case, error) {
+	if len(ids) == 0 {
+		return suite.Cases, nil
+	}
+	var cases []benchmark.Case
+	for _, id := range ids {
+		found := false
+		for i := range suite.Cases {
+			if suite.Cases[i].ID == id {
+				cases = append(cases, suite.Cases[i])
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w %q in suite %s", benchmark.ErrUnknownCase, id, suite.Name)
+		}
+	}
+	return cases, nil
+}
+
+// Get returns a benchmark run with its results.
+func (s *BenchmarkService) Get(ctx context.Context, id string) (*benchmark.Run, error) {
+	return s.store.GetBenchmarkRun(ctx, id)
+}
+
+// List returns benchmark runs, optionally limited to one suite.
+func (s *BenchmarkService) List(ctx context.Context, suite string) ([]benchmark.Run, error) {
+	return s.store.ListBenchmarkRuns(ctx, suite)
+}
+
+// Leaderboard ranks the targets evaluated on a suite across all its runs.
+func (s *BenchmarkService) Leaderboard(ctx context.Context, suite string) ([]benchmark.LeaderboardEntry, error) {
+	if _, err := s.GetSuite(suite); err != nil {
+		return nil, err
+	}
+	runs, err := s.store.ListBenchmarkRuns(ctx, suite)
+	if err != nil {
+		return nil, err
+	}
+	var results []benchmark.Result
+	for i := range runs {
+		results = append(results, runs[i].Results...)
+	}
+	return benchmark.Leaderboard(results), nil
+}
+
+// HandleRunCompleted scores the benchmark result attempted by a finished
+// run. It runs after the orchestrator has settled the plan step, so runs
+// that will be retried are skipped until their final attempt. Validation
+// blocks for up to the configured timeout; callers should not hold up the
+// run completion path on it.
+func (s *BenchmarkService) HandleRunCompleted(ctx context.Context, runID string, status run.Status) {
+	step, err := s.store.GetPlanStepByRunID(ctx, runID)
+	if err != nil || !step.Status.IsTerminal() {
+		return
+	}
+	res, err := s.store.GetBenchmarkResultByStep(ctx, step.ID)
+	if err != nil {
+		// Plan step is not part of a benchmark — normal, ignore silently
+		return
+	}
+
+	r, err := s.store.GetRun(ctx, runID)
+	if err != nil {
+		slog.Error("get benchmark run", "run_id", runID, "error", err)
+		return
+	}
+	res.RunID = r.ID
+	res.CostUSD = r.CostUSD
+	if r.CompletedAt != nil {
+		res.DurationMS = r.CompletedAt.Sub(r.StartedAt).Milliseconds()
+	}
+
+	switch c, err := s.caseFor(ctx, res); {
+	case status != run.StatusCompleted:
+		res.Status, res.Output = benchmark.ResultError, tail(r.Error, maxValidationOutput)
+	case err != nil:
+		res.Status, res.Output = benchmark.ResultError, err.Error()
+	default:
+		res.Status, res.Output = s.validate(ctx, r, c)
+	}
+
+	if err := s.store.UpdateBenchmarkResult(ctx, res); err != nil {
+		slog.Error("update benchmark result", "result_id", res.ID, "error", err)
+		return
+	}
+	slog.Info("benchmark case scored", "benchmark_run_id", res.BenchRunID, "case", res.CaseID,
+		"agent_id", res.AgentID, "model", res.Model, "status", res.Status)
+
+	br, err := s.store.GetBenchmarkRun(ctx, res.BenchRunID)
+	if err != nil || br.Status == benchmark.StatusCompleted || !br.Done() {
+		return
+	}
+	if err := s.store.UpdateBenchmarkRunStatus(ctx, br.ID, benchmark.StatusCompleted); err != nil {
+		slog.Error("complete benchmark run", "benchmark_run_id", br.ID, "error", err)
+		return
+	}
+	slog.Info("benchmark completed", "benchmark_run_id", br.ID, "suite", br.Suite)
+}
+
+// validate runs the case's validation command in the run's workspace.
+func (s *BenchmarkService) validate(ctx context.Context, r *run.Run, c *benchmark.Case) (benchmark.ResultStatus, string) {
+	proj, err := s.store.GetProject(ctx, r.ProjectID)
+	if err != nil {
+		return benchmark.ResultError, err.Error()
+	}
+	dir := r.Workspace(proj.WorkspacePath)
+	if dir == "" {
+		return benchmark.ResultError, "run has no workspace"
+	}
+
+	vctx, cancel := context.WithTimeout(ctx, s.cfg.ValidateTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(vctx, "sh", "-c", c.Validate)
+	cmd.Dir = dir
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err = cmd.Run()
+
+	output := tail(out.String(), maxValidationOutput)
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return benchmark.ResultPassed, output
+	case errors.Is(vctx.Err(), context.DeadlineExceeded):
+		return benchmark.ResultFailed, output + "\nvalidation timed out after " + s.cfg.ValidateTimeout.String()
+	case errors.As(err, &exitErr):
+		return benchmark.ResultFailed, output
+	default:
+		return benchmark.ResultError, err.Error()
+	}
+}
+
+// caseFor looks up the suite case a result scores.
+func (s *BenchmarkService) caseFor(ctx context.Context, res *benchmark.Result) (*benchmark.Case, error) {
+	br, err := s.store.GetBenchmarkRun(ctx, res.BenchRunID)
+	if err != nil {
+		return nil, err
+	}
+	suite, err := s.GetSuite(br.Suite)
+	if err != nil {
+		return nil, err
+	}
+	for i := range suite.Cases {
+		if suite.Cases[i].ID == res.CaseID {
+			return &suite.Cases[i], nil
+		}
+	}
+	return nil, fmt.Errorf("case %s is no longer in suite %s", res.CaseID, br.Suite)
+}
+
+// tail returns the last n bytes of s.
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[len(s)-n:]
+}
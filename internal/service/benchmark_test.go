@@ -0,0 +1,139 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/benchmark"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+const benchRepo = "https://example.com/acme/widget.git"
+
+func newBenchmarkTestSetup(t *testing.T) (*orchMockStore, *service.OrchestratorService, *service.BenchmarkService, string) {
+	t.Helper()
+	store, orchSvc := newOrchTestSetup()
+	workspace := t.TempDir()
+	store.projects = append(store.projects, project.Project{
+		ID: "bench-proj", Name: "benchmark: widget", RepoURL: benchRepo, WorkspacePath: workspace,
+		Config: map[string]string{benchmark.ConfigKeyProject: "true"},
+	})
+	suites := []benchmark.Suite{{
+		Name: "smoke",
+		Cases: []benchmark.Case{
+			{ID: "c1", Repo: benchRepo, Prompt: "Create c1.done", Validate: "test -f c1.done"},
+			{ID: "c2", Repo: benchRepo, Prompt: "Create c2.done", Validate: "test -f c2.done"},
+		},
+	}}
+	cfg := config.Benchmark{ValidateTimeout: 10 * time.Second}
+	benchSvc := service.NewBenchmarkService(store, orchSvc, service.NewProjectService(store), cfg, suites)
+	return store, orchSvc, benchSvc, workspace
+}
+
+// finishRun completes a run the way RuntimeService would and notifies both
+// the orchestrator and the benchmark service.
+func finishRun(ctx context.Context, store *orchMockStore, orchSvc *service.OrchestratorService, benchSvc *service.BenchmarkService, runID string, status run.Status) {
+	now := time.Now()
+	store.runtimeMockStore.mu.Lock()
+	for i := range store.runs {
+		if store.runs[i].ID == runID {
+			store.runs[i].Status = status
+			store.runs[i].CostUSD = 0.25
+			store.runs[i].CompletedAt = &now
+		}
+	}
+	store.runtimeMockStore.mu.Unlock()
+	orchSvc.HandleRunCompleted(ctx, runID, status)
+	benchSvc.HandleRunCompleted(ctx, runID, status)
+}
+
+func TestBenchmark_StartScoresAndRanks(t *testing.T) {
+	store, orchSvc, benchSvc, workspace := newBenchmarkTestSetup(t)
+	ctx := context.Background()
+
+	br, err := benchSvc.Start(ctx, &benchmark.StartRequest{
+		Suite:   "smoke",
+		Targets: []benchmark.Target{{AgentID: "a1", Model: "gpt-4o"}, {AgentID: "a2"}},
+	})
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if len(br.Results) != 4 {
+		t.Fatalf("expected 4 results (2 cases x 2 targets), got %d", len(br.Results))
+	}
+	if len(store.plans) != 1 || len(store.runs) != 4 {
+		t.Fatalf("expected 1 plan with 4 runs, got %d plans and %d runs", len(store.plans), len(store.runs))
+	}
+	for i := range store.steps {
+		if store.steps[i].AgentID == "a1" && store.steps[i].Model != "gpt-4o" {
+			t.Fatalf("expected target model on step, got %q", store.steps[i].Model)
+		}
+	}
+
+	// The agent solved c1 but not c2; a2's attempt at c2 crashed.
+	if err := os.WriteFile(filepath.Join(workspace, "c1.done"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for i := range store.steps {
+		st := store.steps[i]
+		status := run.StatusCompleted
+		if st.AgentID == "a2" && st.TaskID == store.tasks[len(store.tasks)-1].ID {
+			status = run.StatusFailed
+		}
+		finishRun(ctx, store, orchSvc, benchSvc, st.RunID, status)
+	}
+
+	got, err := benchSvc.Get(ctx, br.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != benchmark.StatusCompleted {
+		t.Fatalf("expected completed benchmark, got %s", got.Status)
+	}
+	want := map[string]benchmark.ResultStatus{
+		"c1/a1": benchmark.ResultPassed, "c2/a1": benchmark.ResultFailed,
+		"c1/a2": benchmark.ResultPassed, "c2/a2": benchmark.ResultError,
+	}
+	for _, res := range got.Results {
+		if w := want[res.CaseID+"/"+res.AgentID]; res.Status != w {
+			t.Errorf("%s/%s: expected %s, got %s", res.CaseID, res.AgentID, w, res.Status)
+		}
+		if res.RunID == "" || res.CostUSD != 0.25 {
+			t.Errorf("%s/%s: expected run and cost recorded, got %+v", res.CaseID, res.AgentID, res)
+		}
+	}
+
+	board, err := benchSvc.Leaderboard(ctx, "smoke")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(board) != 2 || board[0].Passed != 1 || board[0].Total != 2 {
+		t.Fatalf("unexpected leaderboard %+v", board)
+	}
+}
+
+func TestBenchmark_StartErrors(t *testing.T) {
+	_, _, benchSvc, _ := newBenchmarkTestSetup(t)
+	ctx := context.Background()
+	targets := []benchmark.Target{{AgentID: "a1"}}
+
+	if _, err := benchSvc.Start(ctx, &benchmark.StartRequest{Suite: "nope", Targets: targets}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for unknown suite, got %v", err)
+	}
+	_, err := benchSvc.Start(ctx, &benchmark.StartRequest{Suite: "smoke", Targets: targets, Cases: []string{"c9"}})
+	if !errors.Is(err, benchmark.ErrUnknownCase) {
+		t.Fatalf("expected ErrUnknownCase, got %v", err)
+	}
+	_, err = benchSvc.Start(ctx, &benchmark.StartRequest{Suite: "smoke", Targets: []benchmark.Target{{AgentID: "ghost"}}})
+	if !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for unknown agent, got %v", err)
+	}
+}
@@ -0,0 +1,359 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/chatops"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/review"
+	"github.com/Strob0t/CodeForge/internal/domain/routing"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+// ChatOpsService runs the slash commands posted as pull request comments
+// ("/codeforge fix"). Commands from commenters with an allowed role start a
+// run on the pull request's branch; a reply to the comment links the run
+// and is updated with its result.
+type ChatOpsService struct {
+	store     database.Store
+	runtime   *RuntimeService
+	reviews   *ReviewService
+	secrets   *SecretService
+	publicURL string
+}
+
+// NewChatOpsService creates a ChatOpsService. Runs are started through
+// runtime; reviews publishes the results of "rerun" commands.
+func NewChatOpsService(store database.Store, runtime *RuntimeService, reviews *ReviewService, secrets *SecretService) *ChatOpsService {
+	return &ChatOpsService{store: store, runtime: runtime, reviews: reviews, secrets: secrets}
+}
+
+// SetPublicURL sets the web UI base URL that replies link to.
+func (s *ChatOpsService) SetPublicURL(u string) {
+	s.publicURL = strings.TrimSuffix(u, "/")
+}
+
+// List returns the pull request commands of a project, newest first.
+func (s *ChatOpsService) List(ctx context.Context, projectID string) ([]chatops.CommandRun, error) {
+	return s.store.ListCommandRuns(ctx, projectID)
+}
+
+// HandleWebhook verifies a pull request comment webhook against every
+// project on the named git provider and runs the command the comment
+// posts. It returns the number of runs started, or
+// gitprovider.ErrInvalidSignature if no project verified the delivery.
+func (s *ChatOpsService) HandleWebhook(ctx context.Context, providerName string, header http.Header, body []byte) (int, error) {
+	projects, err := s.store.ListProjects(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list projects: %w", err)
+	}
+
+	verified, started := false, 0
+	for i := range projects {
+		p := &projects[i]
+		if p.Provider != providerName {
+			continue
+		}
+		prov, err := hostedGitProvider(ctx, s.secrets, p)
+		if err != nil {
+			slog.Warn("pr comment webhook: build provider", "project_id", p.ID, "provider", providerName, "error", err)
+			continue
+		}
+		chat, ok := prov.(gitprovider.PRChat)
+		if !ok || !prov.Capabilities().Webhook {
+			continue
+		}
+		ev, err := chat.ParsePRCommentWebhook(ctx, header, body)
+		switch {
+		case errors.Is(err, gitprovider.ErrInvalidSignature):
+			continue
+		case errors.Is(err, gitprovider.ErrIgnoredEvent):
+			verified = true
+			continue
+		case err != nil:
+			slog.Warn("pr comment webhook: parse", "project_id", p.ID, "provider", providerName, "error", err)
+			verified = true
+			continue
+		}
+		verified = true
+		ran, err := s.handleComment(ctx, p, prov, chat, ev)
+		if err != nil {
+			return started, err
+		}
+		if ran {
+			started++
+		}
+	}
+	if !verified {
+		return 0, gitprovider.ErrInvalidSignature
+	}
+	return started, nil
+}
+
+// handleComment runs the command a pull request comment posts, from a
+// webhook or a poll. It returns whether a run started.
+func (s *ChatOpsService) handleComment(ctx context.Context, p *project.Project, prov gitprovider.Provider, chat gitprovider.PRChat, ev *gitprovider.PRCommentEvent) (bool, error) {
+	inv, found, parseErr := chatops.Parse(ev.Body)
+	if !found {
+		return false, nil
+	}
+	return s.start(ctx, p, replier(prov, chat), ev, inv, parseErr)
+}
+
+// start records a command and starts its run. It returns false without an
+// error if the comment's command was already handled or did not start a
+// run: unknown commands, help and refused commenters are answered right
+// away, and failures to start are reported in the reply.
+func (s *ChatOpsService) start(ctx context.Context, p *project.Project, chat gitprovider.PRChat, ev *gitprovider.PRCommentEvent, inv *chatops.Invocation, parseErr error) (bool, error) {
+	c := &chatops.CommandRun{
+		ProjectID: p.ID,
+		Provider:  p.Provider,
+		PRNumber:  ev.Number,
+		CommentID: ev.CommentID,
+		Branch:    ev.Branch,
+		Command:   inv.Command,
+		Args:      inv.Args,
+		Author:    ev.Author,
+		Status:    chatops.StatusRunning,
+	}
+	if err := s.store.CreateCommandRun(ctx, c); err != nil {
+		if errors.Is(err, domain.ErrConflict) {
+			slog.Info("pr command already handled", "project_id", p.ID, "pr", ev.Number, "comment_id", ev.CommentID)
+			return false, nil
+		}
+		return false, err
+	}
+
+	switch {
+	case !chatops.Allowed(p.Config, ev.AuthorRole):
+		c.Status, c.Error = chatops.StatusFailed, fmt.Sprintf("%s (role %s)", chatops.ErrNotAllowed, ev.AuthorRole)
+	case parseErr != nil:
+		c.Status, c.Error = chatops.StatusFailed, parseErr.Error()+"\n\n"+chatops.Help()
+	case c.Command == chatops.CommandHelp:
+		c.Status, c.Result = chatops.StatusCompleted, chatops.Help()
+	default:
+		if err := s.startRun(ctx, p, ev.BaseBranch, c); err != nil {
+			slog.Error("pr command failed to start", "project_id", p.ID, "pr", ev.Number, "command", c.Command, "error", err)
+			c.Status, c.Error = chatops.StatusFailed, err.Error()
+		}
+	}
+	if c.Status != chatops.StatusRunning {
+		s.save(ctx, c)
+	}
+	s.reply(ctx, chat, c)
+	if c.RunID == "" {
+		return false, nil
+	}
+	slog.Info("pr command started", "project_id", p.ID, "pr", ev.Number, "command", c.Command, "author", c.Author, "run_id", c.RunID)
+	return true, nil
+}
+
+// startRun creates the command's task and starts its run on the pull
+// request's branch, recording the run on c.
+func (s *ChatOpsService) startRun(ctx context.Context, p *project.Project, base string, c *chatops.CommandRun) error {
+	agentID, err := s.agent(ctx, p)
+	if err != nil {
+		return err
+	}
+	t, err := s.store.CreateTask(ctx, task.CreateRequest{
+		ProjectID: p.ID,
+		Title:     fmt.Sprintf("#%d %s %s", c.PRNumber, chatops.Prefix, c.Command),
+		Prompt:    c.Prompt(base),
+	})
+	if err != nil {
+		return fmt.Errorf("create task: %w", err)
+	}
+	req := &run.StartRequest{
+		TaskID:       t.ID,
+		AgentID:      agentID,
+		ProjectID:    p.ID,
+		Branch:       c.Branch,
+		TriggerEvent: chatops.TriggerEvent,
+	}
+	switch c.Command {
+	case chatops.CommandRerun:
+		req.TaskType = string(routing.TaskReview)
+	case chatops.CommandFix:
+		req.DeliverMode = run.DeliverModePush
+	}
+	r, err := s.runtime.StartRun(ctx, req)
+	if err != nil {
+		return fmt.Errorf("start run: %w", err)
+	}
+	c.RunID = r.ID
+	if err := s.store.UpdateCommandRun(ctx, c); err != nil {
+		return fmt.Errorf("record run: %w", err)
+	}
+	return nil
+}
+
+// agent returns the configured command agent, or the project's first agent.
+func (s *ChatOpsService) agent(ctx context.Context, p *project.Project) (string, error) {
+	if id := p.Config[chatops.ConfigAgent]; id != "" {
+		return id, nil
+	}
+	agents, err := s.store.ListAgents(ctx, p.ID)
+	if err != nil {
+		return "", fmt.Errorf("list agents: %w", err)
+	}
+	if len(agents) == 0 {
+		return "", chatops.ErrNoAgent
+	}
+	return agents[0].ID, nil
+}
+
+// HandleRunCompleted records the result of a run started by a command and
+// updates the reply: "rerun" publishes the run's review to the pull
+// request, "fix" reports the pushed commit with the run's summary and
+// "explain" posts the run's output. Runs of other origins are ignored.
+func (s *ChatOpsService) HandleRunCompleted(ctx context.Context, runID string, status run.Status) {
+	c, err := s.store.GetCommandRunByRun(ctx, runID)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotFound) {
+			slog.Error("pr command lookup failed", "run_id", runID, "error", err)
+		}
+		return
+	}
+	r, err := s.store.GetRun(ctx, runID)
+	if err != nil {
+		slog.Error("pr command: get run", "run_id", runID, "error", err)
+		return
+	}
+
+	if status == run.StatusCompleted {
+		c.Result, err = s.result(ctx, c, r)
+	} else {
+		err = fmt.Errorf("run %s", status)
+		if r.Error != "" {
+			err = errors.New(r.Error)
+		}
+	}
+	c.Status = chatops.StatusCompleted
+	if err != nil {
+		c.Status, c.Error = chatops.StatusFailed, err.Error()
+	}
+	s.save(ctx, c)
+
+	p, err := s.store.GetProject(ctx, c.ProjectID)
+	if err != nil {
+		slog.Error("pr command: get project", "project_id", c.ProjectID, "error", err)
+		return
+	}
+	prov, err := hostedGitProvider(ctx, s.secrets, p)
+	if err != nil {
+		slog.Warn("pr command: build provider", "project_id", p.ID, "error", err)
+		return
+	}
+	chat, _ := prov.(gitprovider.PRChat)
+	s.reply(ctx, replier(prov, chat), c)
+}
+
+// result returns the reply text of a completed command run.
+func (s *ChatOpsService) result(ctx context.Context, c *chatops.CommandRun, r *run.Run) (string, error) {
+	switch c.Command {
+	case chatops.CommandRerun:
+		if s.reviews == nil {
+			return "", ErrReviewPublishUnsupported
+		}
+		res, err := s.reviews.Publish(ctx, r.ID, &review.PublishRequest{
+			PRNumber: c.PRNumber,
+			Key:      fmt.Sprintf("pr-%d", c.PRNumber), // Reruns update the same comments
+		})
+		if err != nil {
+			return "", fmt.Errorf("publish review: %w", err)
+		}
+		msg := fmt.Sprintf("Published the review: %d new, %d updated and %d resolved comments.", res.Created, res.Updated, res.Resolved)
+		if res.Status != "" {
+			msg += " Status: " + res.Status + "."
+		}
+		return msg, nil
+	case chatops.CommandFix:
+		payload, delivered := lastDelivery(ctx, s.runtime, r.ID)
+		switch {
+		case payload == nil:
+			return "No changes to push.", nil
+		case !delivered:
+			return "", fmt.Errorf("push to %s: %s", c.Branch, payload["error"])
+		}
+		msg := fmt.Sprintf("Pushed %s to `%s`.", payload["commit_hash"], c.Branch)
+		if r.Summary != "" {
+			msg += "\n\n" + r.Summary
+		}
+		return msg, nil
+	default:
+		if strings.TrimSpace(r.Output) == "" {
+			return "The run produced no output.", nil
+		}
+		return r.Output, nil
+	}
+}
+
+// reply creates or updates the reply to a command. Replies are advisory,
+// so failures are logged rather than returned.
+func (s *ChatOpsService) reply(ctx context.Context, chat gitprovider.PRChat, c *chatops.CommandRun) {
+	if chat == nil {
+		return
+	}
+	body := c.Reply(runURL(s.publicURL, c.ProjectID, c.RunID))
+	if c.ReplyID != "" {
+		if err := chat.UpdatePRReply(ctx, c.PRNumber, c.ReplyID, body); err != nil {
+			slog.Warn("pr command reply update failed", "project_id", c.ProjectID, "pr", c.PRNumber, "error", err)
+		}
+		return
+	}
+	id, err := chat.ReplyToPR(ctx, c.PRNumber, body)
+	if err != nil {
+		slog.Warn("pr command reply failed", "project_id", c.ProjectID, "pr", c.PRNumber, "error", err)
+		return
+	}
+	c.ReplyID = id
+	s.save(ctx, c)
+}
+
+// save stores c and logs failures.
+func (s *ChatOpsService) save(ctx context.Context, c *chatops.CommandRun) {
+	if err := s.store.UpdateCommandRun(ctx, c); err != nil {
+		slog.Error("pr command update failed", "command_id", c.ID, "error", err)
+	}
+}
+
+// replier returns chat if prov can comment on pull requests, or nil.
+func replier(prov gitprovider.Provider, chat gitprovider.PRChat) gitprovider.PRChat {
+	if chat == nil || !prov.Capabilities().PullRequest {
+		return nil
+	}
+	return chat
+}
+
+// lastDelivery returns the payload of a run's last delivery event and
+// whether that delivery completed. The payload is nil if the run delivered
+// nothing.
+func lastDelivery(ctx context.Context, runtime *RuntimeService, runID string) (map[string]string, bool) {
+	events, err := runtime.loadRunEvents(ctx, runID)
+	if err != nil {
+		slog.Warn("load run events", "run_id", runID, "error", err)
+		return nil, false
+	}
+	for i := len(events) - 1; i >= 0; i-- {
+		typ := events[i].Type
+		if typ != event.TypeDeliveryCompleted && typ != event.TypeDeliveryFailed {
+			continue
+		}
+		var payload map[string]string
+		if err := json.Unmarshal(events[i].Payload, &payload); err == nil {
+			return payload, typ == event.TypeDeliveryCompleted
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,280 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/agent"
+	"github.com/Strob0t/CodeForge/internal/domain/chatops"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/poll"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/review"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/secret"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+// prReplies holds the replies posted through the "fake-chat" provider,
+// keyed by reply ID; prComments holds the published review comments.
+// prPolled is the comment feed its poller reads, failing with pollErr.
+var (
+	prReplies  map[string]string
+	prComments []gitprovider.PRComment
+	prPolled   []gitprovider.PRCommentEvent
+	pollErr    error
+)
+
+// fakeChatProvider accepts webhooks whose X-Fake-Token header matches the
+// webhook secret; their body is the PRCommentEvent as JSON. Branch
+// worktrees are empty directories.
+type fakeChatProvider struct {
+	gitprovider.Provider // unused local operations
+	secret               string
+}
+
+func init() {
+	gitprovider.Register("fake-chat", func(cfg map[string]string) (gitprovider.Provider, error) {
+		return &fakeChatProvider{secret: cfg[gitprovider.ConfigWebhookKey]}, nil
+	})
+}
+
+func (p *fakeChatProvider) Name() string { return "fake-chat" }
+func (p *fakeChatProvider) Capabilities() gitprovider.Capabilities {
+	return gitprovider.Capabilities{PullRequest: true, Worktree: true, Webhook: p.secret != ""}
+}
+func (p *fakeChatProvider) AddBranchWorktree(_ context.Context, _, worktreePath, _ string) error {
+	return os.MkdirAll(worktreePath, 0o755)
+}
+func (p *fakeChatProvider) ParsePRCommentWebhook(_ context.Context, header http.Header, body []byte) (*gitprovider.PRCommentEvent, error) {
+	if header.Get("X-Fake-Token") != p.secret {
+		return nil, gitprovider.ErrInvalidSignature
+	}
+	var ev gitprovider.PRCommentEvent
+	if err := json.Unmarshal(body, &ev); err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
+func (p *fakeChatProvider) ReplyToPR(_ context.Context, number int, body string) (string, error) {
+	id := fmt.Sprintf("%d-%d", number, len(prReplies)+1)
+	prReplies[id] = body
+	return id, nil
+}
+func (p *fakeChatProvider) UpdatePRReply(_ context.Context, _ int, commentID, body string) error {
+	if _, ok := prReplies[commentID]; !ok {
+		return errors.New("no such reply")
+	}
+	prReplies[commentID] = body
+	return nil
+}
+func (p *fakeChatProvider) ListPRComments(_ context.Context, _ int) ([]gitprovider.PRComment, error) {
+	return append([]gitprovider.PRComment(nil), prComments...), nil
+}
+func (p *fakeChatProvider) CreatePRComment(_ context.Context, _ int, c *gitprovider.PRComment) error {
+	c.ID = fmt.Sprintf("c%d", len(prComments)+1)
+	prComments = append(prComments, *c)
+	return nil
+}
+func (p *fakeChatProvider) UpdatePRComment(_ context.Context, _ int, c *gitprovider.PRComment) error {
+	for i := range prComments {
+		if prComments[i].ID == c.ID {
+			prComments[i].Body = c.Body
+			return nil
+		}
+	}
+	return errors.New("no such comment")
+}
+
+func (p *fakeChatProvider) PollIssues(_ context.Context, _ *poll.Cursor) ([]gitprovider.IssueEvent, error) {
+	return nil, nil
+}
+
+// PollPRComments keeps the number of comments read as the position.
+func (p *fakeChatProvider) PollPRComments(_ context.Context, cursor *poll.Cursor) ([]gitprovider.PRCommentEvent, error) {
+	if pollErr != nil {
+		return nil, pollErr
+	}
+	start := cursor.Position == ""
+	read, _ := strconv.Atoi(cursor.Position)
+	cursor.Position = strconv.Itoa(len(prPolled))
+	if start {
+		return nil, nil
+	}
+	return prPolled[read:], nil
+}
+
+type chatOpsTestEnv struct {
+	store   *runtimeMockStore
+	events  *runtimeMockEventStore
+	secrets *service.SecretService
+	reviews *service.ReviewService
+	svc     *service.ChatOpsService
+}
+
+func newChatOpsTestEnv(t *testing.T) *chatOpsTestEnv {
+	t.Helper()
+	prReplies, prComments, prPolled, pollErr = make(map[string]string), nil, nil, nil
+	store := &runtimeMockStore{
+		projects: []project.Project{{ID: "gh-chat", Name: "webapp", Provider: "fake-chat", WorkspacePath: t.TempDir(), Config: map[string]string{
+			gitprovider.ConfigWebhookSecret: "HOOK",
+		}}},
+		agents: []agent.Agent{
+			{ID: "chat-agent", ProjectID: "gh-chat", Name: "coder", Backend: "aider", Status: agent.StatusIdle, Config: map[string]string{}},
+		},
+	}
+	events := &runtimeMockEventStore{}
+	runtime := service.NewRuntimeService(store, &runtimeMockQueue{}, &runtimeMockBroadcaster{}, events,
+		service.NewPolicyService("headless-safe-sandbox", nil), &config.Runtime{StallThreshold: 5, QualityGateTimeout: time.Minute})
+	projects := service.NewProjectService(store)
+	projects.SetWorktreeRoot(t.TempDir())
+	runtime.SetProjectService(projects)
+	secrets := newTestSecretService(t, store)
+	if _, err := secrets.Create(context.Background(), "", &secret.CreateRequest{Name: "HOOK", Value: "s3cret"}); err != nil {
+		t.Fatal(err)
+	}
+	reviews := service.NewReviewService(store, secrets)
+	svc := service.NewChatOpsService(store, runtime, reviews, secrets)
+	svc.SetPublicURL("http://ui.example/")
+	return &chatOpsTestEnv{store: store, events: events, secrets: secrets, reviews: reviews, svc: svc}
+}
+
+// comment delivers a comment on pull request 7 and returns the number of
+// runs it started.
+func (e *chatOpsTestEnv) comment(t *testing.T, id, body string, role chatops.Role) int {
+	t.Helper()
+	data, err := json.Marshal(&gitprovider.PRCommentEvent{Number: 7, CommentID: id, Body: body, Author: "alice",
+		AuthorRole: role, Branch: "fix-login", BaseBranch: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := e.svc.HandleWebhook(context.Background(), "fake-chat", http.Header{"X-Fake-Token": {"s3cret"}}, data)
+	if err != nil {
+		t.Fatalf("HandleWebhook: %v", err)
+	}
+	return n
+}
+
+// command returns the recorded command of a comment.
+func (e *chatOpsTestEnv) command(t *testing.T, commentID string) chatops.CommandRun {
+	t.Helper()
+	cmds, err := e.svc.List(context.Background(), "gh-chat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cmds {
+		if c.CommentID == commentID {
+			return c
+		}
+	}
+	t.Fatalf("no command for comment %s in %+v", commentID, cmds)
+	return chatops.CommandRun{}
+}
+
+func TestChatOpsService_Webhook(t *testing.T) {
+	env := newChatOpsTestEnv(t)
+	ctx := context.Background()
+
+	if _, err := env.svc.HandleWebhook(ctx, "fake-chat", http.Header{"X-Fake-Token": {"wrong"}}, []byte(`{}`)); !errors.Is(err, gitprovider.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+	if n := env.comment(t, "1", "LGTM", chatops.RoleOwner); n != 0 || len(prReplies) != 0 {
+		t.Fatalf("expected plain comments to be ignored, got %d runs, replies %v", n, prReplies)
+	}
+
+	// Commenters outside the allowlist are refused.
+	if n := env.comment(t, "2", "/codeforge fix", chatops.RoleContributor); n != 0 {
+		t.Fatalf("expected no run for a contributor, got %d", n)
+	}
+	if c := env.command(t, "2"); c.Status != chatops.StatusFailed || !strings.Contains(prReplies[c.ReplyID], "not allowed") {
+		t.Fatalf("expected a refusal, got %+v, %q", c, prReplies[c.ReplyID])
+	}
+
+	// Unknown commands are answered with the help.
+	env.comment(t, "3", "/codeforge deploy", chatops.RoleOwner)
+	if c := env.command(t, "3"); !strings.Contains(prReplies[c.ReplyID], "/codeforge explain") {
+		t.Fatalf("expected help, got %q", prReplies[c.ReplyID])
+	}
+
+	if n := env.comment(t, "4", "/codeforge fix the login test", chatops.RoleCollaborator); n != 1 {
+		t.Fatalf("expected a fix run, got %d", n)
+	}
+	if n := env.comment(t, "4", "/codeforge fix the login test", chatops.RoleCollaborator); n != 0 {
+		t.Fatalf("expected redelivery to start nothing, got %d", n)
+	}
+	c := env.command(t, "4")
+	r, err := env.store.GetRun(ctx, c.RunID)
+	if err != nil || r.Branch != "fix-login" || r.DeliverMode != run.DeliverModePush || r.WorktreePath == "" {
+		t.Fatalf("unexpected run %+v, %v", r, err)
+	}
+	tk, err := env.store.GetTask(ctx, r.TaskID)
+	if err != nil || !strings.Contains(tk.Prompt, "fix-login") || !strings.Contains(tk.Prompt, "the login test") {
+		t.Fatalf("unexpected task %+v, %v", tk, err)
+	}
+	if reply := prReplies[c.ReplyID]; !strings.Contains(reply, "running") || !strings.Contains(reply, "http://ui.example/projects/gh-chat?run=") {
+		t.Fatalf("unexpected reply %q", reply)
+	}
+
+	_ = env.events.Append(ctx, &event.AgentEvent{RunID: c.RunID, Type: event.TypeDeliveryCompleted,
+		Payload: json.RawMessage(`{"mode": "push", "commit_hash": "abc123", "branch_name": "fix-login"}`)})
+	env.svc.HandleRunCompleted(ctx, c.RunID, run.StatusCompleted)
+	c = env.command(t, "4")
+	if c.Status != chatops.StatusCompleted || !strings.Contains(prReplies[c.ReplyID], "Pushed abc123 to `fix-login`") {
+		t.Fatalf("expected the pushed commit in the reply, got %+v, %q", c, prReplies[c.ReplyID])
+	}
+}
+
+func TestChatOpsService_ExplainAndRerun(t *testing.T) {
+	env := newChatOpsTestEnv(t)
+	ctx := context.Background()
+
+	env.comment(t, "10", "/codeforge explain", chatops.RoleMember)
+	explain := env.command(t, "10")
+	r, err := env.store.GetRun(ctx, explain.RunID)
+	if err != nil || r.DeliverMode != run.DeliverModeNone {
+		t.Fatalf("expected an explain run without delivery, got %+v, %v", r, err)
+	}
+	env.store.mu.Lock()
+	for i := range env.store.runs {
+		if env.store.runs[i].ID == explain.RunID {
+			env.store.runs[i].Output = "This adds a login retry."
+		}
+	}
+	env.store.mu.Unlock()
+	env.svc.HandleRunCompleted(ctx, explain.RunID, run.StatusCompleted)
+	if reply := prReplies[env.command(t, "10").ReplyID]; !strings.HasSuffix(reply, "This adds a login retry.") {
+		t.Fatalf("expected the explanation in the reply, got %q", reply)
+	}
+
+	env.comment(t, "11", "/codeforge rerun", chatops.RoleOwner)
+	rerun := env.command(t, "11")
+	if _, err := env.reviews.Record(ctx, rerun.RunID, &review.Review{Summary: "Looks good.", Approved: true}); err != nil {
+		t.Fatal(err)
+	}
+	env.svc.HandleRunCompleted(ctx, rerun.RunID, run.StatusCompleted)
+	rerun = env.command(t, "11")
+	if rerun.Status != chatops.StatusCompleted || len(prComments) != 1 || !strings.Contains(prComments[0].Body, "Looks good.") {
+		t.Fatalf("expected the review published, got %+v, %v", rerun, prComments)
+	}
+	if reply := prReplies[rerun.ReplyID]; !strings.Contains(reply, "Published the review: 1 new") {
+		t.Fatalf("unexpected reply %q", reply)
+	}
+
+	// A failed run is reported with its error.
+	env.comment(t, "12", "/codeforge explain", chatops.RoleOwner)
+	failed := env.command(t, "12")
+	env.svc.HandleRunCompleted(ctx, failed.RunID, run.StatusTimeout)
+	if failed = env.command(t, "12"); failed.Status != chatops.StatusFailed || !strings.Contains(prReplies[failed.ReplyID], "run timeout") {
+		t.Fatalf("expected the failure in the reply, got %+v, %q", failed, prReplies[failed.ReplyID])
+	}
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/ci"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/port/ciprovider"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+)
+
+var (
+	// ErrCIFailed is returned when a delivery gated on CI did not pass it.
+	ErrCIFailed = errors.New("ci: the delivered commit did not pass CI")
+	// ErrCITimeout is returned when CI did not finish within the wait
+	// timeout of a delivery gate.
+	ErrCITimeout = errors.New("ci: timed out waiting for CI")
+)
+
+// defaultCIProviders names the CI provider of projects on a git host that
+// set no ci_provider.
+var defaultCIProviders = map[string]string{
+	"github": "github-actions",
+	"gitlab": "gitlab-ci",
+}
+
+// CIService reads the workflow runs and pipelines of the commits runs
+// deliver from the projects' CI providers and links them to the runs. A
+// poller keeps the CI of recent deliveries current until it finished, and
+// review approval and delivery can be gated on green CI.
+type CIService struct {
+	store   database.Store
+	secrets *SecretService
+	cfg     config.CI
+}
+
+// NewCIService creates a CIService. secrets resolves the API token named
+// by git_token_secret.
+func NewCIService(store database.Store, secrets *SecretService, cfg config.CI) *CIService {
+	return &CIService{store: store, secrets: secrets, cfg: cfg}
+}
+
+// Status returns the CI of the commit a run delivered. CI that has not
+// finished is checked again first; if the CI provider fails, the previous
+// result is returned with the error.
+func (s *CIService) Status(ctx context.Context, runID string) (*ci.Status, error) {
+	st, err := s.store.GetRunCIStatus(ctx, runID)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
+	}
+	d, derr := s.store.GetRunDelivery(ctx, runID)
+	switch {
+	case derr == nil && (st == nil || st.SHA != d.CommitSHA):
+		st = &ci.Status{RunID: d.RunID, ProjectID: d.ProjectID, Branch: d.Branch, SHA: d.CommitSHA}
+	case derr != nil && !errors.Is(derr, domain.ErrNotFound):
+		return nil, derr
+	case st == nil:
+		return nil, fmt.Errorf("ci of run %s: %w", runID, domain.ErrNotFound)
+	}
+	if st.State.Done() {
+		return st, nil
+	}
+	p, err := s.store.GetProject(ctx, st.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	prov, err := s.provider(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	s.check(ctx, prov, st)
+	if err := s.store.SaveRunCIStatus(ctx, st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// Check returns the CI of a commit on a branch without linking it to a
+// run.
+func (s *CIService) Check(ctx context.Context, p *project.Project, branch, sha string) (*ci.Status, error) {
+	prov, err := s.provider(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	st := &ci.Status{ProjectID: p.ID, Branch: branch, SHA: sha}
+	s.check(ctx, prov, st)
+	if st.Error != "" {
+		return nil, fmt.Errorf("ci: %s", st.Error)
+	}
+	return st, nil
+}
+
+// Wait checks the CI of the commit a run pushed every poll interval (every
+// minute if polling is off) until it finished, and records it for the
+// run. It returns ErrCITimeout with the last result if CI did not finish
+// within the wait timeout; commits without pipelines wait for them to
+// start.
+func (s *CIService) Wait(ctx context.Context, p *project.Project, runID, branch, sha string) (*ci.Status, error) {
+	prov, err := s.provider(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	interval := s.cfg.PollInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	deadline := time.NewTimer(s.cfg.WaitTimeout)
+	defer deadline.Stop()
+	st := &ci.Status{RunID: runID, ProjectID: p.ID, Branch: branch, SHA: sha}
+	for {
+		s.check(ctx, prov, st)
+		if err := s.store.SaveRunCIStatus(ctx, st); err != nil {
+			slog.Warn("ci: save status", "run_id", runID, "error", err)
+		}
+		if st.State.Done() {
+			return st, nil
+		}
+		select {
+		case <-ctx.Done():
+			return st, ctx.Err()
+		case <-deadline.C:
+			return st, fmt.Errorf("%w after %s (%s)", ErrCITimeout, s.cfg.WaitTimeout, st.State)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// StartPoller checks the CI of recent deliveries every poll interval until
+// the context is cancelled or the returned cancel function is called. A
+// zero interval disables the poller.
+func (s *CIService) StartPoller(ctx context.Context) (cancel func()) {
+	ctx, cancel = context.WithCancel(ctx)
+	if s.cfg.PollInterval <= 0 {
+		return cancel
+	}
+	go func() {
+		ticker := time.NewTicker(s.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.PollDue(ctx); err != nil {
+					slog.Error("ci poll failed", "error", err)
+				}
+			}
+		}
+	}()
+	return cancel
+}
+
+// PollDue checks the CI of the deliveries within the watch window whose
+// CI has not finished and returns how many were checked. A failing project
+// is logged and does not stop the others.
+func (s *CIService) PollDue(ctx context.Context) (int, error) {
+	projects, err := s.store.ListProjects(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list projects: %w", err)
+	}
+	since := time.Now().Add(-s.cfg.WatchWindow)
+	checked := 0
+	for i := range projects {
+		p := &projects[i]
+		if p.Config[ciprovider.ConfigProvider] == "" && defaultCIProviders[p.Provider] == "" {
+			continue
+		}
+		deliveries, err := s.store.ListRunDeliveries(ctx, p.ID, maxWatchedDeliveries)
+		if err != nil {
+			slog.Warn("ci poll: list deliveries", "project_id", p.ID, "error", err)
+			continue
+		}
+		var prov ciprovider.Provider
+		for j := range deliveries {
+			d := &deliveries[j]
+			if d.DeliveredAt.Before(since) {
+				break
+			}
+			st, err := s.store.GetRunCIStatus(ctx, d.RunID)
+			if err == nil && st.SHA == d.CommitSHA && st.State.Done() {
+				continue
+			}
+			if prov == nil {
+				if prov, err = s.provider(ctx, p); err != nil {
+					slog.Warn("ci poll: build provider", "project_id", p.ID, "error", err)
+					break
+				}
+			}
+			st = &ci.Status{RunID: d.RunID, ProjectID: p.ID, Branch: d.Branch, SHA: d.CommitSHA}
+			s.check(ctx, prov, st)
+			if err := s.store.SaveRunCIStatus(ctx, st); err != nil {
+				slog.Warn("ci poll: save status", "run_id", d.RunID, "error", err)
+				continue
+			}
+			checked++
+		}
+	}
+	return checked, nil
+}
+
+// check lists the pipelines of the status's commit and updates its state.
+// Errors are recorded on the status, which keeps its earlier pipelines.
+func (s *CIService) check(ctx context.Context, prov ciprovider.Provider, st *ci.Status) {
+	st.Provider = prov.Name()
+	st.CheckedAt = time.Now().UTC()
+	pipelines, err := prov.ListPipelines(ctx, st.Branch, st.SHA)
+	if err != nil {
+		slog.Warn("ci: list pipelines", "project_id", st.ProjectID, "run_id", st.RunID, "provider", st.Provider, "error", err)
+		st.Error = err.Error()
+		if st.State == "" {
+			st.State = ci.Overall(st.Pipelines)
+		}
+		return
+	}
+	st.Pipelines, st.State, st.Error = pipelines, ci.Overall(pipelines), ""
+}
+
+// provider builds the CI provider of a project: the one named by
+// ci_provider, or that of its git host.
+func (s *CIService) provider(ctx context.Context, p *project.Project) (ciprovider.Provider, error) {
+	name := p.Config[ciprovider.ConfigProvider]
+	if name == "" {
+		name = defaultCIProviders[p.Provider]
+	}
+	if name == "" {
+		return nil, fmt.Errorf("ci provider of project %s: %w", p.ID, domain.ErrNotFound)
+	}
+	cfg, err := hostedConfig(ctx, s.secrets, p)
+	if err != nil {
+		return nil, err
+	}
+	return ciprovider.New(name, cfg)
+}
@@ -0,0 +1,156 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/ci"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/seatbelt"
+	"github.com/Strob0t/CodeForge/internal/port/ciprovider"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+// fakePipelines is the CI of every commit the "fake-pipelines" provider
+// is asked about; calls counts the requests.
+var fakePipelines struct {
+	sync.Mutex
+	states []ci.State
+	calls  int
+}
+
+type fakePipelineProvider struct{}
+
+func init() {
+	ciprovider.Register("fake-pipelines", func(_ map[string]string) (ciprovider.Provider, error) {
+		return fakePipelineProvider{}, nil
+	})
+}
+
+func setFakePipelines(states ...ci.State) {
+	fakePipelines.Lock()
+	defer fakePipelines.Unlock()
+	fakePipelines.states, fakePipelines.calls = states, 0
+}
+
+func (fakePipelineProvider) Name() string { return "fake-pipelines" }
+func (fakePipelineProvider) ListPipelines(_ context.Context, branch, sha string) ([]ci.Pipeline, error) {
+	fakePipelines.Lock()
+	defer fakePipelines.Unlock()
+	fakePipelines.calls++
+	out := make([]ci.Pipeline, len(fakePipelines.states))
+	for i, s := range fakePipelines.states {
+		out[i] = ci.Pipeline{ID: string(rune('a' + i)), Branch: branch, SHA: sha, State: s}
+	}
+	return out, nil
+}
+
+func TestCIService(t *testing.T) {
+	store := &runtimeMockStore{
+		projects: []project.Project{
+			{ID: "proj-1", Config: map[string]string{ciprovider.ConfigProvider: "fake-pipelines"}},
+			{ID: "proj-2", Provider: "local"},
+		},
+		deliveries: []seatbelt.Delivery{
+			{RunID: "run-old", ProjectID: "proj-1", Mode: run.DeliverModeBranch, Branch: "codeforge/old", CommitSHA: "def", DeliveredAt: time.Now().Add(-48 * time.Hour)},
+			{RunID: "run-1", ProjectID: "proj-1", Mode: run.DeliverModePR, Branch: "codeforge/run-1", CommitSHA: "abc", DeliveredAt: time.Now()},
+			{RunID: "run-2", ProjectID: "proj-2", Mode: run.DeliverModeBranch, Branch: "codeforge/run-2", CommitSHA: "123", DeliveredAt: time.Now()},
+		},
+	}
+	svc := service.NewCIService(store, nil, config.CI{PollInterval: time.Minute, WaitTimeout: time.Minute, WatchWindow: 24 * time.Hour})
+	ctx := context.Background()
+
+	if _, err := svc.Status(ctx, "run-none"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected not found for a run without delivery, got %v", err)
+	}
+	if _, err := svc.Status(ctx, "run-2"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected not found for a project without CI provider, got %v", err)
+	}
+
+	setFakePipelines(ci.StateSuccess, ci.StateRunning)
+	st, err := svc.Status(ctx, "run-1")
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if st.State != ci.StatePending || st.Provider != "fake-pipelines" || st.SHA != "abc" || len(st.Pipelines) != 2 {
+		t.Fatalf("unexpected status %+v", st)
+	}
+
+	// The poller checks unfinished CI of deliveries within the window only.
+	setFakePipelines(ci.StateSuccess, ci.StateFailure)
+	if n, err := svc.PollDue(ctx); err != nil || n != 1 {
+		t.Fatalf("poll: got %d, %v; want 1", n, err)
+	}
+	if st, _ = store.GetRunCIStatus(ctx, "run-1"); st.State != ci.StateFailure {
+		t.Fatalf("state after poll = %q, want failure", st.State)
+	}
+	if _, err := store.GetRunCIStatus(ctx, "run-old"); err == nil {
+		t.Error("deliveries outside the watch window must not be checked")
+	}
+
+	// Finished CI is not checked again.
+	setFakePipelines(ci.StateSuccess)
+	if n, _ := svc.PollDue(ctx); n != 0 {
+		t.Errorf("poll checked %d finished deliveries", n)
+	}
+	if st, _ = svc.Status(ctx, "run-1"); st.State != ci.StateFailure || fakePipelines.calls != 0 {
+		t.Errorf("finished CI was checked again: %+v after %d calls", st, fakePipelines.calls)
+	}
+}
+
+func TestDeliver_BranchGatedOnCI(t *testing.T) {
+	dir := initDeliverTestRepo(t)
+	remote := t.TempDir()
+	for _, args := range [][]string{
+		{"git", "init", "--bare", remote},
+		{"git", "-C", dir, "remote", "add", "origin", remote},
+	} {
+		if out, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+			t.Fatalf("git setup %v: %s: %v", args, out, err)
+		}
+	}
+
+	store := &deliverMockStore{proj: &project.Project{ID: "proj-1", WorkspacePath: dir, Config: map[string]string{
+		ciprovider.ConfigProvider: "fake-pipelines",
+		ci.ConfigGateDelivery:     "true",
+	}}}
+	ciSvc := service.NewCIService(store, nil, config.CI{PollInterval: time.Millisecond, WaitTimeout: 50 * time.Millisecond, WatchWindow: time.Hour})
+	svc := service.NewDeliverService(store, &config.Runtime{DeliveryCommitPrefix: "codeforge:"})
+	svc.SetCIService(ciSvc)
+	ctx := context.Background()
+
+	deliver := func(id string) (*service.DeliveryResult, error) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte(id), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return svc.Deliver(ctx, &run.Run{ID: id, ProjectID: "proj-1", DeliverMode: run.DeliverModeBranch}, id)
+	}
+
+	setFakePipelines(ci.StateFailure)
+	if _, err := deliver("run-red1"); !errors.Is(err, service.ErrCIFailed) {
+		t.Fatalf("expected red CI to fail the delivery, got %v", err)
+	}
+	setFakePipelines(ci.StateRunning)
+	if _, err := deliver("run-slow"); !errors.Is(err, service.ErrCITimeout) {
+		t.Fatalf("expected unfinished CI to time out, got %v", err)
+	}
+	setFakePipelines(ci.StateSuccess)
+	result, err := deliver("run-green")
+	if err != nil {
+		t.Fatalf("expected green CI to deliver, got %v", err)
+	}
+	st, err := store.GetRunCIStatus(ctx, "run-green")
+	if err != nil || st.State != ci.StateSuccess || st.SHA != result.CommitHash {
+		t.Fatalf("expected the gate's check recorded for the run, got %+v, %v", st, err)
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+)
+
+// RunCitations resolves the citation markers in a run's final output to
+// the workspace file lines or knowledge base documents they refer to, in
+// order of first citation. IDs are looked up in the latest context pack of
+// the run's task, then among the current chunks of the project's index and
+// knowledge bases; citations that resolve to neither keep only their ID.
+func (s *RetrievalService) RunCitations(ctx context.Context, runID string) ([]retrieval.Citation, error) {
+	r, err := s.store.GetRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	ids := retrieval.CitedIDs(r.Output)
+	out := make([]retrieval.Citation, 0, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+
+	found := make(map[string]retrieval.Citation, len(ids))
+	pack, err := s.store.GetContextPackByTask(ctx, r.TaskID)
+	switch {
+	case err == nil:
+		for i := range pack.Entries {
+			if c := pack.Entries[i].Citation; c != nil {
+				found[c.ID] = *c
+			}
+		}
+	case !errors.Is(err, domain.ErrNotFound):
+		return nil, fmt.Errorf("get context pack: %w", err)
+	}
+
+	if len(found) < len(ids) {
+		chunks, err := s.currentChunks(ctx, r.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range chunks {
+			id := chunks[i].ID()
+			if _, ok := found[id]; !ok {
+				found[id] = chunks[i].Citation()
+			}
+		}
+	}
+
+	for _, id := range ids {
+		c, ok := found[id]
+		if !ok {
+			c = retrieval.Citation{ID: id}
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// currentChunks returns the chunks a search of the project would rank now,
+// or none if the project has no index.
+func (s *RetrievalService) currentChunks(ctx context.Context, projectID string) ([]retrieval.Chunk, error) {
+	idx, err := s.store.GetRetrievalIndex(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	chunks, err := s.store.ListRetrievalChunks(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if s.knowledge != nil {
+		p, err := s.store.GetProject(ctx, projectID)
+		if err != nil {
+			return nil, fmt.Errorf("get project: %w", err)
+		}
+		kc, err := s.knowledge.Chunks(ctx, p, idx)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, kc...)
+	}
+	return chunks, nil
+}
@@ -0,0 +1,152 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	cfcontext "github.com/Strob0t/CodeForge/internal/domain/context"
+	"github.com/Strob0t/CodeForge/internal/domain/mode"
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestRetrievalService_RunCitations(t *testing.T) {
+	svc, store, _ := newRetrievalTestEnv(t)
+	ctx := context.Background()
+
+	if _, err := svc.Index(ctx, "proj-1"); err != nil {
+		t.Fatal(err)
+	}
+	file := store.chunks["proj-1"][0]
+	doc := retrieval.Citation{ID: "aaaaaaaaaaaa", URL: "https://wiki.example.com/setup", Title: "Setup", Source: "wiki"}
+	if err := store.CreateContextPack(ctx, &cfcontext.ContextPack{
+		TaskID: "task-1", ProjectID: "proj-1", TokenBudget: 100,
+		Entries: []cfcontext.ContextEntry{{Kind: cfcontext.EntrySnippet, Path: doc.URL, Content: "x", Citation: &doc}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	store.mu.Lock()
+	store.runs = append(store.runs, run.Run{
+		ID: "run-cite", TaskID: "task-1", ProjectID: "proj-1", Status: run.StatusCompleted,
+		Output: "Install it " + retrieval.Cite(doc.ID) + " and call it " + retrieval.Cite(file.ID()) + retrieval.Cite("0123456789ab"),
+	})
+	store.mu.Unlock()
+
+	got, err := svc.RunCitations(ctx, "run-cite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []retrieval.Citation{doc, file.Citation(), {ID: "0123456789ab"}}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("citation %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if got[1].Path == "" || got[1].StartLine != 1 {
+		t.Fatalf("expected file citation with lines, got %+v", got[1])
+	}
+
+	if _, err := svc.RunCitations(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestHandleRunComplete_Citations(t *testing.T) {
+	svc, store, queue, _ := newRuntimeTestEnv()
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "guide.md"), []byte("alpha\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store.projects[0].WorkspacePath = dir
+	rs := service.NewRetrievalService(store, &config.Retrieval{
+		EmbeddingProvider: "litellm", EmbeddingModel: "m", BatchSize: 2, ChunkLines: 60, MaxFiles: 10,
+	}, &fakeEmbedder{name: "litellm", dims: 4})
+	if _, err := rs.Index(ctx, "proj-1"); err != nil {
+		t.Fatal(err)
+	}
+	co := service.NewContextOptimizerService(store, &config.Orchestrator{})
+	co.SetRetrievalService(rs)
+	svc.SetContextOptimizer(co)
+	id := store.chunks["proj-1"][0].ID()
+
+	start := func(profile string) (*run.Run, messagequeue.RunStartPayload) {
+		t.Helper()
+		r, err := svc.StartRun(ctx, &run.StartRequest{TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", PolicyProfile: profile})
+		if err != nil {
+			t.Fatalf("StartRun failed: %v", err)
+		}
+		return r, lastStart(t, queue)
+	}
+	complete := func(runID, output string) *run.Run {
+		t.Helper()
+		err := svc.HandleRunComplete(ctx, &messagequeue.RunCompletePayload{
+			RunID: runID, TaskID: "task-1", ProjectID: "proj-1", Status: "completed", Output: output,
+		})
+		if err != nil {
+			t.Fatalf("HandleRunComplete failed: %v", err)
+		}
+		r, _ := store.GetRun(ctx, runID)
+		return r
+	}
+
+	// Plan-mode runs get citable snippets and must cite them.
+	r, p := start("plan-readonly")
+	if !strings.Contains(p.Prompt, "## Citations") || len(p.Context) == 0 || !strings.HasPrefix(p.Context[0].Content, retrieval.Cite(id)) {
+		t.Fatalf("expected citable context and citation instructions, got %+v", p)
+	}
+	if got := complete(r.ID, "Use alpha."); got.Status != run.StatusRunning {
+		t.Fatalf("expected run sent back for citations, got %s", got.Status)
+	}
+	if p := lastStart(t, queue); !strings.Contains(p.Prompt, "## Output repair") || !strings.Contains(p.Prompt, "cites none") {
+		t.Fatalf("expected a citation repair prompt, got %q", p.Prompt)
+	}
+	if got := complete(r.ID, "Use alpha "+retrieval.Cite(id)+"."); got.Status != run.StatusCompleted {
+		t.Fatalf("expected completed run, got %+v", got)
+	}
+
+	// Repairs are bounded.
+	r, _ = start("plan-readonly")
+	for range mode.MaxOutputRepairs {
+		complete(r.ID, "Use alpha [cite:0123456789ab].")
+	}
+	if got := complete(r.ID, "Use alpha."); got.Status != run.StatusFailed || !strings.Contains(got.Error, "citations missing or invalid") {
+		t.Fatalf("expected failed run after repairs, got %+v", got)
+	}
+
+	// Other runs may answer without citations.
+	r, p = start("trusted-mount-autonomous")
+	if strings.Contains(p.Prompt, "## Citations") {
+		t.Fatal("expected no citation instructions outside plan mode")
+	}
+	if got := complete(r.ID, "Done."); got.Status != run.StatusCompleted {
+		t.Fatalf("expected completed run, got %+v", got)
+	}
+}
+
+// lastStart returns the last published run start payload.
+func lastStart(t *testing.T, queue *runtimeMockQueue) messagequeue.RunStartPayload {
+	t.Helper()
+	msg, ok := queue.lastMessage(messagequeue.SubjectRunStart)
+	if !ok {
+		t.Fatal("expected a run start message")
+	}
+	var p messagequeue.RunStartPayload
+	if err := json.Unmarshal(msg.Data, &p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+// Commit status contexts reported to git hosts. Branch protection rules
+// refer to these names.
+const (
+	StatusContextReview = "codeforge/review"
+	StatusContextRun    = "codeforge/run"
+)
+
+// maxStatusDescription is the longest description GitHub accepts.
+const maxStatusDescription = 140
+
+// hostedGitProvider builds the project's git provider from its
+// hostedConfig.
+func hostedGitProvider(ctx context.Context, secrets *SecretService, p *project.Project) (gitprovider.Provider, error) {
+	cfg, err := hostedConfig(ctx, secrets, p)
+	if err != nil {
+		return nil, err
+	}
+	return gitprovider.New(p.Provider, cfg)
+}
+
+// hostedConfig returns the project config with the API token named by
+// git_token_secret and the webhook secret named by git_webhook_secret
+// resolved into it. GitHub projects without a token secret authenticate
+// as their installation of the tenant's GitHub App, if they name one.
+func hostedConfig(ctx context.Context, secrets *SecretService, p *project.Project) (map[string]string, error) {
+	cfg := maps.Clone(p.Config)
+	if cfg == nil {
+		cfg = make(map[string]string)
+	}
+	for ref, key := range map[string]string{
+		gitprovider.ConfigTokenSecret:   gitprovider.ConfigToken,
+		gitprovider.ConfigWebhookSecret: gitprovider.ConfigWebhookKey,
+	} {
+		name := p.Config[ref]
+		if name == "" {
+			continue
+		}
+		vals, err := secrets.Resolve(ctx, p.ID, []string{name})
+		if err != nil {
+			return nil, fmt.Errorf("resolve %s: %w", ref, err)
+		}
+		cfg[key] = vals[name]
+	}
+	if cfg[gitprovider.ConfigToken] == "" && secrets != nil {
+		token, err := secrets.githubApps.Token(ctx, p)
+		if err != nil {
+			return nil, fmt.Errorf("github app token: %w", err)
+		}
+		if token != "" {
+			cfg[gitprovider.ConfigToken] = token
+		}
+	}
+	return cfg, nil
+}
+
+// statusReporter returns prov as a StatusReporter if it can report commit
+// statuses, or nil.
+func statusReporter(prov gitprovider.Provider) gitprovider.StatusReporter {
+	reporter, ok := prov.(gitprovider.StatusReporter)
+	if !ok || !prov.Capabilities().CommitStatus {
+		return nil
+	}
+	return reporter
+}
+
+// setCommitStatus reports a commit status and logs failures: statuses are
+// advisory on the CodeForge side, so reporting never fails the caller.
+func setCommitStatus(ctx context.Context, reporter gitprovider.StatusReporter, sha string, st *gitprovider.CommitStatus) bool {
+	if r := []rune(st.Description); len(r) > maxStatusDescription {
+		st.Description = string(r[:maxStatusDescription-3]) + "..."
+	}
+	if err := reporter.SetCommitStatus(ctx, sha, st); err != nil {
+		slog.Warn("commit status report failed", "sha", sha, "context", st.Context, "state", st.State, "error", err)
+		return false
+	}
+	return true
+}
+
+// runURL returns the web UI link for a run, or "" without a public URL.
+func runURL(publicURL, projectID, runID string) string {
+	if publicURL == "" {
+		return ""
+	}
+	return publicURL + "/projects/" + url.PathEscape(projectID) + "?" + url.Values{"run": {runID}}.Encode()
+}
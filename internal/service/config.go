@@ -0,0 +1,101 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+)
+
+// ConfigExplanation is the effective configuration with the origin of
+// every value.
+type ConfigExplanation struct {
+	File           string          `json:"file"`
+	LoadedAt       time.Time       `json:"loaded_at"`
+	Values         []config.Value  `json:"values"`
+	PendingRestart []config.Change `json:"pending_restart"` // Changed since startup; the old value stays in use until a restart
+}
+
+// ConfigService holds the loaded configuration and reloads it, e.g. on
+// SIGHUP. Values with a live handler (OnChange) take effect on reload; all
+// others take effect on the next restart.
+type ConfigService struct {
+	opts config.Options
+
+	mu       sync.RWMutex
+	boot     *config.Config // Config the server started with
+	cfg      *config.Config // Latest loaded config
+	prov     config.Provenance
+	loadedAt time.Time
+	live     map[string]func(*config.Config)
+}
+
+// NewConfigService creates a ConfigService for a config loaded from opts.
+func NewConfigService(opts config.Options, cfg *config.Config, prov config.Provenance) *ConfigService {
+	if opts.File == "" {
+		opts.File = config.DefaultConfigFile
+	}
+	return &ConfigService{
+		opts:     opts,
+		boot:     cfg,
+		cfg:      cfg,
+		prov:     prov,
+		loadedAt: time.Now(),
+		live:     make(map[string]func(*config.Config)),
+	}
+}
+
+// OnChange registers fn to apply a changed key without a restart.
+func (s *ConfigService) OnChange(key string, fn func(*config.Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.live[key] = fn
+}
+
+// Explain returns the loaded configuration, secrets redacted, and the
+// changes still waiting for a restart.
+func (s *ConfigService) Explain() *ConfigExplanation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	pending := []config.Change{}
+	for _, c := range config.Diff(s.boot, s.cfg) {
+		if s.live[c.Key] == nil {
+			c.RestartRequired = true
+			pending = append(pending, c)
+		}
+	}
+	return &ConfigExplanation{
+		File:           s.opts.File,
+		LoadedAt:       s.loadedAt,
+		Values:         config.Explain(s.cfg, s.prov),
+		PendingRestart: pending,
+	}
+}
+
+// Reload loads the configuration again and returns the values that
+// changed since the last load. An invalid configuration is rejected and
+// the current one stays in place.
+func (s *ConfigService) Reload() ([]config.Change, error) {
+	cfg, prov, err := config.LoadFrom(s.opts)
+	if err != nil {
+		return nil, fmt.Errorf("reload config: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changes := config.Diff(s.cfg, cfg)
+	for i := range changes {
+		if fn := s.live[changes[i].Key]; fn != nil {
+			fn(cfg)
+		} else {
+			changes[i].RestartRequired = true
+		}
+		slog.Info("config value changed", "key", changes[i].Key,
+			"old", changes[i].Old, "new", changes[i].New, "restart_required", changes[i].RestartRequired)
+	}
+	s.cfg, s.prov, s.loadedAt = cfg, prov, time.Now()
+	slog.Info("config reloaded", "file", s.opts.File, "changes", len(changes))
+	return changes, nil
+}
@@ -0,0 +1,62 @@
+package service_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestConfigService_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "codeforge.yaml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("logging:\n  level: info\nrate:\n  burst: 100\n")
+	opts := config.Options{File: path}
+	cfg, prov, err := config.LoadFrom(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := service.NewConfigService(opts, cfg, prov)
+	var level string
+	svc.OnChange("logging.level", func(c *config.Config) { level = c.Logging.Level })
+
+	write("logging:\n  level: debug\nrate:\n  burst: 50\n")
+	changes, err := svc.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || level != "debug" {
+		t.Fatalf("unexpected changes %+v (level %q)", changes, level)
+	}
+	if changes[0].Key != "logging.level" || changes[0].RestartRequired || changes[1].Key != "rate.burst" || !changes[1].RestartRequired {
+		t.Fatalf("unexpected changes %+v", changes)
+	}
+
+	ex := svc.Explain()
+	if len(ex.PendingRestart) != 1 || ex.PendingRestart[0].Key != "rate.burst" || ex.PendingRestart[0].Old != 100 || ex.PendingRestart[0].New != 50 {
+		t.Fatalf("unexpected pending changes %+v", ex.PendingRestart)
+	}
+	for _, v := range ex.Values {
+		if v.Key == "rate.burst" && (v.Value != 50 || v.Origin != path+":4") {
+			t.Fatalf("unexpected value %+v", v)
+		}
+	}
+
+	// An invalid file is rejected and the loaded config stays.
+	write("logging:\n  levle: debug\n")
+	if _, err := svc.Reload(); err == nil || !strings.Contains(err.Error(), "did you mean level?") {
+		t.Fatalf("expected the unknown key to be reported, got %v", err)
+	}
+	write("logging:\n  level: debug\nrate:\n  burst: 50\n")
+	if changes, err := svc.Reload(); err != nil || len(changes) != 0 {
+		t.Fatalf("expected no changes against the last valid config, got %+v, %v", changes, err)
+	}
+}
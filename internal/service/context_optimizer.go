@@ -1,24 +1,49 @@
 package service
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"os"
 	"path/filepath"
-	"sort"
+	"slices"
 	"strings"
 
 	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/domain/codegraph"
 	cfcontext "github.com/Strob0t/CodeForge/internal/domain/context"
+	"github.com/Strob0t/CodeForge/internal/domain/memory"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/research"
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
 	"github.com/Strob0t/CodeForge/internal/port/database"
 )
 
 // ContextOptimizerService builds context packs for tasks by scoring file relevance,
 // trimming to token budgets, and injecting shared context from team collaboration.
 type ContextOptimizerService struct {
-	store   database.Store
-	orchCfg *config.Orchestrator
+	store       database.Store
+	orchCfg     *config.Orchestrator
+	tokenizer   *TokenizerService
+	memories    *MemoryService
+	modes       *ModeService
+	retrieval   *RetrievalService
+	conventions *ConventionService
+}
+
+// PackScope tailors a context pack to the run it is built for.
+type PackScope struct {
+	SubProject *project.SubProject // Only scan this sub-project's files
+	Model      string              // Count tokens for this model ("" for the default)
+	Mode       string              // Agent mode; its skip_memories disables memory recall
+	Budget     int                 // Token budget; 0 uses orchestrator.default_context_budget
 }
 
 // NewContextOptimizerService creates a ContextOptimizerService.
@@ -26,28 +51,136 @@ func NewContextOptimizerService(store database.Store, orchCfg *config.Orchestrat
 	return &ContextOptimizerService{store: store, orchCfg: orchCfg}
 }
 
+// SetTokenizer counts entry tokens with the tokenizer of the run's model
+// instead of the 4-characters-per-token heuristic.
+func (s *ContextOptimizerService) SetTokenizer(t *TokenizerService) {
+	s.tokenizer = t
+}
+
+// SetMemoryService recalls project memories matching the task into context
+// packs. Modes are looked up to honor their skip_memories toggle.
+func (s *ContextOptimizerService) SetMemoryService(m *MemoryService, modes *ModeService) {
+	s.memories = m
+	s.modes = modes
+}
+
+// SetRetrievalService adds the chunks of the project's retrieval index and
+// knowledge bases best matching the task to context packs, as citable
+// snippets.
+func (s *ContextOptimizerService) SetRetrievalService(r *RetrievalService) {
+	s.retrieval = r
+}
+
+// SetConventionService adds the project's conventions document to
+// context packs.
+func (s *ContextOptimizerService) SetConventionService(c *ConventionService) {
+	s.conventions = c
+}
+
+// countTokens returns the tokens of text for model.
+func (s *ContextOptimizerService) countTokens(model, text string) int {
+	if s.tokenizer == nil {
+		return cfcontext.EstimateTokens(text)
+	}
+	return s.tokenizer.Count(model, text)
+}
+
 // GetPackByTask returns the existing context pack for a task, if any.
 func (s *ContextOptimizerService) GetPackByTask(ctx context.Context, taskID string) (*cfcontext.ContextPack, error) {
 	return s.store.GetContextPackByTask(ctx, taskID)
 }
 
-// BuildContextPack creates a context pack for a task by:
-// 1. Scanning workspace files and scoring by keyword relevance
-// 2. Injecting shared context items (if teamID is provided)
-// 3. Packing entries within the token budget
-// 4. Persisting the pack in the store
+// BuildContextPack creates a context pack for a task, scoped to the task's
+// sub-project if it targets one (see BuildScopedContextPack). Tokens are
+// counted for the tokenizer's default model and memories are recalled
+// without a mode.
 func (s *ContextOptimizerService) BuildContextPack(ctx context.Context, taskID, projectID, teamID string) (*cfcontext.ContextPack, error) {
-	proj, err := s.store.GetProject(ctx, projectID)
+	t, err := s.store.GetTask(ctx, taskID)
 	if err != nil {
-		return nil, fmt.Errorf("get project: %w", err)
+		return nil, fmt.Errorf("get task: %w", err)
 	}
+	return s.BuildScopedContextPack(ctx, t, projectID, teamID, PackScope{SubProject: subProjectScope(ctx, s.store, t, nil)})
+}
 
+// BuildScopedContextPack creates a context pack for a task by:
+// 1. Scanning workspace files (only those of the scope's sub-project, if set) and scoring by keyword relevance
+// 2. Injecting shared context items (if teamID is provided)
+// 3. Attaching research findings addressed to this task
+// 4. Recalling project memories matching the task prompt (see RecallMemories)
+// 5. Adding retrieved chunks matching the task prompt as citable snippets
+// 6. Adding the project's conventions document
+// 7. Applying the task's curation: pins, exclusions and priority overrides
+// 8. Packing entries within the token budget, counted for the scope's model
+// 9. Persisting the pack in the store
+func (s *ContextOptimizerService) BuildScopedContextPack(ctx context.Context, t *task.Task, projectID, teamID string, scope PackScope) (*cfcontext.ContextPack, error) {
+	preview, err := s.assemble(ctx, t, projectID, teamID, scope)
+	if err != nil {
+		return nil, err
+	}
+	pack := preview.Pack
+	if pack == nil {
+		return nil, nil
+	}
+
+	if err := s.store.CreateContextPack(ctx, pack); err != nil {
+		return nil, fmt.Errorf("persist context pack: %w", err)
+	}
+
+	slog.Info("context pack built",
+		"task_id", t.ID,
+		"entries", len(pack.Entries),
+		"tokens_used", pack.TokensUsed,
+		"budget", pack.TokenBudget,
+		"dropped", len(preview.Dropped),
+	)
+	return pack, nil
+}
+
+// PreviewContextPack returns the context pack that would be built for a
+// task now, scoped like BuildContextPack, without persisting it. The
+// preview lists the candidates left out and why.
+func (s *ContextOptimizerService) PreviewContextPack(ctx context.Context, taskID, projectID, teamID string) (*cfcontext.PackPreview, error) {
 	t, err := s.store.GetTask(ctx, taskID)
 	if err != nil {
 		return nil, fmt.Errorf("get task: %w", err)
 	}
+	return s.assemble(ctx, t, projectID, teamID, PackScope{SubProject: subProjectScope(ctx, s.store, t, nil)})
+}
+
+// GetCuration returns the context curation of a task.
+func (s *ContextOptimizerService) GetCuration(ctx context.Context, taskID string) (*cfcontext.Curation, error) {
+	return s.store.GetContextCuration(ctx, taskID)
+}
+
+// SetCuration validates and stores the context curation of a task,
+// replacing any earlier one. Later packs for the task apply it.
+func (s *ContextOptimizerService) SetCuration(ctx context.Context, c *cfcontext.Curation) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	t, err := s.store.GetTask(ctx, c.TaskID)
+	if err != nil {
+		return fmt.Errorf("get task: %w", err)
+	}
+	c.ProjectID = t.ProjectID
+	if c.Rules == nil {
+		c.Rules = []cfcontext.CurationRule{}
+	}
+	return s.store.SaveContextCuration(ctx, c)
+}
+
+// DeleteCuration removes the context curation of a task.
+func (s *ContextOptimizerService) DeleteCuration(ctx context.Context, taskID string) error {
+	return s.store.DeleteContextCuration(ctx, taskID)
+}
 
-	budget := s.orchCfg.DefaultContextBudget
+// budget returns the token budget of a scope and the tokens of it context
+// entries may take, leaving orchestrator.prompt_reserve for the prompt.
+func (s *ContextOptimizerService) budget(scope PackScope) (budget, available int) {
+	budget = s.orchCfg.DefaultContextBudget
+	if scope.Budget > 0 {
+		budget = scope.Budget
+	}
 	if budget <= 0 {
 		budget = 4096
 	}
@@ -55,50 +188,137 @@ func (s *ContextOptimizerService) BuildContextPack(ctx context.Context, taskID,
 	if reserve <= 0 {
 		reserve = 1024
 	}
-	available := budget - reserve
+	available = budget - reserve
 	if available <= 0 {
 		available = budget / 2
 	}
+	return budget, available
+}
+
+// RepoMapEntry returns the repo map as a context entry of a run built with
+// scope. Its tokens are counted with the tokenizer of the scope's model,
+// and it keeps the most depended-on files that fit in what the pack, which
+// may be nil, left of the budget. It returns false if no file fits. A nil
+// ContextOptimizerService has no budget and counts with the heuristic.
+func (s *ContextOptimizerService) RepoMapEntry(m *codegraph.RepoMap, scope PackScope, pack *cfcontext.ContextPack) (cfcontext.ContextEntry, bool) {
+	left, count := math.MaxInt, cfcontext.EstimateTokens
+	if s != nil {
+		_, left = s.budget(scope)
+		if pack != nil {
+			left -= pack.TokensUsed
+		}
+		count = func(text string) int { return s.countTokens(scope.Model, text) }
+	}
+	if !m.Fit(left, count) {
+		return cfcontext.ContextEntry{}, false
+	}
+	text := m.Text()
+	return cfcontext.ContextEntry{
+		Kind:     cfcontext.EntrySummary,
+		Path:     "repo-map",
+		Content:  text,
+		Tokens:   count(text),
+		Priority: 70, // Overview; below the entries picked for the task.
+	}, true
+}
+
+// assemble gathers the candidate entries of a task's context pack, applies
+// the task's curation and packs them within the token budget. The preview's
+// pack is nil when no entry made it in.
+func (s *ContextOptimizerService) assemble(ctx context.Context, t *task.Task, projectID, teamID string, scope PackScope) (*cfcontext.PackPreview, error) {
+	taskID := t.ID
+	sp, model := scope.SubProject, scope.Model
+	proj, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+
+	budget, available := s.budget(scope)
 
 	var candidates []cfcontext.ContextEntry
 
-	// Scan workspace files if workspace path is set.
+	// Scan workspace files if workspace path is set. A sub-project limits
+	// the scan to its directory so unrelated packages do not crowd it out.
 	if proj.WorkspacePath != "" {
-		fileEntries := s.scanWorkspaceFiles(proj.WorkspacePath, t.Prompt)
+		root, prefix := proj.WorkspacePath, ""
+		if sp != nil {
+			root, prefix = filepath.Join(root, filepath.FromSlash(sp.Path)), sp.Path+"/"
+		}
+		fileEntries := s.scanWorkspaceFiles(root, prefix, t.Prompt, model)
 		candidates = append(candidates, fileEntries...)
 	}
 
-	// Inject shared context if team is specified.
+	// Inject shared context if team is specified. Stored item counts are
+	// for the default model, so they are recounted for this one.
 	if teamID != "" {
 		sc, err := s.store.GetSharedContextByTeam(ctx, teamID)
 		if err == nil && sc != nil {
 			for _, item := range sc.Items {
+				tokens := item.Tokens
+				if s.tokenizer != nil {
+					tokens = s.tokenizer.Count(model, item.Value)
+				}
 				candidates = append(candidates, cfcontext.ContextEntry{
 					Kind:     cfcontext.EntryShared,
 					Path:     item.Key,
 					Content:  item.Value,
-					Tokens:   item.Tokens,
+					Tokens:   tokens,
 					Priority: 90, // Shared context is high priority.
 				})
 			}
 		}
 	}
 
-	if len(candidates) == 0 {
+	// Attach findings from research runs that named this task as their follow-up.
+	candidates = append(candidates, s.researchEntries(ctx, projectID, taskID, model)...)
+
+	candidates = append(candidates, s.RecallMemories(ctx, projectID, t.Prompt, scope.Mode, model)...)
+
+	candidates = append(candidates, s.retrievalEntries(ctx, projectID, t.Prompt, sp, model)...)
+
+	if s.conventions != nil {
+		if text := s.conventions.Section(ctx, projectID); text != "" {
+			candidates = append(candidates, cfcontext.ContextEntry{
+				Kind:     cfcontext.EntryConventions,
+				Path:     "conventions",
+				Content:  text,
+				Tokens:   s.countTokens(model, text),
+				Priority: 95, // Applies to every change; above shared context.
+			})
+		}
+	}
+
+	preview := &cfcontext.PackPreview{Dropped: []cfcontext.DroppedEntry{}}
+	cur, err := s.store.GetContextCuration(ctx, taskID)
+	switch {
+	case err == nil:
+		preview.Curation = cur
+	case !errors.Is(err, domain.ErrNotFound):
+		return nil, fmt.Errorf("get context curation: %w", err)
+	}
+
+	var pins []cfcontext.ContextEntry
+	if cur != nil {
+		candidates, pins = s.curate(ctx, proj, cur, candidates, model, preview)
+	}
+
+	if len(candidates)+len(pins) == 0 {
 		slog.Debug("no context candidates found", "task_id", taskID, "project_id", projectID)
-		return nil, nil
+		return preview, nil
 	}
 
-	// Sort by priority descending.
-	sort.Slice(candidates, func(i, j int) bool {
-		return candidates[i].Priority > candidates[j].Priority
-	})
+	// Sort by priority descending, pinned entries first.
+	byPriority := func(a, b cfcontext.ContextEntry) int { return cmp.Compare(b.Priority, a.Priority) }
+	slices.SortStableFunc(pins, byPriority)
+	slices.SortStableFunc(candidates, byPriority)
+	candidates = append(pins, candidates...)
 
 	// Pack entries within budget.
 	var packed []cfcontext.ContextEntry
 	tokensUsed := 0
 	for i := range candidates {
 		if tokensUsed+candidates[i].Tokens > available {
+			preview.Dropped = append(preview.Dropped, droppedEntry(&candidates[i], cfcontext.DropBudget))
 			continue
 		}
 		packed = append(packed, candidates[i])
@@ -106,32 +326,253 @@ func (s *ContextOptimizerService) BuildContextPack(ctx context.Context, taskID,
 	}
 
 	if len(packed) == 0 {
-		return nil, nil
+		return preview, nil
 	}
 
-	pack := &cfcontext.ContextPack{
+	preview.Pack = &cfcontext.ContextPack{
 		TaskID:      taskID,
 		ProjectID:   projectID,
 		TokenBudget: budget,
 		TokensUsed:  tokensUsed,
 		Entries:     packed,
 	}
+	return preview, nil
+}
 
-	if err := s.store.CreateContextPack(ctx, pack); err != nil {
-		return nil, fmt.Errorf("persist context pack: %w", err)
+// curate applies a curation to the candidate entries of a pack. Excluded
+// entries are recorded as dropped in preview and priority overrides
+// applied. Pinned entries are returned apart, along with the targets of
+// pins no candidate matched; pins whose target is not found are listed in
+// preview.Pinned.
+func (s *ContextOptimizerService) curate(ctx context.Context, proj *project.Project, cur *cfcontext.Curation, candidates []cfcontext.ContextEntry, model string, preview *cfcontext.PackPreview) (kept, pins []cfcontext.ContextEntry) {
+	matched := make(map[*cfcontext.CurationRule]bool)
+	for i := range candidates {
+		e := candidates[i]
+		r := cur.Match(&e)
+		if r == nil {
+			kept = append(kept, e)
+			continue
+		}
+		matched[r] = true
+		switch r.Action {
+		case cfcontext.CurationExclude:
+			preview.Dropped = append(preview.Dropped, droppedEntry(&e, cfcontext.DropExcluded))
+		case cfcontext.CurationPin:
+			pins = append(pins, e)
+		case cfcontext.CurationPriority:
+			e.Priority = r.Priority
+			kept = append(kept, e)
+		}
+	}
+	for i := range cur.Rules {
+		r := &cur.Rules[i]
+		if r.Action != cfcontext.CurationPin || matched[r] {
+			continue
+		}
+		if e := s.pinnedEntry(ctx, proj, r, model); e != nil {
+			pins = append(pins, *e)
+		} else {
+			preview.Pinned = append(preview.Pinned, cmp.Or(r.ChunkID, r.Path))
+		}
 	}
+	return kept, pins
+}
 
-	slog.Info("context pack built",
-		"task_id", taskID,
-		"entries", len(packed),
-		"tokens_used", tokensUsed,
-		"budget", budget,
-	)
-	return pack, nil
+// pinnedEntry loads the target of a pin rule no candidate matched: a
+// chunk of the project's retrieval index or a workspace file. It returns
+// nil if the target cannot be found.
+func (s *ContextOptimizerService) pinnedEntry(ctx context.Context, proj *project.Project, r *cfcontext.CurationRule, model string) *cfcontext.ContextEntry {
+	if r.ChunkID != "" {
+		chunks, err := s.store.ListRetrievalChunks(ctx, proj.ID)
+		if err != nil {
+			slog.Warn("list retrieval chunks for pin failed", "project_id", proj.ID, "error", err)
+			return nil
+		}
+		for i := range chunks {
+			if chunks[i].ID() != r.ChunkID {
+				continue
+			}
+			c := chunks[i].Citation()
+			text := retrieval.Cite(c.ID) + " " + c.Label() + "\n" + chunks[i].Content
+			return &cfcontext.ContextEntry{
+				Kind:     cfcontext.EntrySnippet,
+				Path:     chunks[i].Path,
+				Content:  text,
+				Tokens:   s.countTokens(model, text),
+				Priority: 100,
+				Citation: &c,
+			}
+		}
+		return nil
+	}
+	if proj.WorkspacePath == "" || strings.HasSuffix(r.Path, "/") {
+		return nil
+	}
+	rel, err := workspacePath(r.Path, false)
+	if err != nil {
+		return nil
+	}
+	data, err := readWorkspaceFile(proj.WorkspacePath, rel)
+	if err != nil {
+		return nil
+	}
+	text := string(data)
+	return &cfcontext.ContextEntry{
+		Kind:     cfcontext.EntryFile,
+		Path:     rel,
+		Content:  text,
+		Tokens:   s.countTokens(model, text),
+		Priority: 100,
+	}
+}
+
+// droppedEntry describes an entry left out of a pack for reason.
+func droppedEntry(e *cfcontext.ContextEntry, reason string) cfcontext.DroppedEntry {
+	d := cfcontext.DroppedEntry{Kind: e.Kind, Path: e.Path, Tokens: e.Tokens, Priority: e.Priority, Reason: reason}
+	if e.Citation != nil {
+		d.ChunkID = e.Citation.ID
+	}
+	return d
+}
+
+// researchEntries returns context entries for research reports whose
+// follow-up task is taskID. Lookup failures are logged and skipped.
+func (s *ContextOptimizerService) researchEntries(ctx context.Context, projectID, taskID, model string) []cfcontext.ContextEntry {
+	arts, err := s.store.ListArtifactsByProject(ctx, projectID, artifact.KindResearchReport)
+	if err != nil {
+		slog.Warn("list research artifacts failed", "project_id", projectID, "error", err)
+		return nil
+	}
+
+	var result []cfcontext.ContextEntry
+	for i := range arts {
+		if arts[i].Metadata[research.MetaFollowUpTaskID] != taskID {
+			continue
+		}
+		full, err := s.store.GetArtifact(ctx, arts[i].ID)
+		if err != nil {
+			slog.Warn("get research artifact failed", "artifact_id", arts[i].ID, "error", err)
+			continue
+		}
+		var report research.Report
+		if err := json.Unmarshal(full.Data, &report); err != nil {
+			slog.Warn("decode research report failed", "artifact_id", full.ID, "error", err)
+			continue
+		}
+		text := report.Render()
+		if text == "" {
+			continue
+		}
+		result = append(result, cfcontext.ContextEntry{
+			Kind:     cfcontext.EntryResearch,
+			Path:     "research/" + full.RunID,
+			Content:  text,
+			Tokens:   s.countTokens(model, text),
+			Priority: 85, // Research was requested for this task explicitly.
+		})
+	}
+	return result
+}
+
+// RecallMemories returns context entries for the project memories best
+// matching query, counted for model. Nothing is recalled without a memory
+// service, when recall is disabled or when the mode sets skip_memories.
+// Recall failures are logged and skipped.
+func (s *ContextOptimizerService) RecallMemories(ctx context.Context, projectID, query, modeID, model string) []cfcontext.ContextEntry {
+	if s.memories == nil || !s.memories.Enabled() || strings.TrimSpace(query) == "" {
+		return nil
+	}
+	if modeID != "" && s.modes != nil {
+		if m, err := s.modes.Get(modeID); err == nil && m.SkipMemories {
+			return nil
+		}
+	}
+	recalled, err := s.memories.Recall(ctx, projectID, memory.RecallRequest{Query: query})
+	if err != nil {
+		slog.Warn("memory recall failed", "project_id", projectID, "error", err)
+		return nil
+	}
+
+	result := make([]cfcontext.ContextEntry, 0, len(recalled))
+	for i := range recalled {
+		result = append(result, cfcontext.ContextEntry{
+			Kind:     cfcontext.EntryMemory,
+			Path:     memoryPathPrefix + recalled[i].ID,
+			Content:  recalled[i].Content,
+			Tokens:   s.countTokens(model, recalled[i].Content),
+			Priority: 80, // Already ranked by recall; below shared context and research.
+		})
+	}
+	return result
+}
+
+// memoryPathPrefix prefixes the memory ID in the path of memory entries.
+const memoryPathPrefix = "memory/"
+
+// memoryIDs returns the IDs of the memory entries among entries.
+func memoryIDs(entries []cfcontext.ContextEntry) []string {
+	var ids []string
+	for i := range entries {
+		if entries[i].Kind == cfcontext.EntryMemory {
+			ids = append(ids, strings.TrimPrefix(entries[i].Path, memoryPathPrefix))
+		}
+	}
+	return ids
+}
+
+// retrievalLimit is the number of retrieved chunks offered to a pack.
+const retrievalLimit = 8
+
+// retrievalEntries returns citable snippets for the chunks of the project's
+// retrieval index and knowledge bases best matching query. With a
+// sub-project, workspace chunks outside it are left out. Projects without
+// an index get none; search failures are logged and skipped.
+func (s *ContextOptimizerService) retrievalEntries(ctx context.Context, projectID, query string, sp *project.SubProject, model string) []cfcontext.ContextEntry {
+	if s.retrieval == nil || strings.TrimSpace(query) == "" {
+		return nil
+	}
+	results, err := s.retrieval.Search(ctx, projectID, &retrieval.SearchRequest{Query: query, Limit: retrievalLimit})
+	if err != nil {
+		if !errors.Is(err, retrieval.ErrNotIndexed) {
+			slog.Warn("retrieval for context pack failed", "project_id", projectID, "error", err)
+		}
+		return nil
+	}
+
+	var result []cfcontext.ContextEntry
+	for i := range results {
+		r := &results[i]
+		if sp != nil && r.Source == "" && !strings.HasPrefix(r.Path, sp.Path+"/") {
+			continue
+		}
+		c := r.Citation()
+		text := retrieval.Cite(c.ID) + " " + c.Label() + "\n" + r.Content
+		result = append(result, cfcontext.ContextEntry{
+			Kind:     cfcontext.EntrySnippet,
+			Path:     r.Path,
+			Content:  text,
+			Tokens:   s.countTokens(model, text),
+			Priority: 75, // Ranked by similarity; below memories.
+			Citation: &c,
+		})
+	}
+	return result
+}
+
+// citationIDs returns the chunk IDs of the citable entries among entries.
+func citationIDs(entries []cfcontext.ContextEntry) []string {
+	var ids []string
+	for i := range entries {
+		if entries[i].Citation != nil {
+			ids = append(ids, entries[i].Citation.ID)
+		}
+	}
+	return ids
 }
 
-// scanWorkspaceFiles reads workspace files and scores them against the task prompt.
-func (s *ContextOptimizerService) scanWorkspaceFiles(workspacePath, taskPrompt string) []cfcontext.ContextEntry {
+// scanWorkspaceFiles reads workspace files and scores them against the task
+// prompt. Entry paths are relative to workspacePath, prepended with prefix.
+func (s *ContextOptimizerService) scanWorkspaceFiles(workspacePath, prefix, taskPrompt, model string) []cfcontext.ContextEntry {
 	const maxFiles = 50
 	const maxFileSize = 32 * 1024 // 32 KB per file
 
@@ -167,14 +608,14 @@ func (s *ContextOptimizerService) scanWorkspaceFiles(workspacePath, taskPrompt s
 				if se.IsDir() || strings.HasPrefix(se.Name(), ".") {
 					continue
 				}
-				entry := s.readAndScore(filepath.Join(subPath, se.Name()), name+"/"+se.Name(), taskPrompt, maxFileSize)
+				entry := s.readAndScore(filepath.Join(subPath, se.Name()), prefix+name+"/"+se.Name(), taskPrompt, model, maxFileSize)
 				if entry != nil {
 					result = append(result, *entry)
 					fileCount++
 				}
 			}
 		} else {
-			entry := s.readAndScore(filepath.Join(workspacePath, name), name, taskPrompt, maxFileSize)
+			entry := s.readAndScore(filepath.Join(workspacePath, name), prefix+name, taskPrompt, model, maxFileSize)
 			if entry != nil {
 				result = append(result, *entry)
 				fileCount++
@@ -186,7 +627,7 @@ func (s *ContextOptimizerService) scanWorkspaceFiles(workspacePath, taskPrompt s
 }
 
 // readAndScore reads a file and returns a ContextEntry with relevance scoring.
-func (s *ContextOptimizerService) readAndScore(absPath, relPath, taskPrompt string, maxSize int64) *cfcontext.ContextEntry {
+func (s *ContextOptimizerService) readAndScore(absPath, relPath, taskPrompt, model string, maxSize int64) *cfcontext.ContextEntry {
 	info, err := os.Stat(absPath)
 	if err != nil || info.Size() > maxSize || info.Size() == 0 {
 		return nil
@@ -203,7 +644,7 @@ func (s *ContextOptimizerService) readAndScore(absPath, relPath, taskPrompt stri
 		return nil
 	}
 
-	tokens := cfcontext.EstimateTokens(text)
+	tokens := s.countTokens(model, text)
 	return &cfcontext.ContextEntry{
 		Kind:     cfcontext.EntryFile,
 		Path:     relPath,
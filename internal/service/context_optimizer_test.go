@@ -2,8 +2,11 @@ package service_test
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/Strob0t/CodeForge/internal/config"
@@ -193,3 +196,74 @@ func TestScoreFileRelevance(t *testing.T) {
 		t.Errorf("expected 0 score for unrelated file, got %d", scoreZero)
 	}
 }
+
+func TestPreviewContextPack_Curation(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "auth.go", "package auth\n\nfunc authenticate() {}")
+	writeTestFile(t, dir, "handler.go", "package main\n\nfunc authHandler() {}")
+	writeTestFile(t, dir, "notes/auth.md", "# Auth notes")
+	writeTestFile(t, dir, "style.txt", "Tabs, not spaces.")
+	writeTestFile(t, dir, "big.go", "// auth\n"+strings.Repeat("a", 30000))
+
+	store := &runtimeMockStore{
+		projects: []project.Project{{ID: "proj-1", Name: "test", WorkspacePath: dir}},
+		tasks:    []task.Task{{ID: "task-1", ProjectID: "proj-1", Prompt: "Fix auth handler"}},
+	}
+	orchCfg := &config.Orchestrator{DefaultContextBudget: 8192, PromptReserve: 1024}
+	svc := service.NewContextOptimizerService(store, orchCfg)
+	ctx := context.Background()
+
+	err := svc.SetCuration(ctx, &cfcontext.Curation{TaskID: "task-1", Rules: []cfcontext.CurationRule{
+		{Path: "style.txt", Action: cfcontext.CurationPin},
+		{Path: "ghost.go", Action: cfcontext.CurationPin},
+		{Path: "notes/", Action: cfcontext.CurationExclude},
+		{Path: "auth.go", Action: cfcontext.CurationPriority, Priority: 5},
+	}})
+	if err != nil {
+		t.Fatalf("set curation: %v", err)
+	}
+
+	preview, err := svc.PreviewContextPack(ctx, "task-1", "proj-1", "")
+	if err != nil {
+		t.Fatalf("preview: %v", err)
+	}
+	if preview.Pack == nil || preview.Curation == nil || preview.Curation.ProjectID != "proj-1" {
+		t.Fatalf("expected a curated pack, got %+v", preview)
+	}
+	var paths []string
+	for _, e := range preview.Pack.Entries {
+		paths = append(paths, e.Path)
+	}
+	if !slices.Equal(paths, []string{"style.txt", "handler.go", "auth.go"}) {
+		t.Fatalf("expected the pin first and auth.go last, got %v", paths)
+	}
+	dropped := make(map[string]string)
+	for _, d := range preview.Dropped {
+		dropped[d.Path] = d.Reason
+	}
+	if dropped["notes/auth.md"] != cfcontext.DropExcluded || dropped["big.go"] != cfcontext.DropBudget {
+		t.Fatalf("expected the notes excluded and big.go over budget, got %v", dropped)
+	}
+	if !slices.Equal(preview.Pinned, []string{"ghost.go"}) {
+		t.Fatalf("expected the missing pin reported, got %v", preview.Pinned)
+	}
+	if len(store.contextPacks) != 0 {
+		t.Fatal("expected a preview not to persist the pack")
+	}
+
+	if err := svc.SetCuration(ctx, &cfcontext.Curation{TaskID: "task-1", Rules: []cfcontext.CurationRule{{Action: cfcontext.CurationPin}}}); !errors.Is(err, cfcontext.ErrInvalidCuration) {
+		t.Fatalf("expected an invalid curation rejected, got %v", err)
+	}
+	if err := svc.DeleteCuration(ctx, "task-1"); err != nil {
+		t.Fatalf("delete curation: %v", err)
+	}
+	pack, err := svc.BuildContextPack(ctx, "task-1", "proj-1", "")
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	for _, e := range pack.Entries {
+		if e.Path == "style.txt" {
+			t.Fatal("expected no pin after deleting the curation")
+		}
+	}
+}
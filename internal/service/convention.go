@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/convention"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+)
+
+// Limits of a conventions extraction.
+const (
+	maxConventionSampleBytes = 64 * 1024
+	conventionGitTimeout     = 30 * time.Second
+)
+
+// ConventionService extracts the conventions of project repositories —
+// formatting, linters, file naming, test layout and commit style — into a
+// document that context packs and conversation prompts carry. Documents
+// are refreshed when they get old or after a big merge.
+type ConventionService struct {
+	store database.Store
+	cfg   config.Conventions
+}
+
+// NewConventionService creates a ConventionService.
+func NewConventionService(store database.Store, cfg config.Conventions) *ConventionService {
+	return &ConventionService{store: store, cfg: cfg}
+}
+
+// Get returns the conventions of a project.
+func (s *ConventionService) Get(ctx context.Context, projectID string) (*convention.Conventions, error) {
+	return s.store.GetProjectConventions(ctx, projectID)
+}
+
+// Section returns the conventions of a project as a prompt section, or ""
+// if none were extracted. Failures are logged, since conventions only add
+// guidance and must not block runs or replies.
+func (s *ConventionService) Section(ctx context.Context, projectID string) string {
+	c, err := s.store.GetProjectConventions(ctx, projectID)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotFound) {
+			slog.Warn("get project conventions failed", "project_id", projectID, "error", err)
+		}
+		return ""
+	}
+	return c.Render()
+}
+
+// Extract analyzes the workspace of a project and its recent commits and
+// stores the resulting conventions, replacing earlier ones. Workspaces
+// that are not git repositories have no commit style.
+func (s *ConventionService) Extract(ctx context.Context, projectID, trigger string) (*convention.Conventions, error) {
+	proj, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return s.extract(ctx, proj, trigger)
+}
+
+func (s *ConventionService) extract(ctx context.Context, proj *project.Project, trigger string) (*convention.Conventions, error) {
+	root := proj.WorkspacePath
+	if root == "" {
+		return nil, fmt.Errorf("project %s has no workspace (not cloned)", proj.ID)
+	}
+	files, err := conventionFiles(root)
+	if err != nil {
+		return nil, err
+	}
+	in := &convention.Input{Files: files, Contents: make(map[string][]byte)}
+	var sources []string
+	for _, f := range files {
+		switch {
+		case convention.IsConfig(f):
+			if data := readFileHead(filepath.Join(root, filepath.FromSlash(f))); data != nil {
+				in.Contents[f] = data
+			}
+		case convention.LanguageOf(f) != "":
+			sources = append(sources, f)
+		}
+	}
+	for _, f := range sampleEvenly(sources, s.cfg.SampleFiles) {
+		if data := readFileHead(filepath.Join(root, filepath.FromSlash(f))); data != nil {
+			in.Contents[f] = data
+		}
+	}
+
+	gitCtx, cancel := context.WithTimeout(ctx, conventionGitTimeout)
+	defer cancel()
+	head := gitHead(gitCtx, root)
+	if head != "" {
+		out, err := runDeliverGit(gitCtx, root, "log", "-n", strconv.Itoa(s.cfg.Commits), "--no-merges", "--format=%s")
+		if err != nil {
+			slog.Warn("commit history for conventions unavailable", "project_id", proj.ID, "error", err)
+		} else {
+			in.Subjects = strings.Split(strings.TrimSpace(out), "\n")
+		}
+	}
+
+	c := convention.Analyze(in)
+	c.ProjectID, c.Trigger, c.Commit = proj.ID, trigger, head
+	if err := s.store.SaveProjectConventions(ctx, c); err != nil {
+		return nil, err
+	}
+	slog.Info("project conventions extracted", "project_id", proj.ID, "trigger", trigger,
+		"files", len(files), "sampled", c.SampledFiles, "languages", len(c.Languages), "commit", head)
+	return c, nil
+}
+
+// StartRefresher checks for stale conventions every check interval until
+// the returned cancel is called. It does nothing if the interval is 0.
+func (s *ConventionService) StartRefresher(ctx context.Context) (cancel func()) {
+	ctx, cancel = context.WithCancel(ctx)
+	if s.cfg.CheckInterval <= 0 {
+		return cancel
+	}
+	go func() {
+		ticker := time.NewTicker(s.cfg.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.RefreshDue(ctx); err != nil {
+					slog.Error("project conventions refresh check failed", "error", err)
+				}
+			}
+		}
+	}()
+	return cancel
+}
+
+// RefreshDue extracts the conventions of every cloned project that has
+// none, whose conventions are older than the refresh interval, or whose
+// HEAD moved by at least merge_files changed files since the extraction.
+// It returns how many were extracted; a failing project is logged and
+// does not stop the others.
+func (s *ConventionService) RefreshDue(ctx context.Context) (int, error) {
+	projects, err := s.store.ListProjects(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list projects: %w", err)
+	}
+	extracted := 0
+	for i := range projects {
+		p := &projects[i]
+		if p.WorkspacePath == "" {
+			continue
+		}
+		trigger, err := s.due(ctx, p)
+		if err != nil {
+			slog.Warn("project conventions check failed", "project_id", p.ID, "error", err)
+			continue
+		}
+		if trigger == "" {
+			continue
+		}
+		if _, err := s.extract(ctx, p, trigger); err != nil {
+			slog.Warn("project conventions extraction failed", "project_id", p.ID, "trigger", trigger, "error", err)
+			continue
+		}
+		extracted++
+	}
+	return extracted, nil
+}
+
+// due returns the trigger to extract a project's conventions with, or ""
+// if they are current.
+func (s *ConventionService) due(ctx context.Context, p *project.Project) (string, error) {
+	c, err := s.store.GetProjectConventions(ctx, p.ID)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return convention.TriggerSchedule, nil
+	case err != nil:
+		return "", err
+	case time.Since(c.ExtractedAt) >= s.cfg.RefreshInterval:
+		return convention.TriggerSchedule, nil
+	}
+
+	gitCtx, cancel := context.WithTimeout(ctx, conventionGitTimeout)
+	defer cancel()
+	head := gitHead(gitCtx, p.WorkspacePath)
+	if head == "" || c.Commit == "" || head == c.Commit {
+		return "", nil
+	}
+	out, err := runDeliverGit(gitCtx, p.WorkspacePath, "diff", "--name-only", c.Commit, head)
+	if err != nil {
+		// The extracted commit is gone, e.g. after a force push.
+		return convention.TriggerMerge, nil
+	}
+	if changed := strings.Count(out, "\n"); changed >= s.cfg.MergeFiles {
+		return convention.TriggerMerge, nil
+	}
+	return "", nil
+}
+
+// conventionFiles returns the files below root, relative and
+// slash-separated. Hidden and dependency directories are skipped.
+func conventionFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Unreadable entries are skipped
+		}
+		if d.IsDir() {
+			if name := d.Name(); p != root && (strings.HasPrefix(name, ".") || graphSkipDirs[name]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if len(files) >= maxGraphFiles {
+			return fs.SkipAll
+		}
+		rel, _ := filepath.Rel(root, p)
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan workspace: %w", err)
+	}
+	return files, nil
+}
+
+// sampleEvenly returns at most n of files, evenly spaced so every part of the
+// workspace is sampled.
+func sampleEvenly(files []string, n int) []string {
+	if len(files) <= n {
+		return files
+	}
+	picked := make([]string, 0, n)
+	for i := range n {
+		picked = append(picked, files[i*len(files)/n])
+	}
+	return slices.Compact(picked)
+}
+
+// readFileHead returns the start of a file, or nil if it cannot be read.
+func readFileHead(path string) []byte {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxConventionSampleBytes))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// gitHead returns the HEAD commit of a workspace, or "" if it is not a
+// git repository or has no commits.
+func gitHead(ctx context.Context, dir string) string {
+	out, err := runDeliverGit(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
@@ -0,0 +1,125 @@
+package service_test
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	cfcontext "github.com/Strob0t/CodeForge/internal/domain/context"
+	"github.com/Strob0t/CodeForge/internal/domain/convention"
+	"github.com/Strob0t/CodeForge/internal/domain/microagent"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+// commitAll commits every change of a workspace with a message.
+func commitAll(t *testing.T, dir, msg string) {
+	t.Helper()
+	for _, args := range [][]string{{"add", "."}, {"commit", "-q", "-m", msg}} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, out)
+		}
+	}
+}
+
+func TestConventionService(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available in test environment")
+	}
+	dir := writeWorkspace(t, map[string]string{
+		".editorconfig":         "root = true\n\n[*]\nend_of_line = lf\n",
+		".golangci.yml":         "linters:\n  enable: [errcheck]\n",
+		"main.go":               "package main\n\nfunc main() {\n\trun()\n}\n",
+		"run_state.go":          "package main\n\nfunc run() {\n\tif true {\n\t\treturn\n\t}\n}\n",
+		"run_state_test.go":     "package main\n",
+		"node_modules/x/ab.js":  "module.exports = 1\n",
+		"internal/event_log.go": "package internal\n",
+	})
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.email", "test@test.com"},
+		{"config", "user.name", "Test"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, out)
+		}
+	}
+	commitAll(t, dir, "feat(core): add run state")
+
+	store := &runtimeMockStore{
+		projects: []project.Project{{ID: "proj-1", WorkspacePath: dir}, {ID: "proj-2"}},
+		tasks:    []task.Task{{ID: "task-1", ProjectID: "proj-1", Prompt: "Fix the run state"}},
+	}
+	svc := service.NewConventionService(store, config.Conventions{
+		RefreshInterval: time.Hour, MergeFiles: 3, SampleFiles: 10, Commits: 10,
+	})
+	ctx := context.Background()
+
+	// Projects without conventions get them; uncloned ones are skipped.
+	if n, err := svc.RefreshDue(ctx); err != nil || n != 1 {
+		t.Fatalf("first refresh: got %d, %v; want 1", n, err)
+	}
+	c, err := svc.Get(ctx, "proj-1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if c.Trigger != convention.TriggerSchedule || c.Commit == "" {
+		t.Errorf("unexpected trigger %q, commit %q", c.Trigger, c.Commit)
+	}
+	if len(c.Languages) != 1 {
+		t.Fatalf("expected Go only, node_modules skipped; got %+v", c.Languages)
+	}
+	if l := c.Languages[0]; l.Name != "Go" || l.Files != 4 || l.IndentStyle != "tab" || l.FileNaming != "snake_case" || l.TestPattern != "*_test.go" {
+		t.Errorf("unexpected Go conventions %+v", l)
+	}
+	if len(c.Linters) != 1 || c.Linters[0].Tool != "golangci-lint" {
+		t.Errorf("unexpected linters %+v", c.Linters)
+	}
+	if len(c.EditorConfig) != 1 || c.EditorConfig[0].Settings["end_of_line"] != "lf" {
+		t.Errorf("unexpected editorconfig %+v", c.EditorConfig)
+	}
+	if c.Commits == nil || c.Commits.Sampled != 1 || c.Commits.Conventional != 1 {
+		t.Errorf("unexpected commit style %+v", c.Commits)
+	}
+
+	// Small changes keep the conventions; a big merge refreshes them.
+	if n, _ := svc.RefreshDue(ctx); n != 0 {
+		t.Fatalf("expected current conventions kept, extracted %d", n)
+	}
+	writeFile(t, dir, "main.go", "package main\n\nfunc main() {}\n")
+	commitAll(t, dir, "fix: simplify main")
+	if n, _ := svc.RefreshDue(ctx); n != 0 {
+		t.Fatalf("expected one changed file below merge_files, extracted %d", n)
+	}
+	for _, f := range []string{"a.go", "b.go", "c.go"} {
+		writeFile(t, dir, f, "package main\n")
+	}
+	commitAll(t, dir, "Merge feature branch")
+	if n, _ := svc.RefreshDue(ctx); n != 1 {
+		t.Fatalf("expected a big merge to refresh, extracted %d", n)
+	}
+	if c, _ = svc.Get(ctx, "proj-1"); c.Trigger != convention.TriggerMerge || c.Languages[0].Files != 7 || c.Commits.Sampled != 3 {
+		t.Errorf("unexpected conventions after merge: trigger %q, %+v, %+v", c.Trigger, c.Languages, c.Commits)
+	}
+
+	// Context packs and conversation microagents carry the document.
+	opt := service.NewContextOptimizerService(store, &config.Orchestrator{DefaultContextBudget: 8192, PromptReserve: 1024})
+	opt.SetConventionService(svc)
+	pack, err := opt.BuildContextPack(ctx, "task-1", "proj-1", "")
+	if err != nil {
+		t.Fatalf("build context pack: %v", err)
+	}
+	if e := pack.Entries[0]; e.Kind != cfcontext.EntryConventions || !strings.Contains(e.Content, "snake_case file names") {
+		t.Errorf("expected the conventions first in the pack, got %+v", e)
+	}
+	micro := service.NewMicroagentService(store)
+	micro.SetConventionService(svc)
+	agents, acts := micro.Activate(ctx, "proj-1", &microagent.Input{Prompt: "hello"})
+	if len(agents) != 1 || agents[0].Name != "project-conventions" || len(acts) != 0 {
+		t.Errorf("expected only the conventions without activation, got %+v, %+v", agents, acts)
+	}
+}
@@ -0,0 +1,506 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
+	"github.com/Strob0t/CodeForge/internal/adapter/ws"
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	cfcontext "github.com/Strob0t/CodeForge/internal/domain/context"
+	"github.com/Strob0t/CodeForge/internal/domain/conversation"
+	"github.com/Strob0t/CodeForge/internal/domain/featureflag"
+	"github.com/Strob0t/CodeForge/internal/domain/microagent"
+	"github.com/Strob0t/CodeForge/internal/domain/routing"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/broadcast"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+)
+
+// attachmentTextMax is the max bytes of a text attachment sent inline with
+// a conversation message.
+const attachmentTextMax = 32 << 10
+
+const summaryPrompt = "Summarize the conversation below so the summary can replace it in a chat with a coding assistant. " +
+	"Keep decisions, requirements, file names, identifiers and open questions; drop pleasantries. " +
+	"Write at most a few short paragraphs."
+
+// ConversationService runs project conversations with an LLM. Every
+// message is persisted; when the messages sent to the LLM exceed the
+// configured budget, older turns are compressed into a summary that is
+// stored next to them and replaces them in later prompts.
+type ConversationService struct {
+	store     database.Store
+	llm       *litellm.Client
+	cfg       *config.Conversation
+	routing   *RoutingService
+	tokenizer *TokenizerService
+	contexts  *ContextOptimizerService
+	micro     *MicroagentService
+	flags     *FeatureFlagService
+	attach    *AttachmentService
+	hub       broadcast.Broadcaster
+}
+
+// NewConversationService creates a ConversationService.
+func NewConversationService(store database.Store, llm *litellm.Client, cfg *config.Conversation) *ConversationService {
+	return &ConversationService{store: store, llm: llm, cfg: cfg}
+}
+
+// SetRoutingService writes summaries with the model of the summarize
+// route instead of the conversation's model.
+func (s *ConversationService) SetRoutingService(r *RoutingService) {
+	s.routing = r
+}
+
+// SetTokenizer counts message tokens with the tokenizer of the
+// conversation's model instead of the heuristic.
+func (s *ConversationService) SetTokenizer(t *TokenizerService) {
+	s.tokenizer = t
+}
+
+// SetContextOptimizer recalls project memories matching each user message
+// into the prompt of the reply.
+func (s *ConversationService) SetContextOptimizer(co *ContextOptimizerService) {
+	s.contexts = co
+}
+
+// SetMicroagentService adds the knowledge of microagents whose keywords
+// appear in a user message to the prompt of the reply.
+func (s *ConversationService) SetMicroagentService(m *MicroagentService) {
+	s.micro = m
+}
+
+// SetFeatureFlagService switches memory recall and microagents
+// (agentic_conversations) per tenant and project.
+func (s *ConversationService) SetFeatureFlagService(f *FeatureFlagService) {
+	s.flags = f
+}
+
+// SetAttachmentService lets messages carry attachments of the
+// conversation's project.
+func (s *ConversationService) SetAttachmentService(a *AttachmentService) {
+	s.attach = a
+}
+
+// SetBroadcaster relays streamed reply deltas and stored messages to
+// WebSocket clients.
+func (s *ConversationService) SetBroadcaster(hub broadcast.Broadcaster) {
+	s.hub = hub
+}
+
+// Create starts a conversation about a project.
+func (s *ConversationService) Create(ctx context.Context, projectID string, req conversation.CreateRequest) (*conversation.Conversation, error) {
+	if _, err := s.store.GetProject(ctx, projectID); err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+	c := &conversation.Conversation{ProjectID: projectID, Title: req.Title, Model: req.Model, Mode: req.Mode}
+	if err := s.store.CreateConversation(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Get returns a conversation.
+func (s *ConversationService) Get(ctx context.Context, id string) (*conversation.Conversation, error) {
+	return s.store.GetConversation(ctx, id)
+}
+
+// List returns the conversations of a project.
+func (s *ConversationService) List(ctx context.Context, projectID string) ([]conversation.Conversation, error) {
+	return s.store.ListConversations(ctx, projectID)
+}
+
+// Messages returns all messages of a conversation, summaries included.
+// With compressed set, only the view sent to the LLM is returned.
+func (s *ConversationService) Messages(ctx context.Context, id string, compressed bool) ([]conversation.Message, error) {
+	if _, err := s.store.GetConversation(ctx, id); err != nil {
+		return nil, err
+	}
+	msgs, err := s.store.ListConversationMessages(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if compressed {
+		return conversation.View(msgs), nil
+	}
+	return msgs, nil
+}
+
+// Send appends a user message, compresses older turns if the view exceeds
+// the budget, and returns the model's reply, which is appended as well.
+// Memories recalled for the message and the knowledge of microagents it
+// activates are sent ahead of the view and recorded on the reply, as is
+// the token usage the LLM reports.
+func (s *ConversationService) Send(ctx context.Context, id string, req conversation.SendRequest) (*conversation.Message, error) {
+	return s.send(ctx, id, req, false, nil)
+}
+
+// Stream is Send with the reply streamed from the LLM. Each piece of the
+// reply is passed to onDelta, if set, and broadcast as it arrives; the
+// reply is stored once the stream ends. A failed stream stores no reply.
+func (s *ConversationService) Stream(ctx context.Context, id string, req conversation.SendRequest, onDelta func(string)) (*conversation.Message, error) {
+	return s.send(ctx, id, req, true, onDelta)
+}
+
+func (s *ConversationService) send(ctx context.Context, id string, req conversation.SendRequest, stream bool, onDelta func(string)) (*conversation.Message, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if s.llm == nil {
+		return nil, errors.New("no LLM client configured")
+	}
+	c, err := s.store.GetConversation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	model := s.model(c)
+
+	var attachments []artifact.Artifact
+	if len(req.Attachments) > 0 {
+		if s.attach == nil {
+			return nil, fmt.Errorf("%w: attachments are not enabled", ErrUnknownAttachment)
+		}
+		if attachments, err = s.attach.Resolve(ctx, c.ProjectID, req.Attachments); err != nil {
+			return nil, err
+		}
+	}
+
+	user := &conversation.Message{
+		ConversationID: id,
+		Role:           conversation.RoleUser,
+		Content:        req.Content,
+		Tokens:         s.count(model, req.Content),
+		Attachments:    req.Attachments,
+	}
+	if err := s.store.AppendConversationMessage(ctx, user); err != nil {
+		return nil, err
+	}
+
+	view, err := s.compressedView(ctx, c, model)
+	if err != nil {
+		return nil, err
+	}
+	messages := chatMessages(view)
+	if len(attachments) > 0 && len(messages) > 0 {
+		attachFiles(&messages[len(messages)-1], attachments, s.supportsVision(ctx, model))
+	}
+	agentic := s.flags.Enabled(ctx, featureflag.AgenticConversations, c.ProjectID)
+	var memories []cfcontext.ContextEntry
+	if s.contexts != nil && agentic {
+		memories = s.contexts.RecallMemories(ctx, c.ProjectID, req.Content, c.Mode, model)
+	}
+	if len(memories) > 0 {
+		messages = append([]litellm.ChatMessage{memoryMessage(memories)}, messages...)
+	}
+	var acts []microagent.Activation
+	if s.micro != nil && agentic {
+		var agents []microagent.Microagent
+		agents, acts = s.micro.Activate(ctx, c.ProjectID, &microagent.Input{Prompt: req.Content})
+		if len(agents) > 0 {
+			messages = append([]litellm.ChatMessage{{Role: "system", Content: microagentSection(agents)}}, messages...)
+		}
+	}
+	creq := litellm.ChatCompletionRequest{
+		Model:     model,
+		Messages:  messages,
+		MaxTokens: s.cfg.MaxTokens,
+	}
+	var resp *litellm.ChatCompletionResponse
+	if stream {
+		resp, err = s.llm.ChatCompletionStream(ctx, creq, func(delta string) {
+			if s.hub != nil {
+				s.hub.BroadcastEvent(ctx, ws.EventConversationDelta, ws.ConversationDeltaEvent{
+					ConversationID: id,
+					ProjectID:      c.ProjectID,
+					Delta:          delta,
+				})
+			}
+			if onDelta != nil {
+				onDelta(delta)
+			}
+		})
+	} else {
+		resp, err = s.llm.ChatCompletion(ctx, creq)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("conversation reply: %w", err)
+	}
+
+	reply := &conversation.Message{
+		ConversationID: id,
+		Role:           conversation.RoleAssistant,
+		Content:        resp.Content,
+		Tokens:         s.count(model, resp.Content),
+		Model:          model,
+		Memories:       memoryIDs(memories),
+		Microagents:    activationIDs(acts),
+	}
+	if !resp.Cached {
+		reply.PromptTokens = resp.TokensIn
+		reply.CompletionTokens = resp.TokensOut
+	}
+	if err := s.store.AppendConversationMessage(ctx, reply); err != nil {
+		return nil, err
+	}
+	s.broadcastMessage(ctx, c, reply)
+	return reply, nil
+}
+
+// AppendToolCall records a tool call of a run started in a conversation
+// as a tool message with the call's output. Output beyond
+// tool_output_max bytes is cut from the message and stored in full as a
+// tool_output artifact of the run, which the message links to.
+func (s *ConversationService) AppendToolCall(ctx context.Context, r *run.Run, call conversation.ToolCall, output string) (*conversation.Message, error) {
+	c, err := s.store.GetConversation(ctx, r.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+	content, truncated := conversation.TruncateOutput(output, s.cfg.ToolOutputMax)
+	if truncated {
+		art := &artifact.Artifact{
+			RunID:       r.ID,
+			ProjectID:   r.ProjectID,
+			Kind:        artifact.KindToolOutput,
+			Name:        "tool-output-" + call.CallID + ".txt",
+			ContentType: "text/plain",
+			Data:        []byte(output),
+			Metadata:    map[string]string{"call_id": call.CallID, "tool": call.Name},
+		}
+		if err := s.store.CreateArtifact(ctx, art); err != nil {
+			return nil, fmt.Errorf("store tool output: %w", err)
+		}
+		call.Truncated = true
+		call.ArtifactID = art.ID
+	}
+	m := &conversation.Message{
+		ConversationID: c.ID,
+		Role:           conversation.RoleTool,
+		Content:        content,
+		Tokens:         s.count(s.model(c), content),
+		ToolCall:       &call,
+	}
+	if err := s.store.AppendConversationMessage(ctx, m); err != nil {
+		return nil, err
+	}
+	s.broadcastMessage(ctx, c, m)
+	return m, nil
+}
+
+// AppendRunAnswer records the final output of a run started in a
+// conversation as an assistant message, or its error if it has no output.
+// A run with neither records nothing.
+func (s *ConversationService) AppendRunAnswer(ctx context.Context, r *run.Run, output, errMsg string) (*conversation.Message, error) {
+	c, err := s.store.GetConversation(ctx, r.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+	content := strings.TrimSpace(output)
+	if content == "" && errMsg != "" {
+		content = "Run failed: " + errMsg
+	}
+	if content == "" {
+		return nil, nil
+	}
+	m := &conversation.Message{
+		ConversationID: c.ID,
+		Role:           conversation.RoleAssistant,
+		Content:        content,
+		Tokens:         s.count(s.model(c), content),
+	}
+	if err := s.store.AppendConversationMessage(ctx, m); err != nil {
+		return nil, err
+	}
+	s.broadcastMessage(ctx, c, m)
+	return m, nil
+}
+
+// broadcastMessage announces a stored message to WebSocket clients.
+func (s *ConversationService) broadcastMessage(ctx context.Context, c *conversation.Conversation, m *conversation.Message) {
+	if s.hub == nil {
+		return
+	}
+	tool := ""
+	if m.ToolCall != nil {
+		tool = m.ToolCall.Name
+	}
+	s.hub.BroadcastEvent(ctx, ws.EventConversationMessage, ws.ConversationMessageEvent{
+		ConversationID:   c.ID,
+		ProjectID:        c.ProjectID,
+		MessageID:        m.ID,
+		Seq:              m.Seq,
+		Role:             string(m.Role),
+		Content:          m.Content,
+		Tool:             tool,
+		Model:            m.Model,
+		PromptTokens:     m.PromptTokens,
+		CompletionTokens: m.CompletionTokens,
+	})
+}
+
+// compressedView returns the view of a conversation, first compressing its
+// older turns into a new summary if the view exceeds the budget. A failed
+// summary is logged and the uncompressed view is used.
+func (s *ConversationService) compressedView(ctx context.Context, c *conversation.Conversation, model string) ([]conversation.Message, error) {
+	msgs, err := s.store.ListConversationMessages(ctx, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	view := conversation.View(msgs)
+	older := conversation.Compaction(view, s.cfg.SummarizeAt, s.cfg.KeepRecent)
+	if older == nil {
+		return view, nil
+	}
+
+	summary, err := s.summarize(ctx, c, model, older)
+	if err != nil {
+		slog.Warn("conversation summary failed, sending full view", "conversation_id", c.ID, "error", err)
+		return view, nil
+	}
+	if err := s.store.AppendConversationMessage(ctx, summary); err != nil {
+		return nil, err
+	}
+	slog.Info("conversation compressed",
+		"conversation_id", c.ID,
+		"covers", summary.Covers,
+		"tokens_before", conversation.Tokens(older),
+		"tokens_after", summary.Tokens,
+	)
+	return append([]conversation.Message{*summary}, view[len(older):]...), nil
+}
+
+// summarize compresses messages, which may start with an earlier summary,
+// into a summary message. It uses the summarize route when one applies and
+// the conversation's model otherwise.
+func (s *ConversationService) summarize(ctx context.Context, c *conversation.Conversation, model string, older []conversation.Message) (*conversation.Message, error) {
+	var b strings.Builder
+	for i := range older {
+		if older[i].Role == conversation.RoleSummary {
+			b.WriteString("Summary of the earlier conversation:\n")
+		} else {
+			b.WriteString(string(older[i].Role) + ":\n")
+		}
+		b.WriteString(older[i].Content)
+		b.WriteString("\n\n")
+	}
+
+	var resp *litellm.ChatCompletionResponse
+	used := model
+	call := func(m string) error {
+		var err error
+		resp, err = s.llm.ChatCompletion(ctx, litellm.ChatCompletionRequest{
+			Model: m,
+			Messages: []litellm.ChatMessage{
+				{Role: "system", Content: summaryPrompt},
+				{Role: "user", Content: b.String()},
+			},
+			Temperature: 0.2,
+			MaxTokens:   s.cfg.SummaryMaxTokens,
+		})
+		used = m
+		return err
+	}
+	var err error
+	if s.routing != nil {
+		err = s.routing.Call(ctx, routing.Request{TaskType: routing.TaskSummarize, ProjectID: c.ProjectID}, call)
+		if errors.Is(err, routing.ErrNoModel) {
+			err = call(model)
+		}
+	} else {
+		err = call(model)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	content := strings.TrimSpace(resp.Content)
+	if content == "" {
+		return nil, errors.New("empty summary")
+	}
+	return &conversation.Message{
+		ConversationID: c.ID,
+		Role:           conversation.RoleSummary,
+		Content:        content,
+		Tokens:         s.count(model, content),
+		Covers:         older[len(older)-1].Seq,
+		Model:          used,
+	}, nil
+}
+
+func (s *ConversationService) model(c *conversation.Conversation) string {
+	if c.Model != "" {
+		return c.Model
+	}
+	return s.cfg.Model
+}
+
+func (s *ConversationService) count(model, text string) int {
+	if s.tokenizer == nil {
+		return cfcontext.EstimateTokens(text)
+	}
+	return s.tokenizer.Count(model, text)
+}
+
+// chatMessages converts a view to chat messages. A summary is passed as a
+// system message.
+func chatMessages(view []conversation.Message) []litellm.ChatMessage {
+	out := make([]litellm.ChatMessage, len(view))
+	for i := range view {
+		if view[i].Role == conversation.RoleSummary {
+			out[i] = litellm.ChatMessage{Role: "system", Content: "Summary of the earlier conversation:\n" + view[i].Content}
+			continue
+		}
+		out[i] = litellm.ChatMessage{Role: string(view[i].Role), Content: view[i].Content}
+	}
+	return out
+}
+
+// supportsVision reports whether LiteLLM marks model as accepting images.
+// If the model info cannot be read, images are described instead.
+func (s *ConversationService) supportsVision(ctx context.Context, model string) bool {
+	ok, err := s.llm.SupportsVision(ctx, model)
+	if err != nil {
+		slog.Warn("model vision support unknown", "model", model, "error", err)
+	}
+	return ok
+}
+
+// attachFiles adds the attachments of the message being sent to it: images
+// as image parts for vision models, text files inline (up to
+// attachmentTextMax bytes each), and other files by name only.
+func attachFiles(m *litellm.ChatMessage, attachments []artifact.Artifact, vision bool) {
+	var b strings.Builder
+	b.WriteString(m.Content)
+	for i := range attachments {
+		a := &attachments[i]
+		switch {
+		case a.IsImage() && vision:
+			m.Images = append(m.Images, "data:"+a.ContentType+";base64,"+base64.StdEncoding.EncodeToString(a.Data))
+		case a.IsText():
+			text, cut := conversation.TruncateOutput(string(a.Data), attachmentTextMax)
+			fmt.Fprintf(&b, "\n\nAttached file %s:\n```\n%s\n```", a.Name, text)
+			if cut {
+				fmt.Fprintf(&b, "\n(truncated to %d of %d bytes)", len(text), a.Size)
+			}
+		default:
+			fmt.Fprintf(&b, "\n\n[Attached file %s (%s, %d bytes) cannot be shown to this model]", a.Name, a.ContentType, a.Size)
+		}
+	}
+	m.Content = b.String()
+}
+
+// memoryMessage lists recalled memories in a system message.
+func memoryMessage(entries []cfcontext.ContextEntry) litellm.ChatMessage {
+	var b strings.Builder
+	b.WriteString("Relevant project memories:")
+	for i := range entries {
+		b.WriteString("\n- ")
+		b.WriteString(entries[i].Content)
+	}
+	return litellm.ChatMessage{Role: "system", Content: b.String()}
+}
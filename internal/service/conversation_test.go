@@ -0,0 +1,234 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
+	"github.com/Strob0t/CodeForge/internal/adapter/ws"
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/domain/conversation"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+// chatRecorder is a fake LiteLLM endpoint that answers summary requests
+// with "SUMMARY" and everything else with "ok", recording each request.
+type chatRecorder struct {
+	mu       sync.Mutex
+	requests []litellm.ChatCompletionRequest
+}
+
+func (c *chatRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req litellm.ChatCompletionRequest
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	c.mu.Lock()
+	c.requests = append(c.requests, req)
+	c.mu.Unlock()
+
+	content := "ok"
+	if strings.HasPrefix(req.Messages[0].Content, "Summarize the conversation") {
+		content = "SUMMARY"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"choices": []map[string]any{{"message": map[string]string{"content": content}}},
+		"usage":   map[string]int{"prompt_tokens": 10, "completion_tokens": 1},
+	})
+}
+
+func TestConversationService_SendCompressesOlderTurns(t *testing.T) {
+	_, store, _, _ := newRuntimeTestEnv()
+	llm := &chatRecorder{}
+	srv := httptest.NewServer(llm)
+	defer srv.Close()
+
+	svc := service.NewConversationService(store, litellm.NewClient(srv.URL, ""), &config.Conversation{
+		Model: "chat-model", MaxTokens: 100, SummarizeAt: 30, KeepRecent: 2, SummaryMaxTokens: 50,
+	})
+	svc.SetRoutingService(newTestRoutingService(t, store))
+	ctx := context.Background()
+
+	c, err := svc.Create(ctx, "proj-1", conversation.CreateRequest{Title: "auth"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	// Each user message counts 10 tokens, each reply 1: the third turn
+	// exceeds 30 and compresses everything but the last two messages.
+	for range 3 {
+		if _, err := svc.Send(ctx, c.ID, conversation.SendRequest{Content: strings.Repeat("x", 40)}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+
+	if len(llm.requests) != 4 {
+		t.Fatalf("expected 3 replies and 1 summary, got %d requests", len(llm.requests))
+	}
+	if sum := llm.requests[2]; sum.Model != "summary-default" || !strings.Contains(sum.Messages[1].Content, "user:") {
+		t.Fatalf("expected summary request on the summarize route, got %+v", sum)
+	}
+	last := llm.requests[3]
+	if last.Model != "chat-model" || len(last.Messages) != 3 || last.Messages[0].Role != "system" ||
+		!strings.HasSuffix(last.Messages[0].Content, "SUMMARY") {
+		t.Fatalf("expected reply on the compressed view, got %+v", last.Messages)
+	}
+
+	all, err := svc.Messages(ctx, c.ID, false)
+	if err != nil {
+		t.Fatalf("Messages failed: %v", err)
+	}
+	if len(all) != 7 {
+		t.Fatalf("expected originals and summary to be kept, got %d messages", len(all))
+	}
+	view, err := svc.Messages(ctx, c.ID, true)
+	if err != nil {
+		t.Fatalf("Messages failed: %v", err)
+	}
+	if len(view) != 4 || view[0].Role != conversation.RoleSummary || view[0].Covers != 3 || view[0].Model != "summary-default" {
+		t.Fatalf("unexpected compressed view %+v", view)
+	}
+}
+
+func TestConversationService_SendRejectsEmptyContent(t *testing.T) {
+	_, store, _, _ := newRuntimeTestEnv()
+	svc := service.NewConversationService(store, litellm.NewClient("http://unused", ""), &config.Conversation{Model: "m"})
+	if _, err := svc.Send(context.Background(), "conv-1", conversation.SendRequest{}); err != conversation.ErrContentRequired {
+		t.Fatalf("expected ErrContentRequired, got %v", err)
+	}
+}
+
+func TestConversationService_StreamRelaysDeltas(t *testing.T) {
+	_, store, _, bc := newRuntimeTestEnv()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"model":"chat-model","choices":[{"delta":{"content":"Hel"}}]}`,
+			`{"model":"chat-model","choices":[{"delta":{"content":"lo"}}]}`,
+			`{"model":"chat-model","choices":[],"usage":{"prompt_tokens":12,"completion_tokens":2}}`,
+			`[DONE]`,
+		} {
+			_, _ = w.Write([]byte("data: " + chunk + "\n\n"))
+		}
+	}))
+	defer srv.Close()
+
+	svc := service.NewConversationService(store, litellm.NewClient(srv.URL, ""), &config.Conversation{Model: "chat-model", MaxTokens: 100})
+	svc.SetBroadcaster(bc)
+	ctx := context.Background()
+	c, err := svc.Create(ctx, "proj-1", conversation.CreateRequest{Title: "stream"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	var deltas []string
+	reply, err := svc.Stream(ctx, c.ID, conversation.SendRequest{Content: "hi"}, func(d string) { deltas = append(deltas, d) })
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if strings.Join(deltas, "|") != "Hel|lo" {
+		t.Fatalf("unexpected deltas %q", deltas)
+	}
+	if reply.Content != "Hello" || reply.PromptTokens != 12 || reply.CompletionTokens != 2 {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+	msgs, err := svc.Messages(ctx, c.ID, false)
+	if err != nil {
+		t.Fatalf("Messages failed: %v", err)
+	}
+	if len(msgs) != 2 || msgs[1].Content != "Hello" || msgs[1].CompletionTokens != 2 {
+		t.Fatalf("expected stored reply with usage, got %+v", msgs)
+	}
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	var types []string
+	for _, ev := range bc.events {
+		types = append(types, ev.EventType)
+	}
+	want := []string{ws.EventConversationDelta, ws.EventConversationDelta, ws.EventConversationMessage}
+	if strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Fatalf("broadcast %v, want %v", types, want)
+	}
+	if ev := bc.events[0].Data.(ws.ConversationDeltaEvent); ev.ConversationID != c.ID || ev.ProjectID != "proj-1" || ev.Delta != "Hel" {
+		t.Fatalf("unexpected delta event %+v", ev)
+	}
+}
+
+func TestRuntimeService_ConversationRunTranscript(t *testing.T) {
+	svc, store, _, _ := newRuntimeTestEnv()
+	ctx := context.Background()
+	convs := service.NewConversationService(store, nil, &config.Conversation{Model: "m", ToolOutputMax: 8})
+	svc.SetConversationService(convs)
+
+	c, err := convs.Create(ctx, "proj-1", conversation.CreateRequest{Title: "agent"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := svc.StartRun(ctx, &run.StartRequest{TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", ConversationID: "missing"}); err == nil {
+		t.Fatal("expected an unknown conversation to be rejected")
+	}
+	r, err := svc.StartRun(ctx, &run.StartRequest{
+		TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", PolicyProfile: "plan-readonly", ConversationID: c.ID,
+	})
+	if err != nil {
+		t.Fatalf("StartRun failed: %v", err)
+	}
+
+	// An allowed call is recorded with its result, a denied one right away.
+	calls := []messagequeue.ToolCallRequestPayload{
+		{RunID: r.ID, CallID: "call-1", Tool: "Read", Path: "main.go"},
+		{RunID: r.ID, CallID: "call-2", Tool: "Edit", Path: "main.go"},
+	}
+	for i := range calls {
+		if err := svc.HandleToolCallRequest(ctx, &calls[i]); err != nil {
+			t.Fatalf("HandleToolCallRequest failed: %v", err)
+		}
+	}
+	if err := svc.HandleToolCallResult(ctx, &messagequeue.ToolCallResultPayload{
+		RunID: r.ID, CallID: "call-1", Tool: "Read", Success: true, Output: "package main\n",
+	}); err != nil {
+		t.Fatalf("HandleToolCallResult failed: %v", err)
+	}
+	if err := svc.HandleRunComplete(ctx, &messagequeue.RunCompletePayload{
+		RunID: r.ID, TaskID: "task-1", ProjectID: "proj-1", Status: "completed", Output: "main.go is the entry point.",
+	}); err != nil {
+		t.Fatalf("HandleRunComplete failed: %v", err)
+	}
+
+	msgs, err := convs.Messages(ctx, c.ID, false)
+	if err != nil {
+		t.Fatalf("Messages failed: %v", err)
+	}
+	if len(msgs) != 3 {
+		t.Fatalf("expected denied call, call result and answer, got %+v", msgs)
+	}
+	denied, read, answer := msgs[0], msgs[1], msgs[2]
+	if denied.Role != conversation.RoleTool || denied.ToolCall.Name != "Edit" || denied.ToolCall.Success ||
+		!strings.HasPrefix(denied.ToolCall.Error, "denied by policy") {
+		t.Fatalf("unexpected denied call %+v %+v", denied, denied.ToolCall)
+	}
+	tc := read.ToolCall
+	if read.Role != conversation.RoleTool || tc.Name != "Read" || !tc.Success || tc.Args["path"] != "main.go" || tc.RunID != r.ID {
+		t.Fatalf("unexpected tool call %+v", tc)
+	}
+	if read.Content != "package " || !tc.Truncated || tc.ArtifactID == "" {
+		t.Fatalf("expected output truncated to an artifact, got %q %+v", read.Content, tc)
+	}
+	art, err := store.GetArtifact(ctx, tc.ArtifactID)
+	if err != nil || string(art.Data) != "package main\n" || art.Kind != artifact.KindToolOutput {
+		t.Fatalf("expected full output artifact, got %+v %v", art, err)
+	}
+	if answer.Role != conversation.RoleAssistant || answer.Content != "main.go is the entry point." {
+		t.Fatalf("unexpected answer %+v", answer)
+	}
+	if view, _ := convs.Messages(ctx, c.ID, true); len(view) != 1 {
+		t.Fatalf("expected tool calls left out of the LLM view, got %+v", view)
+	}
+}
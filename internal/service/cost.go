@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/cost"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+)
+
+// Daily cost series lengths.
+const (
+	DefaultCostDays = 30
+	MaxCostDays     = 366
+)
+
+// CostService summarizes the LLM spend of runs per project, day, plan step
+// and tool. Project, daily and tool totals are read from daily rollups the
+// service keeps current.
+type CostService struct {
+	store database.Store
+	cfg   config.Costs
+}
+
+// NewCostService creates a CostService.
+func NewCostService(store database.Store, cfg config.Costs) *CostService {
+	return &CostService{store: store, cfg: cfg}
+}
+
+// StartRollups recomputes all cost rollups and then, every rollup
+// interval, the days within the rollup window, until the context is
+// cancelled or the returned cancel function is called.
+func (s *CostService) StartRollups(ctx context.Context) (cancel func()) {
+	ctx, cancel = context.WithCancel(ctx)
+	go func() {
+		ticker := time.NewTicker(s.cfg.RollupInterval)
+		defer ticker.Stop()
+		full := true
+		for {
+			if err := s.RefreshRollups(ctx, full); err != nil {
+				slog.Error("cost rollup failed", "full", full, "error", err)
+			} else {
+				full = false
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return cancel
+}
+
+// RefreshRollups recomputes the cost rollups of the days within the rollup
+// window, or of every day if full is set.
+func (s *CostService) RefreshRollups(ctx context.Context, full bool) error {
+	var from time.Time
+	if !full {
+		from = time.Now().Add(-s.cfg.RollupWindow)
+	}
+	start := time.Now()
+	if err := s.store.RefreshCostRollups(ctx, from); err != nil {
+		return err
+	}
+	slog.Debug("cost rollups refreshed", "full", full, "duration", time.Since(start))
+	return nil
+}
+
+// Daily returns the daily totals of the last days, oldest first, for a
+// project or, with an empty projectID, all projects. Days without runs are
+// omitted.
+func (s *CostService) Daily(ctx context.Context, projectID string, days int) ([]cost.Day, error) {
+	if days < 1 || days > MaxCostDays {
+		return nil, fmt.Errorf("days must be between 1 and %d", MaxCostDays)
+	}
+	if projectID != "" {
+		if _, err := s.store.GetProject(ctx, projectID); err != nil {
+			return nil, err
+		}
+	}
+	since := time.Now().UTC().AddDate(0, 0, 1-days)
+	return s.store.ListDailyCosts(ctx, projectID, since)
+}
+
+// Summaries returns the cost summary of every project, most expensive
+// first.
+func (s *CostService) Summaries(ctx context.Context) ([]cost.Summary, error) {
+	return s.store.ListCostSummaries(ctx, "")
+}
+
+// Project returns the cost summary of a project.
+func (s *CostService) Project(ctx context.Context, projectID string) (*cost.Summary, error) {
+	sums, err := s.store.ListCostSummaries(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(sums) == 0 {
+		return nil, fmt.Errorf("project %s: %w", projectID, domain.ErrNotFound)
+	}
+	return &sums[0], nil
+}
+
+// ByStep returns the cost of each step of a plan, most expensive first,
+// with its usage per tool and model.
+func (s *CostService) ByStep(ctx context.Context, planID string) ([]cost.StepCost, error) {
+	if _, err := s.store.GetPlan(ctx, planID); err != nil {
+		return nil, err
+	}
+	return s.store.ListStepCosts(ctx, planID)
+}
+
+// ByTool returns the usage of a project's runs per tool and model, most
+// expensive first.
+func (s *CostService) ByTool(ctx context.Context, projectID string) ([]cost.Usage, error) {
+	if _, err := s.store.GetProject(ctx, projectID); err != nil {
+		return nil, err
+	}
+	return s.store.ListToolCosts(ctx, projectID)
+}
@@ -0,0 +1,83 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestCostService_RefreshRollups(t *testing.T) {
+	store := &runtimeMockStore{}
+	svc := service.NewCostService(store, config.Costs{RollupInterval: time.Hour, RollupWindow: 48 * time.Hour})
+	ctx := context.Background()
+
+	// The first pass after a leader takes over recomputes every day, later
+	// passes only the window.
+	cancel := svc.StartRollups(ctx)
+	defer cancel()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		store.mu.Lock()
+		n := len(store.rollupsFrom)
+		store.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected an initial rollup pass")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := svc.RefreshRollups(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if !store.rollupsFrom[0].IsZero() {
+		t.Fatalf("expected a full first pass, got one from %s", store.rollupsFrom[0])
+	}
+	if since := time.Since(store.rollupsFrom[1]); since < 48*time.Hour || since > 49*time.Hour {
+		t.Fatalf("expected a pass over the last 48h, got one from %s", store.rollupsFrom[1])
+	}
+}
+
+func TestCostService_Daily(t *testing.T) {
+	now := time.Now().UTC()
+	store := &runtimeMockStore{
+		projects: []project.Project{{ID: "proj-1"}, {ID: "proj-2"}},
+		runs: []run.Run{
+			{ID: "r1", ProjectID: "proj-1", CostUSD: 0.5, CreatedAt: now},
+			{ID: "r2", ProjectID: "proj-2", CostUSD: 0.25, CreatedAt: now},
+			{ID: "r3", ProjectID: "proj-1", CostUSD: 1, CreatedAt: now.AddDate(0, 0, -2)},
+			{ID: "r4", ProjectID: "proj-1", CostUSD: 4, CreatedAt: now.AddDate(0, 0, -3)},
+		},
+	}
+	svc := service.NewCostService(store, config.Defaults().Costs)
+	ctx := context.Background()
+
+	days, err := svc.Daily(ctx, "proj-1", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 2 || days[0].CostUSD != 1 || days[1].Day != now.Format(time.DateOnly) || days[1].CostUSD != 0.5 {
+		t.Fatalf("expected the last 3 days of proj-1 oldest first, got %+v", days)
+	}
+	if days, _ = svc.Daily(ctx, "", 1); len(days) != 1 || days[0].Runs != 2 || days[0].CostUSD != 0.75 {
+		t.Fatalf("expected today's totals of all projects, got %+v", days)
+	}
+
+	if _, err := svc.Daily(ctx, "missing", 3); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected not found for an unknown project, got %v", err)
+	}
+	if _, err := svc.Daily(ctx, "", service.MaxCostDays+1); err == nil {
+		t.Fatal("expected an error for too many days")
+	}
+}
@@ -3,16 +3,25 @@ package service
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 
+	"github.com/Strob0t/CodeForge/internal/adapter/gitlocal"
 	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/domain/ci"
+	"github.com/Strob0t/CodeForge/internal/domain/gitpolicy"
+	"github.com/Strob0t/CodeForge/internal/domain/lsp"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
 	"github.com/Strob0t/CodeForge/internal/domain/run"
 	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
 )
 
 // DeliveryResult holds the outcome of a delivery operation.
@@ -22,12 +31,24 @@ type DeliveryResult struct {
 	CommitHash string          `json:"commit_hash,omitempty"`
 	BranchName string          `json:"branch_name,omitempty"`
 	PRURL      string          `json:"pr_url,omitempty"`
+	Draft      bool            `json:"draft,omitempty"`     // Pull request opened as a draft by the diagnostics gate
+	Artifacts  []string        `json:"artifacts,omitempty"` // IDs of the patch bundle artifacts of a mirror delivery
+	Warnings   []string        `json:"warnings,omitempty"`  // Ways the commit and branch fail the project's git policy, and skipped gates
+
+	// Diagnostics are the new errors of the changed files the diagnostics
+	// gate let through as a draft pull request.
+	Diagnostics []lsp.Diagnostic `json:"diagnostics,omitempty"`
 }
 
 // DeliverService executes delivery strategies after a successful run.
 type DeliverService struct {
-	store database.Store
-	cfg   *config.Runtime
+	store      database.Store
+	cfg        *config.Runtime
+	secrets    *SecretService
+	githubApps *GitHubAppService
+	ci         *CIService
+	lsp        *LSPService
+	publicURL  string
 }
 
 // NewDeliverService creates a new DeliverService.
@@ -35,6 +56,35 @@ func NewDeliverService(store database.Store, cfg *config.Runtime) *DeliverServic
 	return &DeliverService{store: store, cfg: cfg}
 }
 
+// SetSecretService sets the secret service used to resolve the git host API
+// token for commit status reports.
+func (s *DeliverService) SetSecretService(secrets *SecretService) {
+	s.secrets = secrets
+}
+
+// SetGitHubApps authenticates pushes and pull requests of GitHub projects
+// that name an installation of their tenant's GitHub App.
+func (s *DeliverService) SetGitHubApps(apps *GitHubAppService) {
+	s.githubApps = apps
+}
+
+// SetCIService enables gating deliveries on green CI of the pushed commit
+// for projects with ci_gate_delivery set.
+func (s *DeliverService) SetCIService(ci *CIService) {
+	s.ci = ci
+}
+
+// SetLSPService enables gating deliveries on the diagnostics of the
+// changed files for projects with lsp_diagnostics_gate set.
+func (s *DeliverService) SetLSPService(l *LSPService) {
+	s.lsp = l
+}
+
+// SetPublicURL sets the web UI base URL that commit statuses link to.
+func (s *DeliverService) SetPublicURL(u string) {
+	s.publicURL = strings.TrimSuffix(u, "/")
+}
+
 // Deliver executes the delivery strategy for the given run.
 func (s *DeliverService) Deliver(ctx context.Context, r *run.Run, taskTitle string) (*DeliveryResult, error) {
 	if r.DeliverMode == "" || r.DeliverMode == run.DeliverModeNone {
@@ -46,37 +96,164 @@ func (s *DeliverService) Deliver(ctx context.Context, r *run.Run, taskTitle stri
 	if err != nil {
 		return nil, fmt.Errorf("get project for delivery: %w", err)
 	}
-	dir := proj.WorkspacePath
+	if !run.AllowsDeliverMode(proj.Config, r.DeliverMode) {
+		return nil, fmt.Errorf("%w: %q", run.ErrDeliverModeNotAllowed, r.DeliverMode)
+	}
+	dir := r.Workspace(proj.WorkspacePath)
 	if dir == "" {
 		return nil, fmt.Errorf("project %s has no workspace_path", r.ProjectID)
 	}
 
-	shortID := r.ID
-	if len(shortID) > 8 {
-		shortID = shortID[:8]
+	n := s.names(proj.Config, r, taskTitle)
+	newErrors, err := s.diagnosticsGate(ctx, dir, proj, r, n)
+	if err != nil {
+		return nil, err
 	}
-
+	var result *DeliveryResult
 	switch r.DeliverMode {
 	case run.DeliverModePatch:
-		return s.deliverPatch(ctx, dir, r, shortID)
+		result, err = s.deliverPatch(ctx, dir, r, n)
 	case run.DeliverModeCommitLocal:
-		return s.deliverCommitLocal(ctx, dir, r, shortID, taskTitle)
+		result, err = s.deliverCommitLocal(ctx, dir, r, n)
 	case run.DeliverModeBranch:
-		return s.deliverBranch(ctx, dir, r, shortID, taskTitle)
+		result, err = s.deliverBranch(ctx, dir, r, n)
 	case run.DeliverModePR:
-		return s.deliverPR(ctx, dir, r, shortID, taskTitle)
+		result, err = s.deliverPR(ctx, dir, r, n, len(newErrors) > 0)
+	case run.DeliverModePush:
+		result, err = s.deliverPush(ctx, dir, r, n)
+	case run.DeliverModeMirror:
+		result, err = s.deliverMirror(ctx, dir, r, proj.Config[run.ConfigMirrorURL], n)
 	default:
 		return nil, fmt.Errorf("unsupported deliver mode %q", r.DeliverMode)
 	}
+	if err != nil {
+		return nil, err
+	}
+	if r.DeliverMode != run.DeliverModePatch {
+		result.Warnings = n.warnings
+	}
+	result.Diagnostics = newErrors
+	return result, nil
 }
 
-func (s *DeliverService) deliverPatch(ctx context.Context, dir string, r *run.Run, shortID string) (*DeliveryResult, error) {
+// diagnosticsGate checks the files a run changed with the language servers
+// before a delivery pushes them, if the project's lsp_diagnostics_gate has
+// a rule for the target branch: the base branch of branch, pull request
+// and mirror deliveries, or the run's branch for push deliveries. New
+// errors fail the delivery, or are returned for a draft pull request when
+// the rule says so. With lsp disabled for the project the gate is skipped
+// with a warning.
+func (s *DeliverService) diagnosticsGate(ctx context.Context, dir string, proj *project.Project, r *run.Run, n *deliveryNames) ([]lsp.Diagnostic, error) {
+	switch r.DeliverMode {
+	case run.DeliverModeBranch, run.DeliverModePR, run.DeliverModeMirror, run.DeliverModePush:
+	default:
+		return nil, nil
+	}
+	if s.lsp == nil || proj.Config[lsp.ConfigDiagnosticsGate] == "" {
+		return nil, nil
+	}
+	rules, err := lsp.ParseGateRules(proj.Config[lsp.ConfigDiagnosticsGate])
+	if err != nil {
+		return nil, fmt.Errorf("diagnostics gate: %w", err)
+	}
+	target := r.Branch
+	if r.DeliverMode != run.DeliverModePush {
+		if out, err := runDeliverGit(ctx, dir, "rev-parse", "--abbrev-ref", "HEAD"); err == nil && strings.TrimSpace(out) != "HEAD" {
+			target = strings.TrimSpace(out)
+		}
+	}
+	rule := lsp.GateFor(rules, target)
+	if rule == nil {
+		return nil, nil
+	}
+
+	paths, err := changedFiles(ctx, dir)
+	if err != nil {
+		return nil, fmt.Errorf("diagnostics gate: %w", err)
+	}
+	diags, err := s.lsp.DiagnosticsDiff(ctx, r.ProjectID, dir, paths)
+	if errors.Is(err, lsp.ErrDisabled) {
+		n.warnings = append(n.warnings, "diagnostics gate skipped: "+err.Error())
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("diagnostics gate: %w", err)
+	}
+	newErrors := lsp.Errors(diags)
+	if len(newErrors) == 0 {
+		return nil, nil
+	}
+	slog.Info("delivery introduces new errors", "run_id", r.ID, "branch", target, "errors", len(newErrors), "action", rule.Action)
+	if rule.Action == lsp.GateDraft && r.DeliverMode == run.DeliverModePR {
+		return newErrors, nil
+	}
+	first := newErrors[0]
+	return nil, fmt.Errorf("%w: %d for %s, first %s:%d: %s",
+		lsp.ErrNewDiagnostics, len(newErrors), target, first.Path, first.Range.Start.Line, first.Message)
+}
+
+// changedFiles returns the modified and untracked files of the checkout
+// dir, without deleted ones.
+func changedFiles(ctx context.Context, dir string) ([]string, error) {
+	var paths []string
+	for _, args := range [][]string{
+		{"diff", "--name-only", "--no-renames", "--diff-filter=d", "HEAD"},
+		{"ls-files", "--others", "--exclude-standard"},
+	} {
+		out, err := runDeliverGit(ctx, dir, args...)
+		if err != nil {
+			return nil, fmt.Errorf("git %s: %w", args[0], err)
+		}
+		for _, p := range strings.Split(strings.TrimSpace(out), "\n") {
+			if p != "" {
+				paths = append(paths, filepath.FromSlash(p))
+			}
+		}
+	}
+	return paths, nil
+}
+
+// deliveryNames are the commit message and branch name of a delivery.
+type deliveryNames struct {
+	shortID  string
+	title    string // Commit subject without the run reference, e.g. for pull request titles
+	message  string
+	branch   string
+	warnings []string // Ways the names fail the project's git policy
+}
+
+// names generates the commit message and branch name of a delivery by the
+// project's git policy. An invalid policy is reported as a warning and
+// the default conventions are used instead.
+func (s *DeliverService) names(cfg map[string]string, r *run.Run, taskTitle string) *deliveryNames {
+	n := &deliveryNames{shortID: r.ID}
+	if len(n.shortID) > 8 {
+		n.shortID = n.shortID[:8]
+	}
+	pol, err := gitpolicy.FromConfig(cfg)
+	if err != nil {
+		n.warnings = append(n.warnings, "invalid git policy, using the defaults: "+err.Error())
+		pol, _ = gitpolicy.FromConfig(nil)
+	}
+	vars := gitpolicy.Vars{TaskID: r.TaskID, TaskTitle: taskTitle, RunID: r.ID, Prefix: s.cfg.DeliveryCommitPrefix}
+	commit := pol.Commit(vars)
+	n.title, n.message = commit.Title, commit.Message
+	n.warnings = append(n.warnings, commit.Warnings...)
+	if r.DeliverMode != run.DeliverModePush {
+		var warnings []string
+		n.branch, warnings = pol.Branch(vars)
+		n.warnings = append(n.warnings, warnings...)
+	}
+	return n
+}
+
+func (s *DeliverService) deliverPatch(ctx context.Context, dir string, r *run.Run, n *deliveryNames) (*DeliveryResult, error) {
 	diff, err := runDeliverGit(ctx, dir, "diff", "HEAD")
 	if err != nil {
 		return nil, fmt.Errorf("git diff: %w", err)
 	}
 
-	patchFile := filepath.Join(dir, fmt.Sprintf("%s.patch", shortID))
+	patchFile := filepath.Join(dir, fmt.Sprintf("%s.patch", n.shortID))
 	if err := os.WriteFile(patchFile, []byte(diff), 0o644); err != nil {
 		return nil, fmt.Errorf("write patch: %w", err)
 	}
@@ -88,13 +265,12 @@ func (s *DeliverService) deliverPatch(ctx context.Context, dir string, r *run.Ru
 	}, nil
 }
 
-func (s *DeliverService) deliverCommitLocal(ctx context.Context, dir string, r *run.Run, shortID, taskTitle string) (*DeliveryResult, error) {
+func (s *DeliverService) deliverCommitLocal(ctx context.Context, dir string, r *run.Run, n *deliveryNames) (*DeliveryResult, error) {
 	if _, err := runDeliverGit(ctx, dir, "add", "-A"); err != nil {
 		return nil, fmt.Errorf("git add: %w", err)
 	}
 
-	msg := fmt.Sprintf("%s %s [run %s]", s.cfg.DeliveryCommitPrefix, taskTitle, shortID)
-	if _, err := runDeliverGit(ctx, dir, "commit", "-m", msg); err != nil {
+	if _, err := runDeliverGit(ctx, dir, "commit", "-m", n.message); err != nil {
 		return nil, fmt.Errorf("git commit: %w", err)
 	}
 
@@ -110,47 +286,59 @@ func (s *DeliverService) deliverCommitLocal(ctx context.Context, dir string, r *
 	}, nil
 }
 
-func (s *DeliverService) deliverBranch(ctx context.Context, dir string, r *run.Run, shortID, taskTitle string) (*DeliveryResult, error) {
-	branchName := fmt.Sprintf("codeforge/%s", shortID)
-
-	if _, err := runDeliverGit(ctx, dir, "checkout", "-b", branchName); err != nil {
+func (s *DeliverService) deliverBranch(ctx context.Context, dir string, r *run.Run, n *deliveryNames) (*DeliveryResult, error) {
+	if _, err := runDeliverGit(ctx, dir, "checkout", "-b", n.branch); err != nil {
 		return nil, fmt.Errorf("git checkout -b: %w", err)
 	}
 
 	// Commit on the new branch
-	result, err := s.deliverCommitLocal(ctx, dir, r, shortID, taskTitle)
+	result, err := s.deliverCommitLocal(ctx, dir, r, n)
 	if err != nil {
 		return nil, fmt.Errorf("commit on branch: %w", err)
 	}
 
-	if _, pushErr := runDeliverGit(ctx, dir, "push", "-u", "origin", branchName); pushErr != nil {
+	if _, pushErr := runDeliverCmd(ctx, dir, s.remoteEnv(ctx, r), "git", "push", "-u", "origin", n.branch); pushErr != nil {
 		slog.Warn("git push failed (branch delivery)", "run_id", r.ID, "error", pushErr)
 		// Return branch result even if push fails — local branch is still created
+	} else {
+		s.reportRunStatus(ctx, r, result.CommitHash)
+		if err := s.awaitCI(ctx, r, n.branch, result.CommitHash); err != nil {
+			return nil, err
+		}
 	}
 
-	slog.Info("branch delivered", "run_id", r.ID, "branch", branchName)
+	slog.Info("branch delivered", "run_id", r.ID, "branch", n.branch)
 	return &DeliveryResult{
 		Mode:       run.DeliverModeBranch,
-		BranchName: branchName,
+		BranchName: n.branch,
 		CommitHash: result.CommitHash,
 	}, nil
 }
 
-func (s *DeliverService) deliverPR(ctx context.Context, dir string, r *run.Run, shortID, taskTitle string) (*DeliveryResult, error) {
+// deliverPR opens a pull request of a delivery branch, as a draft if the
+// diagnostics gate asks for one.
+func (s *DeliverService) deliverPR(ctx context.Context, dir string, r *run.Run, n *deliveryNames, draft bool) (*DeliveryResult, error) {
 	// First create branch
-	branchResult, err := s.deliverBranch(ctx, dir, r, shortID, taskTitle)
+	branchResult, err := s.deliverBranch(ctx, dir, r, n)
 	if err != nil {
 		return nil, fmt.Errorf("branch for PR: %w", err)
 	}
 
 	// Try to create PR using gh CLI
-	prTitle := fmt.Sprintf("%s %s", s.cfg.DeliveryCommitPrefix, taskTitle)
+	prTitle := n.title
 	prBody := fmt.Sprintf("Automated delivery from CodeForge run %s", r.ID)
-	prURL, prErr := runDeliverCmd(ctx, dir, "gh", "pr", "create",
+	if r.Summary != "" {
+		prBody += "\n\n" + r.Summary
+	}
+	args := []string{"pr", "create",
 		"--title", prTitle,
 		"--body", prBody,
 		"--head", branchResult.BranchName,
-	)
+	}
+	if draft {
+		args = append(args, "--draft")
+	}
+	prURL, prErr := runDeliverCmd(ctx, dir, s.remoteEnv(ctx, r), "gh", args...)
 	if prErr != nil {
 		slog.Warn("gh pr create failed, falling back to branch-only", "run_id", r.ID, "error", prErr)
 		return branchResult, nil
@@ -162,18 +350,194 @@ func (s *DeliverService) deliverPR(ctx context.Context, dir string, r *run.Run,
 		BranchName: branchResult.BranchName,
 		CommitHash: branchResult.CommitHash,
 		PRURL:      strings.TrimSpace(prURL),
+		Draft:      draft,
+	}, nil
+}
+
+// deliverPush commits the run's changes onto the remote branch its worktree
+// was checked out from, e.g. to update a pull request. Unlike branch
+// delivery, a failed push fails the delivery: the commit is detached and
+// would be lost with the worktree.
+func (s *DeliverService) deliverPush(ctx context.Context, dir string, r *run.Run, n *deliveryNames) (*DeliveryResult, error) {
+	if r.Branch == "" || r.WorktreePath == "" {
+		return nil, fmt.Errorf("push delivery needs a run on a branch worktree")
+	}
+	result, err := s.deliverCommitLocal(ctx, dir, r, n)
+	if err != nil {
+		return nil, fmt.Errorf("commit for push: %w", err)
+	}
+	if _, err := runDeliverCmd(ctx, dir, s.remoteEnv(ctx, r), "git", "push", "origin", "HEAD:refs/heads/"+r.Branch); err != nil {
+		return nil, fmt.Errorf("git push to %s: %w", r.Branch, err)
+	}
+	s.reportRunStatus(ctx, r, result.CommitHash)
+	if err := s.awaitCI(ctx, r, r.Branch, result.CommitHash); err != nil {
+		return nil, err
+	}
+
+	slog.Info("push delivered", "run_id", r.ID, "branch", r.Branch, "hash", result.CommitHash)
+	return &DeliveryResult{
+		Mode:       run.DeliverModePush,
+		BranchName: r.Branch,
+		CommitHash: result.CommitHash,
+	}, nil
+}
+
+// deliverMirror commits the run's changes on a new branch, pushes it to
+// the project's mirror remote and attaches the commit as a patch bundle: a
+// git format-patch series to apply with git am, and a git bundle to fetch
+// from. It is for hosts CodeForge cannot push to; the bundles carry the
+// change across. Like branch delivery, a failed push is only logged, as the
+// bundles still deliver the commit.
+func (s *DeliverService) deliverMirror(ctx context.Context, dir string, r *run.Run, mirrorURL string, n *deliveryNames) (*DeliveryResult, error) {
+	if mirrorURL == "" {
+		return nil, fmt.Errorf("mirror delivery needs the project's %s", run.ConfigMirrorURL)
+	}
+	if _, err := runDeliverGit(ctx, dir, "checkout", "-b", n.branch); err != nil {
+		return nil, fmt.Errorf("git checkout -b: %w", err)
+	}
+	result, err := s.deliverCommitLocal(ctx, dir, r, n)
+	if err != nil {
+		return nil, fmt.Errorf("commit on branch: %w", err)
+	}
+
+	arts, err := s.storePatchBundle(ctx, dir, r, n.branch, result.CommitHash)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := runDeliverGit(ctx, dir, "push", mirrorURL, "HEAD:refs/heads/"+n.branch); err != nil {
+		slog.Warn("git push to mirror failed", "run_id", r.ID, "error", err)
+	}
+
+	slog.Info("mirror delivered", "run_id", r.ID, "branch", n.branch, "artifacts", len(arts))
+	return &DeliveryResult{
+		Mode:       run.DeliverModeMirror,
+		BranchName: n.branch,
+		CommitHash: result.CommitHash,
+		Artifacts:  arts,
 	}, nil
 }
 
+// storePatchBundle stores the delivery commit (on top of its parent) as a
+// format-patch series and a git bundle of the branch, and returns the IDs
+// of the artifacts.
+func (s *DeliverService) storePatchBundle(ctx context.Context, dir string, r *run.Run, branchName, commit string) ([]string, error) {
+	patch, err := runDeliverGit(ctx, dir, "format-patch", "--stdout", "-1", commit)
+	if err != nil {
+		return nil, fmt.Errorf("git format-patch: %w", err)
+	}
+	tmp, err := os.MkdirTemp("", "codeforge-bundle-")
+	if err != nil {
+		return nil, fmt.Errorf("create bundle dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+	bundlePath := filepath.Join(tmp, "run.bundle")
+	if _, err := runDeliverGit(ctx, dir, "bundle", "create", bundlePath, branchName, "^"+commit+"^"); err != nil {
+		return nil, fmt.Errorf("git bundle: %w", err)
+	}
+	bundle, err := os.ReadFile(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("read bundle: %w", err)
+	}
+
+	name := strings.ReplaceAll(branchName, "/", "-")
+	meta := map[string]string{"branch": branchName, "commit": commit}
+	var ids []string
+	for _, a := range []*artifact.Artifact{
+		{Name: name + ".patch", ContentType: "text/x-patch", Data: []byte(patch), Metadata: map[string]string{"format": "format-patch"}},
+		{Name: name + ".bundle", ContentType: "application/x-git-bundle", Data: bundle, Metadata: map[string]string{"format": "bundle"}},
+	} {
+		a.RunID, a.ProjectID, a.Kind = r.ID, r.ProjectID, artifact.KindPatchBundle
+		maps.Copy(a.Metadata, meta)
+		if err := s.store.CreateArtifact(ctx, a); err != nil {
+			return nil, fmt.Errorf("store %s: %w", a.Name, err)
+		}
+		ids = append(ids, a.ID)
+	}
+	return ids, nil
+}
+
+// reportRunStatus marks a pushed delivery commit with a successful
+// codeforge/run status, so branch protection can require a CodeForge run.
+// Projects whose git provider cannot report statuses are skipped.
+func (s *DeliverService) reportRunStatus(ctx context.Context, r *run.Run, sha string) {
+	p, err := s.store.GetProject(ctx, r.ProjectID)
+	if err != nil || p.Provider == "" {
+		return
+	}
+	prov, err := hostedGitProvider(ctx, s.secrets, p)
+	if err != nil {
+		slog.Warn("run status: build git provider", "run_id", r.ID, "provider", p.Provider, "error", err)
+		return
+	}
+	reporter := statusReporter(prov)
+	if reporter == nil {
+		return
+	}
+	setCommitStatus(ctx, reporter, sha, &gitprovider.CommitStatus{
+		Context:     StatusContextRun,
+		State:       gitprovider.StatusSuccess,
+		Description: fmt.Sprintf("CodeForge run %s completed", r.ID),
+		TargetURL:   runURL(s.publicURL, r.ProjectID, r.ID),
+	})
+}
+
+// awaitCI waits for CI of a pushed delivery commit if the run's project
+// gates delivery on it, and fails the delivery unless CI passed. A pull
+// request delivery then does not open its pull request.
+func (s *DeliverService) awaitCI(ctx context.Context, r *run.Run, branch, sha string) error {
+	if s.ci == nil {
+		return nil
+	}
+	p, err := s.store.GetProject(ctx, r.ProjectID)
+	if err != nil || p.Config[ci.ConfigGateDelivery] != "true" {
+		return nil
+	}
+	slog.Info("delivery waits for ci", "run_id", r.ID, "branch", branch, "sha", sha)
+	st, err := s.ci.Wait(ctx, p, r.ID, branch, sha)
+	if err != nil {
+		return fmt.Errorf("ci gate: %w", err)
+	}
+	if !st.Green() {
+		return fmt.Errorf("%w: %s on %s", ErrCIFailed, st.State, shortCommitHash(sha))
+	}
+	return nil
+}
+
+// remoteEnv returns the environment authenticating git pushes and the gh
+// CLI as the installation of the tenant's GitHub App the run's project
+// names, or nil to use the ambient credentials.
+func (s *DeliverService) remoteEnv(ctx context.Context, r *run.Run) []string {
+	if s.githubApps == nil {
+		return nil
+	}
+	p, err := s.store.GetProject(ctx, r.ProjectID)
+	if err != nil {
+		return nil
+	}
+	token, err := s.githubApps.Token(ctx, p)
+	if err != nil {
+		slog.Warn("delivery: github app token", "run_id", r.ID, "error", err)
+		return nil
+	}
+	if token == "" {
+		return nil
+	}
+	return append(gitlocal.AuthEnv("x-access-token", token), "GH_TOKEN="+token, "GH_ENTERPRISE_TOKEN="+token)
+}
+
 // runDeliverGit runs a git command in the given directory.
 func runDeliverGit(ctx context.Context, dir string, args ...string) (string, error) {
-	return runDeliverCmd(ctx, dir, "git", args...)
+	return runDeliverCmd(ctx, dir, nil, "git", args...)
 }
 
-// runDeliverCmd runs an arbitrary command in the given directory.
-func runDeliverCmd(ctx context.Context, dir, name string, args ...string) (string, error) {
+// runDeliverCmd runs an arbitrary command in the given directory, with env
+// added to the process environment.
+func runDeliverCmd(ctx context.Context, dir string, env []string, name string, args ...string) (string, error) {
 	cmd := exec.CommandContext(ctx, name, args...)
 	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
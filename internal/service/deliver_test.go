@@ -2,14 +2,22 @@ package service_test
 
 import (
 	"context"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/domain/featureflag"
+	"github.com/Strob0t/CodeForge/internal/domain/gitpolicy"
+	"github.com/Strob0t/CodeForge/internal/domain/lsp"
 	"github.com/Strob0t/CodeForge/internal/domain/project"
 	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/langserver"
 	"github.com/Strob0t/CodeForge/internal/service"
 )
 
@@ -210,6 +218,262 @@ func TestDeliver_Branch(t *testing.T) {
 	}
 }
 
+func TestDeliver_Push(t *testing.T) {
+	origin := initDeliverTestRepo(t)
+	clone := filepath.Join(t.TempDir(), "clone")
+	wt := filepath.Join(t.TempDir(), "run-1")
+	git := func(dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %s: %v", args, out, err)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git(origin, "branch", "feature")
+	git("", "clone", origin, clone)
+	git(clone, "worktree", "add", "--detach", wt, "origin/feature")
+	git(wt, "config", "user.email", "test@test.com")
+	git(wt, "config", "user.name", "Test")
+	if err := os.WriteFile(filepath.Join(wt, "hello.txt"), []byte("fixed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := &deliverMockStore{proj: &project.Project{ID: "proj-1", WorkspacePath: clone}}
+	svc := service.NewDeliverService(store, &config.Runtime{DeliveryCommitPrefix: "codeforge:"})
+	r := &run.Run{ID: "run-abcd1234", ProjectID: "proj-1", DeliverMode: run.DeliverModePush, WorktreePath: wt}
+
+	if _, err := svc.Deliver(context.Background(), r, "fix"); err == nil {
+		t.Fatal("expected error for push delivery without a branch")
+	}
+	r.Branch = "feature"
+	result, err := svc.Deliver(context.Background(), r, "fix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Mode != run.DeliverModePush || result.BranchName != "feature" || result.CommitHash == "" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if head := git(origin, "rev-parse", "feature"); head != result.CommitHash {
+		t.Fatalf("expected feature at %s, got %s", result.CommitHash, head)
+	}
+}
+
+func TestDeliver_BranchReportsCommitStatus(t *testing.T) {
+	dir := initDeliverTestRepo(t)
+	remote := t.TempDir()
+	for _, args := range [][]string{
+		{"git", "init", "--bare", remote},
+		{"git", "-C", dir, "remote", "add", "origin", remote},
+	} {
+		if out, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+			t.Fatalf("git setup %v: %s: %v", args, out, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("pushed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	currentPR = &fakePR{}
+	store := &deliverMockStore{
+		proj: &project.Project{ID: "proj-1", WorkspacePath: dir, Provider: "fake-git", Config: map[string]string{"token": "ghp"}},
+	}
+	svc := service.NewDeliverService(store, &config.Runtime{DeliveryCommitPrefix: "codeforge:"})
+	svc.SetPublicURL("http://ui.example")
+
+	r := &run.Run{ID: "run-abcd1234", ProjectID: "proj-1", DeliverMode: run.DeliverModeBranch}
+	result, err := svc.Deliver(context.Background(), r, "status work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := result.CommitHash + "/codeforge/run=success"
+	if len(currentPR.statuses) != 1 || currentPR.statuses[0] != want {
+		t.Fatalf("expected %q, got %v", want, currentPR.statuses)
+	}
+}
+
+func TestDeliver_Mirror(t *testing.T) {
+	dir := initDeliverTestRepo(t)
+	mirror := t.TempDir()
+	if out, err := exec.Command("git", "init", "--bare", mirror).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare: %s: %v", out, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("mirrored"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := &deliverMockStore{proj: &project.Project{ID: "proj-1", WorkspacePath: dir, Config: map[string]string{
+		run.ConfigDeliverModes: "mirror,patch",
+	}}}
+	svc := service.NewDeliverService(store, &config.Runtime{DeliveryCommitPrefix: "codeforge:"})
+	r := &run.Run{ID: "run-abcd1234", ProjectID: "proj-1", DeliverMode: run.DeliverModeMirror}
+
+	if _, err := svc.Deliver(context.Background(), r, "air-gapped fix"); err == nil {
+		t.Fatal("expected error for mirror delivery without a mirror_url")
+	}
+	store.proj.Config[run.ConfigMirrorURL] = mirror
+	result, err := svc.Deliver(context.Background(), r, "air-gapped fix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Mode != run.DeliverModeMirror || result.BranchName != "codeforge/run-abcd" || len(result.Artifacts) != 2 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if out, err := exec.Command("git", "-C", mirror, "rev-parse", result.BranchName).Output(); err != nil ||
+		strings.TrimSpace(string(out)) != result.CommitHash {
+		t.Fatalf("expected the branch pushed to the mirror, got %q, %v", out, err)
+	}
+
+	patch, bundle := store.artifacts[0], store.artifacts[1]
+	if patch.Kind != artifact.KindPatchBundle || patch.Name != "codeforge-run-abcd.patch" ||
+		!strings.Contains(string(patch.Data), "Subject: [PATCH] codeforge: air-gapped fix") {
+		t.Fatalf("unexpected patch artifact %+v", patch)
+	}
+	bundlePath := filepath.Join(t.TempDir(), bundle.Name)
+	if err := os.WriteFile(bundlePath, bundle.Data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "-C", dir, "bundle", "verify", bundlePath).CombinedOutput(); err != nil {
+		t.Fatalf("expected a valid bundle: %s: %v", out, err)
+	}
+
+	r.DeliverMode = run.DeliverModePR
+	if _, err := svc.Deliver(context.Background(), r, "upstream"); !errors.Is(err, run.ErrDeliverModeNotAllowed) {
+		t.Fatalf("expected ErrDeliverModeNotAllowed, got %v", err)
+	}
+}
+
+func TestDeliver_DiagnosticsGate(t *testing.T) {
+	// setup returns a repo whose committed main.go has one bad line and
+	// whose change adds another, and a delivery service gating on gate.
+	setup := func(t *testing.T, gate string) (*service.DeliverService, *service.FeatureFlagService, string) {
+		t.Helper()
+		dir := initDeliverTestRepo(t)
+		git := func(args ...string) string {
+			t.Helper()
+			out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+			if err != nil {
+				t.Fatalf("git %v: %s: %v", args, out, err)
+			}
+			return strings.TrimSpace(string(out))
+		}
+		if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\nbad()\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		git("add", "main.go")
+		git("commit", "-m", "main")
+		if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\nbad()\nbad()\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		gate = strings.ReplaceAll(gate, "{base}", git("rev-parse", "--abbrev-ref", "HEAD"))
+
+		store := &deliverMockStore{proj: &project.Project{ID: "proj-1", WorkspacePath: dir, Config: map[string]string{lsp.ConfigDiagnosticsGate: gate}}}
+		cfg := config.LSP{Servers: map[string][]string{"go": {"gopls"}}, RequestTimeout: 5 * time.Second, IdleTimeout: time.Minute}
+		lspSvc := service.NewLSPService(store, cfg, func(_ context.Context, _ []string, root string) (langserver.Server, error) {
+			return &fakeLangServer{root: root}, nil
+		})
+		flags := service.NewFeatureFlagService(store)
+		lspSvc.SetFeatureFlagService(flags)
+		if _, err := flags.Set(context.Background(), featureflag.LSP, &featureflag.SetRequest{ProjectID: "proj-1", Enabled: true}); err != nil {
+			t.Fatal(err)
+		}
+		svc := service.NewDeliverService(store, &config.Runtime{DeliveryCommitPrefix: "codeforge:"})
+		svc.SetLSPService(lspSvc)
+		return svc, flags, dir
+	}
+	ctx := context.Background()
+	r := &run.Run{ID: "run-abcd1234", ProjectID: "proj-1", DeliverMode: run.DeliverModeBranch}
+
+	svc, _, dir := setup(t, "{base}=fail")
+	if _, err := svc.Deliver(ctx, r, "more bad"); !errors.Is(err, lsp.ErrNewDiagnostics) {
+		t.Fatalf("expected ErrNewDiagnostics, got %v", err)
+	}
+	if out, _ := exec.Command("git", "-C", dir, "status", "--porcelain").Output(); !strings.Contains(string(out), "main.go") {
+		t.Fatal("expected the change left uncommitted")
+	}
+
+	// A draft rule only lets pull requests through, with the new errors.
+	svc, _, _ = setup(t, "release/*=fail,{base}=draft")
+	if _, err := svc.Deliver(ctx, r, "more bad"); !errors.Is(err, lsp.ErrNewDiagnostics) {
+		t.Fatalf("expected ErrNewDiagnostics for a branch delivery, got %v", err)
+	}
+	r.DeliverMode = run.DeliverModePR
+	result, err := svc.Deliver(ctx, r, "more bad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.CommitHash == "" || len(result.Diagnostics) != 1 || result.Diagnostics[0].Path != "main.go" || result.Diagnostics[0].Range.Start.Line != 3 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	// Other branches are not gated, and without lsp the gate is skipped.
+	r.DeliverMode = run.DeliverModeBranch
+	svc, _, _ = setup(t, "release/*=fail")
+	if result, err := svc.Deliver(ctx, r, "more bad"); err != nil || len(result.Diagnostics) != 0 {
+		t.Fatalf("expected an ungated delivery, got %+v, %v", result, err)
+	}
+	svc, flags, _ := setup(t, "{base}=fail")
+	if _, err := flags.Set(ctx, featureflag.LSP, &featureflag.SetRequest{ProjectID: "proj-1", Enabled: false}); err != nil {
+		t.Fatal(err)
+	}
+	result, err = svc.Deliver(ctx, r, "more bad")
+	if err != nil || len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "diagnostics gate skipped") {
+		t.Fatalf("expected a skipped gate, got %+v, %v", result, err)
+	}
+
+	svc, _, _ = setup(t, "{base}=warn")
+	if _, err := svc.Deliver(ctx, r, "more bad"); err == nil {
+		t.Fatal("expected an error for an invalid gate")
+	}
+}
+
+func TestStartRun_DeliverModeNotAllowed(t *testing.T) {
+	svc, store, _, _ := newRuntimeTestEnv()
+	store.projects[0].Config = map[string]string{run.ConfigDeliverModes: "mirror"}
+
+	_, err := svc.StartRun(context.Background(), &run.StartRequest{TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", DeliverMode: run.DeliverModePR})
+	if !errors.Is(err, run.ErrDeliverModeNotAllowed) {
+		t.Fatalf("expected ErrDeliverModeNotAllowed, got %v", err)
+	}
+	if _, err := svc.StartRun(context.Background(), &run.StartRequest{TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", DeliverMode: run.DeliverModeMirror}); err != nil {
+		t.Fatalf("StartRun failed: %v", err)
+	}
+}
+
+func TestDeliver_GitPolicy(t *testing.T) {
+	dir := initDeliverTestRepo(t)
+	if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("conventional"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store := &deliverMockStore{proj: &project.Project{ID: "proj-1", WorkspacePath: dir, Config: map[string]string{
+		gitpolicy.ConfigCommitStyle:    gitpolicy.StyleConventional,
+		gitpolicy.ConfigTicketPattern:  `[A-Z]+-[0-9]+`,
+		gitpolicy.ConfigBranchTemplate: "codeforge/{task-slug}-{run-shortid}",
+	}}}
+	svc := service.NewDeliverService(store, &config.Runtime{DeliveryCommitPrefix: "codeforge:"})
+	r := &run.Run{ID: "run-abcd1234", TaskID: "task-1", ProjectID: "proj-1", DeliverMode: run.DeliverModeBranch}
+
+	result, err := svc.Deliver(context.Background(), r, "Add retry to webhook sender")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.BranchName != "codeforge/add-retry-to-webhook-sender-run-abcd" {
+		t.Fatalf("expected the branch from the template, got %q", result.BranchName)
+	}
+	out, err := exec.Command("git", "-C", dir, "log", "-1", "--format=%B").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg := string(out); !strings.HasPrefix(msg, "feat: Add retry to webhook sender\n\nCodeForge run run-abcd1234") {
+		t.Fatalf("expected a conventional commit, got %q", msg)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "references no ticket") {
+		t.Fatalf("expected a warning for the missing ticket, got %q", result.Warnings)
+	}
+}
+
 func TestDeliver_NoWorkspacePath(t *testing.T) {
 	store := &deliverMockStore{
 		proj: &project.Project{ID: "proj-1", WorkspacePath: ""},
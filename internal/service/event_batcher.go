@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/port/eventstore"
+)
+
+// EventBatcher is an eventstore.Store that buffers appended agent events and
+// writes them to the wrapped store in batches. Events are written in append
+// order and stamped with strictly increasing creation times, so the order of
+// a run's events survives batching. A batch is written once it is full, by
+// the flusher every flush interval, and as soon as a run completes or is
+// interrupted. Worker result subscribers call Flush before acknowledging a
+// message, so the events it produced are stored first. Reads and deletes
+// write the buffer first, so callers see their own events. Events that fail
+// to write are put back in front of the buffer and written with the next
+// batch, up to maxEventFlushAttempts times.
+type EventBatcher struct {
+	events eventstore.Store
+	cfg    config.Events
+
+	flushMu sync.Mutex // Serializes writes so batches land in order
+
+	mu       sync.Mutex
+	buf      []event.AgentEvent
+	last     time.Time
+	attempts map[time.Time]int // Failed writes of re-buffered events, by creation time
+}
+
+// NewEventBatcher creates an EventBatcher writing to events.
+func NewEventBatcher(events eventstore.Store, cfg config.Events) *EventBatcher {
+	return &EventBatcher{events: events, cfg: cfg}
+}
+
+// Start writes the buffered events every flush interval until the context is
+// cancelled or the returned cancel function is called. Cancelling writes the
+// events still buffered before it returns.
+func (b *EventBatcher) Start(ctx context.Context) (cancel func()) {
+	ctx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(b.cfg.FlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := b.Flush(ctx); err != nil {
+					slog.Error("flush agent events", "error", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		stop()
+		<-done
+		if err := b.Flush(context.Background()); err != nil {
+			slog.Error("flush agent events", "error", err)
+		}
+	}
+}
+
+// eventFlushTimeout bounds one write of the buffered events.
+const eventFlushTimeout = 30 * time.Second
+
+// maxEventFlushAttempts is how often an event is written before it is
+// dropped, so a bad event cannot stay in the buffer forever.
+const maxEventFlushAttempts = 5
+
+// Append buffers the event and sets its creation time. It writes the buffer
+// when it is full or the event ends a run and returns the error of writing
+// this event; failures of other events in the batch are only logged.
+func (b *EventBatcher) Append(_ context.Context, ev *event.AgentEvent) error {
+	b.mu.Lock()
+	now := time.Now().Truncate(time.Microsecond) // Postgres keeps microseconds
+	if !now.After(b.last) {
+		now = b.last.Add(time.Microsecond)
+	}
+	b.last = now
+	ev.CreatedAt = now
+	b.buf = append(b.buf, *ev)
+	full := len(b.buf) >= b.cfg.BatchSize
+	b.mu.Unlock()
+
+	if !full && !endsRun(ev.Type) {
+		return nil
+	}
+	// Creation times are unique, so they identify the caller's event.
+	for _, f := range b.flush() {
+		if f.createdAt.Equal(now) {
+			return f.err
+		}
+	}
+	return nil
+}
+
+func endsRun(t event.Type) bool {
+	return t == event.TypeRunCompleted || t == event.TypeRunInterrupted
+}
+
+// failedEvent is an event a flush could not write.
+type failedEvent struct {
+	createdAt time.Time
+	err       error
+}
+
+// Flush writes the buffered events and returns the errors of the events it
+// could not write. Those stay buffered, so a worker result subscriber
+// returns the error and the message is redelivered instead of acknowledged.
+func (b *EventBatcher) Flush(_ context.Context) error {
+	failed := b.flush()
+	if len(failed) == 0 {
+		return nil
+	}
+	errs := make([]error, len(failed))
+	for i, f := range failed {
+		errs[i] = f.err
+	}
+	return fmt.Errorf("append %d events: %w", len(failed), errors.Join(errs...))
+}
+
+// flush writes the buffered events. A failed batch is retried one event at
+// a time so a single bad event does not hold back the others; the events
+// that still fail are put back in front of the buffer, in order, and
+// returned. An event that failed maxEventFlushAttempts times is dropped.
+func (b *EventBatcher) flush() []failedEvent {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	batch := b.buf
+	b.buf = nil
+	b.mu.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	// A batch holds events of every tenant and must outlive the request
+	// that filled it, so it is written without the caller's context.
+	ctx, cancel := context.WithTimeout(context.Background(), eventFlushTimeout)
+	defer cancel()
+	err := b.events.AppendBatch(ctx, batch)
+	if err == nil {
+		b.forget(batch)
+		return nil
+	}
+	slog.Warn("append event batch failed, appending events one by one", "events", len(batch), "error", err)
+
+	var failed []failedEvent
+	var written, retry []event.AgentEvent
+	for i := range batch {
+		ev := &batch[i]
+		if err := b.events.Append(ctx, ev); err != nil {
+			failed = append(failed, failedEvent{createdAt: ev.CreatedAt, err: err})
+			retry = append(retry, *ev)
+			continue
+		}
+		written = append(written, *ev)
+	}
+	b.forget(written)
+	b.requeue(retry, failed)
+	return failed
+}
+
+// forget clears the failed writes of the events that were written.
+func (b *EventBatcher) forget(evs []event.AgentEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.attempts) == 0 {
+		return
+	}
+	for i := range evs {
+		delete(b.attempts, evs[i].CreatedAt)
+	}
+}
+
+// requeue puts the events that failed to write back in front of the
+// buffer, so they keep their order, and drops those out of attempts.
+func (b *EventBatcher) requeue(evs []event.AgentEvent, failed []failedEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.attempts == nil {
+		b.attempts = make(map[time.Time]int)
+	}
+	kept := make([]event.AgentEvent, 0, len(evs)+len(b.buf))
+	for i := range evs {
+		ev := &evs[i]
+		b.attempts[ev.CreatedAt]++
+		if n := b.attempts[ev.CreatedAt]; n >= maxEventFlushAttempts {
+			delete(b.attempts, ev.CreatedAt)
+			slog.Error("drop agent event", "type", ev.Type, "run_id", ev.RunID, "task_id", ev.TaskID, "attempts", n, "error", failed[i].err)
+			continue
+		}
+		slog.Warn("append event failed, keeping it buffered", "type", ev.Type, "run_id", ev.RunID, "task_id", ev.TaskID, "error", failed[i].err)
+		kept = append(kept, *ev)
+	}
+	b.buf = append(kept, b.buf...)
+}
+
+// flushEvents writes the events es buffers, if any. Worker result
+// subscribers call it before a message is acknowledged, so a crash after
+// the ack cannot lose the events the message produced.
+func flushEvents(ctx context.Context, es eventstore.Store) error {
+	if f, ok := es.(eventstore.Flusher); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
+
+// AppendBatch writes the buffered events, then the given ones.
+func (b *EventBatcher) AppendBatch(ctx context.Context, evs []event.AgentEvent) error {
+	if err := b.Flush(ctx); err != nil {
+		return err
+	}
+	return b.events.AppendBatch(ctx, evs)
+}
+
+// LoadByTask writes the buffered events and loads the task's events.
+func (b *EventBatcher) LoadByTask(ctx context.Context, taskID string) ([]event.AgentEvent, error) {
+	if err := b.Flush(ctx); err != nil {
+		return nil, err
+	}
+	return b.events.LoadByTask(ctx, taskID)
+}
+
+// LoadByAgent writes the buffered events and loads the agent's events.
+func (b *EventBatcher) LoadByAgent(ctx context.Context, agentID string) ([]event.AgentEvent, error) {
+	if err := b.Flush(ctx); err != nil {
+		return nil, err
+	}
+	return b.events.LoadByAgent(ctx, agentID)
+}
+
+// LoadByRun writes the buffered events and loads the run's events.
+func (b *EventBatcher) LoadByRun(ctx context.Context, runID string) ([]event.AgentEvent, error) {
+	if err := b.Flush(ctx); err != nil {
+		return nil, err
+	}
+	return b.events.LoadByRun(ctx, runID)
+}
+
+// DeleteByRun writes the buffered events and deletes the run's events, so
+// none of them is written after the delete.
+func (b *EventBatcher) DeleteByRun(ctx context.Context, runID string) (int64, error) {
+	if err := b.Flush(ctx); err != nil {
+		return 0, err
+	}
+	return b.events.DeleteByRun(ctx, runID)
+}
+
+// AppendAudit writes the audit entry at once; audit entries are not batched.
+func (b *EventBatcher) AppendAudit(ctx context.Context, e *event.AuditEntry) error {
+	return b.events.AppendAudit(ctx, e)
+}
+
+// LoadAudit loads audit entries from the wrapped store.
+func (b *EventBatcher) LoadAudit(ctx context.Context, tenantID string, afterSeq int64, until time.Time, limit int) ([]event.AuditEntry, error) {
+	return b.events.LoadAudit(ctx, tenantID, afterSeq, until, limit)
+}
@@ -0,0 +1,182 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestEventBatcher_FlushesInOrder(t *testing.T) {
+	store := &runtimeMockEventStore{}
+	b := service.NewEventBatcher(store, config.Events{BatchSize: 3, FlushInterval: time.Hour})
+	ctx := context.Background()
+
+	for i := range 2 {
+		if err := b.Append(ctx, &event.AgentEvent{RunID: "run-1", Type: event.TypeToolCalled, Version: i + 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(store.events) != 0 {
+		t.Fatalf("expected events to stay buffered, got %d written", len(store.events))
+	}
+	if err := b.Append(ctx, &event.AgentEvent{RunID: "run-1", Type: event.TypeToolCalled, Version: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.batches) != 1 || store.batches[0] != 3 {
+		t.Fatalf("expected one batch of 3 once full, got %v", store.batches)
+	}
+	for i := 1; i < len(store.events); i++ {
+		if store.events[i].Version != i+1 || !store.events[i].CreatedAt.After(store.events[i-1].CreatedAt) {
+			t.Fatalf("expected events in append order with increasing times, got %+v", store.events)
+		}
+	}
+
+	// Finishing a run and reading its events write the buffer at once.
+	_ = b.Append(ctx, &event.AgentEvent{RunID: "run-1", Type: event.TypeRunCompleted, Version: 4})
+	if len(store.batches) != 2 || store.batches[1] != 1 {
+		t.Fatalf("expected the completed run to flush, got %v", store.batches)
+	}
+	_ = b.Append(ctx, &event.AgentEvent{RunID: "run-2", Type: event.TypeToolCalled})
+	if evs, err := b.LoadByRun(ctx, "run-2"); err != nil || len(evs) != 1 {
+		t.Fatalf("expected to read a buffered event, got %v, %v", evs, err)
+	}
+}
+
+func TestEventBatcher_FlushesOnInterval(t *testing.T) {
+	store := &runtimeMockEventStore{}
+	b := service.NewEventBatcher(store, config.Events{BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+	cancel := b.Start(context.Background())
+
+	_ = b.Append(context.Background(), &event.AgentEvent{RunID: "run-1", Type: event.TypeToolCalled})
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		store.mu.Lock()
+		n := len(store.events)
+		store.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the flusher to write the event")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Cancelling writes what is still buffered.
+	_ = b.Append(context.Background(), &event.AgentEvent{RunID: "run-1", Type: event.TypeToolCalled})
+	cancel()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.events) != 2 {
+		t.Fatalf("expected cancel to flush, got %d events", len(store.events))
+	}
+}
+
+func TestEventBatcher_FallsBackToSingleAppends(t *testing.T) {
+	store := &runtimeMockEventStore{batchErr: errors.New("copy failed")}
+	b := service.NewEventBatcher(store, config.Events{BatchSize: 2, FlushInterval: time.Hour})
+	ctx := context.Background()
+
+	_ = b.Append(ctx, &event.AgentEvent{RunID: "run-1", Type: event.TypeToolCalled, Version: 1})
+	if err := b.Append(ctx, &event.AgentEvent{RunID: "run-1", Type: event.TypeToolCalled, Version: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.events) != 2 || store.events[0].Version != 1 || store.events[1].Version != 2 {
+		t.Fatalf("expected both events appended in order, got %+v", store.events)
+	}
+}
+
+func TestEventBatcher_ScopesErrors(t *testing.T) {
+	store := &runtimeMockEventStore{batchErr: errors.New("copy failed"), failRun: "run-bad"}
+	b := service.NewEventBatcher(store, config.Events{BatchSize: 2, FlushInterval: time.Hour})
+	ctx := context.Background()
+
+	// Another run's bad event does not fail this caller's append.
+	_ = b.Append(ctx, &event.AgentEvent{RunID: "run-bad", Type: event.TypeToolCalled})
+	if err := b.Append(ctx, &event.AgentEvent{RunID: "run-ok", Type: event.TypeToolCalled}); err != nil {
+		t.Fatalf("expected no error for a stored event, got %v", err)
+	}
+	if err := b.Append(ctx, &event.AgentEvent{RunID: "run-bad", Type: event.TypeRunCompleted}); err == nil {
+		t.Fatal("expected the error of the caller's own event")
+	}
+
+	_ = b.Append(ctx, &event.AgentEvent{RunID: "run-bad", Type: event.TypeToolCalled})
+	if err := b.Flush(ctx); err == nil {
+		t.Fatal("expected Flush to report the failed event")
+	}
+}
+
+func TestEventBatcher_FlushesBeforeAck(t *testing.T) {
+	_, store, queue, bc := newRuntimeTestEnv()
+	events := &runtimeMockEventStore{}
+	b := service.NewEventBatcher(events, config.Events{BatchSize: 100, FlushInterval: time.Hour})
+	svc := service.NewRuntimeService(store, queue, bc, b, service.NewPolicyService("headless-safe-sandbox", nil), &config.Runtime{StallThreshold: 5})
+	ctx := context.Background()
+	if _, err := svc.StartSubscribers(ctx); err != nil {
+		t.Fatal(err)
+	}
+	store.mu.Lock()
+	store.runs = append(store.runs, run.Run{ID: "run-1", TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", Status: run.StatusRunning})
+	store.mu.Unlock()
+
+	result := messagequeue.ToolCallResultPayload{RunID: "run-1", CallID: "c1", Tool: "Read", Success: true}
+	if err := queue.deliver(ctx, messagequeue.SubjectRunToolCallResult, result); err != nil {
+		t.Fatal(err)
+	}
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	if len(events.events) == 0 {
+		t.Fatal("expected the result's events stored before the message is acknowledged")
+	}
+}
+
+func TestEventBatcher_KeepsFailedEvents(t *testing.T) {
+	store := &runtimeMockEventStore{batchErr: errors.New("copy failed"), failRun: "run-bad"}
+	b := service.NewEventBatcher(store, config.Events{BatchSize: 100, FlushInterval: time.Hour})
+	ctx := context.Background()
+
+	_ = b.Append(ctx, &event.AgentEvent{RunID: "run-bad", Type: event.TypeToolCalled, Version: 1})
+	_ = b.Append(ctx, &event.AgentEvent{RunID: "run-ok", Type: event.TypeToolCalled, Version: 2})
+	if err := b.Flush(ctx); err == nil {
+		t.Fatal("expected Flush to report the failed event")
+	}
+
+	// The failed event stays buffered and is written once the store
+	// recovers, before the events appended after it.
+	store.mu.Lock()
+	store.failRun = ""
+	store.mu.Unlock()
+	_ = b.Append(ctx, &event.AgentEvent{RunID: "run-ok", Type: event.TypeToolCalled, Version: 3})
+	if err := b.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.events) != 3 || store.events[1].Version != 1 || store.events[2].Version != 3 {
+		t.Fatalf("expected the failed event written before later ones, got %+v", store.events)
+	}
+}
+
+func TestEventBatcher_DropsEventAfterAttempts(t *testing.T) {
+	store := &runtimeMockEventStore{batchErr: errors.New("copy failed"), failRun: "run-bad"}
+	b := service.NewEventBatcher(store, config.Events{BatchSize: 100, FlushInterval: time.Hour})
+	ctx := context.Background()
+
+	_ = b.Append(ctx, &event.AgentEvent{RunID: "run-bad", Type: event.TypeToolCalled})
+	failures := 0
+	for range 10 {
+		if b.Flush(ctx) != nil {
+			failures++
+		}
+	}
+	if failures != 5 {
+		t.Fatalf("expected the event dropped after 5 failed writes, failed %d times", failures)
+	}
+}
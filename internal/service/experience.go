@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/experience"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+)
+
+// ExperienceService keeps the experience pool: it records how execution
+// plans ended and finds the entries of features similar to a new one, so
+// planning can learn from earlier successes and failures.
+type ExperienceService struct {
+	store   database.Store
+	orchCfg *config.Orchestrator
+}
+
+// NewExperienceService creates an ExperienceService.
+func NewExperienceService(store database.Store, orchCfg *config.Orchestrator) *ExperienceService {
+	return &ExperienceService{store: store, orchCfg: orchCfg}
+}
+
+// Record adds the outcome of a finished plan to the pool. Failures are
+// logged, since recording must not affect the plan.
+func (s *ExperienceService) Record(ctx context.Context, p *plan.ExecutionPlan) {
+	outcome := experience.OutcomeSuccess
+	if p.Status == plan.StatusFailed {
+		outcome = experience.OutcomeFailure
+	}
+	e := experience.FromPlan(p, outcome, time.Now())
+	if err := s.store.CreateExperience(ctx, e); err != nil {
+		if errors.Is(err, domain.ErrConflict) {
+			return // Already recorded.
+		}
+		slog.Warn("record experience failed", "plan_id", p.ID, "error", err)
+		return
+	}
+	slog.Info("experience recorded", "plan_id", p.ID, "outcome", outcome, "pitfalls", len(e.Pitfalls))
+}
+
+// List returns the experience entries of a project, newest first.
+func (s *ExperienceService) List(ctx context.Context, projectID string) ([]experience.Entry, error) {
+	return s.store.ListExperiences(ctx, projectID)
+}
+
+// Search returns the entries of a project most similar to feature,
+// weighted by usefulness, up to the configured limit.
+func (s *ExperienceService) Search(ctx context.Context, projectID, feature string) ([]experience.Match, error) {
+	if s.orchCfg.ExperienceLimit <= 0 {
+		return nil, nil
+	}
+	entries, err := s.store.ListExperiences(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return experience.Search(entries, feature, s.orchCfg.ExperienceLimit, s.orchCfg.ExperienceMinUsefulness), nil
+}
+
+// Rate records whether an entry was useful for planning. Entries rated
+// not useful lose weight in searches and are eventually skipped.
+func (s *ExperienceService) Rate(ctx context.Context, id string, req experience.RateRequest) (*experience.Entry, error) {
+	return s.store.RateExperience(ctx, id, req.Useful)
+}
@@ -0,0 +1,98 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/agent"
+	"github.com/Strob0t/CodeForge/internal/domain/experience"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestOrchestrator_RecordsExperienceWhenPlanEnds(t *testing.T) {
+	store, orchSvc := newOrchTestSetup()
+	orchSvc.SetExperiencePool(service.NewExperienceService(store, &config.Orchestrator{ExperienceLimit: 3}))
+	ctx := context.Background()
+
+	p, err := orchSvc.CreatePlan(ctx, &plan.CreatePlanRequest{
+		Name:      "export billing",
+		ProjectID: "proj-1",
+		Protocol:  plan.ProtocolSequential,
+		Steps:     []plan.CreateStepRequest{{TaskID: "t1", AgentID: "a1"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orchSvc.StartPlan(ctx, p.ID); err != nil {
+		t.Fatalf("start plan: %v", err)
+	}
+	store.mu.Lock()
+	runID := store.steps[0].RunID
+	store.mu.Unlock()
+	orchSvc.HandleRunCompleted(ctx, runID, run.StatusFailed)
+
+	if len(store.experiences) != 1 {
+		t.Fatalf("expected 1 experience, got %d", len(store.experiences))
+	}
+	if e := store.experiences[0]; e.PlanID != p.ID || e.Outcome != experience.OutcomeFailure || e.Feature != "export billing" || e.Steps != 1 {
+		t.Fatalf("unexpected experience %+v", e)
+	}
+}
+
+func TestDecomposeFeature_AddsSimilarExperience(t *testing.T) {
+	var prompt string
+	body, _ := json.Marshal(mockDecomposeResponse())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req litellm.ChatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		prompt = req.Messages[1].Content
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"content": string(body)}}},
+		})
+	}))
+	defer srv.Close()
+
+	store := &orchMockStore{}
+	store.projects = []project.Project{{ID: "p1", Name: "TestProject"}}
+	store.agents = []agent.Agent{{ID: "a1", ProjectID: "p1", Name: "Coder", Backend: "aider", Status: agent.StatusIdle}}
+	store.experiences = []experience.Entry{
+		{ID: "e1", ProjectID: "p1", Feature: "JWT authentication", Outcome: experience.OutcomeFailure, Pitfalls: []string{"token clock skew"}},
+		{ID: "e2", ProjectID: "p1", Feature: "CSV billing export", Outcome: experience.OutcomeSuccess},
+	}
+	orchCfg := &config.Orchestrator{MaxParallel: 4, Mode: "semi_auto", DecomposeModel: "m", ExperienceLimit: 3, ExperienceMinUsefulness: 0.3}
+	runtimeSvc := service.NewRuntimeService(store, &runtimeMockQueue{}, &runtimeMockBroadcaster{}, &runtimeMockEventStore{},
+		service.NewPolicyService("headless-safe-sandbox", nil), &config.Runtime{StallThreshold: 5})
+	orchSvc := service.NewOrchestratorService(store, &runtimeMockBroadcaster{}, &runtimeMockEventStore{}, runtimeSvc, orchCfg)
+	meta := service.NewMetaAgentService(store, litellm.NewClient(srv.URL, ""), orchSvc, orchCfg)
+	experienceSvc := service.NewExperienceService(store, orchCfg)
+	meta.SetExperiencePool(experienceSvc)
+	ctx := context.Background()
+
+	if _, err := meta.DecomposeFeature(ctx, &plan.DecomposeRequest{ProjectID: "p1", Feature: "Add JWT authentication"}); err != nil {
+		t.Fatalf("DecomposeFeature failed: %v", err)
+	}
+	if !strings.Contains(prompt, "JWT authentication (failure") || !strings.Contains(prompt, "pitfall: token clock skew") ||
+		strings.Contains(prompt, "CSV billing") {
+		t.Fatalf("expected only the similar experience in the prompt, got:\n%s", prompt)
+	}
+
+	// Two "not useful" ratings drop the entry below the minimum usefulness.
+	for range 2 {
+		if _, err := experienceSvc.Rate(ctx, "e1", experience.RateRequest{}); err != nil {
+			t.Fatalf("Rate failed: %v", err)
+		}
+	}
+	if matches, _ := experienceSvc.Search(ctx, "p1", "Add JWT authentication"); len(matches) != 0 {
+		t.Fatalf("expected the badly rated entry to be skipped, got %+v", matches)
+	}
+}
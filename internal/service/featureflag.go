@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/featureflag"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+)
+
+// FeatureFlagBucket is the key-value bucket that propagates overrides to
+// all servers.
+const FeatureFlagBucket = "codeforge_feature_flags"
+
+// FeatureFlagService switches experimental subsystems on and off at
+// runtime, for all tenants, a tenant or a project. Overrides are stored in
+// the database and cached in memory; with a key-value bucket, every change
+// reaches the caches of all servers.
+//
+// A nil *FeatureFlagService reports every flag at its default.
+type FeatureFlagService struct {
+	store database.Store
+	kv    messagequeue.KeyValue
+
+	mu        sync.RWMutex
+	overrides map[string]featureflag.Flag // By featureflag.ID
+	tenants   map[string]string           // Project ID to tenant ID
+}
+
+// NewFeatureFlagService creates a FeatureFlagService.
+func NewFeatureFlagService(store database.Store) *FeatureFlagService {
+	return &FeatureFlagService{
+		store:     store,
+		overrides: make(map[string]featureflag.Flag),
+		tenants:   make(map[string]string),
+	}
+}
+
+// SetKeyValue enables propagation of changes through kv.
+func (s *FeatureFlagService) SetKeyValue(kv messagequeue.KeyValue) {
+	s.kv = kv
+}
+
+// Load reads all overrides from the database into the cache.
+func (s *FeatureFlagService) Load(ctx context.Context) error {
+	flags, err := s.store.ListFeatureFlags(ctx)
+	if err != nil {
+		return fmt.Errorf("load feature flags: %w", err)
+	}
+	overrides := make(map[string]featureflag.Flag, len(flags))
+	for i := range flags {
+		overrides[flags[i].ID()] = flags[i]
+	}
+	s.mu.Lock()
+	s.overrides = overrides
+	s.mu.Unlock()
+	return nil
+}
+
+// StartWatcher applies changes made on other servers to the cache until
+// ctx is done or cancel is called. It does nothing without a key-value
+// bucket.
+func (s *FeatureFlagService) StartWatcher(ctx context.Context) (cancel func(), err error) {
+	if s.kv == nil {
+		return func() {}, nil
+	}
+	return s.kv.Watch(ctx, s.apply)
+}
+
+// apply updates the cache from a bucket entry; a nil value is a deleted
+// override.
+func (s *FeatureFlagService) apply(id string, value []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if value == nil {
+		delete(s.overrides, id)
+		return
+	}
+	var f featureflag.Flag
+	if err := json.Unmarshal(value, &f); err != nil {
+		slog.Warn("invalid feature flag in bucket", "id", id, "error", err)
+		return
+	}
+	s.overrides[id] = f
+}
+
+// Enabled reports whether a flag is on for a project: the project's
+// override wins over its tenant's, which wins over the global one. Without
+// a project, the request's tenant is used.
+func (s *FeatureFlagService) Enabled(ctx context.Context, key featureflag.Key, projectID string) bool {
+	enabled, _ := s.state(ctx, key, projectID)
+	return enabled
+}
+
+// Evaluate returns the state of every flag for a project.
+func (s *FeatureFlagService) Evaluate(ctx context.Context, projectID string) ([]featureflag.State, error) {
+	if _, err := s.store.GetProject(ctx, projectID); err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+	states := make([]featureflag.State, 0, len(featureflag.Definitions))
+	for _, d := range featureflag.Definitions {
+		enabled, scope := s.state(ctx, d.Key, projectID)
+		states = append(states, featureflag.State{Key: d.Key, Enabled: enabled, Scope: scope})
+	}
+	return states, nil
+}
+
+func (s *FeatureFlagService) state(ctx context.Context, key featureflag.Key, projectID string) (bool, featureflag.Scope) {
+	def, _ := featureflag.Lookup(key)
+	if s == nil {
+		return def.Default, featureflag.ScopeDefault
+	}
+	tenantID := tenant.FromContext(ctx)
+	if projectID != "" {
+		tenantID = s.projectTenant(ctx, projectID)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, id := range []string{
+		featureflag.ID(key, tenantID, projectID),
+		featureflag.ID(key, tenantID, ""),
+		featureflag.ID(key, "", ""),
+	} {
+		if f, ok := s.overrides[id]; ok {
+			return f.Enabled, f.Scope()
+		}
+	}
+	return def.Default, featureflag.ScopeDefault
+}
+
+// projectTenant returns the tenant of a project, or the request's tenant
+// if the project cannot be read.
+func (s *FeatureFlagService) projectTenant(ctx context.Context, projectID string) string {
+	s.mu.RLock()
+	tenantID, ok := s.tenants[projectID]
+	s.mu.RUnlock()
+	if ok {
+		return tenantID
+	}
+	p, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return tenant.FromContext(ctx)
+	}
+	tenantID = p.TenantID
+	if tenantID == "" {
+		tenantID = tenant.DefaultID
+	}
+	s.mu.Lock()
+	s.tenants[projectID] = tenantID
+	s.mu.Unlock()
+	return tenantID
+}
+
+// List returns every flag with the overrides visible to the request's
+// tenant: the global ones and its own.
+func (s *FeatureFlagService) List(ctx context.Context) []featureflag.Status {
+	scoped := tenant.FromContext(ctx)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]featureflag.Status, 0, len(featureflag.Definitions))
+	for _, d := range featureflag.Definitions {
+		st := featureflag.Status{Definition: d, Overrides: []featureflag.Flag{}}
+		for _, f := range s.overrides {
+			if f.Key == d.Key && (scoped == "" || f.TenantID == "" || f.TenantID == scoped) {
+				st.Overrides = append(st.Overrides, f)
+			}
+		}
+		slices.SortFunc(st.Overrides, func(a, b featureflag.Flag) int {
+			return strings.Compare(a.ID(), b.ID())
+		})
+		result = append(result, st)
+	}
+	return result
+}
+
+// Set overrides a flag for all tenants, a tenant or a project. Tenant-scoped
+// requests may only override flags of their own tenant and its projects.
+func (s *FeatureFlagService) Set(ctx context.Context, key featureflag.Key, req *featureflag.SetRequest) (*featureflag.Flag, error) {
+	if err := req.Validate(key); err != nil {
+		return nil, fmt.Errorf("validate feature flag: %w", err)
+	}
+	tenantID, err := s.scope(ctx, req.TenantID, req.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &featureflag.Flag{Key: key, TenantID: tenantID, ProjectID: req.ProjectID, Enabled: req.Enabled}
+	if err := s.store.SetFeatureFlag(ctx, f); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.overrides[f.ID()] = *f
+	s.mu.Unlock()
+	s.publish(ctx, f.ID(), f)
+	slog.Info("feature flag set", "key", key, "scope", f.Scope(), "tenant_id", f.TenantID, "project_id", f.ProjectID, "enabled", f.Enabled)
+	return f, nil
+}
+
+// Delete removes an override; the flag falls back to the next broader
+// scope.
+func (s *FeatureFlagService) Delete(ctx context.Context, key featureflag.Key, tenantID, projectID string) error {
+	if _, ok := featureflag.Lookup(key); !ok {
+		return fmt.Errorf("feature flag %s: %w", key, domain.ErrNotFound)
+	}
+	if tenantID != "" && projectID != "" {
+		return fmt.Errorf("validate feature flag: %w", featureflag.ErrProjectOrTenant)
+	}
+	tenantID, err := s.scope(ctx, tenantID, projectID)
+	if err != nil {
+		return err
+	}
+	if err := s.store.DeleteFeatureFlag(ctx, key, tenantID, projectID); err != nil {
+		return err
+	}
+	id := featureflag.ID(key, tenantID, projectID)
+	s.mu.Lock()
+	delete(s.overrides, id)
+	s.mu.Unlock()
+	s.publish(ctx, id, nil)
+	slog.Info("feature flag override deleted", "id", id)
+	return nil
+}
+
+// scope returns the tenant an override belongs to: the project's tenant
+// for project overrides, else tenantID. Tenant-scoped requests cannot
+// reach other tenants or the global scope.
+func (s *FeatureFlagService) scope(ctx context.Context, tenantID, projectID string) (string, error) {
+	if projectID != "" {
+		p, err := s.store.GetProject(ctx, projectID)
+		if err != nil {
+			return "", fmt.Errorf("get project: %w", err)
+		}
+		tenantID = p.TenantID
+		if tenantID == "" {
+			tenantID = tenant.DefaultID
+		}
+	}
+	if scoped := tenant.FromContext(ctx); scoped != "" && tenantID != scoped {
+		if tenantID == "" {
+			return "", fmt.Errorf("global feature flags: %w", domain.ErrNotFound)
+		}
+		return "", fmt.Errorf("tenant %s: %w", tenantID, domain.ErrNotFound)
+	}
+	if tenantID != "" && projectID == "" {
+		if _, err := s.store.GetTenant(ctx, tenantID); err != nil {
+			return "", fmt.Errorf("get tenant: %w", err)
+		}
+	}
+	return tenantID, nil
+}
+
+// publish propagates a change to the other servers. The database stays the
+// source of truth, so a failure is logged and the others pick the change up
+// on their next start.
+func (s *FeatureFlagService) publish(ctx context.Context, id string, f *featureflag.Flag) {
+	if s.kv == nil {
+		return
+	}
+	var err error
+	if f == nil {
+		err = s.kv.Delete(ctx, id)
+	} else {
+		var data []byte
+		if data, err = json.Marshal(f); err == nil {
+			err = s.kv.Put(ctx, id, data)
+		}
+	}
+	if err != nil {
+		slog.Error("propagate feature flag", "id", id, "error", err)
+	}
+}
@@ -0,0 +1,326 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/agent"
+	"github.com/Strob0t/CodeForge/internal/domain/conversation"
+	"github.com/Strob0t/CodeForge/internal/domain/featureflag"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+// memoryKV is an in-memory messagequeue.KeyValue that notifies watchers
+// synchronously.
+type memoryKV struct {
+	mu       sync.Mutex
+	values   map[string][]byte
+	revs     map[string]uint64
+	rev      uint64
+	watchers []func(string, []byte)
+}
+
+func (kv *memoryKV) GetRevision(_ context.Context, key string) ([]byte, uint64, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	return kv.values[key], kv.revs[key], nil
+}
+
+func (kv *memoryKV) Update(ctx context.Context, key string, value []byte, revision uint64) error {
+	kv.mu.Lock()
+	current := kv.revs[key]
+	kv.mu.Unlock()
+	if current != revision {
+		return messagequeue.ErrKeyChanged
+	}
+	return kv.Put(ctx, key, value)
+}
+
+func (kv *memoryKV) Get(_ context.Context, key string) ([]byte, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	return kv.values[key], nil
+}
+
+func (kv *memoryKV) Create(ctx context.Context, key string, value []byte) error {
+	kv.mu.Lock()
+	_, ok := kv.values[key]
+	kv.mu.Unlock()
+	if ok {
+		return messagequeue.ErrKeyExists
+	}
+	return kv.Put(ctx, key, value)
+}
+
+func (kv *memoryKV) Put(_ context.Context, key string, value []byte) error {
+	kv.mu.Lock()
+	if kv.values == nil {
+		kv.values = make(map[string][]byte)
+		kv.revs = make(map[string]uint64)
+	}
+	kv.values[key] = value
+	kv.rev++
+	kv.revs[key] = kv.rev
+	watchers := kv.watchers
+	kv.mu.Unlock()
+	for _, fn := range watchers {
+		fn(key, value)
+	}
+	return nil
+}
+
+func (kv *memoryKV) Delete(_ context.Context, key string) error {
+	kv.mu.Lock()
+	delete(kv.values, key)
+	delete(kv.revs, key)
+	watchers := kv.watchers
+	kv.mu.Unlock()
+	for _, fn := range watchers {
+		fn(key, nil)
+	}
+	return nil
+}
+
+func (kv *memoryKV) Watch(_ context.Context, fn func(string, []byte)) (func(), error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	for k, v := range kv.values {
+		fn(k, v)
+	}
+	kv.watchers = append(kv.watchers, fn)
+	return func() {}, nil
+}
+
+var _ messagequeue.KeyValue = (*memoryKV)(nil)
+
+func newFeatureFlagTestEnv() (*service.FeatureFlagService, *runtimeMockStore) {
+	_, store, _, _ := newRuntimeTestEnv()
+	store.tenants = []tenant.Tenant{{ID: tenant.DefaultID}, {ID: "acme"}}
+	store.projects = append(store.projects, project.Project{ID: "proj-2", TenantID: "acme", Name: "acme-project"})
+	return service.NewFeatureFlagService(store), store
+}
+
+func TestFeatureFlagService_Enabled(t *testing.T) {
+	svc, _ := newFeatureFlagTestEnv()
+	ctx := context.Background()
+
+	var nilSvc *service.FeatureFlagService
+	if nilSvc.Enabled(ctx, featureflag.GraphRAG, "proj-2") || !nilSvc.Enabled(ctx, featureflag.AgenticConversations, "proj-2") {
+		t.Fatal("expected a nil service to report the defaults")
+	}
+
+	set := func(req featureflag.SetRequest) {
+		t.Helper()
+		if _, err := svc.Set(ctx, featureflag.GraphRAG, &req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect := func(projectID string, enabled bool, scope featureflag.Scope) {
+		t.Helper()
+		states, err := svc.Evaluate(ctx, projectID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if states[0].Key != featureflag.GraphRAG || states[0].Enabled != enabled || states[0].Scope != scope {
+			t.Fatalf("project %s: expected %v from %s, got %+v", projectID, enabled, scope, states[0])
+		}
+		if svc.Enabled(ctx, featureflag.GraphRAG, projectID) != enabled {
+			t.Fatalf("project %s: Enabled disagrees with Evaluate", projectID)
+		}
+	}
+
+	expect("proj-2", false, featureflag.ScopeDefault)
+	set(featureflag.SetRequest{Enabled: true})
+	expect("proj-1", true, featureflag.ScopeGlobal)
+	expect("proj-2", true, featureflag.ScopeGlobal)
+	set(featureflag.SetRequest{TenantID: "acme", Enabled: false})
+	expect("proj-1", true, featureflag.ScopeGlobal)
+	expect("proj-2", false, featureflag.ScopeTenant)
+	set(featureflag.SetRequest{ProjectID: "proj-2", Enabled: true})
+	expect("proj-2", true, featureflag.ScopeProject)
+
+	if err := svc.Delete(ctx, featureflag.GraphRAG, "", "proj-2"); err != nil {
+		t.Fatal(err)
+	}
+	expect("proj-2", false, featureflag.ScopeTenant)
+	if err := svc.Delete(ctx, featureflag.GraphRAG, "", "proj-2"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a deleted override, got %v", err)
+	}
+	if _, err := svc.Evaluate(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for an unknown project, got %v", err)
+	}
+}
+
+func TestFeatureFlagService_TenantScope(t *testing.T) {
+	svc, _ := newFeatureFlagTestEnv()
+	ctx := context.Background()
+	acmeCtx := tenant.NewContext(ctx, "acme")
+
+	if _, err := svc.Set(ctx, featureflag.LSP, &featureflag.SetRequest{TenantID: tenant.DefaultID, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Set(ctx, featureflag.LSP, &featureflag.SetRequest{Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range []featureflag.SetRequest{{}, {TenantID: tenant.DefaultID}, {ProjectID: "proj-1"}} {
+		if _, err := svc.Set(acmeCtx, featureflag.LSP, &req); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected %+v to be out of acme's reach, got %v", req, err)
+		}
+	}
+	f, err := svc.Set(acmeCtx, featureflag.LSP, &featureflag.SetRequest{ProjectID: "proj-2", Enabled: false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.TenantID != "acme" || f.Scope() != featureflag.ScopeProject {
+		t.Fatalf("expected the project's tenant on its override, got %+v", f)
+	}
+	if _, err := svc.Set(ctx, featureflag.LSP, &featureflag.SetRequest{TenantID: "missing"}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for an unknown tenant, got %v", err)
+	}
+
+	var lsp featureflag.Status
+	for _, st := range svc.List(acmeCtx) {
+		if st.Key == featureflag.LSP {
+			lsp = st
+		}
+	}
+	if len(lsp.Overrides) != 2 || lsp.Overrides[0].Scope() != featureflag.ScopeGlobal || lsp.Overrides[1].ProjectID != "proj-2" {
+		t.Fatalf("expected the global and acme's own override, got %+v", lsp.Overrides)
+	}
+}
+
+func TestFeatureFlagService_Propagation(t *testing.T) {
+	kv := &memoryKV{}
+	a, store := newFeatureFlagTestEnv()
+	a.SetKeyValue(kv)
+	b := service.NewFeatureFlagService(store)
+	b.SetKeyValue(kv)
+	ctx := context.Background()
+
+	if _, err := a.Set(ctx, featureflag.AgenticConversations, &featureflag.SetRequest{TenantID: "acme", Enabled: false}); err != nil {
+		t.Fatal(err)
+	}
+	// b loads from the database and follows the bucket from then on.
+	if err := b.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.StartWatcher(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if b.Enabled(ctx, featureflag.AgenticConversations, "proj-2") {
+		t.Fatal("expected the loaded override")
+	}
+	if _, err := a.Set(ctx, featureflag.LSP, &featureflag.SetRequest{ProjectID: "proj-2", Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !b.Enabled(ctx, featureflag.LSP, "proj-2") {
+		t.Fatal("expected the override set on a to reach b")
+	}
+	if err := a.Delete(ctx, featureflag.AgenticConversations, "acme", ""); err != nil {
+		t.Fatal(err)
+	}
+	if !b.Enabled(ctx, featureflag.AgenticConversations, "proj-2") {
+		t.Fatal("expected the deletion on a to reach b")
+	}
+}
+
+func TestStartRun_FeatureFlags(t *testing.T) {
+	svc, store, queue, _ := newRuntimeTestEnv()
+	ws := t.TempDir()
+	for name, src := range map[string]string{
+		"go.mod":         "module example.com/app\n",
+		"domain/item.go": "package domain\n\ntype Item struct{}\n",
+		"main.go":        "package main\n\nimport \"example.com/app/domain\"\n\nvar _ domain.Item\n\nfunc main() {}\n",
+	} {
+		p := filepath.Join(ws, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.projects[0].WorkspacePath = ws
+	flags := service.NewFeatureFlagService(store)
+	svc.SetGraphService(service.NewGraphService(store, svc))
+	svc.SetFeatureFlagService(flags)
+	ctx := context.Background()
+
+	start := func() messagequeue.RunStartPayload {
+		t.Helper()
+		if _, err := svc.StartRun(ctx, &run.StartRequest{TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1"}); err != nil {
+			t.Fatalf("StartRun failed: %v", err)
+		}
+		msg, ok := queue.lastMessage(messagequeue.SubjectRunStart)
+		if !ok {
+			t.Fatal("expected run start message")
+		}
+		var payload messagequeue.RunStartPayload
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			t.Fatal(err)
+		}
+		return payload
+	}
+
+	if p := start(); len(p.Context) != 0 || p.Config["lsp"] != "" {
+		t.Fatalf("expected no repo map and no lsp by default, got %+v", p)
+	}
+	store.agents[0].Status = agent.StatusIdle
+	for _, key := range []featureflag.Key{featureflag.GraphRAG, featureflag.LSP} {
+		if _, err := flags.Set(ctx, key, &featureflag.SetRequest{ProjectID: "proj-1", Enabled: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := start()
+	if len(p.Context) != 1 || p.Context[0].Path != "repo-map" || !strings.HasPrefix(p.Context[0].Content, "domain/item.go (1 dependents)") {
+		t.Fatalf("expected the repo map in the context, got %+v", p.Context)
+	}
+	if p.Config["lsp"] != "true" {
+		t.Fatalf("expected lsp in the run config, got %v", p.Config)
+	}
+	if _, ok := store.agents[0].Config["lsp"]; ok {
+		t.Fatal("expected the agent config untouched")
+	}
+}
+
+func TestConversationService_AgenticFlag(t *testing.T) {
+	microSvc, store := newMicroagentTestEnv(t)
+	llm := &chatRecorder{}
+	srv := httptest.NewServer(llm)
+	defer srv.Close()
+
+	flags := service.NewFeatureFlagService(store)
+	svc := service.NewConversationService(store, litellm.NewClient(srv.URL, ""), &config.Conversation{Model: "chat-model", KeepRecent: 2})
+	svc.SetMicroagentService(microSvc)
+	svc.SetFeatureFlagService(flags)
+	ctx := context.Background()
+	if _, err := flags.Set(ctx, featureflag.AgenticConversations, &featureflag.SetRequest{ProjectID: "proj-1", Enabled: false}); err != nil {
+		t.Fatal(err)
+	}
+	c, err := svc.Create(ctx, "proj-1", conversation.CreateRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reply, err := svc.Send(ctx, c.ID, conversation.SendRequest{Content: "Why is there a Null Pointer here?"})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if len(reply.Microagents) != 0 || llm.requests[0].Messages[0].Role == "system" {
+		t.Fatalf("expected a plain chat without microagents, got %v", reply.Microagents)
+	}
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/github"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+// installationTokenMargin is how long before its expiry a cached
+// installation token is replaced, so that no git or API call outlives it.
+const installationTokenMargin = 10 * time.Minute
+
+// githubProvider is the registry name of the GitHub git provider.
+const githubProvider = "github"
+
+// GitHubAppService authenticates projects as installations of their
+// tenant's GitHub App and onboards the repositories an installation adds
+// as projects.
+type GitHubAppService struct {
+	store    database.Store
+	secrets  *SecretService
+	projects *ProjectService
+	now      func() time.Time
+
+	mu     sync.Mutex
+	tokens map[string]*github.InstallationToken // tenant ID + "/" + installation ID
+}
+
+// NewGitHubAppService creates a GitHubAppService. The apps' keys are
+// tenant-wide secrets resolved through secrets; onboarded projects are
+// created through projects, against the tenant's quota.
+func NewGitHubAppService(store database.Store, secrets *SecretService, projects *ProjectService) *GitHubAppService {
+	return &GitHubAppService{
+		store:    store,
+		secrets:  secrets,
+		projects: projects,
+		now:      time.Now,
+		tokens:   make(map[string]*github.InstallationToken),
+	}
+}
+
+// Set replaces the GitHub App of a tenant. The private key secret must
+// exist and hold an RSA key.
+func (s *GitHubAppService) Set(ctx context.Context, tenantID string, app *tenant.GitHubApp) (*tenant.Tenant, error) {
+	if err := app.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := s.store.GetTenant(ctx, tenantID); err != nil {
+		return nil, err
+	}
+	if _, err := s.client(ctx, tenantID, app); err != nil {
+		return nil, fmt.Errorf("%w: %w", tenant.ErrInvalidGitHubApp, err)
+	}
+	if err := s.store.SetTenantGitHubApp(ctx, tenantID, app); err != nil {
+		return nil, err
+	}
+	s.forget(tenantID)
+	slog.Info("tenant github app set", "tenant_id", tenantID, "app_id", app.AppID)
+	return s.store.GetTenant(ctx, tenantID)
+}
+
+// Delete removes the GitHub App of a tenant. Its projects fall back to
+// their token secrets.
+func (s *GitHubAppService) Delete(ctx context.Context, tenantID string) error {
+	if err := s.store.SetTenantGitHubApp(ctx, tenantID, nil); err != nil {
+		return err
+	}
+	s.forget(tenantID)
+	slog.Info("tenant github app removed", "tenant_id", tenantID)
+	return nil
+}
+
+// Token returns an installation token for a GitHub project that names an
+// installation of its tenant's app, and "" for other projects. Tokens are
+// cached until shortly before they expire. A nil service has no tokens.
+func (s *GitHubAppService) Token(ctx context.Context, p *project.Project) (string, error) {
+	ref := p.Config[github.ConfigInstallationID]
+	if s == nil || p.Provider != githubProvider || ref == "" {
+		return "", nil
+	}
+	installationID, err := strconv.ParseInt(ref, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("project %s: invalid %s %q", p.ID, github.ConfigInstallationID, ref)
+	}
+	tenantID := tenantOf(p)
+	key := tenantID + "/" + ref
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tok, ok := s.tokens[key]; ok && s.now().Add(installationTokenMargin).Before(tok.ExpiresAt) {
+		return tok.Token, nil
+	}
+	t, err := s.store.GetTenant(ctx, tenantID)
+	if err != nil {
+		return "", fmt.Errorf("get tenant: %w", err)
+	}
+	if t.GitHubApp == nil {
+		return "", fmt.Errorf("tenant %s has no github app: %w", tenantID, domain.ErrNotFound)
+	}
+	client, err := s.client(ctx, tenantID, t.GitHubApp)
+	if err != nil {
+		return "", err
+	}
+	tok, err := client.InstallationToken(ctx, installationID)
+	if err != nil {
+		return "", err
+	}
+	s.tokens[key] = tok
+	return tok.Token, nil
+}
+
+// HandleWebhook processes an installation webhook of a tenant's GitHub App.
+// Repositories the installation adds become GitHub projects of the tenant
+// authenticating as the installation (existing projects of a repository are
+// switched to it); repositories it removes lose the installation. It
+// returns the number of projects created.
+func (s *GitHubAppService) HandleWebhook(ctx context.Context, tenantID string, header http.Header, body []byte) (int, error) {
+	t, err := s.store.GetTenant(ctx, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	if t.GitHubApp == nil || t.GitHubApp.WebhookSecret == "" {
+		return 0, gitprovider.ErrInvalidSignature
+	}
+	ctx = tenant.NewContext(ctx, tenantID)
+	vals, err := s.secrets.Resolve(ctx, "", []string{t.GitHubApp.WebhookSecret})
+	if err != nil {
+		return 0, fmt.Errorf("resolve webhook secret: %w", err)
+	}
+	ev, err := github.ParseInstallationWebhook(vals[t.GitHubApp.WebhookSecret], header, body)
+	if errors.Is(err, gitprovider.ErrIgnoredEvent) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	projects, err := s.store.ListProjects(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list projects: %w", err)
+	}
+	installation := strconv.FormatInt(ev.InstallationID, 10)
+	created := 0
+	for _, repo := range ev.Added {
+		matched := false
+		for i := range projects {
+			p := &projects[i]
+			if !s.ownsRepo(t.ID, p, repo) {
+				continue
+			}
+			matched = true
+			s.setInstallation(ctx, p, installation)
+		}
+		if matched {
+			continue
+		}
+		p, err := s.projects.Create(ctx, project.CreateRequest{
+			TenantID: t.ID,
+			Name:     repo,
+			RepoURL:  repoURL(t.GitHubApp.APIURL, repo),
+			Provider: githubProvider,
+			Config:   onboardConfig(t.GitHubApp, repo, installation),
+		})
+		if err != nil {
+			return created, fmt.Errorf("onboard %s: %w", repo, err)
+		}
+		created++
+		slog.Info("github app repository onboarded", "tenant_id", t.ID, "repo", repo, "project_id", p.ID)
+	}
+	for _, repo := range ev.Removed {
+		for i := range projects {
+			if p := &projects[i]; s.ownsRepo(t.ID, p, repo) && p.Config[github.ConfigInstallationID] == installation {
+				s.setInstallation(ctx, p, "")
+			}
+		}
+	}
+	return created, nil
+}
+
+// client returns the GitHub App client of a tenant's app.
+func (s *GitHubAppService) client(ctx context.Context, tenantID string, app *tenant.GitHubApp) (*github.App, error) {
+	vals, err := s.secrets.Resolve(tenant.NewContext(ctx, tenantID), "", []string{app.PrivateKeySecret})
+	if err != nil {
+		return nil, fmt.Errorf("resolve private key: %w", err)
+	}
+	return github.NewApp(app.APIURL, app.AppID, []byte(vals[app.PrivateKeySecret]))
+}
+
+// forget drops the cached installation tokens of a tenant.
+func (s *GitHubAppService) forget(tenantID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.tokens {
+		if strings.HasPrefix(key, tenantID+"/") {
+			delete(s.tokens, key)
+		}
+	}
+}
+
+// ownsRepo reports whether p is a GitHub project of the tenant for repo.
+func (s *GitHubAppService) ownsRepo(tenantID string, p *project.Project, repo string) bool {
+	return tenantOf(p) == tenantID && p.Provider == githubProvider && strings.EqualFold(p.Config[github.ConfigRepo], repo)
+}
+
+// setInstallation points a project at an installation, or with an empty
+// installation removes it. Failures are logged.
+func (s *GitHubAppService) setInstallation(ctx context.Context, p *project.Project, installation string) {
+	if p.Config[github.ConfigInstallationID] == installation {
+		return
+	}
+	p.Config = maps.Clone(p.Config)
+	if installation == "" {
+		delete(p.Config, github.ConfigInstallationID)
+	} else {
+		p.Config[github.ConfigInstallationID] = installation
+	}
+	if err := s.store.UpdateProject(ctx, p); err != nil {
+		slog.Error("github app: update project installation", "project_id", p.ID, "error", err)
+		return
+	}
+	slog.Info("github app installation updated", "project_id", p.ID, "installation", installation)
+}
+
+// onboardConfig returns the config of a project onboarded for repo.
+func onboardConfig(app *tenant.GitHubApp, repo, installation string) map[string]string {
+	cfg := map[string]string{
+		github.ConfigRepo:           repo,
+		github.ConfigInstallationID: installation,
+	}
+	if app.APIURL != "" {
+		cfg[github.ConfigAPIURL] = app.APIURL
+	}
+	return cfg
+}
+
+// repoURL returns the HTTPS clone URL of repo on github.com, or on the
+// GitHub Enterprise host of apiURL (https://host/api/v3).
+func repoURL(apiURL, repo string) string {
+	host := "https://github.com"
+	if apiURL != "" {
+		host = strings.TrimSuffix(strings.TrimRight(apiURL, "/"), "/api/v3")
+	}
+	return host + "/" + repo + ".git"
+}
@@ -0,0 +1,120 @@
+package service_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/github"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/secret"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestGitHubAppService_OnboardsAndMintsTokens(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	minted := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/app/installations/7/access_tokens" {
+			http.NotFound(w, r)
+			return
+		}
+		minted++
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{"token": fmt.Sprintf("ghs_%d", minted), "expires_at": time.Now().Add(time.Hour)})
+	}))
+	defer srv.Close()
+
+	_, store, _, _ := newRuntimeTestEnv()
+	store.tenants = append(store.tenants, tenant.Tenant{ID: "acme", Name: "Acme"})
+	store.projects = append(store.projects, project.Project{
+		ID: "proj-api", TenantID: "acme", Name: "api", Provider: "github",
+		Config: map[string]string{github.ConfigRepo: "Acme/API"},
+	})
+	secrets := newTestSecretService(t, store)
+	ctx := context.Background()
+	acme := tenant.NewContext(ctx, "acme")
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	for name, value := range map[string]string{"GH_APP_KEY": string(pemKey), "GH_APP_WEBHOOK": "s3cret"} {
+		if _, err := secrets.Create(acme, "", &secret.CreateRequest{Name: name, Value: value}); err != nil {
+			t.Fatalf("create secret %s: %v", name, err)
+		}
+	}
+	svc := service.NewGitHubAppService(store, secrets, service.NewProjectService(store))
+
+	if _, err := svc.Set(ctx, "acme", &tenant.GitHubApp{AppID: 42, PrivateKeySecret: "MISSING"}); !errors.Is(err, tenant.ErrInvalidGitHubApp) {
+		t.Fatalf("expected ErrInvalidGitHubApp for an unknown key secret, got %v", err)
+	}
+	app := &tenant.GitHubApp{AppID: 42, PrivateKeySecret: "GH_APP_KEY", WebhookSecret: "GH_APP_WEBHOOK", APIURL: srv.URL + "/api/v3"}
+	if _, err := svc.Set(ctx, "acme", app); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	// webhook delivers a signed installation event.
+	webhook := func(secret, event, body string) (int, error) {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		h := http.Header{}
+		h.Set("X-GitHub-Event", event)
+		h.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		return svc.HandleWebhook(ctx, "acme", h, []byte(body))
+	}
+
+	installed := `{"action":"created","installation":{"id":7},"repositories":[{"full_name":"acme/webapp"},{"full_name":"acme/api"}]}`
+	if _, err := webhook("wrong", "installation", installed); !errors.Is(err, gitprovider.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+	created, err := webhook("s3cret", "installation", installed)
+	if err != nil || created != 1 {
+		t.Fatalf("expected one project onboarded, got %d, %v", created, err)
+	}
+	onboarded := store.projects[len(store.projects)-1]
+	if onboarded.TenantID != "acme" || onboarded.Provider != "github" || onboarded.RepoURL != srv.URL+"/acme/webapp.git" ||
+		onboarded.Config[github.ConfigRepo] != "acme/webapp" || onboarded.Config[github.ConfigInstallationID] != "7" ||
+		onboarded.Config[github.ConfigAPIURL] != app.APIURL {
+		t.Fatalf("unexpected onboarded project %+v", onboarded)
+	}
+	existing, _ := store.GetProject(ctx, "proj-api")
+	if existing.Config[github.ConfigInstallationID] != "7" {
+		t.Fatalf("expected the existing project switched to the installation, got %+v", existing.Config)
+	}
+
+	for range 2 {
+		token, err := svc.Token(ctx, &onboarded)
+		if err != nil || token != "ghs_1" {
+			t.Fatalf("expected the cached installation token, got %q, %v", token, err)
+		}
+	}
+	if minted != 1 {
+		t.Fatalf("expected one token minted, got %d", minted)
+	}
+	if token, err := svc.Token(ctx, &store.projects[0]); err != nil || token != "" {
+		t.Fatalf("expected no token for a project without installation, got %q, %v", token, err)
+	}
+
+	removed := `{"action":"removed","installation":{"id":7},"repositories_removed":[{"full_name":"acme/api"}]}`
+	if _, err := webhook("s3cret", "installation_repositories", removed); err != nil {
+		t.Fatalf("HandleWebhook failed: %v", err)
+	}
+	existing, _ = store.GetProject(ctx, "proj-api")
+	if _, ok := existing.Config[github.ConfigInstallationID]; ok {
+		t.Fatalf("expected the installation removed from the project, got %+v", existing.Config)
+	}
+}
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/codegraph"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/tagger"
+)
+
+// Workspace scan limits for the code graph.
+const (
+	maxGraphFiles    = 20000
+	maxGraphFileSize = 512 * 1024
+)
+
+// graphSkipDirs are directories never scanned for the code graph.
+var graphSkipDirs = map[string]bool{
+	"node_modules": true, "vendor": true, "__pycache__": true, "dist": true,
+	"build": true, "target": true, "venv": true, "coverage": true,
+}
+
+// GraphService builds the dependency graph of a project workspace and
+// answers impact queries over it. A graph built with Build is persisted
+// and kept current by Update; projects without one have their workspace
+// parsed on every query.
+type GraphService struct {
+	store   database.Store
+	runtime *RuntimeService
+	parser  *codegraph.Parser
+	tagger  tagger.Tagger // nil disables the ctags fallback
+}
+
+// NewGraphService creates a GraphService that parses files with all
+// registered grammars. runtime resolves the files touched by a run; it may
+// be nil, in which case impact requests must name files.
+func NewGraphService(store database.Store, runtime *RuntimeService) *GraphService {
+	parser, _ := codegraph.NewParser(nil)
+	return &GraphService{store: store, runtime: runtime, parser: parser}
+}
+
+// SetParser limits the graph to the grammars of p.
+func (s *GraphService) SetParser(p *codegraph.Parser) {
+	s.parser = p
+}
+
+// SetTagger sets the tagger that extracts the declarations of files no
+// enabled grammar handles.
+func (s *GraphService) SetTagger(t tagger.Tagger) {
+	s.tagger = t
+}
+
+// Impact returns the downstream files, symbols and test targets affected by
+// the changed files of req. For a run, the graph is built from the run's
+// workspace so files it created are known.
+func (s *GraphService) Impact(ctx context.Context, projectID string, req *codegraph.ImpactRequest) (*codegraph.Impact, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	proj, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	root := proj.WorkspacePath
+
+	files := req.Files
+	if req.RunID != "" {
+		if s.runtime == nil {
+			return nil, fmt.Errorf("impact of runs is not available")
+		}
+		r, err := s.store.GetRun(ctx, req.RunID)
+		if err != nil {
+			return nil, err
+		}
+		if r.ProjectID != projectID {
+			return nil, fmt.Errorf("run %s: %w", r.ID, domain.ErrNotFound)
+		}
+		root = r.Workspace(proj.WorkspacePath)
+		touched, err := s.runtime.FilesTouched(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range touched {
+			files = append(files, relWorkspacePath(root, p))
+		}
+	}
+	if root == "" {
+		return nil, fmt.Errorf("project %s has no workspace (not cloned)", projectID)
+	}
+
+	var g *codegraph.Graph
+	if req.RunID != "" {
+		g, err = s.buildGraph(ctx, root)
+	} else {
+		g, err = s.graphOf(ctx, proj)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return g.Impact(files, req.MaxDepth), nil
+}
+
+// RepoMap returns the most depended-on files of a project's workspace with
+// their exported declarations, see codegraph.Graph.RepoMap.
+func (s *GraphService) RepoMap(ctx context.Context, projectID string, maxFiles int) (*codegraph.RepoMap, error) {
+	g, err := s.projectGraph(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return g.RepoMap(maxFiles), nil
+}
+
+// RepoMapStatus returns the enabled grammars, whether the ctags fallback is
+// active and the files and declarations per language of a project's repo
+// map.
+func (s *GraphService) RepoMapStatus(ctx context.Context, projectID string) (*codegraph.RepoMapStatus, error) {
+	g, err := s.projectGraph(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return &codegraph.RepoMapStatus{
+		Grammars:  s.parser.Grammars(),
+		Ctags:     s.tagger != nil,
+		Languages: g.Languages(),
+	}, nil
+}
+
+// projectGraph returns the graph of a project's workspace, see graphOf.
+func (s *GraphService) projectGraph(ctx context.Context, projectID string) (*codegraph.Graph, error) {
+	proj, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return s.graphOf(ctx, proj)
+}
+
+// graphOf loads the persisted graph of a project, or builds the graph of
+// its workspace if none was persisted.
+func (s *GraphService) graphOf(ctx context.Context, proj *project.Project) (*codegraph.Graph, error) {
+	st, err := s.store.GetCodeGraph(ctx, proj.ID)
+	switch {
+	case err == nil:
+		files, err := s.store.ListCodeGraphFiles(ctx, proj.ID)
+		if err != nil {
+			return nil, err
+		}
+		return codegraph.New(st.GoModule, files), nil
+	case !errors.Is(err, domain.ErrNotFound):
+		return nil, err
+	}
+	if proj.WorkspacePath == "" {
+		return nil, fmt.Errorf("project %s has no workspace (not cloned)", proj.ID)
+	}
+	return s.buildGraph(ctx, proj.WorkspacePath)
+}
+
+// buildGraph parses the files below root with the enabled grammars and tags
+// the other files with the tagger, if set. Hidden and dependency
+// directories are skipped.
+func (s *GraphService) buildGraph(ctx context.Context, root string) (*codegraph.Graph, error) {
+	var rels []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Unreadable entries are skipped
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if p != root && (strings.HasPrefix(name, ".") || graphSkipDirs[name]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if len(rels) >= maxGraphFiles {
+			return fs.SkipAll
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxGraphFileSize {
+			return nil
+		}
+		if s.parser.LanguageOf(name) == "" && s.tagger == nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, p)
+		rels = append(rels, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan workspace: %w", err)
+	}
+	if len(rels) >= maxGraphFiles {
+		slog.Warn("code graph truncated", "root", root, "files", maxGraphFiles)
+	}
+	return codegraph.New(goModulePath(root), s.parseFiles(ctx, root, rels)), nil
+}
+
+// parseFiles parses the given files below root (slash-separated, relative)
+// with the enabled grammars and tags the others with the tagger, if set.
+// Missing, oversized and unreadable files are left out, as are files in
+// hidden or dependency directories. A failing tagger only costs the
+// declarations of the files it would have tagged.
+func (s *GraphService) parseFiles(ctx context.Context, root string, rels []string) []*codegraph.File {
+	var files []*codegraph.File
+	var others []string // Files for the tagger
+	for _, rel := range rels {
+		if !graphPath(rel) {
+			continue
+		}
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		info, err := os.Stat(p)
+		if err != nil || !info.Mode().IsRegular() || info.Size() > maxGraphFileSize {
+			continue
+		}
+		if s.parser.LanguageOf(path.Base(rel)) == "" {
+			if s.tagger != nil {
+				others = append(others, rel)
+			}
+			continue
+		}
+		src, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		files = append(files, s.parser.Parse(rel, src))
+	}
+	if len(others) > 0 {
+		tags, err := s.tagger.Tag(ctx, root, others)
+		if err != nil {
+			slog.Warn("ctags fallback failed", "root", root, "error", err)
+		}
+		files = append(files, taggedFiles(tags)...)
+	}
+	return files
+}
+
+// graphPath reports whether a workspace file belongs in the code graph: no
+// directory on its path is hidden or a dependency directory.
+func graphPath(rel string) bool {
+	dirs := strings.Split(rel, "/")
+	for _, d := range dirs[:len(dirs)-1] {
+		if strings.HasPrefix(d, ".") || graphSkipDirs[d] {
+			return false
+		}
+	}
+	return true
+}
+
+// taggedFiles groups tags into graph files. Their language is the tagger's
+// language name in lower case.
+func taggedFiles(tags []tagger.Tag) []*codegraph.File {
+	byPath := make(map[string]*codegraph.File)
+	var files []*codegraph.File
+	for _, t := range tags {
+		f := byPath[t.Path]
+		if f == nil {
+			f = &codegraph.File{Path: t.Path, Language: codegraph.Language(strings.ToLower(t.Language)), Tagged: true}
+			byPath[t.Path] = f
+			files = append(files, f)
+		}
+		f.Symbols = append(f.Symbols, codegraph.Symbol{Name: t.Name, Kind: t.Kind, Path: t.Path, Line: t.Line})
+	}
+	return files
+}
+
+// goModulePath returns the module path declared in root/go.mod, or "".
+func goModulePath(root string) string {
+	f, err := os.Open(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(sc.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(rest), `"`)
+		}
+	}
+	return ""
+}
+
+// relWorkspacePath turns a path recorded by an agent, which may be absolute,
+// into a slash-separated path relative to the workspace.
+func relWorkspacePath(root, p string) string {
+	if filepath.IsAbs(p) && root != "" {
+		if rel, err := filepath.Rel(root, p); err == nil && !strings.HasPrefix(rel, "..") {
+			p = rel
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(p))
+}
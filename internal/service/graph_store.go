@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/codegraph"
+)
+
+// Build parses a project's workspace and persists its graph, replacing
+// the one stored before.
+func (s *GraphService) Build(ctx context.Context, projectID string) (*codegraph.State, error) {
+	proj, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if proj.WorkspacePath == "" {
+		return nil, fmt.Errorf("project %s has no workspace (not cloned)", projectID)
+	}
+	module := goModulePath(proj.WorkspacePath)
+	g, err := s.buildGraph(ctx, proj.WorkspacePath)
+	if err != nil {
+		return nil, err
+	}
+	edges := g.Edges()
+	st := &codegraph.State{ProjectID: projectID, GoModule: module, Files: g.Len(), Edges: len(edges)}
+	if err := s.store.ReplaceCodeGraph(ctx, st, g.Files(), edges); err != nil {
+		return nil, err
+	}
+	slog.Info("code graph built", "project_id", projectID, "files", st.Files, "edges", st.Edges)
+	return st, nil
+}
+
+// Update reparses the given files (slash-separated, relative to the
+// workspace) in a project's persisted graph, e.g. after they were edited;
+// deleted files leave the graph. Only the changed files are parsed and
+// only the edges that changed with them are written. Projects without a
+// persisted graph are left alone and return nil.
+func (s *GraphService) Update(ctx context.Context, projectID string, paths []string) (*codegraph.State, error) {
+	st, err := s.store.GetCodeGraph(ctx, projectID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	proj, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	root := proj.WorkspacePath
+	if root == "" {
+		return nil, fmt.Errorf("project %s has no workspace (not cloned)", projectID)
+	}
+	stored, err := s.store.ListCodeGraphFiles(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	prevEdges, err := s.store.ListCodeGraphEdges(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rels []string
+	for _, p := range paths {
+		if rel := relWorkspacePath(root, p); !slices.Contains(rels, rel) {
+			rels = append(rels, rel)
+		}
+	}
+	parsed := s.parseFiles(ctx, root, rels)
+	byPath := make(map[string]*codegraph.File, len(stored)+len(parsed))
+	for _, f := range stored {
+		byPath[f.Path] = f
+	}
+	u := &codegraph.Update{Files: parsed}
+	fresh := make(map[string]bool, len(parsed))
+	for _, f := range parsed {
+		fresh[f.Path] = true
+		byPath[f.Path] = f
+	}
+	for _, rel := range rels {
+		if _, ok := byPath[rel]; ok && !fresh[rel] {
+			u.Deleted = append(u.Deleted, rel)
+			delete(byPath, rel)
+		}
+	}
+	if slices.Contains(rels, "go.mod") {
+		st.GoModule = goModulePath(root)
+	}
+
+	files := make([]*codegraph.File, 0, len(byPath))
+	for _, f := range byPath {
+		files = append(files, f)
+	}
+	g := codegraph.New(st.GoModule, files)
+	edges := g.Edges()
+	u.Added, u.Removed = codegraph.DiffEdges(prevEdges, edges)
+	st.Files, st.Edges = g.Len(), len(edges)
+	if err := s.store.UpdateCodeGraph(ctx, st, u); err != nil {
+		return nil, err
+	}
+	slog.Info("code graph updated", "project_id", projectID,
+		"parsed", len(u.Files), "deleted", len(u.Deleted), "edges_added", len(u.Added), "edges_removed", len(u.Removed))
+	return st, nil
+}
+
+// State returns the state of a project's persisted graph.
+func (s *GraphService) State(ctx context.Context, projectID string) (*codegraph.State, error) {
+	return s.store.GetCodeGraph(ctx, projectID)
+}
+
+// Neighbors returns the files a file of a project's persisted graph
+// directly depends on and those directly depending on it.
+func (s *GraphService) Neighbors(ctx context.Context, projectID, path string) (*codegraph.Neighbors, error) {
+	if _, err := s.store.GetCodeGraph(ctx, projectID); err != nil {
+		return nil, err
+	}
+	return s.store.GetCodeGraphNeighbors(ctx, projectID, relWorkspacePath("", path))
+}
+
+// Path returns the shortest chain of dependencies from one file of a
+// project's persisted graph to another.
+func (s *GraphService) Path(ctx context.Context, projectID, from, to string) (*codegraph.DependencyPath, error) {
+	if _, err := s.store.GetCodeGraph(ctx, projectID); err != nil {
+		return nil, err
+	}
+	from, to = relWorkspacePath("", from), relWorkspacePath("", to)
+	for _, p := range []string{from, to} {
+		if _, err := s.store.GetCodeGraphNeighbors(ctx, projectID, p); err != nil {
+			return nil, err
+		}
+	}
+	edges, err := s.store.ListCodeGraphEdges(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	path := codegraph.ShortestPath(edges, from, to)
+	if path == nil {
+		path = []string{}
+	}
+	return &codegraph.DependencyPath{From: from, To: to, Path: path}, nil
+}
@@ -0,0 +1,260 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	_ "github.com/Strob0t/CodeForge/internal/adapter/treesitter"
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/codegraph"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/tagger"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func writeWorkspace(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for rel, src := range files {
+		p := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestGraphImpact(t *testing.T) {
+	dir := writeWorkspace(t, map[string]string{
+		"go.mod":                       "module example.com/app\n\ngo 1.24\n",
+		"domain/item.go":               "package domain\n\ntype Item struct{}\n",
+		"service/svc.go":               "package service\n\nimport \"example.com/app/domain\"\n\nvar _ domain.Item\n",
+		"service/svc_test.go":          "package service\n",
+		"node_modules/pkg/index.js":    "require('../../domain')\n",
+		".git/hooks/pre-commit.py":     "import os\n",
+		"unrelated/unrelated.go":       "package unrelated\n",
+		"unrelated/unrelated_test.go":  "package unrelated\n",
+		"service/internal/deep/gen.go": "package deep\n",
+	})
+	store := &runtimeMockStore{projects: []project.Project{{ID: "proj-1", WorkspacePath: dir}}}
+	svc := service.NewGraphService(store, nil)
+
+	impact, err := svc.Impact(context.Background(), "proj-1", &codegraph.ImpactRequest{Files: []string{"./domain/item.go"}})
+	if err != nil {
+		t.Fatalf("Impact failed: %v", err)
+	}
+	if len(impact.Files) != 2 || impact.Files[0].Path != "service/svc.go" || impact.Files[0].Via != "domain/item.go" {
+		t.Fatalf("unexpected impacted files %+v", impact.Files)
+	}
+	if len(impact.Tests) != 1 || impact.Tests[0].Target != "./service" {
+		t.Fatalf("unexpected test targets %+v", impact.Tests)
+	}
+
+	if _, err := svc.Impact(context.Background(), "proj-1", &codegraph.ImpactRequest{RunID: "run-1"}); err == nil {
+		t.Fatal("expected error for run impact without runtime")
+	}
+	if _, err := svc.Impact(context.Background(), "proj-1", &codegraph.ImpactRequest{}); !errors.Is(err, codegraph.ErrImpactInput) {
+		t.Fatalf("expected ErrImpactInput, got %v", err)
+	}
+}
+
+func TestGraphImpact_Run(t *testing.T) {
+	dir := writeWorkspace(t, map[string]string{
+		"src/api.ts":      "export function call() {}\n",
+		"src/app.ts":      "import { call } from './api';\n",
+		"src/app.test.ts": "import './app';\n",
+	})
+	_, store, _, _ := newRuntimeTestEnv()
+	es := &runtimeMockEventStore{}
+	runtimeSvc := service.NewRuntimeService(store, &runtimeMockQueue{}, &runtimeMockBroadcaster{}, es,
+		service.NewPolicyService("headless-safe-sandbox", nil), &config.Runtime{})
+	store.runs = append(store.runs,
+		run.Run{ID: "run-1", ProjectID: "proj-1", WorktreePath: dir},
+		run.Run{ID: "run-2", ProjectID: "proj-2"},
+	)
+	_ = es.Append(context.Background(), &event.AgentEvent{
+		RunID: "run-1", Type: event.TypeToolCallApproved,
+		Payload: []byte(`{"tool":"Edit","path":"` + filepath.ToSlash(filepath.Join(dir, "src", "api.ts")) + `"}`),
+	})
+	svc := service.NewGraphService(store, runtimeSvc)
+
+	impact, err := svc.Impact(context.Background(), "proj-1", &codegraph.ImpactRequest{RunID: "run-1"})
+	if err != nil {
+		t.Fatalf("Impact failed: %v", err)
+	}
+	if len(impact.Changed) != 1 || impact.Changed[0] != "src/api.ts" {
+		t.Fatalf("expected run's edited file as changed, got %v", impact.Changed)
+	}
+	if len(impact.Tests) != 1 || impact.Tests[0].Target != "src/app.test.ts" || impact.Tests[0].Depth != 2 {
+		t.Fatalf("unexpected test targets %+v", impact.Tests)
+	}
+
+	if _, err := svc.Impact(context.Background(), "proj-1", &codegraph.ImpactRequest{RunID: "run-2"}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a foreign run, got %v", err)
+	}
+}
+
+// fakeTagger tags every file it is given with one function, naming the
+// language after the file extension.
+type fakeTagger struct {
+	files []string
+	err   error
+}
+
+func (f *fakeTagger) Tag(_ context.Context, _ string, files []string) ([]tagger.Tag, error) {
+	f.files = files
+	if f.err != nil {
+		return nil, f.err
+	}
+	var tags []tagger.Tag
+	for _, p := range files {
+		tags = append(tags, tagger.Tag{Path: p, Language: strings.TrimPrefix(filepath.Ext(p), "."), Name: "run", Kind: "function", Line: 1})
+	}
+	return tags, nil
+}
+
+func TestGraphRepoMapStatus(t *testing.T) {
+	dir := writeWorkspace(t, map[string]string{
+		"main.go":            "package main\n\nfunc Run() {}\n",
+		"lib/billing.rs":     "pub fn charge() {}\n",
+		"app/billing.rb":     "def run; end\n",
+		"node_modules/x.rb":  "def skipped; end\n",
+		"infra/main.tf":      "output \"url\" {}\n",
+		"Sources/App.swift":  "public struct App {}\n",
+		"vendor/lib/lib.swi": "skipped\n",
+	})
+	store := &runtimeMockStore{projects: []project.Project{{ID: "proj-1", WorkspacePath: dir}}}
+	svc := service.NewGraphService(store, nil)
+	parser, err := codegraph.NewParser([]string{"go", "rust", "terraform"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.SetParser(parser)
+	tg := &fakeTagger{}
+	svc.SetTagger(tg)
+
+	st, err := svc.RepoMapStatus(context.Background(), "proj-1")
+	if err != nil {
+		t.Fatalf("RepoMapStatus failed: %v", err)
+	}
+	slices.Sort(tg.files)
+	if !slices.Equal(tg.files, []string{"Sources/App.swift", "app/billing.rb"}) {
+		t.Fatalf("unexpected files for the tagger %v", tg.files)
+	}
+	if !st.Ctags || !slices.Equal(st.Grammars, []string{"go", "rust", "terraform"}) {
+		t.Fatalf("unexpected status %+v", st)
+	}
+	want := []codegraph.LanguageStats{
+		{Language: codegraph.LangGo, Source: codegraph.SourceGrammar, Files: 1, Symbols: 1},
+		{Language: "rb", Source: codegraph.SourceCtags, Files: 1, Symbols: 1},
+		{Language: codegraph.LangRust, Source: codegraph.SourceGrammar, Files: 1, Symbols: 1},
+		{Language: "swift", Source: codegraph.SourceCtags, Files: 1, Symbols: 1},
+		{Language: codegraph.LangTerraform, Source: codegraph.SourceGrammar, Files: 1, Symbols: 1},
+	}
+	if !slices.Equal(st.Languages, want) {
+		t.Fatalf("got %+v, want %+v", st.Languages, want)
+	}
+
+	// A failing tagger degrades to the grammars' files.
+	tg.err = errors.New("ctags crashed")
+	m, err := svc.RepoMap(context.Background(), "proj-1", 0)
+	if err != nil {
+		t.Fatalf("RepoMap failed: %v", err)
+	}
+	if m.Total != 3 {
+		t.Fatalf("expected 3 files without the tagger's, got %d", m.Total)
+	}
+}
+
+func TestGraphPersistence(t *testing.T) {
+	dir := writeWorkspace(t, map[string]string{
+		"go.mod":         "module example.com/app\n\ngo 1.24\n",
+		"main.go":        "package main\n\nimport \"example.com/app/store\"\n",
+		"store/store.go": "package store\n\nfunc Open() {}\n",
+		"api/api.go":     "package api\n",
+	})
+	store := &runtimeMockStore{projects: []project.Project{{ID: "proj-1", WorkspacePath: dir}}}
+	svc := service.NewGraphService(store, nil)
+	ctx := context.Background()
+
+	if st, err := svc.Update(ctx, "proj-1", []string{"main.go"}); st != nil || err != nil {
+		t.Fatalf("expected projects without a graph left alone, got %+v, %v", st, err)
+	}
+	if _, err := svc.Neighbors(ctx, "proj-1", "main.go"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before building, got %v", err)
+	}
+	st, err := svc.Build(ctx, "proj-1")
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if st.GoModule != "example.com/app" || st.Files != 3 || st.Edges != 1 {
+		t.Fatalf("unexpected state %+v", st)
+	}
+
+	// The API comes to use the store; the graph learns it from the two
+	// changed files only.
+	writeFile(t, dir, "api/api.go", "package api\n\nimport \"example.com/app/store\"\n")
+	writeFile(t, dir, "api/routes.go", "package api\n")
+	if st, err = svc.Update(ctx, "proj-1", []string{"api/api.go", filepath.Join(dir, "api", "routes.go")}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if st.Files != 4 || st.Edges != 4 {
+		t.Fatalf("unexpected state after update %+v", st)
+	}
+	n, err := svc.Neighbors(ctx, "proj-1", "store/store.go")
+	if err != nil {
+		t.Fatalf("neighbors: %v", err)
+	}
+	if !slices.Equal(n.Dependents, []string{"api/api.go", "main.go"}) || len(n.Dependencies) != 0 {
+		t.Fatalf("unexpected neighbors %+v", n)
+	}
+	dp, err := svc.Path(ctx, "proj-1", "api/routes.go", "./store/store.go")
+	if err != nil {
+		t.Fatalf("path: %v", err)
+	}
+	if !slices.Equal(dp.Path, []string{"api/routes.go", "api/api.go", "store/store.go"}) {
+		t.Fatalf("unexpected path %+v", dp)
+	}
+
+	// Deleted files leave the graph with their edges.
+	if err := os.Remove(filepath.Join(dir, "api", "api.go")); err != nil {
+		t.Fatal(err)
+	}
+	if st, err = svc.Update(ctx, "proj-1", []string{"api/api.go"}); err != nil || st.Files != 3 || st.Edges != 1 {
+		t.Fatalf("unexpected state after delete %+v, %v", st, err)
+	}
+	if dp, _ = svc.Path(ctx, "proj-1", "api/routes.go", "store/store.go"); len(dp.Path) != 0 {
+		t.Fatalf("expected no path after delete, got %v", dp.Path)
+	}
+	if _, err := svc.Neighbors(ctx, "proj-1", "api/api.go"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a deleted file, got %v", err)
+	}
+
+	// Queries use the persisted graph instead of parsing the workspace.
+	if err := os.MkdirAll(filepath.Join(dir, "extra"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "extra/extra.go", "package extra\n")
+	m, err := svc.RepoMap(ctx, "proj-1", 0)
+	if err != nil || m.Total != 3 {
+		t.Fatalf("expected the repo map of the persisted graph, got %+v, %v", m, err)
+	}
+}
+
+func writeFile(t *testing.T, dir, rel, src string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(rel)), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/sandbox"
+)
+
+// Image build sandbox settings.
+const (
+	// ImageDigestMarker prefixes the line a build prints with the digest of
+	// the image it pushed. Builds that print none failed.
+	ImageDigestMarker = "CODEFORGE_IMAGE_DIGEST="
+	// imageAuthEnv hands the build the Docker config with the registry
+	// credentials, which it writes to imageDockerConfig.
+	imageAuthEnv      = "CODEFORGE_REGISTRY_AUTH"
+	imageDockerConfig = "/tmp/codeforge-docker"
+	// imageBuildTail is how many output lines a failed build reports.
+	imageBuildTail = 20
+	// defaultRegistryUsername authenticates specs without a username;
+	// registries authenticating by token accept any.
+	defaultRegistryUsername = "codeforge"
+)
+
+var imageDigestLine = regexp.MustCompile(`^` + ImageDigestMarker + `(sha256:[0-9a-f]{64})\s*$`)
+
+// ImageBuildService builds the container images of image plan steps from
+// the workspace of a run in a sandbox, pushes them to their registry with
+// credentials from the project's secrets and records their digests as
+// artifacts of the run.
+type ImageBuildService struct {
+	store   database.Store
+	secrets *SecretService
+	driver  sandbox.Driver
+	cfg     *config.Sandbox
+}
+
+// NewImageBuildService creates an ImageBuildService that builds in
+// sandboxes launched with driver.
+func NewImageBuildService(store database.Store, secrets *SecretService, driver sandbox.Driver, cfg *config.Sandbox) *ImageBuildService {
+	return &ImageBuildService{store: store, secrets: secrets, driver: driver, cfg: cfg}
+}
+
+// Build builds the image of spec from the workspace of run r in a sandbox
+// named after the image step, pushes it and records its digest as an
+// artifact of r. It returns the output of the build if it pushed nothing.
+func (s *ImageBuildService) Build(ctx context.Context, stepID string, r *run.Run, spec *plan.ImageSpec) (*plan.ImageBuild, error) {
+	p, err := s.store.GetProject(ctx, r.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+	ws := sandbox.Workspace{RepoURL: p.RepoURL, Ref: r.Branch}
+	hostPath := r.WorktreePath
+	if hostPath == "" {
+		hostPath = p.WorkspacePath
+	}
+	if hostPath != "" {
+		if ws.HostPath, err = filepath.Abs(hostPath); err != nil {
+			return nil, fmt.Errorf("resolve workspace: %w", err)
+		}
+	}
+
+	env := make(map[string]string)
+	if spec.RegistrySecret != "" {
+		auth, err := s.registryAuth(ctx, r.ProjectID, spec)
+		if err != nil {
+			return nil, err
+		}
+		env[imageAuthEnv] = auth
+		env["DOCKER_CONFIG"] = imageDockerConfig
+	}
+	image, script := s.cfg.Build.DockerfileImage, dockerfileScript(spec)
+	if spec.Builder == plan.ImageBuilderBuildpacks {
+		image, script = s.cfg.Build.BuildpacksImage, buildpacksScript(spec)
+	}
+
+	timeout := s.cfg.Build.Timeout
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	id, err := s.driver.Start(ctx, &sandbox.Spec{
+		RunID:     stepID,
+		ProjectID: r.ProjectID,
+		Image:     image,
+		Command:   []string{"sh", "-c", script},
+		Env:       env,
+		Workspace: ws,
+		Limits: sandbox.Limits{
+			MemoryMB:  s.cfg.MemoryMB,
+			CPUs:      s.cfg.CPUs,
+			PIDs:      s.cfg.PIDs,
+			StorageMB: s.cfg.StorageMB,
+		},
+		NetworkMode: s.cfg.NetworkMode,
+		Timeout:     timeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("start build sandbox: %w", err)
+	}
+	defer func() {
+		if err := s.driver.Remove(context.WithoutCancel(ctx), id); err != nil {
+			slog.Warn("remove build sandbox failed", "step_id", stepID, "sandbox_id", id, "error", err)
+		}
+	}()
+	slog.Info("image build started", "step_id", stepID, "run_id", r.ID, "builder", spec.Builder, "repository", spec.Repository, "sandbox_id", id)
+
+	var digest string
+	var tail []string
+	err = s.driver.Logs(ctx, id, func(l sandbox.Line) {
+		if m := imageDigestLine.FindStringSubmatch(l.Text); m != nil {
+			digest = m[1]
+			return
+		}
+		if tail = append(tail, l.Text); len(tail) > imageBuildTail {
+			tail = tail[1:]
+		}
+	})
+	switch {
+	case digest != "":
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return nil, fmt.Errorf("image build timed out after %s", timeout)
+	case ctx.Err() != nil:
+		return nil, fmt.Errorf("image build: %w", ctx.Err())
+	case err != nil:
+		return nil, fmt.Errorf("image build sandbox: %w", err)
+	default:
+		return nil, fmt.Errorf("image build pushed no image:\n%s", strings.Join(tail, "\n"))
+	}
+
+	now := time.Now().UTC()
+	b := &plan.ImageBuild{ImageSpec: *spec, SourceRunID: r.ID, Digest: digest, BuiltAt: &now}
+	if err := s.recordDigest(ctx, stepID, r, b); err != nil {
+		return nil, err
+	}
+	slog.Info("image pushed", "step_id", stepID, "run_id", r.ID, "image", b.Reference())
+	return b, nil
+}
+
+// imageDigestReport is the data of an image digest artifact.
+type imageDigestReport struct {
+	StepID     string            `json:"step_id"`
+	Builder    plan.ImageBuilder `json:"builder"`
+	Repository string            `json:"repository"`
+	Digest     string            `json:"digest"`
+	Reference  string            `json:"reference"` // repository@digest
+	Tags       []string          `json:"tags"`      // Pushed tagged references
+	BuiltAt    time.Time         `json:"built_at"`
+}
+
+// recordDigest stores the pushed image as an image digest artifact of r.
+func (s *ImageBuildService) recordDigest(ctx context.Context, stepID string, r *run.Run, b *plan.ImageBuild) error {
+	data, err := json.Marshal(imageDigestReport{
+		StepID:     stepID,
+		Builder:    b.Builder,
+		Repository: b.Repository,
+		Digest:     b.Digest,
+		Reference:  b.Reference(),
+		Tags:       b.References(),
+		BuiltAt:    *b.BuiltAt,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal image digest: %w", err)
+	}
+	a := &artifact.Artifact{
+		RunID:       r.ID,
+		ProjectID:   r.ProjectID,
+		Kind:        artifact.KindImageDigest,
+		Name:        "image-" + path.Base(b.Repository) + ".json",
+		ContentType: "application/json",
+		Data:        data,
+		Metadata: map[string]string{
+			"step_id":    stepID,
+			"repository": b.Repository,
+			"digest":     b.Digest,
+		},
+	}
+	if err := s.store.CreateArtifact(ctx, a); err != nil {
+		return fmt.Errorf("record image digest: %w", err)
+	}
+	return nil
+}
+
+// registryAuth returns a Docker config authenticating to the registry of
+// spec with the password or token in its registry secret.
+func (s *ImageBuildService) registryAuth(ctx context.Context, projectID string, spec *plan.ImageSpec) (string, error) {
+	if s.secrets == nil {
+		return "", errors.New("registry_secret requires the secret store")
+	}
+	vals, err := s.secrets.Resolve(ctx, projectID, []string{spec.RegistrySecret})
+	if err != nil {
+		return "", fmt.Errorf("resolve registry_secret: %w", err)
+	}
+	user := spec.RegistryUsername
+	if user == "" {
+		user = defaultRegistryUsername
+	}
+	host := spec.Registry()
+	if host == "docker.io" {
+		host = "https://index.docker.io/v1/"
+	}
+	cfg := map[string]map[string]map[string]string{"auths": {host: {
+		"auth": base64.StdEncoding.EncodeToString([]byte(user + ":" + vals[spec.RegistrySecret])),
+	}}}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return "", fmt.Errorf("marshal registry auth: %w", err)
+	}
+	return string(data), nil
+}
+
+// authScript writes the registry credentials handed to a build, if any, to
+// its Docker config.
+const authScript = `if [ -n "$` + imageAuthEnv + `" ]; then mkdir -p "$DOCKER_CONFIG" && printf '%s' "$` + imageAuthEnv + `" > "$DOCKER_CONFIG/config.json"; fi
+`
+
+// dockerfileScript returns the shell script that builds and pushes the
+// Dockerfile of spec with kaniko and prints the digest line.
+func dockerfileScript(spec *plan.ImageSpec) string {
+	dir := path.Join(sandbox.WorkDir, spec.Context)
+	args := []string{
+		"/kaniko/executor",
+		"--context", shellQuote("dir://" + dir),
+		"--dockerfile", shellQuote(path.Join(dir, spec.Dockerfile)),
+		"--digest-file", "/tmp/codeforge-digest",
+	}
+	for _, ref := range spec.References() {
+		args = append(args, "--destination", shellQuote(ref))
+	}
+	keys := make([]string, 0, len(spec.BuildArgs))
+	for k := range spec.BuildArgs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "--build-arg", shellQuote(k+"="+spec.BuildArgs[k]))
+	}
+	return "set -e\n" + authScript + strings.Join(args, " ") + "\n" +
+		`echo "` + ImageDigestMarker + `$(cat /tmp/codeforge-digest)"` + "\n"
+}
+
+// buildpacksScript returns the shell script that builds and pushes the
+// context of spec with the buildpacks lifecycle and prints the digest line
+// from its report.
+func buildpacksScript(spec *plan.ImageSpec) string {
+	refs := spec.References()
+	args := []string{
+		"/cnb/lifecycle/creator",
+		"-app", shellQuote(path.Join(sandbox.WorkDir, spec.Context)),
+		"-report", "/tmp/codeforge-report.toml",
+	}
+	for _, ref := range refs[1:] {
+		args = append(args, "-tag", shellQuote(ref))
+	}
+	args = append(args, shellQuote(refs[0]))
+	return "set -e\n" + authScript + strings.Join(args, " ") + "\n" +
+		`sed -n 's/^ *digest = "\(sha256:[0-9a-f]*\)"/` + ImageDigestMarker + `\1/p' /tmp/codeforge-report.toml` + "\n"
+}
+
+// shellQuote quotes s as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
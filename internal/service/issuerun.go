@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/issuerun"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+// IssueRunService runs issues handed to CodeForge on the project's git
+// host: when an issue webhook adds the project's trigger label or
+// assignee, it creates a task for the issue, starts a run with the
+// project's issue agent and keeps a comment on the issue up to date with
+// the run's progress and pull request.
+type IssueRunService struct {
+	store     database.Store
+	runtime   *RuntimeService
+	secrets   *SecretService
+	publicURL string
+}
+
+// NewIssueRunService creates an IssueRunService. Runs are started through
+// runtime, which also builds their context packs.
+func NewIssueRunService(store database.Store, runtime *RuntimeService, secrets *SecretService) *IssueRunService {
+	return &IssueRunService{store: store, runtime: runtime, secrets: secrets}
+}
+
+// SetPublicURL sets the web UI base URL that issue comments link to.
+func (s *IssueRunService) SetPublicURL(u string) {
+	s.publicURL = strings.TrimSuffix(u, "/")
+}
+
+// GetSettings returns the automation settings of a project.
+func (s *IssueRunService) GetSettings(ctx context.Context, projectID string) (*issuerun.Settings, error) {
+	p, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	settings := issuerun.SettingsFromConfig(p.Config)
+	return &settings, nil
+}
+
+// UpdateSettings validates settings and stores them in the project config.
+// The agent, if set, must belong to the project.
+func (s *IssueRunService) UpdateSettings(ctx context.Context, projectID string, settings *issuerun.Settings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	p, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if settings.AgentID != "" {
+		a, err := s.store.GetAgent(ctx, settings.AgentID)
+		if err != nil {
+			return err
+		}
+		if a.ProjectID != projectID {
+			return fmt.Errorf("agent %s: %w", settings.AgentID, domain.ErrNotFound)
+		}
+	}
+	p.Config = settings.Apply(p.Config)
+	if err := s.store.UpdateProject(ctx, p); err != nil {
+		return fmt.Errorf("update project config: %w", err)
+	}
+	slog.Info("issue automation updated", "project_id", projectID,
+		"label", settings.Label, "assignee", settings.Assignee, "deliver_mode", settings.DeliverMode)
+	return nil
+}
+
+// List returns the issue runs of a project, newest first.
+func (s *IssueRunService) List(ctx context.Context, projectID string) ([]issuerun.IssueRun, error) {
+	return s.store.ListIssueRuns(ctx, projectID)
+}
+
+// HandleWebhook verifies an issue webhook against every project on the
+// named git provider and starts a run for each project whose trigger the
+// change matches. It returns the number of runs started, or
+// gitprovider.ErrInvalidSignature if no project verified the delivery.
+func (s *IssueRunService) HandleWebhook(ctx context.Context, providerName string, header http.Header, body []byte) (int, error) {
+	projects, err := s.store.ListProjects(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list projects: %w", err)
+	}
+
+	verified, started := false, 0
+	for i := range projects {
+		p := &projects[i]
+		if p.Provider != providerName {
+			continue
+		}
+		prov, err := hostedGitProvider(ctx, s.secrets, p)
+		if err != nil {
+			slog.Warn("issue webhook: build provider", "project_id", p.ID, "provider", providerName, "error", err)
+			continue
+		}
+		tracker, ok := prov.(gitprovider.IssueTracker)
+		if !ok || !prov.Capabilities().Webhook {
+			continue
+		}
+		ev, err := tracker.ParseIssueWebhook(header, body)
+		switch {
+		case errors.Is(err, gitprovider.ErrInvalidSignature):
+			continue
+		case errors.Is(err, gitprovider.ErrIgnoredEvent):
+			verified = true
+			continue
+		case err != nil:
+			slog.Warn("issue webhook: parse", "project_id", p.ID, "provider", providerName, "error", err)
+			verified = true
+			continue
+		}
+		verified = true
+		ran, err := s.handleEvent(ctx, p, prov, tracker, ev)
+		if err != nil {
+			return started, err
+		}
+		if ran {
+			started++
+		}
+	}
+	if !verified {
+		return 0, gitprovider.ErrInvalidSignature
+	}
+	return started, nil
+}
+
+// handleEvent starts a run for an issue change that matches the project's
+// trigger, from a webhook or a poll. It returns whether a run started.
+func (s *IssueRunService) handleEvent(ctx context.Context, p *project.Project, prov gitprovider.Provider, tracker gitprovider.IssueTracker, ev *gitprovider.IssueEvent) (bool, error) {
+	settings := issuerun.SettingsFromConfig(p.Config)
+	if !settings.Triggers(ev.AddedLabels, ev.AddedAssignees) {
+		return false, nil
+	}
+	return s.start(ctx, p, commenter(prov, tracker), &settings, &ev.Issue)
+}
+
+// start creates the task and run for an issue. It returns false without
+// an error if the issue already has a running issue run. Failures after
+// the issue run is recorded mark it failed and are reported on the issue.
+func (s *IssueRunService) start(ctx context.Context, p *project.Project, tracker gitprovider.IssueTracker, settings *issuerun.Settings, issue *issuerun.Issue) (bool, error) {
+	ir := &issuerun.IssueRun{
+		ProjectID:   p.ID,
+		Provider:    p.Provider,
+		IssueNumber: issue.Number,
+		IssueURL:    issue.URL,
+		Status:      issuerun.StatusRunning,
+	}
+	if err := s.store.CreateIssueRun(ctx, ir); err != nil {
+		if errors.Is(err, domain.ErrConflict) {
+			slog.Info("issue already running", "project_id", p.ID, "issue", issue.Number)
+			return false, nil
+		}
+		return false, err
+	}
+
+	if err := s.startRun(ctx, p.ID, settings, issue, ir); err != nil {
+		slog.Error("issue run failed to start", "project_id", p.ID, "issue", issue.Number, "error", err)
+		ir.Status, ir.Error = issuerun.StatusFailed, err.Error()
+		s.save(ctx, ir)
+		s.comment(ctx, tracker, ir, "")
+		return false, nil
+	}
+	s.comment(ctx, tracker, ir, "")
+	slog.Info("issue run started", "project_id", p.ID, "issue", issue.Number, "task_id", ir.TaskID, "run_id", ir.RunID)
+	return true, nil
+}
+
+// startRun creates the issue's task and starts its run, recording both on
+// ir as soon as they exist.
+func (s *IssueRunService) startRun(ctx context.Context, projectID string, settings *issuerun.Settings, issue *issuerun.Issue, ir *issuerun.IssueRun) error {
+	agentID, err := s.agent(ctx, projectID, settings)
+	if err != nil {
+		return err
+	}
+	t, err := s.store.CreateTask(ctx, task.CreateRequest{
+		ProjectID: projectID,
+		Title:     fmt.Sprintf("#%d %s", issue.Number, issue.Title),
+		Prompt:    issue.Prompt(),
+	})
+	if err != nil {
+		return fmt.Errorf("create task: %w", err)
+	}
+	ir.TaskID = t.ID
+	r, err := s.runtime.StartRun(ctx, &run.StartRequest{
+		TaskID:       t.ID,
+		AgentID:      agentID,
+		ProjectID:    projectID,
+		DeliverMode:  settings.DeliverMode,
+		TriggerEvent: issuerun.TriggerEvent,
+	})
+	if err != nil {
+		return fmt.Errorf("start run: %w", err)
+	}
+	ir.RunID = r.ID
+	if err := s.store.UpdateIssueRun(ctx, ir); err != nil {
+		return fmt.Errorf("record run: %w", err)
+	}
+	return nil
+}
+
+// agent returns the configured issue agent, or the project's first agent.
+func (s *IssueRunService) agent(ctx context.Context, projectID string, settings *issuerun.Settings) (string, error) {
+	if settings.AgentID != "" {
+		return settings.AgentID, nil
+	}
+	agents, err := s.store.ListAgents(ctx, projectID)
+	if err != nil {
+		return "", fmt.Errorf("list agents: %w", err)
+	}
+	if len(agents) == 0 {
+		return "", issuerun.ErrNoAgent
+	}
+	return agents[0].ID, nil
+}
+
+// HandleRunCompleted records the outcome of a run started for an issue
+// and updates the issue's comment. Runs of other origins are ignored.
+func (s *IssueRunService) HandleRunCompleted(ctx context.Context, runID string, status run.Status) {
+	ir, err := s.store.GetIssueRunByRun(ctx, runID)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotFound) {
+			slog.Error("issue run lookup failed", "run_id", runID, "error", err)
+		}
+		return
+	}
+
+	summary := ""
+	r, err := s.store.GetRun(ctx, runID)
+	if err == nil {
+		summary = r.Summary
+	}
+	if status == run.StatusCompleted {
+		ir.Status = issuerun.StatusCompleted
+		ir.PRURL = s.pullRequestURL(ctx, runID)
+	} else {
+		ir.Status, ir.Error = issuerun.StatusFailed, "run "+string(status)
+		if r != nil && r.Error != "" {
+			ir.Error = r.Error
+		}
+	}
+	s.save(ctx, ir)
+
+	p, err := s.store.GetProject(ctx, ir.ProjectID)
+	if err != nil {
+		slog.Error("issue run: get project", "project_id", ir.ProjectID, "error", err)
+		return
+	}
+	prov, err := hostedGitProvider(ctx, s.secrets, p)
+	if err != nil {
+		slog.Warn("issue run: build provider", "project_id", p.ID, "error", err)
+		return
+	}
+	tracker, _ := prov.(gitprovider.IssueTracker)
+	s.comment(ctx, commenter(prov, tracker), ir, summary)
+}
+
+// pullRequestURL returns the pull request the run's delivery opened, if any.
+func (s *IssueRunService) pullRequestURL(ctx context.Context, runID string) string {
+	if payload, delivered := lastDelivery(ctx, s.runtime, runID); delivered {
+		return payload["pr_url"]
+	}
+	return ""
+}
+
+// comment creates or updates the progress comment of ir, followed by the
+// run's summary if any. Comments are advisory, so failures are logged
+// rather than returned.
+func (s *IssueRunService) comment(ctx context.Context, tracker gitprovider.IssueTracker, ir *issuerun.IssueRun, summary string) {
+	if tracker == nil {
+		return
+	}
+	body := ir.Comment(runURL(s.publicURL, ir.ProjectID, ir.RunID))
+	if summary != "" {
+		body += "\n\n" + summary
+	}
+	if ir.CommentID != "" {
+		if err := tracker.UpdateIssueComment(ctx, ir.IssueNumber, ir.CommentID, body); err != nil {
+			slog.Warn("issue comment update failed", "project_id", ir.ProjectID, "issue", ir.IssueNumber, "error", err)
+		}
+		return
+	}
+	id, err := tracker.CreateIssueComment(ctx, ir.IssueNumber, body)
+	if err != nil {
+		slog.Warn("issue comment failed", "project_id", ir.ProjectID, "issue", ir.IssueNumber, "error", err)
+		return
+	}
+	ir.CommentID = id
+	s.save(ctx, ir)
+}
+
+// save stores ir and logs failures.
+func (s *IssueRunService) save(ctx context.Context, ir *issuerun.IssueRun) {
+	if err := s.store.UpdateIssueRun(ctx, ir); err != nil {
+		slog.Error("issue run update failed", "issue_run_id", ir.ID, "error", err)
+	}
+}
+
+// commenter returns tracker if prov can comment on issues, or nil.
+func commenter(prov gitprovider.Provider, tracker gitprovider.IssueTracker) gitprovider.IssueTracker {
+	if tracker == nil || !prov.Capabilities().Issues {
+		return nil
+	}
+	return tracker
+}
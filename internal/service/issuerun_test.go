@@ -0,0 +1,218 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/agent"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/issuerun"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/secret"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+// issueComments holds the comments posted through the "fake-issues"
+// provider, keyed by comment ID.
+var issueComments map[string]string
+
+// fakeIssueProvider accepts webhooks whose X-Fake-Token header matches the
+// webhook secret; their body is the IssueEvent as JSON.
+type fakeIssueProvider struct {
+	gitprovider.Provider // unused local operations
+	secret               string
+}
+
+func init() {
+	gitprovider.Register("fake-issues", func(cfg map[string]string) (gitprovider.Provider, error) {
+		return &fakeIssueProvider{secret: cfg[gitprovider.ConfigWebhookKey]}, nil
+	})
+}
+
+func (p *fakeIssueProvider) Name() string { return "fake-issues" }
+func (p *fakeIssueProvider) Capabilities() gitprovider.Capabilities {
+	return gitprovider.Capabilities{Issues: true, Webhook: p.secret != ""}
+}
+func (p *fakeIssueProvider) ParseIssueWebhook(header http.Header, body []byte) (*gitprovider.IssueEvent, error) {
+	if header.Get("X-Fake-Token") != p.secret {
+		return nil, gitprovider.ErrInvalidSignature
+	}
+	var ev gitprovider.IssueEvent
+	if err := json.Unmarshal(body, &ev); err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
+func (p *fakeIssueProvider) CreateIssueComment(_ context.Context, number int, body string) (string, error) {
+	id := fmt.Sprintf("%d-%d", number, len(issueComments)+1)
+	issueComments[id] = body
+	return id, nil
+}
+func (p *fakeIssueProvider) UpdateIssueComment(_ context.Context, _ int, commentID, body string) error {
+	if _, ok := issueComments[commentID]; !ok {
+		return errors.New("no such comment")
+	}
+	issueComments[commentID] = body
+	return nil
+}
+
+func newIssueRunTestEnv(t *testing.T) (*runtimeMockStore, *runtimeMockEventStore, *service.IssueRunService) {
+	t.Helper()
+	issueComments = make(map[string]string)
+	store := &runtimeMockStore{
+		projects: []project.Project{{ID: "gh-issues", Name: "webapp", Provider: "fake-issues", Config: map[string]string{
+			gitprovider.ConfigWebhookSecret: "HOOK",
+		}}},
+		agents: []agent.Agent{
+			{ID: "issue-agent", ProjectID: "gh-issues", Name: "coder", Backend: "aider", Status: agent.StatusIdle, Config: map[string]string{}},
+		},
+	}
+	events := &runtimeMockEventStore{}
+	runtime := service.NewRuntimeService(store, &runtimeMockQueue{}, &runtimeMockBroadcaster{}, events,
+		service.NewPolicyService("headless-safe-sandbox", nil), &config.Runtime{StallThreshold: 5, QualityGateTimeout: time.Minute})
+	secrets := newTestSecretService(t, store)
+	if _, err := secrets.Create(context.Background(), "", &secret.CreateRequest{Name: "HOOK", Value: "s3cret"}); err != nil {
+		t.Fatal(err)
+	}
+	svc := service.NewIssueRunService(store, runtime, secrets)
+	svc.SetPublicURL("http://ui.example/")
+	return store, events, svc
+}
+
+func issueWebhook(t *testing.T, ev *gitprovider.IssueEvent) []byte {
+	t.Helper()
+	body, err := json.Marshal(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestIssueRunService_Settings(t *testing.T) {
+	store, _, svc := newIssueRunTestEnv(t)
+	store.agents = append(store.agents, agent.Agent{ID: "other-agent", ProjectID: "proj-1"})
+	ctx := context.Background()
+
+	got, err := svc.GetSettings(ctx, "gh-issues")
+	if err != nil || got.Enabled() || got.DeliverMode != run.DeliverModePR {
+		t.Fatalf("expected disabled settings delivering PRs, got %+v, %v", got, err)
+	}
+	if err := svc.UpdateSettings(ctx, "gh-issues", &issuerun.Settings{Label: "codeforge", DeliverMode: "email"}); !errors.Is(err, issuerun.ErrInvalidDeliverMode) {
+		t.Fatalf("expected ErrInvalidDeliverMode, got %v", err)
+	}
+	if err := svc.UpdateSettings(ctx, "gh-issues", &issuerun.Settings{Label: "codeforge", AgentID: "other-agent"}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for another project's agent, got %v", err)
+	}
+	if err := svc.UpdateSettings(ctx, "gh-issues", &issuerun.Settings{Assignee: " @codeforge-bot ", AgentID: "issue-agent", DeliverMode: run.DeliverModeBranch}); err != nil {
+		t.Fatal(err)
+	}
+	got, err = svc.GetSettings(ctx, "gh-issues")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := issuerun.Settings{Assignee: "codeforge-bot", AgentID: "issue-agent", DeliverMode: run.DeliverModeBranch}
+	if *got != want {
+		t.Fatalf("expected %+v, got %+v", want, *got)
+	}
+	if _, ok := store.projects[0].Config[issuerun.ConfigLabel]; ok {
+		t.Fatal("expected empty label to be removed from the project config")
+	}
+}
+
+func TestIssueRunService_Webhook(t *testing.T) {
+	store, events, svc := newIssueRunTestEnv(t)
+	ctx := context.Background()
+	if err := svc.UpdateSettings(ctx, "gh-issues", &issuerun.Settings{Label: "codeforge"}); err != nil {
+		t.Fatal(err)
+	}
+	header := http.Header{"X-Fake-Token": {"s3cret"}}
+	labeled := issueWebhook(t, &gitprovider.IssueEvent{
+		Issue:       issuerun.Issue{Number: 12, Title: "Fix login", Body: "It breaks", URL: "https://git.example/issues/12"},
+		AddedLabels: []string{"CodeForge"},
+	})
+
+	if _, err := svc.HandleWebhook(ctx, "fake-issues", http.Header{"X-Fake-Token": {"wrong"}}, labeled); !errors.Is(err, gitprovider.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+	other := issueWebhook(t, &gitprovider.IssueEvent{Issue: issuerun.Issue{Number: 13}, AddedLabels: []string{"bug"}})
+	if n, err := svc.HandleWebhook(ctx, "fake-issues", header, other); err != nil || n != 0 {
+		t.Fatalf("expected no run for another label, got %d, %v", n, err)
+	}
+
+	n, err := svc.HandleWebhook(ctx, "fake-issues", header, labeled)
+	if err != nil || n != 1 {
+		t.Fatalf("HandleWebhook = %d, %v", n, err)
+	}
+	if n, err := svc.HandleWebhook(ctx, "fake-issues", header, labeled); err != nil || n != 0 {
+		t.Fatalf("expected redelivery to start nothing, got %d, %v", n, err)
+	}
+
+	runs, err := svc.List(ctx, "gh-issues")
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("expected one issue run, got %v, %v", runs, err)
+	}
+	ir := runs[0]
+	if ir.Status != issuerun.StatusRunning || ir.RunID == "" || ir.TaskID == "" || ir.CommentID == "" {
+		t.Fatalf("unexpected issue run %+v", ir)
+	}
+	r, err := store.GetRun(ctx, ir.RunID)
+	if err != nil || r.AgentID != "issue-agent" || r.DeliverMode != run.DeliverModePR {
+		t.Fatalf("unexpected run %+v, %v", r, err)
+	}
+	tk, err := store.GetTask(ctx, ir.TaskID)
+	if err != nil || tk.Title != "#12 Fix login" || !strings.Contains(tk.Prompt, "It breaks") {
+		t.Fatalf("unexpected task %+v, %v", tk, err)
+	}
+	if c := issueComments[ir.CommentID]; !strings.Contains(c, "started run") || !strings.Contains(c, "http://ui.example/projects/gh-issues?run=") {
+		t.Fatalf("unexpected comment %q", c)
+	}
+
+	_ = events.Append(ctx, &event.AgentEvent{RunID: ir.RunID, Type: event.TypeDeliveryCompleted,
+		Payload: json.RawMessage(`{"mode": "pr", "pr_url": "https://git.example/pulls/5"}`)})
+	_ = store.SetRunSummary(ctx, ir.RunID, "### What changed\n- Fixed the login redirect")
+	svc.HandleRunCompleted(ctx, ir.RunID, run.StatusCompleted)
+	runs, _ = svc.List(ctx, "gh-issues")
+	if runs[0].Status != issuerun.StatusCompleted || runs[0].PRURL != "https://git.example/pulls/5" {
+		t.Fatalf("expected completed issue run with PR, got %+v", runs[0])
+	}
+	if c := issueComments[ir.CommentID]; !strings.Contains(c, "completed") || !strings.Contains(c, "https://git.example/pulls/5") ||
+		!strings.Contains(c, "Fixed the login redirect") || len(issueComments) != 1 {
+		t.Fatalf("expected the comment to be updated, got %v", issueComments)
+	}
+
+	// Once the run finished, labeling the issue again starts a new run.
+	if n, err := svc.HandleWebhook(ctx, "fake-issues", header, labeled); err != nil || n != 1 {
+		t.Fatalf("expected a new run after completion, got %d, %v", n, err)
+	}
+}
+
+func TestIssueRunService_NoAgent(t *testing.T) {
+	store, _, svc := newIssueRunTestEnv(t)
+	store.agents = nil
+	ctx := context.Background()
+	if err := svc.UpdateSettings(ctx, "gh-issues", &issuerun.Settings{Assignee: "codeforge-bot"}); err != nil {
+		t.Fatal(err)
+	}
+	body := issueWebhook(t, &gitprovider.IssueEvent{Issue: issuerun.Issue{Number: 3}, AddedAssignees: []string{"codeforge-bot"}})
+	n, err := svc.HandleWebhook(ctx, "fake-issues", http.Header{"X-Fake-Token": {"s3cret"}}, body)
+	if err != nil || n != 0 {
+		t.Fatalf("HandleWebhook = %d, %v", n, err)
+	}
+	runs, _ := svc.List(ctx, "gh-issues")
+	if len(runs) != 1 || runs[0].Status != issuerun.StatusFailed || runs[0].RunID != "" {
+		t.Fatalf("expected a failed issue run, got %+v", runs)
+	}
+	if c := issueComments[runs[0].CommentID]; !strings.Contains(c, issuerun.ErrNoAgent.Error()) {
+		t.Fatalf("expected the failure on the issue, got %q", c)
+	}
+}
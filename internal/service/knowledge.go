@@ -0,0 +1,343 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/knowledge"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/docsource"
+)
+
+// KnowledgeService ingests the knowledge bases of each tenant: it fetches
+// their documents from Confluence, Notion or the web, chunks and embeds
+// them with the service-wide retrieval settings, and refreshes them on
+// their interval. Retrieval searches of attached projects include them.
+type KnowledgeService struct {
+	store     database.Store
+	retrieval *RetrievalService
+	secrets   *SecretService
+	cfg       config.Knowledge
+	now       func() time.Time
+
+	mu       sync.Mutex
+	attempts map[string]time.Time // Last refresh attempt per knowledge base ID
+	running  map[string]bool
+}
+
+// NewKnowledgeService creates a KnowledgeService.
+func NewKnowledgeService(store database.Store, retrieval *RetrievalService, cfg config.Knowledge) *KnowledgeService {
+	return &KnowledgeService{
+		store:     store,
+		retrieval: retrieval,
+		cfg:       cfg,
+		now:       time.Now,
+		attempts:  make(map[string]time.Time),
+		running:   make(map[string]bool),
+	}
+}
+
+// SetSecretService enables token_secret in knowledge base configs.
+func (s *KnowledgeService) SetSecretService(secrets *SecretService) {
+	s.secrets = secrets
+}
+
+// Create registers a knowledge base. Its documents are fetched by the
+// first refresh, on request or when the refresher finds it due.
+func (s *KnowledgeService) Create(ctx context.Context, req *knowledge.CreateRequest) (*knowledge.KnowledgeBase, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validate knowledge base: %w", err)
+	}
+	tenantID := req.TenantID
+	scoped := tenant.FromContext(ctx)
+	switch {
+	case tenantID == "" && scoped != "":
+		tenantID = scoped
+	case tenantID == "":
+		tenantID = tenant.DefaultID
+	case scoped != "" && tenantID != scoped:
+		return nil, fmt.Errorf("tenant %s: %w", tenantID, domain.ErrNotFound)
+	}
+	if _, err := s.store.GetTenant(ctx, tenantID); err != nil {
+		return nil, fmt.Errorf("get tenant: %w", err)
+	}
+	if err := s.checkProjects(ctx, tenantID, req.ProjectIDs); err != nil {
+		return nil, err
+	}
+
+	kb := &knowledge.KnowledgeBase{
+		TenantID:        tenantID,
+		Name:            req.Name,
+		Kind:            req.Kind,
+		Config:          req.Config,
+		ProjectIDs:      req.ProjectIDs,
+		RefreshInterval: req.RefreshInterval,
+	}
+	if err := s.store.CreateKnowledgeBase(ctx, kb); err != nil {
+		return nil, err
+	}
+	slog.Info("knowledge base created", "kb_id", kb.ID, "tenant_id", tenantID, "kind", kb.Kind)
+	return kb, nil
+}
+
+// Get returns a knowledge base by ID.
+func (s *KnowledgeService) Get(ctx context.Context, id string) (*knowledge.KnowledgeBase, error) {
+	return s.store.GetKnowledgeBase(ctx, id)
+}
+
+// List returns the knowledge bases of the request's tenant, or of all
+// tenants for unscoped requests.
+func (s *KnowledgeService) List(ctx context.Context) ([]knowledge.KnowledgeBase, error) {
+	return s.store.ListKnowledgeBases(ctx)
+}
+
+// Update replaces the name, kind, config, projects and refresh interval of
+// a knowledge base. Its tenant and chunks stay the same until the next
+// refresh.
+func (s *KnowledgeService) Update(ctx context.Context, id string, req *knowledge.CreateRequest) (*knowledge.KnowledgeBase, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validate knowledge base: %w", err)
+	}
+	kb, err := s.store.GetKnowledgeBase(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkProjects(ctx, kb.TenantID, req.ProjectIDs); err != nil {
+		return nil, err
+	}
+	kb.Name, kb.Kind, kb.Config = req.Name, req.Kind, req.Config
+	kb.ProjectIDs, kb.RefreshInterval = req.ProjectIDs, req.RefreshInterval
+	if err := s.store.UpdateKnowledgeBase(ctx, kb); err != nil {
+		return nil, err
+	}
+	return kb, nil
+}
+
+// Delete removes a knowledge base and its chunks.
+func (s *KnowledgeService) Delete(ctx context.Context, id string) error {
+	if err := s.store.DeleteKnowledgeBase(ctx, id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.attempts, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// checkProjects checks that the projects a knowledge base is attached to
+// belong to its tenant.
+func (s *KnowledgeService) checkProjects(ctx context.Context, tenantID string, ids []string) error {
+	for _, id := range ids {
+		p, err := s.store.GetProject(ctx, id)
+		if err != nil {
+			return fmt.Errorf("get project %s: %w", id, err)
+		}
+		if tenantOf(p) != tenantID {
+			return fmt.Errorf("project %s: %w", id, domain.ErrNotFound)
+		}
+	}
+	return nil
+}
+
+// Refresh fetches the documents of a knowledge base, embeds their chunks
+// and replaces its stored chunks. On failure the previous chunks are kept
+// and the error is recorded on the knowledge base.
+func (s *KnowledgeService) Refresh(ctx context.Context, id string) (*knowledge.KnowledgeBase, error) {
+	kb, err := s.store.GetKnowledgeBase(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	if s.running[id] {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("knowledge base %s is being refreshed: %w", id, domain.ErrConflict)
+	}
+	s.running[id] = true
+	s.attempts[id] = s.now()
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, id)
+		s.mu.Unlock()
+	}()
+
+	if err := s.refresh(ctx, kb); err != nil {
+		slog.Warn("knowledge base refresh failed", "kb_id", kb.ID, "tenant_id", kb.TenantID, "kind", kb.Kind, "error", err)
+		if serr := s.store.SetKnowledgeBaseError(ctx, kb.ID, err.Error()); serr != nil {
+			slog.Error("record knowledge base error", "kb_id", kb.ID, "error", serr)
+		}
+		return nil, err
+	}
+	slog.Info("knowledge base refreshed",
+		"kb_id", kb.ID,
+		"tenant_id", kb.TenantID,
+		"documents", kb.Documents,
+		"chunks", kb.Chunks,
+	)
+	return kb, nil
+}
+
+func (s *KnowledgeService) refresh(ctx context.Context, kb *knowledge.KnowledgeBase) error {
+	cfg, err := s.sourceConfig(ctx, kb)
+	if err != nil {
+		return err
+	}
+	src, err := docsource.New(string(kb.Kind), cfg)
+	if err != nil {
+		return err
+	}
+	fetchCtx, cancel := context.WithTimeout(ctx, s.cfg.FetchTimeout)
+	docs, err := src.Fetch(fetchCtx, s.cfg.MaxDocuments)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("fetch documents: %w", err)
+	}
+
+	rc := s.retrieval.cfg
+	var chunks []retrieval.Chunk
+	for _, d := range docs {
+		for _, c := range retrieval.Split(d.URL, d.Content, rc.ChunkLines, rc.ChunkOverlap) {
+			c.Title = d.Title
+			chunks = append(chunks, c)
+		}
+	}
+	texts := make([]string, len(chunks))
+	for i := range chunks {
+		texts[i] = chunks[i].Content
+	}
+	e, err := s.retrieval.DefaultEmbedding()
+	if err != nil {
+		return err
+	}
+	e, vecs, err := s.retrieval.EmbedWith(ctx, e, texts)
+	if err != nil {
+		return err
+	}
+	for i := range chunks {
+		chunks[i].Embedding = vecs[i]
+	}
+
+	kb.Provider, kb.Model, kb.Dimensions = e.Provider, e.Model, e.Dimensions
+	kb.Documents, kb.Chunks = len(docs), len(chunks)
+	return s.store.ReplaceKnowledgeChunks(ctx, kb, chunks)
+}
+
+// sourceConfig returns the knowledge base's config with its token_secret
+// resolved from the tenant's secrets.
+func (s *KnowledgeService) sourceConfig(ctx context.Context, kb *knowledge.KnowledgeBase) (map[string]string, error) {
+	cfg := maps.Clone(kb.Config)
+	name := cfg[knowledge.ConfigTokenSecret]
+	if name == "" {
+		return cfg, nil
+	}
+	if s.secrets == nil {
+		return nil, errors.New("token_secret needs secrets.master_key")
+	}
+	env, err := s.secrets.Resolve(tenant.NewContext(ctx, kb.TenantID), "", []string{name})
+	if err != nil {
+		return nil, fmt.Errorf("resolve token: %w", err)
+	}
+	cfg[knowledge.ConfigToken] = env[name]
+	return cfg, nil
+}
+
+// StartRefresher runs RefreshDue every check interval until ctx is done or
+// cancel is called. It does nothing if the interval is 0.
+func (s *KnowledgeService) StartRefresher(ctx context.Context) (cancel func()) {
+	ctx, cancel = context.WithCancel(ctx)
+	if s.cfg.CheckInterval <= 0 {
+		return cancel
+	}
+	go func() {
+		ticker := time.NewTicker(s.cfg.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.RefreshDue(ctx); err != nil {
+					slog.Error("knowledge base refresh check failed", "error", err)
+				}
+			}
+		}
+	}()
+	return cancel
+}
+
+// RefreshDue refreshes every knowledge base whose refresh interval has
+// passed since its last refresh attempt and returns how many succeeded.
+// A failing knowledge base records its error and does not stop the others.
+func (s *KnowledgeService) RefreshDue(ctx context.Context) (int, error) {
+	kbs, err := s.store.ListKnowledgeBases(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list knowledge bases: %w", err)
+	}
+	refreshed := 0
+	for i := range kbs {
+		kb := &kbs[i]
+		if !kb.Due(s.now(), s.lastAttempt(kb)) {
+			continue
+		}
+		if _, err := s.Refresh(ctx, kb.ID); err == nil {
+			refreshed++
+		}
+	}
+	return refreshed, nil
+}
+
+// lastAttempt returns the time of the last refresh attempt of a knowledge
+// base: the last one made by this process, or else its last successful
+// refresh.
+func (s *KnowledgeService) lastAttempt(kb *knowledge.KnowledgeBase) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if at, ok := s.attempts[kb.ID]; ok {
+		return at
+	}
+	if kb.RefreshedAt != nil {
+		return *kb.RefreshedAt
+	}
+	return time.Time{}
+}
+
+// Chunks returns the chunks of the knowledge bases attached to a project
+// that were embedded like its index, so they are comparable with its
+// queries. Their source is the knowledge base's name.
+func (s *KnowledgeService) Chunks(ctx context.Context, p *project.Project, idx *retrieval.Index) ([]retrieval.Chunk, error) {
+	kbs, err := s.store.ListKnowledgeBases(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list knowledge bases: %w", err)
+	}
+	var chunks []retrieval.Chunk
+	for i := range kbs {
+		kb := &kbs[i]
+		if kb.TenantID != tenantOf(p) || !kb.Attached(p.ID) || kb.Chunks == 0 {
+			continue
+		}
+		if kb.Provider != idx.Provider || kb.Model != idx.Model || kb.Dimensions != idx.Dimensions {
+			slog.Debug("knowledge base skipped: embedding differs from the project index",
+				"kb_id", kb.ID, "project_id", p.ID, "kb_model", kb.Provider+"/"+kb.Model, "index_model", idx.Provider+"/"+idx.Model)
+			continue
+		}
+		kc, err := s.store.ListKnowledgeChunks(ctx, kb.ID)
+		if err != nil {
+			return nil, err
+		}
+		for j := range kc {
+			kc[j].Source = kb.Name
+		}
+		chunks = append(chunks, kc...)
+	}
+	return chunks, nil
+}
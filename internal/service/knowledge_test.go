@@ -0,0 +1,99 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "github.com/Strob0t/CodeForge/internal/adapter/webcrawl"
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/knowledge"
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestKnowledgeService_RefreshAndSearch(t *testing.T) {
+	retrievalSvc, store, _ := newRetrievalTestEnv(t)
+	store.tenants = []tenant.Tenant{{ID: tenant.DefaultID, Name: "Default"}, {ID: "acme", Name: "Acme"}}
+	ctx := context.Background()
+
+	var down atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<title>Runbook</title><p>beta beta beta</p>`))
+	}))
+	defer srv.Close()
+
+	svc := service.NewKnowledgeService(store, retrievalSvc, config.Knowledge{MaxDocuments: 10, FetchTimeout: time.Minute})
+	retrievalSvc.SetKnowledgeService(svc)
+
+	req := &knowledge.CreateRequest{
+		Name: "runbooks", Kind: knowledge.KindURL, RefreshInterval: "1h",
+		Config: map[string]string{knowledge.ConfigURL: srv.URL}, ProjectIDs: []string{"proj-1"},
+	}
+	if _, err := svc.Create(tenant.NewContext(ctx, "acme"), req); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected a project of another tenant to be rejected, got %v", err)
+	}
+	kb, err := svc.Create(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kb.TenantID != tenant.DefaultID {
+		t.Fatalf("unexpected knowledge base %+v", kb)
+	}
+	other := &knowledge.CreateRequest{Name: "acme docs", Kind: knowledge.KindURL, Config: req.Config}
+	if _, err := svc.Create(tenant.NewContext(ctx, "acme"), other); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the knowledge base with an interval is refreshed on schedule,
+	// and only once per interval.
+	if n, err := svc.RefreshDue(ctx); err != nil || n != 1 {
+		t.Fatalf("expected one refresh, got %d, %v", n, err)
+	}
+	if n, _ := svc.RefreshDue(ctx); n != 0 {
+		t.Fatalf("expected no refresh within the interval, got %d", n)
+	}
+	got, _ := svc.Get(ctx, kb.ID)
+	if got.Documents != 1 || got.Chunks != 1 || got.Provider != "litellm" || got.Dimensions != 4 || got.RefreshedAt == nil {
+		t.Fatalf("unexpected refreshed knowledge base %+v", got)
+	}
+
+	if _, err := retrievalSvc.Index(ctx, "proj-1"); err != nil {
+		t.Fatal(err)
+	}
+	results, err := retrievalSvc.Search(ctx, "proj-1", &retrieval.SearchRequest{Query: "beta"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cited bool
+	for _, r := range results {
+		if r.Source != "" {
+			cited = r.Source == "runbooks" && r.Title == "Runbook" && r.Path == srv.URL
+			break
+		}
+	}
+	if !cited || len(results) != 4 {
+		t.Fatalf("expected the runbook among the project's results, got %+v", results)
+	}
+
+	// A failed refresh records its error and keeps the chunks.
+	down.Store(true)
+	if _, err := svc.Refresh(ctx, kb.ID); err == nil {
+		t.Fatal("expected refresh to fail")
+	}
+	got, _ = svc.Get(ctx, kb.ID)
+	if got.LastError == "" || got.Chunks != 1 {
+		t.Fatalf("expected the error recorded and the chunks kept, got %+v", got)
+	}
+}
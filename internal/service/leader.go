@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+)
+
+// LeaderBucket is the key-value bucket holding the leader lease.
+const LeaderBucket = "codeforge_leader"
+
+// leaderKey is the key of the lease in LeaderBucket.
+const leaderKey = "lease"
+
+// LeaderElector elects one server of a deployment to run the background
+// jobs that must not run on every replica, such as the sync poller and
+// the audit publisher. The leader holds a lease in a key-value bucket
+// whose values expire after the lease duration and renews it every third
+// of it; when the leader stops or dies, the lease expires and another
+// server takes over. Jobs start when the server becomes leader and are
+// cancelled when it loses the lease. Without a bucket every server leads.
+type LeaderElector struct {
+	kv    messagequeue.KeyValue
+	id    string
+	lease time.Duration
+
+	mu      sync.Mutex
+	jobs    []leaderJob
+	cancels []func()
+	leader  bool
+	renewed time.Time
+}
+
+// leaderJob is a background job run by the leader.
+type leaderJob struct {
+	name  string
+	start func(ctx context.Context) (cancel func())
+}
+
+// NewLeaderElector creates an elector for the server id. kv must expire
+// values lease after they were last set; nil disables the election.
+func NewLeaderElector(kv messagequeue.KeyValue, id string, lease time.Duration) *LeaderElector {
+	return &LeaderElector{kv: kv, id: id, lease: lease}
+}
+
+// Register adds a job to start whenever this server becomes leader. Jobs
+// are registered before Start.
+func (e *LeaderElector) Register(name string, start func(ctx context.Context) (cancel func())) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.jobs = append(e.jobs, leaderJob{name: name, start: start})
+}
+
+// IsLeader reports whether this server currently runs the jobs.
+func (e *LeaderElector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+// Start campaigns for the lease until ctx is done or cancel is called.
+// Cancel stops the jobs and releases the lease, so another server takes
+// over without waiting for it to expire.
+func (e *LeaderElector) Start(ctx context.Context) (cancel func()) {
+	if e.kv == nil {
+		e.lead(ctx)
+		return func() { e.stepDown("stopped") }
+	}
+
+	ctx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(e.lease / 3)
+		defer ticker.Stop()
+		for {
+			e.campaign(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() {
+		stop()
+		<-done
+		e.resign()
+	}
+}
+
+// campaign renews the lease if this server holds it, or else tries to
+// acquire it.
+func (e *LeaderElector) campaign(ctx context.Context) {
+	if !e.IsLeader() {
+		err := e.kv.Create(ctx, leaderKey, []byte(e.id))
+		if errors.Is(err, messagequeue.ErrKeyExists) {
+			return
+		}
+		if err != nil {
+			slog.Warn("acquire leader lease", "error", err)
+			return
+		}
+		e.lead(ctx)
+		return
+	}
+
+	value, rev, err := e.kv.GetRevision(ctx, leaderKey)
+	if err == nil && string(value) != e.id {
+		e.stepDown("lease taken over")
+		return
+	}
+	if err == nil {
+		err = e.kv.Update(ctx, leaderKey, []byte(e.id), rev)
+	}
+	switch {
+	case err == nil:
+		e.mu.Lock()
+		e.renewed = time.Now()
+		e.mu.Unlock()
+	case errors.Is(err, messagequeue.ErrKeyChanged):
+		e.stepDown("lease taken over")
+	default:
+		// Keep leading while the lease may still be held.
+		slog.Warn("renew leader lease", "error", err)
+		e.mu.Lock()
+		expired := time.Since(e.renewed) >= e.lease
+		e.mu.Unlock()
+		if expired {
+			e.stepDown("lease expired")
+		}
+	}
+}
+
+// lead makes this server the leader and starts the jobs.
+func (e *LeaderElector) lead(ctx context.Context) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.leader = true
+	e.renewed = time.Now()
+	for _, job := range e.jobs {
+		e.cancels = append(e.cancels, job.start(ctx))
+	}
+	slog.Info("elected leader", "id", e.id, "jobs", len(e.jobs))
+}
+
+// stepDown cancels the jobs and gives up leadership.
+func (e *LeaderElector) stepDown(reason string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.leader {
+		return
+	}
+	for _, cancel := range e.cancels {
+		cancel()
+	}
+	e.cancels = nil
+	e.leader = false
+	slog.Info("stepped down as leader", "id", e.id, "reason", reason)
+}
+
+// resign steps down and deletes the lease if this server still holds it.
+func (e *LeaderElector) resign() {
+	if !e.IsLeader() {
+		return
+	}
+	e.stepDown("stopped")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if value, _, err := e.kv.GetRevision(ctx, leaderKey); err == nil && string(value) == e.id {
+		if err := e.kv.Delete(ctx, leaderKey); err != nil {
+			slog.Warn("release leader lease", "error", err)
+		}
+	}
+}
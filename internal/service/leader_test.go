@@ -0,0 +1,103 @@
+package service_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+// countingJob returns a leader job that counts how many times it runs.
+func countingJob(running *atomic.Int32) func(context.Context) func() {
+	return func(context.Context) func() {
+		running.Add(1)
+		return func() { running.Add(-1) }
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLeaderElector_OneLeaderAtATime(t *testing.T) {
+	kv := &memoryKV{}
+	ctx := context.Background()
+	var running1, running2 atomic.Int32
+
+	e1 := service.NewLeaderElector(kv, "server-1", 300*time.Millisecond)
+	e1.Register("job", countingJob(&running1))
+	cancel1 := e1.Start(ctx)
+	waitFor(t, "server-1 to lead", e1.IsLeader)
+
+	e2 := service.NewLeaderElector(kv, "server-2", 300*time.Millisecond)
+	e2.Register("job", countingJob(&running2))
+	cancel2 := e2.Start(ctx)
+	defer cancel2()
+
+	// Server 1 renews its lease; server 2 stays a follower.
+	time.Sleep(400 * time.Millisecond)
+	if !e1.IsLeader() || e2.IsLeader() {
+		t.Fatalf("expected server-1 to keep leading, leaders: %v %v", e1.IsLeader(), e2.IsLeader())
+	}
+	if running1.Load() != 1 || running2.Load() != 0 {
+		t.Fatalf("expected the job to run on server-1 only, got %d and %d", running1.Load(), running2.Load())
+	}
+
+	// Stopping server 1 releases the lease to server 2.
+	cancel1()
+	if e1.IsLeader() || running1.Load() != 0 {
+		t.Fatal("expected server-1 to stop its jobs")
+	}
+	waitFor(t, "server-2 to take over", e2.IsLeader)
+	if running2.Load() != 1 {
+		t.Errorf("expected the job to run on server-2, got %d", running2.Load())
+	}
+}
+
+func TestLeaderElector_StepsDownWhenLeaseTaken(t *testing.T) {
+	kv := &memoryKV{}
+	ctx := context.Background()
+	var running atomic.Int32
+
+	e := service.NewLeaderElector(kv, "server-1", 150*time.Millisecond)
+	e.Register("job", countingJob(&running))
+	cancel := e.Start(ctx)
+	defer cancel()
+	waitFor(t, "server-1 to lead", e.IsLeader)
+
+	// Another server took the lease after this one failed to renew it.
+	if err := kv.Put(ctx, "lease", []byte("server-2")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "server-1 to step down", func() bool { return !e.IsLeader() })
+	if running.Load() != 0 {
+		t.Errorf("expected the job to stop, got %d running", running.Load())
+	}
+	if v, _ := kv.Get(ctx, "lease"); string(v) != "server-2" {
+		t.Errorf("expected the other server's lease to stay, got %q", v)
+	}
+}
+
+func TestLeaderElector_WithoutBucketAlwaysLeads(t *testing.T) {
+	var running atomic.Int32
+	e := service.NewLeaderElector(nil, "server-1", 0)
+	e.Register("job", countingJob(&running))
+
+	cancel := e.Start(context.Background())
+	if !e.IsLeader() || running.Load() != 1 {
+		t.Fatal("expected the server to lead and run the job")
+	}
+	cancel()
+	if running.Load() != 0 {
+		t.Error("expected the job to stop")
+	}
+}
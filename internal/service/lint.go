@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/domain/lint"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+)
+
+var (
+	// ErrNoLinters is returned when no linter is configured or detected for
+	// a run's workspace.
+	ErrNoLinters = errors.New("lint: no linters configured or detected")
+	// ErrLintInProgress is returned when a run's quality gate is still
+	// executing.
+	ErrLintInProgress = errors.New("lint: quality gate of the run is in progress")
+)
+
+// stackLinters maps a detected stack to its linter.
+var stackLinters = map[string]lint.Tool{
+	"go":     lint.ToolGolangciLint,
+	"python": lint.ToolRuff,
+	"node":   lint.ToolESLint,
+}
+
+// LintPlan describes how the linters of a run are executed.
+type LintPlan struct {
+	Root    string // Workspace root that finding paths are relative to
+	Dir     string // Directory the linters run in
+	Linters []messagequeue.LinterPayload
+}
+
+// resolveLintPlan determines the linters of a run: the project's linters
+// config, or the linters of the stacks detected in the run's directory.
+// lint_command_<tool> overrides the command of a tool.
+func resolveLintPlan(ctx context.Context, store database.Store, r *run.Run) LintPlan {
+	plan := LintPlan{Root: r.WorktreePath}
+	proj, err := store.GetProject(ctx, r.ProjectID)
+	if err != nil {
+		proj = nil
+	} else {
+		plan.Root = r.Workspace(proj.WorkspacePath)
+	}
+	plan.Dir = plan.Root
+	if sp := runScope(ctx, store, r); sp != nil {
+		plan.Dir = filepath.Join(plan.Root, filepath.FromSlash(sp.Path))
+	}
+
+	var cfg map[string]string
+	if proj != nil {
+		cfg = proj.Config
+	}
+	var tools []lint.Tool
+	switch configured := strings.TrimSpace(cfg[lint.ConfigKeyLinters]); configured {
+	case "none":
+		return plan
+	case "":
+		if plan.Dir == "" {
+			return plan
+		}
+		for _, st := range DetectStack(plan.Dir) {
+			if t, ok := stackLinters[st.Name]; ok {
+				tools = append(tools, t)
+			}
+		}
+	default:
+		var unknown []string
+		tools, unknown = lint.ParseTools(configured)
+		if len(unknown) > 0 {
+			slog.Warn("unknown linters ignored", "project_id", r.ProjectID, "linters", unknown)
+		}
+	}
+	for _, t := range tools {
+		cmd := cfg[lint.ConfigKeyCommandPrefix+string(t)]
+		if cmd == "" {
+			cmd = t.DefaultCommand()
+		}
+		plan.Linters = append(plan.Linters, messagequeue.LinterPayload{Tool: string(t), Command: cmd})
+	}
+	return plan
+}
+
+// LintService runs static analysis tools on runs through the quality gate
+// worker, normalizes their findings and marks the findings in code the run
+// changed. New critical findings block the approval of a run's review.
+type LintService struct {
+	store   database.Store
+	queue   messagequeue.Queue
+	runtime *RuntimeService
+}
+
+// NewLintService creates a LintService. runtime provides the files a run
+// touched when its changes are no longer in the workspace diff; it may be
+// nil.
+func NewLintService(store database.Store, queue messagequeue.Queue, runtime *RuntimeService) *LintService {
+	return &LintService{store: store, queue: queue, runtime: runtime}
+}
+
+// Plan returns how the linters of r are executed.
+func (s *LintService) Plan(ctx context.Context, r *run.Run) LintPlan {
+	return resolveLintPlan(ctx, s.store, r)
+}
+
+// Run asks the worker to run the linters of a run in its workspace. The
+// result arrives as a quality gate result and is stored by Record.
+func (s *LintService) Run(ctx context.Context, runID string) (*LintPlan, error) {
+	r, err := s.store.GetRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if r.Status == run.StatusQualityGate {
+		return nil, ErrLintInProgress
+	}
+	plan := s.Plan(ctx, r)
+	if len(plan.Linters) == 0 {
+		return nil, ErrNoLinters
+	}
+	data, err := json.Marshal(messagequeue.QualityGateRequestPayload{
+		RunID:         r.ID,
+		ProjectID:     r.ProjectID,
+		WorkspacePath: plan.Dir,
+		Linters:       plan.Linters,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal lint request: %w", err)
+	}
+	if err := s.queue.Publish(ctx, messagequeue.SubjectQualityGateRequest, data); err != nil {
+		return nil, fmt.Errorf("publish lint request: %w", err)
+	}
+	slog.Info("linters requested", "run_id", r.ID, "linters", len(plan.Linters))
+	return &plan, nil
+}
+
+// Record normalizes the linter output of a quality gate result and stores
+// it as the lint report of the run. It returns nil if the result has no
+// linter output.
+func (s *LintService) Record(ctx context.Context, r *run.Run, result *messagequeue.QualityGateResultPayload) (*lint.Report, error) {
+	if len(result.LintResults) == 0 {
+		return nil, nil
+	}
+	plan := s.Plan(ctx, r)
+	commands := make(map[string]string, len(plan.Linters))
+	for _, l := range plan.Linters {
+		commands[l.Tool] = l.Command
+	}
+	changes := s.changes(ctx, r, plan.Root)
+
+	rep := &lint.Report{RunID: r.ID, ProjectID: r.ProjectID, Tools: []lint.ToolResult{}, Findings: []lint.Finding{}}
+	for _, lr := range result.LintResults {
+		res := lint.ToolResult{Tool: lint.Tool(lr.Tool), Command: commands[lr.Tool]}
+		findings, err := lint.Parse(res.Tool, lr.Output, plan.Dir, plan.Root)
+		if err != nil {
+			res.Error = err.Error()
+		}
+		lint.MarkNew(findings, changes)
+		rep.Add(res, findings)
+	}
+	rep.CreatedAt = time.Now().UTC()
+
+	data, err := json.Marshal(rep)
+	if err != nil {
+		return nil, fmt.Errorf("marshal lint report: %w", err)
+	}
+	art := &artifact.Artifact{
+		RunID:       r.ID,
+		ProjectID:   r.ProjectID,
+		Kind:        artifact.KindLintReport,
+		Name:        "lint-report.json",
+		ContentType: "application/json",
+		Data:        data,
+		Metadata: map[string]string{
+			"findings": strconv.Itoa(len(rep.Findings)),
+			"new":      strconv.Itoa(rep.New),
+			"blocking": strconv.Itoa(rep.Blocking),
+		},
+	}
+	if err := s.store.CreateArtifact(ctx, art); err != nil {
+		return nil, fmt.Errorf("store lint report: %w", err)
+	}
+	return rep, nil
+}
+
+// changes collects what the run changed: the uncommitted diff and the
+// untracked files of its workspace, plus the files its tool calls wrote
+// for changes that were already committed.
+func (s *LintService) changes(ctx context.Context, r *run.Run, root string) *lint.Changes {
+	changes := lint.ParseDiff("")
+	if root != "" {
+		gitCtx, cancel := context.WithTimeout(ctx, diffStatTimeout)
+		defer cancel()
+		if out, err := runDeliverGit(gitCtx, root, "diff", "-U0", "--no-color", "--no-ext-diff", "HEAD"); err == nil {
+			changes = lint.ParseDiff(out)
+		} else {
+			slog.Debug("lint diff skipped", "run_id", r.ID, "error", err)
+		}
+		if out, err := runDeliverGit(gitCtx, root, "ls-files", "--others", "--exclude-standard"); err == nil {
+			changes.AddFiles(strings.Split(strings.TrimSpace(out), "\n")...)
+		}
+	}
+	if s.runtime != nil {
+		touched, err := s.runtime.FilesTouched(ctx, r)
+		if err != nil {
+			slog.Warn("lint: files touched by run", "run_id", r.ID, "error", err)
+		}
+		for _, p := range touched {
+			changes.AddFiles(relWorkspacePath(root, p))
+		}
+	}
+	return changes
+}
+
+// Get returns the latest lint report of a run.
+func (s *LintService) Get(ctx context.Context, runID string) (*lint.Report, error) {
+	return latestLintReport(ctx, s.store, runID)
+}
+
+// latestLintReport loads the most recent lint report artifact of a run.
+func latestLintReport(ctx context.Context, store database.Store, runID string) (*lint.Report, error) {
+	arts, err := store.ListArtifactsByRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(arts) - 1; i >= 0; i-- {
+		if arts[i].Kind != artifact.KindLintReport {
+			continue
+		}
+		full, err := store.GetArtifact(ctx, arts[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		var rep lint.Report
+		if err := json.Unmarshal(full.Data, &rep); err != nil {
+			return nil, fmt.Errorf("decode lint report %s: %w", full.ID, err)
+		}
+		return &rep, nil
+	}
+	return nil, fmt.Errorf("lint report of run %s: %w", runID, domain.ErrNotFound)
+}
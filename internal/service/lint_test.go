@@ -0,0 +1,193 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/domain/lint"
+	"github.com/Strob0t/CodeForge/internal/domain/review"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestLintService_RunAndRecord(t *testing.T) {
+	dir := initDeliverTestRepo(t)
+	files := map[string]string{
+		"go.mod": "module example.com/app\n",
+		"a.go":   "package app\n\nfunc A() {}\n\nfunc B() {}\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{{"add", "."}, {"commit", "-m", "app"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s: %v", args, out, err)
+		}
+	}
+	// The run changes line 3 of a.go and adds new.go.
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package app\n\nfunc A() { x() }\n\nfunc B() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "new.go"), []byte("package app\n\nvar y int\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, store, queue, _ := newRuntimeTestEnv()
+	store.projects[0].WorkspacePath = dir
+	store.runs = append(store.runs, run.Run{ID: "run-1", TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", Status: run.StatusCompleted})
+	runtimeSvc := service.NewRuntimeService(store, queue, &runtimeMockBroadcaster{}, &runtimeMockEventStore{},
+		service.NewPolicyService("headless-safe-sandbox", nil), &config.Runtime{})
+	lintSvc := service.NewLintService(store, queue, runtimeSvc)
+	runtimeSvc.SetLintService(lintSvc)
+	ctx := context.Background()
+
+	if _, err := lintSvc.Run(ctx, "run-1"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	msg, ok := queue.lastMessage(messagequeue.SubjectQualityGateRequest)
+	if !ok {
+		t.Fatal("expected quality gate request")
+	}
+	var req messagequeue.QualityGateRequestPayload
+	_ = json.Unmarshal(msg.Data, &req)
+	if len(req.Linters) != 1 || req.Linters[0].Tool != "golangci-lint" || req.RunLint || req.RunTests || req.WorkspacePath != dir {
+		t.Fatalf("unexpected lint request %+v", req)
+	}
+
+	output := `{"Issues":[
+ {"FromLinter":"typecheck","Text":"undefined: x","Pos":{"Filename":"a.go","Line":3,"Column":12}},
+ {"FromLinter":"typecheck","Text":"old problem","Pos":{"Filename":"a.go","Line":5,"Column":1}},
+ {"FromLinter":"unused","Text":"var y is unused","Pos":{"Filename":"new.go","Line":3,"Column":5}}
+]}`
+	err := runtimeSvc.HandleQualityGateResult(ctx, &messagequeue.QualityGateResultPayload{
+		RunID:       "run-1",
+		LintResults: []messagequeue.LintResultPayload{{Tool: "golangci-lint", Output: output}},
+	})
+	if err != nil {
+		t.Fatalf("HandleQualityGateResult failed: %v", err)
+	}
+	rep, err := lintSvc.Get(ctx, "run-1")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(rep.Findings) != 3 || rep.New != 2 || rep.Blocking != 1 || rep.Tools[0].Command == "" {
+		t.Fatalf("unexpected report %+v", rep)
+	}
+	if f := rep.BlockingFindings()[0]; f.Path != "a.go" || f.Line != 3 {
+		t.Fatalf("unexpected blocking finding %+v", f)
+	}
+}
+
+func TestLintService_NoLinters(t *testing.T) {
+	_, store, queue, _ := newRuntimeTestEnv()
+	store.projects[0].WorkspacePath = writeWorkspace(t, map[string]string{"go.mod": "module example.com/app\n"})
+	store.projects[0].Config = map[string]string{lint.ConfigKeyLinters: "none"}
+	store.runs = append(store.runs, run.Run{ID: "run-1", TaskID: "task-1", ProjectID: "proj-1", Status: run.StatusCompleted})
+	lintSvc := service.NewLintService(store, queue, nil)
+	ctx := context.Background()
+	if _, err := lintSvc.Run(ctx, "run-1"); !errors.Is(err, service.ErrNoLinters) {
+		t.Fatalf("expected ErrNoLinters, got %v", err)
+	}
+	if _, err := lintSvc.Get(ctx, "run-1"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	store.projects[0].Config = map[string]string{lint.ConfigKeyLinters: "ruff", "lint_command_ruff": "ruff check --output-format=json src"}
+	plan, err := lintSvc.Run(ctx, "run-1")
+	if err != nil || len(plan.Linters) != 1 || plan.Linters[0].Command != "ruff check --output-format=json src" {
+		t.Fatalf("unexpected plan %+v (%v)", plan, err)
+	}
+}
+
+func TestReviewService_PublishBlockedByLint(t *testing.T) {
+	store, svc := newReviewTestEnv(t)
+	ctx := context.Background()
+	if _, err := svc.Record(ctx, "review-1", &review.Review{Summary: "LGTM", Approved: true}); err != nil {
+		t.Fatal(err)
+	}
+	rep := lint.Report{RunID: "review-1", ProjectID: "gh-proj"}
+	rep.Add(lint.ToolResult{Tool: lint.ToolRuff}, []lint.Finding{
+		{Tool: lint.ToolRuff, Rule: "F821", Path: "app.py", Line: 4, Severity: review.SeverityCritical, Message: "Undefined name `x`", New: true},
+		{Tool: lint.ToolRuff, Rule: "F821", Path: "lib.py", Line: 9, Severity: review.SeverityCritical, Message: "Undefined name `z`"},
+	})
+	data, _ := json.Marshal(rep)
+	if err := store.CreateArtifact(ctx, &artifact.Artifact{RunID: "review-1", ProjectID: "gh-proj", Kind: artifact.KindLintReport, Name: "lint-report.json", Data: data}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := svc.Publish(ctx, "review-1", &review.PublishRequest{PRNumber: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.LintBlocking != 1 || res.Status != "failure" {
+		t.Fatalf("expected review blocked by lint, got %+v", res)
+	}
+	found := false
+	for _, c := range currentPR.comments {
+		if c.Path == "app.py" && strings.Contains(c.Body, "[ruff F821] Undefined name `x`") {
+			found = true
+		}
+		if c.Path == "lib.py" {
+			t.Fatalf("pre-existing finding published: %+v", c)
+		}
+	}
+	if !found {
+		t.Fatalf("blocking finding not published: %+v", currentPR.comments)
+	}
+}
+
+func TestReviewService_ExportSARIF(t *testing.T) {
+	store, svc := newReviewTestEnv(t)
+	ctx := context.Background()
+
+	if _, err := svc.ExportSARIF(ctx, "review-1"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound without review and lint report, got %v", err)
+	}
+
+	// A lint report alone is enough to export.
+	rep := lint.Report{RunID: "review-1", ProjectID: "gh-proj"}
+	rep.Add(lint.ToolResult{Tool: lint.ToolRuff}, []lint.Finding{
+		{Tool: lint.ToolRuff, Rule: "S608", Path: "db.py", Line: 12, Severity: review.SeverityCritical, Message: "Possible SQL injection", New: true},
+	})
+	data, _ := json.Marshal(rep)
+	if err := store.CreateArtifact(ctx, &artifact.Artifact{RunID: "review-1", ProjectID: "gh-proj", Kind: artifact.KindLintReport, Name: "lint-report.json", Data: data}); err != nil {
+		t.Fatal(err)
+	}
+	log, err := svc.ExportSARIF(ctx, "review-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log.Runs) != 1 || log.Runs[0].Tool.Driver.Name != "ruff" {
+		t.Fatalf("expected only the lint run, got %+v", log.Runs)
+	}
+	if p := log.Runs[0].Tool.Driver.Rules[0].Properties; p == nil || p.SecuritySeverity == "" {
+		t.Fatalf("expected ruff S rule to be a security rule, got %+v", log.Runs[0].Tool.Driver.Rules[0])
+	}
+
+	if _, err := svc.Record(ctx, "review-1", &review.Review{Summary: "one problem", Findings: []review.Finding{
+		{Path: "db.py", Line: 12, Severity: review.SeverityCritical, Message: "Query built from user input"},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	log, err = svc.ExportSARIF(ctx, "review-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log.Runs) != 2 || log.Runs[0].Tool.Driver.Name != "CodeForge Review" || len(log.Runs[0].Results) != 1 {
+		t.Fatalf("expected review and lint runs, got %+v", log.Runs)
+	}
+}
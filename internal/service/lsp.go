@@ -0,0 +1,404 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/codegraph"
+	"github.com/Strob0t/CodeForge/internal/domain/featureflag"
+	"github.com/Strob0t/CodeForge/internal/domain/lsp"
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/langserver"
+)
+
+// LSPService answers code intelligence queries on project workspaces with
+// language servers, applies their renames, code actions and formatting
+// when the policy profile allows editing the files, and diffs the
+// diagnostics of changes for the delivery gate. A server is started per
+// workspace and language on first use and stopped when idle.
+type LSPService struct {
+	store  database.Store
+	cfg    config.LSP
+	start  langserver.Starter
+	flags  *FeatureFlagService
+	policy *PolicyService
+
+	mu      sync.Mutex
+	servers map[lspKey]*lspServer
+}
+
+type lspKey struct {
+	root     string
+	language codegraph.Language
+}
+
+type lspServer struct {
+	ready chan struct{} // Closed when srv or err is set
+	srv   langserver.Server
+	err   error
+	used  time.Time
+}
+
+// NewLSPService creates an LSPService starting servers with start.
+func NewLSPService(store database.Store, cfg config.LSP, start langserver.Starter) *LSPService {
+	return &LSPService{store: store, cfg: cfg, start: start, servers: make(map[lspKey]*lspServer)}
+}
+
+// SetFeatureFlagService gates the service by the lsp feature flag. Without
+// it the service is enabled for all projects.
+func (s *LSPService) SetFeatureFlagService(f *FeatureFlagService) {
+	s.flags = f
+}
+
+// SetPolicyService sets the policy engine edits are checked against.
+// Without it edits are only previewed, never applied.
+func (s *LSPService) SetPolicyService(p *PolicyService) {
+	s.policy = p
+}
+
+// Definition returns where the symbol at a position is declared.
+func (s *LSPService) Definition(ctx context.Context, projectID string, req *lsp.PositionRequest) ([]lsp.Location, error) {
+	return lspQuery(ctx, s, projectID, req.Path, req.Validate(), func(ctx context.Context, srv langserver.Server) ([]lsp.Location, error) {
+		return srv.Definition(ctx, req.Path, req.Position())
+	})
+}
+
+// References returns the uses of the symbol at a position.
+func (s *LSPService) References(ctx context.Context, projectID string, req *lsp.PositionRequest) ([]lsp.Location, error) {
+	return lspQuery(ctx, s, projectID, req.Path, req.Validate(), func(ctx context.Context, srv langserver.Server) ([]lsp.Location, error) {
+		return srv.References(ctx, req.Path, req.Position())
+	})
+}
+
+// Hover returns the documentation of the symbol at a position, or nil.
+func (s *LSPService) Hover(ctx context.Context, projectID string, req *lsp.PositionRequest) (*lsp.Hover, error) {
+	return lspQuery(ctx, s, projectID, req.Path, req.Validate(), func(ctx context.Context, srv langserver.Server) (*lsp.Hover, error) {
+		return srv.Hover(ctx, req.Path, req.Position())
+	})
+}
+
+// Symbols returns the declarations of a file.
+func (s *LSPService) Symbols(ctx context.Context, projectID, path string) ([]lsp.Symbol, error) {
+	req := &lsp.FormatRequest{Path: path}
+	return lspQuery(ctx, s, projectID, path, req.Validate(), func(ctx context.Context, srv langserver.Server) ([]lsp.Symbol, error) {
+		return srv.Symbols(ctx, path)
+	})
+}
+
+// CodeActions returns the code actions for a range of a file.
+func (s *LSPService) CodeActions(ctx context.Context, projectID string, req *lsp.CodeActionRequest) ([]lsp.CodeAction, error) {
+	return lspQuery(ctx, s, projectID, req.Path, req.Validate(), func(ctx context.Context, srv langserver.Server) ([]lsp.CodeAction, error) {
+		return srv.CodeActions(ctx, req.Path, req.Range)
+	})
+}
+
+// Rename renames the symbol at a position across the workspace, or only
+// returns the edit unless req.Apply is set.
+func (s *LSPService) Rename(ctx context.Context, projectID string, req *lsp.RenameRequest) (*lsp.EditResult, error) {
+	edit, err := lspQuery(ctx, s, projectID, req.Path, req.Validate(), func(ctx context.Context, srv langserver.Server) (*lsp.WorkspaceEdit, error) {
+		return srv.Rename(ctx, req.Path, req.Position(), req.NewName)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.edit(ctx, projectID, lsp.OpRename, req.PolicyProfile, edit, req.Apply)
+}
+
+// ApplyCodeAction applies the code action titled req.Title.
+func (s *LSPService) ApplyCodeAction(ctx context.Context, projectID string, req *lsp.CodeActionRequest) (*lsp.EditResult, error) {
+	if req.Title == "" {
+		return nil, fmt.Errorf("%w: title is required", lsp.ErrInvalidRequest)
+	}
+	actions, err := s.CodeActions(ctx, projectID, req)
+	if err != nil {
+		return nil, err
+	}
+	for i := range actions {
+		if actions[i].Title != req.Title {
+			continue
+		}
+		if actions[i].Edit == nil {
+			return nil, fmt.Errorf("%w: %q only runs a server command", lsp.ErrActionNotFound, req.Title)
+		}
+		return s.edit(ctx, projectID, lsp.OpCodeAction, req.PolicyProfile, actions[i].Edit, true)
+	}
+	return nil, fmt.Errorf("%w: %q", lsp.ErrActionNotFound, req.Title)
+}
+
+// Format formats a file, or only returns the edits unless req.Apply is
+// set.
+func (s *LSPService) Format(ctx context.Context, projectID string, req *lsp.FormatRequest) (*lsp.EditResult, error) {
+	edits, err := lspQuery(ctx, s, projectID, req.Path, req.Validate(), func(ctx context.Context, srv langserver.Server) ([]lsp.TextEdit, error) {
+		return srv.Format(ctx, req.Path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	edit := &lsp.WorkspaceEdit{}
+	if len(edits) > 0 {
+		edit.Files = []lsp.FileEdit{{Path: req.Path, Edits: edits}}
+	}
+	return s.edit(ctx, projectID, lsp.OpFormat, req.PolicyProfile, edit, req.Apply)
+}
+
+// DiagnosticsDiff checks the given changed files of a git checkout of a
+// project's workspace (the workspace itself when root is empty) and
+// returns the diagnostics the changes introduce over the files' content at
+// HEAD. The committed content is checked in place of each changed file,
+// against the rest of the checkout as it is. Files without a language
+// server are skipped, and added files have no baseline.
+func (s *LSPService) DiagnosticsDiff(ctx context.Context, projectID, root string, paths []string) ([]lsp.Diagnostic, error) {
+	proj, err := s.project(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if root == "" {
+		root = proj.WorkspacePath
+	}
+	added := []lsp.Diagnostic{}
+	for _, p := range paths {
+		if !filepath.IsLocal(p) {
+			return nil, fmt.Errorf("%w: %s is outside the workspace", lsp.ErrInvalidRequest, p)
+		}
+		key, err := s.keyOf(root, p)
+		if errors.Is(err, lsp.ErrNoServer) {
+			continue
+		}
+		var baseline []lsp.Diagnostic
+		if committed, err := runDeliverGit(ctx, root, "show", "HEAD:"+filepath.ToSlash(p)); err == nil {
+			if baseline, err = lspCall(ctx, s, key, func(ctx context.Context, srv langserver.Server) ([]lsp.Diagnostic, error) {
+				return srv.Diagnostics(ctx, p, []byte(committed))
+			}); err != nil {
+				return nil, fmt.Errorf("diagnostics of %s at HEAD: %w", p, err)
+			}
+		}
+		current, err := lspCall(ctx, s, key, func(ctx context.Context, srv langserver.Server) ([]lsp.Diagnostic, error) {
+			return srv.Diagnostics(ctx, p, nil)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("diagnostics of %s: %w", p, err)
+		}
+		added = append(added, lsp.NewDiagnostics(baseline, current)...)
+	}
+	return added, nil
+}
+
+// edit checks the files of an edit against the policy profile and writes
+// them when apply is set. Nothing is written unless every file may be
+// edited and every edit applies.
+func (s *LSPService) edit(ctx context.Context, projectID, op, profile string, edit *lsp.WorkspaceEdit, apply bool) (*lsp.EditResult, error) {
+	res := &lsp.EditResult{Edit: *edit}
+	if res.Edit.Files == nil {
+		res.Edit.Files = []lsp.FileEdit{}
+	}
+	if !apply || len(edit.Files) == 0 {
+		return res, nil
+	}
+	if s.policy == nil {
+		return nil, fmt.Errorf("%w: no policy engine to check %s against", lsp.ErrEditDenied, op)
+	}
+	if profile == "" {
+		profile = s.policy.DefaultProfile()
+	}
+	proj, err := s.project(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	contents := make([][]byte, len(edit.Files))
+	for i := range edit.Files {
+		p := edit.Files[i].Path
+		if !filepath.IsLocal(p) {
+			return nil, fmt.Errorf("%w: %s is outside the workspace", lsp.ErrEditDenied, p)
+		}
+		d, err := s.policy.Evaluate(ctx, profile, policy.ToolCall{Tool: lsp.PolicyTool, Command: op, Path: p})
+		if err != nil {
+			return nil, err
+		}
+		if d != policy.DecisionAllow {
+			return nil, fmt.Errorf("%w: %s of %s (%s in profile %s)", lsp.ErrEditDenied, op, p, d, profile)
+		}
+		abs := filepath.Join(proj.WorkspacePath, filepath.FromSlash(p))
+		content, err := os.ReadFile(abs)
+		if err != nil {
+			return nil, err
+		}
+		if contents[i], err = lsp.ApplyEdits(content, edit.Files[i].Edits); err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	for i := range edit.Files {
+		abs := filepath.Join(proj.WorkspacePath, filepath.FromSlash(edit.Files[i].Path))
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(abs, contents[i], info.Mode().Perm()); err != nil {
+			return nil, err
+		}
+	}
+	slog.Info("lsp edit applied", "project_id", projectID, "operation", op, "files", len(edit.Files), "policy_profile", profile)
+	res.Applied = true
+	return res, nil
+}
+
+// lspQuery calls the language server of a file of a request, unless the
+// request is invalid.
+func lspQuery[T any](ctx context.Context, s *LSPService, projectID, path string, invalid error, call func(context.Context, langserver.Server) (T, error)) (T, error) {
+	var zero T
+	if invalid != nil {
+		return zero, invalid
+	}
+	proj, err := s.project(ctx, projectID)
+	if err != nil {
+		return zero, err
+	}
+	key, err := s.keyOf(proj.WorkspacePath, path)
+	if err != nil {
+		return zero, err
+	}
+	return lspCall(ctx, s, key, call)
+}
+
+// lspCall calls the language server of key, bounded by the request
+// timeout. A server that exited is dropped so the next call starts it
+// again.
+func lspCall[T any](ctx context.Context, s *LSPService, key lspKey, call func(context.Context, langserver.Server) (T, error)) (T, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.RequestTimeout)
+	defer cancel()
+	srv, err := s.server(ctx, key)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	res, err := call(ctx, srv)
+	if errors.Is(err, langserver.ErrClosed) {
+		s.drop(key, srv)
+	}
+	return res, err
+}
+
+// project returns a project with a workspace the lsp flag is on for.
+func (s *LSPService) project(ctx context.Context, projectID string) (*project.Project, error) {
+	proj, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if s.flags != nil && !s.flags.Enabled(ctx, featureflag.LSP, proj.ID) {
+		return nil, lsp.ErrDisabled
+	}
+	if proj.WorkspacePath == "" {
+		return nil, fmt.Errorf("project %s has no workspace (not cloned)", proj.ID)
+	}
+	return proj, nil
+}
+
+// keyOf returns the server key of a file of the workspace root.
+func (s *LSPService) keyOf(root, path string) (lspKey, error) {
+	lang := codegraph.LanguageOf(path)
+	if len(s.cfg.Servers[string(lang)]) == 0 {
+		return lspKey{}, fmt.Errorf("%w: %s", lsp.ErrNoServer, path)
+	}
+	return lspKey{root: root, language: lang}, nil
+}
+
+// server returns the running server of key, starting it if needed.
+// Concurrent callers wait for the same start.
+func (s *LSPService) server(ctx context.Context, key lspKey) (langserver.Server, error) {
+	s.mu.Lock()
+	e, ok := s.servers[key]
+	if !ok {
+		e = &lspServer{ready: make(chan struct{})}
+		s.servers[key] = e
+	}
+	e.used = time.Now()
+	s.mu.Unlock()
+
+	if !ok {
+		e.srv, e.err = s.start(ctx, s.cfg.Servers[string(key.language)], key.root)
+		if e.err != nil {
+			s.mu.Lock()
+			delete(s.servers, key)
+			s.mu.Unlock()
+			slog.Warn("language server failed to start", "language", key.language, "root", key.root, "error", e.err)
+		} else {
+			slog.Info("language server started", "language", key.language, "root", key.root)
+		}
+		close(e.ready)
+	}
+	select {
+	case <-e.ready:
+		return e.srv, e.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// drop forgets srv if it is still the server of key and closes it.
+func (s *LSPService) drop(key lspKey, srv langserver.Server) {
+	s.mu.Lock()
+	if e, ok := s.servers[key]; ok && e.srv == srv {
+		delete(s.servers, key)
+	} else {
+		srv = nil
+	}
+	s.mu.Unlock()
+	if srv != nil {
+		_ = srv.Close()
+	}
+}
+
+// Start stops servers unused for the idle timeout until the returned
+// function is called, which also stops the others.
+func (s *LSPService) Start(ctx context.Context) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		t := time.NewTicker(max(s.cfg.IdleTimeout/4, time.Second))
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				s.closeIdle(time.Now().Add(-s.cfg.IdleTimeout))
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+		s.closeIdle(time.Now().Add(time.Hour))
+	}
+}
+
+// closeIdle closes the started servers last used before cutoff.
+func (s *LSPService) closeIdle(cutoff time.Time) {
+	var idle []langserver.Server
+	s.mu.Lock()
+	for k, e := range s.servers {
+		select {
+		case <-e.ready:
+		default:
+			continue // Still starting
+		}
+		if e.used.Before(cutoff) {
+			delete(s.servers, k)
+			idle = append(idle, e.srv)
+		}
+	}
+	s.mu.Unlock()
+	for _, srv := range idle {
+		_ = srv.Close()
+	}
+}
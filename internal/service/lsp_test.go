@@ -0,0 +1,263 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/featureflag"
+	"github.com/Strob0t/CodeForge/internal/domain/lsp"
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/port/langserver"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+// fakeLangServer renames "total" on the first line of invoice.go and
+// main.go, formats by prepending a comment, and reports an error on every
+// line containing "bad".
+type fakeLangServer struct {
+	root   string
+	mu     sync.Mutex
+	closed bool
+}
+
+func word(line, col, end int) lsp.Range {
+	return lsp.Range{Start: lsp.Position{Line: line, Column: col}, End: lsp.Position{Line: line, Column: end}}
+}
+
+func (f *fakeLangServer) Definition(_ context.Context, path string, _ lsp.Position) ([]lsp.Location, error) {
+	if f.isClosed() {
+		return nil, langserver.ErrClosed
+	}
+	return []lsp.Location{{Path: path, Range: word(1, 6, 11)}}, nil
+}
+
+func (f *fakeLangServer) References(ctx context.Context, path string, pos lsp.Position) ([]lsp.Location, error) {
+	return f.Definition(ctx, path, pos)
+}
+
+func (f *fakeLangServer) Hover(context.Context, string, lsp.Position) (*lsp.Hover, error) {
+	return &lsp.Hover{Contents: "func total() int"}, nil
+}
+
+func (f *fakeLangServer) Symbols(context.Context, string) ([]lsp.Symbol, error) {
+	return []lsp.Symbol{{Name: "total", Kind: "function", Range: word(1, 1, 17)}}, nil
+}
+
+func (f *fakeLangServer) Rename(_ context.Context, _ string, _ lsp.Position, newName string) (*lsp.WorkspaceEdit, error) {
+	return &lsp.WorkspaceEdit{Files: []lsp.FileEdit{
+		{Path: "invoice.go", Edits: []lsp.TextEdit{{Range: word(1, 6, 11), NewText: newName}}},
+		{Path: ".env", Edits: []lsp.TextEdit{{Range: word(1, 1, 6), NewText: newName}}},
+	}}, nil
+}
+
+func (f *fakeLangServer) CodeActions(context.Context, string, lsp.Range) ([]lsp.CodeAction, error) {
+	return []lsp.CodeAction{
+		{Title: "Add doc comment", Kind: "quickfix", Edit: &lsp.WorkspaceEdit{Files: []lsp.FileEdit{
+			{Path: "invoice.go", Edits: []lsp.TextEdit{{Range: word(1, 1, 1), NewText: "// total sums.\n"}}},
+		}}},
+		{Title: "Run generate"},
+	}, nil
+}
+
+func (f *fakeLangServer) Format(context.Context, string) ([]lsp.TextEdit, error) {
+	return []lsp.TextEdit{{Range: word(1, 1, 1), NewText: "// Formatted.\n"}}, nil
+}
+
+func (f *fakeLangServer) Diagnostics(_ context.Context, path string, content []byte) ([]lsp.Diagnostic, error) {
+	if content == nil {
+		var err error
+		if content, err = os.ReadFile(filepath.Join(f.root, path)); err != nil {
+			return nil, err
+		}
+	}
+	var diags []lsp.Diagnostic
+	for i, line := range strings.Split(string(content), "\n") {
+		if strings.Contains(line, "bad") {
+			diags = append(diags, lsp.Diagnostic{Path: path, Range: word(i+1, 1, len(line)+1), Severity: lsp.SeverityError, Message: "bad line"})
+		}
+	}
+	return diags, nil
+}
+
+func (f *fakeLangServer) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func (f *fakeLangServer) isClosed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
+
+type lspTestEnv struct {
+	svc     *service.LSPService
+	flags   *service.FeatureFlagService
+	dir     string
+	mu      sync.Mutex
+	started []*fakeLangServer
+}
+
+func newLSPTestEnv(t *testing.T) *lspTestEnv {
+	t.Helper()
+	env := &lspTestEnv{dir: writeWorkspace(t, map[string]string{
+		"invoice.go": "func total() {}\n",
+		".env":       "TOKEN=secret\n",
+		"README.md":  "# Invoices\n",
+	})}
+	store := &runtimeMockStore{projects: []project.Project{{ID: "proj-1", WorkspacePath: env.dir}}}
+	cfg := config.LSP{Servers: map[string][]string{"go": {"gopls"}}, RequestTimeout: 5 * time.Second, IdleTimeout: time.Minute}
+	env.svc = service.NewLSPService(store, cfg, func(_ context.Context, command []string, root string) (langserver.Server, error) {
+		if command[0] != "gopls" || root != env.dir {
+			t.Errorf("unexpected start of %v in %s", command, root)
+		}
+		srv := &fakeLangServer{root: root}
+		env.mu.Lock()
+		env.started = append(env.started, srv)
+		env.mu.Unlock()
+		return srv, nil
+	})
+	env.flags = service.NewFeatureFlagService(store)
+	env.svc.SetFeatureFlagService(env.flags)
+	env.svc.SetPolicyService(service.NewPolicyService("headless-safe-sandbox", nil))
+	if _, err := env.flags.Set(context.Background(), featureflag.LSP, &featureflag.SetRequest{ProjectID: "proj-1", Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	return env
+}
+
+func (env *lspTestEnv) read(t *testing.T, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(env.dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestLSPServiceQueries(t *testing.T) {
+	env := newLSPTestEnv(t)
+	ctx := context.Background()
+	pos := &lsp.PositionRequest{Path: "invoice.go", Line: 1, Column: 6}
+
+	defs, err := env.svc.Definition(ctx, "proj-1", pos)
+	if err != nil || len(defs) != 1 || defs[0].Path != "invoice.go" {
+		t.Fatalf("Definition = %+v, %v", defs, err)
+	}
+	if _, err := env.svc.References(ctx, "proj-1", pos); err != nil {
+		t.Fatal(err)
+	}
+	if h, err := env.svc.Hover(ctx, "proj-1", pos); err != nil || h.Contents != "func total() int" {
+		t.Fatalf("Hover = %+v, %v", h, err)
+	}
+	if syms, err := env.svc.Symbols(ctx, "proj-1", "invoice.go"); err != nil || len(syms) != 1 {
+		t.Fatalf("Symbols = %+v, %v", syms, err)
+	}
+	if len(env.started) != 1 {
+		t.Fatalf("expected one server for the workspace, started %d", len(env.started))
+	}
+
+	if _, err := env.svc.Symbols(ctx, "proj-1", "README.md"); !errors.Is(err, lsp.ErrNoServer) {
+		t.Fatalf("expected ErrNoServer for markdown, got %v", err)
+	}
+	if _, err := env.svc.Definition(ctx, "proj-1", &lsp.PositionRequest{Path: "../x.go", Line: 1, Column: 1}); !errors.Is(err, lsp.ErrInvalidRequest) {
+		t.Fatalf("expected ErrInvalidRequest for a path outside the workspace, got %v", err)
+	}
+
+	// A server that exited is started again on the next query.
+	_ = env.started[0].Close()
+	if _, err := env.svc.Definition(ctx, "proj-1", pos); !errors.Is(err, langserver.ErrClosed) {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+	if _, err := env.svc.Definition(ctx, "proj-1", pos); err != nil || len(env.started) != 2 {
+		t.Fatalf("expected a restarted server, got %v with %d started", err, len(env.started))
+	}
+
+	if _, err := env.flags.Set(ctx, featureflag.LSP, &featureflag.SetRequest{ProjectID: "proj-1", Enabled: false}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.svc.Definition(ctx, "proj-1", pos); !errors.Is(err, lsp.ErrDisabled) {
+		t.Fatalf("expected ErrDisabled, got %v", err)
+	}
+}
+
+func TestLSPServiceEdits(t *testing.T) {
+	env := newLSPTestEnv(t)
+	ctx := context.Background()
+	rename := &lsp.RenameRequest{PositionRequest: lsp.PositionRequest{Path: "invoice.go", Line: 1, Column: 6}, NewName: "sum"}
+
+	// Previews are returned without a policy check.
+	res, err := env.svc.Rename(ctx, "proj-1", rename)
+	if err != nil || res.Applied || len(res.Edit.Files) != 2 {
+		t.Fatalf("Rename preview = %+v, %v", res, err)
+	}
+
+	// The safe sandbox denies editing .env, so nothing is written.
+	rename.Apply = true
+	if _, err := env.svc.Rename(ctx, "proj-1", rename); !errors.Is(err, lsp.ErrEditDenied) {
+		t.Fatalf("expected ErrEditDenied, got %v", err)
+	}
+	if got := env.read(t, "invoice.go"); got != "func total() {}\n" {
+		t.Fatalf("expected invoice.go untouched, got %q", got)
+	}
+	rename.PolicyProfile = "plan-readonly"
+	if _, err := env.svc.Rename(ctx, "proj-1", rename); !errors.Is(err, lsp.ErrEditDenied) {
+		t.Fatalf("expected ErrEditDenied in plan mode, got %v", err)
+	}
+
+	format, err := env.svc.Format(ctx, "proj-1", &lsp.FormatRequest{Path: "invoice.go", Apply: true})
+	if err != nil || !format.Applied {
+		t.Fatalf("Format = %+v, %v", format, err)
+	}
+	if got := env.read(t, "invoice.go"); got != "// Formatted.\nfunc total() {}\n" {
+		t.Fatalf("unexpected formatted file %q", got)
+	}
+
+	actions, err := env.svc.CodeActions(ctx, "proj-1", &lsp.CodeActionRequest{Path: "invoice.go"})
+	if err != nil || len(actions) != 2 {
+		t.Fatalf("CodeActions = %+v, %v", actions, err)
+	}
+	if _, err := env.svc.ApplyCodeAction(ctx, "proj-1", &lsp.CodeActionRequest{Path: "invoice.go", Title: "Run generate"}); !errors.Is(err, lsp.ErrActionNotFound) {
+		t.Fatalf("expected ErrActionNotFound for a command, got %v", err)
+	}
+	if _, err := env.svc.ApplyCodeAction(ctx, "proj-1", &lsp.CodeActionRequest{Path: "invoice.go", Title: "Add doc comment", PolicyProfile: "trusted-mount-autonomous"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := env.read(t, "invoice.go"); got != "// total sums.\n// Formatted.\nfunc total() {}\n" {
+		t.Fatalf("unexpected file after the code action %q", got)
+	}
+}
+
+func TestLSPServiceEditPolicyRule(t *testing.T) {
+	env := newLSPTestEnv(t)
+	custom := policy.PresetTrustedMountAutonomous()
+	custom.Name = "no-lsp-format"
+	custom.Rules = append([]policy.PermissionRule{{Specifier: policy.ToolSpecifier{Tool: lsp.PolicyTool, SubPattern: lsp.OpFormat}, Decision: policy.DecisionDeny}}, custom.Rules...)
+	env.svc.SetPolicyService(service.NewPolicyService("headless-safe-sandbox", []policy.PolicyProfile{custom}))
+
+	if _, err := env.svc.Format(context.Background(), "proj-1", &lsp.FormatRequest{Path: "invoice.go", Apply: true, PolicyProfile: "no-lsp-format"}); !errors.Is(err, lsp.ErrEditDenied) {
+		t.Fatalf("expected the format rule to deny, got %v", err)
+	}
+}
+
+func TestLSPServiceIdle(t *testing.T) {
+	env := newLSPTestEnv(t)
+	if _, err := env.svc.Symbols(context.Background(), "proj-1", "invoice.go"); err != nil {
+		t.Fatal(err)
+	}
+	stop := env.svc.Start(context.Background())
+	stop()
+	if !env.started[0].isClosed() {
+		t.Fatal("expected the server closed on stop")
+	}
+}
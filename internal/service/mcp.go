@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/mcpclient"
+	"github.com/Strob0t/CodeForge/internal/domain/mcpserver"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/vault"
+)
+
+// tokenLeeway is how long before expiry an access token is refreshed.
+const tokenLeeway = time.Minute
+
+// ErrMCPServerDisabled is returned for calls to a disabled MCP server.
+var ErrMCPServerDisabled = errors.New("mcp server is disabled")
+
+// MCPService manages external MCP servers and calls their tools. OAuth
+// client secrets and tokens are encrypted with the vault; tokens are
+// fetched with the client credentials grant and refreshed before they
+// expire.
+type MCPService struct {
+	store database.Store
+	vault *vault.Vault
+	http  *http.Client
+	now   func() time.Time
+	mu    sync.Mutex // Serializes token refreshes
+}
+
+// NewMCPService creates an MCPService. v may be nil, in which case only
+// servers without auth can be registered.
+func NewMCPService(store database.Store, v *vault.Vault) *MCPService {
+	return &MCPService{store: store, vault: v, http: &http.Client{Timeout: 60 * time.Second}, now: time.Now}
+}
+
+// SetHTTPClient replaces the client used for MCP and token requests.
+func (s *MCPService) SetHTTPClient(c *http.Client) {
+	s.http = c
+}
+
+// Create registers an MCP server.
+func (s *MCPService) Create(ctx context.Context, req *mcpserver.CreateRequest) (*mcpserver.Server, error) {
+	if err := req.Validate(false); err != nil {
+		return nil, err
+	}
+	m := &mcpserver.Server{}
+	req.Apply(m)
+	if err := s.setSecret(m, req.ClientSecret); err != nil {
+		return nil, err
+	}
+	if err := s.store.CreateMCPServer(ctx, m); err != nil {
+		return nil, err
+	}
+	slog.Info("mcp server registered", "mcp_server_id", m.ID, "name", m.Name, "auth", m.Auth)
+	return m, nil
+}
+
+// Get returns an MCP server by ID.
+func (s *MCPService) Get(ctx context.Context, id string) (*mcpserver.Server, error) {
+	return s.store.GetMCPServer(ctx, id)
+}
+
+// List returns all MCP servers.
+func (s *MCPService) List(ctx context.Context) ([]mcpserver.Server, error) {
+	return s.store.ListMCPServers(ctx)
+}
+
+// Update replaces the fields of an MCP server. An empty client secret
+// keeps the stored one. The cached token is dropped, since the token URL,
+// client or scopes may have changed.
+func (s *MCPService) Update(ctx context.Context, id string, req *mcpserver.CreateRequest) (*mcpserver.Server, error) {
+	m, err := s.store.GetMCPServer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Validate(m.SecretCiphertext != nil); err != nil {
+		return nil, err
+	}
+	secret := req.ClientSecret
+	if secret == "" && req.Auth == mcpserver.AuthOAuth2 {
+		// The secret is bound to the server name; re-encrypt it on renames.
+		if secret, err = s.clientSecret(m); err != nil {
+			return nil, err
+		}
+	}
+	req.Apply(m)
+	if err := s.setSecret(m, secret); err != nil {
+		return nil, err
+	}
+	m.TokenCiphertext, m.TokenExpiresAt = nil, nil
+	if err := s.store.UpdateMCPServer(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Delete removes an MCP server.
+func (s *MCPService) Delete(ctx context.Context, id string) error {
+	return s.store.DeleteMCPServer(ctx, id)
+}
+
+// Tools connects to a server and lists its tools.
+func (s *MCPService) Tools(ctx context.Context, id string) ([]mcpserver.Tool, error) {
+	var tools []mcpserver.Tool
+	err := s.withClient(ctx, id, func(c *mcpclient.Client) (err error) {
+		tools, err = c.ListTools(ctx)
+		return err
+	})
+	return tools, err
+}
+
+// CallTool connects to a server and calls one of its tools.
+func (s *MCPService) CallTool(ctx context.Context, id string, req *mcpserver.CallRequest) (*mcpserver.CallResult, error) {
+	if req.Name == "" {
+		return nil, mcpserver.ErrToolRequired
+	}
+	var res *mcpserver.CallResult
+	err := s.withClient(ctx, id, func(c *mcpclient.Client) (err error) {
+		res, err = c.CallTool(ctx, req)
+		return err
+	})
+	return res, err
+}
+
+// withClient opens a session with a server and runs fn. If the server
+// rejects a cached token, the token is fetched again and fn retried once.
+func (s *MCPService) withClient(ctx context.Context, id string, fn func(*mcpclient.Client) error) error {
+	m, err := s.store.GetMCPServer(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !m.Enabled {
+		return ErrMCPServerDisabled
+	}
+	force := false
+	for {
+		token, fresh, err := s.token(ctx, m, force)
+		if err != nil {
+			return fmt.Errorf("mcp server %s: %w", m.Name, err)
+		}
+		c := mcpclient.New(m.URL, token, s.http)
+		err = c.Initialize(ctx)
+		if err == nil {
+			err = fn(c)
+			if cerr := c.Close(ctx); cerr != nil {
+				slog.Debug("close mcp session failed", "mcp_server_id", m.ID, "error", cerr)
+			}
+		}
+		if errors.Is(err, mcpclient.ErrUnauthorized) && m.Auth == mcpserver.AuthOAuth2 && !fresh {
+			force = true
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("mcp server %s: %w", m.Name, err)
+		}
+		return nil
+	}
+}
+
+// token returns the access token of a server, fetching a new one if the
+// cached token expired or force is set. fresh reports whether it was just
+// fetched.
+func (s *MCPService) token(ctx context.Context, m *mcpserver.Server, force bool) (token string, fresh bool, err error) {
+	if m.Auth != mcpserver.AuthOAuth2 {
+		return "", true, nil
+	}
+	if s.vault == nil {
+		return "", false, ErrSecretsUnavailable
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Another request may have refreshed the token meanwhile.
+	current, err := s.store.GetMCPServer(ctx, m.ID)
+	if err != nil {
+		return "", false, err
+	}
+	*m = *current
+	cached := s.cachedToken(m)
+	if !force && cached.Valid(s.now(), tokenLeeway) {
+		return cached.AccessToken, false, nil
+	}
+
+	secret, err := s.clientSecret(m)
+	if err != nil {
+		return "", false, err
+	}
+	var t *mcpserver.Token
+	if cached != nil && cached.RefreshToken != "" {
+		t, err = mcpclient.FetchToken(ctx, s.http, m.OAuth, secret, cached.RefreshToken, s.now())
+		if err != nil {
+			slog.Info("mcp token refresh failed, requesting a new token", "mcp_server_id", m.ID, "error", err)
+		}
+	}
+	if t == nil {
+		if t, err = mcpclient.FetchToken(ctx, s.http, m.OAuth, secret, "", s.now()); err != nil {
+			return "", false, err
+		}
+	}
+
+	data, _ := json.Marshal(t)
+	if m.TokenCiphertext, err = s.vault.Encrypt(data, mcpAAD("token", m.Name)); err != nil {
+		return "", false, err
+	}
+	m.TokenExpiresAt = nil
+	if !t.ExpiresAt.IsZero() {
+		m.TokenExpiresAt = &t.ExpiresAt
+	}
+	if err := s.store.UpdateMCPServer(ctx, m); err != nil {
+		return "", false, fmt.Errorf("store token: %w", err)
+	}
+	slog.Info("mcp token issued", "mcp_server_id", m.ID, "expires_at", m.TokenExpiresAt)
+	return t.AccessToken, true, nil
+}
+
+// cachedToken decrypts the stored token, or returns nil.
+func (s *MCPService) cachedToken(m *mcpserver.Server) *mcpserver.Token {
+	if m.TokenCiphertext == nil {
+		return nil
+	}
+	data, err := s.vault.Decrypt(m.TokenCiphertext, mcpAAD("token", m.Name))
+	if err != nil {
+		slog.Warn("stored mcp token is unreadable", "mcp_server_id", m.ID, "error", err)
+		return nil
+	}
+	var t mcpserver.Token
+	if json.Unmarshal(data, &t) != nil {
+		return nil
+	}
+	return &t
+}
+
+func (s *MCPService) clientSecret(m *mcpserver.Server) (string, error) {
+	if m.SecretCiphertext == nil {
+		return "", mcpserver.ErrOAuthIncomplete
+	}
+	if s.vault == nil {
+		return "", ErrSecretsUnavailable
+	}
+	data, err := s.vault.Decrypt(m.SecretCiphertext, mcpAAD("secret", m.Name))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// setSecret encrypts the client secret of an OAuth server, or clears it
+// for servers without auth.
+func (s *MCPService) setSecret(m *mcpserver.Server, secret string) error {
+	if m.Auth != mcpserver.AuthOAuth2 {
+		m.SecretCiphertext = nil
+		return nil
+	}
+	if s.vault == nil {
+		return ErrSecretsUnavailable
+	}
+	ct, err := s.vault.Encrypt([]byte(secret), mcpAAD("secret", m.Name))
+	if err != nil {
+		return err
+	}
+	m.SecretCiphertext = ct
+	return nil
+}
+
+// mcpAAD binds a ciphertext to its kind and server.
+func mcpAAD(kind, name string) []byte {
+	return []byte("mcp-server:" + kind + ":" + name)
+}
@@ -0,0 +1,215 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/mcpserver"
+	"github.com/Strob0t/CodeForge/internal/service"
+	"github.com/Strob0t/CodeForge/internal/vault"
+)
+
+// fakeMCPHost is an MCP server behind an OAuth authorization server. Only
+// the latest issued access token is accepted.
+type fakeMCPHost struct {
+	mu      sync.Mutex
+	valid   string   // Accepted access token
+	refresh string   // Latest refresh token
+	grants  []string // Grant types requested, in order
+	scopes  []string // Scopes requested, in order
+	issued  int
+}
+
+func (h *fakeMCPHost) serveToken(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if id, secret, ok := r.BasicAuth(); !ok || id != "codeforge" || secret != "s3cret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":"invalid_client"}`))
+		return
+	}
+	grant := r.PostFormValue("grant_type")
+	if grant == "refresh_token" && r.PostFormValue("refresh_token") != h.refresh {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+	h.grants = append(h.grants, grant)
+	h.scopes = append(h.scopes, r.PostFormValue("scope"))
+	h.issued++
+	h.valid = fmt.Sprintf("access-%d", h.issued)
+	h.refresh = fmt.Sprintf("refresh-%d", h.issued)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"access_token": h.valid, "token_type": "Bearer", "expires_in": 3600, "refresh_token": h.refresh,
+	})
+}
+
+func (h *fakeMCPHost) serveMCP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	valid := h.valid
+	h.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer "+valid {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.Method == http.MethodDelete {
+		return
+	}
+	var req struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params struct {
+			Name      string         `json:"name"`
+			Arguments map[string]any `json:"arguments"`
+		} `json:"params"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	reply := func(result any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}
+	switch req.Method {
+	case "initialize":
+		w.Header().Set("Mcp-Session-Id", "session-1")
+		reply(map[string]any{"protocolVersion": "2025-06-18", "capabilities": map[string]any{"tools": map[string]any{}}})
+	case "tools/list":
+		// Answer over SSE, after an unrelated notification.
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+		fmt.Fprintf(w, "data: {\"jsonrpc\":\"2.0\",\"id\":%s,\"result\":{\"tools\":[{\"name\":\"echo\",\"description\":\"Echoes\"}]}}\n\n", req.ID)
+	case "tools/call":
+		reply(map[string]any{"content": []map[string]any{{"type": "text", "text": fmt.Sprint(req.Params.Arguments["text"])}}})
+	default:
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func newMCPTestEnv(t *testing.T) (*service.MCPService, *runtimeMockStore, *fakeMCPHost, *mcpserver.Server) {
+	t.Helper()
+	host := &fakeMCPHost{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", host.serveToken)
+	mux.HandleFunc("/mcp", host.serveMCP)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	v, err := vault.New("0123456789abcdef0123456789abcdef", "mcp-servers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, store, _, _ := newRuntimeTestEnv()
+	svc := service.NewMCPService(store, v)
+	m, err := svc.Create(context.Background(), &mcpserver.CreateRequest{
+		Name: "vendor-tools", URL: srv.URL + "/mcp", Auth: mcpserver.AuthOAuth2,
+		OAuth:        &mcpserver.OAuth{TokenURL: srv.URL + "/token", ClientID: "codeforge", Scopes: []string{"tools:read", "tools:call"}},
+		ClientSecret: "s3cret",
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	return svc, store, host, m
+}
+
+func TestMCPService_OAuthTokenLifecycle(t *testing.T) {
+	svc, store, host, m := newMCPTestEnv(t)
+	ctx := context.Background()
+
+	stored, _ := store.GetMCPServer(ctx, m.ID)
+	if stored.SecretCiphertext == nil || bytes.Contains(stored.SecretCiphertext, []byte("s3cret")) {
+		t.Fatal("client secret must be stored encrypted")
+	}
+
+	tools, err := svc.Tools(ctx, m.ID)
+	if err != nil {
+		t.Fatalf("Tools failed: %v", err)
+	}
+	if len(tools) != 1 || tools[0].Name != "echo" {
+		t.Fatalf("unexpected tools %+v", tools)
+	}
+	stored, _ = store.GetMCPServer(ctx, m.ID)
+	if stored.TokenCiphertext == nil || bytes.Contains(stored.TokenCiphertext, []byte("access-1")) || stored.TokenExpiresAt == nil {
+		t.Fatal("token must be stored encrypted with its expiry")
+	}
+
+	// The cached token is reused.
+	res, err := svc.CallTool(ctx, m.ID, &mcpserver.CallRequest{Name: "echo", Arguments: map[string]any{"text": "hi"}})
+	if err != nil || res.Content[0]["text"] != "hi" {
+		t.Fatalf("CallTool = %+v, %v", res, err)
+	}
+	if len(host.grants) != 1 || host.grants[0] != "client_credentials" || host.scopes[0] != "tools:read tools:call" {
+		t.Fatalf("unexpected token requests %v %v", host.grants, host.scopes)
+	}
+
+	// A revoked token is refreshed with the refresh token and the call retried.
+	host.mu.Lock()
+	host.valid = "revoked"
+	host.mu.Unlock()
+	if _, err := svc.Tools(ctx, m.ID); err != nil {
+		t.Fatalf("Tools after revocation failed: %v", err)
+	}
+	if len(host.grants) != 2 || host.grants[1] != "refresh_token" {
+		t.Fatalf("expected a refresh, got %v", host.grants)
+	}
+}
+
+func TestMCPService_Update(t *testing.T) {
+	svc, store, _, m := newMCPTestEnv(t)
+	ctx := context.Background()
+	if _, err := svc.Tools(ctx, m.ID); err != nil {
+		t.Fatalf("Tools failed: %v", err)
+	}
+
+	// Renaming keeps the secret usable and drops the cached token.
+	disabled := false
+	req := &mcpserver.CreateRequest{Name: "renamed", URL: m.URL, Auth: mcpserver.AuthOAuth2, OAuth: m.OAuth, Enabled: &disabled}
+	if _, err := svc.Update(ctx, m.ID, req); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	stored, _ := store.GetMCPServer(ctx, m.ID)
+	if stored.TokenCiphertext != nil {
+		t.Fatal("update must drop the cached token")
+	}
+	if _, err := svc.Tools(ctx, m.ID); !errors.Is(err, service.ErrMCPServerDisabled) {
+		t.Fatalf("expected ErrMCPServerDisabled, got %v", err)
+	}
+
+	enabled := true
+	req.Enabled = &enabled
+	if _, err := svc.Update(ctx, m.ID, req); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if _, err := svc.Tools(ctx, m.ID); err != nil {
+		t.Fatalf("Tools after rename failed: %v", err)
+	}
+}
+
+func TestMCPService_Validation(t *testing.T) {
+	_, store, _, _ := newRuntimeTestEnv()
+	svc := service.NewMCPService(store, nil)
+	ctx := context.Background()
+
+	if _, err := svc.Create(ctx, &mcpserver.CreateRequest{Name: "x", URL: "ftp://example.com"}); !errors.Is(err, mcpserver.ErrInvalidURL) {
+		t.Fatalf("expected ErrInvalidURL, got %v", err)
+	}
+	if _, err := svc.Create(ctx, &mcpserver.CreateRequest{Name: "x", URL: "https://example.com/mcp", Auth: mcpserver.AuthOAuth2}); !errors.Is(err, mcpserver.ErrOAuthIncomplete) {
+		t.Fatalf("expected ErrOAuthIncomplete, got %v", err)
+	}
+	_, err := svc.Create(ctx, &mcpserver.CreateRequest{
+		Name: "x", URL: "https://example.com/mcp", Auth: mcpserver.AuthOAuth2,
+		OAuth: &mcpserver.OAuth{TokenURL: "https://example.com/token", ClientID: "id"}, ClientSecret: "secret",
+	})
+	if !errors.Is(err, service.ErrSecretsUnavailable) {
+		t.Fatalf("expected ErrSecretsUnavailable without a master key, got %v", err)
+	}
+	m, err := svc.Create(ctx, &mcpserver.CreateRequest{Name: "public", URL: "https://example.com/mcp"})
+	if err != nil || m.Auth != mcpserver.AuthNone || m.Transport != mcpserver.TransportStreamableHTTP || !m.Enabled {
+		t.Fatalf("unexpected server %+v, %v", m, err)
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/memory"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+)
+
+// MemoryService stores project memories and recalls them by composite
+// score. Memories are embedded with the project's retrieval embedding
+// settings, so recall compares them with queries of the same model.
+type MemoryService struct {
+	store     database.Store
+	retrieval *RetrievalService
+	cfg       *config.Memory
+}
+
+// NewMemoryService creates a MemoryService.
+func NewMemoryService(store database.Store, retrieval *RetrievalService, cfg *config.Memory) *MemoryService {
+	return &MemoryService{store: store, retrieval: retrieval, cfg: cfg}
+}
+
+// Enabled reports whether memories are recalled into context automatically.
+func (s *MemoryService) Enabled() bool {
+	return s.cfg.Enabled
+}
+
+// Store embeds and stores a memory of a project.
+func (s *MemoryService) Store(ctx context.Context, projectID string, req memory.CreateRequest) (*memory.Memory, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	p, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+	e, vecs, err := s.retrieval.EmbedTexts(ctx, p, []string{req.Content})
+	if err != nil {
+		return nil, fmt.Errorf("embed memory: %w", err)
+	}
+
+	m := &memory.Memory{
+		ProjectID:  projectID,
+		Content:    strings.TrimSpace(req.Content),
+		Source:     req.Source,
+		Importance: 0.5,
+		Provider:   e.Provider,
+		Model:      e.Model,
+		Embedding:  vecs[0],
+	}
+	if req.Importance != nil {
+		m.Importance = *req.Importance
+	}
+	if err := s.store.CreateMemory(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// List returns the memories of a project, newest first.
+func (s *MemoryService) List(ctx context.Context, projectID string) ([]memory.Memory, error) {
+	return s.store.ListMemories(ctx, projectID)
+}
+
+// Delete removes a memory.
+func (s *MemoryService) Delete(ctx context.Context, id string) error {
+	return s.store.DeleteMemory(ctx, id)
+}
+
+// Recall returns the memories of a project best matching a query, scored
+// by similarity, recency and importance. Memories below the configured
+// minimum score are left out.
+func (s *MemoryService) Recall(ctx context.Context, projectID string, req memory.RecallRequest) ([]memory.Recalled, error) {
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, memory.ErrContentRequired
+	}
+	p, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+	memories, err := s.store.ListMemories(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(memories) == 0 {
+		return nil, nil
+	}
+	e, vecs, err := s.retrieval.EmbedTexts(ctx, p, []string{req.Query})
+	if err != nil {
+		return nil, fmt.Errorf("embed query: %w", err)
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = s.cfg.Limit
+	}
+	w := memory.Weights{
+		Semantic:   s.cfg.SemanticWeight,
+		Recency:    s.cfg.RecencyWeight,
+		Importance: s.cfg.ImportanceWeight,
+		HalfLife:   s.cfg.HalfLife,
+	}
+	recalled := memory.Recall(memories, e, vecs[0], w, time.Now(), limit, s.cfg.MinScore)
+	slog.Debug("memories recalled", "project_id", projectID, "candidates", len(memories), "recalled", len(recalled))
+	return recalled, nil
+}
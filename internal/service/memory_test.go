@@ -0,0 +1,149 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
+	"github.com/Strob0t/CodeForge/internal/config"
+	cfcontext "github.com/Strob0t/CodeForge/internal/domain/context"
+	"github.com/Strob0t/CodeForge/internal/domain/conversation"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/memory"
+	"github.com/Strob0t/CodeForge/internal/domain/mode"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func newMemoryTestEnv(t *testing.T) (*service.MemoryService, *runtimeMockStore) {
+	t.Helper()
+	retrievalSvc, store, _ := newRetrievalTestEnv(t)
+	svc := service.NewMemoryService(store, retrievalSvc, &config.Memory{
+		Enabled: true, Limit: 5, MinScore: 0.4,
+		SemanticWeight: 0.5, RecencyWeight: 0.3, ImportanceWeight: 0.2, HalfLife: 720 * time.Hour,
+	})
+	ctx := context.Background()
+	high, low := 0.9, 0.1
+	for _, req := range []memory.CreateRequest{
+		{Content: "alpha alpha: prefer table-driven tests", Importance: &high},
+		{Content: "beta: the deploy script needs sudo", Importance: &low},
+	} {
+		if _, err := svc.Store(ctx, "proj-1", req); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+	}
+	return svc, store
+}
+
+func TestMemoryService_Recall(t *testing.T) {
+	svc, store := newMemoryTestEnv(t)
+	if m := store.memories[0]; m.Provider != "litellm" || len(m.Embedding) != 4 {
+		t.Fatalf("expected memory embedded with the project's settings, got %+v", m)
+	}
+
+	recalled, err := svc.Recall(context.Background(), "proj-1", memory.RecallRequest{Query: "alpha"})
+	if err != nil {
+		t.Fatalf("Recall failed: %v", err)
+	}
+	// The beta memory scores 0.3 recency + 0.02 importance, below 0.4.
+	if len(recalled) != 1 || recalled[0].ID != "mem-1" || recalled[0].Score < 0.9 {
+		t.Fatalf("expected only the alpha memory, got %+v", recalled)
+	}
+	if _, err := svc.Recall(context.Background(), "proj-1", memory.RecallRequest{}); err != memory.ErrContentRequired {
+		t.Fatalf("expected ErrContentRequired, got %v", err)
+	}
+}
+
+func TestContextOptimizer_RecallsMemoriesPerMode(t *testing.T) {
+	memSvc, store := newMemoryTestEnv(t)
+	modes := service.NewModeService()
+	if err := modes.Register(&mode.Mode{ID: "scratch", Name: "Scratch", Autonomy: 3, SkipMemories: true}); err != nil {
+		t.Fatal(err)
+	}
+	co := service.NewContextOptimizerService(store, &config.Orchestrator{DefaultContextBudget: 4096, PromptReserve: 1024})
+	co.SetMemoryService(memSvc, modes)
+	store.tasks[0].Prompt = "alpha"
+	ctx := context.Background()
+
+	pack, err := co.BuildScopedContextPack(ctx, &store.tasks[0], "proj-1", "", service.PackScope{Mode: "coder"})
+	if err != nil {
+		t.Fatalf("BuildScopedContextPack failed: %v", err)
+	}
+	var memories []cfcontext.ContextEntry
+	for _, e := range pack.Entries {
+		if e.Kind == cfcontext.EntryMemory {
+			memories = append(memories, e)
+		}
+	}
+	if len(memories) != 1 || memories[0].Path != "memory/mem-1" || memories[0].Tokens == 0 {
+		t.Fatalf("expected the alpha memory in the pack, got %+v", memories)
+	}
+
+	if got := co.RecallMemories(ctx, "proj-1", "alpha", "scratch", ""); got != nil {
+		t.Fatalf("expected no memories for a skip_memories mode, got %+v", got)
+	}
+}
+
+func TestStartRun_RecordsRecalledMemories(t *testing.T) {
+	memSvc, store := newMemoryTestEnv(t)
+	co := service.NewContextOptimizerService(store, &config.Orchestrator{DefaultContextBudget: 4096, PromptReserve: 1024})
+	co.SetMemoryService(memSvc, service.NewModeService())
+	store.tasks[0].Prompt = "alpha"
+
+	es := &runtimeMockEventStore{}
+	svc := service.NewRuntimeService(store, &runtimeMockQueue{}, &runtimeMockBroadcaster{}, es,
+		service.NewPolicyService("headless-safe-sandbox", nil), &config.Runtime{StallThreshold: 5})
+	svc.SetContextOptimizer(co)
+
+	r, err := svc.StartRun(context.Background(), &run.StartRequest{TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1"})
+	if err != nil {
+		t.Fatalf("StartRun failed: %v", err)
+	}
+	for _, ev := range es.events {
+		if ev.Type != event.TypeMemoriesRecalled {
+			continue
+		}
+		var payload map[string]string
+		if err := json.Unmarshal(ev.Payload, &payload); err != nil {
+			t.Fatal(err)
+		}
+		if ev.RunID != r.ID || payload["memory_ids"] != "mem-1" || payload["count"] != "1" {
+			t.Fatalf("unexpected memory event %+v with payload %v", ev, payload)
+		}
+		return
+	}
+	t.Fatal("expected a memories recalled event")
+}
+
+func TestConversationService_SendRecallsMemories(t *testing.T) {
+	memSvc, store := newMemoryTestEnv(t)
+	co := service.NewContextOptimizerService(store, &config.Orchestrator{})
+	co.SetMemoryService(memSvc, service.NewModeService())
+	llm := &chatRecorder{}
+	srv := httptest.NewServer(llm)
+	defer srv.Close()
+
+	svc := service.NewConversationService(store, litellm.NewClient(srv.URL, ""), &config.Conversation{Model: "chat-model", KeepRecent: 2})
+	svc.SetContextOptimizer(co)
+	ctx := context.Background()
+	c, err := svc.Create(ctx, "proj-1", conversation.CreateRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reply, err := svc.Send(ctx, c.ID, conversation.SendRequest{Content: "how do we test alpha?"})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if len(reply.Memories) != 1 || reply.Memories[0] != "mem-1" {
+		t.Fatalf("expected the reply to record mem-1, got %v", reply.Memories)
+	}
+	first := llm.requests[0].Messages[0]
+	if first.Role != "system" || !strings.Contains(first.Content, "prefer table-driven tests") {
+		t.Fatalf("expected memories ahead of the conversation, got %+v", first)
+	}
+}
@@ -5,23 +5,29 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
 	"github.com/Strob0t/CodeForge/internal/config"
 	"github.com/Strob0t/CodeForge/internal/domain/agent"
+	"github.com/Strob0t/CodeForge/internal/domain/experience"
 	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/routing"
 	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/port/agentbackend"
 	"github.com/Strob0t/CodeForge/internal/port/database"
 )
 
 // MetaAgentService uses an LLM to decompose features into subtasks and build execution plans.
 type MetaAgentService struct {
-	store   database.Store
-	llm     *litellm.Client
-	orchSvc *OrchestratorService
-	orchCfg *config.Orchestrator
+	store      database.Store
+	llm        *litellm.Client
+	orchSvc    *OrchestratorService
+	orchCfg    *config.Orchestrator
+	routing    *RoutingService
+	experience *ExperienceService
 }
 
 // NewMetaAgentService creates a MetaAgentService with all dependencies.
@@ -39,6 +45,18 @@ func NewMetaAgentService(
 	}
 }
 
+// SetRoutingService routes the decomposition model through the routing
+// rules. Without it the configured decompose model is used.
+func (s *MetaAgentService) SetRoutingService(r *RoutingService) {
+	s.routing = r
+}
+
+// SetExperiencePool adds the outcomes of similar past plans to the
+// decomposition prompt.
+func (s *MetaAgentService) SetExperiencePool(e *ExperienceService) {
+	s.experience = e
+}
+
 // DecomposeFeature uses an LLM to break a feature description into subtasks,
 // creates the tasks in the database, and builds an execution plan.
 func (s *MetaAgentService) DecomposeFeature(ctx context.Context, req *plan.DecomposeRequest) (*plan.ExecutionPlan, error) {
@@ -47,7 +65,8 @@ func (s *MetaAgentService) DecomposeFeature(ctx context.Context, req *plan.Decom
 	}
 
 	// Verify project exists
-	if _, err := s.store.GetProject(ctx, req.ProjectID); err != nil {
+	proj, err := s.store.GetProject(ctx, req.ProjectID)
+	if err != nil {
 		return nil, fmt.Errorf("get project: %w", err)
 	}
 
@@ -56,9 +75,25 @@ func (s *MetaAgentService) DecomposeFeature(ctx context.Context, req *plan.Decom
 	if err != nil {
 		return nil, fmt.Errorf("list agents: %w", err)
 	}
+	// Clones belong to automatic scaling, not to plans.
+	agents = slices.DeleteFunc(agents, func(a agent.Agent) bool { return a.Ephemeral() })
 	if len(agents) == 0 {
 		return nil, fmt.Errorf("project has no agents configured")
 	}
+	if req.Backend != "" {
+		agents = slices.DeleteFunc(agents, func(a agent.Agent) bool { return a.Backend != req.Backend })
+		if len(agents) == 0 {
+			return nil, fmt.Errorf("project has no agents with backend %q", req.Backend)
+		}
+	}
+
+	// The project's stacks steer backend selection.
+	var stacks []string
+	if proj.WorkspacePath != "" {
+		for _, st := range DetectStack(proj.WorkspacePath) {
+			stacks = append(stacks, st.Name)
+		}
+	}
 
 	// Load existing tasks for context
 	tasks, err := s.store.ListTasks(ctx, req.ProjectID)
@@ -67,26 +102,46 @@ func (s *MetaAgentService) DecomposeFeature(ctx context.Context, req *plan.Decom
 	}
 
 	// Build and send LLM request
-	model := req.Model
-	if model == "" {
-		model = s.orchCfg.DecomposeModel
-	}
 	maxTokens := s.orchCfg.DecomposeMaxTokens
 	if maxTokens == 0 {
 		maxTokens = 4096
 	}
 
-	systemPrompt, userPrompt := buildDecomposePrompt(req.Feature, req.Context, agents, tasks)
+	// Look up how similar features went before. Lookup failures only
+	// cost the prompt its experience section.
+	var prior string
+	if s.experience != nil {
+		matches, err := s.experience.Search(ctx, req.ProjectID, req.Feature)
+		if err != nil {
+			slog.Warn("experience search failed", "project_id", req.ProjectID, "error", err)
+		}
+		prior = experience.Render(matches)
+	}
 
-	llmResp, err := s.llm.ChatCompletion(ctx, litellm.ChatCompletionRequest{
-		Model: model,
-		Messages: []litellm.ChatMessage{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userPrompt},
-		},
-		Temperature: 0.2,
-		MaxTokens:   maxTokens,
-	})
+	systemPrompt, userPrompt := buildDecomposePrompt(req.Feature, req.Context, prior, agents, tasks)
+
+	var llmResp *litellm.ChatCompletionResponse
+	call := func(model string) error {
+		llmResp, err = s.llm.ChatCompletion(ctx, litellm.ChatCompletionRequest{
+			Model: model,
+			Messages: []litellm.ChatMessage{
+				{Role: "system", Content: systemPrompt},
+				{Role: "user", Content: userPrompt},
+			},
+			Temperature: 0.2,
+			MaxTokens:   maxTokens,
+		})
+		return err
+	}
+	if s.routing != nil {
+		err = s.routing.Call(ctx, routing.Request{TaskType: routing.TaskDecompose, ProjectID: req.ProjectID, Model: req.Model}, call)
+	} else {
+		model := req.Model
+		if model == "" {
+			model = s.orchCfg.DecomposeModel
+		}
+		err = call(model)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("llm decomposition: %w", err)
 	}
@@ -123,7 +178,7 @@ func (s *MetaAgentService) DecomposeFeature(ctx context.Context, req *plan.Decom
 	// Build plan steps with agent assignment
 	steps := make([]plan.CreateStepRequest, len(result.Subtasks))
 	for i, st := range result.Subtasks {
-		agentID := selectAgent(agents, st.AgentHint)
+		agentID := selectAgent(agents, &result.Subtasks[i], stacks)
 		deps := make([]string, len(st.DependsOn))
 		for j, d := range st.DependsOn {
 			deps[j] = strconv.Itoa(d)
@@ -173,7 +228,7 @@ func (s *MetaAgentService) DecomposeFeature(ctx context.Context, req *plan.Decom
 }
 
 // buildDecomposePrompt constructs the system and user prompts for feature decomposition.
-func buildDecomposePrompt(feature, extraContext string, agents []agent.Agent, tasks []task.Task) (system, user string) {
+func buildDecomposePrompt(feature, extraContext, prior string, agents []agent.Agent, tasks []task.Task) (system, user string) {
 	system = `You are a software engineering project planner. Given a feature description, decompose it into concrete, actionable subtasks. Each subtask should be small enough for a single coding agent to complete in one session.
 
 Rules:
@@ -182,7 +237,8 @@ Rules:
 - Set depends_on to indices of subtasks that must complete first (empty array for independent tasks).
 - Choose strategy: "single" (one agent), "pair" (two agents alternating), or "team" (multiple agents in parallel).
 - Choose protocol: "sequential", "parallel", "ping_pong", or "consensus" — matching the strategy.
-- Set agent_hint to a preferred backend name if applicable, or empty string.`
+- Set agent_hint to a preferred backend name if applicable, or empty string.
+- Set estimated_lines to the expected number of changed lines, and mcp_tools to true if the subtask needs tools of MCP servers.`
 
 	var b strings.Builder
 	b.WriteString("Feature: ")
@@ -195,6 +251,11 @@ Rules:
 		b.WriteString("\n")
 	}
 
+	if prior != "" {
+		b.WriteString("\nOutcomes of similar past features (avoid their pitfalls):\n")
+		b.WriteString(prior)
+	}
+
 	b.WriteString("\nAvailable agents:\n")
 	for i := range agents {
 		fmt.Fprintf(&b, "- %s (backend: %s, status: %s)\n", agents[i].Name, agents[i].Backend, agents[i].Status)
@@ -219,7 +280,9 @@ Output JSON:
       "title": "short task title",
       "prompt": "detailed instructions for the coding agent",
       "depends_on": [],
-      "agent_hint": ""
+      "agent_hint": "",
+      "estimated_lines": 0,
+      "mcp_tools": false
     }
   ]
 }`)
@@ -227,25 +290,33 @@ Output JSON:
 	return system, b.String()
 }
 
-// selectAgent picks the best agent for a subtask based on the hint.
-func selectAgent(agents []agent.Agent, hint string) string {
-	if hint == "" {
-		return agents[0].ID
-	}
+// selectAgent picks the agent for a subtask: the one matching its backend
+// hint, else the one whose backend's capabilities suit the subtask and the
+// project's stacks best (see selectByCapabilities).
+func selectAgent(agents []agent.Agent, st *plan.SubtaskDefinition, stacks []string) string {
+	if hint := st.AgentHint; hint != "" {
+		// Match by backend
+		for i := range agents {
+			if strings.EqualFold(agents[i].Backend, hint) {
+				return agents[i].ID
+			}
+		}
 
-	// Match by backend
-	for i := range agents {
-		if strings.EqualFold(agents[i].Backend, hint) {
-			return agents[i].ID
+		// Match by name (substring)
+		hint = strings.ToLower(hint)
+		for i := range agents {
+			if strings.Contains(strings.ToLower(agents[i].Name), hint) {
+				return agents[i].ID
+			}
 		}
 	}
 
-	// Match by name (substring)
-	hint = strings.ToLower(hint)
-	for i := range agents {
-		if strings.Contains(strings.ToLower(agents[i].Name), hint) {
-			return agents[i].ID
-		}
+	if id := selectByCapabilities(agents, &agentbackend.Requirements{
+		Stacks:    stacks,
+		DiffLines: st.EstimatedLines,
+		MCPTools:  st.MCPTools,
+	}); id != "" {
+		return id
 	}
 
 	// Prefer idle agents as fallback
@@ -258,6 +329,27 @@ func selectAgent(agents []agent.Agent, hint string) string {
 	return agents[0].ID
 }
 
+// selectByCapabilities returns the agent whose backend fits r with the
+// highest score, preferring idle agents on ties, or "" if no backend fits.
+// Backends that are not registered here count as fitting with score 0.
+func selectByCapabilities(agents []agent.Agent, r *agentbackend.Requirements) string {
+	best, bestScore, bestIdle := "", 0, false
+	for i := range agents {
+		score := 0
+		if caps, ok := agentbackend.Describe(agents[i].Backend); ok {
+			if !caps.Fits(r) {
+				continue
+			}
+			score = caps.Score(r)
+		}
+		idle := agents[i].Status == agent.StatusIdle
+		if best == "" || score > bestScore || (score == bestScore && idle && !bestIdle) {
+			best, bestScore, bestIdle = agents[i].ID, score, idle
+		}
+	}
+	return best
+}
+
 // extractJSON attempts to extract a JSON object from a string that may contain
 // markdown fences or other surrounding text.
 func extractJSON(s string) string {
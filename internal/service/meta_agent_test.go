@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
@@ -12,6 +15,8 @@ import (
 	"github.com/Strob0t/CodeForge/internal/domain/agent"
 	"github.com/Strob0t/CodeForge/internal/domain/plan"
 	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/port/agentbackend"
 	"github.com/Strob0t/CodeForge/internal/service"
 )
 
@@ -263,6 +268,78 @@ func TestDecomposeFeatureAgentSelection(t *testing.T) {
 	}
 }
 
+// testBackend is an agent backend with fixed capabilities.
+type testBackend struct {
+	name string
+	caps agentbackend.Capabilities
+}
+
+func (b *testBackend) Name() string                            { return b.name }
+func (b *testBackend) Capabilities() agentbackend.Capabilities { return b.caps }
+func (b *testBackend) Execute(_ context.Context, _ *task.Task) (*task.Result, error) {
+	return nil, nil
+}
+func (b *testBackend) Stop(_ context.Context, _ string) error { return nil }
+
+var registerTestBackends = sync.OnceFunc(func() {
+	for _, b := range []*testBackend{
+		{name: "test-small", caps: agentbackend.Capabilities{Edit: true, MaxDiffLines: 100, CostTier: 1}},
+		{name: "test-go", caps: agentbackend.Capabilities{Edit: true, Languages: []string{"go"}, MaxDiffLines: 300, CostTier: 1}},
+	} {
+		agentbackend.Register(b.name, func(map[string]string) (agentbackend.Backend, error) { return b, nil })
+	}
+})
+
+func TestDecomposeFeatureCapabilitySelection(t *testing.T) {
+	registerTestBackends()
+	result := plan.DecomposeResult{
+		PlanName: "Test",
+		Strategy: plan.StrategySingle,
+		Protocol: plan.ProtocolSequential,
+		Subtasks: []plan.SubtaskDefinition{
+			{Title: "Small", Prompt: "Do it", DependsOn: []int{}, EstimatedLines: 50},
+			{Title: "Large", Prompt: "Do more", DependsOn: []int{}, EstimatedLines: 1000},
+		},
+	}
+	body, _ := json.Marshal(result)
+	store, meta, srv := newMetaTestSetup(t, string(body))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/x\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store.projects[0].WorkspacePath = dir
+	store.agents = append(store.agents,
+		agent.Agent{ID: "a2", ProjectID: "p1", Name: "Small", Backend: "test-small", Status: agent.StatusIdle},
+		agent.Agent{ID: "a3", ProjectID: "p1", Name: "Gopher", Backend: "test-go", Status: agent.StatusIdle},
+	)
+
+	p, err := meta.DecomposeFeature(context.Background(), &plan.DecomposeRequest{ProjectID: "p1", Feature: "Test feature"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The Go specialist suits the Go project best; only the unregistered
+	// aider backend sets no size limit.
+	if p.Steps[0].AgentID != "a3" || p.Steps[1].AgentID != "a1" {
+		t.Errorf("expected agents a3 and a1, got %q and %q", p.Steps[0].AgentID, p.Steps[1].AgentID)
+	}
+
+	p, err = meta.DecomposeFeature(context.Background(), &plan.DecomposeRequest{ProjectID: "p1", Feature: "Test feature", Backend: "test-small"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := range p.Steps {
+		if p.Steps[i].AgentID != "a2" {
+			t.Errorf("expected the override to assign a2 to step %d, got %q", i, p.Steps[i].AgentID)
+		}
+	}
+
+	if _, err := meta.DecomposeFeature(context.Background(), &plan.DecomposeRequest{ProjectID: "p1", Feature: "Test feature", Backend: "openhands"}); err == nil {
+		t.Error("expected an error for a backend without agents")
+	}
+}
+
 func TestDecomposeFeatureMarkdownFences(t *testing.T) {
 	result := mockDecomposeResponse()
 	result.Subtasks = []plan.SubtaskDefinition{
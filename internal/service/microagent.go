@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/domain/microagent"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+)
+
+// conventionsMicroagent names the project's conventions among the
+// activated microagents.
+const conventionsMicroagent = "project-conventions"
+
+// MicroagentService manages project microagents and activates them for
+// runs and conversations.
+type MicroagentService struct {
+	store       database.Store
+	conventions *ConventionService
+}
+
+// NewMicroagentService creates a MicroagentService.
+func NewMicroagentService(store database.Store) *MicroagentService {
+	return &MicroagentService{store: store}
+}
+
+// SetConventionService adds the project's conventions document to the
+// microagents activated for conversations, as an always active entry.
+// Runs get it through their context pack instead.
+func (s *MicroagentService) SetConventionService(c *ConventionService) {
+	s.conventions = c
+}
+
+// Create stores a microagent of a project.
+func (s *MicroagentService) Create(ctx context.Context, projectID string, req *microagent.CreateRequest) (*microagent.Microagent, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := s.store.GetProject(ctx, projectID); err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+	m := &microagent.Microagent{ProjectID: projectID}
+	req.Apply(m)
+	if err := s.store.CreateMicroagent(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Get returns a microagent by ID.
+func (s *MicroagentService) Get(ctx context.Context, id string) (*microagent.Microagent, error) {
+	return s.store.GetMicroagent(ctx, id)
+}
+
+// List returns the microagents of a project.
+func (s *MicroagentService) List(ctx context.Context, projectID string) ([]microagent.Microagent, error) {
+	return s.store.ListMicroagents(ctx, projectID)
+}
+
+// Update replaces the fields of a microagent.
+func (s *MicroagentService) Update(ctx context.Context, id string, req *microagent.CreateRequest) (*microagent.Microagent, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	m, err := s.store.GetMicroagent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	req.Apply(m)
+	if err := s.store.UpdateMicroagent(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Delete removes a microagent.
+func (s *MicroagentService) Delete(ctx context.Context, id string) error {
+	return s.store.DeleteMicroagent(ctx, id)
+}
+
+// Activate returns the microagents of a project whose trigger matches in,
+// led by the project's conventions if it has them; those have no
+// activation. Failures are logged and activate nothing, since microagents
+// only add knowledge and must not block runs or replies.
+func (s *MicroagentService) Activate(ctx context.Context, projectID string, in *microagent.Input) ([]microagent.Microagent, []microagent.Activation) {
+	var conventions []microagent.Microagent
+	if s.conventions != nil {
+		if text := s.conventions.Section(ctx, projectID); text != "" {
+			conventions = append(conventions, microagent.Microagent{
+				ProjectID: projectID,
+				Name:      conventionsMicroagent,
+				Knowledge: text,
+				Enabled:   true,
+			})
+		}
+	}
+	agents, err := s.store.ListMicroagents(ctx, projectID)
+	if err != nil {
+		slog.Warn("list microagents failed", "project_id", projectID, "error", err)
+		return conventions, nil
+	}
+	matched, acts := microagent.Activate(agents, in)
+	return append(conventions, matched...), acts
+}
+
+// ActivateForRun activates microagents for a run by its prompt, the
+// changed files of its workspace and the event that started it. The
+// workspace is only inspected if a microagent has file patterns.
+func (s *MicroagentService) ActivateForRun(ctx context.Context, r *run.Run, prompt, ev string) ([]microagent.Microagent, []microagent.Activation) {
+	agents, err := s.store.ListMicroagents(ctx, r.ProjectID)
+	if err != nil {
+		slog.Warn("list microagents failed", "project_id", r.ProjectID, "error", err)
+		return nil, nil
+	}
+	in := &microagent.Input{Prompt: prompt, Event: ev}
+	for i := range agents {
+		if agents[i].Enabled && len(agents[i].Trigger.FilePatterns) > 0 {
+			in.Files = s.changedFiles(ctx, r)
+			break
+		}
+	}
+	return microagent.Activate(agents, in)
+}
+
+// changedFiles returns the files of the run's workspace that differ from
+// HEAD, untracked files included. Workspaces that are not git
+// repositories have no changed files.
+func (s *MicroagentService) changedFiles(ctx context.Context, r *run.Run) []string {
+	proj, err := s.store.GetProject(ctx, r.ProjectID)
+	if err != nil {
+		return nil
+	}
+	dir := r.Workspace(proj.WorkspacePath)
+	if dir == "" {
+		return nil
+	}
+	gitCtx, cancel := context.WithTimeout(ctx, diffStatTimeout)
+	defer cancel()
+
+	var files []string
+	for _, args := range [][]string{
+		{"diff", "--name-only", "--no-ext-diff", "HEAD"},
+		{"ls-files", "--others", "--exclude-standard"},
+	} {
+		out, err := runDeliverGit(gitCtx, dir, args...)
+		if err != nil {
+			slog.Debug("microagent file scan skipped", "run_id", r.ID, "error", err)
+			return files
+		}
+		for _, f := range strings.Split(strings.TrimSpace(out), "\n") {
+			if f != "" {
+				files = append(files, f)
+			}
+		}
+	}
+	return files
+}
+
+// microagentSection formats the knowledge of activated microagents as a
+// prompt section.
+func microagentSection(agents []microagent.Microagent) string {
+	return "## Project knowledge (microagents)\n\n" + microagent.Render(agents)
+}
+
+// activationIDs returns the microagent IDs of activations.
+func activationIDs(acts []microagent.Activation) []string {
+	ids := make([]string, len(acts))
+	for i := range acts {
+		ids[i] = acts[i].ID
+	}
+	return ids
+}
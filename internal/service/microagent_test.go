@@ -0,0 +1,120 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/conversation"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/microagent"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func newMicroagentTestEnv(t *testing.T) (*service.MicroagentService, *runtimeMockStore) {
+	t.Helper()
+	_, store, _, _ := newRuntimeTestEnv()
+	svc := service.NewMicroagentService(store)
+	ctx := context.Background()
+	for _, req := range []microagent.CreateRequest{
+		{Name: "null-safety", Knowledge: "Check pointers with the nilguard helper.", Trigger: microagent.Trigger{Keywords: []string{"null pointer"}}},
+		{Name: "migrations", Knowledge: "Migrations use goose.", Trigger: microagent.Trigger{FilePatterns: []string{"*.sql"}, Events: []string{"pm.issue.created"}}},
+	} {
+		if _, err := svc.Create(ctx, "proj-1", &req); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	return svc, store
+}
+
+func TestMicroagentService_Create(t *testing.T) {
+	svc, _ := newMicroagentTestEnv(t)
+	ctx := context.Background()
+	req := &microagent.CreateRequest{Name: "migrations", Knowledge: "x", Trigger: microagent.Trigger{Keywords: []string{"sql"}}}
+	if _, err := svc.Create(ctx, "proj-1", req); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("expected ErrConflict for a duplicate name, got %v", err)
+	}
+	if _, err := svc.Create(ctx, "missing", req); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for an unknown project, got %v", err)
+	}
+}
+
+func TestStartRun_InjectsMicroagents(t *testing.T) {
+	microSvc, store := newMicroagentTestEnv(t)
+	queue := &runtimeMockQueue{}
+	es := &runtimeMockEventStore{}
+	svc := service.NewRuntimeService(store, queue, &runtimeMockBroadcaster{}, es,
+		service.NewPolicyService("headless-safe-sandbox", nil), &config.Runtime{StallThreshold: 5})
+	svc.SetMicroagentService(microSvc)
+
+	r, err := svc.StartRun(context.Background(), &run.StartRequest{
+		TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", TriggerEvent: "pm.issue.created",
+	})
+	if err != nil {
+		t.Fatalf("StartRun failed: %v", err)
+	}
+
+	msg, ok := queue.lastMessage(messagequeue.SubjectRunStart)
+	if !ok {
+		t.Fatal("expected run start message to be published")
+	}
+	var payload messagequeue.RunStartPayload
+	if err := json.Unmarshal(msg.Data, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(payload.Prompt, "### migrations\nMigrations use goose.") ||
+		!strings.Contains(payload.Prompt, "### null-safety\nCheck pointers") {
+		t.Fatalf("expected both microagents in the prompt, got %q", payload.Prompt)
+	}
+
+	for _, ev := range es.events {
+		if ev.Type != event.TypeMicroagentsActivated {
+			continue
+		}
+		var data map[string]string
+		if err := json.Unmarshal(ev.Payload, &data); err != nil {
+			t.Fatal(err)
+		}
+		if ev.RunID != r.ID || data["names"] != "migrations,null-safety" ||
+			data["reasons"] != "event:pm.issue.created,keyword:null pointer" || data["count"] != "2" {
+			t.Fatalf("unexpected microagent event %+v with payload %v", ev, data)
+		}
+		return
+	}
+	t.Fatal("expected a microagents activated event")
+}
+
+func TestConversationService_SendActivatesMicroagents(t *testing.T) {
+	microSvc, store := newMicroagentTestEnv(t)
+	llm := &chatRecorder{}
+	srv := httptest.NewServer(llm)
+	defer srv.Close()
+
+	svc := service.NewConversationService(store, litellm.NewClient(srv.URL, ""), &config.Conversation{Model: "chat-model", KeepRecent: 2})
+	svc.SetMicroagentService(microSvc)
+	ctx := context.Background()
+	c, err := svc.Create(ctx, "proj-1", conversation.CreateRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reply, err := svc.Send(ctx, c.ID, conversation.SendRequest{Content: "Why is there a Null Pointer here?"})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if len(reply.Microagents) != 1 || reply.Microagents[0] != "ma-1" {
+		t.Fatalf("expected the reply to record ma-1, got %v", reply.Microagents)
+	}
+	first := llm.requests[0].Messages[0]
+	if first.Role != "system" || !strings.Contains(first.Content, "nilguard") {
+		t.Fatalf("expected microagent knowledge ahead of the conversation, got %+v", first)
+	}
+}
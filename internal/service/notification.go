@@ -0,0 +1,442 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/notification"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/notifier"
+)
+
+const (
+	// notifyBatchSize bounds the notifications sent per pass.
+	notifyBatchSize = 100
+	// notifyMaxBackoff caps the wait before a failed notification is
+	// sent again.
+	notifyMaxBackoff = time.Hour
+)
+
+var (
+	// ErrUnknownNotifier is returned for rules of a kind no notifier is
+	// registered for.
+	ErrUnknownNotifier = errors.New("no notifier is registered for this kind")
+	// ErrSlackNotInteractive is returned for interactive Slack rules on a
+	// server without a Slack signing secret to verify the clicks with.
+	ErrSlackNotInteractive = errors.New("interactive slack rules need notify.slack_signing_secret")
+)
+
+// NotificationService routes the events of projects to chat webhooks and
+// email by each project's rules. Notifications go through an outbox that a leader
+// job sends from: right away, after the project's quiet hours or, for
+// low-priority events of rules in digest mode, batched into the daily
+// digest. Failed sends are retried with backoff until max_attempts.
+type NotificationService struct {
+	store     database.Store
+	secrets   *SecretService
+	approvals *OrchestratorService
+	cfg       config.Notify
+	publicURL string
+	now       func() time.Time
+}
+
+// NewNotificationService creates a NotificationService.
+func NewNotificationService(store database.Store, cfg config.Notify) *NotificationService {
+	return &NotificationService{store: store, cfg: cfg, now: time.Now}
+}
+
+// SetSecretService enables webhook_secret in rule configs.
+func (s *NotificationService) SetSecretService(secrets *SecretService) {
+	s.secrets = secrets
+}
+
+// SetApprovalService enables the Approve and Reject buttons of interactive
+// Slack rules; clicks are votes on the plan approval step.
+func (s *NotificationService) SetApprovalService(orch *OrchestratorService) {
+	s.approvals = orch
+}
+
+// SetPublicURL sets the web UI base URL that digests link to.
+func (s *NotificationService) SetPublicURL(u string) {
+	s.publicURL = strings.TrimSuffix(u, "/")
+}
+
+// Kinds returns the kinds rules can notify, sorted. Email needs
+// notify.smtp_host.
+func (s *NotificationService) Kinds() []string {
+	kinds := slices.DeleteFunc(notifier.Available(), func(k string) bool {
+		return k == string(notification.KindEmail) && s.cfg.SMTPHost == ""
+	})
+	slices.Sort(kinds)
+	return kinds
+}
+
+// CreateRule adds a routing rule to a project.
+func (s *NotificationService) CreateRule(ctx context.Context, projectID string, req *notification.CreateRuleRequest) (*notification.Rule, error) {
+	if err := s.validate(req); err != nil {
+		return nil, err
+	}
+	if _, err := s.store.GetProject(ctx, projectID); err != nil {
+		return nil, err
+	}
+	r := &notification.Rule{ProjectID: projectID}
+	applyRule(r, req)
+	if err := s.store.CreateNotificationRule(ctx, r); err != nil {
+		return nil, err
+	}
+	slog.Info("notification rule created", "rule_id", r.ID, "project_id", projectID, "kind", r.Kind)
+	return r, nil
+}
+
+// GetRule returns a routing rule by ID.
+func (s *NotificationService) GetRule(ctx context.Context, id string) (*notification.Rule, error) {
+	return s.store.GetNotificationRule(ctx, id)
+}
+
+// ListRules returns the routing rules of a project.
+func (s *NotificationService) ListRules(ctx context.Context, projectID string) ([]notification.Rule, error) {
+	return s.store.ListNotificationRules(ctx, projectID)
+}
+
+// UpdateRule replaces the fields of a routing rule. Notifications already
+// waiting in the outbox are sent by the updated rule.
+func (s *NotificationService) UpdateRule(ctx context.Context, id string, req *notification.CreateRuleRequest) (*notification.Rule, error) {
+	if err := s.validate(req); err != nil {
+		return nil, err
+	}
+	r, err := s.store.GetNotificationRule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	applyRule(r, req)
+	if err := s.store.UpdateNotificationRule(ctx, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// DeleteRule removes a routing rule and the notifications waiting for it.
+func (s *NotificationService) DeleteRule(ctx context.Context, id string) error {
+	return s.store.DeleteNotificationRule(ctx, id)
+}
+
+// GetSettings returns the quiet hours and digest time of a project, or
+// the defaults if it has set none.
+func (s *NotificationService) GetSettings(ctx context.Context, projectID string) (*notification.Settings, error) {
+	if _, err := s.store.GetProject(ctx, projectID); err != nil {
+		return nil, err
+	}
+	return s.settings(ctx, projectID)
+}
+
+// SetSettings replaces the quiet hours and digest time of a project. They
+// apply to notifications enqueued from then on.
+func (s *NotificationService) SetSettings(ctx context.Context, projectID string, st *notification.Settings) (*notification.Settings, error) {
+	if err := st.Validate(); err != nil {
+		return nil, fmt.Errorf("validate notification settings: %w", err)
+	}
+	if _, err := s.store.GetProject(ctx, projectID); err != nil {
+		return nil, err
+	}
+	st.ProjectID = projectID
+	if err := s.store.SetNotificationSettings(ctx, st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// Notify enqueues msg for every enabled rule of its project that routes
+// its event type. Failures are logged, not returned, so that they do not
+// fail what is being notified about.
+func (s *NotificationService) Notify(ctx context.Context, msg *notification.Message) {
+	rules, err := s.store.ListNotificationRules(ctx, msg.ProjectID)
+	if err != nil {
+		slog.Error("list notification rules", "project_id", msg.ProjectID, "error", err)
+		return
+	}
+	var st *notification.Settings
+	now := s.now()
+	if msg.At.IsZero() {
+		msg.At = now
+	}
+	for i := range rules {
+		r := &rules[i]
+		if !r.Enabled || !r.Matches(msg.EventType) {
+			continue
+		}
+		if st == nil {
+			if st, err = s.settings(ctx, msg.ProjectID); err != nil {
+				slog.Error("get notification settings", "project_id", msg.ProjectID, "error", err)
+				st = &notification.Settings{ProjectID: msg.ProjectID}
+			}
+		}
+		p := &notification.Pending{RuleID: r.ID, ProjectID: msg.ProjectID, Message: *msg, SendAt: now}
+		switch {
+		case r.Digest && msg.Priority == notification.PriorityLow:
+			p.Digest, p.SendAt = true, st.NextDigest(now)
+		case msg.Priority != notification.PriorityHigh:
+			if until, quiet := st.QuietUntil(now); quiet {
+				p.SendAt = until
+			}
+		}
+		if err := s.store.EnqueueNotification(ctx, p); err != nil {
+			slog.Error("enqueue notification", "rule_id", r.ID, "event_type", msg.EventType, "error", err)
+		}
+	}
+}
+
+// HandleRunCompleted notifies about a finished run as a run.<status>
+// event. Completed runs are low priority, the others normal.
+func (s *NotificationService) HandleRunCompleted(ctx context.Context, runID string, status run.Status) {
+	r, err := s.store.GetRun(ctx, runID)
+	if err != nil {
+		slog.Error("notification: get run", "run_id", runID, "error", err)
+		return
+	}
+	title := "Run " + string(status)
+	if t, err := s.store.GetTask(ctx, r.TaskID); err == nil {
+		title += ": " + t.Title
+	}
+	msg := &notification.Message{
+		EventType: "run." + string(status),
+		ProjectID: r.ProjectID,
+		Priority:  notification.PriorityNormal,
+		Title:     title,
+		Text:      r.Error,
+		URL:       runURL(s.publicURL, r.ProjectID, r.ID),
+	}
+	if status == run.StatusCompleted {
+		msg.Priority, msg.Text = notification.PriorityLow, r.Summary
+	}
+	s.Notify(ctx, msg)
+}
+
+// StartSender runs Send every send interval until ctx is done or cancel
+// is called. It does nothing if the interval is 0.
+func (s *NotificationService) StartSender(ctx context.Context) (cancel func()) {
+	ctx, cancel = context.WithCancel(ctx)
+	if s.cfg.SendInterval <= 0 {
+		return cancel
+	}
+	go func() {
+		ticker := time.NewTicker(s.cfg.SendInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.Send(ctx); err != nil {
+					slog.Error("notification send failed", "error", err)
+				}
+			}
+		}
+	}()
+	return cancel
+}
+
+// Send sends the notifications that are due and returns how many messages
+// were delivered. The due digest notifications of a rule go out as one
+// message. Notifications of deleted or disabled rules are dropped.
+func (s *NotificationService) Send(ctx context.Context) (int, error) {
+	due, err := s.store.ListDueNotifications(ctx, s.now(), notifyBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("list due notifications: %w", err)
+	}
+	byRule := make(map[string][]notification.Pending)
+	var order []string
+	for i := range due {
+		if _, ok := byRule[due[i].RuleID]; !ok {
+			order = append(order, due[i].RuleID)
+		}
+		byRule[due[i].RuleID] = append(byRule[due[i].RuleID], due[i])
+	}
+
+	sent := 0
+	for _, ruleID := range order {
+		sent += s.sendRule(ctx, ruleID, byRule[ruleID])
+	}
+	return sent, nil
+}
+
+// sendRule sends the due notifications of one rule.
+func (s *NotificationService) sendRule(ctx context.Context, ruleID string, pending []notification.Pending) int {
+	r, err := s.store.GetNotificationRule(ctx, ruleID)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		s.drop(ctx, pending)
+		return 0
+	case err != nil:
+		slog.Error("get notification rule", "rule_id", ruleID, "error", err)
+		return 0
+	case !r.Enabled:
+		s.drop(ctx, pending)
+		return 0
+	}
+	out, err := s.notifier(ctx, r)
+	if err != nil {
+		s.retry(ctx, r, pending, err)
+		return 0
+	}
+
+	var digest []notification.Pending
+	sent := 0
+	for i := range pending {
+		if pending[i].Digest {
+			digest = append(digest, pending[i])
+			continue
+		}
+		msg := pending[i].Message
+		msg.Mentions = append(slices.Clone(r.Mentions), msg.Mentions...)
+		if s.deliver(ctx, out, r, &msg, pending[i:i+1]) {
+			sent++
+		}
+	}
+	if len(digest) > 0 && s.deliver(ctx, out, r, s.digest(r, digest), digest) {
+		sent++
+	}
+	return sent
+}
+
+// deliver sends msg for the pending notifications it stands for, removing
+// them from the outbox on success and scheduling a retry on failure.
+func (s *NotificationService) deliver(ctx context.Context, out notifier.Notifier, r *notification.Rule, msg *notification.Message, pending []notification.Pending) bool {
+	sendCtx, cancel := context.WithTimeout(ctx, s.cfg.SendTimeout)
+	err := out.Send(sendCtx, msg)
+	cancel()
+	if err != nil {
+		s.retry(ctx, r, pending, err)
+		return false
+	}
+	if err := s.store.DeleteNotifications(ctx, pendingIDs(pending)); err != nil {
+		slog.Error("remove sent notifications", "rule_id", r.ID, "error", err)
+	}
+	return true
+}
+
+// digest summarizes pending notifications in one message.
+func (s *NotificationService) digest(r *notification.Rule, pending []notification.Pending) *notification.Message {
+	msg := &notification.Message{
+		EventType: "digest",
+		ProjectID: r.ProjectID,
+		Priority:  notification.PriorityLow,
+		Title:     fmt.Sprintf("Daily digest: %d notifications", len(pending)),
+		Mentions:  r.Mentions,
+		At:        s.now(),
+	}
+	if s.publicURL != "" {
+		msg.URL = s.publicURL + "/projects/" + url.PathEscape(r.ProjectID)
+	}
+	for i := range pending {
+		msg.Items = append(msg.Items, pending[i].Message)
+	}
+	return msg
+}
+
+// retry schedules the pending notifications again with exponential
+// backoff, or drops those that reached max_attempts.
+func (s *NotificationService) retry(ctx context.Context, r *notification.Rule, pending []notification.Pending, cause error) {
+	slog.Warn("notification delivery failed", "rule_id", r.ID, "project_id", r.ProjectID, "kind", r.Kind, "error", cause)
+	var dropped []notification.Pending
+	for i := range pending {
+		p := &pending[i]
+		if p.Attempts+1 >= s.cfg.MaxAttempts {
+			dropped = append(dropped, *p)
+			continue
+		}
+		wait := s.cfg.SendInterval << min(p.Attempts, 16)
+		if wait <= 0 || wait > notifyMaxBackoff {
+			wait = notifyMaxBackoff
+		}
+		if err := s.store.RetryNotification(ctx, p.ID, s.now().Add(wait), cause.Error()); err != nil {
+			slog.Error("record notification failure", "notification_id", p.ID, "error", err)
+		}
+	}
+	if len(dropped) > 0 {
+		slog.Error("notifications dropped after max attempts", "rule_id", r.ID, "count", len(dropped), "error", cause)
+		s.drop(ctx, dropped)
+	}
+}
+
+// drop removes pending notifications without sending them.
+func (s *NotificationService) drop(ctx context.Context, pending []notification.Pending) {
+	if err := s.store.DeleteNotifications(ctx, pendingIDs(pending)); err != nil {
+		slog.Error("remove dropped notifications", "error", err)
+	}
+}
+
+// notifier creates the rule's notifier, resolving its webhook_secret from
+// the project's secrets and adding the SMTP settings to email rules.
+func (s *NotificationService) notifier(ctx context.Context, r *notification.Rule) (notifier.Notifier, error) {
+	cfg := maps.Clone(r.Config)
+	if cfg == nil {
+		cfg = make(map[string]string)
+	}
+	if r.Kind == notification.KindEmail {
+		cfg[notification.ConfigSMTPHost] = s.cfg.SMTPHost
+		cfg[notification.ConfigSMTPPort] = strconv.Itoa(s.cfg.SMTPPort)
+		cfg[notification.ConfigSMTPUsername] = s.cfg.SMTPUsername
+		cfg[notification.ConfigSMTPPassword] = s.cfg.SMTPPassword
+		cfg[notification.ConfigSMTPFrom] = s.cfg.SMTPFrom
+	}
+	if name := cfg[notification.ConfigWebhookSecret]; name != "" {
+		if s.secrets == nil {
+			return nil, errors.New("webhook_secret needs secrets.master_key")
+		}
+		env, err := s.secrets.Resolve(ctx, r.ProjectID, []string{name})
+		if err != nil {
+			return nil, fmt.Errorf("resolve webhook: %w", err)
+		}
+		delete(cfg, notification.ConfigWebhookSecret)
+		cfg[notification.ConfigWebhookURL] = env[name]
+	}
+	return notifier.New(string(r.Kind), cfg)
+}
+
+// settings returns the project's settings or the defaults.
+func (s *NotificationService) settings(ctx context.Context, projectID string) (*notification.Settings, error) {
+	st, err := s.store.GetNotificationSettings(ctx, projectID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return &notification.Settings{ProjectID: projectID}, nil
+	}
+	return st, err
+}
+
+// validate checks a rule request and that its kind has a notifier.
+func (s *NotificationService) validate(req *notification.CreateRuleRequest) error {
+	if err := req.Validate(); err != nil {
+		return fmt.Errorf("validate notification rule: %w", err)
+	}
+	if !slices.Contains(s.Kinds(), string(req.Kind)) {
+		return fmt.Errorf("validate notification rule: %s: %w", req.Kind, ErrUnknownNotifier)
+	}
+	if req.Config[notification.ConfigInteractive] == "true" && s.cfg.SlackSigningSecret == "" {
+		return fmt.Errorf("validate notification rule: %w", ErrSlackNotInteractive)
+	}
+	return nil
+}
+
+func applyRule(r *notification.Rule, req *notification.CreateRuleRequest) {
+	r.Name, r.Events, r.Kind, r.Config = req.Name, req.Events, req.Kind, req.Config
+	r.Mentions, r.Digest = req.Mentions, req.Digest
+	r.Enabled = req.Enabled == nil || *req.Enabled
+}
+
+func pendingIDs(pending []notification.Pending) []string {
+	out := make([]string, len(pending))
+	for i := range pending {
+		out[i] = pending[i].ID
+	}
+	return out
+}
@@ -0,0 +1,333 @@
+package service_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/notification"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/port/notifier"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+// fakeHook stands in for a Slack webhook; this package does not import
+// the Slack adapter.
+type fakeHook struct {
+	mu   sync.Mutex
+	sent []notification.Message
+	fail error
+}
+
+func (h *fakeHook) Send(_ context.Context, msg *notification.Message) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.fail != nil {
+		return h.fail
+	}
+	h.sent = append(h.sent, *msg)
+	return nil
+}
+
+func (h *fakeHook) messages() []notification.Message {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.sent)
+}
+
+var (
+	hooksMu sync.Mutex
+	hooks   = make(map[string]*fakeHook)
+)
+
+// hook returns the fake webhook behind url.
+func hook(url string) *fakeHook {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	if hooks[url] == nil {
+		hooks[url] = &fakeHook{}
+	}
+	return hooks[url]
+}
+
+func init() {
+	notifier.Register(string(notification.KindSlack), func(cfg map[string]string) (notifier.Notifier, error) {
+		return hook(cfg[notification.ConfigWebhookURL]), nil
+	})
+}
+
+func newNotifyTestSetup(t *testing.T) (*runtimeMockStore, *service.NotificationService) {
+	t.Helper()
+	store := &runtimeMockStore{}
+	store.projects = []project.Project{{ID: "proj-1", Name: "test"}}
+	return store, service.NewNotificationService(store, config.Notify{
+		SendInterval: 10 * time.Second, SendTimeout: time.Second, MaxAttempts: 3,
+	})
+}
+
+// createSlackRule adds a Slack rule for events whose webhook is the fake
+// hook named after the test.
+func createSlackRule(t *testing.T, svc *service.NotificationService, name string, digest bool, events ...string) *fakeHook {
+	t.Helper()
+	url := "https://hooks.slack.test/" + t.Name() + "/" + name
+	_, err := svc.CreateRule(context.Background(), "proj-1", &notification.CreateRuleRequest{
+		Name:     name,
+		Events:   events,
+		Kind:     notification.KindSlack,
+		Config:   map[string]string{notification.ConfigWebhookURL: url},
+		Mentions: []string{"U" + name},
+		Digest:   digest,
+	})
+	if err != nil {
+		t.Fatalf("create rule %s: %v", name, err)
+	}
+	return hook(url)
+}
+
+func notifyMessage(eventType string, prio notification.Priority) *notification.Message {
+	return &notification.Message{EventType: eventType, ProjectID: "proj-1", Priority: prio, Title: eventType}
+}
+
+func TestNotification_CreateRuleUnknownKind(t *testing.T) {
+	_, svc := newNotifyTestSetup(t)
+	_, err := svc.CreateRule(context.Background(), "proj-1", &notification.CreateRuleRequest{
+		Name: "runs", Kind: notification.KindDiscord,
+		Config: map[string]string{notification.ConfigWebhookURL: "https://discord.test/api/webhooks/1/x"},
+	})
+	if !errors.Is(err, service.ErrUnknownNotifier) {
+		t.Fatalf("expected ErrUnknownNotifier, got %v", err)
+	}
+}
+
+func TestNotification_RoutesByEventType(t *testing.T) {
+	store, svc := newNotifyTestSetup(t)
+	approvals := createSlackRule(t, svc, "approvals", false, "plan.approval.*")
+	runs := createSlackRule(t, svc, "runs", false, "run.failed")
+	ctx := context.Background()
+
+	msg := notifyMessage("run.failed", notification.PriorityNormal)
+	msg.Mentions = []string{"here"}
+	svc.Notify(ctx, msg)
+	if n, err := svc.Send(ctx); err != nil || n != 1 {
+		t.Fatalf("expected one message sent, got %d %v", n, err)
+	}
+	if got := approvals.messages(); len(got) != 0 {
+		t.Fatalf("expected nothing for the approvals rule, got %+v", got)
+	}
+	got := runs.messages()
+	if len(got) != 1 || got[0].EventType != "run.failed" || !slices.Equal(got[0].Mentions, []string{"Uruns", "here"}) {
+		t.Fatalf("expected run.failed with the rule's and the message's mentions, got %+v", got)
+	}
+	if len(store.outbox) != 0 {
+		t.Fatalf("expected the outbox empty after sending, got %+v", store.outbox)
+	}
+}
+
+func TestNotification_QuietHours(t *testing.T) {
+	_, svc := newNotifyTestSetup(t)
+	h := createSlackRule(t, svc, "all", false)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	if _, err := svc.SetSettings(ctx, "proj-1", &notification.Settings{
+		QuietStart: now.Add(-time.Hour).Format("15:04"),
+		QuietEnd:   now.Add(time.Hour).Format("15:04"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	svc.Notify(ctx, notifyMessage("run.failed", notification.PriorityNormal))
+	if n, err := svc.Send(ctx); err != nil || n != 0 {
+		t.Fatalf("expected the normal message held in quiet hours, got %d %v", n, err)
+	}
+	svc.Notify(ctx, notifyMessage("plan.approval.escalated", notification.PriorityHigh))
+	if n, err := svc.Send(ctx); err != nil || n != 1 {
+		t.Fatalf("expected the high-priority message sent in quiet hours, got %d %v", n, err)
+	}
+	if got := h.messages(); len(got) != 1 || got[0].EventType != "plan.approval.escalated" {
+		t.Fatalf("expected only the escalation sent, got %+v", got)
+	}
+}
+
+func TestNotification_Digest(t *testing.T) {
+	store, svc := newNotifyTestSetup(t)
+	h := createSlackRule(t, svc, "digest", true)
+	ctx := context.Background()
+
+	svc.Notify(ctx, notifyMessage("run.completed", notification.PriorityLow))
+	svc.Notify(ctx, notifyMessage("plan.approval.voted", notification.PriorityLow))
+	svc.Notify(ctx, notifyMessage("run.failed", notification.PriorityNormal))
+	if n, err := svc.Send(ctx); err != nil || n != 1 {
+		t.Fatalf("expected only the normal message sent before the digest, got %d %v", n, err)
+	}
+
+	store.mu.Lock()
+	for i := range store.outbox {
+		if !store.outbox[i].Digest {
+			t.Errorf("expected only digest entries left, got %+v", store.outbox[i])
+		}
+		store.outbox[i].SendAt = time.Now().Add(-time.Minute)
+	}
+	store.mu.Unlock()
+
+	if n, err := svc.Send(ctx); err != nil || n != 1 {
+		t.Fatalf("expected one digest message, got %d %v", n, err)
+	}
+	got := h.messages()
+	if len(got) != 2 {
+		t.Fatalf("expected two messages, got %+v", got)
+	}
+	digest := got[1]
+	if digest.Title != "Daily digest: 2 notifications" || len(digest.Items) != 2 || digest.Items[0].EventType != "run.completed" {
+		t.Fatalf("unexpected digest %+v", digest)
+	}
+}
+
+func TestNotification_RetryThenDrop(t *testing.T) {
+	store, svc := newNotifyTestSetup(t)
+	h := createSlackRule(t, svc, "flaky", false)
+	h.fail = errors.New("status 500")
+	ctx := context.Background()
+
+	svc.Notify(ctx, notifyMessage("run.failed", notification.PriorityNormal))
+	for attempt := 1; attempt < 3; attempt++ {
+		if n, err := svc.Send(ctx); err != nil || n != 0 {
+			t.Fatalf("attempt %d: expected nothing delivered, got %d %v", attempt, n, err)
+		}
+		store.mu.Lock()
+		if len(store.outbox) != 1 || store.outbox[0].Attempts != attempt || store.outbox[0].LastError != "status 500" || !store.outbox[0].SendAt.After(time.Now()) {
+			store.mu.Unlock()
+			t.Fatalf("attempt %d: expected a retry scheduled, got %+v", attempt, store.outbox)
+		}
+		store.outbox[0].SendAt = time.Now().Add(-time.Second)
+		store.mu.Unlock()
+	}
+	if _, err := svc.Send(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.outbox) != 0 {
+		t.Fatalf("expected the notification dropped after max attempts, got %+v", store.outbox)
+	}
+}
+
+func TestNotification_ApprovalEscalation(t *testing.T) {
+	store, orchSvc, p := newPolicyApprovalPlan(t, &policy.ApprovalPolicy{
+		TimeoutSeconds: 3600, EscalateAfterSeconds: 600, EscalateTo: []string{"lead"},
+	}, false)
+	notifySvc := service.NewNotificationService(store, config.Notify{SendInterval: time.Second, SendTimeout: time.Second, MaxAttempts: 3})
+	orchSvc.SetNotificationService(notifySvc)
+	h := createSlackRule(t, notifySvc, "escalations", false, "plan.approval.escalated")
+	ctx := context.Background()
+
+	gate := stepByIndex(t, orchSvc, p.ID, 1)
+	backdateApproval(store, gate.ID, 15*time.Minute)
+	if _, err := orchSvc.CheckApprovals(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := notifySvc.Send(ctx); err != nil || n != 1 {
+		t.Fatalf("expected the escalation sent, got %d %v", n, err)
+	}
+	got := h.messages()
+	if len(got) != 1 || got[0].Priority != notification.PriorityHigh || !slices.Equal(got[0].Mentions, []string{"Uescalations", "lead"}) {
+		t.Fatalf("expected a high-priority escalation mentioning escalate_to, got %+v", got)
+	}
+}
+
+// slackClick builds a signed click on an approval button.
+func slackClick(t *testing.T, responseURL, user, action, planID, stepID string) (http.Header, []byte) {
+	t.Helper()
+	payload, _ := json.Marshal(map[string]any{
+		"type":         "block_actions",
+		"user":         map[string]string{"id": user, "username": strings.ToLower(user)},
+		"response_url": responseURL,
+		"message":      map[string]string{"text": "*Approval requested: gated plan / gate*"},
+		"actions":      []map[string]string{{"action_id": action, "value": planID + "/" + stepID}},
+	})
+	body := url.Values{"payload": {string(payload)}}.Encode()
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte("v0:" + ts + ":" + body))
+	return http.Header{
+		"X-Slack-Request-Timestamp": {ts},
+		"X-Slack-Signature":         {"v0=" + hex.EncodeToString(mac.Sum(nil))},
+	}, []byte(body)
+}
+
+func TestNotification_SlackApproval(t *testing.T) {
+	store, orchSvc, p := newPolicyApprovalPlan(t, &policy.ApprovalPolicy{Approvers: []string{"slack:UALICE"}}, false)
+	notifySvc := service.NewNotificationService(store, config.Notify{SendTimeout: time.Second, SlackSigningSecret: "s3cret"})
+	notifySvc.SetApprovalService(orchSvc)
+	ctx := context.Background()
+	gate := stepByIndex(t, orchSvc, p.ID, 1)
+
+	answers := make(chan map[string]any, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		var m map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&m)
+		answers <- m
+	}))
+	defer srv.Close()
+	answer := func() map[string]any {
+		t.Helper()
+		select {
+		case m := <-answers:
+			return m
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected an answer at the response URL")
+			return nil
+		}
+	}
+
+	header, body := slackClick(t, srv.URL, "UALICE", "plan_approval_approve", p.ID, gate.ID)
+	header.Set("X-Slack-Signature", "v0=00")
+	if err := notifySvc.HandleSlackInteraction(ctx, header, body); !errors.Is(err, notifier.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+
+	// Someone who is not an approver is told so, and the step keeps waiting.
+	header, body = slackClick(t, srv.URL, "UBOB", "plan_approval_reject", p.ID, gate.ID)
+	if err := notifySvc.HandleSlackInteraction(ctx, header, body); err != nil {
+		t.Fatal(err)
+	}
+	if m := answer(); m["response_type"] != "ephemeral" || m["text"] != "You are not an approver of this step." {
+		t.Fatalf("expected an ephemeral refusal, got %v", m)
+	}
+
+	header, body = slackClick(t, srv.URL, "UALICE", "plan_approval_approve", p.ID, gate.ID)
+	if err := notifySvc.HandleSlackInteraction(ctx, header, body); err != nil {
+		t.Fatal(err)
+	}
+	m := answer()
+	if m["replace_original"] != true || m["text"] != "*Approval requested: gated plan / gate*\nApproved by <@UALICE>" {
+		t.Fatalf("expected the message replaced with the outcome, got %v", m)
+	}
+	gate = stepByIndex(t, orchSvc, p.ID, 1)
+	if gate.Status != plan.StepStatusCompleted || gate.Approval.By != "slack:UALICE" || gate.Approval.Name != "ualice" {
+		t.Fatalf("expected the gate approved by the Slack user, got %s %+v", gate.Status, gate.Approval)
+	}
+}
+
+func TestNotification_InteractiveRuleNeedsSigningSecret(t *testing.T) {
+	_, svc := newNotifyTestSetup(t)
+	_, err := svc.CreateRule(context.Background(), "proj-1", &notification.CreateRuleRequest{
+		Name: "approvals", Kind: notification.KindSlack,
+		Config: map[string]string{notification.ConfigWebhookURL: "https://hooks.slack.test/x", notification.ConfigInteractive: "true"},
+	})
+	if !errors.Is(err, service.ErrSlackNotInteractive) {
+		t.Fatalf("expected ErrSlackNotInteractive, got %v", err)
+	}
+}
@@ -3,12 +3,19 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
 	"github.com/Strob0t/CodeForge/internal/adapter/ws"
 	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/a2a"
 	cfcontext "github.com/Strob0t/CodeForge/internal/domain/context"
 	"github.com/Strob0t/CodeForge/internal/domain/event"
 	"github.com/Strob0t/CodeForge/internal/domain/plan"
@@ -20,13 +27,39 @@ import (
 
 // OrchestratorService manages execution plans — multi-agent DAGs with scheduling protocols.
 type OrchestratorService struct {
-	store     database.Store
-	hub       broadcast.Broadcaster
-	events    eventstore.Store
-	runtime   *RuntimeService
-	orchCfg   *config.Orchestrator
-	sharedCtx *SharedContextService
-	mu        sync.Mutex // serializes plan advancement
+	store      database.Store
+	hub        broadcast.Broadcaster
+	events     eventstore.Store
+	runtime    *RuntimeService
+	orchCfg    *config.Orchestrator
+	sharedCtx  *SharedContextService
+	experience *ExperienceService
+	pool       *PoolManagerService
+	arbiter    *litellm.Client
+	retrieval  *RetrievalService
+	graph      *GraphService
+	images     *ImageBuildService
+	audit      *AuditService
+	notify     *NotificationService
+	publicURL  string
+	mu         sync.Mutex        // serializes plan advancement
+	trees      map[string]string // Plan ID to the workspace tree its steps left; guarded by mu
+	builds     sync.Map          // Step ID to the context.CancelFunc of its image build
+}
+
+// ErrStepNotAwaitingApproval is returned when resolving a step that is not
+// an approval step paused for a decision.
+var ErrStepNotAwaitingApproval = errors.New("step is not waiting for approval")
+
+// SetAuditService records approval decisions in the audit log.
+func (s *OrchestratorService) SetAuditService(a *AuditService) {
+	s.audit = a
+}
+
+// SetNotificationService routes approval events to the projects'
+// notification rules.
+func (s *OrchestratorService) SetNotificationService(n *NotificationService) {
+	s.notify = n
 }
 
 // SetSharedContext sets the shared context service for auto-populating run outputs.
@@ -34,6 +67,47 @@ func (s *OrchestratorService) SetSharedContext(sc *SharedContextService) {
 	s.sharedCtx = sc
 }
 
+// SetExperiencePool records the outcome of every finished plan in the
+// experience pool.
+func (s *OrchestratorService) SetExperiencePool(e *ExperienceService) {
+	s.experience = e
+}
+
+// SetPoolManager lets busy agents be scaled out to clones when their
+// steps start.
+func (s *OrchestratorService) SetPoolManager(pm *PoolManagerService) {
+	s.pool = pm
+}
+
+// SetArbiter lets finished ping_pong debates be summarized by the
+// orchestrator's arbiter model.
+func (s *OrchestratorService) SetArbiter(llm *litellm.Client) {
+	s.arbiter = llm
+}
+
+// SetRetrieval lets human edits between plan steps refresh the edited
+// files in the project's retrieval index.
+func (s *OrchestratorService) SetRetrieval(r *RetrievalService) {
+	s.retrieval = r
+}
+
+// SetGraph lets human edits between plan steps update the edited files in
+// the project's persisted code graph.
+func (s *OrchestratorService) SetGraph(g *GraphService) {
+	s.graph = g
+}
+
+// SetImageBuilder lets image steps build and push container images.
+// Without it they fail.
+func (s *OrchestratorService) SetImageBuilder(b *ImageBuildService) {
+	s.images = b
+}
+
+// SetPublicURL sets the web UI base URL used for approval deep links.
+func (s *OrchestratorService) SetPublicURL(u string) {
+	s.publicURL = strings.TrimSuffix(u, "/")
+}
+
 // NewOrchestratorService creates an OrchestratorService with all dependencies.
 func NewOrchestratorService(
 	store database.Store,
@@ -48,11 +122,26 @@ func NewOrchestratorService(
 		events:  events,
 		runtime: runtime,
 		orchCfg: orchCfg,
+		trees:   make(map[string]string),
 	}
 }
 
 // CreatePlan validates and persists a new execution plan.
 func (s *OrchestratorService) CreatePlan(ctx context.Context, req *plan.CreatePlanRequest) (*plan.ExecutionPlan, error) {
+	// References to other steps' outputs in step prompts make those steps
+	// dependencies. Missing tasks fail when their step starts.
+	prompts := make([]string, len(req.Steps))
+	for i := range req.Steps {
+		if req.Steps[i].TaskID == "" {
+			continue
+		}
+		if t, err := s.store.GetTask(ctx, req.Steps[i].TaskID); err == nil {
+			prompts[i] = t.Prompt
+		}
+	}
+	if err := req.LinkRefs(prompts); err != nil {
+		return nil, fmt.Errorf("validate plan: %w", err)
+	}
 	if err := req.Validate(); err != nil {
 		return nil, fmt.Errorf("validate plan: %w", err)
 	}
@@ -74,13 +163,24 @@ func (s *OrchestratorService) CreatePlan(ctx context.Context, req *plan.CreatePl
 
 	// Build steps with correct initial state
 	for _, sr := range req.Steps {
+		var image *plan.ImageBuild
+		if sr.Image != nil {
+			image = &plan.ImageBuild{ImageSpec: *sr.Image}
+		}
 		p.Steps = append(p.Steps, plan.Step{
+			Type:          sr.Type,
+			Name:          sr.Name,
 			TaskID:        sr.TaskID,
 			AgentID:       sr.AgentID,
+			Model:         sr.Model,
 			PolicyProfile: sr.PolicyProfile,
 			DeliverMode:   sr.DeliverMode,
 			DependsOn:     sr.DependsOn, // indices; DB adapter remaps to UUIDs
 			Status:        plan.StepStatusPending,
+			Retry:         sr.Retry,
+			When:          sr.When,
+			Loop:          sr.Loop,
+			Image:         image,
 		})
 	}
 
@@ -124,6 +224,17 @@ func (s *OrchestratorService) GetPlan(ctx context.Context, id string) (*plan.Exe
 	return s.store.GetPlan(ctx, id)
 }
 
+// GetPlanGraph returns the plan as a node/edge graph including each step's
+// attempt count and retry history.
+func (s *OrchestratorService) GetPlanGraph(ctx context.Context, id string) (*plan.Graph, error) {
+	p, err := s.store.GetPlan(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	g := plan.BuildGraph(p)
+	return &g, nil
+}
+
 // ListPlans returns all plans for a project.
 func (s *OrchestratorService) ListPlans(ctx context.Context, projectID string) ([]plan.ExecutionPlan, error) {
 	return s.store.ListPlansByProject(ctx, projectID)
@@ -148,6 +259,12 @@ func (s *OrchestratorService) CancelPlan(ctx context.Context, planID string) err
 			if p.Steps[i].RunID != "" {
 				_ = s.runtime.CancelRun(ctx, p.Steps[i].RunID)
 			}
+			if cancel, ok := s.builds.LoadAndDelete(p.Steps[i].ID); ok {
+				cancel.(context.CancelFunc)()
+			}
+			_ = s.store.UpdatePlanStepStatus(ctx, p.Steps[i].ID, plan.StepStatusCancelled, "", "plan cancelled")
+			s.broadcastStepStatus(ctx, p, &p.Steps[i], plan.StepStatusCancelled)
+		case plan.StepStatusWaitingApproval:
 			_ = s.store.UpdatePlanStepStatus(ctx, p.Steps[i].ID, plan.StepStatusCancelled, "", "plan cancelled")
 			s.broadcastStepStatus(ctx, p, &p.Steps[i], plan.StepStatusCancelled)
 		}
@@ -164,6 +281,12 @@ func (s *OrchestratorService) CancelPlan(ctx context.Context, planID string) err
 	return nil
 }
 
+// ListPendingApprovals returns the approval steps waiting for a human,
+// optionally of one project's plans.
+func (s *OrchestratorService) ListPendingApprovals(ctx context.Context, projectID string) ([]plan.Step, error) {
+	return s.store.ListWaitingApprovals(ctx, projectID)
+}
+
 // HandleRunCompleted is the callback invoked by RuntimeService when a run finishes.
 // It finds the corresponding plan step and advances the plan.
 func (s *OrchestratorService) HandleRunCompleted(ctx context.Context, runID string, status run.Status) {
@@ -173,14 +296,24 @@ func (s *OrchestratorService) HandleRunCompleted(ctx context.Context, runID stri
 		return
 	}
 
+	// The run's changes to the workspace are the agent's, not a human's.
+	s.mu.Lock()
+	delete(s.trees, step.PlanID)
+	s.mu.Unlock()
+
 	stepStatus := plan.StepStatusCompleted
 	errMsg := ""
 	switch status {
 	case run.StatusFailed, run.StatusTimeout:
 		stepStatus = plan.StepStatusFailed
+		agentID := step.AgentID
 		r, err := s.store.GetRun(ctx, runID)
 		if err == nil {
 			errMsg = r.Error
+			agentID = r.AgentID
+		}
+		if step.Retry.CanRetry(step.Attempt) && s.retryStep(ctx, step, runID, agentID, errMsg) {
+			return
 		}
 	case run.StatusCancelled:
 		stepStatus = plan.StepStatusCancelled
@@ -196,8 +329,12 @@ func (s *OrchestratorService) HandleRunCompleted(ctx context.Context, runID stri
 		slog.Error("get plan for advancement", "plan_id", step.PlanID, "error", err)
 		return
 	}
+	if p.Protocol == plan.ProtocolPingPong {
+		s.recordDebateTurn(ctx, p, step, runID, stepStatus, errMsg)
+	}
 
-	// Auto-populate SharedContext with run output for downstream agents.
+	// Auto-populate SharedContext with run output for downstream agents,
+	// and with its structured answer if the run's mode declares one.
 	if s.sharedCtx != nil && stepStatus == plan.StepStatusCompleted {
 		r, err := s.store.GetRun(ctx, runID)
 		if err == nil && r.TeamID != "" && r.Output != "" {
@@ -208,12 +345,86 @@ func (s *OrchestratorService) HandleRunCompleted(ctx context.Context, runID stri
 				Author: r.AgentID,
 			})
 		}
+		if err == nil && r.TeamID != "" && len(r.StructuredOutput) > 0 {
+			_, _ = s.sharedCtx.AddItem(ctx, cfcontext.AddSharedItemRequest{
+				TeamID: r.TeamID,
+				Key:    "step_structured_output:" + step.ID,
+				Value:  string(r.StructuredOutput),
+				Author: r.AgentID,
+			})
+		}
 	}
 
 	s.broadcastStepStatus(ctx, p, step, stepStatus)
 	s.advancePlan(ctx, p)
 }
 
+// retryStep records the failed attempt of a step and schedules it to run
+// again once the retry policy's backoff has elapsed. It returns false when
+// no retry was scheduled and the failure should take its normal course.
+func (s *OrchestratorService) retryStep(ctx context.Context, step *plan.Step, runID, agentID, errMsg string) bool {
+	p, err := s.store.GetPlan(ctx, step.PlanID)
+	if err != nil || p.Status != plan.StatusRunning {
+		return false
+	}
+
+	now := time.Now()
+	attempts := append(step.Attempts, plan.StepAttempt{
+		Attempt:  step.Attempt,
+		RunID:    runID,
+		AgentID:  agentID,
+		Model:    step.ModelFor(step.Attempt),
+		Error:    errMsg,
+		FailedAt: now,
+	})
+	delay := step.Retry.Backoff(step.Attempt)
+	retryAt := now.Add(delay)
+	if err := s.store.UpdatePlanStepAttempts(ctx, step.ID, step.Attempt, attempts, &retryAt); err != nil {
+		slog.Error("record step attempt", "step_id", step.ID, "error", err)
+		return false
+	}
+	if err := s.store.UpdatePlanStepStatus(ctx, step.ID, plan.StepStatusPending, "", errMsg); err != nil {
+		slog.Error("reset step for retry", "step_id", step.ID, "error", err)
+		return false
+	}
+	step.Attempts = attempts
+	step.RetryAt = &retryAt
+	step.Error = errMsg
+
+	next := step.Attempt + 1
+	s.appendStepEvent(ctx, event.TypePlanStepRetry, p, step, runID, map[string]string{
+		"agent_id":        agentID,
+		"attempt":         strconv.Itoa(step.Attempt),
+		"next_attempt":    strconv.Itoa(next),
+		"next_agent_id":   step.Retry.AgentFor(next, step.AgentID),
+		"next_model":      step.ModelFor(next),
+		"backoff_seconds": strconv.Itoa(int(delay.Seconds())),
+		"error":           errMsg,
+	})
+	s.broadcastStepStatus(ctx, p, step, plan.StepStatusPending)
+	slog.Info("plan step retry scheduled", "plan_id", p.ID, "step_id", step.ID,
+		"attempt", next, "agent_id", step.Retry.AgentFor(next, step.AgentID), "backoff", delay)
+
+	if delay > 0 {
+		planID := p.ID
+		bg := context.WithoutCancel(ctx)
+		time.AfterFunc(delay, func() { s.resumePlan(bg, planID) })
+		return true
+	}
+	s.advancePlan(ctx, p)
+	return true
+}
+
+// resumePlan reloads a plan and advances it, e.g. once a retry backoff elapsed.
+func (s *OrchestratorService) resumePlan(ctx context.Context, planID string) {
+	p, err := s.store.GetPlan(ctx, planID)
+	if err != nil {
+		slog.Error("resume plan", "plan_id", planID, "error", err)
+		return
+	}
+	s.advancePlan(ctx, p)
+}
+
 // advancePlan is the core scheduling loop. It checks the current state of all steps
 // and dispatches to the appropriate protocol handler.
 func (s *OrchestratorService) advancePlan(ctx context.Context, p *plan.ExecutionPlan) {
@@ -230,9 +441,19 @@ func (s *OrchestratorService) advancePlan(ctx context.Context, p *plan.Execution
 
 	// Check if plan is already terminal
 	if p.Status != plan.StatusRunning {
+		delete(s.trees, p.ID)
 		return
 	}
 
+	// Between steps, changes to the workspace are human edits.
+	if plan.RunningCount(p.Steps) == 0 {
+		s.checkHumanEdits(ctx, p)
+	}
+
+	if p.Protocol == plan.ProtocolSequential || p.Protocol == plan.ProtocolParallel {
+		s.resolveFlow(ctx, p)
+	}
+
 	switch p.Protocol {
 	case plan.ProtocolSequential:
 		s.advanceSequential(ctx, p)
@@ -243,6 +464,9 @@ func (s *OrchestratorService) advancePlan(ctx context.Context, p *plan.Execution
 	case plan.ProtocolConsensus:
 		s.advanceConsensus(ctx, p)
 	}
+	if p.Status != plan.StatusRunning {
+		delete(s.trees, p.ID)
+	}
 }
 
 // advanceSequential: one step at a time. Failure stops the plan.
@@ -324,6 +548,16 @@ func (s *OrchestratorService) advancePingPong(ctx context.Context, p *plan.Execu
 		return // wait for current step
 	}
 
+	// A failed turn awaiting its retry re-runs before the turn passes on.
+	for _, st := range []*plan.Step{s0, s1} {
+		if st.Status == plan.StepStatusPending && st.RetryAt != nil {
+			if st.RetryDue(time.Now()) {
+				s.startStep(ctx, p, st.ID)
+			}
+			return
+		}
+	}
+
 	// Determine which step goes next: alternate, starting with step 0
 	// Step 0 goes on rounds: 1, 3, 5, ... ; Step 1 goes on rounds: 2, 4, 6, ...
 	totalCompleted := s0.Round + s1.Round
@@ -359,6 +593,12 @@ func (s *OrchestratorService) advancePingPong(ctx context.Context, p *plan.Execu
 		slog.Error("reset step to pending", "step_id", next.ID, "error", err)
 		return
 	}
+	// Every round gets the full retry budget.
+	if err := s.store.UpdatePlanStepAttempts(ctx, next.ID, 0, next.Attempts, nil); err != nil {
+		slog.Error("reset step attempts", "step_id", next.ID, "error", err)
+		return
+	}
+	next.Attempt = 0
 
 	s.startStep(ctx, p, next.ID)
 }
@@ -387,8 +627,9 @@ func (s *OrchestratorService) advanceConsensus(ctx context.Context, p *plan.Exec
 	}
 
 	// Launch all pending steps (no dependency constraints in consensus)
+	now := time.Now()
 	for i := range p.Steps {
-		if p.Steps[i].Status == plan.StepStatusPending {
+		if p.Steps[i].Status == plan.StepStatusPending && p.Steps[i].RetryDue(now) {
 			s.startStep(ctx, p, p.Steps[i].ID)
 		}
 	}
@@ -407,15 +648,61 @@ func (s *OrchestratorService) startStep(ctx context.Context, p *plan.ExecutionPl
 		slog.Error("step not found in plan", "step_id", stepID, "plan_id", p.ID)
 		return
 	}
+	if step.IsApproval() {
+		s.requestApproval(ctx, p, step)
+		return
+	}
+	if s.runtime.Draining() {
+		// The step stays pending, without using up an attempt.
+		slog.Warn("plan step not started, server is shutting down", "plan_id", p.ID, "step_id", stepID)
+		return
+	}
+	if step.IsImage() {
+		s.startImageStep(ctx, p, step)
+		return
+	}
 
+	// Retries may switch to a fallback agent or model.
+	attempt := step.Attempt + 1
 	req := &run.StartRequest{
 		TaskID:        step.TaskID,
-		AgentID:       step.AgentID,
+		AgentID:       step.Retry.AgentFor(attempt, step.AgentID),
+		Model:         step.ModelFor(attempt),
 		ProjectID:     p.ProjectID,
 		TeamID:        p.TeamID,
 		PolicyProfile: step.PolicyProfile,
 		DeliverMode:   run.DeliverMode(step.DeliverMode),
+		PlanStepID:    step.ID,
+		// Concurrent steps each get their own worktree instead of sharing one clone.
+		Isolate: p.Protocol == plan.ProtocolParallel || p.Protocol == plan.ProtocolConsensus,
+	}
+
+	// A busy agent may be scaled out to a clone.
+	if s.pool != nil {
+		req.AgentID = s.pool.AcquireAgent(ctx, req.AgentID)
+	}
+
+	// Steps of A2A agents are delegated to the remote agent.
+	ag, err := s.store.GetAgent(ctx, req.AgentID)
+	remote := err == nil && ag.Backend == a2a.BackendName
+	if remote {
+		req.ExecMode, req.Isolate = run.ExecModeRemote, false
+	}
+
+	if err := s.store.UpdatePlanStepAttempts(ctx, stepID, attempt, step.Attempts, nil); err != nil {
+		slog.Error("record step attempt", "step_id", stepID, "error", err)
 	}
+	step.Attempt = attempt
+	step.RetryAt = nil
+
+	prompt, err := s.stepPrompt(ctx, p, step)
+	if err != nil {
+		slog.Error("resolve step prompt", "step_id", stepID, "error", err)
+		_ = s.store.UpdatePlanStepStatus(ctx, stepID, plan.StepStatusFailed, "", err.Error())
+		s.broadcastStepStatus(ctx, p, step, plan.StepStatusFailed)
+		return
+	}
+	req.Prompt = prompt
 
 	r, err := s.runtime.StartRun(ctx, req)
 	if err != nil {
@@ -427,7 +714,53 @@ func (s *OrchestratorService) startStep(ctx context.Context, p *plan.ExecutionPl
 
 	_ = s.store.UpdatePlanStepStatus(ctx, stepID, plan.StepStatusRunning, r.ID, "")
 	s.broadcastStepStatus(ctx, p, step, plan.StepStatusRunning)
-	slog.Info("plan step started", "plan_id", p.ID, "step_id", stepID, "run_id", r.ID)
+	slog.Info("plan step started", "plan_id", p.ID, "step_id", stepID, "run_id", r.ID, "attempt", attempt, "remote", remote)
+
+	if remote {
+		go s.delegateRun(ctx, r, ag)
+	}
+}
+
+// stepPrompt resolves the references to other steps' outputs in the
+// prompt of a step's task. It returns "" if the prompt has none, so the
+// run uses the task prompt.
+func (s *OrchestratorService) stepPrompt(ctx context.Context, p *plan.ExecutionPlan, step *plan.Step) (string, error) {
+	t, err := s.store.GetTask(ctx, step.TaskID)
+	if err != nil || len(plan.ParseRefs(t.Prompt)) == 0 {
+		return "", nil // a missing task fails in StartRun
+	}
+	return plan.Interpolate(t.Prompt, func(ref plan.StepRef) (string, error) {
+		var src *plan.Step
+		for i := range p.Steps {
+			if p.Steps[i].Name == ref.Step {
+				src = &p.Steps[i]
+			}
+		}
+		if src != nil && src.IsImage() && src.Status == plan.StepStatusCompleted && ref.Field == "" {
+			return src.Image.Reference(), nil
+		}
+		if src == nil || src.Status != plan.StepStatusCompleted || src.RunID == "" {
+			return "", fmt.Errorf("%w: {{%s}}: step did not complete", plan.ErrStepRefUnresolved, ref)
+		}
+		r, err := s.store.GetRun(ctx, src.RunID)
+		if err != nil {
+			return "", fmt.Errorf("%w: {{%s}}: %w", plan.ErrStepRefUnresolved, ref, err)
+		}
+		if ref.Field == "" {
+			return r.Output, nil
+		}
+		v, ok := plan.Field(r.StructuredOutput, ref.Field)
+		if !ok {
+			return "", fmt.Errorf("%w: {{%s}}: no such field in the structured output", plan.ErrStepRefUnresolved, ref)
+		}
+		return v, nil
+	})
+}
+
+// approvalURL returns the web UI link for reviewing an approval step.
+func (s *OrchestratorService) approvalURL(p *plan.ExecutionPlan, step *plan.Step) string {
+	q := url.Values{"plan": {p.ID}, "step": {step.ID}}
+	return s.publicURL + "/projects/" + url.PathEscape(p.ProjectID) + "?" + q.Encode()
 }
 
 // completePlan marks the plan as completed.
@@ -439,6 +772,12 @@ func (s *OrchestratorService) completePlan(ctx context.Context, p *plan.Executio
 	p.Status = plan.StatusCompleted
 	s.appendPlanEvent(ctx, event.TypePlanCompleted, p)
 	s.broadcastPlanStatus(ctx, p)
+	if s.experience != nil {
+		s.experience.Record(ctx, p)
+	}
+	if p.Protocol == plan.ProtocolPingPong && s.arbiter != nil && s.orchCfg.ArbiterModel != "" {
+		go s.summarizeDebate(context.WithoutCancel(ctx), p)
+	}
 	slog.Info("plan completed", "plan_id", p.ID)
 }
 
@@ -458,6 +797,9 @@ func (s *OrchestratorService) failPlan(ctx context.Context, p *plan.ExecutionPla
 	p.Status = plan.StatusFailed
 	s.appendPlanEvent(ctx, event.TypePlanFailed, p)
 	s.broadcastPlanStatus(ctx, p)
+	if s.experience != nil {
+		s.experience.Record(ctx, p)
+	}
 	slog.Info("plan failed", "plan_id", p.ID)
 }
 
@@ -479,17 +821,22 @@ func (s *OrchestratorService) broadcastStepStatus(ctx context.Context, p *plan.E
 		Status:    string(status),
 		RunID:     step.RunID,
 		Error:     step.Error,
+		Attempt:   step.Attempt,
 	})
 }
 
 func (s *OrchestratorService) appendPlanEvent(ctx context.Context, evtType event.Type, p *plan.ExecutionPlan) {
-	payload, _ := json.Marshal(map[string]string{
+	fields := map[string]string{
 		"plan_id":    p.ID,
 		"name":       p.Name,
 		"protocol":   string(p.Protocol),
 		"status":     string(p.Status),
 		"project_id": p.ProjectID,
-	})
+	}
+	if p.RerunOf != "" {
+		fields["rerun_of"] = p.RerunOf
+	}
+	payload, _ := json.Marshal(fields)
 
 	_ = s.events.Append(ctx, &event.AgentEvent{
 		AgentID:   "",
@@ -499,3 +846,23 @@ func (s *OrchestratorService) appendPlanEvent(ctx context.Context, evtType event
 		Payload:   payload,
 	})
 }
+
+func (s *OrchestratorService) appendStepEvent(ctx context.Context, evtType event.Type, p *plan.ExecutionPlan, step *plan.Step, runID string, fields map[string]string) {
+	data := map[string]string{
+		"plan_id": p.ID,
+		"step_id": step.ID,
+	}
+	for k, v := range fields {
+		data[k] = v
+	}
+	payload, _ := json.Marshal(data)
+
+	_ = s.events.Append(ctx, &event.AgentEvent{
+		AgentID:   fields["agent_id"],
+		TaskID:    step.TaskID,
+		ProjectID: p.ProjectID,
+		RunID:     runID,
+		Type:      evtType,
+		Payload:   payload,
+	})
+}
@@ -2,16 +2,22 @@ package service_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/Strob0t/CodeForge/internal/config"
 	"github.com/Strob0t/CodeForge/internal/domain"
 	"github.com/Strob0t/CodeForge/internal/domain/agent"
 	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
 	"github.com/Strob0t/CodeForge/internal/domain/run"
 	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
 	"github.com/Strob0t/CodeForge/internal/service"
 )
 
@@ -36,8 +42,9 @@ func (m *orchMockStore) CreatePlan(_ context.Context, p *plan.ExecutionPlan) err
 		if s.ID == "" {
 			s.ID = fmt.Sprintf("step-%d-%d", len(m.plans)+1, i)
 		}
-		m.steps = append(m.steps, *s)
 	}
+	plan.RemapStepRefs(p.Steps)
+	m.steps = append(m.steps, p.Steps...)
 	m.plans = append(m.plans, *p)
 	return nil
 }
@@ -53,7 +60,13 @@ func (m *orchMockStore) GetPlan(_ context.Context, id string) (*plan.ExecutionPl
 		var steps []plan.Step
 		for j := range m.steps {
 			if m.steps[j].PlanID == id {
-				steps = append(steps, m.steps[j])
+				st := m.steps[j]
+				if st.Approval != nil {
+					a := *st.Approval
+					a.Votes = slices.Clone(a.Votes)
+					st.Approval = &a
+				}
+				steps = append(steps, st)
 			}
 		}
 		p.Steps = steps
@@ -108,6 +121,23 @@ func (m *orchMockStore) ListPlanSteps(_ context.Context, planID string) ([]plan.
 	return result, nil
 }
 
+func (m *orchMockStore) ListWaitingApprovals(_ context.Context, projectID string) ([]plan.Step, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var result []plan.Step
+	for i := range m.steps {
+		if m.steps[i].Status != plan.StepStatusWaitingApproval {
+			continue
+		}
+		for j := range m.plans {
+			if m.plans[j].ID == m.steps[i].PlanID && (projectID == "" || m.plans[j].ProjectID == projectID) {
+				result = append(result, m.steps[i])
+			}
+		}
+	}
+	return result, nil
+}
+
 func (m *orchMockStore) UpdatePlanStepStatus(_ context.Context, stepID string, status plan.StepStatus, runID, errMsg string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -147,8 +177,80 @@ func (m *orchMockStore) UpdatePlanStepRound(_ context.Context, stepID string, ro
 	return domain.ErrNotFound
 }
 
+func (m *orchMockStore) UpdatePlanStepAttempts(_ context.Context, stepID string, attempt int, attempts []plan.StepAttempt, retryAt *time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.steps {
+		if m.steps[i].ID == stepID {
+			m.steps[i].Attempt = attempt
+			m.steps[i].Attempts = attempts
+			m.steps[i].RetryAt = retryAt
+			return nil
+		}
+	}
+	return domain.ErrNotFound
+}
+
+func (m *orchMockStore) SetPlanStepApproval(_ context.Context, stepID string, a *plan.Approval) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.steps {
+		if m.steps[i].ID != stepID {
+			continue
+		}
+		version := 0
+		if m.steps[i].Approval != nil {
+			version = m.steps[i].Approval.Version
+		}
+		if version != a.Version {
+			return domain.ErrConflict
+		}
+		a.Version++
+		stored := *a
+		stored.Votes = slices.Clone(a.Votes)
+		m.steps[i].Approval = &stored
+		return nil
+	}
+	return domain.ErrNotFound
+}
+
+func (m *orchMockStore) SetPlanStepImage(_ context.Context, stepID string, b *plan.ImageBuild) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.steps {
+		if m.steps[i].ID == stepID {
+			m.steps[i].Image = b
+			return nil
+		}
+	}
+	return domain.ErrNotFound
+}
+
 func newOrchTestSetup() (*orchMockStore, *service.OrchestratorService) {
+	store, orchSvc, _ := newOrchTestSetupWithQueue()
+	return store, orchSvc
+}
+
+// newOrchTestSetupWithQueue also returns the queue run starts are published to.
+func newOrchTestSetupWithQueue() (*orchMockStore, *service.OrchestratorService, *runtimeMockQueue) {
+	return newOrchTestSetupWithPolicies(service.NewPolicyService("headless-safe-sandbox", nil))
+}
+
+// newOrchTestSetupWithPolicies sets up an orchestrator whose runtime uses
+// the given policy profiles.
+func newOrchTestSetupWithPolicies(policies *service.PolicyService) (*orchMockStore, *service.OrchestratorService, *runtimeMockQueue) {
+	return newOrchTestSetupWithConfig(policies, &config.Orchestrator{
+		MaxParallel:       4,
+		PingPongMaxRounds: 3,
+		ConsensusQuorum:   0,
+	})
+}
+
+// newOrchTestSetupWithConfig sets up an orchestrator with the given
+// policy profiles and orchestrator config.
+func newOrchTestSetupWithConfig(policies *service.PolicyService, orchCfg *config.Orchestrator) (*orchMockStore, *service.OrchestratorService, *runtimeMockQueue) {
 	store := &orchMockStore{}
+	store.projects = []project.Project{{ID: "proj-1", Name: "test"}}
 	store.agents = newIdleAgents("a1", "a2", "a3")
 	store.tasks = newPendingTasks("t1", "t2", "t3")
 
@@ -156,21 +258,11 @@ func newOrchTestSetup() (*orchMockStore, *service.OrchestratorService) {
 	es := &runtimeMockEventStore{}
 	queue := &runtimeMockQueue{}
 
-	runtimeSvc := service.NewRuntimeService(store, queue, bc, es,
-		service.NewPolicyService("headless-safe-sandbox", nil),
-		&config.Runtime{StallThreshold: 5},
-	)
-
-	orchCfg := &config.Orchestrator{
-		MaxParallel:       4,
-		PingPongMaxRounds: 3,
-		ConsensusQuorum:   0,
-	}
-
+	runtimeSvc := service.NewRuntimeService(store, queue, bc, es, policies, &config.Runtime{StallThreshold: 5})
 	orchSvc := service.NewOrchestratorService(store, bc, es, runtimeSvc, orchCfg)
 	runtimeSvc.SetOnRunComplete(orchSvc.HandleRunCompleted)
 
-	return store, orchSvc
+	return store, orchSvc, queue
 }
 
 func newIdleAgents(ids ...string) []agent.Agent {
@@ -359,8 +451,35 @@ func TestParallel_AllStart(t *testing.T) {
 	}
 }
 
+func TestParallel_ScalesOutSharedAgent(t *testing.T) {
+	store, orchSvc := newOrchTestSetup()
+	orchSvc.SetPoolManager(service.NewPoolManagerService(store, &runtimeMockBroadcaster{},
+		&config.Orchestrator{MaxTeamSize: 5, AutoScaleAgents: true, AgentIdleTimeout: time.Minute}))
+	ctx := context.Background()
+
+	p, _ := orchSvc.CreatePlan(ctx, &plan.CreatePlanRequest{
+		Name:      "shared agent",
+		ProjectID: "proj-1",
+		Protocol:  plan.ProtocolParallel,
+		Steps:     []plan.CreateStepRequest{{TaskID: "t1", AgentID: "a1"}, {TaskID: "t2", AgentID: "a1"}},
+	})
+	if _, err := orchSvc.StartPlan(ctx, p.ID); err != nil {
+		t.Fatalf("start plan: %v", err)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.runs) != 2 {
+		t.Fatalf("expected 2 runs, got %d", len(store.runs))
+	}
+	if store.runs[0].AgentID != "a1" || store.runs[1].AgentID == "a1" {
+		t.Errorf("expected the second step on a clone of a1, got %s and %s", store.runs[0].AgentID, store.runs[1].AgentID)
+	}
+}
+
 func TestParallel_MaxParallelRespected(t *testing.T) {
 	store := &orchMockStore{}
+	store.projects = []project.Project{{ID: "proj-1", Name: "test"}}
 	store.agents = newIdleAgents("a1", "a2", "a3", "a4", "a5")
 	store.tasks = newPendingTasks("t1", "t2", "t3", "t4", "t5")
 
@@ -619,3 +738,401 @@ func TestGetPlan(t *testing.T) {
 		t.Errorf("expected 2 steps, got %d", len(got.Steps))
 	}
 }
+
+func runningStep(store *orchMockStore, planID string) plan.Step {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for _, s := range store.steps {
+		if s.PlanID == planID && s.Status == plan.StepStatusRunning {
+			return s
+		}
+	}
+	return plan.Step{}
+}
+
+func TestSequential_StepRetryWithFallback(t *testing.T) {
+	store, orchSvc := newOrchTestSetup()
+	ctx := context.Background()
+
+	p, err := orchSvc.CreatePlan(ctx, &plan.CreatePlanRequest{
+		Name:      "retry plan",
+		ProjectID: "proj-1",
+		Protocol:  plan.ProtocolSequential,
+		Steps: []plan.CreateStepRequest{
+			{TaskID: "t1", AgentID: "a1", Retry: &plan.RetryPolicy{
+				MaxAttempts:    3,
+				FallbackAgents: []string{"a2"},
+				FallbackModel:  "openai/gpt-4o",
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("create plan: %v", err)
+	}
+	if _, err := orchSvc.StartPlan(ctx, p.ID); err != nil {
+		t.Fatalf("start plan: %v", err)
+	}
+
+	first := runningStep(store, p.ID)
+	orchSvc.HandleRunCompleted(ctx, first.RunID, run.StatusFailed)
+
+	second := runningStep(store, p.ID)
+	if second.RunID == "" || second.RunID == first.RunID {
+		t.Fatalf("expected step restarted with a new run, got %+v", second)
+	}
+	if second.Attempt != 2 || len(second.Attempts) != 1 || second.Attempts[0].RunID != first.RunID {
+		t.Fatalf("expected retry history, got attempt=%d attempts=%+v", second.Attempt, second.Attempts)
+	}
+	r, err := store.GetRun(ctx, second.RunID)
+	if err != nil {
+		t.Fatalf("get retry run: %v", err)
+	}
+	if r.AgentID != "a2" {
+		t.Errorf("expected fallback agent a2, got %q", r.AgentID)
+	}
+
+	// Exhausting the attempts fails the plan.
+	orchSvc.HandleRunCompleted(ctx, second.RunID, run.StatusFailed)
+	third := runningStep(store, p.ID)
+	orchSvc.HandleRunCompleted(ctx, third.RunID, run.StatusFailed)
+
+	got, _ := orchSvc.GetPlan(ctx, p.ID)
+	if got.Status != plan.StatusFailed {
+		t.Fatalf("expected plan failed after 3 attempts, got %s", got.Status)
+	}
+
+	g, err := orchSvc.GetPlanGraph(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("get plan graph: %v", err)
+	}
+	if len(g.Nodes) != 1 || g.Nodes[0].Attempt != 3 || len(g.Nodes[0].Attempts) != 2 {
+		t.Fatalf("expected retry history in graph, got %+v", g.Nodes)
+	}
+}
+
+func TestSequential_StepRetryBackoff(t *testing.T) {
+	store, orchSvc := newOrchTestSetup()
+	ctx := context.Background()
+
+	p, _ := orchSvc.CreatePlan(ctx, &plan.CreatePlanRequest{
+		Name:      "backoff plan",
+		ProjectID: "proj-1",
+		Protocol:  plan.ProtocolSequential,
+		Steps: []plan.CreateStepRequest{
+			{TaskID: "t1", AgentID: "a1", Retry: &plan.RetryPolicy{MaxAttempts: 2, BackoffSeconds: 3600}},
+		},
+	})
+	if _, err := orchSvc.StartPlan(ctx, p.ID); err != nil {
+		t.Fatalf("start plan: %v", err)
+	}
+	first := runningStep(store, p.ID)
+	orchSvc.HandleRunCompleted(ctx, first.RunID, run.StatusFailed)
+
+	got, _ := orchSvc.GetPlan(ctx, p.ID)
+	st := got.Steps[0]
+	if got.Status != plan.StatusRunning || st.Status != plan.StepStatusPending {
+		t.Fatalf("expected step waiting for retry, got plan=%s step=%s", got.Status, st.Status)
+	}
+	if st.RetryAt == nil || time.Until(*st.RetryAt) < 59*time.Minute {
+		t.Fatalf("expected retry scheduled an hour out, got %v", st.RetryAt)
+	}
+}
+
+func stepByIndex(t *testing.T, orchSvc *service.OrchestratorService, planID string, i int) plan.Step {
+	t.Helper()
+	p, err := orchSvc.GetPlan(context.Background(), planID)
+	if err != nil {
+		t.Fatalf("get plan: %v", err)
+	}
+	return p.Steps[i]
+}
+
+func newApprovalPlan(t *testing.T, orchSvc *service.OrchestratorService) *plan.ExecutionPlan {
+	t.Helper()
+	ctx := context.Background()
+	p, err := orchSvc.CreatePlan(ctx, &plan.CreatePlanRequest{
+		Name:      "gated plan",
+		ProjectID: "proj-1",
+		Protocol:  plan.ProtocolSequential,
+		Steps: []plan.CreateStepRequest{
+			{TaskID: "t1", AgentID: "a1"},
+			{Type: plan.StepTypeApproval},
+			{TaskID: "t2", AgentID: "a2"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("create plan: %v", err)
+	}
+	if _, err := orchSvc.StartPlan(ctx, p.ID); err != nil {
+		t.Fatalf("start plan: %v", err)
+	}
+	first := stepByIndex(t, orchSvc, p.ID, 0)
+	orchSvc.HandleRunCompleted(ctx, first.RunID, run.StatusCompleted)
+	return p
+}
+
+func TestApprovalStep_PausesUntilApproved(t *testing.T) {
+	_, orchSvc := newOrchTestSetup()
+	orchSvc.SetPublicURL("http://ui.example/")
+	ctx := context.Background()
+	p := newApprovalPlan(t, orchSvc)
+
+	gate := stepByIndex(t, orchSvc, p.ID, 1)
+	if gate.Status != plan.StepStatusWaitingApproval {
+		t.Fatalf("expected gate waiting for approval, got %s", gate.Status)
+	}
+	if next := stepByIndex(t, orchSvc, p.ID, 2); next.Status != plan.StepStatusPending {
+		t.Fatalf("expected next step held back, got %s", next.Status)
+	}
+
+	req := &plan.ResolveApprovalRequest{By: "alice", Comment: "looks good"}
+	if _, err := orchSvc.ResolveApproval(ctx, p.ID, stepByIndex(t, orchSvc, p.ID, 2).ID, true, req); !errors.Is(err, service.ErrStepNotAwaitingApproval) {
+		t.Fatalf("expected ErrStepNotAwaitingApproval for a run step, got %v", err)
+	}
+	step, err := orchSvc.ResolveApproval(ctx, p.ID, gate.ID, true, req)
+	if err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if step.Status != plan.StepStatusCompleted || step.Approval == nil || step.Approval.By != "alice" {
+		t.Fatalf("unexpected approved step %+v", step)
+	}
+	if next := stepByIndex(t, orchSvc, p.ID, 2); next.Status != plan.StepStatusRunning {
+		t.Fatalf("expected plan to continue after approval, got %s", next.Status)
+	}
+	if _, err := orchSvc.ResolveApproval(ctx, p.ID, gate.ID, true, req); !errors.Is(err, service.ErrStepNotAwaitingApproval) {
+		t.Fatalf("expected second approval to conflict, got %v", err)
+	}
+}
+
+func TestApprovalStep_RejectFailsPlan(t *testing.T) {
+	_, orchSvc := newOrchTestSetup()
+	ctx := context.Background()
+	p := newApprovalPlan(t, orchSvc)
+
+	gate := stepByIndex(t, orchSvc, p.ID, 1)
+	if _, err := orchSvc.ResolveApproval(ctx, p.ID, gate.ID, false, &plan.ResolveApprovalRequest{By: "bob"}); err != nil {
+		t.Fatalf("reject: %v", err)
+	}
+	got, _ := orchSvc.GetPlan(ctx, p.ID)
+	if got.Status != plan.StatusFailed {
+		t.Fatalf("expected plan failed after rejection, got %s", got.Status)
+	}
+	if got.Steps[2].Status != plan.StepStatusSkipped {
+		t.Fatalf("expected remaining step skipped, got %s", got.Steps[2].Status)
+	}
+}
+
+// finishStep records output on the running step's run and completes it.
+func finishStep(t *testing.T, store *orchMockStore, orchSvc *service.OrchestratorService, planID string, status run.Status, output string) plan.Step {
+	t.Helper()
+	st := runningStep(store, planID)
+	if st.RunID == "" {
+		t.Fatal("expected a running step")
+	}
+	store.runtimeMockStore.mu.Lock()
+	for i := range store.runs {
+		if store.runs[i].ID == st.RunID {
+			store.runs[i].Output = output
+		}
+	}
+	store.runtimeMockStore.mu.Unlock()
+	orchSvc.HandleRunCompleted(context.Background(), st.RunID, status)
+	return st
+}
+
+func TestConditionalStep_RunsOnVerdict(t *testing.T) {
+	for _, tc := range []struct {
+		verdict string
+		want    plan.StepStatus
+	}{
+		{"changes-requested", plan.StepStatusCompleted},
+		{"approved", plan.StepStatusSkipped},
+	} {
+		t.Run(tc.verdict, func(t *testing.T) {
+			store, orchSvc := newOrchTestSetup()
+			ctx := context.Background()
+			p, err := orchSvc.CreatePlan(ctx, &plan.CreatePlanRequest{
+				Name:      "review plan",
+				ProjectID: "proj-1",
+				Protocol:  plan.ProtocolSequential,
+				Steps: []plan.CreateStepRequest{
+					{TaskID: "t1", AgentID: "a1"},
+					{TaskID: "t2", AgentID: "a2", When: &plan.Condition{Step: "0", Verdict: plan.VerdictChangesRequested}},
+					{TaskID: "t3", AgentID: "a3", DependsOn: []string{"0"}},
+				},
+			})
+			if err != nil {
+				t.Fatalf("create plan: %v", err)
+			}
+			if _, err := orchSvc.StartPlan(ctx, p.ID); err != nil {
+				t.Fatalf("start plan: %v", err)
+			}
+
+			finishStep(t, store, orchSvc, p.ID, run.StatusCompleted, "Reviewed.\nVERDICT: "+tc.verdict)
+			for runningStep(store, p.ID).RunID != "" {
+				finishStep(t, store, orchSvc, p.ID, run.StatusCompleted, "done")
+			}
+
+			got, _ := orchSvc.GetPlan(ctx, p.ID)
+			if got.Status != plan.StatusCompleted {
+				t.Fatalf("expected plan completed, got %s", got.Status)
+			}
+			if got.Steps[1].Status != tc.want {
+				t.Fatalf("expected conditional step %s, got %s", tc.want, got.Steps[1].Status)
+			}
+			if got.Steps[2].Status != plan.StepStatusCompleted {
+				t.Fatalf("expected last step completed, got %s", got.Steps[2].Status)
+			}
+		})
+	}
+}
+
+func TestStepRefs_PassOutputsDownstream(t *testing.T) {
+	store, orchSvc, queue := newOrchTestSetupWithQueue()
+	ctx := context.Background()
+	store.runtimeMockStore.mu.Lock()
+	store.tasks[0].Prompt = "Design the API"
+	store.tasks[1].Prompt = "Implement {{ steps.design.output.endpoints.0 }} as designed:\n{{steps.design.output}}"
+	store.runtimeMockStore.mu.Unlock()
+
+	p, err := orchSvc.CreatePlan(ctx, &plan.CreatePlanRequest{
+		Name:      "design then build",
+		ProjectID: "proj-1",
+		Protocol:  plan.ProtocolParallel,
+		Steps: []plan.CreateStepRequest{
+			{TaskID: "t2", AgentID: "a2"},
+			{Name: "design", TaskID: "t1", AgentID: "a1"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("create plan: %v", err)
+	}
+	if len(p.Steps[0].DependsOn) != 1 || p.Steps[0].DependsOn[0] != p.Steps[1].ID {
+		t.Fatalf("expected the reference to add a dependency, got %v", p.Steps[0].DependsOn)
+	}
+	if _, err := orchSvc.StartPlan(ctx, p.ID); err != nil {
+		t.Fatalf("start plan: %v", err)
+	}
+
+	st := runningStep(store, p.ID)
+	store.runtimeMockStore.mu.Lock()
+	for i := range store.runs {
+		if store.runs[i].ID == st.RunID {
+			store.runs[i].StructuredOutput = []byte(`{"endpoints": ["GET /users"]}`)
+		}
+	}
+	store.runtimeMockStore.mu.Unlock()
+	finishStep(t, store, orchSvc, p.ID, run.StatusCompleted, "Use REST.")
+
+	msg, ok := queue.lastMessage(messagequeue.SubjectRunStart)
+	if !ok {
+		t.Fatal("expected the downstream step to start")
+	}
+	var start messagequeue.RunStartPayload
+	if err := json.Unmarshal(msg.Data, &start); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Implement GET /users as designed:\nUse REST."; start.Prompt != want {
+		t.Fatalf("expected prompt %q, got %q", want, start.Prompt)
+	}
+}
+
+func TestStepRefs_Validation(t *testing.T) {
+	store, orchSvc := newOrchTestSetup()
+	ctx := context.Background()
+	store.runtimeMockStore.mu.Lock()
+	store.tasks[0].Prompt = "Review {{steps.build.output}}"
+	store.tasks[1].Prompt = "Fix {{steps.review.output}}"
+	store.runtimeMockStore.mu.Unlock()
+
+	tests := []struct {
+		name  string
+		steps []plan.CreateStepRequest
+		want  error
+	}{
+		{"unknown step", []plan.CreateStepRequest{{Name: "review", TaskID: "t1", AgentID: "a1"}}, plan.ErrStepRefUnknown},
+		{"cycle", []plan.CreateStepRequest{
+			{Name: "review", TaskID: "t1", AgentID: "a1"},
+			{Name: "build", TaskID: "t2", AgentID: "a2"},
+		}, plan.ErrDAGCycle},
+		{"duplicate name", []plan.CreateStepRequest{
+			{Name: "review", TaskID: "t3", AgentID: "a1"},
+			{Name: "review", TaskID: "t3", AgentID: "a2"},
+		}, plan.ErrStepNameDuplicate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := orchSvc.CreatePlan(ctx, &plan.CreatePlanRequest{
+				Name: "refs", ProjectID: "proj-1", Protocol: plan.ProtocolSequential, Steps: tt.steps,
+			})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func newLoopPlan(t *testing.T, orchSvc *service.OrchestratorService) *plan.ExecutionPlan {
+	t.Helper()
+	ctx := context.Background()
+	p, err := orchSvc.CreatePlan(ctx, &plan.CreatePlanRequest{
+		Name:      "implement until green",
+		ProjectID: "proj-1",
+		Protocol:  plan.ProtocolParallel,
+		Steps: []plan.CreateStepRequest{
+			{TaskID: "t1", AgentID: "a1"},
+			{TaskID: "t2", AgentID: "a2", DependsOn: []string{"0"}, Loop: &plan.Loop{From: "0", MaxIterations: 3}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("create plan: %v", err)
+	}
+	if _, err := orchSvc.StartPlan(ctx, p.ID); err != nil {
+		t.Fatalf("start plan: %v", err)
+	}
+	return p
+}
+
+func TestLoop_RepeatsUntilTestsPass(t *testing.T) {
+	store, orchSvc := newOrchTestSetup()
+	ctx := context.Background()
+	p := newLoopPlan(t, orchSvc)
+
+	firstImpl := finishStep(t, store, orchSvc, p.ID, run.StatusCompleted, "implemented")
+	finishStep(t, store, orchSvc, p.ID, run.StatusFailed, "1 test failed")
+
+	again := runningStep(store, p.ID)
+	if again.ID != firstImpl.ID || again.RunID == firstImpl.RunID {
+		t.Fatalf("expected implement step re-run, got %+v", again)
+	}
+	if test := stepByIndex(t, orchSvc, p.ID, 1); test.Status != plan.StepStatusPending || test.Round != 1 {
+		t.Fatalf("expected test step pending in iteration 2, got status=%s round=%d", test.Status, test.Round)
+	}
+
+	finishStep(t, store, orchSvc, p.ID, run.StatusCompleted, "fixed")
+	finishStep(t, store, orchSvc, p.ID, run.StatusCompleted, "all tests passed")
+
+	got, _ := orchSvc.GetPlan(ctx, p.ID)
+	if got.Status != plan.StatusCompleted {
+		t.Fatalf("expected plan completed, got %s", got.Status)
+	}
+}
+
+func TestLoop_ExhaustedFailsPlan(t *testing.T) {
+	store, orchSvc := newOrchTestSetup()
+	ctx := context.Background()
+	p := newLoopPlan(t, orchSvc)
+
+	for range 3 {
+		finishStep(t, store, orchSvc, p.ID, run.StatusCompleted, "implemented")
+		finishStep(t, store, orchSvc, p.ID, run.StatusFailed, "tests failed")
+	}
+
+	got, _ := orchSvc.GetPlan(ctx, p.ID)
+	if got.Status != plan.StatusFailed {
+		t.Fatalf("expected plan failed after 3 iterations, got %s", got.Status)
+	}
+	if got.Steps[1].Round != 2 {
+		t.Fatalf("expected 3 iterations recorded, got round %d", got.Steps[1].Round)
+	}
+}
@@ -0,0 +1,410 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/ws"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/notification"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/logger"
+)
+
+// requestApproval pauses the plan at an approval step and announces it
+// with a deep link to the plan in the web UI. The approval policy of the
+// step's profile (or the project's layered policy) is recorded with the
+// request; if it cannot be resolved the step fails rather than letting
+// anyone decide.
+func (s *OrchestratorService) requestApproval(ctx context.Context, p *plan.ExecutionPlan, step *plan.Step) {
+	eff, err := s.runtime.ResolvePolicy(ctx, &policy.RunContext{ProjectID: p.ProjectID, PolicyProfile: step.PolicyProfile})
+	if err != nil {
+		slog.Error("resolve approval policy", "step_id", step.ID, "error", err)
+		_ = s.store.UpdatePlanStepStatus(ctx, step.ID, plan.StepStatusFailed, "", "resolve approval policy: "+err.Error())
+		s.broadcastStepStatus(ctx, p, step, plan.StepStatusFailed)
+		return
+	}
+	if err := s.store.UpdatePlanStepStatus(ctx, step.ID, plan.StepStatusWaitingApproval, "", ""); err != nil {
+		slog.Error("pause for approval", "step_id", step.ID, "error", err)
+		return
+	}
+	step.Status = plan.StepStatusWaitingApproval
+
+	now := time.Now()
+	a := &plan.Approval{RequestedAt: &now, Policy: eff.Approval}
+	if step.Approval != nil {
+		a.Version = step.Approval.Version // Replaces an earlier request
+	}
+	if err := s.store.SetPlanStepApproval(ctx, step.ID, a); err != nil {
+		slog.Error("record approval request", "step_id", step.ID, "error", err)
+	}
+	step.Approval = a
+
+	link := s.approvalURL(p, step)
+	expiresAt := ""
+	if expires, ok := a.Expires(); ok {
+		expiresAt = expires.UTC().Format(time.RFC3339)
+	}
+	s.recordApproval(ctx, event.TypePlanApprovalRequested, p, step, "", map[string]string{
+		"url":        link,
+		"quorum":     strconv.Itoa(a.Policy.Required()),
+		"expires_at": expiresAt,
+	})
+	s.broadcastStepStatus(ctx, p, step, plan.StepStatusWaitingApproval)
+	s.hub.BroadcastEvent(ctx, ws.EventPlanApproval, ws.PlanApprovalEvent{
+		PlanID:    p.ID,
+		StepID:    step.ID,
+		ProjectID: p.ProjectID,
+		Phase:     "requested",
+		URL:       link,
+		Quorum:    a.Policy.Required(),
+		ExpiresAt: expiresAt,
+	})
+	slog.Info("plan approval requested", "plan_id", p.ID, "step_id", step.ID, "url", link)
+}
+
+// ResolveApproval records a vote on an approval step that is waiting for a
+// decision. Without an approval policy the first vote decides. Under a
+// policy only its approvers and the holders of its approver roles may vote,
+// once each: a rejection rejects the step and approvals complete it once
+// they reach the quorum. Approval completes the step and lets the plan
+// continue; rejection fails the step, which fails the plan like any failed
+// step. A vote arriving after the policy's timeout applies the timeout
+// action instead. req.By must be the authenticated voter (see
+// plan.ResolveApprovalRequest.Bind).
+//
+// Votes are written conditioned on the version of the approval they were
+// based on; a vote losing to a concurrent one is checked again against
+// the approval that won.
+func (s *OrchestratorService) ResolveApproval(ctx context.Context, planID, stepID string, approved bool, req *plan.ResolveApprovalRequest) (*plan.Step, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	for attempt := 1; ; attempt++ {
+		step, err := s.vote(ctx, planID, stepID, approved, req)
+		if !errors.Is(err, domain.ErrConflict) || attempt == maxApprovalAttempts {
+			return step, err
+		}
+	}
+}
+
+// maxApprovalAttempts bounds how often a vote is retried after losing to
+// concurrent votes.
+const maxApprovalAttempts = 5
+
+// vote records one vote on the approval as it is stored now.
+func (s *OrchestratorService) vote(ctx context.Context, planID, stepID string, approved bool, req *plan.ResolveApprovalRequest) (*plan.Step, error) {
+	p, err := s.store.GetPlan(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+	var step *plan.Step
+	for i := range p.Steps {
+		if p.Steps[i].ID == stepID {
+			step = &p.Steps[i]
+			break
+		}
+	}
+	if step == nil {
+		return nil, fmt.Errorf("step %s in plan %s: %w", stepID, planID, domain.ErrNotFound)
+	}
+	if !step.IsApproval() || step.Status != plan.StepStatusWaitingApproval {
+		return nil, ErrStepNotAwaitingApproval
+	}
+
+	// Steps waiting since before approvals were recorded have none. A
+	// resolved approval on a waiting step lost its race with this vote.
+	a := step.Approval
+	if a == nil {
+		a = &plan.Approval{}
+	}
+	if a.Resolved() {
+		return nil, ErrStepNotAwaitingApproval
+	}
+	now := time.Now()
+	if deadline, ok := a.Expires(); ok && !now.Before(deadline) {
+		if _, err := s.expireApproval(ctx, p, step, a, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrStepNotAwaitingApproval
+	}
+	if !a.Policy.Allows(req.By, req.Roles) {
+		return nil, plan.ErrNotApprover
+	}
+	if a.Voted(req.By) {
+		return nil, plan.ErrAlreadyVoted
+	}
+	a.Votes = append(a.Votes, plan.ApprovalVote{Approved: approved, By: req.By, Name: req.Name, Comment: req.Comment, At: now})
+
+	if !approved || a.Approvals() >= a.Policy.Required() {
+		return s.resolveApproval(ctx, p, step, a, approved, req.By, req.Name, req.Comment, now)
+	}
+
+	// Approved, but short of the quorum.
+	if err := s.store.SetPlanStepApproval(ctx, step.ID, a); err != nil {
+		return nil, fmt.Errorf("record vote: %w", err)
+	}
+	step.Approval = a
+	s.recordApproval(ctx, event.TypePlanApprovalVoted, p, step, req.By, map[string]string{
+		"by":        req.By,
+		"name":      req.Name,
+		"comment":   req.Comment,
+		"approvals": strconv.Itoa(a.Approvals()),
+		"quorum":    strconv.Itoa(a.Policy.Required()),
+	})
+	s.hub.BroadcastEvent(ctx, ws.EventPlanApproval, ws.PlanApprovalEvent{
+		PlanID:    p.ID,
+		StepID:    step.ID,
+		ProjectID: p.ProjectID,
+		Phase:     "voted",
+		By:        req.Voter(),
+		Comment:   req.Comment,
+		Approvals: a.Approvals(),
+		Quorum:    a.Policy.Required(),
+	})
+	slog.Info("plan approval vote recorded", "plan_id", p.ID, "step_id", step.ID, "by", req.By, "name", req.Name,
+		"approvals", a.Approvals(), "quorum", a.Policy.Required())
+	return step, nil
+}
+
+// resolveApproval completes or fails an approval step and advances the plan.
+// by is the deciding voter or ApprovalTimeout, name its key name if any.
+func (s *OrchestratorService) resolveApproval(ctx context.Context, p *plan.ExecutionPlan, step *plan.Step, a *plan.Approval, approved bool, by, name, comment string, now time.Time) (*plan.Step, error) {
+	a.Approved, a.By, a.Name, a.Comment, a.ResolvedAt = approved, by, name, comment, &now
+	if err := s.store.SetPlanStepApproval(ctx, step.ID, a); err != nil {
+		return nil, fmt.Errorf("record approval: %w", err)
+	}
+	status, errMsg, evType, phase := plan.StepStatusCompleted, "", event.TypePlanApprovalApproved, "approved"
+	if !approved {
+		status, evType, phase = plan.StepStatusFailed, event.TypePlanApprovalRejected, "rejected"
+		errMsg = "rejected by " + cmp.Or(name, by)
+		if comment != "" {
+			errMsg += ": " + comment
+		}
+	}
+	if err := s.store.UpdatePlanStepStatus(ctx, step.ID, status, "", errMsg); err != nil {
+		return nil, fmt.Errorf("update step status: %w", err)
+	}
+	step.Status, step.Error, step.Approval = status, errMsg, a
+
+	fields := map[string]string{
+		"by":        by,
+		"name":      name,
+		"comment":   comment,
+		"approvals": strconv.Itoa(a.Approvals()),
+	}
+	actor := by
+	if a.Expired {
+		fields["expired"] = "true"
+		actor = ""
+	}
+	s.recordApproval(ctx, evType, p, step, actor, fields)
+	s.hub.BroadcastEvent(ctx, ws.EventPlanApproval, ws.PlanApprovalEvent{
+		PlanID:    p.ID,
+		StepID:    step.ID,
+		ProjectID: p.ProjectID,
+		Phase:     phase,
+		By:        cmp.Or(name, by),
+		Comment:   comment,
+		Approvals: a.Approvals(),
+		Quorum:    a.Policy.Required(),
+	})
+	s.broadcastStepStatus(ctx, p, step, status)
+	slog.Info("plan approval resolved", "plan_id", p.ID, "step_id", step.ID, "approved", approved, "by", by)
+
+	s.advancePlan(ctx, p)
+	return step, nil
+}
+
+// expireApproval resolves an approval step with its policy's timeout action.
+func (s *OrchestratorService) expireApproval(ctx context.Context, p *plan.ExecutionPlan, step *plan.Step, a *plan.Approval, now time.Time) (*plan.Step, error) {
+	a.Expired = true
+	comment := fmt.Sprintf("no decision within %ds", a.Policy.TimeoutSeconds)
+	slog.Warn("plan approval timed out", "plan_id", p.ID, "step_id", step.ID, "action", a.Policy.Action())
+	return s.resolveApproval(ctx, p, step, a, a.Policy.Action() == policy.ApprovalApprove, plan.ApprovalTimeout, "", comment, now)
+}
+
+// escalateApproval announces an approval that is still waiting after its
+// policy's escalation delay, naming the policy's escalation contacts.
+func (s *OrchestratorService) escalateApproval(ctx context.Context, p *plan.ExecutionPlan, step *plan.Step, a *plan.Approval, now time.Time) {
+	a.EscalatedAt = &now
+	if err := s.store.SetPlanStepApproval(ctx, step.ID, a); err != nil {
+		slog.Error("record approval escalation", "step_id", step.ID, "error", err)
+		return
+	}
+	link := s.approvalURL(p, step)
+	s.recordApproval(ctx, event.TypePlanApprovalEscalated, p, step, "", map[string]string{
+		"url":         link,
+		"escalate_to": strings.Join(a.Policy.EscalateTo, ","),
+		"approvals":   strconv.Itoa(a.Approvals()),
+		"quorum":      strconv.Itoa(a.Policy.Required()),
+	})
+	s.hub.BroadcastEvent(ctx, ws.EventPlanApproval, ws.PlanApprovalEvent{
+		PlanID:     p.ID,
+		StepID:     step.ID,
+		ProjectID:  p.ProjectID,
+		Phase:      "escalated",
+		URL:        link,
+		Approvals:  a.Approvals(),
+		Quorum:     a.Policy.Required(),
+		EscalateTo: a.Policy.EscalateTo,
+	})
+	slog.Warn("plan approval escalated", "plan_id", p.ID, "step_id", step.ID, "escalate_to", a.Policy.EscalateTo)
+}
+
+// recordApproval records a step of an approval as a plan event and in the
+// audit log of the plan's tenant, and notifies the project's rules for the
+// event type. actor is the voter's credential, or "" for what the server
+// did on its own, such as requests and timeouts; anonymous votes have no
+// credential either.
+func (s *OrchestratorService) recordApproval(ctx context.Context, evType event.Type, p *plan.ExecutionPlan, step *plan.Step, actor string, fields map[string]string) {
+	s.appendStepEvent(ctx, evType, p, step, "", fields)
+	if s.notify != nil {
+		s.notify.Notify(context.WithoutCancel(ctx), s.approvalMessage(evType, p, step, fields))
+	}
+	if s.audit == nil {
+		return
+	}
+	if actor == plan.ApprovalAnonymous {
+		actor = ""
+	}
+	// Timeouts and escalations run in leader jobs without a tenant.
+	tenantID := tenant.FromContext(ctx)
+	if tenantID == "" {
+		if proj, err := s.store.GetProject(ctx, p.ProjectID); err == nil {
+			tenantID = proj.TenantID
+		}
+	}
+	detail := maps.Clone(fields)
+	detail["plan_id"], detail["step_id"] = p.ID, step.ID
+	s.audit.Record(context.WithoutCancel(ctx), &event.AuditEntry{
+		TenantID:  tenantID,
+		Actor:     actor,
+		Path:      "/api/v1/plans/" + p.ID + "/steps/" + step.ID,
+		RequestID: logger.RequestID(ctx),
+		Action:    string(evType),
+		Detail:    detail,
+	})
+}
+
+// approvalMessage describes an approval event for notifications.
+// Escalations are high priority and mention the policy's escalate_to
+// contacts, votes short of the quorum are low priority.
+func (s *OrchestratorService) approvalMessage(evType event.Type, p *plan.ExecutionPlan, step *plan.Step, fields map[string]string) *notification.Message {
+	subject := p.Name + " / " + cmp.Or(step.Name, step.ID)
+	voter := cmp.Or(fields["name"], fields["by"])
+	msg := &notification.Message{
+		EventType: string(evType),
+		ProjectID: p.ProjectID,
+		Priority:  notification.PriorityNormal,
+		URL:       s.approvalURL(p, step),
+	}
+	switch evType {
+	case event.TypePlanApprovalRequested:
+		msg.Approval = &notification.ApprovalRef{PlanID: p.ID, StepID: step.ID}
+		msg.Title = "Approval requested: " + subject
+		msg.Text = "Needs " + fields["quorum"] + " approval(s)"
+		if fields["expires_at"] != "" {
+			msg.Text += ", expires at " + fields["expires_at"]
+		}
+	case event.TypePlanApprovalVoted:
+		msg.Priority = notification.PriorityLow
+		msg.Title = "Approval vote: " + subject
+		msg.Text = voter + " approved (" + fields["approvals"] + " of " + fields["quorum"] + ")"
+	case event.TypePlanApprovalApproved, event.TypePlanApprovalRejected:
+		msg.Title = "Approved: " + subject
+		if evType == event.TypePlanApprovalRejected {
+			msg.Title = "Rejected: " + subject
+		}
+		msg.Text = "By " + voter
+		if fields["expired"] == "true" {
+			msg.Text = "Timed out"
+		}
+	case event.TypePlanApprovalEscalated:
+		msg.Priority = notification.PriorityHigh
+		msg.Approval = &notification.ApprovalRef{PlanID: p.ID, StepID: step.ID}
+		msg.Title = "Approval overdue: " + subject
+		msg.Text = fields["approvals"] + " of " + fields["quorum"] + " approval(s) so far"
+		if step.Approval != nil && step.Approval.Policy != nil {
+			msg.Mentions = step.Approval.Policy.EscalateTo
+		}
+	}
+	if c := fields["comment"]; c != "" {
+		msg.Text += ": " + c
+	}
+	return msg
+}
+
+// CheckApprovals escalates the waiting approval steps whose escalation
+// delay has passed and resolves those whose timeout has, across all
+// projects. It returns how many approvals timed out.
+func (s *OrchestratorService) CheckApprovals(ctx context.Context) (int, error) {
+	waiting, err := s.store.ListWaitingApprovals(ctx, "")
+	if err != nil {
+		return 0, fmt.Errorf("list waiting approvals: %w", err)
+	}
+
+	expired := 0
+	now := time.Now()
+	for i := range waiting {
+		step := &waiting[i]
+		a := step.Approval
+		if a == nil || a.Resolved() || a.Policy == nil {
+			continue
+		}
+		deadline, timeout := a.Expires()
+		timedOut := timeout && !now.Before(deadline)
+		if !timedOut && !a.EscalationDue(now) {
+			continue
+		}
+		p, err := s.store.GetPlan(ctx, step.PlanID)
+		if err != nil {
+			slog.Error("load plan of waiting approval", "plan_id", step.PlanID, "error", err)
+			continue
+		}
+		if !timedOut {
+			s.escalateApproval(ctx, p, step, a, now)
+			continue
+		}
+		if _, err := s.expireApproval(ctx, p, step, a, now); err != nil {
+			slog.Error("expire approval", "step_id", step.ID, "error", err)
+			continue
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// StartApprovalTimer runs CheckApprovals every check interval until ctx is
+// done or cancel is called. It does nothing if the interval is 0.
+func (s *OrchestratorService) StartApprovalTimer(ctx context.Context) (cancel func()) {
+	ctx, cancel = context.WithCancel(ctx)
+	if s.orchCfg.ApprovalCheckInterval <= 0 {
+		return cancel
+	}
+	go func() {
+		ticker := time.NewTicker(s.orchCfg.ApprovalCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.CheckApprovals(ctx); err != nil {
+					slog.Error("approval timeout check failed", "error", err)
+				}
+			}
+		}
+	}()
+	return cancel
+}
@@ -0,0 +1,249 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+// newPolicyApprovalPlan starts a plan whose approval step runs under a
+// profile with the given approval policy and completes the step before it.
+// With onProject the profile is the project's policy override instead of
+// the step's profile.
+func newPolicyApprovalPlan(t *testing.T, ap *policy.ApprovalPolicy, onProject bool) (*orchMockStore, *service.OrchestratorService, *plan.ExecutionPlan) {
+	t.Helper()
+	profile := policy.PolicyProfile{Name: "gated", Mode: policy.ModeDefault, Approval: ap}
+	store, orchSvc, _ := newOrchTestSetupWithPolicies(service.NewPolicyService("headless-safe-sandbox", []policy.PolicyProfile{profile}))
+	gate := plan.CreateStepRequest{Type: plan.StepTypeApproval, PolicyProfile: "gated"}
+	if onProject {
+		store.projects[0].Config = map[string]string{policy.ConfigKeyProfile: "gated"}
+		gate.PolicyProfile = ""
+	}
+
+	ctx := context.Background()
+	p, err := orchSvc.CreatePlan(ctx, &plan.CreatePlanRequest{
+		Name:      "gated plan",
+		ProjectID: "proj-1",
+		Protocol:  plan.ProtocolSequential,
+		Steps:     []plan.CreateStepRequest{{TaskID: "t1", AgentID: "a1"}, gate, {TaskID: "t2", AgentID: "a2"}},
+	})
+	if err != nil {
+		t.Fatalf("create plan: %v", err)
+	}
+	if _, err := orchSvc.StartPlan(ctx, p.ID); err != nil {
+		t.Fatalf("start plan: %v", err)
+	}
+	orchSvc.HandleRunCompleted(ctx, stepByIndex(t, orchSvc, p.ID, 0).RunID, run.StatusCompleted)
+	return store, orchSvc, p
+}
+
+// backdateApproval moves the request time of a step's approval into the past.
+func backdateApproval(store *orchMockStore, stepID string, d time.Duration) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for i := range store.steps {
+		if store.steps[i].ID == stepID {
+			at := store.steps[i].Approval.RequestedAt.Add(-d)
+			store.steps[i].Approval.RequestedAt = &at
+		}
+	}
+}
+
+func TestApprovalPolicy_Quorum(t *testing.T) {
+	_, orchSvc, p := newPolicyApprovalPlan(t, &policy.ApprovalPolicy{Approvers: []string{"api_key:alice", "api_key:bob", "api_key:carol"}, Quorum: 2}, false)
+	ctx := context.Background()
+	gate := stepByIndex(t, orchSvc, p.ID, 1)
+	if gate.Status != plan.StepStatusWaitingApproval || gate.Approval == nil || gate.Approval.Policy.Required() != 2 {
+		t.Fatalf("expected gate waiting under the profile's policy, got %+v", gate)
+	}
+
+	if _, err := orchSvc.ResolveApproval(ctx, p.ID, gate.ID, true, &plan.ResolveApprovalRequest{By: "api_key:mallory"}); !errors.Is(err, plan.ErrNotApprover) {
+		t.Fatalf("expected ErrNotApprover, got %v", err)
+	}
+	step, err := orchSvc.ResolveApproval(ctx, p.ID, gate.ID, true, &plan.ResolveApprovalRequest{By: "api_key:alice"})
+	if err != nil {
+		t.Fatalf("first approval: %v", err)
+	}
+	if step.Status != plan.StepStatusWaitingApproval || len(step.Approval.Votes) != 1 {
+		t.Fatalf("expected the gate to wait for a second approval, got %s with %d votes", step.Status, len(step.Approval.Votes))
+	}
+	if _, err := orchSvc.ResolveApproval(ctx, p.ID, gate.ID, true, &plan.ResolveApprovalRequest{By: "api_key:alice"}); !errors.Is(err, plan.ErrAlreadyVoted) {
+		t.Fatalf("expected ErrAlreadyVoted, got %v", err)
+	}
+
+	step, err = orchSvc.ResolveApproval(ctx, p.ID, gate.ID, true, &plan.ResolveApprovalRequest{By: "api_key:bob"})
+	if err != nil {
+		t.Fatalf("second approval: %v", err)
+	}
+	if step.Status != plan.StepStatusCompleted || step.Approval.By != "api_key:bob" || step.Approval.Approvals() != 2 {
+		t.Fatalf("expected the quorum to complete the gate, got %+v", step.Approval)
+	}
+	if next := stepByIndex(t, orchSvc, p.ID, 2); next.Status != plan.StepStatusRunning {
+		t.Fatalf("expected plan to continue, got %s", next.Status)
+	}
+}
+
+func TestApprovalPolicy_ConcurrentVotes(t *testing.T) {
+	voters := []string{"api_key:alice", "api_key:bob", "api_key:carol", "api_key:dave"}
+	_, orchSvc, p := newPolicyApprovalPlan(t, &policy.ApprovalPolicy{Approvers: voters, Quorum: len(voters)}, false)
+	ctx := context.Background()
+	gate := stepByIndex(t, orchSvc, p.ID, 1)
+
+	// Votes racing each other are all counted, none overwrites another.
+	var wg sync.WaitGroup
+	errs := make([]error, len(voters))
+	for i, by := range voters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = orchSvc.ResolveApproval(ctx, p.ID, gate.ID, true, &plan.ResolveApprovalRequest{By: by})
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("vote of %s: %v", voters[i], err)
+		}
+	}
+	step := stepByIndex(t, orchSvc, p.ID, 1)
+	if step.Status != plan.StepStatusCompleted || step.Approval.Approvals() != len(voters) {
+		t.Fatalf("expected every vote counted and the gate completed, got %s with %+v", step.Status, step.Approval)
+	}
+}
+
+func TestApprovalPolicy_ApproverRoles(t *testing.T) {
+	_, orchSvc, p := newPolicyApprovalPlan(t, &policy.ApprovalPolicy{
+		Approvers: []string{"api_key:lead"}, ApproverRoles: []string{"release"}, Quorum: 2,
+	}, false)
+	ctx := context.Background()
+	gate := stepByIndex(t, orchSvc, p.ID, 1)
+
+	for _, req := range []*plan.ResolveApprovalRequest{
+		{By: plan.ApprovalAnonymous},
+		{By: "api_key:dev", Roles: []string{"dev"}},
+	} {
+		if _, err := orchSvc.ResolveApproval(ctx, p.ID, gate.ID, true, req); !errors.Is(err, plan.ErrNotApprover) {
+			t.Fatalf("vote of %s: expected ErrNotApprover, got %v", req.By, err)
+		}
+	}
+	if _, err := orchSvc.ResolveApproval(ctx, p.ID, gate.ID, true, &plan.ResolveApprovalRequest{By: "api_key:rel", Roles: []string{"release"}}); err != nil {
+		t.Fatalf("vote of a role holder: %v", err)
+	}
+	step, err := orchSvc.ResolveApproval(ctx, p.ID, gate.ID, true, &plan.ResolveApprovalRequest{By: "api_key:lead"})
+	if err != nil {
+		t.Fatalf("vote of an approver: %v", err)
+	}
+	if step.Status != plan.StepStatusCompleted {
+		t.Fatalf("expected the role holder and the approver to complete the gate, got %s", step.Status)
+	}
+}
+
+func TestApprovalPolicy_RejectionBeforeQuorum(t *testing.T) {
+	store, orchSvc, p := newPolicyApprovalPlan(t, &policy.ApprovalPolicy{Quorum: 2}, true)
+	es := &runtimeMockEventStore{}
+	orchSvc.SetAuditService(service.NewAuditService(store, es, config.Audit{Enabled: true}))
+	ctx := context.Background()
+	gate := stepByIndex(t, orchSvc, p.ID, 1)
+
+	if _, err := orchSvc.ResolveApproval(ctx, p.ID, gate.ID, true, &plan.ResolveApprovalRequest{By: "api_key:k1", Name: "alice"}); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	step, err := orchSvc.ResolveApproval(ctx, p.ID, gate.ID, false, &plan.ResolveApprovalRequest{By: "api_key:k2", Name: "bob", Comment: "not yet"})
+	if err != nil {
+		t.Fatalf("reject: %v", err)
+	}
+	if step.Status != plan.StepStatusFailed || step.Error != "rejected by bob: not yet" {
+		t.Fatalf("expected a single rejection to fail the gate, got %s %q", step.Status, step.Error)
+	}
+
+	// The vote and the decision it made are audited with voter and feedback.
+	entries, err := es.LoadAudit(ctx, tenant.DefaultID, 0, time.Time{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, e := range entries {
+		actions = append(actions, e.Action)
+	}
+	want := []string{string(event.TypePlanApprovalVoted), string(event.TypePlanApprovalRejected)}
+	if !slices.Equal(actions, want) {
+		t.Fatalf("expected audit actions %v, got %v", want, actions)
+	}
+	if last := entries[1]; last.Actor != "api_key:k2" || last.Detail["name"] != "bob" || last.Detail["comment"] != "not yet" || last.Detail["step_id"] != gate.ID {
+		t.Errorf("expected the rejection audited with its voter and comment, got %+v", last)
+	}
+}
+
+func TestApprovalPolicy_Timeout(t *testing.T) {
+	for _, action := range []policy.ApprovalAction{"", policy.ApprovalApprove} {
+		store, orchSvc, p := newPolicyApprovalPlan(t, &policy.ApprovalPolicy{TimeoutSeconds: 3600, TimeoutAction: action}, true)
+		ctx := context.Background()
+		gate := stepByIndex(t, orchSvc, p.ID, 1)
+
+		if n, err := orchSvc.CheckApprovals(ctx); err != nil || n != 0 {
+			t.Fatalf("expected no timeout yet, got %d %v", n, err)
+		}
+		backdateApproval(store, gate.ID, 2*time.Hour)
+		if n, err := orchSvc.CheckApprovals(ctx); err != nil || n != 1 {
+			t.Fatalf("expected one timeout, got %d %v", n, err)
+		}
+
+		gate = stepByIndex(t, orchSvc, p.ID, 1)
+		want := plan.StepStatusFailed
+		if action == policy.ApprovalApprove {
+			want = plan.StepStatusCompleted
+		}
+		if gate.Status != want || !gate.Approval.Expired || gate.Approval.By != plan.ApprovalTimeout {
+			t.Fatalf("action %q: expected %s by timeout, got %s %+v", action, want, gate.Status, gate.Approval)
+		}
+	}
+}
+
+func TestApprovalPolicy_LateVoteAppliesTimeout(t *testing.T) {
+	store, orchSvc, p := newPolicyApprovalPlan(t, &policy.ApprovalPolicy{TimeoutSeconds: 60}, false)
+	gate := stepByIndex(t, orchSvc, p.ID, 1)
+	backdateApproval(store, gate.ID, time.Hour)
+
+	_, err := orchSvc.ResolveApproval(context.Background(), p.ID, gate.ID, true, &plan.ResolveApprovalRequest{By: "api_key:alice"})
+	if !errors.Is(err, service.ErrStepNotAwaitingApproval) {
+		t.Fatalf("expected ErrStepNotAwaitingApproval, got %v", err)
+	}
+	if gate = stepByIndex(t, orchSvc, p.ID, 1); gate.Status != plan.StepStatusFailed || !gate.Approval.Expired {
+		t.Fatalf("expected the gate rejected by its timeout, got %s", gate.Status)
+	}
+}
+
+func TestApprovalPolicy_Escalation(t *testing.T) {
+	store, orchSvc, p := newPolicyApprovalPlan(t, &policy.ApprovalPolicy{
+		TimeoutSeconds: 3600, EscalateAfterSeconds: 600, EscalateTo: []string{"lead"},
+	}, false)
+	ctx := context.Background()
+	gate := stepByIndex(t, orchSvc, p.ID, 1)
+	backdateApproval(store, gate.ID, 15*time.Minute)
+
+	if n, err := orchSvc.CheckApprovals(ctx); err != nil || n != 0 {
+		t.Fatalf("expected no timeout, got %d %v", n, err)
+	}
+	gate = stepByIndex(t, orchSvc, p.ID, 1)
+	if gate.Status != plan.StepStatusWaitingApproval || gate.Approval.EscalatedAt == nil {
+		t.Fatalf("expected the waiting gate escalated, got %s %+v", gate.Status, gate.Approval)
+	}
+	escalated := *gate.Approval.EscalatedAt
+	if _, err := orchSvc.CheckApprovals(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if gate = stepByIndex(t, orchSvc, p.ID, 1); !gate.Approval.EscalatedAt.Equal(escalated) {
+		t.Error("expected the gate to be escalated only once")
+	}
+}
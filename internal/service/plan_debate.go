@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+)
+
+// arbiterTurnChars caps how much of each turn's output the arbiter sees.
+const arbiterTurnChars = 4000
+
+const arbiterSystemPrompt = `You are the arbiter of a debate between two coding agents.
+The first agent proposed a solution and revised it; the second critiqued each version.
+Summarize how the debate was resolved: the final approach, which critiques were
+addressed, and any disagreements left open. Be concise and concrete.`
+
+// recordDebateTurn adds the finished turn of a ping_pong step to the plan's
+// debate transcript. Transcript failures are logged and do not affect the
+// plan.
+func (s *OrchestratorService) recordDebateTurn(ctx context.Context, p *plan.ExecutionPlan, step *plan.Step, runID string, status plan.StepStatus, errMsg string) {
+	index := -1
+	for i := range p.Steps {
+		if p.Steps[i].ID == step.ID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return
+	}
+	t := &plan.DebateTurn{
+		StepID:  step.ID,
+		Round:   step.Round,
+		Role:    plan.TurnRole(index, step.Round),
+		RunID:   runID,
+		AgentID: step.AgentID,
+		Status:  status,
+		Output:  errMsg,
+	}
+	if r, err := s.store.GetRun(ctx, runID); err == nil {
+		t.AgentID = r.AgentID
+		if status == plan.StepStatusCompleted {
+			t.Output = r.Output
+		} else if t.Output == "" {
+			t.Output = r.Error
+		}
+	}
+	if err := s.store.AddDebateTurn(ctx, p.ID, p.ProjectID, t); err != nil {
+		slog.Error("record debate turn", "plan_id", p.ID, "step_id", step.ID, "round", step.Round, "error", err)
+	}
+}
+
+// summarizeDebate asks the arbiter model to summarize the resolution of a
+// finished ping_pong plan and stores the summary with its transcript.
+func (s *OrchestratorService) summarizeDebate(ctx context.Context, p *plan.ExecutionPlan) {
+	turns, err := s.store.ListDebateTurns(ctx, p.ID)
+	if err != nil || len(turns) == 0 {
+		if err != nil {
+			slog.Error("load debate for arbiter", "plan_id", p.ID, "error", err)
+		}
+		return
+	}
+	resp, err := s.arbiter.ChatCompletion(ctx, litellm.ChatCompletionRequest{
+		Model: s.orchCfg.ArbiterModel,
+		Messages: []litellm.ChatMessage{
+			{Role: "system", Content: arbiterSystemPrompt},
+			{Role: "user", Content: renderDebate(p, turns)},
+		},
+		Temperature: 0.2,
+		MaxTokens:   1024,
+	})
+	if err != nil {
+		slog.Error("arbiter summary", "plan_id", p.ID, "error", err)
+		return
+	}
+	sum := &plan.DebateSummary{Text: strings.TrimSpace(resp.Content), Model: resp.Model}
+	if sum.Model == "" {
+		sum.Model = s.orchCfg.ArbiterModel
+	}
+	if err := s.store.SetDebateSummary(ctx, p.ID, p.ProjectID, sum); err != nil {
+		slog.Error("store debate summary", "plan_id", p.ID, "error", err)
+		return
+	}
+	s.appendPlanEvent(ctx, event.TypePlanDebateSummarized, p)
+	slog.Info("debate summarized", "plan_id", p.ID, "model", sum.Model)
+}
+
+// renderDebate formats a debate transcript for the arbiter.
+func renderDebate(p *plan.ExecutionPlan, turns []plan.DebateTurn) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Plan: %s\n", p.Name)
+	if p.Description != "" {
+		fmt.Fprintf(&b, "Goal: %s\n", p.Description)
+	}
+	for i := range turns {
+		t := &turns[i]
+		out := t.Output
+		if len(out) > arbiterTurnChars {
+			out = out[:arbiterTurnChars] + "\n[truncated]"
+		}
+		fmt.Fprintf(&b, "\n## Round %d: %s (%s)\n%s\n", t.Round, t.Role, t.Status, out)
+	}
+	return b.String()
+}
+
+// GetDebate returns the transcript of a ping_pong plan's debate and the
+// arbiter's summary, if any. stepID must be one of the plan's two steps.
+func (s *OrchestratorService) GetDebate(ctx context.Context, planID, stepID string) (*plan.Debate, error) {
+	p, err := s.store.GetPlan(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+	if p.Protocol != plan.ProtocolPingPong {
+		return nil, fmt.Errorf("debate of %s plan %s: %w", p.Protocol, planID, domain.ErrNotFound)
+	}
+	d := &plan.Debate{PlanID: p.ID, Turns: []plan.DebateTurn{}}
+	found := false
+	for i := range p.Steps {
+		d.StepIDs = append(d.StepIDs, p.Steps[i].ID)
+		found = found || p.Steps[i].ID == stepID
+	}
+	if !found {
+		return nil, fmt.Errorf("step %s in plan %s: %w", stepID, planID, domain.ErrNotFound)
+	}
+	turns, err := s.store.ListDebateTurns(ctx, planID)
+	if err != nil {
+		return nil, fmt.Errorf("list debate turns: %w", err)
+	}
+	if turns != nil {
+		d.Turns = turns
+	}
+	sum, err := s.store.GetDebateSummary(ctx, planID)
+	switch {
+	case err == nil:
+		d.Summary = sum
+	case !errors.Is(err, domain.ErrNotFound):
+		return nil, fmt.Errorf("get debate summary: %w", err)
+	}
+	return d, nil
+}
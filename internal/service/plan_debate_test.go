@@ -0,0 +1,134 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func newPingPongPlan(t *testing.T, orchSvc *service.OrchestratorService) *plan.ExecutionPlan {
+	t.Helper()
+	ctx := context.Background()
+	p, err := orchSvc.CreatePlan(ctx, &plan.CreatePlanRequest{
+		Name:      "debate",
+		ProjectID: "proj-1",
+		Protocol:  plan.ProtocolPingPong,
+		Steps: []plan.CreateStepRequest{
+			{TaskID: "t1", AgentID: "a1"},
+			{TaskID: "t2", AgentID: "a2"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("create plan: %v", err)
+	}
+	if _, err := orchSvc.StartPlan(ctx, p.ID); err != nil {
+		t.Fatalf("start plan: %v", err)
+	}
+	return p
+}
+
+func TestPingPong_RecordsDebateTranscript(t *testing.T) {
+	store, orchSvc := newOrchTestSetup()
+	ctx := context.Background()
+	p := newPingPongPlan(t, orchSvc)
+
+	for turn := 1; runningStep(store, p.ID).RunID != ""; turn++ {
+		finishStep(t, store, orchSvc, p.ID, run.StatusCompleted, fmt.Sprintf("turn %d", turn))
+	}
+	got, _ := orchSvc.GetPlan(ctx, p.ID)
+	if got.Status != plan.StatusCompleted {
+		t.Fatalf("expected plan completed, got %s", got.Status)
+	}
+
+	d, err := orchSvc.GetDebate(ctx, p.ID, got.Steps[1].ID)
+	if err != nil {
+		t.Fatalf("get debate: %v", err)
+	}
+	want := []struct {
+		step  int
+		round int
+		role  plan.DebateRole
+	}{
+		{0, 1, plan.DebateProposal},
+		{1, 1, plan.DebateCritique},
+		{0, 2, plan.DebateRevision},
+		{1, 2, plan.DebateCritique},
+		{0, 3, plan.DebateRevision},
+		{1, 3, plan.DebateCritique},
+	}
+	if len(d.Turns) != len(want) {
+		t.Fatalf("expected %d turns, got %d", len(want), len(d.Turns))
+	}
+	for i, w := range want {
+		tr := d.Turns[i]
+		if tr.StepID != got.Steps[w.step].ID || tr.Round != w.round || tr.Role != w.role {
+			t.Errorf("turn %d: got step %s round %d %s, want step %d round %d %s",
+				i, tr.StepID, tr.Round, tr.Role, w.step, w.round, w.role)
+		}
+		if tr.Output != fmt.Sprintf("turn %d", i+1) || tr.RunID == "" {
+			t.Errorf("turn %d: got output %q run %q", i, tr.Output, tr.RunID)
+		}
+	}
+	if d.Summary != nil {
+		t.Errorf("expected no summary without an arbiter, got %+v", d.Summary)
+	}
+}
+
+func TestPingPong_ArbiterSummarizesDebate(t *testing.T) {
+	srv := newMockLLMServer(t, "Step 1 adopted both critiques.")
+	defer srv.Close()
+	store, orchSvc, _ := newOrchTestSetupWithConfig(service.NewPolicyService("headless-safe-sandbox", nil), &config.Orchestrator{
+		MaxParallel:       4,
+		PingPongMaxRounds: 1,
+		ArbiterModel:      "openai/gpt-4o-mini",
+	})
+	orchSvc.SetArbiter(litellm.NewClient(srv.URL, ""))
+	ctx := context.Background()
+	p := newPingPongPlan(t, orchSvc)
+
+	finishStep(t, store, orchSvc, p.ID, run.StatusCompleted, "proposal")
+	finishStep(t, store, orchSvc, p.ID, run.StatusCompleted, "critique")
+
+	var d *plan.Debate
+	waitFor(t, "the arbiter summary", func() bool {
+		var err error
+		d, err = orchSvc.GetDebate(ctx, p.ID, p.Steps[0].ID)
+		return err == nil && d.Summary != nil
+	})
+	if d.Summary.Text != "Step 1 adopted both critiques." || d.Summary.Model != "gpt-4o-mini" {
+		t.Errorf("unexpected summary %+v", d.Summary)
+	}
+	if len(d.Turns) != 2 {
+		t.Errorf("expected 2 turns, got %d", len(d.Turns))
+	}
+}
+
+func TestGetDebate_NotFound(t *testing.T) {
+	_, orchSvc := newOrchTestSetup()
+	ctx := context.Background()
+	p := newPingPongPlan(t, orchSvc)
+	if _, err := orchSvc.GetDebate(ctx, p.ID, "other-step"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected not found for a foreign step, got %v", err)
+	}
+
+	seq, err := orchSvc.CreatePlan(ctx, &plan.CreatePlanRequest{
+		Name:      "sequential",
+		ProjectID: "proj-1",
+		Protocol:  plan.ProtocolSequential,
+		Steps:     []plan.CreateStepRequest{{TaskID: "t3", AgentID: "a3"}},
+	})
+	if err != nil {
+		t.Fatalf("create plan: %v", err)
+	}
+	if _, err := orchSvc.GetDebate(ctx, seq.ID, seq.Steps[0].ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected not found for a sequential plan, got %v", err)
+	}
+}
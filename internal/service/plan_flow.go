@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+)
+
+// resolveFlow settles conditional steps and loops before a scheduling
+// round. Loops whose step missed its exit test re-open their body first,
+// so steps downstream of a loop are not skipped for a failure the loop
+// will retry. Then pending steps that can no longer run are skipped: their
+// condition does not hold or a dependency ended without completing.
+// The caller must hold s.mu.
+func (s *OrchestratorService) resolveFlow(ctx context.Context, p *plan.ExecutionPlan) {
+	for changed := true; changed; {
+		changed = false
+		for i := range p.Steps {
+			if p.Steps[i].Loop != nil && s.iterateLoop(ctx, p, &p.Steps[i]) {
+				changed = true
+			}
+		}
+		for i := range p.Steps {
+			st := &p.Steps[i]
+			if st.Status != plan.StepStatusPending {
+				continue
+			}
+			if reason := s.skipReason(ctx, p, st); reason != "" {
+				s.skipStep(ctx, p, st, reason)
+				changed = true
+			}
+		}
+	}
+}
+
+// iterateLoop re-opens the body of a finished loop step whose outcome does
+// not satisfy the loop's exit test. Once the iterations are used up the
+// step is failed instead. It returns true if any step changed.
+func (s *OrchestratorService) iterateLoop(ctx context.Context, p *plan.ExecutionPlan, st *plan.Step) bool {
+	if st.Status != plan.StepStatusCompleted && st.Status != plan.StepStatusFailed {
+		return false
+	}
+	out := s.stepOutcome(ctx, st)
+	if st.Loop.Done(out) {
+		return false
+	}
+
+	if st.Round+1 >= st.Loop.MaxIterations {
+		if st.Status == plan.StepStatusFailed {
+			return false
+		}
+		msg := fmt.Sprintf("loop exhausted after %d iterations", st.Loop.MaxIterations)
+		if err := s.store.UpdatePlanStepStatus(ctx, st.ID, plan.StepStatusFailed, "", msg); err != nil {
+			slog.Error("fail exhausted loop", "step_id", st.ID, "error", err)
+			return false
+		}
+		st.Status, st.Error = plan.StepStatusFailed, msg
+		s.broadcastStepStatus(ctx, p, st, plan.StepStatusFailed)
+		return true
+	}
+
+	body := make(map[string]bool)
+	for _, id := range plan.LoopBody(p.Steps, st) {
+		body[id] = true
+	}
+	for i := range p.Steps {
+		b := &p.Steps[i]
+		if !body[b.ID] {
+			continue
+		}
+		if err := s.store.UpdatePlanStepAttempts(ctx, b.ID, 0, b.Attempts, nil); err != nil {
+			slog.Error("reset loop step attempts", "step_id", b.ID, "error", err)
+		}
+		if err := s.store.UpdatePlanStepStatus(ctx, b.ID, plan.StepStatusPending, "", ""); err != nil {
+			slog.Error("reset loop step", "step_id", b.ID, "error", err)
+			continue
+		}
+		b.Status, b.Attempt, b.RetryAt, b.Error = plan.StepStatusPending, 0, nil, ""
+		s.broadcastStepStatus(ctx, p, b, plan.StepStatusPending)
+	}
+
+	st.Round++
+	if err := s.store.UpdatePlanStepRound(ctx, st.ID, st.Round); err != nil {
+		slog.Error("record loop iteration", "step_id", st.ID, "error", err)
+	}
+	s.appendStepEvent(ctx, event.TypePlanLoop, p, st, st.RunID, map[string]string{
+		"from":           st.Loop.From,
+		"iteration":      strconv.Itoa(st.Round + 1),
+		"max_iterations": strconv.Itoa(st.Loop.MaxIterations),
+		"status":         string(out.Status),
+		"verdict":        out.Verdict,
+		"steps":          strconv.Itoa(len(body)),
+	})
+	slog.Info("plan loop iteration", "plan_id", p.ID, "step_id", st.ID,
+		"iteration", st.Round+1, "max_iterations", st.Loop.MaxIterations)
+	return true
+}
+
+// skipReason returns why a pending step can no longer run, or "" if it may.
+func (s *OrchestratorService) skipReason(ctx context.Context, p *plan.ExecutionPlan, st *plan.Step) string {
+	for _, dep := range st.DependsOn {
+		if d := findStep(p.Steps, dep); d != nil && d.Status.IsTerminal() && d.Status != plan.StepStatusCompleted {
+			return "dependency " + dep + " did not complete"
+		}
+	}
+	w := st.When
+	if w == nil {
+		return ""
+	}
+	src := findStep(p.Steps, w.Step)
+	if src == nil || !src.Status.IsTerminal() {
+		return ""
+	}
+	if src.Status == plan.StepStatusSkipped || src.Status == plan.StepStatusCancelled {
+		return "condition step " + src.ID + " did not run"
+	}
+	if !w.Matches(s.stepOutcome(ctx, src)) {
+		return "condition not met"
+	}
+	return ""
+}
+
+// skipStep marks a pending step as skipped.
+func (s *OrchestratorService) skipStep(ctx context.Context, p *plan.ExecutionPlan, st *plan.Step, reason string) {
+	if err := s.store.UpdatePlanStepStatus(ctx, st.ID, plan.StepStatusSkipped, "", reason); err != nil {
+		slog.Error("skip plan step", "step_id", st.ID, "error", err)
+		return
+	}
+	st.Status, st.Error = plan.StepStatusSkipped, reason
+	s.appendStepEvent(ctx, event.TypePlanStepSkip, p, st, "", map[string]string{"reason": reason})
+	s.broadcastStepStatus(ctx, p, st, plan.StepStatusSkipped)
+	slog.Info("plan step skipped", "plan_id", p.ID, "step_id", st.ID, "reason", reason)
+}
+
+// stepOutcome collects what a finished step produced for conditions: run
+// steps contribute their output and the verdict reported in it, approval
+// steps the human decision and image steps the pushed image.
+func (s *OrchestratorService) stepOutcome(ctx context.Context, st *plan.Step) plan.Outcome {
+	o := plan.Outcome{Status: st.Status}
+	if st.IsApproval() {
+		if st.Approval.Resolved() {
+			o.Verdict = plan.VerdictRejected
+			if st.Approval.Approved {
+				o.Verdict = plan.VerdictApproved
+			}
+			o.Output = st.Approval.Comment
+		}
+		return o
+	}
+	if st.IsImage() {
+		o.Output = strings.TrimSpace(st.Image.Reference() + "\n" + st.Error)
+		return o
+	}
+	if st.RunID == "" {
+		return o
+	}
+	r, err := s.store.GetRun(ctx, st.RunID)
+	if err != nil {
+		slog.Warn("load step run for condition", "step_id", st.ID, "run_id", st.RunID, "error", err)
+		return o
+	}
+	o.Verdict = plan.ParseVerdict(r.Output)
+	o.Output = strings.TrimSpace(r.Output + "\n" + r.Error)
+	o.Structured = r.StructuredOutput
+	return o
+}
+
+func findStep(steps []plan.Step, id string) *plan.Step {
+	for i := range steps {
+		if steps[i].ID == id {
+			return &steps[i]
+		}
+	}
+	return nil
+}
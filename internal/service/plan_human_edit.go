@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	cfcontext "github.com/Strob0t/CodeForge/internal/domain/context"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+)
+
+// Human edit detection limits.
+const (
+	humanEditTimeout   = 30 * time.Second
+	humanEditDiffLimit = 32 << 10
+)
+
+// humanEditAuthor is the author of shared context items of human edits.
+const humanEditAuthor = "human"
+
+// checkHumanEdits compares the project workspace with the tree the plan's
+// last step left and records the difference as a human edit. Workspaces
+// that are not git repositories are skipped, as are workspaces an active
+// run works in, whose changes cannot be told apart from a human's. The
+// caller must hold s.mu, with no step of the plan running.
+func (s *OrchestratorService) checkHumanEdits(ctx context.Context, p *plan.ExecutionPlan) {
+	proj, err := s.store.GetProject(ctx, p.ProjectID)
+	if err != nil || proj.WorkspacePath == "" {
+		return
+	}
+	runs, err := s.store.ListActiveRuns(ctx, p.ProjectID)
+	if err != nil {
+		return
+	}
+	for i := range runs {
+		if runs[i].Workspace(proj.WorkspacePath) == proj.WorkspacePath {
+			delete(s.trees, p.ID)
+			return
+		}
+	}
+
+	gitCtx, cancel := context.WithTimeout(ctx, humanEditTimeout)
+	defer cancel()
+	tree, err := workspaceTree(gitCtx, proj.WorkspacePath)
+	if err != nil {
+		slog.Debug("human edit check skipped", "plan_id", p.ID, "error", err)
+		return
+	}
+	prev, ok := s.trees[p.ID]
+	s.trees[p.ID] = tree
+	if !ok || prev == tree {
+		return
+	}
+
+	numstat, err := runDeliverGit(gitCtx, proj.WorkspacePath, "diff", "--numstat", "--no-renames", prev, tree)
+	if err != nil {
+		slog.Warn("human edit diff failed", "plan_id", p.ID, "error", err)
+		return
+	}
+	diff, err := runDeliverGit(gitCtx, proj.WorkspacePath, "diff", prev, tree)
+	if err != nil {
+		slog.Warn("human edit diff failed", "plan_id", p.ID, "error", err)
+		return
+	}
+	s.recordHumanEdit(ctx, p, tree, run.ParseNumstat(numstat), diff)
+}
+
+// recordHumanEdit appends a plan.human_edit event with the diff and
+// refreshes what the context packs of the downstream steps draw on: the
+// plan team's shared context gets the diff, and the retrieval index the
+// current content of the edited files. The packs themselves are built
+// from the workspace when each step starts.
+func (s *OrchestratorService) recordHumanEdit(ctx context.Context, p *plan.ExecutionPlan, tree string, ds run.DiffStat, diff string) {
+	truncated := len(diff) > humanEditDiffLimit
+	if truncated {
+		diff = diff[:humanEditDiffLimit]
+	}
+	payload, _ := json.Marshal(map[string]string{
+		"plan_id":    p.ID,
+		"project_id": p.ProjectID,
+		"tree":       tree,
+		"files":      strconv.Itoa(ds.Files),
+		"insertions": strconv.Itoa(ds.Insertions),
+		"deletions":  strconv.Itoa(ds.Deletions),
+		"paths":      strings.Join(ds.Paths, "\n"),
+		"diff":       diff,
+		"truncated":  strconv.FormatBool(truncated),
+	})
+	if err := s.events.Append(ctx, &event.AgentEvent{
+		ProjectID: p.ProjectID,
+		Type:      event.TypePlanHumanEdit,
+		Payload:   payload,
+	}); err != nil {
+		slog.Warn("record human edit", "plan_id", p.ID, "error", err)
+	}
+	slog.Info("human edit between plan steps", "plan_id", p.ID, "files", ds.Files, "insertions", ds.Insertions, "deletions", ds.Deletions)
+
+	if s.sharedCtx != nil && p.TeamID != "" && diff != "" {
+		if _, err := s.sharedCtx.AddItem(ctx, cfcontext.AddSharedItemRequest{
+			TeamID: p.TeamID,
+			Key:    "human_edit:" + tree[:min(len(tree), 12)],
+			Value:  diff,
+			Author: humanEditAuthor,
+		}); err != nil {
+			slog.Warn("share human edit", "plan_id", p.ID, "error", err)
+		}
+	}
+	if s.retrieval != nil && len(ds.Paths) > 0 {
+		if _, err := s.retrieval.Refresh(ctx, p.ProjectID, ds.Paths); err != nil {
+			slog.Warn("refresh retrieval index after human edit", "plan_id", p.ID, "error", err)
+		}
+	}
+	if s.graph != nil && len(ds.Paths) > 0 {
+		if _, err := s.graph.Update(ctx, p.ProjectID, ds.Paths); err != nil {
+			slog.Warn("update code graph after human edit", "plan_id", p.ID, "error", err)
+		}
+	}
+}
+
+// workspaceTree writes the working tree of the git repository dir as a
+// tree object and returns its hash. Untracked files are included, ignored
+// ones are not. A copy of the index keeps the repository's own untouched.
+func workspaceTree(ctx context.Context, dir string) (string, error) {
+	out, err := runDeliverGit(ctx, dir, "rev-parse", "--git-path", "index")
+	if err != nil {
+		return "", err
+	}
+	index := strings.TrimSpace(out)
+	if !filepath.IsAbs(index) {
+		index = filepath.Join(dir, index)
+	}
+	tmp, err := os.MkdirTemp("", "codeforge-index-")
+	if err != nil {
+		return "", fmt.Errorf("create temp index: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	tmpIndex := filepath.Join(tmp, "index")
+	// Without an index (no commit yet), git starts an empty one.
+	if data, err := os.ReadFile(index); err == nil {
+		if err := os.WriteFile(tmpIndex, data, 0o600); err != nil {
+			return "", fmt.Errorf("copy index: %w", err)
+		}
+	}
+
+	env := []string{"GIT_INDEX_FILE=" + tmpIndex}
+	if _, err := runDeliverCmd(ctx, dir, env, "git", "add", "--all"); err != nil {
+		return "", fmt.Errorf("git add: %w", err)
+	}
+	out, err = runDeliverCmd(ctx, dir, env, "git", "write-tree")
+	if err != nil {
+		return "", fmt.Errorf("git write-tree: %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
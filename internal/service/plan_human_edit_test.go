@@ -0,0 +1,103 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestHumanEdit_RecordedBetweenSteps(t *testing.T) {
+	dir := initDeliverTestRepo(t)
+	store := &orchMockStore{}
+	store.projects = []project.Project{{ID: "proj-1", Name: "test", WorkspacePath: dir}}
+	store.agents = newIdleAgents("a1", "a2")
+	store.tasks = newPendingTasks("t1", "t2")
+	es := &runtimeMockEventStore{}
+	runtimeSvc := service.NewRuntimeService(store, &runtimeMockQueue{}, &runtimeMockBroadcaster{}, es,
+		service.NewPolicyService("headless-safe-sandbox", nil), &config.Runtime{StallThreshold: 5})
+	orchSvc := service.NewOrchestratorService(store, &runtimeMockBroadcaster{}, es, runtimeSvc, &config.Orchestrator{MaxParallel: 4})
+	ctx := context.Background()
+
+	p, err := orchSvc.CreatePlan(ctx, &plan.CreatePlanRequest{
+		Name:      "edited plan",
+		ProjectID: "proj-1",
+		Protocol:  plan.ProtocolSequential,
+		Steps: []plan.CreateStepRequest{
+			{TaskID: "t1", AgentID: "a1"},
+			{Type: plan.StepTypeApproval},
+			{TaskID: "t2", AgentID: "a2"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("create plan: %v", err)
+	}
+	if _, err := orchSvc.StartPlan(ctx, p.ID); err != nil {
+		t.Fatalf("start plan: %v", err)
+	}
+
+	// The first step's agent changes the workspace; that is no human edit.
+	if err := os.WriteFile(filepath.Join(dir, "agent.txt"), []byte("agent\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	first := stepByIndex(t, orchSvc, p.ID, 0)
+	store.runtimeMockStore.mu.Lock()
+	for i := range store.runs {
+		if store.runs[i].ID == first.RunID {
+			store.runs[i].Status = run.StatusCompleted
+		}
+	}
+	store.runtimeMockStore.mu.Unlock()
+	orchSvc.HandleRunCompleted(ctx, first.RunID, run.StatusCompleted)
+	if edits := humanEditEvents(es); len(edits) != 0 {
+		t.Fatalf("expected agent changes not recorded as human edits, got %v", edits)
+	}
+
+	// A human edits a file while the plan waits for approval.
+	if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello, world\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gate := stepByIndex(t, orchSvc, p.ID, 1)
+	if _, err := orchSvc.ResolveApproval(ctx, p.ID, gate.ID, true, &plan.ResolveApprovalRequest{By: "alice"}); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+
+	edits := humanEditEvents(es)
+	if len(edits) != 1 {
+		t.Fatalf("expected one human edit event, got %d", len(edits))
+	}
+	if edits[0]["plan_id"] != p.ID || edits[0]["paths"] != "hello.txt" || edits[0]["files"] != "1" {
+		t.Fatalf("unexpected human edit %v", edits[0])
+	}
+	if !strings.Contains(edits[0]["diff"], "+hello, world") {
+		t.Fatalf("expected the diff in the event, got %q", edits[0]["diff"])
+	}
+	if next := stepByIndex(t, orchSvc, p.ID, 2); next.Status != plan.StepStatusRunning {
+		t.Fatalf("expected the plan to continue, got %s", next.Status)
+	}
+}
+
+// humanEditEvents returns the payloads of the recorded human edit events.
+func humanEditEvents(es *runtimeMockEventStore) []map[string]string {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	var edits []map[string]string
+	for i := range es.events {
+		if es.events[i].Type != event.TypePlanHumanEdit {
+			continue
+		}
+		var payload map[string]string
+		_ = json.Unmarshal(es.events[i].Payload, &payload)
+		edits = append(edits, payload)
+	}
+	return edits
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+)
+
+// startImageStep starts building the image of an image step from the
+// workspace of its source run. The build runs in the background and
+// completes the step once the image is pushed.
+func (s *OrchestratorService) startImageStep(ctx context.Context, p *plan.ExecutionPlan, step *plan.Step) {
+	fail := func(msg string) {
+		slog.Error("start image step", "plan_id", p.ID, "step_id", step.ID, "error", msg)
+		_ = s.store.UpdatePlanStepStatus(ctx, step.ID, plan.StepStatusFailed, "", msg)
+		s.broadcastStepStatus(ctx, p, step, plan.StepStatusFailed)
+	}
+	if s.images == nil {
+		fail("image steps require a sandbox driver")
+		return
+	}
+	if step.Image == nil {
+		fail(plan.ErrImageSpec.Error())
+		return
+	}
+	src := p.ImageSource(step)
+	if src == nil {
+		fail(plan.ErrImageSource.Error())
+		return
+	}
+	r, err := s.store.GetRun(ctx, src.RunID)
+	if err != nil {
+		fail("get source run: " + err.Error())
+		return
+	}
+
+	if err := s.store.UpdatePlanStepStatus(ctx, step.ID, plan.StepStatusRunning, "", ""); err != nil {
+		slog.Error("start image step", "step_id", step.ID, "error", err)
+		return
+	}
+	s.broadcastStepStatus(ctx, p, step, plan.StepStatusRunning)
+	slog.Info("plan image step started", "plan_id", p.ID, "step_id", step.ID, "source_run_id", r.ID, "repository", step.Image.Repository)
+
+	buildCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	s.builds.Store(step.ID, cancel)
+	spec := step.Image.ImageSpec
+	go s.buildImage(buildCtx, p.ID, step.ID, r, &spec)
+}
+
+// buildImage builds and pushes the image of an image step, records it on
+// the step and advances the plan. Builds of steps that are no longer
+// running, e.g. of cancelled plans, are discarded.
+func (s *OrchestratorService) buildImage(ctx context.Context, planID, stepID string, r *run.Run, spec *plan.ImageSpec) {
+	b, buildErr := s.images.Build(ctx, stepID, r, spec)
+	if cancel, ok := s.builds.LoadAndDelete(stepID); ok {
+		cancel.(context.CancelFunc)()
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	p, err := s.store.GetPlan(ctx, planID)
+	if err != nil {
+		slog.Error("get plan of image step", "plan_id", planID, "error", err)
+		return
+	}
+	var step *plan.Step
+	for i := range p.Steps {
+		if p.Steps[i].ID == stepID {
+			step = &p.Steps[i]
+		}
+	}
+	if step == nil || step.Status != plan.StepStatusRunning {
+		slog.Info("image build of finished step discarded", "plan_id", planID, "step_id", stepID)
+		return
+	}
+
+	status, errMsg := plan.StepStatusCompleted, ""
+	if buildErr != nil {
+		status, errMsg = plan.StepStatusFailed, buildErr.Error()
+		slog.Warn("plan image step failed", "plan_id", planID, "step_id", stepID, "error", buildErr)
+	} else {
+		if err := s.store.SetPlanStepImage(ctx, stepID, b); err != nil {
+			slog.Error("record step image", "step_id", stepID, "error", err)
+		}
+		step.Image = b
+		s.appendStepEvent(ctx, event.TypePlanImageBuilt, p, step, r.ID, map[string]string{
+			"image":         b.Reference(),
+			"digest":        b.Digest,
+			"source_run_id": r.ID,
+		})
+	}
+	if err := s.store.UpdatePlanStepStatus(ctx, stepID, status, "", errMsg); err != nil {
+		slog.Error("update plan step status", "step_id", stepID, "error", err)
+		return
+	}
+	step.Status, step.Error = status, errMsg
+	s.broadcastStepStatus(ctx, p, step, status)
+	s.advancePlan(ctx, p)
+}
@@ -0,0 +1,162 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/port/sandbox"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+const testImageDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+// fakeBuildDriver prints the lines of a build and exits.
+type fakeBuildDriver struct {
+	mu     sync.Mutex
+	specs  []*sandbox.Spec
+	output []string
+}
+
+func (d *fakeBuildDriver) Name() string { return "fake-build" }
+
+func (d *fakeBuildDriver) Start(_ context.Context, spec *sandbox.Spec) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.specs = append(d.specs, spec)
+	return "sb-" + spec.RunID, nil
+}
+
+func (d *fakeBuildDriver) Logs(_ context.Context, _ string, fn func(sandbox.Line)) error {
+	for _, l := range d.output {
+		fn(sandbox.Line{Stream: "stdout", Text: l})
+	}
+	return nil
+}
+
+func (d *fakeBuildDriver) Remove(_ context.Context, _ string) error { return nil }
+
+// newImagePlan starts a sequential plan whose image step builds the run of
+// the step before it, with a third step deploying the image, and completes
+// the first step.
+func newImagePlan(t *testing.T, output ...string) (*orchMockStore, *service.OrchestratorService, *runtimeMockQueue, *fakeBuildDriver, *plan.ExecutionPlan) {
+	t.Helper()
+	store, orchSvc, queue := newOrchTestSetupWithQueue()
+	driver := &fakeBuildDriver{output: output}
+	orchSvc.SetImageBuilder(service.NewImageBuildService(store, nil, driver, &config.Sandbox{
+		Build: config.ImageBuild{DockerfileImage: "kaniko:debug", BuildpacksImage: "builder:latest", Timeout: time.Minute},
+	}))
+	store.runtimeMockStore.mu.Lock()
+	store.tasks[1].Prompt = "Deploy {{steps.image.output}}"
+	store.runtimeMockStore.mu.Unlock()
+
+	ctx := context.Background()
+	p, err := orchSvc.CreatePlan(ctx, &plan.CreatePlanRequest{
+		Name:      "build and ship",
+		ProjectID: "proj-1",
+		Protocol:  plan.ProtocolSequential,
+		Steps: []plan.CreateStepRequest{
+			{TaskID: "t1", AgentID: "a1"},
+			{Type: plan.StepTypeImage, Name: "image", Image: &plan.ImageSpec{
+				Repository: "ghcr.io/acme/web",
+				Tags:       []string{"v1", "latest"},
+				BuildArgs:  map[string]string{"VERSION": "it's 1"},
+			}},
+			{TaskID: "t2", AgentID: "a2"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("create plan: %v", err)
+	}
+	if _, err := orchSvc.StartPlan(ctx, p.ID); err != nil {
+		t.Fatalf("start plan: %v", err)
+	}
+	orchSvc.HandleRunCompleted(ctx, stepByIndex(t, orchSvc, p.ID, 0).RunID, run.StatusCompleted)
+	return store, orchSvc, queue, driver, p
+}
+
+// waitStepDone waits for a step to leave the running state.
+func waitStepDone(t *testing.T, orchSvc *service.OrchestratorService, planID string, i int) plan.Step {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		st := stepByIndex(t, orchSvc, planID, i)
+		if st.Status != plan.StepStatusRunning && st.Status != plan.StepStatusPending {
+			return st
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("step %d still %s", i, st.Status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestImageStep_BuildsAndRecordsDigest(t *testing.T) {
+	store, orchSvc, queue, driver, p := newImagePlan(t, "INFO pushing", service.ImageDigestMarker+testImageDigest)
+	ctx := context.Background()
+
+	st := waitStepDone(t, orchSvc, p.ID, 1)
+	if st.Status != plan.StepStatusCompleted || st.Image.Digest != testImageDigest || st.Image.Reference() != "ghcr.io/acme/web@"+testImageDigest {
+		t.Fatalf("expected the image step completed with its digest, got %s %+v (%s)", st.Status, st.Image, st.Error)
+	}
+	srcRun := stepByIndex(t, orchSvc, p.ID, 0).RunID
+	if st.Image.SourceRunID != srcRun {
+		t.Fatalf("expected the build of run %s, got %s", srcRun, st.Image.SourceRunID)
+	}
+
+	driver.mu.Lock()
+	spec := driver.specs[0]
+	driver.mu.Unlock()
+	script := spec.Command[2]
+	if spec.Image != "kaniko:debug" || spec.Command[0] != "sh" || spec.Timeout != time.Minute {
+		t.Fatalf("unexpected build sandbox %+v", spec)
+	}
+	for _, want := range []string{
+		"--context 'dir:///workspace'", "--dockerfile '/workspace/Dockerfile'",
+		"--destination 'ghcr.io/acme/web:v1' --destination 'ghcr.io/acme/web:latest'",
+		`--build-arg 'VERSION=it'\''s 1'`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("build script missing %q:\n%s", want, script)
+		}
+	}
+
+	arts, _ := store.ListArtifactsByRun(ctx, srcRun)
+	if len(arts) != 1 || arts[0].Kind != artifact.KindImageDigest || arts[0].Metadata["digest"] != testImageDigest {
+		t.Fatalf("expected an image digest artifact on the source run, got %+v", arts)
+	}
+
+	// Later steps reference the pushed image.
+	msg, ok := queue.lastMessage(messagequeue.SubjectRunStart)
+	if !ok {
+		t.Fatal("expected the deploy step to start")
+	}
+	var start messagequeue.RunStartPayload
+	if err := json.Unmarshal(msg.Data, &start); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Deploy ghcr.io/acme/web@" + testImageDigest; start.Prompt != want {
+		t.Fatalf("expected prompt %q, got %q", want, start.Prompt)
+	}
+}
+
+func TestImageStep_FailedBuildFailsPlan(t *testing.T) {
+	_, orchSvc, _, _, p := newImagePlan(t, "error building image: COPY failed")
+
+	st := waitStepDone(t, orchSvc, p.ID, 1)
+	if st.Status != plan.StepStatusFailed || !strings.Contains(st.Error, "COPY failed") {
+		t.Fatalf("expected the build output in the failed step, got %s %q", st.Status, st.Error)
+	}
+	got, _ := orchSvc.GetPlan(context.Background(), p.ID)
+	if got.Status != plan.StatusFailed {
+		t.Fatalf("expected the plan to fail, got %s", got.Status)
+	}
+}
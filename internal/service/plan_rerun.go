@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+)
+
+// RerunPlan creates a pending plan that re-executes part of a finished
+// one, linked to it as a rerun. Steps the rerun keeps stay completed with
+// their runs, so references to their outputs resolve as before. The new
+// plan is started like any other.
+func (s *OrchestratorService) RerunPlan(ctx context.Context, planID string, req *plan.RerunRequest) (*plan.ExecutionPlan, error) {
+	p, err := s.store.GetPlan(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+	np, err := plan.Rerun(p, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.CreatePlan(ctx, np); err != nil {
+		return nil, fmt.Errorf("store plan: %w", err)
+	}
+
+	s.appendPlanEvent(ctx, event.TypePlanCreated, np)
+	s.broadcastPlanStatus(ctx, np)
+
+	rerun := 0
+	for i := range np.Steps {
+		if np.Steps[i].Status == plan.StepStatusPending {
+			rerun++
+		}
+	}
+	slog.Info("plan rerun created", "plan_id", np.ID, "rerun_of", p.ID, "mode", req.Mode, "steps", rerun)
+	return np, nil
+}
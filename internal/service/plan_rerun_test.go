@@ -0,0 +1,54 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+)
+
+func TestRerunPlan_FailedSteps(t *testing.T) {
+	_, orchSvc := newOrchTestSetup()
+	ctx := context.Background()
+
+	p, err := orchSvc.CreatePlan(ctx, &plan.CreatePlanRequest{
+		Name:      "build",
+		ProjectID: "proj-1",
+		Protocol:  plan.ProtocolSequential,
+		Steps: []plan.CreateStepRequest{
+			{TaskID: "t1", AgentID: "a1"},
+			{TaskID: "t2", AgentID: "a2", DependsOn: []string{"0"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("create plan: %v", err)
+	}
+	if _, err := orchSvc.StartPlan(ctx, p.ID); err != nil {
+		t.Fatalf("start plan: %v", err)
+	}
+	first := stepByIndex(t, orchSvc, p.ID, 0)
+	orchSvc.HandleRunCompleted(ctx, first.RunID, run.StatusCompleted)
+	orchSvc.HandleRunCompleted(ctx, stepByIndex(t, orchSvc, p.ID, 1).RunID, run.StatusFailed)
+	if failed, _ := orchSvc.GetPlan(ctx, p.ID); failed.Status != plan.StatusFailed {
+		t.Fatalf("expected the plan failed, got %s", failed.Status)
+	}
+
+	np, err := orchSvc.RerunPlan(ctx, p.ID, &plan.RerunRequest{Mode: plan.RerunFailed})
+	if err != nil {
+		t.Fatalf("rerun plan: %v", err)
+	}
+	if np.ID == p.ID || np.RerunOf != p.ID || np.Status != plan.StatusPending {
+		t.Fatalf("expected a pending rerun of %s, got %+v", p.ID, np)
+	}
+	if _, err := orchSvc.StartPlan(ctx, np.ID); err != nil {
+		t.Fatalf("start rerun: %v", err)
+	}
+	kept, again := stepByIndex(t, orchSvc, np.ID, 0), stepByIndex(t, orchSvc, np.ID, 1)
+	if kept.Status != plan.StepStatusCompleted || kept.RunID != first.RunID {
+		t.Fatalf("expected the completed step kept with its run, got %+v", kept)
+	}
+	if again.Status != plan.StepStatusRunning || again.RunID == "" || again.DependsOn[0] != kept.ID {
+		t.Fatalf("expected the failed step running again after the kept one, got %+v", again)
+	}
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/a2a"
+	"github.com/Strob0t/CodeForge/internal/domain/agent"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+)
+
+// runHistory averages the finished runs of a task.
+type runHistory struct {
+	runs     int
+	costUSD  float64
+	tokens   int64
+	duration time.Duration
+}
+
+// ValidatePlan checks a stored plan the way its steps would be started,
+// without starting anything: the dependency graph, the steps' agents,
+// tasks, policy profiles, modes and deliver modes. It estimates each
+// step's cost, tokens and duration from earlier runs of its task, or the
+// project's average run cost, and predicts the plan's critical path.
+func (s *OrchestratorService) ValidatePlan(ctx context.Context, planID string) (*plan.Simulation, error) {
+	p, err := s.store.GetPlan(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+	proj, err := s.store.GetProject(ctx, p.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+	sim := &plan.Simulation{PlanID: p.ID, Valid: true, Findings: []plan.Finding{}}
+
+	cycle, unreachable := plan.CheckGraph(p.Steps)
+	for _, id := range cycle {
+		sim.Add(plan.SeverityError, plan.FindingCycle, id, "step depends on itself through its dependencies")
+	}
+	for _, id := range unreachable {
+		sim.Add(plan.SeverityError, plan.FindingUnreachable, id, "step waits on a missing step or a cycle and never starts")
+	}
+
+	history, err := s.taskHistory(ctx, p.Steps)
+	if err != nil {
+		return nil, err
+	}
+	avgCost, avgTokens, err := s.projectRunCost(ctx, p.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	rounds := 1
+	if p.Protocol == plan.ProtocolPingPong && s.orchCfg.PingPongMaxRounds > 0 {
+		rounds = s.orchCfg.PingPongMaxRounds
+	}
+
+	sim.Steps = make([]plan.StepEstimate, len(p.Steps))
+	for i := range p.Steps {
+		st := &p.Steps[i]
+		est := plan.StepEstimate{StepID: st.ID, Name: st.Name, Basis: plan.EstimateNone}
+		if !st.IsApproval() {
+			profile := s.validateStep(ctx, sim, proj, st)
+			if h, ok := history[st.TaskID]; ok {
+				est.Basis = plan.EstimateTask
+				est.CostUSD = h.costUSD * float64(rounds)
+				est.Tokens = h.tokens * int64(rounds)
+				est.Duration = h.duration * time.Duration(rounds)
+			} else {
+				if avgCost > 0 || avgTokens > 0 {
+					est.Basis = plan.EstimateProject
+					est.CostUSD = avgCost * float64(rounds)
+					est.Tokens = avgTokens * int64(rounds)
+				}
+				sim.Add(plan.SeverityWarning, plan.FindingNoEstimate, st.ID, "task has no finished runs; the step's duration is left out of the critical path")
+			}
+			if profile != nil && profile.Termination.MaxCost > 0 && est.CostUSD > profile.Termination.MaxCost {
+				sim.Add(plan.SeverityWarning, plan.FindingBudget, st.ID,
+					fmt.Sprintf("estimated cost $%.2f exceeds the max_cost $%.2f of policy profile %q", est.CostUSD, profile.Termination.MaxCost, profile.Name))
+			}
+		}
+		sim.Steps[i] = est
+		sim.CostUSD += est.CostUSD
+		sim.Tokens += est.Tokens
+	}
+	sim.CriticalPath, sim.Duration = plan.Schedule(p, sim.Steps)
+	return sim, nil
+}
+
+// validateStep adds the findings about a run step's agent, task, policy
+// profile, mode and deliver mode, and returns its policy profile if it
+// resolves.
+func (s *OrchestratorService) validateStep(ctx context.Context, sim *plan.Simulation, proj *project.Project, st *plan.Step) *policy.PolicyProfile {
+	if _, err := s.store.GetTask(ctx, st.TaskID); errors.Is(err, domain.ErrNotFound) {
+		sim.Add(plan.SeverityError, plan.FindingMissingTask, st.ID, fmt.Sprintf("task %s does not exist", st.TaskID))
+	}
+	var ag *agent.Agent
+	agentIDs := []string{st.AgentID}
+	if st.Retry != nil {
+		agentIDs = append(agentIDs, st.Retry.FallbackAgents...)
+	}
+	for i, id := range agentIDs {
+		a, err := s.store.GetAgent(ctx, id)
+		if errors.Is(err, domain.ErrNotFound) {
+			sim.Add(plan.SeverityError, plan.FindingMissingAgent, st.ID, fmt.Sprintf("agent %s does not exist", id))
+			continue
+		}
+		if err == nil && i == 0 {
+			ag = a
+		}
+	}
+
+	eff, err := s.runtime.resolvePolicy(ctx, proj.ID, ag, st.PolicyProfile)
+	if err != nil {
+		sim.Add(plan.SeverityError, plan.FindingPolicy, st.ID, err.Error())
+		return nil
+	}
+	profileName := eff.Name
+	profile, ok := s.runtime.policy.GetProfile(profileName)
+	if !ok {
+		sim.Add(plan.SeverityError, plan.FindingPolicy, st.ID, fmt.Sprintf("unknown policy profile %q", profileName))
+		return nil
+	}
+	remote := ag != nil && ag.Backend == a2a.BackendName
+	if profile.Untrusted() && !remote && s.runtime.sandbox == nil {
+		sim.Add(plan.SeverityError, plan.FindingPolicy, st.ID,
+			fmt.Sprintf("policy profile %q requires %s isolation but no sandbox driver is configured", profileName, profile.Isolation))
+	}
+
+	// The tools of the agent's mode should be usable under the profile.
+	if ag != nil && ag.Config["mode"] != "" && s.runtime.modes != nil {
+		if m, err := s.runtime.modes.Get(ag.Config["mode"]); err == nil {
+			var denied []string
+			for _, tool := range m.Tools {
+				if d, err := s.runtime.policy.Evaluate(ctx, profileName, policy.ToolCall{Tool: tool}); err == nil && d == policy.DecisionDeny {
+					denied = append(denied, tool)
+				}
+			}
+			if len(denied) > 0 {
+				sim.Add(plan.SeverityWarning, plan.FindingPolicy, st.ID,
+					fmt.Sprintf("mode %q uses %s, which policy profile %q denies", m.ID, strings.Join(denied, ", "), profileName))
+			}
+		}
+	}
+
+	deliverMode := run.DeliverMode(st.DeliverMode)
+	if deliverMode == run.DeliverModeNone && s.runtime.runtimeCfg.DefaultDeliverMode != "" {
+		deliverMode = run.DeliverMode(s.runtime.runtimeCfg.DefaultDeliverMode)
+	}
+	if !run.AllowsDeliverMode(proj.Config, deliverMode) {
+		sim.Add(plan.SeverityError, plan.FindingDeliverMode, st.ID,
+			fmt.Sprintf("deliver mode %q is not allowed (allowed: %s)", deliverMode, proj.Config[run.ConfigDeliverModes]))
+	}
+	if profile.Mode == policy.ModePlan && st.DeliverMode != "" {
+		sim.Add(plan.SeverityWarning, plan.FindingDeliverMode, st.ID,
+			fmt.Sprintf("policy profile %q only reads; deliver mode %q has nothing to deliver", profileName, st.DeliverMode))
+	}
+	return &profile
+}
+
+// taskHistory averages the finished runs of the tasks of a plan's steps,
+// by task ID.
+func (s *OrchestratorService) taskHistory(ctx context.Context, steps []plan.Step) (map[string]runHistory, error) {
+	var taskIDs []string
+	for i := range steps {
+		if steps[i].TaskID != "" {
+			taskIDs = append(taskIDs, steps[i].TaskID)
+		}
+	}
+	history := make(map[string]runHistory)
+	if len(taskIDs) == 0 {
+		return history, nil
+	}
+	runs, err := s.store.ListRunsByTasks(ctx, taskIDs)
+	if err != nil {
+		return nil, fmt.Errorf("list task runs: %w", err)
+	}
+	for i := range runs {
+		r := &runs[i]
+		if r.CompletedAt == nil || r.Status == run.StatusCancelled {
+			continue
+		}
+		h := history[r.TaskID]
+		h.runs++
+		h.costUSD += r.CostUSD
+		h.tokens += int64(r.TokensIn + r.TokensOut)
+		h.duration += r.CompletedAt.Sub(r.StartedAt)
+		history[r.TaskID] = h
+	}
+	for id, h := range history {
+		h.costUSD /= float64(h.runs)
+		h.tokens /= int64(h.runs)
+		h.duration /= time.Duration(h.runs)
+		history[id] = h
+	}
+	return history, nil
+}
+
+// projectRunCost returns the average cost and tokens of a project's runs.
+func (s *OrchestratorService) projectRunCost(ctx context.Context, projectID string) (float64, int64, error) {
+	summaries, err := s.store.ListCostSummaries(ctx, projectID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("list cost summaries: %w", err)
+	}
+	for i := range summaries {
+		if c := &summaries[i]; c.ProjectID == projectID && c.Runs > 0 {
+			return c.CostUSD / float64(c.Runs), (c.TokensIn + c.TokensOut) / int64(c.Runs), nil
+		}
+	}
+	return 0, 0, nil
+}
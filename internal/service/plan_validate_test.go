@@ -0,0 +1,89 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestValidatePlan(t *testing.T) {
+	capped := policy.PolicyProfile{Name: "capped", Mode: policy.ModeDefault, Termination: policy.TerminationCondition{MaxCost: 1}}
+	policies := service.NewPolicyService("headless-safe-sandbox", []policy.PolicyProfile{capped})
+	store := &orchMockStore{}
+	store.projects = []project.Project{{ID: "proj-1", Name: "test", Config: map[string]string{run.ConfigDeliverModes: "patch"}}}
+	store.agents = newIdleAgents("a1", "a2")
+	store.agents[1].Config = map[string]string{"mode": "coder"}
+	store.tasks = newPendingTasks("t1", "t2", "t3")
+	es := &runtimeMockEventStore{}
+	runtimeSvc := service.NewRuntimeService(store, &runtimeMockQueue{}, &runtimeMockBroadcaster{}, es, policies, &config.Runtime{StallThreshold: 5})
+	runtimeSvc.SetModeService(service.NewModeService())
+	orchSvc := service.NewOrchestratorService(store, &runtimeMockBroadcaster{}, es, runtimeSvc, &config.Orchestrator{MaxParallel: 4})
+
+	// Two earlier runs of t1: $1 in one minute, $3 in three.
+	start := time.Now().Add(-time.Hour)
+	for i, cost := range []float64{1, 3} {
+		done := start.Add(time.Duration(2*i+1) * time.Minute)
+		store.runs = append(store.runs, run.Run{
+			ID: fmt.Sprintf("old-%d", i), TaskID: "t1", ProjectID: "proj-1", Status: run.StatusCompleted,
+			CostUSD: cost, TokensIn: 100, TokensOut: 50, StartedAt: start, CompletedAt: &done,
+		})
+	}
+
+	ctx := context.Background()
+	p, err := orchSvc.CreatePlan(ctx, &plan.CreatePlanRequest{
+		Name:      "checked plan",
+		ProjectID: "proj-1",
+		Protocol:  plan.ProtocolParallel,
+		Steps: []plan.CreateStepRequest{
+			{TaskID: "t1", AgentID: "a1", PolicyProfile: "capped"},
+			{TaskID: "t2", AgentID: "a2", PolicyProfile: "plan-readonly", DeliverMode: "pr", DependsOn: []string{"0"}},
+			{TaskID: "t3", AgentID: "ghost"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("create plan: %v", err)
+	}
+
+	sim, err := orchSvc.ValidatePlan(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("validate plan: %v", err)
+	}
+	if sim.Valid {
+		t.Fatal("expected the missing agent and the disallowed deliver mode to invalidate the plan")
+	}
+	ids := []string{p.Steps[0].ID, p.Steps[1].ID, p.Steps[2].ID}
+	want := map[string][]string{
+		ids[0]: {plan.FindingBudget},
+		ids[1]: {plan.FindingPolicy, plan.FindingDeliverMode, plan.FindingDeliverMode, plan.FindingNoEstimate, plan.FindingBudget},
+		ids[2]: {plan.FindingMissingAgent, plan.FindingNoEstimate},
+	}
+	got := make(map[string][]string)
+	for _, f := range sim.Findings {
+		got[f.StepID] = append(got[f.StepID], f.Code)
+	}
+	for id, codes := range want {
+		if !slices.Equal(got[id], codes) {
+			t.Errorf("step %s: expected findings %v, got %v", id, codes, got[id])
+		}
+	}
+
+	first := sim.Steps[0]
+	if first.Basis != plan.EstimateTask || first.CostUSD != 2 || first.Tokens != 150 || first.Duration != 2*time.Minute {
+		t.Fatalf("expected the average of t1's runs, got %+v", first)
+	}
+	if sim.Steps[1].Basis != plan.EstimateProject || sim.Steps[1].CostUSD != 2 || sim.Steps[1].Start != 2*time.Minute {
+		t.Fatalf("expected the project average after the first step, got %+v", sim.Steps[1])
+	}
+	if !slices.Equal(sim.CriticalPath, ids[:1]) || sim.Duration != 2*time.Minute || sim.CostUSD != 6 {
+		t.Fatalf("unexpected schedule %v, %s, $%.2f", sim.CriticalPath, sim.Duration, sim.CostUSD)
+	}
+}
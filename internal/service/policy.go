@@ -2,11 +2,14 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"path/filepath"
 	"sort"
 	"strings"
 
+	"github.com/Strob0t/CodeForge/internal/domain/event"
 	"github.com/Strob0t/CodeForge/internal/domain/policy"
 )
 
@@ -41,18 +44,140 @@ func NewPolicyService(defaultProfile string, custom []policy.PolicyProfile) *Pol
 }
 
 // Evaluate checks a ToolCall against a named PolicyProfile and returns a Decision.
+// Composite names (see policy.CompositeName) are evaluated layer by layer.
 func (s *PolicyService) Evaluate(_ context.Context, profileName string, call policy.ToolCall) (policy.Decision, error) {
-	p, ok := s.profiles[profileName]
-	if !ok {
-		return policy.DecisionDeny, fmt.Errorf("unknown policy profile %q", profileName)
+	layers, err := s.layerProfiles(profileName)
+	if err != nil {
+		return policy.DecisionDeny, err
 	}
-	return evaluate(&p, call), nil
+	return evaluateLayers(layers, call), nil
 }
 
-// GetProfile returns a policy profile by name.
+// Resolve merges the given layers (least specific first) into an effective
+// policy. Layers without a profile are skipped, as are repeats of the
+// preceding layer's profile. At least one layer must remain.
+func (s *PolicyService) Resolve(layers []policy.Layer) (*policy.EffectivePolicy, error) {
+	kept := make([]policy.Layer, 0, len(layers))
+	for _, l := range layers {
+		if l.Profile == "" {
+			continue
+		}
+		if policy.IsComposite(l.Profile) {
+			return nil, fmt.Errorf("%s policy profile %q must not be composite", l.Level, l.Profile)
+		}
+		if _, ok := s.profiles[l.Profile]; !ok {
+			return nil, fmt.Errorf("unknown %s policy profile %q", l.Level, l.Profile)
+		}
+		if n := len(kept); n > 0 && kept[n-1].Profile == l.Profile {
+			continue
+		}
+		kept = append(kept, l)
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("no policy layers to resolve")
+	}
+
+	// Mode, quality gate and termination come from the most specific layer,
+	// isolation and egress from the strictest.
+	top := s.profiles[kept[len(kept)-1].Profile]
+	isolation := make([]policy.Isolation, len(kept))
+	egress := make([]*policy.EgressPolicy, len(kept))
+	var approval *policy.ApprovalPolicy
+	for i, l := range kept {
+		isolation[i] = s.profiles[l.Profile].Isolation
+		egress[i] = s.profiles[l.Profile].Egress
+		if a := s.profiles[l.Profile].Approval; a != nil {
+			approval = a
+		}
+	}
+	return &policy.EffectivePolicy{
+		Name:        policy.CompositeName(kept),
+		Layers:      kept,
+		Mode:        top.Mode,
+		QualityGate: top.QualityGate,
+		Termination: top.Termination,
+		Isolation:   policy.StrongestIsolation(isolation...),
+		Egress:      policy.IntersectEgress(egress...),
+		Approval:    approval,
+	}, nil
+}
+
+// Simulate replays the recorded tool-call decisions of a run through the named
+// profile without side effects. Events other than tool-call approvals and
+// denials are ignored.
+func (s *PolicyService) Simulate(profileName, runID string, events []event.AgentEvent) (*policy.SimulationResult, error) {
+	layers, err := s.layerProfiles(profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &policy.SimulationResult{
+		Profile: profileName,
+		RunID:   runID,
+		Calls:   []policy.SimulatedCall{},
+	}
+	for i := range events {
+		ev := &events[i]
+		if ev.Type != event.TypeToolCallApproved && ev.Type != event.TypeToolCallDenied {
+			continue
+		}
+		var payload map[string]string
+		if err := json.Unmarshal(ev.Payload, &payload); err != nil {
+			return nil, fmt.Errorf("decode event %s payload: %w", ev.ID, err)
+		}
+		call := policy.ToolCall{
+			Tool:    payload["tool"],
+			Command: payload["command"],
+			Path:    payload["path"],
+			URL:     payload["url"],
+		}
+		if call.Tool == "" {
+			continue
+		}
+		result.Record(policy.SimulatedCall{
+			CallID:    payload["call_id"],
+			Call:      call,
+			Original:  policy.Decision(payload["decision"]),
+			Simulated: evaluateLayers(layers, call),
+		})
+	}
+	return result, nil
+}
+
+// GetProfile returns a policy profile by name. For a composite name the
+// returned profile carries the most specific layer's mode, quality gate and
+// termination, the strongest isolation and the intersected egress of all
+// layers and the most specific approval policy, and lists the rules of all
+// layers, most specific first.
+// Its rules are informational: use Evaluate for layered decisions.
 func (s *PolicyService) GetProfile(name string) (policy.PolicyProfile, bool) {
-	p, ok := s.profiles[name]
-	return p, ok
+	if !policy.IsComposite(name) {
+		p, ok := s.profiles[name]
+		return p, ok
+	}
+	layers, err := s.layerProfiles(name)
+	if err != nil {
+		return policy.PolicyProfile{}, false
+	}
+	top := layers[len(layers)-1]
+	merged := policy.PolicyProfile{
+		Name:        name,
+		Description: "Composed from " + strings.Join(policy.SplitComposite(name), ", "),
+		Mode:        top.Mode,
+		QualityGate: top.QualityGate,
+		Termination: top.Termination,
+	}
+	egress := make([]*policy.EgressPolicy, 0, len(layers))
+	for i := len(layers) - 1; i >= 0; i-- {
+		merged.Rules = append(merged.Rules, layers[i].Rules...)
+		merged.Isolation = policy.StrongestIsolation(merged.Isolation, layers[i].Isolation)
+		egress = append(egress, layers[i].Egress)
+		if merged.Approval == nil {
+			merged.Approval = layers[i].Approval
+		}
+	}
+	merged.Egress = policy.IntersectEgress(egress...)
+	return merged, true
 }
 
 // ListProfiles returns all available profile names, sorted alphabetically.
@@ -70,8 +195,38 @@ func (s *PolicyService) DefaultProfile() string {
 	return s.defaultProfile
 }
 
-// evaluate performs first-match rule evaluation against a profile.
-func evaluate(profile *policy.PolicyProfile, call policy.ToolCall) policy.Decision {
+// layerProfiles returns the profiles behind a plain or composite name,
+// least specific first.
+func (s *PolicyService) layerProfiles(name string) ([]*policy.PolicyProfile, error) {
+	names := policy.SplitComposite(name)
+	layers := make([]*policy.PolicyProfile, 0, len(names))
+	for _, n := range names {
+		p, ok := s.profiles[n]
+		if !ok {
+			return nil, fmt.Errorf("unknown policy profile %q", n)
+		}
+		layers = append(layers, &p)
+	}
+	return layers, nil
+}
+
+// evaluateLayers evaluates the layers from the most specific down, each
+// first-match, and returns the decision of the first layer with a matching
+// rule, so an override can loosen or tighten what the layers below it
+// decide. When no layer has a matching rule, the most specific layer's
+// mode decides.
+func evaluateLayers(layers []*policy.PolicyProfile, call policy.ToolCall) policy.Decision {
+	for i := len(layers) - 1; i >= 0; i-- {
+		if d, ok := matchRule(layers[i], call); ok {
+			return d
+		}
+	}
+	return defaultDecisionForMode(layers[len(layers)-1].Mode)
+}
+
+// matchRule performs first-match rule evaluation against a profile and
+// reports whether any rule matched.
+func matchRule(profile *policy.PolicyProfile, call policy.ToolCall) (policy.Decision, bool) {
 	for i := range profile.Rules {
 		rule := &profile.Rules[i]
 		if !matchesSpecifier(rule.Specifier, call) {
@@ -83,9 +238,12 @@ func evaluate(profile *policy.PolicyProfile, call policy.ToolCall) policy.Decisi
 		if !matchesCommandConstraints(rule, call.Command) {
 			continue
 		}
-		return rule.Decision
+		if !matchesDomainConstraints(rule, call.URL) {
+			continue
+		}
+		return rule.Decision, true
 	}
-	return defaultDecisionForMode(profile.Mode)
+	return "", false
 }
 
 // matchesSpecifier checks if a ToolCall matches a ToolSpecifier.
@@ -156,6 +314,55 @@ func matchesCommandConstraints(rule *policy.PermissionRule, command string) bool
 	return true
 }
 
+// matchesDomainConstraints checks domain_allow/domain_deny patterns against
+// the host of a URL. If domain_deny matches, the rule does NOT match (skip).
+// If domain_allow is set, the host must match at least one pattern.
+// A URL without a parseable host never satisfies an allow list.
+func matchesDomainConstraints(rule *policy.PermissionRule, rawURL string) bool {
+	if rawURL == "" {
+		return true
+	}
+	if len(rule.DomainAllow) == 0 && len(rule.DomainDeny) == 0 {
+		return true
+	}
+
+	host := ""
+	if u, err := url.Parse(rawURL); err == nil {
+		host = strings.ToLower(u.Hostname())
+	}
+
+	for _, pattern := range rule.DomainDeny {
+		if matchDomainPattern(pattern, host) {
+			return false
+		}
+	}
+
+	if len(rule.DomainAllow) > 0 {
+		for _, pattern := range rule.DomainAllow {
+			if matchDomainPattern(pattern, host) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// matchDomainPattern matches a host against a domain pattern.
+// "*.example.com" matches example.com and any subdomain of it;
+// any other pattern must match the host exactly.
+func matchDomainPattern(pattern, host string) bool {
+	if host == "" {
+		return false
+	}
+	pattern = strings.ToLower(pattern)
+	if base, ok := strings.CutPrefix(pattern, "*."); ok {
+		return host == base || strings.HasSuffix(host, "."+base)
+	}
+	return host == pattern
+}
+
 // matchCommandPattern matches a command against a pattern.
 // The pattern matches if the command starts with it (prefix match).
 func matchCommandPattern(pattern, command string) bool {
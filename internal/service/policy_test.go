@@ -2,8 +2,10 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 
+	"github.com/Strob0t/CodeForge/internal/domain/event"
 	"github.com/Strob0t/CodeForge/internal/domain/policy"
 )
 
@@ -167,6 +169,64 @@ func TestEvaluateCommandDeny(t *testing.T) {
 	}
 }
 
+func TestEvaluateDomainConstraints(t *testing.T) {
+	profile := policy.PolicyProfile{
+		Name: "test",
+		Mode: policy.ModePlan,
+		Rules: []policy.PermissionRule{
+			{
+				Specifier:   policy.ToolSpecifier{Tool: "WebFetch"},
+				Decision:    policy.DecisionAllow,
+				DomainAllow: []string{"*.go.dev", "example.com"},
+				DomainDeny:  []string{"evil.go.dev"},
+			},
+		},
+	}
+	svc := NewPolicyService("test", []policy.PolicyProfile{profile})
+	ctx := context.Background()
+
+	tests := []struct {
+		url  string
+		want policy.Decision
+	}{
+		{"https://go.dev/doc", policy.DecisionAllow},
+		{"https://pkg.go.dev/net/url", policy.DecisionAllow},
+		{"https://EXAMPLE.com/a", policy.DecisionAllow},
+		{"https://sub.example.com/a", policy.DecisionDeny},
+		{"https://evil.go.dev/", policy.DecisionDeny},
+		{"https://notgo.dev/", policy.DecisionDeny},
+		{"not a url", policy.DecisionDeny},
+	}
+	for _, tt := range tests {
+		d, _ := svc.Evaluate(ctx, "test", policy.ToolCall{Tool: "WebFetch", URL: tt.url})
+		if d != tt.want {
+			t.Errorf("WebFetch %q: expected %q, got %q", tt.url, tt.want, d)
+		}
+	}
+}
+
+func TestEvaluateResearchWebPreset(t *testing.T) {
+	svc := NewPolicyService("research-web", nil)
+	ctx := context.Background()
+
+	d, _ := svc.Evaluate(ctx, "research-web", policy.ToolCall{Tool: "WebSearch", Command: "go generics"})
+	if d != policy.DecisionAllow {
+		t.Errorf("research-web should allow WebSearch, got %q", d)
+	}
+	d, _ = svc.Evaluate(ctx, "research-web", policy.ToolCall{Tool: "WebFetch", URL: "https://pkg.go.dev/fmt"})
+	if d != policy.DecisionAllow {
+		t.Errorf("research-web should allow WebFetch on pkg.go.dev, got %q", d)
+	}
+	d, _ = svc.Evaluate(ctx, "research-web", policy.ToolCall{Tool: "WebFetch", URL: "https://random-blog.example/post"})
+	if d != policy.DecisionDeny {
+		t.Errorf("research-web should deny WebFetch on unlisted domain, got %q", d)
+	}
+	d, _ = svc.Evaluate(ctx, "research-web", policy.ToolCall{Tool: "Edit", Path: "main.go"})
+	if d != policy.DecisionDeny {
+		t.Errorf("research-web should deny Edit, got %q", d)
+	}
+}
+
 func TestEvaluateSubPattern(t *testing.T) {
 	profile := policy.PolicyProfile{
 		Name: "test",
@@ -295,8 +355,8 @@ func TestPolicyServiceListProfiles(t *testing.T) {
 	svc := NewPolicyService("headless-safe-sandbox", custom)
 	names := svc.ListProfiles()
 
-	if len(names) != 5 {
-		t.Fatalf("expected 5 profiles (4 presets + 1 custom), got %d: %v", len(names), names)
+	if len(names) != 7 {
+		t.Fatalf("expected 7 profiles (6 presets + 1 custom), got %d: %v", len(names), names)
 	}
 
 	found := false
@@ -412,3 +472,145 @@ func TestEvaluateTrustedMountDeniesSecrets(t *testing.T) {
 		t.Errorf("trusted-mount should deny Edit on secrets/**, got %q", d)
 	}
 }
+
+func TestPolicyServiceSimulate(t *testing.T) {
+	svc := NewPolicyService("headless-safe-sandbox", nil)
+
+	mk := func(typ event.Type, payload map[string]string) event.AgentEvent {
+		data, _ := json.Marshal(payload)
+		return event.AgentEvent{Type: typ, Payload: data}
+	}
+	events := []event.AgentEvent{
+		mk(event.TypeRunStarted, map[string]string{"policy_profile": "trusted-mount-autonomous"}),
+		mk(event.TypeToolCallApproved, map[string]string{"call_id": "1", "tool": "Read", "path": "a.go", "decision": "allow"}),
+		mk(event.TypeToolCallApproved, map[string]string{"call_id": "2", "tool": "Bash", "command": "rm -rf /", "decision": "allow"}),
+		mk(event.TypeToolCallDenied, map[string]string{"call_id": "3", "tool": "Bash", "command": "git status", "decision": "deny"}),
+	}
+
+	res, err := svc.Simulate("headless-safe-sandbox", "run-1", events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Total != 3 {
+		t.Fatalf("expected 3 replayed calls, got %d", res.Total)
+	}
+	if res.Calls[1].Simulated != policy.DecisionDeny || !res.Calls[1].Changed {
+		t.Errorf("expected rm -rf to flip to deny, got %+v", res.Calls[1])
+	}
+	if res.Calls[2].Simulated != policy.DecisionAllow || !res.Calls[2].Changed {
+		t.Errorf("expected git status to flip to allow, got %+v", res.Calls[2])
+	}
+	if res.Allowed != 2 || res.Denied != 1 || res.Changed != 2 {
+		t.Errorf("unexpected counters: %+v", res)
+	}
+
+	if _, err := svc.Simulate("nonexistent", "run-1", events); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+}
+
+func newLayeredPolicyService() *PolicyService {
+	return NewPolicyService("tenant", []policy.PolicyProfile{
+		{
+			Name: "tenant",
+			Mode: policy.ModeAcceptEdits,
+			Rules: []policy.PermissionRule{
+				{Specifier: policy.ToolSpecifier{Tool: "Bash"}, Decision: policy.DecisionDeny, CommandAllow: []string{"rm"}},
+				{Specifier: policy.ToolSpecifier{Tool: "Read"}, Decision: policy.DecisionAllow},
+			},
+			Termination: policy.TerminationCondition{MaxSteps: 100},
+		},
+		{
+			Name: "project",
+			Mode: policy.ModeDefault,
+			Rules: []policy.PermissionRule{
+				{Specifier: policy.ToolSpecifier{Tool: "Bash"}, Decision: policy.DecisionAllow},
+			},
+			Termination: policy.TerminationCondition{MaxSteps: 50},
+			Isolation:   policy.IsolationGVisor,
+			Egress:      &policy.EgressPolicy{AllowDomains: []string{"github.com"}},
+		},
+		{
+			Name: "agent",
+			Mode: policy.ModePlan,
+			Rules: []policy.PermissionRule{
+				{Specifier: policy.ToolSpecifier{Tool: "Read"}, Decision: policy.DecisionAsk},
+			},
+			Termination: policy.TerminationCondition{MaxSteps: 10},
+		},
+	})
+}
+
+func TestPolicyServiceResolve(t *testing.T) {
+	svc := newLayeredPolicyService()
+
+	eff, err := svc.Resolve([]policy.Layer{
+		{Level: policy.LevelTenant, Profile: "tenant"},
+		{Level: policy.LevelProject, Profile: ""},
+		{Level: policy.LevelProject, Profile: "project"},
+		{Level: policy.LevelAgent, Profile: "project"},
+		{Level: policy.LevelAgent, Profile: "agent"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eff.Name != "tenant>project>agent" || len(eff.Layers) != 3 {
+		t.Fatalf("unexpected effective policy %+v", eff)
+	}
+	if eff.Mode != policy.ModePlan || eff.Termination.MaxSteps != 10 {
+		t.Errorf("expected most specific mode and termination, got %s / %d", eff.Mode, eff.Termination.MaxSteps)
+	}
+	if eff.Isolation != policy.IsolationGVisor {
+		t.Errorf("expected the strongest isolation of all layers, got %q", eff.Isolation)
+	}
+	if eff.Egress == nil || !eff.Egress.AllowsDomain("github.com") || eff.Egress.AllowsDomain("gitlab.com") {
+		t.Errorf("expected the project layer's egress to restrict the composite, got %+v", eff.Egress)
+	}
+	if p, _ := svc.GetProfile(eff.Name); p.Isolation != policy.IsolationGVisor || p.Egress == nil {
+		t.Errorf("expected composite profile to keep gvisor isolation and egress, got %q / %+v", p.Isolation, p.Egress)
+	}
+
+	if _, err := svc.Resolve([]policy.Layer{{Level: policy.LevelAgent, Profile: "nonexistent"}}); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+	if _, err := svc.Resolve([]policy.Layer{{Level: policy.LevelTenant}}); err == nil {
+		t.Error("expected error for no layers")
+	}
+}
+
+func TestEvaluateLayeredPrecedence(t *testing.T) {
+	svc := newLayeredPolicyService()
+	ctx := context.Background()
+	name := "tenant>project>agent"
+
+	tests := []struct {
+		call policy.ToolCall
+		want policy.Decision
+	}{
+		{policy.ToolCall{Tool: "Bash", Command: "rm -rf /"}, policy.DecisionAllow}, // project allow overrides tenant deny
+		{policy.ToolCall{Tool: "Bash", Command: "go test"}, policy.DecisionAllow},  // only project matches
+		{policy.ToolCall{Tool: "Read", Path: "a.go"}, policy.DecisionAsk},          // agent ask beats tenant allow
+		{policy.ToolCall{Tool: "Write", Path: "a.go"}, policy.DecisionDeny},        // no match: agent plan mode
+	}
+	for _, tt := range tests {
+		d, err := svc.Evaluate(ctx, name, tt.call)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d != tt.want {
+			t.Errorf("%s %s: expected %s, got %s", tt.call.Tool, tt.call.Command, tt.want, d)
+		}
+	}
+
+	p, ok := svc.GetProfile(name)
+	if !ok {
+		t.Fatal("expected composite profile")
+	}
+	if p.Mode != policy.ModePlan || len(p.Rules) != 4 || p.Rules[0].Specifier.Tool != "Read" {
+		t.Errorf("unexpected composite profile %+v", p)
+	}
+
+	if _, err := svc.Evaluate(ctx, "tenant>nonexistent", policy.ToolCall{Tool: "Read"}); err == nil {
+		t.Error("expected error for unknown layer")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 
 	"github.com/Strob0t/CodeForge/internal/adapter/ws"
 	"github.com/Strob0t/CodeForge/internal/config"
@@ -20,6 +21,8 @@ type PoolManagerService struct {
 	hub       broadcast.Broadcaster
 	orchCfg   *config.Orchestrator
 	sharedCtx *SharedContextService
+	tenants   *TenantService
+	scaling   sync.Mutex // serializes scale-outs and retirements
 }
 
 // SetSharedContext sets the shared context service for auto-initializing team contexts.
@@ -27,6 +30,11 @@ func (s *PoolManagerService) SetSharedContext(sc *SharedContextService) {
 	s.sharedCtx = sc
 }
 
+// SetTenantService keeps automatic scaling within tenant run quotas.
+func (s *PoolManagerService) SetTenantService(t *TenantService) {
+	s.tenants = t
+}
+
 // NewPoolManagerService creates a new PoolManagerService.
 func NewPoolManagerService(
 	store database.Store,
@@ -98,10 +106,10 @@ func (s *PoolManagerService) AssembleTeamForStrategy(
 		return nil, fmt.Errorf("list agents: %w", err)
 	}
 
-	// Filter idle agents.
+	// Filter idle agents. Clones belong to automatic scaling.
 	var idle []agent.Agent
 	for i := range agents {
-		if agents[i].Status == agent.StatusIdle {
+		if agents[i].Status == agent.StatusIdle && !agents[i].Ephemeral() {
 			idle = append(idle, agents[i])
 		}
 	}
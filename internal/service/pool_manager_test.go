@@ -3,7 +3,9 @@ package service_test
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/Strob0t/CodeForge/internal/adapter/ws"
 	"github.com/Strob0t/CodeForge/internal/config"
 	"github.com/Strob0t/CodeForge/internal/domain/agent"
 	"github.com/Strob0t/CodeForge/internal/domain/plan"
@@ -214,3 +216,101 @@ func TestCleanupTeam_ReleasesAgents(t *testing.T) {
 		t.Fatalf("expected agent idle after cleanup, got %s", ag.Status)
 	}
 }
+
+func newScalingTestEnv() (*service.PoolManagerService, *runtimeMockStore, *runtimeMockBroadcaster) {
+	store := &runtimeMockStore{
+		agents: []agent.Agent{{ID: "a1", ProjectID: "proj-1", Name: "coder", Backend: "aider", Status: agent.StatusRunning}},
+	}
+	bc := &runtimeMockBroadcaster{}
+	orchCfg := &config.Orchestrator{MaxTeamSize: 3, AutoScaleAgents: true, AgentIdleTimeout: time.Minute}
+	return service.NewPoolManagerService(store, bc, orchCfg), store, bc
+}
+
+func setAgentStatus(t *testing.T, store *runtimeMockStore, id string, status agent.Status) {
+	t.Helper()
+	if err := store.UpdateAgentStatus(context.Background(), id, status); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAcquireAgent_ScalesOutBusyAgent(t *testing.T) {
+	svc, store, bc := newScalingTestEnv()
+	ctx := context.Background()
+
+	first := svc.AcquireAgent(ctx, "a1")
+	clone, err := store.GetAgent(ctx, first)
+	if err != nil || !clone.Ephemeral() || clone.TemplateID != "a1" || clone.Backend != "aider" {
+		t.Fatalf("expected a clone of a1, got %+v %v", clone, err)
+	}
+	if again := svc.AcquireAgent(ctx, "a1"); again != first {
+		t.Fatalf("expected the idle clone to be reused, got %s", again)
+	}
+
+	setAgentStatus(t, store, first, agent.StatusRunning)
+	second := svc.AcquireAgent(ctx, "a1")
+	if second == first || second == "a1" {
+		t.Fatalf("expected a second clone, got %s", second)
+	}
+
+	// The agent and its two busy clones reach max_team_size.
+	setAgentStatus(t, store, second, agent.StatusRunning)
+	if got := svc.AcquireAgent(ctx, "a1"); got != "a1" {
+		t.Fatalf("expected no clone beyond max_team_size, got %s", got)
+	}
+
+	spawned := 0
+	for _, ev := range bc.events {
+		if ev.EventType == ws.EventAgentLifecycle && ev.Data.(ws.AgentLifecycleEvent).Phase == "spawned" {
+			spawned++
+		}
+	}
+	if spawned != 2 {
+		t.Errorf("expected 2 spawned events, got %d", spawned)
+	}
+}
+
+func TestAcquireAgent_IdleOrDisabled(t *testing.T) {
+	svc, store, _ := newScalingTestEnv()
+	ctx := context.Background()
+	setAgentStatus(t, store, "a1", agent.StatusIdle)
+	if got := svc.AcquireAgent(ctx, "a1"); got != "a1" {
+		t.Fatalf("expected the idle agent itself, got %s", got)
+	}
+
+	setAgentStatus(t, store, "a1", agent.StatusRunning)
+	disabled := service.NewPoolManagerService(store, &runtimeMockBroadcaster{}, &config.Orchestrator{MaxTeamSize: 3})
+	if got := disabled.AcquireAgent(ctx, "a1"); got != "a1" {
+		t.Fatalf("expected no scaling when disabled, got %s", got)
+	}
+}
+
+func TestRetireIdleAgents(t *testing.T) {
+	svc, store, _ := newScalingTestEnv()
+	ctx := context.Background()
+	clone := svc.AcquireAgent(ctx, "a1")
+
+	if n, err := svc.RetireIdleAgents(ctx); err != nil || n != 0 {
+		t.Fatalf("expected the fresh clone to stay, got %d %v", n, err)
+	}
+	store.mu.Lock()
+	for i := range store.agents {
+		if store.agents[i].ID == clone {
+			store.agents[i].UpdatedAt = time.Now().Add(-2 * time.Minute)
+		}
+	}
+	store.mu.Unlock()
+	if n, err := svc.RetireIdleAgents(ctx); err != nil || n != 1 {
+		t.Fatalf("expected the idle clone retired, got %d %v", n, err)
+	}
+	if a, _ := store.GetAgent(ctx, clone); a.Status != agent.StatusStopped {
+		t.Fatalf("expected the clone stopped, got %s", a.Status)
+	}
+
+	// A later scale-out revives the retired clone.
+	if got := svc.AcquireAgent(ctx, "a1"); got != clone {
+		t.Fatalf("expected the retired clone revived, got %s", got)
+	}
+	if a, _ := store.GetAgent(ctx, clone); a.Status != agent.StatusIdle {
+		t.Errorf("expected the revived clone idle, got %s", a.Status)
+	}
+}
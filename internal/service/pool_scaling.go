@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/ws"
+	"github.com/Strob0t/CodeForge/internal/domain/agent"
+)
+
+// AcquireAgent returns the agent to run a plan step assigned to agentID.
+// With automatic scaling enabled and that agent busy, it returns an idle
+// clone of the agent instead, reviving a retired clone or creating one as
+// long as the agent and its active clones stay within MaxTeamSize and the
+// project's tenant may start another run. Otherwise, and on errors, it
+// returns agentID.
+func (s *PoolManagerService) AcquireAgent(ctx context.Context, agentID string) string {
+	if s.orchCfg == nil || !s.orchCfg.AutoScaleAgents {
+		return agentID
+	}
+	s.scaling.Lock()
+	defer s.scaling.Unlock()
+
+	tmpl, err := s.store.GetAgent(ctx, agentID)
+	if err != nil || tmpl.Status != agent.StatusRunning || tmpl.Ephemeral() {
+		return agentID
+	}
+	clones, err := s.store.ListAgentClones(ctx, agentID)
+	if err != nil {
+		slog.Warn("list agent clones", "agent_id", agentID, "error", err)
+		return agentID
+	}
+
+	active := 0
+	var retired *agent.Agent
+	for i := range clones {
+		switch clones[i].Status {
+		case agent.StatusIdle:
+			return clones[i].ID
+		case agent.StatusStopped:
+			if retired == nil {
+				retired = &clones[i]
+			}
+		default:
+			active++
+		}
+	}
+	if s.orchCfg.MaxTeamSize > 0 && active+1 >= s.orchCfg.MaxTeamSize {
+		return agentID
+	}
+	if s.tenants != nil {
+		if err := s.tenants.AdmitRun(ctx, tmpl.ProjectID); err != nil {
+			slog.Info("agent not scaled out", "agent_id", agentID, "reason", err)
+			return agentID
+		}
+	}
+
+	clone := retired
+	if clone != nil {
+		if err := s.store.UpdateAgentStatus(ctx, clone.ID, agent.StatusIdle); err != nil {
+			slog.Warn("revive agent clone", "agent_id", clone.ID, "error", err)
+			return agentID
+		}
+	} else {
+		clone, err = s.store.CloneAgent(ctx, agentID, fmt.Sprintf("%s-%d", tmpl.Name, len(clones)+1))
+		if err != nil {
+			slog.Warn("clone agent", "agent_id", agentID, "error", err)
+			return agentID
+		}
+	}
+	s.broadcastLifecycle(ctx, clone, "spawned")
+	slog.Info("agent scaled out", "agent_id", clone.ID, "template_id", agentID, "revived", retired != nil)
+	return clone.ID
+}
+
+// RetireIdleAgents stops the clones that have been idle for longer than
+// AgentIdleTimeout and returns how many it stopped. Retired clones are
+// kept for their runs' history and revived by later scale-outs.
+func (s *PoolManagerService) RetireIdleAgents(ctx context.Context) (int, error) {
+	s.scaling.Lock()
+	defer s.scaling.Unlock()
+
+	clones, err := s.store.ListAgentClones(ctx, "")
+	if err != nil {
+		return 0, fmt.Errorf("list agent clones: %w", err)
+	}
+	cutoff := time.Now().Add(-s.orchCfg.AgentIdleTimeout)
+	retired := 0
+	for i := range clones {
+		c := &clones[i]
+		if c.Status != agent.StatusIdle || c.UpdatedAt.After(cutoff) {
+			continue
+		}
+		if err := s.store.UpdateAgentStatus(ctx, c.ID, agent.StatusStopped); err != nil {
+			slog.Warn("retire agent clone", "agent_id", c.ID, "error", err)
+			continue
+		}
+		s.broadcastLifecycle(ctx, c, "retired")
+		slog.Info("agent clone retired", "agent_id", c.ID, "template_id", c.TemplateID)
+		retired++
+	}
+	return retired, nil
+}
+
+// StartAgentReaper runs RetireIdleAgents every half idle timeout until ctx
+// is done or cancel is called. It does nothing without automatic scaling.
+func (s *PoolManagerService) StartAgentReaper(ctx context.Context) (cancel func()) {
+	ctx, cancel = context.WithCancel(ctx)
+	if s.orchCfg == nil || !s.orchCfg.AutoScaleAgents || s.orchCfg.AgentIdleTimeout <= 0 {
+		return cancel
+	}
+	go func() {
+		ticker := time.NewTicker(s.orchCfg.AgentIdleTimeout / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.RetireIdleAgents(ctx); err != nil {
+					slog.Error("agent reaper failed", "error", err)
+				}
+			}
+		}
+	}()
+	return cancel
+}
+
+// broadcastLifecycle announces a clone being spawned or retired.
+func (s *PoolManagerService) broadcastLifecycle(ctx context.Context, a *agent.Agent, phase string) {
+	s.hub.BroadcastEvent(ctx, ws.EventAgentLifecycle, ws.AgentLifecycleEvent{
+		AgentID:    a.ID,
+		ProjectID:  a.ProjectID,
+		TemplateID: a.TemplateID,
+		Name:       a.Name,
+		Phase:      phase,
+	})
+}
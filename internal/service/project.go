@@ -4,9 +4,12 @@ package service
 import (
 	"context"
 	"fmt"
+	"maps"
 	"path/filepath"
 
+	"github.com/Strob0t/CodeForge/internal/domain"
 	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
 	"github.com/Strob0t/CodeForge/internal/port/database"
 	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
 )
@@ -14,14 +17,38 @@ import (
 // WorkspaceRoot is the base directory where repositories are cloned.
 const WorkspaceRoot = "data/workspaces"
 
+// WorktreeRoot is the default base directory for per-run git worktrees.
+const WorktreeRoot = "data/worktrees"
+
 // ProjectService handles project business logic.
 type ProjectService struct {
-	store database.Store
+	store        database.Store
+	tenants      *TenantService
+	githubApps   *GitHubAppService
+	worktreeRoot string
 }
 
 // NewProjectService creates a new ProjectService.
 func NewProjectService(store database.Store) *ProjectService {
-	return &ProjectService{store: store}
+	return &ProjectService{store: store, worktreeRoot: WorktreeRoot}
+}
+
+// SetWorktreeRoot overrides the base directory for per-run worktrees.
+func (s *ProjectService) SetWorktreeRoot(dir string) {
+	if dir != "" {
+		s.worktreeRoot = dir
+	}
+}
+
+// SetTenantService enables quota admission for project creation and clones.
+func (s *ProjectService) SetTenantService(t *TenantService) {
+	s.tenants = t
+}
+
+// SetGitHubApps authenticates the git operations of GitHub projects that
+// name an installation of their tenant's GitHub App.
+func (s *ProjectService) SetGitHubApps(apps *GitHubAppService) {
+	s.githubApps = apps
 }
 
 // List returns all projects.
@@ -34,8 +61,24 @@ func (s *ProjectService) Get(ctx context.Context, id string) (*project.Project,
 	return s.store.GetProject(ctx, id)
 }
 
-// Create creates a new project.
+// Create creates a new project, owned by the tenant the request names, or
+// else the context's tenant, or else the default tenant. A context scoped
+// to a tenant cannot see, and so cannot create projects for, other tenants.
 func (s *ProjectService) Create(ctx context.Context, req project.CreateRequest) (*project.Project, error) {
+	scoped := tenant.FromContext(ctx)
+	switch {
+	case req.TenantID == "" && scoped != "":
+		req.TenantID = scoped
+	case req.TenantID == "":
+		req.TenantID = tenant.DefaultID
+	case scoped != "" && req.TenantID != scoped:
+		return nil, fmt.Errorf("tenant %s: %w", req.TenantID, domain.ErrNotFound)
+	}
+	if s.tenants != nil {
+		if err := s.tenants.AdmitProject(ctx, req.TenantID); err != nil {
+			return nil, err
+		}
+	}
 	return s.store.CreateProject(ctx, req)
 }
 
@@ -53,8 +96,13 @@ func (s *ProjectService) Clone(ctx context.Context, id string) (*project.Project
 	if p.RepoURL == "" {
 		return nil, fmt.Errorf("project %s has no repo_url", id)
 	}
+	if s.tenants != nil {
+		if err := s.tenants.AdmitStorage(ctx, p); err != nil {
+			return nil, err
+		}
+	}
 
-	provider, err := gitprovider.New(p.Provider, p.Config)
+	provider, err := s.gitProvider(ctx, p)
 	if err != nil {
 		return nil, fmt.Errorf("create git provider: %w", err)
 	}
@@ -82,7 +130,7 @@ func (s *ProjectService) Status(ctx context.Context, id string) (*project.GitSta
 		return nil, fmt.Errorf("project %s has no workspace (not cloned)", id)
 	}
 
-	provider, err := gitprovider.New(p.Provider, p.Config)
+	provider, err := s.gitProvider(ctx, p)
 	if err != nil {
 		return nil, fmt.Errorf("create git provider: %w", err)
 	}
@@ -100,7 +148,7 @@ func (s *ProjectService) Pull(ctx context.Context, id string) error {
 		return fmt.Errorf("project %s has no workspace (not cloned)", id)
 	}
 
-	provider, err := gitprovider.New(p.Provider, p.Config)
+	provider, err := s.gitProvider(ctx, p)
 	if err != nil {
 		return fmt.Errorf("create git provider: %w", err)
 	}
@@ -118,7 +166,7 @@ func (s *ProjectService) ListBranches(ctx context.Context, id string) ([]project
 		return nil, fmt.Errorf("project %s has no workspace (not cloned)", id)
 	}
 
-	provider, err := gitprovider.New(p.Provider, p.Config)
+	provider, err := s.gitProvider(ctx, p)
 	if err != nil {
 		return nil, fmt.Errorf("create git provider: %w", err)
 	}
@@ -136,10 +184,78 @@ func (s *ProjectService) Checkout(ctx context.Context, id, branch string) error
 		return fmt.Errorf("project %s has no workspace (not cloned)", id)
 	}
 
-	provider, err := gitprovider.New(p.Provider, p.Config)
+	provider, err := s.gitProvider(ctx, p)
 	if err != nil {
 		return fmt.Errorf("create git provider: %w", err)
 	}
 
 	return provider.Checkout(ctx, p.WorkspacePath, branch)
 }
+
+// AllocateWorktree creates an isolated git worktree of the project's workspace
+// for a run at {worktree root}/{project}/{run-id} and returns its path. The
+// worktree checks out the workspace's HEAD, or the head of the remote branch
+// if one is given.
+func (s *ProjectService) AllocateWorktree(ctx context.Context, projectID, runID, branch string) (string, error) {
+	p, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return "", fmt.Errorf("get project: %w", err)
+	}
+	if p.WorkspacePath == "" {
+		return "", fmt.Errorf("project %s has no workspace (not cloned)", projectID)
+	}
+
+	provider, err := s.gitProvider(ctx, p)
+	if err != nil {
+		return "", fmt.Errorf("create git provider: %w", err)
+	}
+	if !provider.Capabilities().Worktree {
+		return "", fmt.Errorf("git provider %q does not support worktrees", provider.Name())
+	}
+
+	path := filepath.Join(s.worktreeRoot, p.ID, runID)
+	if branch != "" {
+		bw, ok := provider.(gitprovider.BranchWorktree)
+		if !ok {
+			return "", fmt.Errorf("git provider %q cannot check out branches into worktrees", provider.Name())
+		}
+		if err := bw.AddBranchWorktree(ctx, p.WorkspacePath, path, branch); err != nil {
+			return "", fmt.Errorf("add worktree of branch %s: %w", branch, err)
+		}
+		return path, nil
+	}
+	if err := provider.AddWorktree(ctx, p.WorkspacePath, path); err != nil {
+		return "", fmt.Errorf("add worktree: %w", err)
+	}
+	return path, nil
+}
+
+// ReleaseWorktree removes a run worktree from the project's repository.
+func (s *ProjectService) ReleaseWorktree(ctx context.Context, projectID, path string) error {
+	p, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("get project: %w", err)
+	}
+
+	provider, err := s.gitProvider(ctx, p)
+	if err != nil {
+		return fmt.Errorf("create git provider: %w", err)
+	}
+
+	return provider.RemoveWorktree(ctx, p.WorkspacePath, path)
+}
+
+// gitProvider builds the git provider of a project, with an installation
+// token of its tenant's GitHub App in its config if it names one.
+func (s *ProjectService) gitProvider(ctx context.Context, p *project.Project) (gitprovider.Provider, error) {
+	token, err := s.githubApps.Token(ctx, p)
+	if err != nil {
+		return nil, fmt.Errorf("github app token: %w", err)
+	}
+	if token == "" {
+		return gitprovider.New(p.Provider, p.Config)
+	}
+	cfg := maps.Clone(p.Config)
+	cfg[gitprovider.ConfigToken] = token
+	return gitprovider.New(p.Provider, cfg)
+}
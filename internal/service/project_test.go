@@ -2,16 +2,46 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/Strob0t/CodeForge/internal/domain"
 	"github.com/Strob0t/CodeForge/internal/domain/agent"
+	"github.com/Strob0t/CodeForge/internal/domain/apikey"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/domain/audit"
+	"github.com/Strob0t/CodeForge/internal/domain/benchmark"
+	"github.com/Strob0t/CodeForge/internal/domain/chatops"
+	"github.com/Strob0t/CodeForge/internal/domain/ci"
+	"github.com/Strob0t/CodeForge/internal/domain/codegraph"
 	cfcontext "github.com/Strob0t/CodeForge/internal/domain/context"
+	"github.com/Strob0t/CodeForge/internal/domain/convention"
+	"github.com/Strob0t/CodeForge/internal/domain/conversation"
+	"github.com/Strob0t/CodeForge/internal/domain/cost"
+	"github.com/Strob0t/CodeForge/internal/domain/experience"
+	"github.com/Strob0t/CodeForge/internal/domain/featureflag"
+	"github.com/Strob0t/CodeForge/internal/domain/issuerun"
+	"github.com/Strob0t/CodeForge/internal/domain/knowledge"
+	"github.com/Strob0t/CodeForge/internal/domain/mcpserver"
+	"github.com/Strob0t/CodeForge/internal/domain/memory"
+	"github.com/Strob0t/CodeForge/internal/domain/microagent"
+	"github.com/Strob0t/CodeForge/internal/domain/notification"
 	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/poll"
 	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/replay"
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+	"github.com/Strob0t/CodeForge/internal/domain/roadmap"
 	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/scope"
+	"github.com/Strob0t/CodeForge/internal/domain/seatbelt"
+	"github.com/Strob0t/CodeForge/internal/domain/secret"
+	"github.com/Strob0t/CodeForge/internal/domain/skill"
 	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/domain/vcsaccount"
 	"github.com/Strob0t/CodeForge/internal/port/database"
 )
 
@@ -20,9 +50,10 @@ var _ database.Store = (*mockStore)(nil)
 
 // mockStore is a minimal in-memory implementation of database.Store for testing.
 type mockStore struct {
-	projects []project.Project
-	agents   []agent.Agent
-	tasks    []task.Task
+	projects    []project.Project
+	subProjects []project.SubProject
+	agents      []agent.Agent
+	tasks       []task.Task
 
 	// Error hooks — set these to inject failures.
 	listProjectsErr  error
@@ -89,6 +120,46 @@ func (m *mockStore) DeleteProject(_ context.Context, id string) error {
 	return domain.ErrNotFound
 }
 
+func (m *mockStore) CreateSubProject(_ context.Context, sp *project.SubProject) error {
+	for i := range m.subProjects {
+		if m.subProjects[i].ProjectID == sp.ProjectID && (m.subProjects[i].Name == sp.Name || m.subProjects[i].Path == sp.Path) {
+			return domain.ErrConflict
+		}
+	}
+	sp.ID = "sub-" + sp.Name
+	m.subProjects = append(m.subProjects, *sp)
+	return nil
+}
+
+func (m *mockStore) GetSubProject(_ context.Context, id string) (*project.SubProject, error) {
+	for i := range m.subProjects {
+		if m.subProjects[i].ID == id {
+			return &m.subProjects[i], nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (m *mockStore) ListSubProjects(_ context.Context, projectID string) ([]project.SubProject, error) {
+	var result []project.SubProject
+	for i := range m.subProjects {
+		if m.subProjects[i].ProjectID == projectID {
+			result = append(result, m.subProjects[i])
+		}
+	}
+	return result, nil
+}
+
+func (m *mockStore) DeleteSubProject(_ context.Context, id string) error {
+	for i := range m.subProjects {
+		if m.subProjects[i].ID == id {
+			m.subProjects = append(m.subProjects[:i], m.subProjects[i+1:]...)
+			return nil
+		}
+	}
+	return domain.ErrNotFound
+}
+
 func (m *mockStore) ListAgents(_ context.Context, _ string) ([]agent.Agent, error) {
 	return m.agents, nil
 }
@@ -118,6 +189,24 @@ func (m *mockStore) UpdateAgentStatus(_ context.Context, id string, status agent
 	return domain.ErrNotFound
 }
 
+func (m *mockStore) SetAgentSubProject(_ context.Context, id, subProjectID string) error {
+	for i := range m.agents {
+		if m.agents[i].ID == id {
+			m.agents[i].SubProjectID = subProjectID
+			return nil
+		}
+	}
+	return domain.ErrNotFound
+}
+
+func (m *mockStore) CloneAgent(_ context.Context, _, _ string) (*agent.Agent, error) {
+	return nil, domain.ErrNotFound
+}
+
+func (m *mockStore) ListAgentClones(_ context.Context, _ string) ([]agent.Agent, error) {
+	return nil, nil
+}
+
 func (m *mockStore) DeleteAgent(_ context.Context, id string) error {
 	for i := range m.agents {
 		if m.agents[i].ID == id {
@@ -168,6 +257,64 @@ func (m *mockStore) CompleteRun(_ context.Context, _ string, _ run.Status, _, _
 	return nil
 }
 func (m *mockStore) ListRunsByTask(_ context.Context, _ string) ([]run.Run, error) { return nil, nil }
+func (m *mockStore) SetRunWorktree(_ context.Context, _, _ string) error           { return nil }
+func (m *mockStore) AddRunRedactions(_ context.Context, _ string, _ int) error     { return nil }
+func (m *mockStore) AddRunTokens(_ context.Context, _ string, _, _ int) error      { return nil }
+func (m *mockStore) SetRunStructuredOutput(_ context.Context, _ string, _ json.RawMessage) error {
+	return nil
+}
+func (m *mockStore) SetRunSummary(_ context.Context, _, _ string) error { return nil }
+func (m *mockStore) ListRunsWithWorktree(_ context.Context, _ time.Time) ([]run.Run, error) {
+	return nil, nil
+}
+func (m *mockStore) ListRunsByTasks(_ context.Context, _ []string) ([]run.Run, error) {
+	return nil, nil
+}
+func (m *mockStore) ListActiveRuns(_ context.Context, _ string) ([]run.Run, error) {
+	return nil, nil
+}
+func (m *mockStore) ListWaitingApprovals(_ context.Context, _ string) ([]plan.Step, error) {
+	return nil, nil
+}
+func (m *mockStore) AddDebateTurn(_ context.Context, _, _ string, _ *plan.DebateTurn) error {
+	return nil
+}
+func (m *mockStore) ListDebateTurns(_ context.Context, _ string) ([]plan.DebateTurn, error) {
+	return nil, nil
+}
+func (m *mockStore) SetDebateSummary(_ context.Context, _, _ string, _ *plan.DebateSummary) error {
+	return nil
+}
+func (m *mockStore) GetDebateSummary(_ context.Context, _ string) (*plan.DebateSummary, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) ListCostSummaries(_ context.Context, _ string) ([]cost.Summary, error) {
+	return nil, nil
+}
+func (m *mockStore) AddRunUsage(_ context.Context, _, _ string, _ cost.Usage) error { return nil }
+func (m *mockStore) ListStepCosts(_ context.Context, _ string) ([]cost.StepCost, error) {
+	return nil, nil
+}
+func (m *mockStore) ListToolCosts(_ context.Context, _ string) ([]cost.Usage, error) {
+	return nil, nil
+}
+func (m *mockStore) RefreshCostRollups(_ context.Context, _ time.Time) error { return nil }
+func (m *mockStore) ListDailyCosts(_ context.Context, _ string, _ time.Time) ([]cost.Day, error) {
+	return nil, nil
+}
+func (m *mockStore) GetRuns(_ context.Context, _ []string) ([]run.Run, error) {
+	return nil, nil
+}
+func (m *mockStore) ListRunsForEventArchival(_ context.Context, _ string, _ time.Time, _ int) ([]run.Run, error) {
+	return nil, nil
+}
+func (m *mockStore) SetRunEventsArchived(_ context.Context, _ string) error { return nil }
+func (m *mockStore) SetRunInterrupted(_ context.Context, _ string, _ bool) error {
+	return nil
+}
+func (m *mockStore) ClaimInterruptedRun(_ context.Context, _ string) (bool, error) {
+	return false, nil
+}
 
 // --- Plan stub methods (satisfy database.Store interface) ---
 
@@ -188,6 +335,15 @@ func (m *mockStore) GetPlanStepByRunID(_ context.Context, _ string) (*plan.Step,
 	return nil, domain.ErrNotFound
 }
 func (m *mockStore) UpdatePlanStepRound(_ context.Context, _ string, _ int) error { return nil }
+func (m *mockStore) UpdatePlanStepAttempts(_ context.Context, _ string, _ int, _ []plan.StepAttempt, _ *time.Time) error {
+	return nil
+}
+func (m *mockStore) SetPlanStepApproval(_ context.Context, _ string, _ *plan.Approval) error {
+	return nil
+}
+func (m *mockStore) SetPlanStepImage(_ context.Context, _ string, _ *plan.ImageBuild) error {
+	return nil
+}
 
 // --- Agent Team stub methods (satisfy database.Store interface) ---
 
@@ -216,6 +372,13 @@ func (m *mockStore) GetContextPackByTask(_ context.Context, _ string) (*cfcontex
 	return nil, domain.ErrNotFound
 }
 func (m *mockStore) DeleteContextPack(_ context.Context, _ string) error { return nil }
+func (m *mockStore) GetContextCuration(_ context.Context, _ string) (*cfcontext.Curation, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) SaveContextCuration(_ context.Context, _ *cfcontext.Curation) error { return nil }
+func (m *mockStore) DeleteContextCuration(_ context.Context, _ string) error {
+	return domain.ErrNotFound
+}
 
 // Shared Context stubs
 func (m *mockStore) CreateSharedContext(_ context.Context, _ *cfcontext.SharedContext) error {
@@ -232,6 +395,388 @@ func (m *mockStore) AddSharedContextItem(_ context.Context, _ cfcontext.AddShare
 }
 func (m *mockStore) DeleteSharedContext(_ context.Context, _ string) error { return nil }
 
+func (m *mockStore) CreateArtifact(_ context.Context, _ *artifact.Artifact) error { return nil }
+func (m *mockStore) GetArtifact(_ context.Context, _ string) (*artifact.Artifact, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) ListArtifactsByRun(_ context.Context, _ string) ([]artifact.Artifact, error) {
+	return nil, nil
+}
+func (m *mockStore) ListArtifactsByProject(_ context.Context, _ string, _ artifact.Kind) ([]artifact.Artifact, error) {
+	return nil, nil
+}
+func (m *mockStore) ListArtifactsByTask(_ context.Context, _ string) ([]artifact.Artifact, error) {
+	return nil, nil
+}
+
+func (m *mockStore) CreateSecret(_ context.Context, _ *secret.Secret) error { return nil }
+func (m *mockStore) GetSecret(_ context.Context, _ string) (*secret.Secret, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) ListSecrets(_ context.Context, _ string) ([]secret.Secret, error) {
+	return nil, nil
+}
+func (m *mockStore) UpdateSecretValue(_ context.Context, _ string, _ []byte) error { return nil }
+func (m *mockStore) DeleteSecret(_ context.Context, _ string) error                { return nil }
+
+// --- Benchmark stub methods ---
+
+func (m *mockStore) CreateBenchmarkRun(_ context.Context, _ *benchmark.Run) error { return nil }
+func (m *mockStore) GetBenchmarkRun(_ context.Context, _ string) (*benchmark.Run, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) ListBenchmarkRuns(_ context.Context, _ string) ([]benchmark.Run, error) {
+	return nil, nil
+}
+func (m *mockStore) UpdateBenchmarkRunStatus(_ context.Context, _ string, _ benchmark.Status) error {
+	return nil
+}
+func (m *mockStore) GetBenchmarkResultByStep(_ context.Context, _ string) (*benchmark.Result, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) UpdateBenchmarkResult(_ context.Context, _ *benchmark.Result) error { return nil }
+func (m *mockStore) CreateFeature(_ context.Context, _ *roadmap.Feature) error          { return nil }
+func (m *mockStore) GetFeature(_ context.Context, _ string) (*roadmap.Feature, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) GetFeatureByExternalID(_ context.Context, _, _, _ string) (*roadmap.Feature, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) ListFeatures(_ context.Context, _ string) ([]roadmap.Feature, error) {
+	return nil, nil
+}
+func (m *mockStore) UpdateFeature(_ context.Context, _ *roadmap.Feature) error { return nil }
+
+func (m *mockStore) CreateTenant(_ context.Context, _ *tenant.Tenant) error { return nil }
+func (m *mockStore) GetTenant(_ context.Context, _ string) (*tenant.Tenant, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) ListTenants(_ context.Context) ([]tenant.Tenant, error) { return nil, nil }
+func (m *mockStore) UpdateTenantQuota(_ context.Context, _ string, _ tenant.Quota) error {
+	return nil
+}
+func (m *mockStore) SetTenantGitHubApp(_ context.Context, _ string, _ *tenant.GitHubApp) error {
+	return nil
+}
+func (m *mockStore) GetTenantUsage(_ context.Context, id string, since time.Time) (*tenant.Usage, error) {
+	return &tenant.Usage{TenantID: id, PeriodStart: since}, nil
+}
+func (m *mockStore) GetTenantIsolation(_ context.Context) (*tenant.IsolationReport, error) {
+	return &tenant.IsolationReport{}, nil
+}
+func (m *mockStore) ReplaceRetrievalIndex(_ context.Context, _ *retrieval.Index, _ []retrieval.Chunk) error {
+	return nil
+}
+func (m *mockStore) GetRetrievalIndex(_ context.Context, _ string) (*retrieval.Index, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) ListRetrievalChunks(_ context.Context, _ string) ([]retrieval.Chunk, error) {
+	return nil, nil
+}
+func (m *mockStore) GetStagedRetrievalIndex(_ context.Context, _ string) (*retrieval.Index, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) ListStagedRetrievalChunks(_ context.Context, _ string) ([]retrieval.Chunk, error) {
+	return nil, nil
+}
+func (m *mockStore) PromoteRetrievalIndex(_ context.Context, _ string) error {
+	return domain.ErrNotFound
+}
+func (m *mockStore) DeleteStagedRetrievalIndex(_ context.Context, _ string) error { return nil }
+func (m *mockStore) CreateGoldenQuery(_ context.Context, _ *retrieval.GoldenQuery) error {
+	return nil
+}
+func (m *mockStore) ListGoldenQueries(_ context.Context, _ string) ([]retrieval.GoldenQuery, error) {
+	return nil, nil
+}
+func (m *mockStore) DeleteGoldenQuery(_ context.Context, _ string) error { return domain.ErrNotFound }
+func (m *mockStore) CreateRetrievalEval(_ context.Context, _ *retrieval.Eval) error {
+	return nil
+}
+func (m *mockStore) ListRetrievalEvals(_ context.Context, _ string, _ int) ([]retrieval.Eval, error) {
+	return nil, nil
+}
+func (m *mockStore) GetCodeGraph(_ context.Context, _ string) (*codegraph.State, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) ReplaceCodeGraph(_ context.Context, _ *codegraph.State, _ []*codegraph.File, _ []codegraph.Edge) error {
+	return nil
+}
+func (m *mockStore) UpdateCodeGraph(_ context.Context, _ *codegraph.State, _ *codegraph.Update) error {
+	return nil
+}
+func (m *mockStore) ListCodeGraphFiles(_ context.Context, _ string) ([]*codegraph.File, error) {
+	return nil, nil
+}
+func (m *mockStore) ListCodeGraphEdges(_ context.Context, _ string) ([]codegraph.Edge, error) {
+	return nil, nil
+}
+func (m *mockStore) GetCodeGraphNeighbors(_ context.Context, _, _ string) (*codegraph.Neighbors, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) GetProjectConventions(_ context.Context, _ string) (*convention.Conventions, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) SaveProjectConventions(_ context.Context, _ *convention.Conventions) error {
+	return nil
+}
+func (m *mockStore) SaveRunDelivery(_ context.Context, _ *seatbelt.Delivery) error {
+	return nil
+}
+func (m *mockStore) UpdateRunDelivery(_ context.Context, _ *seatbelt.Delivery) error {
+	return nil
+}
+func (m *mockStore) GetRunDelivery(_ context.Context, _ string) (*seatbelt.Delivery, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) ListRunDeliveries(_ context.Context, _ string, _ int) ([]seatbelt.Delivery, error) {
+	return nil, nil
+}
+func (m *mockStore) GetRunCIStatus(_ context.Context, _ string) (*ci.Status, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) SaveRunCIStatus(_ context.Context, _ *ci.Status) error {
+	return nil
+}
+func (m *mockStore) CreateConversation(_ context.Context, _ *conversation.Conversation) error {
+	return nil
+}
+func (m *mockStore) GetConversation(_ context.Context, _ string) (*conversation.Conversation, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) ListConversations(_ context.Context, _ string) ([]conversation.Conversation, error) {
+	return nil, nil
+}
+func (m *mockStore) AppendConversationMessage(_ context.Context, _ *conversation.Message) error {
+	return nil
+}
+func (m *mockStore) ListConversationMessages(_ context.Context, _ string) ([]conversation.Message, error) {
+	return nil, nil
+}
+
+func (m *mockStore) CreateMemory(_ context.Context, _ *memory.Memory) error {
+	return nil
+}
+
+func (m *mockStore) ListMemories(_ context.Context, _ string) ([]memory.Memory, error) {
+	return nil, nil
+}
+
+func (m *mockStore) DeleteMemory(_ context.Context, _ string) error {
+	return domain.ErrNotFound
+}
+
+func (m *mockStore) CreateExperience(_ context.Context, _ *experience.Entry) error {
+	return nil
+}
+
+func (m *mockStore) ListExperiences(_ context.Context, _ string) ([]experience.Entry, error) {
+	return nil, nil
+}
+
+func (m *mockStore) RateExperience(_ context.Context, _ string, _ bool) (*experience.Entry, error) {
+	return nil, domain.ErrNotFound
+}
+
+func (m *mockStore) CreateSkill(_ context.Context, _ *skill.Skill) error {
+	return nil
+}
+
+func (m *mockStore) GetSkill(_ context.Context, _ string) (*skill.Skill, error) {
+	return nil, domain.ErrNotFound
+}
+
+func (m *mockStore) ListSkills(_ context.Context, _ string) ([]skill.Skill, error) {
+	return nil, nil
+}
+
+func (m *mockStore) DeleteSkill(_ context.Context, _ string) error {
+	return domain.ErrNotFound
+}
+
+func (m *mockStore) CreateRunPreset(_ context.Context, _ *run.Preset) error {
+	return nil
+}
+
+func (m *mockStore) GetRunPreset(_ context.Context, _, _ string) (*run.Preset, error) {
+	return nil, domain.ErrNotFound
+}
+
+func (m *mockStore) ListRunPresets(_ context.Context, _ string) ([]run.Preset, error) {
+	return nil, nil
+}
+
+func (m *mockStore) UpdateRunPreset(_ context.Context, _ *run.Preset) error {
+	return domain.ErrNotFound
+}
+
+func (m *mockStore) DeleteRunPreset(_ context.Context, _, _ string) error {
+	return domain.ErrNotFound
+}
+
+func (m *mockStore) CreateMicroagent(_ context.Context, _ *microagent.Microagent) error {
+	return nil
+}
+
+func (m *mockStore) GetMicroagent(_ context.Context, _ string) (*microagent.Microagent, error) {
+	return nil, domain.ErrNotFound
+}
+
+func (m *mockStore) ListMicroagents(_ context.Context, _ string) ([]microagent.Microagent, error) {
+	return nil, nil
+}
+
+func (m *mockStore) UpdateMicroagent(_ context.Context, _ *microagent.Microagent) error {
+	return domain.ErrNotFound
+}
+
+func (m *mockStore) DeleteMicroagent(_ context.Context, _ string) error {
+	return domain.ErrNotFound
+}
+
+func (m *mockStore) CreateMCPServer(_ context.Context, _ *mcpserver.Server) error {
+	return nil
+}
+
+func (m *mockStore) GetMCPServer(_ context.Context, _ string) (*mcpserver.Server, error) {
+	return nil, domain.ErrNotFound
+}
+
+func (m *mockStore) ListMCPServers(_ context.Context) ([]mcpserver.Server, error) {
+	return nil, nil
+}
+
+func (m *mockStore) UpdateMCPServer(_ context.Context, _ *mcpserver.Server) error {
+	return domain.ErrNotFound
+}
+
+func (m *mockStore) DeleteMCPServer(_ context.Context, _ string) error {
+	return domain.ErrNotFound
+}
+
+func (m *mockStore) CreateAPIKey(_ context.Context, _ *apikey.Key) error { return nil }
+func (m *mockStore) GetAPIKey(_ context.Context, _ string) (*apikey.Key, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) GetAPIKeyByHash(_ context.Context, _ []byte) (*apikey.Key, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) ListAPIKeys(_ context.Context) ([]apikey.Key, error)        { return nil, nil }
+func (m *mockStore) UpdateAPIKey(_ context.Context, _ *apikey.Key) error        { return domain.ErrNotFound }
+func (m *mockStore) TouchAPIKey(_ context.Context, _ string, _ time.Time) error { return nil }
+func (m *mockStore) DeleteAPIKey(_ context.Context, _ string) error             { return domain.ErrNotFound }
+func (m *mockStore) CreateAuditSink(_ context.Context, _ *audit.Sink) error     { return nil }
+func (m *mockStore) GetAuditSink(_ context.Context, _ string) (*audit.Sink, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) ListAuditSinks(_ context.Context) ([]audit.Sink, error) { return nil, nil }
+func (m *mockStore) UpdateAuditSink(_ context.Context, _ *audit.Sink) error {
+	return domain.ErrNotFound
+}
+func (m *mockStore) SetAuditSinkProgress(_ context.Context, _ string, _ int64, _ string) error {
+	return nil
+}
+func (m *mockStore) DeleteAuditSink(_ context.Context, _ string) error { return domain.ErrNotFound }
+func (m *mockStore) CreateKnowledgeBase(_ context.Context, _ *knowledge.KnowledgeBase) error {
+	return nil
+}
+func (m *mockStore) GetKnowledgeBase(_ context.Context, _ string) (*knowledge.KnowledgeBase, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) ListKnowledgeBases(_ context.Context) ([]knowledge.KnowledgeBase, error) {
+	return nil, nil
+}
+func (m *mockStore) UpdateKnowledgeBase(_ context.Context, _ *knowledge.KnowledgeBase) error {
+	return domain.ErrNotFound
+}
+func (m *mockStore) ReplaceKnowledgeChunks(_ context.Context, _ *knowledge.KnowledgeBase, _ []retrieval.Chunk) error {
+	return nil
+}
+func (m *mockStore) SetKnowledgeBaseError(_ context.Context, _, _ string) error { return nil }
+func (m *mockStore) ListKnowledgeChunks(_ context.Context, _ string) ([]retrieval.Chunk, error) {
+	return nil, nil
+}
+func (m *mockStore) DeleteKnowledgeBase(_ context.Context, _ string) error { return domain.ErrNotFound }
+func (m *mockStore) ListFeatureFlags(_ context.Context) ([]featureflag.Flag, error) {
+	return nil, nil
+}
+func (m *mockStore) SetFeatureFlag(_ context.Context, _ *featureflag.Flag) error { return nil }
+func (m *mockStore) DeleteFeatureFlag(_ context.Context, _ featureflag.Key, _, _ string) error {
+	return domain.ErrNotFound
+}
+func (m *mockStore) CreateIssueRun(_ context.Context, _ *issuerun.IssueRun) error { return nil }
+func (m *mockStore) UpdateIssueRun(_ context.Context, _ *issuerun.IssueRun) error {
+	return domain.ErrNotFound
+}
+func (m *mockStore) GetIssueRunByRun(_ context.Context, _ string) (*issuerun.IssueRun, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) ListIssueRuns(_ context.Context, _ string) ([]issuerun.IssueRun, error) {
+	return nil, nil
+}
+
+func (m *mockStore) CreateCommandRun(_ context.Context, _ *chatops.CommandRun) error { return nil }
+func (m *mockStore) UpdateCommandRun(_ context.Context, _ *chatops.CommandRun) error {
+	return domain.ErrNotFound
+}
+func (m *mockStore) GetCommandRunByRun(_ context.Context, _ string) (*chatops.CommandRun, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) ListCommandRuns(_ context.Context, _ string) ([]chatops.CommandRun, error) {
+	return nil, nil
+}
+
+func (m *mockStore) GetPollCursor(_ context.Context, _ string, _ poll.Source) (*poll.Cursor, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) SetPollCursor(_ context.Context, _ *poll.Cursor) error { return nil }
+func (m *mockStore) ListPollCursors(_ context.Context, _ string) ([]poll.Cursor, error) {
+	return nil, nil
+}
+func (m *mockStore) CreateNotificationRule(_ context.Context, _ *notification.Rule) error { return nil }
+func (m *mockStore) GetNotificationRule(_ context.Context, _ string) (*notification.Rule, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) ListNotificationRules(_ context.Context, _ string) ([]notification.Rule, error) {
+	return nil, nil
+}
+func (m *mockStore) UpdateNotificationRule(_ context.Context, _ *notification.Rule) error { return nil }
+func (m *mockStore) DeleteNotificationRule(_ context.Context, _ string) error             { return nil }
+func (m *mockStore) GetNotificationSettings(_ context.Context, _ string) (*notification.Settings, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) SetNotificationSettings(_ context.Context, _ *notification.Settings) error {
+	return nil
+}
+func (m *mockStore) EnqueueNotification(_ context.Context, _ *notification.Pending) error { return nil }
+func (m *mockStore) ListDueNotifications(_ context.Context, _ time.Time, _ int) ([]notification.Pending, error) {
+	return nil, nil
+}
+func (m *mockStore) RetryNotification(_ context.Context, _ string, _ time.Time, _ string) error {
+	return nil
+}
+func (m *mockStore) DeleteNotifications(_ context.Context, _ []string) error        { return nil }
+func (m *mockStore) CreateReplaySession(_ context.Context, _ *replay.Session) error { return nil }
+func (m *mockStore) GetReplaySession(_ context.Context, _ string) (*replay.Session, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) UpdateReplaySessionPosition(_ context.Context, _ string, _ int) error { return nil }
+func (m *mockStore) GetVCSAccountHealth(_ context.Context, _ string) (*vcsaccount.Health, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) SaveVCSAccountHealth(_ context.Context, _ *vcsaccount.Health) error { return nil }
+func (m *mockStore) ListVCSAccountHealth(_ context.Context) ([]vcsaccount.Health, error) {
+	return nil, nil
+}
+func (m *mockStore) DeleteReplaySession(_ context.Context, _ string) error { return nil }
+func (m *mockStore) CreateScope(_ context.Context, _ *scope.Scope) error   { return nil }
+func (m *mockStore) GetScope(_ context.Context, _ string) (*scope.Scope, error) {
+	return nil, domain.ErrNotFound
+}
+func (m *mockStore) ListScopes(_ context.Context) ([]scope.Scope, error) { return nil, nil }
+func (m *mockStore) UpdateScope(_ context.Context, _ *scope.Scope) error { return nil }
+func (m *mockStore) DeleteScope(_ context.Context, _ string) error       { return domain.ErrNotFound }
+
 // --- ProjectService Tests ---
 
 func TestProjectServiceList(t *testing.T) {
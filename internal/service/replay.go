@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/replay"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/snapshot"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+)
+
+// ReplayService steps through the recorded events of a run: a session
+// advances event by event, shows the workspace checkpoint the snapshot
+// subsystem took after the latest tool call up to its step, and forks a
+// new run of the task from that checkpoint.
+type ReplayService struct {
+	store     database.Store
+	runtime   *RuntimeService
+	snapshots *SnapshotService
+}
+
+// NewReplayService creates a ReplayService.
+func NewReplayService(store database.Store, runtime *RuntimeService, snapshots *SnapshotService) *ReplayService {
+	return &ReplayService{store: store, runtime: runtime, snapshots: snapshots}
+}
+
+// Create starts a session at the first event of a run.
+func (s *ReplayService) Create(ctx context.Context, req *replay.CreateRequest) (*replay.View, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	r, err := s.store.GetRun(ctx, req.RunID)
+	if err != nil {
+		return nil, fmt.Errorf("get run: %w", err)
+	}
+	events, err := s.runtime.loadRunEvents(ctx, r.ID)
+	if err != nil {
+		return nil, fmt.Errorf("load run events: %w", err)
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("replay run %s: %w", r.ID, replay.ErrNoEvents)
+	}
+	rs := &replay.Session{RunID: r.ID, ProjectID: r.ProjectID}
+	if err := s.store.CreateReplaySession(ctx, rs); err != nil {
+		return nil, err
+	}
+	return s.view(ctx, rs, events)
+}
+
+// Get returns a session at its current step.
+func (s *ReplayService) Get(ctx context.Context, id string) (*replay.View, error) {
+	rs, events, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.view(ctx, rs, events)
+}
+
+// Step moves a session and returns it at its new step.
+func (s *ReplayService) Step(ctx context.Context, id string, req *replay.SeekRequest) (*replay.View, error) {
+	rs, events, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	target, err := req.Target(rs.Position, len(events))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.UpdateReplaySessionPosition(ctx, id, target); err != nil {
+		return nil, err
+	}
+	rs.Position = target
+	return s.view(ctx, rs, events)
+}
+
+// State returns the files of the workspace checkpoint at a session's
+// current step.
+func (s *ReplayService) State(ctx context.Context, id string) (*replay.State, error) {
+	rs, events, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	cp, err := s.checkpoint(ctx, rs, events, rs.Position)
+	if err != nil {
+		return nil, err
+	}
+	files, err := s.snapshots.Files(ctx, rs.ProjectID, cp.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &replay.State{Step: rs.Position, Checkpoint: *cp, Files: files}, nil
+}
+
+// File returns the contents of a file of the workspace checkpoint at a
+// session's current step.
+func (s *ReplayService) File(ctx context.Context, id, name string) ([]byte, error) {
+	rs, events, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	cp, err := s.checkpoint(ctx, rs, events, rs.Position)
+	if err != nil {
+		return nil, err
+	}
+	return s.snapshots.ReadFile(ctx, rs.ProjectID, cp.ID, name)
+}
+
+// Fork starts a new run of the replayed run's task in a worktree restored
+// from the checkpoint of a step, prompted with the tool calls made up to
+// it. The fork records a run.forked event linking it to its source.
+func (s *ReplayService) Fork(ctx context.Context, id string, req *replay.ForkRequest) (*run.Run, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	rs, events, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	step := rs.Position
+	if req.Step != nil {
+		step = *req.Step
+		if step < 0 || step >= len(events) {
+			return nil, fmt.Errorf("%w: %d of %d events", replay.ErrOutOfRange, step, len(events))
+		}
+	}
+	cp, err := s.checkpoint(ctx, rs, events, step)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := s.store.GetRun(ctx, rs.RunID)
+	if err != nil {
+		return nil, fmt.Errorf("get run: %w", err)
+	}
+	t, err := s.store.GetTask(ctx, src.TaskID)
+	if err != nil {
+		return nil, fmt.Errorf("get task: %w", err)
+	}
+	fork, err := s.runtime.StartRun(ctx, &run.StartRequest{
+		TaskID:        src.TaskID,
+		AgentID:       src.AgentID,
+		ProjectID:     src.ProjectID,
+		TeamID:        src.TeamID,
+		PolicyProfile: src.PolicyProfile,
+		DeliverMode:   req.DeliverMode,
+		Prompt:        replay.ForkPrompt(t.Prompt, src.ID, events, step, req.Prompt),
+		FromSnapshot:  cp.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.runtime.appendRunEvent(ctx, event.TypeRunForked, fork, map[string]string{
+		"source_run_id": src.ID,
+		"step":          strconv.Itoa(step),
+		"snapshot_id":   cp.ID,
+	})
+	slog.Info("run forked from replay", "run_id", fork.ID, "source_run_id", src.ID, "step", step, "snapshot_id", cp.ID)
+	return fork, nil
+}
+
+// Delete ends a session.
+func (s *ReplayService) Delete(ctx context.Context, id string) error {
+	return s.store.DeleteReplaySession(ctx, id)
+}
+
+// load returns a session and the events of its run.
+func (s *ReplayService) load(ctx context.Context, id string) (*replay.Session, []event.AgentEvent, error) {
+	rs, err := s.store.GetReplaySession(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	events, err := s.runtime.loadRunEvents(ctx, rs.RunID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load run events: %w", err)
+	}
+	if len(events) == 0 {
+		return nil, nil, fmt.Errorf("replay run %s: %w", rs.RunID, replay.ErrNoEvents)
+	}
+	// Events archived or pruned since the session moved past them.
+	rs.Position = min(rs.Position, len(events)-1)
+	return rs, events, nil
+}
+
+// checkpoint returns the workspace checkpoint of a session's run at a
+// step.
+func (s *ReplayService) checkpoint(ctx context.Context, rs *replay.Session, events []event.AgentEvent, step int) (*snapshot.Snapshot, error) {
+	snaps, err := s.snapshots.ListByRun(ctx, rs.RunID)
+	if err != nil {
+		return nil, err
+	}
+	cp := replay.CheckpointAt(events, snaps, step)
+	if cp == nil {
+		return nil, fmt.Errorf("step %d of run %s: %w", step, rs.RunID, replay.ErrNoCheckpoint)
+	}
+	return cp, nil
+}
+
+// view returns a session at its current step.
+func (s *ReplayService) view(ctx context.Context, rs *replay.Session, events []event.AgentEvent) (*replay.View, error) {
+	snaps, err := s.snapshots.ListByRun(ctx, rs.RunID)
+	if err != nil {
+		return nil, err
+	}
+	return &replay.View{
+		Session: *rs,
+		Steps:   len(events),
+		Current: &replay.Step{
+			Index:      rs.Position,
+			Event:      events[rs.Position],
+			Checkpoint: replay.CheckpointAt(events, snaps, rs.Position),
+		},
+	}, nil
+}
@@ -0,0 +1,141 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/agent"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/replay"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestReplaySession(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available in test environment")
+	}
+	ctx := context.Background()
+	repo := initWorktreeTestRepo(t)
+
+	store := &runtimeMockStore{
+		projects: []project.Project{{ID: "proj-1", Name: "test", Provider: "local", WorkspacePath: repo}},
+		agents:   []agent.Agent{{ID: "agent-1", ProjectID: "proj-1", Name: "a", Status: agent.StatusIdle}},
+		tasks:    []task.Task{{ID: "task-1", ProjectID: "proj-1", Title: "t", Prompt: "Fix the parser."}},
+		runs:     []run.Run{{ID: "run-1", TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", Status: run.StatusCompleted}},
+	}
+	es := &runtimeMockEventStore{}
+	queue := &runtimeMockQueue{}
+	cfg := &config.Runtime{SnapshotIgnore: []string{".git"}, SnapshotMaxMB: 16}
+	runtime := service.NewRuntimeService(store, queue, &runtimeMockBroadcaster{}, es,
+		service.NewPolicyService("plan-readonly", nil), cfg)
+	projects := service.NewProjectService(store)
+	projects.SetWorktreeRoot(t.TempDir())
+	runtime.SetProjectService(projects)
+	snapshots := service.NewSnapshotService(store, cfg)
+	runtime.SetSnapshotService(snapshots)
+	svc := service.NewReplayService(store, runtime, snapshots)
+
+	// The run edits main.go twice; a checkpoint follows each edit.
+	record := func(typ event.Type, p map[string]string) {
+		b, _ := json.Marshal(p)
+		es.events = append(es.events, event.AgentEvent{RunID: "run-1", Type: typ, Payload: b})
+	}
+	record(event.TypeRunStarted, nil)
+	for i, content := range []string{"package v1", "package v2"} {
+		callID := []string{"c1", "c2"}[i]
+		record(event.TypeToolCallApproved, map[string]string{"call_id": callID, "tool": "Edit", "path": "main.go"})
+		record(event.TypeToolCallResultEv, map[string]string{"call_id": callID, "tool": "Edit", "success": "true"})
+		writeTestFile(t, repo, "main.go", content)
+		if _, err := snapshots.Checkpoint(ctx, &store.runs[0], callID); err != nil {
+			t.Fatalf("Checkpoint failed: %v", err)
+		}
+	}
+
+	v, err := svc.Create(ctx, &replay.CreateRequest{RunID: "run-1"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if v.Steps != 5 || v.Current.Index != 0 || v.Current.Event.Type != event.TypeRunStarted || v.Current.Checkpoint != nil {
+		t.Fatalf("unexpected session %+v, step %+v", v, v.Current)
+	}
+	if _, err := svc.State(ctx, v.ID); !errors.Is(err, replay.ErrNoCheckpoint) {
+		t.Fatalf("expected ErrNoCheckpoint before the first tool call, got %v", err)
+	}
+
+	// Step to the first edit's result.
+	for range 2 {
+		if v, err = svc.Step(ctx, v.ID, &replay.SeekRequest{}); err != nil {
+			t.Fatalf("Step failed: %v", err)
+		}
+	}
+	if v.Current.Index != 2 || v.Current.Checkpoint == nil || v.Current.Checkpoint.CallID != "c1" {
+		t.Fatalf("expected the c1 checkpoint at step 2, got %+v", v.Current)
+	}
+	st, err := svc.State(ctx, v.ID)
+	if err != nil || len(st.Files) != 1 || st.Files[0].Path != "main.go" {
+		t.Fatalf("State = %+v, %v", st, err)
+	}
+	if b, err := svc.File(ctx, v.ID, "main.go"); err != nil || string(b) != "package v1" {
+		t.Fatalf("File = %q, %v", b, err)
+	}
+
+	to := 4
+	if v, err = svc.Step(ctx, v.ID, &replay.SeekRequest{To: &to}); err != nil {
+		t.Fatalf("Step failed: %v", err)
+	}
+	if b, _ := svc.File(ctx, v.ID, "main.go"); string(b) != "package v2" {
+		t.Fatalf("expected the c2 checkpoint at step 4, got %q", b)
+	}
+	if _, err := svc.Step(ctx, v.ID, &replay.SeekRequest{}); !errors.Is(err, replay.ErrOutOfRange) {
+		t.Fatalf("expected ErrOutOfRange past the last event, got %v", err)
+	}
+
+	// Fork from the first edit: the worktree holds its checkpoint.
+	step := 2
+	fork, err := svc.Fork(ctx, v.ID, &replay.ForkRequest{Step: &step, Prompt: "Keep v1."})
+	if err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	if fork.WorktreePath == "" || fork.TaskID != "task-1" {
+		t.Fatalf("unexpected fork %+v", fork)
+	}
+	if b, _ := os.ReadFile(filepath.Join(fork.WorktreePath, "main.go")); string(b) != "package v1" {
+		t.Fatalf("expected the fork's worktree restored, got %q", b)
+	}
+	msg, ok := queue.lastMessage(messagequeue.SubjectRunStart)
+	if !ok {
+		t.Fatal("expected run start message")
+	}
+	var payload messagequeue.RunStartPayload
+	_ = json.Unmarshal(msg.Data, &payload)
+	if !strings.Contains(payload.Prompt, "after step 3") || !strings.Contains(payload.Prompt, "1. Edit main.go (ok)\n") ||
+		strings.Contains(payload.Prompt, "2. Edit") || !strings.Contains(payload.Prompt, "Keep v1.") {
+		t.Fatalf("unexpected fork prompt:\n%s", payload.Prompt)
+	}
+	forked, _ := es.LoadByRun(ctx, fork.ID)
+	var found bool
+	for i := range forked {
+		found = found || (forked[i].Type == event.TypeRunForked && strings.Contains(string(forked[i].Payload), `"source_run_id":"run-1"`))
+	}
+	if !found {
+		t.Fatal("expected a run.forked event on the fork")
+	}
+
+	if err := svc.Delete(ctx, v.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := svc.Get(ctx, v.ID); err == nil {
+		t.Fatal("expected the session deleted")
+	}
+}
@@ -0,0 +1,412 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
+	"github.com/Strob0t/CodeForge/internal/adapter/ws"
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/agent"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/domain/research"
+	"github.com/Strob0t/CodeForge/internal/domain/routing"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/logger"
+	"github.com/Strob0t/CodeForge/internal/port/broadcast"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/eventstore"
+	"github.com/Strob0t/CodeForge/internal/port/websearch"
+)
+
+// ErrResearchUnavailable is returned when no web search provider is configured.
+var ErrResearchUnavailable = errors.New("research: no web search provider configured")
+
+// ResearchService executes research runs: policy-gated web searches whose
+// findings are stored as a run artifact and picked up by the context
+// optimizer for the follow-up implementation task.
+type ResearchService struct {
+	store   database.Store
+	hub     broadcast.Broadcaster
+	events  eventstore.Store
+	policy  *PolicyService
+	search  websearch.Provider
+	llm     *litellm.Client
+	cfg     *config.Research
+	routing *RoutingService
+}
+
+// NewResearchService creates a ResearchService. search may be nil, in which case
+// research runs are rejected; llm may be nil, in which case findings are not summarized.
+func NewResearchService(
+	store database.Store,
+	hub broadcast.Broadcaster,
+	events eventstore.Store,
+	policySvc *PolicyService,
+	search websearch.Provider,
+	llm *litellm.Client,
+	cfg *config.Research,
+) *ResearchService {
+	return &ResearchService{
+		store:  store,
+		hub:    hub,
+		events: events,
+		policy: policySvc,
+		search: search,
+		llm:    llm,
+		cfg:    cfg,
+	}
+}
+
+// SetRoutingService routes the summary model through the routing rules.
+// Without it the configured summary model is used.
+func (s *ResearchService) SetRoutingService(r *RoutingService) {
+	s.routing = r
+}
+
+// Available reports whether a web search provider is configured.
+func (s *ResearchService) Available() bool {
+	return s.search != nil
+}
+
+// Run executes a research run synchronously and returns the stored report.
+// Every search query is evaluated as a WebSearch tool call and every result
+// URL as a WebFetch tool call; only "allow" decisions pass, because there is
+// no human in the loop to answer "ask".
+func (s *ResearchService) Run(ctx context.Context, req *research.Request) (*research.Report, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validate research request: %w", err)
+	}
+	if s.search == nil {
+		return nil, ErrResearchUnavailable
+	}
+
+	profileName := req.PolicyProfile
+	if profileName == "" {
+		profileName = s.cfg.PolicyProfile
+	}
+	if _, ok := s.policy.GetProfile(profileName); !ok {
+		return nil, fmt.Errorf("unknown policy profile %q", profileName)
+	}
+
+	if _, err := s.store.GetAgent(ctx, req.AgentID); err != nil {
+		return nil, fmt.Errorf("get agent: %w", err)
+	}
+	if _, err := s.store.GetTask(ctx, req.TaskID); err != nil {
+		return nil, fmt.Errorf("get task: %w", err)
+	}
+	if req.FollowUpTaskID != "" {
+		if _, err := s.store.GetTask(ctx, req.FollowUpTaskID); err != nil {
+			return nil, fmt.Errorf("get follow-up task: %w", err)
+		}
+	}
+
+	r := &run.Run{
+		TaskID:        req.TaskID,
+		AgentID:       req.AgentID,
+		ProjectID:     req.ProjectID,
+		PolicyProfile: profileName,
+		ExecMode:      run.ExecModeMount,
+		Status:        run.StatusPending,
+	}
+	if err := s.store.CreateRun(ctx, r); err != nil {
+		return nil, fmt.Errorf("create run: %w", err)
+	}
+	if err := s.store.UpdateRunStatus(ctx, r.ID, run.StatusRunning, 0, 0); err != nil {
+		return nil, fmt.Errorf("update run status: %w", err)
+	}
+	r.Status = run.StatusRunning
+	_ = s.store.UpdateAgentStatus(ctx, req.AgentID, agent.StatusRunning)
+	_ = s.store.UpdateTaskStatus(ctx, req.TaskID, task.StatusRunning)
+
+	s.appendEvent(ctx, event.TypeResearchStarted, r, map[string]string{
+		"provider":       s.search.Name(),
+		"policy_profile": profileName,
+		"queries":        fmt.Sprintf("%d", len(req.Queries)),
+	})
+	s.hub.BroadcastEvent(ctx, ws.EventRunStatus, ws.RunStatusEvent{
+		RunID:     r.ID,
+		TaskID:    r.TaskID,
+		ProjectID: r.ProjectID,
+		Status:    string(r.Status),
+	})
+
+	report, steps, err := s.gather(ctx, req, profileName)
+	if err != nil {
+		s.fail(ctx, r, req, steps, err)
+		return nil, err
+	}
+	report.RunID = r.ID
+
+	if summary, sumErr := s.summarize(ctx, req, report); sumErr != nil {
+		slog.Warn("research summary failed", "run_id", r.ID, "error", sumErr)
+	} else {
+		report.Summary = summary
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		err = fmt.Errorf("marshal research report: %w", err)
+		s.fail(ctx, r, req, steps, err)
+		return nil, err
+	}
+	art := &artifact.Artifact{
+		RunID:       r.ID,
+		ProjectID:   r.ProjectID,
+		Kind:        artifact.KindResearchReport,
+		Name:        "research-report.json",
+		ContentType: "application/json",
+		Data:        data,
+		Metadata: map[string]string{
+			research.MetaFollowUpTaskID: req.FollowUpTaskID,
+			"findings":                  fmt.Sprintf("%d", len(report.Findings)),
+		},
+	}
+	if err := s.store.CreateArtifact(ctx, art); err != nil {
+		err = fmt.Errorf("store research report: %w", err)
+		s.fail(ctx, r, req, steps, err)
+		return nil, err
+	}
+
+	output := report.Summary
+	if output == "" {
+		output = fmt.Sprintf("%d findings from %d queries", len(report.Findings), len(req.Queries))
+	}
+	if err := s.store.CompleteRun(ctx, r.ID, run.StatusCompleted, output, "", 0, steps); err != nil {
+		return nil, fmt.Errorf("complete run: %w", err)
+	}
+	_ = s.store.UpdateTaskStatus(ctx, r.TaskID, task.StatusCompleted)
+	_ = s.store.UpdateTaskResult(ctx, r.TaskID, task.Result{Output: output}, 0)
+	_ = s.store.UpdateAgentStatus(ctx, r.AgentID, agent.StatusIdle)
+
+	s.appendEvent(ctx, event.TypeResearchCompleted, r, map[string]string{
+		"artifact_id":       art.ID,
+		"findings":          fmt.Sprintf("%d", len(report.Findings)),
+		"denied":            fmt.Sprintf("%d", len(report.Denied)),
+		"follow_up_task_id": req.FollowUpTaskID,
+	})
+	s.hub.BroadcastEvent(ctx, ws.EventRunStatus, ws.RunStatusEvent{
+		RunID:     r.ID,
+		TaskID:    r.TaskID,
+		ProjectID: r.ProjectID,
+		Status:    string(run.StatusCompleted),
+		StepCount: steps,
+	})
+	s.hub.BroadcastEvent(ctx, ws.EventResearchReport, ws.ResearchReportEvent{
+		RunID:          r.ID,
+		ProjectID:      r.ProjectID,
+		FollowUpTaskID: req.FollowUpTaskID,
+		ArtifactID:     art.ID,
+		Findings:       len(report.Findings),
+		Denied:         len(report.Denied),
+		Status:         string(run.StatusCompleted),
+	})
+
+	slog.Info("research run completed",
+		"run_id", r.ID,
+		"findings", len(report.Findings),
+		"denied", len(report.Denied),
+		"artifact_id", art.ID,
+	)
+	return report, nil
+}
+
+// gather runs the policy-gated searches and returns the collected findings
+// together with the number of tool calls evaluated.
+func (s *ResearchService) gather(ctx context.Context, req *research.Request, profileName string) (*research.Report, int, error) {
+	limit := req.MaxResults
+	if limit <= 0 {
+		limit = s.cfg.MaxResults
+	}
+
+	report := &research.Report{
+		TaskID:         req.TaskID,
+		ProjectID:      req.ProjectID,
+		FollowUpTaskID: req.FollowUpTaskID,
+		Queries:        req.Queries,
+		CreatedAt:      time.Now().UTC(),
+	}
+	steps := 0
+	searched := 0
+	seen := make(map[string]bool)
+	var lastErr error
+
+	for _, q := range req.Queries {
+		q = strings.TrimSpace(q)
+		steps++
+		d, err := s.policy.Evaluate(ctx, profileName, policy.ToolCall{Tool: "WebSearch", Command: q})
+		if err != nil {
+			return nil, steps, fmt.Errorf("evaluate search policy: %w", err)
+		}
+		if d != policy.DecisionAllow {
+			report.Denied = append(report.Denied, "query: "+q)
+			continue
+		}
+
+		results, err := s.search.Search(ctx, q, limit)
+		if err != nil {
+			slog.Warn("research search failed", "query", q, "error", err)
+			lastErr = err
+			continue
+		}
+		searched++
+
+		for _, res := range results {
+			if res.URL == "" || seen[res.URL] {
+				continue
+			}
+			seen[res.URL] = true
+			steps++
+			d, err := s.policy.Evaluate(ctx, profileName, policy.ToolCall{Tool: "WebFetch", URL: res.URL})
+			if err != nil {
+				return nil, steps, fmt.Errorf("evaluate fetch policy: %w", err)
+			}
+			if d != policy.DecisionAllow {
+				report.Denied = append(report.Denied, res.URL)
+				continue
+			}
+			report.Findings = append(report.Findings, research.Finding{
+				Query: q,
+				Source: research.Source{
+					Title:  res.Title,
+					URL:    res.URL,
+					Domain: hostOf(res.URL),
+				},
+				Summary: res.Snippet,
+				Score:   res.Score,
+			})
+		}
+	}
+
+	if searched == 0 && lastErr != nil {
+		return nil, steps, fmt.Errorf("all searches failed: %w", lastErr)
+	}
+	return report, steps, nil
+}
+
+// summarize asks the LLM for a short synthesis of the findings. It returns an
+// empty summary without error when summarization is disabled or there is nothing to summarize.
+func (s *ResearchService) summarize(ctx context.Context, req *research.Request, report *research.Report) (string, error) {
+	if s.llm == nil || (s.routing == nil && s.cfg.SummaryModel == "") || len(report.Findings) == 0 {
+		return "", nil
+	}
+
+	timeout := time.Duration(s.cfg.SummaryTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var b strings.Builder
+	b.WriteString("Research questions:\n")
+	for _, q := range req.Queries {
+		b.WriteString("- ")
+		b.WriteString(q)
+		b.WriteString("\n")
+	}
+	b.WriteString("\nSources:\n")
+	b.WriteString(report.Render())
+
+	var resp *litellm.ChatCompletionResponse
+	call := func(model string) error {
+		var err error
+		resp, err = s.llm.ChatCompletion(ctx, litellm.ChatCompletionRequest{
+			Model: model,
+			Messages: []litellm.ChatMessage{
+				{Role: "system", Content: "Summarize the sources below for a software engineer about to implement a task. " +
+					"Answer the research questions in at most 10 bullet points and cite the source URL for each claim."},
+				{Role: "user", Content: b.String()},
+			},
+			Temperature: 0.2,
+			MaxTokens:   1024,
+		})
+		return err
+	}
+	if s.routing == nil {
+		if err := call(s.cfg.SummaryModel); err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(resp.Content), nil
+	}
+	err := s.routing.Call(ctx, routing.Request{TaskType: routing.TaskSummarize, ProjectID: req.ProjectID}, call)
+	if errors.Is(err, routing.ErrNoModel) && s.cfg.SummaryModel == "" {
+		// Summarization is disabled and no rule routes it.
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp.Content), nil
+}
+
+// fail marks a research run as failed and releases the agent.
+func (s *ResearchService) fail(ctx context.Context, r *run.Run, req *research.Request, steps int, cause error) {
+	if err := s.store.CompleteRun(ctx, r.ID, run.StatusFailed, "", cause.Error(), 0, steps); err != nil {
+		slog.Error("failed to complete research run", "run_id", r.ID, "error", err)
+	}
+	_ = s.store.UpdateTaskStatus(ctx, r.TaskID, task.StatusFailed)
+	_ = s.store.UpdateTaskResult(ctx, r.TaskID, task.Result{Error: cause.Error()}, 0)
+	_ = s.store.UpdateAgentStatus(ctx, r.AgentID, agent.StatusIdle)
+
+	s.appendEvent(ctx, event.TypeResearchFailed, r, map[string]string{
+		"error": cause.Error(),
+	})
+	s.hub.BroadcastEvent(ctx, ws.EventRunStatus, ws.RunStatusEvent{
+		RunID:     r.ID,
+		TaskID:    r.TaskID,
+		ProjectID: r.ProjectID,
+		Status:    string(run.StatusFailed),
+		StepCount: steps,
+	})
+	s.hub.BroadcastEvent(ctx, ws.EventResearchReport, ws.ResearchReportEvent{
+		RunID:          r.ID,
+		ProjectID:      r.ProjectID,
+		FollowUpTaskID: req.FollowUpTaskID,
+		Status:         string(run.StatusFailed),
+		Error:          cause.Error(),
+	})
+	slog.Warn("research run failed", "run_id", r.ID, "error", cause)
+}
+
+func (s *ResearchService) appendEvent(ctx context.Context, evType event.Type, r *run.Run, payload map[string]string) {
+	if s.events == nil {
+		return
+	}
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("failed to marshal research event payload", "error", err)
+		return
+	}
+	ev := event.AgentEvent{
+		AgentID:   r.AgentID,
+		TaskID:    r.TaskID,
+		ProjectID: r.ProjectID,
+		RunID:     r.ID,
+		Type:      evType,
+		Payload:   payloadJSON,
+		RequestID: logger.RequestID(ctx),
+		Version:   1,
+	}
+	if err := s.events.Append(ctx, &ev); err != nil {
+		slog.Error("failed to append research event", "type", evType, "run_id", r.ID, "error", err)
+	}
+}
+
+// hostOf returns the lowercase host of a URL, or "" if it cannot be parsed.
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
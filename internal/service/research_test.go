@@ -0,0 +1,215 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/ws"
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/agent"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	cfcontext "github.com/Strob0t/CodeForge/internal/domain/context"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/research"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/port/websearch"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+type fakeSearch struct {
+	results map[string][]websearch.Result
+	err     error
+	queries []string
+}
+
+func (f *fakeSearch) Name() string { return "fake" }
+func (f *fakeSearch) Capabilities() websearch.Capabilities {
+	return websearch.Capabilities{Search: true, Snippets: true}
+}
+func (f *fakeSearch) Search(_ context.Context, query string, _ int) ([]websearch.Result, error) {
+	f.queries = append(f.queries, query)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.results[query], nil
+}
+
+func newResearchTestEnv(search websearch.Provider) (*service.ResearchService, *runtimeMockStore, *runtimeMockBroadcaster) {
+	store := &runtimeMockStore{
+		projects: []project.Project{{ID: "proj-1", Name: "test"}},
+		agents:   []agent.Agent{{ID: "agent-1", ProjectID: "proj-1", Name: "researcher", Status: agent.StatusIdle}},
+		tasks: []task.Task{
+			{ID: "task-r", ProjectID: "proj-1", Title: "Research", Prompt: "Research generics"},
+			{ID: "task-impl", ProjectID: "proj-1", Title: "Implement", Prompt: "Implement generic cache"},
+		},
+	}
+	bc := &runtimeMockBroadcaster{}
+	policySvc := service.NewPolicyService("headless-safe-sandbox", nil)
+	cfg := &config.Research{MaxResults: 5, PolicyProfile: "research-web"}
+	svc := service.NewResearchService(store, bc, &runtimeMockEventStore{}, policySvc, search, nil, cfg)
+	return svc, store, bc
+}
+
+func TestResearchRun_StoresReportAndFiltersDomains(t *testing.T) {
+	search := &fakeSearch{results: map[string][]websearch.Result{
+		"go generics": {
+			{Title: "Spec", URL: "https://go.dev/ref/spec", Snippet: "Type parameters"},
+			{Title: "Blog", URL: "https://random-blog.example/generics", Snippet: "Opinions"},
+			{Title: "Spec dup", URL: "https://go.dev/ref/spec"},
+		},
+	}}
+	svc, store, bc := newResearchTestEnv(search)
+
+	report, err := svc.Run(context.Background(), &research.Request{
+		ProjectID:      "proj-1",
+		TaskID:         "task-r",
+		AgentID:        "agent-1",
+		Queries:        []string{"go generics"},
+		FollowUpTaskID: "task-impl",
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(report.Findings) != 1 || report.Findings[0].Source.Domain != "go.dev" {
+		t.Fatalf("expected 1 go.dev finding, got %+v", report.Findings)
+	}
+	if len(report.Denied) != 1 || report.Denied[0] != "https://random-blog.example/generics" {
+		t.Fatalf("expected blog URL denied, got %v", report.Denied)
+	}
+
+	if len(store.runs) != 1 || store.runs[0].Status != run.StatusCompleted {
+		t.Fatalf("expected 1 completed run, got %+v", store.runs)
+	}
+	if store.runs[0].PolicyProfile != "research-web" {
+		t.Fatalf("expected research-web profile, got %q", store.runs[0].PolicyProfile)
+	}
+
+	if len(store.artifacts) != 1 {
+		t.Fatalf("expected 1 artifact, got %d", len(store.artifacts))
+	}
+	art := store.artifacts[0]
+	if art.Kind != artifact.KindResearchReport || art.RunID != store.runs[0].ID {
+		t.Fatalf("unexpected artifact %+v", art)
+	}
+	if art.Metadata[research.MetaFollowUpTaskID] != "task-impl" {
+		t.Fatalf("expected follow-up metadata, got %v", art.Metadata)
+	}
+	var stored research.Report
+	if err := json.Unmarshal(art.Data, &stored); err != nil {
+		t.Fatalf("artifact data is not a report: %v", err)
+	}
+
+	found := false
+	for _, ev := range bc.events {
+		if ev.EventType == ws.EventResearchReport {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected research report broadcast")
+	}
+}
+
+func TestResearchRun_DeniedQuery(t *testing.T) {
+	search := &fakeSearch{}
+	svc, store, _ := newResearchTestEnv(search)
+
+	// plan-readonly has no WebSearch rule, so plan mode falls back to deny.
+	report, err := svc.Run(context.Background(), &research.Request{
+		ProjectID:     "proj-1",
+		TaskID:        "task-r",
+		AgentID:       "agent-1",
+		Queries:       []string{"anything"},
+		PolicyProfile: "plan-readonly",
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(search.queries) != 0 {
+		t.Fatalf("expected no searches, got %v", search.queries)
+	}
+	if len(report.Denied) != 1 || report.Denied[0] != "query: anything" {
+		t.Fatalf("expected denied query, got %v", report.Denied)
+	}
+	if store.runs[0].Status != run.StatusCompleted {
+		t.Fatalf("expected completed run, got %s", store.runs[0].Status)
+	}
+}
+
+func TestResearchRun_AllSearchesFail(t *testing.T) {
+	svc, store, _ := newResearchTestEnv(&fakeSearch{err: errors.New("boom")})
+
+	_, err := svc.Run(context.Background(), &research.Request{
+		ProjectID: "proj-1",
+		TaskID:    "task-r",
+		AgentID:   "agent-1",
+		Queries:   []string{"go generics"},
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if store.runs[0].Status != run.StatusFailed {
+		t.Fatalf("expected failed run, got %s", store.runs[0].Status)
+	}
+	if len(store.artifacts) != 0 {
+		t.Fatalf("expected no artifacts, got %d", len(store.artifacts))
+	}
+}
+
+func TestResearchRun_NoProvider(t *testing.T) {
+	svc, _, _ := newResearchTestEnv(nil)
+	if svc.Available() {
+		t.Fatal("expected research to be unavailable")
+	}
+	_, err := svc.Run(context.Background(), &research.Request{
+		ProjectID: "proj-1",
+		TaskID:    "task-r",
+		AgentID:   "agent-1",
+		Queries:   []string{"q"},
+	})
+	if !errors.Is(err, service.ErrResearchUnavailable) {
+		t.Fatalf("expected ErrResearchUnavailable, got %v", err)
+	}
+}
+
+func TestBuildContextPack_AttachesResearchFindings(t *testing.T) {
+	search := &fakeSearch{results: map[string][]websearch.Result{
+		"go generics": {{Title: "Spec", URL: "https://go.dev/ref/spec", Snippet: "Type parameters"}},
+	}}
+	svc, store, _ := newResearchTestEnv(search)
+	if _, err := svc.Run(context.Background(), &research.Request{
+		ProjectID:      "proj-1",
+		TaskID:         "task-r",
+		AgentID:        "agent-1",
+		Queries:        []string{"go generics"},
+		FollowUpTaskID: "task-impl",
+	}); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	orchCfg := &config.Orchestrator{DefaultContextBudget: 8192, PromptReserve: 1024}
+	co := service.NewContextOptimizerService(store, orchCfg)
+
+	pack, err := co.BuildContextPack(context.Background(), "task-impl", "proj-1", "")
+	if err != nil {
+		t.Fatalf("BuildContextPack failed: %v", err)
+	}
+	if pack == nil || len(pack.Entries) != 1 {
+		t.Fatalf("expected 1 entry, got %+v", pack)
+	}
+	if pack.Entries[0].Kind != cfcontext.EntryResearch {
+		t.Fatalf("expected research entry, got %s", pack.Entries[0].Kind)
+	}
+
+	// The research task itself does not receive its own findings.
+	other, err := co.BuildContextPack(context.Background(), "task-r", "proj-1", "")
+	if err != nil {
+		t.Fatalf("BuildContextPack failed: %v", err)
+	}
+	if other != nil {
+		t.Fatalf("expected no pack for research task, got %+v", other)
+	}
+}
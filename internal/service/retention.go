@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/eventstore"
+)
+
+// RetentionService keeps the agent_events table bounded. Events of runs that
+// finished longer than the retention window ago are rolled into a compressed
+// JSONL artifact and removed from the event store; LoadRunEvents restores
+// them transparently.
+type RetentionService struct {
+	store  database.Store
+	events eventstore.Store
+	cfg    config.Retention
+}
+
+// NewRetentionService creates a RetentionService.
+func NewRetentionService(store database.Store, events eventstore.Store, cfg config.Retention) *RetentionService {
+	return &RetentionService{store: store, events: events, cfg: cfg}
+}
+
+// Compact archives the events of every run past its project's retention
+// window, at most BatchSize runs per project. It returns the number of runs
+// archived; individual failures are logged and retried on the next pass.
+func (s *RetentionService) Compact(ctx context.Context) (int, error) {
+	projects, err := s.store.ListProjects(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list projects: %w", err)
+	}
+	archived := 0
+	for i := range projects {
+		window := event.RetentionWindow(projects[i].Config, s.cfg.EventWindow)
+		if window <= 0 {
+			continue
+		}
+		runs, err := s.store.ListRunsForEventArchival(ctx, projects[i].ID, time.Now().Add(-window), s.cfg.BatchSize)
+		if err != nil {
+			return archived, fmt.Errorf("list runs for archival: %w", err)
+		}
+		for j := range runs {
+			if err := s.archiveRun(ctx, runs[j].ID, runs[j].ProjectID); err != nil {
+				slog.Warn("event archival failed", "run_id", runs[j].ID, "error", err)
+				continue
+			}
+			archived++
+		}
+	}
+	if archived > 0 {
+		slog.Info("run events archived", "runs", archived)
+	}
+	return archived, nil
+}
+
+// archiveRun writes the run's events to an archive artifact, deletes them
+// from the event store and marks the run archived. An archive left behind by
+// an interrupted pass is reused rather than written twice.
+func (s *RetentionService) archiveRun(ctx context.Context, runID, projectID string) error {
+	events, err := s.events.LoadByRun(ctx, runID)
+	if err != nil {
+		return fmt.Errorf("load events: %w", err)
+	}
+	if len(events) > 0 {
+		existing, err := s.findArchive(ctx, runID)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			data, err := event.EncodeArchive(events)
+			if err != nil {
+				return err
+			}
+			art := &artifact.Artifact{
+				RunID:       runID,
+				ProjectID:   projectID,
+				Kind:        artifact.KindEventArchive,
+				Name:        event.ArchiveName,
+				ContentType: event.ArchiveContentType,
+				Data:        data,
+				Metadata:    map[string]string{"events": strconv.Itoa(len(events))},
+			}
+			if err := s.store.CreateArtifact(ctx, art); err != nil {
+				return fmt.Errorf("store archive: %w", err)
+			}
+		}
+		if _, err := s.events.DeleteByRun(ctx, runID); err != nil {
+			return err
+		}
+	}
+	return s.store.SetRunEventsArchived(ctx, runID)
+}
+
+// LoadRunEvents returns the events of a run, reading them from the run's
+// archive artifact once they have been compacted.
+func (s *RetentionService) LoadRunEvents(ctx context.Context, runID string) ([]event.AgentEvent, error) {
+	r, err := s.store.GetRun(ctx, runID)
+	if err != nil {
+		return nil, fmt.Errorf("get run: %w", err)
+	}
+	if r.EventsArchivedAt == nil {
+		return s.events.LoadByRun(ctx, runID)
+	}
+	meta, err := s.findArchive(ctx, runID)
+	if err != nil || meta == nil {
+		// Runs without events are marked archived without an artifact
+		return nil, err
+	}
+	art, err := s.store.GetArtifact(ctx, meta.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get archive: %w", err)
+	}
+	return event.DecodeArchive(art.Data)
+}
+
+// findArchive returns the metadata of the run's event archive, or nil.
+func (s *RetentionService) findArchive(ctx context.Context, runID string) (*artifact.Artifact, error) {
+	arts, err := s.store.ListArtifactsByRun(ctx, runID)
+	if err != nil {
+		return nil, fmt.Errorf("list artifacts: %w", err)
+	}
+	for i := range arts {
+		if arts[i].Kind == artifact.KindEventArchive {
+			return &arts[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// StartCompactor runs Compact every configured interval until cancelled.
+// It is a no-op when the retention window is disabled.
+func (s *RetentionService) StartCompactor(ctx context.Context) (cancel func()) {
+	ctx, cancel = context.WithCancel(ctx)
+	if s.cfg.EventWindow <= 0 || s.cfg.Interval <= 0 {
+		return cancel
+	}
+	go func() {
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.Compact(ctx); err != nil {
+					slog.Error("event compaction failed", "error", err)
+				}
+			}
+		}
+	}()
+	return cancel
+}
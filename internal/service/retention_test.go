@@ -0,0 +1,100 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func newRetentionTestEnv(t *testing.T) (*service.RetentionService, *runtimeMockStore, *runtimeMockEventStore) {
+	t.Helper()
+	old := time.Now().Add(-48 * time.Hour)
+	recent := time.Now().Add(-time.Hour)
+	store := &runtimeMockStore{
+		projects: []project.Project{
+			{ID: "proj-1", Config: map[string]string{}},
+			{ID: "proj-keep", Config: map[string]string{event.ConfigKeyRetention: "0s"}},
+		},
+		runs: []run.Run{
+			{ID: "run-old", ProjectID: "proj-1", Status: run.StatusCompleted, CompletedAt: &old},
+			{ID: "run-recent", ProjectID: "proj-1", Status: run.StatusCompleted, CompletedAt: &recent},
+			{ID: "run-keep", ProjectID: "proj-keep", Status: run.StatusCompleted, CompletedAt: &old},
+			{ID: "run-active", ProjectID: "proj-1", Status: run.StatusRunning},
+		},
+	}
+	es := &runtimeMockEventStore{}
+	for _, id := range []string{"run-old", "run-recent", "run-keep", "run-active"} {
+		for i := 1; i <= 2; i++ {
+			_ = es.Append(context.Background(), &event.AgentEvent{
+				ID: id + "-" + strconv.Itoa(i), RunID: id, Type: event.TypeToolCalled,
+				Payload: json.RawMessage(`{"tool":"Read"}`), Version: i,
+			})
+		}
+	}
+	cfg := config.Retention{EventWindow: 24 * time.Hour, Interval: time.Hour, BatchSize: 10}
+	return service.NewRetentionService(store, es, cfg), store, es
+}
+
+func TestRetentionService_CompactArchivesOldRuns(t *testing.T) {
+	svc, store, es := newRetentionTestEnv(t)
+	ctx := context.Background()
+
+	n, err := svc.Compact(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 archived run, got %d", n)
+	}
+	if left, _ := es.LoadByRun(ctx, "run-old"); len(left) != 0 {
+		t.Fatalf("expected archived events to be removed, %d left", len(left))
+	}
+	for _, id := range []string{"run-recent", "run-keep", "run-active"} {
+		if left, _ := es.LoadByRun(ctx, id); len(left) != 2 {
+			t.Fatalf("expected events of %s to be kept, got %d", id, len(left))
+		}
+	}
+	arts, _ := store.ListArtifactsByRun(ctx, "run-old")
+	if len(arts) != 1 || arts[0].Kind != artifact.KindEventArchive {
+		t.Fatalf("expected one event archive artifact, got %+v", arts)
+	}
+
+	// Archived events are restored transparently
+	events, err := svc.LoadRunEvents(ctx, "run-old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].ID != "run-old-1" || string(events[1].Payload) != `{"tool":"Read"}` {
+		t.Fatalf("unexpected restored events %+v", events)
+	}
+
+	// A second pass has nothing left to do
+	if n, _ := svc.Compact(ctx); n != 0 {
+		t.Fatalf("expected no runs on second pass, got %d", n)
+	}
+}
+
+func TestRetentionService_ReusesArchiveAfterInterruptedPass(t *testing.T) {
+	svc, store, _ := newRetentionTestEnv(t)
+	ctx := context.Background()
+
+	data, _ := event.EncodeArchive([]event.AgentEvent{{ID: "run-old-1", RunID: "run-old"}})
+	_ = store.CreateArtifact(ctx, &artifact.Artifact{RunID: "run-old", ProjectID: "proj-1", Kind: artifact.KindEventArchive, Name: event.ArchiveName, Data: data})
+
+	if _, err := svc.Compact(ctx); err != nil {
+		t.Fatal(err)
+	}
+	arts, _ := store.ListArtifactsByRun(ctx, "run-old")
+	if len(arts) != 1 {
+		t.Fatalf("expected existing archive to be reused, got %d artifacts", len(arts))
+	}
+}
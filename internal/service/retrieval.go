@@ -0,0 +1,563 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+	"github.com/Strob0t/CodeForge/internal/port/broadcast"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/embedding"
+	"github.com/Strob0t/CodeForge/internal/port/reranker"
+)
+
+// RetrievalService indexes project workspaces as embedded text chunks and
+// answers similarity searches over them. The embedding provider and model
+// come from the retrieval config, overridden per project by the
+// embedding.* config keys.
+type RetrievalService struct {
+	store     database.Store
+	cfg       *config.Retrieval
+	providers map[string]embedding.Provider
+	rerankers map[string]reranker.Provider
+	knowledge *KnowledgeService
+	hub       broadcast.Broadcaster
+
+	migMu      sync.Mutex
+	migrations map[string]*indexMigration // Running or last migration by project ID
+}
+
+// NewRetrievalService creates a RetrievalService with the given embedding
+// providers, looked up by name.
+func NewRetrievalService(store database.Store, cfg *config.Retrieval, providers ...embedding.Provider) *RetrievalService {
+	s := &RetrievalService{
+		store:      store,
+		cfg:        cfg,
+		providers:  make(map[string]embedding.Provider, len(providers)),
+		migrations: make(map[string]*indexMigration),
+	}
+	for _, p := range providers {
+		s.providers[p.Name()] = p
+	}
+	return s
+}
+
+// SetRerankers sets the cross-encoder providers, looked up by name, that
+// rerank search results when retrieval.rerank_provider names one.
+func (s *RetrievalService) SetRerankers(providers ...reranker.Provider) {
+	s.rerankers = make(map[string]reranker.Provider, len(providers))
+	for _, p := range providers {
+		s.rerankers[p.Name()] = p
+	}
+}
+
+// SetKnowledgeService adds the chunks of the knowledge bases attached to a
+// project to its searches.
+func (s *RetrievalService) SetKnowledgeService(knowledge *KnowledgeService) {
+	s.knowledge = knowledge
+}
+
+// SetBroadcaster sets the hub that index migrations report progress to.
+func (s *RetrievalService) SetBroadcaster(hub broadcast.Broadcaster) {
+	s.hub = hub
+}
+
+// DefaultEmbedding returns the service-wide embedding settings, which
+// embed the chunks of knowledge bases.
+func (s *RetrievalService) DefaultEmbedding() (retrieval.Embedding, error) {
+	e := retrieval.Embedding{
+		Provider:   s.cfg.EmbeddingProvider,
+		Model:      s.cfg.EmbeddingModel,
+		Dimensions: s.cfg.Dimensions,
+	}
+	return e, e.Validate()
+}
+
+// Embedding returns the embedding settings of a project.
+func (s *RetrievalService) Embedding(p *project.Project) (retrieval.Embedding, error) {
+	e, err := retrieval.Embedding{
+		Provider:   s.cfg.EmbeddingProvider,
+		Model:      s.cfg.EmbeddingModel,
+		Dimensions: s.cfg.Dimensions,
+	}.Override(p.Config)
+	if err != nil {
+		return e, err
+	}
+	return e, e.Validate()
+}
+
+// Status returns the index of a project.
+func (s *RetrievalService) Status(ctx context.Context, projectID string) (*retrieval.Index, error) {
+	return s.store.GetRetrievalIndex(ctx, projectID)
+}
+
+// Index chunks the text files of a project's workspace, embeds the chunks
+// in batches and replaces the project's index. Projects with golden
+// queries are evaluated against the new index (see Evaluate).
+func (s *RetrievalService) Index(ctx context.Context, projectID string) (*retrieval.Index, error) {
+	p, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+	if p.WorkspacePath == "" {
+		return nil, fmt.Errorf("project %s has no workspace (not cloned)", projectID)
+	}
+	e, err := s.Embedding(p)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := s.provider(e.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, chunks, err := s.buildIndex(ctx, p, provider, e, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.ReplaceRetrievalIndex(ctx, idx, chunks); err != nil {
+		return nil, err
+	}
+	slog.Info("retrieval index built",
+		"project_id", projectID,
+		"provider", e.Provider,
+		"model", e.Model,
+		"dimensions", idx.Dimensions,
+		"files", idx.Files,
+		"chunks", idx.Chunks,
+	)
+	s.evaluateAfterIndex(ctx, projectID)
+	return idx, nil
+}
+
+// buildIndex chunks the text files of a project's workspace and embeds the
+// chunks with e. progress, if set, is called with the chunks embedded and
+// their total after each batch.
+func (s *RetrievalService) buildIndex(ctx context.Context, p *project.Project, provider embedding.Provider, e retrieval.Embedding, progress func(done, total int)) (*retrieval.Index, []retrieval.Chunk, error) {
+	files, chunks, err := s.chunkWorkspace(p.WorkspacePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	texts := make([]string, len(chunks))
+	for i := range chunks {
+		texts[i] = chunks[i].Content
+	}
+	var batch func(int)
+	if progress != nil {
+		batch = func(done int) { progress(done, len(texts)) }
+	}
+	vecs, dims, err := s.embed(ctx, provider, e, texts, batch)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range chunks {
+		chunks[i].Embedding = vecs[i]
+	}
+	return &retrieval.Index{
+		ProjectID:  p.ID,
+		Provider:   e.Provider,
+		Model:      e.Model,
+		Dimensions: dims,
+		Files:      files,
+		Chunks:     len(chunks),
+	}, chunks, nil
+}
+
+// Refresh re-chunks and re-embeds the given files (slash-separated, relative
+// to the workspace) in a project's index, e.g. after they were edited;
+// deleted files leave the index. Projects without an index are left
+// alone and return nil. While the index is being migrated, the files are
+// refreshed in the staged index too.
+func (s *RetrievalService) Refresh(ctx context.Context, projectID string, paths []string) (*retrieval.Index, error) {
+	// Refreshes wait for a migration's switch, so they never write an index
+	// it just replaced.
+	mig := s.runningMigration(projectID)
+	if mig != nil {
+		mig.write.Lock()
+		defer mig.write.Unlock()
+	}
+	idx, err := s.store.GetRetrievalIndex(ctx, projectID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get retrieval index: %w", err)
+	}
+	p, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+	if mig != nil {
+		s.dualWrite(ctx, p, mig, paths)
+	}
+	e, err := s.Embedding(p)
+	if err != nil {
+		return nil, err
+	}
+	if !idx.Matches(e) {
+		return nil, fmt.Errorf("index of project %s was built with %s/%s, reindex it", projectID, idx.Provider, idx.Model)
+	}
+	return s.refreshIndex(ctx, p, idx, paths)
+}
+
+// refreshIndex re-chunks and re-embeds files in the live or staged index
+// idx, with the embedding it was built with.
+func (s *RetrievalService) refreshIndex(ctx context.Context, p *project.Project, idx *retrieval.Index, paths []string) (*retrieval.Index, error) {
+	e := indexEmbedding(idx)
+	provider, err := s.provider(e.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshed := make(map[string]bool, len(paths))
+	var fresh []retrieval.Chunk
+	for _, rel := range paths {
+		refreshed[rel] = true
+		if !indexedPath(rel) {
+			continue
+		}
+		src, err := os.ReadFile(filepath.Join(p.WorkspacePath, filepath.FromSlash(rel)))
+		if err != nil || len(src) == 0 || len(src) > maxGraphFileSize || !isText(src) {
+			continue // Deleted, or no longer indexable
+		}
+		fresh = append(fresh, retrieval.Split(rel, string(src), s.cfg.ChunkLines, s.cfg.ChunkOverlap)...)
+	}
+	texts := make([]string, len(fresh))
+	for i := range fresh {
+		texts[i] = fresh[i].Content
+	}
+	vecs, _, err := s.embed(ctx, provider, e, texts, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range fresh {
+		fresh[i].Embedding = vecs[i]
+	}
+
+	old, err := s.indexChunks(ctx, idx)
+	if err != nil {
+		return nil, err
+	}
+	chunks := make([]retrieval.Chunk, 0, len(old)+len(fresh))
+	files := make(map[string]bool)
+	for i := range old {
+		if !refreshed[old[i].Path] {
+			chunks = append(chunks, old[i])
+			files[old[i].Path] = true
+		}
+	}
+	for i := range fresh {
+		chunks = append(chunks, fresh[i])
+		files[fresh[i].Path] = true
+	}
+	idx.Files, idx.Chunks = len(files), len(chunks)
+	if err := s.store.ReplaceRetrievalIndex(ctx, idx, chunks); err != nil {
+		return nil, err
+	}
+	slog.Info("retrieval index refreshed", "project_id", idx.ProjectID, "staged", idx.Staged, "files", len(paths), "chunks", len(fresh))
+	return idx, nil
+}
+
+// indexEmbedding returns the embedding settings an index was built with.
+func indexEmbedding(idx *retrieval.Index) retrieval.Embedding {
+	return retrieval.Embedding{Provider: idx.Provider, Model: idx.Model, Dimensions: idx.Dimensions}
+}
+
+// indexChunks returns the chunks of the live or staged index idx.
+func (s *RetrievalService) indexChunks(ctx context.Context, idx *retrieval.Index) ([]retrieval.Chunk, error) {
+	if idx.Staged {
+		return s.store.ListStagedRetrievalChunks(ctx, idx.ProjectID)
+	}
+	return s.store.ListRetrievalChunks(ctx, idx.ProjectID)
+}
+
+// Search embeds a query with the project's embedding settings and returns
+// the most similar chunks of its index and of the knowledge bases attached
+// to it. The settings must match those the index was built with, since
+// vectors of different models are not comparable; knowledge bases embedded
+// differently are left out. With a reranker configured, the best
+// candidates are reranked (see rerank) unless the request opts out.
+func (s *RetrievalService) Search(ctx context.Context, projectID string, req *retrieval.SearchRequest) ([]retrieval.Result, error) {
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, retrieval.ErrQueryRequired
+	}
+	p, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+	idx, err := s.store.GetRetrievalIndex(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %w", retrieval.ErrNotIndexed, err)
+		}
+		return nil, err
+	}
+	e, err := s.Embedding(p)
+	if err != nil {
+		return nil, err
+	}
+	if !idx.Matches(e) {
+		return nil, fmt.Errorf("index was built with %s/%s, project uses %s/%s; reindex: %w",
+			idx.Provider, idx.Model, e.Provider, e.Model, domain.ErrConflict)
+	}
+	return s.searchIndex(ctx, p, idx, req)
+}
+
+// searchIndex answers a search over the live or staged index idx and the
+// knowledge bases embedded like it. The query is embedded with the
+// provider and model idx was built with.
+func (s *RetrievalService) searchIndex(ctx context.Context, p *project.Project, idx *retrieval.Index, req *retrieval.SearchRequest) ([]retrieval.Result, error) {
+	vec, err := s.embedQuery(ctx, idx.Provider, idx.Model, req.Query)
+	if err != nil {
+		return nil, err
+	}
+	query, err := retrieval.Fit(vec, idx.Dimensions)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := s.indexChunks(ctx, idx)
+	if err != nil {
+		return nil, err
+	}
+	if s.knowledge != nil {
+		kc, err := s.knowledge.Chunks(ctx, p, idx)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, kc...)
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	if s.cfg.RerankProvider == "" || (req.Rerank != nil && !*req.Rerank) {
+		return retrieval.Rank(query, chunks, limit), nil
+	}
+	candidates := retrieval.Rank(query, chunks, max(limit, s.cfg.RerankTopN))
+	return s.rerank(ctx, slog.String("project_id", p.ID), req.Query, candidates, limit), nil
+}
+
+// embedQuery embeds a search query with a provider and model. The vector
+// has the model's size; callers fit it to the dimensions of the index.
+func (s *RetrievalService) embedQuery(ctx context.Context, providerName, model, query string) ([]float32, error) {
+	provider, err := s.provider(providerName)
+	if err != nil {
+		return nil, err
+	}
+	vecs, err := provider.Embed(ctx, model, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embed query: %w", err)
+	}
+	if len(vecs) != 1 {
+		return nil, fmt.Errorf("embed query: got %d vectors", len(vecs))
+	}
+	return vecs[0], nil
+}
+
+// rerank scores candidates with the configured cross-encoder within the
+// latency budget and returns the best limit of them by rerank score. A
+// failed or late rerank keeps the embedding order. The scores before and
+// after, and how many results the rerank promoted into the best limit, are
+// logged with the searched project or scope as owner.
+func (s *RetrievalService) rerank(ctx context.Context, owner slog.Attr, query string, candidates []retrieval.Result, limit int) []retrieval.Result {
+	fallback := candidates[:min(limit, len(candidates))]
+	if len(candidates) == 0 {
+		return fallback
+	}
+	provider, ok := s.rerankers[s.cfg.RerankProvider]
+	if !ok {
+		slog.Warn("unknown rerank provider, keeping embedding order", "provider", s.cfg.RerankProvider)
+		return fallback
+	}
+	docs := make([]string, len(candidates))
+	for i := range candidates {
+		c := &candidates[i]
+		docs[i] = c.Path + "\n" + c.Content
+		if c.Title != "" {
+			docs[i] = c.Title + "\n" + c.Content
+		}
+	}
+
+	rctx, cancel := context.WithTimeout(ctx, s.cfg.RerankTimeout)
+	defer cancel()
+	start := time.Now()
+	scores, err := provider.Rerank(rctx, s.cfg.RerankModel, query, docs)
+	latency := time.Since(start)
+	if err == nil && len(scores) != len(candidates) {
+		err = fmt.Errorf("got %d scores for %d candidates", len(scores), len(candidates))
+	}
+	if err != nil {
+		slog.Warn("rerank failed, keeping embedding order",
+			owner, "provider", provider.Name(), "latency_ms", latency.Milliseconds(), "error", err)
+		return fallback
+	}
+
+	results := retrieval.Rerank(candidates, scores, s.cfg.RerankMinScore, limit)
+	before := make(map[string]bool, len(fallback))
+	for i := range fallback {
+		before[fallback[i].ID] = true
+	}
+	promoted := 0
+	var topAfter float64
+	for i := range results {
+		if !before[results[i].ID] {
+			promoted++
+		}
+	}
+	if len(results) > 0 {
+		topAfter = *results[0].RerankScore
+	}
+	slog.Info("retrieval reranked",
+		owner,
+		"provider", provider.Name(),
+		"candidates", len(candidates),
+		"kept", len(results),
+		"promoted", promoted,
+		"top_score_before", fallback[0].Score,
+		"top_score_after", topAfter,
+		"latency_ms", latency.Milliseconds(),
+	)
+	return results
+}
+
+// EmbedTexts embeds texts with a project's embedding settings, so other
+// services can store vectors comparable with its queries. The returned
+// settings carry the dimensions of the vectors.
+func (s *RetrievalService) EmbedTexts(ctx context.Context, p *project.Project, texts []string) (retrieval.Embedding, [][]float32, error) {
+	e, err := s.Embedding(p)
+	if err != nil {
+		return e, nil, err
+	}
+	return s.EmbedWith(ctx, e, texts)
+}
+
+// EmbedWith embeds texts with the embedding settings e. The returned
+// settings carry the dimensions of the vectors.
+func (s *RetrievalService) EmbedWith(ctx context.Context, e retrieval.Embedding, texts []string) (retrieval.Embedding, [][]float32, error) {
+	provider, err := s.provider(e.Provider)
+	if err != nil {
+		return e, nil, err
+	}
+	vecs, dims, err := s.embed(ctx, provider, e, texts, nil)
+	if err != nil {
+		return e, nil, err
+	}
+	e.Dimensions = dims
+	return e, vecs, nil
+}
+
+func (s *RetrievalService) provider(name string) (embedding.Provider, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown embedding provider %q", name)
+	}
+	return p, nil
+}
+
+// embed embeds texts in batches of the configured size and fits every
+// vector to e.Dimensions. Without configured dimensions the first vector
+// sets them, and a model returning vectors of varying size is an error.
+// progress, if set, is called with the number of texts embedded after
+// each batch.
+func (s *RetrievalService) embed(ctx context.Context, provider embedding.Provider, e retrieval.Embedding, texts []string, progress func(done int)) ([][]float32, int, error) {
+	dims := e.Dimensions
+	out := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += s.cfg.BatchSize {
+		batch := texts[start:min(start+s.cfg.BatchSize, len(texts))]
+		vecs, err := provider.Embed(ctx, e.Model, batch)
+		if err != nil {
+			return nil, 0, fmt.Errorf("embed chunks %d-%d: %w", start, start+len(batch)-1, err)
+		}
+		if len(vecs) != len(batch) {
+			return nil, 0, fmt.Errorf("embed chunks %d-%d: got %d vectors", start, start+len(batch)-1, len(vecs))
+		}
+		for _, v := range vecs {
+			if dims == 0 {
+				dims = len(v)
+			}
+			if v, err = retrieval.Fit(v, dims); err != nil {
+				return nil, 0, err
+			}
+			out = append(out, v)
+		}
+		if progress != nil {
+			progress(len(out))
+		}
+	}
+	return out, dims, nil
+}
+
+// chunkWorkspace splits the text files below root into chunks. Hidden and
+// dependency directories, large files and binary files are skipped.
+func (s *RetrievalService) chunkWorkspace(root string) (int, []retrieval.Chunk, error) {
+	var files int
+	var chunks []retrieval.Chunk
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Unreadable entries are skipped
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if p != root && (strings.HasPrefix(name, ".") || graphSkipDirs[name]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if files >= s.cfg.MaxFiles {
+			return fs.SkipAll
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() == 0 || info.Size() > maxGraphFileSize {
+			return nil
+		}
+		src, err := os.ReadFile(p)
+		if err != nil || !isText(src) {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, p)
+		files++
+		chunks = append(chunks, retrieval.Split(filepath.ToSlash(rel), string(src), s.cfg.ChunkLines, s.cfg.ChunkOverlap)...)
+		return nil
+	})
+	if err != nil {
+		return 0, nil, fmt.Errorf("scan workspace: %w", err)
+	}
+	if files >= s.cfg.MaxFiles {
+		slog.Warn("retrieval index truncated", "root", root, "files", s.cfg.MaxFiles)
+	}
+	return files, chunks, nil
+}
+
+// indexedPath reports whether chunkWorkspace indexes files at the
+// slash-separated path rel: none of its directories is hidden or a
+// dependency directory.
+func indexedPath(rel string) bool {
+	dirs := strings.Split(rel, "/")
+	for _, d := range dirs[:len(dirs)-1] {
+		if strings.HasPrefix(d, ".") || graphSkipDirs[d] {
+			return false
+		}
+	}
+	return true
+}
+
+// isText reports whether src looks like text: no NUL byte in its first
+// 8000 bytes, as git decides.
+func isText(src []byte) bool {
+	return bytes.IndexByte(src[:min(len(src), 8000)], 0) < 0
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+)
+
+// maxEvalK caps the results scored per golden query.
+const maxEvalK = 100
+
+// AddGoldenQuery stores a golden query for a project.
+func (s *RetrievalService) AddGoldenQuery(ctx context.Context, projectID string, q *retrieval.GoldenQuery) error {
+	if err := q.Validate(); err != nil {
+		return err
+	}
+	if _, err := s.store.GetProject(ctx, projectID); err != nil {
+		return fmt.Errorf("get project: %w", err)
+	}
+	q.ProjectID = projectID
+	return s.store.CreateGoldenQuery(ctx, q)
+}
+
+// GoldenQueries returns the golden queries of a project.
+func (s *RetrievalService) GoldenQueries(ctx context.Context, projectID string) ([]retrieval.GoldenQuery, error) {
+	return s.store.ListGoldenQueries(ctx, projectID)
+}
+
+// DeleteGoldenQuery deletes a golden query.
+func (s *RetrievalService) DeleteGoldenQuery(ctx context.Context, id string) error {
+	return s.store.DeleteGoldenQuery(ctx, id)
+}
+
+// Evaluate runs the golden queries of a project against its index, scoring
+// the top k results of each (DefaultEvalK for 0), and stores the
+// evaluation with its changes against the previous one. Queries are
+// searched like agents search, reranked when a reranker is configured.
+func (s *RetrievalService) Evaluate(ctx context.Context, projectID string, k int, trigger string) (*retrieval.Eval, error) {
+	queries, err := s.store.ListGoldenQueries(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(queries) == 0 {
+		return nil, retrieval.ErrNoGoldenQueries
+	}
+	p, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+	idx, err := s.store.GetRetrievalIndex(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %w", retrieval.ErrNotIndexed, err)
+		}
+		return nil, err
+	}
+	e, err := s.Embedding(p)
+	if err != nil {
+		return nil, err
+	}
+	if !idx.Matches(e) {
+		return nil, fmt.Errorf("index was built with %s/%s, project uses %s/%s; reindex: %w",
+			idx.Provider, idx.Model, e.Provider, e.Model, domain.ErrConflict)
+	}
+	return s.evaluate(ctx, p, idx, queries, k, trigger)
+}
+
+// evaluate runs golden queries against the live or staged index idx and
+// stores the evaluation.
+func (s *RetrievalService) evaluate(ctx context.Context, p *project.Project, idx *retrieval.Index, queries []retrieval.GoldenQuery, k int, trigger string) (*retrieval.Eval, error) {
+	if k <= 0 {
+		k = retrieval.DefaultEvalK
+	}
+	k = min(k, maxEvalK)
+	projectID := p.ID
+	e := &retrieval.Eval{
+		ProjectID:    projectID,
+		Trigger:      trigger,
+		K:            k,
+		Provider:     idx.Provider,
+		Model:        idx.Model,
+		Dimensions:   idx.Dimensions,
+		Chunks:       idx.Chunks,
+		ChunkLines:   s.cfg.ChunkLines,
+		ChunkOverlap: s.cfg.ChunkOverlap,
+		Queries:      make([]retrieval.QueryScore, 0, len(queries)),
+	}
+	for i := range queries {
+		results, err := s.searchIndex(ctx, p, idx, &retrieval.SearchRequest{Query: queries[i].Query, Limit: k})
+		if err != nil {
+			return nil, fmt.Errorf("golden query %s: %w", queries[i].ID, err)
+		}
+		e.Queries = append(e.Queries, retrieval.Score(&queries[i], results, k))
+	}
+	e.Summarize()
+
+	prev, err := s.store.ListRetrievalEvals(ctx, projectID, 1)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.CreateRetrievalEval(ctx, e); err != nil {
+		return nil, err
+	}
+	attrs := []any{"project_id", projectID, "trigger", trigger, "k", k, "queries", len(queries), "recall", e.Recall, "mrr", e.MRR}
+	if len(prev) > 0 {
+		e.Compare(&prev[0])
+		attrs = append(attrs, "recall_change", *e.RecallChange, "mrr_change", *e.MRRChange)
+	}
+	if e.RecallChange != nil && *e.RecallChange < 0 {
+		slog.Warn("retrieval quality dropped", attrs...)
+	} else {
+		slog.Info("retrieval evaluated", attrs...)
+	}
+	return e, nil
+}
+
+// Evals returns the latest limit evaluations of a project, newest first,
+// each with its changes against the one before.
+func (s *RetrievalService) Evals(ctx context.Context, projectID string, limit int) ([]retrieval.Eval, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	// One more to compare the oldest returned evaluation against.
+	evals, err := s.store.ListRetrievalEvals(ctx, projectID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	retrieval.Trend(evals)
+	return evals[:min(limit, len(evals))], nil
+}
+
+// evaluateAfterIndex evaluates a freshly indexed project if it has golden
+// queries. Failures are logged; they do not fail the index.
+func (s *RetrievalService) evaluateAfterIndex(ctx context.Context, projectID string) {
+	if _, err := s.Evaluate(ctx, projectID, 0, retrieval.EvalReindex); err != nil && !errors.Is(err, retrieval.ErrNoGoldenQueries) {
+		slog.Warn("retrieval evaluation after reindex failed", "project_id", projectID, "error", err)
+	}
+}
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/ws"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+	"github.com/Strob0t/CodeForge/internal/port/embedding"
+)
+
+// indexMigration tracks the migration of a project's index.
+type indexMigration struct {
+	mu sync.Mutex // Guards the fields below
+	m  retrieval.Migration
+	// staged is set once the staged index exists; refreshes before that
+	// collect their files in dirty to be replayed into it.
+	staged bool
+	dirty  []string
+
+	// write serializes refreshes with replaying dirty files and the switch.
+	write sync.Mutex
+}
+
+// snapshot returns a copy of the migration state.
+func (im *indexMigration) snapshot() retrieval.Migration {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	return im.m
+}
+
+// update changes the migration state, setting its finish time once done.
+func (im *indexMigration) update(fn func(m *retrieval.Migration)) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	fn(&im.m)
+	if im.m.Done() && im.m.FinishedAt == nil {
+		now := time.Now().UTC()
+		im.m.FinishedAt = &now
+	}
+}
+
+// MigrateIndex moves a project's index to another embedding without
+// taking it offline. The new index is built as a staged index in the
+// background while searches keep using the live one, and refreshed files
+// are written to both. If the project has golden queries, both indexes are
+// evaluated and the switch is rejected when recall@k drops by more than
+// the request allows. On the switch the project's embedding config and the
+// live index change together and the old index is deleted. Progress is
+// broadcast as retrieval.migration events; the returned state is the
+// start of it.
+func (s *RetrievalService) MigrateIndex(ctx context.Context, projectID string, req *retrieval.MigrateRequest) (*retrieval.Migration, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	p, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+	idx, err := s.store.GetRetrievalIndex(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %w", retrieval.ErrNotIndexed, err)
+		}
+		return nil, err
+	}
+	to := req.Embedding
+	if to.Provider == "" && to.Model == "" {
+		to, err = s.Embedding(p)
+	} else {
+		err = to.Validate()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if idx.Matches(to) {
+		return nil, fmt.Errorf("%w: %s/%s", retrieval.ErrSameEmbedding, to.Provider, to.Model)
+	}
+	provider, err := s.provider(to.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	mig := &indexMigration{m: retrieval.Migration{
+		ProjectID: projectID,
+		From:      indexEmbedding(idx),
+		To:        to,
+		Phase:     retrieval.MigrationBuilding,
+		StartedAt: time.Now().UTC(),
+	}}
+	s.migMu.Lock()
+	if cur := s.migrations[projectID]; cur != nil {
+		if m := cur.snapshot(); !m.Done() {
+			s.migMu.Unlock()
+			return nil, fmt.Errorf("%w: to %s/%s", retrieval.ErrMigrationRunning, m.To.Provider, m.To.Model)
+		}
+	}
+	s.migrations[projectID] = mig
+	s.migMu.Unlock()
+
+	slog.Info("retrieval index migration started", "project_id", projectID,
+		"from", idx.Provider+"/"+idx.Model, "to", to.Provider+"/"+to.Model)
+	s.broadcastMigration(ctx, mig)
+	go s.runMigration(context.WithoutCancel(ctx), p, provider, mig, req)
+	m := mig.snapshot()
+	return &m, nil
+}
+
+// Migration returns the running or last index migration of a project.
+func (s *RetrievalService) Migration(projectID string) (*retrieval.Migration, error) {
+	s.migMu.Lock()
+	mig := s.migrations[projectID]
+	s.migMu.Unlock()
+	if mig == nil {
+		return nil, fmt.Errorf("index migration of project %s: %w", projectID, domain.ErrNotFound)
+	}
+	m := mig.snapshot()
+	return &m, nil
+}
+
+// runningMigration returns the migration running for a project, or nil.
+func (s *RetrievalService) runningMigration(projectID string) *indexMigration {
+	s.migMu.Lock()
+	defer s.migMu.Unlock()
+	mig := s.migrations[projectID]
+	if mig == nil {
+		return nil
+	}
+	if m := mig.snapshot(); m.Done() {
+		return nil
+	}
+	return mig
+}
+
+// runMigration drives a migration to its end; a failure deletes the
+// staged index and leaves the live one in place.
+func (s *RetrievalService) runMigration(ctx context.Context, p *project.Project, provider embedding.Provider, mig *indexMigration, req *retrieval.MigrateRequest) {
+	if err := s.migrate(ctx, p, provider, mig, req); err != nil {
+		if delErr := s.store.DeleteStagedRetrievalIndex(ctx, p.ID); delErr != nil {
+			slog.Warn("delete staged retrieval index failed", "project_id", p.ID, "error", delErr)
+		}
+		mig.update(func(m *retrieval.Migration) {
+			m.Phase = retrieval.MigrationFailed
+			m.Error = err.Error()
+		})
+	}
+	s.broadcastMigration(ctx, mig)
+	m := mig.snapshot()
+	attrs := []any{"project_id", p.ID, "phase", m.Phase, "to", m.To.Provider + "/" + m.To.Model}
+	if m.Before != nil && m.After != nil {
+		attrs = append(attrs, "recall_before", m.Before.Recall, "recall_after", m.After.Recall)
+	}
+	if m.Phase == retrieval.MigrationFailed {
+		slog.Error("retrieval index migration failed", append(attrs, "error", m.Error)...)
+		return
+	}
+	slog.Info("retrieval index migration finished", attrs...)
+}
+
+// migrate builds, evaluates and switches to the staged index.
+func (s *RetrievalService) migrate(ctx context.Context, p *project.Project, provider embedding.Provider, mig *indexMigration, req *retrieval.MigrateRequest) error {
+	to := mig.snapshot().To
+	staged, chunks, err := s.buildIndex(ctx, p, provider, to, func(done, total int) {
+		mig.update(func(m *retrieval.Migration) { m.Embedded, m.Chunks = done, total })
+		s.broadcastMigration(ctx, mig)
+	})
+	if err != nil {
+		return err
+	}
+	staged.Staged = true
+	if err := s.store.ReplaceRetrievalIndex(ctx, staged, chunks); err != nil {
+		return err
+	}
+	if err := s.replayDirty(ctx, p, mig, staged); err != nil {
+		return err
+	}
+
+	queries, err := s.store.ListGoldenQueries(ctx, p.ID)
+	if err != nil {
+		return err
+	}
+	if len(queries) > 0 {
+		mig.update(func(m *retrieval.Migration) { m.Phase = retrieval.MigrationEvaluating })
+		s.broadcastMigration(ctx, mig)
+		live, err := s.store.GetRetrievalIndex(ctx, p.ID)
+		if err != nil {
+			return err
+		}
+		before, err := s.evaluate(ctx, p, live, queries, 0, retrieval.EvalMigration)
+		if err != nil {
+			return fmt.Errorf("evaluate live index: %w", err)
+		}
+		if staged, err = s.store.GetStagedRetrievalIndex(ctx, p.ID); err != nil {
+			return err
+		}
+		after, err := s.evaluate(ctx, p, staged, queries, 0, retrieval.EvalMigration)
+		if err != nil {
+			return fmt.Errorf("evaluate staged index: %w", err)
+		}
+		mig.update(func(m *retrieval.Migration) { m.Before, m.After = before, after })
+		if m := mig.snapshot(); !req.Force && !m.Accept(req.MaxRecallDrop) {
+			if err := s.store.DeleteStagedRetrievalIndex(ctx, p.ID); err != nil {
+				return err
+			}
+			mig.update(func(m *retrieval.Migration) {
+				m.Phase = retrieval.MigrationRejected
+				m.Error = fmt.Sprintf("recall@%d dropped from %.3f to %.3f", after.K, before.Recall, after.Recall)
+			})
+			return nil
+		}
+	}
+	return s.switchIndex(ctx, p.ID, mig)
+}
+
+// replayDirty refreshes the files edited while the staged index was built
+// in it, and sends later refreshes to it directly.
+func (s *RetrievalService) replayDirty(ctx context.Context, p *project.Project, mig *indexMigration, staged *retrieval.Index) error {
+	mig.write.Lock()
+	defer mig.write.Unlock()
+	mig.mu.Lock()
+	dirty := mig.dirty
+	mig.dirty, mig.staged = nil, true
+	mig.mu.Unlock()
+	if len(dirty) == 0 {
+		return nil
+	}
+	_, err := s.refreshIndex(ctx, p, staged, dirty)
+	return err
+}
+
+// dualWrite refreshes files in the staged index of a running migration,
+// or remembers them until it exists. The caller holds mig.write. A failed
+// refresh is logged; the live index is refreshed regardless.
+func (s *RetrievalService) dualWrite(ctx context.Context, p *project.Project, mig *indexMigration, paths []string) {
+	mig.mu.Lock()
+	if mig.m.Done() {
+		mig.mu.Unlock()
+		return
+	}
+	if !mig.staged {
+		mig.dirty = append(mig.dirty, paths...)
+		mig.mu.Unlock()
+		return
+	}
+	mig.mu.Unlock()
+
+	staged, err := s.store.GetStagedRetrievalIndex(ctx, p.ID)
+	if err == nil {
+		_, err = s.refreshIndex(ctx, p, staged, paths)
+	}
+	if err != nil {
+		slog.Warn("refresh of staged retrieval index failed", "project_id", p.ID, "error", err)
+	}
+}
+
+// switchIndex points the project's embedding config at the staged index
+// and promotes it to the live one, deleting the old. The config is
+// restored if the promotion fails.
+func (s *RetrievalService) switchIndex(ctx context.Context, projectID string, mig *indexMigration) error {
+	mig.write.Lock()
+	defer mig.write.Unlock()
+	p, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("get project: %w", err)
+	}
+	cfg := p.Config
+	p.Config = mig.snapshot().To.Apply(cfg)
+	if err := s.store.UpdateProject(ctx, p); err != nil {
+		return fmt.Errorf("update project embedding: %w", err)
+	}
+	if err := s.store.PromoteRetrievalIndex(ctx, projectID); err != nil {
+		p.Config = cfg
+		if restoreErr := s.store.UpdateProject(ctx, p); restoreErr != nil {
+			slog.Error("restore project embedding failed", "project_id", projectID, "error", restoreErr)
+		}
+		return fmt.Errorf("promote staged index: %w", err)
+	}
+	mig.update(func(m *retrieval.Migration) { m.Phase = retrieval.MigrationSwitched })
+	return nil
+}
+
+// broadcastMigration reports the state of a migration over the hub.
+func (s *RetrievalService) broadcastMigration(ctx context.Context, mig *indexMigration) {
+	if s.hub == nil {
+		return
+	}
+	m := mig.snapshot()
+	ev := ws.IndexMigrationEvent{
+		ProjectID: m.ProjectID,
+		Phase:     m.Phase,
+		Provider:  m.To.Provider,
+		Model:     m.To.Model,
+		Embedded:  m.Embedded,
+		Chunks:    m.Chunks,
+		Error:     m.Error,
+	}
+	if m.Before != nil {
+		ev.RecallBefore = &m.Before.Recall
+	}
+	if m.After != nil {
+		ev.RecallAfter = &m.After.Recall
+	}
+	s.hub.BroadcastEvent(ctx, ws.EventIndexMigration, ev)
+}
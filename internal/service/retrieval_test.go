@@ -0,0 +1,359 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+// fakeEmbedder embeds a text as counts of the words "alpha" and "beta",
+// padded to dims, and records its batch sizes.
+type fakeEmbedder struct {
+	name    string
+	dims    int
+	batches []int
+}
+
+func (f *fakeEmbedder) Name() string { return f.name }
+
+func (f *fakeEmbedder) Embed(_ context.Context, _ string, texts []string) ([][]float32, error) {
+	f.batches = append(f.batches, len(texts))
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		v := make([]float32, f.dims)
+		v[0] = float32(strings.Count(t, "alpha"))
+		v[1] = float32(strings.Count(t, "beta"))
+		v[2] = 0.01
+		out[i] = v
+	}
+	return out, nil
+}
+
+func newRetrievalTestEnv(t *testing.T) (*service.RetrievalService, *runtimeMockStore, *fakeEmbedder) {
+	t.Helper()
+	_, store, _, _ := newRuntimeTestEnv()
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":              "alpha\nalpha\n",
+		"b.md":              "beta\n",
+		"c.txt":             "alpha beta\n",
+		"node_modules/x.js": "alpha\n",
+		".git/HEAD":         "alpha\n",
+		"bin.dat":           "alpha\x00",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.projects[0].WorkspacePath = dir
+
+	ollama := &fakeEmbedder{name: "ollama", dims: 8}
+	svc := service.NewRetrievalService(store, &config.Retrieval{
+		EmbeddingProvider: "litellm",
+		EmbeddingModel:    "text-embedding-3-small",
+		BatchSize:         2,
+		ChunkLines:        60,
+		MaxFiles:          100,
+	}, &fakeEmbedder{name: "litellm", dims: 4}, ollama)
+	return svc, store, ollama
+}
+
+func TestRetrievalService_IndexPerProjectProvider(t *testing.T) {
+	svc, store, ollama := newRetrievalTestEnv(t)
+	store.projects[0].Config = map[string]string{
+		retrieval.ConfigKeyProvider:   "ollama",
+		retrieval.ConfigKeyModel:      "nomic-embed-text",
+		retrieval.ConfigKeyDimensions: "4",
+	}
+
+	idx, err := svc.Index(context.Background(), "proj-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx.Provider != "ollama" || idx.Model != "nomic-embed-text" || idx.Files != 3 || idx.Chunks != 3 || idx.Dimensions != 4 {
+		t.Fatalf("unexpected index %+v", idx)
+	}
+	if len(ollama.batches) != 2 || ollama.batches[0] != 2 || ollama.batches[1] != 1 {
+		t.Fatalf("expected batches of 2, got %v", ollama.batches)
+	}
+	for _, c := range store.chunks["proj-1"] {
+		if len(c.Embedding) != 4 {
+			t.Fatalf("expected vectors truncated to 4 dimensions, got %d", len(c.Embedding))
+		}
+	}
+
+	results, err := svc.Search(context.Background(), "proj-1", &retrieval.SearchRequest{Query: "beta", Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Path != "b.md" {
+		t.Fatalf("expected b.md as best match, got %+v", results)
+	}
+}
+
+func TestRetrievalService_SearchErrors(t *testing.T) {
+	svc, store, _ := newRetrievalTestEnv(t)
+	ctx := context.Background()
+
+	if _, err := svc.Search(ctx, "proj-1", &retrieval.SearchRequest{Query: "alpha"}); !errors.Is(err, retrieval.ErrNotIndexed) {
+		t.Fatalf("expected ErrNotIndexed, got %v", err)
+	}
+	if _, err := svc.Search(ctx, "proj-1", &retrieval.SearchRequest{Query: " "}); !errors.Is(err, retrieval.ErrQueryRequired) {
+		t.Fatalf("expected ErrQueryRequired, got %v", err)
+	}
+
+	if _, err := svc.Index(ctx, "proj-1"); err != nil {
+		t.Fatal(err)
+	}
+	store.projects[0].Config = map[string]string{retrieval.ConfigKeyProvider: "ollama"}
+	if _, err := svc.Search(ctx, "proj-1", &retrieval.SearchRequest{Query: "alpha"}); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("expected ErrConflict after switching provider, got %v", err)
+	}
+
+	store.projects[0].Config = map[string]string{retrieval.ConfigKeyProvider: "openai"}
+	if _, err := svc.Index(ctx, "proj-1"); err == nil {
+		t.Fatal("expected error for unknown provider")
+	}
+	store.projects[0].Config = map[string]string{retrieval.ConfigKeyDimensions: "16"}
+	if _, err := svc.Index(ctx, "proj-1"); !errors.Is(err, retrieval.ErrDimensionMismatch) {
+		t.Fatalf("expected ErrDimensionMismatch for dimensions above the model's, got %v", err)
+	}
+}
+
+func TestRetrievalService_Refresh(t *testing.T) {
+	svc, store, _ := newRetrievalTestEnv(t)
+	ctx := context.Background()
+	if idx, err := svc.Refresh(ctx, "proj-1", []string{"a.go"}); idx != nil || err != nil {
+		t.Fatalf("expected projects without an index left alone, got %+v, %v", idx, err)
+	}
+	if _, err := svc.Index(ctx, "proj-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := store.projects[0].WorkspacePath
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("beta\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "b.md")); err != nil {
+		t.Fatal(err)
+	}
+	idx, err := svc.Refresh(ctx, "proj-1", []string{"a.go", "b.md", "node_modules/x.js"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx.Files != 2 || idx.Chunks != 2 {
+		t.Fatalf("expected a.go and c.txt indexed, got %+v", idx)
+	}
+	results, err := svc.Search(ctx, "proj-1", &retrieval.SearchRequest{Query: "beta", Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Path != "a.go" {
+		t.Fatalf("expected the edited a.go as best match, got %+v", results)
+	}
+
+	store.projects[0].Config = map[string]string{retrieval.ConfigKeyProvider: "ollama"}
+	if _, err := svc.Refresh(ctx, "proj-1", []string{"a.go"}); err == nil {
+		t.Fatal("expected error after switching provider")
+	}
+}
+
+// fakeReranker scores a document by its count of "beta", or fails.
+type fakeReranker struct {
+	err   error
+	delay time.Duration
+}
+
+func (f *fakeReranker) Name() string { return "fake" }
+
+func (f *fakeReranker) Rerank(ctx context.Context, _, _ string, docs []string) ([]float64, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	select {
+	case <-time.After(f.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	scores := make([]float64, len(docs))
+	for i, d := range docs {
+		scores[i] = float64(strings.Count(d, "beta"))
+	}
+	return scores, nil
+}
+
+func TestRetrievalService_Rerank(t *testing.T) {
+	env, store, _ := newRetrievalTestEnv(t)
+	ctx := context.Background()
+	if _, err := env.Index(ctx, "proj-1"); err != nil {
+		t.Fatal(err)
+	}
+	rr := &fakeReranker{}
+	svc := service.NewRetrievalService(store, &config.Retrieval{
+		EmbeddingProvider: "litellm",
+		EmbeddingModel:    "text-embedding-3-small",
+		RerankProvider:    "fake",
+		RerankTopN:        3,
+		RerankMinScore:    0.5,
+		RerankTimeout:     100 * time.Millisecond,
+	}, &fakeEmbedder{name: "litellm", dims: 4})
+	svc.SetRerankers(rr)
+	search := func(rerank *bool) []retrieval.Result {
+		t.Helper()
+		results, err := svc.Search(ctx, "proj-1", &retrieval.SearchRequest{Query: "alpha", Limit: 2, Rerank: rerank})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return results
+	}
+
+	// a.go is the best embedding match, but the cross-encoder only likes
+	// the chunks mentioning beta; the rest falls below the threshold.
+	got := search(nil)
+	if len(got) != 2 || got[0].Path != "c.txt" || got[1].Path != "b.md" || got[0].RerankScore == nil || got[0].Score == 0 {
+		t.Fatalf("expected reranked results with both scores, got %+v", got)
+	}
+
+	off := false
+	if got := search(&off); len(got) != 2 || got[0].Path != "a.go" || got[0].RerankScore != nil {
+		t.Fatalf("expected embedding order without rerank, got %+v", got)
+	}
+
+	rr.err = errors.New("model unavailable")
+	if got := search(nil); len(got) != 2 || got[0].Path != "a.go" {
+		t.Fatalf("expected embedding order after a failed rerank, got %+v", got)
+	}
+	rr.err, rr.delay = nil, 5*time.Second
+	if got := search(nil); len(got) != 2 || got[0].Path != "a.go" {
+		t.Fatalf("expected embedding order after exceeding the latency budget, got %+v", got)
+	}
+}
+
+func TestRetrievalService_Evaluate(t *testing.T) {
+	svc, _, _ := newRetrievalTestEnv(t)
+	ctx := context.Background()
+
+	if _, err := svc.Evaluate(ctx, "proj-1", 0, retrieval.EvalManual); !errors.Is(err, retrieval.ErrNoGoldenQueries) {
+		t.Fatalf("expected ErrNoGoldenQueries, got %v", err)
+	}
+	for _, q := range []retrieval.GoldenQuery{
+		{Query: "alpha", Paths: []string{"a.go", "c.txt"}},
+		{Query: "beta", Paths: []string{"c.txt"}},
+	} {
+		if err := svc.AddGoldenQuery(ctx, "proj-1", &q); err != nil {
+			t.Fatalf("add golden query: %v", err)
+		}
+	}
+	if _, err := svc.Evaluate(ctx, "proj-1", 0, retrieval.EvalManual); !errors.Is(err, retrieval.ErrNotIndexed) {
+		t.Fatalf("expected ErrNotIndexed before indexing, got %v", err)
+	}
+
+	// Indexing evaluates the new index.
+	if _, err := svc.Index(ctx, "proj-1"); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+	evals, err := svc.Evals(ctx, "proj-1", 0)
+	if err != nil || len(evals) != 1 {
+		t.Fatalf("expected one evaluation after indexing, got %d, %v", len(evals), err)
+	}
+	if e := evals[0]; e.Trigger != retrieval.EvalReindex || e.Recall != 1 || e.MRR != 0.75 || e.Chunks != 3 {
+		t.Fatalf("unexpected evaluation %+v", e)
+	}
+
+	e, err := svc.Evaluate(ctx, "proj-1", 1, retrieval.EvalManual)
+	if err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+	if e.Recall != 0.25 || e.MRR != 0.5 || e.RecallChange == nil || *e.RecallChange != -0.75 {
+		t.Fatalf("expected recall@1 0.25 and MRR 0.5 down from the last run, got %+v", e)
+	}
+	if missing := e.Queries[1].Missing; len(missing) != 1 || missing[0] != "c.txt" {
+		t.Fatalf("expected c.txt missing for beta, got %v", missing)
+	}
+
+	evals, _ = svc.Evals(ctx, "proj-1", 1)
+	if len(evals) != 1 || evals[0].ID != e.ID || evals[0].MRRChange == nil || *evals[0].MRRChange != -0.25 {
+		t.Fatalf("expected the latest evaluation compared with the one before, got %+v", evals)
+	}
+}
+
+func TestRetrievalService_MigrateIndex(t *testing.T) {
+	svc, store, ollama := newRetrievalTestEnv(t)
+	ctx := context.Background()
+	to := retrieval.Embedding{Provider: "ollama", Model: "nomic-embed-text", Dimensions: 4}
+
+	if _, err := svc.MigrateIndex(ctx, "proj-1", &retrieval.MigrateRequest{Embedding: to}); !errors.Is(err, retrieval.ErrNotIndexed) {
+		t.Fatalf("expected ErrNotIndexed before indexing, got %v", err)
+	}
+	if err := svc.AddGoldenQuery(ctx, "proj-1", &retrieval.GoldenQuery{Query: "alpha", Paths: []string{"a.go"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Index(ctx, "proj-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.MigrateIndex(ctx, "proj-1", &retrieval.MigrateRequest{}); !errors.Is(err, retrieval.ErrSameEmbedding) {
+		t.Fatalf("expected ErrSameEmbedding for the configured embedding, got %v", err)
+	}
+
+	m, err := svc.MigrateIndex(ctx, "proj-1", &retrieval.MigrateRequest{Embedding: to})
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if m.Phase != retrieval.MigrationBuilding || m.From.Provider != "litellm" {
+		t.Fatalf("expected a building migration from litellm, got %+v", m)
+	}
+	m = waitMigration(t, svc, "proj-1")
+	if m.Phase != retrieval.MigrationSwitched || m.Embedded != 3 || m.Before == nil || m.After == nil || m.After.Recall != m.Before.Recall {
+		t.Fatalf("expected a switch after an equal evaluation, got %+v", m)
+	}
+	if len(ollama.batches) < 2 || ollama.batches[0] != 2 || ollama.batches[1] != 1 {
+		t.Fatalf("expected the staged index embedded by ollama, got batches %v", ollama.batches)
+	}
+
+	cfg := store.projects[0].Config
+	if cfg[retrieval.ConfigKeyProvider] != "ollama" || cfg[retrieval.ConfigKeyModel] != "nomic-embed-text" || cfg[retrieval.ConfigKeyDimensions] != "4" {
+		t.Fatalf("expected the project switched to ollama, got config %v", cfg)
+	}
+	if idx, _ := svc.Status(ctx, "proj-1"); idx == nil || idx.Provider != "ollama" || idx.Staged {
+		t.Fatalf("expected the live index built with ollama, got %+v", idx)
+	}
+	if _, err := store.GetStagedRetrievalIndex(ctx, "proj-1"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected no staged index after the switch, got %v", err)
+	}
+	if results, err := svc.Search(ctx, "proj-1", &retrieval.SearchRequest{Query: "alpha", Limit: 1}); err != nil || len(results) != 1 || results[0].Path != "a.go" {
+		t.Fatalf("expected searches to use the new index, got %v, %v", results, err)
+	}
+}
+
+// waitMigration polls the index migration of a project until it is done.
+func waitMigration(t *testing.T, svc *service.RetrievalService, projectID string) *retrieval.Migration {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		m, err := svc.Migration(projectID)
+		if err != nil {
+			t.Fatalf("migration: %v", err)
+		}
+		if m.Done() {
+			return m
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("migration not done: %+v", m)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
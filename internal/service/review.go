@@ -0,0 +1,368 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/domain/ci"
+	"github.com/Strob0t/CodeForge/internal/domain/lint"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/review"
+	"github.com/Strob0t/CodeForge/internal/domain/sarif"
+	"github.com/Strob0t/CodeForge/internal/domain/testreport"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+var (
+	// ErrReviewPublishUnsupported is returned when the project's git
+	// provider cannot comment on pull requests.
+	ErrReviewPublishUnsupported = errors.New("review: git provider cannot comment on pull requests")
+	// ErrReviewPublish is returned when the git provider rejects a comment.
+	ErrReviewPublish = errors.New("review: git provider rejected the comments")
+)
+
+// ReviewService stores the structured results of review runs and publishes
+// them to pull requests as inline comments plus one summary comment, and
+// as a commit status where the git host supports it.
+type ReviewService struct {
+	store     database.Store
+	secrets   *SecretService
+	ci        *CIService
+	publicURL string
+}
+
+// NewReviewService creates a ReviewService. secrets resolves the API token
+// named by git_token_secret; it may be nil if no project publishes reviews.
+func NewReviewService(store database.Store, secrets *SecretService) *ReviewService {
+	return &ReviewService{store: store, secrets: secrets}
+}
+
+// SetPublicURL sets the web UI base URL that commit statuses link to.
+func (s *ReviewService) SetPublicURL(u string) {
+	s.publicURL = strings.TrimSuffix(u, "/")
+}
+
+// SetCIService enables gating review approval on green CI of the pull
+// request head.
+func (s *ReviewService) SetCIService(ci *CIService) {
+	s.ci = ci
+}
+
+// Record stores rv as the review of a run. A run may be reviewed more than
+// once; the latest review wins.
+func (s *ReviewService) Record(ctx context.Context, runID string, rv *review.Review) (*review.Review, error) {
+	if err := rv.Validate(); err != nil {
+		return nil, err
+	}
+	r, err := s.store.GetRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	rv.RunID = r.ID
+	rv.ProjectID = r.ProjectID
+	rv.CreatedAt = time.Now().UTC()
+
+	data, err := json.Marshal(rv)
+	if err != nil {
+		return nil, fmt.Errorf("marshal review: %w", err)
+	}
+	art := &artifact.Artifact{
+		RunID:       r.ID,
+		ProjectID:   r.ProjectID,
+		Kind:        artifact.KindReviewReport,
+		Name:        "review-report.json",
+		ContentType: "application/json",
+		Data:        data,
+		Metadata: map[string]string{
+			"findings": strconv.Itoa(len(rv.Findings)),
+			"approved": strconv.FormatBool(rv.Approved),
+		},
+	}
+	if err := s.store.CreateArtifact(ctx, art); err != nil {
+		return nil, fmt.Errorf("store review: %w", err)
+	}
+	slog.Info("review recorded", "run_id", r.ID, "findings", len(rv.Findings), "approved", rv.Approved)
+	return rv, nil
+}
+
+// Get returns the latest review of a run.
+func (s *ReviewService) Get(ctx context.Context, runID string) (*review.Review, error) {
+	arts, err := s.store.ListArtifactsByRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(arts) - 1; i >= 0; i-- {
+		if arts[i].Kind != artifact.KindReviewReport {
+			continue
+		}
+		full, err := s.store.GetArtifact(ctx, arts[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		var rv review.Review
+		if err := json.Unmarshal(full.Data, &rv); err != nil {
+			return nil, fmt.Errorf("decode review %s: %w", full.ID, err)
+		}
+		return &rv, nil
+	}
+	return nil, fmt.Errorf("review of run %s: %w", runID, domain.ErrNotFound)
+}
+
+// Publish posts the latest review of a run to a pull request. Comments are
+// keyed by req.Key (default: the run's task ID) and their anchor, so
+// publishing a later review with the same key updates the earlier comments
+// in place and marks inline comments without a remaining finding resolved.
+// If the git provider supports commit statuses, the pull request head gets
+// a codeforge/review status: pending while publishing, then success for an
+// approved review and failure otherwise. New critical findings of the run's
+// lint report are published with the review and fail it. When green CI is
+// required, failed CI fails the review and unfinished CI keeps an approving
+// review pending.
+func (s *ReviewService) Publish(ctx context.Context, runID string, req *review.PublishRequest) (*review.PublishResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	rv, err := s.Get(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.store.GetRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.store.GetProject(ctx, r.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	prov, err := hostedGitProvider(ctx, s.secrets, p)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrReviewPublishUnsupported, err)
+	}
+	commenter, ok := prov.(gitprovider.PRCommenter)
+	if !ok || !prov.Capabilities().PullRequest {
+		return nil, fmt.Errorf("%w: %s", ErrReviewPublishUnsupported, prov.Name())
+	}
+
+	key := req.Key
+	if key == "" {
+		key = r.TaskID
+	}
+	res := &review.PublishResult{PRNumber: req.PRNumber, Key: key}
+
+	status := s.reviewStatus(ctx, prov, r.ProjectID, runID, req.PRNumber, res)
+	status(gitprovider.StatusPending, "CodeForge review is being published")
+
+	// New critical linter findings are published with the review and
+	// block its approval.
+	if lr, err := latestLintReport(ctx, s.store, runID); err == nil {
+		for _, f := range lr.BlockingFindings() {
+			rv.Findings = append(rv.Findings, lintFinding(f))
+		}
+		res.LintBlocking = lr.Blocking
+	} else if !errors.Is(err, domain.ErrNotFound) {
+		slog.Warn("review: load lint report", "run_id", runID, "error", err)
+	}
+
+	if err := s.publishComments(ctx, commenter, rv, key, res); err != nil {
+		status(gitprovider.StatusError, "Publishing the CodeForge review failed")
+		return nil, fmt.Errorf("%w: %w", ErrReviewPublish, err)
+	}
+	testsOK, testsDesc := true, ""
+	if req.RequireTests || p.Config[testreport.ConfigKeyReviewRequireTests] == "true" {
+		testsOK, testsDesc = s.testGate(ctx, runID)
+		res.TestsPassed = &testsOK
+	}
+	ciState := ci.StateSuccess
+	if req.RequireCI || p.Config[ci.ConfigGateReview] == "true" {
+		ciState = s.ciGate(ctx, prov, p, req.PRNumber)
+		res.CIState = string(ciState)
+	}
+	switch {
+	case !testsOK:
+		status(gitprovider.StatusFailure, testsDesc)
+	case res.LintBlocking > 0:
+		status(gitprovider.StatusFailure, fmt.Sprintf("CodeForge review blocked by %d new critical lint findings", res.LintBlocking))
+	case ciState == ci.StateFailure:
+		status(gitprovider.StatusFailure, "CodeForge review requires passing CI, it failed")
+	case rv.Approved && ciState != ci.StateSuccess:
+		status(gitprovider.StatusPending, fmt.Sprintf("CodeForge review approved, waiting for CI (%s)", ciState))
+	case rv.Approved:
+		status(gitprovider.StatusSuccess, "Approved by CodeForge review")
+	default:
+		status(gitprovider.StatusFailure, fmt.Sprintf("CodeForge review requested changes (%d findings)", len(rv.Findings)))
+	}
+
+	slog.Info("review published", "run_id", runID, "pr", req.PRNumber, "key", key,
+		"created", res.Created, "updated", res.Updated, "unchanged", res.Unchanged,
+		"resolved", res.Resolved, "off_diff", res.OffDiff, "status", res.Status)
+	return res, nil
+}
+
+// ExportSARIF returns the review of a run and the findings of its lint
+// report as a SARIF log. Either may be missing, but not both.
+func (s *ReviewService) ExportSARIF(ctx context.Context, runID string) (*sarif.Log, error) {
+	rv, err := s.Get(ctx, runID)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
+	}
+	lr, lerr := latestLintReport(ctx, s.store, runID)
+	if lerr != nil && !errors.Is(lerr, domain.ErrNotFound) {
+		return nil, lerr
+	}
+	if rv == nil && lr == nil {
+		return nil, fmt.Errorf("review or lint report of run %s: %w", runID, domain.ErrNotFound)
+	}
+	return sarif.New(rv, lr), nil
+}
+
+// testGate reports whether the latest test report of a run passed, with a
+// status description if it did not.
+func (s *ReviewService) testGate(ctx context.Context, runID string) (bool, string) {
+	rep, err := latestTestReport(ctx, s.store, runID)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotFound) {
+			slog.Warn("review: load test report", "run_id", runID, "error", err)
+		}
+		return false, "CodeForge review requires passing tests, none were recorded"
+	}
+	if !rep.Passed {
+		return false, fmt.Sprintf("CodeForge review requires passing tests (%d of %d failed)", rep.Failed, rep.Total)
+	}
+	return true, ""
+}
+
+// ciGate returns the CI state of the head of a pull request. It is pending
+// if CI cannot be read, so the review does not approve unchecked changes.
+func (s *ReviewService) ciGate(ctx context.Context, prov gitprovider.Provider, p *project.Project, number int) ci.State {
+	reporter := statusReporter(prov)
+	if s.ci == nil || reporter == nil {
+		slog.Warn("review: ci gate needs a ci service and commit statuses", "project_id", p.ID, "provider", prov.Name())
+		return ci.StatePending
+	}
+	sha, err := reporter.PRHeadSHA(ctx, number)
+	if err != nil {
+		slog.Warn("review: ci gate: get pull request head", "pr", number, "error", err)
+		return ci.StatePending
+	}
+	st, err := s.ci.Check(ctx, p, "", sha)
+	if err != nil {
+		slog.Warn("review: ci gate: check ci", "pr", number, "sha", sha, "error", err)
+		return ci.StatePending
+	}
+	if st.State != ci.StateSuccess && st.State != ci.StateFailure {
+		return ci.StatePending
+	}
+	return st.State
+}
+
+// lintFinding converts a linter finding into a review finding.
+func lintFinding(f lint.Finding) review.Finding {
+	msg := fmt.Sprintf("[%s] %s", f.Tool, f.Message)
+	if f.Rule != "" {
+		msg = fmt.Sprintf("[%s %s] %s", f.Tool, f.Rule, f.Message)
+	}
+	return review.Finding{Path: f.Path, Line: f.Line, Severity: f.Severity, Message: msg}
+}
+
+// reviewStatus returns a function that reports the codeforge/review status
+// on the head of the pull request and records the reported state in res.
+// It is a no-op if the provider cannot report statuses.
+func (s *ReviewService) reviewStatus(ctx context.Context, prov gitprovider.Provider, projectID, runID string, number int, res *review.PublishResult) func(gitprovider.StatusState, string) {
+	reporter := statusReporter(prov)
+	if reporter == nil {
+		return func(gitprovider.StatusState, string) {}
+	}
+	sha, err := reporter.PRHeadSHA(ctx, number)
+	if err != nil {
+		slog.Warn("review status: get pull request head", "run_id", runID, "pr", number, "error", err)
+		return func(gitprovider.StatusState, string) {}
+	}
+	target := runURL(s.publicURL, projectID, runID)
+	return func(state gitprovider.StatusState, desc string) {
+		st := &gitprovider.CommitStatus{Context: StatusContextReview, State: state, Description: desc, TargetURL: target}
+		if setCommitStatus(ctx, reporter, sha, st) {
+			res.Status = string(state)
+		}
+	}
+}
+
+// publishComments creates or updates the inline and summary comments of rv
+// on the pull request and marks stale inline comments resolved.
+func (s *ReviewService) publishComments(ctx context.Context, commenter gitprovider.PRCommenter, rv *review.Review, key string, res *review.PublishResult) error {
+	existing, err := commenter.ListPRComments(ctx, res.PRNumber)
+	if err != nil {
+		return err
+	}
+	published := make(map[string]*gitprovider.PRComment)
+	for i := range existing {
+		if k, anchor, ok := review.ParseMarker(existing[i].Body); ok && k == key {
+			published[anchor] = &existing[i]
+		}
+	}
+	upsert := func(c *gitprovider.PRComment, anchor string) error {
+		prev, ok := published[anchor]
+		delete(published, anchor)
+		switch {
+		case !ok:
+			if err := commenter.CreatePRComment(ctx, res.PRNumber, c); err != nil {
+				return err
+			}
+			res.Created++
+		case prev.Body == c.Body:
+			c.ID = prev.ID
+			res.Unchanged++
+		default:
+			c.ID, c.Path = prev.ID, prev.Path
+			if err := commenter.UpdatePRComment(ctx, res.PRNumber, c); err != nil {
+				return err
+			}
+			res.Updated++
+		}
+		return nil
+	}
+
+	extra := rv.FileFindings()
+	for _, c := range rv.Comments() {
+		pc := &gitprovider.PRComment{Path: c.Path, Line: c.Line, Body: c.Body(key)}
+		err := upsert(pc, c.Anchor)
+		if errors.Is(err, gitprovider.ErrNotInDiff) {
+			extra = append(extra, c.Findings...)
+			res.OffDiff += len(c.Findings)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	summary := &gitprovider.PRComment{Body: rv.SummaryBody(key, extra)}
+	if err := upsert(summary, review.SummaryAnchor); err != nil {
+		return err
+	}
+	res.SummaryID = summary.ID
+
+	for anchor, prev := range published {
+		if anchor == review.SummaryAnchor || prev.Path == "" {
+			continue
+		}
+		resolved := review.ResolvedBody(key, anchor)
+		if prev.Body == resolved {
+			continue
+		}
+		prev.Body = resolved
+		if err := commenter.UpdatePRComment(ctx, res.PRNumber, prev); err != nil {
+			return err
+		}
+		res.Resolved++
+	}
+	return nil
+}
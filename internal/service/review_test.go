@@ -0,0 +1,244 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/ci"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/review"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/secret"
+	"github.com/Strob0t/CodeForge/internal/port/ciprovider"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+// fakePR holds the comments of the pull request behind the "fake-git"
+// provider. Inline comments are only accepted on lines below 100.
+type fakePR struct {
+	comments []gitprovider.PRComment
+	creates  int
+	updates  int
+	statuses []string // sha/context=state
+}
+
+var currentPR *fakePR
+
+type fakeGitProvider struct {
+	gitprovider.Provider // unused local operations
+	pr                   *fakePR
+}
+
+func init() {
+	gitprovider.Register("fake-git", func(cfg map[string]string) (gitprovider.Provider, error) {
+		if cfg[gitprovider.ConfigToken] != "ghp" {
+			return nil, fmt.Errorf("fake-git: bad token %q", cfg[gitprovider.ConfigToken])
+		}
+		return &fakeGitProvider{pr: currentPR}, nil
+	})
+}
+
+func (p *fakeGitProvider) Name() string { return "fake-git" }
+func (p *fakeGitProvider) Capabilities() gitprovider.Capabilities {
+	return gitprovider.Capabilities{PullRequest: true, CommitStatus: true}
+}
+func (p *fakeGitProvider) PRHeadSHA(_ context.Context, number int) (string, error) {
+	return fmt.Sprintf("head-%d", number), nil
+}
+func (p *fakeGitProvider) SetCommitStatus(_ context.Context, sha string, st *gitprovider.CommitStatus) error {
+	if !strings.HasPrefix(st.TargetURL, "http://ui.example/projects/") {
+		return fmt.Errorf("unexpected target url %q", st.TargetURL)
+	}
+	p.pr.statuses = append(p.pr.statuses, fmt.Sprintf("%s/%s=%s", sha, st.Context, st.State))
+	return nil
+}
+func (p *fakeGitProvider) ListPRComments(_ context.Context, _ int) ([]gitprovider.PRComment, error) {
+	return append([]gitprovider.PRComment(nil), p.pr.comments...), nil
+}
+func (p *fakeGitProvider) CreatePRComment(_ context.Context, _ int, c *gitprovider.PRComment) error {
+	if c.Line >= 100 {
+		return gitprovider.ErrNotInDiff
+	}
+	p.pr.creates++
+	c.ID = fmt.Sprintf("c%d", len(p.pr.comments)+1)
+	p.pr.comments = append(p.pr.comments, *c)
+	return nil
+}
+func (p *fakeGitProvider) UpdatePRComment(_ context.Context, _ int, c *gitprovider.PRComment) error {
+	for i := range p.pr.comments {
+		if p.pr.comments[i].ID == c.ID {
+			p.pr.updates++
+			p.pr.comments[i].Body = c.Body
+			return nil
+		}
+	}
+	return errors.New("no such comment")
+}
+
+func newReviewTestEnv(t *testing.T) (*runtimeMockStore, *service.ReviewService) {
+	t.Helper()
+	currentPR = &fakePR{}
+	_, store, _, _ := newRuntimeTestEnv()
+	secrets := newTestSecretService(t, store)
+	if _, err := secrets.Create(context.Background(), "", &secret.CreateRequest{Name: "GH_TOKEN", Value: "ghp"}); err != nil {
+		t.Fatal(err)
+	}
+	store.projects = append(store.projects, project.Project{ID: "gh-proj", Provider: "fake-git", Config: map[string]string{
+		gitprovider.ConfigTokenSecret: "GH_TOKEN",
+	}})
+	store.runs = append(store.runs,
+		run.Run{ID: "review-1", TaskID: "task-1", ProjectID: "gh-proj"},
+		run.Run{ID: "review-2", TaskID: "task-1", ProjectID: "gh-proj"},
+		run.Run{ID: "local-run", TaskID: "task-1", ProjectID: "proj-1"},
+	)
+	svc := service.NewReviewService(store, secrets)
+	svc.SetPublicURL("http://ui.example/")
+	return store, svc
+}
+
+func TestReviewService_RecordAndGet(t *testing.T) {
+	_, svc := newReviewTestEnv(t)
+	ctx := context.Background()
+
+	if _, err := svc.Get(ctx, "review-1"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before recording, got %v", err)
+	}
+	if _, err := svc.Record(ctx, "review-1", &review.Review{}); err == nil {
+		t.Fatal("expected validation error for empty review")
+	}
+	for _, summary := range []string{"first", "second"} {
+		if _, err := svc.Record(ctx, "review-1", &review.Review{Summary: summary}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rv, err := svc.Get(ctx, "review-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rv.Summary != "second" || rv.ProjectID != "gh-proj" {
+		t.Fatalf("expected latest review, got %+v", rv)
+	}
+}
+
+func TestReviewService_PublishIsIdempotent(t *testing.T) {
+	_, svc := newReviewTestEnv(t)
+	ctx := context.Background()
+	currentPR.comments = []gitprovider.PRComment{{ID: "human", Path: "main.go", Line: 3, Body: "nit from a human"}}
+
+	first := &review.Review{Summary: "Needs work.", Findings: []review.Finding{
+		{Path: "main.go", Line: 10, Severity: review.SeverityCritical, Message: "nil deref"},
+		{Path: "main.go", Line: 20, Severity: review.SeverityWarning, Message: "unchecked error"},
+		{Path: "main.go", Line: 150, Severity: review.SeverityInfo, Message: "outside diff"},
+	}}
+	if _, err := svc.Record(ctx, "review-1", first); err != nil {
+		t.Fatal(err)
+	}
+	req := &review.PublishRequest{PRNumber: 7}
+	res, err := svc.Publish(ctx, "review-1", req)
+	if err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if res.Key != "task-1" || res.Created != 3 || res.OffDiff != 1 {
+		t.Fatalf("unexpected first publish %+v", res)
+	}
+	wantStatuses := "head-7/codeforge/review=pending head-7/codeforge/review=failure"
+	if got := strings.Join(currentPR.statuses, " "); res.Status != "failure" || got != wantStatuses {
+		t.Fatalf("expected pending then failure status, got %q (%s)", got, res.Status)
+	}
+	summary := currentPR.comments[len(currentPR.comments)-1]
+	if summary.Path != "" || !strings.Contains(summary.Body, "outside diff") {
+		t.Fatalf("expected off-diff finding in summary, got %+v", summary)
+	}
+
+	res, err = svc.Publish(ctx, "review-1", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Created != 0 || res.Updated != 0 || res.Unchanged != 3 || currentPR.updates != 0 {
+		t.Fatalf("expected re-publish to change nothing, got %+v", res)
+	}
+
+	// A re-run of the review for the same task fixes one finding.
+	second := &review.Review{Summary: "Better.", Findings: []review.Finding{
+		{Path: "main.go", Line: 10, Severity: review.SeverityWarning, Message: "nil deref, now guarded"},
+	}}
+	if _, err := svc.Record(ctx, "review-2", second); err != nil {
+		t.Fatal(err)
+	}
+	res, err = svc.Publish(ctx, "review-2", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Created != 0 || res.Updated != 2 || res.Resolved != 1 {
+		t.Fatalf("expected inline and summary updated and one resolved, got %+v", res)
+	}
+	if len(currentPR.comments) != 4 || currentPR.comments[0].Body != "nit from a human" {
+		t.Fatalf("expected no new comments and human comment untouched, got %+v", currentPR.comments)
+	}
+
+	res, err = svc.Publish(ctx, "review-2", &review.PublishRequest{PRNumber: 7, Key: "other"})
+	if err != nil || res.Created != 2 {
+		t.Fatalf("expected a different key to start a new thread, got %+v, %v", res, err)
+	}
+}
+
+func TestReviewService_PublishUnsupported(t *testing.T) {
+	_, svc := newReviewTestEnv(t)
+	ctx := context.Background()
+	if _, err := svc.Record(ctx, "local-run", &review.Review{Summary: "ok", Approved: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Publish(ctx, "local-run", &review.PublishRequest{PRNumber: 1}); !errors.Is(err, service.ErrReviewPublishUnsupported) {
+		t.Fatalf("expected ErrReviewPublishUnsupported, got %v", err)
+	}
+}
+
+func TestReviewService_PublishApprovedSetsSuccess(t *testing.T) {
+	_, svc := newReviewTestEnv(t)
+	ctx := context.Background()
+	if _, err := svc.Record(ctx, "review-1", &review.Review{Summary: "LGTM", Approved: true}); err != nil {
+		t.Fatal(err)
+	}
+	res, err := svc.Publish(ctx, "review-1", &review.PublishRequest{PRNumber: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != "success" || currentPR.statuses[len(currentPR.statuses)-1] != "head-3/codeforge/review=success" {
+		t.Fatalf("expected success status, got %s %v", res.Status, currentPR.statuses)
+	}
+}
+
+func TestReviewService_PublishGatedOnCI(t *testing.T) {
+	store, svc := newReviewTestEnv(t)
+	store.projects[len(store.projects)-1].Config[ciprovider.ConfigProvider] = "fake-pipelines"
+	svc.SetCIService(service.NewCIService(store, newTestSecretService(t, store), config.CI{WaitTimeout: time.Minute, WatchWindow: time.Hour}))
+	ctx := context.Background()
+	if _, err := svc.Record(ctx, "review-1", &review.Review{Summary: "LGTM", Approved: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		pipelines []ci.State
+		want      string
+	}{
+		{[]ci.State{ci.StateSuccess, ci.StateRunning}, "pending"},
+		{[]ci.State{ci.StateFailure}, "failure"},
+		{[]ci.State{ci.StateSuccess}, "success"},
+	} {
+		setFakePipelines(tt.pipelines...)
+		res, err := svc.Publish(ctx, "review-1", &review.PublishRequest{PRNumber: 3, RequireCI: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Status != tt.want || currentPR.statuses[len(currentPR.statuses)-1] != "head-3/codeforge/review="+tt.want {
+			t.Errorf("CI %v: expected %s status, got %s %v", tt.pipelines, tt.want, res.Status, currentPR.statuses)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/routing"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/resilience"
+)
+
+// RoutingService picks LLM models by task type. Rules are layered like
+// policies: service-wide rules, then the project's tenant, then the
+// project's config; the most specific rule wins. Each model has its own
+// circuit breaker, and a route moves on to its next model while one is
+// open or when a call fails.
+type RoutingService struct {
+	store    database.Store
+	tiers    map[routing.Tier][]string
+	rules    []routing.Rule
+	tenants  map[string][]routing.Rule
+	defaults map[routing.TaskType]string
+	breaker  config.Breaker
+	breakers sync.Map // map[model]*resilience.Breaker
+}
+
+// NewRoutingService validates the routing config and creates a
+// RoutingService. defaults holds the model each task type used before
+// routing rules existed; it applies when no rule matches.
+func NewRoutingService(store database.Store, cfg *config.Routing, breaker config.Breaker, defaults map[routing.TaskType]string) (*RoutingService, error) {
+	s := &RoutingService{
+		store:    store,
+		tiers:    make(map[routing.Tier][]string, len(cfg.Tiers)),
+		tenants:  make(map[string][]routing.Rule, len(cfg.Tenants)),
+		defaults: defaults,
+		breaker:  breaker,
+	}
+	for name, models := range cfg.Tiers {
+		s.tiers[routing.Tier(name)] = models
+	}
+	var err error
+	if s.rules, err = s.convert(cfg.Rules); err != nil {
+		return nil, fmt.Errorf("routing.rules: %w", err)
+	}
+	for id, rules := range cfg.Tenants {
+		if s.tenants[id], err = s.convert(rules); err != nil {
+			return nil, fmt.Errorf("routing.tenants.%s: %w", id, err)
+		}
+	}
+	return s, nil
+}
+
+func (s *RoutingService) convert(rules []config.RoutingRule) ([]routing.Rule, error) {
+	out := make([]routing.Rule, len(rules))
+	for i, r := range rules {
+		out[i] = routing.Rule{TaskType: routing.TaskType(r.TaskType), Tier: routing.Tier(r.Tier), Models: r.Models}
+		if err := out[i].Validate(s.tiers); err != nil {
+			return nil, fmt.Errorf("[%d]: %w", i, err)
+		}
+	}
+	return out, nil
+}
+
+// Explain resolves the route for a request and reports which layer's rule
+// fired and which models were skipped.
+func (s *RoutingService) Explain(ctx context.Context, req routing.Request) (*routing.Decision, error) {
+	if !req.TaskType.Valid() {
+		return nil, fmt.Errorf("unknown task type %q", req.TaskType)
+	}
+	if req.Model != "" {
+		return &routing.Decision{
+			TaskType:   req.TaskType,
+			Level:      routing.LevelRequest,
+			Candidates: []routing.Candidate{{Model: req.Model}},
+			Model:      req.Model,
+		}, nil
+	}
+
+	layers := []routing.Layer{{Level: routing.LevelDefault, Rules: s.rules}}
+	if req.ProjectID != "" {
+		p, err := s.store.GetProject(ctx, req.ProjectID)
+		if err != nil {
+			return nil, fmt.Errorf("get project: %w", err)
+		}
+		layers = append(layers, routing.Layer{Level: routing.LevelTenant, Rules: s.tenants[tenantOf(p)]})
+		if v := p.Config[routing.ConfigKey(req.TaskType)]; v != "" {
+			override := routing.ParseOverride(req.TaskType, v, s.tiers)
+			layers = append(layers, routing.Layer{Level: routing.LevelProject, Rules: []routing.Rule{override}})
+		}
+	}
+
+	d := routing.Resolve(req.TaskType, s.tiers, layers, s.defaults[req.TaskType])
+	for _, c := range d.Candidates {
+		if s.breakerFor(c.Model).Open() {
+			d.Skip(c.Model, "circuit breaker open")
+		}
+	}
+	return d, nil
+}
+
+// Pick returns the model for a request without calling it, for work that
+// runs elsewhere such as agent runs. An empty model means neither a rule
+// nor a default applies.
+func (s *RoutingService) Pick(ctx context.Context, req routing.Request) (string, error) {
+	d, err := s.Explain(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if d.Model == "" && len(d.Candidates) > 0 {
+		return "", fmt.Errorf("%w for %s", routing.ErrNoModel, req.TaskType)
+	}
+	return d.Model, nil
+}
+
+// Call invokes fn with the models of the route in order until one
+// succeeds, skipping models whose breaker is open. It returns the error of
+// the last model tried.
+func (s *RoutingService) Call(ctx context.Context, req routing.Request, fn func(model string) error) error {
+	d, err := s.Explain(ctx, req)
+	if err != nil {
+		return err
+	}
+	if d.Model == "" {
+		return fmt.Errorf("%w for %s", routing.ErrNoModel, req.TaskType)
+	}
+
+	var lastErr error
+	for _, c := range d.Candidates {
+		if c.Skipped != "" {
+			continue
+		}
+		lastErr = s.breakerFor(c.Model).Execute(func() error { return fn(c.Model) })
+		if lastErr == nil || ctx.Err() != nil {
+			return lastErr
+		}
+		slog.Warn("routed model failed, trying next", "task_type", req.TaskType, "model", c.Model, "error", lastErr)
+	}
+	return lastErr
+}
+
+// breakerFor returns the circuit breaker of a model.
+func (s *RoutingService) breakerFor(model string) *resilience.Breaker {
+	if b, ok := s.breakers.Load(model); ok {
+		return b.(*resilience.Breaker)
+	}
+	b, _ := s.breakers.LoadOrStore(model, resilience.NewBreaker(s.breaker.MaxFailures, s.breaker.Timeout))
+	return b.(*resilience.Breaker)
+}
@@ -0,0 +1,134 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/routing"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func newTestRoutingService(t *testing.T, store *runtimeMockStore) *service.RoutingService {
+	t.Helper()
+	svc, err := service.NewRoutingService(store, &config.Routing{
+		Tiers: map[string][]string{
+			"frontier": {"frontier-a", "frontier-b"},
+			"mid":      {"mid-a"},
+			"cheap":    {"cheap-a"},
+		},
+		Rules: []config.RoutingRule{
+			{TaskType: "decompose", Tier: "frontier"},
+			{TaskType: "review", Tier: "mid"},
+		},
+		Tenants: map[string][]config.RoutingRule{
+			"acme": {{TaskType: "review", Models: []string{"acme-reviewer"}}},
+		},
+	}, config.Breaker{MaxFailures: 1, Timeout: time.Minute}, map[routing.TaskType]string{routing.TaskSummarize: "summary-default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return svc
+}
+
+func TestNewRoutingService_InvalidRule(t *testing.T) {
+	_, store, _, _ := newRuntimeTestEnv()
+	_, err := service.NewRoutingService(store, &config.Routing{
+		Rules: []config.RoutingRule{{TaskType: "decompose", Tier: "frontier"}},
+	}, config.Breaker{MaxFailures: 1}, nil)
+	if err == nil {
+		t.Fatal("expected error for a rule using an undefined tier")
+	}
+}
+
+func TestRoutingService_ExplainLayers(t *testing.T) {
+	_, store, _, _ := newRuntimeTestEnv()
+	svc := newTestRoutingService(t, store)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name      string
+		setup     func()
+		req       routing.Request
+		wantLevel routing.Level
+		wantModel string
+	}{
+		{"default rule", func() {}, routing.Request{TaskType: routing.TaskReview, ProjectID: "proj-1"}, routing.LevelDefault, "mid-a"},
+		{"default model", func() {}, routing.Request{TaskType: routing.TaskSummarize}, routing.LevelDefault, "summary-default"},
+		{"tenant rule", func() { store.projects[0].TenantID = "acme" }, routing.Request{TaskType: routing.TaskReview, ProjectID: "proj-1"}, routing.LevelTenant, "acme-reviewer"},
+		{"project tier", func() { store.projects[0].Config = map[string]string{"routing.review": "cheap"} }, routing.Request{TaskType: routing.TaskReview, ProjectID: "proj-1"}, routing.LevelProject, "cheap-a"},
+		{"explicit model", func() {}, routing.Request{TaskType: routing.TaskReview, ProjectID: "proj-1", Model: "mine"}, routing.LevelRequest, "mine"},
+	} {
+		tc.setup()
+		d, err := svc.Explain(ctx, tc.req)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if d.Level != tc.wantLevel || d.Model != tc.wantModel {
+			t.Errorf("%s: got level %s model %q, want %s %q", tc.name, d.Level, d.Model, tc.wantLevel, tc.wantModel)
+		}
+	}
+
+	if _, err := svc.Explain(ctx, routing.Request{TaskType: "chat"}); err == nil {
+		t.Fatal("expected error for unknown task type")
+	}
+}
+
+func TestRoutingService_CallFallsBackOnOpenBreaker(t *testing.T) {
+	_, store, _, _ := newRuntimeTestEnv()
+	svc := newTestRoutingService(t, store)
+	ctx := context.Background()
+	req := routing.Request{TaskType: routing.TaskDecompose}
+
+	var tried []string
+	call := func(model string) error {
+		tried = append(tried, model)
+		if model == "frontier-a" {
+			return errors.New("upstream 503")
+		}
+		return nil
+	}
+	if err := svc.Call(ctx, req, call); err != nil {
+		t.Fatal(err)
+	}
+	if len(tried) != 2 || tried[1] != "frontier-b" {
+		t.Fatalf("expected fallback after the failure, tried %v", tried)
+	}
+
+	// The failure opened frontier-a's breaker: it is skipped without a call.
+	d, _ := svc.Explain(ctx, req)
+	if d.Model != "frontier-b" || d.Candidates[0].Skipped == "" {
+		t.Fatalf("expected frontier-a skipped, got %+v", d)
+	}
+	tried = nil
+	if err := svc.Call(ctx, req, call); err != nil || len(tried) != 1 || tried[0] != "frontier-b" {
+		t.Fatalf("expected only frontier-b to be called, tried %v (err %v)", tried, err)
+	}
+
+	if err := svc.Call(ctx, routing.Request{TaskType: routing.TaskCode}, call); !errors.Is(err, routing.ErrNoModel) {
+		t.Fatalf("expected ErrNoModel without rule or default, got %v", err)
+	}
+}
+
+func TestStartRun_RoutesByTaskType(t *testing.T) {
+	svc, store, queue, _ := newRuntimeTestEnv()
+	svc.SetRoutingService(newTestRoutingService(t, store))
+
+	_, err := svc.StartRun(context.Background(), &run.StartRequest{
+		TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", TaskType: "review",
+	})
+	if err != nil {
+		t.Fatalf("StartRun failed: %v", err)
+	}
+	msg, _ := queue.lastMessage(messagequeue.SubjectRunStart)
+	var payload messagequeue.RunStartPayload
+	_ = json.Unmarshal(msg.Data, &payload)
+	if payload.Config["model"] != "mid-a" {
+		t.Errorf("expected routed model, got %q", payload.Config["model"])
+	}
+}
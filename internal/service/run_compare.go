@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+)
+
+// diffStatTimeout bounds the git call recording a run's diff.
+const diffStatTimeout = 10 * time.Second
+
+// writeTools are the tools whose approved calls count as touching a file.
+var writeTools = map[string]bool{"Edit": true, "Write": true, "MultiEdit": true}
+
+// recordDiffStat records the uncommitted changes in the run's workspace as a
+// run.diffstat event. Workspaces that are not git repositories are skipped.
+func (s *RuntimeService) recordDiffStat(ctx context.Context, r *run.Run) {
+	proj, err := s.store.GetProject(ctx, r.ProjectID)
+	if err != nil {
+		return
+	}
+	dir := r.Workspace(proj.WorkspacePath)
+	if dir == "" {
+		return
+	}
+	gitCtx, cancel := context.WithTimeout(ctx, diffStatTimeout)
+	defer cancel()
+	out, err := runDeliverGit(gitCtx, dir, "diff", "--numstat", "HEAD")
+	if err != nil {
+		slog.Debug("diff stat skipped", "run_id", r.ID, "error", err)
+		return
+	}
+	ds := run.ParseNumstat(out)
+	s.appendRunEvent(ctx, event.TypeRunDiffStat, r, map[string]string{
+		"files":      strconv.Itoa(ds.Files),
+		"insertions": strconv.Itoa(ds.Insertions),
+		"deletions":  strconv.Itoa(ds.Deletions),
+		"paths":      strings.Join(ds.Paths, "\n"),
+	})
+}
+
+// CompareRuns returns a side-by-side comparison of runs of the same task.
+func (s *RuntimeService) CompareRuns(ctx context.Context, ids []string) (*run.Comparison, error) {
+	if len(ids) < 2 || len(ids) > run.MaxCompareRuns {
+		return nil, run.ErrCompareCount
+	}
+	cmp := &run.Comparison{Runs: make([]run.ComparisonEntry, 0, len(ids))}
+	for _, id := range ids {
+		r, err := s.store.GetRun(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("get run %s: %w", id, err)
+		}
+		if cmp.TaskID == "" {
+			cmp.TaskID = r.TaskID
+		} else if r.TaskID != cmp.TaskID {
+			return nil, fmt.Errorf("run %s: %w", id, run.ErrCompareMixedTasks)
+		}
+		events, err := s.loadRunEvents(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("load run events %s: %w", id, err)
+		}
+		entry := compareEntry(r, events)
+		if ag, err := s.store.GetAgent(ctx, r.AgentID); err == nil {
+			entry.Backend = ag.Backend
+		}
+		cmp.Runs = append(cmp.Runs, entry)
+	}
+	return cmp, nil
+}
+
+// compareEntry folds a run and its recorded events into comparison metrics.
+func compareEntry(r *run.Run, events []event.AgentEvent) run.ComparisonEntry {
+	entry := run.ComparisonEntry{
+		RunID:     r.ID,
+		AgentID:   r.AgentID,
+		Status:    r.Status,
+		CostUSD:   r.CostUSD,
+		TokensIn:  r.TokensIn,
+		TokensOut: r.TokensOut,
+		StepCount: r.StepCount,
+	}
+	if r.CompletedAt != nil {
+		entry.DurationMS = r.CompletedAt.Sub(r.StartedAt).Milliseconds()
+	}
+
+	files := make(map[string]bool)
+	for i := range events {
+		var p map[string]string
+		if err := json.Unmarshal(events[i].Payload, &p); err != nil {
+			continue
+		}
+		switch events[i].Type {
+		case event.TypeToolCallApproved:
+			if writeTools[p["tool"]] && p["path"] != "" {
+				files[p["path"]] = true
+			}
+		case event.TypeRunDiffStat:
+			ds := &run.DiffStat{}
+			ds.Files, _ = strconv.Atoi(p["files"])
+			ds.Insertions, _ = strconv.Atoi(p["insertions"])
+			ds.Deletions, _ = strconv.Atoi(p["deletions"])
+			entry.Diff = ds
+			for _, path := range strings.Split(p["paths"], "\n") {
+				if path != "" {
+					files[path] = true
+				}
+			}
+		case event.TypeQualityGatePassed, event.TypeQualityGateFailed:
+			entry.QualityGate = "passed"
+			if events[i].Type == event.TypeQualityGateFailed {
+				entry.QualityGate = "failed"
+			}
+			entry.TestsPassed = parseBoolPtr(p["tests_passed"])
+			entry.LintPassed = parseBoolPtr(p["lint_passed"])
+		}
+	}
+
+	entry.FilesTouched = make([]string, 0, len(files))
+	for f := range files {
+		entry.FilesTouched = append(entry.FilesTouched, f)
+	}
+	sort.Strings(entry.FilesTouched)
+	return entry
+}
+
+// parseBoolPtr parses an optional boolean event field.
+func parseBoolPtr(s string) *bool {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil
+	}
+	return &b
+}
+
+// FilesTouched returns the files a run changed: the paths of its approved
+// write tool calls and of its recorded diff stat.
+func (s *RuntimeService) FilesTouched(ctx context.Context, r *run.Run) ([]string, error) {
+	events, err := s.loadRunEvents(ctx, r.ID)
+	if err != nil {
+		return nil, fmt.Errorf("load run events: %w", err)
+	}
+	return compareEntry(r, events).FilesTouched, nil
+}
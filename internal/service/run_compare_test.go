@@ -0,0 +1,91 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+)
+
+func TestCompareRuns(t *testing.T) {
+	rt, store, _, _ := newRuntimeTestEnv()
+	ctx := context.Background()
+
+	// Two runs of the same task with different tool calls and usage
+	var ids []string
+	for i, path := range []string{"main.go", "util.go"} {
+		r, err := rt.StartRun(ctx, &run.StartRequest{TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", PolicyProfile: "trusted-mount-autonomous"})
+		if err != nil {
+			t.Fatalf("StartRun failed: %v", err)
+		}
+		ids = append(ids, r.ID)
+		_ = rt.HandleToolCallRequest(ctx, &messagequeue.ToolCallRequestPayload{RunID: r.ID, CallID: "c1", Tool: "Edit", Path: path})
+		_ = rt.HandleToolCallResult(ctx, &messagequeue.ToolCallResultPayload{
+			RunID: r.ID, CallID: "c1", Tool: "Edit", Success: true, CostUSD: 0.01 * float64(i+1), TokensIn: 100 * (i + 1), TokensOut: 10,
+		})
+		if err := rt.HandleRunComplete(ctx, &messagequeue.RunCompletePayload{
+			RunID: r.ID, TaskID: "task-1", ProjectID: "proj-1", Status: string(run.StatusFailed), Error: "boom", StepCount: 1,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmp, err := rt.CompareRuns(ctx, ids)
+	if err != nil {
+		t.Fatalf("CompareRuns failed: %v", err)
+	}
+	if cmp.TaskID != "task-1" || len(cmp.Runs) != 2 {
+		t.Fatalf("unexpected comparison %+v", cmp)
+	}
+	first, second := cmp.Runs[0], cmp.Runs[1]
+	if first.TokensIn != 100 || second.TokensIn != 200 || first.TokensOut != 10 {
+		t.Fatalf("unexpected token usage: %+v / %+v", first, second)
+	}
+	if len(first.FilesTouched) != 1 || first.FilesTouched[0] != "main.go" || second.FilesTouched[0] != "util.go" {
+		t.Fatalf("unexpected files touched: %v / %v", first.FilesTouched, second.FilesTouched)
+	}
+	if first.Backend != "aider" || first.Status != run.StatusFailed || first.StepCount != 1 {
+		t.Fatalf("unexpected entry %+v", first)
+	}
+
+	// Runs of another task cannot be compared
+	store.tasks = append(store.tasks, task.Task{ID: "task-2", ProjectID: "proj-1", Status: task.StatusPending})
+	other, err := rt.StartRun(ctx, &run.StartRequest{TaskID: "task-2", AgentID: "agent-1", ProjectID: "proj-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rt.CompareRuns(ctx, []string{ids[0], other.ID}); !errors.Is(err, run.ErrCompareMixedTasks) {
+		t.Fatalf("expected ErrCompareMixedTasks, got %v", err)
+	}
+}
+
+func TestCompareRuns_QualityGateOutcome(t *testing.T) {
+	rt, store, _, _ := newRuntimeTestEnv()
+	ctx := context.Background()
+	completed := time.Now()
+	started := completed.Add(-90 * time.Second)
+	store.runs = append(store.runs,
+		run.Run{ID: "gated", TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", PolicyProfile: "headless-safe-sandbox", Status: run.StatusQualityGate, StartedAt: started},
+		run.Run{ID: "plain", TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", Status: run.StatusCompleted, StartedAt: started, CompletedAt: &completed},
+	)
+	passed, failed := true, false
+	if err := rt.HandleQualityGateResult(ctx, &messagequeue.QualityGateResultPayload{RunID: "gated", TestsPassed: &failed, LintPassed: &passed}); err != nil {
+		t.Fatal(err)
+	}
+
+	cmp, err := rt.CompareRuns(ctx, []string{"gated", "plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gated, plain := cmp.Runs[0], cmp.Runs[1]
+	if gated.QualityGate != "failed" || gated.TestsPassed == nil || *gated.TestsPassed || gated.LintPassed == nil || !*gated.LintPassed {
+		t.Fatalf("unexpected gate outcome %+v", gated)
+	}
+	if plain.QualityGate != "" || plain.TestsPassed != nil || plain.DurationMS != 90000 {
+		t.Fatalf("unexpected plain entry %+v", plain)
+	}
+}
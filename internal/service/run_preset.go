@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/agent"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+)
+
+// ErrUnknownPreset is returned when a run names a preset its project does
+// not have.
+var ErrUnknownPreset = errors.New("unknown run preset")
+
+// ErrPresetBackend is returned when no agent of a preset's backend is
+// available to a run, or the run's agent uses another backend.
+var ErrPresetBackend = errors.New("agent backend does not match the run preset")
+
+// RunPresetService manages the named run presets of projects.
+type RunPresetService struct {
+	store    database.Store
+	policies *PolicyService
+	modes    *ModeService
+}
+
+// NewRunPresetService creates a RunPresetService. Presets naming a policy
+// profile or mode unknown to policies or modes are rejected.
+func NewRunPresetService(store database.Store, policies *PolicyService, modes *ModeService) *RunPresetService {
+	return &RunPresetService{store: store, policies: policies, modes: modes}
+}
+
+// Create stores a preset of a project.
+func (s *RunPresetService) Create(ctx context.Context, projectID string, req *run.PresetRequest) (*run.Preset, error) {
+	if err := s.validate(req); err != nil {
+		return nil, err
+	}
+	if _, err := s.store.GetProject(ctx, projectID); err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+	p := &run.Preset{ProjectID: projectID}
+	req.ApplyTo(p)
+	if err := s.store.CreateRunPreset(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Get returns a preset of a project by name.
+func (s *RunPresetService) Get(ctx context.Context, projectID, name string) (*run.Preset, error) {
+	return s.store.GetRunPreset(ctx, projectID, name)
+}
+
+// List returns the presets of a project.
+func (s *RunPresetService) List(ctx context.Context, projectID string) ([]run.Preset, error) {
+	return s.store.ListRunPresets(ctx, projectID)
+}
+
+// Update replaces the fields of a preset, including its name.
+func (s *RunPresetService) Update(ctx context.Context, projectID, name string, req *run.PresetRequest) (*run.Preset, error) {
+	if err := s.validate(req); err != nil {
+		return nil, err
+	}
+	p, err := s.store.GetRunPreset(ctx, projectID, name)
+	if err != nil {
+		return nil, err
+	}
+	req.ApplyTo(p)
+	if err := s.store.UpdateRunPreset(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Delete removes a preset of a project.
+func (s *RunPresetService) Delete(ctx context.Context, projectID, name string) error {
+	return s.store.DeleteRunPreset(ctx, projectID, name)
+}
+
+// validate checks a preset request and that its policy profile and mode
+// exist.
+func (s *RunPresetService) validate(req *run.PresetRequest) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+	if req.PolicyProfile != "" && s.policies != nil {
+		if _, ok := s.policies.GetProfile(req.PolicyProfile); !ok {
+			return fmt.Errorf("%w: unknown policy profile %q", run.ErrInvalidPreset, req.PolicyProfile)
+		}
+	}
+	if req.Mode != "" && s.modes != nil {
+		if _, err := s.modes.Get(req.Mode); err != nil {
+			return fmt.Errorf("%w: unknown mode %q", run.ErrInvalidPreset, req.Mode)
+		}
+	}
+	return nil
+}
+
+// applyPreset fills the fields a start request leaves empty from the
+// project preset it names. Without an agent, an idle agent of the
+// preset's backend is picked, or else any agent of it; cloned agents are
+// left to scaling.
+func (s *RuntimeService) applyPreset(ctx context.Context, req *run.StartRequest) error {
+	p, err := s.store.GetRunPreset(ctx, req.ProjectID, req.Preset)
+	if errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("%w %q in project %s", ErrUnknownPreset, req.Preset, req.ProjectID)
+	}
+	if err != nil {
+		return fmt.Errorf("get run preset: %w", err)
+	}
+	p.Fill(req)
+	if p.Backend == "" {
+		return nil
+	}
+	if req.AgentID != "" {
+		ag, err := s.store.GetAgent(ctx, req.AgentID)
+		if err != nil {
+			return fmt.Errorf("get agent: %w", err)
+		}
+		if ag.Backend != p.Backend {
+			return fmt.Errorf("%w: agent %s uses %s, preset %q needs %s", ErrPresetBackend, ag.ID, ag.Backend, p.Name, p.Backend)
+		}
+		return nil
+	}
+	agents, err := s.store.ListAgents(ctx, req.ProjectID)
+	if err != nil {
+		return fmt.Errorf("list agents: %w", err)
+	}
+	for i := range agents {
+		a := &agents[i]
+		if a.Backend != p.Backend || a.Ephemeral() {
+			continue
+		}
+		if req.AgentID == "" || a.Status == agent.StatusIdle {
+			req.AgentID = a.ID
+		}
+		if a.Status == agent.StatusIdle {
+			break
+		}
+	}
+	if req.AgentID == "" {
+		return fmt.Errorf("%w: project %s has no %s agent for preset %q", ErrPresetBackend, req.ProjectID, p.Backend, p.Name)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/agent"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestRunPresetService_CRUD(t *testing.T) {
+	_, store, _, _ := newRuntimeTestEnv()
+	svc := service.NewRunPresetService(store, service.NewPolicyService("headless-safe-sandbox", nil), service.NewModeService())
+	ctx := context.Background()
+
+	p, err := svc.Create(ctx, "proj-1", &run.PresetRequest{Name: "quick-fix", Backend: "aider", Mode: "coder", DeliverMode: run.DeliverModePatch})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if p.ID == "" || p.ProjectID != "proj-1" {
+		t.Fatalf("unexpected preset %+v", p)
+	}
+	if _, err := svc.Create(ctx, "proj-1", &run.PresetRequest{Name: "quick-fix"}); !errors.Is(err, domain.ErrConflict) {
+		t.Errorf("expected a name conflict, got %v", err)
+	}
+	for _, req := range []run.PresetRequest{
+		{Name: "1st"},
+		{Name: "docs", Mode: "no-such-mode"},
+		{Name: "docs", PolicyProfile: "no-such-profile"},
+		{Name: "docs", ExecMode: run.ExecModeRemote},
+		{Name: "docs", DeliverMode: run.DeliverModePush},
+	} {
+		if _, err := svc.Create(ctx, "proj-1", &req); !errors.Is(err, run.ErrInvalidPreset) {
+			t.Errorf("create %+v: expected an invalid preset, got %v", req, err)
+		}
+	}
+	if _, err := svc.Create(ctx, "missing", &run.PresetRequest{Name: "docs"}); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected an unknown project, got %v", err)
+	}
+
+	p, err = svc.Update(ctx, "proj-1", "quick-fix", &run.PresetRequest{Name: "quick-patch", DeliverMode: run.DeliverModePatch})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if p.Backend != "" || p.Name != "quick-patch" {
+		t.Errorf("expected the update to replace all fields, got %+v", p)
+	}
+	if err := svc.Delete(ctx, "proj-1", "quick-patch"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if presets, _ := svc.List(ctx, "proj-1"); len(presets) != 0 {
+		t.Errorf("expected no presets, got %d", len(presets))
+	}
+}
+
+func TestStartRun_Preset(t *testing.T) {
+	svc, store, queue, _ := newRuntimeTestEnv()
+	store.agents = append(store.agents,
+		agent.Agent{ID: "agent-2", ProjectID: "proj-1", Name: "busy", Backend: "opencode", Status: agent.StatusRunning},
+		agent.Agent{ID: "agent-3", ProjectID: "proj-1", Name: "idle", Backend: "opencode", Status: agent.StatusIdle},
+	)
+	store.presets = []run.Preset{{
+		ID: "preset-1", ProjectID: "proj-1", Name: "deep-refactor", Backend: "opencode", Mode: "architect",
+		DeliverMode: run.DeliverModePatch, Model: "openai/gpt-4o", ContextBudget: 8192,
+	}}
+	ctx := context.Background()
+
+	r, err := svc.StartRun(ctx, &run.StartRequest{TaskID: "task-1", ProjectID: "proj-1", Preset: "deep-refactor"})
+	if err != nil {
+		t.Fatalf("StartRun failed: %v", err)
+	}
+	if r.AgentID != "agent-3" {
+		t.Errorf("expected the idle opencode agent, got %s", r.AgentID)
+	}
+	if r.DeliverMode != run.DeliverModePatch {
+		t.Errorf("expected the preset's deliver mode, got %q", r.DeliverMode)
+	}
+	msg, ok := queue.lastMessage(messagequeue.SubjectRunStart)
+	if !ok {
+		t.Fatal("expected run start message")
+	}
+	var payload messagequeue.RunStartPayload
+	_ = json.Unmarshal(msg.Data, &payload)
+	if payload.Config["mode"] != "architect" || payload.Config["model"] != "openai/gpt-4o" {
+		t.Errorf("expected the preset's mode and model, got %v", payload.Config)
+	}
+
+	// Fields of the request win over the preset's.
+	store.agents[2].Status = agent.StatusIdle
+	r, err = svc.StartRun(ctx, &run.StartRequest{
+		TaskID: "task-1", AgentID: "agent-2", ProjectID: "proj-1", Preset: "deep-refactor", DeliverMode: run.DeliverModeCommitLocal,
+	})
+	if err != nil {
+		t.Fatalf("StartRun failed: %v", err)
+	}
+	if r.AgentID != "agent-2" || r.DeliverMode != run.DeliverModeCommitLocal {
+		t.Errorf("expected the request's agent and deliver mode, got %s %q", r.AgentID, r.DeliverMode)
+	}
+
+	if _, err := svc.StartRun(ctx, &run.StartRequest{TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", Preset: "deep-refactor"}); !errors.Is(err, service.ErrPresetBackend) {
+		t.Errorf("expected a backend mismatch, got %v", err)
+	}
+	if _, err := svc.StartRun(ctx, &run.StartRequest{TaskID: "task-1", ProjectID: "proj-1", Preset: "missing"}); !errors.Is(err, service.ErrUnknownPreset) {
+		t.Errorf("expected an unknown preset, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+)
+
+// summaryOutputChars bounds the run output sent to the summary model.
+const summaryOutputChars = 8000
+
+const runSummarySystemPrompt = `You summarize the run of a coding agent for the people reviewing it.
+Write concise markdown with exactly these sections, each a few bullets at most:
+### What changed
+### Why
+### Risks
+### Follow-ups
+Only state what the run record supports; write "None" for an empty section.`
+
+// summarizeRun asks the summary model for a natural language summary of a
+// finished run and stores it on the run record. It is skipped when no
+// summary model is configured, when the run already has a summary, and when
+// less than runtime.summary_min_budget of the run's max_cost is left.
+// Failures are logged; the run finishes without a summary.
+func (s *RuntimeService) summarizeRun(ctx context.Context, r *run.Run, status run.Status, output, errMsg string, costUSD float64) {
+	if s.summarizer == nil || s.runtimeCfg.SummaryModel == "" || r.Summary != "" {
+		return
+	}
+	if profile, ok := s.policy.GetProfile(r.Policy()); ok {
+		if maxCost := profile.Termination.MaxCost; maxCost > 0 && maxCost-costUSD < maxCost*s.runtimeCfg.SummaryMinBudget {
+			slog.Info("run summary skipped, cost budget low", "run_id", r.ID, "cost", costUSD, "max_cost", maxCost)
+			return
+		}
+	}
+	resp, err := s.summarizer.ChatCompletion(ctx, litellm.ChatCompletionRequest{
+		Model: s.runtimeCfg.SummaryModel,
+		Messages: []litellm.ChatMessage{
+			{Role: "system", Content: runSummarySystemPrompt},
+			{Role: "user", Content: s.renderRunRecord(ctx, r, status, output, errMsg)},
+		},
+		Temperature: 0.2,
+		MaxTokens:   600,
+	})
+	if err != nil {
+		slog.Error("run summary", "run_id", r.ID, "error", err)
+		return
+	}
+	summary := strings.TrimSpace(resp.Content)
+	if summary == "" {
+		return
+	}
+	if err := s.store.SetRunSummary(ctx, r.ID, summary); err != nil {
+		slog.Error("store run summary", "run_id", r.ID, "error", err)
+		return
+	}
+	r.Summary = summary
+	slog.Info("run summarized", "run_id", r.ID, "model", resp.Model)
+}
+
+// renderRunRecord formats a finished run for the summary model: its task,
+// outcome, diff and quality gate result, and its final output.
+func (s *RuntimeService) renderRunRecord(ctx context.Context, r *run.Run, status run.Status, output, errMsg string) string {
+	var b strings.Builder
+	if t, err := s.store.GetTask(ctx, r.TaskID); err == nil {
+		fmt.Fprintf(&b, "Task: %s\n", t.Title)
+		if t.Prompt != "" {
+			fmt.Fprintf(&b, "Prompt:\n%s\n", t.Prompt)
+		}
+	}
+	fmt.Fprintf(&b, "\nStatus: %s\n", status)
+	if errMsg != "" {
+		fmt.Fprintf(&b, "Error: %s\n", errMsg)
+	}
+
+	events, err := s.loadRunEvents(ctx, r.ID)
+	if err != nil {
+		slog.Debug("run summary without events", "run_id", r.ID, "error", err)
+	}
+	for i := range events {
+		var p map[string]string
+		if err := json.Unmarshal(events[i].Payload, &p); err != nil {
+			continue
+		}
+		switch events[i].Type {
+		case event.TypeRunDiffStat:
+			fmt.Fprintf(&b, "Diff: %s files, +%s -%s\n", p["files"], p["insertions"], p["deletions"])
+			if p["paths"] != "" {
+				fmt.Fprintf(&b, "Changed files:\n%s\n", p["paths"])
+			}
+		case event.TypeQualityGatePassed:
+			b.WriteString("Quality gate: passed\n")
+		case event.TypeQualityGateFailed:
+			fmt.Fprintf(&b, "Quality gate: failed (%s)\n", p["error"])
+		}
+	}
+
+	if len(output) > summaryOutputChars {
+		output = output[len(output)-summaryOutputChars:]
+	}
+	if output != "" {
+		fmt.Fprintf(&b, "\nFinal output of the agent:\n%s\n", output)
+	}
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
+	"github.com/Strob0t/CodeForge/internal/adapter/ws"
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestHandleRunComplete_Summary(t *testing.T) {
+	var prompts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		prompts = append(prompts, body.Messages[len(body.Messages)-1].Content)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"content": "### What changed\n- Guarded the nil pointer\n"}}},
+		})
+	}))
+	defer srv.Close()
+
+	_, store, _, _ := newRuntimeTestEnv()
+	bc := &runtimeMockBroadcaster{}
+	cfg := config.Runtime{StallThreshold: 5, SummaryModel: "summary-model", SummaryMinBudget: 0.1}
+	svc := service.NewRuntimeService(store, &runtimeMockQueue{}, bc, &runtimeMockEventStore{}, service.NewPolicyService("headless-safe-sandbox", nil), &cfg)
+	svc.SetSummarizer(litellm.NewClient(srv.URL, ""))
+	ctx := context.Background()
+
+	// complete starts a run under a profile with max_cost 50 and completes
+	// it at the given cost.
+	complete := func(costUSD float64) *run.Run {
+		t.Helper()
+		r, err := svc.StartRun(ctx, &run.StartRequest{TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", PolicyProfile: "trusted-mount-autonomous"})
+		if err != nil {
+			t.Fatalf("StartRun failed: %v", err)
+		}
+		if err := svc.HandleRunComplete(ctx, &messagequeue.RunCompletePayload{
+			RunID: r.ID, TaskID: "task-1", ProjectID: "proj-1", Status: string(run.StatusCompleted),
+			Output: "Added a nil check to the handler", CostUSD: costUSD, StepCount: 3,
+		}); err != nil {
+			t.Fatalf("HandleRunComplete failed: %v", err)
+		}
+		done, err := store.GetRun(ctx, r.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return done
+	}
+
+	r := complete(1)
+	if r.Summary != "### What changed\n- Guarded the nil pointer" {
+		t.Fatalf("expected the summary stored on the run, got %q", r.Summary)
+	}
+	if len(prompts) != 1 || !strings.Contains(prompts[0], "Task: Fix bug") || !strings.Contains(prompts[0], "Added a nil check") {
+		t.Fatalf("expected the task and output in the prompt, got %q", prompts)
+	}
+	var status *ws.RunStatusEvent
+	for _, ev := range bc.events {
+		if e, ok := ev.Data.(ws.RunStatusEvent); ok && e.RunID == r.ID && e.Status == string(run.StatusCompleted) {
+			status = &e
+		}
+	}
+	if status == nil || status.Summary != r.Summary {
+		t.Fatalf("expected the summary in the run status event, got %+v", status)
+	}
+
+	// Less than 10% of the $50 budget left: no summary.
+	if r := complete(46); r.Summary != "" || len(prompts) != 1 {
+		t.Fatalf("expected no summary with a low budget, got %q after %d calls", r.Summary, len(prompts))
+	}
+}
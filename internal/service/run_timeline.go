@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+)
+
+// llmTool is the tool name workers request LLM calls with.
+const llmTool = "LLM"
+
+// RunTimeline returns the execution of a run segmented into spans, derived
+// from its recorded events.
+func (s *RuntimeService) RunTimeline(ctx context.Context, id string) (*run.Timeline, error) {
+	r, err := s.store.GetRun(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get run: %w", err)
+	}
+	events, err := s.loadRunEvents(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("load run events: %w", err)
+	}
+	return buildTimeline(r, events, time.Now()), nil
+}
+
+// buildTimeline folds a run's events into spans: context building until
+// run.started, each tool call from its approval to its result (LLM calls,
+// commands and other tools), "ask" decisions until the run's next event,
+// the quality gate and the delivery.
+func buildTimeline(r *run.Run, events []event.AgentEvent, now time.Time) *run.Timeline {
+	t := &run.Timeline{RunID: r.ID, Status: r.Status, StartedAt: r.StartedAt, EndedAt: now}
+	if r.CompletedAt != nil {
+		t.EndedAt = *r.CompletedAt
+	}
+
+	calls := make(map[string]int)        // Call ID -> index of its open span
+	phases := make(map[run.SpanKind]int) // Quality gate and delivery -> index of the open span
+	approval := -1                       // Open "ask" span, ended by the next event
+	open := func(s run.Span) int {
+		t.Spans = append(t.Spans, s)
+		return len(t.Spans) - 1
+	}
+	closeSpan := func(i int, end time.Time, status string) {
+		t.Spans[i].End = end
+		t.Spans[i].Status = status
+	}
+
+	for i := range events {
+		ev := &events[i]
+		if approval >= 0 {
+			closeSpan(approval, ev.CreatedAt, run.SpanOK)
+			approval = -1
+		}
+		var p map[string]string
+		_ = json.Unmarshal(ev.Payload, &p)
+
+		switch ev.Type {
+		case event.TypeRunStarted:
+			open(run.Span{Kind: run.SpanContext, Name: "context", Status: run.SpanOK, Start: r.StartedAt, End: ev.CreatedAt})
+		case event.TypeToolCallApproved:
+			calls[p["call_id"]] = open(run.Span{
+				Kind: toolSpanKind(p), Name: p["tool"], CallID: p["call_id"], Detail: toolSpanDetail(p), Start: ev.CreatedAt,
+			})
+		case event.TypeToolCallDenied:
+			if policy.Decision(p["decision"]) == policy.DecisionAsk {
+				approval = open(run.Span{Kind: run.SpanApproval, Name: p["tool"], CallID: p["call_id"], Detail: toolSpanDetail(p), Start: ev.CreatedAt})
+				continue
+			}
+			open(run.Span{
+				Kind: toolSpanKind(p), Name: p["tool"], CallID: p["call_id"], Detail: toolSpanDetail(p),
+				Status: run.SpanDenied, Start: ev.CreatedAt, End: ev.CreatedAt,
+			})
+		case event.TypeToolCallResultEv:
+			if idx, ok := calls[p["call_id"]]; ok {
+				status := run.SpanOK
+				if p["success"] != "true" {
+					status = run.SpanFailed
+				}
+				closeSpan(idx, ev.CreatedAt, status)
+				delete(calls, p["call_id"])
+			}
+		case event.TypeQualityGateStarted:
+			phases[run.SpanQualityGate] = open(run.Span{Kind: run.SpanQualityGate, Name: "quality gate", Start: ev.CreatedAt})
+		case event.TypeQualityGatePassed, event.TypeQualityGateFailed:
+			if idx, ok := phases[run.SpanQualityGate]; ok {
+				closeSpan(idx, ev.CreatedAt, phaseStatus(ev.Type == event.TypeQualityGatePassed))
+				delete(phases, run.SpanQualityGate)
+			}
+		case event.TypeDeliveryStarted:
+			phases[run.SpanDelivery] = open(run.Span{Kind: run.SpanDelivery, Name: "delivery", Detail: p["mode"], Start: ev.CreatedAt})
+		case event.TypeDeliveryCompleted, event.TypeDeliveryFailed:
+			if idx, ok := phases[run.SpanDelivery]; ok {
+				closeSpan(idx, ev.CreatedAt, phaseStatus(ev.Type == event.TypeDeliveryCompleted))
+				delete(phases, run.SpanDelivery)
+			}
+		}
+	}
+	if approval >= 0 {
+		closeSpan(approval, t.EndedAt, run.SpanOK)
+	}
+	t.Finish()
+	return t
+}
+
+// toolSpanKind classifies a tool call event payload.
+func toolSpanKind(p map[string]string) run.SpanKind {
+	switch {
+	case p["tool"] == llmTool:
+		return run.SpanLLM
+	case p["command"] != "":
+		return run.SpanExec
+	}
+	return run.SpanTool
+}
+
+// toolSpanDetail returns the command or path of a tool call other than an
+// LLM call.
+func toolSpanDetail(p map[string]string) string {
+	if p["tool"] == llmTool {
+		return ""
+	}
+	if p["command"] != "" {
+		return p["command"]
+	}
+	return p["path"]
+}
+
+// phaseStatus returns the status of a finished quality gate or delivery.
+func phaseStatus(ok bool) string {
+	if ok {
+		return run.SpanOK
+	}
+	return run.SpanFailed
+}
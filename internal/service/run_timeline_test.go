@@ -0,0 +1,68 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+)
+
+func TestBuildTimeline(t *testing.T) {
+	start := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	at := func(sec int) time.Time { return start.Add(time.Duration(sec) * time.Second) }
+	ev := func(sec int, typ event.Type, payload map[string]string) event.AgentEvent {
+		data, _ := json.Marshal(payload)
+		return event.AgentEvent{RunID: "r1", Type: typ, Payload: data, CreatedAt: at(sec)}
+	}
+	done := at(100)
+	r := &run.Run{ID: "r1", Status: run.StatusCompleted, StartedAt: start, CompletedAt: &done}
+	events := []event.AgentEvent{
+		ev(5, event.TypeRunStarted, nil),
+		ev(5, event.TypeToolCallApproved, map[string]string{"call_id": "c1", "tool": "LLM", "command": "completion"}),
+		ev(35, event.TypeToolCallResultEv, map[string]string{"call_id": "c1", "tool": "LLM", "success": "true"}),
+		ev(36, event.TypeToolCallApproved, map[string]string{"call_id": "c2", "tool": "Bash", "command": "go test ./..."}),
+		ev(56, event.TypeToolCallResultEv, map[string]string{"call_id": "c2", "tool": "Bash", "success": "false"}),
+		ev(57, event.TypeToolCallDenied, map[string]string{"call_id": "c3", "tool": "Write", "path": "go.mod", "decision": "ask"}),
+		ev(67, event.TypeToolCallApproved, map[string]string{"call_id": "c4", "tool": "Edit", "path": "main.go"}),
+		ev(68, event.TypeToolCallResultEv, map[string]string{"call_id": "c4", "tool": "Edit", "success": "true"}),
+		ev(70, event.TypeQualityGateStarted, nil),
+		ev(90, event.TypeQualityGatePassed, nil),
+		ev(91, event.TypeDeliveryStarted, map[string]string{"mode": "pr"}),
+	}
+
+	tl := buildTimeline(r, events, at(1000))
+	if tl.DurationMS != 100_000 {
+		t.Fatalf("expected the run's 100s, got %dms", tl.DurationMS)
+	}
+	want := []struct {
+		kind   run.SpanKind
+		status string
+		offset int64
+		dur    int64
+	}{
+		{run.SpanContext, run.SpanOK, 0, 5_000},
+		{run.SpanLLM, run.SpanOK, 5_000, 30_000},
+		{run.SpanExec, run.SpanFailed, 36_000, 20_000},
+		{run.SpanApproval, run.SpanOK, 57_000, 10_000},
+		{run.SpanTool, run.SpanOK, 67_000, 1_000},
+		{run.SpanQualityGate, run.SpanOK, 70_000, 20_000},
+		{run.SpanDelivery, run.SpanOpen, 91_000, 9_000},
+	}
+	if len(tl.Spans) != len(want) {
+		t.Fatalf("expected %d spans, got %+v", len(want), tl.Spans)
+	}
+	for i, w := range want {
+		s := tl.Spans[i]
+		if s.Kind != w.kind || s.Status != w.status || s.OffsetMS != w.offset || s.DurationMS != w.dur {
+			t.Errorf("span %d: expected %s %s +%d %dms, got %s %s +%d %dms", i, w.kind, w.status, w.offset, w.dur, s.Kind, s.Status, s.OffsetMS, s.DurationMS)
+		}
+	}
+	if tl.Spans[2].Detail != "go test ./..." || tl.Spans[3].Detail != "go.mod" || tl.Spans[1].Detail != "" {
+		t.Errorf("unexpected details %q %q %q", tl.Spans[1].Detail, tl.Spans[2].Detail, tl.Spans[3].Detail)
+	}
+	if tl.TotalsMS[run.SpanLLM] != 30_000 || tl.UnaccountedMS != 5_000 {
+		t.Errorf("unexpected totals %v, unaccounted %dms", tl.TotalsMS, tl.UnaccountedMS)
+	}
+}
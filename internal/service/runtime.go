@@ -3,26 +3,48 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
 	"github.com/Strob0t/CodeForge/internal/adapter/ws"
 	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/a2a"
 	"github.com/Strob0t/CodeForge/internal/domain/agent"
 	cfcontext "github.com/Strob0t/CodeForge/internal/domain/context"
+	"github.com/Strob0t/CodeForge/internal/domain/conversation"
+	"github.com/Strob0t/CodeForge/internal/domain/cost"
 	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/featureflag"
+	"github.com/Strob0t/CodeForge/internal/domain/mode"
 	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+	"github.com/Strob0t/CodeForge/internal/domain/routing"
 	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/seatbelt"
+	"github.com/Strob0t/CodeForge/internal/domain/secret"
+	"github.com/Strob0t/CodeForge/internal/domain/snapshot"
 	"github.com/Strob0t/CodeForge/internal/domain/task"
 	"github.com/Strob0t/CodeForge/internal/logger"
 	"github.com/Strob0t/CodeForge/internal/port/broadcast"
 	"github.com/Strob0t/CodeForge/internal/port/database"
 	"github.com/Strob0t/CodeForge/internal/port/eventstore"
 	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/redact"
 )
 
+// runRepoMapFiles is the number of files in the repo map added to runs
+// with graph RAG.
+const runRepoMapFiles = 50
+
 // RuntimeService orchestrates the step-by-step execution protocol between
 // Go (control plane) and Python (execution plane).
 type RuntimeService struct {
@@ -33,9 +55,55 @@ type RuntimeService struct {
 	policy        *PolicyService
 	deliver       *DeliverService
 	contextOpt    *ContextOptimizerService
+	microagents   *MicroagentService
+	tests         *TestRunnerService
+	lint          *LintService
+	snapshots     *SnapshotService
+	projects      *ProjectService
+	secrets       *SecretService
+	redaction     *redact.Pipeline
+	retention     *RetentionService
+	sandbox       *SandboxService
+	tenants       *TenantService
+	routing       *RoutingService
+	graph         *GraphService
+	flags         *FeatureFlagService
+	modes         *ModeService
+	conversations *ConversationService
+	attachments   *AttachmentService
+	summarizer    *litellm.Client
+	dedupe        *WorkerDedupe
 	onRunComplete func(ctx context.Context, runID string, status run.Status)
 	runtimeCfg    *config.Runtime
 	stallTrackers sync.Map // map[runID]*run.StallTracker
+	runSecrets    sync.Map // map[runID]*runSecrets
+	outputRepairs sync.Map // map[runID]*outputRepair
+	toolCalls     sync.Map // map[runID+"/"+callID]*pendingToolCall
+	owned         sync.Map // map[runID]struct{}: runs started or resumed by this server
+	draining      atomic.Bool
+}
+
+// pendingToolCall is an approved tool call of a conversation run awaiting
+// its result.
+type pendingToolCall struct {
+	call    conversation.ToolCall
+	started time.Time
+}
+
+// runSecrets holds the guard and redactor for the secrets injected into a run.
+type runSecrets struct {
+	guard    *secret.Guard
+	redactor *secret.Redactor
+}
+
+// outputRepair is what a run needs to be sent back to its agent when its
+// final output violates the mode's output schema or lacks citations.
+type outputRepair struct {
+	start     messagequeue.RunStartPayload
+	citations []string // Chunk IDs the answer must cite from; nil if it need not cite
+	attempts  int
+	costUSD   float64 // Spent by the attempts before the current one
+	steps     int
 }
 
 // NewRuntimeService creates a RuntimeService with all dependencies.
@@ -62,11 +130,113 @@ func (s *RuntimeService) SetDeliverService(d *DeliverService) {
 	s.deliver = d
 }
 
+// SetSnapshotService sets the snapshot service used for automatic workspace snapshots.
+func (s *RuntimeService) SetSnapshotService(sn *SnapshotService) {
+	s.snapshots = sn
+}
+
+// SetProjectService sets the project service used to allocate and prune run worktrees.
+func (s *RuntimeService) SetProjectService(p *ProjectService) {
+	s.projects = p
+}
+
+// SetAttachmentService sets the attachment service used to write task
+// attachments into run workspaces.
+func (s *RuntimeService) SetAttachmentService(a *AttachmentService) {
+	s.attachments = a
+}
+
+// SetSecretService sets the secret service used to inject agent secrets into runs.
+func (s *RuntimeService) SetSecretService(sec *SecretService) {
+	s.secrets = sec
+}
+
+// SetRedaction sets the pipeline masking credentials in run output.
+func (s *RuntimeService) SetRedaction(p *redact.Pipeline) {
+	s.redaction = p
+}
+
+// SetWorkerDedupe sets the dedupe that drops worker messages published more
+// than once.
+func (s *RuntimeService) SetWorkerDedupe(d *WorkerDedupe) {
+	s.dedupe = d
+}
+
+// SetRetentionService sets the retention service used to read the events of
+// archived runs.
+func (s *RuntimeService) SetRetentionService(r *RetentionService) {
+	s.retention = r
+}
+
+// SetSandboxService sets the service that launches sandbox-mode runs in
+// their own sandbox. Without it, sandbox-mode runs go to the worker pool.
+func (s *RuntimeService) SetSandboxService(sb *SandboxService) {
+	s.sandbox = sb
+}
+
+// SetSummarizer lets finished runs be summarized by the runtime's summary
+// model.
+func (s *RuntimeService) SetSummarizer(llm *litellm.Client) {
+	s.summarizer = llm
+}
+
+// SetTenantService enables quota admission for new runs.
+func (s *RuntimeService) SetTenantService(t *TenantService) {
+	s.tenants = t
+}
+
+// SetRoutingService routes the model of runs started with a task type and
+// no explicit model.
+func (s *RuntimeService) SetRoutingService(r *RoutingService) {
+	s.routing = r
+}
+
+// SetGraphService sets the service that builds the repo map added to the
+// context of runs when graph RAG is enabled.
+func (s *RuntimeService) SetGraphService(g *GraphService) {
+	s.graph = g
+}
+
+// SetFeatureFlagService switches experimental run features per tenant and
+// project. Without it, all flags have their defaults.
+func (s *RuntimeService) SetFeatureFlagService(f *FeatureFlagService) {
+	s.flags = f
+}
+
+// SetModeService sets the modes whose output schemas runs must satisfy.
+func (s *RuntimeService) SetModeService(m *ModeService) {
+	s.modes = m
+}
+
 // SetContextOptimizer sets the context optimizer for building context packs before runs.
 func (s *RuntimeService) SetContextOptimizer(co *ContextOptimizerService) {
 	s.contextOpt = co
 }
 
+// SetMicroagentService injects the knowledge of triggered microagents
+// into the prompt of started runs.
+func (s *RuntimeService) SetMicroagentService(m *MicroagentService) {
+	s.microagents = m
+}
+
+// SetTestRunner sets the service that stores the test results of quality
+// gates.
+func (s *RuntimeService) SetTestRunner(t *TestRunnerService) {
+	s.tests = t
+}
+
+// SetLintService sets the service that runs linters in quality gates and
+// records their findings.
+func (s *RuntimeService) SetLintService(l *LintService) {
+	s.lint = l
+}
+
+// SetConversationService records the tool calls and answers of runs
+// started in a conversation as its messages.
+func (s *RuntimeService) SetConversationService(c *ConversationService) {
+	s.conversations = c
+}
+
 // SetOnRunComplete registers a callback invoked after a run reaches a terminal state.
 // Used by the OrchestratorService to advance execution plans.
 func (s *RuntimeService) SetOnRunComplete(fn func(context.Context, string, run.Status)) {
@@ -75,6 +245,14 @@ func (s *RuntimeService) SetOnRunComplete(fn func(context.Context, string, run.S
 
 // StartRun creates a new run in the database and publishes a start message to NATS.
 func (s *RuntimeService) StartRun(ctx context.Context, req *run.StartRequest) (*run.Run, error) {
+	if s.draining.Load() {
+		return nil, ErrDraining
+	}
+	if req.Preset != "" {
+		if err := s.applyPreset(ctx, req); err != nil {
+			return nil, err
+		}
+	}
 	if err := req.Validate(); err != nil {
 		return nil, fmt.Errorf("validate start request: %w", err)
 	}
@@ -84,22 +262,43 @@ func (s *RuntimeService) StartRun(ctx context.Context, req *run.StartRequest) (*
 		req.ExecMode = run.ExecModeMount
 	}
 
-	// Default policy profile
+	// Verify agent exists
+	ag, err := s.store.GetAgent(ctx, req.AgentID)
+	if err != nil {
+		return nil, fmt.Errorf("get agent: %w", err)
+	}
+
+	// A2A agents run remotely, as plan steps delegated by the orchestrator.
+	if (ag.Backend == a2a.BackendName) != (req.ExecMode == run.ExecModeRemote) {
+		return nil, ErrRemoteExecMode
+	}
+
+	// Resolve policy: the tenant default layered with project and agent
+	// overrides, and the requested profile on top. The most specific layer
+	// with a matching rule decides a tool call; no layer can weaken the
+	// isolation or egress of the layers below it. The run keeps the
+	// requested profile and records the layers as its effective policy.
+	eff, err := s.resolvePolicy(ctx, req.ProjectID, ag, req.PolicyProfile)
+	if err != nil {
+		return nil, fmt.Errorf("resolve policy: %w", err)
+	}
 	profileName := req.PolicyProfile
 	if profileName == "" {
-		profileName = s.policy.DefaultProfile()
+		profileName = eff.Layers[len(eff.Layers)-1].Profile
 	}
-
-	// Verify policy profile exists
-	profile, ok := s.policy.GetProfile(profileName)
+	profile, ok := s.policy.GetProfile(eff.Name)
 	if !ok {
-		return nil, fmt.Errorf("unknown policy profile %q", profileName)
+		return nil, fmt.Errorf("unknown policy profile %q", eff.Name)
 	}
+	profile.Isolation = policy.StrongestIsolation(profile.Isolation, eff.Isolation)
 
-	// Verify agent exists
-	ag, err := s.store.GetAgent(ctx, req.AgentID)
-	if err != nil {
-		return nil, fmt.Errorf("get agent: %w", err)
+	// Untrusted profiles never run outside a hardened sandbox. Remote
+	// runs execute nothing locally.
+	if profile.Untrusted() && req.ExecMode != run.ExecModeRemote {
+		if s.sandbox == nil {
+			return nil, fmt.Errorf("policy profile %q requires %s isolation but no sandbox driver is configured", profileName, profile.Isolation)
+		}
+		req.ExecMode = run.ExecModeSandbox
 	}
 
 	// Verify task exists
@@ -108,26 +307,94 @@ func (s *RuntimeService) StartRun(ctx context.Context, req *run.StartRequest) (*
 		return nil, fmt.Errorf("get task: %w", err)
 	}
 
-	// Default deliver mode from config
+	// A conversation recording the run must belong to its project
+	if req.ConversationID != "" {
+		c, err := s.store.GetConversation(ctx, req.ConversationID)
+		if err != nil {
+			return nil, fmt.Errorf("get conversation: %w", err)
+		}
+		if c.ProjectID != req.ProjectID {
+			return nil, fmt.Errorf("conversation %s in project %s: %w", c.ID, req.ProjectID, domain.ErrNotFound)
+		}
+	}
+
+	// Resolve the agent's secrets before creating the run so a missing
+	// secret fails fast
+	env, err := s.resolveSecrets(ctx, req.ProjectID, ag)
+	if err != nil {
+		return nil, err
+	}
+
+	// Route the model by task type unless the request names one
+	if req.TaskType != "" && req.Model == "" && s.routing != nil {
+		model, err := s.routing.Pick(ctx, routing.Request{TaskType: routing.TaskType(req.TaskType), ProjectID: req.ProjectID})
+		if err != nil {
+			return nil, fmt.Errorf("route model: %w", err)
+		}
+		req.Model = model
+	}
+
+	// Admit the run against the quota of the project's tenant
+	if s.tenants != nil {
+		if err := s.tenants.AdmitRun(ctx, req.ProjectID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Default deliver mode from config. Runs on an existing branch deliver
+	// only when asked to, as the default modes branch off their own.
 	deliverMode := req.DeliverMode
-	if deliverMode == "" && s.runtimeCfg.DefaultDeliverMode != "" {
+	if deliverMode == "" && req.Branch == "" && s.runtimeCfg.DefaultDeliverMode != "" {
 		deliverMode = run.DeliverMode(s.runtimeCfg.DefaultDeliverMode)
 	}
 
+	// The project may restrict its runs' deliver modes, e.g. to mirror
+	// delivery where the upstream host cannot be pushed to
+	proj, err := s.store.GetProject(ctx, req.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+	if !run.AllowsDeliverMode(proj.Config, deliverMode) {
+		return nil, fmt.Errorf("%w: %q (allowed: %s)", run.ErrDeliverModeNotAllowed, deliverMode, proj.Config[run.ConfigDeliverModes])
+	}
+	if err := s.checkVCSAccount(ctx, proj.ID, deliverMode, req.Branch); err != nil {
+		return nil, err
+	}
+
 	// Create run in DB
 	r := &run.Run{
-		TaskID:        req.TaskID,
-		AgentID:       req.AgentID,
-		ProjectID:     req.ProjectID,
-		TeamID:        req.TeamID,
-		PolicyProfile: profileName,
-		ExecMode:      req.ExecMode,
-		DeliverMode:   deliverMode,
-		Status:        run.StatusPending,
+		TaskID:          req.TaskID,
+		AgentID:         req.AgentID,
+		ProjectID:       req.ProjectID,
+		TeamID:          req.TeamID,
+		ConversationID:  req.ConversationID,
+		PlanStepID:      req.PlanStepID,
+		PolicyProfile:   profileName,
+		EffectivePolicy: eff.Name,
+		ExecMode:        req.ExecMode,
+		DeliverMode:     deliverMode,
+		Branch:          req.Branch,
+		Status:          run.StatusPending,
 	}
 	if err := s.store.CreateRun(ctx, r); err != nil {
 		return nil, fmt.Errorf("create run: %w", err)
 	}
+	s.owned.Store(r.ID, struct{}{})
+
+	// Allocate an isolated worktree so concurrent runs do not share one
+	// clone. Runs on a branch always get one, checked out at its head, and
+	// runs from a snapshot get one to restore it into.
+	if (req.Isolate || req.Branch != "" || req.FromSnapshot != "" || s.runtimeCfg.WorktreeIsolation) && req.ExecMode != run.ExecModeRemote {
+		err := s.allocateWorktree(ctx, r)
+		if err == nil && req.FromSnapshot != "" {
+			err = s.restoreSnapshot(ctx, r, req.FromSnapshot)
+		}
+		if err != nil {
+			_ = s.store.CompleteRun(ctx, r.ID, run.StatusFailed, "", err.Error(), 0, 0)
+			s.owned.Delete(r.ID)
+			return nil, err
+		}
+	}
 
 	// Mark run as running
 	if err := s.store.UpdateRunStatus(ctx, r.ID, run.StatusRunning, 0, 0); err != nil {
@@ -141,13 +408,20 @@ func (s *RuntimeService) StartRun(ctx context.Context, req *run.StartRequest) (*
 	// Mark task as running
 	_ = s.store.UpdateTaskStatus(ctx, req.TaskID, task.StatusRunning)
 
-	// Create stall tracker if policy enables stall detection
-	if profile.Termination.StallDetection {
-		threshold := profile.Termination.StallThreshold
-		if threshold <= 0 {
-			threshold = s.runtimeCfg.StallThreshold
-		}
-		s.stallTrackers.Store(r.ID, run.NewStallTracker(threshold))
+	s.trackStalls(r.ID, &profile)
+	if len(env) > 0 {
+		s.runSecrets.Store(r.ID, newRunSecrets(env))
+	}
+
+	// Remote runs are driven by the orchestrator, not a worker.
+	if req.ExecMode == run.ExecModeRemote {
+		s.announceRun(ctx, r, profile, ag, req.Model)
+		return r, nil
+	}
+
+	prompt := t.Prompt
+	if req.Prompt != "" {
+		prompt = req.Prompt
 	}
 
 	// Publish run start to NATS
@@ -156,11 +430,13 @@ func (s *RuntimeService) StartRun(ctx context.Context, req *run.StartRequest) (*
 		TaskID:        t.ID,
 		ProjectID:     t.ProjectID,
 		AgentID:       ag.ID,
-		Prompt:        t.Prompt,
-		PolicyProfile: profileName,
+		Prompt:        prompt,
+		PolicyProfile: eff.Name,
 		ExecMode:      string(req.ExecMode),
 		DeliverMode:   string(deliverMode),
-		Config:        ag.Config,
+		WorkspacePath: r.WorktreePath,
+		Config:        runConfig(ag.Config, req.Model, req.Mode),
+		Env:           env,
 		Termination: messagequeue.TerminationPayload{
 			MaxSteps:       profile.Termination.MaxSteps,
 			TimeoutSeconds: profile.Termination.TimeoutSeconds,
@@ -168,28 +444,142 @@ func (s *RuntimeService) StartRun(ctx context.Context, req *run.StartRequest) (*
 		},
 	}
 
+	// Scope the run to the task's (or agent's) sub-project, if any.
+	sp := subProjectScope(ctx, s.store, t, ag)
+	if sp != nil {
+		payload.SubProject = &messagequeue.SubProjectPayload{
+			Path:         sp.Path,
+			Language:     sp.Language,
+			BuildCommand: sp.BuildCommand,
+			TestCommand:  sp.TestCommand,
+		}
+	}
+
 	// Build context pack if context optimizer is available.
+	var (
+		citable []string
+		pack    *cfcontext.ContextPack
+	)
+	scope := PackScope{
+		SubProject: sp,
+		Model:      payload.Config["model"],
+		Mode:       payload.Config["mode"],
+		Budget:     req.ContextBudget,
+	}
 	if s.contextOpt != nil {
-		pack, packErr := s.contextOpt.BuildContextPack(ctx, req.TaskID, req.ProjectID, req.TeamID)
+		var packErr error
+		pack, packErr = s.contextOpt.BuildScopedContextPack(ctx, t, req.ProjectID, req.TeamID, scope)
 		if packErr != nil {
 			slog.Warn("context pack build failed", "run_id", r.ID, "error", packErr)
 		} else if pack != nil && len(pack.Entries) > 0 {
 			payload.Context = toContextEntryPayloads(pack.Entries)
+			citable = citationIDs(pack.Entries)
+			if ids := memoryIDs(pack.Entries); len(ids) > 0 {
+				s.appendRunEvent(ctx, event.TypeMemoriesRecalled, r, map[string]string{
+					"memory_ids": strings.Join(ids, ","),
+					"count":      strconv.Itoa(len(ids)),
+				})
+			}
+		}
+	}
+
+	// Add the repo map of the code graph so the agent sees the structure
+	// of the project beyond the files in the context pack, within what the
+	// pack left of the token budget.
+	if s.graph != nil && s.flags.Enabled(ctx, featureflag.GraphRAG, t.ProjectID) {
+		if m, err := s.graph.RepoMap(ctx, t.ProjectID, runRepoMapFiles); err != nil {
+			slog.Warn("repo map failed", "run_id", r.ID, "error", err)
+		} else if e, ok := s.contextOpt.RepoMapEntry(m, scope, pack); ok {
+			payload.Context = append(payload.Context, toContextEntryPayloads([]cfcontext.ContextEntry{e})...)
+		}
+	}
+	if s.flags.Enabled(ctx, featureflag.LSP, t.ProjectID) {
+		payload.Config = maps.Clone(payload.Config)
+		if payload.Config == nil {
+			payload.Config = map[string]string{}
+		}
+		payload.Config["lsp"] = "true"
+	}
+
+	// Inject the knowledge of microagents triggered by the task prompt,
+	// the workspace's changed files or the event that started the run.
+	if s.microagents != nil {
+		if agents, acts := s.microagents.ActivateForRun(ctx, r, prompt, req.TriggerEvent); len(acts) > 0 {
+			payload.Prompt += "\n\n" + microagentSection(agents)
+			names, reasons := make([]string, len(acts)), make([]string, len(acts))
+			for i := range acts {
+				names[i], reasons[i] = acts[i].Name, acts[i].Reason
+			}
+			s.appendRunEvent(ctx, event.TypeMicroagentsActivated, r, map[string]string{
+				"microagent_ids": strings.Join(activationIDs(acts), ","),
+				"names":          strings.Join(names, ","),
+				"reasons":        strings.Join(reasons, ","),
+				"count":          strconv.Itoa(len(acts)),
+			})
 		}
 	}
 
-	if err := s.publishJSON(ctx, messagequeue.SubjectRunStart, payload); err != nil {
+	// Make the task's attachments available in the workspace.
+	if s.attachments != nil {
+		s.materializeAttachments(ctx, r, &payload)
+	}
+
+	// Ask for the final answer in the mode's output format, and runs that
+	// only read and answer (plan permission mode) to cite the retrieved
+	// chunks they were given. The start payload is kept to send the run
+	// back if the answer violates either; a sandbox ends with its run, so
+	// sandboxed runs are not repaired.
+	schema := s.outputSchema(payload.Config["mode"])
+	if schema != nil {
+		payload.Prompt += "\n\n" + schema.Instructions()
+	}
+	if profile.Mode != policy.ModePlan {
+		citable = nil
+	}
+	if len(citable) > 0 {
+		payload.Prompt += "\n\n" + retrieval.CitationInstructions()
+	}
+	if (schema != nil || len(citable) > 0) && req.ExecMode != run.ExecModeSandbox {
+		s.outputRepairs.Store(r.ID, &outputRepair{start: payload, citations: citable})
+	}
+
+	if req.ExecMode == run.ExecModeSandbox && s.sandbox != nil {
+		if err := s.sandbox.Launch(ctx, r, &payload, &profile); err != nil {
+			_ = s.store.CompleteRun(ctx, r.ID, run.StatusFailed, "", err.Error(), 0, 0)
+			return nil, fmt.Errorf("launch sandbox: %w", err)
+		}
+	} else if err := s.publishJSON(ctx, messagequeue.SubjectRunStart, payload); err != nil {
 		return nil, fmt.Errorf("publish run start: %w", err)
 	}
 
-	// Record event
+	s.announceRun(ctx, r, profile, ag, req.Model)
+	return r, nil
+}
+
+// trackStalls creates the stall tracker of a run if its policy enables
+// stall detection.
+func (s *RuntimeService) trackStalls(runID string, profile *policy.PolicyProfile) {
+	if !profile.Termination.StallDetection {
+		return
+	}
+	threshold := profile.Termination.StallThreshold
+	if threshold <= 0 {
+		threshold = s.runtimeCfg.StallThreshold
+	}
+	s.stallTrackers.Store(runID, run.NewStallTracker(threshold))
+}
+
+// announceRun records and broadcasts the start of a run.
+func (s *RuntimeService) announceRun(ctx context.Context, r *run.Run, profile policy.PolicyProfile, ag *agent.Agent, model string) {
 	s.appendRunEvent(ctx, event.TypeRunStarted, r, map[string]string{
-		"policy_profile": profileName,
-		"exec_mode":      string(req.ExecMode),
-		"backend":        ag.Backend,
+		"policy_profile":   r.PolicyProfile,
+		"effective_policy": r.Policy(),
+		"exec_mode":        string(r.ExecMode),
+		"isolation":        string(profile.Isolation),
+		"backend":          ag.Backend,
+		"model":            model,
 	})
 
-	// Broadcast WS
 	s.hub.BroadcastEvent(ctx, ws.EventRunStatus, ws.RunStatusEvent{
 		RunID:     r.ID,
 		TaskID:    r.TaskID,
@@ -197,8 +587,7 @@ func (s *RuntimeService) StartRun(ctx context.Context, req *run.StartRequest) (*
 		Status:    string(r.Status),
 	})
 
-	slog.Info("run started", "run_id", r.ID, "task_id", r.TaskID, "policy", profileName)
-	return r, nil
+	slog.Info("run started", "run_id", r.ID, "task_id", r.TaskID, "policy", r.PolicyProfile, "effective_policy", r.Policy())
 }
 
 // HandleToolCallRequest processes a tool call permission request from a worker.
@@ -214,7 +603,7 @@ func (s *RuntimeService) HandleToolCallRequest(ctx context.Context, req *message
 	}
 
 	// Load policy profile for termination checks
-	profile, ok := s.policy.GetProfile(r.PolicyProfile)
+	profile, ok := s.policy.GetProfile(r.Policy())
 	if !ok {
 		return s.sendToolCallResponse(ctx, req.RunID, req.CallID, string(policy.DecisionDeny), "unknown policy profile")
 	}
@@ -223,6 +612,8 @@ func (s *RuntimeService) HandleToolCallRequest(ctx context.Context, req *message
 	if reason := s.checkTermination(r, &profile); reason != "" {
 		// Terminate the run
 		_ = s.store.CompleteRun(ctx, r.ID, run.StatusTimeout, "", reason, r.CostUSD, r.StepCount)
+		s.stallTrackers.Delete(r.ID)
+		s.owned.Delete(r.ID)
 		s.appendRunEvent(ctx, event.TypeRunCompleted, r, map[string]string{
 			"status": string(run.StatusTimeout),
 			"reason": reason,
@@ -238,15 +629,21 @@ func (s *RuntimeService) HandleToolCallRequest(ctx context.Context, req *message
 		return s.sendToolCallResponse(ctx, req.RunID, req.CallID, string(policy.DecisionDeny), reason)
 	}
 
-	// Evaluate policy
-	call := policy.ToolCall{
-		Tool:    req.Tool,
-		Command: req.Command,
-		Path:    req.Path,
-	}
-	decision, err := s.policy.Evaluate(ctx, r.PolicyProfile, call)
-	if err != nil {
-		return s.sendToolCallResponse(ctx, req.RunID, req.CallID, string(policy.DecisionDeny), err.Error())
+	// Commands reading injected secrets or dumping the environment are
+	// denied before the policy is consulted
+	sec := s.secretsFor(ctx, r)
+	decision := policy.DecisionDeny
+	reason := sec.guard.Check(req.Command)
+	if reason == "" {
+		call := policy.ToolCall{
+			Tool:    req.Tool,
+			Command: req.Command,
+			Path:    req.Path,
+		}
+		decision, err = s.policy.Evaluate(ctx, r.Policy(), call)
+		if err != nil {
+			return s.sendToolCallResponse(ctx, req.RunID, req.CallID, string(policy.DecisionDeny), err.Error())
+		}
 	}
 
 	// Record event
@@ -257,7 +654,10 @@ func (s *RuntimeService) HandleToolCallRequest(ctx context.Context, req *message
 	s.appendRunEvent(ctx, evType, r, map[string]string{
 		"call_id":  req.CallID,
 		"tool":     req.Tool,
+		"command":  s.redactOutput(ctx, r.ID, sec, req.Command),
+		"path":     req.Path,
 		"decision": string(decision),
+		"reason":   reason,
 	})
 
 	// Broadcast WS
@@ -266,18 +666,21 @@ func (s *RuntimeService) HandleToolCallRequest(ctx context.Context, req *message
 		phase = "denied"
 	}
 	s.hub.BroadcastEvent(ctx, ws.EventToolCallStatus, ws.ToolCallStatusEvent{
-		RunID:    r.ID,
-		CallID:   req.CallID,
-		Tool:     req.Tool,
-		Decision: string(decision),
-		Phase:    phase,
+		RunID:     r.ID,
+		ProjectID: r.ProjectID,
+		CallID:    req.CallID,
+		Tool:      req.Tool,
+		Decision:  string(decision),
+		Phase:     phase,
 	})
 
+	s.recordToolCallRequest(ctx, r, sec, req, decision, reason)
+
 	// Increment step count
 	newSteps := r.StepCount + 1
 	_ = s.store.UpdateRunStatus(ctx, r.ID, run.StatusRunning, newSteps, r.CostUSD)
 
-	return s.sendToolCallResponse(ctx, req.RunID, req.CallID, string(decision), "")
+	return s.sendToolCallResponse(ctx, req.RunID, req.CallID, string(decision), reason)
 }
 
 // HandleToolCallResult processes the outcome of an executed tool call.
@@ -287,9 +690,20 @@ func (s *RuntimeService) HandleToolCallResult(ctx context.Context, result *messa
 		return fmt.Errorf("get run: %w", err)
 	}
 
-	// Update run cost
+	// Update run cost and token usage
 	newCost := r.CostUSD + result.CostUSD
 	_ = s.store.UpdateRunStatus(ctx, r.ID, r.Status, r.StepCount, newCost)
+	if result.TokensIn > 0 || result.TokensOut > 0 {
+		if err := s.store.AddRunTokens(ctx, r.ID, result.TokensIn, result.TokensOut); err != nil {
+			slog.Warn("failed to record run tokens", "run_id", r.ID, "error", err)
+		}
+	}
+	if err := s.store.AddRunUsage(ctx, r.ID, r.ProjectID, cost.Usage{
+		Tool: result.Tool, Model: result.Model, Calls: 1, CostUSD: result.CostUSD,
+		TokensIn: int64(result.TokensIn), TokensOut: int64(result.TokensOut),
+	}); err != nil {
+		slog.Warn("failed to record run usage", "run_id", r.ID, "error", err)
+	}
 
 	// Check stall detection
 	if tracker, ok := s.stallTrackers.Load(r.ID); ok {
@@ -299,6 +713,7 @@ func (s *RuntimeService) HandleToolCallResult(ctx context.Context, result *messa
 			slog.Warn("stall detected, terminating run", "run_id", r.ID, "tool", result.Tool)
 			_ = s.store.CompleteRun(ctx, r.ID, run.StatusFailed, "", "stall detected: agent not making progress", newCost, r.StepCount)
 			s.stallTrackers.Delete(r.ID)
+			s.owned.Delete(r.ID)
 			s.appendRunEvent(ctx, event.TypeStallDetected, r, map[string]string{
 				"tool":       result.Tool,
 				"step_count": fmt.Sprintf("%d", r.StepCount),
@@ -318,20 +733,33 @@ func (s *RuntimeService) HandleToolCallResult(ctx context.Context, result *messa
 		}
 	}
 
+	s.recordToolCallResult(ctx, r, result)
+
 	// Record event
 	s.appendRunEvent(ctx, event.TypeToolCallResultEv, r, map[string]string{
-		"call_id": result.CallID,
-		"tool":    result.Tool,
-		"success": fmt.Sprintf("%t", result.Success),
-		"cost":    fmt.Sprintf("%.6f", result.CostUSD),
+		"call_id":    result.CallID,
+		"tool":       result.Tool,
+		"success":    fmt.Sprintf("%t", result.Success),
+		"cost":       fmt.Sprintf("%.6f", result.CostUSD),
+		"model":      result.Model,
+		"tokens_in":  fmt.Sprintf("%d", result.TokensIn),
+		"tokens_out": fmt.Sprintf("%d", result.TokensOut),
 	})
 
+	// Checkpoint the workspace for replay sessions (best-effort)
+	if s.snapshots != nil && result.Success && snapshot.Mode(s.runtimeCfg.SnapshotMode).Checkpoints(result.Tool) {
+		if _, err := s.snapshots.Checkpoint(ctx, r, result.CallID); err != nil {
+			slog.Error("workspace checkpoint failed", "run_id", r.ID, "call_id", result.CallID, "error", err)
+		}
+	}
+
 	// Broadcast WS
 	s.hub.BroadcastEvent(ctx, ws.EventToolCallStatus, ws.ToolCallStatusEvent{
-		RunID:  r.ID,
-		CallID: result.CallID,
-		Tool:   result.Tool,
-		Phase:  "result",
+		RunID:     r.ID,
+		ProjectID: r.ProjectID,
+		CallID:    result.CallID,
+		Tool:      result.Tool,
+		Phase:     "result",
 	})
 
 	return nil
@@ -343,10 +771,21 @@ func (s *RuntimeService) HandleRunComplete(ctx context.Context, payload *message
 	if err != nil {
 		return fmt.Errorf("get run: %w", err)
 	}
+	// A completion replayed after the run finished or moved on to its
+	// quality gates must not finish it again.
+	if r.Status != run.StatusPending && r.Status != run.StatusRunning {
+		slog.Info("ignoring completion of finished run", "run_id", r.ID, "status", r.Status)
+		return nil
+	}
 
 	// Clean up stall tracker
 	s.stallTrackers.Delete(r.ID)
 
+	// Mask secret values and credentials before the output is stored or broadcast
+	sec := s.secretsFor(ctx, r)
+	payload.Output = s.redactOutput(ctx, r.ID, sec, payload.Output)
+	payload.Error = s.redactOutput(ctx, r.ID, sec, payload.Error)
+
 	// Determine final status
 	status := run.Status(payload.Status)
 	if status == "" {
@@ -357,9 +796,22 @@ func (s *RuntimeService) HandleRunComplete(ctx context.Context, payload *message
 		}
 	}
 
+	// Hold the final answer to the mode's output schema and citations; a
+	// violation may send the run back to the agent instead of completing it.
+	status, repairing := s.enforceOutput(ctx, r, status, payload)
+	if repairing {
+		return nil
+	}
+
+	// Record the diff before quality gates or delivery commit the changes.
+	// Remote runs leave the local workspace untouched.
+	if r.ExecMode != run.ExecModeRemote {
+		s.recordDiffStat(ctx, r)
+	}
+
 	// Check if quality gates should be triggered
-	profile, ok := s.policy.GetProfile(r.PolicyProfile)
-	hasGates := ok && status == run.StatusCompleted &&
+	profile, ok := s.policy.GetProfile(r.Policy())
+	hasGates := ok && status == run.StatusCompleted && r.ExecMode != run.ExecModeRemote &&
 		(profile.QualityGate.RequireTestsPass || profile.QualityGate.RequireLintPass)
 
 	if hasGates {
@@ -368,26 +820,25 @@ func (s *RuntimeService) HandleRunComplete(ctx context.Context, payload *message
 			return fmt.Errorf("update run to quality_gate: %w", err)
 		}
 
-		// Look up project for workspace path
-		proj, projErr := s.store.GetProject(ctx, r.ProjectID)
-		workspacePath := ""
-		if projErr == nil {
-			workspacePath = proj.WorkspacePath
-		}
-
-		// Determine commands (project-level → config defaults)
-		testCmd := s.runtimeCfg.DefaultTestCommand
-		lintCmd := s.runtimeCfg.DefaultLintCommand
+		// Tests run in the run's workspace, or its sub-project directory,
+		// with the resolved test command (see resolveTestPlan).
+		plan := resolveTestPlan(ctx, s.store, s.runtimeCfg, r)
 
 		// Publish quality gate request
 		gateReq := messagequeue.QualityGateRequestPayload{
-			RunID:         r.ID,
-			ProjectID:     r.ProjectID,
-			WorkspacePath: workspacePath,
-			RunTests:      profile.QualityGate.RequireTestsPass,
-			RunLint:       profile.QualityGate.RequireLintPass,
-			TestCommand:   testCmd,
-			LintCommand:   lintCmd,
+			RunID:          r.ID,
+			ProjectID:      r.ProjectID,
+			WorkspacePath:  plan.Dir,
+			RunTests:       profile.QualityGate.RequireTestsPass,
+			RunLint:        profile.QualityGate.RequireLintPass,
+			TestCommand:    plan.Command,
+			LintCommand:    s.runtimeCfg.DefaultLintCommand,
+			TestReportPath: plan.ReportPath,
+		}
+		// Linters run alongside the lint command; new critical findings
+		// fail the lint gate.
+		if s.lint != nil && profile.QualityGate.RequireLintPass {
+			gateReq.Linters = s.lint.Plan(ctx, r).Linters
 		}
 		if err := s.publishJSON(ctx, messagequeue.SubjectQualityGateRequest, gateReq); err != nil {
 			slog.Error("failed to publish quality gate request", "run_id", r.ID, "error", err)
@@ -422,6 +873,143 @@ func (s *RuntimeService) HandleRunComplete(ctx context.Context, payload *message
 	return s.finalizeRun(ctx, r, status, payload)
 }
 
+// outputSchema returns the output schema of a mode, or nil if the mode
+// has none or is unknown.
+func (s *RuntimeService) outputSchema(modeID string) *mode.OutputSchema {
+	if s.modes == nil || modeID == "" {
+		return nil
+	}
+	m, err := s.modes.Get(modeID)
+	if err != nil {
+		return nil
+	}
+	return m.OutputSchema
+}
+
+// enforceOutput validates the final output of a completed run against
+// the output schema of its agent's mode and stores the parsed answer, and
+// checks that it cites the retrieved chunks if the run must cite them. A
+// violation sends the run back to the agent with a repair prompt, at most
+// mode.MaxOutputRepairs times, and then fails the run. It returns the
+// run's status and whether a repair is under way.
+func (s *RuntimeService) enforceOutput(ctx context.Context, r *run.Run, status run.Status, payload *messagequeue.RunCompletePayload) (run.Status, bool) {
+	var rep *outputRepair
+	if v, ok := s.outputRepairs.LoadAndDelete(r.ID); ok {
+		rep = v.(*outputRepair)
+		payload.CostUSD += rep.costUSD
+		payload.StepCount += rep.steps
+	}
+	if status != run.StatusCompleted {
+		return status, false
+	}
+	// The start payload carries the run's mode, which may override the
+	// agent's.
+	var schema *mode.OutputSchema
+	if rep != nil {
+		schema = s.outputSchema(rep.start.Config["mode"])
+	} else if ag, err := s.store.GetAgent(ctx, r.AgentID); err == nil {
+		schema = s.outputSchema(ag.Config["mode"])
+	}
+	var citable []string
+	if rep != nil {
+		citable = rep.citations
+	}
+	if schema == nil && len(citable) == 0 {
+		return status, false
+	}
+
+	var raw json.RawMessage
+	var problems []string
+	if schema != nil {
+		var value any
+		var err error
+		raw, value, err = mode.ParseOutput(payload.Output)
+		if err != nil {
+			problems = []string{err.Error()}
+		} else {
+			problems = schema.Validate(value)
+		}
+	}
+	if len(citable) > 0 {
+		problems = append(problems, retrieval.CheckCitations(payload.Output, citable)...)
+	}
+	if len(problems) == 0 {
+		if schema != nil {
+			if err := s.store.SetRunStructuredOutput(ctx, r.ID, raw); err != nil {
+				slog.Error("store structured output", "run_id", r.ID, "error", err)
+			}
+			r.StructuredOutput = raw
+			s.appendRunEvent(ctx, event.TypeOutputValidated, r, map[string]string{"bytes": strconv.Itoa(len(raw))})
+		}
+		return status, false
+	}
+
+	repairing := rep != nil && rep.attempts < mode.MaxOutputRepairs
+	attempt := 1
+	if rep != nil {
+		attempt += rep.attempts
+	}
+	s.appendRunEvent(ctx, event.TypeOutputInvalid, r, map[string]string{
+		"problems":  strings.Join(problems, "; "),
+		"attempt":   strconv.Itoa(attempt),
+		"repairing": strconv.FormatBool(repairing),
+	})
+	if repairing {
+		start := rep.start
+		start.Prompt += "\n\n## Previous answer\n\n" + payload.Output + "\n\n" + repairPrompt(schema, citable, problems)
+		err := s.publishJSON(ctx, messagequeue.SubjectRunStart, start)
+		if err == nil {
+			err = s.store.UpdateRunStatus(ctx, r.ID, run.StatusRunning, payload.StepCount, payload.CostUSD)
+		}
+		if err == nil {
+			s.outputRepairs.Store(r.ID, &outputRepair{
+				start:     rep.start,
+				citations: rep.citations,
+				attempts:  rep.attempts + 1,
+				costUSD:   payload.CostUSD,
+				steps:     payload.StepCount,
+			})
+			slog.Info("final output invalid, repair requested", "run_id", r.ID, "attempt", attempt)
+			return status, true
+		}
+		slog.Error("request output repair", "run_id", r.ID, "error", err)
+	}
+	if schema != nil {
+		payload.Error = "output schema violated: " + strings.Join(problems, "; ")
+	} else {
+		payload.Error = "citations missing or invalid: " + strings.Join(problems, "; ")
+	}
+	return run.StatusFailed, false
+}
+
+// repairPrompt asks the agent to restate a final answer that violated the
+// output schema (if any) or lacked valid citations (if it must cite).
+func repairPrompt(schema *mode.OutputSchema, citable []string, problems []string) string {
+	if schema == nil {
+		return retrieval.CitationRepairPrompt(problems)
+	}
+	prompt := schema.RepairPrompt(problems)
+	if len(citable) > 0 {
+		prompt += "\n\n" + retrieval.CitationInstructions()
+	}
+	return prompt
+}
+
+// gateResultPayload builds the event payload of a quality gate outcome.
+func gateResultPayload(result *messagequeue.QualityGateResultPayload, errMsg string) map[string]string {
+	payload := map[string]string{}
+	if result.TestsPassed != nil {
+		payload["tests_passed"] = strconv.FormatBool(*result.TestsPassed)
+	}
+	if result.LintPassed != nil {
+		payload["lint_passed"] = strconv.FormatBool(*result.LintPassed)
+	}
+	if errMsg != "" {
+		payload["error"] = errMsg
+	}
+	return payload
+}
+
 // HandleQualityGateResult processes the outcome of a quality gate execution.
 func (s *RuntimeService) HandleQualityGateResult(ctx context.Context, result *messagequeue.QualityGateResultPayload) error {
 	r, err := s.store.GetRun(ctx, result.RunID)
@@ -429,12 +1017,36 @@ func (s *RuntimeService) HandleQualityGateResult(ctx context.Context, result *me
 		return fmt.Errorf("get run: %w", err)
 	}
 
+	// Test results are stored for gated runs as well as for tests
+	// requested on finished runs via the TestRunnerService.
+	recorded := false
+	if s.tests != nil {
+		rep, err := s.tests.Record(ctx, r, result)
+		if err != nil {
+			slog.Warn("test report not stored", "run_id", r.ID, "error", err)
+		}
+		recorded = rep != nil
+	}
+	if s.lint != nil {
+		rep, err := s.lint.Record(ctx, r, result)
+		if err != nil {
+			slog.Warn("lint report not stored", "run_id", r.ID, "error", err)
+		}
+		if rep != nil {
+			recorded = true
+			passed := rep.Blocking == 0 && (result.LintPassed == nil || *result.LintPassed)
+			result.LintPassed = &passed
+		}
+	}
+
 	if r.Status != run.StatusQualityGate {
-		slog.Warn("received quality gate result for non-gated run", "run_id", r.ID, "status", r.Status)
+		if !recorded {
+			slog.Warn("received quality gate result for non-gated run", "run_id", r.ID, "status", r.Status)
+		}
 		return nil
 	}
 
-	profile, _ := s.policy.GetProfile(r.PolicyProfile)
+	profile, _ := s.policy.GetProfile(r.Policy())
 
 	// Determine if gates passed
 	allPassed := result.Error == "" &&
@@ -442,7 +1054,7 @@ func (s *RuntimeService) HandleQualityGateResult(ctx context.Context, result *me
 		(result.LintPassed == nil || *result.LintPassed)
 
 	if allPassed {
-		s.appendRunEvent(ctx, event.TypeQualityGatePassed, r, map[string]string{})
+		s.appendRunEvent(ctx, event.TypeQualityGatePassed, r, gateResultPayload(result, ""))
 		s.hub.BroadcastEvent(ctx, ws.EventQualityGate, ws.QualityGateEvent{
 			RunID:       r.ID,
 			TaskID:      r.TaskID,
@@ -475,9 +1087,7 @@ func (s *RuntimeService) HandleQualityGateResult(ctx context.Context, result *me
 		errMsg = "quality gate failed (rollback)"
 	}
 
-	s.appendRunEvent(ctx, event.TypeQualityGateFailed, r, map[string]string{
-		"error": errMsg,
-	})
+	s.appendRunEvent(ctx, event.TypeQualityGateFailed, r, gateResultPayload(result, errMsg))
 	s.hub.BroadcastEvent(ctx, ws.EventQualityGate, ws.QualityGateEvent{
 		RunID:       r.ID,
 		TaskID:      r.TaskID,
@@ -501,9 +1111,28 @@ func (s *RuntimeService) HandleQualityGateResult(ctx context.Context, result *me
 
 // finalizeRun completes the run lifecycle: update DB, task, agent, broadcast events.
 func (s *RuntimeService) finalizeRun(ctx context.Context, r *run.Run, status run.Status, payload *messagequeue.RunCompletePayload) error {
+	s.runSecrets.Delete(r.ID)
+	s.owned.Delete(r.ID)
+	if s.sandbox != nil {
+		s.sandbox.Release(ctx, r.ID)
+	}
 	if err := s.store.CompleteRun(ctx, r.ID, status, payload.Output, payload.Error, payload.CostUSD, payload.StepCount); err != nil {
+		// Another server finalized the run first, e.g. for a replayed
+		// completion; its task, agent and events are already updated.
+		if errors.Is(err, run.ErrFinished) {
+			slog.Info("run already finalized", "run_id", r.ID)
+			return nil
+		}
 		return fmt.Errorf("complete run: %w", err)
 	}
+	s.recordRunAnswer(ctx, r, payload)
+
+	// Summarize the run unless delivery already did
+	output := payload.Output
+	if output == "" {
+		output = r.Output
+	}
+	s.summarizeRun(ctx, r, status, output, payload.Error, payload.CostUSD)
 
 	// Update task result
 	taskResult := task.Result{
@@ -526,6 +1155,7 @@ func (s *RuntimeService) finalizeRun(ctx context.Context, r *run.Run, status run
 		"step_count": fmt.Sprintf("%d", payload.StepCount),
 		"cost":       fmt.Sprintf("%.6f", payload.CostUSD),
 		"error":      payload.Error,
+		"summary":    r.Summary,
 	})
 
 	// Broadcast WS
@@ -536,6 +1166,7 @@ func (s *RuntimeService) finalizeRun(ctx context.Context, r *run.Run, status run
 		Status:    string(status),
 		StepCount: payload.StepCount,
 		CostUSD:   payload.CostUSD,
+		Summary:   r.Summary,
 	})
 	s.hub.BroadcastEvent(ctx, ws.EventAgentStatus, ws.AgentStatusEvent{
 		AgentID:   r.AgentID,
@@ -545,6 +1176,18 @@ func (s *RuntimeService) finalizeRun(ctx context.Context, r *run.Run, status run
 
 	slog.Info("run finalized", "run_id", r.ID, "status", status, "steps", payload.StepCount)
 
+	// Snapshot the workspace before follow-up steps can modify it (best-effort)
+	if s.snapshots != nil && snapshot.Mode(s.runtimeCfg.SnapshotMode).OnComplete() {
+		if _, err := s.snapshots.Create(ctx, r.ID, &snapshot.CreateRequest{Name: "on-complete"}); err != nil {
+			slog.Error("workspace snapshot failed", "run_id", r.ID, "error", err)
+		}
+	}
+
+	// Janitor pass: prune worktrees of runs past the retention period
+	if _, err := s.PruneWorktrees(ctx); err != nil {
+		slog.Warn("worktree prune failed", "error", err)
+	}
+
 	// Notify orchestrator (if registered) about run completion
 	if s.onRunComplete != nil {
 		s.onRunComplete(ctx, r.ID, status)
@@ -553,6 +1196,70 @@ func (s *RuntimeService) finalizeRun(ctx context.Context, r *run.Run, status run
 	return nil
 }
 
+// recordToolCallRequest notes an approved tool call of a conversation run
+// until its result arrives, and records a denied one right away.
+func (s *RuntimeService) recordToolCallRequest(ctx context.Context, r *run.Run, sec *runSecrets, req *messagequeue.ToolCallRequestPayload, decision policy.Decision, reason string) {
+	if r.ConversationID == "" || s.conversations == nil {
+		return
+	}
+	call := conversation.ToolCall{RunID: r.ID, CallID: req.CallID, Name: req.Tool, Args: map[string]string{}}
+	if req.Command != "" {
+		call.Args["command"] = s.redactOutput(ctx, r.ID, sec, req.Command)
+	}
+	if req.Path != "" {
+		call.Args["path"] = req.Path
+	}
+	if decision == policy.DecisionAllow {
+		s.toolCalls.Store(r.ID+"/"+req.CallID, &pendingToolCall{call: call, started: time.Now()})
+		return
+	}
+	call.Error = "denied by policy"
+	if reason != "" {
+		call.Error += ": " + reason
+	}
+	if _, err := s.conversations.AppendToolCall(ctx, r, call, ""); err != nil {
+		slog.Warn("failed to record tool call in conversation", "run_id", r.ID, "call_id", req.CallID, "error", err)
+	}
+}
+
+// recordToolCallResult records the result of a conversation run's tool
+// call as a tool message.
+func (s *RuntimeService) recordToolCallResult(ctx context.Context, r *run.Run, result *messagequeue.ToolCallResultPayload) {
+	if r.ConversationID == "" || s.conversations == nil {
+		return
+	}
+	call := conversation.ToolCall{RunID: r.ID, CallID: result.CallID, Name: result.Tool}
+	if p, ok := s.toolCalls.LoadAndDelete(r.ID + "/" + result.CallID); ok {
+		pending := p.(*pendingToolCall)
+		call = pending.call
+		call.DurationMS = time.Since(pending.started).Milliseconds()
+	}
+	sec := s.secretsFor(ctx, r)
+	call.Success = result.Success
+	call.Error = s.redactOutput(ctx, r.ID, sec, result.Error)
+	output := s.redactOutput(ctx, r.ID, sec, result.Output)
+	if _, err := s.conversations.AppendToolCall(ctx, r, call, output); err != nil {
+		slog.Warn("failed to record tool call in conversation", "run_id", r.ID, "call_id", result.CallID, "error", err)
+	}
+}
+
+// recordRunAnswer records the final answer of a conversation run and
+// drops the tool calls still awaiting a result.
+func (s *RuntimeService) recordRunAnswer(ctx context.Context, r *run.Run, payload *messagequeue.RunCompletePayload) {
+	if r.ConversationID == "" || s.conversations == nil {
+		return
+	}
+	s.toolCalls.Range(func(key, _ any) bool {
+		if strings.HasPrefix(key.(string), r.ID+"/") {
+			s.toolCalls.Delete(key)
+		}
+		return true
+	})
+	if _, err := s.conversations.AppendRunAnswer(ctx, r, payload.Output, payload.Error); err != nil {
+		slog.Warn("failed to record run answer in conversation", "run_id", r.ID, "error", err)
+	}
+}
+
 // triggerDelivery attempts to deliver the run output (patch, commit, branch, PR).
 // Delivery is best-effort — failure is logged but does not fail the run.
 func (s *RuntimeService) triggerDelivery(ctx context.Context, r *run.Run) {
@@ -571,6 +1278,9 @@ func (s *RuntimeService) triggerDelivery(ctx context.Context, r *run.Run) {
 		taskTitle = t.Title
 	}
 
+	// Summarize the run first so the summary goes into the PR description
+	s.summarizeRun(ctx, r, run.StatusCompleted, r.Output, "", r.CostUSD)
+
 	s.appendRunEvent(ctx, event.TypeDeliveryStarted, r, map[string]string{
 		"mode": string(r.DeliverMode),
 	})
@@ -606,7 +1316,15 @@ func (s *RuntimeService) triggerDelivery(ctx context.Context, r *run.Run) {
 		"commit_hash": result.CommitHash,
 		"branch_name": result.BranchName,
 		"pr_url":      result.PRURL,
+		"artifacts":   strings.Join(result.Artifacts, ","),
 	})
+	for _, w := range result.Warnings {
+		slog.Warn("delivery does not follow the project's git policy", "run_id", r.ID, "warning", w)
+		s.appendRunEvent(ctx, event.TypeDeliveryWarning, r, map[string]string{
+			"mode":    string(result.Mode),
+			"warning": w,
+		})
+	}
 	s.hub.BroadcastEvent(ctx, ws.EventDelivery, ws.DeliveryEvent{
 		RunID:      r.ID,
 		TaskID:     r.TaskID,
@@ -617,7 +1335,31 @@ func (s *RuntimeService) triggerDelivery(ctx context.Context, r *run.Run) {
 		CommitHash: result.CommitHash,
 		BranchName: result.BranchName,
 		PRURL:      result.PRURL,
+		Artifacts:  result.Artifacts,
+		Warnings:   result.Warnings,
 	})
+	s.recordDelivery(ctx, r, result)
+}
+
+// recordDelivery stores where a delivery left the run's changes on the git
+// host, so seatbelt mode can trace CI failures and reverts back to the run.
+// Deliveries that stay local are not recorded.
+func (s *RuntimeService) recordDelivery(ctx context.Context, r *run.Run, result *DeliveryResult) {
+	if !seatbelt.Watched(result.Mode) || result.BranchName == "" {
+		return
+	}
+	d := &seatbelt.Delivery{
+		RunID:     r.ID,
+		ProjectID: r.ProjectID,
+		TaskID:    r.TaskID,
+		Mode:      result.Mode,
+		Branch:    result.BranchName,
+		CommitSHA: result.CommitHash,
+		PRURL:     result.PRURL,
+	}
+	if err := s.store.SaveRunDelivery(ctx, d); err != nil {
+		slog.Error("record run delivery failed", "run_id", r.ID, "error", err)
+	}
 }
 
 // CancelRun cancels a running run and notifies the worker.
@@ -631,8 +1373,14 @@ func (s *RuntimeService) CancelRun(ctx context.Context, runID string) error {
 		return fmt.Errorf("run %s is not active (status: %s)", runID, r.Status)
 	}
 
-	// Clean up stall tracker
+	// Clean up stall tracker, secrets and sandbox
 	s.stallTrackers.Delete(runID)
+	s.runSecrets.Delete(runID)
+	s.outputRepairs.Delete(runID)
+	s.owned.Delete(runID)
+	if s.sandbox != nil {
+		s.sandbox.Release(ctx, runID)
+	}
 
 	// Update DB
 	if err := s.store.CompleteRun(ctx, r.ID, run.StatusCancelled, "", "cancelled by user", r.CostUSD, r.StepCount); err != nil {
@@ -669,6 +1417,169 @@ func (s *RuntimeService) CancelRun(ctx context.Context, runID string) error {
 	return nil
 }
 
+// SimulatePolicy replays the tool calls recorded for a run through the named
+// policy profile and reports which calls would have been allowed, asked or denied.
+func (s *RuntimeService) SimulatePolicy(ctx context.Context, profileName, runID string) (*policy.SimulationResult, error) {
+	if _, ok := s.policy.GetProfile(profileName); !ok {
+		return nil, fmt.Errorf("policy profile %q: %w", profileName, domain.ErrNotFound)
+	}
+	if _, err := s.store.GetRun(ctx, runID); err != nil {
+		return nil, fmt.Errorf("get run: %w", err)
+	}
+	if s.events == nil {
+		return nil, fmt.Errorf("event store not configured")
+	}
+	events, err := s.loadRunEvents(ctx, runID)
+	if err != nil {
+		return nil, fmt.Errorf("load run events: %w", err)
+	}
+	return s.policy.Simulate(profileName, runID, events)
+}
+
+// loadRunEvents returns a run's events, including those already archived.
+func (s *RuntimeService) loadRunEvents(ctx context.Context, runID string) ([]event.AgentEvent, error) {
+	if s.retention != nil {
+		return s.retention.LoadRunEvents(ctx, runID)
+	}
+	return s.events.LoadByRun(ctx, runID)
+}
+
+// ResolvePolicy returns the effective policy for a run context: the tenant
+// default layered with the project's and agent's policy_profile config
+// overrides and, on top, the explicit profile if one is given.
+func (s *RuntimeService) ResolvePolicy(ctx context.Context, rc *policy.RunContext) (*policy.EffectivePolicy, error) {
+	var ag *agent.Agent
+	if rc.AgentID != "" {
+		a, err := s.store.GetAgent(ctx, rc.AgentID)
+		if err != nil {
+			return nil, fmt.Errorf("get agent: %w", err)
+		}
+		ag = a
+	}
+	projectID := rc.ProjectID
+	if projectID == "" && ag != nil {
+		projectID = ag.ProjectID
+	}
+	return s.resolvePolicy(ctx, projectID, ag, rc.PolicyProfile)
+}
+
+// resolvePolicy layers the tenant default profile with project and agent
+// overrides and the run's requested profile, if any.
+func (s *RuntimeService) resolvePolicy(ctx context.Context, projectID string, ag *agent.Agent, requested string) (*policy.EffectivePolicy, error) {
+	layers := []policy.Layer{{Level: policy.LevelTenant, Profile: s.policy.DefaultProfile()}}
+	if projectID != "" {
+		proj, err := s.store.GetProject(ctx, projectID)
+		if err != nil {
+			return nil, fmt.Errorf("get project: %w", err)
+		}
+		layers = append(layers, policy.Layer{Level: policy.LevelProject, Profile: proj.Config[policy.ConfigKeyProfile]})
+	}
+	if ag != nil {
+		layers = append(layers, policy.Layer{Level: policy.LevelAgent, Profile: ag.Config[policy.ConfigKeyProfile]})
+	}
+	layers = append(layers, policy.Layer{Level: policy.LevelRun, Profile: requested})
+	return s.policy.Resolve(layers)
+}
+
+// allocateWorktree creates the run's worktree and records it on the run.
+// Projects without a cloned workspace have no shared clone to protect and
+// run without a worktree, unless the run needs one to check out its branch.
+func (s *RuntimeService) allocateWorktree(ctx context.Context, r *run.Run) error {
+	if s.projects == nil {
+		if r.Branch != "" {
+			return fmt.Errorf("run on branch %s: project service not configured", r.Branch)
+		}
+		slog.Warn("worktree isolation unavailable, project service not configured", "run_id", r.ID)
+		return nil
+	}
+	proj, err := s.store.GetProject(ctx, r.ProjectID)
+	if err != nil {
+		return fmt.Errorf("get project: %w", err)
+	}
+	if proj.WorkspacePath == "" {
+		if r.Branch != "" {
+			return fmt.Errorf("run on branch %s: project %s has no workspace (not cloned)", r.Branch, r.ProjectID)
+		}
+		return nil
+	}
+	path, err := s.projects.AllocateWorktree(ctx, r.ProjectID, r.ID, r.Branch)
+	if err != nil {
+		return fmt.Errorf("allocate worktree: %w", err)
+	}
+	if err := s.store.SetRunWorktree(ctx, r.ID, path); err != nil {
+		_ = s.projects.ReleaseWorktree(ctx, r.ProjectID, path)
+		return fmt.Errorf("record worktree: %w", err)
+	}
+	r.WorktreePath = path
+	slog.Info("run worktree allocated", "run_id", r.ID, "path", path)
+	return nil
+}
+
+// checkVCSAccount fails a run that fetches a remote branch or pushes its
+// delivery to the git host if the latest check of the project's account
+// found it broken or, for pushes, unable to push, so the run fails before
+// it starts rather than mid-way. Unchecked accounts are not held up.
+func (s *RuntimeService) checkVCSAccount(ctx context.Context, projectID string, mode run.DeliverMode, branch string) error {
+	push := mode == run.DeliverModeBranch || mode == run.DeliverModePR || mode == run.DeliverModePush
+	if !push && branch == "" {
+		return nil
+	}
+	h, err := s.store.GetVCSAccountHealth(ctx, projectID)
+	if err != nil {
+		return nil
+	}
+	if err := h.Usable(push); err != nil {
+		if h.Error != "" {
+			return fmt.Errorf("%w (checked %s): %s", err, h.CheckedAt.UTC().Format(time.RFC3339), h.Error)
+		}
+		return fmt.Errorf("%w (checked %s)", err, h.CheckedAt.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// restoreSnapshot replaces the files of a new run's worktree with a
+// workspace snapshot, keeping the worktree's git link.
+func (s *RuntimeService) restoreSnapshot(ctx context.Context, r *run.Run, snapshotID string) error {
+	if s.snapshots == nil || r.WorktreePath == "" {
+		return fmt.Errorf("restore snapshot %s: the run has no worktree to restore into", snapshotID)
+	}
+	if _, err := s.snapshots.restoreInto(ctx, r.WorktreePath, r.ProjectID, snapshotID, []string{".git"}); err != nil {
+		return fmt.Errorf("restore snapshot %s: %w", snapshotID, err)
+	}
+	slog.Info("run worktree restored from snapshot", "run_id", r.ID, "snapshot_id", snapshotID)
+	return nil
+}
+
+// PruneWorktrees removes the worktrees of runs that finished longer than the
+// configured retention ago and clears them from the runs table. It returns
+// the number of worktrees removed; individual failures are logged and skipped.
+func (s *RuntimeService) PruneWorktrees(ctx context.Context) (int, error) {
+	if s.projects == nil {
+		return 0, nil
+	}
+	runs, err := s.store.ListRunsWithWorktree(ctx, time.Now().Add(-s.runtimeCfg.WorktreeRetention))
+	if err != nil {
+		return 0, fmt.Errorf("list runs with worktree: %w", err)
+	}
+	pruned := 0
+	for i := range runs {
+		r := &runs[i]
+		if err := s.projects.ReleaseWorktree(ctx, r.ProjectID, r.WorktreePath); err != nil {
+			slog.Warn("release worktree failed", "run_id", r.ID, "path", r.WorktreePath, "error", err)
+			continue
+		}
+		if err := s.store.SetRunWorktree(ctx, r.ID, ""); err != nil {
+			slog.Warn("clear run worktree failed", "run_id", r.ID, "error", err)
+			continue
+		}
+		pruned++
+	}
+	if pruned > 0 {
+		slog.Info("run worktrees pruned", "count", pruned)
+	}
+	return pruned, nil
+}
+
 // GetRun returns a run by ID.
 func (s *RuntimeService) GetRun(ctx context.Context, id string) (*run.Run, error) {
 	return s.store.GetRun(ctx, id)
@@ -679,6 +1590,12 @@ func (s *RuntimeService) ListRunsByTask(ctx context.Context, taskID string) ([]r
 	return s.store.ListRunsByTask(ctx, taskID)
 }
 
+// ListActiveRuns returns the pending, running and quality-gate runs,
+// optionally of one project.
+func (s *RuntimeService) ListActiveRuns(ctx context.Context, projectID string) ([]run.Run, error) {
+	return s.store.ListActiveRuns(ctx, projectID)
+}
+
 // StartSubscribers subscribes to all run-related NATS subjects.
 // Returns cancel functions for each subscription.
 func (s *RuntimeService) StartSubscribers(ctx context.Context) ([]func(), error) {
@@ -703,7 +1620,12 @@ func (s *RuntimeService) StartSubscribers(ctx context.Context) ([]func(), error)
 		if err := json.Unmarshal(data, &result); err != nil {
 			return fmt.Errorf("unmarshal tool call result: %w", err)
 		}
-		return s.HandleToolCallResult(msgCtx, &result)
+		return s.dedupe.Apply(msgCtx, messagequeue.SubjectRunToolCallResult, result.IdempotencyKey, func() error {
+			if err := s.HandleToolCallResult(msgCtx, &result); err != nil {
+				return err
+			}
+			return flushEvents(msgCtx, s.events)
+		})
 	})
 	if err != nil {
 		cancelAll(cancels)
@@ -717,7 +1639,12 @@ func (s *RuntimeService) StartSubscribers(ctx context.Context) ([]func(), error)
 		if err := json.Unmarshal(data, &payload); err != nil {
 			return fmt.Errorf("unmarshal run complete: %w", err)
 		}
-		return s.HandleRunComplete(msgCtx, &payload)
+		return s.dedupe.Apply(msgCtx, messagequeue.SubjectRunComplete, payload.IdempotencyKey, func() error {
+			if err := s.HandleRunComplete(msgCtx, &payload); err != nil {
+				return err
+			}
+			return flushEvents(msgCtx, s.events)
+		})
 	})
 	if err != nil {
 		cancelAll(cancels)
@@ -731,7 +1658,12 @@ func (s *RuntimeService) StartSubscribers(ctx context.Context) ([]func(), error)
 		if err := json.Unmarshal(data, &result); err != nil {
 			return fmt.Errorf("unmarshal quality gate result: %w", err)
 		}
-		return s.HandleQualityGateResult(msgCtx, &result)
+		return s.dedupe.Apply(msgCtx, messagequeue.SubjectQualityGateResult, result.IdempotencyKey, func() error {
+			if err := s.HandleQualityGateResult(msgCtx, &result); err != nil {
+				return err
+			}
+			return flushEvents(msgCtx, s.events)
+		})
 	})
 	if err != nil {
 		cancelAll(cancels)
@@ -745,12 +1677,7 @@ func (s *RuntimeService) StartSubscribers(ctx context.Context) ([]func(), error)
 		if err := json.Unmarshal(data, &output); err != nil {
 			return fmt.Errorf("unmarshal run output: %w", err)
 		}
-		s.hub.BroadcastEvent(msgCtx, ws.EventTaskOutput, ws.TaskOutputEvent{
-			TaskID: output.TaskID,
-			Line:   output.Line,
-			Stream: output.Stream,
-		})
-		return nil
+		return s.HandleRunOutput(msgCtx, &output)
 	})
 	if err != nil {
 		cancelAll(cancels)
@@ -758,11 +1685,124 @@ func (s *RuntimeService) StartSubscribers(ctx context.Context) ([]func(), error)
 	}
 	cancels = append(cancels, cancel)
 
+	// Blocked connection attempts from sandbox egress proxies
+	cancel, err = s.queue.Subscribe(ctx, messagequeue.SubjectRunEgressBlocked, func(msgCtx context.Context, _ string, data []byte) error {
+		var blocked messagequeue.EgressBlockedPayload
+		if err := json.Unmarshal(data, &blocked); err != nil {
+			return fmt.Errorf("unmarshal egress blocked: %w", err)
+		}
+		return s.HandleEgressBlocked(msgCtx, &blocked)
+	})
+	if err != nil {
+		cancelAll(cancels)
+		return nil, fmt.Errorf("subscribe egress blocked: %w", err)
+	}
+	cancels = append(cancels, cancel)
+
 	return cancels, nil
 }
 
+// HandleRunOutput broadcasts a streamed output line, masking the values of
+// any secrets injected into the run and credentials found by the
+// redaction pipeline.
+func (s *RuntimeService) HandleRunOutput(ctx context.Context, output *messagequeue.RunOutputPayload) error {
+	var sec *runSecrets
+	if v, ok := s.runSecrets.Load(output.RunID); ok {
+		sec = v.(*runSecrets)
+	} else if r, err := s.store.GetRun(ctx, output.RunID); err == nil {
+		sec = s.secretsFor(ctx, r)
+	}
+	s.hub.BroadcastEvent(ctx, ws.EventTaskOutput, ws.TaskOutputEvent{
+		TaskID: output.TaskID,
+		RunID:  output.RunID,
+		Line:   s.redactOutput(ctx, output.RunID, sec, output.Line),
+		Stream: output.Stream,
+	})
+	return nil
+}
+
+// HandleEgressBlocked records a connection attempt denied by a sandbox's
+// egress proxy as a policy event of the run.
+func (s *RuntimeService) HandleEgressBlocked(ctx context.Context, blocked *messagequeue.EgressBlockedPayload) error {
+	r, err := s.store.GetRun(ctx, blocked.RunID)
+	if err != nil {
+		return fmt.Errorf("get run: %w", err)
+	}
+	slog.Warn("sandbox egress blocked", "run_id", r.ID, "host", blocked.Host, "port", blocked.Port, "reason", blocked.Reason)
+	s.appendRunEvent(ctx, event.TypeEgressBlocked, r, map[string]string{
+		"policy_profile":   r.PolicyProfile,
+		"effective_policy": r.Policy(),
+		"host":             blocked.Host,
+		"port":             blocked.Port,
+		"method":           blocked.Method,
+		"reason":           blocked.Reason,
+	})
+	return nil
+}
+
+// redactOutput masks secrets and credentials in text produced by a run and
+// adds the number of masks to the run's redaction count.
+func (s *RuntimeService) redactOutput(ctx context.Context, runID string, sec *runSecrets, text string) string {
+	n := 0
+	if sec != nil {
+		text, n = sec.redactor.RedactCount(text)
+	}
+	text, m := s.redaction.Redact(text)
+	if n+m > 0 && runID != "" {
+		if err := s.store.AddRunRedactions(ctx, runID, n+m); err != nil {
+			slog.Warn("record run redactions failed", "run_id", runID, "error", err)
+		}
+	}
+	return text
+}
+
 // --- Internal helpers ---
 
+// resolveSecrets decrypts the secrets listed in the agent's config.
+func (s *RuntimeService) resolveSecrets(ctx context.Context, projectID string, ag *agent.Agent) (map[string]string, error) {
+	names := secret.ParseNames(ag.Config[secret.ConfigKey])
+	if len(names) == 0 {
+		return nil, nil
+	}
+	if !s.secrets.Available() {
+		return nil, ErrSecretsUnavailable
+	}
+	env, err := s.secrets.Resolve(ctx, projectID, names)
+	if err != nil {
+		return nil, fmt.Errorf("resolve secrets: %w", err)
+	}
+	return env, nil
+}
+
+// secretsFor returns the secret guard and redactor of a run. Entries are
+// rebuilt from the agent config when missing (e.g. after a restart) and
+// cached while the run is active.
+func (s *RuntimeService) secretsFor(ctx context.Context, r *run.Run) *runSecrets {
+	if v, ok := s.runSecrets.Load(r.ID); ok {
+		return v.(*runSecrets)
+	}
+	rs := &runSecrets{}
+	if ag, err := s.store.GetAgent(ctx, r.AgentID); err == nil && s.secrets.Available() {
+		env, err := s.resolveSecrets(ctx, r.ProjectID, ag)
+		if err != nil {
+			slog.Warn("resolve run secrets failed", "run_id", r.ID, "error", err)
+		}
+		rs = newRunSecrets(env)
+	}
+	if r.Status == run.StatusRunning || r.Status == run.StatusQualityGate {
+		s.runSecrets.Store(r.ID, rs)
+	}
+	return rs
+}
+
+func newRunSecrets(env map[string]string) *runSecrets {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	return &runSecrets{guard: secret.NewGuard(names), redactor: secret.NewRedactor(env)}
+}
+
 func (s *RuntimeService) checkTermination(r *run.Run, profile *policy.PolicyProfile) string {
 	tc := profile.Termination
 
@@ -812,6 +1852,7 @@ func (s *RuntimeService) appendRunEvent(ctx context.Context, evType event.Type,
 		AgentID:   r.AgentID,
 		TaskID:    r.TaskID,
 		ProjectID: r.ProjectID,
+		RunID:     r.ID,
 		Type:      evType,
 		Payload:   payloadJSON,
 		RequestID: logger.RequestID(ctx),
@@ -842,3 +1883,23 @@ func cancelAll(fns []func()) {
 		fn()
 	}
 }
+
+// runConfig returns the agent config for a run, with the model and mode
+// replaced when the run overrides them. The agent's own config map is not
+// modified.
+func runConfig(cfg map[string]string, model, mode string) map[string]string {
+	if model == "" && mode == "" {
+		return cfg
+	}
+	out := make(map[string]string, len(cfg)+2)
+	for k, v := range cfg {
+		out[k] = v
+	}
+	if model != "" {
+		out["model"] = model
+	}
+	if mode != "" {
+		out["mode"] = mode
+	}
+	return out
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+)
+
+// ErrDraining is returned by StartRun while the server shuts down.
+var ErrDraining = errors.New("server is shutting down; start the run again shortly")
+
+// drainPoll is how often Drain checks whether the runs it waits for have
+// finished.
+const drainPoll = 500 * time.Millisecond
+
+// Drain prepares the server to shut down: StartRun is refused from now
+// on, the active runs this server started are given up to timeout to
+// finish, and the rest are marked interrupted so that another server, or
+// this one after a restart, resumes them (see ResumeInterruptedRuns).
+// Workers keep executing interrupted runs; their messages wait in the
+// durable NATS consumers. It returns how many runs were interrupted.
+func (s *RuntimeService) Drain(ctx context.Context, timeout time.Duration) int {
+	s.draining.Store(true)
+	deadline := time.Now().Add(timeout)
+	for {
+		active := s.ownedActiveRuns(ctx)
+		if len(active) == 0 {
+			return 0
+		}
+		if !time.Now().Before(deadline) {
+			return s.interruptRuns(ctx, active)
+		}
+		select {
+		case <-ctx.Done():
+			return s.interruptRuns(context.WithoutCancel(ctx), active)
+		case <-time.After(drainPoll):
+		}
+	}
+}
+
+// Draining reports whether the server is draining runs to shut down.
+func (s *RuntimeService) Draining() bool {
+	return s.draining.Load()
+}
+
+// ownedActiveRuns returns the runs started or resumed by this server that
+// are still active, and forgets the ones that finished, including those
+// completed by another server.
+func (s *RuntimeService) ownedActiveRuns(ctx context.Context) []run.Run {
+	var ids []string
+	s.owned.Range(func(k, _ any) bool {
+		ids = append(ids, k.(string))
+		return true
+	})
+	if len(ids) == 0 {
+		return nil
+	}
+	runs, err := s.store.GetRuns(ctx, ids)
+	if err != nil {
+		slog.Warn("drain: load active runs", "error", err)
+		return nil
+	}
+	active := runs[:0]
+	for i := range runs {
+		if runs[i].Status.Active() {
+			active = append(active, runs[i])
+		} else {
+			s.owned.Delete(runs[i].ID)
+		}
+	}
+	return active
+}
+
+// interruptRuns marks runs as interrupted and returns how many were.
+func (s *RuntimeService) interruptRuns(ctx context.Context, runs []run.Run) int {
+	n := 0
+	for i := range runs {
+		r := &runs[i]
+		if err := s.store.SetRunInterrupted(ctx, r.ID, true); err != nil {
+			slog.Error("drain: mark run interrupted", "run_id", r.ID, "error", err)
+			continue
+		}
+		s.owned.Delete(r.ID)
+		s.appendRunEvent(ctx, event.TypeRunInterrupted, r, map[string]string{
+			"status": string(r.Status),
+			"reason": "server shutdown",
+		})
+		n++
+	}
+	return n
+}
+
+// ResumeInterruptedRuns takes over the active runs a server marked
+// interrupted when it shut down: it claims them by clearing the mark,
+// restores their stall detection and records the resumption. A run
+// another server claimed first is left to it. Called on startup; it
+// returns how many runs were resumed.
+func (s *RuntimeService) ResumeInterruptedRuns(ctx context.Context) (int, error) {
+	runs, err := s.store.ListActiveRuns(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for i := range runs {
+		r := &runs[i]
+		if r.InterruptedAt == nil {
+			continue
+		}
+		claimed, err := s.store.ClaimInterruptedRun(ctx, r.ID)
+		if err != nil {
+			slog.Error("resume interrupted run", "run_id", r.ID, "error", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if profile, ok := s.policy.GetProfile(r.Policy()); ok {
+			s.trackStalls(r.ID, &profile)
+		}
+		s.owned.Store(r.ID, struct{}{})
+		s.appendRunEvent(ctx, event.TypeRunResumed, r, map[string]string{
+			"interrupted_at": r.InterruptedAt.UTC().Format(time.RFC3339),
+		})
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/port/sandbox"
+)
+
+// RunStartEnv is the env var that hands a sandboxed worker the start
+// message of the single run it executes.
+const RunStartEnv = "CODEFORGE_RUN_START"
+
+// sandboxExitGrace is how long a run may stay active after its sandbox
+// exited before it is failed; the worker's completion message may still
+// be in flight.
+const sandboxExitGrace = 30 * time.Second
+
+// SandboxService launches sandbox-mode runs in their own sandbox through a
+// sandbox driver, streams the sandbox output back over NATS and removes the
+// sandbox when the run ends.
+type SandboxService struct {
+	store  database.Store
+	queue  messagequeue.Queue
+	driver sandbox.Driver
+	cfg    *config.Sandbox
+	active sync.Map // map[runID]string (sandbox ID)
+}
+
+// NewSandboxService creates a SandboxService that launches sandboxes with driver.
+func NewSandboxService(store database.Store, queue messagequeue.Queue, driver sandbox.Driver, cfg *config.Sandbox) *SandboxService {
+	return &SandboxService{store: store, queue: queue, driver: driver, cfg: cfg}
+}
+
+// Driver returns the name of the sandbox driver.
+func (s *SandboxService) Driver() string {
+	return s.driver.Name()
+}
+
+// Launch starts the sandbox of a run. The worker in the sandbox executes
+// the run described by payload and reports over NATS like a pooled worker.
+// The profile's timeout kills the sandbox after that long, its isolation
+// selects the sandbox backend and its egress policy is enforced by a proxy
+// sidecar.
+func (s *SandboxService) Launch(ctx context.Context, r *run.Run, payload *messagequeue.RunStartPayload, profile *policy.PolicyProfile) error {
+	timeout := time.Duration(profile.Termination.TimeoutSeconds) * time.Second
+	p, err := s.store.GetProject(ctx, r.ProjectID)
+	if err != nil {
+		return fmt.Errorf("get project: %w", err)
+	}
+	ws := sandbox.Workspace{RepoURL: p.RepoURL}
+	hostPath := r.WorktreePath
+	if hostPath == "" {
+		hostPath = p.WorkspacePath
+	}
+	if hostPath != "" {
+		if ws.HostPath, err = filepath.Abs(hostPath); err != nil {
+			return fmt.Errorf("resolve workspace: %w", err)
+		}
+	}
+
+	// The worker sees the workspace at the sandbox's work dir.
+	start := *payload
+	start.WorkspacePath = sandbox.WorkDir
+	data, err := json.Marshal(&start)
+	if err != nil {
+		return fmt.Errorf("marshal run start: %w", err)
+	}
+	env := make(map[string]string, len(s.cfg.Env)+1)
+	for k, v := range s.cfg.Env {
+		env[k] = v
+	}
+	env[RunStartEnv] = string(data)
+
+	spec := &sandbox.Spec{
+		RunID:     r.ID,
+		ProjectID: r.ProjectID,
+		Image:     s.cfg.Image,
+		Env:       env,
+		Workspace: ws,
+		Limits: sandbox.Limits{
+			MemoryMB:  s.cfg.MemoryMB,
+			CPUs:      s.cfg.CPUs,
+			PIDs:      s.cfg.PIDs,
+			StorageMB: s.cfg.StorageMB,
+		},
+		NetworkMode: s.cfg.NetworkMode,
+		Timeout:     timeout,
+		Isolation:   profile.Isolation,
+	}
+	if profile.Egress != nil {
+		spec.Egress = &sandbox.Egress{Policy: *profile.Egress, Image: s.cfg.EgressImage, NoProxy: s.cfg.NoProxy}
+	}
+	id, err := s.driver.Start(ctx, spec)
+	if err != nil {
+		return fmt.Errorf("start sandbox: %w", err)
+	}
+	s.active.Store(r.ID, id)
+	slog.Info("sandbox started", "run_id", r.ID, "driver", s.driver.Name(), "sandbox_id", id, "isolation", profile.Isolation)
+
+	go s.follow(r.ID, r.TaskID, r.ProjectID, id, timeout)
+	return nil
+}
+
+// follow streams the output of a sandbox to runs.output until it exits,
+// then removes it and fails the run if the worker did not complete it.
+func (s *SandboxService) follow(runID, taskID, projectID, id string, timeout time.Duration) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	err := s.driver.Logs(ctx, id, func(l sandbox.Line) {
+		data, _ := json.Marshal(messagequeue.RunOutputPayload{RunID: runID, TaskID: taskID, Line: l.Text, Stream: "sandbox"})
+		if err := s.queue.Publish(context.Background(), messagequeue.SubjectRunOutput, data); err != nil {
+			slog.Warn("publish sandbox output failed", "run_id", runID, "error", err)
+		}
+	})
+	reason := "sandbox exited before the run completed"
+	switch {
+	case ctx.Err() != nil:
+		reason = "sandbox timed out"
+	case err != nil:
+		slog.Error("sandbox logs failed", "run_id", runID, "sandbox_id", id, "error", err)
+		reason = "sandbox failed: " + err.Error()
+	}
+	s.Release(context.Background(), runID)
+
+	if ctx.Err() == nil {
+		time.Sleep(sandboxExitGrace)
+	}
+	r, err := s.store.GetRun(context.Background(), runID)
+	if err != nil || (r.Status != run.StatusRunning && r.Status != run.StatusPending) {
+		return
+	}
+	slog.Warn("failing run of exited sandbox", "run_id", runID, "reason", reason)
+	data, _ := json.Marshal(messagequeue.RunCompletePayload{
+		RunID:     runID,
+		TaskID:    taskID,
+		ProjectID: projectID,
+		Status:    string(run.StatusFailed),
+		Error:     reason,
+		CostUSD:   r.CostUSD,
+		StepCount: r.StepCount,
+	})
+	if err := s.queue.Publish(context.Background(), messagequeue.SubjectRunComplete, data); err != nil {
+		slog.Error("publish sandbox run failure", "run_id", runID, "error", err)
+	}
+}
+
+// Release removes the sandbox of a run, if it has one. Failures are logged;
+// the driver's own garbage collection removes sandboxes left behind.
+func (s *SandboxService) Release(ctx context.Context, runID string) {
+	v, ok := s.active.LoadAndDelete(runID)
+	if !ok {
+		return
+	}
+	id := v.(string)
+	if err := s.driver.Remove(ctx, id); err != nil {
+		slog.Warn("remove sandbox failed", "run_id", runID, "sandbox_id", id, "error", err)
+		return
+	}
+	slog.Info("sandbox removed", "run_id", runID, "sandbox_id", id)
+}
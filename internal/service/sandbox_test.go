@@ -0,0 +1,228 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/port/sandbox"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+// fakeSandboxDriver prints one line per sandbox and keeps it running until
+// it is removed.
+type fakeSandboxDriver struct {
+	mu      sync.Mutex
+	specs   []*sandbox.Spec
+	removed []string
+	done    map[string]chan struct{}
+}
+
+func (d *fakeSandboxDriver) Name() string { return "fake" }
+
+func (d *fakeSandboxDriver) Start(_ context.Context, spec *sandbox.Spec) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.specs = append(d.specs, spec)
+	id := "sb-" + spec.RunID
+	d.done[id] = make(chan struct{})
+	return id, nil
+}
+
+func (d *fakeSandboxDriver) Logs(ctx context.Context, id string, fn func(sandbox.Line)) error {
+	d.mu.Lock()
+	done := d.done[id]
+	d.mu.Unlock()
+	fn(sandbox.Line{Stream: "stdout", Text: "worker started"})
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+func (d *fakeSandboxDriver) Remove(_ context.Context, id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.removed = append(d.removed, id)
+	if ch, ok := d.done[id]; ok {
+		close(ch)
+		delete(d.done, id)
+	}
+	return nil
+}
+
+func TestStartRun_Sandbox(t *testing.T) {
+	svc, store, queue, _ := newRuntimeTestEnv()
+	ctx := context.Background()
+	driver := &fakeSandboxDriver{done: make(map[string]chan struct{})}
+	svc.SetSandboxService(service.NewSandboxService(store, queue, driver, &config.Sandbox{
+		Image:    "codeforge-worker:latest",
+		MemoryMB: 512,
+		PIDs:     64,
+		Env:      map[string]string{"NATS_URL": "nats://nats:4222"},
+	}))
+
+	r, err := svc.StartRun(ctx, &run.StartRequest{TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", ExecMode: run.ExecModeSandbox})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := queue.lastMessage(messagequeue.SubjectRunStart); ok {
+		t.Fatal("sandbox run should not be published to the worker pool")
+	}
+
+	driver.mu.Lock()
+	if len(driver.specs) != 1 {
+		t.Fatalf("expected one sandbox, got %d", len(driver.specs))
+	}
+	spec := driver.specs[0]
+	driver.mu.Unlock()
+	if spec.Image != "codeforge-worker:latest" || spec.Limits.MemoryMB != 512 || spec.Limits.PIDs != 64 ||
+		spec.Workspace.HostPath != "/tmp/test-workspace" || spec.Env["NATS_URL"] != "nats://nats:4222" {
+		t.Fatalf("unexpected sandbox spec %+v", spec)
+	}
+	if spec.Timeout != 600*time.Second {
+		t.Fatalf("expected the policy timeout, got %v", spec.Timeout)
+	}
+	var start messagequeue.RunStartPayload
+	if err := json.Unmarshal([]byte(spec.Env[service.RunStartEnv]), &start); err != nil {
+		t.Fatal(err)
+	}
+	if start.RunID != r.ID || start.WorkspacePath != sandbox.WorkDir {
+		t.Fatalf("unexpected run start %+v", start)
+	}
+
+	// Sandbox output is streamed to runs.output.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if msg, ok := queue.lastMessage(messagequeue.SubjectRunOutput); ok {
+			var out messagequeue.RunOutputPayload
+			_ = json.Unmarshal(msg.Data, &out)
+			if out.RunID != r.ID || out.Line != "worker started" || out.Stream != "sandbox" {
+				t.Fatalf("unexpected output %+v", out)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("sandbox output was not published")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Cancelling the run removes its sandbox.
+	if err := svc.CancelRun(ctx, r.ID); err != nil {
+		t.Fatal(err)
+	}
+	driver.mu.Lock()
+	defer driver.mu.Unlock()
+	if len(driver.removed) != 1 || driver.removed[0] != "sb-"+r.ID {
+		t.Fatalf("expected sandbox to be removed, got %v", driver.removed)
+	}
+}
+
+func TestStartRun_UntrustedProfile(t *testing.T) {
+	svc, store, queue, _ := newRuntimeTestEnv()
+	ctx := context.Background()
+	req := &run.StartRequest{TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", PolicyProfile: "headless-untrusted-sandbox"}
+
+	// Without a sandbox driver an untrusted run must not fall back to the worker pool.
+	if _, err := svc.StartRun(ctx, req); err == nil || !strings.Contains(err.Error(), "gvisor isolation") {
+		t.Fatalf("expected isolation error, got %v", err)
+	}
+	if _, ok := queue.lastMessage(messagequeue.SubjectRunStart); ok {
+		t.Fatal("untrusted run was published to the worker pool")
+	}
+
+	driver := &fakeSandboxDriver{done: make(map[string]chan struct{})}
+	svc.SetSandboxService(service.NewSandboxService(store, queue, driver, &config.Sandbox{
+		Image:       "codeforge-worker:latest",
+		EgressImage: "codeforge-egress:latest",
+		NoProxy:     []string{"nats", "litellm"},
+	}))
+	r, err := svc.StartRun(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ExecMode != run.ExecModeSandbox {
+		t.Fatalf("expected untrusted run to be forced into sandbox mode, got %q", r.ExecMode)
+	}
+	driver.mu.Lock()
+	if len(driver.specs) != 1 || driver.specs[0].Isolation != policy.IsolationGVisor {
+		driver.mu.Unlock()
+		t.Fatalf("expected one gvisor sandbox, got %+v", driver.specs)
+	}
+	eg := driver.specs[0].Egress
+	driver.mu.Unlock()
+	if eg == nil || eg.Image != "codeforge-egress:latest" || !eg.Policy.AllowPackageRegistries || len(eg.NoProxy) != 2 {
+		t.Fatalf("expected registry-only egress proxy, got %+v", eg)
+	}
+	if err := svc.CancelRun(ctx, r.ID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStartRun_RequestedProfileKeepsProjectIsolation(t *testing.T) {
+	svc, store, queue, _ := newRuntimeTestEnv()
+	ctx := context.Background()
+	store.projects[0].Config = map[string]string{policy.ConfigKeyProfile: "headless-untrusted-sandbox"}
+
+	// Naming a trusted mount profile does not lift the project's isolation.
+	req := &run.StartRequest{TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", PolicyProfile: "trusted-mount-autonomous"}
+	if _, err := svc.StartRun(ctx, req); err == nil || !strings.Contains(err.Error(), "gvisor isolation") {
+		t.Fatalf("expected isolation error, got %v", err)
+	}
+
+	driver := &fakeSandboxDriver{done: make(map[string]chan struct{})}
+	svc.SetSandboxService(service.NewSandboxService(store, queue, driver, &config.Sandbox{Image: "codeforge-worker:latest"}))
+	r, err := svc.StartRun(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ExecMode != run.ExecModeSandbox || r.PolicyProfile != "trusted-mount-autonomous" ||
+		r.EffectivePolicy != "headless-safe-sandbox>headless-untrusted-sandbox>trusted-mount-autonomous" {
+		t.Fatalf("expected a layered run in sandbox mode, got %q under %q as %q", r.ExecMode, r.PolicyProfile, r.EffectivePolicy)
+	}
+	driver.mu.Lock()
+	defer driver.mu.Unlock()
+	if len(driver.specs) != 1 || driver.specs[0].Isolation != policy.IsolationGVisor {
+		t.Fatalf("expected one gvisor sandbox, got %+v", driver.specs)
+	}
+}
+
+func TestHandleEgressBlocked(t *testing.T) {
+	_, store, queue, _ := newRuntimeTestEnv()
+	es := &runtimeMockEventStore{}
+	svc := service.NewRuntimeService(store, queue, &runtimeMockBroadcaster{}, es,
+		service.NewPolicyService("headless-untrusted-sandbox", nil), &config.Runtime{})
+	store.runs = append(store.runs, run.Run{ID: "run-1", TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", PolicyProfile: "headless-untrusted-sandbox", Status: run.StatusRunning})
+	ctx := context.Background()
+
+	err := svc.HandleEgressBlocked(ctx, &messagequeue.EgressBlockedPayload{
+		RunID: "run-1", ProjectID: "proj-1", Host: "evil.example.com", Port: "443", Method: "CONNECT", Reason: "host evil.example.com not in egress allowlist",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if len(es.events) != 1 || es.events[0].Type != event.TypeEgressBlocked || es.events[0].AgentID != "agent-1" {
+		t.Fatalf("expected one egress blocked event, got %+v", es.events)
+	}
+	var payload map[string]string
+	_ = json.Unmarshal(es.events[0].Payload, &payload)
+	if payload["host"] != "evil.example.com" || payload["policy_profile"] != "headless-untrusted-sandbox" {
+		t.Fatalf("unexpected event payload %v", payload)
+	}
+
+	if err := svc.HandleEgressBlocked(ctx, &messagequeue.EgressBlockedPayload{RunID: "missing"}); err == nil {
+		t.Fatal("expected error for an unknown run")
+	}
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+	"github.com/Strob0t/CodeForge/internal/domain/scope"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+)
+
+// ScopeService groups a tenant's projects and knowledge bases into
+// retrieval scopes and searches them together: the indexes of the member
+// projects and the knowledge bases attached to them are ranked against
+// the query, weighted by source, merged, and reranked like project
+// searches.
+type ScopeService struct {
+	store     database.Store
+	retrieval *RetrievalService
+}
+
+// NewScopeService creates a ScopeService.
+func NewScopeService(store database.Store, retrieval *RetrievalService) *ScopeService {
+	return &ScopeService{store: store, retrieval: retrieval}
+}
+
+// Create registers a scope.
+func (s *ScopeService) Create(ctx context.Context, req *scope.CreateRequest) (*scope.Scope, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validate scope: %w", err)
+	}
+	tenantID := req.TenantID
+	scoped := tenant.FromContext(ctx)
+	switch {
+	case tenantID == "" && scoped != "":
+		tenantID = scoped
+	case tenantID == "":
+		tenantID = tenant.DefaultID
+	case scoped != "" && tenantID != scoped:
+		return nil, fmt.Errorf("tenant %s: %w", tenantID, domain.ErrNotFound)
+	}
+	if _, err := s.store.GetTenant(ctx, tenantID); err != nil {
+		return nil, fmt.Errorf("get tenant: %w", err)
+	}
+	if err := s.checkSources(ctx, tenantID, req); err != nil {
+		return nil, err
+	}
+
+	sc := &scope.Scope{
+		TenantID:       tenantID,
+		Name:           req.Name,
+		Description:    req.Description,
+		Projects:       req.Projects,
+		KnowledgeBases: req.KnowledgeBases,
+	}
+	if err := s.store.CreateScope(ctx, sc); err != nil {
+		return nil, err
+	}
+	slog.Info("scope created", "scope_id", sc.ID, "tenant_id", tenantID, "projects", len(sc.Projects))
+	return sc, nil
+}
+
+// Get returns a scope by ID.
+func (s *ScopeService) Get(ctx context.Context, id string) (*scope.Scope, error) {
+	return s.store.GetScope(ctx, id)
+}
+
+// List returns the scopes of the request's tenant, or of all tenants for
+// unscoped requests.
+func (s *ScopeService) List(ctx context.Context) ([]scope.Scope, error) {
+	return s.store.ListScopes(ctx)
+}
+
+// Update replaces the name, description and sources of a scope. Its
+// tenant stays the same.
+func (s *ScopeService) Update(ctx context.Context, id string, req *scope.CreateRequest) (*scope.Scope, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validate scope: %w", err)
+	}
+	sc, err := s.store.GetScope(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkSources(ctx, sc.TenantID, req); err != nil {
+		return nil, err
+	}
+	sc.Name, sc.Description = req.Name, req.Description
+	sc.Projects, sc.KnowledgeBases = req.Projects, req.KnowledgeBases
+	if err := s.store.UpdateScope(ctx, sc); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// Delete removes a scope. Its projects and knowledge bases are kept.
+func (s *ScopeService) Delete(ctx context.Context, id string) error {
+	return s.store.DeleteScope(ctx, id)
+}
+
+// checkSources checks that the projects and knowledge bases of a scope
+// belong to its tenant.
+func (s *ScopeService) checkSources(ctx context.Context, tenantID string, req *scope.CreateRequest) error {
+	for i := range req.Projects {
+		id := req.Projects[i].ID
+		p, err := s.store.GetProject(ctx, id)
+		if err != nil {
+			return fmt.Errorf("get project %s: %w", id, err)
+		}
+		if tenantOf(p) != tenantID {
+			return fmt.Errorf("project %s: %w", id, domain.ErrNotFound)
+		}
+	}
+	for i := range req.KnowledgeBases {
+		id := req.KnowledgeBases[i].ID
+		kb, err := s.store.GetKnowledgeBase(ctx, id)
+		if err != nil {
+			return fmt.Errorf("get knowledge base %s: %w", id, err)
+		}
+		if kb.TenantID != tenantID {
+			return fmt.Errorf("knowledge base %s: %w", id, domain.ErrNotFound)
+		}
+	}
+	return nil
+}
+
+// Search ranks the chunks of the scope's project indexes and knowledge
+// bases against a query and merges them by weighted score. The query is
+// embedded once per embedding model among the sources, since vectors of
+// different models are not comparable. Projects without a usable index
+// and knowledge bases that cannot be searched are skipped and listed in
+// the result. With a reranker configured, the best merged candidates are
+// reranked unless the request opts out, and their rerank scores weighted.
+func (s *ScopeService) Search(ctx context.Context, id string, req *retrieval.SearchRequest) (*scope.SearchResult, error) {
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, retrieval.ErrQueryRequired
+	}
+	sc, err := s.store.GetScope(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	rerank := s.retrieval.cfg.RerankProvider != "" && (req.Rerank == nil || *req.Rerank)
+	n := limit
+	if rerank {
+		n = max(limit, s.retrieval.cfg.RerankTopN)
+	}
+
+	q := &queryVectors{retrieval: s.retrieval, query: req.Query, vecs: make(map[string][]float32)}
+	out := &scope.SearchResult{}
+	var candidates []scope.Result
+	for i := range sc.Projects {
+		src := &sc.Projects[i]
+		results, reason, err := s.searchProject(ctx, sc, src.ID, q, n)
+		switch {
+		case err != nil:
+			return nil, err
+		case reason != "":
+			out.Skipped = append(out.Skipped, scope.Skipped{ProjectID: src.ID, Reason: reason})
+		default:
+			candidates = append(candidates, scope.Weigh(results, src.ID, "", src.EffectiveWeight())...)
+		}
+	}
+
+	kbs, err := s.store.ListKnowledgeBases(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list knowledge bases: %w", err)
+	}
+	for i := range kbs {
+		kb := &kbs[i]
+		if kb.TenantID != sc.TenantID || kb.Chunks == 0 {
+			continue
+		}
+		weight, ok := sc.KnowledgeWeight(kb.ID, kb.Attached)
+		if !ok {
+			continue
+		}
+		vec, reason, err := q.vector(ctx, retrieval.Embedding{Provider: kb.Provider, Model: kb.Model, Dimensions: kb.Dimensions})
+		switch {
+		case err != nil:
+			return nil, err
+		case reason != "":
+			out.Skipped = append(out.Skipped, scope.Skipped{KnowledgeBaseID: kb.ID, Reason: reason})
+			continue
+		}
+		chunks, err := s.store.ListKnowledgeChunks(ctx, kb.ID)
+		if err != nil {
+			return nil, err
+		}
+		for j := range chunks {
+			chunks[j].Source = kb.Name
+		}
+		candidates = append(candidates, scope.Weigh(retrieval.Rank(vec, chunks, n), "", kb.ID, weight)...)
+	}
+
+	merged := scope.Merge(candidates, n)
+	if rerank && len(merged) > 0 {
+		merged = s.rerank(ctx, sc, req.Query, merged)
+	}
+	out.Results = scope.Merge(merged, limit)
+	return out, nil
+}
+
+// searchProject returns the best n chunks of a member project's index, or
+// the reason the project is skipped: it is gone, not indexed, or its index
+// was built with another embedding than the project now uses.
+func (s *ScopeService) searchProject(ctx context.Context, sc *scope.Scope, projectID string, q *queryVectors, n int) ([]retrieval.Result, string, error) {
+	p, err := s.store.GetProject(ctx, projectID)
+	if errors.Is(err, domain.ErrNotFound) || (err == nil && tenantOf(p) != sc.TenantID) {
+		return nil, "project not found", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("get project %s: %w", projectID, err)
+	}
+	idx, err := s.store.GetRetrievalIndex(ctx, projectID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, retrieval.ErrNotIndexed.Error(), nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	e, err := s.retrieval.Embedding(p)
+	if err != nil {
+		return nil, err.Error(), nil
+	}
+	if !idx.Matches(e) {
+		return nil, fmt.Sprintf("index was built with %s/%s, project uses %s/%s; reindex",
+			idx.Provider, idx.Model, e.Provider, e.Model), nil
+	}
+	vec, reason, err := q.vector(ctx, indexEmbedding(idx))
+	if err != nil || reason != "" {
+		return nil, reason, err
+	}
+	chunks, err := s.retrieval.indexChunks(ctx, idx)
+	if err != nil {
+		return nil, "", err
+	}
+	return retrieval.Rank(vec, chunks, n), "", nil
+}
+
+// rerank reranks merged candidates with the retrieval service's
+// cross-encoder and weights their rerank scores by source. A failed
+// rerank keeps the candidates' embedding scores.
+func (s *ScopeService) rerank(ctx context.Context, sc *scope.Scope, query string, candidates []scope.Result) []scope.Result {
+	plain := make([]retrieval.Result, len(candidates))
+	sources := make(map[string]*scope.Result, len(candidates))
+	for i := range candidates {
+		plain[i] = candidates[i].Result
+		sources[candidates[i].ID] = &candidates[i]
+	}
+	reranked := s.retrieval.rerank(ctx, slog.String("scope_id", sc.ID), query, plain, len(plain))
+	out := make([]scope.Result, 0, len(reranked))
+	for i := range reranked {
+		src := sources[reranked[i].ID]
+		out = append(out, scope.Weigh(reranked[i:i+1], src.ProjectID, src.KnowledgeBaseID, src.Weight)...)
+	}
+	return out
+}
+
+// queryVectors embeds the query of a scope search once per provider and
+// model, and fits the vector to the dimensions of each source.
+type queryVectors struct {
+	retrieval *RetrievalService
+	query     string
+	vecs      map[string][]float32
+}
+
+// vector returns the query vector for sources embedded with e, or the
+// reason such sources cannot be searched. Failing to embed the query is
+// an error.
+func (q *queryVectors) vector(ctx context.Context, e retrieval.Embedding) ([]float32, string, error) {
+	if _, err := q.retrieval.provider(e.Provider); err != nil {
+		return nil, err.Error(), nil
+	}
+	key := e.Provider + "/" + e.Model
+	vec, ok := q.vecs[key]
+	if !ok {
+		var err error
+		if vec, err = q.retrieval.embedQuery(ctx, e.Provider, e.Model, q.query); err != nil {
+			return nil, "", err
+		}
+		q.vecs[key] = vec
+	}
+	fitted, err := retrieval.Fit(vec, e.Dimensions)
+	if err != nil {
+		return nil, err.Error(), nil
+	}
+	return fitted, "", nil
+}
@@ -0,0 +1,124 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/knowledge"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/retrieval"
+	"github.com/Strob0t/CodeForge/internal/domain/scope"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestScopeService_Search(t *testing.T) {
+	retrievalSvc, store, _ := newRetrievalTestEnv(t)
+	store.tenants = []tenant.Tenant{{ID: tenant.DefaultID, Name: "Default"}, {ID: "acme", Name: "Acme"}}
+	ctx := context.Background()
+
+	// proj-1 is the app, proj-2 the shared SDK, proj-3 is not indexed.
+	sdk := t.TempDir()
+	if err := os.WriteFile(filepath.Join(sdk, "sdk.go"), []byte("beta beta\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store.projects = append(store.projects,
+		project.Project{ID: "proj-2", Name: "sdk", WorkspacePath: sdk},
+		project.Project{ID: "proj-3", Name: "docs", WorkspacePath: t.TempDir()},
+		project.Project{ID: "proj-4", Name: "other", TenantID: "acme"},
+	)
+	for _, id := range []string{"proj-1", "proj-2"} {
+		if _, err := retrievalSvc.Index(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The runbooks are attached to the SDK and searched with its weight;
+	// the other knowledge base is attached elsewhere and left out.
+	for _, kb := range []*knowledge.KnowledgeBase{
+		{TenantID: tenant.DefaultID, Name: "runbooks", ProjectIDs: []string{"proj-2"}},
+		{TenantID: tenant.DefaultID, Name: "elsewhere", ProjectIDs: []string{"proj-9"}},
+	} {
+		if err := store.CreateKnowledgeBase(ctx, kb); err != nil {
+			t.Fatal(err)
+		}
+		kb.Provider, kb.Model, kb.Dimensions, kb.Documents, kb.Chunks = "litellm", "text-embedding-3-small", 4, 1, 1
+		chunks := []retrieval.Chunk{{Path: "https://wiki/" + kb.Name, Title: "Runbook", StartLine: 1, EndLine: 1, Content: "beta", Embedding: []float32{0, 1, 0, 0}}}
+		if err := store.ReplaceKnowledgeChunks(ctx, kb, chunks); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	svc := service.NewScopeService(store, retrievalSvc)
+	req := &scope.CreateRequest{
+		Name:     "platform",
+		Projects: []scope.Source{{ID: "proj-1"}, {ID: "proj-2", Weight: 2}, {ID: "proj-4"}},
+	}
+	if _, err := svc.Create(ctx, req); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected a project of another tenant to be rejected, got %v", err)
+	}
+	req.Projects[2].ID = "proj-3"
+	sc, err := svc.Create(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.TenantID != tenant.DefaultID {
+		t.Fatalf("unexpected scope %+v", sc)
+	}
+
+	if _, err := svc.Search(ctx, sc.ID, &retrieval.SearchRequest{Query: " "}); !errors.Is(err, retrieval.ErrQueryRequired) {
+		t.Fatalf("expected ErrQueryRequired, got %v", err)
+	}
+	res, err := svc.Search(ctx, sc.ID, &retrieval.SearchRequest{Query: "beta", Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Results) != 3 {
+		t.Fatalf("expected 3 results, got %+v", res.Results)
+	}
+	if r := res.Results[0]; r.Path != "sdk.go" || r.ProjectID != "proj-2" || r.Weight != 2 || r.RankScore <= 1 {
+		t.Fatalf("expected the SDK first by weight, got %+v", r)
+	}
+	if r := res.Results[1]; r.Source != "runbooks" || r.KnowledgeBaseID == "" || r.Weight != 2 {
+		t.Fatalf("expected the SDK's runbooks second, got %+v", r)
+	}
+	if r := res.Results[2]; r.Path != "b.md" || r.ProjectID != "proj-1" || r.Weight != 1 {
+		t.Fatalf("expected the app's best chunk third, got %+v", r)
+	}
+	if len(res.Skipped) != 1 || res.Skipped[0].ProjectID != "proj-3" || res.Skipped[0].Reason != retrieval.ErrNotIndexed.Error() {
+		t.Fatalf("expected the unindexed project skipped, got %+v", res.Skipped)
+	}
+
+	// Reranked results are ordered by their weighted rerank scores.
+	reranking := service.NewRetrievalService(store, &config.Retrieval{
+		EmbeddingProvider: "litellm",
+		EmbeddingModel:    "text-embedding-3-small",
+		RerankProvider:    "fake",
+		RerankTopN:        5,
+		RerankTimeout:     time.Second,
+	}, &fakeEmbedder{name: "litellm", dims: 4})
+	reranking.SetRerankers(&fakeReranker{})
+	res, err = service.NewScopeService(store, reranking).Search(ctx, sc.ID, &retrieval.SearchRequest{Query: "beta", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Results) != 2 || res.Results[0].RerankScore == nil || res.Results[0].RankScore != 2**res.Results[0].RerankScore {
+		t.Fatalf("expected weighted rerank scores, got %+v", res.Results)
+	}
+
+	req.KnowledgeBases = []scope.Source{{ID: "kb-404"}}
+	if _, err := svc.Update(ctx, sc.ID, req); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected an unknown knowledge base to be rejected, got %v", err)
+	}
+	if err := svc.Delete(ctx, sc.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Search(ctx, sc.ID, &retrieval.SearchRequest{Query: "beta"}); err == nil {
+		t.Fatal("expected the scope deleted")
+	}
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/ws"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/seatbelt"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+// maxWatchedDeliveries bounds the latest deliveries of a project that a
+// webhook is matched against.
+const maxWatchedDeliveries = 200
+
+// maxDeliveryDetail bounds the failure summary kept on a delivery.
+const maxDeliveryDetail = 500
+
+// SeatbeltService rolls runs back when their delivered changes fail
+// downstream: CI check and push webhooks of the git host are matched
+// against the recorded deliveries, failing runs are marked
+// failed_downstream and, in follow-up mode, a task to fix a failed check
+// is created with the check's output attached.
+type SeatbeltService struct {
+	store       database.Store
+	runtime     *RuntimeService
+	secrets     *SecretService
+	attachments *AttachmentService
+}
+
+// NewSeatbeltService creates a SeatbeltService. Run events are appended and
+// broadcast through runtime; follow-up tasks get the check output through
+// attachments.
+func NewSeatbeltService(store database.Store, runtime *RuntimeService, secrets *SecretService, attachments *AttachmentService) *SeatbeltService {
+	return &SeatbeltService{store: store, runtime: runtime, secrets: secrets, attachments: attachments}
+}
+
+// Get returns the delivery of a run.
+func (s *SeatbeltService) Get(ctx context.Context, runID string) (*seatbelt.Delivery, error) {
+	return s.store.GetRunDelivery(ctx, runID)
+}
+
+// HandleWebhook verifies a CI or push webhook of providerName against the
+// projects of that provider with seatbelt mode on and fails the runs whose
+// deliveries it concerns. It returns how many runs were marked, or
+// gitprovider.ErrInvalidSignature if no project verified the delivery.
+func (s *SeatbeltService) HandleWebhook(ctx context.Context, providerName string, header http.Header, body []byte) (int, error) {
+	projects, err := s.store.ListProjects(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list projects: %w", err)
+	}
+
+	verified, marked := false, 0
+	for i := range projects {
+		p := &projects[i]
+		if p.Provider != providerName {
+			continue
+		}
+		mode, err := seatbelt.ModeFromConfig(p.Config)
+		if err != nil {
+			slog.Warn("seatbelt webhook: project config", "project_id", p.ID, "error", err)
+			continue
+		}
+		if mode == seatbelt.ModeOff {
+			continue
+		}
+		prov, err := hostedGitProvider(ctx, s.secrets, p)
+		if err != nil {
+			slog.Warn("seatbelt webhook: build provider", "project_id", p.ID, "provider", providerName, "error", err)
+			continue
+		}
+		watcher, ok := prov.(gitprovider.DeliveryWatcher)
+		if !ok || !prov.Capabilities().Webhook {
+			continue
+		}
+		sig, err := watcher.ParseDeliveryWebhook(header, body)
+		switch {
+		case errors.Is(err, gitprovider.ErrInvalidSignature):
+			continue
+		case errors.Is(err, gitprovider.ErrIgnoredEvent):
+			verified = true
+			continue
+		case err != nil:
+			slog.Warn("seatbelt webhook: parse", "project_id", p.ID, "provider", providerName, "error", err)
+			verified = true
+			continue
+		}
+		verified = true
+		n, err := s.handleSignal(ctx, p, mode, sig)
+		if err != nil {
+			return marked, err
+		}
+		marked += n
+	}
+	if !verified {
+		return 0, gitprovider.ErrInvalidSignature
+	}
+	return marked, nil
+}
+
+// handleSignal fails the deliveries of a project that sig concerns and
+// returns how many there were.
+func (s *SeatbeltService) handleSignal(ctx context.Context, p *project.Project, mode seatbelt.Mode, sig *seatbelt.Signal) (int, error) {
+	deliveries, err := s.store.ListRunDeliveries(ctx, p.ID, maxWatchedDeliveries)
+	if err != nil {
+		return 0, fmt.Errorf("list run deliveries: %w", err)
+	}
+	marked := 0
+	for i := range deliveries {
+		d := &deliveries[i]
+		if !sig.Matches(d) {
+			continue
+		}
+		if err := s.fail(ctx, mode, d, sig); err != nil {
+			slog.Error("seatbelt: fail delivery", "run_id", d.RunID, "kind", sig.Kind, "error", err)
+			continue
+		}
+		marked++
+	}
+	return marked, nil
+}
+
+// fail records the failure on a delivery, creates the follow-up task of a
+// failed check in follow-up mode and marks the delivery's run
+// failed_downstream.
+func (s *SeatbeltService) fail(ctx context.Context, mode seatbelt.Mode, d *seatbelt.Delivery, sig *seatbelt.Signal) error {
+	now := time.Now().UTC()
+	d.FailedAt = &now
+	d.CheckURL = sig.URL
+	switch sig.Kind {
+	case seatbelt.KindCheckFailed:
+		d.Status, d.Check = seatbelt.StatusFailed, sig.Check
+		d.Detail = truncate(sig.Log, maxDeliveryDetail)
+	case seatbelt.KindRevert:
+		d.Status = seatbelt.StatusReverted
+		d.Detail = fmt.Sprintf("commit %s on %s", shortCommitHash(sig.SHA), sig.Branch)
+	}
+	if mode == seatbelt.ModeFollowUp && sig.Kind == seatbelt.KindCheckFailed {
+		id, err := s.followUp(ctx, d, sig)
+		if err != nil {
+			slog.Warn("seatbelt: follow-up task failed", "run_id", d.RunID, "error", err)
+		}
+		d.FollowUpTaskID = id
+	}
+	if err := s.store.UpdateRunDelivery(ctx, d); err != nil {
+		return err
+	}
+
+	r, err := s.store.GetRun(ctx, d.RunID)
+	if err != nil {
+		return fmt.Errorf("get run: %w", err)
+	}
+	if err := s.store.UpdateRunStatus(ctx, r.ID, run.StatusFailedDownstream, r.StepCount, r.CostUSD); err != nil {
+		return err
+	}
+	r.Status = run.StatusFailedDownstream
+
+	s.runtime.appendRunEvent(ctx, event.TypeDeliveryDownstream, r, map[string]string{
+		"status":            string(d.Status),
+		"check":             d.Check,
+		"url":               d.CheckURL,
+		"detail":            d.Detail,
+		"follow_up_task_id": d.FollowUpTaskID,
+	})
+	s.runtime.hub.BroadcastEvent(ctx, ws.EventRunStatus, ws.RunStatusEvent{
+		RunID:     r.ID,
+		TaskID:    r.TaskID,
+		ProjectID: r.ProjectID,
+		Status:    string(r.Status),
+		StepCount: r.StepCount,
+		CostUSD:   r.CostUSD,
+	})
+	s.runtime.hub.BroadcastEvent(ctx, ws.EventDelivery, ws.DeliveryEvent{
+		RunID:      r.ID,
+		TaskID:     r.TaskID,
+		ProjectID:  r.ProjectID,
+		Status:     string(run.StatusFailedDownstream),
+		Mode:       string(d.Mode),
+		CommitHash: d.CommitSHA,
+		BranchName: d.Branch,
+		PRURL:      d.PRURL,
+		Error:      d.Failure(),
+	})
+	slog.Info("run failed downstream", "run_id", r.ID, "status", d.Status, "check", d.Check,
+		"follow_up_task_id", d.FollowUpTaskID)
+	return nil
+}
+
+// followUp creates the task that fixes a failed check of a delivery and
+// attaches the check's output to it.
+func (s *SeatbeltService) followUp(ctx context.Context, d *seatbelt.Delivery, sig *seatbelt.Signal) (string, error) {
+	title := d.TaskID
+	if t, err := s.store.GetTask(ctx, d.TaskID); err == nil {
+		title = t.Title
+	}
+	logName := ""
+	if sig.Log != "" {
+		logName = "ci-output.log"
+	}
+	t, err := s.store.CreateTask(ctx, task.CreateRequest{
+		ProjectID: d.ProjectID,
+		Title:     fmt.Sprintf("Fix CI: %s", d.Check),
+		Prompt:    seatbelt.FollowUpPrompt(d, title, logName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("create task: %w", err)
+	}
+	if logName != "" {
+		// Keep the end of long output, where the failures usually are.
+		log := sig.Log
+		if limit := s.attachments.MaxBytes(); len(log) > limit {
+			log = log[len(log)-limit:]
+		}
+		if _, err := s.attachments.Upload(ctx, d.ProjectID, t.ID, logName, "text/plain", []byte(log)); err != nil {
+			return t.ID, fmt.Errorf("attach check output: %w", err)
+		}
+	}
+	return t.ID, nil
+}
+
+// shortCommitHash abbreviates a commit hash.
+func shortCommitHash(h string) string {
+	if len(h) > 12 {
+		return h[:12]
+	}
+	return h
+}
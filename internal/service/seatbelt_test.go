@@ -0,0 +1,167 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/domain/event"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/seatbelt"
+	"github.com/Strob0t/CodeForge/internal/domain/secret"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+// fakeCIProvider accepts webhooks whose X-Fake-Token header matches the
+// webhook secret; their body is the seatbelt.Signal as JSON.
+type fakeCIProvider struct {
+	gitprovider.Provider // unused local operations
+	secret               string
+}
+
+func init() {
+	gitprovider.Register("fake-ci", func(cfg map[string]string) (gitprovider.Provider, error) {
+		return &fakeCIProvider{secret: cfg[gitprovider.ConfigWebhookKey]}, nil
+	})
+}
+
+func (p *fakeCIProvider) Name() string { return "fake-ci" }
+func (p *fakeCIProvider) Capabilities() gitprovider.Capabilities {
+	return gitprovider.Capabilities{Webhook: p.secret != ""}
+}
+func (p *fakeCIProvider) ParseDeliveryWebhook(header http.Header, body []byte) (*seatbelt.Signal, error) {
+	if header.Get("X-Fake-Token") != p.secret {
+		return nil, gitprovider.ErrInvalidSignature
+	}
+	var sig seatbelt.Signal
+	if err := json.Unmarshal(body, &sig); err != nil {
+		return nil, err
+	}
+	return &sig, nil
+}
+
+func TestSeatbeltService(t *testing.T) {
+	store := &runtimeMockStore{
+		projects: []project.Project{{ID: "proj-1", Provider: "fake-ci", Config: map[string]string{
+			gitprovider.ConfigWebhookSecret: "HOOK",
+			seatbelt.ConfigMode:             string(seatbelt.ModeFollowUp),
+		}}},
+		tasks: []task.Task{{ID: "task-1", ProjectID: "proj-1", Title: "Add login"}},
+		runs: []run.Run{
+			{ID: "run-1", TaskID: "task-1", ProjectID: "proj-1", Status: run.StatusCompleted, DeliverMode: run.DeliverModePR},
+			{ID: "run-2", TaskID: "task-1", ProjectID: "proj-1", Status: run.StatusCompleted, DeliverMode: run.DeliverModePush},
+		},
+		deliveries: []seatbelt.Delivery{
+			{RunID: "run-1", ProjectID: "proj-1", TaskID: "task-1", Mode: run.DeliverModePR, Branch: "codeforge/run-1",
+				CommitSHA: "0123456789abcdef", PRURL: "https://git.example/acme/app/pull/12", Status: seatbelt.StatusDelivered},
+			{RunID: "run-2", ProjectID: "proj-1", TaskID: "task-1", Mode: run.DeliverModePush, Branch: "feature",
+				CommitSHA: "fedcba9876543210", Status: seatbelt.StatusDelivered},
+		},
+	}
+	events := &runtimeMockEventStore{}
+	runtime := service.NewRuntimeService(store, &runtimeMockQueue{}, &runtimeMockBroadcaster{}, events,
+		service.NewPolicyService("headless-safe-sandbox", nil), &config.Runtime{StallThreshold: 5, QualityGateTimeout: time.Minute})
+	secrets := newTestSecretService(t, store)
+	if _, err := secrets.Create(context.Background(), "", &secret.CreateRequest{Name: "HOOK", Value: "s3cret"}); err != nil {
+		t.Fatal(err)
+	}
+	attachments := service.NewAttachmentService(store, config.Attachments{MaxBytes: 1024, AllowedTypes: []string{"text/*"}})
+	svc := service.NewSeatbeltService(store, runtime, secrets, attachments)
+	ctx := context.Background()
+
+	send := func(token string, sig *seatbelt.Signal) (int, error) {
+		t.Helper()
+		body, err := json.Marshal(sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return svc.HandleWebhook(ctx, "fake-ci", http.Header{"X-Fake-Token": {token}}, body)
+	}
+
+	failed := &seatbelt.Signal{Kind: seatbelt.KindCheckFailed, Branch: "codeforge/run-1", SHA: "1111111", Check: "test",
+		URL: "https://ci.example/1", Log: "FAIL TestLogin\nexpected 200, got 500"}
+	if _, err := send("wrong", failed); !errors.Is(err, gitprovider.ErrInvalidSignature) {
+		t.Fatalf("expected an invalid signature, got %v", err)
+	}
+
+	// A failed check on the created branch fails the run and, in follow-up
+	// mode, creates a fix task with the check output attached.
+	if n, err := send("s3cret", failed); err != nil || n != 1 {
+		t.Fatalf("check failure: got %d, %v; want 1", n, err)
+	}
+	r, _ := store.GetRun(ctx, "run-1")
+	if r.Status != run.StatusFailedDownstream {
+		t.Errorf("run status = %q, want failed_downstream", r.Status)
+	}
+	d, err := svc.Get(ctx, "run-1")
+	if err != nil {
+		t.Fatalf("get delivery: %v", err)
+	}
+	if d.Status != seatbelt.StatusFailed || d.Check != "test" || d.CheckURL != "https://ci.example/1" || d.FailedAt == nil || d.FollowUpTaskID == "" {
+		t.Fatalf("unexpected delivery %+v", d)
+	}
+	fix, err := store.GetTask(ctx, d.FollowUpTaskID)
+	if err != nil {
+		t.Fatalf("get follow-up task: %v", err)
+	}
+	if fix.Title != "Fix CI: test" || !strings.Contains(fix.Prompt, `"Add login"`) || !strings.Contains(fix.Prompt, "ci-output.log") {
+		t.Errorf("unexpected follow-up task %+v", fix)
+	}
+	var attached bool
+	for _, a := range store.artifacts {
+		if a.Kind == artifact.KindAttachment && a.TaskID == fix.ID && string(a.Data) == failed.Log {
+			attached = true
+		}
+	}
+	if !attached {
+		t.Errorf("expected the check output attached to the follow-up task, got %+v", store.artifacts)
+	}
+	var downstream bool
+	for _, ev := range events.events {
+		downstream = downstream || (ev.Type == event.TypeDeliveryDownstream && ev.RunID == "run-1")
+	}
+	if !downstream {
+		t.Error("expected a downstream failure event on the run")
+	}
+
+	// Failed deliveries do not fail again; later commits of shared branches
+	// are not the run's.
+	if n, _ := send("s3cret", failed); n != 0 {
+		t.Errorf("repeated failure marked %d runs", n)
+	}
+	if n, _ := send("s3cret", &seatbelt.Signal{Kind: seatbelt.KindCheckFailed, Branch: "feature", SHA: "2222222", Check: "test"}); n != 0 {
+		t.Errorf("failure of another commit on a push branch marked %d runs", n)
+	}
+
+	// Reverting the pull request and the pushed commit marks both runs.
+	tasks := len(store.tasks)
+	revert := &seatbelt.Signal{Kind: seatbelt.KindRevert, Branch: "main", SHA: "3333333333333333",
+		RevertedCommits: []string{"fedcba9"}, RevertedRequests: []int{12}}
+	if n, err := send("s3cret", revert); err != nil || n != 2 {
+		t.Fatalf("revert: got %d, %v; want 2", n, err)
+	}
+	if d, _ = svc.Get(ctx, "run-2"); d.Status != seatbelt.StatusReverted || d.Detail != "commit 333333333333 on main" {
+		t.Errorf("unexpected reverted delivery %+v", d)
+	}
+	if r, _ = store.GetRun(ctx, "run-2"); r.Status != run.StatusFailedDownstream {
+		t.Errorf("run status = %q, want failed_downstream", r.Status)
+	}
+	if len(store.tasks) != tasks {
+		t.Error("reverts must not create follow-up tasks")
+	}
+
+	// Projects without seatbelt mode do not verify deliveries.
+	store.projects[0].Config[seatbelt.ConfigMode] = ""
+	if _, err := send("s3cret", failed); !errors.Is(err, gitprovider.ErrInvalidSignature) {
+		t.Errorf("expected no project to verify with seatbelt mode off, got %v", err)
+	}
+}
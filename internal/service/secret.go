@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/secret"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/vault"
+)
+
+// ErrSecretsUnavailable is returned when no secrets master key is configured.
+var ErrSecretsUnavailable = errors.New("secrets: no master key configured")
+
+// SecretService manages encrypted project and tenant-wide secrets and
+// resolves them for injection into agent runs.
+//
+// Each tenant's secrets are encrypted with a data key derived from the
+// master key for that tenant. Secrets stored before per-tenant keys were
+// encrypted with the shared key of the vault itself; they stay readable
+// until Rekey moves them to their tenant's key.
+type SecretService struct {
+	store database.Store
+	vault *vault.Vault
+
+	mu      sync.Mutex
+	tenants map[string]*vault.Vault // Data keys per tenant ID
+
+	githubApps *GitHubAppService // Installation tokens for hosted git providers (optional)
+}
+
+// NewSecretService creates a SecretService. v may be nil, in which case all
+// operations return ErrSecretsUnavailable.
+func NewSecretService(store database.Store, v *vault.Vault) *SecretService {
+	return &SecretService{store: store, vault: v, tenants: make(map[string]*vault.Vault)}
+}
+
+// SetGitHubApps sets the service minting installation tokens for GitHub
+// projects without a git_token_secret.
+func (s *SecretService) SetGitHubApps(apps *GitHubAppService) {
+	s.githubApps = apps
+}
+
+// Available reports whether secrets can be stored and resolved.
+func (s *SecretService) Available() bool {
+	return s != nil && s.vault != nil
+}
+
+// Create encrypts and stores a secret. An empty projectID creates a
+// tenant-wide secret available to all projects.
+func (s *SecretService) Create(ctx context.Context, projectID string, req *secret.CreateRequest) (*secret.Secret, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validate secret: %w", err)
+	}
+	if !s.Available() {
+		return nil, ErrSecretsUnavailable
+	}
+	tenantID, err := s.scopeTenant(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	sec := &secret.Secret{TenantID: tenantID, ProjectID: projectID, Name: req.Name}
+	if err := s.encrypt(sec, req.Value); err != nil {
+		return nil, err
+	}
+	if err := s.store.CreateSecret(ctx, sec); err != nil {
+		return nil, err
+	}
+	slog.Info("secret created", "secret_id", sec.ID, "tenant_id", tenantID, "project_id", projectID, "name", sec.Name)
+	return sec, nil
+}
+
+// List returns the secrets of a project, or the tenant-wide secrets if
+// projectID is empty. Values are never returned.
+func (s *SecretService) List(ctx context.Context, projectID string) ([]secret.Secret, error) {
+	return s.store.ListSecrets(ctx, projectID)
+}
+
+// Update rotates the value of a secret.
+func (s *SecretService) Update(ctx context.Context, id string, req *secret.UpdateRequest) (*secret.Secret, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validate secret: %w", err)
+	}
+	if !s.Available() {
+		return nil, ErrSecretsUnavailable
+	}
+	sec, err := s.store.GetSecret(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.encrypt(sec, req.Value); err != nil {
+		return nil, err
+	}
+	if err := s.store.UpdateSecretValue(ctx, id, sec.Ciphertext); err != nil {
+		return nil, err
+	}
+	slog.Info("secret rotated", "secret_id", id, "name", sec.Name)
+	return s.store.GetSecret(ctx, id)
+}
+
+// Delete removes a secret.
+func (s *SecretService) Delete(ctx context.Context, id string) error {
+	return s.store.DeleteSecret(ctx, id)
+}
+
+// Resolve decrypts the named secrets for a project. Project secrets take
+// precedence over tenant-wide secrets of the same name; a name defined in
+// neither scope is an error.
+func (s *SecretService) Resolve(ctx context.Context, projectID string, names []string) (map[string]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	if !s.Available() {
+		return nil, ErrSecretsUnavailable
+	}
+	tenantID, err := s.scopeTenant(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	ctx = tenant.NewContext(ctx, tenantID)
+	scopes := []string{""}
+	if projectID != "" {
+		scopes = append(scopes, projectID)
+	}
+	byName := make(map[string]secret.Secret)
+	for _, scope := range scopes {
+		secs, err := s.store.ListSecrets(ctx, scope)
+		if err != nil {
+			return nil, fmt.Errorf("list secrets: %w", err)
+		}
+		for i := range secs {
+			byName[secs[i].Name] = secs[i]
+		}
+	}
+
+	env := make(map[string]string, len(names))
+	for _, name := range names {
+		sec, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("secret %s: %w", name, domain.ErrNotFound)
+		}
+		plain, _, err := s.decrypt(&sec)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt secret %s: %w", name, err)
+		}
+		env[name] = string(plain)
+	}
+	return env, nil
+}
+
+// KeyStatus counts all secrets, those still encrypted with the shared key
+// and those no known key opens.
+func (s *SecretService) KeyStatus(ctx context.Context) (total, legacy, unreadable int, err error) {
+	if !s.Available() {
+		return 0, 0, 0, ErrSecretsUnavailable
+	}
+	err = s.each(ctx, func(sec *secret.Secret) error {
+		total++
+		if _, isLegacy, err := s.decrypt(sec); err != nil {
+			unreadable++
+		} else if isLegacy {
+			legacy++
+		}
+		return nil
+	})
+	return total, legacy, unreadable, err
+}
+
+// Rekey re-encrypts every secret still encrypted with the shared key with
+// its tenant's key and returns how many it moved. It is safe to run again.
+func (s *SecretService) Rekey(ctx context.Context) (int, error) {
+	if !s.Available() {
+		return 0, ErrSecretsUnavailable
+	}
+	n := 0
+	err := s.each(ctx, func(sec *secret.Secret) error {
+		plain, isLegacy, err := s.decrypt(sec)
+		if err != nil {
+			return fmt.Errorf("decrypt secret %s: %w", sec.ID, err)
+		}
+		if !isLegacy {
+			return nil
+		}
+		if err := s.encrypt(sec, string(plain)); err != nil {
+			return err
+		}
+		if err := s.store.UpdateSecretValue(ctx, sec.ID, sec.Ciphertext); err != nil {
+			return err
+		}
+		n++
+		return nil
+	})
+	if n > 0 {
+		slog.Info("secrets moved to tenant keys", "count", n)
+	}
+	return n, err
+}
+
+// each calls fn for the tenant-wide secrets of every tenant and the
+// secrets of every project.
+func (s *SecretService) each(ctx context.Context, fn func(*secret.Secret) error) error {
+	tenants, err := s.store.ListTenants(ctx)
+	if err != nil {
+		return fmt.Errorf("list tenants: %w", err)
+	}
+	type scope struct {
+		ctx       context.Context
+		projectID string
+	}
+	scopes := make([]scope, 0, len(tenants))
+	for i := range tenants {
+		scopes = append(scopes, scope{tenant.NewContext(ctx, tenants[i].ID), ""})
+	}
+	projects, err := s.store.ListProjects(ctx)
+	if err != nil {
+		return fmt.Errorf("list projects: %w", err)
+	}
+	for i := range projects {
+		scopes = append(scopes, scope{ctx, projects[i].ID})
+	}
+	for _, sc := range scopes {
+		secs, err := s.store.ListSecrets(sc.ctx, sc.projectID)
+		if err != nil {
+			return fmt.Errorf("list secrets: %w", err)
+		}
+		for j := range secs {
+			if err := fn(&secs[j]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// scopeTenant returns the tenant owning a project's secrets, or for
+// tenant-wide secrets (empty projectID) the context's tenant.
+func (s *SecretService) scopeTenant(ctx context.Context, projectID string) (string, error) {
+	if projectID == "" {
+		if id := tenant.FromContext(ctx); id != "" {
+			return id, nil
+		}
+		return tenant.DefaultID, nil
+	}
+	p, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return "", fmt.Errorf("get project: %w", err)
+	}
+	return tenantOf(p), nil
+}
+
+// tenantVault returns the data key vault of a tenant.
+func (s *SecretService) tenantVault(id string) (*vault.Vault, error) {
+	if id == "" {
+		id = tenant.DefaultID
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.tenants[id]; ok {
+		return v, nil
+	}
+	v, err := s.vault.Derive("tenant/" + id)
+	if err != nil {
+		return nil, err
+	}
+	s.tenants[id] = v
+	return v, nil
+}
+
+// encrypt sets the ciphertext of sec to value sealed with its tenant's key.
+func (s *SecretService) encrypt(sec *secret.Secret, value string) error {
+	v, err := s.tenantVault(sec.TenantID)
+	if err != nil {
+		return err
+	}
+	ct, err := v.Encrypt([]byte(value), secretAAD(sec.ProjectID, sec.Name))
+	if err != nil {
+		return err
+	}
+	sec.Ciphertext = ct
+	return nil
+}
+
+// decrypt opens a secret with its tenant's key, or else with the shared
+// key, which legacy reports.
+func (s *SecretService) decrypt(sec *secret.Secret) (plain []byte, legacy bool, err error) {
+	v, err := s.tenantVault(sec.TenantID)
+	if err != nil {
+		return nil, false, err
+	}
+	aad := secretAAD(sec.ProjectID, sec.Name)
+	if plain, err = v.Decrypt(sec.Ciphertext, aad); err == nil {
+		return plain, false, nil
+	}
+	if plain, err = s.vault.Decrypt(sec.Ciphertext, aad); err == nil {
+		return plain, true, nil
+	}
+	return nil, false, err
+}
+
+// secretAAD binds a ciphertext to its scope and name.
+func secretAAD(projectID, name string) []byte {
+	return []byte(projectID + "/" + name)
+}
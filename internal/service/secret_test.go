@@ -0,0 +1,230 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/ws"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/secret"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/service"
+	"github.com/Strob0t/CodeForge/internal/vault"
+)
+
+func newTestSecretService(t *testing.T, store *runtimeMockStore) *service.SecretService {
+	t.Helper()
+	v, err := vault.New("0123456789abcdef0123456789abcdef", "secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service.NewSecretService(store, v)
+}
+
+func TestSecretService_ResolvePrefersProjectScope(t *testing.T) {
+	_, store, _, _ := newRuntimeTestEnv()
+	svc := newTestSecretService(t, store)
+	ctx := context.Background()
+
+	if _, err := svc.Create(ctx, "", &secret.CreateRequest{Name: "API_KEY", Value: "tenant-key"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Create(ctx, "", &secret.CreateRequest{Name: "DB_PASS", Value: "tenant-pass"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Create(ctx, "proj-1", &secret.CreateRequest{Name: "API_KEY", Value: "project-key"}); err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := svc.Resolve(ctx, "proj-1", []string{"API_KEY", "DB_PASS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env["API_KEY"] != "project-key" || env["DB_PASS"] != "tenant-pass" {
+		t.Fatalf("unexpected env %v", env)
+	}
+	if _, err := svc.Resolve(ctx, "proj-1", []string{"MISSING"}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for missing secret, got %v", err)
+	}
+	for i := range store.secrets {
+		if strings.Contains(string(store.secrets[i].Ciphertext), "key") {
+			t.Fatal("secret stored in plaintext")
+		}
+	}
+}
+
+func TestSecretService_UpdateRotatesValue(t *testing.T) {
+	_, store, _, _ := newRuntimeTestEnv()
+	svc := newTestSecretService(t, store)
+	ctx := context.Background()
+
+	sec, err := svc.Create(ctx, "proj-1", &secret.CreateRequest{Name: "TOKEN", Value: "old"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Update(ctx, sec.ID, &secret.UpdateRequest{Value: "new"}); err != nil {
+		t.Fatal(err)
+	}
+	env, err := svc.Resolve(ctx, "proj-1", []string{"TOKEN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env["TOKEN"] != "new" {
+		t.Fatalf("expected rotated value, got %q", env["TOKEN"])
+	}
+	if _, err := svc.Create(ctx, "proj-1", &secret.CreateRequest{Name: "TOKEN", Value: "dup"}); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("expected ErrConflict for duplicate name, got %v", err)
+	}
+}
+
+func TestSecretService_Unavailable(t *testing.T) {
+	_, store, _, _ := newRuntimeTestEnv()
+	svc := service.NewSecretService(store, nil)
+	_, err := svc.Create(context.Background(), "", &secret.CreateRequest{Name: "TOKEN", Value: "x"})
+	if !errors.Is(err, service.ErrSecretsUnavailable) {
+		t.Fatalf("expected ErrSecretsUnavailable, got %v", err)
+	}
+}
+
+func TestSecretService_TenantKeys(t *testing.T) {
+	_, store, _, _ := newRuntimeTestEnv()
+	store.tenants = []tenant.Tenant{{ID: tenant.DefaultID}, {ID: "acme"}}
+	store.projects = append(store.projects, project.Project{ID: "proj-acme", TenantID: "acme"})
+	svc := newTestSecretService(t, store)
+	ctx := context.Background()
+	acmeCtx := tenant.NewContext(ctx, "acme")
+
+	// Tenant-wide names are unique per tenant.
+	if _, err := svc.Create(ctx, "", &secret.CreateRequest{Name: "API_KEY", Value: "default-key"}); err != nil {
+		t.Fatal(err)
+	}
+	sec, err := svc.Create(acmeCtx, "", &secret.CreateRequest{Name: "API_KEY", Value: "acme-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sec.TenantID != "acme" {
+		t.Fatalf("expected the secret to belong to acme, got %q", sec.TenantID)
+	}
+	env, err := svc.Resolve(ctx, "proj-acme", []string{"API_KEY"})
+	if err != nil || env["API_KEY"] != "acme-key" {
+		t.Fatalf("expected the project tenant's secret, got %v %v", env, err)
+	}
+
+	// The value is sealed with acme's data key, not the shared key.
+	acmeKey, _ := vault.New("0123456789abcdef0123456789abcdef", "secrets/tenant/acme")
+	if plain, err := acmeKey.Decrypt(sec.Ciphertext, []byte("/API_KEY")); err != nil || string(plain) != "acme-key" {
+		t.Fatalf("expected acme's key to open the secret, got %q %v", plain, err)
+	}
+
+	// A secret written with the shared key before tenant keys stays
+	// readable until it is rekeyed.
+	shared, _ := vault.New("0123456789abcdef0123456789abcdef", "secrets")
+	ct, _ := shared.Encrypt([]byte("legacy"), []byte("proj-acme/OLD_TOKEN"))
+	if err := store.CreateSecret(ctx, &secret.Secret{TenantID: "acme", ProjectID: "proj-acme", Name: "OLD_TOKEN", Ciphertext: ct}); err != nil {
+		t.Fatal(err)
+	}
+	if env, err := svc.Resolve(ctx, "proj-acme", []string{"OLD_TOKEN"}); err != nil || env["OLD_TOKEN"] != "legacy" {
+		t.Fatalf("expected the legacy secret to resolve, got %v %v", env, err)
+	}
+
+	tenants := service.NewTenantService(store)
+	tenants.SetSecretService(svc)
+	report, err := tenants.VerifyIsolation(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OK || report.Secrets != 3 || report.LegacySecrets != 1 {
+		t.Fatalf("expected one legacy secret to be reported, got %+v", report)
+	}
+
+	if n, err := svc.Rekey(ctx); err != nil || n != 1 {
+		t.Fatalf("Rekey = %d, %v", n, err)
+	}
+	if n, _ := svc.Rekey(ctx); n != 0 {
+		t.Fatalf("a second rekey must be a no-op, moved %d", n)
+	}
+	if env, err := svc.Resolve(ctx, "proj-acme", []string{"OLD_TOKEN"}); err != nil || env["OLD_TOKEN"] != "legacy" {
+		t.Fatalf("expected the rekeyed secret to resolve, got %v %v", env, err)
+	}
+	if report, _ = tenants.VerifyIsolation(ctx); !report.OK {
+		t.Fatalf("expected isolation to verify, got %v", report.Problems)
+	}
+}
+
+func TestStartRun_InjectsAndGuardsSecrets(t *testing.T) {
+	rt, store, queue, bc := newRuntimeTestEnv()
+	secrets := newTestSecretService(t, store)
+	rt.SetSecretService(secrets)
+	ctx := context.Background()
+
+	if _, err := secrets.Create(ctx, "proj-1", &secret.CreateRequest{Name: "API_KEY", Value: "sk-live-123"}); err != nil {
+		t.Fatal(err)
+	}
+	store.agents[0].Config = map[string]string{secret.ConfigKey: "API_KEY"}
+
+	r, err := rt.StartRun(ctx, &run.StartRequest{TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1"})
+	if err != nil {
+		t.Fatalf("StartRun failed: %v", err)
+	}
+	msg, _ := queue.lastMessage(messagequeue.SubjectRunStart)
+	var payload messagequeue.RunStartPayload
+	_ = json.Unmarshal(msg.Data, &payload)
+	if payload.Env["API_KEY"] != "sk-live-123" {
+		t.Fatalf("expected secret in run env, got %v", payload.Env)
+	}
+
+	// Echoing the secret is denied regardless of policy
+	if err := rt.HandleToolCallRequest(ctx, &messagequeue.ToolCallRequestPayload{
+		RunID: r.ID, CallID: "call-1", Tool: "Bash", Command: "echo $API_KEY",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	msg, _ = queue.lastMessage(messagequeue.SubjectRunToolCallResponse)
+	var resp messagequeue.ToolCallResponsePayload
+	_ = json.Unmarshal(msg.Data, &resp)
+	if resp.Decision != "deny" || !strings.Contains(resp.Reason, "API_KEY") {
+		t.Fatalf("expected deny referencing API_KEY, got %+v", resp)
+	}
+
+	// Streamed output is redacted
+	_ = rt.HandleRunOutput(ctx, &messagequeue.RunOutputPayload{RunID: r.ID, TaskID: "task-1", Line: "token=sk-live-123", Stream: "stdout"})
+	last := bc.events[len(bc.events)-1]
+	out, ok := last.Data.(ws.TaskOutputEvent)
+	if !ok || out.Line != "token=[secret:API_KEY]" {
+		t.Fatalf("expected redacted output, got %+v", last)
+	}
+
+	// The final error is redacted before it is stored
+	if err := rt.HandleRunComplete(ctx, &messagequeue.RunCompletePayload{
+		RunID: r.ID, TaskID: "task-1", ProjectID: "proj-1", Status: string(run.StatusFailed), Error: "401 for sk-live-123",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := store.GetRun(ctx, r.ID)
+	if got.Error != "401 for [secret:API_KEY]" {
+		t.Fatalf("expected redacted run error, got %q", got.Error)
+	}
+	if got.Redactions != 2 {
+		t.Fatalf("expected 2 redactions (output line and error), got %d", got.Redactions)
+	}
+}
+
+func TestStartRun_MissingSecretFails(t *testing.T) {
+	rt, store, _, _ := newRuntimeTestEnv()
+	rt.SetSecretService(newTestSecretService(t, store))
+	store.agents[0].Config = map[string]string{secret.ConfigKey: "NOPE"}
+
+	_, err := rt.StartRun(context.Background(), &run.StartRequest{TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1"})
+	if !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if len(store.runs) != 0 {
+		t.Fatal("expected no run to be created")
+	}
+}
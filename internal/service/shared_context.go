@@ -15,9 +15,10 @@ import (
 
 // SharedContextService manages team-level shared context for collaboration.
 type SharedContextService struct {
-	store database.Store
-	hub   broadcast.Broadcaster
-	queue messagequeue.Queue
+	store     database.Store
+	hub       broadcast.Broadcaster
+	queue     messagequeue.Queue
+	tokenizer *TokenizerService
 }
 
 // NewSharedContextService creates a SharedContextService with all dependencies.
@@ -29,6 +30,12 @@ func NewSharedContextService(
 	return &SharedContextService{store: store, hub: hub, queue: queue}
 }
 
+// SetTokenizer counts item tokens with the tokenizer of the default model.
+// Context packs recount them for the model of their run.
+func (s *SharedContextService) SetTokenizer(t *TokenizerService) {
+	s.tokenizer = t
+}
+
 // InitForTeam creates a new empty shared context for a team.
 func (s *SharedContextService) InitForTeam(ctx context.Context, teamID, projectID string) (*cfcontext.SharedContext, error) {
 	sc := &cfcontext.SharedContext{
@@ -50,6 +57,9 @@ func (s *SharedContextService) AddItem(ctx context.Context, req cfcontext.AddSha
 	if err := req.Validate(); err != nil {
 		return nil, fmt.Errorf("validate: %w", err)
 	}
+	if s.tokenizer != nil {
+		req.Tokens = s.tokenizer.Count("", req.Value)
+	}
 
 	item, err := s.store.AddSharedContextItem(ctx, req)
 	if err != nil {
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/skill"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/skillregistry"
+)
+
+var (
+	// ErrSkillSigningUnavailable is returned for exports without a signing key.
+	ErrSkillSigningUnavailable = errors.New("skills: no signing key configured")
+	// ErrSkillRegistryUnavailable is returned when no registry is configured.
+	ErrSkillRegistryUnavailable = errors.New("skills: no registry configured")
+	// ErrBundleTooLarge is returned for bundles above skills.max_bundle_bytes.
+	ErrBundleTooLarge = errors.New("skills: bundle too large")
+	// ErrBundleChecksum is returned when a registry bundle does not match
+	// the digest listed in the index.
+	ErrBundleChecksum = errors.New("skills: bundle does not match its registry checksum")
+)
+
+// SkillService manages project skills and moves them between projects and
+// installs as signed bundles, optionally through a shared registry.
+type SkillService struct {
+	store    database.Store
+	registry skillregistry.Registry
+	cfg      *config.Skills
+	key      ed25519.PrivateKey
+	trusted  map[string]ed25519.PublicKey
+}
+
+// NewSkillService creates a SkillService. The configured signing key's
+// own public key is trusted in addition to the trusted keys. registry may
+// be nil, which disables registry installs.
+func NewSkillService(store database.Store, registry skillregistry.Registry, cfg *config.Skills) (*SkillService, error) {
+	s := &SkillService{store: store, registry: registry, cfg: cfg, trusted: make(map[string]ed25519.PublicKey)}
+	for id, k := range cfg.TrustedKeys {
+		pub, err := skill.ParsePublicKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("trusted key %s: %w", id, err)
+		}
+		s.trusted[id] = pub
+	}
+	if cfg.SigningKey != "" {
+		key, err := skill.ParsePrivateKey(cfg.SigningKey)
+		if err != nil {
+			return nil, err
+		}
+		s.key = key
+		s.trusted[cfg.KeyID] = key.Public().(ed25519.PublicKey)
+	}
+	return s, nil
+}
+
+// MaxBundleBytes returns the size limit of imported bundles.
+func (s *SkillService) MaxBundleBytes() int {
+	return s.cfg.MaxBundleBytes
+}
+
+// Create stores a new skill of a project.
+func (s *SkillService) Create(ctx context.Context, projectID string, req *skill.CreateRequest) (*skill.Skill, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	sk := skill.New(projectID, req)
+	if err := s.store.CreateSkill(ctx, sk); err != nil {
+		return nil, err
+	}
+	return sk, nil
+}
+
+// Get returns a skill by ID.
+func (s *SkillService) Get(ctx context.Context, id string) (*skill.Skill, error) {
+	return s.store.GetSkill(ctx, id)
+}
+
+// List returns the skills of a project.
+func (s *SkillService) List(ctx context.Context, projectID string) ([]skill.Skill, error) {
+	return s.store.ListSkills(ctx, projectID)
+}
+
+// Delete removes a skill.
+func (s *SkillService) Delete(ctx context.Context, id string) error {
+	return s.store.DeleteSkill(ctx, id)
+}
+
+// Export returns a skill as a bundle signed with the configured key.
+func (s *SkillService) Export(ctx context.Context, id string) ([]byte, *skill.Skill, error) {
+	if s.key == nil {
+		return nil, nil, ErrSkillSigningUnavailable
+	}
+	sk, err := s.store.GetSkill(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := skill.Pack(skill.ManifestOf(sk, time.Now()), s.cfg.KeyID, s.key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("pack skill %s: %w", sk.Name, err)
+	}
+	return data, sk, nil
+}
+
+// Import verifies a bundle and creates its skill in a project. A project
+// that already has a skill of the same name fails with domain.ErrConflict.
+func (s *SkillService) Import(ctx context.Context, projectID string, data []byte) (*skill.Skill, error) {
+	return s.importBundle(ctx, projectID, data, nil)
+}
+
+// importBundle imports a bundle; if listed is set, the bundle must hold
+// the skill and version the registry lists.
+func (s *SkillService) importBundle(ctx context.Context, projectID string, data []byte, listed *skill.IndexEntry) (*skill.Skill, error) {
+	if len(data) > s.cfg.MaxBundleBytes {
+		return nil, ErrBundleTooLarge
+	}
+	m, err := skill.Unpack(data, s.trusted)
+	if err != nil {
+		return nil, err
+	}
+	if listed != nil && (m.Name != listed.Name || m.Version != listed.Version) {
+		return nil, fmt.Errorf("%w: registry lists %s %s, bundle holds %s %s",
+			skill.ErrInvalidBundle, listed.Name, listed.Version, m.Name, m.Version)
+	}
+	sk := skill.New(projectID, m.Request())
+	sk.Signer = m.Signer
+	if err := s.store.CreateSkill(ctx, sk); err != nil {
+		return nil, err
+	}
+	slog.Info("skill imported", "project_id", projectID, "skill", sk.Name, "version", sk.Version, "signer", sk.Signer)
+	return sk, nil
+}
+
+// Registry lists the bundles of the configured registry.
+func (s *SkillService) Registry(ctx context.Context) ([]skill.IndexEntry, error) {
+	if s.registry == nil {
+		return nil, ErrSkillRegistryUnavailable
+	}
+	ix, err := s.registry.Index(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetch skill registry: %w", err)
+	}
+	return ix.Skills, nil
+}
+
+// Install downloads a registry bundle and imports it into a project. The
+// bundle must match the index checksum, if listed, and be signed by a
+// trusted key like any other import.
+func (s *SkillService) Install(ctx context.Context, projectID string, req *skill.InstallRequest) (*skill.Skill, error) {
+	if s.registry == nil {
+		return nil, ErrSkillRegistryUnavailable
+	}
+	ix, err := s.registry.Index(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetch skill registry: %w", err)
+	}
+	e, ok := ix.Find(req.Name, req.Version)
+	if !ok {
+		return nil, fmt.Errorf("skill %s %s in registry: %w", req.Name, req.Version, domain.ErrNotFound)
+	}
+
+	data, err := s.registry.Download(ctx, e.URL, int64(s.cfg.MaxBundleBytes))
+	if err != nil {
+		return nil, fmt.Errorf("download skill %s: %w", e.Name, err)
+	}
+	if e.SHA256 != "" {
+		sum := sha256.Sum256(data)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), e.SHA256) {
+			return nil, ErrBundleChecksum
+		}
+	}
+	return s.importBundle(ctx, projectID, data, e)
+}
@@ -0,0 +1,127 @@
+package service_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/skillindex"
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/skill"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func newSkillKey(t *testing.T) (seed, pub string) {
+	t.Helper()
+	pk, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(key.Seed()), base64.StdEncoding.EncodeToString(pk)
+}
+
+func TestSkillService_ExportImport(t *testing.T) {
+	ctx := context.Background()
+	seed, pub := newSkillKey(t)
+	source := &runtimeMockStore{}
+	exporter, err := service.NewSkillService(source, nil, &config.Skills{SigningKey: seed, KeyID: "team-a", MaxBundleBytes: 1 << 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sk, err := exporter.Create(ctx, "proj-1", &skill.CreateRequest{
+		Name: "go-tests", Prompt: "write table-driven tests", Tools: []string{"Read"},
+		Fixtures: []skill.Fixture{{Name: "add", Input: "func Add", Expect: "TestAdd"}},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	bundle, _, err := exporter.Export(ctx, sk.ID)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	// Another install trusts team-a's public key but cannot sign itself.
+	target := &runtimeMockStore{}
+	importer, err := service.NewSkillService(target, nil, &config.Skills{
+		KeyID: "local", TrustedKeys: map[string]string{"team-a": pub}, MaxBundleBytes: 1 << 20,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := importer.Import(ctx, "proj-2", bundle)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if imported.ProjectID != "proj-2" || imported.Signer != "team-a" || imported.Prompt != sk.Prompt || len(imported.Fixtures) != 1 {
+		t.Fatalf("unexpected imported skill %+v", imported)
+	}
+	if _, err := importer.Import(ctx, "proj-2", bundle); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("expected ErrConflict for a duplicate name, got %v", err)
+	}
+	if _, _, err := importer.Export(ctx, imported.ID); !errors.Is(err, service.ErrSkillSigningUnavailable) {
+		t.Fatalf("expected ErrSkillSigningUnavailable, got %v", err)
+	}
+
+	untrusting, _ := service.NewSkillService(&runtimeMockStore{}, nil, &config.Skills{KeyID: "local", MaxBundleBytes: 1 << 20})
+	if _, err := untrusting.Import(ctx, "proj-3", bundle); !errors.Is(err, skill.ErrUntrustedSigner) {
+		t.Fatalf("expected ErrUntrustedSigner, got %v", err)
+	}
+}
+
+func TestSkillService_InstallFromRegistry(t *testing.T) {
+	ctx := context.Background()
+	seed, _ := newSkillKey(t)
+	cfg := &config.Skills{SigningKey: seed, KeyID: "team-a", MaxBundleBytes: 1 << 20}
+	source, _ := service.NewSkillService(&runtimeMockStore{}, nil, cfg)
+	sk, _ := source.Create(ctx, "proj-1", &skill.CreateRequest{Name: "go-tests", Version: "1.2.0", Prompt: "write tests"})
+	bundle, _, err := source.Export(ctx, sk.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(bundle)
+
+	index := skill.Index{Skills: []skill.IndexEntry{
+		{Name: "go-tests", Version: "1.2.0", URL: "go-tests.tar.gz", SHA256: hex.EncodeToString(sum[:])},
+		{Name: "go-tests", Version: "1.1.0", URL: "go-tests.tar.gz", SHA256: "00"},
+	}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.json":
+			_ = json.NewEncoder(w).Encode(index)
+		case "/go-tests.tar.gz":
+			_, _ = w.Write(bundle)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	store := &runtimeMockStore{}
+	svc, _ := service.NewSkillService(store, skillindex.NewRegistry(srv.URL+"/index.json"), cfg)
+	entries, err := svc.Registry(ctx)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Registry = %+v, %v", entries, err)
+	}
+
+	installed, err := svc.Install(ctx, "proj-2", &skill.InstallRequest{Name: "go-tests"})
+	if err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	if installed.Version != "1.2.0" || installed.Signer != "team-a" || len(store.skills) != 1 {
+		t.Fatalf("unexpected installed skill %+v", installed)
+	}
+	if _, err := svc.Install(ctx, "proj-3", &skill.InstallRequest{Name: "go-tests", Version: "1.1.0"}); !errors.Is(err, service.ErrBundleChecksum) {
+		t.Fatalf("expected ErrBundleChecksum, got %v", err)
+	}
+	if _, err := svc.Install(ctx, "proj-3", &skill.InstallRequest{Name: "missing"}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/slack"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/port/notifier"
+)
+
+// HandleSlackInteraction records a click on the Approve or Reject button
+// of an approval request posted by an interactive Slack rule as a vote of
+// slack:<user ID>. Once the vote decides the step, the message is replaced
+// with the outcome and who decided it; votes short of the quorum and
+// refused votes are answered only to the voter. It returns
+// notifier.ErrInvalidSignature for requests that do not verify with
+// notify.slack_signing_secret.
+func (s *NotificationService) HandleSlackInteraction(ctx context.Context, header http.Header, body []byte) error {
+	if s.approvals == nil {
+		return notifier.ErrInvalidSignature
+	}
+	in, err := slack.ParseInteraction(s.cfg.SlackSigningSecret, header, body, s.now())
+	if errors.Is(err, notifier.ErrIgnoredInteraction) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	req := &plan.ResolveApprovalRequest{}
+	if err := req.Bind(plan.Voter{ID: policy.ApproverSlackPrefix + in.UserID, Name: in.UserName}); err != nil {
+		return err
+	}
+	step, err := s.approvals.ResolveApproval(ctx, in.PlanID, in.StepID, in.Approve, req)
+	text, replace := slackOutcome(in, step, err)
+
+	// Slack wants the interaction acknowledged within 3 seconds; the answer
+	// goes to the response URL after that.
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.cfg.SendTimeout)
+		defer cancel()
+		if err := slack.Respond(ctx, in.ResponseURL, text, replace); err != nil {
+			slog.Warn("answer slack interaction", "plan_id", in.PlanID, "step_id", in.StepID, "error", err)
+		}
+	}()
+	if err != nil && !refusedVote(err) {
+		return err
+	}
+	slog.Info("slack approval vote", "plan_id", in.PlanID, "step_id", in.StepID, "by", req.By, "approved", in.Approve, "error", err)
+	return nil
+}
+
+// slackOutcome returns the answer to a vote and whether it replaces the
+// message voted on.
+func slackOutcome(in *slack.Interaction, step *plan.Step, err error) (string, bool) {
+	switch {
+	case errors.Is(err, plan.ErrNotApprover):
+		return "You are not an approver of this step.", false
+	case errors.Is(err, plan.ErrAlreadyVoted):
+		return "You already voted on this step.", false
+	case errors.Is(err, ErrStepNotAwaitingApproval):
+		return "This step is no longer waiting for approval.", false
+	case errors.Is(err, domain.ErrNotFound):
+		return "This approval step no longer exists.", false
+	case err != nil:
+		return "Your vote could not be recorded, please try again.", false
+	case step.Status == plan.StepStatusWaitingApproval:
+		return fmt.Sprintf("Your approval was recorded (%d of %d).", step.Approval.Approvals(), step.Approval.Policy.Required()), false
+	}
+	outcome := "Approved by "
+	if step.Status != plan.StepStatusCompleted {
+		outcome = "Rejected by "
+	}
+	text := outcome + "<@" + in.UserID + ">"
+	if in.Text != "" {
+		text = in.Text + "\n" + text
+	}
+	return text, true
+}
+
+// refusedVote reports whether err refuses a vote for a reason the voter
+// is told about, rather than failing the request.
+func refusedVote(err error) bool {
+	return errors.Is(err, plan.ErrNotApprover) || errors.Is(err, plan.ErrAlreadyVoted) ||
+		errors.Is(err, ErrStepNotAwaitingApproval) || errors.Is(err, domain.ErrNotFound)
+}
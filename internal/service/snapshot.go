@@ -0,0 +1,456 @@
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/snapshot"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+)
+
+// ErrWorkspaceBusy is returned when restoring into or editing the workspace of an active run.
+var ErrWorkspaceBusy = errors.New("workspace is in use by an active run")
+
+// errSnapshotTooLarge aborts archiving once the configured size limit is exceeded.
+var errSnapshotTooLarge = errors.New("snapshot exceeds size limit")
+
+// SnapshotService captures full workspace tarballs into the artifact store
+// and restores them, covering untracked files, generated artifacts and
+// dependency caches that git history does not.
+type SnapshotService struct {
+	store database.Store
+	cfg   *config.Runtime
+}
+
+// NewSnapshotService creates a SnapshotService.
+func NewSnapshotService(store database.Store, cfg *config.Runtime) *SnapshotService {
+	return &SnapshotService{store: store, cfg: cfg}
+}
+
+// Create archives the workspace of the run's project and stores it as a
+// workspace snapshot artifact of the run.
+func (s *SnapshotService) Create(ctx context.Context, runID string, req *snapshot.CreateRequest) (*snapshot.Snapshot, error) {
+	r, err := s.store.GetRun(ctx, runID)
+	if err != nil {
+		return nil, fmt.Errorf("get run: %w", err)
+	}
+	return s.create(ctx, r, req, "")
+}
+
+// Checkpoint snapshots the run's workspace after its tool call callID, so
+// replay sessions can inspect the workspace at that step.
+func (s *SnapshotService) Checkpoint(ctx context.Context, r *run.Run, callID string) (*snapshot.Snapshot, error) {
+	return s.create(ctx, r, &snapshot.CreateRequest{Name: "checkpoint-" + callID}, callID)
+}
+
+func (s *SnapshotService) create(ctx context.Context, r *run.Run, req *snapshot.CreateRequest, callID string) (*snapshot.Snapshot, error) {
+	dir, err := s.workspace(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns := append(append([]string{}, s.cfg.SnapshotIgnore...), req.Ignore...)
+	data, files, err := archiveWorkspace(dir, patterns, int64(s.cfg.SnapshotMaxMB)<<20)
+	if err != nil {
+		return nil, fmt.Errorf("archive workspace: %w", err)
+	}
+
+	name := req.Name
+	if name == "" {
+		name = "snapshot-" + time.Now().UTC().Format("20060102-150405")
+	}
+	art := &artifact.Artifact{
+		RunID:       r.ID,
+		ProjectID:   r.ProjectID,
+		Kind:        artifact.KindWorkspaceSnapshot,
+		Name:        name,
+		ContentType: snapshot.ContentType,
+		Data:        data,
+		Metadata: map[string]string{
+			snapshot.MetaFiles:  strconv.Itoa(files),
+			snapshot.MetaIgnore: strings.Join(patterns, ","),
+		},
+	}
+	if callID != "" {
+		art.Metadata[snapshot.MetaCallID] = callID
+	}
+	if err := s.store.CreateArtifact(ctx, art); err != nil {
+		return nil, fmt.Errorf("store snapshot: %w", err)
+	}
+
+	slog.Info("workspace snapshot created", "run_id", r.ID, "snapshot_id", art.ID, "files", files, "bytes", len(data))
+	snap := snapshot.FromArtifact(art)
+	return &snap, nil
+}
+
+// ListByRun returns the snapshots taken for a run.
+func (s *SnapshotService) ListByRun(ctx context.Context, runID string) ([]snapshot.Snapshot, error) {
+	arts, err := s.store.ListArtifactsByRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	return toSnapshots(arts), nil
+}
+
+// ListByProject returns all snapshots of a project, across runs.
+func (s *SnapshotService) ListByProject(ctx context.Context, projectID string) ([]snapshot.Snapshot, error) {
+	arts, err := s.store.ListArtifactsByProject(ctx, projectID, artifact.KindWorkspaceSnapshot)
+	if err != nil {
+		return nil, err
+	}
+	return toSnapshots(arts), nil
+}
+
+// Restore replaces the run's workspace (its worktree, or the shared project
+// workspace) with the contents of a snapshot from the same project. Paths matching the snapshot's ignore
+// patterns were never captured and are left in place.
+func (s *SnapshotService) Restore(ctx context.Context, runID string, req *snapshot.RestoreRequest) (*snapshot.Snapshot, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validate restore request: %w", err)
+	}
+	r, err := s.store.GetRun(ctx, runID)
+	if err != nil {
+		return nil, fmt.Errorf("get run: %w", err)
+	}
+	if r.Status == run.StatusPending || r.Status == run.StatusRunning || r.Status == run.StatusQualityGate {
+		return nil, ErrWorkspaceBusy
+	}
+
+	dir, err := s.workspace(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := s.restoreInto(ctx, dir, r.ProjectID, req.SnapshotID, nil)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("workspace snapshot restored", "run_id", r.ID, "snapshot_id", snap.ID, "source_run_id", snap.RunID)
+	return snap, nil
+}
+
+// restoreInto replaces the contents of dir with a snapshot of the project.
+// Paths matching keep are left as they are in dir.
+func (s *SnapshotService) restoreInto(ctx context.Context, dir, projectID, snapshotID string, keep []string) (*snapshot.Snapshot, error) {
+	art, err := s.get(ctx, projectID, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	snap := snapshot.FromArtifact(art)
+	if err := clearWorkspace(dir, "", append(append([]string{}, snap.Ignore...), keep...)); err != nil {
+		return nil, fmt.Errorf("clear workspace: %w", err)
+	}
+	if err := extractWorkspace(dir, art.Data, keep); err != nil {
+		return nil, fmt.Errorf("extract snapshot: %w", err)
+	}
+	return &snap, nil
+}
+
+// Files lists the regular files of a snapshot of the project by path.
+func (s *SnapshotService) Files(ctx context.Context, projectID, snapshotID string) ([]snapshot.File, error) {
+	art, err := s.get(ctx, projectID, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	files := []snapshot.File{}
+	err = walkArchive(art.Data, func(hdr *tar.Header, _ io.Reader) error {
+		files = append(files, snapshot.File{Path: hdr.Name, Size: hdr.Size})
+		return nil
+	})
+	return files, err
+}
+
+// ReadFile returns the content of a regular file of a snapshot of the
+// project.
+func (s *SnapshotService) ReadFile(ctx context.Context, projectID, snapshotID, name string) ([]byte, error) {
+	art, err := s.get(ctx, projectID, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	var content []byte
+	err = walkArchive(art.Data, func(hdr *tar.Header, r io.Reader) error {
+		if hdr.Name != name {
+			return nil
+		}
+		var err error
+		content, err = io.ReadAll(r)
+		if err == nil {
+			err = errStopWalk
+		}
+		return err
+	})
+	if errors.Is(err, errStopWalk) {
+		return content, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("file %s in snapshot %s: %w", name, snapshotID, domain.ErrNotFound)
+}
+
+// get returns a snapshot artifact of the project.
+func (s *SnapshotService) get(ctx context.Context, projectID, snapshotID string) (*artifact.Artifact, error) {
+	art, err := s.store.GetArtifact(ctx, snapshotID)
+	if err != nil {
+		return nil, fmt.Errorf("get snapshot: %w", err)
+	}
+	if art.Kind != artifact.KindWorkspaceSnapshot {
+		return nil, fmt.Errorf("snapshot %s: %w", snapshotID, domain.ErrNotFound)
+	}
+	if art.ProjectID != projectID {
+		return nil, fmt.Errorf("snapshot %s belongs to another project", snapshotID)
+	}
+	return art, nil
+}
+
+func (s *SnapshotService) workspace(ctx context.Context, r *run.Run) (string, error) {
+	proj, err := s.store.GetProject(ctx, r.ProjectID)
+	if err != nil {
+		return "", fmt.Errorf("get project: %w", err)
+	}
+	dir := r.Workspace(proj.WorkspacePath)
+	if dir == "" {
+		return "", fmt.Errorf("project %s has no workspace_path", r.ProjectID)
+	}
+	return dir, nil
+}
+
+func toSnapshots(arts []artifact.Artifact) []snapshot.Snapshot {
+	out := make([]snapshot.Snapshot, 0, len(arts))
+	for i := range arts {
+		if arts[i].Kind == artifact.KindWorkspaceSnapshot {
+			out = append(out, snapshot.FromArtifact(&arts[i]))
+		}
+	}
+	return out
+}
+
+// limitWriter fails once more than max bytes have been written.
+type limitWriter struct {
+	w   io.Writer
+	n   int64
+	max int64
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	l.n += int64(len(p))
+	if l.max > 0 && l.n > l.max {
+		return 0, errSnapshotTooLarge
+	}
+	return l.w.Write(p)
+}
+
+// archiveWorkspace writes a gzipped tarball of dir, skipping ignored paths.
+// It returns the archive and the number of regular files it contains.
+func archiveWorkspace(dir string, ignore []string, maxBytes int64) ([]byte, int, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&limitWriter{w: &buf, max: maxBytes})
+	tw := tar.NewWriter(gz)
+	files := 0
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if snapshot.Ignored(ignore, rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		if d.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if _, err := io.Copy(tw, f); err != nil {
+			return err
+		}
+		files++
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), files, nil
+}
+
+// errStopWalk ends walkArchive early without an error.
+var errStopWalk = errors.New("stop walk")
+
+// walkArchive calls fn for the regular files of a snapshot archive in
+// order, with their content.
+func walkArchive(data []byte, fn func(*tar.Header, io.Reader) error) error {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := fn(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// clearWorkspace removes everything below dir except ignored paths.
+// Directories that still contain ignored paths are kept.
+func clearWorkspace(dir, rel string, ignore []string) error {
+	entries, err := os.ReadDir(filepath.Join(dir, filepath.FromSlash(rel)))
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		child := path.Join(rel, e.Name())
+		if snapshot.Ignored(ignore, child) {
+			continue
+		}
+		full := filepath.Join(dir, filepath.FromSlash(child))
+		if e.IsDir() {
+			if err := clearWorkspace(dir, child, ignore); err != nil {
+				return err
+			}
+			if rest, err := os.ReadDir(full); err == nil && len(rest) > 0 {
+				continue
+			}
+		}
+		if err := os.RemoveAll(full); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractWorkspace unpacks a snapshot archive into dir, skipping entries
+// matching skip. Entries must stay inside dir and are never written
+// through symlinks from the archive.
+func extractWorkspace(dir string, data []byte, skip []string) error {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	links := make(map[string]bool)
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rel := strings.TrimSuffix(hdr.Name, "/")
+		if !filepath.IsLocal(filepath.FromSlash(rel)) {
+			return fmt.Errorf("unsafe path %q in snapshot", hdr.Name)
+		}
+		if snapshot.Ignored(skip, rel) {
+			continue
+		}
+		for p := path.Dir(rel); p != "."; p = path.Dir(p) {
+			if links[p] {
+				return fmt.Errorf("path %q in snapshot crosses a symlink", hdr.Name)
+			}
+		}
+		target := filepath.Join(dir, filepath.FromSlash(rel))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, hdr.FileInfo().Mode().Perm()|0o700); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+			links[rel] = true
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := writeSnapshotFile(target, tr, hdr.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeSnapshotFile(target string, r io.Reader, perm fs.FileMode) error {
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,123 @@
+package service_test
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/snapshot"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func newSnapshotTestEnv(t *testing.T) (*service.SnapshotService, *runtimeMockStore, string) {
+	t.Helper()
+	dir := t.TempDir()
+	store := &runtimeMockStore{
+		projects: []project.Project{{ID: "proj-1", Name: "test", WorkspacePath: dir}},
+		runs: []run.Run{
+			{ID: "run-1", ProjectID: "proj-1", Status: run.StatusCompleted},
+			{ID: "run-2", ProjectID: "proj-1", Status: run.StatusFailed},
+			{ID: "run-active", ProjectID: "proj-1", Status: run.StatusRunning},
+		},
+	}
+	cfg := &config.Runtime{SnapshotIgnore: []string{"node_modules"}, SnapshotMaxMB: 16}
+	return service.NewSnapshotService(store, cfg), store, dir
+}
+
+func writeTestFile(t *testing.T, dir, rel, content string) {
+	t.Helper()
+	p := filepath.Join(dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSnapshotCreateAndRestore(t *testing.T) {
+	svc, store, dir := newSnapshotTestEnv(t)
+	ctx := context.Background()
+
+	writeTestFile(t, dir, "main.go", "package main")
+	writeTestFile(t, dir, "gen/out.txt", "generated")
+	writeTestFile(t, dir, "web/node_modules/dep/index.js", "cached")
+	writeTestFile(t, dir, "build.log", "noise")
+
+	snap, err := svc.Create(ctx, "run-1", &snapshot.CreateRequest{Name: "before", Ignore: []string{"*.log"}})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if snap.Files != 2 || snap.Name != "before" || len(snap.Ignore) != 2 {
+		t.Fatalf("unexpected snapshot %+v", snap)
+	}
+
+	// Diverge the workspace.
+	writeTestFile(t, dir, "main.go", "package broken")
+	writeTestFile(t, dir, "extra/new.go", "package extra")
+	if err := os.RemoveAll(filepath.Join(dir, "gen")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restore into another run of the same project (fork).
+	if _, err := svc.Restore(ctx, "run-2", &snapshot.RestoreRequest{SnapshotID: snap.ID}); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	if b, _ := os.ReadFile(filepath.Join(dir, "main.go")); string(b) != "package main" {
+		t.Errorf("expected main.go restored, got %q", b)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "gen", "out.txt")); string(b) != "generated" {
+		t.Errorf("expected untracked file restored, got %q", b)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "extra")); !os.IsNotExist(err) {
+		t.Errorf("expected extra/ removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "web", "node_modules", "dep", "index.js")); err != nil {
+		t.Errorf("expected ignored path kept: %v", err)
+	}
+
+	snaps, err := svc.ListByProject(ctx, "proj-1")
+	if err != nil || len(snaps) != 1 {
+		t.Fatalf("expected 1 project snapshot, got %d (%v)", len(snaps), err)
+	}
+	if len(store.artifacts) != 1 {
+		t.Fatalf("expected 1 artifact, got %d", len(store.artifacts))
+	}
+}
+
+func TestSnapshotRestoreActiveRun(t *testing.T) {
+	svc, _, dir := newSnapshotTestEnv(t)
+	ctx := context.Background()
+	writeTestFile(t, dir, "main.go", "package main")
+
+	snap, err := svc.Create(ctx, "run-1", &snapshot.CreateRequest{})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	_, err = svc.Restore(ctx, "run-active", &snapshot.RestoreRequest{SnapshotID: snap.ID})
+	if !errors.Is(err, service.ErrWorkspaceBusy) {
+		t.Fatalf("expected ErrWorkspaceBusy, got %v", err)
+	}
+}
+
+func TestSnapshotSizeLimit(t *testing.T) {
+	svc, store, dir := newSnapshotTestEnv(t)
+	big := make([]byte, 2<<20)
+	_, _ = rand.Read(big) // incompressible
+	writeTestFile(t, dir, "blob.bin", string(big))
+
+	limited := service.NewSnapshotService(store, &config.Runtime{SnapshotMaxMB: 1})
+	if _, err := limited.Create(context.Background(), "run-1", &snapshot.CreateRequest{}); err == nil {
+		t.Fatal("expected size limit error")
+	}
+	if _, err := svc.Create(context.Background(), "run-1", &snapshot.CreateRequest{}); err != nil {
+		t.Fatalf("expected snapshot within limit, got %v", err)
+	}
+}
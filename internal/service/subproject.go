@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/Strob0t/CodeForge/internal/domain/agent"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+)
+
+// ErrForeignSubProject is returned when a task or agent targets a
+// sub-project of another project.
+var ErrForeignSubProject = errors.New("sub-project belongs to another project")
+
+// CreateSubProject defines a sub-project of a monorepo project.
+func (s *ProjectService) CreateSubProject(ctx context.Context, projectID string, req *project.CreateSubProjectRequest) (*project.SubProject, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validate sub-project: %w", err)
+	}
+	if _, err := s.store.GetProject(ctx, projectID); err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+	sp := &project.SubProject{
+		ProjectID:    projectID,
+		Name:         req.Name,
+		Path:         req.Path,
+		Language:     req.Language,
+		BuildCommand: req.BuildCommand,
+		TestCommand:  req.TestCommand,
+	}
+	if err := s.store.CreateSubProject(ctx, sp); err != nil {
+		return nil, err
+	}
+	return sp, nil
+}
+
+// GetSubProject returns a sub-project by ID.
+func (s *ProjectService) GetSubProject(ctx context.Context, id string) (*project.SubProject, error) {
+	return s.store.GetSubProject(ctx, id)
+}
+
+// ListSubProjects returns the sub-projects of a project, ordered by path.
+func (s *ProjectService) ListSubProjects(ctx context.Context, projectID string) ([]project.SubProject, error) {
+	return s.store.ListSubProjects(ctx, projectID)
+}
+
+// DeleteSubProject removes a sub-project. Tasks and agents that targeted
+// it fall back to the whole repository.
+func (s *ProjectService) DeleteSubProject(ctx context.Context, id string) error {
+	return s.store.DeleteSubProject(ctx, id)
+}
+
+// checkSubProject verifies that the sub-project id exists and belongs to
+// projectID. An empty id is valid and means the whole repository.
+func checkSubProject(ctx context.Context, store database.Store, projectID, id string) error {
+	if id == "" {
+		return nil
+	}
+	sp, err := store.GetSubProject(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get sub-project: %w", err)
+	}
+	if sp.ProjectID != projectID {
+		return ErrForeignSubProject
+	}
+	return nil
+}
+
+// subProjectScope returns the sub-project work on t is scoped to: the
+// task's own sub-project, else the one of the agent doing the work. It
+// returns nil if neither targets a sub-project or the lookup fails.
+func subProjectScope(ctx context.Context, store database.Store, t *task.Task, ag *agent.Agent) *project.SubProject {
+	id := t.SubProjectID
+	if id == "" && ag != nil {
+		id = ag.SubProjectID
+	}
+	if id == "" {
+		return nil
+	}
+	sp, err := store.GetSubProject(ctx, id)
+	if err != nil {
+		slog.Warn("sub-project lookup failed, using whole repository", "task_id", t.ID, "sub_project_id", id, "error", err)
+		return nil
+	}
+	return sp
+}
+
+// runScope returns the sub-project scope of a run, derived from its task
+// and agent.
+func runScope(ctx context.Context, store database.Store, r *run.Run) *project.SubProject {
+	t, err := store.GetTask(ctx, r.TaskID)
+	if err != nil {
+		return nil
+	}
+	ag, err := store.GetAgent(ctx, r.AgentID)
+	if err != nil {
+		ag = nil
+	}
+	return subProjectScope(ctx, store, t, ag)
+}
@@ -0,0 +1,96 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestBuildContextPack_SubProjectScope(t *testing.T) {
+	dir := t.TempDir()
+	for _, rel := range []string{"services/api/auth.go", "services/web/auth.go", "auth.md"} {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		_ = os.MkdirAll(filepath.Dir(path), 0o755)
+		_ = os.WriteFile(path, []byte("func checkAuth() {}"), 0o644)
+	}
+
+	store := &runtimeMockStore{
+		projects:    []project.Project{{ID: "proj-1", WorkspacePath: dir}},
+		subProjects: []project.SubProject{{ID: "sub-api", ProjectID: "proj-1", Name: "api", Path: "services/api"}},
+		tasks:       []task.Task{{ID: "task-1", ProjectID: "proj-1", SubProjectID: "sub-api", Prompt: "Fix auth check"}},
+	}
+	svc := service.NewContextOptimizerService(store, &config.Orchestrator{DefaultContextBudget: 8192, PromptReserve: 1024})
+
+	pack, err := svc.BuildContextPack(context.Background(), "task-1", "proj-1", "")
+	if err != nil {
+		t.Fatalf("BuildContextPack failed: %v", err)
+	}
+	if pack == nil || len(pack.Entries) != 1 || pack.Entries[0].Path != "services/api/auth.go" {
+		t.Fatalf("expected only the sub-project file, got %+v", pack)
+	}
+}
+
+func TestStartRun_AgentSubProject(t *testing.T) {
+	svc, store, queue, _ := newRuntimeTestEnv()
+	ctx := context.Background()
+	store.subProjects = []project.SubProject{{
+		ID: "sub-web", ProjectID: "proj-1", Name: "web", Path: "apps/web",
+		Language: "typescript", BuildCommand: "npm run build", TestCommand: "npm test",
+	}}
+	store.agents[0].SubProjectID = "sub-web"
+
+	r, err := svc.StartRun(ctx, &run.StartRequest{TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1"})
+	if err != nil {
+		t.Fatalf("StartRun failed: %v", err)
+	}
+	msg, _ := queue.lastMessage(messagequeue.SubjectRunStart)
+	var start messagequeue.RunStartPayload
+	_ = json.Unmarshal(msg.Data, &start)
+	if start.SubProject == nil || start.SubProject.Path != "apps/web" || start.SubProject.BuildCommand != "npm run build" {
+		t.Fatalf("expected sub-project in run start payload, got %+v", start.SubProject)
+	}
+
+	// Quality gates run the sub-project's tests inside its directory.
+	if err := svc.HandleRunComplete(ctx, &messagequeue.RunCompletePayload{RunID: r.ID, Status: "completed"}); err != nil {
+		t.Fatalf("HandleRunComplete failed: %v", err)
+	}
+	msg, ok := queue.lastMessage(messagequeue.SubjectQualityGateRequest)
+	if !ok {
+		t.Fatal("expected quality gate request")
+	}
+	var gate messagequeue.QualityGateRequestPayload
+	_ = json.Unmarshal(msg.Data, &gate)
+	if gate.WorkspacePath != filepath.Join("/tmp/test-workspace", "apps", "web") || gate.TestCommand != "npm test" {
+		t.Fatalf("unexpected gate request %+v", gate)
+	}
+	if gate.LintCommand != "golangci-lint run ./..." {
+		t.Fatalf("expected default lint command, got %q", gate.LintCommand)
+	}
+}
+
+func TestTaskCreate_RejectsForeignSubProject(t *testing.T) {
+	store := &runtimeMockStore{
+		subProjects: []project.SubProject{{ID: "sub-1", ProjectID: "proj-2", Name: "lib", Path: "lib"}},
+	}
+	svc := service.NewTaskService(store, &runtimeMockQueue{})
+
+	_, err := svc.Create(context.Background(), task.CreateRequest{ProjectID: "proj-1", SubProjectID: "sub-1", Title: "t"})
+	if !errors.Is(err, service.ErrForeignSubProject) {
+		t.Fatalf("expected ErrForeignSubProject, got %v", err)
+	}
+
+	tk, err := svc.Create(context.Background(), task.CreateRequest{ProjectID: "proj-2", SubProjectID: "sub-1", Title: "t"})
+	if err != nil || tk.SubProjectID != "sub-1" {
+		t.Fatalf("expected task in sub-project, got %+v, %v", tk, err)
+	}
+}
@@ -0,0 +1,466 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/issuerun"
+	"github.com/Strob0t/CodeForge/internal/domain/poll"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/roadmap"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+	"github.com/Strob0t/CodeForge/internal/port/pmprovider"
+)
+
+var (
+	// ErrNoPMProvider is returned for projects not linked to a PM platform.
+	ErrNoPMProvider = errors.New("sync: project has no pm_provider configured")
+	// ErrPMCapability is returned when the linked provider does not
+	// support the requested operation.
+	ErrPMCapability = errors.New("sync: operation not supported by pm provider")
+	// ErrPMPush is returned when the PM platform rejects a pushed change.
+	ErrPMPush = errors.New("sync: pm provider rejected the change")
+)
+
+// SyncService keeps a project's roadmap features in sync with the PM
+// platform the project is linked to via its pm_provider config. Items are
+// imported on demand and updated from webhooks; status changes made in
+// CodeForge are pushed back to the platform.
+//
+// For projects that cannot receive webhooks, its poller fetches the same
+// changes from the git host and the PM platform (see Poll).
+type SyncService struct {
+	store     database.Store
+	secrets   *SecretService
+	issueRuns *IssueRunService
+	chatOps   *ChatOpsService
+}
+
+// NewSyncService creates a SyncService. secrets resolves the API tokens and
+// webhook secrets named in project config; it may be nil if no project
+// needs them.
+func NewSyncService(store database.Store, secrets *SecretService) *SyncService {
+	return &SyncService{store: store, secrets: secrets}
+}
+
+// SetIssueRunService sets the service polled issue changes start runs with.
+func (s *SyncService) SetIssueRunService(issueRuns *IssueRunService) {
+	s.issueRuns = issueRuns
+}
+
+// SetChatOpsService sets the service polled pull request comments run
+// commands with.
+func (s *SyncService) SetChatOpsService(chatOps *ChatOpsService) {
+	s.chatOps = chatOps
+}
+
+// ListFeatures returns the roadmap features of a project.
+func (s *SyncService) ListFeatures(ctx context.Context, projectID string) ([]roadmap.Feature, error) {
+	if _, err := s.store.GetProject(ctx, projectID); err != nil {
+		return nil, err
+	}
+	return s.store.ListFeatures(ctx, projectID)
+}
+
+// Import fetches all items of the linked PM project and creates or updates
+// the matching roadmap features.
+func (s *SyncService) Import(ctx context.Context, projectID string) (*roadmap.ImportResult, error) {
+	p, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	prov, err := s.provider(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if !prov.Capabilities().Import {
+		return nil, ErrPMCapability
+	}
+
+	items, err := prov.ListItems(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list %s items: %w", prov.Name(), err)
+	}
+	res := &roadmap.ImportResult{Provider: prov.Name()}
+	for i := range items {
+		created, changed, err := s.apply(ctx, p.ID, prov.Name(), &items[i], false)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case created:
+			res.Created++
+		case changed:
+			res.Updated++
+		default:
+			res.Unchanged++
+		}
+	}
+	slog.Info("roadmap imported", "project_id", p.ID, "provider", res.Provider,
+		"created", res.Created, "updated", res.Updated, "unchanged", res.Unchanged)
+	return res, nil
+}
+
+// UpdateFeatureStatus changes the status of a feature. Features mirroring
+// an item of the linked PM platform push the change there first, so a
+// rejected transition leaves both sides unchanged.
+func (s *SyncService) UpdateFeatureStatus(ctx context.Context, id string, req *roadmap.UpdateStatusRequest) (*roadmap.Feature, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	f, err := s.store.GetFeature(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if f.Status == req.Status {
+		return f, nil
+	}
+
+	p, err := s.store.GetProject(ctx, f.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if itemID := f.ExternalIDs[p.Config[pmprovider.ConfigProvider]]; itemID != "" {
+		prov, err := s.provider(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		if prov.Capabilities().StatusSync {
+			if err := prov.UpdateStatus(ctx, itemID, req.Status); err != nil {
+				return nil, fmt.Errorf("%w: %s: %w", ErrPMPush, prov.Name(), err)
+			}
+		}
+	}
+
+	f.Status = req.Status
+	if err := s.store.UpdateFeature(ctx, f); err != nil {
+		return nil, err
+	}
+	slog.Info("roadmap feature status updated", "feature_id", f.ID, "status", f.Status)
+	return f, nil
+}
+
+// HandleWebhook applies a webhook delivery from the named PM provider to
+// every project linked to it whose configuration verifies and accepts the
+// delivery. It returns the number of projects updated, and
+// pmprovider.ErrInvalidSignature if no linked project could verify it.
+func (s *SyncService) HandleWebhook(ctx context.Context, providerName string, header http.Header, body []byte) (int, error) {
+	projects, err := s.store.ListProjects(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list projects: %w", err)
+	}
+
+	verified, applied := false, 0
+	for i := range projects {
+		p := &projects[i]
+		if p.Config[pmprovider.ConfigProvider] != providerName {
+			continue
+		}
+		prov, err := s.provider(ctx, p)
+		if err != nil {
+			slog.Warn("pm webhook: build provider", "project_id", p.ID, "provider", providerName, "error", err)
+			continue
+		}
+		if !prov.Capabilities().Webhook {
+			continue
+		}
+		ev, err := prov.ParseWebhook(header, body)
+		switch {
+		case errors.Is(err, pmprovider.ErrInvalidSignature):
+			continue
+		case errors.Is(err, pmprovider.ErrIgnoredEvent):
+			verified = true
+			continue
+		case err != nil:
+			slog.Warn("pm webhook: parse", "project_id", p.ID, "provider", providerName, "error", err)
+			verified = true
+			continue
+		}
+		verified = true
+		if _, _, err := s.apply(ctx, p.ID, providerName, &ev.Item, ev.Action == pmprovider.ActionDeleted); err != nil {
+			return applied, err
+		}
+		applied++
+		slog.Info("pm webhook applied", "project_id", p.ID, "provider", providerName,
+			"action", ev.Action, "item", ev.Item.Key)
+	}
+	if !verified {
+		return 0, pmprovider.ErrInvalidSignature
+	}
+	return applied, nil
+}
+
+// apply creates or updates the feature mirroring item. Deleted items
+// cancel their feature rather than removing it.
+func (s *SyncService) apply(ctx context.Context, projectID, providerName string, item *roadmap.Item, deleted bool) (created, changed bool, err error) {
+	if deleted {
+		item.Status = roadmap.FeatureStatusCancelled
+	}
+	f, err := s.store.GetFeatureByExternalID(ctx, projectID, providerName, item.ID)
+	if errors.Is(err, domain.ErrNotFound) {
+		if deleted {
+			return false, false, nil
+		}
+		f = &roadmap.Feature{
+			ProjectID:   projectID,
+			ExternalIDs: map[string]string{providerName: item.ID},
+		}
+		f.Apply(item)
+		if err := s.store.CreateFeature(ctx, f); err != nil {
+			return false, false, err
+		}
+		return true, true, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	if deleted {
+		// Keep the local description and labels of a deleted item.
+		if f.Status == item.Status {
+			return false, false, nil
+		}
+		f.Status = item.Status
+	} else if !f.Apply(item) {
+		return false, false, nil
+	}
+	if err := s.store.UpdateFeature(ctx, f); err != nil {
+		return false, false, err
+	}
+	return false, true, nil
+}
+
+// provider builds the PM provider a project is linked to, resolving the
+// secrets its config refers to.
+func (s *SyncService) provider(ctx context.Context, p *project.Project) (pmprovider.Provider, error) {
+	name := p.Config[pmprovider.ConfigProvider]
+	if name == "" {
+		return nil, ErrNoPMProvider
+	}
+	cfg := maps.Clone(p.Config)
+	for ref, key := range map[string]string{
+		pmprovider.ConfigTokenSecret:   pmprovider.ConfigToken,
+		pmprovider.ConfigWebhookSecret: pmprovider.ConfigWebhookKey,
+	} {
+		secretName := p.Config[ref]
+		if secretName == "" {
+			continue
+		}
+		vals, err := s.secrets.Resolve(ctx, p.ID, []string{secretName})
+		if err != nil {
+			return nil, fmt.Errorf("resolve %s: %w", ref, err)
+		}
+		cfg[key] = vals[secretName]
+	}
+	return pmprovider.New(name, cfg)
+}
+
+// pollTick is how often the poller checks which projects are due.
+const pollTick = 15 * time.Second
+
+// StartPoller polls each project with a poll_interval until cancelled.
+// Projects are polled again after their interval plus a random jitter,
+// and their first poll is jittered too, so that a restart does not poll
+// all projects at once.
+func (s *SyncService) StartPoller(ctx context.Context) (cancel func()) {
+	ctx, cancel = context.WithCancel(ctx)
+	go func() {
+		sched := &pollSchedule{next: make(map[string]time.Time), invalid: make(map[string]string)}
+		ticker := time.NewTicker(pollTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.pollDue(ctx, sched, now)
+			}
+		}
+	}()
+	return cancel
+}
+
+// pollSchedule is the poller's state.
+type pollSchedule struct {
+	next    map[string]time.Time // Project ID -> time of its next poll
+	invalid map[string]string    // Project ID -> invalid poll_interval already logged
+}
+
+// pollDue polls the projects whose next poll is due and schedules the one
+// after it.
+func (s *SyncService) pollDue(ctx context.Context, sched *pollSchedule, now time.Time) {
+	projects, err := s.store.ListProjects(ctx)
+	if err != nil {
+		slog.Error("poll: list projects", "error", err)
+		return
+	}
+	for i := range projects {
+		p := &projects[i]
+		settings, err := poll.SettingsFromConfig(p.Config)
+		if err != nil {
+			if raw := p.Config[poll.ConfigInterval]; sched.invalid[p.ID] != raw {
+				sched.invalid[p.ID] = raw
+				slog.Warn("poll: invalid settings", "project_id", p.ID, "error", err)
+			}
+			delete(sched.next, p.ID)
+			continue
+		}
+		delete(sched.invalid, p.ID)
+		if !settings.Enabled() {
+			delete(sched.next, p.ID)
+			continue
+		}
+		due, ok := sched.next[p.ID]
+		if !ok {
+			sched.next[p.ID] = now.Add(time.Duration(rand.Float64() * float64(settings.Jitter)))
+			continue
+		}
+		if now.Before(due) {
+			continue
+		}
+		sched.next[p.ID] = now.Add(settings.Delay(rand.Float64()))
+		if _, err := s.Poll(ctx, p.ID); err != nil {
+			slog.Error("poll failed", "project_id", p.ID, "error", err)
+		}
+	}
+}
+
+// Poll fetches the changes webhooks would deliver for a project and
+// applies them the same way: issue changes start issue runs, pull request
+// comments run commands and PM items update roadmap features. Sources the
+// project's providers cannot poll are skipped. A source that fails records
+// its error on its cursor without stopping the others.
+func (s *SyncService) Poll(ctx context.Context, projectID string) (*poll.Result, error) {
+	p, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	res := &poll.Result{}
+	if err := s.pollGit(ctx, p, res); err != nil {
+		return res, err
+	}
+
+	if p.Config[pmprovider.ConfigProvider] != "" {
+		prov, err := s.provider(ctx, p)
+		if err != nil {
+			slog.Warn("poll: build pm provider", "project_id", p.ID, "error", err)
+		} else if prov.Capabilities().Import {
+			res.Features, err = s.pollSource(ctx, p, poll.SourcePMItems, func(*poll.Cursor) (int, error) {
+				imported, err := s.Import(ctx, p.ID)
+				if err != nil {
+					return 0, err
+				}
+				return imported.Created + imported.Updated, nil
+			})
+			if err != nil {
+				return res, err
+			}
+		}
+	}
+	return res, nil
+}
+
+// pollGit polls the project's git host for issue changes and pull request
+// comments.
+func (s *SyncService) pollGit(ctx context.Context, p *project.Project, res *poll.Result) error {
+	if p.Provider == "" {
+		return nil
+	}
+	prov, err := hostedGitProvider(ctx, s.secrets, p)
+	if err != nil {
+		slog.Warn("poll: build git provider", "project_id", p.ID, "provider", p.Provider, "error", err)
+		return nil
+	}
+	poller, ok := prov.(gitprovider.Poller)
+	if !ok {
+		return nil
+	}
+
+	settings := issuerun.SettingsFromConfig(p.Config)
+	if tracker, ok := prov.(gitprovider.IssueTracker); ok && s.issueRuns != nil && settings.Enabled() && prov.Capabilities().Issues {
+		res.IssueRuns, err = s.pollSource(ctx, p, poll.SourceIssues, func(c *poll.Cursor) (int, error) {
+			events, err := poller.PollIssues(ctx, c)
+			if err != nil {
+				return 0, err
+			}
+			started := 0
+			for i := range events {
+				ran, err := s.issueRuns.handleEvent(ctx, p, prov, tracker, &events[i])
+				if err != nil {
+					return started, err
+				}
+				if ran {
+					started++
+				}
+			}
+			return started, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if chat, ok := prov.(gitprovider.PRChat); ok && s.chatOps != nil && prov.Capabilities().PullRequest {
+		res.Commands, err = s.pollSource(ctx, p, poll.SourcePRComments, func(c *poll.Cursor) (int, error) {
+			events, err := poller.PollPRComments(ctx, c)
+			if err != nil {
+				return 0, err
+			}
+			started := 0
+			for i := range events {
+				ran, err := s.chatOps.handleComment(ctx, p, prov, chat, &events[i])
+				if err != nil {
+					return started, err
+				}
+				if ran {
+					started++
+				}
+			}
+			return started, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pollSource reads a source with fn from its stored cursor and stores the
+// cursor fn advanced. If fn fails, the cursor stays where it was and
+// records the error, so that the next poll retries. Only store errors are
+// returned.
+func (s *SyncService) pollSource(ctx context.Context, p *project.Project, source poll.Source, fn func(*poll.Cursor) (int, error)) (int, error) {
+	cursor, err := s.store.GetPollCursor(ctx, p.ID, source)
+	if errors.Is(err, domain.ErrNotFound) {
+		cursor, err = &poll.Cursor{ProjectID: p.ID, Source: source}, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	next := *cursor
+	next.Error = ""
+	n, err := fn(&next)
+	if err != nil {
+		slog.Warn("poll: source failed", "project_id", p.ID, "source", source, "error", err)
+		next = *cursor
+		next.Error = err.Error()
+	}
+	return n, s.store.SetPollCursor(ctx, &next)
+}
+
+// ListPollCursors returns how far each polled source of a project has
+// been read.
+func (s *SyncService) ListPollCursors(ctx context.Context, projectID string) ([]poll.Cursor, error) {
+	if _, err := s.store.GetProject(ctx, projectID); err != nil {
+		return nil, err
+	}
+	return s.store.ListPollCursors(ctx, projectID)
+}
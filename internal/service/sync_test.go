@@ -0,0 +1,234 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain/chatops"
+	"github.com/Strob0t/CodeForge/internal/domain/poll"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/roadmap"
+	"github.com/Strob0t/CodeForge/internal/domain/secret"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+	"github.com/Strob0t/CodeForge/internal/port/pmprovider"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+// fakePM is the platform behind the "fake-pm" provider used by these tests.
+type fakePM struct {
+	items   []roadmap.Item
+	pushed  map[string]roadmap.FeatureStatus
+	pushErr error
+}
+
+var currentPM *fakePM
+
+type fakePMProvider struct {
+	pm      *fakePM
+	project string
+	secret  string
+}
+
+// fakeDelivery is the webhook body understood by fakePMProvider.
+type fakeDelivery struct {
+	Project string            `json:"project"`
+	Action  pmprovider.Action `json:"action"`
+	Item    roadmap.Item      `json:"item"`
+}
+
+func init() {
+	pmprovider.Register("fake-pm", func(cfg map[string]string) (pmprovider.Provider, error) {
+		if cfg[pmprovider.ConfigToken] != "tok" {
+			return nil, fmt.Errorf("fake-pm: bad token %q", cfg[pmprovider.ConfigToken])
+		}
+		return &fakePMProvider{pm: currentPM, project: cfg["fake_project"], secret: cfg[pmprovider.ConfigWebhookKey]}, nil
+	})
+}
+
+func (p *fakePMProvider) Name() string { return "fake-pm" }
+func (p *fakePMProvider) Capabilities() pmprovider.Capabilities {
+	return pmprovider.Capabilities{Import: true, StatusSync: true, Webhook: p.secret != ""}
+}
+func (p *fakePMProvider) ListItems(_ context.Context) ([]roadmap.Item, error) {
+	return p.pm.items, nil
+}
+func (p *fakePMProvider) UpdateStatus(_ context.Context, itemID string, status roadmap.FeatureStatus) error {
+	if p.pm.pushErr != nil {
+		return p.pm.pushErr
+	}
+	p.pm.pushed[itemID] = status
+	return nil
+}
+func (p *fakePMProvider) ParseWebhook(header http.Header, body []byte) (*pmprovider.WebhookEvent, error) {
+	if header.Get("X-Secret") != p.secret {
+		return nil, pmprovider.ErrInvalidSignature
+	}
+	var d fakeDelivery
+	if err := json.Unmarshal(body, &d); err != nil {
+		return nil, err
+	}
+	if d.Project != p.project {
+		return nil, pmprovider.ErrIgnoredEvent
+	}
+	return &pmprovider.WebhookEvent{Action: d.Action, Item: d.Item}, nil
+}
+
+func newSyncTestEnv(t *testing.T) (*runtimeMockStore, *service.SyncService) {
+	t.Helper()
+	currentPM = &fakePM{pushed: make(map[string]roadmap.FeatureStatus)}
+	_, store, _, _ := newRuntimeTestEnv()
+	secrets := newTestSecretService(t, store)
+	ctx := context.Background()
+	for name, value := range map[string]string{"PM_TOKEN": "tok", "PM_HOOK": "hook"} {
+		if _, err := secrets.Create(ctx, "", &secret.CreateRequest{Name: name, Value: value}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.projects = append(store.projects, project.Project{ID: "pm-proj", Name: "linked", Config: map[string]string{
+		pmprovider.ConfigProvider:      "fake-pm",
+		pmprovider.ConfigTokenSecret:   "PM_TOKEN",
+		pmprovider.ConfigWebhookSecret: "PM_HOOK",
+		"fake_project":                 "ENG",
+	}})
+	return store, service.NewSyncService(store, secrets)
+}
+
+func TestSyncService_Import(t *testing.T) {
+	store, svc := newSyncTestEnv(t)
+	ctx := context.Background()
+	currentPM.items = []roadmap.Item{
+		{ID: "1", Key: "ENG-1", Title: "SSO", Status: roadmap.FeatureStatusPlanned, Labels: []string{"auth"}},
+		{ID: "2", Key: "ENG-2", Title: "Audit log", Status: roadmap.FeatureStatusInProgress},
+	}
+
+	res, err := svc.Import(ctx, "pm-proj")
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if res.Created != 2 || res.Provider != "fake-pm" {
+		t.Fatalf("unexpected first import %+v", res)
+	}
+
+	currentPM.items[1].Status = roadmap.FeatureStatusDone
+	res, err = svc.Import(ctx, "pm-proj")
+	if err != nil {
+		t.Fatalf("re-import: %v", err)
+	}
+	if res.Created != 0 || res.Updated != 1 || res.Unchanged != 1 {
+		t.Fatalf("unexpected re-import %+v", res)
+	}
+	f, _ := store.GetFeatureByExternalID(ctx, "pm-proj", "fake-pm", "2")
+	if f == nil || f.Status != roadmap.FeatureStatusDone {
+		t.Fatalf("expected feature updated to done, got %+v", f)
+	}
+
+	if _, err := svc.Import(ctx, "proj-1"); !errors.Is(err, service.ErrNoPMProvider) {
+		t.Fatalf("expected ErrNoPMProvider for unlinked project, got %v", err)
+	}
+}
+
+func TestSyncService_UpdateFeatureStatusPushes(t *testing.T) {
+	_, svc := newSyncTestEnv(t)
+	ctx := context.Background()
+	currentPM.items = []roadmap.Item{{ID: "7", Title: "SSO", Status: roadmap.FeatureStatusPlanned}}
+	if _, err := svc.Import(ctx, "pm-proj"); err != nil {
+		t.Fatal(err)
+	}
+	features, _ := svc.ListFeatures(ctx, "pm-proj")
+	id := features[0].ID
+
+	currentPM.pushErr = errors.New("no transition")
+	if _, err := svc.UpdateFeatureStatus(ctx, id, &roadmap.UpdateStatusRequest{Status: roadmap.FeatureStatusDone}); err == nil {
+		t.Fatal("expected push failure to be returned")
+	}
+	features, _ = svc.ListFeatures(ctx, "pm-proj")
+	if features[0].Status != roadmap.FeatureStatusPlanned {
+		t.Fatalf("expected status unchanged after failed push, got %s", features[0].Status)
+	}
+
+	currentPM.pushErr = nil
+	f, err := svc.UpdateFeatureStatus(ctx, id, &roadmap.UpdateStatusRequest{Status: roadmap.FeatureStatusInProgress})
+	if err != nil {
+		t.Fatalf("update status: %v", err)
+	}
+	if f.Status != roadmap.FeatureStatusInProgress || currentPM.pushed["7"] != roadmap.FeatureStatusInProgress {
+		t.Fatalf("expected status stored and pushed, got %s / %v", f.Status, currentPM.pushed)
+	}
+}
+
+func TestSyncService_HandleWebhook(t *testing.T) {
+	store, svc := newSyncTestEnv(t)
+	ctx := context.Background()
+	deliver := func(secret string, d fakeDelivery) (int, error) {
+		body, _ := json.Marshal(d)
+		h := http.Header{}
+		h.Set("X-Secret", secret)
+		return svc.HandleWebhook(ctx, "fake-pm", h, body)
+	}
+	item := roadmap.Item{ID: "9", Key: "ENG-9", Title: "Dark mode", Status: roadmap.FeatureStatusPlanned}
+
+	if _, err := deliver("wrong", fakeDelivery{Project: "ENG", Action: pmprovider.ActionCreated, Item: item}); !errors.Is(err, pmprovider.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+	if n, err := deliver("hook", fakeDelivery{Project: "OPS", Action: pmprovider.ActionCreated, Item: item}); err != nil || n != 0 {
+		t.Fatalf("expected event for another project ignored, got %d, %v", n, err)
+	}
+	if n, err := deliver("hook", fakeDelivery{Project: "ENG", Action: pmprovider.ActionCreated, Item: item}); err != nil || n != 1 {
+		t.Fatalf("expected event applied, got %d, %v", n, err)
+	}
+	if _, err := deliver("hook", fakeDelivery{Project: "ENG", Action: pmprovider.ActionDeleted, Item: item}); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := store.GetFeatureByExternalID(ctx, "pm-proj", "fake-pm", "9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Title != "Dark mode" || f.Status != roadmap.FeatureStatusCancelled {
+		t.Fatalf("expected deleted item to cancel its feature, got %+v", f)
+	}
+	if len(currentPM.pushed) != 0 {
+		t.Fatalf("expected webhook changes not pushed back, got %v", currentPM.pushed)
+	}
+}
+
+func TestSyncService_Poll(t *testing.T) {
+	env := newChatOpsTestEnv(t)
+	ctx := context.Background()
+	svc := service.NewSyncService(env.store, env.secrets)
+	svc.SetChatOpsService(env.svc)
+
+	// The first poll starts the feed at the present.
+	prPolled = []gitprovider.PRCommentEvent{{Number: 7, CommentID: "20", Body: "/codeforge explain", Author: "alice",
+		AuthorRole: chatops.RoleOwner, Branch: "fix-login", BaseBranch: "main"}}
+	if res, err := svc.Poll(ctx, "gh-chat"); err != nil || res.Commands != 0 {
+		t.Fatalf("expected an empty first poll, got %+v, %v", res, err)
+	}
+
+	prPolled = append(prPolled, gitprovider.PRCommentEvent{Number: 7, CommentID: "21", Body: "/codeforge fix", Author: "alice",
+		AuthorRole: chatops.RoleOwner, Branch: "fix-login", BaseBranch: "main"})
+	res, err := svc.Poll(ctx, "gh-chat")
+	if err != nil || res.Commands != 1 {
+		t.Fatalf("expected one command, got %+v, %v", res, err)
+	}
+	if c := env.command(t, "21"); c.Command != chatops.CommandFix || c.RunID == "" {
+		t.Fatalf("unexpected command %+v", c)
+	}
+
+	// A failing source keeps its position and records the error.
+	pollErr = errors.New("rate limited")
+	if res, err := svc.Poll(ctx, "gh-chat"); err != nil || res.Commands != 0 {
+		t.Fatalf("expected the failure to be recorded, got %+v, %v", res, err)
+	}
+	cursors, err := svc.ListPollCursors(ctx, "gh-chat")
+	if err != nil || len(cursors) != 1 {
+		t.Fatalf("ListPollCursors = %+v, %v", cursors, err)
+	}
+	if c := cursors[0]; c.Source != poll.SourcePRComments || c.Position != "2" || c.Error != "rate limited" {
+		t.Fatalf("unexpected cursor %+v", c)
+	}
+}
@@ -34,6 +34,10 @@ func (s *TaskService) Get(ctx context.Context, id string) (*task.Task, error) {
 
 // Create creates a task, saves it to DB, and publishes it to NATS.
 func (s *TaskService) Create(ctx context.Context, req task.CreateRequest) (*task.Task, error) {
+	if err := checkSubProject(ctx, s.store, req.ProjectID, req.SubProjectID); err != nil {
+		return nil, err
+	}
+
 	t, err := s.store.CreateTask(ctx, req)
 	if err != nil {
 		return nil, err
@@ -68,6 +68,7 @@ func (s *TaskPlannerService) PlanFeature(ctx context.Context, req *plan.PlanFeat
 		Context:   enrichedContext,
 		Model:     req.Model,
 		AutoStart: req.AutoStart,
+		Backend:   req.Backend,
 	}
 
 	p, err := s.meta.DecomposeFeature(ctx, decompReq)
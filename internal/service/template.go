@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/scaffold"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+// TemplateService creates projects from templates: it writes the
+// template's files into a new repository and wires its agents, modes and
+// pipelines into the project.
+type TemplateService struct {
+	store         database.Store
+	projects      *ProjectService
+	agents        *AgentService
+	orch          *OrchestratorService
+	templates     []scaffold.Template
+	workspaceRoot string
+}
+
+// NewTemplateService creates a TemplateService for the given templates.
+// The templates' modes are registered with modes; every policy profile
+// they reference must exist.
+func NewTemplateService(
+	store database.Store,
+	projects *ProjectService,
+	agents *AgentService,
+	orch *OrchestratorService,
+	modes *ModeService,
+	policies *PolicyService,
+	templates []scaffold.Template,
+) (*TemplateService, error) {
+	for i := range templates {
+		t := &templates[i]
+		profiles := []string{t.Config[policy.ConfigKeyProfile]}
+		for _, a := range t.Agents {
+			profiles = append(profiles, a.PolicyProfile)
+		}
+		for _, name := range profiles {
+			if _, ok := policies.GetProfile(name); name != "" && !ok {
+				return nil, fmt.Errorf("project template %s: unknown policy profile %q", t.Name, name)
+			}
+		}
+		for j := range t.Modes {
+			if err := modes.Register(&t.Modes[j]); err != nil {
+				return nil, fmt.Errorf("project template %s: %w", t.Name, err)
+			}
+		}
+	}
+	slices.SortFunc(templates, func(a, b scaffold.Template) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return &TemplateService{
+		store:         store,
+		projects:      projects,
+		agents:        agents,
+		orch:          orch,
+		templates:     templates,
+		workspaceRoot: WorkspaceRoot,
+	}, nil
+}
+
+// SetWorkspaceRoot overrides the base directory of new repositories.
+func (s *TemplateService) SetWorkspaceRoot(dir string) {
+	if dir != "" {
+		s.workspaceRoot = dir
+	}
+}
+
+// List returns the loaded templates, sorted by name.
+func (s *TemplateService) List() []scaffold.Template {
+	return s.templates
+}
+
+// Get returns a template by name.
+func (s *TemplateService) Get(name string) (*scaffold.Template, error) {
+	for i := range s.templates {
+		if s.templates[i].Name == name {
+			return &s.templates[i], nil
+		}
+	}
+	return nil, fmt.Errorf("project template %s: %w", name, domain.ErrNotFound)
+}
+
+// Create creates a project from a template. The template's files are
+// committed to a new repository in the project's workspace, pushed to
+// req.RepoURL if it is set, and the template's agents and pipelines are
+// created for the project. If any step fails, the project is removed again.
+func (s *TemplateService) Create(ctx context.Context, name string, req *scaffold.CreateRequest) (*scaffold.Result, error) {
+	t, err := s.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Validate(t); err != nil {
+		return nil, fmt.Errorf("validate request: %w", err)
+	}
+	files, err := t.Render(req.Name, req.Variables)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := gitprovider.New(req.Provider, req.Config)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", scaffold.ErrCannotInitialize, err)
+	}
+	initializer, ok := provider.(gitprovider.Initializer)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", req.Provider, scaffold.ErrCannotInitialize)
+	}
+
+	p, err := s.projects.Create(ctx, project.CreateRequest{
+		TenantID:    req.TenantID,
+		Name:        req.Name,
+		Description: req.Description,
+		RepoURL:     req.RepoURL,
+		Provider:    req.Provider,
+		Config:      t.ProjectConfig(req.Config),
+	})
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(s.workspaceRoot, p.ID)
+	result, err := s.wire(ctx, t, req, p, dir, files, initializer)
+	if err != nil {
+		if rmErr := os.RemoveAll(dir); rmErr != nil {
+			slog.Error("remove template workspace", "project_id", p.ID, "error", rmErr)
+		}
+		if delErr := s.store.DeleteProject(context.WithoutCancel(ctx), p.ID); delErr != nil {
+			slog.Error("delete project after failed template", "project_id", p.ID, "error", delErr)
+		}
+		return nil, err
+	}
+
+	slog.Info("project created from template",
+		"project_id", p.ID,
+		"template", t.Name,
+		"files", len(result.Files),
+		"agents", len(result.Agents),
+		"plans", len(result.Plans),
+	)
+	return result, nil
+}
+
+// wire writes and commits the files of a new project and creates its
+// agents and pipelines.
+func (s *TemplateService) wire(
+	ctx context.Context,
+	t *scaffold.Template,
+	req *scaffold.CreateRequest,
+	p *project.Project,
+	dir string,
+	files []scaffold.File,
+	initializer gitprovider.Initializer,
+) (*scaffold.Result, error) {
+	result := &scaffold.Result{Template: t.Name, Project: p}
+	for _, f := range files {
+		if err := writeTemplateFile(dir, f); err != nil {
+			return nil, err
+		}
+		result.Files = append(result.Files, f.Path)
+	}
+	if err := initializer.Init(ctx, dir, req.RepoURL, "Initial commit from template "+t.Name); err != nil {
+		return nil, fmt.Errorf("initialize repository: %w", err)
+	}
+	p.WorkspacePath = dir
+	if err := s.store.UpdateProject(ctx, p); err != nil {
+		return nil, fmt.Errorf("update project workspace: %w", err)
+	}
+
+	agentIDs := make(map[string]string, len(t.Agents))
+	for i := range t.Agents {
+		a := &t.Agents[i]
+		created, err := s.agents.Create(ctx, p.ID, a.Name, a.Backend, a.AgentConfig())
+		if err != nil {
+			return nil, fmt.Errorf("create agent %s: %w", a.Name, err)
+		}
+		agentIDs[a.Name] = created.ID
+		result.Agents = append(result.Agents, *created)
+	}
+
+	for i := range t.Pipelines {
+		pl, err := s.createPipeline(ctx, p.ID, &t.Pipelines[i], agentIDs)
+		if err != nil {
+			return nil, err
+		}
+		result.Plans = append(result.Plans, *pl)
+	}
+	if req.AutoStart {
+		for i := range result.Plans {
+			started, err := s.orch.StartPlan(ctx, result.Plans[i].ID)
+			if err != nil {
+				return nil, fmt.Errorf("start plan %s: %w", result.Plans[i].Name, err)
+			}
+			result.Plans[i] = *started
+		}
+	}
+	return result, nil
+}
+
+// createPipeline creates a task per step of a pipeline and an execution
+// plan running them with the steps' agents.
+func (s *TemplateService) createPipeline(ctx context.Context, projectID string, pl *scaffold.Pipeline, agentIDs map[string]string) (*plan.ExecutionPlan, error) {
+	steps := make([]plan.CreateStepRequest, len(pl.Steps))
+	for i, st := range pl.Steps {
+		created, err := s.store.CreateTask(ctx, task.CreateRequest{
+			ProjectID: projectID,
+			Title:     st.Title,
+			Prompt:    st.Prompt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("pipeline %s: create task %d: %w", pl.Name, i, err)
+		}
+		deps := make([]string, len(st.DependsOn))
+		for j, d := range st.DependsOn {
+			deps[j] = strconv.Itoa(d)
+		}
+		steps[i] = plan.CreateStepRequest{
+			Name:      st.Name,
+			TaskID:    created.ID,
+			AgentID:   agentIDs[st.Agent],
+			DependsOn: deps,
+		}
+	}
+	p, err := s.orch.CreatePlan(ctx, &plan.CreatePlanRequest{
+		Name:        pl.Name,
+		Description: pl.Description,
+		ProjectID:   projectID,
+		Protocol:    pl.Protocol,
+		Steps:       steps,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("pipeline %s: %w", pl.Name, err)
+	}
+	return p, nil
+}
+
+// writeTemplateFile writes a rendered file below dir.
+func writeTemplateFile(dir string, f scaffold.File) error {
+	target := filepath.Join(dir, filepath.FromSlash(f.Path))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("create directory for %s: %w", f.Path, err)
+	}
+	mode := os.FileMode(0o644)
+	if f.Executable {
+		mode = 0o755
+	}
+	if err := os.WriteFile(target, []byte(f.Content), mode); err != nil {
+		return fmt.Errorf("write %s: %w", f.Path, err)
+	}
+	return nil
+}
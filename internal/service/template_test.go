@@ -0,0 +1,176 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	_ "github.com/Strob0t/CodeForge/internal/adapter/gitlocal"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/aider"
+	"github.com/Strob0t/CodeForge/internal/domain/mode"
+	"github.com/Strob0t/CodeForge/internal/domain/plan"
+	"github.com/Strob0t/CodeForge/internal/domain/scaffold"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+var registerAider sync.Once
+
+func newTemplateTestSetup(t *testing.T, templates ...scaffold.Template) (*orchMockStore, *service.ModeService, *service.TemplateService, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available in test environment")
+	}
+	registerAider.Do(func() { aider.Register(&runtimeMockQueue{}) })
+
+	store, orchSvc := newOrchTestSetup()
+	modes := service.NewModeService()
+	svc, err := service.NewTemplateService(store, service.NewProjectService(store),
+		service.NewAgentService(store, &runtimeMockQueue{}, &runtimeMockBroadcaster{}), orchSvc,
+		modes, service.NewPolicyService("headless-safe-sandbox", nil), templates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	svc.SetWorkspaceRoot(root)
+	return store, modes, svc, root
+}
+
+func serviceTemplate() scaffold.Template {
+	return scaffold.Template{
+		Name:      "go-service",
+		Stack:     scaffold.Stack{Language: "go", TestCommand: "go test ./..."},
+		Variables: []scaffold.Variable{{Name: "module"}},
+		Files: []scaffold.File{
+			{Path: "go.mod", Content: "module {{ .Vars.module }}\n"},
+			{Path: "scripts/check.sh", Content: "#!/bin/sh\ngo vet ./...\n", Executable: true},
+		},
+		Config: map[string]string{"policy_profile": "headless-safe-sandbox"},
+		Modes:  []mode.Mode{{ID: "service-coder", Name: "Service Coder", Autonomy: 3}},
+		Agents: []scaffold.Agent{
+			{Name: "coder", Backend: "aider", Mode: "service-coder", Model: "gpt-4o"},
+			{Name: "reviewer", Backend: "aider", Mode: "reviewer", PolicyProfile: "plan-readonly"},
+		},
+		Pipelines: []scaffold.Pipeline{{
+			Name: "first-endpoint", Protocol: plan.ProtocolSequential,
+			Steps: []scaffold.Step{
+				{Title: "Implement", Prompt: "Add GET /version", Agent: "coder"},
+				{Title: "Review", Prompt: "Review GET /version", Agent: "reviewer", DependsOn: []int{0}},
+			},
+		}},
+	}
+}
+
+func TestTemplateService_Create(t *testing.T) {
+	store, modes, svc, root := newTemplateTestSetup(t, serviceTemplate())
+	ctx := context.Background()
+
+	if _, err := modes.Get("service-coder"); err != nil {
+		t.Fatalf("expected template mode to be registered: %v", err)
+	}
+
+	res, err := svc.Create(ctx, "go-service", &scaffold.CreateRequest{
+		Name:      "billing",
+		Variables: map[string]string{"module": "example.com/billing"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := res.Project
+	if p.Provider != "local" || p.Config["test_command"] != "go test ./..." || p.Config["policy_profile"] != "headless-safe-sandbox" {
+		t.Fatalf("unexpected project %+v", p)
+	}
+	if p.WorkspacePath != filepath.Join(root, p.ID) {
+		t.Fatalf("expected workspace in %s, got %q", root, p.WorkspacePath)
+	}
+	stored, _ := store.GetProject(ctx, p.ID)
+	if stored.WorkspacePath != p.WorkspacePath {
+		t.Fatalf("expected stored workspace path, got %q", stored.WorkspacePath)
+	}
+
+	data, err := os.ReadFile(filepath.Join(p.WorkspacePath, "go.mod"))
+	if err != nil || string(data) != "module example.com/billing\n" {
+		t.Fatalf("unexpected go.mod %q (%v)", data, err)
+	}
+	info, err := os.Stat(filepath.Join(p.WorkspacePath, "scripts", "check.sh"))
+	if err != nil || info.Mode().Perm()&0o100 == 0 {
+		t.Fatalf("expected executable script, got %v (%v)", info, err)
+	}
+	out, err := exec.Command("git", "-C", p.WorkspacePath, "status", "--porcelain").Output()
+	if err != nil || len(out) != 0 {
+		t.Fatalf("expected committed repository, got %q (%v)", out, err)
+	}
+
+	if len(res.Agents) != 2 {
+		t.Fatalf("expected 2 agents, got %d", len(res.Agents))
+	}
+	if cfg := res.Agents[0].Config; cfg["mode"] != "service-coder" || cfg["model"] != "gpt-4o" {
+		t.Fatalf("unexpected coder config %v", cfg)
+	}
+	if cfg := res.Agents[1].Config; cfg["mode"] != "reviewer" || cfg["policy_profile"] != "plan-readonly" {
+		t.Fatalf("unexpected reviewer config %v", cfg)
+	}
+
+	if len(res.Plans) != 1 {
+		t.Fatalf("expected 1 plan, got %d", len(res.Plans))
+	}
+	pl := res.Plans[0]
+	if pl.ProjectID != p.ID || pl.Status != plan.StatusPending || len(pl.Steps) != 2 {
+		t.Fatalf("unexpected plan %+v", pl)
+	}
+	if len(pl.Steps[1].DependsOn) != 1 || pl.Steps[1].DependsOn[0] != pl.Steps[0].ID {
+		t.Fatalf("expected review step to depend on the first step, got %v", pl.Steps[1].DependsOn)
+	}
+	for _, st := range pl.Steps {
+		task, err := store.GetTask(ctx, st.TaskID)
+		if err != nil || task.ProjectID != p.ID {
+			t.Fatalf("expected a task of the project for step %s, got %+v (%v)", st.ID, task, err)
+		}
+	}
+}
+
+func TestTemplateService_CreateErrors(t *testing.T) {
+	broken := serviceTemplate()
+	broken.Name = "broken"
+	broken.Agents[1].Backend = "missing-backend"
+	_, _, svc, root := newTemplateTestSetup(t, serviceTemplate(), broken)
+	ctx := context.Background()
+	vars := map[string]string{"module": "example.com/billing"}
+
+	if _, err := svc.Create(ctx, "rails-app", &scaffold.CreateRequest{Name: "x"}); err == nil {
+		t.Fatal("expected error for unknown template")
+	}
+	if _, err := svc.Create(ctx, "go-service", &scaffold.CreateRequest{Name: "billing"}); !errors.Is(err, scaffold.ErrMissingVariable) {
+		t.Fatalf("expected ErrMissingVariable, got %v", err)
+	}
+	_, err := svc.Create(ctx, "go-service", &scaffold.CreateRequest{Name: "billing", Provider: "svn", Variables: vars})
+	if !errors.Is(err, scaffold.ErrCannotInitialize) {
+		t.Fatalf("expected ErrCannotInitialize, got %v", err)
+	}
+
+	// A failure after the project was created removes its workspace.
+	if _, err := svc.Create(ctx, "broken", &scaffold.CreateRequest{Name: "billing", Variables: vars}); err == nil {
+		t.Fatal("expected error for unknown agent backend")
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("expected workspace removed, got %v (%v)", entries, err)
+	}
+}
+
+func TestNewTemplateService_UnknownPolicy(t *testing.T) {
+	store, orchSvc := newOrchTestSetup()
+	tmpl := serviceTemplate()
+	tmpl.Agents[1].PolicyProfile = "yolo"
+	_, err := service.NewTemplateService(store, service.NewProjectService(store),
+		service.NewAgentService(store, &runtimeMockQueue{}, &runtimeMockBroadcaster{}), orchSvc,
+		service.NewModeService(), service.NewPolicyService("headless-safe-sandbox", nil), []scaffold.Template{tmpl})
+	if err == nil {
+		t.Fatal("expected error for unknown policy profile")
+	}
+}
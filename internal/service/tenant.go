@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+)
+
+// TenantService manages tenants and admits runs, projects and clones
+// against their quotas.
+//
+// Admission reads usage and then acts, so requests racing for the last
+// unit of a quota may overshoot it by their number.
+type TenantService struct {
+	store   database.Store
+	secrets *SecretService
+	now     func() time.Time
+}
+
+// NewTenantService creates a TenantService.
+func NewTenantService(store database.Store) *TenantService {
+	return &TenantService{store: store, now: time.Now}
+}
+
+// SetSecretService lets VerifyIsolation check the encryption of secrets.
+func (s *TenantService) SetSecretService(secrets *SecretService) {
+	s.secrets = secrets
+}
+
+// Create validates and stores a tenant.
+func (s *TenantService) Create(ctx context.Context, req *tenant.CreateRequest) (*tenant.Tenant, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validate tenant: %w", err)
+	}
+	t := &tenant.Tenant{ID: req.ID, Name: req.Name, Quota: req.Quota}
+	if err := s.store.CreateTenant(ctx, t); err != nil {
+		return nil, err
+	}
+	slog.Info("tenant created", "tenant_id", t.ID, "name", t.Name)
+	return t, nil
+}
+
+// Get returns a tenant by ID.
+func (s *TenantService) Get(ctx context.Context, id string) (*tenant.Tenant, error) {
+	return s.store.GetTenant(ctx, id)
+}
+
+// List returns all tenants.
+func (s *TenantService) List(ctx context.Context) ([]tenant.Tenant, error) {
+	return s.store.ListTenants(ctx)
+}
+
+// SetQuota replaces the quota of a tenant. Lowering a limit below the
+// current usage denies further admissions but stops nothing running.
+func (s *TenantService) SetQuota(ctx context.Context, id string, q tenant.Quota) (*tenant.Tenant, error) {
+	if err := q.Validate(); err != nil {
+		return nil, fmt.Errorf("validate quota: %w", err)
+	}
+	if err := s.store.UpdateTenantQuota(ctx, id, q); err != nil {
+		return nil, err
+	}
+	slog.Info("tenant quota updated", "tenant_id", id, "quota", q)
+	return s.store.GetTenant(ctx, id)
+}
+
+// Usage returns the current usage of a tenant, including the on-disk size
+// of its project workspaces, with the resources at their limit marked.
+func (s *TenantService) Usage(ctx context.Context, id string) (*tenant.Usage, error) {
+	u, err := s.usage(ctx, id, true)
+	if err != nil {
+		return nil, err
+	}
+	u.Evaluate()
+	return u, nil
+}
+
+// VerifyIsolation checks that every tenant-owned table enforces row-level
+// security for the database role in use and, if secrets are configured,
+// that every secret is encrypted with its tenant's key.
+func (s *TenantService) VerifyIsolation(ctx context.Context) (*tenant.IsolationReport, error) {
+	r, err := s.store.GetTenantIsolation(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if s.secrets.Available() {
+		if r.Secrets, r.LegacySecrets, r.UnreadableSecrets, err = s.secrets.KeyStatus(ctx); err != nil {
+			return nil, fmt.Errorf("check secret keys: %w", err)
+		}
+	}
+	r.Evaluate()
+	return r, nil
+}
+
+// AdmitRun checks that the tenant owning a project may start another run:
+// it must be below its concurrent run limit and monthly token budget.
+func (s *TenantService) AdmitRun(ctx context.Context, projectID string) error {
+	p, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("get project: %w", err)
+	}
+	u, err := s.usage(ctx, tenantOf(p), false)
+	if err != nil {
+		return err
+	}
+	if err := u.Check(tenant.ResourceConcurrentRuns); err != nil {
+		return err
+	}
+	return u.Check(tenant.ResourceMonthlyTokens)
+}
+
+// AdmitProject checks that a tenant may create another project.
+func (s *TenantService) AdmitProject(ctx context.Context, tenantID string) error {
+	u, err := s.usage(ctx, tenantID, false)
+	if err != nil {
+		return err
+	}
+	return u.Check(tenant.ResourceProjects)
+}
+
+// AdmitStorage checks that the tenant owning a project is below its
+// storage limit before the project's repository is cloned.
+func (s *TenantService) AdmitStorage(ctx context.Context, p *project.Project) error {
+	u, err := s.usage(ctx, tenantOf(p), true)
+	if err != nil {
+		return err
+	}
+	return u.Check(tenant.ResourceStorage)
+}
+
+// usage loads a tenant's quota and counted usage, measuring storage only
+// if asked to since that walks the workspaces.
+func (s *TenantService) usage(ctx context.Context, id string, withStorage bool) (*tenant.Usage, error) {
+	t, err := s.store.GetTenant(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get tenant: %w", err)
+	}
+	u, err := s.store.GetTenantUsage(ctx, id, tenant.MonthStart(s.now()))
+	if err != nil {
+		return nil, err
+	}
+	u.Quota = t.Quota
+	if withStorage {
+		if u.StorageMB, err = s.storageMB(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+// storageMB sums the sizes of the cloned workspaces of a tenant's projects.
+func (s *TenantService) storageMB(ctx context.Context, id string) (int64, error) {
+	projects, err := s.store.ListProjects(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list projects: %w", err)
+	}
+	var total int64
+	for i := range projects {
+		p := &projects[i]
+		if tenantOf(p) != id || p.WorkspacePath == "" {
+			continue
+		}
+		n, err := dirSize(p.WorkspacePath)
+		if err != nil {
+			return 0, fmt.Errorf("measure workspace of project %s: %w", p.ID, err)
+		}
+		total += n
+	}
+	return (total + 1<<20 - 1) >> 20, nil
+}
+
+// dirSize returns the total size of the regular files below dir. Files
+// removed while walking, or a missing dir, count as zero.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
+// tenantOf returns the tenant owning a project.
+func tenantOf(p *project.Project) string {
+	if p.TenantID == "" {
+		return tenant.DefaultID
+	}
+	return p.TenantID
+}
@@ -0,0 +1,104 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/tenant"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestStartRun_TenantQuota(t *testing.T) {
+	svc, store, _, _ := newRuntimeTestEnv()
+	store.tenants = []tenant.Tenant{{ID: tenant.DefaultID, Quota: tenant.Quota{MaxConcurrentRuns: 1}}}
+	tenants := service.NewTenantService(store)
+	svc.SetTenantService(tenants)
+	ctx := context.Background()
+	req := func() *run.StartRequest {
+		return &run.StartRequest{TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1"}
+	}
+
+	if _, err := svc.StartRun(ctx, req()); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	_, err := svc.StartRun(ctx, req())
+	var qe *tenant.QuotaError
+	if !errors.As(err, &qe) || qe.Resource != tenant.ResourceConcurrentRuns || !qe.Retryable() {
+		t.Fatalf("expected concurrent run quota error, got %v", err)
+	}
+	if len(store.runs) != 1 {
+		t.Fatalf("denied run must not be created, have %d runs", len(store.runs))
+	}
+
+	// The finished run frees its slot, but used up the token budget.
+	store.runs[0].Status = run.StatusCompleted
+	store.runs[0].TokensIn, store.runs[0].TokensOut = 60, 40
+	if _, err := tenants.SetQuota(ctx, tenant.DefaultID, tenant.Quota{MaxConcurrentRuns: 1, MonthlyTokenBudget: 100}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = svc.StartRun(ctx, req())
+	if !errors.As(err, &qe) || qe.Resource != tenant.ResourceMonthlyTokens || qe.Used != 100 {
+		t.Fatalf("expected token budget quota error, got %v", err)
+	}
+
+	u, err := tenants.Usage(ctx, tenant.DefaultID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.ConcurrentRuns != 0 || u.Projects != 1 || !slices.Equal(u.Exceeded, []tenant.Resource{tenant.ResourceMonthlyTokens}) {
+		t.Fatalf("unexpected usage %+v", u)
+	}
+}
+
+func TestTenantService_StorageQuota(t *testing.T) {
+	_, store, _, _ := newRuntimeTestEnv()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "blob"), make([]byte, 1<<20+1), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store.projects[0].WorkspacePath = dir
+	store.projects[0].RepoURL = "https://example.com/repo.git"
+	store.tenants = []tenant.Tenant{{ID: tenant.DefaultID, Quota: tenant.Quota{MaxStorageMB: 2}}}
+	tenants := service.NewTenantService(store)
+	projects := service.NewProjectService(store)
+	projects.SetTenantService(tenants)
+	ctx := context.Background()
+
+	u, err := tenants.Usage(ctx, tenant.DefaultID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.StorageMB != 2 || !slices.Equal(u.Exceeded, []tenant.Resource{tenant.ResourceStorage}) {
+		t.Fatalf("unexpected usage %+v", u)
+	}
+	if _, err := projects.Clone(ctx, "proj-1"); !errors.Is(err, domain.ErrQuotaExceeded) {
+		t.Fatalf("expected clone to be denied by the storage quota, got %v", err)
+	}
+	if _, err := projects.Create(ctx, project.CreateRequest{Name: "x", TenantID: "missing"}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected unknown tenant to fail, got %v", err)
+	}
+}
+
+func TestProjectService_CreateInScopedTenant(t *testing.T) {
+	_, store, _, _ := newRuntimeTestEnv()
+	projects := service.NewProjectService(store)
+	ctx := tenant.NewContext(context.Background(), "acme")
+
+	p, err := projects.Create(ctx, project.CreateRequest{Name: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.TenantID != "acme" {
+		t.Fatalf("expected the project to belong to the scoped tenant, got %q", p.TenantID)
+	}
+	if _, err := projects.Create(ctx, project.CreateRequest{Name: "y", TenantID: "other"}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected another tenant to be invisible, got %v", err)
+	}
+}
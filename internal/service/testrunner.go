@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/artifact"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/testreport"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+)
+
+var (
+	// ErrNoTestCommand is returned when no test command is configured or
+	// detected for a run's workspace.
+	ErrNoTestCommand = errors.New("tests: no test command configured or detected")
+	// ErrTestsInProgress is returned when a run's quality gate is still
+	// executing its tests.
+	ErrTestsInProgress = errors.New("tests: quality gate of the run is in progress")
+)
+
+// Stack is a toolchain detected in a workspace directory.
+type Stack struct {
+	Name        string `json:"name"`   // "go", "python" or "node"
+	Marker      string `json:"marker"` // File that identified the stack
+	TestCommand string `json:"test_command"`
+}
+
+// stackMarkers lists the files identifying a stack, in detection order.
+var stackMarkers = []Stack{
+	{Name: "go", Marker: "go.mod", TestCommand: "go test -json ./..."},
+	{Name: "python", Marker: "pyproject.toml", TestCommand: "pytest -rA"},
+	{Name: "python", Marker: "pytest.ini", TestCommand: "pytest -rA"},
+	{Name: "python", Marker: "setup.py", TestCommand: "pytest -rA"},
+	{Name: "python", Marker: "conftest.py", TestCommand: "pytest -rA"},
+	{Name: "node", Marker: "package.json", TestCommand: "npm test --silent"},
+}
+
+// DetectStack returns the toolchains found in dir, one per stack, in
+// detection order. A package.json only counts if it defines a test script.
+func DetectStack(dir string) []Stack {
+	var stacks []Stack
+	seen := make(map[string]bool)
+	for _, st := range stackMarkers {
+		if seen[st.Name] {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, st.Marker))
+		if err != nil {
+			continue
+		}
+		if st.Name == "node" {
+			var pkg struct {
+				Scripts map[string]string `json:"scripts"`
+			}
+			if json.Unmarshal(data, &pkg) != nil || pkg.Scripts["test"] == "" {
+				continue
+			}
+		}
+		seen[st.Name] = true
+		stacks = append(stacks, st)
+	}
+	return stacks
+}
+
+// TestPlan describes how the tests of a run are executed.
+type TestPlan struct {
+	Dir        string // Directory the command runs in
+	Command    string
+	ReportPath string // JUnit XML written by the command, relative to Dir
+}
+
+// resolveTestPlan determines the test command of a run: the command of its
+// sub-project, the project's test_command config, the configured default,
+// and finally the command of the stack detected in the test directory.
+func resolveTestPlan(ctx context.Context, store database.Store, cfg *config.Runtime, r *run.Run) TestPlan {
+	plan := TestPlan{Dir: r.WorktreePath, Command: cfg.DefaultTestCommand}
+	proj, err := store.GetProject(ctx, r.ProjectID)
+	if err != nil {
+		proj = nil
+	} else {
+		plan.Dir = r.Workspace(proj.WorkspacePath)
+	}
+	var spCommand string
+	if sp := runScope(ctx, store, r); sp != nil {
+		plan.Dir = filepath.Join(plan.Dir, filepath.FromSlash(sp.Path))
+		spCommand = sp.TestCommand
+	}
+
+	switch {
+	case spCommand != "":
+		plan.Command = spCommand
+	case proj != nil && proj.Config[testreport.ConfigKeyCommand] != "":
+		plan.Command = proj.Config[testreport.ConfigKeyCommand]
+	case plan.Command == "" && plan.Dir != "":
+		if stacks := DetectStack(plan.Dir); len(stacks) > 0 {
+			plan.Command = stacks[0].TestCommand
+		}
+	}
+	if proj != nil {
+		plan.ReportPath = proj.Config[testreport.ConfigKeyReportPath]
+	}
+	return plan
+}
+
+// TestRunnerService runs the tests of finished runs through the quality
+// gate worker and stores the parsed results as run artifacts.
+type TestRunnerService struct {
+	store      database.Store
+	queue      messagequeue.Queue
+	runtimeCfg *config.Runtime
+}
+
+// NewTestRunnerService creates a TestRunnerService.
+func NewTestRunnerService(store database.Store, queue messagequeue.Queue, runtimeCfg *config.Runtime) *TestRunnerService {
+	return &TestRunnerService{store: store, queue: queue, runtimeCfg: runtimeCfg}
+}
+
+// Plan returns how the tests of r are executed.
+func (s *TestRunnerService) Plan(ctx context.Context, r *run.Run) TestPlan {
+	return resolveTestPlan(ctx, s.store, s.runtimeCfg, r)
+}
+
+// Run asks the worker to execute the tests of a run in its workspace. The
+// result arrives as a quality gate result and is stored by Record.
+func (s *TestRunnerService) Run(ctx context.Context, runID string) (*TestPlan, error) {
+	r, err := s.store.GetRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	if r.Status == run.StatusQualityGate {
+		return nil, ErrTestsInProgress
+	}
+	plan := s.Plan(ctx, r)
+	if plan.Command == "" {
+		return nil, ErrNoTestCommand
+	}
+	data, err := json.Marshal(messagequeue.QualityGateRequestPayload{
+		RunID:          r.ID,
+		ProjectID:      r.ProjectID,
+		WorkspacePath:  plan.Dir,
+		RunTests:       true,
+		TestCommand:    plan.Command,
+		TestReportPath: plan.ReportPath,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal test request: %w", err)
+	}
+	if err := s.queue.Publish(ctx, messagequeue.SubjectQualityGateRequest, data); err != nil {
+		return nil, fmt.Errorf("publish test request: %w", err)
+	}
+	slog.Info("tests requested", "run_id", r.ID, "command", plan.Command)
+	return &plan, nil
+}
+
+// Record parses the test output of a quality gate result and stores it as
+// the test report of the run. It returns nil if the result has no tests.
+func (s *TestRunnerService) Record(ctx context.Context, r *run.Run, result *messagequeue.QualityGateResultPayload) (*testreport.Report, error) {
+	if result.TestsPassed == nil {
+		return nil, nil
+	}
+	rep := testreport.Parse(result.TestOutput, result.TestReport, *result.TestsPassed)
+	rep.RunID = r.ID
+	rep.ProjectID = r.ProjectID
+	rep.Command = s.Plan(ctx, r).Command
+	rep.CreatedAt = time.Now().UTC()
+
+	data, err := json.Marshal(rep)
+	if err != nil {
+		return nil, fmt.Errorf("marshal test report: %w", err)
+	}
+	art := &artifact.Artifact{
+		RunID:       r.ID,
+		ProjectID:   r.ProjectID,
+		Kind:        artifact.KindTestReport,
+		Name:        "test-report.json",
+		ContentType: "application/json",
+		Data:        data,
+		Metadata: map[string]string{
+			"format": string(rep.Format),
+			"passed": strconv.FormatBool(rep.Passed),
+			"total":  strconv.Itoa(rep.Total),
+			"failed": strconv.Itoa(rep.Failed),
+		},
+	}
+	if err := s.store.CreateArtifact(ctx, art); err != nil {
+		return nil, fmt.Errorf("store test report: %w", err)
+	}
+	return rep, nil
+}
+
+// Get returns the latest test report of a run.
+func (s *TestRunnerService) Get(ctx context.Context, runID string) (*testreport.Report, error) {
+	return latestTestReport(ctx, s.store, runID)
+}
+
+// latestTestReport loads the most recent test report artifact of a run.
+func latestTestReport(ctx context.Context, store database.Store, runID string) (*testreport.Report, error) {
+	arts, err := store.ListArtifactsByRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(arts) - 1; i >= 0; i-- {
+		if arts[i].Kind != artifact.KindTestReport {
+			continue
+		}
+		full, err := store.GetArtifact(ctx, arts[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		var rep testreport.Report
+		if err := json.Unmarshal(full.Data, &rep); err != nil {
+			return nil, fmt.Errorf("decode test report %s: %w", full.ID, err)
+		}
+		return &rep, nil
+	}
+	return nil, fmt.Errorf("test report of run %s: %w", runID, domain.ErrNotFound)
+}
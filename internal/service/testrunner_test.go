@@ -0,0 +1,137 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/review"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/testreport"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestDetectStack(t *testing.T) {
+	dir := writeWorkspace(t, map[string]string{
+		"go.mod":         "module example.com/app\n",
+		"pyproject.toml": "[project]\nname = \"x\"\n",
+		"setup.py":       "",
+		"package.json":   `{"scripts": {"build": "vite build"}}`,
+	})
+	stacks := service.DetectStack(dir)
+	if len(stacks) != 2 || stacks[0].Name != "go" || stacks[1].Marker != "pyproject.toml" {
+		t.Fatalf("unexpected stacks %+v", stacks)
+	}
+
+	web := writeWorkspace(t, map[string]string{"package.json": `{"scripts": {"test": "vitest run"}}`})
+	if stacks := service.DetectStack(web); len(stacks) != 1 || stacks[0].TestCommand != "npm test --silent" {
+		t.Fatalf("unexpected stacks %+v", stacks)
+	}
+}
+
+func TestTestRunner_RunAndRecord(t *testing.T) {
+	dir := writeWorkspace(t, map[string]string{"services/api/go.mod": "module example.com/api\n"})
+	_, store, queue, _ := newRuntimeTestEnv()
+	store.projects[0].WorkspacePath = dir
+	store.projects[0].Config = map[string]string{testreport.ConfigKeyReportPath: "report.xml"}
+	store.subProjects = []project.SubProject{{ID: "sub-api", ProjectID: "proj-1", Name: "api", Path: "services/api"}}
+	store.tasks[0].SubProjectID = "sub-api"
+	store.runs = append(store.runs, run.Run{ID: "run-1", TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", Status: run.StatusCompleted})
+
+	cfg := &config.Runtime{}
+	tests := service.NewTestRunnerService(store, queue, cfg)
+	runtimeSvc := service.NewRuntimeService(store, queue, &runtimeMockBroadcaster{}, &runtimeMockEventStore{},
+		service.NewPolicyService("headless-safe-sandbox", nil), cfg)
+	runtimeSvc.SetTestRunner(tests)
+	ctx := context.Background()
+
+	plan, err := tests.Run(ctx, "run-1")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	msg, ok := queue.lastMessage(messagequeue.SubjectQualityGateRequest)
+	if !ok {
+		t.Fatal("expected quality gate request")
+	}
+	var req messagequeue.QualityGateRequestPayload
+	_ = json.Unmarshal(msg.Data, &req)
+	if req.TestCommand != "go test -json ./..." || req.WorkspacePath != filepath.Join(dir, "services", "api") ||
+		!req.RunTests || req.RunLint || req.TestReportPath != "report.xml" || plan.Command != req.TestCommand {
+		t.Fatalf("unexpected test request %+v", req)
+	}
+
+	if _, err := tests.Get(ctx, "run-1"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before results, got %v", err)
+	}
+	failed := false
+	err = runtimeSvc.HandleQualityGateResult(ctx, &messagequeue.QualityGateResultPayload{
+		RunID:       "run-1",
+		TestsPassed: &failed,
+		TestOutput:  "--- FAIL: TestLogin (0.01s)\n    login_test.go:8: bad password accepted\nFAIL\nFAIL\texample.com/api\t0.02s\n",
+	})
+	if err != nil {
+		t.Fatalf("HandleQualityGateResult failed: %v", err)
+	}
+	rep, err := tests.Get(ctx, "run-1")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if rep.Passed || rep.Failed != 1 || rep.Format != testreport.FormatGo || rep.Command != "go test -json ./..." ||
+		rep.Cases[0].Message != "login_test.go:8: bad password accepted" {
+		t.Fatalf("unexpected report %+v", rep)
+	}
+	if r, _ := store.GetRun(ctx, "run-1"); r.Status != run.StatusCompleted {
+		t.Fatalf("ad-hoc test results must not change the run, got %s", r.Status)
+	}
+
+	store.runs[len(store.runs)-1].Status = run.StatusQualityGate
+	if _, err := tests.Run(ctx, "run-1"); !errors.Is(err, service.ErrTestsInProgress) {
+		t.Fatalf("expected ErrTestsInProgress, got %v", err)
+	}
+}
+
+func TestTestRunner_NoCommand(t *testing.T) {
+	_, store, queue, _ := newRuntimeTestEnv()
+	store.projects[0].WorkspacePath = t.TempDir()
+	store.runs = append(store.runs, run.Run{ID: "run-1", TaskID: "task-1", ProjectID: "proj-1", Status: run.StatusCompleted})
+	tests := service.NewTestRunnerService(store, queue, &config.Runtime{})
+	if _, err := tests.Run(context.Background(), "run-1"); !errors.Is(err, service.ErrNoTestCommand) {
+		t.Fatalf("expected ErrNoTestCommand, got %v", err)
+	}
+}
+
+func TestReviewService_PublishRequiresTests(t *testing.T) {
+	store, svc := newReviewTestEnv(t)
+	ctx := context.Background()
+	tests := service.NewTestRunnerService(store, &runtimeMockQueue{}, &config.Runtime{})
+	if _, err := svc.Record(ctx, "review-1", &review.Review{Summary: "LGTM", Approved: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := svc.Publish(ctx, "review-1", &review.PublishRequest{PRNumber: 3, RequireTests: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != "failure" || res.TestsPassed == nil || *res.TestsPassed {
+		t.Fatalf("expected failure without test results, got %+v", res)
+	}
+
+	r, _ := store.GetRun(ctx, "review-1")
+	passed := true
+	if _, err := tests.Record(ctx, r, &messagequeue.QualityGateResultPayload{RunID: r.ID, TestsPassed: &passed, TestOutput: "ok  \texample.com/app\t0.1s\n"}); err != nil {
+		t.Fatal(err)
+	}
+	res, err = svc.Publish(ctx, "review-1", &review.PublishRequest{PRNumber: 3, RequireTests: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != "success" || !*res.TestsPassed {
+		t.Fatalf("expected success with passing tests, got %+v", res)
+	}
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
+	"github.com/Strob0t/CodeForge/internal/adapter/sentencepiece"
+	"github.com/Strob0t/CodeForge/internal/adapter/tiktoken"
+	"github.com/Strob0t/CodeForge/internal/config"
+	cfcontext "github.com/Strob0t/CodeForge/internal/domain/context"
+	"github.com/Strob0t/CodeForge/internal/port/tokenizer"
+)
+
+// heuristicTokenizer counts with cfcontext.EstimateTokens. It is used for
+// models without a tokenizer file.
+type heuristicTokenizer struct{}
+
+func (heuristicTokenizer) Name() string          { return "heuristic" }
+func (heuristicTokenizer) Count(text string) int { return cfcontext.EstimateTokens(text) }
+
+// TokenizerService counts tokens with the tokenizer of a model's family
+// and tracks how close the counts come to the prompt tokens LiteLLM
+// reports, next to the character heuristic.
+type TokenizerService struct {
+	cfg *config.Tokenizers
+	llm *litellm.Client
+
+	mu     sync.Mutex
+	loaded map[string]tokenizer.Tokenizer // By file name; heuristic if loading failed
+	stats  map[string]*cfcontext.TokenAccuracy
+}
+
+// NewTokenizerService creates a TokenizerService and registers it as the
+// usage observer of llm. llm may be nil, in which case nothing is observed
+// and probes are unavailable.
+func NewTokenizerService(cfg *config.Tokenizers, llm *litellm.Client) *TokenizerService {
+	s := &TokenizerService{
+		cfg:    cfg,
+		llm:    llm,
+		loaded: make(map[string]tokenizer.Tokenizer),
+		stats:  make(map[string]*cfcontext.TokenAccuracy),
+	}
+	if llm != nil {
+		llm.SetUsageObserver(s.Observe)
+	}
+	return s
+}
+
+// For returns the tokenizer of a model; "" selects the default model.
+// Tokenizer files are loaded on first use.
+func (s *TokenizerService) For(model string) tokenizer.Tokenizer {
+	if model == "" {
+		model = s.cfg.DefaultModel
+	}
+	f := s.family(model)
+	if f == nil {
+		return heuristicTokenizer{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.loaded[f.File]; ok {
+		return t
+	}
+	t, err := loadTokenizer(filepath.Join(s.cfg.Dir, f.File), f)
+	if err != nil {
+		slog.Warn("tokenizer unavailable, using heuristic", "model", model, "file", f.File, "error", err)
+		t = heuristicTokenizer{}
+	}
+	s.loaded[f.File] = t
+	return t
+}
+
+// Count returns the tokens of text for a model; "" selects the default model.
+func (s *TokenizerService) Count(model, text string) int {
+	return s.For(model).Count(text)
+}
+
+// family returns the first family with a prefix of the model name, with
+// or without its provider.
+func (s *TokenizerService) family(model string) *config.TokenizerFamily {
+	bare := model
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		bare = model[i+1:]
+	}
+	for i := range s.cfg.Families {
+		for _, p := range s.cfg.Families[i].Prefixes {
+			if strings.HasPrefix(bare, p) || strings.HasPrefix(model, p) {
+				return &s.cfg.Families[i]
+			}
+		}
+	}
+	return nil
+}
+
+func loadTokenizer(path string, f *config.TokenizerFamily) (tokenizer.Tokenizer, error) {
+	switch f.Kind {
+	case "tiktoken":
+		return tiktoken.Load(path, f.Pattern)
+	case "sentencepiece":
+		return sentencepiece.Load(path)
+	}
+	return nil, fmt.Errorf("unknown tokenizer kind %q", f.Kind)
+}
+
+// Observe records the prompt tokens LiteLLM reported for a chat completion
+// against the heuristic and tokenizer counts of its messages.
+func (s *TokenizerService) Observe(req *litellm.ChatCompletionRequest, resp *litellm.ChatCompletionResponse) {
+	sample := s.measure(req, resp)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.stats[req.Model]
+	if !ok {
+		a = &cfcontext.TokenAccuracy{Model: req.Model}
+		s.stats[req.Model] = a
+	}
+	a.Tokenizer = sample.Tokenizer
+	a.Add(sample.Actual, sample.Heuristic, sample.Counted)
+}
+
+// measure compares a single completion's prompt tokens against the counts
+// of its messages, including the chat formatting overhead.
+func (s *TokenizerService) measure(req *litellm.ChatCompletionRequest, resp *litellm.ChatCompletionResponse) cfcontext.TokenAccuracy {
+	t := s.For(req.Model)
+	var heuristic, counted int
+	for _, m := range req.Messages {
+		heuristic += cfcontext.EstimateTokens(m.Content)
+		counted += t.Count(m.Content)
+	}
+	sample := cfcontext.TokenAccuracy{Model: req.Model, Tokenizer: t.Name()}
+	sample.Add(resp.TokensIn,
+		cfcontext.ChatTokens(heuristic, len(req.Messages)),
+		cfcontext.ChatTokens(counted, len(req.Messages)))
+	return sample
+}
+
+// Diagnostics returns the accuracy of the heuristic and the tokenizer per
+// observed model, sorted by model.
+func (s *TokenizerService) Diagnostics() []cfcontext.TokenAccuracy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]cfcontext.TokenAccuracy, 0, len(s.stats))
+	for _, a := range s.stats {
+		out = append(out, *a)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Model < out[j].Model })
+	return out
+}
+
+// Probe sends text as a single-token completion to model, bypassing the
+// response cache, and returns the comparison for this call. Like every
+// completion, the probe is included in the diagnostics.
+func (s *TokenizerService) Probe(ctx context.Context, model, text string) (*cfcontext.TokenAccuracy, error) {
+	if s.llm == nil {
+		return nil, errors.New("no LLM client configured")
+	}
+	if model == "" {
+		model = s.cfg.DefaultModel
+	}
+	if text == "" {
+		return nil, errors.New("text is required")
+	}
+	req := litellm.ChatCompletionRequest{
+		Model:     model,
+		Messages:  []litellm.ChatMessage{{Role: "user", Content: text}},
+		MaxTokens: 1,
+	}
+	resp, err := s.llm.ChatCompletion(litellm.WithCacheBypass(ctx), req)
+	if err != nil {
+		return nil, fmt.Errorf("probe %s: %w", model, err)
+	}
+	sample := s.measure(&req, resp)
+	return &sample, nil
+}
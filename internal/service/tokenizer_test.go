@@ -0,0 +1,136 @@
+package service_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/litellm"
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/codegraph"
+	cfcontext "github.com/Strob0t/CodeForge/internal/domain/context"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+// newTestTokenizerService writes a small tiktoken rank file in which
+// "hello" and " hello" are single tokens and serves it for gpt-4o models.
+// llama models point at a missing file.
+func newTestTokenizerService(t *testing.T) *service.TokenizerService {
+	t.Helper()
+	dir := t.TempDir()
+	var b strings.Builder
+	tokens := []string{"he", "ll", "hell", "hello", " hello"}
+	for i := range 256 {
+		tokens = append(tokens, string([]byte{byte(i)}))
+	}
+	for rank, tok := range tokens {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(tok)), rank)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "test.tiktoken"), []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return service.NewTokenizerService(&config.Tokenizers{
+		Dir:          dir,
+		DefaultModel: "openai/gpt-4o-mini",
+		Families: []config.TokenizerFamily{
+			{Prefixes: []string{"gpt-4o"}, Kind: "tiktoken", File: "test.tiktoken"},
+			{Prefixes: []string{"llama"}, Kind: "sentencepiece", File: "missing.model"},
+		},
+	}, nil)
+}
+
+func TestTokenizerService_For(t *testing.T) {
+	svc := newTestTokenizerService(t)
+	for model, want := range map[string]string{
+		"openai/gpt-4o-mini": "tiktoken:test",
+		"gpt-4o":             "tiktoken:test",
+		"":                   "tiktoken:test", // Default model
+		"ollama/llama3":      "heuristic",     // Tokenizer file missing
+		"anthropic/claude-3": "heuristic",     // No family
+	} {
+		if got := svc.For(model).Name(); got != want {
+			t.Errorf("For(%q) = %s, want %s", model, got, want)
+		}
+	}
+	if got := svc.Count("gpt-4o", "hello hello hello"); got != 3 {
+		t.Errorf("expected 3 tokens, got %d", got)
+	}
+}
+
+func TestTokenizerService_Observe(t *testing.T) {
+	svc := newTestTokenizerService(t)
+	req := &litellm.ChatCompletionRequest{
+		Model:    "gpt-4o",
+		Messages: []litellm.ChatMessage{{Role: "user", Content: "hello hello hello"}},
+	}
+	// Counted: 3 + 3 message + 3 reply overhead. Heuristic: 17/4 + 6.
+	svc.Observe(req, &litellm.ChatCompletionResponse{TokensIn: 9})
+	svc.Observe(req, &litellm.ChatCompletionResponse{Cached: true}) // No usage, ignored
+
+	diag := svc.Diagnostics()
+	if len(diag) != 1 {
+		t.Fatalf("expected 1 model, got %d", len(diag))
+	}
+	d := diag[0]
+	if d.Model != "gpt-4o" || d.Tokenizer != "tiktoken:test" || d.Samples != 1 {
+		t.Fatalf("unexpected diagnostics %+v", d)
+	}
+	if d.Actual != 9 || d.Counted != 9 || d.Heuristic != 10 {
+		t.Fatalf("unexpected token sums %+v", d)
+	}
+	if d.CountedError != 0 || math.Abs(d.HeuristicError-1.0/9) > 1e-9 {
+		t.Fatalf("unexpected errors %+v", d)
+	}
+}
+
+func TestBuildContextPack_CountsWithTokenizer(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "greet.txt"), []byte("hello hello hello"), 0o644)
+	store := &runtimeMockStore{
+		projects: []project.Project{{ID: "proj-1", Name: "test", WorkspacePath: dir}},
+		tasks:    []task.Task{{ID: "task-1", ProjectID: "proj-1", Prompt: "say hello"}},
+	}
+	svc := service.NewContextOptimizerService(store, &config.Orchestrator{DefaultContextBudget: 8192, PromptReserve: 1024})
+	svc.SetTokenizer(newTestTokenizerService(t))
+
+	pack, err := svc.BuildContextPack(context.Background(), "task-1", "proj-1", "")
+	if err != nil {
+		t.Fatalf("BuildContextPack failed: %v", err)
+	}
+	if pack == nil || len(pack.Entries) != 1 || pack.Entries[0].Tokens != 3 || pack.TokensUsed != 3 {
+		t.Fatalf("expected one entry of 3 tokens, got %+v", pack)
+	}
+}
+
+func TestRepoMapEntry_CountsWithTokenizer(t *testing.T) {
+	svc := service.NewContextOptimizerService(&runtimeMockStore{}, &config.Orchestrator{DefaultContextBudget: 1100, PromptReserve: 1024})
+	svc.SetTokenizer(newTestTokenizerService(t))
+	repoMap := func() *codegraph.RepoMap {
+		return &codegraph.RepoMap{Total: 2, Files: []codegraph.MapFile{
+			{Path: "a.go", Dependents: 2},
+			{Path: "b.go", Dependents: 1},
+		}}
+	}
+	scope := service.PackScope{Model: "gpt-4o"}
+
+	// The test tokenizer counts a byte per token here, so the map takes
+	// 40 tokens where the heuristic estimates 10.
+	e, ok := svc.RepoMapEntry(repoMap(), scope, nil)
+	if !ok || e.Tokens != 40 || e.Path != "repo-map" {
+		t.Fatalf("expected the whole map in 40 tokens, got %+v", e)
+	}
+	e, ok = svc.RepoMapEntry(repoMap(), scope, &cfcontext.ContextPack{TokensUsed: 38})
+	if !ok || e.Content != "a.go (2 dependents)\n... 1 more files\n" || e.Tokens != 37 {
+		t.Fatalf("expected the map cut to what the pack left, got %+v", e)
+	}
+	if _, ok := svc.RepoMapEntry(repoMap(), scope, &cfcontext.ContextPack{TokensUsed: 76}); ok {
+		t.Fatal("expected no repo map once the pack used the budget")
+	}
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/github"
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/notification"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/vcsaccount"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+)
+
+// ErrNoVCSAccount is returned when checking a project without a git host
+// token, or whose provider cannot inspect its token.
+var ErrNoVCSAccount = errors.New("project has no git host token to check")
+
+// VCSAccountService checks the git host tokens of projects: on request
+// and, on the leader, every check interval. Each check is stored as the
+// project's account health, which runs consult before they need the host,
+// and a token becoming broken or about to expire raises a notification.
+type VCSAccountService struct {
+	store   database.Store
+	secrets *SecretService
+	notify  *NotificationService
+	cfg     config.VCSAccounts
+	now     func() time.Time
+}
+
+// NewVCSAccountService creates a VCSAccountService.
+func NewVCSAccountService(store database.Store, secrets *SecretService, cfg config.VCSAccounts) *VCSAccountService {
+	return &VCSAccountService{store: store, secrets: secrets, cfg: cfg, now: time.Now}
+}
+
+// SetNotificationService sets the service account changes are notified
+// through.
+func (s *VCSAccountService) SetNotificationService(n *NotificationService) {
+	s.notify = n
+}
+
+// Get returns the latest check of a project's account.
+func (s *VCSAccountService) Get(ctx context.Context, projectID string) (*vcsaccount.Health, error) {
+	return s.store.GetVCSAccountHealth(ctx, projectID)
+}
+
+// List returns the checked accounts and the projects using them.
+func (s *VCSAccountService) List(ctx context.Context) ([]vcsaccount.Account, error) {
+	healths, err := s.store.ListVCSAccountHealth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return vcsaccount.Group(healths), nil
+}
+
+// Test checks a project's token now.
+func (s *VCSAccountService) Test(ctx context.Context, projectID string) (*vcsaccount.Health, error) {
+	p, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return s.check(ctx, p)
+}
+
+// StartChecker runs CheckAll every check interval until ctx is done or
+// cancel is called. It does nothing if the interval is 0.
+func (s *VCSAccountService) StartChecker(ctx context.Context) (cancel func()) {
+	ctx, cancel = context.WithCancel(ctx)
+	if s.cfg.CheckInterval <= 0 {
+		return cancel
+	}
+	go func() {
+		ticker := time.NewTicker(s.cfg.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.CheckAll(ctx); err != nil {
+					slog.Error("vcs account check failed", "error", err)
+				}
+			}
+		}
+	}()
+	return cancel
+}
+
+// CheckAll checks the token of every project that has one and returns how
+// many were checked. A failing project is logged and does not stop the
+// others.
+func (s *VCSAccountService) CheckAll(ctx context.Context) (int, error) {
+	projects, err := s.store.ListProjects(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list projects: %w", err)
+	}
+	checked := 0
+	for i := range projects {
+		if _, err := s.check(ctx, &projects[i]); err != nil {
+			if !errors.Is(err, ErrNoVCSAccount) {
+				slog.Error("vcs account check", "project_id", projects[i].ID, "error", err)
+			}
+			continue
+		}
+		checked++
+	}
+	return checked, nil
+}
+
+// check inspects a project's token, stores the result and notifies about
+// a change of its status. A token that cannot be resolved or fails the
+// inspection is a broken account, not an error.
+func (s *VCSAccountService) check(ctx context.Context, p *project.Project) (*vcsaccount.Health, error) {
+	secretName, installation := p.Config[gitprovider.ConfigTokenSecret], p.Config[github.ConfigInstallationID]
+	if p.Provider == "" || (secretName == "" && installation == "") {
+		return nil, ErrNoVCSAccount
+	}
+	h := &vcsaccount.Health{ProjectID: p.ID, Provider: p.Provider}
+	var info *vcsaccount.TokenInfo
+	cfg, err := hostedConfig(ctx, s.secrets, p)
+	if err == nil {
+		h.Account = accountKey(p, cfg[gitprovider.ConfigToken])
+		info, err = inspectToken(ctx, p, cfg)
+	} else {
+		h.Account = accountKey(p, "")
+	}
+	if errors.Is(err, ErrNoVCSAccount) {
+		return nil, err
+	}
+	h.Record(info, err, s.cfg.ExpiryWarning, s.now())
+
+	prev, err := s.store.GetVCSAccountHealth(ctx, p.ID)
+	if err != nil {
+		prev = nil
+	}
+	if err := s.store.SaveVCSAccountHealth(ctx, h); err != nil {
+		return nil, err
+	}
+	if event := vcsaccount.Transition(prev, h); event != "" && s.notify != nil {
+		s.notify.Notify(ctx, accountMessage(event, p, h))
+	}
+	return h, nil
+}
+
+// inspectToken asks the project's git host about the resolved token.
+func inspectToken(ctx context.Context, p *project.Project, cfg map[string]string) (*vcsaccount.TokenInfo, error) {
+	prov, err := gitprovider.New(p.Provider, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("create %s provider: %w", p.Provider, err)
+	}
+	inspector, ok := prov.(gitprovider.TokenInspector)
+	if !ok {
+		return nil, fmt.Errorf("%w: provider %s cannot inspect tokens", ErrNoVCSAccount, p.Provider)
+	}
+	if cfg[gitprovider.ConfigToken] == "" {
+		return nil, errors.New("the token resolved to an empty value")
+	}
+	return inspector.InspectToken(ctx)
+}
+
+// accountKey identifies the account of a project's token: the provider and
+// a fingerprint of the token, so projects sharing a token share an
+// account. GitHub App tokens change hourly and are identified by their
+// installation instead, as are tokens that could not be resolved by the
+// secret naming them.
+func accountKey(p *project.Project, token string) string {
+	switch {
+	case p.Config[gitprovider.ConfigTokenSecret] == "" && p.Config[github.ConfigInstallationID] != "":
+		return p.Provider + ":installation:" + p.Config[github.ConfigInstallationID]
+	case token == "":
+		return p.Provider + ":secret:" + p.Config[gitprovider.ConfigTokenSecret]
+	}
+	sum := sha256.Sum256([]byte(token))
+	return p.Provider + ":" + hex.EncodeToString(sum[:6])
+}
+
+// accountMessage is the notification of an account status change.
+func accountMessage(event string, p *project.Project, h *vcsaccount.Health) *notification.Message {
+	msg := &notification.Message{EventType: event, ProjectID: p.ID, Text: h.Error}
+	switch event {
+	case vcsaccount.EventBroken:
+		msg.Priority = notification.PriorityHigh
+		msg.Title = "Git host token of " + p.Name + " is broken"
+	case vcsaccount.EventExpiring:
+		msg.Priority = notification.PriorityNormal
+		msg.Title = "Git host token of " + p.Name + " expires on " + h.ExpiresAt.UTC().Format(time.DateOnly)
+		msg.Text = "Renew the token before it expires; clones and deliveries of the project will fail after."
+	default:
+		msg.Priority = notification.PriorityLow
+		msg.Title = "Git host token of " + p.Name + " works again"
+	}
+	return msg
+}
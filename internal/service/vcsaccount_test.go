@@ -0,0 +1,137 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/adapter/github"
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/notification"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/secret"
+	"github.com/Strob0t/CodeForge/internal/domain/vcsaccount"
+	"github.com/Strob0t/CodeForge/internal/port/gitprovider"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestVCSAccountChecker(t *testing.T) {
+	ctx := context.Background()
+	// The token expires in three days, then is revoked, then replaced.
+	var revoked atomic.Bool
+	expires := time.Now().Add(72 * time.Hour).UTC().Format("2006-01-02 15:04:05 MST")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if revoked.Load() {
+			http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("GitHub-Authentication-Token-Expiration", expires)
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		if r.URL.Path == "/user" {
+			_, _ = w.Write([]byte(`{"login":"octocat"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"permissions":{"push":true}}`))
+	}))
+	defer srv.Close()
+
+	runtimeSvc, store, _, _ := newRuntimeTestEnv()
+	store.projects[0].Provider = "github"
+	store.projects[0].Config = map[string]string{
+		github.ConfigRepo:             "acme/webapp",
+		github.ConfigAPIURL:           srv.URL,
+		gitprovider.ConfigTokenSecret: "GITHUB_TOKEN",
+	}
+	secrets := newTestSecretService(t, store)
+	if _, err := secrets.Create(ctx, "proj-1", &secret.CreateRequest{Name: "GITHUB_TOKEN", Value: "ghp_test"}); err != nil {
+		t.Fatal(err)
+	}
+	notifySvc := service.NewNotificationService(store, config.Notify{SendInterval: time.Second, SendTimeout: time.Second, MaxAttempts: 3})
+	if _, err := notifySvc.CreateRule(ctx, "proj-1", &notification.CreateRuleRequest{
+		Name:   "accounts",
+		Events: []string{"vcs.account.*"},
+		Kind:   notification.KindSlack,
+		Config: map[string]string{notification.ConfigWebhookURL: "https://hooks.slack.test/" + t.Name()},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	svc := service.NewVCSAccountService(store, secrets, config.VCSAccounts{CheckInterval: time.Hour, ExpiryWarning: 7 * 24 * time.Hour})
+	svc.SetNotificationService(notifySvc)
+
+	events := func() []string {
+		var out []string
+		for i := range store.outbox {
+			out = append(out, store.outbox[i].Message.EventType)
+		}
+		return out
+	}
+
+	if n, err := svc.CheckAll(ctx); err != nil || n != 1 {
+		t.Fatalf("CheckAll = %d, %v", n, err)
+	}
+	h, err := svc.Get(ctx, "proj-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Status != vcsaccount.StatusExpiring || !h.CanPush || h.Login != "octocat" || h.RateRemaining != 4999 || h.ExpiresAt == nil {
+		t.Fatalf("unexpected health %+v", h)
+	}
+	if got := events(); len(got) != 1 || got[0] != vcsaccount.EventExpiring {
+		t.Fatalf("expected an expiring notification, got %v", got)
+	}
+
+	// Checking again does not notify again.
+	if _, err := svc.Test(ctx, "proj-1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := events(); len(got) != 1 {
+		t.Fatalf("expected no repeated notification, got %v", got)
+	}
+
+	revoked.Store(true)
+	if h, err = svc.Test(ctx, "proj-1"); err != nil || h.Status != vcsaccount.StatusBroken || h.Error == "" {
+		t.Fatalf("Test = %+v, %v", h, err)
+	}
+	if got := events(); len(got) != 2 || got[1] != vcsaccount.EventBroken {
+		t.Fatalf("expected a broken notification, got %v", got)
+	}
+	accounts, err := svc.List(ctx)
+	if err != nil || len(accounts) != 1 || accounts[0].Status != vcsaccount.StatusBroken || len(accounts[0].Projects) != 1 {
+		t.Fatalf("List = %+v, %v", accounts, err)
+	}
+
+	// Runs that deliver to the host are refused before they start; local
+	// runs are not.
+	_, err = runtimeSvc.StartRun(ctx, &run.StartRequest{TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", DeliverMode: run.DeliverModePR})
+	if !errors.Is(err, vcsaccount.ErrBroken) {
+		t.Fatalf("expected ErrBroken starting a PR run, got %v", err)
+	}
+	if _, err := runtimeSvc.StartRun(ctx, &run.StartRequest{TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1"}); err != nil {
+		t.Fatalf("StartRun without delivery failed: %v", err)
+	}
+
+	revoked.Store(false)
+	expires = time.Now().Add(90 * 24 * time.Hour).UTC().Format("2006-01-02 15:04:05 MST")
+	if h, err = svc.Test(ctx, "proj-1"); err != nil || h.Status != vcsaccount.StatusOK {
+		t.Fatalf("Test = %+v, %v", h, err)
+	}
+	if got := events(); len(got) != 3 || got[2] != vcsaccount.EventRecovered {
+		t.Fatalf("expected a recovered notification, got %v", got)
+	}
+}
+
+func TestVCSAccountChecker_SkipsProjectsWithoutToken(t *testing.T) {
+	_, store, _, _ := newRuntimeTestEnv()
+	svc := service.NewVCSAccountService(store, newTestSecretService(t, store), config.VCSAccounts{})
+	if n, err := svc.CheckAll(context.Background()); err != nil || n != 0 {
+		t.Fatalf("CheckAll = %d, %v", n, err)
+	}
+	if _, err := svc.Test(context.Background(), "proj-1"); !errors.Is(err, service.ErrNoVCSAccount) {
+		t.Fatalf("expected ErrNoVCSAccount, got %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+)
+
+// WorkerDedupeBucket is the key-value bucket of the idempotency keys of
+// applied worker messages.
+const WorkerDedupeBucket = "codeforge_worker_results"
+
+// WorkerDedupeStats is a point-in-time view of the worker dedupe counters.
+type WorkerDedupeStats struct {
+	Enabled    bool  `json:"enabled"`
+	Applied    int64 `json:"applied"`    // Keyed messages applied once
+	Duplicates int64 `json:"duplicates"` // Copies dropped because their key was claimed
+	FailOpen   int64 `json:"fail_open"`  // Messages applied unchecked because the bucket failed
+}
+
+// workerDedupeLease is how long a claimed key stays pending without being
+// renewed. The consumer applying a message renews it every half lease, so
+// the key of a consumer that crashed can be claimed again when the message
+// is redelivered after the ack wait.
+const workerDedupeLease = 20 * time.Second
+
+// errWorkerMessageInFlight is returned for a copy of a message another
+// consumer is applying; the copy is redelivered later and then dropped if
+// the other consumer succeeded.
+var errWorkerMessageInFlight = errors.New("worker message is being applied by another consumer")
+
+// dedupeEntry is the value of an idempotency key: pending, owned by the
+// consumer applying the message until its lease expires, or done.
+type dedupeEntry struct {
+	Done  bool      `json:"done,omitempty"`
+	Owner string    `json:"owner,omitempty"`
+	Until time.Time `json:"until,omitzero"`
+}
+
+// WorkerDedupe applies each worker result message once. Workers
+// give every message an idempotency key that stays the same when they
+// retry publishing it. The first consumer to receive a key claims it as
+// pending in a key-value bucket shared by all servers and marks it done
+// once the message was applied; later copies are dropped. Keys expire with
+// the bucket's TTL.
+type WorkerDedupe struct {
+	kv messagequeue.KeyValue
+
+	applied, duplicates, failOpen atomic.Int64
+}
+
+// NewWorkerDedupe creates a WorkerDedupe storing keys in kv.
+func NewWorkerDedupe(kv messagequeue.KeyValue) *WorkerDedupe {
+	return &WorkerDedupe{kv: kv}
+}
+
+// Stats returns the dedupe counters.
+func (d *WorkerDedupe) Stats() WorkerDedupeStats {
+	if d == nil {
+		return WorkerDedupeStats{}
+	}
+	return WorkerDedupeStats{
+		Enabled:    true,
+		Applied:    d.applied.Load(),
+		Duplicates: d.duplicates.Load(),
+		FailOpen:   d.failOpen.Load(),
+	}
+}
+
+// Apply calls apply unless a message with the same subject and key was
+// already applied. apply must include everything the message has to leave
+// behind, such as flushing its events: the key is only marked done once
+// apply succeeded. If apply fails the key is released, so the redelivered
+// message is applied again; a copy arriving while another consumer holds
+// the key fails and is redelivered. Messages without a key, and all
+// messages of a nil WorkerDedupe, are always applied. If the bucket is
+// unreachable the message is applied rather than lost, and counted as a
+// fail-open apply: a replay during the outage is not caught.
+func (d *WorkerDedupe) Apply(ctx context.Context, subject, key string, apply func() error) error {
+	if d == nil || key == "" {
+		return apply()
+	}
+	sum := sha256.Sum256([]byte(subject + "\x00" + key))
+	storeKey := hex.EncodeToString(sum[:])
+	owner := rand.Text()
+
+	claimed, err := d.claim(ctx, storeKey, owner)
+	switch {
+	case errors.Is(err, errWorkerMessageInFlight):
+		return err
+	case err != nil:
+		d.failOpen.Add(1)
+		slog.Warn("worker dedupe unavailable, applying message unchecked", "subject", subject, "idempotency_key", key, "error", err)
+		return apply()
+	case !claimed:
+		d.duplicates.Add(1)
+		slog.Info("duplicate worker message dropped", "subject", subject, "idempotency_key", key)
+		return nil
+	}
+
+	stop := d.renew(ctx, storeKey, owner)
+	err = apply()
+	stop()
+	bg := context.WithoutCancel(ctx)
+	if err != nil {
+		if derr := d.kv.Delete(bg, storeKey); derr != nil {
+			slog.Warn("release worker message key", "subject", subject, "error", derr)
+		}
+		return err
+	}
+	done, _ := json.Marshal(dedupeEntry{Done: true})
+	if perr := d.kv.Put(bg, storeKey, done); perr != nil {
+		// The key stays pending, so a replay after the lease is applied again.
+		slog.Warn("mark worker message applied", "subject", subject, "error", perr)
+	}
+	d.applied.Add(1)
+	return nil
+}
+
+// claim marks a key pending for owner. It reports false if the key is
+// done, and errWorkerMessageInFlight while another owner's lease runs. A
+// key whose lease expired is taken over.
+func (d *WorkerDedupe) claim(ctx context.Context, key, owner string) (bool, error) {
+	pending, err := json.Marshal(dedupeEntry{Owner: owner, Until: time.Now().Add(workerDedupeLease)})
+	if err != nil {
+		return false, err
+	}
+	err = d.kv.Create(ctx, key, pending)
+	if !errors.Is(err, messagequeue.ErrKeyExists) {
+		return err == nil, err
+	}
+	value, rev, err := d.kv.GetRevision(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if value != nil {
+		var e dedupeEntry
+		if json.Unmarshal(value, &e) != nil || e.Done {
+			return false, nil // Done, or a key of an older server version
+		}
+		if time.Now().Before(e.Until) {
+			return false, errWorkerMessageInFlight
+		}
+	}
+	err = d.kv.Update(ctx, key, pending, rev)
+	if errors.Is(err, messagequeue.ErrKeyChanged) {
+		return false, errWorkerMessageInFlight
+	}
+	return err == nil, err
+}
+
+// renew extends the lease of owner's pending key every half lease until
+// the returned function is called.
+func (d *WorkerDedupe) renew(ctx context.Context, key, owner string) (stop func()) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(workerDedupeLease / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := d.extend(ctx, key, owner); err != nil {
+					slog.Warn("renew worker message lease", "error", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-finished
+	}
+}
+
+// extend pushes the end of owner's lease on key a lease ahead.
+func (d *WorkerDedupe) extend(ctx context.Context, key, owner string) error {
+	value, rev, err := d.kv.GetRevision(ctx, key)
+	if err != nil {
+		return err
+	}
+	var e dedupeEntry
+	if value == nil || json.Unmarshal(value, &e) != nil || e.Owner != owner {
+		return errors.New("lease lost to another consumer")
+	}
+	e.Until = time.Now().Add(workerDedupeLease)
+	pending, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return d.kv.Update(ctx, key, pending, rev)
+}
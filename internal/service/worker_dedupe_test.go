@@ -0,0 +1,180 @@
+package service_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestWorkerDedupe_Apply(t *testing.T) {
+	d := service.NewWorkerDedupe(&memoryKV{})
+	ctx := context.Background()
+	applied := 0
+	apply := func() error { applied++; return nil }
+
+	_ = d.Apply(ctx, messagequeue.SubjectRunComplete, "k1", apply)
+	_ = d.Apply(ctx, messagequeue.SubjectRunComplete, "k1", apply)
+	if applied != 1 {
+		t.Fatalf("expected a replayed key to be dropped, applied %d times", applied)
+	}
+	_ = d.Apply(ctx, messagequeue.SubjectRunOutput, "k1", apply)
+	_ = d.Apply(ctx, messagequeue.SubjectRunOutput, "", apply)
+	_ = d.Apply(ctx, messagequeue.SubjectRunOutput, "", apply)
+	if applied != 4 {
+		t.Fatalf("expected keys scoped to the subject and unkeyed messages applied, applied %d times", applied)
+	}
+
+	// A failed message is applied again when it is redelivered.
+	failed := errors.New("store down")
+	if err := d.Apply(ctx, messagequeue.SubjectRunComplete, "k2", func() error { return failed }); !errors.Is(err, failed) {
+		t.Fatalf("expected the apply error, got %v", err)
+	}
+	_ = d.Apply(ctx, messagequeue.SubjectRunComplete, "k2", apply)
+	if applied != 5 {
+		t.Fatal("expected the failed message to be retried")
+	}
+
+	var none *service.WorkerDedupe
+	_ = none.Apply(ctx, messagequeue.SubjectRunComplete, "k1", apply)
+	if applied != 6 {
+		t.Fatal("expected a nil dedupe to apply every message")
+	}
+	if st := d.Stats(); !st.Enabled || st.Applied != 3 || st.Duplicates != 1 || st.FailOpen != 0 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+// dedupeKey returns the bucket key of a message's idempotency key.
+func dedupeKey(subject, key string) string {
+	sum := sha256.Sum256([]byte(subject + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestWorkerDedupe_ClaimedByCrashedConsumer(t *testing.T) {
+	kv := &memoryKV{}
+	d := service.NewWorkerDedupe(kv)
+	ctx := context.Background()
+	storeKey := dedupeKey(messagequeue.SubjectRunComplete, "k1")
+	applied := 0
+	apply := func() error { applied++; return nil }
+
+	// A consumer claimed the key and is still within its lease: the copy
+	// is neither applied nor acknowledged.
+	pending, _ := json.Marshal(map[string]any{"owner": "other", "until": time.Now().Add(time.Minute)})
+	_ = kv.Put(ctx, storeKey, pending)
+	if err := d.Apply(ctx, messagequeue.SubjectRunComplete, "k1", apply); err == nil || applied != 0 {
+		t.Fatalf("expected the copy to be redelivered later, got %v after %d applies", err, applied)
+	}
+
+	// The consumer crashed and its lease ran out: the redelivered message
+	// is applied and then counts as done.
+	pending, _ = json.Marshal(map[string]any{"owner": "other", "until": time.Now().Add(-time.Second)})
+	_ = kv.Put(ctx, storeKey, pending)
+	if err := d.Apply(ctx, messagequeue.SubjectRunComplete, "k1", apply); err != nil || applied != 1 {
+		t.Fatalf("expected the message of the crashed consumer applied, got %v after %d applies", err, applied)
+	}
+	_ = d.Apply(ctx, messagequeue.SubjectRunComplete, "k1", apply)
+	if applied != 1 || d.Stats().Duplicates != 1 {
+		t.Fatalf("expected a later copy dropped, got %d applies, %+v", applied, d.Stats())
+	}
+}
+
+func TestRuntimeService_FailedEventFlushIsRedelivered(t *testing.T) {
+	_, store, queue, bc := newRuntimeTestEnv()
+	events := &runtimeMockEventStore{batchErr: errors.New("copy failed"), failRun: "run-f1"}
+	b := service.NewEventBatcher(events, config.Events{BatchSize: 100, FlushInterval: time.Hour})
+	svc := service.NewRuntimeService(store, queue, bc, b, service.NewPolicyService("headless-safe-sandbox", nil), &config.Runtime{StallThreshold: 5})
+	svc.SetWorkerDedupe(service.NewWorkerDedupe(&memoryKV{}))
+	ctx := context.Background()
+	if _, err := svc.StartSubscribers(ctx); err != nil {
+		t.Fatal(err)
+	}
+	store.mu.Lock()
+	store.runs = append(store.runs, run.Run{ID: "run-f1", TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", Status: run.StatusRunning})
+	store.mu.Unlock()
+
+	// The result is applied but its events cannot be stored, so the
+	// message is not acknowledged and its key is released.
+	result := messagequeue.ToolCallResultPayload{RunID: "run-f1", CallID: "c1", Tool: "Read", Success: true, IdempotencyKey: "res-f1"}
+	if err := queue.deliver(ctx, messagequeue.SubjectRunToolCallResult, result); err == nil {
+		t.Fatal("expected the failed flush to fail the message")
+	}
+
+	events.mu.Lock()
+	events.failRun = ""
+	events.mu.Unlock()
+	if err := queue.deliver(ctx, messagequeue.SubjectRunToolCallResult, result); err != nil {
+		t.Fatalf("expected the redelivered message to be applied, got %v", err)
+	}
+	if evs, _ := events.LoadByRun(ctx, "run-f1"); len(evs) == 0 {
+		t.Fatal("expected the events of the redelivered message stored")
+	}
+}
+
+// unavailableKV is a key-value bucket whose every call fails.
+type unavailableKV struct{ memoryKV }
+
+func (*unavailableKV) Create(context.Context, string, []byte) error {
+	return errors.New("nats unavailable")
+}
+
+func TestWorkerDedupe_FailsOpen(t *testing.T) {
+	d := service.NewWorkerDedupe(&unavailableKV{})
+	applied := 0
+	for range 2 {
+		_ = d.Apply(context.Background(), messagequeue.SubjectRunComplete, "k1", func() error { applied++; return nil })
+	}
+	if applied != 2 || d.Stats().FailOpen != 2 {
+		t.Fatalf("expected both messages applied and counted as fail-open, got %d applied, %+v", applied, d.Stats())
+	}
+}
+
+func TestRuntimeService_ReplayedWorkerResults(t *testing.T) {
+	svc, store, queue, _ := newRuntimeTestEnv()
+	svc.SetWorkerDedupe(service.NewWorkerDedupe(&memoryKV{}))
+	ctx := context.Background()
+	if _, err := svc.StartSubscribers(ctx); err != nil {
+		t.Fatal(err)
+	}
+	store.mu.Lock()
+	store.runs = append(store.runs, run.Run{
+		ID: "run-d1", TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1",
+		PolicyProfile: "plan-readonly", Status: run.StatusRunning, StartedAt: time.Now(),
+	})
+	store.mu.Unlock()
+
+	// A tool result published twice is counted once.
+	result := messagequeue.ToolCallResultPayload{RunID: "run-d1", CallID: "c1", Tool: "Read", Success: true, CostUSD: 0.25, IdempotencyKey: "res-1"}
+	for range 2 {
+		if err := queue.deliver(ctx, messagequeue.SubjectRunToolCallResult, result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r, _ := store.GetRun(ctx, "run-d1"); r.CostUSD != 0.25 {
+		t.Fatalf("expected the cost counted once, got %v", r.CostUSD)
+	}
+
+	// A second completion, even under another key, leaves the run as the
+	// first one finished it.
+	complete := messagequeue.RunCompletePayload{RunID: "run-d1", TaskID: "task-1", ProjectID: "proj-1", Status: "completed", Output: "done", CostUSD: 0.25, StepCount: 1, IdempotencyKey: "done-1"}
+	if err := queue.deliver(ctx, messagequeue.SubjectRunComplete, complete); err != nil {
+		t.Fatal(err)
+	}
+	complete.Status, complete.Output, complete.CostUSD, complete.IdempotencyKey = "failed", "", 0.5, "done-2"
+	if err := queue.deliver(ctx, messagequeue.SubjectRunComplete, complete); err != nil {
+		t.Fatal(err)
+	}
+	r, _ := store.GetRun(ctx, "run-d1")
+	if r.Status != run.StatusCompleted || r.Output != "done" || r.CostUSD != 0.25 {
+		t.Fatalf("expected the first completion to stand, got %s %q %v", r.Status, r.Output, r.CostUSD)
+	}
+}
@@ -0,0 +1,356 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/workspace"
+	"github.com/Strob0t/CodeForge/internal/port/database"
+)
+
+// Workspace file limits.
+const (
+	maxWorkspaceFileSize = 2 << 20
+	maxWorkspaceEntries  = 10000
+)
+
+// ErrInvalidPath is returned for workspace paths that are absolute, leave
+// the workspace or point into its .git directory.
+var ErrInvalidPath = errors.New("invalid workspace path")
+
+// ErrFileTooLarge is returned for workspace files beyond the size limit.
+var ErrFileTooLarge = errors.New("workspace file too large")
+
+// ErrWriteDenied is returned for writes the project's policy denies.
+var ErrWriteDenied = errors.New("write denied by policy")
+
+// WorkspaceService browses the workspace of a project or run and applies
+// human edits to it. Writes are checked against the project's policy and
+// refused while a run works in the workspace.
+type WorkspaceService struct {
+	store  database.Store
+	policy *PolicyService
+}
+
+// NewWorkspaceService creates a WorkspaceService.
+func NewWorkspaceService(store database.Store, policies *PolicyService) *WorkspaceService {
+	return &WorkspaceService{store: store, policy: policies}
+}
+
+// Tree lists the directory dir of a workspace to the given depth; depth 0
+// lists the whole subtree. The .git directory is left out.
+func (s *WorkspaceService) Tree(ctx context.Context, projectID, runID, dir string, depth int) (*workspace.Tree, error) {
+	_, root, err := s.root(ctx, projectID, runID)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := workspacePath(dir, true)
+	if err != nil {
+		return nil, err
+	}
+	info, err := statWorkspaceFile(root, rel)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%w: %s is not a directory", ErrInvalidPath, rel)
+	}
+
+	base := filepath.Join(root, filepath.FromSlash(rel))
+	tree := &workspace.Tree{Path: rel, Entries: []workspace.Entry{}}
+	err = filepath.WalkDir(base, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == base {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if len(tree.Entries) == maxWorkspaceEntries {
+			tree.Truncated = true
+			return filepath.SkipAll
+		}
+		sub, _ := filepath.Rel(base, p)
+		e := workspace.Entry{Path: path.Join(rel, filepath.ToSlash(sub)), Dir: d.IsDir()}
+		if info, err := d.Info(); err == nil && !e.Dir {
+			e.Size = info.Size()
+		}
+		tree.Entries = append(tree.Entries, e)
+		if e.Dir && depth > 0 && strings.Count(sub, string(filepath.Separator))+1 >= depth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list %s: %w", rel, err)
+	}
+	return tree, nil
+}
+
+// Read returns the lines start to end (1-based, inclusive) of a workspace
+// file; end 0 reads to the end of the file.
+func (s *WorkspaceService) Read(ctx context.Context, projectID, runID, name string, start, end int) (*workspace.File, error) {
+	_, root, err := s.root(ctx, projectID, runID)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := workspacePath(name, false)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readWorkspaceFile(root, rel)
+	if err != nil {
+		return nil, err
+	}
+	f := &workspace.File{Path: rel, Hash: workspace.Hash(data), Size: int64(len(data))}
+	if !utf8.Valid(data) {
+		f.Binary = true
+		return f, nil
+	}
+	lines := workspace.SplitLines(string(data))
+	f.Lines = len(lines)
+	if start < 1 {
+		start = 1
+	}
+	if end < 1 || end > len(lines) {
+		end = len(lines)
+	}
+	if start > end {
+		return f, nil
+	}
+	f.StartLine, f.EndLine = start, end
+	f.Content = strings.Join(lines[start-1:end], "")
+	return f, nil
+}
+
+// Write replaces a workspace file, or creates it when req has no base hash.
+func (s *WorkspaceService) Write(ctx context.Context, projectID, runID, name string, req *workspace.WriteRequest) (*workspace.File, error) {
+	return s.update(ctx, projectID, runID, name, "Write", req.BaseHash, func(string) (string, error) {
+		return req.Content, nil
+	})
+}
+
+// Patch applies line edits to a workspace file.
+func (s *WorkspaceService) Patch(ctx context.Context, projectID, runID, name string, req *workspace.PatchRequest) (*workspace.File, error) {
+	if req.BaseHash == "" {
+		return nil, fmt.Errorf("%w: base_hash is required", workspace.ErrInvalidEdit)
+	}
+	return s.update(ctx, projectID, runID, name, "Edit", req.BaseHash, func(content string) (string, error) {
+		return workspace.ApplyEdits(content, req.Edits)
+	})
+}
+
+// update checks a write with the policy tool, compares the file with the
+// base hash and writes what edit makes of its content.
+func (s *WorkspaceService) update(ctx context.Context, projectID, runID, name, tool, baseHash string, edit func(string) (string, error)) (*workspace.File, error) {
+	proj, root, err := s.root(ctx, projectID, runID)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := workspacePath(name, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkPolicy(ctx, proj, tool, rel); err != nil {
+		return nil, err
+	}
+	if err := s.checkIdle(ctx, proj, root); err != nil {
+		return nil, err
+	}
+
+	old, err := readWorkspaceFile(root, rel)
+	switch {
+	case errors.Is(err, domain.ErrNotFound) && baseHash == "":
+		old = nil
+	case err != nil:
+		return nil, err
+	case baseHash == "":
+		return nil, fmt.Errorf("%s exists, base_hash is required: %w", rel, domain.ErrConflict)
+	case workspace.Hash(old) != baseHash:
+		return nil, fmt.Errorf("%s changed since base_hash: %w", rel, domain.ErrConflict)
+	}
+	if !utf8.Valid(old) {
+		return nil, fmt.Errorf("%w: %s is not a text file", ErrInvalidPath, rel)
+	}
+	content, err := edit(string(old))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxWorkspaceFileSize {
+		return nil, fmt.Errorf("%w: %d bytes exceeds %d", ErrFileTooLarge, len(content), maxWorkspaceFileSize)
+	}
+	if err := writeWorkspaceFile(root, rel, []byte(content)); err != nil {
+		return nil, err
+	}
+	slog.Info("workspace file written", "project_id", projectID, "run_id", runID, "path", rel, "bytes", len(content))
+
+	return &workspace.File{
+		Path:  rel,
+		Hash:  workspace.Hash([]byte(content)),
+		Size:  int64(len(content)),
+		Lines: len(workspace.SplitLines(content)),
+	}, nil
+}
+
+// root returns the project and the workspace of its run, or of the
+// project itself when runID is empty.
+func (s *WorkspaceService) root(ctx context.Context, projectID, runID string) (*project.Project, string, error) {
+	proj, err := s.store.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, "", fmt.Errorf("get project: %w", err)
+	}
+	dir := proj.WorkspacePath
+	if runID != "" {
+		r, err := s.store.GetRun(ctx, runID)
+		if err != nil {
+			return nil, "", fmt.Errorf("get run: %w", err)
+		}
+		if r.ProjectID != projectID {
+			return nil, "", fmt.Errorf("run %s of project %s: %w", runID, projectID, domain.ErrNotFound)
+		}
+		dir = r.Workspace(proj.WorkspacePath)
+	}
+	if dir == "" {
+		return nil, "", fmt.Errorf("project %s has no workspace (not cloned): %w", projectID, domain.ErrNotFound)
+	}
+	return proj, dir, nil
+}
+
+// checkPolicy evaluates a write as tool call of the project's policy. The
+// human writing is the approver, so "ask" permits the write.
+func (s *WorkspaceService) checkPolicy(ctx context.Context, proj *project.Project, tool, rel string) error {
+	eff, err := s.policy.Resolve([]policy.Layer{
+		{Level: policy.LevelTenant, Profile: s.policy.DefaultProfile()},
+		{Level: policy.LevelProject, Profile: proj.Config[policy.ConfigKeyProfile]},
+	})
+	if err != nil {
+		return fmt.Errorf("resolve policy: %w", err)
+	}
+	d, err := s.policy.Evaluate(ctx, eff.Name, policy.ToolCall{Tool: tool, Path: rel})
+	if err != nil {
+		return fmt.Errorf("evaluate policy: %w", err)
+	}
+	if d == policy.DecisionDeny {
+		return fmt.Errorf("%w: %s of %s (policy %s)", ErrWriteDenied, tool, rel, eff.Name)
+	}
+	return nil
+}
+
+// checkIdle refuses writes to a workspace an active run of the project
+// works in.
+func (s *WorkspaceService) checkIdle(ctx context.Context, proj *project.Project, root string) error {
+	runs, err := s.store.ListActiveRuns(ctx, proj.ID)
+	if err != nil {
+		return fmt.Errorf("list active runs: %w", err)
+	}
+	for i := range runs {
+		if runs[i].Workspace(proj.WorkspacePath) == root {
+			return fmt.Errorf("%w: run %s", ErrWorkspaceBusy, runs[i].ID)
+		}
+	}
+	return nil
+}
+
+// workspacePath cleans a slash-separated path relative to the workspace
+// root. The root itself ("") is only valid for directories.
+func workspacePath(p string, dir bool) (string, error) {
+	p = path.Clean("/" + strings.TrimSpace(filepath.ToSlash(p)))[1:]
+	switch {
+	case p == "" && dir:
+		return "", nil
+	case p == "":
+		return "", fmt.Errorf("%w: path is required", ErrInvalidPath)
+	case p == ".git" || strings.HasPrefix(p, ".git/"):
+		return "", fmt.Errorf("%w: %s is in the .git directory", ErrInvalidPath, p)
+	}
+	return p, nil
+}
+
+// statWorkspaceFile stats a file below root. Symlinks may not leave root.
+func statWorkspaceFile(root, rel string) (fs.FileInfo, error) {
+	r, err := os.OpenRoot(root)
+	if err != nil {
+		return nil, fmt.Errorf("open workspace: %w", err)
+	}
+	defer r.Close()
+	info, err := r.Stat(filepath.FromSlash(cmp.Or(rel, ".")))
+	if err != nil {
+		return nil, fileError(err)
+	}
+	return info, nil
+}
+
+// readWorkspaceFile reads a file below root. Symlinks may not leave root.
+func readWorkspaceFile(root, rel string) ([]byte, error) {
+	r, err := os.OpenRoot(root)
+	if err != nil {
+		return nil, fmt.Errorf("open workspace: %w", err)
+	}
+	defer r.Close()
+	f, err := r.Open(filepath.FromSlash(rel))
+	if err != nil {
+		return nil, fileError(err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fileError(err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%w: %s is a directory", ErrInvalidPath, rel)
+	}
+	if info.Size() > maxWorkspaceFileSize {
+		return nil, fmt.Errorf("%w: %s has %d bytes, limit %d", ErrFileTooLarge, rel, info.Size(), maxWorkspaceFileSize)
+	}
+	return io.ReadAll(f)
+}
+
+// writeWorkspaceFile writes a file below root, creating its directories.
+// Symlinks may not leave root.
+func writeWorkspaceFile(root, rel string, data []byte) error {
+	r, err := os.OpenRoot(root)
+	if err != nil {
+		return fmt.Errorf("open workspace: %w", err)
+	}
+	defer r.Close()
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		if err := r.Mkdir(filepath.Join(parts[:i]...), 0o755); err != nil && !errors.Is(err, fs.ErrExist) {
+			return fileError(err)
+		}
+	}
+	f, err := r.OpenFile(filepath.FromSlash(rel), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fileError(err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("write %s: %w", rel, err)
+	}
+	return f.Close()
+}
+
+// fileError maps missing files to domain.ErrNotFound and paths escaping
+// the workspace to ErrInvalidPath.
+func fileError(err error) error {
+	var pathErr *fs.PathError
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("%w: %w", domain.ErrNotFound, err)
+	case errors.As(err, &pathErr) && strings.Contains(pathErr.Err.Error(), "escapes"):
+		return fmt.Errorf("%w: %w", ErrInvalidPath, err)
+	}
+	return err
+}
@@ -0,0 +1,115 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/domain"
+	"github.com/Strob0t/CodeForge/internal/domain/policy"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/workspace"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func TestWorkspaceService_TreeAndRead(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"main.go":          "package main\n\nfunc main() {}\n",
+		"pkg/util/util.go": "package util\n",
+		".git/config":      "[core]\n",
+	} {
+		writeTestFile(t, dir, name, content)
+	}
+	if err := os.Symlink(t.TempDir(), filepath.Join(dir, "outside")); err != nil {
+		t.Fatal(err)
+	}
+	_, store, _, _ := newRuntimeTestEnv()
+	store.projects[0].WorkspacePath = dir
+	svc := service.NewWorkspaceService(store, service.NewPolicyService("headless-safe-sandbox", nil))
+	ctx := context.Background()
+
+	tree, err := svc.Tree(ctx, "proj-1", "", "", 1)
+	if err != nil {
+		t.Fatalf("Tree failed: %v", err)
+	}
+	if got := entryPaths(tree); len(got) != 3 || !got["main.go"] || !got["pkg"] || !got["outside"] {
+		t.Fatalf("expected the top-level entries without .git, got %+v", tree.Entries)
+	}
+	tree, err = svc.Tree(ctx, "proj-1", "", "pkg", 0)
+	if err != nil || !entryPaths(tree)["pkg/util/util.go"] {
+		t.Fatalf("expected the subtree of pkg, got %+v, %v", tree, err)
+	}
+
+	f, err := svc.Read(ctx, "proj-1", "", "main.go", 3, 3)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if f.Content != "func main() {}\n" || f.Lines != 3 || f.StartLine != 3 || f.Hash != workspace.Hash([]byte("package main\n\nfunc main() {}\n")) {
+		t.Fatalf("unexpected file %+v", f)
+	}
+	for _, name := range []string{"../etc/passwd", ".git/config", "outside/../../x", "outside/secret"} {
+		if _, err := svc.Read(ctx, "proj-1", "", name, 0, 0); !errors.Is(err, service.ErrInvalidPath) && !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("Read(%q): expected ErrInvalidPath or not found, got %v", name, err)
+		}
+	}
+	writeTestFile(t, dir, "outside/secret", "s3cret")
+	if _, err := svc.Read(ctx, "proj-1", "", "outside/secret", 0, 0); !errors.Is(err, service.ErrInvalidPath) {
+		t.Fatalf("expected a symlink out of the workspace refused, got %v", err)
+	}
+	if _, err := svc.Read(ctx, "proj-1", "", "missing.go", 0, 0); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestWorkspaceService_WriteAndPatch(t *testing.T) {
+	dir := t.TempDir()
+	_, store, _, _ := newRuntimeTestEnv()
+	store.projects[0].WorkspacePath = dir
+	svc := service.NewWorkspaceService(store, service.NewPolicyService("headless-safe-sandbox", nil))
+	ctx := context.Background()
+
+	f, err := svc.Write(ctx, "proj-1", "", "docs/notes/todo.md", &workspace.WriteRequest{Content: "one\ntwo\n"})
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "docs", "notes", "todo.md")); string(data) != "one\ntwo\n" || f.Lines != 2 {
+		t.Fatalf("unexpected file %q, %+v", data, f)
+	}
+	if _, err := svc.Write(ctx, "proj-1", "", "docs/notes/todo.md", &workspace.WriteRequest{Content: "x"}); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("expected ErrConflict overwriting without base_hash, got %v", err)
+	}
+
+	patch := &workspace.PatchRequest{BaseHash: f.Hash, Edits: []workspace.Edit{{StartLine: 2, EndLine: 2, Text: "TWO\nthree\n"}}}
+	f, err = svc.Patch(ctx, "proj-1", "", "docs/notes/todo.md", patch)
+	if err != nil {
+		t.Fatalf("Patch failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "docs", "notes", "todo.md")); string(data) != "one\nTWO\nthree\n" || f.Lines != 3 {
+		t.Fatalf("unexpected patched file %q, %+v", data, f)
+	}
+	if _, err := svc.Patch(ctx, "proj-1", "", "docs/notes/todo.md", patch); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("expected ErrConflict for a stale base_hash, got %v", err)
+	}
+
+	store.projects[0].Config = map[string]string{policy.ConfigKeyProfile: "plan-readonly"}
+	if _, err := svc.Patch(ctx, "proj-1", "", "docs/notes/todo.md", &workspace.PatchRequest{BaseHash: f.Hash}); !errors.Is(err, service.ErrWriteDenied) {
+		t.Fatalf("expected ErrWriteDenied under plan-readonly, got %v", err)
+	}
+	store.projects[0].Config = nil
+
+	store.runs = append(store.runs, run.Run{ID: "run-active", ProjectID: "proj-1", Status: run.StatusRunning})
+	if _, err := svc.Write(ctx, "proj-1", "", "new.txt", &workspace.WriteRequest{Content: "x"}); !errors.Is(err, service.ErrWorkspaceBusy) {
+		t.Fatalf("expected ErrWorkspaceBusy while a run is active, got %v", err)
+	}
+}
+
+func entryPaths(tree *workspace.Tree) map[string]bool {
+	paths := make(map[string]bool, len(tree.Entries))
+	for _, e := range tree.Entries {
+		paths[e.Path] = true
+	}
+	return paths
+}
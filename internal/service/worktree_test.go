@@ -0,0 +1,171 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/Strob0t/CodeForge/internal/adapter/gitlocal"
+	"github.com/Strob0t/CodeForge/internal/config"
+	"github.com/Strob0t/CodeForge/internal/domain/agent"
+	"github.com/Strob0t/CodeForge/internal/domain/project"
+	"github.com/Strob0t/CodeForge/internal/domain/run"
+	"github.com/Strob0t/CodeForge/internal/domain/task"
+	"github.com/Strob0t/CodeForge/internal/port/messagequeue"
+	"github.com/Strob0t/CodeForge/internal/service"
+)
+
+func initWorktreeTestRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init"},
+		{"config", "user.email", "test@test.com"},
+		{"config", "user.name", "Test"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, out)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"add", "."}, {"commit", "-m", "init"}} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, out)
+		}
+	}
+	return dir
+}
+
+func TestStartRun_IsolatedWorktreeLifecycle(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available in test environment")
+	}
+	ctx := context.Background()
+	repo := initWorktreeTestRepo(t)
+	root := t.TempDir()
+
+	store := &runtimeMockStore{
+		projects: []project.Project{{ID: "proj-1", Name: "test", Provider: "local", WorkspacePath: repo}},
+		agents:   []agent.Agent{{ID: "agent-1", ProjectID: "proj-1", Name: "a", Status: agent.StatusIdle}},
+		tasks:    []task.Task{{ID: "task-1", ProjectID: "proj-1", Title: "t", Prompt: "p"}},
+	}
+	queue := &runtimeMockQueue{}
+	cfg := &config.Runtime{WorktreeRetention: time.Hour}
+	svc := service.NewRuntimeService(store, queue, &runtimeMockBroadcaster{}, &runtimeMockEventStore{},
+		service.NewPolicyService("plan-readonly", nil), cfg)
+	projects := service.NewProjectService(store)
+	projects.SetWorktreeRoot(root)
+	svc.SetProjectService(projects)
+
+	r, err := svc.StartRun(ctx, &run.StartRequest{TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", Isolate: true})
+	if err != nil {
+		t.Fatalf("StartRun failed: %v", err)
+	}
+	want := filepath.Join(root, "proj-1", r.ID)
+	if r.WorktreePath != want {
+		t.Fatalf("expected worktree %q, got %q", want, r.WorktreePath)
+	}
+	if _, err := os.Stat(filepath.Join(want, "main.go")); err != nil {
+		t.Fatalf("expected checked-out worktree: %v", err)
+	}
+	msg, ok := queue.lastMessage(messagequeue.SubjectRunStart)
+	if !ok {
+		t.Fatal("expected run start message")
+	}
+	var payload messagequeue.RunStartPayload
+	_ = json.Unmarshal(msg.Data, &payload)
+	if payload.WorkspacePath != want {
+		t.Fatalf("expected workspace_path %q in payload, got %q", want, payload.WorkspacePath)
+	}
+
+	// Completion keeps the worktree within the retention period.
+	if err := svc.HandleRunComplete(ctx, &messagequeue.RunCompletePayload{RunID: r.ID, Status: "completed"}); err != nil {
+		t.Fatalf("HandleRunComplete failed: %v", err)
+	}
+	if _, err := os.Stat(want); err != nil {
+		t.Fatalf("expected worktree retained: %v", err)
+	}
+
+	// Past the retention period the janitor removes it.
+	past := time.Now().Add(-2 * time.Hour)
+	store.mu.Lock()
+	store.runs[0].CompletedAt = &past
+	store.mu.Unlock()
+	n, err := svc.PruneWorktrees(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 pruned worktree, got %d (%v)", n, err)
+	}
+	if _, err := os.Stat(want); !os.IsNotExist(err) {
+		t.Fatalf("expected worktree removed, got %v", err)
+	}
+	if store.runs[0].WorktreePath != "" {
+		t.Fatalf("expected worktree cleared on run, got %q", store.runs[0].WorktreePath)
+	}
+}
+
+func TestStartRun_IsolateWithoutClone(t *testing.T) {
+	svc, store, _, _ := newRuntimeTestEnv()
+	store.projects[0].WorkspacePath = ""
+	svc.SetProjectService(service.NewProjectService(store))
+
+	// Nothing is cloned, so there is no shared clone to isolate from.
+	r, err := svc.StartRun(context.Background(), &run.StartRequest{
+		TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", Isolate: true,
+	})
+	if err != nil {
+		t.Fatalf("StartRun failed: %v", err)
+	}
+	if r.WorktreePath != "" {
+		t.Fatalf("expected no worktree, got %q", r.WorktreePath)
+	}
+}
+
+func TestStartRun_Branch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available in test environment")
+	}
+	ctx := context.Background()
+	origin := initWorktreeTestRepo(t)
+	for _, args := range [][]string{{"checkout", "-b", "feature"}, {"commit", "--allow-empty", "-m", "feature"}, {"checkout", "-"}} {
+		if out, err := exec.Command("git", append([]string{"-C", origin}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, out)
+		}
+	}
+	clone := filepath.Join(t.TempDir(), "clone")
+	if out, err := exec.Command("git", "clone", origin, clone).CombinedOutput(); err != nil {
+		t.Fatalf("git clone: %s", out)
+	}
+
+	svc, store, _, _ := newRuntimeTestEnv()
+	store.projects[0].Provider, store.projects[0].WorkspacePath = "local", clone
+	projects := service.NewProjectService(store)
+	projects.SetWorktreeRoot(t.TempDir())
+	svc.SetProjectService(projects)
+
+	r, err := svc.StartRun(ctx, &run.StartRequest{
+		TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", Branch: "feature", DeliverMode: run.DeliverModePush,
+	})
+	if err != nil {
+		t.Fatalf("StartRun failed: %v", err)
+	}
+	if r.WorktreePath == "" || r.Branch != "feature" {
+		t.Fatalf("expected a worktree of the branch, got %+v", r)
+	}
+	out, err := exec.Command("git", "-C", r.WorktreePath, "log", "-1", "--format=%s").Output()
+	if err != nil || strings.TrimSpace(string(out)) != "feature" {
+		t.Fatalf("expected the branch head checked out, got %q (%v)", out, err)
+	}
+
+	// Without a clone there is nothing to check the branch out from.
+	store.projects[0].WorkspacePath = ""
+	if _, err := svc.StartRun(ctx, &run.StartRequest{TaskID: "task-1", AgentID: "agent-1", ProjectID: "proj-1", Branch: "feature"}); err == nil {
+		t.Fatal("expected error for a branch run without a workspace")
+	}
+}
@@ -0,0 +1,87 @@
+// Package vault encrypts values at rest with AES-256-GCM using keys derived
+// from a single master key via HKDF-SHA256.
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// MinMasterKeyLen is the minimum master key length in bytes.
+const MinMasterKeyLen = 32
+
+var (
+	ErrKeyTooShort = errors.New("vault: master key must be at least 32 bytes")
+	ErrCiphertext  = errors.New("vault: ciphertext is invalid or was encrypted with another key")
+)
+
+// Vault encrypts and decrypts values for one purpose. Vaults derived for
+// different purposes from the same master key cannot read each other's data.
+type Vault struct {
+	aead    cipher.AEAD
+	master  string
+	purpose string
+}
+
+// New derives a purpose-specific key from masterKey and returns a Vault.
+func New(masterKey, purpose string) (*Vault, error) {
+	if len(masterKey) < MinMasterKeyLen {
+		return nil, ErrKeyTooShort
+	}
+	key, err := DeriveKey([]byte(masterKey), purpose)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+	return &Vault{aead: aead, master: masterKey, purpose: purpose}, nil
+}
+
+// Derive returns a vault for a scope within the vault's purpose, such as
+// one tenant, whose key is derived from the same master key.
+func (v *Vault) Derive(scope string) (*Vault, error) {
+	return New(v.master, v.purpose+"/"+scope)
+}
+
+// DeriveKey derives a 256-bit key for purpose from the master key.
+func DeriveKey(master []byte, purpose string) ([]byte, error) {
+	key, err := hkdf.Key(sha256.New, master, nil, "codeforge/"+purpose, 32)
+	if err != nil {
+		return nil, fmt.Errorf("vault: derive key: %w", err)
+	}
+	return key, nil
+}
+
+// Encrypt seals plaintext. aad binds the ciphertext to its context (e.g.
+// the owning record) so it cannot be moved to another record.
+func (v *Vault) Encrypt(plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, v.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("vault: nonce: %w", err)
+	}
+	return v.aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// Decrypt opens a ciphertext produced by Encrypt with the same aad.
+func (v *Vault) Decrypt(ciphertext, aad []byte) ([]byte, error) {
+	n := v.aead.NonceSize()
+	if len(ciphertext) < n {
+		return nil, ErrCiphertext
+	}
+	plain, err := v.aead.Open(nil, ciphertext[:n], ciphertext[n:], aad)
+	if err != nil {
+		return nil, ErrCiphertext
+	}
+	return plain, nil
+}
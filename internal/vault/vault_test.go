@@ -0,0 +1,74 @@
+package vault_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/Strob0t/CodeForge/internal/vault"
+)
+
+const testKey = "0123456789abcdef0123456789abcdef"
+
+func TestVault_RoundTrip(t *testing.T) {
+	v, err := vault.New(testKey, "secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, err := v.Encrypt([]byte("hunter2"), []byte("p1/TOKEN"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(ct, []byte("hunter2")) {
+		t.Fatal("ciphertext contains plaintext")
+	}
+	plain, err := v.Decrypt(ct, []byte("p1/TOKEN"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plain) != "hunter2" {
+		t.Fatalf("got %q", plain)
+	}
+}
+
+func TestVault_RejectsWrongContext(t *testing.T) {
+	v, _ := vault.New(testKey, "secrets")
+	ct, _ := v.Encrypt([]byte("hunter2"), []byte("p1/TOKEN"))
+
+	if _, err := v.Decrypt(ct, []byte("p2/TOKEN")); !errors.Is(err, vault.ErrCiphertext) {
+		t.Fatalf("expected ErrCiphertext for wrong aad, got %v", err)
+	}
+	other, _ := vault.New(testKey, "other-purpose")
+	if _, err := other.Decrypt(ct, []byte("p1/TOKEN")); !errors.Is(err, vault.ErrCiphertext) {
+		t.Fatalf("expected ErrCiphertext for other purpose, got %v", err)
+	}
+	if _, err := v.Decrypt([]byte("x"), nil); !errors.Is(err, vault.ErrCiphertext) {
+		t.Fatalf("expected ErrCiphertext for truncated input, got %v", err)
+	}
+}
+
+func TestVault_KeyTooShort(t *testing.T) {
+	if _, err := vault.New("short", "secrets"); !errors.Is(err, vault.ErrKeyTooShort) {
+		t.Fatalf("expected ErrKeyTooShort, got %v", err)
+	}
+}
+
+func TestVault_Derive(t *testing.T) {
+	v, _ := vault.New(testKey, "secrets")
+	acme, err := v.Derive("tenant/acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, _ := acme.Encrypt([]byte("hunter2"), nil)
+	if _, err := v.Decrypt(ct, nil); !errors.Is(err, vault.ErrCiphertext) {
+		t.Fatalf("expected the parent vault to fail, got %v", err)
+	}
+	other, _ := v.Derive("tenant/other")
+	if _, err := other.Decrypt(ct, nil); !errors.Is(err, vault.ErrCiphertext) {
+		t.Fatalf("expected another tenant's vault to fail, got %v", err)
+	}
+	again, _ := vault.New(testKey, "secrets/tenant/acme")
+	if plain, err := again.Decrypt(ct, nil); err != nil || string(plain) != "hunter2" {
+		t.Fatalf("derived key must be reproducible, got %q %v", plain, err)
+	}
+}
@@ -18,6 +18,7 @@ class WorkerSettings:
     log_level: str
     log_service: str
     health_port: int
+    run_start: str
 
     def __init__(self) -> None:
         self.nats_url = os.environ.get("NATS_URL", "nats://localhost:4222")
@@ -26,3 +27,5 @@ class WorkerSettings:
         self.log_level = os.environ.get("CODEFORGE_WORKER_LOG_LEVEL", "info")
         self.log_service = os.environ.get("CODEFORGE_WORKER_LOG_SERVICE", "codeforge-worker")
         self.health_port = int(os.environ.get("CODEFORGE_WORKER_HEALTH_PORT", "8081"))
+        # Set by the control plane when it starts the worker in a run sandbox
+        self.run_start = os.environ.get("CODEFORGE_RUN_START", "")
@@ -15,7 +15,7 @@ from codeforge.llm import LiteLLMClient
 from codeforge.logger import setup_logging
 from codeforge.models import QualityGateRequest, QualityGateResult, RunStartMessage, TaskMessage, TaskResult
 from codeforge.qualitygate import QualityGateExecutor
-from codeforge.runtime import RuntimeClient
+from codeforge.runtime import RuntimeClient, publish_result
 
 if TYPE_CHECKING:
     from nats.aio.client import Client as NATSClient
@@ -29,8 +29,10 @@ SUBJECT_RUN_START = "runs.start"
 SUBJECT_QG_REQUEST = "runs.qualitygate.request"
 SUBJECT_QG_RESULT = "runs.qualitygate.result"
 HEADER_REQUEST_ID = "X-Request-ID"
-HEADER_RETRY_COUNT = "Retry-Count"
-MAX_RETRIES = 3
+HEADER_DLQ_ERROR = "CodeForge-DLQ-Error"
+HEADER_DLQ_DELIVERIES = "CodeForge-DLQ-Deliveries"
+DLQ_PREFIX = "dlq."
+MAX_DELIVER = 4
 
 logger = structlog.get_logger()
 
@@ -140,18 +142,23 @@ class TaskConsumer:
 
             # Publish result back
             if self._js is not None:
-                await self._js.publish(SUBJECT_RESULT, result.model_dump_json().encode())
+                await publish_result(
+                    self._js,
+                    SUBJECT_RESULT,
+                    result.model_dump_json().encode(),
+                    result.idempotency_key,
+                )
 
             await msg.ack()
             log.info("task completed", status=result.status)
 
-        except Exception:
-            retries = self._retry_count(msg)
-            log.exception("failed to process message", retry=retries)
+        except Exception as exc:
+            deliveries = self._delivery_count(msg)
+            log.exception("failed to process message", delivery=deliveries)
 
-            if retries >= MAX_RETRIES:
-                log.warning("max retries reached, moving to DLQ", retry=retries)
-                await self._move_to_dlq(msg)
+            if deliveries >= MAX_DELIVER:
+                log.warning("max deliveries reached, moving to DLQ", delivery=deliveries)
+                await self._move_to_dlq(msg, deliveries, str(exc))
             else:
                 await msg.nak()
 
@@ -167,34 +174,7 @@ class TaskConsumer:
                 await msg.nak()
                 return
 
-            runtime = RuntimeClient(
-                js=self._js,
-                run_id=run_msg.run_id,
-                task_id=run_msg.task_id,
-                project_id=run_msg.project_id,
-                termination=run_msg.termination,
-            )
-            await runtime.start_cancel_listener()
-
-            # Enrich prompt with pre-packed context entries (Phase 5D)
-            enriched_prompt = run_msg.prompt
-            if run_msg.context:
-                context_section = "\n\n--- Relevant Context ---\n"
-                for entry in run_msg.context:
-                    context_section += f"\n### {entry.kind}: {entry.path}\n{entry.content}\n"
-                enriched_prompt = run_msg.prompt + context_section
-                log.info("context injected", entries=len(run_msg.context))
-
-            # Convert to TaskMessage for executor compatibility
-            task = TaskMessage(
-                id=run_msg.task_id,
-                project_id=run_msg.project_id,
-                title=run_msg.prompt[:80],
-                prompt=enriched_prompt,
-                config=run_msg.config,
-            )
-
-            await self._executor.execute_with_runtime(task, runtime)
+            await self._execute_run(run_msg)
             await msg.ack()
             log.info("run processing complete")
 
@@ -202,6 +182,54 @@ class TaskConsumer:
             logger.exception("failed to process run start message")
             await msg.nak()
 
+    async def _execute_run(self, run_msg: RunStartMessage) -> None:
+        """Execute a run with a RuntimeClient, injecting its context pack into the prompt."""
+        log = logger.bind(run_id=run_msg.run_id, task_id=run_msg.task_id)
+        runtime = RuntimeClient(
+            js=self._js,
+            run_id=run_msg.run_id,
+            task_id=run_msg.task_id,
+            project_id=run_msg.project_id,
+            termination=run_msg.termination,
+            env=run_msg.env,
+        )
+        await runtime.start_cancel_listener()
+
+        # Enrich prompt with pre-packed context entries (Phase 5D)
+        enriched_prompt = run_msg.prompt
+        if run_msg.context:
+            context_section = "\n\n--- Relevant Context ---\n"
+            for entry in run_msg.context:
+                context_section += f"\n### {entry.kind}: {entry.path}\n{entry.content}\n"
+            enriched_prompt = run_msg.prompt + context_section
+            log.info("context injected", entries=len(run_msg.context))
+
+        # Convert to TaskMessage for executor compatibility
+        task = TaskMessage(
+            id=run_msg.task_id,
+            project_id=run_msg.project_id,
+            title=run_msg.prompt[:80],
+            prompt=enriched_prompt,
+            config=run_msg.config,
+        )
+
+        await self._executor.execute_with_runtime(task, runtime)
+
+    async def run_once(self, data: bytes) -> None:
+        """Execute the single run described by a run start message and disconnect.
+
+        Used inside a run sandbox, which the control plane starts per run
+        instead of publishing the run to the worker pool.
+        """
+        run_msg = RunStartMessage.model_validate_json(data)
+        self._nc = await nats.connect(self.nats_url)
+        self._js = self._nc.jetstream()
+        logger.info("connected to NATS", url=self.nats_url, run_id=run_msg.run_id)
+        try:
+            await self._execute_run(run_msg)
+        finally:
+            await self.stop()
+
     async def _process_quality_gate_messages(self, sub: object) -> None:
         """Message processing loop for quality gate requests."""
         while self._running:
@@ -226,9 +254,11 @@ class TaskConsumer:
             result: QualityGateResult = await self._gate_executor.execute(request)
 
             if self._js is not None:
-                await self._js.publish(
+                await publish_result(
+                    self._js,
                     SUBJECT_QG_RESULT,
                     result.model_dump_json().encode(),
+                    result.idempotency_key,
                 )
 
             await msg.ack()
@@ -239,26 +269,28 @@ class TaskConsumer:
             await msg.nak()
 
     @staticmethod
-    def _retry_count(msg: nats.aio.msg.Msg) -> int:
-        """Extract the Retry-Count header value, defaulting to 0."""
-        if msg.headers and HEADER_RETRY_COUNT in msg.headers:
-            try:
-                return int(msg.headers[HEADER_RETRY_COUNT])
-            except (ValueError, TypeError):
-                return 0
-        return 0
+    def _delivery_count(msg: nats.aio.msg.Msg) -> int:
+        """Return how often JetStream has delivered the message, defaulting to 1."""
+        try:
+            return int(msg.metadata.num_delivered)
+        except Exception:
+            return 1
 
-    async def _move_to_dlq(self, msg: nats.aio.msg.Msg) -> None:
-        """Publish message to DLQ subject and ack the original."""
+    async def _move_to_dlq(self, msg: nats.aio.msg.Msg, deliveries: int, error: str) -> None:
+        """Publish message to the dead-letter stream and ack the original."""
         if self._js is None:
             return
-        dlq_subject = msg.subject + ".dlq"
+        dlq_subject = DLQ_PREFIX + msg.subject
         headers = dict(msg.headers) if msg.headers else {}
+        headers[HEADER_DLQ_ERROR] = error
+        headers[HEADER_DLQ_DELIVERIES] = str(deliveries)
         try:
-            await self._js.publish(dlq_subject, msg.data, headers=headers or None)
+            await self._js.publish(dlq_subject, msg.data, headers=headers)
             logger.warning("message moved to DLQ", dlq_subject=dlq_subject)
         except Exception:
             logger.exception("failed to publish to DLQ", dlq_subject=dlq_subject)
+            await msg.nak()
+            return
         await msg.ack()
 
     async def _publish_output(self, task_id: str, line: str, stream: str = "stdout", request_id: str = "") -> None:
@@ -303,6 +335,10 @@ async def main() -> None:
         litellm_key=settings.litellm_api_key,
     )
 
+    if settings.run_start:
+        await consumer.run_once(settings.run_start.encode())
+        return
+
     loop = asyncio.get_running_loop()
     for sig in (signal.SIGINT, signal.SIGTERM):
         loop.add_signal_handler(sig, lambda: asyncio.create_task(consumer.stop()))
@@ -85,9 +85,13 @@ class AgentExecutor:
             # Report result
             await runtime.report_tool_result(
                 call_id=decision.call_id,
+                tool="LLM",
                 success=True,
                 output=response.content[:200],
-                cost_usd=0.0,  # Cost tracking from LLM response (future)
+                cost_usd=response.cost_usd,
+                tokens_in=response.tokens_in,
+                tokens_out=response.tokens_out,
+                model=response.model,
             )
 
             if runtime.is_cancelled:
@@ -6,6 +6,9 @@ from dataclasses import dataclass
 
 import httpx
 
+# LiteLLM Proxy reports the cost of a completion in this response header.
+COST_HEADER = "x-litellm-response-cost"
+
 
 @dataclass(frozen=True)
 class CompletionResponse:
@@ -15,6 +18,7 @@ class CompletionResponse:
     tokens_in: int
     tokens_out: int
     model: str
+    cost_usd: float = 0.0
 
 
 class LiteLLMClient:
@@ -61,11 +65,17 @@ class LiteLLMClient:
         tokens_in = usage.get("prompt_tokens", 0) if isinstance(usage, dict) else 0
         tokens_out = usage.get("completion_tokens", 0) if isinstance(usage, dict) else 0
 
+        try:
+            cost_usd = float(resp.headers.get(COST_HEADER, 0) or 0)
+        except ValueError:
+            cost_usd = 0.0
+
         return CompletionResponse(
             content=str(content),
             tokens_in=int(tokens_in),
             tokens_out=int(tokens_out),
-            model=model,
+            model=str(data.get("model") or model),
+            cost_usd=cost_usd,
         )
 
     async def health(self) -> bool:
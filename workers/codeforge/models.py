@@ -2,11 +2,17 @@
 
 from __future__ import annotations
 
+import uuid
 from enum import StrEnum
 
 from pydantic import BaseModel, Field
 
 
+def new_idempotency_key() -> str:
+    """Return a key identifying one result message across publish retries."""
+    return uuid.uuid4().hex
+
+
 class TaskStatus(StrEnum):
     """Status of a task in the pipeline."""
 
@@ -39,6 +45,7 @@ class TaskResult(BaseModel):
     tokens_in: int = 0
     tokens_out: int = 0
     cost_usd: float = 0.0
+    idempotency_key: str = Field(default_factory=new_idempotency_key)
 
 
 # --- Run Protocol Models (Phase 4B) ---
@@ -62,6 +69,24 @@ class ContextEntry(BaseModel):
     priority: int = 50
 
 
+class SubProject(BaseModel):
+    """Monorepo sub-project a run is scoped to; path is relative to the workspace."""
+
+    path: str
+    language: str = ""
+    build_command: str = ""
+    test_command: str = ""
+
+
+class Attachment(BaseModel):
+    """Task attachment written into the workspace; path is relative to its root."""
+
+    id: str
+    path: str
+    content_type: str = ""
+    size: int = 0
+
+
 class RunStartMessage(BaseModel):
     """Message received from NATS when a run is started."""
 
@@ -72,9 +97,13 @@ class RunStartMessage(BaseModel):
     prompt: str
     policy_profile: str = ""
     exec_mode: str = "mount"
+    workspace_path: str = ""  # isolated worktree; empty means the project workspace
+    sub_project: SubProject | None = None
     config: dict[str, str] = Field(default_factory=dict)
+    env: dict[str, str] = Field(default_factory=dict)  # resolved secrets for tool processes; never log
     termination: TerminationConfig = Field(default_factory=TerminationConfig)
     context: list[ContextEntry] = Field(default_factory=list)
+    attachments: list[Attachment] = Field(default_factory=list)  # also listed in the prompt
 
 
 class ToolCallDecision(BaseModel):
@@ -96,11 +125,26 @@ class RunCompleteMessage(BaseModel):
     error: str = ""
     cost_usd: float = 0.0
     step_count: int = 0
+    idempotency_key: str = Field(default_factory=new_idempotency_key)
 
 
 # --- Quality Gate Models (Phase 4C) ---
 
 
+class LinterCommand(BaseModel):
+    """A linter to run during a quality gate; its JSON output is parsed by Go."""
+
+    tool: str
+    command: str
+
+
+class LintResult(BaseModel):
+    """Raw output of one requested linter."""
+
+    tool: str
+    output: str = ""
+
+
 class QualityGateRequest(BaseModel):
     """Request from Go control plane to execute quality gate checks."""
 
@@ -111,6 +155,8 @@ class QualityGateRequest(BaseModel):
     run_lint: bool = False
     test_command: str = ""
     lint_command: str = ""
+    test_report_path: str = ""  # JUnit XML written by the test command, relative to workspace_path
+    linters: list[LinterCommand] = Field(default_factory=list)
 
 
 class QualityGateResult(BaseModel):
@@ -121,4 +167,7 @@ class QualityGateResult(BaseModel):
     lint_passed: bool | None = None
     test_output: str = ""
     lint_output: str = ""
+    test_report: str = ""
     error: str = ""
+    lint_results: list[LintResult] = Field(default_factory=list)
+    idempotency_key: str = Field(default_factory=new_idempotency_key)
@@ -7,14 +7,16 @@ commands in the project workspace, and reports results back.
 from __future__ import annotations
 
 import asyncio
+from pathlib import Path
 
 import structlog
 
-from codeforge.models import QualityGateRequest, QualityGateResult
+from codeforge.models import LintResult, QualityGateRequest, QualityGateResult
 
 logger = structlog.get_logger()
 
 DEFAULT_TIMEOUT_SECONDS = 120
+MAX_REPORT_BYTES = 4 * 1024 * 1024
 
 
 class QualityGateExecutor:
@@ -38,6 +40,8 @@ class QualityGateExecutor:
             )
             result.tests_passed = passed
             result.test_output = output
+            if request.test_report_path:
+                result.test_report = self._read_report(request.workspace_path, request.test_report_path, log)
 
         if request.run_lint and request.lint_command:
             passed, output = await self._run_command(
@@ -48,10 +52,16 @@ class QualityGateExecutor:
             result.lint_passed = passed
             result.lint_output = output
 
+        # Linters exit non-zero on any finding; only their output matters.
+        for linter in request.linters:
+            _, output = await self._run_command(linter.command, request.workspace_path, log)
+            result.lint_results.append(LintResult(tool=linter.tool, output=output))
+
         log.info(
             "quality gate execution completed",
             tests_passed=result.tests_passed,
             lint_passed=result.lint_passed,
+            linters=len(result.lint_results),
         )
         return result
 
@@ -89,3 +99,20 @@ class QualityGateExecutor:
         except Exception as exc:
             log.error("gate command error", command=command, error=str(exc))
             return False, str(exc)
+
+    @staticmethod
+    def _read_report(cwd: str, report_path: str, log: structlog.stdlib.BoundLogger) -> str:
+        """Read the test report file written by the test command, if any."""
+        root = Path(cwd).resolve()
+        path = (root / report_path).resolve()
+        if not path.is_relative_to(root):
+            log.warning("test report outside workspace", path=report_path)
+            return ""
+        try:
+            if path.stat().st_size > MAX_REPORT_BYTES:
+                log.warning("test report too large", path=report_path)
+                return ""
+            return path.read_text(errors="replace")
+        except OSError as exc:
+            log.info("test report not readable", path=report_path, error=str(exc))
+            return ""
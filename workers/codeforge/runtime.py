@@ -14,7 +14,7 @@ from typing import TYPE_CHECKING
 
 import structlog
 
-from codeforge.models import RunCompleteMessage, ToolCallDecision
+from codeforge.models import RunCompleteMessage, ToolCallDecision, new_idempotency_key
 
 if TYPE_CHECKING:
     from nats.js.client import JetStreamContext
@@ -31,9 +31,32 @@ SUBJECT_RUN_CANCEL = "runs.cancel"
 
 RESPONSE_TIMEOUT_SECONDS = 30
 
+# Result messages are retried with the same idempotency key, which is also
+# the JetStream message ID, so the control plane applies them once.
+HEADER_MSG_ID = "Nats-Msg-Id"
+PUBLISH_ATTEMPTS = 3
+PUBLISH_BACKOFF_SECONDS = 0.2
+
 logger = structlog.get_logger()
 
 
+async def publish_result(js: JetStreamContext, subject: str, data: bytes, key: str) -> None:
+    """Publish a result message, retrying failed attempts with the same key.
+
+    An attempt that raised may still have been stored, so the control plane
+    can receive the message more than once; it drops copies by the key.
+    """
+    for attempt in range(1, PUBLISH_ATTEMPTS + 1):
+        try:
+            await js.publish(subject, data, headers={HEADER_MSG_ID: key})
+            return
+        except Exception:
+            if attempt == PUBLISH_ATTEMPTS:
+                raise
+            logger.warning("publish failed, retrying", subject=subject, attempt=attempt)
+            await asyncio.sleep(PUBLISH_BACKOFF_SECONDS * attempt)
+
+
 class RuntimeClient:
     """Handles the run protocol: request permission, report results, complete run.
 
@@ -48,12 +71,14 @@ class RuntimeClient:
         task_id: str,
         project_id: str,
         termination: TerminationConfig,
+        env: dict[str, str] | None = None,
     ) -> None:
         self._js = js
         self.run_id = run_id
         self.task_id = task_id
         self.project_id = project_id
         self.termination = termination
+        self.env = env or {}  # secret env vars for tool subprocesses
         self._step_count = 0
         self._total_cost = 0.0
         self._cancelled = False
@@ -161,8 +186,15 @@ class RuntimeClient:
         output: str = "",
         error: str = "",
         cost_usd: float = 0.0,
+        tokens_in: int = 0,
+        tokens_out: int = 0,
+        model: str = "",
     ) -> None:
-        """Report the outcome of an executed tool call back to the control plane."""
+        """Report the outcome of an executed tool call back to the control plane.
+
+        LLM calls pass the model that answered, so the control plane can
+        attribute their tokens and cost per model.
+        """
         self._step_count += 1
         self._total_cost += cost_usd
 
@@ -174,10 +206,17 @@ class RuntimeClient:
             "output": output,
             "error": error,
             "cost_usd": cost_usd,
+            "tokens_in": tokens_in,
+            "tokens_out": tokens_out,
+            "idempotency_key": new_idempotency_key(),
         }
-        await self._js.publish(
+        if model:
+            result["model"] = model
+        await publish_result(
+            self._js,
             SUBJECT_TOOLCALL_RESULT,
             json.dumps(result).encode(),
+            result["idempotency_key"],
         )
 
     async def complete_run(
@@ -197,9 +236,11 @@ class RuntimeClient:
             cost_usd=self._total_cost,
             step_count=self._step_count,
         )
-        await self._js.publish(
+        await publish_result(
+            self._js,
             SUBJECT_RUN_COMPLETE,
             msg.model_dump_json().encode(),
+            msg.idempotency_key,
         )
         self._log.info(
             "run completed",
@@ -189,3 +189,29 @@ async def test_handle_run_start_without_context(consumer: TaskConsumer) -> None:
     assert task_arg.prompt == "Refactor utils module"
     assert "--- Relevant Context ---" not in task_arg.prompt
     msg.ack.assert_called_once()
+
+
+async def test_run_once_executes_single_run(consumer: TaskConsumer, monkeypatch: pytest.MonkeyPatch) -> None:
+    """run_once should execute the given run and disconnect without subscribing."""
+    run_msg = RunStartMessage(
+        run_id="run-3",
+        task_id="task-3",
+        project_id="proj-1",
+        agent_id="agent-1",
+        prompt="Add input validation",
+    )
+    nc = MagicMock()
+    nc.jetstream = MagicMock(return_value=AsyncMock())
+    nc.is_connected = False
+    monkeypatch.setattr("codeforge.consumer.nats.connect", AsyncMock(return_value=nc))
+    consumer._executor = MagicMock()
+    consumer._executor.execute_with_runtime = AsyncMock()
+    consumer._llm = AsyncMock()
+
+    await consumer.run_once(run_msg.model_dump_json().encode())
+
+    task_arg, runtime_arg = consumer._executor.execute_with_runtime.call_args.args
+    assert task_arg.prompt == "Add input validation"
+    assert runtime_arg.run_id == "run-3"
+    nc.jetstream.return_value.subscribe.assert_called_once()  # cancel listener only
+    consumer._llm.close.assert_called_once()
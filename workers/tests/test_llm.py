@@ -36,6 +36,27 @@ async def test_completion_parses_response(client: LiteLLMClient) -> None:
     assert result.tokens_in == 10
     assert result.tokens_out == 5
     assert result.model == "test-model"
+    assert result.cost_usd == 0.0
+
+
+async def test_completion_reads_cost_and_model(client: LiteLLMClient) -> None:
+    """completion() should report the proxy's cost header and the answering model."""
+    mock_response = httpx.Response(
+        200,
+        json={
+            "model": "gpt-4o-mini",
+            "choices": [{"message": {"content": "ok"}}],
+            "usage": {"prompt_tokens": 100, "completion_tokens": 20},
+        },
+        headers={"x-litellm-response-cost": "0.00042"},
+        request=_FAKE_REQUEST,
+    )
+
+    with patch.object(client._client, "post", new_callable=AsyncMock, return_value=mock_response):
+        result = await client.completion(prompt="test", model="cheap")
+
+    assert result.model == "gpt-4o-mini"
+    assert result.cost_usd == pytest.approx(0.00042)
 
 
 async def test_completion_empty_choices(client: LiteLLMClient) -> None:
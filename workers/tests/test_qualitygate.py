@@ -2,12 +2,13 @@
 
 from __future__ import annotations
 
+from pathlib import Path
 from unittest.mock import AsyncMock, MagicMock
 
 import pytest
 
 from codeforge.consumer import TaskConsumer
-from codeforge.models import QualityGateRequest, QualityGateResult
+from codeforge.models import LinterCommand, QualityGateRequest, QualityGateResult
 from codeforge.qualitygate import QualityGateExecutor
 
 
@@ -122,6 +123,53 @@ async def test_execute_no_commands(executor: QualityGateExecutor) -> None:
     assert result.lint_passed is None
 
 
+async def test_execute_reads_test_report(executor: QualityGateExecutor, tmp_path: Path) -> None:
+    """The JUnit report written by the test command should be returned."""
+    request = QualityGateRequest(
+        run_id="run-report",
+        project_id="proj-1",
+        workspace_path=str(tmp_path),
+        run_tests=True,
+        test_command="echo '<testsuite/>' > report.xml",
+        test_report_path="report.xml",
+    )
+    result = await executor.execute(request)
+
+    assert result.tests_passed is True
+    assert result.test_report.strip() == "<testsuite/>"
+
+
+async def test_execute_ignores_report_outside_workspace(executor: QualityGateExecutor, tmp_path: Path) -> None:
+    """Report paths escaping the workspace should not be read."""
+    request = QualityGateRequest(
+        run_id="run-escape",
+        project_id="proj-1",
+        workspace_path=str(tmp_path),
+        run_tests=True,
+        test_command="true",
+        test_report_path="../../etc/passwd",
+    )
+    result = await executor.execute(request)
+
+    assert result.test_report == ""
+
+
+async def test_execute_linters(executor: QualityGateExecutor, tmp_path: Path) -> None:
+    """Linter output should be returned even when the linter exits non-zero."""
+    request = QualityGateRequest(
+        run_id="run-lint",
+        project_id="proj-1",
+        workspace_path=str(tmp_path),
+        linters=[LinterCommand(tool="ruff", command="echo '[]' && exit 1")],
+    )
+    result = await executor.execute(request)
+
+    assert result.lint_passed is None
+    assert len(result.lint_results) == 1
+    assert result.lint_results[0].tool == "ruff"
+    assert result.lint_results[0].output.strip() == "[]"
+
+
 async def test_handle_quality_gate_message(consumer: TaskConsumer) -> None:
     """Consumer should parse quality gate request and publish result."""
     request = QualityGateRequest(
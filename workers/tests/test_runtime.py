@@ -13,6 +13,7 @@ from codeforge.models import (
     ToolCallDecision,
 )
 from codeforge.runtime import (
+    HEADER_MSG_ID,
     SUBJECT_RUN_COMPLETE,
     SUBJECT_RUN_OUTPUT,
     SUBJECT_TOOLCALL_REQUEST,
@@ -136,6 +137,9 @@ async def test_report_tool_result(runtime: RuntimeClient, mock_js: AsyncMock) ->
         success=True,
         output="file contents",
         cost_usd=0.005,
+        tokens_in=120,
+        tokens_out=30,
+        model="gpt-4o-mini",
     )
 
     assert runtime.step_count == 1
@@ -148,6 +152,27 @@ async def test_report_tool_result(runtime: RuntimeClient, mock_js: AsyncMock) ->
     assert result["call_id"] == "call-1"
     assert result["success"] is True
     assert result["cost_usd"] == pytest.approx(0.005)
+    assert result["tokens_in"] == 120
+    assert result["tokens_out"] == 30
+    assert result["model"] == "gpt-4o-mini"
+
+
+async def test_report_tool_result_retries_with_same_key(
+    runtime: RuntimeClient, mock_js: AsyncMock, monkeypatch: pytest.MonkeyPatch
+) -> None:
+    """A failed publish should be retried with the same idempotency key."""
+    monkeypatch.setattr("codeforge.runtime.PUBLISH_BACKOFF_SECONDS", 0)
+    mock_js.publish.side_effect = [TimeoutError(), None]
+
+    await runtime.report_tool_result(call_id="call-1", tool="Read", success=True, cost_usd=0.01)
+
+    assert mock_js.publish.call_count == 2
+    first, second = mock_js.publish.call_args_list
+    key = json.loads(first.args[1])["idempotency_key"]
+    assert key
+    assert json.loads(second.args[1])["idempotency_key"] == key
+    assert first.kwargs["headers"] == {HEADER_MSG_ID: key}
+    assert second.kwargs["headers"] == {HEADER_MSG_ID: key}
 
 
 async def test_report_tool_result_accumulates(runtime: RuntimeClient, mock_js: AsyncMock) -> None:
@@ -205,6 +230,7 @@ def test_run_start_message_parsing() -> None:
             "policy_profile": "headless-safe-sandbox",
             "exec_mode": "mount",
             "config": {"model": "gpt-4"},
+            "env": {"API_KEY": "sk-test"},
             "termination": {"max_steps": 100, "timeout_seconds": 300, "max_cost": 10.0},
         }
     )
@@ -215,6 +241,7 @@ def test_run_start_message_parsing() -> None:
     assert msg.termination.max_steps == 100
     assert msg.termination.max_cost == pytest.approx(10.0)
     assert msg.config["model"] == "gpt-4"
+    assert msg.env == {"API_KEY": "sk-test"}
 
 
 def test_tool_call_decision_parsing() -> None: